@@ -10,10 +10,13 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/bturcanu/OpenClause/pkg/approvals"
 	"github.com/bturcanu/OpenClause/pkg/archiver"
 	"github.com/bturcanu/OpenClause/pkg/config"
 	"github.com/bturcanu/OpenClause/pkg/evidence"
-	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/bturcanu/OpenClause/pkg/metering"
+	"github.com/bturcanu/OpenClause/pkg/pgpool"
+	"github.com/bturcanu/OpenClause/pkg/tenants"
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
 )
@@ -33,8 +36,35 @@ func (m minioUploader) Upload(ctx context.Context, key string, body []byte) erro
 	return nil
 }
 
+// DeleteBefore removes objects under prefix last modified before the cutoff.
+func (m minioUploader) DeleteBefore(ctx context.Context, prefix string, before time.Time) (int, error) {
+	n := 0
+	for obj := range m.client.ListObjects(ctx, m.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if obj.Err != nil {
+			return n, fmt.Errorf("list %s: %w", prefix, obj.Err)
+		}
+		if !obj.LastModified.Before(before) {
+			continue
+		}
+		if err := m.client.RemoveObject(ctx, m.bucket, obj.Key, minio.RemoveObjectOptions{}); err != nil {
+			return n, fmt.Errorf("remove %s: %w", obj.Key, err)
+		}
+		n++
+	}
+	return n, nil
+}
+
 func main() {
 	log := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	// ── Configuration ────────────────────────────────────────────────────
+	effectiveCfg, err := config.Load()
+	if err != nil {
+		log.Error("invalid configuration", "error", err)
+		os.Exit(1)
+	}
+	log.Info("configuration loaded", "file", os.Getenv(config.FileEnv), "settings", effectiveCfg.Settings())
+
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
@@ -45,31 +75,64 @@ func main() {
 		config.EnvOr("POSTGRES_PORT", "5432"),
 		config.EnvOr("POSTGRES_DB", "openclause"),
 	)
-	pool, err := pgxpool.New(ctx, dbURL)
+	pool, err := pgpool.New(ctx, dbURL, pgpool.ConfigFromEnv(nil))
 	if err != nil {
 		log.Error("postgres connect failed", "error", err)
 		os.Exit(1)
 	}
 	defer pool.Close()
 
-	minioClient, err := minio.New(config.EnvOr("EVIDENCE_S3_ENDPOINT", "localhost:9000"), &minio.Options{
-		Creds:  credentials.NewStaticV4(config.EnvOr("EVIDENCE_S3_ACCESS_KEY", "minioadmin"), config.EnvOr("EVIDENCE_S3_SECRET_KEY", "minioadmin"), ""),
-		Secure: config.EnvOr("EVIDENCE_S3_SECURE", "false") == "true",
-	})
-	if err != nil {
-		log.Error("minio init failed", "error", err)
-		os.Exit(1)
+	// ARCHIVER_DIR keeps bundles on local disk instead of S3.
+	var uploader archiver.Uploader
+	if dir := os.Getenv("ARCHIVER_DIR"); dir != "" {
+		uploader = archiver.NewFSUploader(dir)
+	} else {
+		minioClient, err := minio.New(config.EnvOr("EVIDENCE_S3_ENDPOINT", "localhost:9000"), &minio.Options{
+			Creds:  credentials.NewStaticV4(config.EnvOr("EVIDENCE_S3_ACCESS_KEY", "minioadmin"), config.EnvOr("EVIDENCE_S3_SECRET_KEY", "minioadmin"), ""),
+			Secure: config.EnvOrBool("EVIDENCE_S3_SECURE", false),
+		})
+		if err != nil {
+			log.Error("minio init failed", "error", err)
+			os.Exit(1)
+		}
+		uploader = minioUploader{
+			client: minioClient,
+			bucket: config.EnvOr("EVIDENCE_S3_BUCKET", "openclause-evidence"),
+		}
 	}
 
 	store := evidence.NewStore(pool)
-	svc := archiver.New(store, minioUploader{
-		client: minioClient,
-		bucket: config.EnvOr("EVIDENCE_S3_BUCKET", "openclause-evidence"),
-	})
+	svc := archiver.New(store, uploader)
+
+	tenantStore := tenants.NewStore(pool)
+	meter := metering.NewRecorder(metering.NewStore(pool), log)
+	svc.SetMeter(meter)
+	svc.SetOutbox(approvals.NewStore(pool))
 
 	onceTenant := os.Getenv("ARCHIVER_TENANT_ID")
-	runOnce := config.EnvOr("ARCHIVER_RUN_ONCE", "true") == "true"
-	interval := time.Duration(config.EnvOrInt("ARCHIVER_INTERVAL_SEC", 300)) * time.Second
+	runOnce := config.EnvOrBool("ARCHIVER_RUN_ONCE", true)
+	interval := config.EnvOrDuration("ARCHIVER_INTERVAL_SEC", time.Second, 5*time.Minute)
+	outboxRetention := time.Duration(config.EnvOrInt("OUTBOX_RETENTION_DAYS", 30)) * 24 * time.Hour
+
+	// prune enforces the tenant's retention_days setting on archived bundles.
+	prune := func(tenantID string) {
+		rec, err := tenantStore.GetSettings(ctx, tenantID)
+		if err != nil {
+			log.Error("tenant settings lookup failed", "tenant_id", tenantID, "error", err)
+			return
+		}
+		if rec == nil || rec.Settings.RetentionDays == 0 {
+			return
+		}
+		n, err := svc.PruneTenant(ctx, tenantID, time.Duration(rec.Settings.RetentionDays)*24*time.Hour)
+		if err != nil {
+			log.Error("prune archived evidence failed", "tenant_id", tenantID, "error", err)
+			return
+		}
+		if n > 0 {
+			log.Info("pruned archived evidence bundles", "tenant_id", tenantID, "count", n, "retention_days", rec.Settings.RetentionDays)
+		}
+	}
 
 	run := func() {
 		tenants := []string{}
@@ -92,6 +155,21 @@ func main() {
 			if key != "" {
 				log.Info("archived evidence bundle", "tenant_id", tenantID, "key", key)
 			}
+			prune(tenantID)
+		}
+		// The outbox is shared by all tenants, so it is archived once per run
+		// rather than per tenant.
+		if onceTenant == "" {
+			keys, n, err := svc.ArchiveOutbox(ctx, outboxRetention)
+			if err != nil {
+				log.Error("archive notification outbox failed", "error", err)
+			}
+			if n > 0 {
+				log.Info("archived notification outbox rows", "rows", n, "bundles", len(keys))
+			}
+		}
+		if err := meter.Flush(ctx); err != nil {
+			log.Error("usage flush failed", "error", err)
 		}
 	}
 
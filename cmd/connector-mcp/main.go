@@ -0,0 +1,155 @@
+// Connector-MCP exposes upstream Model Context Protocol servers as governed
+// tools: each server listed in MCP_SERVERS is served under /servers/{tool},
+// and its MCP tools become that tool's actions.
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/config"
+	"github.com/bturcanu/OpenClause/pkg/connectors/mcp"
+	"github.com/bturcanu/OpenClause/pkg/connectors/sdk"
+	"github.com/bturcanu/OpenClause/pkg/diagnostics"
+	ocOtel "github.com/bturcanu/OpenClause/pkg/otel"
+	"github.com/bturcanu/OpenClause/pkg/secrets"
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+func main() {
+	log := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	slog.SetDefault(log)
+
+	// ── Configuration ────────────────────────────────────────────────────
+	effectiveCfg, err := config.Load("INTERNAL_AUTH_TOKEN", "MCP_SERVERS")
+	if err != nil {
+		log.Error("invalid configuration", "error", err)
+		os.Exit(1)
+	}
+	log.Info("configuration loaded", "file", os.Getenv(config.FileEnv), "settings", effectiveCfg.Settings())
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	upstreams, err := mcp.ParseServers(os.Getenv("MCP_SERVERS"))
+	if err != nil {
+		log.Error("invalid MCP_SERVERS", "error", err)
+		os.Exit(1)
+	}
+	if len(upstreams) == 0 {
+		log.Error("MCP_SERVERS is required")
+		os.Exit(1)
+	}
+	if _, ok := upstreams[types.PlanTool]; ok {
+		log.Error("MCP_SERVERS may not use the reserved tool name", "tool", types.PlanTool)
+		os.Exit(1)
+	}
+
+	internalToken := os.Getenv("INTERNAL_AUTH_TOKEN")
+
+	// ── Upstream servers ─────────────────────────────────────────────────
+	secretResolver := secrets.NewResolverFromEnv(nil, log)
+	httpClient := &http.Client{Timeout: config.EnvOrDuration("MCP_TIMEOUT_SEC", time.Second, 15*time.Second)}
+	servers := make(map[string]*mcp.Server, len(upstreams))
+	for tool, url := range upstreams {
+		env := tokenEnv(tool)
+		token, err := secretResolver.Bind(ctx, os.Getenv(env), nil)
+		if err != nil {
+			log.Error("resolve "+env, "error", err)
+			os.Exit(1)
+		}
+		servers[tool] = mcp.NewServer(tool, mcp.NewClient(url, token.Get, httpClient))
+	}
+	go secretResolver.Run(ctx, config.EnvOrDuration("SECRETS_REFRESH_SEC", time.Second, 5*time.Minute))
+
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(ocOtel.Middleware)
+	r.Use(middleware.Recoverer)
+	r.Use(middleware.Timeout(30 * time.Second))
+
+	r.Get("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("OK"))
+	})
+
+	for tool, server := range servers {
+		sdkCfg := sdk.Config{InternalToken: internalToken, Logger: log, Name: "connector-mcp", Endpoint: upstreams[tool]}
+		r.Route("/servers/"+tool, func(r chi.Router) {
+			r.Post("/exec", sdk.Handler(server, sdkCfg))
+			r.Get("/manifest", sdk.ManifestFuncHandler(server.Manifest, sdkCfg))
+			// healthz reports whether the upstream server answers.
+			r.Get("/healthz", func(w http.ResponseWriter, r *http.Request) {
+				if err := server.Ping(r.Context()); err != nil {
+					log.Warn("mcp server unreachable", "tool", tool, "error", err)
+					http.Error(w, "upstream unavailable", http.StatusBadGateway)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte("OK"))
+			})
+		})
+	}
+
+	addr := config.EnvOr("CONNECTOR_MCP_ADDR", ":8084")
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           r,
+		ReadTimeout:       15 * time.Second,
+		ReadHeaderTimeout: 5 * time.Second,
+		WriteTimeout:      35 * time.Second,
+		IdleTimeout:       60 * time.Second,
+	}
+
+	metricsAddr := config.EnvOr("CONNECTOR_MCP_METRICS_ADDR", "127.0.0.1:9094")
+	metricsSrv := diagnostics.NewServer(diagnostics.Config{Addr: metricsAddr, InternalToken: internalToken})
+	go func() {
+		log.Info("metrics server starting", "addr", metricsAddr)
+		if err := metricsSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Error("metrics server error", "error", err)
+		}
+	}()
+
+	go func() {
+		log.Info("connector-mcp starting", "addr", addr, "servers", serverNames(upstreams))
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Error("server error", "error", err)
+			cancel()
+		}
+	}()
+
+	<-ctx.Done()
+	log.Info("shutting down connector-mcp")
+	shutCtx, shutCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer shutCancel()
+	if err := srv.Shutdown(shutCtx); err != nil {
+		log.Error("shutdown error", "error", err)
+	}
+	if err := metricsSrv.Shutdown(shutCtx); err != nil {
+		log.Error("metrics server shutdown error", "error", err)
+	}
+}
+
+// tokenEnv is the variable holding the bearer token (or secret reference)
+// for the server exposed as tool: MCP_<TOOL>_TOKEN, with "-" as "_".
+func tokenEnv(tool string) string {
+	return "MCP_" + strings.ToUpper(strings.ReplaceAll(tool, "-", "_")) + "_TOKEN"
+}
+
+func serverNames(upstreams map[string]string) []string {
+	names := make([]string, 0, len(upstreams))
+	for tool := range upstreams {
+		names = append(names, tool)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,108 @@
+// Connector-sandbox runs the commands allowlisted in SANDBOX_COMMANDS_FILE
+// as governed actions, without a shell, under timeouts and output caps and,
+// unless a command allows it, without network access.
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/config"
+	"github.com/bturcanu/OpenClause/pkg/connectors/sandbox"
+	"github.com/bturcanu/OpenClause/pkg/diagnostics"
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+func main() {
+	log := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	slog.SetDefault(log)
+
+	// ── Configuration ────────────────────────────────────────────────────
+	effectiveCfg, err := config.Load("INTERNAL_AUTH_TOKEN", "SANDBOX_COMMANDS_FILE")
+	if err != nil {
+		log.Error("invalid configuration", "error", err)
+		os.Exit(1)
+	}
+	log.Info("configuration loaded", "file", os.Getenv(config.FileEnv), "settings", effectiveCfg.Settings())
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	commands, err := sandbox.LoadCommands(os.Getenv("SANDBOX_COMMANDS_FILE"))
+	if err != nil {
+		log.Error("invalid SANDBOX_COMMANDS_FILE", "error", err)
+		os.Exit(1)
+	}
+	if len(commands) == 0 {
+		log.Error("SANDBOX_COMMANDS_FILE allowlists no commands")
+		os.Exit(1)
+	}
+	tool := config.EnvOr("SANDBOX_TOOL", "sandbox")
+	if tool == types.PlanTool {
+		log.Error("SANDBOX_TOOL may not be the reserved tool name", "tool", types.PlanTool)
+		os.Exit(1)
+	}
+
+	internalToken := os.Getenv("INTERNAL_AUTH_TOKEN")
+
+	connector := sandbox.New(sandbox.Config{
+		Logger:        log,
+		Tool:          tool,
+		Commands:      commands,
+		InternalToken: internalToken,
+	})
+
+	addr := config.EnvOr("CONNECTOR_SANDBOX_ADDR", ":8085")
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           connector.Handler(),
+		ReadTimeout:       15 * time.Second,
+		ReadHeaderTimeout: 5 * time.Second,
+		// Commands may run for as long as the gateway waits on them.
+		WriteTimeout: 5 * time.Minute,
+		IdleTimeout:  60 * time.Second,
+	}
+
+	metricsAddr := config.EnvOr("CONNECTOR_SANDBOX_METRICS_ADDR", "127.0.0.1:9095")
+	metricsSrv := diagnostics.NewServer(diagnostics.Config{Addr: metricsAddr, InternalToken: internalToken})
+	go func() {
+		log.Info("metrics server starting", "addr", metricsAddr)
+		if err := metricsSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Error("metrics server error", "error", err)
+		}
+	}()
+
+	go func() {
+		log.Info("connector-sandbox starting", "addr", addr, "tool", tool, "commands", commandNames(commands))
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Error("server error", "error", err)
+			cancel()
+		}
+	}()
+
+	<-ctx.Done()
+	log.Info("shutting down connector-sandbox")
+	shutCtx, shutCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer shutCancel()
+	if err := srv.Shutdown(shutCtx); err != nil {
+		log.Error("shutdown error", "error", err)
+	}
+	if err := metricsSrv.Shutdown(shutCtx); err != nil {
+		log.Error("metrics server shutdown error", "error", err)
+	}
+}
+
+func commandNames(commands map[string]sandbox.Command) []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
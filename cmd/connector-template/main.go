@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
@@ -10,10 +11,23 @@ import (
 	"github.com/bturcanu/OpenClause/pkg/config"
 	"github.com/bturcanu/OpenClause/pkg/connectors"
 	"github.com/bturcanu/OpenClause/pkg/connectors/sdk"
+	"github.com/bturcanu/OpenClause/pkg/diagnostics"
 )
 
 type templateConnector struct{}
 
+// manifest lists the actions the connector implements; the gateway turns it
+// into LLM tool definitions at GET /v1/tools/spec.
+var manifest = connectors.Manifest{
+	Tool: "template",
+	Actions: []connectors.ActionManifest{{
+		Action:      "echo",
+		Description: "Echo the call back without side effects.",
+		Params:      json.RawMessage(`{"type":"object","properties":{}}`),
+		ReadOnly:    true,
+	}},
+}
+
 func (t templateConnector) Exec(_ context.Context, req connectors.ExecRequest) connectors.ExecResponse {
 	output, _ := json.Marshal(map[string]any{
 		"tool":     req.Tool,
@@ -24,8 +38,26 @@ func (t templateConnector) Exec(_ context.Context, req connectors.ExecRequest) c
 	return connectors.ExecResponse{Status: "success", OutputJSON: output}
 }
 
+// Plan describes what Exec would do without doing it. Connectors that change
+// upstream state should render the exact request here so approvers see it.
+func (t templateConnector) Plan(_ context.Context, req connectors.ExecRequest) (connectors.ExecPlan, error) {
+	return connectors.ExecPlan{
+		Summary:  fmt.Sprintf("Echo %s.%s on %s", req.Tool, req.Action, req.Resource),
+		ReadOnly: true,
+	}, nil
+}
+
 func main() {
 	log := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	// ── Configuration ────────────────────────────────────────────────────
+	effectiveCfg, err := config.Load()
+	if err != nil {
+		log.Error("invalid configuration", "error", err)
+		os.Exit(1)
+	}
+	log.Info("configuration loaded", "file", os.Getenv(config.FileEnv), "settings", effectiveCfg.Settings())
+
 	addr := config.EnvOr("CONNECTOR_TEMPLATE_ADDR", ":8099")
 	internalToken := os.Getenv("INTERNAL_AUTH_TOKEN")
 
@@ -33,12 +65,23 @@ func main() {
 	mux.HandleFunc("/exec", sdk.Handler(templateConnector{}, sdk.Config{
 		InternalToken: internalToken,
 		Logger:        log,
+		Name:          "connector-template",
 	}))
+	mux.HandleFunc("GET /manifest", sdk.ManifestHandler(manifest, sdk.Config{InternalToken: internalToken}))
 	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write([]byte("OK"))
 	})
 
+	metricsAddr := config.EnvOr("CONNECTOR_TEMPLATE_METRICS_ADDR", "127.0.0.1:9099")
+	metricsSrv := diagnostics.NewServer(diagnostics.Config{Addr: metricsAddr, InternalToken: internalToken})
+	go func() {
+		log.Info("metrics server starting", "addr", metricsAddr)
+		if err := metricsSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Error("metrics server error", "error", err)
+		}
+	}()
+
 	log.Info("connector-template starting", "addr", addr)
 	if err := http.ListenAndServe(addr, mux); err != nil && err != http.ErrServerClosed {
 		log.Error("server error", "error", err)
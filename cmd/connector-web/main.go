@@ -0,0 +1,104 @@
+// Connector-web serves the read-only web.fetch action: https fetches limited
+// to each tenant's allowed domains, public addresses, text content types and
+// a size and time budget.
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/approvals"
+	"github.com/bturcanu/OpenClause/pkg/config"
+	"github.com/bturcanu/OpenClause/pkg/connectors/web"
+	"github.com/bturcanu/OpenClause/pkg/diagnostics"
+)
+
+func main() {
+	log := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	slog.SetDefault(log)
+
+	// ── Configuration ────────────────────────────────────────────────────
+	effectiveCfg, err := config.Load("INTERNAL_AUTH_TOKEN", "WEB_FETCH_ALLOWED_DOMAINS")
+	if err != nil {
+		log.Error("invalid configuration", "error", err)
+		os.Exit(1)
+	}
+	log.Info("configuration loaded", "file", os.Getenv(config.FileEnv), "settings", effectiveCfg.Settings())
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	allowlists, err := web.ParseAllowlists(os.Getenv("WEB_FETCH_ALLOWED_DOMAINS"))
+	if err != nil {
+		log.Error("invalid WEB_FETCH_ALLOWED_DOMAINS", "error", err)
+		os.Exit(1)
+	}
+	// Reuse the webhook egress parser for the CIDR list.
+	egress, err := approvals.ParseEgressPolicy("", os.Getenv("WEB_FETCH_ALLOWED_CIDRS"))
+	if err != nil {
+		log.Error("invalid WEB_FETCH_ALLOWED_CIDRS", "error", err)
+		os.Exit(1)
+	}
+	var contentTypes []string
+	for _, t := range strings.Split(os.Getenv("WEB_FETCH_CONTENT_TYPES"), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			contentTypes = append(contentTypes, t)
+		}
+	}
+
+	internalToken := os.Getenv("INTERNAL_AUTH_TOKEN")
+
+	connector := web.New(web.Config{
+		Logger:        log,
+		Allowlists:    allowlists,
+		AllowedCIDRs:  egress.AllowedCIDRs,
+		ContentTypes:  contentTypes,
+		MaxBytes:      int64(config.EnvOrInt("WEB_FETCH_MAX_BYTES", web.DefaultMaxBytes)),
+		Timeout:       config.EnvOrDuration("WEB_FETCH_TIMEOUT_SEC", time.Second, web.DefaultTimeout),
+		InternalToken: internalToken,
+	})
+
+	addr := config.EnvOr("CONNECTOR_WEB_ADDR", ":8086")
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           connector.Handler(),
+		ReadTimeout:       15 * time.Second,
+		ReadHeaderTimeout: 5 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       60 * time.Second,
+	}
+
+	metricsAddr := config.EnvOr("CONNECTOR_WEB_METRICS_ADDR", "127.0.0.1:9096")
+	metricsSrv := diagnostics.NewServer(diagnostics.Config{Addr: metricsAddr, InternalToken: internalToken})
+	go func() {
+		log.Info("metrics server starting", "addr", metricsAddr)
+		if err := metricsSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Error("metrics server error", "error", err)
+		}
+	}()
+
+	go func() {
+		log.Info("connector-web starting", "addr", addr, "tenants", len(allowlists))
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Error("server error", "error", err)
+			cancel()
+		}
+	}()
+
+	<-ctx.Done()
+	log.Info("shutting down connector-web")
+	shutCtx, shutCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer shutCancel()
+	if err := srv.Shutdown(shutCtx); err != nil {
+		log.Error("shutdown error", "error", err)
+	}
+	if err := metricsSrv.Shutdown(shutCtx); err != nil {
+		log.Error("metrics server shutdown error", "error", err)
+	}
+}
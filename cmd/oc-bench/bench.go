@@ -0,0 +1,450 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/sdk/client"
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+// callClass is one kind of synthetic call and the decision the default
+// policy bundle gives it.
+type callClass struct {
+	name   string
+	tool   string
+	action string
+	risk   int
+	want   types.Decision
+}
+
+var classes = []callClass{
+	{name: "allow", tool: "jira", action: "issue.list", risk: 1, want: types.DecisionAllow},
+	{name: "approve", tool: "slack", action: "msg.post", risk: 8, want: types.DecisionApprove},
+	{name: "deny", tool: "slack", action: "workspace.export", risk: 1, want: types.DecisionDeny},
+}
+
+type tenantKey struct {
+	tenant string
+	key    string
+}
+
+// weightedClass is a class and its share of the mix.
+type weightedClass struct {
+	class  callClass
+	weight int
+}
+
+type benchConfig struct {
+	server      string
+	tenants     []tenantKey
+	agent       string
+	mix         []weightedClass
+	sizes       []int
+	concurrency int
+	duration    time.Duration
+	requests    int
+	rate        float64
+	timeout     time.Duration
+	seed        uint64
+}
+
+// parseKeys parses "tenant:key,..." as API_KEYS does.
+func parseKeys(s string) ([]tenantKey, error) {
+	var out []tenantKey
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		tenant, key, ok := strings.Cut(pair, ":")
+		if !ok || tenant == "" || key == "" {
+			return nil, fmt.Errorf("-keys: %q is not tenant:key", pair)
+		}
+		out = append(out, tenantKey{tenant: tenant, key: key})
+	}
+	if len(out) == 0 {
+		return nil, errors.New("-keys: at least one tenant:key is required")
+	}
+	return out, nil
+}
+
+// parseMix parses "allow=80,approve=15,deny=5". Classes left out get no
+// calls.
+func parseMix(s string) ([]weightedClass, error) {
+	var out []weightedClass
+	total := 0
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, w, ok := strings.Cut(part, "=")
+		i := slices.IndexFunc(classes, func(c callClass) bool { return c.name == name })
+		if !ok || i < 0 {
+			return nil, fmt.Errorf("-mix: %q is not allow=N, approve=N or deny=N", part)
+		}
+		weight, err := strconv.Atoi(w)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("-mix: weight of %s must be a non-negative integer", name)
+		}
+		if weight > 0 {
+			out = append(out, weightedClass{class: classes[i], weight: weight})
+			total += weight
+		}
+	}
+	if total == 0 {
+		return nil, errors.New("-mix: at least one class needs a positive weight")
+	}
+	return out, nil
+}
+
+// parseSizes parses the comma-separated params sizes.
+func parseSizes(s string) ([]int, error) {
+	var out []int
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("-payload-sizes: %q is not a size in bytes", part)
+		}
+		out = append(out, n)
+	}
+	if len(out) == 0 {
+		return nil, errors.New("-payload-sizes: at least one size is required")
+	}
+	return out, nil
+}
+
+// pick chooses a class by weight.
+func (c benchConfig) pick(rng *rand.Rand) callClass {
+	total := 0
+	for _, w := range c.mix {
+		total += w.weight
+	}
+	n := rng.IntN(total)
+	for _, w := range c.mix {
+		if n < w.weight {
+			return w.class
+		}
+		n -= w.weight
+	}
+	return c.mix[len(c.mix)-1].class
+}
+
+// params returns a JSON object of about size bytes.
+func params(size int) json.RawMessage {
+	const overhead = len(`{"text":""}`)
+	return json.RawMessage(`{"text":"` + strings.Repeat("x", max(size-overhead, 0)) + `"}`)
+}
+
+type job struct {
+	class  callClass
+	tenant int
+	size   int
+}
+
+type result struct {
+	class    string
+	latency  time.Duration
+	decision types.Decision
+	unexpect bool
+	recorded bool   // the response carried an event ID
+	errCode  string // "" on success
+}
+
+// bench sends calls until the duration passes, the request count is
+// reached or ctx is done, and summarizes them.
+func bench(ctx context.Context, cfg benchConfig) *report {
+	if cfg.duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.duration)
+		defer cancel()
+	}
+	hc := &http.Client{Transport: &http.Transport{
+		MaxIdleConns:        cfg.concurrency,
+		MaxIdleConnsPerHost: cfg.concurrency,
+		IdleConnTimeout:     90 * time.Second,
+	}}
+	clients := make([]*client.Client, len(cfg.tenants))
+	for i, tk := range cfg.tenants {
+		c := client.New(cfg.server, tk.key)
+		c.SetHTTPClient(hc)
+		// Retries would hide the latency being measured.
+		c.SetRetryPolicy(client.RetryPolicy{MaxAttempts: 1})
+		clients[i] = c
+	}
+	payloads := make(map[int]json.RawMessage, len(cfg.sizes))
+	for _, n := range cfg.sizes {
+		payloads[n] = params(n)
+	}
+
+	jobs := make(chan job)
+	go func() {
+		defer close(jobs)
+		rng := rand.New(rand.NewPCG(cfg.seed, 0))
+		var tick <-chan time.Time
+		if cfg.rate > 0 {
+			t := time.NewTicker(time.Duration(float64(time.Second) / cfg.rate))
+			defer t.Stop()
+			tick = t.C
+		}
+		for i := 0; cfg.requests <= 0 || i < cfg.requests; i++ {
+			if tick != nil {
+				select {
+				case <-ctx.Done():
+					return
+				case <-tick:
+				}
+			}
+			j := job{class: cfg.pick(rng), tenant: i % len(cfg.tenants), size: cfg.sizes[rng.IntN(len(cfg.sizes))]}
+			select {
+			case <-ctx.Done():
+				return
+			case jobs <- j:
+			}
+		}
+	}()
+
+	results := make(chan result, cfg.concurrency)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for range cfg.concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				results <- call(ctx, cfg, clients[j.tenant], cfg.tenants[j.tenant].tenant, j.class, payloads[j.size])
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	agg := newAggregate()
+	for r := range results {
+		agg.add(r)
+	}
+	return agg.report(time.Since(start))
+}
+
+// call makes one tool call. Calls in flight when the run ends finish,
+// bounded by the per-call timeout, so the last ones are not counted as
+// failures.
+func call(ctx context.Context, cfg benchConfig, c *client.Client, tenant string, class callClass, p json.RawMessage) result {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cfg.timeout)
+	defer cancel()
+	req := types.ToolCallRequest{
+		TenantID:  tenant,
+		AgentID:   cfg.agent,
+		Tool:      class.tool,
+		Action:    class.action,
+		Params:    p,
+		RiskScore: class.risk,
+	}
+	start := time.Now()
+	resp, err := c.Submit(ctx, req)
+	r := result{class: class.name, latency: time.Since(start)}
+	if err != nil {
+		r.errCode = errorCode(err)
+		return r
+	}
+	r.decision = resp.Decision
+	r.unexpect = resp.Decision != class.want
+	r.recorded = resp.EventID != ""
+	return r
+}
+
+// errorCode names a failure for the report: the API error code, the HTTP
+// status, or the kind of transport failure.
+func errorCode(err error) string {
+	var apiErr *types.APIError
+	var statusErr *client.StatusError
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr.Code
+	case errors.As(err, &statusErr):
+		return "HTTP_" + strconv.Itoa(statusErr.StatusCode)
+	case errors.Is(err, context.DeadlineExceeded):
+		return "TIMEOUT"
+	default:
+		return "TRANSPORT"
+	}
+}
+
+// aggregate collects results by class.
+type aggregate struct {
+	latencies map[string][]time.Duration // successful calls only
+	requests  map[string]int
+	errors    map[string]int
+	unexpect  map[string]int
+	byCode    map[string]int
+	recorded  int
+}
+
+func newAggregate() *aggregate {
+	return &aggregate{
+		latencies: make(map[string][]time.Duration),
+		requests:  make(map[string]int),
+		errors:    make(map[string]int),
+		unexpect:  make(map[string]int),
+		byCode:    make(map[string]int),
+	}
+}
+
+func (a *aggregate) add(r result) {
+	a.requests[r.class]++
+	if r.errCode != "" {
+		a.errors[r.class]++
+		a.byCode[r.errCode]++
+		return
+	}
+	a.latencies[r.class] = append(a.latencies[r.class], r.latency)
+	if r.unexpect {
+		a.unexpect[r.class]++
+	}
+	if r.recorded {
+		a.recorded++
+	}
+}
+
+// report is the result of a run. Latencies are of successful calls.
+type report struct {
+	DurationSec          float64                `json:"duration_sec"`
+	Requests             int                    `json:"requests"`
+	RequestsPerSec       float64                `json:"requests_per_sec"`
+	Errors               int                    `json:"errors"`
+	ErrorsByCode         map[string]int         `json:"errors_by_code,omitempty"`
+	UnexpectedDecisions  int                    `json:"unexpected_decisions"`
+	EvidenceWrites       int                    `json:"evidence_writes"`
+	EvidenceWritesPerSec float64                `json:"evidence_writes_per_sec"`
+	Latency              latency                `json:"latency"`
+	Classes              map[string]classReport `json:"classes"`
+}
+
+type classReport struct {
+	Requests            int     `json:"requests"`
+	Errors              int     `json:"errors"`
+	UnexpectedDecisions int     `json:"unexpected_decisions"`
+	Latency             latency `json:"latency"`
+}
+
+type latency struct {
+	P50MS  float64 `json:"p50_ms"`
+	P90MS  float64 `json:"p90_ms"`
+	P95MS  float64 `json:"p95_ms"`
+	P99MS  float64 `json:"p99_ms"`
+	MaxMS  float64 `json:"max_ms"`
+	MeanMS float64 `json:"mean_ms"`
+}
+
+func (a *aggregate) report(elapsed time.Duration) *report {
+	secs := elapsed.Seconds()
+	rep := &report{
+		DurationSec:    secs,
+		EvidenceWrites: a.recorded,
+		Classes:        make(map[string]classReport),
+	}
+	var all []time.Duration
+	for _, c := range classes {
+		n := a.requests[c.name]
+		if n == 0 {
+			continue
+		}
+		rep.Requests += n
+		rep.Errors += a.errors[c.name]
+		rep.UnexpectedDecisions += a.unexpect[c.name]
+		all = append(all, a.latencies[c.name]...)
+		rep.Classes[c.name] = classReport{
+			Requests:            n,
+			Errors:              a.errors[c.name],
+			UnexpectedDecisions: a.unexpect[c.name],
+			Latency:             summarize(a.latencies[c.name]),
+		}
+	}
+	rep.Latency = summarize(all)
+	if len(a.byCode) > 0 {
+		rep.ErrorsByCode = a.byCode
+	}
+	if secs > 0 {
+		rep.RequestsPerSec = float64(rep.Requests) / secs
+		rep.EvidenceWritesPerSec = float64(rep.EvidenceWrites) / secs
+	}
+	return rep
+}
+
+// summarize computes nearest-rank percentiles of ds, sorting it in place.
+func summarize(ds []time.Duration) latency {
+	if len(ds) == 0 {
+		return latency{}
+	}
+	slices.Sort(ds)
+	var sum time.Duration
+	for _, d := range ds {
+		sum += d
+	}
+	return latency{
+		P50MS:  ms(percentile(ds, 50)),
+		P90MS:  ms(percentile(ds, 90)),
+		P95MS:  ms(percentile(ds, 95)),
+		P99MS:  ms(percentile(ds, 99)),
+		MaxMS:  ms(ds[len(ds)-1]),
+		MeanMS: ms(sum / time.Duration(len(ds))),
+	}
+}
+
+// percentile returns the nearest-rank p-th percentile of sorted ds.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	return sorted[min(max(i, 0), len(sorted)-1)]
+}
+
+func ms(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+
+// writeText writes the report as a table.
+func (r *report) writeText(w io.Writer) {
+	fmt.Fprintf(w, "%d calls in %.1fs (%.1f/s), %d failed, %d unexpected decisions\n",
+		r.Requests, r.DurationSec, r.RequestsPerSec, r.Errors, r.UnexpectedDecisions)
+	fmt.Fprintf(w, "evidence writes: %d (%.1f/s)\n\n", r.EvidenceWrites, r.EvidenceWritesPerSec)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "class\tcalls\tfailed\tunexpected\tp50 ms\tp90 ms\tp95 ms\tp99 ms\tmax ms\t")
+	row := func(name string, calls, failed, unexpected int, l latency) {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%.1f\t%.1f\t%.1f\t%.1f\t%.1f\t\n",
+			name, calls, failed, unexpected, l.P50MS, l.P90MS, l.P95MS, l.P99MS, l.MaxMS)
+	}
+	for _, c := range classes {
+		if cr, ok := r.Classes[c.name]; ok {
+			row(c.name, cr.Requests, cr.Errors, cr.UnexpectedDecisions, cr.Latency)
+		}
+	}
+	row("all", r.Requests, r.Errors, r.UnexpectedDecisions, r.Latency)
+	tw.Flush()
+	if len(r.ErrorsByCode) > 0 {
+		codes := make([]string, 0, len(r.ErrorsByCode))
+		for code := range r.ErrorsByCode {
+			codes = append(codes, code)
+		}
+		slices.Sort(codes)
+		fmt.Fprintln(w, "\nfailures:")
+		for _, code := range codes {
+			fmt.Fprintf(w, "  %s: %d\n", code, r.ErrorsByCode[code])
+		}
+	}
+}
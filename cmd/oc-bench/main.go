@@ -0,0 +1,114 @@
+// Command oc-bench drives synthetic tool-call traffic against a gateway and
+// reports latency percentiles and evidence-write throughput, for capacity
+// planning and catching performance regressions.
+//
+//	oc-bench -keys tenant1:sk-test-key-1,tenant2:sk-test-key-2 \
+//	    -concurrency 32 -duration 1m -mix allow=80,approve=15,deny=5
+//
+// Each class of call is chosen so the default policy bundle decides it as
+// named: allow reads jira.issue.list, approve posts a high-risk Slack message
+// and deny calls an action outside the allowlist. Every call is recorded as
+// evidence, so the evidence-write rate is the rate of calls that got an
+// event ID back.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/config"
+)
+
+// errRegression is returned when a run breaches -max-p99 or
+// -max-error-rate.
+var errRegression = errors.New("regression threshold exceeded")
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+	err := run(ctx, os.Args[1:], os.Stdout)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "oc-bench:", err)
+		os.Exit(1)
+	}
+}
+
+// run parses args, runs the benchmark and writes the report to stdout.
+// Interrupting the run still reports the calls made so far.
+func run(ctx context.Context, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("oc-bench", flag.ContinueOnError)
+	server := fs.String("server", config.EnvOr("OPENCLAUSE_URL", "http://localhost:8080"), "gateway URL")
+	keys := fs.String("keys", config.EnvOr("API_KEYS", "tenant1:sk-test-key-1"), "comma-separated tenant:key pairs; calls are spread across the tenants")
+	agent := fs.String("agent", "oc-bench", "agent ID of every call")
+	mix := fs.String("mix", "allow=80,approve=15,deny=5", "relative weights of allow, approve and deny calls")
+	sizes := fs.String("payload-sizes", "256", "comma-separated params sizes in bytes, chosen uniformly")
+	concurrency := fs.Int("concurrency", 8, "calls in flight")
+	duration := fs.Duration("duration", 30*time.Second, "how long to run")
+	requests := fs.Int("requests", 0, "stop after this many calls (0: run for -duration)")
+	rate := fs.Float64("rate", 0, "calls per second across all workers (0: as fast as -concurrency allows)")
+	timeout := fs.Duration("timeout", 10*time.Second, "per-call timeout")
+	seed := fs.Uint64("seed", 1, "seed for the call mix; the same seed sends the same sequence")
+	jsonOut := fs.Bool("json", false, "write the report as JSON")
+	maxP99 := fs.Duration("max-p99", 0, "fail if the overall p99 latency exceeds this (0: no limit)")
+	maxErrRate := fs.Float64("max-error-rate", -1, "fail if the fraction of failed calls exceeds this (negative: no limit)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg := benchConfig{
+		server:      *server,
+		agent:       *agent,
+		concurrency: *concurrency,
+		duration:    *duration,
+		requests:    *requests,
+		rate:        *rate,
+		timeout:     *timeout,
+		seed:        *seed,
+	}
+	var err error
+	if cfg.tenants, err = parseKeys(*keys); err != nil {
+		return err
+	}
+	if cfg.mix, err = parseMix(*mix); err != nil {
+		return err
+	}
+	if cfg.sizes, err = parseSizes(*sizes); err != nil {
+		return err
+	}
+	if cfg.concurrency < 1 {
+		return errors.New("-concurrency must be at least 1")
+	}
+	if cfg.requests <= 0 && cfg.duration <= 0 {
+		return errors.New("one of -duration and -requests must be positive")
+	}
+
+	rep := bench(ctx, cfg)
+	if *jsonOut {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(rep); err != nil {
+			return err
+		}
+	} else {
+		rep.writeText(stdout)
+	}
+
+	if *maxP99 > 0 && rep.Latency.P99MS > float64(*maxP99)/float64(time.Millisecond) {
+		return fmt.Errorf("%w: p99 %.1fms > %s", errRegression, rep.Latency.P99MS, *maxP99)
+	}
+	if *maxErrRate >= 0 && rep.Requests > 0 && float64(rep.Errors)/float64(rep.Requests) > *maxErrRate {
+		return fmt.Errorf("%w: %d of %d calls failed", errRegression, rep.Errors, rep.Requests)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+// fakeGateway decides calls the way the default bundle does and rejects
+// keys other than key-1 and key-2.
+func fakeGateway(t *testing.T, calls *atomic.Int64) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("X-API-Key")
+		if key != "key-1" && key != "key-2" {
+			types.ErrUnauthorized("bad key").WriteJSON(w)
+			return
+		}
+		var req types.ToolCallRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.AgentID != "oc-bench" {
+			t.Errorf("request = %+v, %v", req, err)
+		}
+		calls.Add(1)
+		decision := types.DecisionDeny
+		switch req.Tool + "." + req.Action {
+		case "jira.issue.list":
+			decision = types.DecisionAllow
+		case "slack.msg.post":
+			decision = types.DecisionApprove
+		}
+		_ = json.NewEncoder(w).Encode(types.ToolCallResponse{EventID: "evt", Decision: decision})
+	}))
+}
+
+func TestRun_JSONReport(t *testing.T) {
+	var calls atomic.Int64
+	srv := fakeGateway(t, &calls)
+	defer srv.Close()
+
+	var out bytes.Buffer
+	args := []string{"-server", srv.URL, "-keys", "t1:key-1,t2:key-2", "-requests", "200", "-concurrency", "4",
+		"-mix", "allow=2,approve=1,deny=1", "-payload-sizes", "64,2048", "-json"}
+	if err := run(context.Background(), args, &out); err != nil {
+		t.Fatal(err)
+	}
+	var rep report
+	if err := json.Unmarshal(out.Bytes(), &rep); err != nil {
+		t.Fatalf("%v: %s", err, out.String())
+	}
+	if rep.Requests != 200 || calls.Load() != 200 || rep.Errors != 0 || rep.EvidenceWrites != 200 || rep.UnexpectedDecisions != 0 {
+		t.Fatalf("report = %+v", rep)
+	}
+	for _, name := range []string{"allow", "approve", "deny"} {
+		if rep.Classes[name].Requests == 0 {
+			t.Errorf("no %s calls: %+v", name, rep.Classes)
+		}
+	}
+	if rep.Classes["allow"].Requests < rep.Classes["deny"].Requests {
+		t.Errorf("mix not weighted: %+v", rep.Classes)
+	}
+	if rep.Latency.P50MS <= 0 || rep.Latency.P99MS < rep.Latency.P50MS {
+		t.Errorf("latency = %+v", rep.Latency)
+	}
+}
+
+func TestRun_Failures(t *testing.T) {
+	var calls atomic.Int64
+	srv := fakeGateway(t, &calls)
+	defer srv.Close()
+
+	var out bytes.Buffer
+	args := []string{"-server", srv.URL, "-keys", "t1:wrong", "-requests", "10", "-max-error-rate", "0.5"}
+	err := run(context.Background(), args, &out)
+	if !errors.Is(err, errRegression) {
+		t.Fatalf("err = %v, want regression", err)
+	}
+	if !strings.Contains(out.String(), "UNAUTHORIZED: 10") {
+		t.Errorf("report does not list failures:\n%s", out.String())
+	}
+
+	args = []string{"-server", srv.URL, "-keys", "t1:key-1", "-requests", "5", "-max-p99", "1ns"}
+	if err := run(context.Background(), args, &out); !errors.Is(err, errRegression) {
+		t.Fatalf("p99 threshold: err = %v", err)
+	}
+}
+
+func TestRun_InvalidFlags(t *testing.T) {
+	for _, args := range [][]string{
+		{"-keys", "no-colon"},
+		{"-mix", "allow=1,bogus=2"},
+		{"-mix", "allow=0"},
+		{"-payload-sizes", "big"},
+		{"-concurrency", "0"},
+		{"-duration", "0s"},
+	} {
+		if err := run(context.Background(), args, &bytes.Buffer{}); err == nil {
+			t.Errorf("%v accepted", args)
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	ds := make([]time.Duration, 100)
+	for i := range ds {
+		ds[i] = time.Duration(100-i) * time.Millisecond
+	}
+	l := summarize(ds)
+	if l.P50MS != 50 || l.P99MS != 99 || l.MaxMS != 100 || l.MeanMS != 50.5 {
+		t.Fatalf("summarize = %+v", l)
+	}
+}
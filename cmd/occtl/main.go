@@ -0,0 +1,327 @@
+// Command occtl is the OpenClause operator CLI. It talks to the gateway's
+// HTTP API with an admin or auditor token, and checks audit logs offline.
+//
+//	occtl report -tenant acme -from 2026-07-01 -to 2026-10-01 -format pdf
+//	occtl audit-verify oc-gateway-audit.jsonl
+//	occtl policy-validate policy/bundles/v0
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/config"
+	"github.com/bturcanu/OpenClause/pkg/evidence"
+	"github.com/bturcanu/OpenClause/pkg/policy"
+)
+
+const usage = `usage: occtl <command> [flags]
+
+Commands:
+  report           Download a tenant's audit report (HTML, PDF or JSON)
+  audit-verify     Check the hash chain of audit log files (or stdin)
+  policy-validate  Validate a policy bundle before it is activated
+
+Flags shared by every command:
+  -server  Gateway URL (default $OPENCLAUSE_URL or http://localhost:8080)
+  -token   Admin or auditor token (default $OPENCLAUSE_TOKEN)
+
+Run "occtl <command> -h" for a command's flags.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	var err error
+	switch os.Args[1] {
+	case "report":
+		err = runReport(context.Background(), os.Args[2:], os.Stdout)
+	case "audit-verify":
+		err = runAuditVerify(os.Args[2:], os.Stdin, os.Stdout)
+	case "policy-validate":
+		err = runPolicyValidate(context.Background(), os.Args[2:], os.Stdout)
+	case "-h", "-help", "--help", "help":
+		fmt.Fprint(os.Stdout, usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "occtl: unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "occtl:", err)
+		os.Exit(1)
+	}
+}
+
+// client is the connection shared by every command.
+type client struct {
+	server string
+	token  string
+	http   *http.Client
+}
+
+// newFlagSet returns a flag set for command with the shared -server and
+// -token flags bound to c.
+func newFlagSet(command string, c *client) *flag.FlagSet {
+	fs := flag.NewFlagSet("occtl "+command, flag.ContinueOnError)
+	fs.StringVar(&c.server, "server", config.EnvOr("OPENCLAUSE_URL", "http://localhost:8080"), "gateway URL")
+	fs.StringVar(&c.token, "token", os.Getenv("OPENCLAUSE_TOKEN"), "admin or auditor token")
+	c.http = &http.Client{Timeout: 5 * time.Minute}
+	return fs
+}
+
+// get fetches path with query and returns the response body, or an error
+// carrying the API's message for non-2xx responses.
+func (c *client) get(ctx context.Context, path string, query url.Values) (io.ReadCloser, error) {
+	u := strings.TrimRight(c.server, "/") + path + "?" + query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.do(req, path)
+}
+
+// post sends body to path, as get does.
+func (c *client) post(ctx context.Context, path, contentType string, body io.Reader) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(c.server, "/")+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	return c.do(req, path)
+}
+
+func (c *client) do(req *http.Request, path string) (io.ReadCloser, error) {
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("%s %s: %s: %s", req.Method, path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return resp.Body, nil
+}
+
+// runReport implements "occtl report". The report is written to -o, or to
+// stdout for "-o -".
+func runReport(ctx context.Context, args []string, stdout io.Writer) error {
+	var c client
+	fs := newFlagSet("report", &c)
+	tenant := fs.String("tenant", "", "tenant ID (defaults to the auditor token's tenant)")
+	from := fs.String("from", "", "start of the period, YYYY-MM-DD or RFC 3339 (required)")
+	to := fs.String("to", "", "end of the period, exclusive (required)")
+	format := fs.String("format", "pdf", "html, pdf or json")
+	sample := fs.Int("sample", 25, "number of events to sample")
+	seed := fs.String("seed", "", "sample seed; the same seed reproduces the same sample")
+	out := fs.String("o", "", `output file (default openclause-report-<tenant>-<from>-<to>.<format>; "-" for stdout)`)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *from == "" || *to == "" {
+		return errors.New("report: -from and -to are required")
+	}
+
+	q := url.Values{"from": {*from}, "to": {*to}, "format": {*format}, "sample": {strconv.Itoa(*sample)}}
+	if *tenant != "" {
+		q.Set("tenant_id", *tenant)
+	}
+	if *seed != "" {
+		q.Set("seed", *seed)
+	}
+	body, err := c.get(ctx, "/dashboard/report", q)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	if *out == "-" {
+		_, err = io.Copy(stdout, body)
+		return err
+	}
+	name := *out
+	if name == "" {
+		name = fmt.Sprintf("openclause-report-%s-%s-%s.%s", *tenant, *from, *to, *format)
+		if *tenant == "" {
+			name = fmt.Sprintf("openclause-report-%s-%s.%s", *from, *to, *format)
+		}
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, body); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, "wrote", name)
+	return nil
+}
+
+// runAuditVerify implements "occtl audit-verify": it checks each file's
+// chain, or stdin's when no files are given, and stops at the first break.
+func runAuditVerify(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("occtl audit-verify", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: occtl audit-verify [file ...]")
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		n, err := evidence.VerifyAuditLog(stdin)
+		if err != nil {
+			return fmt.Errorf("stdin: %w", err)
+		}
+		fmt.Fprintf(stdout, "stdin: %d records, chain intact\n", n)
+		return nil
+	}
+	for _, name := range fs.Args() {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		n, err := evidence.VerifyAuditLog(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		fmt.Fprintf(stdout, "%s: %d records, chain intact\n", name, n)
+	}
+	return nil
+}
+
+// runPolicyValidate implements "occtl policy-validate": it sends a bundle,
+// a directory of Rego modules and data.json or a .tar.gz built by "opa
+// build", to the gateway's validation API, prints the issues found and
+// fails if any is an error.
+func runPolicyValidate(ctx context.Context, args []string, stdout io.Writer) error {
+	var c client
+	fs := newFlagSet("policy-validate", &c)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: occtl policy-validate [flags] <bundle dir or .tar.gz>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("policy-validate: one bundle is required")
+	}
+	bundle, err := readPolicyBundle(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	body, err := c.post(ctx, "/v1/admin/policies/validate", "application/gzip", bytes.NewReader(bundle))
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	var report policy.LintReport
+	if err := json.NewDecoder(body).Decode(&report); err != nil {
+		return fmt.Errorf("policy-validate: decode report: %w", err)
+	}
+
+	errs := 0
+	for _, is := range report.Issues {
+		where := is.File
+		if is.Line > 0 {
+			where += ":" + strconv.Itoa(is.Line)
+		}
+		if where == "" {
+			where = fs.Arg(0)
+		}
+		msg := is.Message
+		if is.Input != "" {
+			msg += " (input " + is.Input + ")"
+		}
+		fmt.Fprintf(stdout, "%s: %s: %s\n", where, is.Severity, msg)
+		if is.Severity == policy.LintError {
+			errs++
+		}
+	}
+	if !report.Compiled {
+		fmt.Fprintln(stdout, "note: the gateway's policy engine does not support dry runs; only static checks ran")
+	}
+	if !report.Valid {
+		return fmt.Errorf("policy-validate: %d errors", errs)
+	}
+	fmt.Fprintf(stdout, "%s: valid\n", fs.Arg(0))
+	return nil
+}
+
+// readPolicyBundle returns the bundle at name: the file itself, or for a
+// directory a gzipped tar of its .rego files, other than tests, and its
+// data.json.
+func readPolicyBundle(name string) ([]byte, error) {
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return os.ReadFile(name)
+	}
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	err = filepath.WalkDir(name, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(name, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel != "data.json" && (!strings.HasSuffix(rel, ".rego") || strings.HasSuffix(rel, "_test.rego")) {
+			return nil
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := tw.WriteHeader(&tar.Header{Name: rel, Mode: 0o644, Size: int64(len(b)), Typeflag: tar.TypeReg}); err != nil {
+			return err
+		}
+		_, err = tw.Write(b)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
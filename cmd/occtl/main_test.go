@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bturcanu/OpenClause/pkg/evidence"
+	"github.com/bturcanu/OpenClause/pkg/policy"
+)
+
+func TestRunReport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/dashboard/report" || r.Header.Get("Authorization") != "Bearer aud-tok" {
+			http.Error(w, `{"code":"UNAUTHORIZED"}`, http.StatusUnauthorized)
+			return
+		}
+		q := r.URL.Query()
+		if q.Get("tenant_id") != "acme" || q.Get("from") != "2026-07-01" || q.Get("format") != "json" || q.Get("sample") != "5" {
+			http.Error(w, "bad query "+r.URL.RawQuery, http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"tenant_id":"acme"}`))
+	}))
+	defer srv.Close()
+
+	var out bytes.Buffer
+	args := []string{"-server", srv.URL, "-token", "aud-tok", "-tenant", "acme", "-from", "2026-07-01", "-to", "2026-10-01", "-format", "json", "-sample", "5", "-o", "-"}
+	if err := runReport(context.Background(), args, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != `{"tenant_id":"acme"}` {
+		t.Fatalf("output = %q", out.String())
+	}
+
+	args[3] = "wrong"
+	if err := runReport(context.Background(), args, &out); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("bad token error = %v", err)
+	}
+	if err := runReport(context.Background(), []string{"-server", srv.URL}, &out); err == nil {
+		t.Fatal("missing -from/-to accepted")
+	}
+}
+
+func TestRunAuditVerify(t *testing.T) {
+	var log bytes.Buffer
+	a := evidence.NewAuditLogger("oc-gateway", &log)
+	for _, kind := range []string{"tool_event", "approval_approved"} {
+		if err := a.Record(context.Background(), kind, "t1", "e1", "", map[string]string{"k": "v"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := runAuditVerify(nil, bytes.NewReader(log.Bytes()), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "stdin: 2 records, chain intact\n" {
+		t.Fatalf("output = %q", out.String())
+	}
+	tampered := strings.Replace(log.String(), `"k":"v"`, `"k":"w"`, 1)
+	if err := runAuditVerify(nil, strings.NewReader(tampered), &out); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Fatalf("tampered log error = %v", err)
+	}
+}
+
+func TestRunPolicyValidate(t *testing.T) {
+	var got *policy.Bundle
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/admin/policies/validate" || r.Header.Get("Authorization") != "Bearer adm" {
+			http.Error(w, `{"code":"UNAUTHORIZED"}`, http.StatusUnauthorized)
+			return
+		}
+		b, err := policy.ReadBundle(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		got = b
+		_ = json.NewEncoder(w).Encode(policy.NewLintReport(policy.Lint(b), true))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.rego":      "package oc.main\n\ndefault decision := \"deny\"\n\ndefault reason := \"no\"\n",
+		"main_test.rego": "package oc.main_test\n",
+		"data.json":      `{"allowlist": {}}`,
+		"README.md":      "docs",
+	}
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var out bytes.Buffer
+	args := []string{"-server", srv.URL, "-token", "adm", dir}
+	if err := runPolicyValidate(context.Background(), args, &out); err != nil {
+		t.Fatalf("%v\n%s", err, out.String())
+	}
+	if len(got.Modules) != 1 || string(got.Data) != files["data.json"] || !strings.HasSuffix(out.String(), ": valid\n") {
+		t.Fatalf("bundle = %+v, output = %q", got, out.String())
+	}
+
+	escalate := strings.Replace(files["main.rego"], `"deny"`, `"escalate"`, 1)
+	if err := os.WriteFile(filepath.Join(dir, "main.rego"), []byte(escalate), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if err := runPolicyValidate(context.Background(), args, &out); err == nil || !strings.Contains(out.String(), `main.rego:3: error: decision "escalate"`) {
+		t.Fatalf("escalate bundle: %v\n%s", err, out.String())
+	}
+}
@@ -0,0 +1,194 @@
+// OpenClause runs the gateway, the approvals service with its notification
+// dispatcher, and mock Slack and Jira connectors in one process, for
+// proof-of-concepts and small installs. The services share one Postgres pool
+// and reach the connectors in-process; all of it is configured from a single
+// file.
+//
+// In lite mode (mode: lite) nothing outside the process is needed: evidence
+// and approvals live in SQLite files, policy is evaluated in process, and the
+// evidence archiver runs here, writing bundles to ARCHIVER_DIR.
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/approvals"
+	"github.com/bturcanu/OpenClause/pkg/approvals/service"
+	"github.com/bturcanu/OpenClause/pkg/archiver"
+	"github.com/bturcanu/OpenClause/pkg/config"
+	"github.com/bturcanu/OpenClause/pkg/connectors"
+	"github.com/bturcanu/OpenClause/pkg/connectors/jira"
+	"github.com/bturcanu/OpenClause/pkg/connectors/slack"
+	"github.com/bturcanu/OpenClause/pkg/evidence"
+	"github.com/bturcanu/OpenClause/pkg/gateway"
+	ocOtel "github.com/bturcanu/OpenClause/pkg/otel"
+	"github.com/bturcanu/OpenClause/pkg/pgpool"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func main() {
+	configPath := flag.String("config", "", "config file (YAML or TOML); defaults to $"+config.FileEnv)
+	flag.Parse()
+
+	log := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	slog.SetDefault(log)
+
+	// ── Configuration ────────────────────────────────────────────────────
+	if *configPath != "" {
+		if err := os.Setenv(config.FileEnv, *configPath); err != nil {
+			log.Error("set config file", "error", err)
+			os.Exit(1)
+		}
+	}
+	effectiveCfg, err := config.Load("INTERNAL_AUTH_TOKEN")
+	if err != nil {
+		log.Error("invalid configuration", "error", err)
+		os.Exit(1)
+	}
+	log.Info("configuration loaded", "file", os.Getenv(config.FileEnv), "settings", effectiveCfg.Settings())
+
+	internalToken := os.Getenv("INTERNAL_AUTH_TOKEN")
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	// ── OpenTelemetry ────────────────────────────────────────────────────
+	otelShutdown, err := ocOtel.Setup(ctx, ocOtel.ConfigFromEnv("openclause"))
+	if err != nil {
+		log.Error("otel setup failed", "error", err)
+	} else {
+		defer otelShutdown(context.Background()) //nolint:errcheck // best-effort shutdown
+	}
+
+	// ── Postgres (shared) ────────────────────────────────────────────────
+	var pool *pgxpool.Pool
+	lite := effectiveCfg.Mode == config.LiteMode
+	if !lite {
+		pool, err = pgpool.New(ctx, pgpool.DSNFromEnv(nil), pgpool.ConfigFromEnv(nil))
+		if err != nil {
+			log.Error("postgres connect failed", "error", err)
+			os.Exit(1)
+		}
+		defer pool.Close()
+		if err := pgpool.RegisterMetrics(pool, "postgres"); err != nil {
+			log.Error("register pool metrics failed", "error", err)
+		}
+	}
+
+	// ── In-process connectors ────────────────────────────────────────────
+	// Mock Slack and Jira connectors serve inproc:// URLs unless the config
+	// points a tool at a deployed connector.
+	local := connectors.NewLocal(nil)
+	if os.Getenv("CONNECTOR_SLACK_URL") == "" {
+		c := slack.New(slack.Config{Logger: log, Mock: true, InternalToken: internalToken})
+		if err := os.Setenv("CONNECTOR_SLACK_URL", local.Handle("connector-slack", c.Handler())); err != nil {
+			log.Error("set CONNECTOR_SLACK_URL", "error", err)
+			os.Exit(1)
+		}
+	}
+	if os.Getenv("CONNECTOR_JIRA_URL") == "" {
+		c := jira.New(jira.Config{Logger: log, Mock: true, InternalToken: internalToken})
+		if err := os.Setenv("CONNECTOR_JIRA_URL", local.Handle("connector-jira", c.Handler())); err != nil {
+			log.Error("set CONNECTOR_JIRA_URL", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	// ── Evidence archiver (lite mode) ────────────────────────────────────
+	if lite {
+		store, err := evidence.OpenSQLite(ctx, config.EnvOr("EVIDENCE_SQLITE_PATH", "openclause-evidence.db"))
+		if err != nil {
+			log.Error("open evidence store for archiving failed", "error", err)
+			os.Exit(1)
+		}
+		defer store.Close()
+		outbox, err := approvals.OpenSQLite(ctx, config.EnvOr("APPROVALS_SQLITE_PATH", "openclause-approvals.db"))
+		if err != nil {
+			log.Error("open approvals store for archiving failed", "error", err)
+			os.Exit(1)
+		}
+		defer outbox.Close()
+		svc := archiver.New(store, archiver.NewFSUploader(config.EnvOr("ARCHIVER_DIR", "openclause-archive")))
+		svc.SetOutbox(outbox)
+		interval := config.EnvOrDuration("ARCHIVER_INTERVAL_SEC", time.Second, 5*time.Minute)
+		outboxRetention := time.Duration(config.EnvOrInt("OUTBOX_RETENTION_DAYS", 30)) * 24 * time.Hour
+		go runArchiver(ctx, log, svc, store, interval, outboxRetention)
+	}
+
+	// ── Audit log ────────────────────────────────────────────────────────
+	// Both services share one audit chain.
+	audit, err := evidence.OpenAuditLog(evidence.AuditConfigFromEnv(nil, "openclause"))
+	if err != nil {
+		log.Error("audit log setup failed", "error", err)
+		os.Exit(1)
+	}
+	defer audit.Close()
+
+	// ── Services ─────────────────────────────────────────────────────────
+	// Either service failing stops the other.
+	errs := make(chan error, 2)
+	go func() {
+		errs <- gateway.Run(ctx, log, gateway.Options{Pool: pool, ConnectorTransport: local, Audit: audit})
+		cancel()
+	}()
+	go func() {
+		errs <- service.Run(ctx, log, service.Options{Pool: pool, NotifyTransport: local, Audit: audit})
+		cancel()
+	}()
+	failed := false
+	for range 2 {
+		if err := <-errs; err != nil {
+			log.Error("service failed", "error", err)
+			failed = true
+		}
+	}
+	if failed {
+		if pool != nil {
+			pool.Close()
+		}
+		os.Exit(1)
+	}
+}
+
+// runArchiver archives every tenant's new evidence and the notification
+// outbox's old rows each interval until ctx is done, as cmd/archiver does
+// against Postgres and S3.
+func runArchiver(ctx context.Context, log *slog.Logger, svc *archiver.Service, store *evidence.SQLiteStore, interval, outboxRetention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		tenantIDs, err := store.ListTenantIDs(ctx)
+		if err != nil {
+			log.Error("list tenants failed", "error", err)
+			continue
+		}
+		for _, tenantID := range tenantIDs {
+			key, err := svc.ArchiveTenant(ctx, tenantID)
+			if err != nil {
+				log.Error("archive tenant failed", "tenant_id", tenantID, "error", err)
+				continue
+			}
+			if key != "" {
+				log.Info("archived evidence bundle", "tenant_id", tenantID, "key", key)
+			}
+		}
+		keys, n, err := svc.ArchiveOutbox(ctx, outboxRetention)
+		if err != nil {
+			log.Error("archive notification outbox failed", "error", err)
+		}
+		if n > 0 {
+			log.Info("archived notification outbox rows", "rows", n, "bundles", len(keys))
+		}
+	}
+}
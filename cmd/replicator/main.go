@@ -0,0 +1,170 @@
+// Replicator keeps a standby region's evidence store a verified copy of the
+// primary region's. It pulls each tenant's tool events from the primary
+// gateway, re-verifies every chain link before applying it, and serves the
+// failover status of the standby.
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/auth"
+	"github.com/bturcanu/OpenClause/pkg/config"
+	"github.com/bturcanu/OpenClause/pkg/diagnostics"
+	"github.com/bturcanu/OpenClause/pkg/evidence"
+	"github.com/bturcanu/OpenClause/pkg/mysqldb"
+	ocOtel "github.com/bturcanu/OpenClause/pkg/otel"
+	"github.com/bturcanu/OpenClause/pkg/pgpool"
+	"github.com/bturcanu/OpenClause/pkg/replication"
+	"github.com/bturcanu/OpenClause/pkg/secrets"
+	"github.com/go-chi/chi/v5"
+)
+
+func main() {
+	log := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	slog.SetDefault(log)
+
+	// ── Configuration ────────────────────────────────────────────────────
+	effectiveCfg, err := config.Load("REPLICATION_PRIMARY_URL", "REPLICATION_TOKEN")
+	if err != nil {
+		log.Error("invalid configuration", "error", err)
+		os.Exit(1)
+	}
+	log.Info("configuration loaded", "file", os.Getenv(config.FileEnv), "settings", effectiveCfg.Settings())
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	// ── OpenTelemetry ────────────────────────────────────────────────────
+	otelShutdown, err := ocOtel.Setup(ctx, ocOtel.ConfigFromEnv("oc-replicator"))
+	if err != nil {
+		log.Error("otel setup failed", "error", err)
+	} else {
+		defer otelShutdown(context.Background()) //nolint:errcheck // best-effort shutdown
+	}
+
+	// ── Standby evidence store ───────────────────────────────────────────
+	var store evidence.ReplicaStore
+	var ping func(context.Context) error
+	switch backend := config.EnvOr("EVIDENCE_BACKEND", "postgres"); backend {
+	case "postgres":
+		pool, err := pgpool.New(ctx, pgpool.DSNFromEnv(nil), pgpool.ConfigFromEnv(nil))
+		if err != nil {
+			log.Error("postgres connect failed", "error", err)
+			os.Exit(1)
+		}
+		defer pool.Close()
+		store, ping = evidence.NewStore(pool), pool.Ping
+	case "mysql":
+		db, err := mysqldb.Open(ctx, os.Getenv("MYSQL_DSN"))
+		if err != nil {
+			log.Error("mysql connect failed", "error", err)
+			os.Exit(1)
+		}
+		defer db.Close()
+		store, ping = evidence.NewMySQLStore(db), db.PingContext
+	case "sqlite":
+		sqliteStore, err := evidence.OpenSQLite(ctx, config.EnvOr("EVIDENCE_SQLITE_PATH", "openclause-evidence.db"))
+		if err != nil {
+			log.Error("sqlite evidence store open failed", "error", err)
+			os.Exit(1)
+		}
+		defer sqliteStore.Close()
+		store, ping = sqliteStore, func(context.Context) error { return nil }
+	default:
+		log.Error("unknown EVIDENCE_BACKEND", "backend", backend)
+		os.Exit(1)
+	}
+
+	secretResolver := secrets.NewResolverFromEnv(nil, log)
+	token, err := secretResolver.Resolve(ctx, os.Getenv("REPLICATION_TOKEN"))
+	if err != nil {
+		log.Error("resolve REPLICATION_TOKEN failed", "error", err)
+		os.Exit(1)
+	}
+	adminToken, err := secretResolver.Resolve(ctx, os.Getenv("ADMIN_API_TOKEN"))
+	if err != nil {
+		log.Error("resolve ADMIN_API_TOKEN failed", "error", err)
+		os.Exit(1)
+	}
+
+	repl := replication.New(replication.Config{
+		Store:        store,
+		PrimaryURL:   os.Getenv("REPLICATION_PRIMARY_URL"),
+		Token:        token,
+		BatchSize:    config.EnvOrInt("REPLICATION_BATCH_SIZE", replication.DefaultBatchSize),
+		MaxLagEvents: int64(config.EnvOrInt("REPLICATION_MAX_LAG_EVENTS", 0)),
+		MaxStale:     config.EnvOrDuration("REPLICATION_MAX_STALE_SEC", time.Second, replication.DefaultMaxStale),
+		Logger:       log,
+	})
+	if err := replication.RegisterGauges(repl); err != nil {
+		log.Error("register replication metrics failed", "error", err)
+	}
+	go repl.Run(ctx, config.EnvOrDuration("REPLICATION_INTERVAL_SEC", time.Second, 5*time.Second))
+
+	// ── Router ───────────────────────────────────────────────────────────
+	r := chi.NewRouter()
+	r.Get("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("OK"))
+	})
+	r.Get("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if err := ping(r.Context()); err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte("NOT READY"))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("OK"))
+	})
+	if adminToken == "" {
+		log.Warn("ADMIN_API_TOKEN is not set; the replication status endpoint rejects every request")
+	}
+	r.Group(func(r chi.Router) {
+		r.Use(auth.AdminAuth(adminToken))
+		r.Get("/v1/admin/replication/status", repl.HandleStatus)
+	})
+
+	addr := config.EnvOr("REPLICATOR_ADDR", ":8087")
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           r,
+		ReadTimeout:       15 * time.Second,
+		ReadHeaderTimeout: 5 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       60 * time.Second,
+	}
+
+	metricsAddr := config.EnvOr("REPLICATOR_METRICS_ADDR", "127.0.0.1:9097")
+	metricsSrv := diagnostics.NewServer(diagnostics.Config{Addr: metricsAddr, InternalToken: os.Getenv("INTERNAL_AUTH_TOKEN")})
+	go func() {
+		log.Info("metrics server starting", "addr", metricsAddr)
+		if err := metricsSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Error("metrics server error", "error", err)
+		}
+	}()
+
+	go func() {
+		log.Info("replicator starting", "addr", addr, "primary", os.Getenv("REPLICATION_PRIMARY_URL"))
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Error("server error", "error", err)
+			cancel()
+		}
+	}()
+
+	<-ctx.Done()
+	log.Info("shutting down replicator")
+	shutCtx, shutCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer shutCancel()
+	if err := srv.Shutdown(shutCtx); err != nil {
+		log.Error("shutdown error", "error", err)
+	}
+	if err := metricsSrv.Shutdown(shutCtx); err != nil {
+		log.Error("metrics server shutdown error", "error", err)
+	}
+}
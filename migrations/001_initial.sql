@@ -11,6 +11,62 @@ CREATE TABLE IF NOT EXISTS tenants (
     created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
 );
 
+-- Lifecycle columns used by the tenant admin API. Deleted tenants are kept
+-- (status = 'deleted') because evidence rows reference them.
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'active'
+    CHECK (status IN ('active', 'suspended', 'deleted'));
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS suspended_at TIMESTAMPTZ;
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+-- ── Tenant API keys (issued by the admin API; API_KEYS env keys are separate)
+
+CREATE TABLE IF NOT EXISTS tenant_api_keys (
+    id          TEXT PRIMARY KEY,
+    tenant_id   TEXT NOT NULL REFERENCES tenants(id),
+    key_hash    TEXT NOT NULL UNIQUE,
+    key_prefix  TEXT NOT NULL,
+    label       TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    revoked_at  TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_tenant_api_keys_tenant ON tenant_api_keys(tenant_id);
+
+-- ── Tenant governance settings (admin API) with an append-only change audit
+
+CREATE TABLE IF NOT EXISTS tenant_settings (
+    tenant_id   TEXT PRIMARY KEY REFERENCES tenants(id),
+    settings    JSONB NOT NULL DEFAULT '{}',
+    version     INT NOT NULL DEFAULT 1,
+    updated_by  TEXT NOT NULL DEFAULT '',
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS tenant_settings_audit (
+    id          BIGSERIAL PRIMARY KEY,
+    tenant_id   TEXT NOT NULL REFERENCES tenants(id),
+    version     INT NOT NULL,
+    old_settings JSONB,
+    new_settings JSONB NOT NULL,
+    changed_by  TEXT NOT NULL DEFAULT '',
+    changed_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_tenant_settings_audit_tenant ON tenant_settings_audit(tenant_id, version DESC);
+
+-- ── Tenant blocklist: resources and principals denied before policy
+
+CREATE TABLE IF NOT EXISTS tenant_blocklist (
+    id          UUID PRIMARY KEY,
+    tenant_id   TEXT NOT NULL REFERENCES tenants(id),
+    kind        TEXT NOT NULL,
+    value       TEXT NOT NULL,
+    reason      TEXT NOT NULL DEFAULT '',
+    created_by  TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (tenant_id, kind, value)
+);
+
 -- ── Agents ──────────────────────────────────────────────────────────────────
 
 CREATE TABLE IF NOT EXISTS agents (
@@ -68,6 +124,23 @@ CREATE INDEX IF NOT EXISTS idx_tool_events_tool_action
 CREATE INDEX IF NOT EXISTS idx_tool_events_tenant_seq
     ON tool_events(tenant_id, event_seq ASC);
 
+-- Trace view: every event recorded under one trace ID.
+CREATE INDEX IF NOT EXISTS idx_tool_events_tenant_trace
+    ON tool_events(tenant_id, trace_id, event_seq);
+
+-- Replication: a standby resumes after the hash of its chain head.
+CREATE INDEX IF NOT EXISTS idx_tool_events_tenant_hash
+    ON tool_events(tenant_id, hash);
+
+-- The risk score after the policy's risk_overrides; NULL when policy left
+-- the agent's risk_score unchanged.
+ALTER TABLE tool_events ADD COLUMN IF NOT EXISTS adjusted_risk_score INTEGER
+    CHECK (adjusted_risk_score >= 0 AND adjusted_risk_score <= 10);
+
+-- Canonical JSON form of payload_canon and result_canon: 1 = legacy sorted
+-- encoding/json, 2 = RFC 8785 (JCS).
+ALTER TABLE tool_events ADD COLUMN IF NOT EXISTS canon_version SMALLINT NOT NULL DEFAULT 1;
+
 -- ── Tool results (execution outcomes) ───────────────────────────────────────
 
 CREATE TABLE IF NOT EXISTS tool_results (
@@ -84,6 +157,40 @@ CREATE TABLE IF NOT EXISTS tool_results (
 
 CREATE UNIQUE INDEX IF NOT EXISTS idx_tool_results_event ON tool_results(event_id);
 
+-- The compensating (undo) call the connector declared for this execution.
+ALTER TABLE tool_results ADD COLUMN IF NOT EXISTS compensation_json JSONB;
+
+-- The cost the connector reported for this execution (see budget_spend).
+ALTER TABLE tool_results ADD COLUMN IF NOT EXISTS cost DOUBLE PRECISION;
+
+-- The connector build that performed this execution: its name and version
+-- as reported, the upstream endpoint it called, and the connector URL the
+-- gateway routed to.
+ALTER TABLE tool_results ADD COLUMN IF NOT EXISTS connector_name TEXT;
+ALTER TABLE tool_results ADD COLUMN IF NOT EXISTS connector_version TEXT;
+ALTER TABLE tool_results ADD COLUMN IF NOT EXISTS connector_endpoint TEXT;
+ALTER TABLE tool_results ADD COLUMN IF NOT EXISTS connector_backend TEXT;
+
+-- Output over the gateway's limit: output_json holds a truncated stand-in,
+-- output_bytes and output_sha256 describe the full output, and output_ref
+-- is the blob key it was spilled to, if any.
+ALTER TABLE tool_results ADD COLUMN IF NOT EXISTS output_truncated BOOLEAN;
+ALTER TABLE tool_results ADD COLUMN IF NOT EXISTS output_bytes BIGINT;
+ALTER TABLE tool_results ADD COLUMN IF NOT EXISTS output_sha256 TEXT;
+ALTER TABLE tool_results ADD COLUMN IF NOT EXISTS output_ref TEXT;
+
+-- Why the output is withheld from the agent pending review (types.Quarantine);
+-- NULL when it was returned.
+ALTER TABLE tool_results ADD COLUMN IF NOT EXISTS quarantine_json JSONB;
+
+-- Change ticket filed for an approved execution under the change_ticket
+-- policy requirement (types.ChangeTicket); NULL when none was required.
+ALTER TABLE tool_results ADD COLUMN IF NOT EXISTS change_ticket_json JSONB;
+
+-- Schedule a scheduled execution ran from (types.ExecutionSchedule); NULL
+-- for executions run on request.
+ALTER TABLE tool_results ADD COLUMN IF NOT EXISTS schedule_json JSONB;
+
 -- ── Tool execution links (approval resume endpoint) ──────────────────────────
 
 CREATE TABLE IF NOT EXISTS tool_executions (
@@ -96,6 +203,33 @@ CREATE TABLE IF NOT EXISTS tool_executions (
 CREATE INDEX IF NOT EXISTS idx_tool_executions_execution
     ON tool_executions(execution_event_id);
 
+-- Approved calls scheduled to run later with execute_at. The grant is
+-- consumed when the call is scheduled.
+CREATE TABLE IF NOT EXISTS scheduled_executions (
+    id                  TEXT PRIMARY KEY,
+    parent_event_id     TEXT NOT NULL REFERENCES tool_events(event_id),
+    tenant_id           TEXT NOT NULL,
+    grant_id            TEXT NOT NULL,
+    execute_at          TIMESTAMPTZ NOT NULL,
+    status              TEXT NOT NULL DEFAULT 'scheduled'
+                        CHECK (status IN ('scheduled', 'running', 'executed', 'cancelled', 'failed')),
+    execution_event_id  TEXT,
+    error_msg           TEXT NOT NULL DEFAULT '',
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at          TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_executions_parent
+    ON scheduled_executions(parent_event_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_scheduled_executions_due
+    ON scheduled_executions(execute_at) WHERE status = 'scheduled';
+
+-- When a running schedule's claim lapses; a scheduler that stopped mid-run
+-- leaves the row to be claimed again after it.
+ALTER TABLE scheduled_executions ADD COLUMN IF NOT EXISTS lease_until TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_scheduled_executions_lease
+    ON scheduled_executions(lease_until) WHERE status = 'running';
+
 -- ── Approval requests ───────────────────────────────────────────────────────
 
 CREATE TABLE IF NOT EXISTS approval_requests (
@@ -110,7 +244,7 @@ CREATE TABLE IF NOT EXISTS approval_requests (
     reason      TEXT DEFAULT '',
     deny_reason TEXT DEFAULT '',
     denied_by   TEXT DEFAULT '',
-    status      TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'denied', 'expired')),
+    status      TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'denied', 'expired', 'cancelled')),
     created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
     updated_at  TIMESTAMPTZ,
     expires_at  TIMESTAMPTZ NOT NULL
@@ -122,6 +256,55 @@ CREATE INDEX IF NOT EXISTS idx_approval_requests_tenant_status
 CREATE INDEX IF NOT EXISTS idx_approval_requests_event
     ON approval_requests(event_id);
 
+-- Keyset pagination of pending requests (newest first).
+CREATE INDEX IF NOT EXISTS idx_approval_requests_tenant_status_created
+    ON approval_requests(tenant_id, status, created_at, id);
+
+-- Agent session of the gated call, so an approver can grant the session.
+ALTER TABLE approval_requests ADD COLUMN IF NOT EXISTS session_id TEXT NOT NULL DEFAULT '';
+
+-- Connector dry-run of the gated call (connectors.ExecPlan), shown to approvers.
+ALTER TABLE approval_requests ADD COLUMN IF NOT EXISTS plan JSONB;
+
+-- Approver group policy routed the request to; a directory-backed approver
+-- authorizer admits only its members.
+ALTER TABLE approval_requests ADD COLUMN IF NOT EXISTS approver_group TEXT NOT NULL DEFAULT '';
+
+-- 'quarantine' for a review of an executed call's withheld output, released
+-- (released_by) or denied instead of granted; empty for ordinary requests.
+ALTER TABLE approval_requests ADD COLUMN IF NOT EXISTS kind TEXT NOT NULL DEFAULT '';
+ALTER TABLE approval_requests ADD COLUMN IF NOT EXISTS released_by TEXT NOT NULL DEFAULT '';
+
+-- The agent's latest calls before the request (approvals.AgentActivity),
+-- newest first, shown to approvers.
+ALTER TABLE approval_requests ADD COLUMN IF NOT EXISTS recent_activity JSONB;
+
+-- Lifetime policy chose for grants on the request whose approver sets none;
+-- 0 leaves it to the tenant's grant_ttl_sec, then one hour.
+ALTER TABLE approval_requests ADD COLUMN IF NOT EXISTS grant_ttl_sec INTEGER NOT NULL DEFAULT 0;
+
+-- The requesting agent may withdraw a pending request ('cancelled').
+ALTER TABLE approval_requests DROP CONSTRAINT IF EXISTS approval_requests_status_check;
+ALTER TABLE approval_requests ADD CONSTRAINT approval_requests_status_check
+    CHECK (status IN ('pending', 'approved', 'denied', 'expired', 'cancelled'));
+
+-- ── Approval comments ───────────────────────────────────────────────────────
+
+-- Questions from approvers and answers from the requesting agent, threaded
+-- on the request.
+CREATE TABLE IF NOT EXISTS approval_comments (
+    id                  TEXT PRIMARY KEY,
+    approval_request_id TEXT NOT NULL REFERENCES approval_requests(id),
+    tenant_id           TEXT NOT NULL REFERENCES tenants(id),
+    author              TEXT NOT NULL,
+    role                TEXT NOT NULL CHECK (role IN ('approver', 'requester')),
+    body                TEXT NOT NULL,
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_approval_comments_request
+    ON approval_comments(approval_request_id, created_at);
+
 -- ── Approval grants ─────────────────────────────────────────────────────────
 
 CREATE TABLE IF NOT EXISTS approval_grants (
@@ -144,6 +327,35 @@ CREATE TABLE IF NOT EXISTS approval_grants (
 CREATE INDEX IF NOT EXISTS idx_approval_grants_tenant
     ON approval_grants(tenant_id, uses_left, expires_at);
 
+-- Session-scoped grants (scope_session_id <> '') cover later calls in the
+-- same agent session; max_uses = 0 means unlimited until expires_at.
+ALTER TABLE approval_grants ADD COLUMN IF NOT EXISTS scope_session_id TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_approval_grants_session
+    ON approval_grants(tenant_id, scope_session_id) WHERE scope_session_id <> '';
+
+-- Business-hours grants: when set, a types.TimeWindow outside which the
+-- grant cannot be consumed.
+ALTER TABLE approval_grants ADD COLUMN IF NOT EXISTS scope_valid_hours JSONB;
+
+-- OIDC identity (issuer and subject) of an approver who signed in.
+ALTER TABLE approval_grants ADD COLUMN IF NOT EXISTS approver_issuer TEXT NOT NULL DEFAULT '';
+ALTER TABLE approval_grants ADD COLUMN IF NOT EXISTS approver_subject TEXT NOT NULL DEFAULT '';
+
+-- One row per consumption of a grant: the event it let through.
+CREATE TABLE IF NOT EXISTS grant_usages (
+    id          TEXT PRIMARY KEY,
+    grant_id    TEXT NOT NULL REFERENCES approval_grants(id),
+    tenant_id   TEXT NOT NULL REFERENCES tenants(id),
+    event_id    TEXT NOT NULL,
+    agent_id    TEXT NOT NULL DEFAULT '',
+    resource    TEXT DEFAULT '',
+    used_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_grant_usages_grant
+    ON grant_usages(grant_id, used_at);
+
 -- ── Notification outbox (reliable webhook/slack fanout) ─────────────────────
 
 CREATE TABLE IF NOT EXISTS approval_notification_outbox (
@@ -160,7 +372,7 @@ CREATE TABLE IF NOT EXISTS approval_notification_outbox (
     reason                TEXT DEFAULT '',
     approver_group        TEXT DEFAULT '',
     approval_url          TEXT NOT NULL,
-    notify_kind           TEXT NOT NULL,          -- webhook | slack
+    notify_kind           TEXT NOT NULL,          -- webhook | slack | slack_update | slack_comment | teams | email | sms | opsgenie
     notify_url            TEXT DEFAULT '',
     secret_ref            TEXT DEFAULT '',
     slack_channel         TEXT DEFAULT '',
@@ -176,6 +388,31 @@ CREATE TABLE IF NOT EXISTS approval_notification_outbox (
 CREATE INDEX IF NOT EXISTS idx_approval_notification_outbox_due
     ON approval_notification_outbox(status, next_attempt_at);
 
+-- Where a "slack" row's message was posted, and the resolution carried by the
+-- "slack_update" rows that rewrite it (parent_id) once the request is decided.
+ALTER TABLE approval_notification_outbox ADD COLUMN IF NOT EXISTS slack_message_channel TEXT NOT NULL DEFAULT '';
+ALTER TABLE approval_notification_outbox ADD COLUMN IF NOT EXISTS slack_message_ts TEXT NOT NULL DEFAULT '';
+ALTER TABLE approval_notification_outbox ADD COLUMN IF NOT EXISTS parent_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE approval_notification_outbox ADD COLUMN IF NOT EXISTS resolution TEXT NOT NULL DEFAULT '';
+ALTER TABLE approval_notification_outbox ADD COLUMN IF NOT EXISTS resolved_by TEXT NOT NULL DEFAULT '';
+ALTER TABLE approval_notification_outbox ADD COLUMN IF NOT EXISTS resolution_reason TEXT NOT NULL DEFAULT '';
+
+-- Provider-specific route settings (e.g. email "to") copied from the notify route.
+ALTER TABLE approval_notification_outbox ADD COLUMN IF NOT EXISTS notify_config JSONB NOT NULL DEFAULT '{}';
+
+-- The request's connector plan, copied so notifications can show it.
+ALTER TABLE approval_notification_outbox ADD COLUMN IF NOT EXISTS plan JSONB;
+
+-- The request's recent agent activity, copied so notifications can show it.
+ALTER TABLE approval_notification_outbox ADD COLUMN IF NOT EXISTS recent_activity JSONB;
+
+-- The comment a "slack_comment" row replies with in the thread of its parent.
+ALTER TABLE approval_notification_outbox ADD COLUMN IF NOT EXISTS comment JSONB;
+
+-- Delivery status lookups by approval request.
+CREATE INDEX IF NOT EXISTS idx_approval_notification_outbox_request
+    ON approval_notification_outbox(approval_request_id);
+
 -- ── Evidence archival checkpoints ───────────────────────────────────────────
 
 CREATE TABLE IF NOT EXISTS evidence_archive_checkpoints (
@@ -195,3 +432,59 @@ CREATE TABLE IF NOT EXISTS policy_versions (
     deployed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
     notes       TEXT DEFAULT ''
 );
+
+-- ── Connector credentials (per-tenant upstream accounts) ───────────────────
+-- Values are encrypted by the application (AES-256-GCM keyring or AWS KMS);
+-- key_id names the key that sealed each row so keys can be rotated.
+
+CREATE TABLE IF NOT EXISTS connector_credentials (
+    tenant_id   TEXT NOT NULL REFERENCES tenants(id),
+    connector   TEXT NOT NULL,
+    name        TEXT NOT NULL,
+    key_id      TEXT NOT NULL,
+    ciphertext  BYTEA NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (tenant_id, connector, name)
+);
+
+-- ── Usage metering (per tenant, per calendar-month billing period, UTC) ────
+-- No FK to tenants: static API_KEYS tenants are metered too.
+
+CREATE TABLE IF NOT EXISTS usage_counters (
+    tenant_id   TEXT NOT NULL,
+    period      TEXT NOT NULL,
+    metric      TEXT NOT NULL,
+    value       BIGINT NOT NULL DEFAULT 0,
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (tenant_id, period, metric)
+);
+
+CREATE INDEX IF NOT EXISTS idx_usage_counters_period ON usage_counters(period);
+
+-- ── Budget spend (connector-reported cost per UTC day and month) ──────────
+-- agent_id is '' for the tenant's total; period is YYYY-MM-DD or YYYY-MM.
+
+CREATE TABLE IF NOT EXISTS budget_spend (
+    tenant_id   TEXT NOT NULL,
+    agent_id    TEXT NOT NULL DEFAULT '',
+    period      TEXT NOT NULL,
+    spent       DOUBLE PRECISION NOT NULL DEFAULT 0,
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (tenant_id, agent_id, period)
+);
+
+-- ── Compliance digests (one row per tenant digest and period) ─────────────
+-- The approvals service claims a period before sending it; sent_at is set
+-- once delivered, and a failed claim is retried after its lease.
+
+CREATE TABLE IF NOT EXISTS tenant_digests (
+    tenant_id    TEXT NOT NULL,
+    name         TEXT NOT NULL,
+    period_start TIMESTAMPTZ NOT NULL,
+    claimed_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    attempts     INT NOT NULL DEFAULT 0,
+    sent_at      TIMESTAMPTZ,
+    last_error   TEXT,
+    PRIMARY KEY (tenant_id, name, period_start)
+);
@@ -0,0 +1,253 @@
+-- ═══════════════════════════════════════════════════════════════════════════
+-- mysql/001_initial.sql — OpenClause schema for MySQL 8.0+ / Aurora MySQL 3
+-- ═══════════════════════════════════════════════════════════════════════════
+-- Mirrors migrations/001_initial.sql for the evidence and approvals stores.
+-- Differences from Postgres: TEXT keys become VARCHAR, JSONB becomes JSON,
+-- BYTEA becomes LONGBLOB, and TIMESTAMPTZ becomes DATETIME(6) holding UTC
+-- (the stores force time_zone='+00:00' on every session).
+
+-- ── Tenants ─────────────────────────────────────────────────────────────────
+
+CREATE TABLE IF NOT EXISTS tenants (
+    id          VARCHAR(128) PRIMARY KEY,
+    name        VARCHAR(255) NOT NULL,
+    config      JSON,
+    created_at  DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+-- ── Agents ──────────────────────────────────────────────────────────────────
+
+CREATE TABLE IF NOT EXISTS agents (
+    id          VARCHAR(128) PRIMARY KEY,
+    tenant_id   VARCHAR(128) NOT NULL,
+    name        VARCHAR(255) NOT NULL,
+    labels      JSON,
+    created_at  DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    INDEX idx_agents_tenant (tenant_id),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+-- ── Tool events (one per incoming request) ──────────────────────────────────
+
+CREATE TABLE IF NOT EXISTS tool_events (
+    event_seq       BIGINT NOT NULL AUTO_INCREMENT UNIQUE,
+    event_id        VARCHAR(64) PRIMARY KEY,
+    tenant_id       VARCHAR(128) NOT NULL,
+    agent_id        VARCHAR(255) NOT NULL,
+    tool            VARCHAR(255) NOT NULL,
+    action          VARCHAR(255) NOT NULL,
+    payload_json    JSON NOT NULL,
+    payload_canon   LONGBLOB NOT NULL,
+    risk_score      INT NOT NULL DEFAULT 0 CHECK (risk_score >= 0 AND risk_score <= 10),
+    adjusted_risk_score INT CHECK (adjusted_risk_score >= 0 AND adjusted_risk_score <= 10),
+    decision        VARCHAR(16) NOT NULL CHECK (decision IN ('allow', 'deny', 'approve')),
+    policy_result   JSON,
+    idempotency_key VARCHAR(255) NOT NULL,
+    session_id      VARCHAR(255) DEFAULT '',
+    user_id         VARCHAR(255) DEFAULT '',
+    source_ip       VARCHAR(64) DEFAULT '',
+    trace_id        VARCHAR(64) DEFAULT '',
+    received_at     DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    requested_at    DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    hash            VARCHAR(128) NOT NULL,
+    prev_hash       VARCHAR(128) NOT NULL DEFAULT '',
+    canon_version   SMALLINT NOT NULL DEFAULT 1,
+    UNIQUE INDEX idx_tool_events_idempotency (tenant_id, idempotency_key),
+    INDEX idx_tool_events_tenant_ts (tenant_id, received_at),
+    INDEX idx_tool_events_tenant_agent_ts (tenant_id, agent_id, received_at),
+    INDEX idx_tool_events_decision (decision),
+    INDEX idx_tool_events_tool_action (tool, action),
+    INDEX idx_tool_events_tenant_seq (tenant_id, event_seq),
+    INDEX idx_tool_events_tenant_trace (tenant_id, trace_id, event_seq),
+    INDEX idx_tool_events_tenant_hash (tenant_id, hash),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+-- ── Tool results (execution outcomes) ───────────────────────────────────────
+
+CREATE TABLE IF NOT EXISTS tool_results (
+    id              BIGINT AUTO_INCREMENT PRIMARY KEY,
+    event_id        VARCHAR(64) NOT NULL,
+    tenant_id       VARCHAR(128) NOT NULL,
+    status          VARCHAR(16) NOT NULL CHECK (status IN ('success', 'error', 'timeout')),
+    output_json     JSON,
+    error_msg       TEXT,
+    duration_ms     BIGINT NOT NULL DEFAULT 0,
+    result_canon    LONGBLOB,
+    compensation_json JSON,
+    cost            DOUBLE,
+    connector_name     VARCHAR(255),
+    connector_version  VARCHAR(255),
+    connector_endpoint VARCHAR(2048),
+    connector_backend  VARCHAR(2048),
+    output_truncated   BOOLEAN,                                   -- output_json is a truncated stand-in
+    output_bytes       BIGINT,
+    output_sha256      VARCHAR(64),
+    output_ref         VARCHAR(1024),
+    quarantine_json    JSON,                                      -- output withheld pending review
+    change_ticket_json JSON,                                      -- change ticket filed for an approved execution
+    schedule_json      JSON,                                      -- schedule a scheduled execution ran from
+    created_at      DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    UNIQUE INDEX idx_tool_results_event (event_id),
+    FOREIGN KEY (event_id) REFERENCES tool_events(event_id),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+-- ── Tool execution links (approval resume endpoint) ──────────────────────────
+
+CREATE TABLE IF NOT EXISTS tool_executions (
+    parent_event_id      VARCHAR(64) PRIMARY KEY,
+    execution_event_id   VARCHAR(64) NOT NULL UNIQUE,
+    consumed_grant_id    VARCHAR(64),
+    created_at           DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    FOREIGN KEY (parent_event_id) REFERENCES tool_events(event_id),
+    FOREIGN KEY (execution_event_id) REFERENCES tool_events(event_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+-- Approved calls scheduled to run later with execute_at.
+CREATE TABLE IF NOT EXISTS scheduled_executions (
+    id                  VARCHAR(64) PRIMARY KEY,
+    parent_event_id     VARCHAR(64) NOT NULL,
+    tenant_id           VARCHAR(128) NOT NULL,
+    grant_id            VARCHAR(64) NOT NULL,
+    execute_at          DATETIME(6) NOT NULL,
+    status              VARCHAR(16) NOT NULL DEFAULT 'scheduled', -- scheduled, running, executed, cancelled, failed
+    execution_event_id  VARCHAR(64),
+    error_msg           TEXT NOT NULL,
+    lease_until         DATETIME(6),                              -- when a running claim lapses
+    created_at          DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at          DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    INDEX idx_scheduled_executions_parent (parent_event_id, created_at),
+    INDEX idx_scheduled_executions_due (status, execute_at),
+    FOREIGN KEY (parent_event_id) REFERENCES tool_events(event_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+-- ── Approval requests ───────────────────────────────────────────────────────
+
+CREATE TABLE IF NOT EXISTS approval_requests (
+    id          VARCHAR(64) PRIMARY KEY,
+    event_id    VARCHAR(64) NOT NULL,
+    tenant_id   VARCHAR(128) NOT NULL,
+    agent_id    VARCHAR(255) NOT NULL,
+    tool        VARCHAR(255) NOT NULL,
+    action      VARCHAR(255) NOT NULL,
+    resource    TEXT,
+    session_id  VARCHAR(255) NOT NULL DEFAULT '',
+    risk_score  INT NOT NULL DEFAULT 0,
+    reason      TEXT,
+    deny_reason TEXT,
+    denied_by   VARCHAR(255) DEFAULT '',
+    plan        JSON,                                          -- connector dry-run shown to approvers
+    approver_group VARCHAR(255) NOT NULL DEFAULT '',           -- policy's approver group
+    kind        VARCHAR(32) NOT NULL DEFAULT '',               -- 'quarantine' for withheld-output reviews
+    released_by VARCHAR(255) NOT NULL DEFAULT '',              -- approver who released quarantined output
+    recent_activity JSON,                                      -- agent's latest calls, newest first
+    grant_ttl_sec INT NOT NULL DEFAULT 0,                      -- policy's default grant lifetime; 0 = tenant's, then 1h
+    status      VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'denied', 'expired', 'cancelled')),
+    created_at  DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at  DATETIME(6),
+    expires_at  DATETIME(6) NOT NULL,
+    INDEX idx_approval_requests_tenant_status (tenant_id, status),
+    INDEX idx_approval_requests_event (event_id),
+    INDEX idx_approval_requests_tenant_status_created (tenant_id, status, created_at, id),
+    FOREIGN KEY (event_id) REFERENCES tool_events(event_id),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+-- ── Approval comments ───────────────────────────────────────────────────────
+
+CREATE TABLE IF NOT EXISTS approval_comments (
+    id                  VARCHAR(64) PRIMARY KEY,
+    approval_request_id VARCHAR(64) NOT NULL,
+    tenant_id           VARCHAR(128) NOT NULL,
+    author              VARCHAR(255) NOT NULL,
+    role                VARCHAR(16) NOT NULL CHECK (role IN ('approver', 'requester')),
+    body                TEXT NOT NULL,
+    created_at          DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    INDEX idx_approval_comments_request (approval_request_id, created_at),
+    FOREIGN KEY (approval_request_id) REFERENCES approval_requests(id),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+-- ── Approval grants ─────────────────────────────────────────────────────────
+
+CREATE TABLE IF NOT EXISTS approval_grants (
+    id                      VARCHAR(64) PRIMARY KEY,
+    request_id              VARCHAR(64) NOT NULL,
+    tenant_id               VARCHAR(128) NOT NULL,
+    approver                VARCHAR(255) NOT NULL,
+    approver_issuer         VARCHAR(255) NOT NULL DEFAULT '',  -- OIDC identity of a signed-in approver
+    approver_subject        VARCHAR(255) NOT NULL DEFAULT '',
+    scope_tool              VARCHAR(255) NOT NULL,
+    scope_action            VARCHAR(255) NOT NULL,
+    scope_resource_pattern  TEXT,
+    scope_tenant_id         VARCHAR(128) NOT NULL,
+    scope_agent_id          VARCHAR(255) DEFAULT '',
+    scope_session_id        VARCHAR(255) NOT NULL DEFAULT '',
+    scope_valid_hours       JSON,
+    max_uses                INT NOT NULL DEFAULT 1,
+    uses_left               INT NOT NULL DEFAULT 1,
+    expires_at              DATETIME(6) NOT NULL,
+    granted_at              DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at              DATETIME(6),
+    INDEX idx_approval_grants_tenant (tenant_id, uses_left, expires_at),
+    INDEX idx_approval_grants_session (tenant_id, scope_session_id),
+    FOREIGN KEY (request_id) REFERENCES approval_requests(id),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+CREATE TABLE IF NOT EXISTS grant_usages (
+    id          VARCHAR(64) PRIMARY KEY,
+    grant_id    VARCHAR(64) NOT NULL,
+    tenant_id   VARCHAR(128) NOT NULL,
+    event_id    VARCHAR(64) NOT NULL,
+    agent_id    VARCHAR(255) NOT NULL DEFAULT '',
+    resource    TEXT,
+    used_at     DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    INDEX idx_grant_usages_grant (grant_id, used_at),
+    FOREIGN KEY (grant_id) REFERENCES approval_grants(id),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+-- ── Notification outbox (reliable webhook/slack fanout) ─────────────────────
+
+CREATE TABLE IF NOT EXISTS approval_notification_outbox (
+    id                    VARCHAR(64) PRIMARY KEY,
+    approval_request_id   VARCHAR(64) NOT NULL,
+    tenant_id             VARCHAR(128) NOT NULL,
+    event_id              VARCHAR(64) NOT NULL,
+    trace_id              VARCHAR(64) DEFAULT '',
+    tool                  VARCHAR(255) NOT NULL,
+    action                VARCHAR(255) NOT NULL,
+    resource              TEXT,
+    risk_score            INT NOT NULL DEFAULT 0,
+    risk_factors          JSON,
+    reason                TEXT,
+    approver_group        VARCHAR(255) DEFAULT '',
+    approval_url          TEXT NOT NULL,
+    notify_kind           VARCHAR(32) NOT NULL,             -- webhook | slack | slack_update | slack_comment | teams | email | sms | opsgenie
+    notify_url            TEXT,
+    secret_ref            VARCHAR(255) DEFAULT '',
+    slack_channel         VARCHAR(255) DEFAULT '',
+    notify_config         JSON,                                        -- provider-specific route settings
+    plan                  JSON,                                        -- the request's connector plan
+    recent_activity       JSON,                                        -- the request's recent agent activity
+    comment               JSON,                                        -- slack_comment: the comment to reply with
+    slack_message_channel VARCHAR(255) NOT NULL DEFAULT '',
+    slack_message_ts      VARCHAR(64) NOT NULL DEFAULT '',
+    parent_id             VARCHAR(128) NOT NULL DEFAULT '',            -- slack_update: the slack row it rewrites
+    resolution            VARCHAR(16) NOT NULL DEFAULT '',             -- approved|denied|expired
+    resolved_by           VARCHAR(255) NOT NULL DEFAULT '',
+    resolution_reason     TEXT,
+    status                VARCHAR(16) NOT NULL DEFAULT 'pending', -- pending|processing|sent|failed
+    attempt_count         INT NOT NULL DEFAULT 0,
+    next_attempt_at       DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    last_error            TEXT,
+    sent_at               DATETIME(6),
+    created_at            DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at            DATETIME(6),
+    INDEX idx_approval_notification_outbox_due (status, next_attempt_at),
+    FOREIGN KEY (approval_request_id) REFERENCES approval_requests(id),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(id),
+    FOREIGN KEY (event_id) REFERENCES tool_events(event_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
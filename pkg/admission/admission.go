@@ -0,0 +1,175 @@
+// Package admission provides adaptive load shedding for request handlers.
+//
+// A Controller tracks in-flight requests and an exponentially weighted moving
+// average of request latency. Under pressure it rejects low-priority work
+// first so that higher-priority calls keep completing instead of every
+// request timing out at once. The average decays while no request
+// completes, so shed traffic is admitted again once it has had time to
+// recover instead of waiting on samples it is not allowed to produce.
+package admission
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Priority orders requests for shedding. Lower priorities are shed first.
+type Priority int
+
+const (
+	PriorityLow Priority = iota
+	PriorityNormal
+	PriorityHigh
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityHigh:
+		return "high"
+	default:
+		return "normal"
+	}
+}
+
+// Config holds shedding thresholds.
+type Config struct {
+	// MaxInFlight is the hard cap on concurrently admitted requests.
+	// Zero disables in-flight based shedding.
+	MaxInFlight int
+	// TargetLatency is the latency above which low-priority work is shed
+	// (and normal-priority work above twice the target). Zero disables
+	// latency based shedding.
+	TargetLatency time.Duration
+}
+
+const (
+	lowShedFraction    = 0.5
+	normalShedFraction = 0.8
+	ewmaAlpha          = 0.2
+	// ewmaHalfLife is how fast the latency average decays toward zero
+	// between samples.
+	ewmaHalfLife = time.Second
+)
+
+// Controller decides whether to admit a request. Safe for concurrent use.
+// A nil *Controller admits everything.
+type Controller struct {
+	cfg Config
+
+	mu       sync.Mutex
+	inFlight int
+	ewma     time.Duration
+	// sampled is when ewma last took a sample; zero before the first.
+	sampled time.Time
+}
+
+// New creates an admission controller.
+func New(cfg Config) *Controller {
+	return &Controller{cfg: cfg}
+}
+
+// Admit reports whether a request of priority p may proceed. When admitted,
+// the returned release func must be called exactly once when the request
+// finishes; it records the observed latency.
+func (c *Controller) Admit(p Priority) (release func(), ok bool) {
+	if c == nil {
+		return func() {}, true
+	}
+
+	c.mu.Lock()
+	if c.shouldShedLocked(p) {
+		c.mu.Unlock()
+		return nil, false
+	}
+	c.inFlight++
+	c.mu.Unlock()
+
+	start := time.Now()
+	var once sync.Once
+	return func() {
+		once.Do(func() { c.done(time.Since(start)) })
+	}, true
+}
+
+// InFlight returns the current number of admitted requests.
+func (c *Controller) InFlight() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.inFlight
+}
+
+// Latency returns the current latency moving average.
+func (c *Controller) Latency() time.Duration {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.latencyLocked(time.Now())
+}
+
+// latencyLocked is the latency average decayed for the time since its last
+// sample.
+func (c *Controller) latencyLocked(now time.Time) time.Duration {
+	if c.sampled.IsZero() {
+		return c.ewma
+	}
+	idle := now.Sub(c.sampled)
+	if idle <= 0 {
+		return c.ewma
+	}
+	return time.Duration(float64(c.ewma) * math.Exp2(-float64(idle)/float64(ewmaHalfLife)))
+}
+
+func (c *Controller) shouldShedLocked(p Priority) bool {
+	if max := c.cfg.MaxInFlight; max > 0 {
+		load := float64(c.inFlight) / float64(max)
+		switch p {
+		case PriorityLow:
+			if load >= lowShedFraction {
+				return true
+			}
+		case PriorityNormal:
+			if load >= normalShedFraction {
+				return true
+			}
+		default:
+			if c.inFlight >= max {
+				return true
+			}
+		}
+	}
+	if target := c.cfg.TargetLatency; target > 0 {
+		latency := c.latencyLocked(time.Now())
+		switch p {
+		case PriorityLow:
+			if latency > target {
+				return true
+			}
+		case PriorityNormal:
+			if latency > 2*target {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *Controller) done(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.inFlight--
+	now := time.Now()
+	if c.sampled.IsZero() {
+		c.ewma, c.sampled = d, now
+		return
+	}
+	c.ewma = time.Duration(ewmaAlpha*float64(d) + (1-ewmaAlpha)*float64(c.latencyLocked(now)))
+	c.sampled = now
+}
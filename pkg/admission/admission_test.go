@@ -0,0 +1,117 @@
+package admission
+
+import (
+	"testing"
+	"time"
+)
+
+func TestController_ShedsLowPriorityFirst(t *testing.T) {
+	c := New(Config{MaxInFlight: 4})
+
+	var releases []func()
+	for range 2 {
+		rel, ok := c.Admit(PriorityNormal)
+		if !ok {
+			t.Fatal("expected normal request to be admitted")
+		}
+		releases = append(releases, rel)
+	}
+
+	// 2/4 in flight: low priority is shed, normal still admitted.
+	if _, ok := c.Admit(PriorityLow); ok {
+		t.Fatal("expected low priority request to be shed at 50% load")
+	}
+	rel, ok := c.Admit(PriorityNormal)
+	if !ok {
+		t.Fatal("expected normal request to be admitted at 50% load")
+	}
+	releases = append(releases, rel)
+
+	// 3/4 in flight (75%): normal still admitted, 4/4 then sheds normal.
+	rel, ok = c.Admit(PriorityNormal)
+	if !ok {
+		t.Fatal("expected normal request to be admitted at 75% load")
+	}
+	releases = append(releases, rel)
+	if _, ok := c.Admit(PriorityNormal); ok {
+		t.Fatal("expected normal request to be shed at full load")
+	}
+	if _, ok := c.Admit(PriorityHigh); ok {
+		t.Fatal("expected high priority request to be shed at hard cap")
+	}
+
+	for _, rel := range releases {
+		rel()
+	}
+	if c.InFlight() != 0 {
+		t.Fatalf("expected 0 in flight after release, got %d", c.InFlight())
+	}
+	if _, ok := c.Admit(PriorityLow); !ok {
+		t.Fatal("expected low priority request to be admitted after drain")
+	}
+}
+
+func TestController_LatencyShedding(t *testing.T) {
+	c := New(Config{TargetLatency: 10 * time.Millisecond})
+	c.ewma = 15 * time.Millisecond
+
+	if _, ok := c.Admit(PriorityLow); ok {
+		t.Fatal("expected low priority to be shed above target latency")
+	}
+	if _, ok := c.Admit(PriorityNormal); !ok {
+		t.Fatal("expected normal priority to be admitted below 2x target latency")
+	}
+
+	c.ewma = 25 * time.Millisecond
+	if _, ok := c.Admit(PriorityNormal); ok {
+		t.Fatal("expected normal priority to be shed above 2x target latency")
+	}
+	if _, ok := c.Admit(PriorityHigh); !ok {
+		t.Fatal("expected high priority to ignore latency shedding")
+	}
+}
+
+func TestController_LatencyDecaysWhileShedding(t *testing.T) {
+	c := New(Config{TargetLatency: 10 * time.Millisecond})
+	c.ewma = 40 * time.Millisecond
+	c.sampled = time.Now()
+	if _, ok := c.Admit(PriorityLow); ok {
+		t.Fatal("expected low priority to be shed right after a slow sample")
+	}
+
+	// No request has completed for three half-lives: 40ms decays to 5ms.
+	c.sampled = time.Now().Add(-3 * ewmaHalfLife)
+	if got := c.Latency(); got > 6*time.Millisecond {
+		t.Fatalf("latency = %v, want it decayed to about 5ms", got)
+	}
+	rel, ok := c.Admit(PriorityLow)
+	if !ok {
+		t.Fatal("expected low priority to be admitted once latency decayed")
+	}
+	rel()
+	if got := c.Latency(); got > 10*time.Millisecond {
+		t.Fatalf("latency after a fast sample = %v", got)
+	}
+}
+
+func TestController_ReleaseIsIdempotent(t *testing.T) {
+	c := New(Config{MaxInFlight: 10})
+	rel, ok := c.Admit(PriorityNormal)
+	if !ok {
+		t.Fatal("expected admission")
+	}
+	rel()
+	rel()
+	if c.InFlight() != 0 {
+		t.Fatalf("expected 0 in flight, got %d", c.InFlight())
+	}
+}
+
+func TestController_NilAdmitsEverything(t *testing.T) {
+	var c *Controller
+	rel, ok := c.Admit(PriorityLow)
+	if !ok || rel == nil {
+		t.Fatal("nil controller should admit")
+	}
+	rel()
+}
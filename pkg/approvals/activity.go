@@ -0,0 +1,29 @@
+package approvals
+
+import (
+	"fmt"
+	"time"
+)
+
+// ActivityLines renders an agent's recent activity as plain-text lines for
+// notifications: a heading, then one line per call with its time, tool
+// call, resource, decision and result status. It returns nil without
+// activity.
+func ActivityLines(activity []AgentActivity) []string {
+	if len(activity) == 0 {
+		return nil
+	}
+	lines := []string{"Recent agent activity (newest first):"}
+	for _, a := range activity {
+		line := fmt.Sprintf("  %s %s.%s", a.At.UTC().Format(time.RFC3339), a.Tool, a.Action)
+		if a.Resource != "" {
+			line += " on " + truncateRunes(a.Resource, maxPlanFieldValue)
+		}
+		line += ": " + a.Decision
+		if a.Status != "" {
+			line += ", " + a.Status
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
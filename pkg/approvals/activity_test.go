@@ -0,0 +1,26 @@
+package approvals
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestActivityLines(t *testing.T) {
+	if ActivityLines(nil) != nil {
+		t.Fatal("no activity must render nothing")
+	}
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.FixedZone("CET", 3600))
+	got := ActivityLines([]AgentActivity{
+		{Tool: "jira", Action: "issue.create", Resource: "OPS", Decision: "allow", Status: "success", At: at},
+		{Tool: "slack", Action: "msg.post", Decision: "deny", At: at},
+	})
+	want := []string{
+		"Recent agent activity (newest first):",
+		"  2026-01-02T02:04:05Z jira.issue.create on OPS: allow, success",
+		"  2026-01-02T02:04:05Z slack.msg.post: deny",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ActivityLines = %q", got)
+	}
+}
@@ -2,6 +2,16 @@ package approvals
 
 import "strings"
 
+// Authorizer decides who may resolve a tenant's approval requests. group is
+// the request's approver group, empty when policy named none.
+type Authorizer interface {
+	AllowEmail(tenantID, group, email string) bool
+	AllowSlack(tenantID, group, userID string) bool
+}
+
+// ApproverAuthorizer allows the approvers listed per tenant in the
+// APPROVER_EMAIL_ALLOWLIST and APPROVER_SLACK_ALLOWLIST variables, whatever
+// the request's approver group.
 type ApproverAuthorizer struct {
 	emailByTenant map[string]map[string]struct{}
 	slackByTenant map[string]map[string]struct{}
@@ -14,7 +24,7 @@ func NewApproverAuthorizer(emailAllowlist, slackAllowlist string) *ApproverAutho
 	}
 }
 
-func (a *ApproverAuthorizer) AllowEmail(tenantID, email string) bool {
+func (a *ApproverAuthorizer) AllowEmail(tenantID, _, email string) bool {
 	if email == "" {
 		return false
 	}
@@ -26,7 +36,7 @@ func (a *ApproverAuthorizer) AllowEmail(tenantID, email string) bool {
 	return ok
 }
 
-func (a *ApproverAuthorizer) AllowSlack(tenantID, userID string) bool {
+func (a *ApproverAuthorizer) AllowSlack(tenantID, _, userID string) bool {
 	if userID == "" {
 		return false
 	}
@@ -0,0 +1,84 @@
+package approvals
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// AutoApprover is the approver recorded on grants, resolutions and Slack
+// updates for requests approved by a tenant's auto-approval rules.
+const AutoApprover = "auto-approver"
+
+// AutoApproval returns the name of the tenant rule that approves req
+// without a human, or "" when none does.
+type AutoApproval func(ctx context.Context, req ApprovalRequest) (rule string, err error)
+
+// SetAutoApproval registers fn to decide which requests are approved
+// automatically. It must be called before the handlers start serving.
+func (h *Handlers) SetAutoApproval(fn AutoApproval) {
+	h.autoApproval = fn
+}
+
+// AutoApprovePending applies the auto-approval rules to every pending
+// request, including those the gateway wrote to the store directly. The
+// approvals service runs it before each notification dispatch.
+func (h *Handlers) AutoApprovePending(ctx context.Context) error {
+	if h.autoApproval == nil {
+		return nil
+	}
+	counts, err := h.store.CountPendingByTenant(ctx)
+	if err != nil {
+		return err
+	}
+	for tenantID := range counts {
+		var after types.Cursor
+		for {
+			reqs, err := h.store.ListPending(ctx, tenantID, defaultPendingLimit, after)
+			if err != nil {
+				return err
+			}
+			for i := range reqs {
+				h.autoApprove(ctx, &reqs[i])
+			}
+			if len(reqs) < defaultPendingLimit {
+				break
+			}
+			last := reqs[len(reqs)-1]
+			after = types.Cursor{Time: last.CreatedAt, ID: last.ID}
+		}
+	}
+	return nil
+}
+
+// autoApprove grants req as AutoApprover, for a single use, when one of its
+// tenant's rules matches, and reports whether it did. Failures are logged
+// and leave req pending for a human. Quarantine requests always wait for a
+// human.
+func (h *Handlers) autoApprove(ctx context.Context, req *ApprovalRequest) bool {
+	if h.autoApproval == nil || req.IsQuarantine() {
+		return false
+	}
+	rule, err := h.autoApproval(ctx, *req)
+	if err != nil {
+		slog.Error("auto-approval rules unavailable", "tenant_id", req.TenantID, "id", req.ID, "error", err)
+		return false
+	}
+	if rule == "" {
+		return false
+	}
+	if _, err := h.grant(ctx, req, GrantInput{Approver: AutoApprover, MaxUses: 1}); err != nil {
+		slog.Error("auto-approve request failed", "tenant_id", req.TenantID, "id", req.ID, "rule", rule, "error", err)
+		return false
+	}
+	req.Status = "approved"
+	autoApprovals.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("tenant", req.TenantID),
+		attribute.String("rule", rule),
+	))
+	h.publishResolution(ctx, req, "approved", AutoApprover, "auto-approval rule "+rule)
+	return true
+}
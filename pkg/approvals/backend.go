@@ -0,0 +1,26 @@
+package approvals
+
+import "context"
+
+// Backend is the full persistence contract for approvals: request CRUD for
+// the HTTP handlers, grant consumption for the gateway, the notification
+// outbox, its dead-letter API and retention, and the pending and outbox
+// gauges. Postgres (Store) and MySQL (MySQLStore) and SQLite (SQLiteStore)
+// implement it.
+type Backend interface {
+	handlersStore
+	notificationStore
+	deadLetterStore
+	pendingCounter
+	outboxRetentionStore
+	FindAndConsumeGrant(ctx context.Context, eventID, tenantID, agentID, sessionID, tool, action, resource string) (*ApprovalGrant, error)
+	FindAndConsumeSessionGrant(ctx context.Context, eventID, tenantID, agentID, sessionID, tool, action, resource string) (*ApprovalGrant, error)
+	ExpireRequests(ctx context.Context) ([]string, error)
+	ListRequestsByEvents(ctx context.Context, tenantID string, eventIDs []string) ([]ApprovalRequest, error)
+}
+
+var (
+	_ Backend = (*Store)(nil)
+	_ Backend = (*MySQLStore)(nil)
+	_ Backend = (*SQLiteStore)(nil)
+)
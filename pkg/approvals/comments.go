@@ -0,0 +1,158 @@
+package approvals
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"github.com/go-chi/chi/v5"
+)
+
+// Comment is a note on an approval request's thread: a question from an
+// approver or an answer from the requesting agent.
+type Comment struct {
+	ID        string    `json:"id"`
+	RequestID string    `json:"request_id"`
+	TenantID  string    `json:"tenant_id"`
+	Author    string    `json:"author"`
+	Role      string    `json:"role"` // RoleApprover or RoleRequester
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Comment roles.
+const (
+	RoleApprover  = "approver"
+	RoleRequester = "requester"
+)
+
+// MaxCommentBytes bounds a comment's body.
+const MaxCommentBytes = 4000
+
+// CommentInput is the body of POST /v1/approvals/requests/{id}/comments.
+type CommentInput struct {
+	// Author names an approver commenting without signing in. A requester
+	// comment is always by the request's agent.
+	Author string `json:"author,omitempty"`
+	Role   string `json:"role,omitempty"` // default RoleApprover
+	Body   string `json:"body"`
+}
+
+// CommentsPage is the body of GET /v1/approvals/requests/{id}/comments.
+type CommentsPage struct {
+	Comments []Comment `json:"comments"`
+}
+
+// CommentSink receives comments after they are stored. Implementations
+// must not block.
+type CommentSink interface {
+	PublishComment(context.Context, ApprovalRequest, Comment)
+}
+
+// NewComment validates body and returns a comment on req by author in
+// role, ready for AddComment.
+func NewComment(req *ApprovalRequest, author, role, body string) (Comment, *types.APIError) {
+	body = strings.TrimSpace(body)
+	switch {
+	case body == "":
+		return Comment{}, types.ErrValidation(&types.ValidationError{Field: "body", Reason: "is required"})
+	case len(body) > MaxCommentBytes:
+		return Comment{}, types.ErrValidation(&types.ValidationError{Field: "body", Reason: fmt.Sprintf("exceeds %d bytes", MaxCommentBytes)})
+	}
+	return Comment{RequestID: req.ID, TenantID: req.TenantID, Author: author, Role: role, Body: body}, nil
+}
+
+// AddCommentSink registers a sink notified after each comment. It must be
+// called before the handlers start serving.
+func (h *Handlers) AddCommentSink(s CommentSink) {
+	h.commentSinks = append(h.commentSinks, s)
+}
+
+// CreateComment handles POST /v1/approvals/requests/{id}/comments
+func (h *Handlers) CreateComment(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	var in CommentInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		types.ErrBadRequest("invalid JSON body").WriteJSON(w)
+		return
+	}
+	c, apiErr := h.Comment(r.Context(), chi.URLParam(r, "id"), in)
+	if apiErr != nil {
+		apiErr.WriteJSON(w)
+		return
+	}
+	writeJSON(w, http.StatusCreated, c)
+}
+
+// Comment adds in to the thread of request id and publishes it. Approver
+// comments are by the approver in in, or the signed-in approver in ctx,
+// who must be allowed to resolve the request; requester comments are by
+// the request's agent. The comments endpoint and the approvals UI share it.
+func (h *Handlers) Comment(ctx context.Context, id string, in CommentInput) (*Comment, *types.APIError) {
+	req, err := h.store.GetRequest(ctx, id)
+	if err != nil {
+		slog.Error("get approval request failed", "error", err)
+		return nil, types.ErrInternal("failed to add comment")
+	}
+	if req == nil {
+		return nil, types.ErrNotFound("approval request not found")
+	}
+	var author string
+	switch in.Role {
+	case "", RoleApprover:
+		in.Role = RoleApprover
+		approver, _, apiErr := h.verifiedApprover(ctx, in.Author)
+		if apiErr != nil {
+			return nil, apiErr
+		}
+		if h.authorizer != nil && !h.authorizer.AllowEmail(req.TenantID, req.ApproverGroup, approver) {
+			return nil, types.ErrForbidden("approver is not allowed for tenant")
+		}
+		author = approver
+	case RoleRequester:
+		author = req.AgentID
+	default:
+		return nil, types.ErrValidation(&types.ValidationError{Field: "role", Reason: "must be approver or requester"})
+	}
+	c, apiErr := NewComment(req, author, in.Role, in.Body)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	stored, err := h.store.AddComment(ctx, c)
+	if err != nil {
+		slog.Error("add comment failed", "error", err, "id", id)
+		return nil, types.ErrInternal("failed to add comment")
+	}
+	for _, s := range h.commentSinks {
+		s.PublishComment(ctx, *req, *stored)
+	}
+	return stored, nil
+}
+
+// ListComments handles GET /v1/approvals/requests/{id}/comments. The
+// thread is returned oldest first.
+func (h *Handlers) ListComments(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	req, err := h.store.GetRequest(r.Context(), id)
+	if err != nil {
+		slog.Error("get approval request failed", "error", err)
+		types.ErrInternal("failed to retrieve approval request").WriteJSON(w)
+		return
+	}
+	if req == nil {
+		types.ErrNotFound("approval request not found").WriteJSON(w)
+		return
+	}
+	comments, err := h.store.ListComments(r.Context(), []string{id})
+	if err != nil {
+		slog.Error("list comments failed", "error", err, "id", id)
+		types.ErrInternal("failed to list comments").WriteJSON(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, CommentsPage{Comments: comments})
+}
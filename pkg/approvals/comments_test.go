@@ -0,0 +1,62 @@
+package approvals
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/bturcanu/OpenClause/pkg/oidc"
+)
+
+type recordingCommentSink struct{ got []Comment }
+
+func (s *recordingCommentSink) PublishComment(_ context.Context, _ ApprovalRequest, c Comment) {
+	s.got = append(s.got, c)
+}
+
+func TestComment_RolesAndAuthorization(t *testing.T) {
+	store := &fakeHandlersStore{group: "security"}
+	authz := NewApproverAuthorizer("tenant1:alice@example.com", "")
+	h := NewHandlers(store, authz)
+	sink := &recordingCommentSink{}
+	h.AddCommentSink(sink)
+	ctx := context.Background()
+
+	c, apiErr := h.Comment(ctx, "req-1", CommentInput{Author: "alice@example.com", Body: "  Why does this need prod access?  "})
+	if apiErr != nil {
+		t.Fatal(apiErr)
+	}
+	if c.Role != RoleApprover || c.Author != "alice@example.com" || c.Body != "Why does this need prod access?" || c.RequestID != "req-1" || c.TenantID != "tenant1" {
+		t.Fatalf("approver comment = %+v", c)
+	}
+	// A requester comment is always by the request's agent.
+	c, apiErr = h.Comment(ctx, "req-1", CommentInput{Author: "alice@example.com", Role: RoleRequester, Body: "Rotating the leaked key"})
+	if apiErr != nil || c.Author != "agent-1" || c.Role != RoleRequester {
+		t.Fatalf("requester comment = %+v, %v", c, apiErr)
+	}
+	if len(sink.got) != 2 {
+		t.Fatalf("published %d comments, want 2", len(sink.got))
+	}
+
+	for name, tc := range map[string]struct {
+		ctx  context.Context
+		in   CommentInput
+		code int
+	}{
+		"unlisted approver": {ctx, CommentInput{Author: "mallory@example.com", Body: "ok"}, http.StatusForbidden},
+		"no approver":       {ctx, CommentInput{Body: "ok"}, http.StatusBadRequest},
+		"impersonation": {oidc.WithSession(ctx, oidc.Session{Identity: oidc.Identity{Email: "alice@example.com"}}),
+			CommentInput{Author: "bob@example.com", Body: "ok"}, http.StatusForbidden},
+		"empty body":   {ctx, CommentInput{Author: "alice@example.com", Body: " "}, http.StatusUnprocessableEntity},
+		"long body":    {ctx, CommentInput{Author: "alice@example.com", Body: strings.Repeat("x", MaxCommentBytes+1)}, http.StatusUnprocessableEntity},
+		"unknown role": {ctx, CommentInput{Role: "auditor", Body: "ok"}, http.StatusUnprocessableEntity},
+	} {
+		if _, apiErr := h.Comment(tc.ctx, "req-1", tc.in); apiErr == nil || apiErr.HTTPCode != tc.code {
+			t.Errorf("%s: error %v, want HTTP %d", name, apiErr, tc.code)
+		}
+	}
+	if len(store.comments) != 2 {
+		t.Fatalf("stored %d comments, want 2", len(store.comments))
+	}
+}
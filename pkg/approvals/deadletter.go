@@ -0,0 +1,183 @@
+package approvals
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"github.com/go-chi/chi/v5"
+)
+
+// DeadLetter is a notification outbox row as shown by the dead-letter and
+// delivery status APIs. Rows are dead letters once the dispatcher gives up
+// on them and sets status "failed".
+type DeadLetter struct {
+	ID                string     `json:"id"`
+	ApprovalRequestID string     `json:"approval_request_id"`
+	TenantID          string     `json:"tenant_id"`
+	EventID           string     `json:"event_id"`
+	Tool              string     `json:"tool"`
+	Action            string     `json:"action"`
+	Resource          string     `json:"resource"`
+	NotifyKind        string     `json:"notify_kind"`
+	NotifyURL         string     `json:"notify_url,omitempty"`
+	SlackChannel      string     `json:"slack_channel,omitempty"`
+	ParentID          string     `json:"parent_id,omitempty"`
+	Status            string     `json:"status"`
+	Attempts          int        `json:"attempt_count"`
+	LastError         string     `json:"last_error"`
+	NextAttemptAt     time.Time  `json:"next_attempt_at"`
+	SentAt            *time.Time `json:"sent_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+type deadLetterStore interface {
+	ListFailedNotifications(ctx context.Context, tenantID string, limit int, after types.Cursor) ([]DeadLetter, error)
+	GetNotification(ctx context.Context, id string) (*DeadLetter, error)
+	RequeueNotification(ctx context.Context, id string) (bool, error)
+	PurgeFailedNotifications(ctx context.Context, tenantID, id string, before time.Time) (int64, error)
+}
+
+// DeadLetterHandlers exposes terminally failed notifications so operators
+// can inspect, requeue, or purge them without SQL access.
+type DeadLetterHandlers struct {
+	store deadLetterStore
+}
+
+// NewDeadLetterHandlers creates dead-letter handlers backed by store.
+func NewDeadLetterHandlers(store deadLetterStore) *DeadLetterHandlers {
+	return &DeadLetterHandlers{store: store}
+}
+
+// RegisterRoutes mounts the dead-letter routes on r. Like the other
+// approvals routes they are internal-only.
+func (h *DeadLetterHandlers) RegisterRoutes(r chi.Router) {
+	r.Get("/v1/approvals/notifications/failed", h.List)
+	r.Delete("/v1/approvals/notifications/failed", h.Purge)
+	r.Get("/v1/approvals/notifications/{id}", h.Get)
+	r.Post("/v1/approvals/notifications/{id}/requeue", h.Requeue)
+	r.Delete("/v1/approvals/notifications/{id}", h.Delete)
+}
+
+// List handles GET /v1/approvals/notifications/failed?tenant_id=&limit=&cursor=
+func (h *DeadLetterHandlers) List(w http.ResponseWriter, r *http.Request) {
+	limit, after, ok := parsePage(w, r)
+	if !ok {
+		return
+	}
+	items, err := h.store.ListFailedNotifications(r.Context(), r.URL.Query().Get("tenant_id"), limit, after)
+	if err != nil {
+		slog.Error("list failed notifications failed", "error", err)
+		types.ErrInternal("failed to list failed notifications").WriteJSON(w)
+		return
+	}
+	page := DeadLetterPage{Notifications: items}
+	if n := len(items); n > 0 {
+		page.NextCursor = nextCursor(n, limit, types.Cursor{Time: items[n-1].UpdatedAt, ID: items[n-1].ID})
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// Get handles GET /v1/approvals/notifications/{id}
+func (h *DeadLetterHandlers) Get(w http.ResponseWriter, r *http.Request) {
+	n, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, n)
+}
+
+// Requeue handles POST /v1/approvals/notifications/{id}/requeue. The row is
+// returned to pending with a fresh attempt budget and picked up on the next
+// dispatcher tick.
+func (h *DeadLetterHandlers) Requeue(w http.ResponseWriter, r *http.Request) {
+	n, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+	if n.Status != "failed" {
+		types.ErrConflict("only failed notifications can be requeued").WriteJSON(w)
+		return
+	}
+	requeued, err := h.store.RequeueNotification(r.Context(), n.ID)
+	if err != nil {
+		slog.Error("requeue notification failed", "error", err, "id", n.ID)
+		types.ErrInternal("failed to requeue notification").WriteJSON(w)
+		return
+	}
+	if !requeued {
+		types.ErrConflict("only failed notifications can be requeued").WriteJSON(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "requeued"})
+}
+
+// Delete handles DELETE /v1/approvals/notifications/{id} for a failed row.
+func (h *DeadLetterHandlers) Delete(w http.ResponseWriter, r *http.Request) {
+	n, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+	if n.Status != "failed" {
+		types.ErrConflict("only failed notifications can be purged").WriteJSON(w)
+		return
+	}
+	purged, err := h.store.PurgeFailedNotifications(r.Context(), "", n.ID, time.Time{})
+	if err != nil {
+		slog.Error("purge notification failed", "error", err, "id", n.ID)
+		types.ErrInternal("failed to purge notification").WriteJSON(w)
+		return
+	}
+	if purged == 0 {
+		types.ErrConflict("only failed notifications can be purged").WriteJSON(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "purged"})
+}
+
+// Purge handles DELETE /v1/approvals/notifications/failed?tenant_id=&before=
+// and deletes every failed row matching the optional filters.
+func (h *DeadLetterHandlers) Purge(w http.ResponseWriter, r *http.Request) {
+	var before time.Time
+	if v := r.URL.Query().Get("before"); v != "" {
+		var err error
+		if before, err = time.Parse(time.RFC3339, v); err != nil {
+			types.ErrBadRequest("before must be an RFC 3339 timestamp").WriteJSON(w)
+			return
+		}
+	}
+	purged, err := h.store.PurgeFailedNotifications(r.Context(), r.URL.Query().Get("tenant_id"), "", before)
+	if err != nil {
+		slog.Error("purge failed notifications failed", "error", err)
+		types.ErrInternal("failed to purge notifications").WriteJSON(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int64{"purged": purged})
+}
+
+func (h *DeadLetterHandlers) load(w http.ResponseWriter, r *http.Request) (*DeadLetter, bool) {
+	id := chi.URLParam(r, "id")
+	n, err := h.store.GetNotification(r.Context(), id)
+	if err != nil {
+		slog.Error("get notification failed", "error", err, "id", id)
+		types.ErrInternal("failed to get notification").WriteJSON(w)
+		return nil, false
+	}
+	if n == nil {
+		types.ErrNotFound("notification not found").WriteJSON(w)
+		return nil, false
+	}
+	return n, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("response encode failed", "error", err)
+	}
+}
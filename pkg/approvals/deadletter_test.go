@@ -0,0 +1,164 @@
+package approvals
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"github.com/go-chi/chi/v5"
+)
+
+type fakeDeadLetterStore struct {
+	rows        map[string]*DeadLetter
+	purgeTenant string
+	purgeBefore time.Time
+}
+
+func (f *fakeDeadLetterStore) ListFailedNotifications(_ context.Context, tenantID string, limit int, after types.Cursor) ([]DeadLetter, error) {
+	out := []DeadLetter{}
+	for _, n := range f.rows {
+		if n.Status != "failed" || (tenantID != "" && n.TenantID != tenantID) {
+			continue
+		}
+		if !after.IsZero() && !n.UpdatedAt.Before(after.Time) && (!n.UpdatedAt.Equal(after.Time) || n.ID >= after.ID) {
+			continue
+		}
+		out = append(out, *n)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].UpdatedAt.Equal(out[j].UpdatedAt) {
+			return out[i].UpdatedAt.After(out[j].UpdatedAt)
+		}
+		return out[i].ID > out[j].ID
+	})
+	if len(out) > pageLimit(limit) {
+		out = out[:pageLimit(limit)]
+	}
+	return out, nil
+}
+
+func (f *fakeDeadLetterStore) GetNotification(_ context.Context, id string) (*DeadLetter, error) {
+	if n, ok := f.rows[id]; ok {
+		cp := *n
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (f *fakeDeadLetterStore) RequeueNotification(_ context.Context, id string) (bool, error) {
+	n, ok := f.rows[id]
+	if !ok || n.Status != "failed" {
+		return false, nil
+	}
+	n.Status, n.Attempts = "pending", 0
+	return true, nil
+}
+
+func (f *fakeDeadLetterStore) PurgeFailedNotifications(_ context.Context, tenantID, id string, before time.Time) (int64, error) {
+	f.purgeTenant, f.purgeBefore = tenantID, before
+	var n int64
+	for k, row := range f.rows {
+		if row.Status == "failed" && (tenantID == "" || row.TenantID == tenantID) && (id == "" || k == id) {
+			delete(f.rows, k)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func TestDeadLetterHandlers(t *testing.T) {
+	store := &fakeDeadLetterStore{rows: map[string]*DeadLetter{
+		"n1": {ID: "n1", TenantID: "t1", NotifyKind: "webhook", Status: "failed", Attempts: 10, LastError: "max retries exceeded: webhook status=500"},
+		"n2": {ID: "n2", TenantID: "t2", NotifyKind: "slack", Status: "failed", LastError: "slack channel is empty"},
+		"n3": {ID: "n3", TenantID: "t1", NotifyKind: "webhook", Status: "sent"},
+		"n4": {ID: "n4", TenantID: "t1", NotifyKind: "webhook", Status: "failed", UpdatedAt: time.Date(2026, 1, 3, 0, 0, 0, 0, time.UTC)},
+	}}
+	r := chi.NewRouter()
+	NewDeadLetterHandlers(store).RegisterRoutes(r)
+	do := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+
+	list := func(query string) DeadLetterPage {
+		t.Helper()
+		rec := do(http.MethodGet, "/v1/approvals/notifications/failed?"+query)
+		var page DeadLetterPage
+		if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil || rec.Code != http.StatusOK {
+			t.Fatalf("list %s = %d %s", query, rec.Code, rec.Body)
+		}
+		return page
+	}
+	page := list("tenant_id=t1&limit=1")
+	if len(page.Notifications) != 1 || page.Notifications[0].ID != "n4" || page.NextCursor == "" {
+		t.Fatalf("first page = %+v", page)
+	}
+	page = list("tenant_id=t1&limit=1&cursor=" + page.NextCursor)
+	if len(page.Notifications) != 1 || page.Notifications[0].ID != "n1" || page.Notifications[0].LastError == "" {
+		t.Fatalf("second page = %+v", page)
+	}
+	if page = list("tenant_id=t1&limit=1&cursor=" + page.NextCursor); len(page.Notifications) != 0 || page.NextCursor != "" {
+		t.Fatalf("last page = %+v", page)
+	}
+	for _, q := range []string{"offset=10", "cursor=%21%21", "limit=ten"} {
+		if rec := do(http.MethodGet, "/v1/approvals/notifications/failed?"+q); rec.Code != http.StatusBadRequest {
+			t.Fatalf("list %s = %d", q, rec.Code)
+		}
+	}
+
+	if rec := do(http.MethodGet, "/v1/approvals/notifications/nope"); rec.Code != http.StatusNotFound {
+		t.Fatalf("missing = %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/v1/approvals/notifications/n3/requeue"); rec.Code != http.StatusConflict {
+		t.Fatalf("requeue sent row = %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/v1/approvals/notifications/n1/requeue"); rec.Code != http.StatusOK || store.rows["n1"].Status != "pending" {
+		t.Fatalf("requeue = %d, row %+v", rec.Code, store.rows["n1"])
+	}
+
+	if rec := do(http.MethodDelete, "/v1/approvals/notifications/n3"); rec.Code != http.StatusConflict {
+		t.Fatalf("purge sent row = %d", rec.Code)
+	}
+	if rec := do(http.MethodDelete, "/v1/approvals/notifications/failed?before=yesterday"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("bad before = %d", rec.Code)
+	}
+	rec := do(http.MethodDelete, "/v1/approvals/notifications/failed?tenant_id=t2&before=2026-01-02T00:00:00Z")
+	if rec.Code != http.StatusOK || rec.Body.String() != "{\"purged\":1}\n" || store.purgeTenant != "t2" || store.purgeBefore.IsZero() {
+		t.Fatalf("purge = %d %s", rec.Code, rec.Body)
+	}
+	if _, ok := store.rows["n2"]; ok {
+		t.Fatal("n2 should be purged")
+	}
+}
+
+func TestListDeliveries(t *testing.T) {
+	sentAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	store := &fakeHandlersStore{deliveries: []DeadLetter{
+		{ID: "n1", ApprovalRequestID: "r1", NotifyKind: "slack", Status: "sent", Attempts: 1, SentAt: &sentAt},
+		{ID: "n2", ApprovalRequestID: "r1", NotifyKind: "webhook", Status: "pending", Attempts: 3, LastError: "webhook status=503"},
+	}}
+	r := chi.NewRouter()
+	NewHandlers(store, nil).RegisterRoutes(r)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/approvals/requests/r1/deliveries", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("deliveries = %d %s", rec.Code, rec.Body)
+	}
+	var got []map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0]["sent_at"] != "2026-01-02T03:04:05Z" || got[1]["last_error"] != "webhook status=503" || got[1]["attempt_count"] != 3.0 {
+		t.Fatalf("deliveries = %v", got)
+	}
+	if _, ok := got[1]["sent_at"]; ok {
+		t.Fatalf("unsent row should omit sent_at: %v", got[1])
+	}
+}
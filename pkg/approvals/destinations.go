@@ -0,0 +1,112 @@
+package approvals
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	defaultDestinationRate  = 2.0
+	defaultDestinationBurst = 10
+	// destinationFailureThreshold consecutive failures back an endpoint off;
+	// a single error may be specific to one payload.
+	destinationFailureThreshold = 2
+)
+
+// destination is the delivery state shared by every outbox row bound for the
+// same endpoint. A token bucket caps how many of them one tick may send, and
+// repeated failures back the whole endpoint off so its other rows are
+// deferred without a request instead of each timing out in turn.
+type destination struct {
+	limiter      *rate.Limiter
+	failures     int
+	backoffUntil time.Time
+}
+
+// destinations tracks per-endpoint rate limits and backoff for a Dispatcher.
+type destinations struct {
+	mu    sync.Mutex
+	limit rate.Limit
+	burst int
+	byKey map[string]*destination
+}
+
+func newDestinations() *destinations {
+	return &destinations{
+		limit: rate.Limit(defaultDestinationRate),
+		burst: defaultDestinationBurst,
+		byKey: map[string]*destination{},
+	}
+}
+
+// destinationKey identifies the endpoint an item is delivered to: the URL
+// host for webhook and Teams rows, otherwise the kind and tenant (one Slack
+// workspace per tenant). Slack updates share the bucket of the posts.
+func destinationKey(item NotificationOutbox) string {
+	if item.NotifyURL != "" {
+		if u, err := url.Parse(item.NotifyURL); err == nil && u.Host != "" {
+			return strings.ToLower(u.Host)
+		}
+	}
+	kind := strings.TrimSuffix(strings.ToLower(item.NotifyKind), "_update")
+	return kind + ":" + item.TenantID
+}
+
+func (s *destinations) get(key string) *destination {
+	dst, ok := s.byKey[key]
+	if !ok {
+		dst = &destination{limiter: rate.NewLimiter(s.limit, s.burst)}
+		s.byKey[key] = dst
+	}
+	return dst
+}
+
+// setLimit changes the per-destination rate for existing and new endpoints.
+func (s *destinations) setLimit(perSec float64, burst int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limit, s.burst = rate.Limit(perSec), burst
+	for _, dst := range s.byKey {
+		dst.limiter.SetLimit(s.limit)
+		dst.limiter.SetBurst(s.burst)
+	}
+}
+
+// admit reports whether an item for key may be delivered now. If not, it
+// returns when the endpoint will next accept one and why it is held back.
+func (s *destinations) admit(key string, now time.Time) (time.Time, string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dst := s.get(key)
+	if now.Before(dst.backoffUntil) {
+		return dst.backoffUntil, "destination backing off after failure", false
+	}
+	r := dst.limiter.ReserveN(now, 1)
+	if !r.OK() {
+		return now.Add(maxDispatchBackoff), "destination rate limited", false
+	}
+	if delay := r.DelayFrom(now); delay > 0 {
+		r.CancelAt(now)
+		return now.Add(delay), "destination rate limited", false
+	}
+	return time.Time{}, "", true
+}
+
+// record updates the shared backoff for key after a delivery attempt.
+func (s *destinations) record(key string, ok bool, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dst := s.get(key)
+	if ok {
+		dst.failures, dst.backoffUntil = 0, time.Time{}
+		return
+	}
+	dst.failures++
+	if dst.failures >= destinationFailureThreshold {
+		dst.backoffUntil = now.Add(backoffForAttempt(dst.failures - destinationFailureThreshold + 1))
+	}
+}
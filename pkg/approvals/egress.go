@@ -0,0 +1,253 @@
+package approvals
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrRedirectRefused is returned for a webhook delivery answered with a
+// redirect. Following it would reach a destination the egress policy never
+// checked.
+var ErrRedirectRefused = errors.New("webhook redirect refused")
+
+// EgressPolicy decides which destinations webhook deliveries may reach. The
+// zero policy allows any https host whose addresses are all public.
+// AllowedDomains, when set, limits deliveries to those hosts and their
+// subdomains. AllowedCIDRs, when set, is the complete list of address
+// ranges a destination may resolve to, and may name private ranges to allow
+// receivers on an internal network.
+type EgressPolicy struct {
+	AllowedDomains []string `json:"allowed_domains,omitempty"`
+	AllowedCIDRs   []string `json:"allowed_cidrs,omitempty"`
+}
+
+// ParseEgressPolicy builds a policy from comma-separated domain and CIDR
+// lists, as read from the environment.
+func ParseEgressPolicy(domains, cidrs string) (EgressPolicy, error) {
+	var p EgressPolicy
+	for _, d := range strings.Split(domains, ",") {
+		if d = strings.TrimSpace(d); d != "" {
+			p.AllowedDomains = append(p.AllowedDomains, d)
+		}
+	}
+	for _, c := range strings.Split(cidrs, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			p.AllowedCIDRs = append(p.AllowedCIDRs, c)
+		}
+	}
+	if err := p.Validate(); err != nil {
+		return EgressPolicy{}, fmt.Errorf("approvals.ParseEgressPolicy: %w", err)
+	}
+	return p, nil
+}
+
+// Validate checks the domains and CIDRs.
+func (p EgressPolicy) Validate() error {
+	var errs []error
+	for i, d := range p.AllowedDomains {
+		if d == "" || strings.ContainsAny(d, "/:*@ ") || strings.HasPrefix(d, ".") {
+			errs = append(errs, fmt.Errorf("allowed_domains[%d]: %q is not a domain", i, d))
+		}
+	}
+	for i, c := range p.AllowedCIDRs {
+		if _, err := netip.ParsePrefix(c); err != nil {
+			errs = append(errs, fmt.Errorf("allowed_cidrs[%d]: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CheckURL checks a destination before anything is sent to it: https, a
+// host allowed by AllowedDomains, and, for a literal IP, an allowed
+// address. Hostnames are checked again by address when dialed.
+func (p EgressPolicy) CheckURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("only https scheme allowed, got %q", u.Scheme)
+	}
+	host := u.Hostname()
+	if host == "" {
+		return fmt.Errorf("empty hostname")
+	}
+	if ip, err := netip.ParseAddr(host); err == nil {
+		return p.CheckAddr(ip)
+	}
+	if !p.domainAllowed(host) {
+		return fmt.Errorf("host %s is not in the allowed domains", host)
+	}
+	return nil
+}
+
+// blockedPrefixes are non-public ranges the net/netip predicates in
+// CheckAddr do not cover: "this network", carrier-grade NAT, and NAT64,
+// which can translate to any IPv4 address behind the gateway.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+// CheckAddr checks one address a destination resolved to.
+func (p EgressPolicy) CheckAddr(ip netip.Addr) error {
+	ip = ip.Unmap()
+	if len(p.AllowedCIDRs) > 0 {
+		for _, c := range p.AllowedCIDRs {
+			if prefix, err := netip.ParsePrefix(c); err == nil && prefix.Contains(ip) {
+				return nil
+			}
+		}
+		return fmt.Errorf("address %s is not in the allowed CIDRs", ip)
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsUnspecified() || ip.IsMulticast() {
+		return fmt.Errorf("private/loopback IP not allowed: %s", ip)
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(ip) {
+			return fmt.Errorf("private/loopback IP not allowed: %s", ip)
+		}
+	}
+	return nil
+}
+
+func (p EgressPolicy) domainAllowed(host string) bool {
+	if len(p.AllowedDomains) == 0 {
+		return true
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, d := range p.AllowedDomains {
+		d = strings.ToLower(d)
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// EgressPolicies returns a tenant's own egress policy, or nil to use the
+// deployment default.
+type EgressPolicies func(ctx context.Context, tenantID string) (*EgressPolicy, error)
+
+// Egress chooses the policy for a tenant's webhook deliveries: the tenant's
+// own when it has one, otherwise Default. A nil Egress applies the zero
+// policy to every tenant.
+type Egress struct {
+	Default EgressPolicy
+	Tenants EgressPolicies
+}
+
+// Policy returns the policy tenantID's deliveries are held to.
+func (g *Egress) Policy(ctx context.Context, tenantID string) (EgressPolicy, error) {
+	if g == nil {
+		return EgressPolicy{}, nil
+	}
+	if g.Tenants != nil {
+		p, err := g.Tenants(ctx, tenantID)
+		if err != nil {
+			return EgressPolicy{}, fmt.Errorf("approvals.Egress: %w", err)
+		}
+		if p != nil {
+			return *p, nil
+		}
+	}
+	return g.Default, nil
+}
+
+type egressPolicyKey struct{}
+
+// WithEgressPolicy makes an egress client hold requests made with the
+// returned context to p.
+func WithEgressPolicy(ctx context.Context, p EgressPolicy) context.Context {
+	return context.WithValue(ctx, egressPolicyKey{}, p)
+}
+
+func egressPolicyFrom(ctx context.Context) (EgressPolicy, bool) {
+	p, ok := ctx.Value(egressPolicyKey{}).(EgressPolicy)
+	return p, ok
+}
+
+// NewEgressClient returns the client webhook deliveries are sent with.
+// For requests whose context carries a policy (see WithEgressPolicy) it
+// resolves the host when dialing, refuses the connection unless every
+// address is allowed, and connects only to the addresses it checked, so a DNS
+// answer that changes after CheckURL cannot redirect the delivery.
+// Redirects are refused, connections are not reused across requests, and
+// proxies from the environment are not used. Requests without a policy
+// are sent unchecked.
+func NewEgressClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			p, ok := egressPolicyFrom(ctx)
+			if !ok {
+				return dialer.DialContext(ctx, network, addr)
+			}
+			pinned, err := resolvePinned(ctx, p, addr)
+			if err != nil {
+				return nil, err
+			}
+			var conn net.Conn
+			for _, a := range pinned {
+				if conn, err = dialer.DialContext(ctx, network, a); err == nil {
+					return conn, nil
+				}
+			}
+			return nil, err
+		},
+		ForceAttemptHTTP2:   true,
+		DisableKeepAlives:   true,
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if _, ok := egressPolicyFrom(req.Context()); ok {
+				return ErrRedirectRefused
+			}
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return nil
+		},
+	}
+}
+
+// resolvePinned resolves addr's host, checks every address against p, and
+// returns them as host:port to dial in turn.
+func resolvePinned(ctx context.Context, p EgressPolicy, addr string) ([]string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil, err
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("no addresses for %s", host)
+	}
+	out := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		if err := p.CheckAddr(ip); err != nil {
+			return nil, fmt.Errorf("egress to %s refused: %w", host, err)
+		}
+		out = append(out, net.JoinHostPort(ip.Unmap().String(), port))
+	}
+	return out, nil
+}
+
+// ValidateWebhookURL checks rawURL against the zero egress policy.
+func ValidateWebhookURL(rawURL string) error {
+	return EgressPolicy{}.CheckURL(rawURL)
+}
@@ -0,0 +1,114 @@
+package approvals
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEgressPolicyCheckURL(t *testing.T) {
+	restricted := EgressPolicy{AllowedDomains: []string{"hooks.acme.io"}, AllowedCIDRs: []string{"10.20.0.0/16"}}
+	for _, tc := range []struct {
+		policy EgressPolicy
+		url    string
+		ok     bool
+	}{
+		{EgressPolicy{}, "https://hooks.example.com/x", true},
+		{EgressPolicy{}, "http://hooks.example.com/x", false},
+		{EgressPolicy{}, "https://127.0.0.1/x", false},
+		{EgressPolicy{}, "https://10.20.1.2/x", false},
+		{EgressPolicy{}, "https://[::ffff:169.254.169.254]/x", false},
+		{EgressPolicy{}, "https://0.0.0.0/x", false},
+		{EgressPolicy{}, "https://0.1.2.3/x", false},
+		{EgressPolicy{}, "https://100.64.0.1/x", false},
+		{EgressPolicy{}, "https://100.127.255.254/x", false},
+		{EgressPolicy{}, "https://100.128.0.1/x", true},
+		{EgressPolicy{}, "https://[64:ff9b::a9fe:a9fe]/x", false},
+		{EgressPolicy{}, "https://8.8.8.8/x", true},
+		{restricted, "https://hooks.acme.io/x", true},
+		{restricted, "https://eu.hooks.acme.io/x", true},
+		{restricted, "https://evilhooks.acme.io/x", false},
+		{restricted, "https://hooks.example.com/x", false},
+		{restricted, "https://10.20.1.2/x", true},
+		{restricted, "https://8.8.8.8/x", false},
+	} {
+		if err := tc.policy.CheckURL(tc.url); (err == nil) != tc.ok {
+			t.Errorf("%+v CheckURL(%s) = %v", tc.policy, tc.url, err)
+		}
+	}
+}
+
+func TestParseEgressPolicy(t *testing.T) {
+	p, err := ParseEgressPolicy(" hooks.acme.io, ,acme.net", "10.0.0.0/8")
+	if err != nil || len(p.AllowedDomains) != 2 || p.AllowedDomains[1] != "acme.net" || len(p.AllowedCIDRs) != 1 {
+		t.Fatalf("ParseEgressPolicy = %+v, %v", p, err)
+	}
+	for _, bad := range [][2]string{{"*.acme.io", ""}, {"https://acme.io", ""}, {"", "10.0.0.0/33"}, {"", "10.0.0.1"}} {
+		if _, err := ParseEgressPolicy(bad[0], bad[1]); err == nil {
+			t.Errorf("ParseEgressPolicy(%q, %q) should fail", bad[0], bad[1])
+		}
+	}
+}
+
+func TestEgressPolicyFallsBackToDefault(t *testing.T) {
+	g := &Egress{
+		Default: EgressPolicy{AllowedDomains: []string{"acme.io"}},
+		Tenants: func(_ context.Context, tenantID string) (*EgressPolicy, error) {
+			if tenantID == "own" {
+				return &EgressPolicy{AllowedCIDRs: []string{"10.0.0.0/8"}}, nil
+			}
+			return nil, nil
+		},
+	}
+	if p, _ := g.Policy(context.Background(), "own"); len(p.AllowedCIDRs) != 1 || len(p.AllowedDomains) != 0 {
+		t.Fatalf("own policy = %+v", p)
+	}
+	if p, _ := g.Policy(context.Background(), "other"); len(p.AllowedDomains) != 1 {
+		t.Fatalf("default policy = %+v", p)
+	}
+	if p, err := (*Egress)(nil).Policy(context.Background(), "any"); err != nil || len(p.AllowedDomains) != 0 {
+		t.Fatalf("nil Egress = %+v, %v", p, err)
+	}
+}
+
+func TestEgressClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/ok", http.StatusFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+	client := NewEgressClient(0)
+	do := func(ctx context.Context, url string) (*http.Response, error) {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
+		return client.Do(req)
+	}
+	// The test server's URL names 127.0.0.1; localhost has to be resolved,
+	// and is checked by the address it resolves to.
+	localhost := strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)
+
+	loopback := WithEgressPolicy(context.Background(), EgressPolicy{AllowedCIDRs: []string{"127.0.0.0/8", "::1/128"}})
+	resp, err := do(loopback, localhost+"/ok")
+	if err != nil {
+		t.Fatalf("allowed destination: %v", err)
+	}
+	resp.Body.Close()
+
+	if _, err := do(WithEgressPolicy(context.Background(), EgressPolicy{}), localhost+"/ok"); err == nil || !strings.Contains(err.Error(), "egress to localhost refused") {
+		t.Fatalf("loopback under the zero policy = %v", err)
+	}
+	if _, err := do(loopback, srv.URL+"/redirect"); !errors.Is(err, ErrRedirectRefused) {
+		t.Fatalf("redirect = %v", err)
+	}
+	// Requests without a policy, such as connector calls, are not checked.
+	resp, err = do(context.Background(), srv.URL+"/redirect")
+	if err != nil || resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("unchecked request = %v, %v", resp, err)
+	}
+	resp.Body.Close()
+}
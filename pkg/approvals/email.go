@@ -0,0 +1,169 @@
+package approvals
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+// EmailConfig is the SMTP relay used by the email provider.
+type EmailConfig struct {
+	Addr     string // host:port
+	From     string
+	Username string
+	Password string
+}
+
+// emailProvider sends a plain-text approval email. Recipients come from the
+// route's config["to"] (comma-separated); config["from"] overrides the
+// sender per tenant.
+type emailProvider struct {
+	cfg        EmailConfig
+	summarizer Summarizer
+}
+
+// NewEmailProvider returns the email provider for RegisterProvider("email", …).
+func NewEmailProvider(cfg EmailConfig) NotificationProvider {
+	return emailProvider{cfg: cfg, summarizer: TemplateSummarizer{}}
+}
+
+func (emailProvider) ValidateRoute(route types.PolicyNotify) error {
+	if len(emailRecipients(route.Config)) == 0 {
+		return errors.New(`email requires config.to`)
+	}
+	return nil
+}
+
+func emailRecipients(cfg map[string]string) []string {
+	var out []string
+	for _, to := range strings.Split(cfg["to"], ",") {
+		if to = strings.TrimSpace(to); to != "" {
+			out = append(out, to)
+		}
+	}
+	return out
+}
+
+// addresses returns the sender and recipients for a route's config.
+func (p emailProvider) addresses(cfg map[string]string) (string, []string, error) {
+	to := emailRecipients(cfg)
+	if len(to) == 0 {
+		return "", nil, Permanent(errors.New("email recipients are empty"))
+	}
+	from := cfg["from"]
+	if from == "" {
+		from = p.cfg.From
+	}
+	if p.cfg.Addr == "" || from == "" {
+		return "", nil, Permanent(errors.New("email provider is not configured"))
+	}
+	for _, addr := range append([]string{from}, to...) {
+		if strings.ContainsAny(addr, "\r\n") {
+			return "", nil, Permanent(fmt.Errorf("invalid email address %q", addr))
+		}
+	}
+	return from, to, nil
+}
+
+// writeHeader writes the message header and the blank line ending it.
+func writeHeader(body *strings.Builder, from string, to []string, subject, id string) {
+	fmt.Fprintf(body, "From: %s\r\n", from)
+	fmt.Fprintf(body, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(body, "Subject: %s\r\n", strings.NewReplacer("\r", " ", "\n", " ").Replace(subject))
+	fmt.Fprintf(body, "Message-ID: <%s@openclause>\r\n", id)
+	body.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n")
+}
+
+func (p emailProvider) Deliver(ctx context.Context, item NotificationOutbox) (Delivery, error) {
+	from, to, err := p.addresses(item.NotifyConfig)
+	if err != nil {
+		return Delivery{}, err
+	}
+
+	subject := fmt.Sprintf("[OpenClause] Approval needed: %s.%s (%s)", item.Tool, item.Action, item.TenantID)
+	var body strings.Builder
+	writeHeader(&body, from, to, subject, item.ID)
+	fmt.Fprintf(&body, "%s\r\n\r\nResource: %s\r\nRisk: %d\r\nApprover group: %s\r\n\r\n",
+		p.summarizer.Summarize(item), item.Resource, item.RiskScore, item.ApproverGroup)
+	if lines := PlanLines(item.Plan); lines != nil {
+		fmt.Fprintf(&body, "%s\r\n\r\n", strings.Join(lines, "\r\n"))
+	}
+	if lines := ActivityLines(item.RecentActivity); lines != nil {
+		fmt.Fprintf(&body, "%s\r\n\r\n", strings.Join(lines, "\r\n"))
+	}
+	fmt.Fprintf(&body, "Review: %s\r\n", item.ApprovalURL)
+
+	return Delivery{}, p.send(ctx, from, to, []byte(body.String()))
+}
+
+// DeliverDigest emails a compliance digest to the route's recipients.
+func (p emailProvider) DeliverDigest(ctx context.Context, route types.PolicyNotify, m DigestMessage) error {
+	from, to, err := p.addresses(route.Config)
+	if err != nil {
+		return err
+	}
+	var body strings.Builder
+	writeHeader(&body, from, to, m.Subject, m.ID)
+	for _, l := range m.Text {
+		body.WriteString(strings.NewReplacer("\r", " ", "\n", " ").Replace(l))
+		body.WriteString("\r\n")
+	}
+	return p.send(ctx, from, to, []byte(body.String()))
+}
+
+// send is smtp.SendMail with a context-bound connection.
+func (p emailProvider) send(ctx context.Context, from string, to []string, msg []byte) error {
+	host, _, err := net.SplitHostPort(p.cfg.Addr)
+	if err != nil {
+		return Permanent(fmt.Errorf("smtp addr: %w", err))
+	}
+	conn, err := (&net.Dialer{Timeout: 10 * time.Second}).DialContext(ctx, "tcp", p.cfg.Addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}); err != nil {
+			return err
+		}
+	}
+	if p.cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", p.cfg.Username, p.cfg.Password, host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := c.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
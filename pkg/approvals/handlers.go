@@ -7,14 +7,17 @@ import (
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/bturcanu/OpenClause/pkg/oidc"
 	"github.com/bturcanu/OpenClause/pkg/types"
 	"github.com/go-chi/chi/v5"
 )
@@ -23,25 +26,120 @@ const maxBodyBytes = 1 << 20 // 1 MB
 
 // Handlers groups the HTTP handlers for the approvals service.
 type Handlers struct {
-	store              handlersStore
-	authorizer         *ApproverAuthorizer
-	slackSigningSecret string
+	store               handlersStore
+	authorizer          Authorizer
+	slackSigningSecrets []string
+	sinks               []ResolutionSink
+	requestSinks        []RequestSink
+	commentSinks        []CommentSink
+	defaults            InputDefaults
+	autoApproval        AutoApproval
+	grantDefaults       GrantDefaults
+	requireVerified     bool
 }
 
+// InputDefaults fills unset fields of a new approval request, typically
+// from tenant settings.
+type InputDefaults func(context.Context, *CreateApprovalInput) error
+
+// GrantDefaults fills unset fields of a grant on req, typically from tenant
+// settings.
+type GrantDefaults func(ctx context.Context, req ApprovalRequest, in *GrantInput) error
+
 type handlersStore interface {
 	CreateRequest(context.Context, CreateApprovalInput) (*ApprovalRequest, error)
 	GetRequest(context.Context, string) (*ApprovalRequest, error)
 	GrantRequest(context.Context, string, GrantInput) (*ApprovalGrant, error)
 	DenyRequest(context.Context, string, DenyInput) error
-	ListPending(context.Context, string, int, int) ([]ApprovalRequest, error)
+	ReleaseRequest(context.Context, string, ReleaseInput) error
+	CancelRequest(context.Context, string, CancelInput) error
+	ListPending(context.Context, string, int, types.Cursor) ([]ApprovalRequest, error)
+	ListRequestNotifications(context.Context, string) ([]DeadLetter, error)
+	AddComment(context.Context, Comment) (*Comment, error)
+	ListComments(context.Context, []string) ([]Comment, error)
+	GetGrant(context.Context, string) (*ApprovalGrant, error)
+	ListGrantUsages(context.Context, string, int, types.Cursor) ([]GrantUsage, error)
+	pendingCounter
 }
 
-// NewHandlers creates handlers backed by the given store.
-func NewHandlers(store handlersStore, authorizer *ApproverAuthorizer, slackSigningSecret string) *Handlers {
+// NewHandlers creates handlers backed by the given store. Slack requests are
+// accepted when signed with any of slackSigningSecrets, so the previous
+// secret keeps working while a rotation rolls out. A nil authorizer lets any
+// approver resolve requests.
+func NewHandlers(store handlersStore, authorizer Authorizer, slackSigningSecrets ...string) *Handlers {
 	return &Handlers{
-		store:              store,
-		authorizer:         authorizer,
-		slackSigningSecret: slackSigningSecret,
+		store:               store,
+		authorizer:          authorizer,
+		slackSigningSecrets: slackSigningSecrets,
+	}
+}
+
+// SetInputDefaults registers fn to complete new approval requests. It must
+// be called before the handlers start serving.
+func (h *Handlers) SetInputDefaults(fn InputDefaults) {
+	h.defaults = fn
+}
+
+// SetRequireVerifiedApprover makes approving and denying require a
+// signed-in approver (see package oidc) instead of trusting the approver
+// named in the request body. It must be called before the handlers start
+// serving.
+func (h *Handlers) SetRequireVerifiedApprover(required bool) {
+	h.requireVerified = required
+}
+
+// SetGrantDefaults registers fn to complete grants before they are stored.
+// It must be called before the handlers start serving.
+func (h *Handlers) SetGrantDefaults(fn GrantDefaults) {
+	h.grantDefaults = fn
+}
+
+// grant applies the grant defaults to in and grants req. A lifetime the
+// approver left unset comes from the request's policy decision first.
+func (h *Handlers) grant(ctx context.Context, req *ApprovalRequest, in GrantInput) (*ApprovalGrant, error) {
+	if in.ExpiresInSec <= 0 {
+		in.ExpiresInSec = req.GrantTTLSec
+	}
+	if h.grantDefaults != nil {
+		if err := h.grantDefaults(ctx, *req, &in); err != nil {
+			return nil, err
+		}
+	}
+	return h.store.GrantRequest(ctx, req.ID, in)
+}
+
+// AddResolutionSink registers a sink notified after each approve/deny.
+// It must be called before the handlers start serving.
+func (h *Handlers) AddResolutionSink(s ResolutionSink) {
+	h.sinks = append(h.sinks, s)
+}
+
+// AddRequestSink registers a sink notified after each request is created.
+// It must be called before the handlers start serving.
+func (h *Handlers) AddRequestSink(s RequestSink) {
+	h.requestSinks = append(h.requestSinks, s)
+}
+
+// PublishExpired reports requests expired by the store's ExpireRequests to
+// the resolution sinks.
+func (h *Handlers) PublishExpired(ctx context.Context, ids []string) {
+	if len(h.sinks) == 0 {
+		return
+	}
+	for _, id := range ids {
+		req, err := h.store.GetRequest(ctx, id)
+		if err != nil || req == nil {
+			slog.Error("load expired approval request failed", "id", id, "error", err)
+			continue
+		}
+		h.publishResolution(ctx, req, "expired", "", "approval request expired")
+	}
+}
+
+func (h *Handlers) publishResolution(ctx context.Context, req *ApprovalRequest, status, approver, reason string) {
+	res := Resolution{Request: *req, Status: status, Approver: approver, Reason: reason, At: time.Now().UTC()}
+	for _, s := range h.sinks {
+		s.PublishResolution(ctx, res)
 	}
 }
 
@@ -52,8 +150,14 @@ func NewHandlers(store handlersStore, authorizer *ApproverAuthorizer, slackSigni
 func (h *Handlers) RegisterRoutes(r chi.Router) {
 	r.Post("/v1/approvals/requests", h.CreateRequest)
 	r.Get("/v1/approvals/requests/{id}", h.GetRequest)
+	r.Get("/v1/approvals/requests/{id}/deliveries", h.ListDeliveries)
 	r.Post("/v1/approvals/requests/{id}/approve", h.ApproveRequest)
 	r.Post("/v1/approvals/requests/{id}/deny", h.DenyRequest)
+	r.Post("/v1/approvals/requests/{id}/release", h.ReleaseRequest)
+	r.Post("/v1/approvals/requests/{id}/cancel", h.CancelRequest)
+	r.Get("/v1/approvals/requests/{id}/comments", h.ListComments)
+	r.Post("/v1/approvals/requests/{id}/comments", h.CreateComment)
+	r.Get("/v1/approvals/grants/{id}/usages", h.ListGrantUsages)
 	r.Get("/v1/approvals/pending", h.ListPending)
 }
 
@@ -70,6 +174,11 @@ func (h *Handlers) CreateRequest(w http.ResponseWriter, r *http.Request) {
 		types.ErrBadRequest("tenant_id, event_id, tool, and action are required").WriteJSON(w)
 		return
 	}
+	if h.defaults != nil {
+		if err := h.defaults(r.Context(), &in); err != nil {
+			slog.Error("apply tenant approval defaults failed", "tenant_id", in.TenantID, "error", err)
+		}
+	}
 
 	req, err := h.store.CreateRequest(r.Context(), in)
 	if err != nil {
@@ -77,6 +186,10 @@ func (h *Handlers) CreateRequest(w http.ResponseWriter, r *http.Request) {
 		types.ErrInternal("failed to create approval request").WriteJSON(w)
 		return
 	}
+	for _, s := range h.requestSinks {
+		s.PublishRequest(r.Context(), *req)
+	}
+	h.autoApprove(r.Context(), req)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
@@ -105,9 +218,32 @@ func (h *Handlers) GetRequest(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// ListDeliveries handles GET /v1/approvals/requests/{id}/deliveries. It
+// returns the request's notification outbox rows so approvers can see
+// whether anyone was actually notified.
+func (h *Handlers) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	req, err := h.store.GetRequest(r.Context(), id)
+	if err != nil {
+		slog.Error("get approval request failed", "error", err)
+		types.ErrInternal("failed to retrieve approval request").WriteJSON(w)
+		return
+	}
+	if req == nil {
+		types.ErrNotFound("approval request not found").WriteJSON(w)
+		return
+	}
+	items, err := h.store.ListRequestNotifications(r.Context(), id)
+	if err != nil {
+		slog.Error("list request notifications failed", "error", err, "id", id)
+		types.ErrInternal("failed to list deliveries").WriteJSON(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
 // ApproveRequest handles POST /v1/approvals/requests/{id}/approve
 func (h *Handlers) ApproveRequest(w http.ResponseWriter, r *http.Request) {
-	id := chi.URLParam(r, "id")
 	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
 	var in GrantInput
 	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
@@ -115,81 +251,228 @@ func (h *Handlers) ApproveRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if in.Approver == "" {
-		types.ErrBadRequest("approver is required").WriteJSON(w)
+	grant, apiErr := h.Approve(r.Context(), chi.URLParam(r, "id"), in)
+	if apiErr != nil {
+		apiErr.WriteJSON(w)
 		return
 	}
 
-	req, err := h.store.GetRequest(r.Context(), id)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(grant); err != nil {
+		slog.Error("response encode failed", "error", err)
+	}
+}
+
+// Approve grants request id for the approver in in, or for the signed-in
+// approver in ctx, and publishes the resolution. The approve endpoint and
+// the approvals UI share it.
+func (h *Handlers) Approve(ctx context.Context, id string, in GrantInput) (*ApprovalGrant, *types.APIError) {
+	approver, identity, apiErr := h.verifiedApprover(ctx, in.Approver)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	in.Approver = approver
+	if identity != nil {
+		in.ApproverIssuer, in.ApproverSubject = identity.Issuer, identity.Subject
+	}
+
+	req, err := h.store.GetRequest(ctx, id)
 	if err != nil {
 		slog.Error("get approval request failed", "error", err)
-		types.ErrInternal("failed to approve request").WriteJSON(w)
-		return
+		return nil, types.ErrInternal("failed to approve request")
 	}
 	if req == nil {
-		types.ErrNotFound("approval request not found").WriteJSON(w)
-		return
+		return nil, types.ErrNotFound("approval request not found")
 	}
-	if h.authorizer != nil && !h.authorizer.AllowEmail(req.TenantID, in.Approver) {
-		types.ErrForbidden("approver is not allowed for tenant").WriteJSON(w)
-		return
+	if req.IsQuarantine() {
+		return nil, types.ErrConflict("quarantine requests are released, not approved")
+	}
+	if h.authorizer != nil && !h.authorizer.AllowEmail(req.TenantID, req.ApproverGroup, in.Approver) {
+		return nil, types.ErrForbidden("approver is not allowed for tenant")
+	}
+	if in.SessionScope && req.SessionID == "" {
+		return nil, types.ErrValidation(ErrNoSession)
+	}
+	if err := types.ValidateResourcePattern(in.ResourcePattern); err != nil {
+		return nil, types.ErrValidation(&types.ValidationError{Field: "resource_pattern", Reason: err.Error()})
+	}
+	if in.ValidHours != nil {
+		if err := in.ValidHours.Validate(); err != nil {
+			return nil, types.ErrValidation(&types.ValidationError{Field: "valid_hours", Reason: err.Error()})
+		}
 	}
 
-	grant, err := h.store.GrantRequest(r.Context(), id, in)
+	grant, err := h.grant(ctx, req, in)
 	if err != nil {
 		slog.Error("approve request failed", "error", err)
-		types.ErrInternal("failed to approve request").WriteJSON(w)
+		return nil, types.ErrInternal("failed to approve request")
+	}
+	h.publishResolution(ctx, req, "approved", in.Approver, "")
+	return grant, nil
+}
+
+// DenyRequest handles POST /v1/approvals/requests/{id}/deny
+func (h *Handlers) DenyRequest(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	var in DenyInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		types.ErrBadRequest("invalid JSON body").WriteJSON(w)
+		return
+	}
+
+	if apiErr := h.Deny(r.Context(), chi.URLParam(r, "id"), in); apiErr != nil {
+		apiErr.WriteJSON(w)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	if err := json.NewEncoder(w).Encode(grant); err != nil {
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]string{"status": "denied"}); err != nil {
 		slog.Error("response encode failed", "error", err)
 	}
 }
 
-// DenyRequest handles POST /v1/approvals/requests/{id}/deny
-func (h *Handlers) DenyRequest(w http.ResponseWriter, r *http.Request) {
-	id := chi.URLParam(r, "id")
+// Deny denies request id for the approver in in, or for the signed-in
+// approver in ctx, and publishes the resolution.
+func (h *Handlers) Deny(ctx context.Context, id string, in DenyInput) *types.APIError {
+	approver, _, apiErr := h.verifiedApprover(ctx, in.Approver)
+	if apiErr != nil {
+		return apiErr
+	}
+	in.Approver = approver
+
+	req, err := h.store.GetRequest(ctx, id)
+	if err != nil {
+		slog.Error("get approval request failed", "error", err)
+		return types.ErrInternal("failed to deny request")
+	}
+	if req == nil {
+		return types.ErrNotFound("approval request not found")
+	}
+	if h.authorizer != nil && !h.authorizer.AllowEmail(req.TenantID, req.ApproverGroup, in.Approver) {
+		return types.ErrForbidden("approver is not allowed for tenant")
+	}
+
+	if err := h.store.DenyRequest(ctx, id, in); err != nil {
+		slog.Error("deny request failed", "error", err)
+		return types.ErrInternal("failed to deny request")
+	}
+	h.publishResolution(ctx, req, "denied", in.Approver, in.Reason)
+	return nil
+}
+
+// ReleaseRequest handles POST /v1/approvals/requests/{id}/release
+func (h *Handlers) ReleaseRequest(w http.ResponseWriter, r *http.Request) {
 	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
-	var in DenyInput
+	var in ReleaseInput
 	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
 		types.ErrBadRequest("invalid JSON body").WriteJSON(w)
 		return
 	}
 
-	if in.Approver == "" {
-		types.ErrBadRequest("approver is required").WriteJSON(w)
+	if apiErr := h.Release(r.Context(), chi.URLParam(r, "id"), in); apiErr != nil {
+		apiErr.WriteJSON(w)
 		return
 	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "released"})
+}
 
-	req, err := h.store.GetRequest(r.Context(), id)
+// Release releases the output withheld by quarantine request id to the
+// agent, for the approver in in or the signed-in approver in ctx, and
+// publishes the resolution.
+func (h *Handlers) Release(ctx context.Context, id string, in ReleaseInput) *types.APIError {
+	approver, _, apiErr := h.verifiedApprover(ctx, in.Approver)
+	if apiErr != nil {
+		return apiErr
+	}
+	in.Approver = approver
+
+	req, err := h.store.GetRequest(ctx, id)
 	if err != nil {
 		slog.Error("get approval request failed", "error", err)
-		types.ErrInternal("failed to deny request").WriteJSON(w)
-		return
+		return types.ErrInternal("failed to release request")
 	}
 	if req == nil {
-		types.ErrNotFound("approval request not found").WriteJSON(w)
+		return types.ErrNotFound("approval request not found")
+	}
+	if !req.IsQuarantine() {
+		return types.ErrConflict("only quarantine requests can be released")
+	}
+	if h.authorizer != nil && !h.authorizer.AllowEmail(req.TenantID, req.ApproverGroup, in.Approver) {
+		return types.ErrForbidden("approver is not allowed for tenant")
+	}
+
+	if err := h.store.ReleaseRequest(ctx, id, in); err != nil {
+		slog.Error("release request failed", "error", err)
+		return types.ErrInternal("failed to release request")
+	}
+	h.publishResolution(ctx, req, "released", in.Approver, "")
+	return nil
+}
+
+// CancelRequest handles POST /v1/approvals/requests/{id}/cancel
+func (h *Handlers) CancelRequest(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	var in CancelInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		types.ErrBadRequest("invalid JSON body").WriteJSON(w)
 		return
 	}
-	if h.authorizer != nil && !h.authorizer.AllowEmail(req.TenantID, in.Approver) {
-		types.ErrForbidden("approver is not allowed for tenant").WriteJSON(w)
+	if in.TenantID == "" {
+		types.ErrBadRequest("tenant_id is required").WriteJSON(w)
 		return
 	}
 
-	if err := h.store.DenyRequest(r.Context(), id, in); err != nil {
-		slog.Error("deny request failed", "error", err)
-		types.ErrInternal("failed to deny request").WriteJSON(w)
+	if apiErr := h.Cancel(r.Context(), chi.URLParam(r, "id"), in); apiErr != nil {
+		apiErr.WriteJSON(w)
 		return
 	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "cancelled"})
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(map[string]string{"status": "denied"}); err != nil {
-		slog.Error("response encode failed", "error", err)
+// Cancel withdraws pending request id for the agent that made it, queues
+// the rewrite of its Slack messages and publishes the resolution.
+func (h *Handlers) Cancel(ctx context.Context, id string, in CancelInput) *types.APIError {
+	req, err := h.store.GetRequest(ctx, id)
+	if err != nil {
+		slog.Error("get approval request failed", "error", err)
+		return types.ErrInternal("failed to cancel request")
+	}
+	if apiErr := CheckCancel(req, in); apiErr != nil {
+		return apiErr
 	}
+
+	if err := h.store.CancelRequest(ctx, id, in); errors.Is(err, ErrNotPending) {
+		return types.ErrConflict("approval request is no longer pending")
+	} else if err != nil {
+		slog.Error("cancel request failed", "error", err)
+		return types.ErrInternal("failed to cancel request")
+	}
+	h.publishResolution(ctx, req, "cancelled", "", in.Reason)
+	return nil
+}
+
+// verifiedApprover returns the approver to record: the signed-in
+// approver's identity when ctx carries one, otherwise the name the caller
+// gave, which is refused when verified approvers are required. A caller
+// naming someone other than the signed-in approver is refused.
+func (h *Handlers) verifiedApprover(ctx context.Context, named string) (string, *oidc.Identity, *types.APIError) {
+	s, ok := oidc.FromContext(ctx)
+	if !ok {
+		if h.requireVerified {
+			return "", nil, types.ErrUnauthorized("approver must sign in")
+		}
+		if named == "" {
+			return "", nil, types.ErrBadRequest("approver is required")
+		}
+		return named, nil, nil
+	}
+	approver := s.Approver()
+	if named != "" && !strings.EqualFold(named, approver) {
+		return "", nil, types.ErrForbidden("approver does not match the signed-in identity")
+	}
+	return approver, &s.Identity, nil
 }
 
 // SlackInteractions handles POST /v1/integrations/slack/interactions.
@@ -199,7 +482,7 @@ func (h *Handlers) SlackInteractions(w http.ResponseWriter, r *http.Request) {
 		types.ErrBadRequest("invalid request body").WriteJSON(w)
 		return
 	}
-	if !VerifySlackRequest(rawBody, r.Header.Get("X-Slack-Signature"), r.Header.Get("X-Slack-Request-Timestamp"), h.slackSigningSecret, time.Now()) {
+	if !VerifySlackRequest(rawBody, r.Header.Get("X-Slack-Signature"), r.Header.Get("X-Slack-Request-Timestamp"), h.slackSigningSecrets, time.Now()) {
 		types.ErrUnauthorized("invalid slack signature").WriteJSON(w)
 		return
 	}
@@ -265,17 +548,23 @@ func (h *Handlers) SlackInteractions(w http.ResponseWriter, r *http.Request) {
 		types.ErrBadRequest("interaction event mismatch").WriteJSON(w)
 		return
 	}
-	if h.authorizer != nil && !h.authorizer.AllowSlack(req.TenantID, in.User.ID) {
+	if h.authorizer != nil && !h.authorizer.AllowSlack(req.TenantID, req.ApproverGroup, in.User.ID) {
 		types.ErrForbidden("slack user is not allowed for tenant").WriteJSON(w)
 		return
 	}
 
 	approver := "slack:" + in.User.ID
-	switch decision {
-	case "approve":
-		_, err = h.store.GrantRequest(r.Context(), requestID, GrantInput{Approver: approver, MaxUses: 1})
-	case "deny":
-		err = h.store.DenyRequest(r.Context(), requestID, DenyInput{Approver: approver, Reason: "denied from Slack"})
+	var status, reason string
+	switch {
+	case decision == "approve" && req.IsQuarantine():
+		status = "released"
+		err = h.store.ReleaseRequest(r.Context(), requestID, ReleaseInput{Approver: approver})
+	case decision == "approve":
+		status = "approved"
+		_, err = h.grant(r.Context(), req, GrantInput{Approver: approver, MaxUses: 1})
+	case decision == "deny":
+		status, reason = "denied", "denied from Slack"
+		err = h.store.DenyRequest(r.Context(), requestID, DenyInput{Approver: approver, Reason: reason})
 	default:
 		types.ErrBadRequest("unknown action").WriteJSON(w)
 		return
@@ -285,6 +574,7 @@ func (h *Handlers) SlackInteractions(w http.ResponseWriter, r *http.Request) {
 		types.ErrInternal("failed to process interaction").WriteJSON(w)
 		return
 	}
+	h.publishResolution(r.Context(), req, status, approver, reason)
 
 	username := in.User.Username
 	if username == "" {
@@ -294,9 +584,12 @@ func (h *Handlers) SlackInteractions(w http.ResponseWriter, r *http.Request) {
 		username = in.User.ID
 	}
 	verb := "Processed"
-	if decision == "approve" {
+	switch status {
+	case "approved":
 		verb = "Approved"
-	} else if decision == "deny" {
+	case "released":
+		verb = "Released"
+	case "denied":
 		verb = "Denied"
 	}
 	text := fmt.Sprintf("%s by @%s", verb, username)
@@ -309,8 +602,11 @@ func (h *Handlers) SlackInteractions(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func VerifySlackRequest(rawBody []byte, signatureHeader, timestampHeader, secret string, now time.Time) bool {
-	if secret == "" || signatureHeader == "" || timestampHeader == "" {
+// VerifySlackRequest checks a Slack v0 request signature against each of
+// secrets (current first, then previous ones during rotation). Empty secrets
+// are ignored; with none configured every request is rejected.
+func VerifySlackRequest(rawBody []byte, signatureHeader, timestampHeader string, secrets []string, now time.Time) bool {
+	if signatureHeader == "" || timestampHeader == "" {
 		return false
 	}
 	ts, err := strconv.ParseInt(timestampHeader, 10, 64)
@@ -323,47 +619,45 @@ func VerifySlackRequest(rawBody []byte, signatureHeader, timestampHeader, secret
 	}
 
 	base := "v0:" + timestampHeader + ":" + string(rawBody)
-	mac := hmac.New(sha256.New, []byte(secret))
-	_, _ = mac.Write([]byte(base))
-	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
-	return hmac.Equal([]byte(expected), []byte(signatureHeader))
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+		mac := hmac.New(sha256.New, []byte(secret))
+		_, _ = mac.Write([]byte(base))
+		expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+		if hmac.Equal([]byte(expected), []byte(signatureHeader)) {
+			return true
+		}
+	}
+	return false
 }
 
-// ListPending handles GET /v1/approvals/pending?tenant_id=...&limit=...&offset=...
+// ListPending handles GET /v1/approvals/pending?tenant_id=...&limit=...&cursor=...
 func (h *Handlers) ListPending(w http.ResponseWriter, r *http.Request) {
 	tenantID := r.URL.Query().Get("tenant_id")
 	if tenantID == "" {
 		types.ErrBadRequest("tenant_id query param required").WriteJSON(w)
 		return
 	}
-
-	var limit, offset int
-	if v := r.URL.Query().Get("limit"); v != "" {
-		var err error
-		limit, err = strconv.Atoi(v)
-		if err != nil {
-			types.ErrBadRequest("invalid limit parameter").WriteJSON(w)
-			return
-		}
-	}
-	if v := r.URL.Query().Get("offset"); v != "" {
-		var err error
-		offset, err = strconv.Atoi(v)
-		if err != nil {
-			types.ErrBadRequest("invalid offset parameter").WriteJSON(w)
-			return
-		}
+	limit, after, ok := parsePage(w, r)
+	if !ok {
+		return
 	}
 
-	reqs, err := h.store.ListPending(r.Context(), tenantID, limit, offset)
+	reqs, err := h.store.ListPending(r.Context(), tenantID, limit, after)
 	if err != nil {
 		slog.Error("list pending failed", "error", err)
 		types.ErrInternal("failed to list pending requests").WriteJSON(w)
 		return
 	}
 
+	page := PendingPage{Requests: reqs}
+	if n := len(reqs); n > 0 {
+		page.NextCursor = nextCursor(n, limit, types.Cursor{Time: reqs[n-1].CreatedAt, ID: reqs[n-1].ID})
+	}
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(reqs); err != nil {
+	if err := json.NewEncoder(w).Encode(page); err != nil {
 		slog.Error("response encode failed", "error", err)
 	}
 }
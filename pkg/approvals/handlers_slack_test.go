@@ -13,6 +13,10 @@ import (
 	"net/url"
 	"testing"
 	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/oidc"
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"github.com/go-chi/chi/v5"
 )
 
 func TestSlackInteractionInvalidSignatureRejected(t *testing.T) {
@@ -31,19 +35,35 @@ func TestSlackInteractionInvalidSignatureRejected(t *testing.T) {
 }
 
 type fakeHandlersStore struct {
-	granted bool
+	group      string
+	kind       string
+	grantTTL   int
+	released   []ReleaseInput
+	cancelled  []CancelInput
+	granted    bool
+	grants     []GrantInput
+	deliveries []DeadLetter
+	pending    []ApprovalRequest
+	comments   []Comment
+	usages     []GrantUsage
+	cancelErr  error // returned by CancelRequest
 }
 
-func (f *fakeHandlersStore) CreateRequest(context.Context, CreateApprovalInput) (*ApprovalRequest, error) {
-	return &ApprovalRequest{}, nil
+func (f *fakeHandlersStore) CreateRequest(_ context.Context, in CreateApprovalInput) (*ApprovalRequest, error) {
+	return &ApprovalRequest{ID: "req-new", EventID: in.EventID, TenantID: in.TenantID, Tool: in.Tool, Action: in.Action, RiskScore: in.RiskScore, Status: "pending"}, nil
 }
 
-func (f *fakeHandlersStore) GetRequest(context.Context, string) (*ApprovalRequest, error) {
-	return &ApprovalRequest{TenantID: "tenant1", EventID: "evt-1"}, nil
+func (f *fakeHandlersStore) GetRequest(_ context.Context, id string) (*ApprovalRequest, error) {
+	status := "pending"
+	if len(f.cancelled) > 0 {
+		status = "cancelled"
+	}
+	return &ApprovalRequest{ID: id, TenantID: "tenant1", EventID: "evt-1", AgentID: "agent-1", ApproverGroup: f.group, Kind: f.kind, GrantTTLSec: f.grantTTL, Status: status}, nil
 }
 
-func (f *fakeHandlersStore) GrantRequest(_ context.Context, _ string, _ GrantInput) (*ApprovalGrant, error) {
+func (f *fakeHandlersStore) GrantRequest(_ context.Context, _ string, in GrantInput) (*ApprovalGrant, error) {
 	f.granted = true
+	f.grants = append(f.grants, in)
 	return &ApprovalGrant{ID: "g1"}, nil
 }
 
@@ -51,8 +71,60 @@ func (f *fakeHandlersStore) DenyRequest(context.Context, string, DenyInput) erro
 	return nil
 }
 
-func (f *fakeHandlersStore) ListPending(context.Context, string, int, int) ([]ApprovalRequest, error) {
-	return nil, nil
+func (f *fakeHandlersStore) ReleaseRequest(_ context.Context, _ string, in ReleaseInput) error {
+	f.released = append(f.released, in)
+	return nil
+}
+
+func (f *fakeHandlersStore) CancelRequest(_ context.Context, _ string, in CancelInput) error {
+	if f.cancelErr != nil {
+		return f.cancelErr
+	}
+	f.cancelled = append(f.cancelled, in)
+	return nil
+}
+
+func (f *fakeHandlersStore) ListPending(_ context.Context, tenantID string, _ int, _ types.Cursor) ([]ApprovalRequest, error) {
+	var out []ApprovalRequest
+	for _, r := range f.pending {
+		if r.TenantID == tenantID {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeHandlersStore) CountPendingByTenant(context.Context) (map[string]int64, error) {
+	counts := map[string]int64{}
+	for _, r := range f.pending {
+		counts[r.TenantID]++
+	}
+	return counts, nil
+}
+
+func (f *fakeHandlersStore) ListRequestNotifications(context.Context, string) ([]DeadLetter, error) {
+	return f.deliveries, nil
+}
+
+func (f *fakeHandlersStore) AddComment(_ context.Context, c Comment) (*Comment, error) {
+	c.ID = fmt.Sprintf("c%d", len(f.comments)+1)
+	f.comments = append(f.comments, c)
+	return &c, nil
+}
+
+func (f *fakeHandlersStore) ListComments(context.Context, []string) ([]Comment, error) {
+	return f.comments, nil
+}
+
+func (f *fakeHandlersStore) GetGrant(_ context.Context, id string) (*ApprovalGrant, error) {
+	if id != "grant-1" {
+		return nil, nil
+	}
+	return &ApprovalGrant{ID: id, TenantID: "tenant1", MaxUses: 3, UsesLeft: 3 - len(f.usages)}, nil
+}
+
+func (f *fakeHandlersStore) ListGrantUsages(context.Context, string, int, types.Cursor) ([]GrantUsage, error) {
+	return f.usages, nil
 }
 
 func TestVerifySlackRequestFixture(t *testing.T) {
@@ -63,12 +135,31 @@ func TestVerifySlackRequestFixture(t *testing.T) {
 	_, _ = mac.Write([]byte("v0:" + ts + ":" + string(body)))
 	sig := "v0=" + hex.EncodeToString(mac.Sum(nil))
 
-	ok := VerifySlackRequest(body, sig, ts, secret, time.Unix(1700000000, 0))
+	ok := VerifySlackRequest(body, sig, ts, []string{secret}, time.Unix(1700000000, 0))
 	if !ok {
 		t.Fatalf("expected signature verification to pass")
 	}
 }
 
+func TestVerifySlackRequestRotation(t *testing.T) {
+	body := []byte("payload=%7B%22type%22%3A%22block_actions%22%7D")
+	ts := "1700000000"
+	mac := hmac.New(sha256.New, []byte("old-secret"))
+	_, _ = mac.Write([]byte("v0:" + ts + ":" + string(body)))
+	sig := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	now := time.Unix(1700000000, 0)
+
+	if !VerifySlackRequest(body, sig, ts, []string{"new-secret", "old-secret"}, now) {
+		t.Fatal("request signed with the previous secret should verify")
+	}
+	if VerifySlackRequest(body, sig, ts, []string{"new-secret"}, now) {
+		t.Fatal("request signed with a retired secret should not verify")
+	}
+	if VerifySlackRequest(body, sig, ts, []string{"", ""}, now) || VerifySlackRequest(body, sig, ts, nil, now) {
+		t.Fatal("no configured secret must reject every request")
+	}
+}
+
 func TestSlackInteractionApproveCreatesGrant(t *testing.T) {
 	store := &fakeHandlersStore{}
 	authz := NewApproverAuthorizer("", "tenant1:u123")
@@ -98,3 +189,248 @@ func TestSlackInteractionApproveCreatesGrant(t *testing.T) {
 		t.Fatalf("expected grant to be created")
 	}
 }
+
+func TestApproveRequestRejectsInvalidResourcePattern(t *testing.T) {
+	store := &fakeHandlersStore{}
+	r := chi.NewRouter()
+	NewHandlers(store, nil).RegisterRoutes(r)
+
+	for body, want := range map[string]int{
+		`{"approver":"alice","resource_pattern":"jira://project"}`:        http.StatusUnprocessableEntity,
+		`{"approver":"alice","resource_pattern":"["}`:                     http.StatusUnprocessableEntity,
+		`{"approver":"alice","resource_pattern":"jira://project/OPS/**"}`: http.StatusCreated,
+	} {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/v1/approvals/requests/req-1/approve", bytes.NewReader([]byte(body))))
+		if rr.Code != want {
+			t.Errorf("%s: status = %d, want %d (%s)", body, rr.Code, want, rr.Body.String())
+		}
+	}
+}
+
+func TestReleaseRequest_QuarantineOnly(t *testing.T) {
+	post := func(store *fakeHandlersStore, action string) int {
+		r := chi.NewRouter()
+		NewHandlers(store, nil).RegisterRoutes(r)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/v1/approvals/requests/req-1/"+action,
+			bytes.NewReader([]byte(`{"approver":"alice"}`))))
+		return rr.Code
+	}
+
+	// A request resolved between the read and the cancel is a conflict too.
+	raced := &fakeHandlersStore{cancelErr: fmt.Errorf("cancel: %w", ErrNotPending)}
+	if apiErr := NewHandlers(raced, nil).Cancel(context.Background(), "req-1", CancelInput{TenantID: "tenant1", AgentID: "agent-1"}); apiErr == nil || apiErr.HTTPCode != http.StatusConflict {
+		t.Fatalf("cancel of a request resolved concurrently: %+v", apiErr)
+	}
+
+	quarantined := &fakeHandlersStore{kind: KindQuarantine}
+	if code := post(quarantined, "release"); code != http.StatusOK || len(quarantined.released) != 1 || quarantined.released[0].Approver != "alice" {
+		t.Errorf("release: status %d, released %v", code, quarantined.released)
+	}
+	if code := post(quarantined, "approve"); code != http.StatusConflict || quarantined.granted {
+		t.Errorf("approving a quarantine request: status %d, granted %v", code, quarantined.granted)
+	}
+	ordinary := &fakeHandlersStore{}
+	if code := post(ordinary, "release"); code != http.StatusConflict || len(ordinary.released) != 0 {
+		t.Errorf("releasing an ordinary request: status %d", code)
+	}
+}
+
+func TestCancelRequest_RequestingAgentOnly(t *testing.T) {
+	store := &fakeHandlersStore{}
+	sink := &recordingResolutionSink{}
+	h := NewHandlers(store, nil)
+	h.AddResolutionSink(sink)
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+	cancel := func(body string) int {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/v1/approvals/requests/req-1/cancel", bytes.NewReader([]byte(body))))
+		return rr.Code
+	}
+
+	for body, want := range map[string]int{
+		`{"agent_id":"agent-1"}`:                       http.StatusBadRequest,
+		`{"tenant_id":"tenant2","agent_id":"agent-1"}`: http.StatusNotFound,
+		`{"tenant_id":"tenant1"}`:                      http.StatusUnprocessableEntity,
+		`{"tenant_id":"tenant1","agent_id":"agent-2"}`: http.StatusForbidden,
+	} {
+		if code := cancel(body); code != want {
+			t.Errorf("%s: status = %d, want %d", body, code, want)
+		}
+	}
+	if len(store.cancelled) != 0 || len(sink.got) != 0 {
+		t.Fatalf("refused cancellations reached the store: %+v", store.cancelled)
+	}
+
+	if code := cancel(`{"tenant_id":"tenant1","agent_id":"agent-1","reason":"no longer needed"}`); code != http.StatusOK || len(store.cancelled) != 1 {
+		t.Fatalf("cancel: status = %d, cancelled %+v", code, store.cancelled)
+	}
+	if len(sink.got) != 1 || sink.got[0].Status != "cancelled" || sink.got[0].Reason != "no longer needed" {
+		t.Fatalf("resolutions = %+v", sink.got)
+	}
+	if code := cancel(`{"tenant_id":"tenant1","agent_id":"agent-1"}`); code != http.StatusConflict {
+		t.Fatalf("second cancel: status = %d, want 409", code)
+	}
+
+	quarantined := &fakeHandlersStore{kind: KindQuarantine}
+	if apiErr := NewHandlers(quarantined, nil).Cancel(context.Background(), "req-1", CancelInput{TenantID: "tenant1", AgentID: "agent-1"}); apiErr == nil || apiErr.HTTPCode != http.StatusConflict {
+		t.Fatalf("quarantine cancel: %+v", apiErr)
+	}
+}
+
+// groupAuthorizer allows alice for the security group only.
+type groupAuthorizer struct{}
+
+func (groupAuthorizer) AllowEmail(tenantID, group, email string) bool {
+	return tenantID == "tenant1" && group == "security" && email == "alice@example.com"
+}
+
+func (groupAuthorizer) AllowSlack(string, string, string) bool { return false }
+
+func TestApproveRequest_AuthorizesApproverGroup(t *testing.T) {
+	for group, want := range map[string]int{"security": http.StatusCreated, "finance": http.StatusForbidden} {
+		store := &fakeHandlersStore{group: group}
+		r := chi.NewRouter()
+		NewHandlers(store, groupAuthorizer{}).RegisterRoutes(r)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/v1/approvals/requests/req-1/approve",
+			bytes.NewReader([]byte(`{"approver":"alice@example.com"}`))))
+		if rr.Code != want {
+			t.Errorf("group %s: status = %d, want %d (%s)", group, rr.Code, want, rr.Body.String())
+		}
+	}
+}
+
+func TestApproveRequest_VerifiedApprover(t *testing.T) {
+	store := &fakeHandlersStore{}
+	h := NewHandlers(store, nil)
+	h.SetRequireVerifiedApprover(true)
+	alice := oidc.Session{Identity: oidc.Identity{Issuer: "https://idp.example.com", Subject: "00u1", Email: "alice@example.com"}}
+	approve := func(ctx context.Context, body string) int {
+		r := chi.NewRouter()
+		h.RegisterRoutes(r)
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/v1/approvals/requests/req-1/approve", bytes.NewReader([]byte(body)))
+		r.ServeHTTP(rr, req.WithContext(ctx))
+		return rr.Code
+	}
+
+	if code := approve(context.Background(), `{"approver":"alice@example.com"}`); code != http.StatusUnauthorized {
+		t.Fatalf("unverified: status = %d, want 401", code)
+	}
+	signedIn := oidc.WithSession(context.Background(), alice)
+	if code := approve(signedIn, `{"approver":"bob@example.com"}`); code != http.StatusForbidden {
+		t.Fatalf("impersonation: status = %d, want 403", code)
+	}
+	if code := approve(signedIn, `{}`); code != http.StatusCreated || len(store.grants) != 1 {
+		t.Fatalf("signed in: status = %d, grants %+v", code, store.grants)
+	}
+	if g := store.grants[0]; g.Approver != "alice@example.com" || g.ApproverIssuer != "https://idp.example.com" || g.ApproverSubject != "00u1" {
+		t.Fatalf("grant = %+v, want alice's verified identity", g)
+	}
+}
+
+func TestApproveRequest_GrantHours(t *testing.T) {
+	store := &fakeHandlersStore{}
+	h := NewHandlers(store, nil)
+	office := &types.TimeWindow{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "09:00", End: "17:00", Timezone: "Europe/Berlin"}
+	h.SetGrantDefaults(func(_ context.Context, req ApprovalRequest, in *GrantInput) error {
+		if in.ValidHours == nil && req.TenantID == "tenant1" {
+			in.ValidHours = office
+		}
+		return nil
+	})
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+	approve := func(body string) int {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/v1/approvals/requests/req-1/approve", bytes.NewReader([]byte(body))))
+		return rr.Code
+	}
+
+	if code := approve(`{"approver":"alice","valid_hours":{"start":"9am","end":"17:00"}}`); code != http.StatusUnprocessableEntity {
+		t.Fatalf("invalid valid_hours: status = %d, want 422", code)
+	}
+	if code := approve(`{"approver":"alice"}`); code != http.StatusCreated || len(store.grants) != 1 || store.grants[0].ValidHours != office {
+		t.Fatalf("default hours: status %d, grants %+v", code, store.grants)
+	}
+	if code := approve(`{"approver":"alice","valid_hours":{"start":"00:00","end":"00:00"}}`); code != http.StatusCreated || len(store.grants) != 2 || store.grants[1].ValidHours == office {
+		t.Fatalf("explicit hours: status %d, grants %+v", code, store.grants)
+	}
+}
+
+func TestApproveRequest_GrantTTL(t *testing.T) {
+	store := &fakeHandlersStore{}
+	h := NewHandlers(store, nil)
+	h.SetGrantDefaults(func(_ context.Context, _ ApprovalRequest, in *GrantInput) error {
+		if in.ExpiresInSec <= 0 {
+			in.ExpiresInSec = 7200 // the tenant's grant_ttl_sec
+		}
+		return nil
+	})
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+	approve := func(body string) GrantInput {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/v1/approvals/requests/req-1/approve", bytes.NewReader([]byte(body))))
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("approve %s: status = %d", body, rr.Code)
+		}
+		return store.grants[len(store.grants)-1]
+	}
+
+	// The approver's lifetime wins, then the policy's, then the tenant's.
+	if g := approve(`{"approver":"alice"}`); g.ExpiresInSec != 7200 {
+		t.Fatalf("tenant default: expires_in_sec = %d", g.ExpiresInSec)
+	}
+	store.grantTTL = 300
+	if g := approve(`{"approver":"alice"}`); g.ExpiresInSec != 300 {
+		t.Fatalf("policy default: expires_in_sec = %d", g.ExpiresInSec)
+	}
+	if g := approve(`{"approver":"alice","expires_in_sec":60}`); g.ExpiresInSec != 60 {
+		t.Fatalf("explicit: expires_in_sec = %d", g.ExpiresInSec)
+	}
+}
+
+type recordingResolutionSink struct{ got []Resolution }
+
+func (s *recordingResolutionSink) PublishResolution(_ context.Context, res Resolution) {
+	s.got = append(s.got, res)
+}
+
+func TestAutoApproval(t *testing.T) {
+	store := &fakeHandlersStore{pending: []ApprovalRequest{
+		{ID: "req-low", TenantID: "tenant1", Tool: "jira", Action: "issue.list", RiskScore: 1},
+		{ID: "req-high", TenantID: "tenant1", Tool: "jira", Action: "issue.delete", RiskScore: 9},
+	}}
+	sink := &recordingResolutionSink{}
+	h := NewHandlers(store, nil)
+	h.AddResolutionSink(sink)
+	h.SetAutoApproval(func(_ context.Context, req ApprovalRequest) (string, error) {
+		if req.RiskScore < 3 {
+			return "low-risk", nil
+		}
+		return "", nil
+	})
+
+	if err := h.AutoApprovePending(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.grants) != 1 || store.grants[0].Approver != AutoApprover || store.grants[0].MaxUses != 1 {
+		t.Fatalf("grants = %+v, want one single-use grant by %s", store.grants, AutoApprover)
+	}
+	if len(sink.got) != 1 || sink.got[0].Request.ID != "req-low" || sink.got[0].Approver != AutoApprover || sink.got[0].Reason != "auto-approval rule low-risk" {
+		t.Fatalf("resolutions = %+v", sink.got)
+	}
+
+	// Requests created over the API are checked at once.
+	rr := httptest.NewRecorder()
+	body := `{"tenant_id":"tenant1","event_id":"evt-2","tool":"jira","action":"issue.list","risk_score":1}`
+	h.CreateRequest(rr, httptest.NewRequest(http.MethodPost, "/v1/approvals/requests", bytes.NewReader([]byte(body))))
+	if rr.Code != http.StatusCreated || len(store.grants) != 2 || !bytes.Contains(rr.Body.Bytes(), []byte(`"status":"approved"`)) {
+		t.Fatalf("create = %d %s, grants %d", rr.Code, rr.Body.String(), len(store.grants))
+	}
+}
@@ -0,0 +1,277 @@
+package approvals
+
+import (
+	"context"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Webhook notification data is encrypted as a compact JWE (RFC 7516) with
+// A256GCM content encryption. RSA keys wrap the content key with
+// RSA-OAEP-256; P-256 keys agree on it directly with ECDH-ES.
+const (
+	JWEContentType = "application/jose"
+
+	jweEnc        = "A256GCM"
+	jweAlgRSA     = "RSA-OAEP-256"
+	jweAlgECDH    = "ECDH-ES"
+	minRSAKeyBits = 2048
+)
+
+var jweB64 = base64.RawURLEncoding
+
+// EncryptionKey is the public JWK a tenant's webhook notification data is
+// encrypted to: an RSA key of at least 2048 bits or an EC P-256 key. Kid,
+// when set, is copied to the JWE header so receivers can pick the
+// decryption key during a rotation.
+type EncryptionKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// EncryptionKeys returns the key a tenant's webhook notifications are
+// encrypted to, or nil to send them in the clear.
+type EncryptionKeys func(ctx context.Context, tenantID string) (*EncryptionKey, error)
+
+// Validate checks that k is a usable public key.
+func (k EncryptionKey) Validate() error {
+	_, err := k.publicKey()
+	return err
+}
+
+func (k EncryptionKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := jweB64.DecodeString(k.N)
+		if err != nil || len(n) == 0 {
+			return nil, errors.New("invalid n")
+		}
+		e, err := jweB64.DecodeString(k.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("invalid e")
+		}
+		pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		if pub.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("RSA key must be at least %d bits", minRSAKeyBits)
+		}
+		if pub.E < 3 || pub.E%2 == 0 {
+			return nil, errors.New("invalid e")
+		}
+		return pub, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, errors.New("crv must be P-256")
+		}
+		x, err := jweB64.DecodeString(k.X)
+		if err != nil || len(x) != 32 {
+			return nil, errors.New("invalid x")
+		}
+		y, err := jweB64.DecodeString(k.Y)
+		if err != nil || len(y) != 32 {
+			return nil, errors.New("invalid y")
+		}
+		pub, err := ecdh.P256().NewPublicKey(append(append([]byte{4}, x...), y...))
+		if err != nil {
+			return nil, errors.New("point not on P-256")
+		}
+		return pub, nil
+	case "":
+		return nil, errors.New("kty is required")
+	}
+	return nil, fmt.Errorf("kty must be RSA or EC, got %q", k.Kty)
+}
+
+// jweHeader is the JWE protected header.
+type jweHeader struct {
+	Alg string         `json:"alg"`
+	Enc string         `json:"enc"`
+	Kid string         `json:"kid,omitempty"`
+	Cty string         `json:"cty,omitempty"`
+	Epk *EncryptionKey `json:"epk,omitempty"`
+}
+
+// EncryptJWE encrypts plaintext, a JSON document, to key and returns the
+// compact JWE.
+func EncryptJWE(key EncryptionKey, plaintext []byte) (string, error) {
+	pub, err := key.publicKey()
+	if err != nil {
+		return "", fmt.Errorf("approvals.EncryptJWE: %w", err)
+	}
+	header := jweHeader{Enc: jweEnc, Kid: key.Kid, Cty: "json"}
+	var cek, wrapped []byte
+	switch pub := pub.(type) {
+	case *rsa.PublicKey:
+		header.Alg = jweAlgRSA
+		cek = make([]byte, 32)
+		if _, err := rand.Read(cek); err != nil {
+			return "", fmt.Errorf("approvals.EncryptJWE: %w", err)
+		}
+		if wrapped, err = rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, cek, nil); err != nil {
+			return "", fmt.Errorf("approvals.EncryptJWE: %w", err)
+		}
+	case *ecdh.PublicKey:
+		header.Alg = jweAlgECDH
+		eph, err := ecdh.P256().GenerateKey(rand.Reader)
+		if err != nil {
+			return "", fmt.Errorf("approvals.EncryptJWE: %w", err)
+		}
+		z, err := eph.ECDH(pub)
+		if err != nil {
+			return "", fmt.Errorf("approvals.EncryptJWE: %w", err)
+		}
+		point := eph.PublicKey().Bytes()
+		header.Epk = &EncryptionKey{Kty: "EC", Crv: "P-256", X: jweB64.EncodeToString(point[1:33]), Y: jweB64.EncodeToString(point[33:])}
+		cek = concatKDF(z, jweEnc, 256)
+	}
+	rawHeader, err := json.Marshal(header)
+	if err != nil {
+		return "", fmt.Errorf("approvals.EncryptJWE: %w", err)
+	}
+	protected := jweB64.EncodeToString(rawHeader)
+	gcm, err := newGCM(cek)
+	if err != nil {
+		return "", fmt.Errorf("approvals.EncryptJWE: %w", err)
+	}
+	iv := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(iv); err != nil {
+		return "", fmt.Errorf("approvals.EncryptJWE: %w", err)
+	}
+	sealed := gcm.Seal(nil, iv, plaintext, []byte(protected))
+	ciphertext, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+	return strings.Join([]string{
+		protected,
+		jweB64.EncodeToString(wrapped),
+		jweB64.EncodeToString(iv),
+		jweB64.EncodeToString(ciphertext),
+		jweB64.EncodeToString(tag),
+	}, "."), nil
+}
+
+// DecryptJWE decrypts a compact JWE made by EncryptJWE with the private
+// half of the tenant's key, an *rsa.PrivateKey or P-256 *ecdsa.PrivateKey
+// or *ecdh.PrivateKey. Receivers in Go can use it to read encrypted
+// notification data.
+func DecryptJWE(token string, priv crypto.PrivateKey) ([]byte, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 5 {
+		return nil, errors.New("approvals.DecryptJWE: malformed JWE")
+	}
+	var raw [5][]byte
+	for i, p := range parts {
+		b, err := jweB64.DecodeString(p)
+		if err != nil {
+			return nil, fmt.Errorf("approvals.DecryptJWE: part %d: %w", i, err)
+		}
+		raw[i] = b
+	}
+	var header jweHeader
+	if err := json.Unmarshal(raw[0], &header); err != nil {
+		return nil, fmt.Errorf("approvals.DecryptJWE: header: %w", err)
+	}
+	if header.Enc != jweEnc {
+		return nil, fmt.Errorf("approvals.DecryptJWE: unsupported enc %q", header.Enc)
+	}
+	var cek []byte
+	switch header.Alg {
+	case jweAlgRSA:
+		key, ok := priv.(*rsa.PrivateKey)
+		if !ok {
+			return nil, errors.New("approvals.DecryptJWE: RSA-OAEP-256 needs an RSA key")
+		}
+		var err error
+		if cek, err = rsa.DecryptOAEP(sha256.New(), nil, key, raw[1], nil); err != nil {
+			return nil, fmt.Errorf("approvals.DecryptJWE: %w", err)
+		}
+	case jweAlgECDH:
+		key, err := ecdhKey(priv)
+		if err != nil {
+			return nil, fmt.Errorf("approvals.DecryptJWE: %w", err)
+		}
+		if header.Epk == nil {
+			return nil, errors.New("approvals.DecryptJWE: missing epk")
+		}
+		epk, err := header.Epk.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("approvals.DecryptJWE: epk: %w", err)
+		}
+		z, err := key.ECDH(epk.(*ecdh.PublicKey))
+		if err != nil {
+			return nil, fmt.Errorf("approvals.DecryptJWE: %w", err)
+		}
+		cek = concatKDF(z, jweEnc, 256)
+	default:
+		return nil, fmt.Errorf("approvals.DecryptJWE: unsupported alg %q", header.Alg)
+	}
+	gcm, err := newGCM(cek)
+	if err != nil {
+		return nil, fmt.Errorf("approvals.DecryptJWE: %w", err)
+	}
+	if len(raw[2]) != gcm.NonceSize() {
+		return nil, errors.New("approvals.DecryptJWE: invalid iv")
+	}
+	plaintext, err := gcm.Open(nil, raw[2], append(raw[3], raw[4]...), []byte(parts[0]))
+	if err != nil {
+		return nil, fmt.Errorf("approvals.DecryptJWE: %w", err)
+	}
+	return plaintext, nil
+}
+
+func ecdhKey(priv crypto.PrivateKey) (*ecdh.PrivateKey, error) {
+	switch key := priv.(type) {
+	case *ecdh.PrivateKey:
+		if key.Curve() != ecdh.P256() {
+			return nil, errors.New("ECDH-ES needs a P-256 key")
+		}
+		return key, nil
+	case *ecdsa.PrivateKey:
+		return key.ECDH()
+	}
+	return nil, errors.New("ECDH-ES needs an EC key")
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// concatKDF derives a keyBits-long key from the shared secret z as in RFC
+// 7518 §4.6.2, with empty PartyUInfo and PartyVInfo. For ECDH-ES direct key
+// agreement the algorithm ID is the enc value.
+func concatKDF(z []byte, algID string, keyBits int) []byte {
+	var info []byte
+	info = binary.BigEndian.AppendUint32(info, uint32(len(algID)))
+	info = append(info, algID...)
+	info = binary.BigEndian.AppendUint32(info, 0)
+	info = binary.BigEndian.AppendUint32(info, 0)
+	info = binary.BigEndian.AppendUint32(info, uint32(keyBits))
+	var out []byte
+	for round := uint32(1); len(out)*8 < keyBits; round++ {
+		h := sha256.New()
+		_ = binary.Write(h, binary.BigEndian, round)
+		h.Write(z)
+		h.Write(info)
+		out = h.Sum(out)
+	}
+	return out[:keyBits/8]
+}
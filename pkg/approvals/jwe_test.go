@@ -0,0 +1,183 @@
+package approvals
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func rsaEncryptionKey(t *testing.T) (EncryptionKey, *rsa.PrivateKey) {
+	t.Helper()
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return EncryptionKey{
+		Kty: "RSA",
+		Kid: "rsa-1",
+		N:   jweB64.EncodeToString(priv.N.Bytes()),
+		E:   jweB64.EncodeToString(big.NewInt(int64(priv.E)).Bytes()),
+	}, priv
+}
+
+func ecEncryptionKey(t *testing.T) (EncryptionKey, *ecdsa.PrivateKey) {
+	t.Helper()
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := priv.PublicKey.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return EncryptionKey{
+		Kty: "EC",
+		Crv: "P-256",
+		X:   jweB64.EncodeToString(pub[1:33]),
+		Y:   jweB64.EncodeToString(pub[33:]),
+	}, priv
+}
+
+func TestJWERoundTrip(t *testing.T) {
+	rsaKey, rsaPriv := rsaEncryptionKey(t)
+	ecKey, ecPriv := ecEncryptionKey(t)
+	for _, tc := range []struct {
+		name string
+		key  EncryptionKey
+		priv crypto.PrivateKey
+		alg  string
+	}{
+		{"rsa", rsaKey, rsaPriv, jweAlgRSA},
+		{"ec", ecKey, ecPriv, jweAlgECDH},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			plaintext := []byte(`{"resource":"project/OPS"}`)
+			token, err := EncryptJWE(tc.key, plaintext)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(token, "OPS") || strings.Count(token, ".") != 4 {
+				t.Fatalf("token = %s", token)
+			}
+			raw, _ := jweB64.DecodeString(strings.Split(token, ".")[0])
+			var header jweHeader
+			if err := json.Unmarshal(raw, &header); err != nil || header.Alg != tc.alg || header.Enc != jweEnc || header.Kid != tc.key.Kid {
+				t.Fatalf("header = %s", raw)
+			}
+			got, err := DecryptJWE(token, tc.priv)
+			if err != nil || string(got) != string(plaintext) {
+				t.Fatalf("DecryptJWE = %s, %v", got, err)
+			}
+			// The header is authenticated.
+			tampered := jweB64.EncodeToString([]byte(strings.Replace(string(raw), `"cty":"json"`, `"cty":"jsoN"`, 1))) + token[strings.Index(token, "."):]
+			if _, err := DecryptJWE(tampered, tc.priv); err == nil {
+				t.Fatal("tampered header decrypted")
+			}
+		})
+	}
+}
+
+func TestEncryptionKeyValidate(t *testing.T) {
+	rsaKey, _ := rsaEncryptionKey(t)
+	ecKey, _ := ecEncryptionKey(t)
+	small, _ := rsa.GenerateKey(rand.Reader, 1024)
+	offCurve := ecKey
+	offCurve.Y = offCurve.X
+	for _, tc := range []struct {
+		name string
+		key  EncryptionKey
+		ok   bool
+	}{
+		{"rsa", rsaKey, true},
+		{"ec", ecKey, true},
+		{"small rsa", EncryptionKey{Kty: "RSA", N: jweB64.EncodeToString(small.N.Bytes()), E: "AQAB"}, false},
+		{"off curve", offCurve, false},
+		{"p-384", EncryptionKey{Kty: "EC", Crv: "P-384", X: ecKey.X, Y: ecKey.Y}, false},
+		{"oct", EncryptionKey{Kty: "oct"}, false},
+		{"empty", EncryptionKey{}, false},
+	} {
+		if err := tc.key.Validate(); (err == nil) != tc.ok {
+			t.Errorf("%s: Validate() = %v", tc.name, err)
+		}
+	}
+}
+
+func TestDispatcherEncryptsWebhookData(t *testing.T) {
+	key, priv := ecEncryptionKey(t)
+	bodies := make(chan []byte, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !VerifyWebhookV2(r.Header, body, []string{"secret"}, DefaultWebhookTolerance, time.Now()) {
+			t.Error("v2 signature did not verify")
+		}
+		bodies <- body
+	}))
+	defer srv.Close()
+
+	store := &fakeNotificationStore{
+		items: []NotificationOutbox{
+			{ID: "d1", ApprovalRequestID: "r1", TenantID: "tenant1", EventID: "e1", Tool: "jira", Action: "issue.create", Resource: "project/OPS", NotifyKind: "webhook", NotifyURL: srv.URL, SecretRef: "s1"},
+			{ID: "d2", ApprovalRequestID: "r2", TenantID: "tenant2", EventID: "e2", Tool: "jira", Action: "issue.create", Resource: "project/OPS", NotifyKind: "webhook", NotifyURL: srv.URL},
+		},
+		sent:    map[string]bool{},
+		failed:  map[string]bool{},
+		retries: map[string]int{},
+		lastErr: map[string]string{},
+	}
+	d := NewDispatcher(store, "oc://approvals", map[string]string{"s1": "secret"}, "", "token")
+	d.SkipWebhookValidation = true
+	d.SetEncryptionKeys(func(_ context.Context, tenantID string) (*EncryptionKey, error) {
+		switch tenantID {
+		case "tenant1":
+			return &key, nil
+		case "tenant2":
+			return nil, errors.New("settings unavailable")
+		}
+		return nil, nil
+	})
+	if err := d.DispatchOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	body := <-bodies
+	if strings.Contains(string(body), "project/OPS") {
+		t.Fatalf("body leaks data: %s", body)
+	}
+	var ev struct {
+		TenantID        string `json:"tenantid"`
+		DataContentType string `json:"datacontenttype"`
+		Data            string `json:"data"`
+	}
+	if err := json.Unmarshal(body, &ev); err != nil || ev.DataContentType != JWEContentType || ev.TenantID != "tenant1" {
+		t.Fatalf("event = %s, %v", body, err)
+	}
+	plaintext, err := DecryptJWE(ev.Data, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var data map[string]any
+	if err := json.Unmarshal(plaintext, &data); err != nil || data["resource"] != "project/OPS" || data["approval_request_id"] != "r1" {
+		t.Fatalf("data = %s, %v", plaintext, err)
+	}
+	// A failed key lookup is retried, never sent in the clear.
+	if !store.sent["d1"] || store.sent["d2"] || store.failed["d2"] || store.retries["d2"] == 0 {
+		t.Fatalf("sent = %v, failed = %v, retries = %v", store.sent, store.failed, store.retries)
+	}
+	select {
+	case b := <-bodies:
+		t.Fatalf("unexpected delivery: %s", b)
+	default:
+	}
+}
@@ -0,0 +1,46 @@
+package approvals
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+var autoApprovals metric.Int64Counter
+
+func init() {
+	meter := otel.Meter("github.com/bturcanu/OpenClause/pkg/approvals")
+	var err error
+	autoApprovals, err = meter.Int64Counter("oc.approvals.auto_approved",
+		metric.WithDescription("Approval requests approved by tenant auto-approval rules, by tenant and rule."),
+	)
+	if err != nil {
+		panic(err)
+	}
+}
+
+type pendingCounter interface {
+	CountPendingByTenant(context.Context) (map[string]int64, error)
+}
+
+// RegisterPendingGauge publishes the number of pending, unexpired approval
+// requests per tenant. The count is read from the store on each collection.
+func RegisterPendingGauge(store pendingCounter) error {
+	meter := otel.Meter("github.com/bturcanu/OpenClause/pkg/approvals")
+	_, err := meter.Int64ObservableGauge("oc.approvals.pending",
+		metric.WithDescription("Pending approval requests awaiting a decision."),
+		metric.WithInt64Callback(func(ctx context.Context, o metric.Int64Observer) error {
+			counts, err := store.CountPendingByTenant(ctx)
+			if err != nil {
+				return err
+			}
+			for tenantID, n := range counts {
+				o.Observe(n, metric.WithAttributes(attribute.String("tenant", tenantID)))
+			}
+			return nil
+		}),
+	)
+	return err
+}
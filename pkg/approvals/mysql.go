@@ -0,0 +1,16 @@
+package approvals
+
+import "database/sql"
+
+// MySQLStore manages approval requests and grants in MySQL 8 / Aurora MySQL
+// using the schema in migrations/mysql. It mirrors Store query-for-query;
+// row locks use SELECT … FOR UPDATE [SKIP LOCKED], which MySQL 8 supports.
+type MySQLStore struct {
+	sqlApprovals
+}
+
+// NewMySQLStore wraps db, which should be opened with mysqldb.Open so that
+// timestamps round-trip in UTC.
+func NewMySQLStore(db *sql.DB) *MySQLStore {
+	return &MySQLStore{sqlApprovals{db: db}}
+}
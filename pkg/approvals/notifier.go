@@ -7,16 +7,15 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log/slog"
-	"net"
 	"net/http"
-	"net/url"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/bturcanu/OpenClause/pkg/connectors"
+	"github.com/bturcanu/OpenClause/pkg/types"
 )
 
 const (
@@ -43,11 +42,18 @@ func (TemplateSummarizer) Summarize(n NotificationOutbox) string {
 type Dispatcher struct {
 	store                 notificationStore
 	httpClient            *http.Client
+	webhookClient         *http.Client
+	egress                *Egress
 	source                string
+	secretsMu             sync.RWMutex
 	secrets               map[string]string
 	summarizer            Summarizer
 	slackURL              string
 	internalToken         string
+	providersMu           sync.RWMutex
+	providers             map[string]NotificationProvider
+	destinations          *destinations
+	encryptionKeys        EncryptionKeys
 	SkipWebhookValidation bool // testing only — disables SSRF URL checks
 }
 
@@ -56,18 +62,121 @@ type notificationStore interface {
 	MarkNotificationSent(context.Context, string) error
 	MarkNotificationRetry(context.Context, string, int, time.Time, string) error
 	MarkNotificationFailed(context.Context, string, string) error
+	MarkSlackNotificationSent(ctx context.Context, id, channel, ts string) error
+	SlackMessageFor(ctx context.Context, outboxID string) (*SlackMessage, error)
 }
 
+// NewDispatcher creates a dispatcher with the built-in webhook, slack,
+// slack_update, slack_comment, teams, sms and opsgenie providers registered. Others, such
+// as email, are added with RegisterProvider.
 func NewDispatcher(store notificationStore, source string, secrets map[string]string, slackURL, internalToken string) *Dispatcher {
-	return &Dispatcher{
+	d := &Dispatcher{
 		store:         store,
 		httpClient:    &http.Client{Timeout: 10 * time.Second},
+		webhookClient: NewEgressClient(10 * time.Second),
 		source:        source,
 		secrets:       secrets,
 		summarizer:    TemplateSummarizer{},
 		slackURL:      strings.TrimRight(slackURL, "/"),
 		internalToken: internalToken,
+		providers:     map[string]NotificationProvider{},
+		destinations:  newDestinations(),
 	}
+	d.RegisterProvider("webhook", webhookProvider{d})
+	d.RegisterProvider("slack", slackProvider{d})
+	d.RegisterProvider("slack_update", slackUpdateProvider{d})
+	d.RegisterProvider("slack_comment", slackCommentProvider{d})
+	d.RegisterProvider("teams", teamsProvider{d})
+	d.RegisterProvider("sms", smsProvider{d: d})
+	d.RegisterProvider("opsgenie", opsgenieProvider{d: d})
+	return d
+}
+
+// SetSecret replaces the signing secret for ref, e.g. when a secrets
+// manager rotates it.
+func (d *Dispatcher) SetSecret(ref, secret string) {
+	d.secretsMu.Lock()
+	defer d.secretsMu.Unlock()
+	if d.secrets == nil {
+		d.secrets = make(map[string]string)
+	}
+	d.secrets[ref] = secret
+}
+
+func (d *Dispatcher) secret(ref string) string {
+	d.secretsMu.RLock()
+	defer d.secretsMu.RUnlock()
+	return d.secrets[ref]
+}
+
+// RegisterProvider makes p deliver outbox rows whose notify_kind is kind,
+// replacing any provider already registered for it.
+func (d *Dispatcher) RegisterProvider(kind string, p NotificationProvider) {
+	d.providersMu.Lock()
+	defer d.providersMu.Unlock()
+	d.providers[strings.ToLower(kind)] = p
+}
+
+// SetDestinationLimit caps deliveries to any one endpoint at perSec with
+// bursts of up to burst. Rows over the limit are deferred to a later tick
+// without using up an attempt.
+func (d *Dispatcher) SetDestinationLimit(perSec float64, burst int) {
+	d.destinations.setLimit(perSec, burst)
+}
+
+// SetTransport replaces the transport used for Slack connector and paging
+// API calls, e.g. with a connectors.Local for an in-process Slack
+// connector. Webhook and Teams deliveries always use the egress client. It
+// must be called before the first DispatchOnce.
+func (d *Dispatcher) SetTransport(rt http.RoundTripper) {
+	d.httpClient = &http.Client{Timeout: d.httpClient.Timeout, Transport: rt}
+}
+
+// SetEgress sets the egress policies webhook and Teams deliveries are held
+// to; without it every tenant gets the zero EgressPolicy. It must be called
+// before the first DispatchOnce.
+func (d *Dispatcher) SetEgress(g *Egress) {
+	d.egress = g
+}
+
+// webhookRequest returns a POST of body to a tenant-configured URL after
+// checking the URL against the tenant's egress policy. The request carries
+// the policy, so the egress client checks the addresses it dials too.
+// A URL the policy refuses is a permanent error.
+func (d *Dispatcher) webhookRequest(ctx context.Context, tenantID, rawURL string, body []byte) (*http.Request, error) {
+	if !d.SkipWebhookValidation {
+		policy, err := d.egress.Policy(ctx, tenantID)
+		if err != nil {
+			return nil, err
+		}
+		if err := policy.CheckURL(rawURL); err != nil {
+			return nil, Permanent(fmt.Errorf("egress policy: %w", err))
+		}
+		ctx = WithEgressPolicy(ctx, policy)
+	}
+	return http.NewRequestWithContext(ctx, http.MethodPost, rawURL, bytes.NewReader(body))
+}
+
+// SetEncryptionKeys makes webhook deliveries encrypt their CloudEvent data
+// to the tenant's key, when it has one. It must be called before the first
+// DispatchOnce.
+func (d *Dispatcher) SetEncryptionKeys(keys EncryptionKeys) {
+	d.encryptionKeys = keys
+}
+
+// encryptionKey returns the key tenantID's webhook data is encrypted to, or
+// nil.
+func (d *Dispatcher) encryptionKey(ctx context.Context, tenantID string) (*EncryptionKey, error) {
+	if d.encryptionKeys == nil {
+		return nil, nil
+	}
+	return d.encryptionKeys(ctx, tenantID)
+}
+
+func (d *Dispatcher) provider(kind string) NotificationProvider {
+	d.providersMu.RLock()
+	defer d.providersMu.RUnlock()
+	return d.providers[strings.ToLower(kind)]
 }
 
 func (d *Dispatcher) DispatchOnce(ctx context.Context) error {
@@ -76,168 +185,70 @@ func (d *Dispatcher) DispatchOnce(ctx context.Context) error {
 		return err
 	}
 	for _, item := range items {
-		switch strings.ToLower(item.NotifyKind) {
-		case "webhook":
-			if item.NotifyURL == "" {
-				_ = d.store.MarkNotificationFailed(ctx, item.ID, "webhook notify_url is empty")
-				continue
-			}
-			if err := d.deliverWebhook(ctx, item); err != nil {
-				if item.Attempts >= maxNotificationAttempts {
-					if markErr := d.store.MarkNotificationFailed(ctx, item.ID, "max retries exceeded: "+err.Error()); markErr != nil {
-						slog.Error("mark notification failed error", "id", item.ID, "error", markErr)
-					}
-					continue
-				}
-				next := time.Now().UTC().Add(backoffForAttempt(item.Attempts))
-				if markErr := d.store.MarkNotificationRetry(ctx, item.ID, item.Attempts, next, err.Error()); markErr != nil {
-					slog.Error("mark notification retry error", "id", item.ID, "error", markErr)
-				}
-				continue
+		p := d.provider(item.NotifyKind)
+		if p == nil {
+			d.fail(ctx, item, "unsupported notify kind")
+			continue
+		}
+		// Rows for a throttled or failing endpoint wait for a later tick so
+		// one slow destination cannot hold up the rest of the batch.
+		key := destinationKey(item)
+		if next, why, ok := d.destinations.admit(key, time.Now().UTC()); !ok {
+			d.postpone(ctx, item, next, why)
+			continue
+		}
+		delivery, err := p.Deliver(ctx, item)
+		var perm *permanentError
+		switch {
+		case errors.As(err, &perm):
+			d.fail(ctx, item, perm.Error())
+		case err != nil:
+			// A row waiting on its parent says nothing about the endpoint.
+			if !errors.Is(err, errParentNotPosted) {
+				d.destinations.record(key, false, time.Now().UTC())
 			}
-			if markErr := d.store.MarkNotificationSent(ctx, item.ID); markErr != nil {
+			d.retryOrFail(ctx, item, err)
+		case delivery.MessageID != "":
+			if markErr := d.store.MarkSlackNotificationSent(ctx, item.ID, delivery.Channel, delivery.MessageID); markErr != nil {
 				slog.Error("mark notification sent error", "id", item.ID, "error", markErr)
 			}
-		case "slack":
-			if item.SlackChannel == "" {
-				_ = d.store.MarkNotificationFailed(ctx, item.ID, "slack channel is empty")
-				continue
-			}
-			if err := d.deliverSlack(ctx, item); err != nil {
-				if item.Attempts >= maxNotificationAttempts {
-					if markErr := d.store.MarkNotificationFailed(ctx, item.ID, "max retries exceeded: "+err.Error()); markErr != nil {
-						slog.Error("mark notification failed error", "id", item.ID, "error", markErr)
-					}
-					continue
-				}
-				next := time.Now().UTC().Add(backoffForAttempt(item.Attempts))
-				if markErr := d.store.MarkNotificationRetry(ctx, item.ID, item.Attempts, next, err.Error()); markErr != nil {
-					slog.Error("mark notification retry error", "id", item.ID, "error", markErr)
-				}
-				continue
-			}
+		default:
 			if markErr := d.store.MarkNotificationSent(ctx, item.ID); markErr != nil {
 				slog.Error("mark notification sent error", "id", item.ID, "error", markErr)
 			}
-		default:
-			_ = d.store.MarkNotificationFailed(ctx, item.ID, "unsupported notify kind")
+		}
+		if err == nil {
+			d.destinations.record(key, true, time.Now().UTC())
 		}
 	}
 	return nil
 }
 
-func ValidateWebhookURL(rawURL string) error {
-	u, err := url.Parse(rawURL)
-	if err != nil {
-		return fmt.Errorf("invalid URL: %w", err)
-	}
-	if u.Scheme != "https" {
-		return fmt.Errorf("only https scheme allowed, got %q", u.Scheme)
-	}
-	host := u.Hostname()
-	if host == "" {
-		return fmt.Errorf("empty hostname")
+func (d *Dispatcher) fail(ctx context.Context, item NotificationOutbox, reason string) {
+	if markErr := d.store.MarkNotificationFailed(ctx, item.ID, reason); markErr != nil {
+		slog.Error("mark notification failed error", "id", item.ID, "error", markErr)
 	}
-	ip := net.ParseIP(host)
-	if ip != nil {
-		if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() {
-			return fmt.Errorf("private/loopback IP not allowed: %s", ip)
-		}
-	}
-	return nil
 }
 
-func (d *Dispatcher) deliverWebhook(ctx context.Context, item NotificationOutbox) error {
-	if !d.SkipWebhookValidation {
-		if err := ValidateWebhookURL(item.NotifyURL); err != nil {
-			return fmt.Errorf("webhook URL validation: %w", err)
-		}
+// postpone reschedules item for next without counting the claim as an
+// attempt.
+func (d *Dispatcher) postpone(ctx context.Context, item NotificationOutbox, next time.Time, reason string) {
+	if markErr := d.store.MarkNotificationRetry(ctx, item.ID, max(item.Attempts-1, 0), next, reason); markErr != nil {
+		slog.Error("mark notification retry error", "id", item.ID, "error", markErr)
 	}
-	body, err := BuildApprovalRequestedCloudEvent(item, d.source, d.summarizer.Summarize(item))
-	if err != nil {
-		return err
-	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, item.NotifyURL, bytes.NewReader(body))
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Content-Type", "application/cloudevents+json")
-	req.Header.Set("Ce-Specversion", "1.0")
-	req.Header.Set("Ce-Type", "oc.approval.requested")
-	req.Header.Set("Ce-Id", item.ID)
-	req.Header.Set("Ce-Source", d.source)
-	if secret, ok := d.secrets[item.SecretRef]; ok && secret != "" {
-		req.Header.Set("X-OC-Signature-256", SignBodyHMACSHA256(body, secret))
-	}
-	resp, err := d.httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	_, _ = io.Copy(io.Discard, resp.Body)
-	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-		return nil
-	}
-	return fmt.Errorf("webhook status=%d", resp.StatusCode)
 }
 
-func (d *Dispatcher) deliverSlack(ctx context.Context, item NotificationOutbox) error {
-	if d.slackURL == "" {
-		return fmt.Errorf("slack connector url is empty")
+// retryOrFail schedules another attempt with backoff, or marks the item
+// failed once it has used maxNotificationAttempts.
+func (d *Dispatcher) retryOrFail(ctx context.Context, item NotificationOutbox, err error) {
+	if item.Attempts >= maxNotificationAttempts {
+		d.fail(ctx, item, "max retries exceeded: "+err.Error())
+		return
 	}
-	params := map[string]any{
-		"channel":             item.SlackChannel,
-		"tool":                item.Tool,
-		"action":              item.Action,
-		"resource":            item.Resource,
-		"risk_score":          item.RiskScore,
-		"reason":              item.Reason,
-		"approval_url":        item.ApprovalURL,
-		"approval_request_id": item.ApprovalRequestID,
-		"event_id":            item.EventID,
-		"tenant_id":           item.TenantID,
-		"risk_factors":        item.RiskFactors,
+	next := time.Now().UTC().Add(backoffForAttempt(item.Attempts))
+	if markErr := d.store.MarkNotificationRetry(ctx, item.ID, item.Attempts, next, err.Error()); markErr != nil {
+		slog.Error("mark notification retry error", "id", item.ID, "error", markErr)
 	}
-	paramsJSON, err := json.Marshal(params)
-	if err != nil {
-		return err
-	}
-	execReqBody, err := json.Marshal(connectors.ExecRequest{
-		EventID:  item.EventID,
-		TenantID: item.TenantID,
-		Tool:     "slack",
-		Action:   "approval.request",
-		Params:   paramsJSON,
-		Resource: item.Resource,
-	})
-	if err != nil {
-		return err
-	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.slackURL+"/exec", bytes.NewReader(execReqBody))
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Content-Type", "application/json")
-	if d.internalToken != "" {
-		req.Header.Set("X-Internal-Token", d.internalToken)
-	}
-	resp, err := d.httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		_, _ = io.Copy(io.Discard, resp.Body)
-		return fmt.Errorf("slack connector status=%d", resp.StatusCode)
-	}
-	var execResp connectors.ExecResponse
-	if err := json.NewDecoder(resp.Body).Decode(&execResp); err != nil {
-		return err
-	}
-	if execResp.Status != "success" {
-		return fmt.Errorf("slack delivery failed: %s", execResp.Error)
-	}
-	return nil
 }
 
 func backoffForAttempt(attempt int) time.Duration {
@@ -257,46 +268,64 @@ func SignBodyHMACSHA256(rawBody []byte, secret string) string {
 	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
 }
 
-type cloudEvent struct {
-	SpecVersion     string         `json:"specversion"`
-	ID              string         `json:"id"`
-	Type            string         `json:"type"`
-	Source          string         `json:"source"`
-	Time            string         `json:"time"`
-	DataContentType string         `json:"datacontenttype"`
-	Data            map[string]any `json:"data"`
+// BuildApprovalRequestedCloudEvent builds the oc.approval.requested event
+// posted to webhook notify routes.
+func BuildApprovalRequestedCloudEvent(n NotificationOutbox, source, summary string) ([]byte, error) {
+	ev, err := approvalRequestedEvent(n, source, summary)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(ev)
 }
 
-func BuildApprovalRequestedCloudEvent(n NotificationOutbox, source, summary string) ([]byte, error) {
-	ev := cloudEvent{
-		SpecVersion:     "1.0",
-		ID:              n.ID,
-		Type:            "oc.approval.requested",
-		Source:          source,
-		Time:            time.Now().UTC().Format(time.RFC3339Nano),
-		DataContentType: "application/json",
-		Data: map[string]any{
-			"approval_request_id": n.ApprovalRequestID,
-			"event_id":            n.EventID,
-			"tenant_id":           n.TenantID,
-			"tool":                n.Tool,
-			"action":              n.Action,
-			"resource":            n.Resource,
-			"risk_score":          n.RiskScore,
-			"risk_factors":        n.RiskFactors,
-			"approval_url":        n.ApprovalURL,
-			"created_at":          n.CreatedAt.Format(time.RFC3339),
-			"trace_id":            n.TraceID,
-			"approver_group":      n.ApproverGroup,
-			"summary":             summary,
-			"raw": map[string]any{
-				"reason": n.Reason,
-			},
-		},
+// BuildEncryptedApprovalRequestedCloudEvent is BuildApprovalRequestedCloudEvent
+// with the data encrypted to key: data becomes the compact JWE of the JSON
+// data and datacontenttype is application/jose. The envelope attributes
+// stay readable for routing.
+func BuildEncryptedApprovalRequestedCloudEvent(n NotificationOutbox, source, summary string, key EncryptionKey) ([]byte, error) {
+	ev, err := approvalRequestedEvent(n, source, summary)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(ev.Data)
+	if err != nil {
+		return nil, err
+	}
+	if ev.Data, err = EncryptJWE(key, data); err != nil {
+		return nil, err
 	}
+	ev.DataContentType = JWEContentType
 	return json.Marshal(ev)
 }
 
+func approvalRequestedEvent(n NotificationOutbox, source, summary string) (types.CloudEvent, error) {
+	data := map[string]any{
+		"approval_request_id": n.ApprovalRequestID,
+		"event_id":            n.EventID,
+		"tenant_id":           n.TenantID,
+		"tool":                n.Tool,
+		"action":              n.Action,
+		"resource":            n.Resource,
+		"risk_score":          n.RiskScore,
+		"risk_factors":        n.RiskFactors,
+		"approval_url":        n.ApprovalURL,
+		"created_at":          n.CreatedAt.Format(time.RFC3339),
+		"trace_id":            n.TraceID,
+		"approver_group":      n.ApproverGroup,
+		"summary":             summary,
+		"raw": map[string]any{
+			"reason": n.Reason,
+		},
+	}
+	if n.Plan != nil {
+		data["plan"] = n.Plan
+	}
+	if len(n.RecentActivity) > 0 {
+		data["recent_activity"] = n.RecentActivity
+	}
+	return types.NewCloudEvent(types.EventApprovalRequested, n.ID, source, n.TenantID, n.ApprovalRequestID, time.Now(), data)
+}
+
 func ParseSecretRefMap(raw string) map[string]string {
 	out := map[string]string{}
 	for _, pair := range strings.Split(raw, ",") {
@@ -3,12 +3,19 @@ package approvals
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/connectors"
+	"github.com/bturcanu/OpenClause/pkg/types"
 )
 
 func TestBuildApprovalRequestedCloudEvent(t *testing.T) {
@@ -25,6 +32,7 @@ func TestBuildApprovalRequestedCloudEvent(t *testing.T) {
 		ApprovalURL:       "http://localhost:8081/v1/approvals/requests/req-1",
 		CreatedAt:         time.Now().UTC(),
 		TraceID:           "trace-1",
+		Plan:              &connectors.ExecPlan{Summary: "Create Task in OPS: Rotate keys", Operation: "POST /rest/api/3/issue"},
 	}
 	raw, err := BuildApprovalRequestedCloudEvent(n, "oc://approvals", "summary")
 	if err != nil {
@@ -38,6 +46,40 @@ func TestBuildApprovalRequestedCloudEvent(t *testing.T) {
 	if body["type"] != "oc.approval.requested" {
 		t.Fatalf("unexpected type: %v", body["type"])
 	}
+	data, _ := body["data"].(map[string]any)
+	plan, _ := data["plan"].(map[string]any)
+	if plan["summary"] != "Create Task in OPS: Rotate keys" {
+		t.Fatalf("data.plan = %v", data["plan"])
+	}
+}
+
+func TestPlanLines(t *testing.T) {
+	if PlanLines(nil) != nil {
+		t.Fatal("nil plan should render no lines")
+	}
+	got := PlanLines(&connectors.ExecPlan{
+		Summary:   "Create Task in OPS: Rotate keys",
+		Operation: "POST /rest/api/3/issue",
+		Fields: map[string]any{
+			"summary":     "Rotate keys",
+			"description": "line one\nline two",
+			"labels":      []string{"sec"},
+		},
+	})
+	want := []string{
+		"Plan: Create Task in OPS: Rotate keys",
+		"Operation: POST /rest/api/3/issue",
+		"  description: line one line two",
+		`  labels: ["sec"]`,
+		"  summary: Rotate keys",
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("PlanLines = %q, want %q", got, want)
+	}
+	readOnly := PlanLines(&connectors.ExecPlan{Summary: "List issues", Operation: "GET /rest/api/3/search", ReadOnly: true})
+	if readOnly[1] != "Operation: GET /rest/api/3/search (read-only)" {
+		t.Fatalf("read-only operation = %q", readOnly[1])
+	}
 }
 
 func TestSignBodyHMACSHA256(t *testing.T) {
@@ -54,6 +96,7 @@ type fakeNotificationStore struct {
 	failed  map[string]bool
 	retries map[string]int
 	lastErr map[string]string
+	posted  map[string]SlackMessage
 }
 
 func (f *fakeNotificationStore) ClaimDueNotifications(context.Context, int) ([]NotificationOutbox, error) {
@@ -93,9 +136,51 @@ func (f *fakeNotificationStore) MarkNotificationFailed(_ context.Context, id str
 	return nil
 }
 
+func (f *fakeNotificationStore) MarkSlackNotificationSent(_ context.Context, id, channel, ts string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent[id] = true
+	if f.posted == nil {
+		f.posted = map[string]SlackMessage{}
+	}
+	f.posted[id] = SlackMessage{Channel: channel, TS: ts}
+	return nil
+}
+
+func (f *fakeNotificationStore) SlackMessageFor(_ context.Context, outboxID string) (*SlackMessage, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, item := range f.items {
+		if item.ID != outboxID {
+			continue
+		}
+		m := f.posted[outboxID]
+		switch {
+		case f.sent[outboxID]:
+			m.Status = "sent"
+		case f.failed[outboxID]:
+			m.Status = "failed"
+		default:
+			m.Status = "pending"
+		}
+		return &m, nil
+	}
+	return nil, nil
+}
+
 func TestDispatcherRetriesThenSucceeds(t *testing.T) {
 	var hits atomic.Int32
-	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+	var mu sync.Mutex
+	var deliveries, attempts []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !VerifyWebhookV2(r.Header, body, []string{"secret"}, DefaultWebhookTolerance, time.Now()) {
+			t.Errorf("v2 signature did not verify: %q", r.Header.Get(HeaderSignatureV2))
+		}
+		mu.Lock()
+		deliveries = append(deliveries, r.Header.Get(HeaderDeliveryID))
+		attempts = append(attempts, r.Header.Get(HeaderAttempt))
+		mu.Unlock()
 		h := hits.Add(1)
 		if h == 1 {
 			w.WriteHeader(http.StatusInternalServerError)
@@ -143,6 +228,40 @@ func TestDispatcherRetriesThenSucceeds(t *testing.T) {
 	if !store.sent["d1"] {
 		t.Fatalf("expected sent after retry")
 	}
+	if !slices.Equal(deliveries, []string{"d1", "d1"}) || !slices.Equal(attempts, []string{"1", "2"}) {
+		t.Fatalf("delivery ids = %v, attempts = %v", deliveries, attempts)
+	}
+}
+
+func TestVerifyWebhookV2(t *testing.T) {
+	body := []byte(`{"a":1}`)
+	now := time.Now()
+	req := httptest.NewRequest(http.MethodPost, "https://hooks.example.com", nil)
+	req.Header.Set("Content-Type", "application/cloudevents+json")
+	SignWebhookRequest(req, body, "secret", "d1", 3, now)
+	if req.Header.Get(HeaderSignature) != SignBodyHMACSHA256(body, "secret") {
+		t.Fatal("v1 signature should still be sent")
+	}
+	secrets := []string{"old", "secret"}
+	if !VerifyWebhookV2(req.Header, body, secrets, DefaultWebhookTolerance, now) {
+		t.Fatal("valid signature rejected")
+	}
+	if VerifyWebhookV2(req.Header, []byte(`{"a":2}`), secrets, DefaultWebhookTolerance, now) {
+		t.Fatal("tampered body accepted")
+	}
+	if VerifyWebhookV2(req.Header, body, secrets, DefaultWebhookTolerance, now.Add(10*time.Minute)) {
+		t.Fatal("replayed signature accepted after tolerance")
+	}
+	replay := req.Header.Clone()
+	replay.Set(HeaderAttempt, "4")
+	if VerifyWebhookV2(replay, body, secrets, DefaultWebhookTolerance, now) {
+		t.Fatal("tampered attempt header accepted")
+	}
+	unsigned := httptest.NewRequest(http.MethodPost, "https://hooks.example.com", nil)
+	SignWebhookRequest(unsigned, body, "", "d1", 1, now)
+	if unsigned.Header.Get(HeaderDeliveryID) != "d1" || unsigned.Header.Get(HeaderSignatureV2) != "" {
+		t.Fatalf("unsigned headers = %v", unsigned.Header)
+	}
 }
 
 func TestDispatcherDeliversSlackNotification(t *testing.T) {
@@ -192,3 +311,286 @@ func TestDispatcherDeliversSlackNotification(t *testing.T) {
 		t.Fatalf("expected one connector delivery, got %d", hits.Load())
 	}
 }
+
+func TestDispatcherUpdatesSlackMessageOnResolution(t *testing.T) {
+	var mu sync.Mutex
+	var calls []connectors.ExecRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req connectors.ExecRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode exec request: %v", err)
+		}
+		mu.Lock()
+		calls = append(calls, req)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","output_json":{"ok":true,"channel":"C0SEC","ts":"1700000000.000001"}}`))
+	}))
+	defer srv.Close()
+
+	base := NotificationOutbox{
+		ApprovalRequestID: "r1",
+		TenantID:          "tenant1",
+		EventID:           "e1",
+		Tool:              "jira",
+		Action:            "issue.create",
+		Resource:          "project/SEC",
+		ApprovalURL:       "http://localhost/x",
+		SlackChannel:      "#security-approvals",
+	}
+	update := base
+	update.ID, update.NotifyKind, update.ParentID = "d-slack-1:update", "slack_update", "d-slack-1"
+	update.Resolution, update.ResolvedBy, update.ResolutionReason = "denied", "alice", "not today"
+	comment := base
+	comment.ID, comment.NotifyKind, comment.ParentID = "c1", "slack_comment", "d-slack-1"
+	comment.Comment = &Comment{ID: "cm1", Author: "alice", Role: RoleApprover, Body: "why?"}
+	post := base
+	post.ID, post.NotifyKind = "d-slack-1", "slack"
+
+	// The update and comment are claimed before their message has been
+	// posted, so they wait.
+	store := &fakeNotificationStore{
+		items:   []NotificationOutbox{update, comment, post},
+		sent:    map[string]bool{},
+		failed:  map[string]bool{},
+		retries: map[string]int{},
+		lastErr: map[string]string{},
+	}
+	d := NewDispatcher(store, "oc://approvals", nil, srv.URL, "token")
+
+	if err := d.DispatchOnce(context.Background()); err != nil {
+		t.Fatalf("dispatch once #1: %v", err)
+	}
+	if store.sent["d-slack-1:update"] || store.retries["d-slack-1:update"] != 1 {
+		t.Fatalf("update should wait for the message: sent=%v retries=%v", store.sent, store.retries)
+	}
+	if got := store.posted["d-slack-1"]; got.Channel != "C0SEC" || got.TS != "1700000000.000001" {
+		t.Fatalf("posted message = %+v", got)
+	}
+
+	if err := d.DispatchOnce(context.Background()); err != nil {
+		t.Fatalf("dispatch once #2: %v", err)
+	}
+	if !store.sent["d-slack-1:update"] || !store.sent["c1"] {
+		t.Fatalf("expected update and comment to be sent, lastErr=%v", store.lastErr)
+	}
+	if len(calls) != 3 {
+		t.Fatalf("connector calls = %+v", calls)
+	}
+	params := map[string]map[string]any{}
+	for _, c := range calls[1:] {
+		var p map[string]any
+		if err := json.Unmarshal(c.Params, &p); err != nil {
+			t.Fatal(err)
+		}
+		params[c.Action] = p
+	}
+	if p := params["approval.resolve"]; p["channel"] != "C0SEC" || p["ts"] != "1700000000.000001" || p["status"] != "denied" || p["resolution_reason"] != "not today" {
+		t.Fatalf("resolve params = %v", p)
+	}
+	if p := params["approval.comment"]; p["channel"] != "C0SEC" || p["thread_ts"] != "1700000000.000001" || p["author"] != "alice" || p["body"] != "why?" {
+		t.Fatalf("comment params = %v", p)
+	}
+}
+
+type stubProvider struct {
+	err   error
+	items []string
+}
+
+func (stubProvider) ValidateRoute(types.PolicyNotify) error { return nil }
+
+func (p *stubProvider) Deliver(_ context.Context, item NotificationOutbox) (Delivery, error) {
+	p.items = append(p.items, item.ID)
+	return Delivery{}, p.err
+}
+
+func TestDispatcherRoutesToRegisteredProviders(t *testing.T) {
+	var card map[string]any
+	teams := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&card)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer teams.Close()
+
+	store := &fakeNotificationStore{
+		items: []NotificationOutbox{
+			{ID: "n-teams", NotifyKind: "teams", NotifyURL: teams.URL, Tool: "jira", Action: "issue.delete", ApprovalURL: "https://oc/x"},
+			{ID: "n-pager", NotifyKind: "PAGER", TenantID: "t1"},
+			{ID: "n-broken", NotifyKind: "broken"},
+			{ID: "n-unknown", NotifyKind: "carrier-pigeon"},
+		},
+		sent:    map[string]bool{},
+		failed:  map[string]bool{},
+		retries: map[string]int{},
+		lastErr: map[string]string{},
+	}
+	d := NewDispatcher(store, "oc://approvals", nil, "", "")
+	d.SkipWebhookValidation = true
+	pager := &stubProvider{}
+	d.RegisterProvider("pager", pager)
+	d.RegisterProvider("broken", &stubProvider{err: Permanent(errors.New("no route"))})
+
+	if err := d.DispatchOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !store.sent["n-teams"] || card["@type"] != "MessageCard" {
+		t.Fatalf("teams: sent=%v card=%v lastErr=%v", store.sent["n-teams"], card, store.lastErr)
+	}
+	if !store.sent["n-pager"] || len(pager.items) != 1 {
+		t.Fatalf("custom provider not used: %v", pager.items)
+	}
+	if !store.failed["n-broken"] || store.lastErr["n-broken"] != "no route" || store.retries["n-broken"] != 0 {
+		t.Fatalf("permanent error should fail without retry: %v", store.lastErr)
+	}
+	if !store.failed["n-unknown"] || store.lastErr["n-unknown"] != "unsupported notify kind" {
+		t.Fatalf("unknown kind: %v", store.lastErr)
+	}
+}
+
+func TestDispatcherThrottlesPerDestination(t *testing.T) {
+	var downHits, upHits atomic.Int32
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		downHits.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer down.Close()
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		upHits.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer up.Close()
+
+	store := &fakeNotificationStore{
+		items: []NotificationOutbox{
+			{ID: "down1", NotifyKind: "webhook", NotifyURL: down.URL},
+			{ID: "up1", NotifyKind: "webhook", NotifyURL: up.URL},
+			{ID: "down2", NotifyKind: "webhook", NotifyURL: down.URL + "/other"},
+			{ID: "up2", NotifyKind: "webhook", NotifyURL: up.URL},
+			{ID: "down3", NotifyKind: "webhook", NotifyURL: down.URL},
+			{ID: "up3", NotifyKind: "webhook", NotifyURL: up.URL},
+		},
+		sent:    map[string]bool{},
+		failed:  map[string]bool{},
+		retries: map[string]int{},
+		lastErr: map[string]string{},
+	}
+	d := NewDispatcher(store, "oc://approvals", nil, "", "")
+	d.SkipWebhookValidation = true
+	d.SetDestinationLimit(0.001, 2)
+
+	if err := d.DispatchOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if downHits.Load() != 2 || store.retries["down1"] != 1 || store.retries["down2"] != 1 {
+		t.Fatalf("down: hits=%d retries=%v", downHits.Load(), store.retries)
+	}
+	if r, ok := store.retries["down3"]; !ok || r != 0 || store.lastErr["down3"] != "destination backing off after failure" {
+		t.Fatalf("down3 should be postponed without an attempt: retries=%v lastErr=%v", store.retries, store.lastErr)
+	}
+	if upHits.Load() != 2 || !store.sent["up1"] || !store.sent["up2"] {
+		t.Fatalf("up: hits=%d sent=%v", upHits.Load(), store.sent)
+	}
+	if r, ok := store.retries["up3"]; !ok || r != 0 || store.lastErr["up3"] != "destination rate limited" {
+		t.Fatalf("up3 should be rate limited: retries=%v lastErr=%v", store.retries, store.lastErr)
+	}
+}
+
+func TestDispatcherDeliversPages(t *testing.T) {
+	var mu sync.Mutex
+	var sms []url.Values
+	var alert map[string]any
+	var smsAuth, genieAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/2010-04-01/Accounts/AC1/Messages.json":
+			_ = r.ParseForm()
+			sms = append(sms, r.PostForm)
+			user, pass, _ := r.BasicAuth()
+			smsAuth = user + ":" + pass
+			w.WriteHeader(http.StatusCreated)
+		case "/v2/alerts":
+			_ = json.NewDecoder(r.Body).Decode(&alert)
+			genieAuth = r.Header.Get("Authorization")
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	base := NotificationOutbox{ApprovalRequestID: "r1", TenantID: "t1", Tool: "aws", Action: "iam.delete", RiskScore: 9, ApprovalURL: "https://oc/r1"}
+	page := base
+	page.ID, page.NotifyKind, page.SecretRef = "n-sms", "sms", "twilio"
+	page.NotifyConfig = map[string]string{
+		"account_sid": "AC1", "from": "+15550100", "to": "+15550101, +15550102",
+		"template": "{{.Tool}}.{{.Action}} risk {{.RiskScore}}: {{.ApprovalURL}}",
+	}
+	genie := base
+	genie.ID, genie.NotifyKind, genie.SecretRef = "n-genie", "opsgenie", "genie"
+	genie.NotifyConfig = map[string]string{"responders": "team:secops,user:oncall@acme.io"}
+	missing := base
+	missing.ID, missing.NotifyKind, missing.SecretRef = "n-nokey", "opsgenie", "unset"
+
+	store := &fakeNotificationStore{
+		items:   []NotificationOutbox{page, genie, missing},
+		sent:    map[string]bool{},
+		failed:  map[string]bool{},
+		retries: map[string]int{},
+		lastErr: map[string]string{},
+	}
+	d := NewDispatcher(store, "oc://approvals", map[string]string{"twilio": "tw-token", "genie": "og-key"}, "", "")
+	d.RegisterProvider("sms", smsProvider{d: d, baseURL: srv.URL})
+	d.RegisterProvider("opsgenie", opsgenieProvider{d: d, baseURL: srv.URL})
+
+	if err := d.DispatchOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !store.sent["n-sms"] || len(sms) != 2 || smsAuth != "AC1:tw-token" {
+		t.Fatalf("sms: sent=%v messages=%v auth=%q lastErr=%v", store.sent, sms, smsAuth, store.lastErr)
+	}
+	if sms[1].Get("To") != "+15550102" || sms[0].Get("Body") != "aws.iam.delete risk 9: https://oc/r1" {
+		t.Fatalf("sms messages = %v", sms)
+	}
+	if !store.sent["n-genie"] || genieAuth != "GenieKey og-key" {
+		t.Fatalf("opsgenie: sent=%v auth=%q lastErr=%v", store.sent, genieAuth, store.lastErr)
+	}
+	if alert["alias"] != "oc-approval-r1" || alert["priority"] != "P1" || alert["message"] != "Approval needed: aws.iam.delete (t1, risk 9)" {
+		t.Fatalf("alert = %v", alert)
+	}
+	if rs, _ := alert["responders"].([]any); len(rs) != 2 || rs[1].(map[string]any)["username"] != "oncall@acme.io" {
+		t.Fatalf("responders = %v", alert["responders"])
+	}
+	if !store.failed["n-nokey"] || store.lastErr["n-nokey"] != `opsgenie secret_ref "unset" is not configured` {
+		t.Fatalf("missing key should fail permanently: %v", store.lastErr)
+	}
+}
+
+func TestValidateNotifyRoute(t *testing.T) {
+	cases := []struct {
+		route   types.PolicyNotify
+		wantErr string
+	}{
+		{types.PolicyNotify{Kind: "webhook", URL: "https://x"}, ""},
+		{types.PolicyNotify{Kind: "webhook"}, "webhook requires url"},
+		{types.PolicyNotify{Kind: "slack", Channel: "#sec"}, ""},
+		{types.PolicyNotify{Kind: "teams"}, "teams requires url"},
+		{types.PolicyNotify{Kind: "email", Config: map[string]string{"to": " a@x.io, b@x.io "}}, ""},
+		{types.PolicyNotify{Kind: "email", Config: map[string]string{"to": " , "}}, "email requires config.to"},
+		{types.PolicyNotify{Kind: "slack_update"}, "kind must be one of email, opsgenie, slack, sms, teams, webhook"},
+		{types.PolicyNotify{Kind: "sms", SecretRef: "tw", Config: map[string]string{"account_sid": "AC1", "from": "+15550100", "to": "+15550101"}}, ""},
+		{types.PolicyNotify{Kind: "sms", SecretRef: "tw", Config: map[string]string{"account_sid": "AC1", "from": "+15550100", "to": "555-0101"}}, `sms number "555-0101" must be in E.164 format`},
+		{types.PolicyNotify{Kind: "sms", Config: map[string]string{"account_sid": "AC1", "from": "+15550100", "to": "+15550101"}}, "sms requires secret_ref (Twilio auth token)"},
+		{types.PolicyNotify{Kind: "opsgenie", SecretRef: "og", Config: map[string]string{"responders": "team:secops", "priority": "P1", "region": "eu"}}, ""},
+		{types.PolicyNotify{Kind: "opsgenie", SecretRef: "og", Config: map[string]string{"priority": "urgent"}}, "opsgenie config.priority must be P1-P5"},
+		{types.PolicyNotify{Kind: "opsgenie", SecretRef: "og", Config: map[string]string{"template": "{{.Tool"}}, "opsgenie config.template: template: message:1: unclosed action"},
+	}
+	for _, c := range cases {
+		err := ValidateNotifyRoute(c.route)
+		if (c.wantErr == "" && err != nil) || (c.wantErr != "" && (err == nil || err.Error() != c.wantErr)) {
+			t.Errorf("%+v: err = %v, want %q", c.route, err, c.wantErr)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package approvals
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+// PendingPage is one page of GET /v1/approvals/pending. NextCursor is set
+// when more requests may follow; pass it back as ?cursor= for the next page.
+type PendingPage struct {
+	Requests   []ApprovalRequest `json:"requests"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+// DeadLetterPage is one page of GET /v1/approvals/notifications/failed.
+type DeadLetterPage struct {
+	Notifications []DeadLetter `json:"notifications"`
+	NextCursor    string       `json:"next_cursor,omitempty"`
+}
+
+// GrantUsagePage is one page of GET /v1/approvals/grants/{id}/usages.
+type GrantUsagePage struct {
+	Grant      ApprovalGrant `json:"grant"`
+	Usages     []GrantUsage  `json:"usages"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+// parsePage reads the limit and cursor query parameters of a listing. It
+// writes a 400 and returns false when either is malformed, or when the
+// retired offset parameter is used.
+func parsePage(w http.ResponseWriter, r *http.Request) (limit int, after types.Cursor, ok bool) {
+	q := r.URL.Query()
+	if q.Has("offset") {
+		types.ErrBadRequest("offset pagination is not supported; use cursor").WriteJSON(w)
+		return 0, after, false
+	}
+	if v := q.Get("limit"); v != "" {
+		var err error
+		if limit, err = strconv.Atoi(v); err != nil {
+			types.ErrBadRequest("invalid limit parameter").WriteJSON(w)
+			return 0, after, false
+		}
+	}
+	after, err := types.ParseCursor(q.Get("cursor"))
+	if err != nil {
+		types.ErrBadRequest("invalid cursor parameter").WriteJSON(w)
+		return 0, after, false
+	}
+	return limit, after, true
+}
+
+// nextCursor returns the cursor after last when the page is full, and ""
+// when the listing is exhausted.
+func nextCursor(n, limit int, last types.Cursor) string {
+	if n < pageLimit(limit) {
+		return ""
+	}
+	return last.String()
+}
@@ -0,0 +1,283 @@
+package approvals
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+const (
+	twilioAPIURL     = "https://api.twilio.com"
+	opsgenieAPIURL   = "https://api.opsgenie.com"
+	opsgenieEUAPIURL = "https://api.eu.opsgenie.com"
+
+	maxSMSBody          = 1600 // Twilio's limit for one message
+	maxOpsgenieMessage  = 130
+	defaultSMSTemplate  = `OpenClause approval needed: {{.Summary}} Review: {{.ApprovalURL}}`
+	defaultOpsgenieText = `Approval needed: {{.Tool}}.{{.Action}} ({{.TenantID}}, risk {{.RiskScore}})`
+)
+
+// messageData is what a route's config.template is executed against: every
+// NotificationOutbox field plus the dispatcher's summary.
+type messageData struct {
+	NotificationOutbox
+	Summary string
+}
+
+func parseMessageTemplate(cfg map[string]string, fallback string) (*template.Template, error) {
+	text := cfg["template"]
+	if text == "" {
+		text = fallback
+	}
+	return template.New("message").Option("missingkey=error").Parse(text)
+}
+
+// renderMessage executes the route's config.template, or fallback, for item.
+func (d *Dispatcher) renderMessage(item NotificationOutbox, fallback string) (string, error) {
+	tmpl, err := parseMessageTemplate(item.NotifyConfig, fallback)
+	if err != nil {
+		return "", Permanent(fmt.Errorf("template: %w", err))
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, messageData{NotificationOutbox: item, Summary: d.summarizer.Summarize(item)}); err != nil {
+		return "", Permanent(fmt.Errorf("template: %w", err))
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+func truncateRunes(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return s
+}
+
+// ── SMS (Twilio) ─────────────────────────────────────────────────────────────
+
+// smsProvider texts each number in config.to through the Twilio Messages
+// API. The route's secret_ref names the account's auth token.
+type smsProvider struct {
+	d *Dispatcher
+	// baseURL overrides the Twilio API host; set by tests.
+	baseURL string
+}
+
+func (smsProvider) ValidateRoute(route types.PolicyNotify) error {
+	switch {
+	case route.SecretRef == "":
+		return errors.New("sms requires secret_ref (Twilio auth token)")
+	case route.Config["account_sid"] == "":
+		return errors.New("sms requires config.account_sid")
+	case route.Config["from"] == "":
+		return errors.New("sms requires config.from")
+	case len(smsRecipients(route.Config)) == 0:
+		return errors.New("sms requires config.to")
+	}
+	for _, to := range smsRecipients(route.Config) {
+		if !strings.HasPrefix(to, "+") {
+			return fmt.Errorf("sms number %q must be in E.164 format", to)
+		}
+	}
+	if _, err := parseMessageTemplate(route.Config, defaultSMSTemplate); err != nil {
+		return fmt.Errorf("sms config.template: %w", err)
+	}
+	return nil
+}
+
+func smsRecipients(cfg map[string]string) []string {
+	var out []string
+	for _, to := range strings.Split(cfg["to"], ",") {
+		if to = strings.TrimSpace(to); to != "" {
+			out = append(out, to)
+		}
+	}
+	return out
+}
+
+func (p smsProvider) Deliver(ctx context.Context, item NotificationOutbox) (Delivery, error) {
+	d := p.d
+	sid, from := item.NotifyConfig["account_sid"], item.NotifyConfig["from"]
+	to := smsRecipients(item.NotifyConfig)
+	if sid == "" || from == "" || len(to) == 0 {
+		return Delivery{}, Permanent(errors.New("sms route is missing account_sid, from or to"))
+	}
+	token := d.secret(item.SecretRef)
+	if token == "" {
+		return Delivery{}, Permanent(fmt.Errorf("sms secret_ref %q is not configured", item.SecretRef))
+	}
+	body, err := d.renderMessage(item, defaultSMSTemplate)
+	if err != nil {
+		return Delivery{}, err
+	}
+	body = truncateRunes(body, maxSMSBody)
+
+	base := p.baseURL
+	if base == "" {
+		base = twilioAPIURL
+	}
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", base, url.PathEscape(sid))
+	// A retry resends to every number; a duplicate page beats a missed one.
+	for _, number := range to {
+		form := url.Values{"To": {number}, "From": {from}, "Body": {body}}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+		if err != nil {
+			return Delivery{}, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(sid, token)
+		if err := d.post(d.httpClient, req, "twilio"); err != nil {
+			return Delivery{}, err
+		}
+	}
+	return Delivery{}, nil
+}
+
+// ── Opsgenie ─────────────────────────────────────────────────────────────────
+
+// opsgenieProvider opens an Opsgenie alert aliased to the approval request,
+// so retries and repeated routes deduplicate. The route's secret_ref names
+// the integration's API key.
+type opsgenieProvider struct {
+	d *Dispatcher
+	// baseURL overrides the region's API host; set by tests.
+	baseURL string
+}
+
+var opsgeniePriorities = map[string]bool{"P1": true, "P2": true, "P3": true, "P4": true, "P5": true}
+
+func (opsgenieProvider) ValidateRoute(route types.PolicyNotify) error {
+	if route.SecretRef == "" {
+		return errors.New("opsgenie requires secret_ref (API key)")
+	}
+	if p := route.Config["priority"]; p != "" && !opsgeniePriorities[p] {
+		return errors.New("opsgenie config.priority must be P1-P5")
+	}
+	switch route.Config["region"] {
+	case "", "us", "eu":
+	default:
+		return errors.New(`opsgenie config.region must be "us" or "eu"`)
+	}
+	if _, err := opsgenieResponders(route.Config); err != nil {
+		return err
+	}
+	if _, err := parseMessageTemplate(route.Config, defaultOpsgenieText); err != nil {
+		return fmt.Errorf("opsgenie config.template: %w", err)
+	}
+	return nil
+}
+
+// opsgenieResponders parses config.responders, e.g. "team:secops,user:a@x.io".
+func opsgenieResponders(cfg map[string]string) ([]map[string]string, error) {
+	var out []map[string]string
+	for _, r := range strings.Split(cfg["responders"], ",") {
+		if r = strings.TrimSpace(r); r == "" {
+			continue
+		}
+		typ, name, ok := strings.Cut(r, ":")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("opsgenie responder %q must be type:name", r)
+		}
+		switch typ {
+		case "team", "user", "escalation", "schedule":
+		default:
+			return nil, fmt.Errorf("opsgenie responder type %q must be team, user, escalation or schedule", typ)
+		}
+		key := "name"
+		if typ == "user" {
+			key = "username"
+		}
+		out = append(out, map[string]string{"type": typ, key: name})
+	}
+	return out, nil
+}
+
+// opsgeniePriority maps risk to a priority when the route does not set one.
+func opsgeniePriority(riskScore int) string {
+	switch {
+	case riskScore >= 9:
+		return "P1"
+	case riskScore >= 7:
+		return "P2"
+	default:
+		return "P3"
+	}
+}
+
+func opsgenieDescription(summary string, item NotificationOutbox) string {
+	desc := summary
+	if lines := PlanLines(item.Plan); lines != nil {
+		desc += "\n\n" + strings.Join(lines, "\n")
+	}
+	if lines := ActivityLines(item.RecentActivity); lines != nil {
+		desc += "\n\n" + strings.Join(lines, "\n")
+	}
+	return desc + "\n\nReview: " + item.ApprovalURL
+}
+
+func (p opsgenieProvider) Deliver(ctx context.Context, item NotificationOutbox) (Delivery, error) {
+	d := p.d
+	key := d.secret(item.SecretRef)
+	if key == "" {
+		return Delivery{}, Permanent(fmt.Errorf("opsgenie secret_ref %q is not configured", item.SecretRef))
+	}
+	responders, err := opsgenieResponders(item.NotifyConfig)
+	if err != nil {
+		return Delivery{}, Permanent(err)
+	}
+	message, err := d.renderMessage(item, defaultOpsgenieText)
+	if err != nil {
+		return Delivery{}, err
+	}
+	priority := item.NotifyConfig["priority"]
+	if priority == "" {
+		priority = opsgeniePriority(item.RiskScore)
+	}
+	base := p.baseURL
+	if base == "" {
+		base = opsgenieAPIURL
+		if item.NotifyConfig["region"] == "eu" {
+			base = opsgenieEUAPIURL
+		}
+	}
+
+	alert := map[string]any{
+		"message":     truncateRunes(message, maxOpsgenieMessage),
+		"alias":       "oc-approval-" + item.ApprovalRequestID,
+		"description": opsgenieDescription(d.summarizer.Summarize(item), item),
+		"priority":    priority,
+		"source":      d.source,
+		"tags":        []string{"openclause", "approval", item.Tool},
+		"details": map[string]string{
+			"tenant_id":           item.TenantID,
+			"approval_request_id": item.ApprovalRequestID,
+			"event_id":            item.EventID,
+			"tool_call":           item.Tool + "." + item.Action,
+			"resource":            item.Resource,
+			"risk_score":          fmt.Sprintf("%d", item.RiskScore),
+			"approver_group":      item.ApproverGroup,
+			"approval_url":        item.ApprovalURL,
+		},
+	}
+	if len(responders) > 0 {
+		alert["responders"] = responders
+	}
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return Delivery{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, base+"/v2/alerts", bytes.NewReader(body))
+	if err != nil {
+		return Delivery{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "GenieKey "+key)
+	return Delivery{}, d.post(d.httpClient, req, "opsgenie")
+}
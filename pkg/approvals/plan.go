@@ -0,0 +1,52 @@
+package approvals
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bturcanu/OpenClause/pkg/connectors"
+)
+
+// maxPlanFieldValue bounds each field value in plan text, e.g. a long Jira
+// description or Slack message.
+const maxPlanFieldValue = 500
+
+// PlanLines renders a connector plan as plain-text lines for notifications:
+// the summary, the upstream operation, then one "name: value" line per field
+// in name order. It returns nil for a nil plan.
+func PlanLines(plan *connectors.ExecPlan) []string {
+	if plan == nil {
+		return nil
+	}
+	lines := []string{"Plan: " + plan.Summary}
+	if plan.Operation != "" {
+		op := plan.Operation
+		if plan.ReadOnly {
+			op += " (read-only)"
+		}
+		lines = append(lines, "Operation: "+op)
+	}
+	names := make([]string, 0, len(plan.Fields))
+	for name := range plan.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("  %s: %s", name, planValue(plan.Fields[name])))
+	}
+	return lines
+}
+
+func planValue(v any) string {
+	s, ok := v.(string)
+	if !ok {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		s = string(b)
+	}
+	return truncateRunes(strings.ReplaceAll(s, "\n", " "), maxPlanFieldValue)
+}
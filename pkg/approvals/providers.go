@@ -0,0 +1,423 @@
+package approvals
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/connectors"
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+// NotificationProvider delivers approval notifications of one notify kind.
+// The dispatcher claims outbox rows, hands each to the provider registered
+// for its kind, and records the outcome.
+type NotificationProvider interface {
+	// ValidateRoute checks a policy or tenant notify route before it is
+	// stored. It must not depend on provider state.
+	ValidateRoute(route types.PolicyNotify) error
+	// Deliver sends item. Errors are retried with backoff unless wrapped
+	// with Permanent.
+	Deliver(ctx context.Context, item NotificationOutbox) (Delivery, error)
+}
+
+// Delivery describes a delivered notification. MessageID is set by providers
+// whose messages can be updated later (Slack's ts).
+type Delivery struct {
+	Channel   string
+	MessageID string
+}
+
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks a delivery error as not worth retrying; the outbox row is
+// failed immediately.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// DigestMessage is a tenant's periodic compliance digest. Digests are
+// delivered straight to the route's provider, not through the outbox; the
+// digest job records which periods were sent and retries failures.
+type DigestMessage struct {
+	// ID is stable for the tenant, digest and period, so a retried delivery
+	// can be recognized as a duplicate.
+	ID       string
+	TenantID string
+	Subject  string
+	// Text is the plain-text body, one line per entry.
+	Text []string
+}
+
+// DigestProvider is implemented by notification providers that can also
+// deliver compliance digests.
+type DigestProvider interface {
+	DeliverDigest(ctx context.Context, route types.PolicyNotify, m DigestMessage) error
+}
+
+// ValidateDigestRoute checks a route a tenant's digests are sent to: it
+// must be a valid notify route whose kind can deliver digests.
+func ValidateDigestRoute(route types.PolicyNotify) error {
+	if err := ValidateNotifyRoute(route); err != nil {
+		return err
+	}
+	if _, ok := routeKinds[route.Kind].(DigestProvider); !ok {
+		return fmt.Errorf("kind %s cannot deliver digests", route.Kind)
+	}
+	return nil
+}
+
+// DeliverDigest sends m with the provider registered for route's kind.
+func (d *Dispatcher) DeliverDigest(ctx context.Context, route types.PolicyNotify, m DigestMessage) error {
+	p, ok := d.provider(route.Kind).(DigestProvider)
+	if !ok {
+		return fmt.Errorf("approvals.DeliverDigest: no digest provider registered for kind %q", route.Kind)
+	}
+	return p.DeliverDigest(ctx, route, m)
+}
+
+// routeKinds are the notify kinds a route may name. slack_update and
+// slack_comment rows are created internally and never configured.
+var routeKinds = map[string]NotificationProvider{
+	"webhook":  webhookProvider{},
+	"slack":    slackProvider{},
+	"teams":    teamsProvider{},
+	"email":    emailProvider{},
+	"sms":      smsProvider{},
+	"opsgenie": opsgenieProvider{},
+}
+
+// ValidateNotifyRoute checks a notify route against the requirements of its
+// kind.
+func ValidateNotifyRoute(route types.PolicyNotify) error {
+	p, ok := routeKinds[route.Kind]
+	if !ok {
+		kinds := make([]string, 0, len(routeKinds))
+		for k := range routeKinds {
+			kinds = append(kinds, k)
+		}
+		sort.Strings(kinds)
+		return fmt.Errorf("kind must be one of %s", strings.Join(kinds, ", "))
+	}
+	return p.ValidateRoute(route)
+}
+
+// ── Webhook ──────────────────────────────────────────────────────────────────
+
+// webhookProvider POSTs a signed CloudEvent to the route URL, with its data
+// encrypted when the tenant has an encryption key.
+type webhookProvider struct{ d *Dispatcher }
+
+func (webhookProvider) ValidateRoute(route types.PolicyNotify) error {
+	if route.URL == "" {
+		return errors.New("webhook requires url")
+	}
+	return nil
+}
+
+func (p webhookProvider) Deliver(ctx context.Context, item NotificationOutbox) (Delivery, error) {
+	d := p.d
+	if item.NotifyURL == "" {
+		return Delivery{}, Permanent(errors.New("webhook notify_url is empty"))
+	}
+	// A tenant with a key never gets its data in the clear: a failed
+	// lookup is retried rather than sent unencrypted.
+	key, err := d.encryptionKey(ctx, item.TenantID)
+	if err != nil {
+		return Delivery{}, fmt.Errorf("webhook encryption key: %w", err)
+	}
+	var body []byte
+	if key != nil {
+		body, err = BuildEncryptedApprovalRequestedCloudEvent(item, d.source, d.summarizer.Summarize(item), *key)
+		if err != nil {
+			return Delivery{}, Permanent(fmt.Errorf("webhook encryption: %w", err))
+		}
+	} else if body, err = BuildApprovalRequestedCloudEvent(item, d.source, d.summarizer.Summarize(item)); err != nil {
+		return Delivery{}, err
+	}
+	req, err := d.webhookRequest(ctx, item.TenantID, item.NotifyURL, body)
+	if err != nil {
+		return Delivery{}, err
+	}
+	req.Header.Set("Content-Type", "application/cloudevents+json")
+	req.Header.Set("Ce-Specversion", "1.0")
+	req.Header.Set("Ce-Type", types.EventApprovalRequested)
+	req.Header.Set("Ce-Id", item.ID)
+	req.Header.Set("Ce-Source", d.source)
+	// The outbox row is one delivery; Attempts was incremented when it was
+	// claimed.
+	SignWebhookRequest(req, body, d.secret(item.SecretRef), item.ID, item.Attempts, time.Now())
+	return Delivery{}, d.post(d.webhookClient, req, "webhook")
+}
+
+// post sends req with c and treats any non-2xx status as an error.
+func (d *Dispatcher) post(c *http.Client, req *http.Request, what string) error {
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	return fmt.Errorf("%s status=%d", what, resp.StatusCode)
+}
+
+// ── Slack ────────────────────────────────────────────────────────────────────
+
+// slackProvider posts an interactive approval message through the Slack
+// connector and reports where it was posted.
+type slackProvider struct{ d *Dispatcher }
+
+func (slackProvider) ValidateRoute(route types.PolicyNotify) error {
+	if route.Channel == "" {
+		return errors.New("slack requires channel")
+	}
+	return nil
+}
+
+func (p slackProvider) Deliver(ctx context.Context, item NotificationOutbox) (Delivery, error) {
+	if item.SlackChannel == "" {
+		return Delivery{}, Permanent(errors.New("slack channel is empty"))
+	}
+	params := map[string]any{
+		"channel":             item.SlackChannel,
+		"tool":                item.Tool,
+		"action":              item.Action,
+		"resource":            item.Resource,
+		"risk_score":          item.RiskScore,
+		"reason":              item.Reason,
+		"approval_url":        item.ApprovalURL,
+		"approval_request_id": item.ApprovalRequestID,
+		"event_id":            item.EventID,
+		"tenant_id":           item.TenantID,
+		"risk_factors":        item.RiskFactors,
+	}
+	if item.Plan != nil {
+		params["plan"] = item.Plan
+	}
+	if len(item.RecentActivity) > 0 {
+		params["recent_activity"] = item.RecentActivity
+	}
+	out, err := p.d.execSlack(ctx, item, "approval.request", params)
+	if err != nil {
+		return Delivery{}, err
+	}
+	var posted struct {
+		Channel string `json:"channel"`
+		TS      string `json:"ts"`
+	}
+	if len(out) > 0 {
+		if err := json.Unmarshal(out, &posted); err != nil {
+			slog.Warn("slack approval response not understood; message will not be updated", "id", item.ID, "error", err)
+		}
+	}
+	return Delivery{Channel: posted.Channel, MessageID: posted.TS}, nil
+}
+
+// slackUpdateProvider rewrites a posted approval message with the request's
+// resolution and removes its buttons. It waits for the parent "slack" row
+// to be sent.
+type slackUpdateProvider struct{ d *Dispatcher }
+
+var errParentNotPosted = errors.New("slack approval message not yet posted")
+
+func (slackUpdateProvider) ValidateRoute(types.PolicyNotify) error {
+	return errors.New("slack_update is created internally")
+}
+
+func (p slackUpdateProvider) Deliver(ctx context.Context, item NotificationOutbox) (Delivery, error) {
+	channel, ts, err := p.d.parentSlackMessage(ctx, item)
+	if err != nil {
+		return Delivery{}, err
+	}
+	params := map[string]any{
+		"channel":             channel,
+		"ts":                  ts,
+		"status":              item.Resolution,
+		"resolved_by":         item.ResolvedBy,
+		"resolution_reason":   item.ResolutionReason,
+		"tool":                item.Tool,
+		"action":              item.Action,
+		"resource":            item.Resource,
+		"risk_score":          item.RiskScore,
+		"reason":              item.Reason,
+		"approval_url":        item.ApprovalURL,
+		"approval_request_id": item.ApprovalRequestID,
+	}
+	_, err = p.d.execSlack(ctx, item, "approval.resolve", params)
+	return Delivery{}, err
+}
+
+// slackCommentProvider posts a comment on the request as a reply in the
+// thread of its Slack approval message, once that message is posted.
+type slackCommentProvider struct{ d *Dispatcher }
+
+func (slackCommentProvider) ValidateRoute(types.PolicyNotify) error {
+	return errors.New("slack_comment is created internally")
+}
+
+func (p slackCommentProvider) Deliver(ctx context.Context, item NotificationOutbox) (Delivery, error) {
+	if item.Comment == nil {
+		return Delivery{}, Permanent(errors.New("slack_comment row has no comment"))
+	}
+	channel, ts, err := p.d.parentSlackMessage(ctx, item)
+	if err != nil {
+		return Delivery{}, err
+	}
+	params := map[string]any{
+		"channel":             channel,
+		"thread_ts":           ts,
+		"author":              item.Comment.Author,
+		"role":                item.Comment.Role,
+		"body":                item.Comment.Body,
+		"approval_url":        item.ApprovalURL,
+		"approval_request_id": item.ApprovalRequestID,
+	}
+	_, err = p.d.execSlack(ctx, item, "approval.comment", params)
+	return Delivery{}, err
+}
+
+// parentSlackMessage returns where the "slack" row item.ParentID posted its
+// message. It fails with errParentNotPosted, to retry, while the message is
+// still in flight.
+func (d *Dispatcher) parentSlackMessage(ctx context.Context, item NotificationOutbox) (channel, ts string, err error) {
+	parent, err := d.store.SlackMessageFor(ctx, item.ParentID)
+	if err != nil {
+		return "", "", err
+	}
+	switch {
+	case parent == nil || parent.Status == "failed":
+		return "", "", Permanent(errors.New("slack approval message was never posted"))
+	case parent.Status != "sent":
+		return "", "", errParentNotPosted
+	case parent.TS == "":
+		return "", "", Permanent(errors.New("slack approval message has no ts"))
+	}
+	channel = parent.Channel
+	if channel == "" {
+		channel = item.SlackChannel
+	}
+	return channel, parent.TS, nil
+}
+
+// execSlack runs a slack connector action and returns its output_json.
+func (d *Dispatcher) execSlack(ctx context.Context, item NotificationOutbox, action string, params map[string]any) (json.RawMessage, error) {
+	if d.slackURL == "" {
+		return nil, fmt.Errorf("slack connector url is empty")
+	}
+	paramsJSON, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	execReqBody, err := json.Marshal(connectors.ExecRequest{
+		EventID:  item.EventID,
+		TenantID: item.TenantID,
+		Tool:     "slack",
+		Action:   action,
+		Params:   paramsJSON,
+		Resource: item.Resource,
+		// The connector gets as long as the notifier waits for it.
+		TimeoutMS: d.httpClient.Timeout.Milliseconds(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.slackURL+"/exec", bytes.NewReader(execReqBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if d.internalToken != "" {
+		req.Header.Set("X-Internal-Token", d.internalToken)
+	}
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil, fmt.Errorf("slack connector status=%d", resp.StatusCode)
+	}
+	var execResp connectors.ExecResponse
+	if err := json.NewDecoder(resp.Body).Decode(&execResp); err != nil {
+		return nil, err
+	}
+	if execResp.Status != "success" {
+		return nil, fmt.Errorf("slack delivery failed: %s", execResp.Error)
+	}
+	return execResp.OutputJSON, nil
+}
+
+// ── Microsoft Teams ──────────────────────────────────────────────────────────
+
+// teamsProvider posts a message card to a Teams incoming webhook URL.
+type teamsProvider struct{ d *Dispatcher }
+
+func (teamsProvider) ValidateRoute(route types.PolicyNotify) error {
+	if route.URL == "" {
+		return errors.New("teams requires url")
+	}
+	return nil
+}
+
+func (p teamsProvider) Deliver(ctx context.Context, item NotificationOutbox) (Delivery, error) {
+	d := p.d
+	if item.NotifyURL == "" {
+		return Delivery{}, Permanent(errors.New("teams notify_url is empty"))
+	}
+	summary := d.summarizer.Summarize(item)
+	sections := []map[string]any{{
+		"facts": []map[string]string{
+			{"name": "Tenant", "value": item.TenantID},
+			{"name": "Tool call", "value": item.Tool + "." + item.Action},
+			{"name": "Resource", "value": item.Resource},
+			{"name": "Risk", "value": fmt.Sprintf("%d", item.RiskScore)},
+			{"name": "Approver group", "value": item.ApproverGroup},
+		},
+	}}
+	if lines := PlanLines(item.Plan); lines != nil {
+		// Teams renders a trailing double space as a line break.
+		sections = append(sections, map[string]any{"text": strings.Join(lines, "  \n")})
+	}
+	if lines := ActivityLines(item.RecentActivity); lines != nil {
+		sections = append(sections, map[string]any{"text": strings.Join(lines, "  \n")})
+	}
+	card := map[string]any{
+		"@type":      "MessageCard",
+		"@context":   "https://schema.org/extensions",
+		"summary":    summary,
+		"themeColor": "D69E2E",
+		"title":      "Approval needed",
+		"text":       summary,
+		"sections":   sections,
+		"potentialAction": []map[string]any{{
+			"@type":   "OpenUri",
+			"name":    "Review",
+			"targets": []map[string]string{{"os": "default", "uri": item.ApprovalURL}},
+		}},
+	}
+	body, err := json.Marshal(card)
+	if err != nil {
+		return Delivery{}, err
+	}
+	req, err := d.webhookRequest(ctx, item.TenantID, item.NotifyURL, body)
+	if err != nil {
+		return Delivery{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return Delivery{}, d.post(d.webhookClient, req, "teams")
+}
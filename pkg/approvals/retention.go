@@ -0,0 +1,63 @@
+package approvals
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// OutboxStats is a snapshot of the notification outbox: the number of rows
+// in each status and when the oldest row still waiting to be delivered
+// (pending or processing) was queued.
+type OutboxStats struct {
+	Depth         map[string]int64
+	OldestPending *time.Time
+}
+
+// outboxRetentionStore is the part of the outbox the retention job and the
+// outbox gauges use.
+type outboxRetentionStore interface {
+	ListArchivableNotifications(ctx context.Context, cutoff time.Time, limit int) ([]DeadLetter, error)
+	DeleteNotifications(ctx context.Context, ids []string) (int64, error)
+	OutboxStats(ctx context.Context) (OutboxStats, error)
+}
+
+// RegisterOutboxGauges publishes the outbox depth by status and the age of
+// the oldest undelivered row, so a stalled dispatcher or a table that is
+// not being archived shows up before it becomes a problem. Both are read
+// from the store on each collection.
+func RegisterOutboxGauges(store outboxRetentionStore) error {
+	meter := otel.Meter("github.com/bturcanu/OpenClause/pkg/approvals")
+	depth, err := meter.Int64ObservableGauge("oc.approvals.outbox.depth",
+		metric.WithDescription("Notification outbox rows, by status."),
+	)
+	if err != nil {
+		return err
+	}
+	age, err := meter.Float64ObservableGauge("oc.approvals.outbox.oldest_pending_age",
+		metric.WithDescription("Age of the oldest pending or processing notification outbox row."),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return err
+	}
+	_, err = meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		stats, err := store.OutboxStats(ctx)
+		if err != nil {
+			return err
+		}
+		for status, n := range stats.Depth {
+			o.ObserveInt64(depth, n, metric.WithAttributes(attribute.String("status", status)))
+		}
+		var oldest float64
+		if stats.OldestPending != nil {
+			oldest = time.Since(*stats.OldestPending).Seconds()
+		}
+		o.ObserveFloat64(age, oldest)
+		return nil
+	}, depth, age)
+	return err
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/approvals"
+	"github.com/bturcanu/OpenClause/pkg/evidence"
+	"github.com/bturcanu/OpenClause/pkg/oidc"
+)
+
+// auditResolutions writes approval outcomes and comments to the audit log.
+type auditResolutions struct {
+	audit *evidence.AuditLogger
+	log   *slog.Logger
+}
+
+// auditResolution is the body of an approval_* audit record.
+type auditResolution struct {
+	RequestID     string `json:"request_id"`
+	AgentID       string `json:"agent_id"`
+	Tool          string `json:"tool"`
+	Action        string `json:"action"`
+	Resource      string `json:"resource,omitempty"`
+	RiskScore     int    `json:"risk_score"`
+	Kind          string `json:"kind,omitempty"`
+	ApproverGroup string `json:"approver_group,omitempty"`
+	Approver      string `json:"approver,omitempty"`
+	// ApproverIssuer and ApproverSubject identify an approver who signed
+	// in with OIDC.
+	ApproverIssuer  string    `json:"approver_issuer,omitempty"`
+	ApproverSubject string    `json:"approver_subject,omitempty"`
+	Reason          string    `json:"reason,omitempty"`
+	ResolvedAt      time.Time `json:"resolved_at"`
+}
+
+func (a auditResolutions) PublishResolution(ctx context.Context, res approvals.Resolution) {
+	req := res.Request
+	body := auditResolution{
+		RequestID:     req.ID,
+		AgentID:       req.AgentID,
+		Tool:          req.Tool,
+		Action:        req.Action,
+		Resource:      req.Resource,
+		RiskScore:     req.RiskScore,
+		Kind:          req.Kind,
+		ApproverGroup: req.ApproverGroup,
+		Approver:      res.Approver,
+		Reason:        res.Reason,
+		ResolvedAt:    res.At,
+	}
+	if s, ok := oidc.FromContext(ctx); ok {
+		body.ApproverIssuer, body.ApproverSubject = s.Issuer, s.Subject
+	}
+	if err := a.audit.Record(ctx, "approval_"+res.Status, req.TenantID, req.EventID, "", body); err != nil {
+		a.log.ErrorContext(ctx, "audit log write failed", "request_id", req.ID, "error", err)
+	}
+}
+
+// PublishComment records c as an "approval_comment", with the signed-in
+// approver's identity when there is one.
+func (a auditResolutions) PublishComment(ctx context.Context, req approvals.ApprovalRequest, c approvals.Comment) {
+	body := struct {
+		approvals.Comment
+		AgentID         string `json:"agent_id"`
+		ApproverIssuer  string `json:"approver_issuer,omitempty"`
+		ApproverSubject string `json:"approver_subject,omitempty"`
+	}{Comment: c, AgentID: req.AgentID}
+	if s, ok := oidc.FromContext(ctx); ok && c.Role == approvals.RoleApprover {
+		body.ApproverIssuer, body.ApproverSubject = s.Issuer, s.Subject
+	}
+	if err := a.audit.Record(ctx, "approval_comment", req.TenantID, req.EventID, "", body); err != nil {
+		a.log.ErrorContext(ctx, "audit log write failed", "request_id", req.ID, "error", err)
+	}
+}
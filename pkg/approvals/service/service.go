@@ -0,0 +1,516 @@
+// Package service runs the approvals service: the approvals API, Slack
+// interactions, the pending-approvals page, and the background notifier and
+// digest jobs. cmd/approvals runs it on its own; cmd/openclause runs it
+// alongside the gateway in one process.
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/approvals"
+	"github.com/bturcanu/OpenClause/pkg/config"
+	"github.com/bturcanu/OpenClause/pkg/dashboard"
+	"github.com/bturcanu/OpenClause/pkg/diagnostics"
+	"github.com/bturcanu/OpenClause/pkg/digest"
+	"github.com/bturcanu/OpenClause/pkg/directory"
+	"github.com/bturcanu/OpenClause/pkg/events"
+	"github.com/bturcanu/OpenClause/pkg/evidence"
+	"github.com/bturcanu/OpenClause/pkg/mysqldb"
+	"github.com/bturcanu/OpenClause/pkg/oidc"
+	"github.com/bturcanu/OpenClause/pkg/pgpool"
+	"github.com/bturcanu/OpenClause/pkg/secrets"
+	"github.com/bturcanu/OpenClause/pkg/siem"
+	"github.com/bturcanu/OpenClause/pkg/tenants"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Options are what a process embedding the approvals service shares with
+// it. Zero fields are built from the environment, as cmd/approvals does.
+type Options struct {
+	// Pool is the Postgres pool; nil connects with the POSTGRES_* and
+	// PG_POOL_* settings, except in lite mode, which runs without one.
+	Pool *pgxpool.Pool
+	// NotifyTransport carries notification webhooks and Slack connector
+	// calls; nil uses HTTP. A connectors.Local serves an in-process Slack
+	// connector at an inproc:// CONNECTOR_SLACK_URL.
+	NotifyTransport http.RoundTripper
+	// Audit receives the audit trail of approval outcomes; nil opens the
+	// AUDIT_LOG_* destinations as service oc-approvals.
+	Audit *evidence.AuditLogger
+	// Env holds settings for variables the process environment leaves
+	// unset, such as those openclause.LoadConfig reads from a file.
+	Env config.Env
+}
+
+// Server is an approvals service built by New: its HTTP API and the
+// notifier and digest jobs behind it.
+type Server struct {
+	handler http.Handler
+	cancel  context.CancelFunc
+	closers []func(context.Context) error
+}
+
+// New builds the approvals service from the environment and starts its
+// background jobs without serving it, so a service embedding it can mount
+// Handler on its own server. ctx bounds the jobs; Close stops them and
+// releases what New opened. Configuration must already be loaded.
+func New(ctx context.Context, log *slog.Logger, opts Options) (_ *Server, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	s := &Server{cancel: cancel}
+	env := opts.Env
+	defer func() {
+		if err != nil {
+			_ = s.Close(context.Background())
+		}
+	}()
+
+	// ── Postgres ─────────────────────────────────────────────────────────
+	// Lite mode runs without Postgres: tenants get the default settings and
+	// digests are unavailable.
+	pool := opts.Pool
+	if pool == nil && env.Get("OC_MODE") != config.LiteMode {
+		pool, err = pgpool.New(ctx, pgpool.DSNFromEnv(env), pgpool.ConfigFromEnv(env))
+		if err != nil {
+			return nil, fmt.Errorf("service.New: postgres connect: %w", err)
+		}
+		s.onClose(func(context.Context) error { pool.Close(); return nil })
+		if err := pgpool.RegisterMetrics(pool, "postgres"); err != nil {
+			log.Error("register pool metrics failed", "error", err)
+		}
+	}
+
+	var store approvals.Backend
+	switch backend := env.Or("APPROVALS_BACKEND", "postgres"); backend {
+	case "postgres":
+		store = approvals.NewStore(pool)
+	case "mysql":
+		mysqlDB, err := mysqldb.Open(ctx, env.Get("MYSQL_DSN"))
+		if err != nil {
+			return nil, fmt.Errorf("service.New: mysql connect: %w", err)
+		}
+		s.onClose(func(context.Context) error { return mysqlDB.Close() })
+		store = approvals.NewMySQLStore(mysqlDB)
+	case "sqlite":
+		sqliteStore, err := approvals.OpenSQLite(ctx, env.Or("APPROVALS_SQLITE_PATH", "openclause-approvals.db"))
+		if err != nil {
+			return nil, fmt.Errorf("service.New: sqlite store open: %w", err)
+		}
+		s.onClose(func(context.Context) error { return sqliteStore.Close() })
+		store = sqliteStore
+	default:
+		return nil, fmt.Errorf("service.New: unknown APPROVALS_BACKEND %q", backend)
+	}
+	if err := approvals.RegisterPendingGauge(store); err != nil {
+		log.Error("register pending gauge failed", "error", err)
+	}
+	if err := approvals.RegisterOutboxGauges(store); err != nil {
+		log.Error("register outbox gauges failed", "error", err)
+	}
+	internalToken := env.Get("INTERNAL_AUTH_TOKEN")
+	if internalToken == "" {
+		return nil, errors.New("service.New: INTERNAL_AUTH_TOKEN is required")
+	}
+
+	// ── Secrets ──────────────────────────────────────────────────────────
+	secretResolver := secrets.NewResolverFromEnv(env, log)
+	slackSigningSecret, err := secretResolver.Resolve(ctx, env.Get("SLACK_SIGNING_SECRET"))
+	if err != nil {
+		return nil, fmt.Errorf("service.New: resolve SLACK_SIGNING_SECRET: %w", err)
+	}
+	// Previous secrets stay valid while a Slack signing secret rotation rolls out.
+	slackPreviousSecrets, err := secretResolver.Resolve(ctx, env.Get("SLACK_SIGNING_SECRET_PREVIOUS"))
+	if err != nil {
+		return nil, fmt.Errorf("service.New: resolve SLACK_SIGNING_SECRET_PREVIOUS: %w", err)
+	}
+	slackSigningSecrets := []string{slackSigningSecret}
+	for _, prev := range strings.Split(slackPreviousSecrets, ",") {
+		if prev = strings.TrimSpace(prev); prev != "" {
+			slackSigningSecrets = append(slackSigningSecrets, prev)
+		}
+	}
+	authorizer, err := approverAuthorizer(ctx, env, log, secretResolver)
+	if err != nil {
+		return nil, err
+	}
+	handlers := approvals.NewHandlers(store, authorizer, slackSigningSecrets...)
+	authn, err := approverOIDC(ctx, env, log, secretResolver)
+	if err != nil {
+		return nil, err
+	}
+	// Signed-in approvers replace the free-text approver name.
+	handlers.SetRequireVerifiedApprover(authn != nil)
+	// Tenant settings live in Postgres regardless of APPROVALS_BACKEND; a
+	// nil cache, in lite mode, applies the defaults.
+	var settingsCache *tenants.SettingsCache
+	if pool != nil {
+		settingsCache = tenants.NewSettingsCache(tenants.NewStore(pool), env.Duration("TENANT_SETTINGS_CACHE_SEC", time.Second, 30*time.Second))
+	}
+	handlers.SetInputDefaults(settingsCache.ApplyApprovalDefaults)
+	handlers.SetAutoApproval(settingsCache.AutoApproval)
+	handlers.SetGrantDefaults(settingsCache.ApplyGrantDefaults)
+	emitter := events.New(events.Config{
+		Source:    env.Or("EVENTS_SOURCE", "oc://approvals"),
+		QueueSize: env.Int("EVENTS_QUEUE_SIZE", 1000),
+	}, settingsCache.EventSubscriptions)
+	s.onClose(func(context.Context) error { return emitter.Close() })
+	handlers.AddRequestSink(emitter)
+	handlers.AddResolutionSink(emitter)
+	audit := opts.Audit
+	if audit == nil {
+		if audit, err = evidence.OpenAuditLog(evidence.AuditConfigFromEnv(env, "oc-approvals")); err != nil {
+			return nil, fmt.Errorf("service.New: audit log setup: %w", err)
+		}
+		s.onClose(func(context.Context) error { return audit.Close() })
+	}
+	if audit != nil {
+		auditSink := auditResolutions{audit: audit, log: log}
+		handlers.AddResolutionSink(auditSink)
+		handlers.AddCommentSink(auditSink)
+	}
+	if path := env.Get("SIEM_CONFIG_FILE"); path != "" {
+		siemRouter, err := siem.NewFromFile(ctx, path, env, secretResolver)
+		if err != nil {
+			return nil, fmt.Errorf("service.New: siem setup: %w", err)
+		}
+		handlers.AddResolutionSink(siemRouter)
+		s.onClose(func(context.Context) error { return siemRouter.Close() })
+	}
+	dispatcher := approvals.NewDispatcher(
+		store,
+		env.Or("APPROVALS_NOTIFIER_SOURCE", "oc://approvals"),
+		nil,
+		env.Or("CONNECTOR_SLACK_URL", "http://localhost:8082"),
+		internalToken,
+	)
+	if opts.NotifyTransport != nil {
+		dispatcher.SetTransport(opts.NotifyTransport)
+	}
+	dispatcher.SetEncryptionKeys(settingsCache.NotificationKey)
+	// Webhook destinations are held to the tenant's egress policy, or to
+	// the deployment's.
+	egressDefault, err := approvals.ParseEgressPolicy(env.Get("WEBHOOK_ALLOWED_DOMAINS"), env.Get("WEBHOOK_ALLOWED_CIDRS"))
+	if err != nil {
+		return nil, fmt.Errorf("service.New: %w", err)
+	}
+	egress := &approvals.Egress{Default: egressDefault, Tenants: settingsCache.WebhookEgress}
+	dispatcher.SetEgress(egress)
+	emitter.SetEgress(egress)
+	dispatcher.SetDestinationLimit(
+		float64(env.Int("APPROVALS_NOTIFIER_DEST_RATE_PER_MIN", 120))/60,
+		env.Int("APPROVALS_NOTIFIER_DEST_BURST", 10),
+	)
+	for ref, raw := range approvals.ParseSecretRefMap(env.Get("WEBHOOK_SECRET_REFS")) {
+		secret, err := secretResolver.Bind(ctx, raw, func(v string) {
+			dispatcher.SetSecret(ref, v)
+			emitter.SetSecret(ref, v)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("service.New: resolve WEBHOOK_SECRET_REFS %s: %w", ref, err)
+		}
+		dispatcher.SetSecret(ref, secret.Get())
+		emitter.SetSecret(ref, secret.Get())
+	}
+	// The email provider is only available when an SMTP relay is configured.
+	if addr := env.Get("NOTIFY_SMTP_ADDR"); addr != "" {
+		smtpPassword, err := secretResolver.Resolve(ctx, env.Get("NOTIFY_SMTP_PASSWORD"))
+		if err != nil {
+			return nil, fmt.Errorf("service.New: resolve NOTIFY_SMTP_PASSWORD: %w", err)
+		}
+		dispatcher.RegisterProvider("email", approvals.NewEmailProvider(approvals.EmailConfig{
+			Addr:     addr,
+			From:     env.Get("NOTIFY_SMTP_FROM"),
+			Username: env.Get("NOTIFY_SMTP_USERNAME"),
+			Password: smtpPassword,
+		}))
+	}
+	go secretResolver.Run(ctx, env.Duration("SECRETS_REFRESH_SEC", time.Second, 5*time.Minute))
+
+	// ── Router ───────────────────────────────────────────────────────────
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(middleware.RealIP)
+	r.Use(middleware.Recoverer)
+	r.Use(middleware.Timeout(15 * time.Second))
+
+	r.Get("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("OK"))
+	})
+
+	// Slack interactions are externally authenticated via Slack signature headers.
+	r.Post("/v1/integrations/slack/interactions", handlers.SlackInteractions)
+
+	// API routes with internal auth. With OIDC configured, approve and deny
+	// also need the approver's ID token as a bearer token.
+	ui := &pendingUI{store: store, handlers: handlers, authorizer: authorizer, log: log}
+	r.Group(func(r chi.Router) {
+		r.Use(internalAuthMiddleware(internalToken))
+		if authn != nil {
+			r.Use(authn.Authenticate)
+		}
+		handlers.RegisterRoutes(r)
+		approvals.NewDeadLetterHandlers(store).RegisterRoutes(r)
+		if authn == nil {
+			// Minimal web UI for pending approvals
+			r.Get("/ui/pending", ui.pending)
+		}
+	})
+	// With OIDC, approvers sign in to the web UI and resolve requests
+	// from it.
+	if authn != nil {
+		authn.RegisterRoutes(r)
+		r.Group(func(r chi.Router) {
+			r.Use(authn.RequireSession)
+			r.Get("/ui/pending", ui.pending)
+			r.Post("/ui/requests/{id}/approve", ui.resolve("approve"))
+			r.Post("/ui/requests/{id}/release", ui.resolve("release"))
+			r.Post("/ui/requests/{id}/deny", ui.resolve("deny"))
+			r.Post("/ui/requests/{id}/comments", ui.comment)
+		})
+	}
+
+	if env.Bool("APPROVALS_NOTIFIER_ENABLED", true) {
+		interval := env.Duration("APPROVALS_NOTIFIER_INTERVAL_SEC", time.Second, 5*time.Second)
+		go func() {
+			t := time.NewTicker(interval)
+			defer t.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-t.C:
+					// Expire first so the resulting Slack updates go out this tick.
+					if ids, err := store.ExpireRequests(ctx); err != nil {
+						log.Error("approval expiry failed", "error", err)
+					} else if len(ids) > 0 {
+						log.Info("expired approval requests", "count", len(ids))
+						handlers.PublishExpired(ctx, ids)
+					}
+					// Likewise auto-approve before dispatch.
+					if err := handlers.AutoApprovePending(ctx); err != nil {
+						log.Error("auto-approval failed", "error", err)
+					}
+					if err := dispatcher.DispatchOnce(ctx); err != nil {
+						log.Error("notification dispatch failed", "error", err)
+					}
+				}
+			}
+		}()
+	}
+
+	// Digests read the gateway's evidence tables, so they need the Postgres
+	// evidence and approvals backends like the dashboard does.
+	if env.Bool("APPROVALS_DIGESTS_ENABLED", false) {
+		if pool == nil || env.Or("EVIDENCE_BACKEND", "postgres") != "postgres" || env.Or("APPROVALS_BACKEND", "postgres") != "postgres" {
+			return nil, errors.New("service.New: APPROVALS_DIGESTS_ENABLED requires the postgres evidence and approvals backends")
+		}
+		job := digest.NewJob(dashboard.NewStore(pool), settingsCache, dispatcher, digest.NewStore(pool), log)
+		interval := env.Duration("APPROVALS_DIGESTS_INTERVAL_SEC", time.Second, 5*time.Minute)
+		go func() {
+			t := time.NewTicker(interval)
+			defer t.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-t.C:
+					if err := job.RunOnce(ctx); err != nil {
+						log.Error("digest run failed", "error", err)
+					}
+				}
+			}
+		}()
+	}
+
+	s.handler = r
+	return s, nil
+}
+
+// Handler returns the approvals API, the Slack interactions endpoint and
+// the pending-approvals page.
+func (s *Server) Handler() http.Handler { return s.handler }
+
+// Close stops the background jobs and releases what New opened. Call it
+// once requests have drained.
+func (s *Server) Close(ctx context.Context) error {
+	s.cancel()
+	var errs []error
+	for i := len(s.closers) - 1; i >= 0; i-- {
+		if err := s.closers[i](ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	s.closers = nil
+	return errors.Join(errs...)
+}
+
+func (s *Server) onClose(fn func(context.Context) error) {
+	s.closers = append(s.closers, fn)
+}
+
+// Run builds the approvals service from the environment and serves it on
+// APPROVALS_ADDR, with metrics and diagnostics on APPROVALS_METRICS_ADDR,
+// until ctx is done or the server fails. Configuration must already be
+// loaded.
+func Run(ctx context.Context, log *slog.Logger, opts Options) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	s, err := New(ctx, log, opts)
+	if err != nil {
+		return err
+	}
+	env := opts.Env
+
+	// ── Server ───────────────────────────────────────────────────────────
+	addr := env.Or("APPROVALS_ADDR", ":8081")
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           s.Handler(),
+		ReadTimeout:       15 * time.Second,
+		ReadHeaderTimeout: 5 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       60 * time.Second,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Info("approvals service starting", "addr", addr)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			serveErr <- err
+			cancel()
+		}
+	}()
+
+	// ── Metrics + diagnostics (internal) ────────────────────────────────
+	metricsAddr := env.Or("APPROVALS_METRICS_ADDR", "127.0.0.1:9091")
+	metricsSrv := diagnostics.NewServer(diagnostics.Config{Addr: metricsAddr, InternalToken: env.Get("INTERNAL_AUTH_TOKEN")})
+	go func() {
+		log.Info("metrics server starting", "addr", metricsAddr)
+		if err := metricsSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Error("metrics server error", "error", err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Info("shutting down approvals service")
+	shutCtx, shutCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer shutCancel()
+	if err := srv.Shutdown(shutCtx); err != nil {
+		log.Error("shutdown error", "error", err)
+	}
+	if err := metricsSrv.Shutdown(shutCtx); err != nil {
+		log.Error("metrics server shutdown error", "error", err)
+	}
+	if err := s.Close(shutCtx); err != nil {
+		log.Error("approvals service close failed", "error", err)
+	}
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("service.Run: serve: %w", err)
+	default:
+		return nil
+	}
+}
+
+// approverAuthorizer authorizes approvers by membership of the tenant's
+// directory groups when APPROVER_DIRECTORY names a provider, syncing them in
+// the background until ctx is done, and by the env allowlists otherwise.
+func approverAuthorizer(ctx context.Context, env config.Env, log *slog.Logger, resolver *secrets.Resolver) (approvals.Authorizer, error) {
+	kind := env.Get("APPROVER_DIRECTORY")
+	if kind == "" {
+		return approvals.NewApproverAuthorizer(
+			env.Get("APPROVER_EMAIL_ALLOWLIST"),
+			env.Get("APPROVER_SLACK_ALLOWLIST"),
+		), nil
+	}
+	groups, err := directory.ParseGroups(env.Get("APPROVER_DIRECTORY_GROUPS"))
+	if err != nil {
+		return nil, fmt.Errorf("service.New: APPROVER_DIRECTORY_GROUPS: %w", err)
+	}
+	token, err := resolver.Resolve(ctx, env.Get("APPROVER_DIRECTORY_TOKEN"))
+	if err != nil {
+		return nil, fmt.Errorf("service.New: resolve APPROVER_DIRECTORY_TOKEN: %w", err)
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	baseURL := env.Get("APPROVER_DIRECTORY_URL")
+	slackAttr := env.Get("APPROVER_DIRECTORY_SLACK_ATTRIBUTE")
+	var provider directory.Provider
+	switch kind {
+	case "scim":
+		provider = &directory.SCIM{BaseURL: baseURL, Token: token, SlackAttribute: slackAttr, HTTPClient: client}
+	case "okta":
+		provider = &directory.Okta{BaseURL: baseURL, Token: token, SlackAttribute: slackAttr, HTTPClient: client}
+	case "azuread":
+		// Azure AD authenticates with a client secret, so the token variable
+		// carries it.
+		provider = &directory.AzureAD{
+			TenantID:       env.Get("APPROVER_DIRECTORY_AZURE_TENANT_ID"),
+			ClientID:       env.Get("APPROVER_DIRECTORY_AZURE_CLIENT_ID"),
+			ClientSecret:   token,
+			SlackAttribute: slackAttr,
+			GraphURL:       baseURL,
+			HTTPClient:     client,
+		}
+	default:
+		return nil, fmt.Errorf("service.New: unknown APPROVER_DIRECTORY %q", kind)
+	}
+	dir := directory.New(provider, groups, log)
+	go dir.Run(ctx, env.Duration("APPROVER_DIRECTORY_SYNC_SEC", time.Second, 5*time.Minute))
+	return dir, nil
+}
+
+// approverOIDC returns the authenticator that signs approvers in with the
+// APPROVER_OIDC_ISSUER provider, or nil when none is configured.
+func approverOIDC(ctx context.Context, env config.Env, log *slog.Logger, resolver *secrets.Resolver) (*oidc.Authenticator, error) {
+	issuer := env.Get("APPROVER_OIDC_ISSUER")
+	if issuer == "" {
+		return nil, nil
+	}
+	clientSecret, err := resolver.Resolve(ctx, env.Get("APPROVER_OIDC_CLIENT_SECRET"))
+	if err != nil {
+		return nil, fmt.Errorf("service.New: resolve APPROVER_OIDC_CLIENT_SECRET: %w", err)
+	}
+	sessionKey, err := resolver.Resolve(ctx, env.Get("APPROVALS_SESSION_KEY"))
+	if err != nil {
+		return nil, fmt.Errorf("service.New: resolve APPROVALS_SESSION_KEY: %w", err)
+	}
+	provider, err := oidc.Discover(ctx, oidc.Config{
+		Issuer:       issuer,
+		ClientID:     env.Get("APPROVER_OIDC_CLIENT_ID"),
+		ClientSecret: clientSecret,
+		RedirectURL:  env.Or("APPROVER_OIDC_REDIRECT_URL", strings.TrimRight(env.Or("APPROVALS_URL", "http://localhost:8081"), "/")+"/ui/callback"),
+		Scopes:       strings.Fields(env.Or("APPROVER_OIDC_SCOPES", "openid email profile")),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("service.New: %w", err)
+	}
+	authn, err := oidc.NewAuthenticator(provider, []byte(sessionKey),
+		env.Duration("APPROVALS_SESSION_TTL_SEC", time.Second, 8*time.Hour), "/ui", log)
+	if err != nil {
+		return nil, fmt.Errorf("service.New: APPROVALS_SESSION_KEY: %w", err)
+	}
+	return authn, nil
+}
+
+// internalAuthMiddleware validates the X-Internal-Token header for service-to-service calls.
+func internalAuthMiddleware(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			provided := r.Header.Get("X-Internal-Token")
+			if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package service
+
+import (
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/bturcanu/OpenClause/pkg/approvals"
+	"github.com/bturcanu/OpenClause/pkg/oidc"
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"github.com/go-chi/chi/v5"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Minimal server-rendered UI
+// ──────────────────────────────────────────────────────────────────────────────
+
+// pendingUI serves the pending-approvals page. With OIDC sign-in the page
+// shows a signed-in approver only the requests they may resolve, with
+// approve (or, for quarantined output, release) and deny buttons and a
+// form to comment on each request's thread.
+type pendingUI struct {
+	store      approvals.Backend
+	handlers   *approvals.Handlers
+	authorizer approvals.Authorizer
+	log        *slog.Logger
+}
+
+// pending handles GET /ui/pending.
+func (u *pendingUI) pending(w http.ResponseWriter, r *http.Request) {
+	tenantID := r.URL.Query().Get("tenant_id")
+	if tenantID == "" {
+		http.Error(w, "tenant_id required", http.StatusBadRequest)
+		return
+	}
+	after, err := types.ParseCursor(r.URL.Query().Get("cursor"))
+	if err != nil {
+		http.Error(w, "invalid cursor", http.StatusBadRequest)
+		return
+	}
+	const pageSize = 100
+	reqs, err := u.store.ListPending(r.Context(), tenantID, pageSize, after)
+	if err != nil {
+		u.log.Error("list pending failed", "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	var next string
+	if len(reqs) == pageSize {
+		last := reqs[len(reqs)-1]
+		next = types.Cursor{Time: last.CreatedAt, ID: last.ID}.String()
+	}
+	session, signedIn := oidc.FromContext(r.Context())
+	if signedIn {
+		allowed := reqs[:0]
+		for _, req := range reqs {
+			if u.authorizer == nil || u.authorizer.AllowEmail(req.TenantID, req.ApproverGroup, session.Approver()) {
+				allowed = append(allowed, req)
+			}
+		}
+		reqs = allowed
+	}
+	comments := make(map[string][]approvals.Comment)
+	if len(reqs) > 0 {
+		ids := make([]string, len(reqs))
+		for i, req := range reqs {
+			ids[i] = req.ID
+		}
+		all, err := u.store.ListComments(r.Context(), ids)
+		if err != nil {
+			u.log.Error("list comments failed", "error", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		for _, c := range all {
+			comments[c.RequestID] = append(comments[c.RequestID], c)
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := pendingTmpl.Execute(w, struct {
+		TenantID   string
+		Requests   []approvals.ApprovalRequest
+		Comments   map[string][]approvals.Comment
+		NextCursor string
+		SignedIn   bool
+		Approver   string
+		CSRF       string
+	}{
+		TenantID: tenantID, Requests: reqs, Comments: comments, NextCursor: next,
+		SignedIn: signedIn, Approver: session.Approver(), CSRF: session.CSRF,
+	}); err != nil {
+		u.log.Error("template execute failed", "error", err)
+	}
+}
+
+// resolve handles the approve, release and deny forms, POST
+// /ui/requests/{id}/approve, /release and /deny, as the signed-in approver.
+func (u *pendingUI) resolve(decision string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, 64<<10)
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "invalid form", http.StatusBadRequest)
+			return
+		}
+		session, _ := oidc.FromContext(r.Context())
+		if !session.CheckCSRF(r.PostFormValue("csrf")) {
+			http.Error(w, "invalid form token; reload the page", http.StatusForbidden)
+			return
+		}
+		id := chi.URLParam(r, "id")
+		var apiErr *types.APIError
+		switch decision {
+		case "approve":
+			_, apiErr = u.handlers.Approve(r.Context(), id, approvals.GrantInput{MaxUses: 1})
+		case "release":
+			apiErr = u.handlers.Release(r.Context(), id, approvals.ReleaseInput{})
+		default:
+			apiErr = u.handlers.Deny(r.Context(), id, approvals.DenyInput{Reason: "denied from the approvals UI"})
+		}
+		if apiErr != nil {
+			http.Error(w, apiErr.Message, apiErr.HTTPCode)
+			return
+		}
+		http.Redirect(w, r, "/ui/pending?"+url.Values{"tenant_id": {r.PostFormValue("tenant_id")}}.Encode(), http.StatusSeeOther)
+	}
+}
+
+// comment handles the comment form, POST /ui/requests/{id}/comments, as the
+// signed-in approver.
+func (u *pendingUI) comment(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 64<<10)
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+	session, _ := oidc.FromContext(r.Context())
+	if !session.CheckCSRF(r.PostFormValue("csrf")) {
+		http.Error(w, "invalid form token; reload the page", http.StatusForbidden)
+		return
+	}
+	if _, apiErr := u.handlers.Comment(r.Context(), chi.URLParam(r, "id"), approvals.CommentInput{Body: r.PostFormValue("body")}); apiErr != nil {
+		http.Error(w, apiErr.Message, apiErr.HTTPCode)
+		return
+	}
+	http.Redirect(w, r, "/ui/pending?"+url.Values{"tenant_id": {r.PostFormValue("tenant_id")}}.Encode(), http.StatusSeeOther)
+}
+
+var pendingTmpl = template.Must(template.New("pending").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Pending Approvals — {{.TenantID}}</title>
+  <style>
+    body { font-family: system-ui, sans-serif; max-width: 900px; margin: 2rem auto; padding: 0 1rem; }
+    table { width: 100%; border-collapse: collapse; margin-top: 1rem; }
+    th, td { text-align: left; padding: 0.5rem 0.75rem; border-bottom: 1px solid #e2e8f0; }
+    th { background: #f7fafc; font-weight: 600; }
+    tr:hover { background: #edf2f7; }
+    .badge { display: inline-block; padding: 2px 8px; border-radius: 4px; font-size: 0.85em; }
+    .badge-pending { background: #fefcbf; color: #744210; }
+    .risk-high { color: #c53030; font-weight: 600; }
+    h1 { color: #2d3748; }
+    .empty { color: #718096; padding: 2rem 0; }
+    form.inline { display: inline; }
+    ul.activity { margin: 0.25rem 0; padding-left: 1.25rem; font-size: 0.85em; color: #4a5568; }
+    ul.comments { margin: 0.25rem 0; padding-left: 1.25rem; font-size: 0.9em; }
+    .when { color: #718096; font-size: 0.85em; }
+  </style>
+</head>
+<body>
+  <h1>Pending Approvals</h1>
+  <p>Tenant: <strong>{{.TenantID}}</strong></p>
+  {{if .SignedIn}}
+  <form class="inline" method="post" action="/ui/logout">Signed in as <strong>{{.Approver}}</strong> <button type="submit">Sign out</button></form>
+  {{end}}
+  {{if .Requests}}
+  <table>
+    <thead>
+      <tr><th>ID</th><th>Tool</th><th>Action</th><th>Agent</th><th>Risk</th><th>Reason</th><th>Created</th>{{if .SignedIn}}<th></th>{{end}}</tr>
+    </thead>
+    <tbody>
+      {{range .Requests}}
+      <tr>
+        <td><code>{{.ID}}</code></td>
+        <td>{{.Tool}}</td>
+        <td>{{.Action}}</td>
+        <td>{{.AgentID}}</td>
+        <td {{if ge .RiskScore 7}}class="risk-high"{{end}}>{{.RiskScore}}</td>
+        <td>{{if .IsQuarantine}}<span class="badge badge-pending">output withheld</span> {{end}}{{.Reason}}
+          {{with .RecentActivity}}<details><summary>Recent agent activity</summary><ul class="activity">
+            {{range .}}<li>{{.At.Format "2006-01-02 15:04:05"}} <code>{{.Tool}}.{{.Action}}</code>{{with .Resource}} on <code>{{.}}</code>{{end}}: {{.Decision}}{{with .Status}}, {{.}}{{end}}</li>{{end}}
+          </ul></details>{{end}}
+          {{with index $.Comments .ID}}<ul class="comments">
+            {{range .}}<li><strong>{{.Author}}</strong>{{if eq .Role "requester"}} (agent){{end}} <span class="when">{{.CreatedAt.Format "2006-01-02 15:04"}}</span>: {{.Body}}</li>{{end}}
+          </ul>{{end}}
+          {{if $.SignedIn}}<form method="post" action="/ui/requests/{{.ID}}/comments">
+            <input type="hidden" name="csrf" value="{{$.CSRF}}"><input type="hidden" name="tenant_id" value="{{$.TenantID}}">
+            <input type="text" name="body" maxlength="4000" placeholder="Ask a question" required> <button type="submit">Comment</button>
+          </form>{{end}}</td>
+        <td>{{.CreatedAt.Format "2006-01-02 15:04"}}</td>
+        {{if $.SignedIn}}
+        <td>
+          {{if .IsQuarantine}}
+          <form class="inline" method="post" action="/ui/requests/{{.ID}}/release">
+            <input type="hidden" name="csrf" value="{{$.CSRF}}"><input type="hidden" name="tenant_id" value="{{$.TenantID}}">
+            <button type="submit">Release</button>
+          </form>
+          {{else}}
+          <form class="inline" method="post" action="/ui/requests/{{.ID}}/approve">
+            <input type="hidden" name="csrf" value="{{$.CSRF}}"><input type="hidden" name="tenant_id" value="{{$.TenantID}}">
+            <button type="submit">Approve</button>
+          </form>
+          {{end}}
+          <form class="inline" method="post" action="/ui/requests/{{.ID}}/deny">
+            <input type="hidden" name="csrf" value="{{$.CSRF}}"><input type="hidden" name="tenant_id" value="{{$.TenantID}}">
+            <button type="submit">Deny</button>
+          </form>
+        </td>
+        {{end}}
+      </tr>
+      {{end}}
+    </tbody>
+  </table>
+  {{if .NextCursor}}<p><a href="?tenant_id={{.TenantID}}&cursor={{.NextCursor}}">Older requests &rarr;</a></p>{{end}}
+  {{else}}
+  <p class="empty">No pending approvals.</p>
+  {{end}}
+</body>
+</html>`))
@@ -0,0 +1,170 @@
+package approvals
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3" // registers the "sqlite3" driver (requires cgo)
+)
+
+// sqliteSchema mirrors the approval tables from migrations/mysql. Foreign
+// keys to tenants and tool_events are omitted: the evidence log lives in a
+// separate database file.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS approval_requests (
+    id          TEXT PRIMARY KEY,
+    event_id    TEXT NOT NULL,
+    tenant_id   TEXT NOT NULL,
+    agent_id    TEXT NOT NULL,
+    tool        TEXT NOT NULL,
+    action      TEXT NOT NULL,
+    resource    TEXT,
+    session_id  TEXT NOT NULL DEFAULT '',
+    risk_score  INTEGER NOT NULL DEFAULT 0,
+    reason      TEXT,
+    deny_reason TEXT,
+    denied_by   TEXT DEFAULT '',
+    plan        BLOB,
+    approver_group TEXT NOT NULL DEFAULT '',
+    kind        TEXT NOT NULL DEFAULT '',
+    released_by TEXT NOT NULL DEFAULT '',
+    recent_activity BLOB,
+    grant_ttl_sec INTEGER NOT NULL DEFAULT 0,
+    status      TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'denied', 'expired', 'cancelled')),
+    created_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at  TIMESTAMP,
+    expires_at  TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_approval_requests_tenant_status_created ON approval_requests(tenant_id, status, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_approval_requests_event ON approval_requests(event_id);
+
+CREATE TABLE IF NOT EXISTS approval_comments (
+    id                  TEXT PRIMARY KEY,
+    approval_request_id TEXT NOT NULL REFERENCES approval_requests(id),
+    tenant_id           TEXT NOT NULL,
+    author              TEXT NOT NULL,
+    role                TEXT NOT NULL CHECK (role IN ('approver', 'requester')),
+    body                TEXT NOT NULL,
+    created_at          TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_approval_comments_request ON approval_comments(approval_request_id, created_at);
+
+CREATE TABLE IF NOT EXISTS approval_grants (
+    id                      TEXT PRIMARY KEY,
+    request_id              TEXT NOT NULL REFERENCES approval_requests(id),
+    tenant_id               TEXT NOT NULL,
+    approver                TEXT NOT NULL,
+    approver_issuer         TEXT NOT NULL DEFAULT '',
+    approver_subject        TEXT NOT NULL DEFAULT '',
+    scope_tool              TEXT NOT NULL,
+    scope_action            TEXT NOT NULL,
+    scope_resource_pattern  TEXT,
+    scope_tenant_id         TEXT NOT NULL,
+    scope_agent_id          TEXT DEFAULT '',
+    scope_session_id        TEXT NOT NULL DEFAULT '',
+    scope_valid_hours       BLOB,
+    max_uses                INTEGER NOT NULL DEFAULT 1,
+    uses_left               INTEGER NOT NULL DEFAULT 1,
+    expires_at              TIMESTAMP NOT NULL,
+    granted_at              TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at              TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_approval_grants_tenant ON approval_grants(tenant_id, uses_left, expires_at);
+CREATE INDEX IF NOT EXISTS idx_approval_grants_session ON approval_grants(tenant_id, scope_session_id);
+
+CREATE TABLE IF NOT EXISTS grant_usages (
+    id          TEXT PRIMARY KEY,
+    grant_id    TEXT NOT NULL REFERENCES approval_grants(id),
+    tenant_id   TEXT NOT NULL,
+    event_id    TEXT NOT NULL,
+    agent_id    TEXT NOT NULL DEFAULT '',
+    resource    TEXT,
+    used_at     TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_grant_usages_grant ON grant_usages(grant_id, used_at);
+
+CREATE TABLE IF NOT EXISTS approval_notification_outbox (
+    id                    TEXT PRIMARY KEY,
+    approval_request_id   TEXT NOT NULL REFERENCES approval_requests(id),
+    tenant_id             TEXT NOT NULL,
+    event_id              TEXT NOT NULL,
+    trace_id              TEXT DEFAULT '',
+    tool                  TEXT NOT NULL,
+    action                TEXT NOT NULL,
+    resource              TEXT,
+    risk_score            INTEGER NOT NULL DEFAULT 0,
+    risk_factors          BLOB,
+    reason                TEXT,
+    approver_group        TEXT DEFAULT '',
+    approval_url          TEXT NOT NULL,
+    notify_kind           TEXT NOT NULL,
+    notify_url            TEXT,
+    secret_ref            TEXT DEFAULT '',
+    slack_channel         TEXT DEFAULT '',
+    notify_config         BLOB,
+    plan                  BLOB,
+    recent_activity       BLOB,
+    comment               BLOB,
+    slack_message_channel TEXT NOT NULL DEFAULT '',
+    slack_message_ts      TEXT NOT NULL DEFAULT '',
+    parent_id             TEXT NOT NULL DEFAULT '',
+    resolution            TEXT NOT NULL DEFAULT '',
+    resolved_by           TEXT NOT NULL DEFAULT '',
+    resolution_reason     TEXT,
+    status                TEXT NOT NULL DEFAULT 'pending',
+    attempt_count         INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at       TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_error            TEXT,
+    sent_at               TIMESTAMP,
+    created_at            TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at            TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_approval_notification_outbox_due ON approval_notification_outbox(status, next_attempt_at);
+`
+
+// sqliteDialect rewrites the MySQL constructs in the shared queries. Row
+// locks are dropped because transactions begin IMMEDIATE, which already
+// holds the database write lock; timestamps are written in the same UTC
+// text form the driver uses for bound time.Time values so they compare
+// correctly as strings.
+var sqliteDialect = strings.NewReplacer(
+	"NOW(6)", "strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')",
+	"FOR UPDATE SKIP LOCKED", "",
+	"FOR UPDATE", "",
+	"ON DUPLICATE KEY UPDATE id = approval_notification_outbox.id", "ON CONFLICT DO NOTHING",
+	"CONCAT(o.id, ':update')", "o.id || ':update'",
+)
+
+// SQLiteStore manages approval requests and grants in a single SQLite file,
+// for lite mode and single-node installs. It shares its queries with
+// MySQLStore.
+type SQLiteStore struct {
+	sqlApprovals
+}
+
+// OpenSQLite opens (creating if needed) the SQLite database at path and
+// applies the approvals schema. The binary must be built with CGO_ENABLED=1.
+func OpenSQLite(ctx context.Context, path string) (*SQLiteStore, error) {
+	q := url.Values{}
+	q.Set("_busy_timeout", "5000")
+	q.Set("_journal_mode", "WAL")
+	q.Set("_txlock", "immediate")
+	q.Set("_foreign_keys", "on")
+	db, err := sql.Open("sqlite3", "file:"+path+"?"+q.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("approvals.OpenSQLite: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, sqliteSchema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("approvals.OpenSQLite schema: %w", err)
+	}
+	return &SQLiteStore{sqlApprovals{db: db, dialect: sqliteDialect}}, nil
+}
+
+// Close closes the database.
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
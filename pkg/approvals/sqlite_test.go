@@ -0,0 +1,249 @@
+//go:build cgo
+
+package approvals
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+func TestSQLiteStore_RequestGrantLifecycle(t *testing.T) {
+	ctx := context.Background()
+	s, err := OpenSQLite(ctx, filepath.Join(t.TempDir(), "approvals.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+
+	req, err := s.CreateRequest(ctx, CreateApprovalInput{
+		EventID: "e1", TenantID: "t1", AgentID: "a1", Tool: "jira", Action: "issue.delete",
+		Resource: "OPS-1", Reason: "destructive action requires approval",
+		Notify:          []types.PolicyNotify{{Kind: "slack", Channel: "#approvals"}},
+		ApprovalBaseURL: "http://localhost:8081",
+		ApproverGroup:   "security",
+		GrantTTLSec:     900,
+		RecentActivity:  []AgentActivity{{EventID: "e0", Tool: "jira", Action: "issue.get", Decision: "allow", At: time.Now().UTC().Truncate(time.Second)}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending, err := s.ListPending(ctx, "t1", 10, types.Cursor{})
+	if err != nil || len(pending) != 1 || pending[0].ID != req.ID || pending[0].ApproverGroup != "security" || pending[0].GrantTTLSec != 900 {
+		t.Fatalf("pending = %+v, %v", pending, err)
+	}
+	if got := pending[0].RecentActivity; len(got) != 1 || got[0] != req.RecentActivity[0] {
+		t.Fatalf("recent activity = %+v, want %+v", got, req.RecentActivity)
+	}
+
+	due, err := s.ClaimDueNotifications(ctx, 10)
+	if err != nil || len(due) != 1 || len(due[0].RecentActivity) != 1 {
+		t.Fatalf("due = %+v, %v", due, err)
+	}
+	if err := s.MarkNotificationSent(ctx, due[0].ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// A comment is stored with the request and mirrored to the Slack thread.
+	c, apiErr := NewComment(req, "alice", RoleApprover, "Which ticket is this for?")
+	if apiErr != nil {
+		t.Fatal(apiErr)
+	}
+	if _, err := s.AddComment(ctx, c); err != nil {
+		t.Fatal(err)
+	}
+	comments, err := s.ListComments(ctx, []string{req.ID})
+	if err != nil || len(comments) != 1 || comments[0].Body != c.Body || comments[0].ID == "" {
+		t.Fatalf("comments = %+v, %v", comments, err)
+	}
+	due, err = s.ClaimDueNotifications(ctx, 10)
+	if err != nil || len(due) != 1 || due[0].NotifyKind != "slack_comment" || due[0].Comment == nil || due[0].Comment.ID != comments[0].ID {
+		t.Fatalf("comment notifications = %+v, %v", due, err)
+	}
+	if err := s.MarkNotificationSent(ctx, due[0].ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.GrantRequest(ctx, req.ID, GrantInput{Approver: "alice", MaxUses: 1, ExpiresInSec: 3600}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.GetRequest(ctx, req.ID)
+	if err != nil || got.Status != "approved" {
+		t.Fatalf("request = %+v, %v", got, err)
+	}
+	// Resolving the request queues an update of the Slack message.
+	due, err = s.ClaimDueNotifications(ctx, 10)
+	if err != nil || len(due) != 1 || due[0].NotifyKind != "slack_update" {
+		t.Fatalf("updates = %+v, %v", due, err)
+	}
+
+	g, err := s.FindAndConsumeGrant(ctx, "e1", "t1", "a1", "", "jira", "issue.delete", "OPS-1")
+	if err != nil || g == nil {
+		t.Fatalf("grant = %+v, %v", g, err)
+	}
+	usages, err := s.ListGrantUsages(ctx, g.ID, 10, types.Cursor{})
+	if err != nil || len(usages) != 1 || usages[0].EventID != "e1" || usages[0].AgentID != "a1" || usages[0].Resource != "OPS-1" {
+		t.Fatalf("usages = %+v, %v", usages, err)
+	}
+	if got, err := s.GetGrant(ctx, g.ID); err != nil || got == nil || got.UsesLeft != 0 {
+		t.Fatalf("consumed grant = %+v, %v", got, err)
+	}
+	if g, err := s.FindAndConsumeGrant(ctx, "e2", "t1", "a1", "", "jira", "issue.delete", "OPS-1"); err != nil || g != nil {
+		t.Fatalf("second use = %+v, %v", g, err)
+	}
+}
+
+func TestSQLiteStore_ReleaseQuarantine(t *testing.T) {
+	ctx := context.Background()
+	s, err := OpenSQLite(ctx, filepath.Join(t.TempDir(), "approvals.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+
+	ordinary, err := s.CreateRequest(ctx, CreateApprovalInput{EventID: "e1", TenantID: "t1", AgentID: "a1", Tool: "jira", Action: "issue.get"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ReleaseRequest(ctx, ordinary.ID, ReleaseInput{Approver: "alice"}); err == nil {
+		t.Error("released an ordinary request")
+	}
+
+	req, err := s.CreateRequest(ctx, CreateApprovalInput{EventID: "e2", TenantID: "t1", AgentID: "a1", Tool: "jira", Action: "issue.get", Kind: KindQuarantine})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ReleaseRequest(ctx, req.ID, ReleaseInput{Approver: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.ListRequestsByEvents(ctx, "t1", []string{"e2"})
+	if err != nil || len(got) != 1 || got[0].Status != "approved" || !got[0].IsQuarantine() {
+		t.Fatalf("requests = %+v, %v", got, err)
+	}
+	if err := s.ReleaseRequest(ctx, req.ID, ReleaseInput{Approver: "alice"}); err == nil {
+		t.Error("released a request twice")
+	}
+	// Releasing creates no grant for later calls.
+	if g, err := s.FindAndConsumeGrant(ctx, "e3", "t1", "a1", "", "jira", "issue.get", ""); err != nil || g != nil {
+		t.Fatalf("grant = %+v, %v", g, err)
+	}
+}
+
+func TestSQLiteStore_CancelRequest(t *testing.T) {
+	ctx := context.Background()
+	s, err := OpenSQLite(ctx, filepath.Join(t.TempDir(), "approvals.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+
+	req, err := s.CreateRequest(ctx, CreateApprovalInput{
+		EventID: "e1", TenantID: "t1", AgentID: "a1", Tool: "jira", Action: "issue.delete",
+		Notify: []types.PolicyNotify{{Kind: "slack", Channel: "#approvals"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	due, err := s.ClaimDueNotifications(ctx, 10)
+	if err != nil || len(due) != 1 {
+		t.Fatalf("due = %+v, %v", due, err)
+	}
+	if err := s.MarkSlackNotificationSent(ctx, due[0].ID, "C1", "1.0"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.CancelRequest(ctx, req.ID, CancelInput{TenantID: "t1", AgentID: "a2"}); err == nil {
+		t.Error("cancelled another agent's request")
+	}
+	if err := s.CancelRequest(ctx, req.ID, CancelInput{TenantID: "t1", AgentID: "a1", Reason: "superseded"}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.GetRequest(ctx, req.ID)
+	if err != nil || got.Status != "cancelled" {
+		t.Fatalf("request = %+v, %v", got, err)
+	}
+	if pending, err := s.ListPending(ctx, "t1", 10, types.Cursor{}); err != nil || len(pending) != 0 {
+		t.Fatalf("pending = %+v, %v", pending, err)
+	}
+	due, err = s.ClaimDueNotifications(ctx, 10)
+	if err != nil || len(due) != 1 || due[0].NotifyKind != "slack_update" || due[0].Resolution != "cancelled" || due[0].ResolutionReason != "superseded" {
+		t.Fatalf("updates = %+v, %v", due, err)
+	}
+	if err := s.CancelRequest(ctx, req.ID, CancelInput{TenantID: "t1", AgentID: "a1"}); !errors.Is(err, ErrNotPending) {
+		t.Errorf("second cancel = %v, want ErrNotPending", err)
+	}
+}
+
+func TestSQLiteStore_OutboxRetention(t *testing.T) {
+	ctx := context.Background()
+	s, err := OpenSQLite(ctx, filepath.Join(t.TempDir(), "approvals.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+
+	resolved, err := s.CreateRequest(ctx, CreateApprovalInput{
+		EventID: "e1", TenantID: "t1", AgentID: "a1", Tool: "jira", Action: "issue.delete",
+		Notify: []types.PolicyNotify{{Kind: "slack", Channel: "#approvals"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateRequest(ctx, CreateApprovalInput{
+		EventID: "e2", TenantID: "t1", AgentID: "a1", Tool: "jira", Action: "issue.delete",
+		Notify: []types.PolicyNotify{{Kind: "slack", Channel: "#approvals"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	due, err := s.ClaimDueNotifications(ctx, 10)
+	if err != nil || len(due) != 2 {
+		t.Fatalf("due = %+v, %v", due, err)
+	}
+	for _, n := range due {
+		if err := s.MarkSlackNotificationSent(ctx, n.ID, "C1", "1.0"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.DenyRequest(ctx, resolved.ID, DenyInput{Approver: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := s.OutboxStats(ctx)
+	if err != nil || stats.Depth["sent"] != 2 || stats.Depth["pending"] != 1 || stats.OldestPending == nil {
+		t.Fatalf("stats = %+v, %v", stats, err)
+	}
+
+	// The pending request's row and the message its pending update edits
+	// are both kept.
+	later := time.Now().Add(time.Hour)
+	if rows, err := s.ListArchivableNotifications(ctx, later, 10); err != nil || len(rows) != 0 {
+		t.Fatalf("archivable = %+v, %v", rows, err)
+	}
+	due, err = s.ClaimDueNotifications(ctx, 10)
+	if err != nil || len(due) != 1 || due[0].NotifyKind != "slack_update" {
+		t.Fatalf("updates = %+v, %v", due, err)
+	}
+	if err := s.MarkNotificationSent(ctx, due[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if rows, err := s.ListArchivableNotifications(ctx, time.Now().Add(-time.Hour), 10); err != nil || len(rows) != 0 {
+		t.Fatalf("archivable before cutoff = %+v, %v", rows, err)
+	}
+	rows, err := s.ListArchivableNotifications(ctx, later, 10)
+	if err != nil || len(rows) != 2 || rows[0].ApprovalRequestID != resolved.ID || rows[1].ApprovalRequestID != resolved.ID {
+		t.Fatalf("archivable = %+v, %v", rows, err)
+	}
+
+	ids := []string{rows[0].ID, rows[1].ID, "missing"}
+	if n, err := s.DeleteNotifications(ctx, ids); err != nil || n != 2 {
+		t.Fatalf("deleted = %d, %v", n, err)
+	}
+	stats, err = s.OutboxStats(ctx)
+	if err != nil || stats.Depth["sent"] != 1 || stats.Depth["pending"] != 0 || stats.OldestPending != nil {
+		t.Fatalf("stats after delete = %+v, %v", stats, err)
+	}
+}
@@ -0,0 +1,1224 @@
+package approvals
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// sqlApprovals holds the database/sql queries shared by the MySQL and
+// SQLite backends. They mirror Store query-for-query and are written for
+// MySQL; dialect rewrites the few constructs SQLite spells differently.
+type sqlApprovals struct {
+	db      *sql.DB
+	dialect *strings.Replacer // nil for MySQL
+}
+
+// q returns query in the backend's dialect.
+func (s *sqlApprovals) q(query string) string {
+	if s.dialect == nil {
+		return query
+	}
+	return s.dialect.Replace(query)
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Approval Requests
+// ──────────────────────────────────────────────────────────────────────────────
+
+// CreateRequest inserts a new pending approval request.
+func (s *sqlApprovals) CreateRequest(ctx context.Context, in CreateApprovalInput) (*ApprovalRequest, error) {
+	if in.TenantID == "" || in.EventID == "" || in.Tool == "" || in.Action == "" {
+		return nil, fmt.Errorf("approvals.CreateRequest: tenant_id, event_id, tool, and action are required")
+	}
+
+	now := time.Now().UTC()
+	req := &ApprovalRequest{
+		ID:        uuid.NewString(),
+		EventID:   in.EventID,
+		TenantID:  in.TenantID,
+		AgentID:   in.AgentID,
+		Tool:      in.Tool,
+		Action:    in.Action,
+		Resource:  in.Resource,
+		SessionID: in.SessionID,
+		RiskScore: in.RiskScore,
+		Reason:    in.Reason,
+		Status:    "pending",
+		CreatedAt: now,
+		ExpiresAt: now.Add(in.RequestTTL()),
+		Plan:      in.Plan,
+
+		ApproverGroup:  in.ApproverGroup,
+		Kind:           in.Kind,
+		RecentActivity: in.RecentActivity,
+		GrantTTLSec:    max(in.GrantTTLSec, 0),
+	}
+	planJSON, err := encodePlan(in.Plan)
+	if err != nil {
+		return nil, fmt.Errorf("approvals.CreateRequest: %w", err)
+	}
+	activityJSON, err := encodeActivity(in.RecentActivity)
+	if err != nil {
+		return nil, fmt.Errorf("approvals.CreateRequest: %w", err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("approvals.CreateRequest begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // rollback after commit is a no-op
+
+	_, err = tx.ExecContext(ctx, s.q(`
+		INSERT INTO approval_requests (
+			id, event_id, tenant_id, agent_id, tool, action, resource, session_id,
+			risk_score, reason, status, created_at, expires_at, plan, approver_group, kind, recent_activity,
+			grant_ttl_sec
+		) VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)`),
+		req.ID, req.EventID, req.TenantID, req.AgentID,
+		req.Tool, req.Action, req.Resource, req.SessionID,
+		req.RiskScore, req.Reason, req.Status,
+		req.CreatedAt, req.ExpiresAt, planJSON, req.ApproverGroup, req.Kind, activityJSON,
+		req.GrantTTLSec,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("approvals.CreateRequest insert request: %w", err)
+	}
+
+	approvalURL := buildApprovalURL(in.ApprovalBaseURL, req.ID)
+	riskFactorsJSON, err := json.Marshal(in.RiskFactors)
+	if err != nil {
+		return nil, fmt.Errorf("approvals.CreateRequest marshal risk factors: %w", err)
+	}
+
+	for _, n := range in.Notify {
+		if n.Kind == "" {
+			continue
+		}
+		configJSON, err := notifyConfigJSON(n.Config)
+		if err != nil {
+			return nil, fmt.Errorf("approvals.CreateRequest: %w", err)
+		}
+		_, err = tx.ExecContext(ctx, s.q(`
+			INSERT INTO approval_notification_outbox (
+				id, approval_request_id, tenant_id, event_id, trace_id, tool, action, resource,
+				risk_score, risk_factors, reason, approver_group, approval_url,
+				notify_kind, notify_url, secret_ref, slack_channel, notify_config, plan, recent_activity,
+				status, attempt_count, next_attempt_at, created_at, updated_at
+			) VALUES (
+				?,?,?,?,?,?,?,?,
+				?,?,?,?,?,
+				?,?,?,?,?,?,?,
+				'pending',0,NOW(6),NOW(6),NOW(6)
+			)`),
+			uuid.NewString(), req.ID, req.TenantID, req.EventID, in.TraceID, req.Tool, req.Action, req.Resource,
+			req.RiskScore, string(riskFactorsJSON), req.Reason, in.ApproverGroup, approvalURL,
+			n.Kind, n.URL, n.SecretRef, n.Channel, string(configJSON), planJSON, activityJSON,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("approvals.CreateRequest insert outbox: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("approvals.CreateRequest commit: %w", err)
+	}
+	return req, nil
+}
+
+const sqlRequestColumns = `id, event_id, tenant_id, agent_id, tool, action, resource, session_id,
+		       risk_score, reason, deny_reason, status, created_at, expires_at, plan, approver_group, kind, recent_activity,
+		       grant_ttl_sec`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSQLRequest(row rowScanner) (*ApprovalRequest, error) {
+	r := &ApprovalRequest{}
+	var resource, reason, denyReason sql.NullString
+	var plan, activity []byte
+	if err := row.Scan(
+		&r.ID, &r.EventID, &r.TenantID, &r.AgentID,
+		&r.Tool, &r.Action, &resource, &r.SessionID,
+		&r.RiskScore, &reason, &denyReason, &r.Status,
+		&r.CreatedAt, &r.ExpiresAt, &plan, &r.ApproverGroup, &r.Kind, &activity,
+		&r.GrantTTLSec,
+	); err != nil {
+		return nil, err
+	}
+	r.Resource, r.Reason, r.DenyReason = resource.String, reason.String, denyReason.String
+	if len(plan) > 0 {
+		if err := json.Unmarshal(plan, &r.Plan); err != nil {
+			return nil, fmt.Errorf("unmarshal plan: %w", err)
+		}
+	}
+	if len(activity) > 0 {
+		if err := json.Unmarshal(activity, &r.RecentActivity); err != nil {
+			return nil, fmt.Errorf("unmarshal recent activity: %w", err)
+		}
+	}
+	return r, nil
+}
+
+// GetRequest fetches a single approval request.
+func (s *sqlApprovals) GetRequest(ctx context.Context, id string) (*ApprovalRequest, error) {
+	r, err := scanSQLRequest(s.db.QueryRowContext(ctx, s.q(`
+		SELECT `+sqlRequestColumns+`
+		FROM approval_requests WHERE id = ?`), id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("approvals.GetRequest: %w", err)
+	}
+	return r, nil
+}
+
+// ListRequestsByEvents returns the tenant's approval requests raised for
+// any of eventIDs, oldest first.
+func (s *sqlApprovals) ListRequestsByEvents(ctx context.Context, tenantID string, eventIDs []string) ([]ApprovalRequest, error) {
+	reqs := make([]ApprovalRequest, 0)
+	if len(eventIDs) == 0 {
+		return reqs, nil
+	}
+	args := make([]any, 0, len(eventIDs)+1)
+	args = append(args, tenantID)
+	for _, id := range eventIDs {
+		args = append(args, id)
+	}
+	rows, err := s.db.QueryContext(ctx, s.q(`
+		SELECT `+sqlRequestColumns+`
+		FROM approval_requests
+		WHERE tenant_id = ? AND event_id IN (?`+strings.Repeat(",?", len(eventIDs)-1)+`)
+		ORDER BY created_at ASC`), args...)
+	if err != nil {
+		return nil, fmt.Errorf("approvals.ListRequestsByEvents: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		r, err := scanSQLRequest(rows)
+		if err != nil {
+			return nil, fmt.Errorf("approvals.ListRequestsByEvents scan: %w", err)
+		}
+		reqs = append(reqs, *r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("approvals.ListRequestsByEvents iteration: %w", err)
+	}
+	return reqs, nil
+}
+
+// ListPending returns a page of a tenant's pending requests, newest first,
+// starting after the cursor.
+func (s *sqlApprovals) ListPending(ctx context.Context, tenantID string, limit int, after types.Cursor) ([]ApprovalRequest, error) {
+	rows, err := s.db.QueryContext(ctx, s.q(`
+		SELECT `+sqlRequestColumns+`
+		FROM approval_requests
+		WHERE tenant_id = ? AND status = 'pending' AND expires_at > NOW(6)
+		  AND (? = '' OR created_at < ? OR (created_at = ? AND id < ?))
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?`), tenantID, after.ID, after.Time, after.Time, after.ID, pageLimit(limit))
+	if err != nil {
+		return nil, fmt.Errorf("approvals.ListPending: %w", err)
+	}
+	defer rows.Close()
+
+	reqs := make([]ApprovalRequest, 0)
+	for rows.Next() {
+		r, err := scanSQLRequest(rows)
+		if err != nil {
+			return nil, fmt.Errorf("approvals.ListPending scan: %w", err)
+		}
+		reqs = append(reqs, *r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("approvals.ListPending iteration: %w", err)
+	}
+	return reqs, nil
+}
+
+// CountPendingByTenant returns the number of pending, unexpired requests per tenant.
+func (s *sqlApprovals) CountPendingByTenant(ctx context.Context) (map[string]int64, error) {
+	rows, err := s.db.QueryContext(ctx, s.q(`
+		SELECT tenant_id, COUNT(*)
+		FROM approval_requests
+		WHERE status = 'pending' AND expires_at > NOW(6)
+		GROUP BY tenant_id`))
+	if err != nil {
+		return nil, fmt.Errorf("approvals.CountPendingByTenant: %w", err)
+	}
+	defer rows.Close()
+
+	out := make(map[string]int64)
+	for rows.Next() {
+		var tenantID string
+		var n int64
+		if err := rows.Scan(&tenantID, &n); err != nil {
+			return nil, fmt.Errorf("approvals.CountPendingByTenant scan: %w", err)
+		}
+		out[tenantID] = n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("approvals.CountPendingByTenant iteration: %w", err)
+	}
+	return out, nil
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Approval Grants
+// ──────────────────────────────────────────────────────────────────────────────
+
+// GrantRequest approves a pending request, creating a grant.
+// The status check is performed inside the transaction to eliminate TOCTOU races.
+func (s *sqlApprovals) GrantRequest(ctx context.Context, requestID string, in GrantInput) (*ApprovalGrant, error) {
+	if in.Approver == "" {
+		return nil, fmt.Errorf("approvals.GrantRequest: approver is required")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("approvals.GrantRequest begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // rollback after commit is a no-op
+
+	res, err := tx.ExecContext(ctx, s.q(`
+		UPDATE approval_requests SET status = 'approved', updated_at = NOW(6)
+		WHERE id = ? AND status = 'pending' AND expires_at > NOW(6)`), requestID)
+	if err != nil {
+		return nil, fmt.Errorf("approvals.GrantRequest update: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return nil, fmt.Errorf("approval request %s not found, not pending, or expired", requestID)
+	}
+
+	var tenantID, agentID, tool, action, sessionID string
+	var resource sql.NullString
+	if err := tx.QueryRowContext(ctx, s.q(`
+		SELECT tenant_id, agent_id, tool, action, resource, session_id
+		FROM approval_requests WHERE id = ?`), requestID,
+	).Scan(&tenantID, &agentID, &tool, &action, &resource, &sessionID); err != nil {
+		return nil, fmt.Errorf("approvals.GrantRequest fetch: %w", err)
+	}
+
+	now := time.Now().UTC()
+	maxUses, expiry, resourcePattern, scopeSession, err := in.grantDefaults(now, resource.String, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("approvals.GrantRequest: %w", err)
+	}
+
+	grant := &ApprovalGrant{
+		ID:        uuid.NewString(),
+		RequestID: requestID,
+		TenantID:  tenantID,
+		Approver:  in.Approver,
+		Scope: ApprovalScope{
+			Tool:            tool,
+			Action:          action,
+			ResourcePattern: resourcePattern,
+			TenantID:        tenantID,
+			AgentID:         agentID,
+			SessionID:       scopeSession,
+			ValidHours:      in.ValidHours,
+		},
+		MaxUses:   maxUses,
+		UsesLeft:  maxUses,
+		ExpiresAt: expiry,
+		GrantedAt: now,
+
+		ApproverIssuer:  in.ApproverIssuer,
+		ApproverSubject: in.ApproverSubject,
+	}
+	validHours, err := encodeValidHours(grant.Scope.ValidHours)
+	if err != nil {
+		return nil, fmt.Errorf("approvals.GrantRequest: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, s.q(`
+		INSERT INTO approval_grants (
+			id, request_id, tenant_id, approver, approver_issuer, approver_subject,
+			scope_tool, scope_action, scope_resource_pattern, scope_tenant_id, scope_agent_id,
+			scope_session_id, scope_valid_hours, max_uses, uses_left, expires_at, granted_at
+		) VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)`),
+		grant.ID, grant.RequestID, grant.TenantID, grant.Approver, grant.ApproverIssuer, grant.ApproverSubject,
+		grant.Scope.Tool, grant.Scope.Action, grant.Scope.ResourcePattern,
+		grant.Scope.TenantID, grant.Scope.AgentID, grant.Scope.SessionID, validHours,
+		grant.MaxUses, grant.UsesLeft, grant.ExpiresAt, grant.GrantedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("approvals.GrantRequest insert: %w", err)
+	}
+	if err := s.enqueueSlackUpdates(ctx, tx, requestID, "approved", in.Approver, ""); err != nil {
+		return nil, fmt.Errorf("approvals.GrantRequest: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("approvals.GrantRequest commit: %w", err)
+	}
+	return grant, nil
+}
+
+// DenyRequest marks a pending request as denied.
+// The original reason is preserved; deny_reason stores the denier's rationale.
+func (s *sqlApprovals) DenyRequest(ctx context.Context, requestID string, in DenyInput) error {
+	if in.Approver == "" {
+		return fmt.Errorf("approvals.DenyRequest: approver is required")
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("approvals.DenyRequest begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // rollback after commit is a no-op
+
+	res, err := tx.ExecContext(ctx, s.q(`
+		UPDATE approval_requests SET status = 'denied', deny_reason = ?, denied_by = ?, updated_at = NOW(6)
+		WHERE id = ? AND status = 'pending'`), in.Reason, in.Approver, requestID)
+	if err != nil {
+		return fmt.Errorf("approvals.DenyRequest: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return fmt.Errorf("approval request %s not found or not pending", requestID)
+	}
+	if err := s.enqueueSlackUpdates(ctx, tx, requestID, "denied", in.Approver, in.Reason); err != nil {
+		return fmt.Errorf("approvals.DenyRequest: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("approvals.DenyRequest commit: %w", err)
+	}
+	return nil
+}
+
+// CancelRequest marks a pending request cancelled by the agent that made
+// it and queues the rewrite of its Slack messages. ErrNotPending is
+// returned when the request is no longer pending.
+func (s *sqlApprovals) CancelRequest(ctx context.Context, requestID string, in CancelInput) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("approvals.CancelRequest begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // rollback after commit is a no-op
+
+	res, err := tx.ExecContext(ctx, s.q(`
+		UPDATE approval_requests SET status = 'cancelled', updated_at = NOW(6)
+		WHERE id = ? AND tenant_id = ? AND agent_id = ? AND status = 'pending' AND kind = ''`),
+		requestID, in.TenantID, in.AgentID)
+	if err != nil {
+		return fmt.Errorf("approvals.CancelRequest: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("approvals.CancelRequest: %w", err)
+	} else if n == 0 {
+		return fmt.Errorf("approvals.CancelRequest %s: %w", requestID, ErrNotPending)
+	}
+	if err := s.enqueueSlackUpdates(ctx, tx, requestID, "cancelled", in.AgentID, in.Reason); err != nil {
+		return fmt.Errorf("approvals.CancelRequest: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("approvals.CancelRequest commit: %w", err)
+	}
+	return nil
+}
+
+// ReleaseRequest marks a pending quarantine request approved, releasing
+// the withheld output to the agent. It creates no grant.
+func (s *sqlApprovals) ReleaseRequest(ctx context.Context, requestID string, in ReleaseInput) error {
+	if in.Approver == "" {
+		return fmt.Errorf("approvals.ReleaseRequest: approver is required")
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("approvals.ReleaseRequest begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // rollback after commit is a no-op
+
+	res, err := tx.ExecContext(ctx, s.q(`
+		UPDATE approval_requests SET status = 'approved', released_by = ?, updated_at = NOW(6)
+		WHERE id = ? AND status = 'pending' AND kind = 'quarantine'`), in.Approver, requestID)
+	if err != nil {
+		return fmt.Errorf("approvals.ReleaseRequest: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return fmt.Errorf("quarantine request %s not found or not pending", requestID)
+	}
+	if err := s.enqueueSlackUpdates(ctx, tx, requestID, "released", in.Approver, ""); err != nil {
+		return fmt.Errorf("approvals.ReleaseRequest: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("approvals.ReleaseRequest commit: %w", err)
+	}
+	return nil
+}
+
+// ExpireRequests marks pending requests past expires_at as expired so their
+// Slack messages are rewritten, and returns the IDs it expired.
+func (s *sqlApprovals) ExpireRequests(ctx context.Context) ([]string, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("approvals.ExpireRequests begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // rollback after commit is a no-op
+
+	rows, err := tx.QueryContext(ctx, s.q(`
+		SELECT id FROM approval_requests
+		WHERE status = 'pending' AND expires_at <= NOW(6)
+		FOR UPDATE SKIP LOCKED`))
+	if err != nil {
+		return nil, fmt.Errorf("approvals.ExpireRequests: %w", err)
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("approvals.ExpireRequests scan: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("approvals.ExpireRequests iteration: %w", err)
+	}
+
+	for _, id := range ids {
+		if _, err := tx.ExecContext(ctx, s.q(`
+			UPDATE approval_requests SET status = 'expired', updated_at = NOW(6)
+			WHERE id = ?`), id); err != nil {
+			return nil, fmt.Errorf("approvals.ExpireRequests update: %w", err)
+		}
+		if err := s.enqueueSlackUpdates(ctx, tx, id, "expired", "", ""); err != nil {
+			return nil, fmt.Errorf("approvals.ExpireRequests: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("approvals.ExpireRequests commit: %w", err)
+	}
+	return ids, nil
+}
+
+// enqueueSlackUpdates is the database/sql form of the Store function.
+func (s *sqlApprovals) enqueueSlackUpdates(ctx context.Context, tx *sql.Tx, requestID, resolution, resolvedBy, reason string) error {
+	_, err := tx.ExecContext(ctx, s.q(`
+		INSERT INTO approval_notification_outbox (
+			id, approval_request_id, tenant_id, event_id, trace_id, tool, action, resource,
+			risk_score, risk_factors, reason, approver_group, approval_url,
+			notify_kind, slack_channel, parent_id, resolution, resolved_by, resolution_reason,
+			status, attempt_count, next_attempt_at, created_at, updated_at
+		)
+		SELECT CONCAT(o.id, ':update'), o.approval_request_id, o.tenant_id, o.event_id, o.trace_id, o.tool, o.action, o.resource,
+		       o.risk_score, o.risk_factors, o.reason, o.approver_group, o.approval_url,
+		       'slack_update', o.slack_channel, o.id, ?, ?, ?,
+		       'pending', 0, NOW(6), NOW(6), NOW(6)
+		FROM approval_notification_outbox o
+		WHERE o.approval_request_id = ? AND o.notify_kind = 'slack' AND o.status <> 'failed'
+		ON DUPLICATE KEY UPDATE id = approval_notification_outbox.id`), resolution, resolvedBy, reason, requestID)
+	if err != nil {
+		return fmt.Errorf("enqueue slack updates: %w", err)
+	}
+	return nil
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Comments
+// ──────────────────────────────────────────────────────────────────────────────
+
+// AddComment stores c on its request's thread and queues its Slack thread
+// replies.
+func (s *sqlApprovals) AddComment(ctx context.Context, c Comment) (*Comment, error) {
+	c.ID = uuid.NewString()
+	c.CreatedAt = time.Now().UTC()
+	commentJSON, err := json.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("approvals.AddComment: %w", err)
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("approvals.AddComment begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // rollback after commit is a no-op
+
+	if _, err := tx.ExecContext(ctx, s.q(`
+		INSERT INTO approval_comments (id, approval_request_id, tenant_id, author, role, body, created_at)
+		VALUES (?,?,?,?,?,?,?)`),
+		c.ID, c.RequestID, c.TenantID, c.Author, c.Role, c.Body, c.CreatedAt,
+	); err != nil {
+		return nil, fmt.Errorf("approvals.AddComment insert: %w", err)
+	}
+	rows, err := tx.QueryContext(ctx, s.q(`
+		SELECT id FROM approval_notification_outbox
+		WHERE approval_request_id = ? AND notify_kind = 'slack' AND status <> 'failed'`), c.RequestID)
+	if err != nil {
+		return nil, fmt.Errorf("approvals.AddComment list slack messages: %w", err)
+	}
+	var parents []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("approvals.AddComment scan: %w", err)
+		}
+		parents = append(parents, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("approvals.AddComment iteration: %w", err)
+	}
+	for _, parent := range parents {
+		if _, err := tx.ExecContext(ctx, s.q(`
+			INSERT INTO approval_notification_outbox (
+				id, approval_request_id, tenant_id, event_id, trace_id, tool, action, resource,
+				risk_score, risk_factors, reason, approver_group, approval_url,
+				notify_kind, slack_channel, parent_id, comment,
+				status, attempt_count, next_attempt_at, created_at, updated_at
+			)
+			SELECT ?, o.approval_request_id, o.tenant_id, o.event_id, o.trace_id, o.tool, o.action, o.resource,
+			       o.risk_score, o.risk_factors, o.reason, o.approver_group, o.approval_url,
+			       'slack_comment', o.slack_channel, o.id, ?,
+			       'pending', 0, NOW(6), NOW(6), NOW(6)
+			FROM approval_notification_outbox o
+			WHERE o.id = ?`), uuid.NewString(), commentJSON, parent,
+		); err != nil {
+			return nil, fmt.Errorf("approvals.AddComment enqueue slack reply: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("approvals.AddComment commit: %w", err)
+	}
+	return &c, nil
+}
+
+// ListComments returns the comments on the given requests, oldest first.
+func (s *sqlApprovals) ListComments(ctx context.Context, requestIDs []string) ([]Comment, error) {
+	out := make([]Comment, 0)
+	if len(requestIDs) == 0 {
+		return out, nil
+	}
+	args := make([]any, len(requestIDs))
+	for i, id := range requestIDs {
+		args[i] = id
+	}
+	rows, err := s.db.QueryContext(ctx, s.q(`
+		SELECT id, approval_request_id, tenant_id, author, role, body, created_at
+		FROM approval_comments
+		WHERE approval_request_id IN (?`+strings.Repeat(",?", len(requestIDs)-1)+`)
+		ORDER BY created_at ASC, id ASC`), args...)
+	if err != nil {
+		return nil, fmt.Errorf("approvals.ListComments: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c Comment
+		if err := rows.Scan(&c.ID, &c.RequestID, &c.TenantID, &c.Author, &c.Role, &c.Body, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("approvals.ListComments scan: %w", err)
+		}
+		out = append(out, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("approvals.ListComments iteration: %w", err)
+	}
+	return out, nil
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Grant consumption (called by gateway)
+// ──────────────────────────────────────────────────────────────────────────────
+
+const sqlGrantColumns = `id, request_id, tenant_id, approver, approver_issuer, approver_subject,
+		       scope_tool, scope_action, scope_resource_pattern, scope_tenant_id, scope_agent_id,
+		       scope_session_id, scope_valid_hours, max_uses, uses_left, expires_at, granted_at`
+
+func scanSQLGrant(row rowScanner) (*ApprovalGrant, error) {
+	g := &ApprovalGrant{}
+	var pattern, scopeAgent sql.NullString
+	var validHours []byte
+	if err := row.Scan(
+		&g.ID, &g.RequestID, &g.TenantID, &g.Approver, &g.ApproverIssuer, &g.ApproverSubject,
+		&g.Scope.Tool, &g.Scope.Action, &pattern,
+		&g.Scope.TenantID, &scopeAgent, &g.Scope.SessionID, &validHours,
+		&g.MaxUses, &g.UsesLeft, &g.ExpiresAt, &g.GrantedAt,
+	); err != nil {
+		return nil, err
+	}
+	g.Scope.ResourcePattern, g.Scope.AgentID = pattern.String, scopeAgent.String
+	if len(validHours) > 0 {
+		if err := json.Unmarshal(validHours, &g.Scope.ValidHours); err != nil {
+			return nil, fmt.Errorf("unmarshal valid hours: %w", err)
+		}
+	}
+	return g, nil
+}
+
+// FindAndConsumeGrant finds a valid grant matching the given scope and atomically
+// decrements its usage, recording the use by eventID. All candidates are
+// locked, then matched in Go.
+// Session grants match only calls from the same session.
+func (s *sqlApprovals) FindAndConsumeGrant(ctx context.Context, eventID, tenantID, agentID, sessionID, tool, action, resource string) (*ApprovalGrant, error) {
+	return s.consumeTraced(ctx, eventID, tenantID, agentID, sessionID, tool, action, resource, false)
+}
+
+// FindAndConsumeSessionGrant is FindAndConsumeGrant restricted to grants
+// scoped to sessionID.
+func (s *sqlApprovals) FindAndConsumeSessionGrant(ctx context.Context, eventID, tenantID, agentID, sessionID, tool, action, resource string) (*ApprovalGrant, error) {
+	if sessionID == "" {
+		return nil, nil
+	}
+	return s.consumeTraced(ctx, eventID, tenantID, agentID, sessionID, tool, action, resource, true)
+}
+
+func (s *sqlApprovals) consumeTraced(ctx context.Context, eventID, tenantID, agentID, sessionID, tool, action, resource string, sessionOnly bool) (*ApprovalGrant, error) {
+	ctx, span := tracer.Start(ctx, "approvals.FindAndConsumeGrant", trace.WithAttributes(
+		attribute.String("oc.tool", tool),
+		attribute.String("oc.action", action),
+		attribute.Bool("oc.session_only", sessionOnly),
+	))
+	defer span.End()
+
+	grant, err := s.findAndConsumeGrant(ctx, eventID, tenantID, agentID, sessionID, tool, action, resource, sessionOnly)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.SetAttributes(attribute.Bool("oc.grant_found", grant != nil))
+	return grant, err
+}
+
+func (s *sqlApprovals) findAndConsumeGrant(ctx context.Context, eventID, tenantID, agentID, sessionID, tool, action, resource string, sessionOnly bool) (*ApprovalGrant, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("approvals.FindAndConsumeGrant begin: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // rollback after commit is a no-op
+
+	// max_uses = 0 marks an unlimited session grant.
+	rows, err := tx.QueryContext(ctx, s.q(`
+		SELECT `+sqlGrantColumns+`
+		FROM approval_grants
+		WHERE tenant_id = ?
+		  AND (uses_left > 0 OR max_uses = 0)
+		  AND expires_at > NOW(6)
+		  AND (scope_tool = ? OR scope_tool = '*')
+		  AND (scope_action = ? OR scope_action = '*')
+		  AND (scope_agent_id = '' OR scope_agent_id IS NULL OR scope_agent_id = ?)
+		  AND (scope_session_id = ? OR (scope_session_id = '' AND NOT ?))
+		ORDER BY granted_at DESC
+		FOR UPDATE`), tenantID, tool, action, agentID, sessionID, sessionOnly)
+	if err != nil {
+		return nil, fmt.Errorf("approvals.FindAndConsumeGrant query: %w", err)
+	}
+
+	// database/sql cannot run the UPDATE while the cursor is open on the same
+	// transaction, so pick the match first and close the rows.
+	var match *ApprovalGrant
+	now := time.Now()
+	for rows.Next() {
+		g, err := scanSQLGrant(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("approvals.FindAndConsumeGrant scan: %w", err)
+		}
+		if matchResource(g.Scope.ResourcePattern, resource) && g.UsableAt(now) {
+			match = g
+			break
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("approvals.FindAndConsumeGrant iteration: %w", err)
+	}
+	if match == nil {
+		return nil, nil
+	}
+
+	if _, err := tx.ExecContext(ctx, s.q(`
+		UPDATE approval_grants SET uses_left = uses_left - 1, updated_at = NOW(6)
+		WHERE id = ? AND max_uses > 0`), match.ID); err != nil {
+		return nil, fmt.Errorf("approvals.FindAndConsumeGrant update: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, s.q(`
+		INSERT INTO grant_usages (id, grant_id, tenant_id, event_id, agent_id, resource, used_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`),
+		uuid.NewString(), match.ID, tenantID, eventID, agentID, resource, time.Now().UTC()); err != nil {
+		return nil, fmt.Errorf("approvals.FindAndConsumeGrant record usage: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("approvals.FindAndConsumeGrant commit: %w", err)
+	}
+
+	if match.MaxUses > 0 {
+		match.UsesLeft--
+	}
+	return match, nil
+}
+
+// GetGrant fetches a single grant.
+func (s *sqlApprovals) GetGrant(ctx context.Context, id string) (*ApprovalGrant, error) {
+	g, err := scanSQLGrant(s.db.QueryRowContext(ctx, s.q(`
+		SELECT `+sqlGrantColumns+`
+		FROM approval_grants WHERE id = ?`), id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("approvals.GetGrant: %w", err)
+	}
+	return g, nil
+}
+
+// ListGrantUsages returns a page of the uses of grant grantID, newest
+// first, starting after the cursor.
+func (s *sqlApprovals) ListGrantUsages(ctx context.Context, grantID string, limit int, after types.Cursor) ([]GrantUsage, error) {
+	rows, err := s.db.QueryContext(ctx, s.q(`
+		SELECT id, grant_id, tenant_id, event_id, agent_id, resource, used_at
+		FROM grant_usages
+		WHERE grant_id = ?
+		  AND (? = '' OR used_at < ? OR (used_at = ? AND id < ?))
+		ORDER BY used_at DESC, id DESC
+		LIMIT ?`), grantID, after.ID, after.Time, after.Time, after.ID, pageLimit(limit))
+	if err != nil {
+		return nil, fmt.Errorf("approvals.ListGrantUsages: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]GrantUsage, 0)
+	for rows.Next() {
+		var u GrantUsage
+		var resource sql.NullString
+		if err := rows.Scan(&u.ID, &u.GrantID, &u.TenantID, &u.EventID, &u.AgentID, &resource, &u.UsedAt); err != nil {
+			return nil, fmt.Errorf("approvals.ListGrantUsages scan: %w", err)
+		}
+		u.Resource = resource.String
+		out = append(out, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("approvals.ListGrantUsages iteration: %w", err)
+	}
+	return out, nil
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Notification outbox
+// ──────────────────────────────────────────────────────────────────────────────
+
+// ClaimDueNotifications claims pending due rows for delivery. MySQL has no
+// UPDATE … RETURNING, so due IDs are locked with SKIP LOCKED, marked
+// processing, and re-read inside one transaction.
+func (s *sqlApprovals) ClaimDueNotifications(ctx context.Context, limit int) ([]NotificationOutbox, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("approvals.ClaimDueNotifications begin: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // rollback after commit is a no-op
+
+	idRows, err := tx.QueryContext(ctx, s.q(`
+		SELECT id
+		FROM approval_notification_outbox
+		WHERE status = 'pending'
+		  AND next_attempt_at <= NOW(6)
+		ORDER BY created_at ASC
+		LIMIT ?
+		FOR UPDATE SKIP LOCKED`), limit)
+	if err != nil {
+		return nil, fmt.Errorf("approvals.ClaimDueNotifications: %w", err)
+	}
+	var ids []any
+	for idRows.Next() {
+		var id string
+		if err := idRows.Scan(&id); err != nil {
+			idRows.Close()
+			return nil, fmt.Errorf("approvals.ClaimDueNotifications scan id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	idRows.Close()
+	if err := idRows.Err(); err != nil {
+		return nil, fmt.Errorf("approvals.ClaimDueNotifications iteration: %w", err)
+	}
+	out := make([]NotificationOutbox, 0, len(ids))
+	if len(ids) == 0 {
+		return out, nil
+	}
+
+	in := "(" + strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",") + ")"
+	if _, err := tx.ExecContext(ctx, s.q(`
+		UPDATE approval_notification_outbox
+		SET status = 'processing',
+		    attempt_count = attempt_count + 1,
+		    updated_at = NOW(6)
+		WHERE id IN `+in), ids...); err != nil {
+		return nil, fmt.Errorf("approvals.ClaimDueNotifications update: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, s.q(`
+		SELECT id, approval_request_id, tenant_id, event_id, trace_id, tool, action, resource,
+		       risk_score, risk_factors, reason, approver_group, approval_url,
+		       notify_kind, notify_url, secret_ref, slack_channel, notify_config, plan, recent_activity,
+		       attempt_count, status, next_attempt_at, created_at,
+		       parent_id, resolution, resolved_by, resolution_reason, comment
+		FROM approval_notification_outbox
+		WHERE id IN `+in+`
+		ORDER BY created_at ASC`), ids...)
+	if err != nil {
+		return nil, fmt.Errorf("approvals.ClaimDueNotifications select: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var n NotificationOutbox
+		var traceID, resource, reason, approverGroup, notifyURL, secretRef, slackChannel, resolutionReason sql.NullString
+		var riskFactors, notifyConfig, plan, activity, comment []byte
+		if err := rows.Scan(
+			&n.ID, &n.ApprovalRequestID, &n.TenantID, &n.EventID, &traceID,
+			&n.Tool, &n.Action, &resource, &n.RiskScore, &riskFactors,
+			&reason, &approverGroup, &n.ApprovalURL,
+			&n.NotifyKind, &notifyURL, &secretRef, &slackChannel, &notifyConfig, &plan, &activity,
+			&n.Attempts, &n.Status, &n.NextAttemptAt, &n.CreatedAt,
+			&n.ParentID, &n.Resolution, &n.ResolvedBy, &resolutionReason, &comment,
+		); err != nil {
+			return nil, fmt.Errorf("approvals.ClaimDueNotifications scan: %w", err)
+		}
+		n.TraceID, n.Resource, n.Reason = traceID.String, resource.String, reason.String
+		n.ApproverGroup, n.NotifyURL = approverGroup.String, notifyURL.String
+		n.SecretRef, n.SlackChannel = secretRef.String, slackChannel.String
+		n.ResolutionReason = resolutionReason.String
+		if len(riskFactors) > 0 {
+			if err := json.Unmarshal(riskFactors, &n.RiskFactors); err != nil {
+				return nil, fmt.Errorf("approvals.ClaimDueNotifications unmarshal risk factors: %w", err)
+			}
+		}
+		if len(notifyConfig) > 0 {
+			if err := json.Unmarshal(notifyConfig, &n.NotifyConfig); err != nil {
+				return nil, fmt.Errorf("approvals.ClaimDueNotifications unmarshal notify config: %w", err)
+			}
+		}
+		if len(plan) > 0 {
+			if err := json.Unmarshal(plan, &n.Plan); err != nil {
+				return nil, fmt.Errorf("approvals.ClaimDueNotifications unmarshal plan: %w", err)
+			}
+		}
+		if len(activity) > 0 {
+			if err := json.Unmarshal(activity, &n.RecentActivity); err != nil {
+				return nil, fmt.Errorf("approvals.ClaimDueNotifications unmarshal recent activity: %w", err)
+			}
+		}
+		if len(comment) > 0 {
+			if err := json.Unmarshal(comment, &n.Comment); err != nil {
+				return nil, fmt.Errorf("approvals.ClaimDueNotifications unmarshal comment: %w", err)
+			}
+		}
+		out = append(out, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("approvals.ClaimDueNotifications iteration: %w", err)
+	}
+	rows.Close()
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("approvals.ClaimDueNotifications commit: %w", err)
+	}
+	return out, nil
+}
+
+// MarkNotificationSent marks an outbox record as delivered.
+func (s *sqlApprovals) MarkNotificationSent(ctx context.Context, id string) error {
+	res, err := s.db.ExecContext(ctx, s.q(`
+		UPDATE approval_notification_outbox
+		SET status = 'sent', sent_at = NOW(6), updated_at = NOW(6), last_error = ''
+		WHERE id = ?`), id)
+	if err != nil {
+		return fmt.Errorf("approvals.MarkNotificationSent: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return fmt.Errorf("approvals.MarkNotificationSent: no rows updated for id %s", id)
+	}
+	return nil
+}
+
+// MarkSlackNotificationSent marks a "slack" outbox record as delivered and
+// stores where the message was posted so it can be updated on resolution.
+func (s *sqlApprovals) MarkSlackNotificationSent(ctx context.Context, id, channel, ts string) error {
+	res, err := s.db.ExecContext(ctx, s.q(`
+		UPDATE approval_notification_outbox
+		SET status = 'sent', sent_at = NOW(6), updated_at = NOW(6), last_error = '',
+		    slack_message_channel = ?, slack_message_ts = ?
+		WHERE id = ?`), channel, ts, id)
+	if err != nil {
+		return fmt.Errorf("approvals.MarkSlackNotificationSent: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return fmt.Errorf("approvals.MarkSlackNotificationSent: no rows updated for id %s", id)
+	}
+	return nil
+}
+
+// SlackMessageFor returns the posted message of a "slack" outbox record, or
+// nil if there is no such record.
+func (s *sqlApprovals) SlackMessageFor(ctx context.Context, outboxID string) (*SlackMessage, error) {
+	var m SlackMessage
+	err := s.db.QueryRowContext(ctx, s.q(`
+		SELECT slack_message_channel, slack_message_ts, status
+		FROM approval_notification_outbox WHERE id = ?`), outboxID).Scan(&m.Channel, &m.TS, &m.Status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("approvals.SlackMessageFor: %w", err)
+	}
+	return &m, nil
+}
+
+// MarkNotificationRetry schedules another delivery attempt with backoff.
+func (s *sqlApprovals) MarkNotificationRetry(ctx context.Context, id string, attempts int, nextAttemptAt time.Time, lastErr string) error {
+	res, err := s.db.ExecContext(ctx, s.q(`
+		UPDATE approval_notification_outbox
+		SET status = 'pending', attempt_count = ?, next_attempt_at = ?, last_error = ?, updated_at = NOW(6)
+		WHERE id = ?`), attempts, nextAttemptAt.UTC(), lastErr, id)
+	if err != nil {
+		return fmt.Errorf("approvals.MarkNotificationRetry: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return fmt.Errorf("approvals.MarkNotificationRetry: no rows updated for id %s", id)
+	}
+	return nil
+}
+
+// MarkNotificationFailed marks an outbox row terminally failed.
+func (s *sqlApprovals) MarkNotificationFailed(ctx context.Context, id string, lastErr string) error {
+	res, err := s.db.ExecContext(ctx, s.q(`
+		UPDATE approval_notification_outbox
+		SET status = 'failed', last_error = ?, updated_at = NOW(6)
+		WHERE id = ?`), lastErr, id)
+	if err != nil {
+		return fmt.Errorf("approvals.MarkNotificationFailed: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return fmt.Errorf("approvals.MarkNotificationFailed: no rows updated for id %s", id)
+	}
+	return nil
+}
+
+// ListFailedNotifications returns a page of terminally failed outbox rows,
+// newest first, optionally for one tenant, starting after the cursor.
+func (s *sqlApprovals) ListFailedNotifications(ctx context.Context, tenantID string, limit int, after types.Cursor) ([]DeadLetter, error) {
+	rows, err := s.db.QueryContext(ctx, s.q(`
+		SELECT `+deadLetterColumns+`
+		FROM approval_notification_outbox
+		WHERE status = 'failed' AND (? = '' OR tenant_id = ?)
+		  AND (? = '' OR COALESCE(updated_at, created_at) < ?
+		       OR (COALESCE(updated_at, created_at) = ? AND id < ?))
+		ORDER BY COALESCE(updated_at, created_at) DESC, id DESC
+		LIMIT ?`), tenantID, tenantID, after.ID, after.Time, after.Time, after.ID, pageLimit(limit))
+	if err != nil {
+		return nil, fmt.Errorf("approvals.ListFailedNotifications: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]DeadLetter, 0)
+	for rows.Next() {
+		n, err := scanDeadLetter(rows)
+		if err != nil {
+			return nil, fmt.Errorf("approvals.ListFailedNotifications scan: %w", err)
+		}
+		out = append(out, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("approvals.ListFailedNotifications iteration: %w", err)
+	}
+	return out, nil
+}
+
+// GetNotification returns one outbox row in any status, or nil.
+func (s *sqlApprovals) GetNotification(ctx context.Context, id string) (*DeadLetter, error) {
+	n, err := scanDeadLetter(s.db.QueryRowContext(ctx, s.q(`
+		SELECT `+deadLetterColumns+`
+		FROM approval_notification_outbox WHERE id = ?`), id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("approvals.GetNotification: %w", err)
+	}
+	return &n, nil
+}
+
+// ListRequestNotifications returns every outbox row for an approval
+// request in any status, oldest first.
+func (s *sqlApprovals) ListRequestNotifications(ctx context.Context, requestID string) ([]DeadLetter, error) {
+	rows, err := s.db.QueryContext(ctx, s.q(`
+		SELECT `+deadLetterColumns+`
+		FROM approval_notification_outbox
+		WHERE approval_request_id = ?
+		ORDER BY created_at ASC, id ASC`), requestID)
+	if err != nil {
+		return nil, fmt.Errorf("approvals.ListRequestNotifications: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]DeadLetter, 0)
+	for rows.Next() {
+		n, err := scanDeadLetter(rows)
+		if err != nil {
+			return nil, fmt.Errorf("approvals.ListRequestNotifications scan: %w", err)
+		}
+		out = append(out, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("approvals.ListRequestNotifications iteration: %w", err)
+	}
+	return out, nil
+}
+
+// RequeueNotification returns a failed row to pending with a fresh attempt
+// budget. It reports false if id is not a failed row.
+func (s *sqlApprovals) RequeueNotification(ctx context.Context, id string) (bool, error) {
+	res, err := s.db.ExecContext(ctx, s.q(`
+		UPDATE approval_notification_outbox
+		SET status = 'pending', attempt_count = 0, next_attempt_at = NOW(6), updated_at = NOW(6)
+		WHERE id = ? AND status = 'failed'`), id)
+	if err != nil {
+		return false, fmt.Errorf("approvals.RequeueNotification: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("approvals.RequeueNotification: %w", err)
+	}
+	return n > 0, nil
+}
+
+// ListArchivableNotifications returns up to limit sent or failed rows last
+// updated before cutoff, by tenant and then oldest first, for the retention
+// job to archive and delete. Rows of a request that is still pending are
+// kept, as are rows a pending slack_update or slack_comment still needs to
+// find the message it edits.
+func (s *sqlApprovals) ListArchivableNotifications(ctx context.Context, cutoff time.Time, limit int) ([]DeadLetter, error) {
+	rows, err := s.db.QueryContext(ctx, s.q(`
+		SELECT `+deadLetterColumns+`
+		FROM approval_notification_outbox o
+		WHERE status IN ('sent', 'failed')
+		  AND COALESCE(updated_at, created_at) < ?
+		  AND NOT EXISTS (SELECT 1 FROM approval_requests r
+		                  WHERE r.id = o.approval_request_id AND r.status = 'pending')
+		  AND NOT EXISTS (SELECT 1 FROM approval_notification_outbox c
+		                  WHERE c.parent_id = o.id AND c.status IN ('pending', 'processing'))
+		ORDER BY tenant_id, created_at, id
+		LIMIT ?`), cutoff.UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("approvals.ListArchivableNotifications: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]DeadLetter, 0)
+	for rows.Next() {
+		n, err := scanDeadLetter(rows)
+		if err != nil {
+			return nil, fmt.Errorf("approvals.ListArchivableNotifications scan: %w", err)
+		}
+		out = append(out, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("approvals.ListArchivableNotifications iteration: %w", err)
+	}
+	return out, nil
+}
+
+// DeleteNotifications deletes the sent or failed rows among ids, once the
+// retention job has archived them. Rows in any other status are left alone.
+func (s *sqlApprovals) DeleteNotifications(ctx context.Context, ids []string) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	res, err := s.db.ExecContext(ctx, s.q(`
+		DELETE FROM approval_notification_outbox
+		WHERE id IN (?`+strings.Repeat(",?", len(ids)-1)+`) AND status IN ('sent', 'failed')`), args...)
+	if err != nil {
+		return 0, fmt.Errorf("approvals.DeleteNotifications: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("approvals.DeleteNotifications: %w", err)
+	}
+	return n, nil
+}
+
+// OutboxStats counts outbox rows by status and finds the oldest row not yet
+// delivered.
+func (s *sqlApprovals) OutboxStats(ctx context.Context) (OutboxStats, error) {
+	rows, err := s.db.QueryContext(ctx, s.q(`
+		SELECT status, COUNT(*)
+		FROM approval_notification_outbox
+		GROUP BY status`))
+	if err != nil {
+		return OutboxStats{}, fmt.Errorf("approvals.OutboxStats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := OutboxStats{Depth: make(map[string]int64)}
+	for rows.Next() {
+		var status string
+		var n int64
+		if err := rows.Scan(&status, &n); err != nil {
+			return OutboxStats{}, fmt.Errorf("approvals.OutboxStats scan: %w", err)
+		}
+		stats.Depth[status] = n
+	}
+	if err := rows.Err(); err != nil {
+		return OutboxStats{}, fmt.Errorf("approvals.OutboxStats iteration: %w", err)
+	}
+
+	var oldest time.Time
+	err = s.db.QueryRowContext(ctx, s.q(`
+		SELECT created_at
+		FROM approval_notification_outbox
+		WHERE status IN ('pending', 'processing')
+		ORDER BY created_at
+		LIMIT 1`)).Scan(&oldest)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return OutboxStats{}, fmt.Errorf("approvals.OutboxStats oldest: %w", err)
+	default:
+		stats.OldestPending = &oldest
+	}
+	return stats, nil
+}
+
+// PurgeFailedNotifications deletes failed rows, narrowed by tenantID, id,
+// and a last-update cutoff when those are set.
+func (s *sqlApprovals) PurgeFailedNotifications(ctx context.Context, tenantID, id string, before time.Time) (int64, error) {
+	var cutoff sql.NullTime
+	if !before.IsZero() {
+		cutoff = sql.NullTime{Time: before.UTC(), Valid: true}
+	}
+	res, err := s.db.ExecContext(ctx, s.q(`
+		DELETE FROM approval_notification_outbox
+		WHERE status = 'failed'
+		  AND (? = '' OR tenant_id = ?)
+		  AND (? = '' OR id = ?)
+		  AND (? IS NULL OR COALESCE(updated_at, created_at) < ?)`),
+		tenantID, tenantID, id, id, cutoff, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("approvals.PurgeFailedNotifications: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("approvals.PurgeFailedNotifications: %w", err)
+	}
+	return n, nil
+}
@@ -3,16 +3,24 @@ package approvals
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"path"
 	"strings"
 	"time"
 
+	"github.com/bturcanu/OpenClause/pkg/connectors"
+	"github.com/bturcanu/OpenClause/pkg/types"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("github.com/bturcanu/OpenClause/pkg/approvals")
+
 // Store manages approval requests and grants in Postgres.
 type Store struct {
 	pool *pgxpool.Pool
@@ -42,11 +50,26 @@ func (s *Store) CreateRequest(ctx context.Context, in CreateApprovalInput) (*App
 		Tool:      in.Tool,
 		Action:    in.Action,
 		Resource:  in.Resource,
+		SessionID: in.SessionID,
 		RiskScore: in.RiskScore,
 		Reason:    in.Reason,
 		Status:    "pending",
 		CreatedAt: now,
-		ExpiresAt: now.Add(24 * time.Hour),
+		ExpiresAt: now.Add(in.RequestTTL()),
+		Plan:      in.Plan,
+
+		ApproverGroup:  in.ApproverGroup,
+		Kind:           in.Kind,
+		RecentActivity: in.RecentActivity,
+		GrantTTLSec:    max(in.GrantTTLSec, 0),
+	}
+	planJSON, err := encodePlan(in.Plan)
+	if err != nil {
+		return nil, fmt.Errorf("approvals.CreateRequest: %w", err)
+	}
+	activityJSON, err := encodeActivity(in.RecentActivity)
+	if err != nil {
+		return nil, fmt.Errorf("approvals.CreateRequest: %w", err)
 	}
 
 	tx, err := s.pool.Begin(ctx)
@@ -57,13 +80,15 @@ func (s *Store) CreateRequest(ctx context.Context, in CreateApprovalInput) (*App
 
 	_, err = tx.Exec(ctx, `
 		INSERT INTO approval_requests (
-			id, event_id, tenant_id, agent_id, tool, action, resource,
-			risk_score, reason, status, created_at, expires_at
-		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)`,
+			id, event_id, tenant_id, agent_id, tool, action, resource, session_id,
+			risk_score, reason, status, created_at, expires_at, plan, approver_group, kind, recent_activity,
+			grant_ttl_sec
+		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18)`,
 		req.ID, req.EventID, req.TenantID, req.AgentID,
-		req.Tool, req.Action, req.Resource,
+		req.Tool, req.Action, req.Resource, req.SessionID,
 		req.RiskScore, req.Reason, req.Status,
-		req.CreatedAt, req.ExpiresAt,
+		req.CreatedAt, req.ExpiresAt, planJSON, req.ApproverGroup, req.Kind, activityJSON,
+		req.GrantTTLSec,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("approvals.CreateRequest insert request: %w", err)
@@ -79,22 +104,26 @@ func (s *Store) CreateRequest(ctx context.Context, in CreateApprovalInput) (*App
 		if n.Kind == "" {
 			continue
 		}
+		configJSON, err := notifyConfigJSON(n.Config)
+		if err != nil {
+			return nil, fmt.Errorf("approvals.CreateRequest: %w", err)
+		}
 		outboxID := uuid.NewString()
 		_, err = tx.Exec(ctx, `
 			INSERT INTO approval_notification_outbox (
 				id, approval_request_id, tenant_id, event_id, trace_id, tool, action, resource,
 				risk_score, risk_factors, reason, approver_group, approval_url,
-				notify_kind, notify_url, secret_ref, slack_channel,
+				notify_kind, notify_url, secret_ref, slack_channel, notify_config, plan, recent_activity,
 				status, attempt_count, next_attempt_at, created_at, updated_at
 			) VALUES (
 				$1,$2,$3,$4,$5,$6,$7,$8,
 				$9,$10,$11,$12,$13,
-				$14,$15,$16,$17,
+				$14,$15,$16,$17,$18,$19,$20,
 				'pending',0,NOW(),NOW(),NOW()
 			)`,
 			outboxID, req.ID, req.TenantID, req.EventID, in.TraceID, req.Tool, req.Action, req.Resource,
 			req.RiskScore, riskFactorsJSON, req.Reason, in.ApproverGroup, approvalURL,
-			n.Kind, n.URL, n.SecretRef, n.Channel,
+			n.Kind, n.URL, n.SecretRef, n.Channel, configJSON, planJSON, activityJSON,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("approvals.CreateRequest insert outbox: %w", err)
@@ -110,18 +139,20 @@ func (s *Store) CreateRequest(ctx context.Context, in CreateApprovalInput) (*App
 // GetRequest fetches a single approval request.
 func (s *Store) GetRequest(ctx context.Context, id string) (*ApprovalRequest, error) {
 	row := s.pool.QueryRow(ctx, `
-		SELECT id, event_id, tenant_id, agent_id, tool, action, resource,
-		       risk_score, reason, deny_reason, status, created_at, expires_at
+		SELECT id, event_id, tenant_id, agent_id, tool, action, resource, session_id,
+		       risk_score, reason, deny_reason, status, created_at, expires_at, plan, approver_group, kind, recent_activity,
+		       grant_ttl_sec
 		FROM approval_requests WHERE id = $1`, id)
 
 	r := &ApprovalRequest{}
 	err := row.Scan(
 		&r.ID, &r.EventID, &r.TenantID, &r.AgentID,
-		&r.Tool, &r.Action, &r.Resource,
+		&r.Tool, &r.Action, &r.Resource, &r.SessionID,
 		&r.RiskScore, &r.Reason, &r.DenyReason, &r.Status,
-		&r.CreatedAt, &r.ExpiresAt,
+		&r.CreatedAt, &r.ExpiresAt, &r.Plan, &r.ApproverGroup, &r.Kind, &r.RecentActivity,
+		&r.GrantTTLSec,
 	)
-	if err == pgx.ErrNoRows {
+	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, nil
 	}
 	if err != nil {
@@ -130,24 +161,64 @@ func (s *Store) GetRequest(ctx context.Context, id string) (*ApprovalRequest, er
 	return r, nil
 }
 
+// ListRequestsByEvents returns the tenant's approval requests raised for
+// any of eventIDs, oldest first.
+func (s *Store) ListRequestsByEvents(ctx context.Context, tenantID string, eventIDs []string) ([]ApprovalRequest, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, event_id, tenant_id, agent_id, tool, action, resource, session_id,
+		       risk_score, reason, deny_reason, status, created_at, expires_at, plan, approver_group, kind, recent_activity,
+		       grant_ttl_sec
+		FROM approval_requests
+		WHERE tenant_id = $1 AND event_id = ANY($2)
+		ORDER BY created_at ASC`, tenantID, eventIDs)
+	if err != nil {
+		return nil, fmt.Errorf("approvals.ListRequestsByEvents: %w", err)
+	}
+	defer rows.Close()
+
+	reqs := make([]ApprovalRequest, 0)
+	for rows.Next() {
+		var r ApprovalRequest
+		if err := rows.Scan(
+			&r.ID, &r.EventID, &r.TenantID, &r.AgentID,
+			&r.Tool, &r.Action, &r.Resource, &r.SessionID,
+			&r.RiskScore, &r.Reason, &r.DenyReason, &r.Status,
+			&r.CreatedAt, &r.ExpiresAt, &r.Plan, &r.ApproverGroup, &r.Kind, &r.RecentActivity,
+			&r.GrantTTLSec,
+		); err != nil {
+			return nil, fmt.Errorf("approvals.ListRequestsByEvents scan: %w", err)
+		}
+		reqs = append(reqs, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("approvals.ListRequestsByEvents iteration: %w", err)
+	}
+	return reqs, nil
+}
+
 const defaultPendingLimit = 200
 
-// ListPending returns pending requests for a tenant (paginated).
-func (s *Store) ListPending(ctx context.Context, tenantID string, limit, offset int) ([]ApprovalRequest, error) {
+// pageLimit clamps a requested page size to (0, defaultPendingLimit]; 0
+// means the default.
+func pageLimit(limit int) int {
 	if limit <= 0 || limit > defaultPendingLimit {
-		limit = defaultPendingLimit
-	}
-	if offset < 0 {
-		offset = 0
+		return defaultPendingLimit
 	}
+	return limit
+}
 
+// ListPending returns a page of a tenant's pending requests, newest first,
+// starting after the cursor.
+func (s *Store) ListPending(ctx context.Context, tenantID string, limit int, after types.Cursor) ([]ApprovalRequest, error) {
 	rows, err := s.pool.Query(ctx, `
-		SELECT id, event_id, tenant_id, agent_id, tool, action, resource,
-		       risk_score, reason, deny_reason, status, created_at, expires_at
+		SELECT id, event_id, tenant_id, agent_id, tool, action, resource, session_id,
+		       risk_score, reason, deny_reason, status, created_at, expires_at, plan, approver_group, kind, recent_activity,
+		       grant_ttl_sec
 		FROM approval_requests
 		WHERE tenant_id = $1 AND status = 'pending' AND expires_at > NOW()
-		ORDER BY created_at DESC
-		LIMIT $2 OFFSET $3`, tenantID, limit, offset)
+		  AND ($4 = '' OR created_at < $3 OR (created_at = $3 AND id < $4))
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2`, tenantID, pageLimit(limit), after.Time, after.ID)
 	if err != nil {
 		return nil, fmt.Errorf("approvals.ListPending: %w", err)
 	}
@@ -158,9 +229,10 @@ func (s *Store) ListPending(ctx context.Context, tenantID string, limit, offset
 		var r ApprovalRequest
 		if err := rows.Scan(
 			&r.ID, &r.EventID, &r.TenantID, &r.AgentID,
-			&r.Tool, &r.Action, &r.Resource,
+			&r.Tool, &r.Action, &r.Resource, &r.SessionID,
 			&r.RiskScore, &r.Reason, &r.DenyReason, &r.Status,
-			&r.CreatedAt, &r.ExpiresAt,
+			&r.CreatedAt, &r.ExpiresAt, &r.Plan, &r.ApproverGroup, &r.Kind, &r.RecentActivity,
+			&r.GrantTTLSec,
 		); err != nil {
 			return nil, fmt.Errorf("approvals.ListPending scan: %w", err)
 		}
@@ -172,6 +244,33 @@ func (s *Store) ListPending(ctx context.Context, tenantID string, limit, offset
 	return reqs, nil
 }
 
+// CountPendingByTenant returns the number of pending, unexpired requests per tenant.
+func (s *Store) CountPendingByTenant(ctx context.Context) (map[string]int64, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT tenant_id, COUNT(*)
+		FROM approval_requests
+		WHERE status = 'pending' AND expires_at > NOW()
+		GROUP BY tenant_id`)
+	if err != nil {
+		return nil, fmt.Errorf("approvals.CountPendingByTenant: %w", err)
+	}
+	defer rows.Close()
+
+	out := make(map[string]int64)
+	for rows.Next() {
+		var tenantID string
+		var n int64
+		if err := rows.Scan(&tenantID, &n); err != nil {
+			return nil, fmt.Errorf("approvals.CountPendingByTenant scan: %w", err)
+		}
+		out[tenantID] = n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("approvals.CountPendingByTenant iteration: %w", err)
+	}
+	return out, nil
+}
+
 // ──────────────────────────────────────────────────────────────────────────────
 // Approval Grants
 // ──────────────────────────────────────────────────────────────────────────────
@@ -202,26 +301,17 @@ func (s *Store) GrantRequest(ctx context.Context, requestID string, in GrantInpu
 
 	// Fetch the request details for the grant scope.
 	row := tx.QueryRow(ctx, `
-		SELECT tenant_id, agent_id, tool, action, resource
+		SELECT tenant_id, agent_id, tool, action, resource, session_id
 		FROM approval_requests WHERE id = $1`, requestID)
-	var tenantID, agentID, tool, action, resource string
-	if err := row.Scan(&tenantID, &agentID, &tool, &action, &resource); err != nil {
+	var tenantID, agentID, tool, action, resource, sessionID string
+	if err := row.Scan(&tenantID, &agentID, &tool, &action, &resource, &sessionID); err != nil {
 		return nil, fmt.Errorf("approvals.GrantRequest fetch: %w", err)
 	}
 
-	maxUses := in.MaxUses
-	if maxUses <= 0 {
-		maxUses = 1
-	}
 	now := time.Now().UTC()
-	expiry := now.Add(1 * time.Hour)
-	if in.ExpiresInSec > 0 {
-		expiry = now.Add(time.Duration(in.ExpiresInSec) * time.Second)
-	}
-
-	resourcePattern := in.ResourcePattern
-	if resourcePattern == "" {
-		resourcePattern = resource
+	maxUses, expiry, resourcePattern, scopeSession, err := in.grantDefaults(now, resource, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("approvals.GrantRequest: %w", err)
 	}
 
 	grant := &ApprovalGrant{
@@ -235,27 +325,39 @@ func (s *Store) GrantRequest(ctx context.Context, requestID string, in GrantInpu
 			ResourcePattern: resourcePattern,
 			TenantID:        tenantID,
 			AgentID:         agentID,
+			SessionID:       scopeSession,
+			ValidHours:      in.ValidHours,
 		},
 		MaxUses:   maxUses,
 		UsesLeft:  maxUses,
 		ExpiresAt: expiry,
 		GrantedAt: now,
+
+		ApproverIssuer:  in.ApproverIssuer,
+		ApproverSubject: in.ApproverSubject,
+	}
+	validHours, err := encodeValidHours(grant.Scope.ValidHours)
+	if err != nil {
+		return nil, fmt.Errorf("approvals.GrantRequest: %w", err)
 	}
 
 	_, err = tx.Exec(ctx, `
 		INSERT INTO approval_grants (
-			id, request_id, tenant_id, approver,
+			id, request_id, tenant_id, approver, approver_issuer, approver_subject,
 			scope_tool, scope_action, scope_resource_pattern, scope_tenant_id, scope_agent_id,
-			max_uses, uses_left, expires_at, granted_at
-		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)`,
-		grant.ID, grant.RequestID, grant.TenantID, grant.Approver,
+			scope_session_id, scope_valid_hours, max_uses, uses_left, expires_at, granted_at
+		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17)`,
+		grant.ID, grant.RequestID, grant.TenantID, grant.Approver, grant.ApproverIssuer, grant.ApproverSubject,
 		grant.Scope.Tool, grant.Scope.Action, grant.Scope.ResourcePattern,
-		grant.Scope.TenantID, grant.Scope.AgentID,
+		grant.Scope.TenantID, grant.Scope.AgentID, grant.Scope.SessionID, validHours,
 		grant.MaxUses, grant.UsesLeft, grant.ExpiresAt, grant.GrantedAt,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("approvals.GrantRequest insert: %w", err)
 	}
+	if err := enqueueSlackUpdates(ctx, tx, requestID, "approved", in.Approver, ""); err != nil {
+		return nil, fmt.Errorf("approvals.GrantRequest: %w", err)
+	}
 
 	if err := tx.Commit(ctx); err != nil {
 		return nil, fmt.Errorf("approvals.GrantRequest commit: %w", err)
@@ -270,7 +372,13 @@ func (s *Store) DenyRequest(ctx context.Context, requestID string, in DenyInput)
 	if in.Approver == "" {
 		return fmt.Errorf("approvals.DenyRequest: approver is required")
 	}
-	res, err := s.pool.Exec(ctx, `
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("approvals.DenyRequest begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback after commit is a no-op
+
+	res, err := tx.Exec(ctx, `
 		UPDATE approval_requests SET status = 'denied', deny_reason = $2, denied_by = $3, updated_at = NOW()
 		WHERE id = $1 AND status = 'pending'`, requestID, in.Reason, in.Approver)
 	if err != nil {
@@ -279,53 +387,298 @@ func (s *Store) DenyRequest(ctx context.Context, requestID string, in DenyInput)
 	if res.RowsAffected() == 0 {
 		return fmt.Errorf("approval request %s not found or not pending", requestID)
 	}
+	if err := enqueueSlackUpdates(ctx, tx, requestID, "denied", in.Approver, in.Reason); err != nil {
+		return fmt.Errorf("approvals.DenyRequest: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("approvals.DenyRequest commit: %w", err)
+	}
+	return nil
+}
+
+// CancelRequest marks a pending request cancelled by the agent that made
+// it and queues the rewrite of its Slack messages. The caller checks that
+// in names the request's tenant and agent (see CheckCancel); ErrNotPending
+// is returned when the request is no longer pending.
+func (s *Store) CancelRequest(ctx context.Context, requestID string, in CancelInput) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("approvals.CancelRequest begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback after commit is a no-op
+
+	res, err := tx.Exec(ctx, `
+		UPDATE approval_requests SET status = 'cancelled', updated_at = NOW()
+		WHERE id = $1 AND tenant_id = $2 AND agent_id = $3 AND status = 'pending' AND kind = ''`,
+		requestID, in.TenantID, in.AgentID)
+	if err != nil {
+		return fmt.Errorf("approvals.CancelRequest: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("approvals.CancelRequest %s: %w", requestID, ErrNotPending)
+	}
+	if err := enqueueSlackUpdates(ctx, tx, requestID, "cancelled", in.AgentID, in.Reason); err != nil {
+		return fmt.Errorf("approvals.CancelRequest: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("approvals.CancelRequest commit: %w", err)
+	}
 	return nil
 }
 
+// ReleaseRequest marks a pending quarantine request approved, releasing
+// the withheld output to the agent. Unlike GrantRequest it creates no
+// grant, so no later call is authorized by it.
+func (s *Store) ReleaseRequest(ctx context.Context, requestID string, in ReleaseInput) error {
+	if in.Approver == "" {
+		return fmt.Errorf("approvals.ReleaseRequest: approver is required")
+	}
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("approvals.ReleaseRequest begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback after commit is a no-op
+
+	res, err := tx.Exec(ctx, `
+		UPDATE approval_requests SET status = 'approved', released_by = $2, updated_at = NOW()
+		WHERE id = $1 AND status = 'pending' AND kind = 'quarantine'`, requestID, in.Approver)
+	if err != nil {
+		return fmt.Errorf("approvals.ReleaseRequest: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("quarantine request %s not found or not pending", requestID)
+	}
+	if err := enqueueSlackUpdates(ctx, tx, requestID, "released", in.Approver, ""); err != nil {
+		return fmt.Errorf("approvals.ReleaseRequest: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("approvals.ReleaseRequest commit: %w", err)
+	}
+	return nil
+}
+
+// ExpireRequests marks pending requests past expires_at as expired so their
+// Slack messages are rewritten, and returns the IDs it expired.
+func (s *Store) ExpireRequests(ctx context.Context) ([]string, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("approvals.ExpireRequests begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback after commit is a no-op
+
+	rows, err := tx.Query(ctx, `
+		UPDATE approval_requests SET status = 'expired', updated_at = NOW()
+		WHERE status = 'pending' AND expires_at <= NOW()
+		RETURNING id`)
+	if err != nil {
+		return nil, fmt.Errorf("approvals.ExpireRequests: %w", err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("approvals.ExpireRequests scan: %w", err)
+	}
+	for _, id := range ids {
+		if err := enqueueSlackUpdates(ctx, tx, id, "expired", "", ""); err != nil {
+			return nil, fmt.Errorf("approvals.ExpireRequests: %w", err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("approvals.ExpireRequests commit: %w", err)
+	}
+	return ids, nil
+}
+
+// enqueueSlackUpdates queues a "slack_update" outbox row for every Slack
+// approval message of the request, in the transaction that resolves it.
+// The ID is derived from the parent row so each message is rewritten once.
+func enqueueSlackUpdates(ctx context.Context, tx pgx.Tx, requestID, resolution, resolvedBy, reason string) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO approval_notification_outbox (
+			id, approval_request_id, tenant_id, event_id, trace_id, tool, action, resource,
+			risk_score, risk_factors, reason, approver_group, approval_url,
+			notify_kind, slack_channel, parent_id, resolution, resolved_by, resolution_reason,
+			status, attempt_count, next_attempt_at, created_at, updated_at
+		)
+		SELECT o.id || ':update', o.approval_request_id, o.tenant_id, o.event_id, o.trace_id, o.tool, o.action, o.resource,
+		       o.risk_score, o.risk_factors, o.reason, o.approver_group, o.approval_url,
+		       'slack_update', o.slack_channel, o.id, $2, $3, $4,
+		       'pending', 0, NOW(), NOW(), NOW()
+		FROM approval_notification_outbox o
+		WHERE o.approval_request_id = $1 AND o.notify_kind = 'slack' AND o.status <> 'failed'
+		ON CONFLICT (id) DO NOTHING`, requestID, resolution, resolvedBy, reason)
+	if err != nil {
+		return fmt.Errorf("enqueue slack updates: %w", err)
+	}
+	return nil
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Comments
+// ──────────────────────────────────────────────────────────────────────────────
+
+// AddComment stores c on its request's thread and, in the same transaction,
+// queues a "slack_comment" outbox row replying in the thread of each Slack
+// approval message of the request.
+func (s *Store) AddComment(ctx context.Context, c Comment) (*Comment, error) {
+	c.ID = uuid.NewString()
+	c.CreatedAt = time.Now().UTC()
+	commentJSON, err := json.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("approvals.AddComment: %w", err)
+	}
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("approvals.AddComment begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback after commit is a no-op
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO approval_comments (id, approval_request_id, tenant_id, author, role, body, created_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7)`,
+		c.ID, c.RequestID, c.TenantID, c.Author, c.Role, c.Body, c.CreatedAt,
+	); err != nil {
+		return nil, fmt.Errorf("approvals.AddComment insert: %w", err)
+	}
+	rows, err := tx.Query(ctx, `
+		SELECT id FROM approval_notification_outbox
+		WHERE approval_request_id = $1 AND notify_kind = 'slack' AND status <> 'failed'`, c.RequestID)
+	if err != nil {
+		return nil, fmt.Errorf("approvals.AddComment list slack messages: %w", err)
+	}
+	parents, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("approvals.AddComment list slack messages: %w", err)
+	}
+	for _, parent := range parents {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO approval_notification_outbox (
+				id, approval_request_id, tenant_id, event_id, trace_id, tool, action, resource,
+				risk_score, risk_factors, reason, approver_group, approval_url,
+				notify_kind, slack_channel, parent_id, comment,
+				status, attempt_count, next_attempt_at, created_at, updated_at
+			)
+			SELECT $1, o.approval_request_id, o.tenant_id, o.event_id, o.trace_id, o.tool, o.action, o.resource,
+			       o.risk_score, o.risk_factors, o.reason, o.approver_group, o.approval_url,
+			       'slack_comment', o.slack_channel, o.id, $3,
+			       'pending', 0, NOW(), NOW(), NOW()
+			FROM approval_notification_outbox o
+			WHERE o.id = $2`, uuid.NewString(), parent, commentJSON,
+		); err != nil {
+			return nil, fmt.Errorf("approvals.AddComment enqueue slack reply: %w", err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("approvals.AddComment commit: %w", err)
+	}
+	return &c, nil
+}
+
+// ListComments returns the comments on the given requests, oldest first.
+func (s *Store) ListComments(ctx context.Context, requestIDs []string) ([]Comment, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, approval_request_id, tenant_id, author, role, body, created_at
+		FROM approval_comments
+		WHERE approval_request_id = ANY($1)
+		ORDER BY created_at ASC, id ASC`, requestIDs)
+	if err != nil {
+		return nil, fmt.Errorf("approvals.ListComments: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]Comment, 0)
+	for rows.Next() {
+		var c Comment
+		if err := rows.Scan(&c.ID, &c.RequestID, &c.TenantID, &c.Author, &c.Role, &c.Body, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("approvals.ListComments scan: %w", err)
+		}
+		out = append(out, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("approvals.ListComments iteration: %w", err)
+	}
+	return out, nil
+}
+
 // ──────────────────────────────────────────────────────────────────────────────
 // Grant consumption (called by gateway)
 // ──────────────────────────────────────────────────────────────────────────────
 
 // FindAndConsumeGrant finds a valid grant matching the given scope and atomically
-// decrements its usage. Iterates through all candidates (not just LIMIT 1) to
-// ensure resource-pattern mismatches don't hide valid grants.
-func (s *Store) FindAndConsumeGrant(ctx context.Context, tenantID, agentID, tool, action, resource string) (*ApprovalGrant, error) {
+// decrements its usage, recording the use by eventID. Iterates through all
+// candidates (not just LIMIT 1) to ensure resource-pattern mismatches don't
+// hide valid grants. Session grants match only calls from the same session,
+// and grants with valid hours only calls made inside them.
+func (s *Store) FindAndConsumeGrant(ctx context.Context, eventID, tenantID, agentID, sessionID, tool, action, resource string) (*ApprovalGrant, error) {
+	return s.consumeTraced(ctx, eventID, tenantID, agentID, sessionID, tool, action, resource, false)
+}
+
+// FindAndConsumeSessionGrant is FindAndConsumeGrant restricted to grants
+// scoped to sessionID. The gateway calls it for new tool calls so a session
+// approval covers them without a fresh approval request.
+func (s *Store) FindAndConsumeSessionGrant(ctx context.Context, eventID, tenantID, agentID, sessionID, tool, action, resource string) (*ApprovalGrant, error) {
+	if sessionID == "" {
+		return nil, nil
+	}
+	return s.consumeTraced(ctx, eventID, tenantID, agentID, sessionID, tool, action, resource, true)
+}
+
+func (s *Store) consumeTraced(ctx context.Context, eventID, tenantID, agentID, sessionID, tool, action, resource string, sessionOnly bool) (*ApprovalGrant, error) {
+	ctx, span := tracer.Start(ctx, "approvals.FindAndConsumeGrant", trace.WithAttributes(
+		attribute.String("oc.tool", tool),
+		attribute.String("oc.action", action),
+		attribute.Bool("oc.session_only", sessionOnly),
+	))
+	defer span.End()
+
+	grant, err := s.findAndConsumeGrant(ctx, eventID, tenantID, agentID, sessionID, tool, action, resource, sessionOnly)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.SetAttributes(attribute.Bool("oc.grant_found", grant != nil))
+	return grant, err
+}
+
+func (s *Store) findAndConsumeGrant(ctx context.Context, eventID, tenantID, agentID, sessionID, tool, action, resource string, sessionOnly bool) (*ApprovalGrant, error) {
 	tx, err := s.pool.Begin(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("approvals.FindAndConsumeGrant begin: %w", err)
 	}
 	defer tx.Rollback(ctx) //nolint:errcheck // rollback after commit is a no-op
 
+	// max_uses = 0 marks an unlimited session grant.
 	rows, err := tx.Query(ctx, `
-		SELECT id, request_id, tenant_id, approver,
+		SELECT id, request_id, tenant_id, approver, approver_issuer, approver_subject,
 		       scope_tool, scope_action, scope_resource_pattern, scope_tenant_id, scope_agent_id,
-		       max_uses, uses_left, expires_at, granted_at
+		       scope_session_id, scope_valid_hours, max_uses, uses_left, expires_at, granted_at
 		FROM approval_grants
 		WHERE tenant_id = $1
-		  AND uses_left > 0
+		  AND (uses_left > 0 OR max_uses = 0)
 		  AND expires_at > NOW()
 		  AND (scope_tool = $2 OR scope_tool = '*')
 		  AND (scope_action = $3 OR scope_action = '*')
 		  AND (scope_agent_id = '' OR scope_agent_id = $4)
+		  AND (scope_session_id = $5 OR (scope_session_id = '' AND NOT $6))
 		ORDER BY granted_at DESC
-		FOR UPDATE`, tenantID, tool, action, agentID)
+		FOR UPDATE`, tenantID, tool, action, agentID, sessionID, sessionOnly)
 	if err != nil {
 		return nil, fmt.Errorf("approvals.FindAndConsumeGrant query: %w", err)
 	}
 	defer rows.Close()
 
+	now := time.Now()
 	for rows.Next() {
 		g := &ApprovalGrant{}
 		if err := rows.Scan(
-			&g.ID, &g.RequestID, &g.TenantID, &g.Approver,
+			&g.ID, &g.RequestID, &g.TenantID, &g.Approver, &g.ApproverIssuer, &g.ApproverSubject,
 			&g.Scope.Tool, &g.Scope.Action, &g.Scope.ResourcePattern,
-			&g.Scope.TenantID, &g.Scope.AgentID,
+			&g.Scope.TenantID, &g.Scope.AgentID, &g.Scope.SessionID, &g.Scope.ValidHours,
 			&g.MaxUses, &g.UsesLeft, &g.ExpiresAt, &g.GrantedAt,
 		); err != nil {
 			return nil, fmt.Errorf("approvals.FindAndConsumeGrant scan: %w", err)
 		}
 
-		if !matchResource(g.Scope.ResourcePattern, resource) {
+		if !matchResource(g.Scope.ResourcePattern, resource) || !g.UsableAt(now) {
 			continue
 		}
 
@@ -333,16 +686,26 @@ func (s *Store) FindAndConsumeGrant(ctx context.Context, tenantID, agentID, tool
 		rows.Close()
 
 		_, err = tx.Exec(ctx, `
-			UPDATE approval_grants SET uses_left = uses_left - 1 WHERE id = $1`, g.ID)
+			UPDATE approval_grants SET uses_left = uses_left - 1, updated_at = NOW()
+			WHERE id = $1 AND max_uses > 0`, g.ID)
 		if err != nil {
 			return nil, fmt.Errorf("approvals.FindAndConsumeGrant update: %w", err)
 		}
+		_, err = tx.Exec(ctx, `
+			INSERT INTO grant_usages (id, grant_id, tenant_id, event_id, agent_id, resource, used_at)
+			VALUES ($1, $2, $3, $4, $5, $6, NOW())`,
+			uuid.NewString(), g.ID, tenantID, eventID, agentID, resource)
+		if err != nil {
+			return nil, fmt.Errorf("approvals.FindAndConsumeGrant record usage: %w", err)
+		}
 
 		if err := tx.Commit(ctx); err != nil {
 			return nil, fmt.Errorf("approvals.FindAndConsumeGrant commit: %w", err)
 		}
 
-		g.UsesLeft--
+		if g.MaxUses > 0 {
+			g.UsesLeft--
+		}
 		return g, nil
 	}
 	if err := rows.Err(); err != nil {
@@ -352,18 +715,61 @@ func (s *Store) FindAndConsumeGrant(ctx context.Context, tenantID, agentID, tool
 	return nil, nil
 }
 
-// matchResource checks whether a resource matches a grant's resource pattern.
-// Uses path.Match which is OS-independent (unlike filepath.Match).
-// Empty or "*" patterns match everything.
-func matchResource(pattern, resource string) bool {
-	if pattern == "" || pattern == "*" {
-		return true
+// GetGrant fetches a single grant.
+func (s *Store) GetGrant(ctx context.Context, id string) (*ApprovalGrant, error) {
+	g := &ApprovalGrant{}
+	err := s.pool.QueryRow(ctx, `
+		SELECT id, request_id, tenant_id, approver, approver_issuer, approver_subject,
+		       scope_tool, scope_action, scope_resource_pattern, scope_tenant_id, scope_agent_id,
+		       scope_session_id, scope_valid_hours, max_uses, uses_left, expires_at, granted_at
+		FROM approval_grants WHERE id = $1`, id).Scan(
+		&g.ID, &g.RequestID, &g.TenantID, &g.Approver, &g.ApproverIssuer, &g.ApproverSubject,
+		&g.Scope.Tool, &g.Scope.Action, &g.Scope.ResourcePattern,
+		&g.Scope.TenantID, &g.Scope.AgentID, &g.Scope.SessionID, &g.Scope.ValidHours,
+		&g.MaxUses, &g.UsesLeft, &g.ExpiresAt, &g.GrantedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
 	}
-	matched, err := path.Match(pattern, resource)
 	if err != nil {
-		return false
+		return nil, fmt.Errorf("approvals.GetGrant: %w", err)
 	}
-	return matched
+	return g, nil
+}
+
+// ListGrantUsages returns a page of the uses of grant grantID, newest
+// first, starting after the cursor.
+func (s *Store) ListGrantUsages(ctx context.Context, grantID string, limit int, after types.Cursor) ([]GrantUsage, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, grant_id, tenant_id, event_id, agent_id, resource, used_at
+		FROM grant_usages
+		WHERE grant_id = $1
+		  AND ($4 = '' OR used_at < $3 OR (used_at = $3 AND id < $4))
+		ORDER BY used_at DESC, id DESC
+		LIMIT $2`, grantID, pageLimit(limit), after.Time, after.ID)
+	if err != nil {
+		return nil, fmt.Errorf("approvals.ListGrantUsages: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]GrantUsage, 0)
+	for rows.Next() {
+		var u GrantUsage
+		if err := rows.Scan(&u.ID, &u.GrantID, &u.TenantID, &u.EventID, &u.AgentID, &u.Resource, &u.UsedAt); err != nil {
+			return nil, fmt.Errorf("approvals.ListGrantUsages scan: %w", err)
+		}
+		out = append(out, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("approvals.ListGrantUsages iteration: %w", err)
+	}
+	return out, nil
+}
+
+// matchResource checks whether a resource matches a grant's resource pattern;
+// see types.MatchResource. Empty or "*" patterns match everything.
+func matchResource(pattern, resource string) bool {
+	return types.MatchResource(pattern, resource)
 }
 
 // ClaimDueNotifications claims pending due rows for delivery using row-level
@@ -390,8 +796,9 @@ func (s *Store) ClaimDueNotifications(ctx context.Context, limit int) ([]Notific
 		WHERE o.id = due.id
 		RETURNING o.id, o.approval_request_id, o.tenant_id, o.event_id, o.trace_id, o.tool, o.action, o.resource,
 		          o.risk_score, o.risk_factors, o.reason, o.approver_group, o.approval_url,
-		          o.notify_kind, o.notify_url, o.secret_ref, o.slack_channel,
-		          o.attempt_count, o.status, o.next_attempt_at, o.created_at`, limit)
+		          o.notify_kind, o.notify_url, o.secret_ref, o.slack_channel, o.notify_config, o.plan, o.recent_activity,
+		          o.attempt_count, o.status, o.next_attempt_at, o.created_at,
+		          o.parent_id, o.resolution, o.resolved_by, o.resolution_reason, o.comment`, limit)
 	if err != nil {
 		return nil, fmt.Errorf("approvals.ClaimDueNotifications: %w", err)
 	}
@@ -400,13 +807,14 @@ func (s *Store) ClaimDueNotifications(ctx context.Context, limit int) ([]Notific
 	out := make([]NotificationOutbox, 0)
 	for rows.Next() {
 		var n NotificationOutbox
-		var riskFactors []byte
+		var riskFactors, notifyConfig []byte
 		if err := rows.Scan(
 			&n.ID, &n.ApprovalRequestID, &n.TenantID, &n.EventID, &n.TraceID,
 			&n.Tool, &n.Action, &n.Resource, &n.RiskScore, &riskFactors,
 			&n.Reason, &n.ApproverGroup, &n.ApprovalURL,
-			&n.NotifyKind, &n.NotifyURL, &n.SecretRef, &n.SlackChannel,
+			&n.NotifyKind, &n.NotifyURL, &n.SecretRef, &n.SlackChannel, &notifyConfig, &n.Plan, &n.RecentActivity,
 			&n.Attempts, &n.Status, &n.NextAttemptAt, &n.CreatedAt,
+			&n.ParentID, &n.Resolution, &n.ResolvedBy, &n.ResolutionReason, &n.Comment,
 		); err != nil {
 			return nil, fmt.Errorf("approvals.ClaimDueNotifications scan: %w", err)
 		}
@@ -415,6 +823,11 @@ func (s *Store) ClaimDueNotifications(ctx context.Context, limit int) ([]Notific
 				return nil, fmt.Errorf("approvals.ClaimDueNotifications unmarshal risk factors: %w", err)
 			}
 		}
+		if len(notifyConfig) > 0 {
+			if err := json.Unmarshal(notifyConfig, &n.NotifyConfig); err != nil {
+				return nil, fmt.Errorf("approvals.ClaimDueNotifications unmarshal notify config: %w", err)
+			}
+		}
 		out = append(out, n)
 	}
 	if err := rows.Err(); err != nil {
@@ -438,6 +851,39 @@ func (s *Store) MarkNotificationSent(ctx context.Context, id string) error {
 	return nil
 }
 
+// MarkSlackNotificationSent marks a "slack" outbox record as delivered and
+// stores where the message was posted so it can be updated on resolution.
+func (s *Store) MarkSlackNotificationSent(ctx context.Context, id, channel, ts string) error {
+	res, err := s.pool.Exec(ctx, `
+		UPDATE approval_notification_outbox
+		SET status = 'sent', sent_at = NOW(), updated_at = NOW(), last_error = '',
+		    slack_message_channel = $2, slack_message_ts = $3
+		WHERE id = $1`, id, channel, ts)
+	if err != nil {
+		return fmt.Errorf("approvals.MarkSlackNotificationSent: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("approvals.MarkSlackNotificationSent: no rows updated for id %s", id)
+	}
+	return nil
+}
+
+// SlackMessageFor returns the posted message of a "slack" outbox record, or
+// nil if there is no such record.
+func (s *Store) SlackMessageFor(ctx context.Context, outboxID string) (*SlackMessage, error) {
+	var m SlackMessage
+	err := s.pool.QueryRow(ctx, `
+		SELECT slack_message_channel, slack_message_ts, status
+		FROM approval_notification_outbox WHERE id = $1`, outboxID).Scan(&m.Channel, &m.TS, &m.Status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("approvals.SlackMessageFor: %w", err)
+	}
+	return &m, nil
+}
+
 // MarkNotificationRetry schedules another delivery attempt with backoff.
 func (s *Store) MarkNotificationRetry(ctx context.Context, id string, attempts int, nextAttemptAt time.Time, lastErr string) error {
 	res, err := s.pool.Exec(ctx, `
@@ -468,6 +914,269 @@ func (s *Store) MarkNotificationFailed(ctx context.Context, id string, lastErr s
 	return nil
 }
 
+// ──────────────────────────────────────────────────────────────────────────────
+// Dead-letter queue
+// ──────────────────────────────────────────────────────────────────────────────
+
+const deadLetterColumns = `id, approval_request_id, tenant_id, event_id, tool, action, COALESCE(resource, ''),
+		       notify_kind, COALESCE(notify_url, ''), COALESCE(slack_channel, ''), parent_id,
+		       status, attempt_count, COALESCE(last_error, ''), next_attempt_at, sent_at,
+		       created_at, updated_at`
+
+// scanDeadLetter reads a row selected with deadLetterColumns. updated_at
+// is read as is rather than coalesced in SQL because SQLite loses the
+// column's timestamp type in an expression.
+func scanDeadLetter(row rowScanner) (DeadLetter, error) {
+	var n DeadLetter
+	var updatedAt *time.Time
+	err := row.Scan(
+		&n.ID, &n.ApprovalRequestID, &n.TenantID, &n.EventID, &n.Tool, &n.Action, &n.Resource,
+		&n.NotifyKind, &n.NotifyURL, &n.SlackChannel, &n.ParentID,
+		&n.Status, &n.Attempts, &n.LastError, &n.NextAttemptAt, &n.SentAt,
+		&n.CreatedAt, &updatedAt,
+	)
+	n.UpdatedAt = n.CreatedAt
+	if updatedAt != nil {
+		n.UpdatedAt = *updatedAt
+	}
+	return n, err
+}
+
+// ListFailedNotifications returns a page of terminally failed outbox rows,
+// newest first, optionally for one tenant, starting after the cursor.
+func (s *Store) ListFailedNotifications(ctx context.Context, tenantID string, limit int, after types.Cursor) ([]DeadLetter, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+deadLetterColumns+`
+		FROM approval_notification_outbox
+		WHERE status = 'failed' AND ($1 = '' OR tenant_id = $1)
+		  AND ($4 = '' OR COALESCE(updated_at, created_at) < $3
+		       OR (COALESCE(updated_at, created_at) = $3 AND id < $4))
+		ORDER BY COALESCE(updated_at, created_at) DESC, id DESC
+		LIMIT $2`, tenantID, pageLimit(limit), after.Time, after.ID)
+	if err != nil {
+		return nil, fmt.Errorf("approvals.ListFailedNotifications: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]DeadLetter, 0)
+	for rows.Next() {
+		n, err := scanDeadLetter(rows)
+		if err != nil {
+			return nil, fmt.Errorf("approvals.ListFailedNotifications scan: %w", err)
+		}
+		out = append(out, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("approvals.ListFailedNotifications iteration: %w", err)
+	}
+	return out, nil
+}
+
+// GetNotification returns one outbox row in any status, or nil.
+func (s *Store) GetNotification(ctx context.Context, id string) (*DeadLetter, error) {
+	n, err := scanDeadLetter(s.pool.QueryRow(ctx, `
+		SELECT `+deadLetterColumns+`
+		FROM approval_notification_outbox WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("approvals.GetNotification: %w", err)
+	}
+	return &n, nil
+}
+
+// ListRequestNotifications returns every outbox row for an approval
+// request in any status, oldest first.
+func (s *Store) ListRequestNotifications(ctx context.Context, requestID string) ([]DeadLetter, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+deadLetterColumns+`
+		FROM approval_notification_outbox
+		WHERE approval_request_id = $1
+		ORDER BY created_at ASC, id ASC`, requestID)
+	if err != nil {
+		return nil, fmt.Errorf("approvals.ListRequestNotifications: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]DeadLetter, 0)
+	for rows.Next() {
+		n, err := scanDeadLetter(rows)
+		if err != nil {
+			return nil, fmt.Errorf("approvals.ListRequestNotifications scan: %w", err)
+		}
+		out = append(out, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("approvals.ListRequestNotifications iteration: %w", err)
+	}
+	return out, nil
+}
+
+// RequeueNotification returns a failed row to pending with a fresh attempt
+// budget. It reports false if id is not a failed row.
+func (s *Store) RequeueNotification(ctx context.Context, id string) (bool, error) {
+	res, err := s.pool.Exec(ctx, `
+		UPDATE approval_notification_outbox
+		SET status = 'pending', attempt_count = 0, next_attempt_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status = 'failed'`, id)
+	if err != nil {
+		return false, fmt.Errorf("approvals.RequeueNotification: %w", err)
+	}
+	return res.RowsAffected() > 0, nil
+}
+
+// PurgeFailedNotifications deletes failed rows, narrowed by tenantID, id,
+// and a last-update cutoff when those are set.
+func (s *Store) PurgeFailedNotifications(ctx context.Context, tenantID, id string, before time.Time) (int64, error) {
+	var cutoff *time.Time
+	if !before.IsZero() {
+		cutoff = &before
+	}
+	res, err := s.pool.Exec(ctx, `
+		DELETE FROM approval_notification_outbox
+		WHERE status = 'failed'
+		  AND ($1 = '' OR tenant_id = $1)
+		  AND ($2 = '' OR id = $2)
+		  AND ($3::timestamptz IS NULL OR COALESCE(updated_at, created_at) < $3)`, tenantID, id, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("approvals.PurgeFailedNotifications: %w", err)
+	}
+	return res.RowsAffected(), nil
+}
+
+// ListArchivableNotifications returns up to limit sent or failed rows last
+// updated before cutoff, by tenant and then oldest first, for the retention
+// job to archive and delete. Rows of a request that is still pending are
+// kept, as are rows a pending slack_update or slack_comment still needs to
+// find the message it edits.
+func (s *Store) ListArchivableNotifications(ctx context.Context, cutoff time.Time, limit int) ([]DeadLetter, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+deadLetterColumns+`
+		FROM approval_notification_outbox o
+		WHERE status IN ('sent', 'failed')
+		  AND COALESCE(updated_at, created_at) < $1
+		  AND NOT EXISTS (SELECT 1 FROM approval_requests r
+		                  WHERE r.id = o.approval_request_id AND r.status = 'pending')
+		  AND NOT EXISTS (SELECT 1 FROM approval_notification_outbox c
+		                  WHERE c.parent_id = o.id AND c.status IN ('pending', 'processing'))
+		ORDER BY tenant_id, created_at, id
+		LIMIT $2`, cutoff, limit)
+	if err != nil {
+		return nil, fmt.Errorf("approvals.ListArchivableNotifications: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]DeadLetter, 0)
+	for rows.Next() {
+		n, err := scanDeadLetter(rows)
+		if err != nil {
+			return nil, fmt.Errorf("approvals.ListArchivableNotifications scan: %w", err)
+		}
+		out = append(out, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("approvals.ListArchivableNotifications iteration: %w", err)
+	}
+	return out, nil
+}
+
+// DeleteNotifications deletes the sent or failed rows among ids, once the
+// retention job has archived them. Rows in any other status are left alone.
+func (s *Store) DeleteNotifications(ctx context.Context, ids []string) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	res, err := s.pool.Exec(ctx, `
+		DELETE FROM approval_notification_outbox
+		WHERE id = ANY($1) AND status IN ('sent', 'failed')`, ids)
+	if err != nil {
+		return 0, fmt.Errorf("approvals.DeleteNotifications: %w", err)
+	}
+	return res.RowsAffected(), nil
+}
+
+// OutboxStats counts outbox rows by status and finds the oldest row not yet
+// delivered.
+func (s *Store) OutboxStats(ctx context.Context) (OutboxStats, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT status, COUNT(*),
+		       MIN(CASE WHEN status IN ('pending', 'processing') THEN created_at END)
+		FROM approval_notification_outbox
+		GROUP BY status`)
+	if err != nil {
+		return OutboxStats{}, fmt.Errorf("approvals.OutboxStats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := OutboxStats{Depth: make(map[string]int64)}
+	for rows.Next() {
+		var status string
+		var n int64
+		var oldest *time.Time
+		if err := rows.Scan(&status, &n, &oldest); err != nil {
+			return OutboxStats{}, fmt.Errorf("approvals.OutboxStats scan: %w", err)
+		}
+		stats.Depth[status] = n
+		if oldest != nil && (stats.OldestPending == nil || oldest.Before(*stats.OldestPending)) {
+			stats.OldestPending = oldest
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return OutboxStats{}, fmt.Errorf("approvals.OutboxStats iteration: %w", err)
+	}
+	return stats, nil
+}
+
+func notifyConfigJSON(cfg map[string]string) ([]byte, error) {
+	if cfg == nil {
+		cfg = map[string]string{}
+	}
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("marshal notify config: %w", err)
+	}
+	return b, nil
+}
+
+// encodeActivity returns activity as a JSON column value, or nil (NULL)
+// when there is none.
+func encodeActivity(activity []AgentActivity) (any, error) {
+	if len(activity) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(activity)
+	if err != nil {
+		return nil, fmt.Errorf("marshal recent activity: %w", err)
+	}
+	return b, nil
+}
+
+// encodePlan returns plan as a JSON column value, or nil (NULL) without one.
+func encodePlan(plan *connectors.ExecPlan) (any, error) {
+	if plan == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(plan)
+	if err != nil {
+		return nil, fmt.Errorf("marshal plan: %w", err)
+	}
+	return string(b), nil
+}
+
+// encodeValidHours returns w as a JSON column value, or nil (NULL) without
+// one.
+func encodeValidHours(w *types.TimeWindow) (any, error) {
+	if w == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(w)
+	if err != nil {
+		return nil, fmt.Errorf("marshal valid hours: %w", err)
+	}
+	return string(b), nil
+}
+
 func buildApprovalURL(baseURL, requestID string) string {
 	base := strings.TrimRight(baseURL, "/")
 	if base == "" {
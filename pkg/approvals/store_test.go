@@ -1,7 +1,11 @@
 package approvals
 
 import (
+	"errors"
 	"testing"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
 )
 
 func TestMatchResource(t *testing.T) {
@@ -31,3 +35,44 @@ func TestMatchResource(t *testing.T) {
 		})
 	}
 }
+
+func TestGrantDefaults_SessionScope(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	maxUses, expiry, pattern, session, err := GrantInput{SessionScope: true}.grantDefaults(now, "C123", "sess-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if maxUses != 0 || pattern != "*" || session != "sess-1" || !expiry.Equal(now.Add(time.Hour)) {
+		t.Errorf("session grant = uses %d, pattern %q, session %q, expiry %v", maxUses, pattern, session, expiry)
+	}
+
+	if _, _, _, _, err := (GrantInput{SessionScope: true}).grantDefaults(now, "C123", ""); !errors.Is(err, ErrNoSession) {
+		t.Errorf("no session: err = %v, want ErrNoSession", err)
+	}
+
+	maxUses, _, pattern, session, _ = GrantInput{ExpiresInSec: 60}.grantDefaults(now, "C123", "sess-1")
+	if maxUses != 1 || pattern != "C123" || session != "" {
+		t.Errorf("request grant = uses %d, pattern %q, session %q", maxUses, pattern, session)
+	}
+}
+
+func TestApprovalGrant_UsableAt(t *testing.T) {
+	g := &ApprovalGrant{Scope: ApprovalScope{ValidHours: &types.TimeWindow{
+		Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "09:00", End: "17:00",
+	}}}
+	// 2026-10-16 is a Friday.
+	for at, want := range map[time.Time]bool{
+		time.Date(2026, 10, 16, 16, 59, 0, 0, time.UTC): true,
+		time.Date(2026, 10, 16, 19, 0, 0, 0, time.UTC):  false,
+		time.Date(2026, 10, 18, 3, 0, 0, 0, time.UTC):   false,
+		time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC):   true,
+	} {
+		if got := g.UsableAt(at); got != want {
+			t.Errorf("UsableAt(%s) = %v, want %v", at, got, want)
+		}
+	}
+	if !(&ApprovalGrant{}).UsableAt(time.Date(2026, 10, 18, 3, 0, 0, 0, time.UTC)) {
+		t.Error("a grant without valid hours should be usable at any time")
+	}
+}
@@ -2,8 +2,12 @@
 package approvals
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"time"
 
+	"github.com/bturcanu/OpenClause/pkg/connectors"
 	"github.com/bturcanu/OpenClause/pkg/types"
 )
 
@@ -19,12 +23,49 @@ type ApprovalRequest struct {
 	Tool       string    `json:"tool"`
 	Action     string    `json:"action"`
 	Resource   string    `json:"resource,omitempty"`
+	SessionID  string    `json:"session_id,omitempty"`
 	RiskScore  int       `json:"risk_score"`
 	Reason     string    `json:"reason"`
 	DenyReason string    `json:"deny_reason,omitempty"`
-	Status     string    `json:"status"` // "pending", "approved", "denied", "expired"
+	Status     string    `json:"status"` // "pending", "approved", "denied", "expired", "cancelled"
 	CreatedAt  time.Time `json:"created_at"`
 	ExpiresAt  time.Time `json:"expires_at"`
+	// Plan is the connector's description of the call, when it gave one.
+	Plan *connectors.ExecPlan `json:"plan,omitempty"`
+	// ApproverGroup is the group policy routed the request to.
+	ApproverGroup string `json:"approver_group,omitempty"`
+	// Kind is KindQuarantine for a review of an executed call's withheld
+	// output; empty for an ordinary request to run a call.
+	Kind string `json:"kind,omitempty"`
+	// RecentActivity is the agent's latest calls before this one, newest
+	// first, so approvers see what led up to the request.
+	RecentActivity []AgentActivity `json:"recent_activity,omitempty"`
+	// GrantTTLSec is the lifetime of grants on the request that set none,
+	// as policy chose it; 0 leaves it to the tenant's grant_ttl_sec, then
+	// DefaultGrantTTL.
+	GrantTTLSec int `json:"grant_ttl_sec,omitempty"`
+}
+
+// AgentActivity summarizes one of an agent's recorded tool calls.
+type AgentActivity struct {
+	EventID  string `json:"event_id"`
+	Tool     string `json:"tool"`
+	Action   string `json:"action"`
+	Resource string `json:"resource,omitempty"`
+	Decision string `json:"decision"`
+	// Status is the execution result status, when the call ran.
+	Status string    `json:"status,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// KindQuarantine marks a request to review output the gateway withheld
+// from the agent. Such requests are released or denied, never approved:
+// a grant would authorize future calls, not this call's output.
+const KindQuarantine = "quarantine"
+
+// IsQuarantine reports whether r reviews withheld output.
+func (r *ApprovalRequest) IsQuarantine() bool {
+	return r.Kind == KindQuarantine
 }
 
 // ──────────────────────────────────────────────────────────────────────────────
@@ -37,10 +78,14 @@ type ApprovalGrant struct {
 	TenantID  string        `json:"tenant_id"`
 	Approver  string        `json:"approver"`
 	Scope     ApprovalScope `json:"scope"`
-	MaxUses   int           `json:"max_uses"`
+	MaxUses   int           `json:"max_uses"` // 0: unlimited until expiry (session grants)
 	UsesLeft  int           `json:"uses_left"`
 	ExpiresAt time.Time     `json:"expires_at"`
 	GrantedAt time.Time     `json:"granted_at"`
+	// ApproverIssuer and ApproverSubject identify the approver at their
+	// OIDC provider when they signed in; empty for unverified approvers.
+	ApproverIssuer  string `json:"approver_issuer,omitempty"`
+	ApproverSubject string `json:"approver_subject,omitempty"`
 }
 
 // ──────────────────────────────────────────────────────────────────────────────
@@ -50,9 +95,26 @@ type ApprovalGrant struct {
 type ApprovalScope struct {
 	Tool            string `json:"tool"`             // exact or "*"
 	Action          string `json:"action"`           // exact or "*"
-	ResourcePattern string `json:"resource_pattern"` // glob pattern
+	ResourcePattern string `json:"resource_pattern"` // glob or resource URI pattern
 	TenantID        string `json:"tenant_id"`
 	AgentID         string `json:"agent_id,omitempty"` // optional restriction
+	// SessionID, when set, limits the grant to calls carrying this
+	// session_id. Session grants are also consumed by new tool calls in the
+	// session, which then execute without a fresh approval request.
+	SessionID string `json:"session_id,omitempty"`
+	// ValidHours, when set, limits use of the grant to calls made inside
+	// the window, e.g. weekdays 09:00–17:00 in the tenant's time zone.
+	ValidHours *types.TimeWindow `json:"valid_hours,omitempty"`
+}
+
+// IsSession reports whether the grant is scoped to an agent session.
+func (g *ApprovalGrant) IsSession() bool {
+	return g.Scope.SessionID != ""
+}
+
+// UsableAt reports whether the grant's valid hours, if any, contain t.
+func (g *ApprovalGrant) UsableAt(t time.Time) bool {
+	return g.Scope.ValidHours == nil || g.Scope.ValidHours.Contains(t)
 }
 
 // ──────────────────────────────────────────────────────────────────────────────
@@ -66,6 +128,7 @@ type CreateApprovalInput struct {
 	Tool            string               `json:"tool"`
 	Action          string               `json:"action"`
 	Resource        string               `json:"resource,omitempty"`
+	SessionID       string               `json:"session_id,omitempty"`
 	RiskScore       int                  `json:"risk_score"`
 	RiskFactors     []string             `json:"risk_factors,omitempty"`
 	Reason          string               `json:"reason"`
@@ -73,13 +136,117 @@ type CreateApprovalInput struct {
 	ApproverGroup   string               `json:"approver_group,omitempty"`
 	Notify          []types.PolicyNotify `json:"notify,omitempty"`
 	ApprovalBaseURL string               `json:"approval_base_url,omitempty"`
+	// ExpiresInSec overrides DefaultRequestTTL when positive.
+	ExpiresInSec int `json:"expires_in_sec,omitempty"`
+	// GrantTTLSec, when positive, is the lifetime of grants on the request
+	// whose approver sets none.
+	GrantTTLSec int `json:"grant_ttl_sec,omitempty"`
+	// Plan is the connector's dry run of the call, shown to approvers.
+	Plan *connectors.ExecPlan `json:"plan,omitempty"`
+	// Kind is KindQuarantine for a review of withheld output.
+	Kind string `json:"kind,omitempty"`
+	// RecentActivity is the agent's latest calls, newest first.
+	RecentActivity []AgentActivity `json:"recent_activity,omitempty"`
+}
+
+// DefaultRequestTTL is how long an approval request stays pending unless
+// the tenant configures otherwise.
+const DefaultRequestTTL = 24 * time.Hour
+
+// DefaultGrantTTL is how long a grant lasts unless the approver, the policy
+// decision or the tenant configures otherwise.
+const DefaultGrantTTL = time.Hour
+
+// RequestTTL returns the pending lifetime for in.
+func (in CreateApprovalInput) RequestTTL() time.Duration {
+	if in.ExpiresInSec > 0 {
+		return time.Duration(in.ExpiresInSec) * time.Second
+	}
+	return DefaultRequestTTL
+}
+
+// Resolution describes an approval outcome for export: a human approve,
+// release or deny, the requesting agent cancelling, or the request expiring
+// undecided.
+type Resolution struct {
+	Request  ApprovalRequest
+	Status   string // "approved", "released", "denied", "cancelled", or "expired"
+	Approver string
+	Reason   string
+	At       time.Time
+}
+
+// ResolutionSink receives approval outcomes after they are persisted.
+// Implementations must not block.
+type ResolutionSink interface {
+	PublishResolution(context.Context, Resolution)
+}
+
+// RequestSink receives approval requests after they are created.
+// Implementations must not block.
+type RequestSink interface {
+	PublishRequest(context.Context, ApprovalRequest)
 }
 
 type GrantInput struct {
-	Approver        string `json:"approver"`
-	MaxUses         int    `json:"max_uses"`
-	ExpiresInSec    int    `json:"expires_in_sec"` // seconds from now
+	Approver string `json:"approver"`
+	// ApproverIssuer and ApproverSubject are the verified OIDC identity of
+	// the approver, set by the handlers from the authenticated session or
+	// token; they are never read from the request body.
+	ApproverIssuer  string `json:"-"`
+	ApproverSubject string `json:"-"`
+
+	MaxUses int `json:"max_uses"`
+	// ExpiresInSec is the grant's lifetime in seconds from now. When 0,
+	// the request's grant_ttl_sec, then the tenant's, then DefaultGrantTTL
+	// applies.
+	ExpiresInSec    int    `json:"expires_in_sec"`
 	ResourcePattern string `json:"resource_pattern,omitempty"`
+	// SessionScope grants the request's tool and action to the rest of its
+	// agent session until expiry. The resource pattern defaults to "*" and
+	// max_uses to 0 (unlimited). The request must carry a session_id.
+	SessionScope bool `json:"session_scope,omitempty"`
+	// ValidHours limits use of the grant to calls made inside the window.
+	// When unset, the tenant's grant_hours setting applies.
+	ValidHours *types.TimeWindow `json:"valid_hours,omitempty"`
+}
+
+// ErrNoSession is returned when a session-scoped grant is requested for an
+// approval request that has no session_id.
+var ErrNoSession = errors.New("approval request has no session_id; session_scope is not available")
+
+// ErrNotPending is returned by CancelRequest when the request is no longer
+// pending, such as when an approver resolved it after the caller read it.
+var ErrNotPending = errors.New("approval request is not pending")
+
+// grantDefaults resolves max uses, expiry, resource pattern, and session
+// scope for a grant on a request with the given resource and session.
+func (in GrantInput) grantDefaults(now time.Time, resource, sessionID string) (maxUses int, expiry time.Time, pattern, scopeSession string, err error) {
+	maxUses = in.MaxUses
+	expiry = now.Add(DefaultGrantTTL)
+	if in.ExpiresInSec > 0 {
+		expiry = now.Add(time.Duration(in.ExpiresInSec) * time.Second)
+	}
+	pattern = in.ResourcePattern
+	if in.SessionScope {
+		if sessionID == "" {
+			return 0, time.Time{}, "", "", ErrNoSession
+		}
+		if maxUses < 0 {
+			maxUses = 0
+		}
+		if pattern == "" {
+			pattern = "*"
+		}
+		return maxUses, expiry, pattern, sessionID, nil
+	}
+	if maxUses <= 0 {
+		maxUses = 1
+	}
+	if pattern == "" {
+		pattern = resource
+	}
+	return maxUses, expiry, pattern, "", nil
 }
 
 type DenyInput struct {
@@ -87,6 +254,47 @@ type DenyInput struct {
 	Reason   string `json:"reason"`
 }
 
+// ReleaseInput releases a quarantined call's output to the agent.
+type ReleaseInput struct {
+	Approver string `json:"approver"`
+}
+
+// CancelInput withdraws a pending request on behalf of the agent that made
+// it. TenantID and AgentID must match the request's.
+type CancelInput struct {
+	TenantID string `json:"tenant_id"`
+	AgentID  string `json:"agent_id"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// maxCancelReasonLen caps the reason an agent gives for a cancellation.
+const maxCancelReasonLen = 1000
+
+// CheckCancel reports why in may not cancel req: a request of another
+// tenant is not found, one of another agent is forbidden, and quarantine
+// reviews, which gate output already produced, cannot be withdrawn.
+func CheckCancel(req *ApprovalRequest, in CancelInput) *types.APIError {
+	if req == nil || req.TenantID != in.TenantID {
+		return types.ErrNotFound("approval request not found")
+	}
+	if in.AgentID == "" {
+		return types.ErrValidation(&types.ValidationError{Field: "agent_id", Reason: "required"})
+	}
+	if len(in.Reason) > maxCancelReasonLen {
+		return types.ErrValidation(&types.ValidationError{Field: "reason", Reason: fmt.Sprintf("must be at most %d bytes", maxCancelReasonLen)})
+	}
+	if req.AgentID != in.AgentID {
+		return types.ErrForbidden("only the requesting agent can cancel an approval request")
+	}
+	if req.IsQuarantine() {
+		return types.ErrConflict("quarantine requests cannot be cancelled")
+	}
+	if req.Status != "pending" {
+		return types.ErrConflict("approval request is " + req.Status)
+	}
+	return nil
+}
+
 type NotificationOutbox struct {
 	ID                string
 	ApprovalRequestID string
@@ -105,8 +313,27 @@ type NotificationOutbox struct {
 	NotifyURL         string
 	SecretRef         string
 	SlackChannel      string
+	NotifyConfig      map[string]string
+	Plan              *connectors.ExecPlan
+	RecentActivity    []AgentActivity
 	Attempts          int
 	Status            string
 	NextAttemptAt     time.Time
 	CreatedAt         time.Time
+
+	// "slack_update" rows rewrite the message posted by the "slack" row
+	// ParentID once the request is approved, denied, cancelled or expires;
+	// "slack_comment" rows reply to it in its thread with Comment.
+	ParentID         string
+	Resolution       string // approved | released | denied | cancelled | expired
+	ResolvedBy       string
+	ResolutionReason string
+	Comment          *Comment
+}
+
+// SlackMessage locates a posted Slack approval message for chat.update.
+type SlackMessage struct {
+	Channel string
+	TS      string
+	Status  string // outbox status of the posting row
 }
@@ -0,0 +1,52 @@
+package approvals
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"github.com/go-chi/chi/v5"
+)
+
+// GrantUsage is one consumption of a grant: the call it let through.
+type GrantUsage struct {
+	ID       string    `json:"id"`
+	GrantID  string    `json:"grant_id"`
+	TenantID string    `json:"tenant_id"`
+	EventID  string    `json:"event_id"`
+	AgentID  string    `json:"agent_id"`
+	Resource string    `json:"resource,omitempty"`
+	UsedAt   time.Time `json:"used_at"`
+}
+
+// ListGrantUsages handles GET /v1/approvals/grants/{id}/usages. Usages are
+// listed newest first with the grant they consumed.
+func (h *Handlers) ListGrantUsages(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	limit, after, ok := parsePage(w, r)
+	if !ok {
+		return
+	}
+	grant, err := h.store.GetGrant(r.Context(), id)
+	if err != nil {
+		slog.Error("get grant failed", "error", err, "id", id)
+		types.ErrInternal("failed to retrieve grant").WriteJSON(w)
+		return
+	}
+	if grant == nil {
+		types.ErrNotFound("grant not found").WriteJSON(w)
+		return
+	}
+	usages, err := h.store.ListGrantUsages(r.Context(), id, limit, after)
+	if err != nil {
+		slog.Error("list grant usages failed", "error", err, "id", id)
+		types.ErrInternal("failed to list grant usages").WriteJSON(w)
+		return
+	}
+	page := GrantUsagePage{Grant: *grant, Usages: usages}
+	if n := len(usages); n > 0 {
+		page.NextCursor = nextCursor(n, limit, types.Cursor{Time: usages[n-1].UsedAt, ID: usages[n-1].ID})
+	}
+	writeJSON(w, http.StatusOK, page)
+}
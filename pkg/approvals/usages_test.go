@@ -0,0 +1,44 @@
+package approvals
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestListGrantUsages(t *testing.T) {
+	usedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	store := &fakeHandlersStore{usages: []GrantUsage{
+		{ID: "u2", GrantID: "grant-1", TenantID: "tenant1", EventID: "evt-2", AgentID: "agent-1", Resource: "OPS-2", UsedAt: usedAt.Add(time.Minute)},
+		{ID: "u1", GrantID: "grant-1", TenantID: "tenant1", EventID: "evt-1", AgentID: "agent-1", Resource: "OPS-1", UsedAt: usedAt},
+	}}
+	r := chi.NewRouter()
+	NewHandlers(store, nil).RegisterRoutes(r)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+	rec := get("/v1/approvals/grants/grant-1/usages")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("usages = %d %s", rec.Code, rec.Body)
+	}
+	var page GrantUsagePage
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+	if page.Grant.ID != "grant-1" || page.Grant.UsesLeft != 1 || len(page.Usages) != 2 || page.Usages[1].EventID != "evt-1" || page.NextCursor != "" {
+		t.Fatalf("page = %+v", page)
+	}
+	if rec := get("/v1/approvals/grants/nope/usages"); rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown grant = %d, want 404", rec.Code)
+	}
+	if rec := get("/v1/approvals/grants/grant-1/usages?cursor=not-a-cursor"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("bad cursor = %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,97 @@
+package approvals
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Outbound webhook headers. HeaderDeliveryID is stable across retries of one
+// delivery, so receivers can use it as an idempotency key; HeaderAttempt
+// counts up from 1 on every retry.
+const (
+	HeaderDeliveryID  = "X-OC-Delivery-Id"
+	HeaderAttempt     = "X-OC-Delivery-Attempt"
+	HeaderSignature   = "X-OC-Signature-256"
+	HeaderSignatureV2 = "X-OC-Signature-V2"
+
+	// DefaultWebhookTolerance is how far a v2 signature's timestamp may be
+	// from the receiver's clock.
+	DefaultWebhookTolerance = 5 * time.Minute
+)
+
+// webhookSignedHeaders are covered by the v2 signature, in this order.
+var webhookSignedHeaders = []string{"Content-Type", HeaderDeliveryID, HeaderAttempt}
+
+// SignWebhookRequest sets the delivery headers on req and, when secret is
+// set, signs it. Both the v1 body signature and the timestamped v2
+// signature over the delivery headers and body are sent, so existing
+// receivers keep working while they move to v2.
+func SignWebhookRequest(req *http.Request, body []byte, secret, deliveryID string, attempt int, now time.Time) {
+	req.Header.Set(HeaderDeliveryID, deliveryID)
+	req.Header.Set(HeaderAttempt, strconv.Itoa(attempt))
+	if secret == "" {
+		return
+	}
+	req.Header.Set(HeaderSignature, SignBodyHMACSHA256(body, secret))
+	req.Header.Set(HeaderSignatureV2, SignWebhookV2(secret, now, req.Header, body))
+}
+
+// SignWebhookV2 returns the X-OC-Signature-V2 value "t=<unix>,v2=<hex>" for
+// a request with header and body sent at ts.
+func SignWebhookV2(secret string, ts time.Time, header http.Header, body []byte) string {
+	t := strconv.FormatInt(ts.Unix(), 10)
+	return "t=" + t + ",v2=" + webhookV2MAC(secret, t, header, body)
+}
+
+// VerifyWebhookV2 checks a received webhook's X-OC-Signature-V2 against any
+// of secrets, rejecting signatures older or newer than tolerance. Receivers
+// should also drop repeated X-OC-Delivery-Id values within the tolerance.
+func VerifyWebhookV2(header http.Header, body []byte, secrets []string, tolerance time.Duration, now time.Time) bool {
+	var t, sig string
+	for _, part := range strings.Split(header.Get(HeaderSignatureV2), ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "t":
+			t = v
+		case "v2":
+			sig = v
+		}
+	}
+	if t == "" || sig == "" {
+		return false
+	}
+	ts, err := strconv.ParseInt(t, 10, 64)
+	if err != nil {
+		return false
+	}
+	sent := time.Unix(ts, 0)
+	if sent.Before(now.Add(-tolerance)) || sent.After(now.Add(tolerance)) {
+		return false
+	}
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+		if hmac.Equal([]byte(webhookV2MAC(secret, t, header, body)), []byte(sig)) {
+			return true
+		}
+	}
+	return false
+}
+
+// webhookV2MAC signs "v2:<t>\n", then "<lowercased name>:<value>\n" for each
+// signed header, then the raw body.
+func webhookV2MAC(secret, t string, header http.Header, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write([]byte("v2:" + t + "\n"))
+	for _, name := range webhookSignedHeaders {
+		_, _ = mac.Write([]byte(strings.ToLower(name) + ":" + header.Get(name) + "\n"))
+	}
+	_, _ = mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
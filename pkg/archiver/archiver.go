@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/bturcanu/OpenClause/pkg/evidence"
+	"github.com/bturcanu/OpenClause/pkg/metering"
 )
 
 type EvidenceStore interface {
@@ -20,15 +21,28 @@ type Uploader interface {
 	Upload(ctx context.Context, key string, body []byte) error
 }
 
+// Pruner is implemented by uploaders that can delete archived bundles, which
+// lets the service enforce per-tenant retention.
+type Pruner interface {
+	DeleteBefore(ctx context.Context, prefix string, before time.Time) (int, error)
+}
+
 type Service struct {
 	store    EvidenceStore
 	uploader Uploader
+	meter    *metering.Recorder
+	outbox   OutboxStore
 }
 
 func New(store EvidenceStore, uploader Uploader) *Service {
 	return &Service{store: store, uploader: uploader}
 }
 
+// SetMeter records the size of each uploaded bundle as archived_bytes usage.
+func (s *Service) SetMeter(m *metering.Recorder) {
+	s.meter = m
+}
+
 type Bundle struct {
 	TenantID     string                `json:"tenant_id"`
 	CreatedAt    time.Time             `json:"created_at"`
@@ -83,5 +97,19 @@ func (s *Service) ArchiveTenant(ctx context.Context, tenantID string) (string, e
 	if err := s.store.UpsertArchiveCheckpoint(ctx, tenantID, checkpointAt, last.Hash, last.EventSeq); err != nil {
 		return "", err
 	}
+	s.meter.Add(tenantID, metering.ArchivedBytes, int64(len(body)))
 	return key, nil
 }
+
+// PruneTenant deletes the tenant's archived bundles older than retention and
+// returns how many were removed. retention <= 0 keeps everything.
+func (s *Service) PruneTenant(ctx context.Context, tenantID string, retention time.Duration) (int, error) {
+	if retention <= 0 {
+		return 0, nil
+	}
+	p, ok := s.uploader.(Pruner)
+	if !ok {
+		return 0, fmt.Errorf("prune: uploader does not support deletion")
+	}
+	return p.DeleteBefore(ctx, fmt.Sprintf("evidence/%s/", tenantID), time.Now().UTC().Add(-retention))
+}
@@ -2,10 +2,16 @@ package archiver
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/bturcanu/OpenClause/pkg/approvals"
 	"github.com/bturcanu/OpenClause/pkg/evidence"
+	"github.com/bturcanu/OpenClause/pkg/metering"
 )
 
 type fakeStore struct {
@@ -64,6 +70,8 @@ func TestArchiveTenantBuildsBundleAndAdvancesCheckpoint(t *testing.T) {
 	store := &fakeStore{events: []evidence.ChainEvent{ev1, ev2}}
 	up := &fakeUploader{}
 	s := New(store, up)
+	usage := &fakeUsage{}
+	s.SetMeter(metering.NewRecorder(usage, nil))
 
 	key, err := s.ArchiveTenant(context.Background(), "tenant1")
 	if err != nil {
@@ -75,4 +83,142 @@ func TestArchiveTenantBuildsBundleAndAdvancesCheckpoint(t *testing.T) {
 	if store.hash != ev2.Hash {
 		t.Fatalf("expected checkpoint hash %s got %s", ev2.Hash, store.hash)
 	}
+	if err := s.meter.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(usage.got) != 1 || usage.got[0].Metric != metering.ArchivedBytes || usage.got[0].Value != int64(len(up.body)) {
+		t.Fatalf("metered %+v, want %d archived bytes", usage.got, len(up.body))
+	}
+}
+
+type fakeUsage struct {
+	got []metering.Counter
+}
+
+func (f *fakeUsage) Increment(_ context.Context, counters []metering.Counter) error {
+	f.got = append(f.got, counters...)
+	return nil
+}
+
+type fakePruner struct {
+	fakeUploader
+	prefix string
+	before time.Time
+}
+
+func (f *fakePruner) DeleteBefore(_ context.Context, prefix string, before time.Time) (int, error) {
+	f.prefix, f.before = prefix, before
+	return 3, nil
+}
+
+func TestPruneTenant(t *testing.T) {
+	if _, err := New(&fakeStore{}, &fakeUploader{}).PruneTenant(context.Background(), "tenant1", time.Hour); err == nil {
+		t.Fatal("expected error for an uploader without deletion support")
+	}
+
+	p := &fakePruner{}
+	svc := New(&fakeStore{}, p)
+	if n, err := svc.PruneTenant(context.Background(), "tenant1", 0); err != nil || n != 0 || p.prefix != "" {
+		t.Fatalf("zero retention must not prune: n=%d err=%v", n, err)
+	}
+	n, err := svc.PruneTenant(context.Background(), "tenant1", 48*time.Hour)
+	if err != nil || n != 3 {
+		t.Fatalf("PruneTenant = %d, %v", n, err)
+	}
+	if p.prefix != "evidence/tenant1/" || time.Since(p.before) < 47*time.Hour {
+		t.Fatalf("DeleteBefore(%q, %v)", p.prefix, p.before)
+	}
+}
+
+func TestFSUploaderUploadAndPrune(t *testing.T) {
+	dir := t.TempDir()
+	u := NewFSUploader(dir)
+	ctx := context.Background()
+	if err := u.Upload(ctx, "evidence/tenant1/genesis_to_h1.json", []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.Upload(ctx, "evidence/tenant2/genesis_to_h2.json", []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	body, err := os.ReadFile(filepath.Join(dir, "evidence", "tenant1", "genesis_to_h1.json"))
+	if err != nil || string(body) != `{}` {
+		t.Fatalf("bundle = %q, %v", body, err)
+	}
+
+	if n, err := u.DeleteBefore(ctx, "evidence/tenant1/", time.Now().Add(-time.Hour)); err != nil || n != 0 {
+		t.Fatalf("pruned %d, %v; want nothing newer than the cutoff removed", n, err)
+	}
+	n, err := u.DeleteBefore(ctx, "evidence/tenant1/", time.Now().Add(time.Hour))
+	if err != nil || n != 1 {
+		t.Fatalf("pruned %d, %v; want 1", n, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "evidence", "tenant2", "genesis_to_h2.json")); err != nil {
+		t.Fatalf("other tenant's bundle removed: %v", err)
+	}
+	if n, err := u.DeleteBefore(ctx, "evidence/missing/", time.Now()); err != nil || n != 0 {
+		t.Fatalf("missing prefix: %d, %v", n, err)
+	}
+}
+
+type fakeOutbox struct {
+	rows    []approvals.DeadLetter
+	deleted []string
+}
+
+func (f *fakeOutbox) ListArchivableNotifications(_ context.Context, _ time.Time, limit int) ([]approvals.DeadLetter, error) {
+	return f.rows[:min(limit, len(f.rows))], nil
+}
+
+func (f *fakeOutbox) DeleteNotifications(_ context.Context, ids []string) (int64, error) {
+	f.deleted = append(f.deleted, ids...)
+	f.rows = f.rows[len(ids):]
+	return int64(len(ids)), nil
+}
+
+type recordingUploader struct {
+	bodies map[string][]byte
+	err    error
+}
+
+func (f *recordingUploader) Upload(_ context.Context, key string, body []byte) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.bodies[key] = body
+	return nil
+}
+
+func TestArchiveOutbox(t *testing.T) {
+	outbox := &fakeOutbox{rows: []approvals.DeadLetter{
+		{ID: "n1", TenantID: "tenant1", Status: "sent"},
+		{ID: "n2", TenantID: "tenant1", Status: "failed"},
+		{ID: "n3", TenantID: "tenant2", Status: "sent"},
+	}}
+
+	failing := New(&fakeStore{}, &recordingUploader{err: errors.New("bucket unavailable")})
+	failing.SetOutbox(outbox)
+	if _, _, err := failing.ArchiveOutbox(context.Background(), time.Hour); err == nil || len(outbox.deleted) != 0 {
+		t.Fatalf("failed upload: err = %v, deleted = %v", err, outbox.deleted)
+	}
+
+	up := &recordingUploader{bodies: map[string][]byte{}}
+	svc := New(&fakeStore{}, up)
+	if keys, n, err := svc.ArchiveOutbox(context.Background(), time.Hour); err != nil || keys != nil || n != 0 {
+		t.Fatalf("without an outbox = %v, %d, %v", keys, n, err)
+	}
+	svc.SetOutbox(outbox)
+	if keys, _, _ := svc.ArchiveOutbox(context.Background(), 0); keys != nil {
+		t.Fatalf("zero retention archived %v", keys)
+	}
+	keys, n, err := svc.ArchiveOutbox(context.Background(), 24*time.Hour)
+	if err != nil || n != 3 || len(keys) != 2 || keys[0] != "outbox/tenant1/n1_to_n2.json" || keys[1] != "outbox/tenant2/n3_to_n3.json" {
+		t.Fatalf("ArchiveOutbox = %v, %d, %v", keys, n, err)
+	}
+	var bundle OutboxBundle
+	if err := json.Unmarshal(up.bodies[keys[0]], &bundle); err != nil || bundle.TenantID != "tenant1" || bundle.RowCount != 2 || bundle.Notifications[1].Status != "failed" {
+		t.Fatalf("bundle = %s, %v", up.bodies[keys[0]], err)
+	}
+	if time.Since(bundle.Before) < 23*time.Hour || len(outbox.rows) != 0 {
+		t.Fatalf("before = %v, rows left = %v", bundle.Before, outbox.rows)
+	}
 }
@@ -0,0 +1,74 @@
+package archiver
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FSUploader writes bundles under a local directory instead of an object
+// store, for lite mode and air-gapped installs. Keys become relative paths.
+type FSUploader struct {
+	dir string
+}
+
+// NewFSUploader stores bundles under dir, creating it on first upload.
+func NewFSUploader(dir string) *FSUploader {
+	return &FSUploader{dir: dir}
+}
+
+// Upload writes body to dir/key. The file is written under a temporary name
+// and renamed, so a crash never leaves a truncated bundle behind.
+func (u *FSUploader) Upload(_ context.Context, key string, body []byte) error {
+	path := filepath.Join(u.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("upload %s: %w", key, err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, body, 0o640); err != nil {
+		return fmt.Errorf("upload %s: %w", key, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("upload %s: %w", key, err)
+	}
+	return nil
+}
+
+// DeleteBefore removes files under prefix last modified before the cutoff.
+func (u *FSUploader) DeleteBefore(ctx context.Context, prefix string, before time.Time) (int, error) {
+	root := filepath.Join(u.dir, filepath.FromSlash(prefix))
+	n := 0
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if !info.ModTime().Before(before) {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		n++
+		return nil
+	})
+	if err != nil {
+		return n, fmt.Errorf("prune %s: %w", prefix, err)
+	}
+	return n, nil
+}
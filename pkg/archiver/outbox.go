@@ -0,0 +1,102 @@
+package archiver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/approvals"
+)
+
+// OutboxStore is the approvals notification outbox, as seen by the
+// retention job.
+type OutboxStore interface {
+	ListArchivableNotifications(ctx context.Context, cutoff time.Time, limit int) ([]approvals.DeadLetter, error)
+	DeleteNotifications(ctx context.Context, ids []string) (int64, error)
+}
+
+// outboxBatchSize is how many outbox rows are archived and deleted at once.
+const outboxBatchSize = 500
+
+// OutboxBundle is one tenant's batch of archived outbox rows.
+type OutboxBundle struct {
+	TenantID      string                 `json:"tenant_id"`
+	CreatedAt     time.Time              `json:"created_at"`
+	Before        time.Time              `json:"before"`
+	RowCount      int                    `json:"row_count"`
+	Notifications []approvals.DeadLetter `json:"notifications"`
+}
+
+// SetOutbox enables ArchiveOutbox against store.
+func (s *Service) SetOutbox(store OutboxStore) {
+	s.outbox = store
+}
+
+// ArchiveOutbox uploads sent and failed notification outbox rows last
+// updated more than retention ago, one bundle per tenant and batch, and
+// deletes each batch once its bundle is stored. It returns the uploaded
+// keys and the number of rows deleted. A failed upload stops the run with
+// the rows still in place; keys are derived from the rows, so the next run
+// rewrites the same bundle.
+func (s *Service) ArchiveOutbox(ctx context.Context, retention time.Duration) ([]string, int64, error) {
+	if s.outbox == nil || retention <= 0 {
+		return nil, 0, nil
+	}
+	before := time.Now().UTC().Add(-retention)
+	var keys []string
+	var deleted int64
+	for {
+		rows, err := s.outbox.ListArchivableNotifications(ctx, before, outboxBatchSize)
+		if err != nil {
+			return keys, deleted, err
+		}
+		var batchDeleted int64
+		// Rows come ordered by tenant, so each tenant's are contiguous.
+		for start := 0; start < len(rows); {
+			end := start + 1
+			for end < len(rows) && rows[end].TenantID == rows[start].TenantID {
+				end++
+			}
+			key, n, err := s.archiveOutboxRows(ctx, before, rows[start:end])
+			if err != nil {
+				return keys, deleted, err
+			}
+			keys = append(keys, key)
+			deleted += n
+			batchDeleted += n
+			start = end
+		}
+		// A batch that deleted nothing would be listed again forever.
+		if len(rows) < outboxBatchSize || batchDeleted == 0 {
+			return keys, deleted, nil
+		}
+	}
+}
+
+func (s *Service) archiveOutboxRows(ctx context.Context, before time.Time, rows []approvals.DeadLetter) (string, int64, error) {
+	tenantID := rows[0].TenantID
+	body, err := json.Marshal(OutboxBundle{
+		TenantID:      tenantID,
+		CreatedAt:     time.Now().UTC(),
+		Before:        before,
+		RowCount:      len(rows),
+		Notifications: rows,
+	})
+	if err != nil {
+		return "", 0, fmt.Errorf("marshal outbox bundle: %w", err)
+	}
+	key := fmt.Sprintf("outbox/%s/%s_to_%s.json", tenantID, rows[0].ID, rows[len(rows)-1].ID)
+	if err := s.uploader.Upload(ctx, key, body); err != nil {
+		return "", 0, err
+	}
+	ids := make([]string, len(rows))
+	for i, n := range rows {
+		ids[i] = n.ID
+	}
+	n, err := s.outbox.DeleteNotifications(ctx, ids)
+	if err != nil {
+		return "", 0, err
+	}
+	return key, n, nil
+}
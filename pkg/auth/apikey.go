@@ -10,37 +10,84 @@ import (
 // KeyStore maps hashed API keys to tenant IDs. Thread-safe.
 // Keys are stored as SHA-256 hashes to protect against memory dumps.
 type KeyStore struct {
-	mu   sync.RWMutex
-	keys map[string]string // SHA-256(apiKey) → tenantID
+	mu          sync.RWMutex
+	keys        map[string]string // SHA-256(apiKey) → tenantID, from API_KEYS
+	provisioned map[string]string // SHA-256(apiKey) → tenantID, from tenant_api_keys
+	disabled    map[string]bool   // suspended or deleted tenants
+	stateLost   bool              // tenant status unknown; reject every key
 }
 
 // NewKeyStore creates a KeyStore from a comma-separated "tenant:key" string.
 // Example: "tenant1:sk-abc,tenant2:sk-def"
 func NewKeyStore(raw string) *KeyStore {
-	ks := &KeyStore{keys: make(map[string]string)}
+	return &KeyStore{keys: parseKeys(raw)}
+}
+
+// Replace swaps the full key set, e.g. after API_KEYS is rotated in a
+// secrets manager. Keys absent from raw stop authenticating immediately.
+func (ks *KeyStore) Replace(raw string) {
+	keys := parseKeys(raw)
+	ks.mu.Lock()
+	ks.keys = keys
+	ks.mu.Unlock()
+}
+
+func parseKeys(raw string) map[string]string {
+	keys := make(map[string]string)
 	if raw == "" {
-		return ks
+		return keys
 	}
 	for _, pair := range strings.Split(raw, ",") {
 		parts := strings.SplitN(strings.TrimSpace(pair), ":", 2)
 		if len(parts) == 2 {
 			tenant := strings.TrimSpace(parts[0])
 			key := strings.TrimSpace(parts[1])
-			ks.keys[hashKey(key)] = tenant
+			keys[HashKey(key)] = tenant
 		}
 	}
-	return ks
+	return keys
+}
+
+// SetProvisioned replaces the keys issued through the tenant admin API and
+// the set of tenants whose keys, including static API_KEYS entries, must be
+// rejected.
+func (ks *KeyStore) SetProvisioned(hashes map[string]string, disabled map[string]bool) {
+	ks.mu.Lock()
+	ks.provisioned = hashes
+	ks.disabled = disabled
+	ks.stateLost = false
+	ks.mu.Unlock()
+}
+
+// SetStateUnknown records that tenant status could not be loaded. Until the
+// next SetProvisioned every key is rejected, so a suspended tenant cannot
+// authenticate while its status is unknown.
+func (ks *KeyStore) SetStateUnknown() {
+	ks.mu.Lock()
+	ks.stateLost = true
+	ks.mu.Unlock()
 }
 
 // Lookup returns the tenant ID for a given API key.
 func (ks *KeyStore) Lookup(apiKey string) (tenantID string, ok bool) {
+	h := HashKey(apiKey)
 	ks.mu.RLock()
 	defer ks.mu.RUnlock()
-	tenantID, ok = ks.keys[hashKey(apiKey)]
+	if ks.stateLost {
+		return "", false
+	}
+	tenantID, ok = ks.keys[h]
+	if !ok {
+		tenantID, ok = ks.provisioned[h]
+	}
+	if ok && ks.disabled[tenantID] {
+		return "", false
+	}
 	return
 }
 
-func hashKey(key string) string {
+// HashKey returns the hex SHA-256 under which an API key is stored.
+func HashKey(key string) string {
 	h := sha256.Sum256([]byte(key))
 	return hex.EncodeToString(h[:])
 }
@@ -40,3 +40,45 @@ func TestNewKeyStore_Whitespace(t *testing.T) {
 		t.Error("should handle whitespace in key pairs")
 	}
 }
+
+func TestKeyStore_Replace(t *testing.T) {
+	ks := NewKeyStore("tenant1:sk-old")
+	ks.Replace("tenant1:sk-new")
+	if _, ok := ks.Lookup("sk-old"); ok {
+		t.Error("rotated-out key should no longer match")
+	}
+	if tenant, ok := ks.Lookup("sk-new"); !ok || tenant != "tenant1" {
+		t.Errorf("Lookup(sk-new) = %q, %v", tenant, ok)
+	}
+}
+
+func TestKeyStore_ProvisionedAndDisabled(t *testing.T) {
+	ks := NewKeyStore("tenant1:sk-env")
+	ks.SetProvisioned(map[string]string{HashKey("sk-db"): "tenant2"}, nil)
+	if tenant, ok := ks.Lookup("sk-db"); !ok || tenant != "tenant2" {
+		t.Errorf("Lookup(sk-db) = %q, %v", tenant, ok)
+	}
+
+	ks.SetProvisioned(map[string]string{HashKey("sk-db"): "tenant2"}, map[string]bool{"tenant1": true, "tenant2": true})
+	for _, key := range []string{"sk-env", "sk-db"} {
+		if _, ok := ks.Lookup(key); ok {
+			t.Errorf("Lookup(%s) should fail for a suspended tenant", key)
+		}
+	}
+}
+
+func TestKeyStore_StateUnknownRejectsAll(t *testing.T) {
+	ks := NewKeyStore("tenant1:sk-env")
+	ks.SetProvisioned(map[string]string{HashKey("sk-db"): "tenant2"}, nil)
+	ks.SetStateUnknown()
+	for _, key := range []string{"sk-env", "sk-db"} {
+		if _, ok := ks.Lookup(key); ok {
+			t.Errorf("Lookup(%s) should fail while tenant status is unknown", key)
+		}
+	}
+
+	ks.SetProvisioned(map[string]string{HashKey("sk-db"): "tenant2"}, nil)
+	if tenant, ok := ks.Lookup("sk-env"); !ok || tenant != "tenant1" {
+		t.Errorf("Lookup(sk-env) after reload = %q, %v", tenant, ok)
+	}
+}
@@ -3,6 +3,7 @@ package auth
 
 import (
 	"context"
+	"crypto/subtle"
 	"net/http"
 	"strings"
 
@@ -57,3 +58,19 @@ func APIKeyAuth(keys *KeyStore) func(http.Handler) http.Handler {
 		})
 	}
 }
+
+// AdminAuth returns middleware that requires the operator token in the
+// X-Admin-Token header. Admin routes are not tenant-scoped, so they never
+// accept tenant API keys.
+func AdminAuth(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			provided := r.Header.Get("X-Admin-Token")
+			if token == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+				types.ErrUnauthorized("invalid admin token").WriteJSON(w)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -93,3 +93,19 @@ func TestAPIKeyAuth_BearerToken(t *testing.T) {
 		t.Errorf("expected 200, got %d", rr.Code)
 	}
 }
+
+func TestAdminAuth(t *testing.T) {
+	handler := AdminAuth("admin-secret")(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for token, want := range map[string]int{"admin-secret": http.StatusOK, "wrong": http.StatusUnauthorized, "": http.StatusUnauthorized} {
+		req := httptest.NewRequest("GET", "/v1/admin/tenants", nil)
+		req.Header.Set("X-Admin-Token", token)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != want {
+			t.Errorf("token %q: expected %d, got %d", token, want, rr.Code)
+		}
+	}
+}
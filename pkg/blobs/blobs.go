@@ -0,0 +1,113 @@
+// Package blobs keeps tool call params that are too large to send inline.
+// Clients upload them to object storage through a presigned URL and send a
+// params_ref instead; the gateway reads the blob back and checks its digest
+// before policy, and again before the connector runs. The gateway also
+// spills connector output it truncates to the same bucket.
+package blobs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+var (
+	// ErrNotFound is returned when no blob was uploaded under a ref.
+	ErrNotFound = errors.New("blob not found")
+	// ErrMismatch is returned when a blob's size or digest differs from its ref.
+	ErrMismatch = errors.New("blob does not match its digest")
+	// ErrInvalidJSON is returned when a blob is not JSON.
+	ErrInvalidJSON = errors.New("blob is not valid JSON")
+)
+
+// Key is the object key of a tenant's blob: tenants cannot reference each
+// other's uploads.
+func Key(tenantID string, ref types.BlobRef) string {
+	return "params/" + tenantID + "/" + strings.TrimPrefix(ref.Digest, "sha256:")
+}
+
+// OutputKey is the object key full connector output is spilled to when the
+// gateway truncates it, by the hex SHA-256 digest of the output.
+func OutputKey(tenantID, sha256Hex string) string {
+	return "outputs/" + tenantID + "/" + sha256Hex
+}
+
+// Verify checks data against ref and that it is JSON, as params must be.
+func Verify(data []byte, ref types.BlobRef) error {
+	if int64(len(data)) != ref.Size {
+		return fmt.Errorf("%w: size is %d, ref says %d", ErrMismatch, len(data), ref.Size)
+	}
+	if types.NewBlobRef(data).Digest != ref.Digest {
+		return ErrMismatch
+	}
+	if !json.Valid(data) {
+		return ErrInvalidJSON
+	}
+	return nil
+}
+
+// S3 stores blobs in an S3-compatible bucket.
+type S3 struct {
+	client *minio.Client
+	bucket string
+}
+
+// NewS3 connects to the bucket at endpoint (host:port) with static
+// credentials.
+func NewS3(endpoint, accessKey, secretKey string, secure bool, bucket string) (*S3, error) {
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(accessKey, secretKey, ""),
+		Secure: secure,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("blobs.NewS3: %w", err)
+	}
+	return &S3{client: client, bucket: bucket}, nil
+}
+
+// PresignPut returns a URL that accepts one PUT of the object at key until
+// ttl passes.
+func (s *S3) PresignPut(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	u, err := s.client.PresignedPutObject(ctx, s.bucket, key, ttl)
+	if err != nil {
+		return "", fmt.Errorf("blobs.PresignPut: %w", err)
+	}
+	return u.String(), nil
+}
+
+// Put writes data to the object at key.
+func (s *S3) Put(ctx context.Context, key string, data []byte) error {
+	_, err := s.client.PutObject(ctx, s.bucket, key, bytes.NewReader(data), int64(len(data)),
+		minio.PutObjectOptions{ContentType: "application/json"})
+	if err != nil {
+		return fmt.Errorf("blobs.Put: %w", err)
+	}
+	return nil
+}
+
+// Get reads the object at key. It reads at most maxBytes+1 bytes, so an
+// oversized object fails Verify rather than being read whole.
+func (s *S3) Get(ctx context.Context, key string, maxBytes int64) ([]byte, error) {
+	obj, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("blobs.Get: %w", err)
+	}
+	defer obj.Close()
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, io.LimitReader(obj, maxBytes+1)); err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("blobs.Get: %w", err)
+	}
+	return buf.Bytes(), nil
+}
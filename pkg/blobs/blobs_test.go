@@ -0,0 +1,38 @@
+package blobs
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+func TestVerify(t *testing.T) {
+	data := []byte(`{"text":"hello"}`)
+	ref := types.NewBlobRef(data)
+	if err := ref.Validate("params_ref"); err != nil {
+		t.Fatalf("Digest produced an invalid ref: %v", err)
+	}
+	if err := Verify(data, ref); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if err := Verify([]byte(`{"text":"hellO"}`), ref); !errors.Is(err, ErrMismatch) {
+		t.Errorf("tampered blob: err = %v", err)
+	}
+	if err := Verify(data[:len(data)-1], ref); !errors.Is(err, ErrMismatch) {
+		t.Errorf("truncated blob: err = %v", err)
+	}
+	notJSON := []byte("not json")
+	if err := Verify(notJSON, types.NewBlobRef(notJSON)); !errors.Is(err, ErrInvalidJSON) {
+		t.Errorf("non-JSON blob: err = %v", err)
+	}
+}
+
+func TestKey(t *testing.T) {
+	ref := types.NewBlobRef([]byte(`{}`))
+	key := Key("tenant1", ref)
+	if !strings.HasPrefix(key, "params/tenant1/") || strings.Contains(key, "sha256:") || key == Key("tenant2", ref) {
+		t.Errorf("Key = %q", key)
+	}
+}
@@ -2,23 +2,50 @@
 package config
 
 import (
+	"fmt"
 	"log/slog"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
-// EnvOr returns the environment variable value or a fallback default.
-func EnvOr(key, fallback string) string {
-	if v := os.Getenv(key); v != "" {
+// Env holds configuration values that apply where the process
+// environment leaves a variable unset, such as those of a config file
+// returned by Resolve. Services read their settings through it, so a
+// program that embeds them can configure them without changing its own
+// environment. A nil Env is the process environment alone.
+type Env map[string]string
+
+// Lookup returns the variable from the process environment, else from e.
+func (e Env) Lookup(key string) (string, bool) {
+	if v, ok := os.LookupEnv(key); ok {
+		return v, true
+	}
+	v, ok := e[key]
+	return v, ok
+}
+
+// Get returns the variable's value, or "" when it is unset.
+func (e Env) Get(key string) string {
+	v, _ := e.Lookup(key)
+	return v
+}
+
+// Or returns the variable's value or a fallback default.
+func (e Env) Or(key, fallback string) string {
+	if v := e.Get(key); v != "" {
 		return v
 	}
 	return fallback
 }
 
-// EnvOrInt returns an integer environment variable or a fallback default.
-// Logs a warning if the value is set but not parseable or not positive.
-func EnvOrInt(key string, fallback int) int {
-	v := os.Getenv(key)
+// Int returns an integer variable or a fallback default. 0 is returned as
+// set, for settings where it means off or unlimited; Load rejects it for
+// the others. Logs a warning if the value is set but not parseable or
+// negative.
+func (e Env) Int(key string, fallback int) int {
+	v := e.Get(key)
 	if v == "" {
 		return fallback
 	}
@@ -27,9 +54,112 @@ func EnvOrInt(key string, fallback int) int {
 		slog.Warn("invalid integer env var, using fallback", "key", key, "value", v, "fallback", fallback)
 		return fallback
 	}
-	if n <= 0 {
-		slog.Warn("env var must be positive, using fallback", "key", key, "value", n, "fallback", fallback)
+	if n < 0 {
+		slog.Warn("env var must not be negative, using fallback", "key", key, "value", n, "fallback", fallback)
 		return fallback
 	}
 	return n
 }
+
+// Bool returns a boolean variable ("true" or "false", in any case) or a
+// fallback default. Logs a warning if the value is set but is neither.
+func (e Env) Bool(key string, fallback bool) bool {
+	v := e.Get(key)
+	if v == "" {
+		return fallback
+	}
+	b, err := parseBool(v)
+	if err != nil {
+		slog.Warn("invalid boolean env var, using fallback", "key", key, "value", v, "fallback", fallback)
+		return fallback
+	}
+	return b
+}
+
+// Duration returns a duration variable or a fallback default. The value is
+// a count of unit, matching the variable's _SEC or _MS suffix, or a Go
+// duration such as "90s" or "5m". As with Int, 0 is returned as set. Logs
+// a warning if the value is set but not parseable or negative.
+func (e Env) Duration(key string, unit, fallback time.Duration) time.Duration {
+	v := e.Get(key)
+	if v == "" {
+		return fallback
+	}
+	d, err := parseDuration(v, unit)
+	if err != nil {
+		slog.Warn("invalid duration env var, using fallback", "key", key, "value", v, "fallback", fallback)
+		return fallback
+	}
+	if d < 0 {
+		slog.Warn("env var must not be negative, using fallback", "key", key, "value", v, "fallback", fallback)
+		return fallback
+	}
+	return d
+}
+
+// Require reports every listed variable that is unset or empty, together.
+func (e Env) Require(keys ...string) error {
+	var missing []string
+	for _, k := range keys {
+		if e.Get(k) == "" {
+			missing = append(missing, k)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s: required", strings.Join(missing, ", "))
+}
+
+// EnvOr returns the environment variable value or a fallback default.
+func EnvOr(key, fallback string) string {
+	return Env(nil).Or(key, fallback)
+}
+
+// EnvOrInt returns an integer environment variable or a fallback default,
+// as Env.Int does.
+func EnvOrInt(key string, fallback int) int {
+	return Env(nil).Int(key, fallback)
+}
+
+// EnvOrBool returns a boolean environment variable or a fallback default,
+// as Env.Bool does.
+func EnvOrBool(key string, fallback bool) bool {
+	return Env(nil).Bool(key, fallback)
+}
+
+// EnvOrDuration returns a duration environment variable or a fallback
+// default, as Env.Duration does.
+func EnvOrDuration(key string, unit, fallback time.Duration) time.Duration {
+	return Env(nil).Duration(key, unit, fallback)
+}
+
+// Require reports every listed environment variable that is unset or
+// empty, together.
+func Require(keys ...string) error {
+	return Env(nil).Require(keys...)
+}
+
+// parseBool accepts only "true" and "false", in any case, so a value
+// such as "yes" or "1" is reported rather than read as false.
+func parseBool(v string) (bool, error) {
+	switch strings.ToLower(v) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	return false, fmt.Errorf("%q is not true or false", v)
+}
+
+// parseDuration reads v as an integer count of unit or as a Go duration.
+func parseDuration(v string, unit time.Duration) (time.Duration, error) {
+	if n, err := strconv.Atoi(v); err == nil {
+		return time.Duration(n) * unit, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a duration", v)
+	}
+	return d, nil
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEnvOrBool(t *testing.T) {
+	for v, want := range map[string]bool{"": true, "true": true, "TRUE": true, "false": false, "False": false, "yes": true, "0": true} {
+		t.Setenv("OC_TEST_BOOL", v)
+		if got := EnvOrBool("OC_TEST_BOOL", true); got != want {
+			t.Errorf("EnvOrBool(%q) = %v, want %v", v, got, want)
+		}
+	}
+}
+
+func TestEnvOrDuration(t *testing.T) {
+	for v, want := range map[string]time.Duration{
+		"":      time.Minute,
+		"90":    90 * time.Second,
+		"90s":   90 * time.Second,
+		"5m":    5 * time.Minute,
+		"0":     0,
+		"-5s":   time.Minute,
+		"soon":  time.Minute,
+		"1h30m": 90 * time.Minute,
+	} {
+		t.Setenv("OC_TEST_SEC", v)
+		if got := EnvOrDuration("OC_TEST_SEC", time.Second, time.Minute); got != want {
+			t.Errorf("EnvOrDuration(%q) = %v, want %v", v, got, want)
+		}
+	}
+	t.Setenv("OC_TEST_MS", "250")
+	if got := EnvOrDuration("OC_TEST_MS", time.Millisecond, time.Second); got != 250*time.Millisecond {
+		t.Errorf("EnvOrDuration(250 ms) = %v", got)
+	}
+}
+
+func TestRequire(t *testing.T) {
+	t.Setenv("OC_TEST_SET", "x")
+	t.Setenv("OC_TEST_EMPTY", "")
+	if err := Require("OC_TEST_SET"); err != nil {
+		t.Fatal(err)
+	}
+	err := Require("OC_TEST_SET", "OC_TEST_EMPTY", "OC_TEST_UNSET")
+	if err == nil || !strings.Contains(err.Error(), "OC_TEST_EMPTY, OC_TEST_UNSET") || strings.Contains(err.Error(), "OC_TEST_SET,") {
+		t.Fatalf("Require = %v, want both missing variables", err)
+	}
+}
@@ -0,0 +1,770 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// FileEnv names the environment variable that points at a config file.
+const FileEnv = "OC_CONFIG_FILE"
+
+// File is the typed schema shared by every service's configuration. Each
+// leaf is tagged with the environment variable it maps to; services keep
+// reading settings through EnvOr or an Env, and Load exports file values
+// into the environment for variables that are not already set (Resolve
+// returns them as an Env instead), so an env var always overrides the
+// file. Numbers and booleans are pointers so an explicit 0 or false in the
+// file is kept apart from an unset key. A number may be 0 only
+// when tagged zero:"allowed", where 0 turns the setting off or leaves the
+// library default.
+type File struct {
+	// Mode is empty for a regular deployment or "lite" to run on SQLite,
+	// the embedded policy and mock connectors with no external services.
+	Mode       string         `yaml:"mode" toml:"mode" env:"OC_MODE"`
+	Postgres   PostgresFile   `yaml:"postgres" toml:"postgres"`
+	MySQL      MySQLFile      `yaml:"mysql" toml:"mysql"`
+	Evidence   EvidenceFile   `yaml:"evidence" toml:"evidence"`
+	OPA        OPAFile        `yaml:"opa" toml:"opa"`
+	Policy     PolicyFile     `yaml:"policy" toml:"policy"`
+	Gateway    GatewayFile    `yaml:"gateway" toml:"gateway"`
+	Approvals  ApprovalsFile  `yaml:"approvals" toml:"approvals"`
+	Connectors ConnectorsFile `yaml:"connectors" toml:"connectors"`
+	Auth       AuthFile       `yaml:"auth" toml:"auth"`
+	Slack      SlackFile      `yaml:"slack" toml:"slack"`
+	Jira       JiraFile       `yaml:"jira" toml:"jira"`
+	Archiver   ArchiverFile   `yaml:"archiver" toml:"archiver"`
+	EventBus   EventBusFile   `yaml:"eventbus" toml:"eventbus"`
+	Events     EventsFile     `yaml:"events" toml:"events"`
+	SIEM       SIEMFile       `yaml:"siem" toml:"siem"`
+	OTel       OTelFile       `yaml:"otel" toml:"otel"`
+	Secrets    SecretsFile    `yaml:"secrets" toml:"secrets"`
+	Creds      CredsFile      `yaml:"connector_credentials" toml:"connector_credentials"`
+	Tenants    TenantsFile    `yaml:"tenants" toml:"tenants"`
+	Metering   MeteringFile   `yaml:"metering" toml:"metering"`
+	Dashboard  DashboardFile  `yaml:"dashboard" toml:"dashboard"`
+}
+
+type PostgresFile struct {
+	Host     string       `yaml:"host" toml:"host" env:"POSTGRES_HOST"`
+	Port     *int         `yaml:"port" toml:"port" env:"POSTGRES_PORT"`
+	User     string       `yaml:"user" toml:"user" env:"POSTGRES_USER"`
+	Password string       `yaml:"password" toml:"password" env:"POSTGRES_PASSWORD" secret:"true"`
+	DB       string       `yaml:"db" toml:"db" env:"POSTGRES_DB"`
+	SSLMode  string       `yaml:"sslmode" toml:"sslmode" env:"POSTGRES_SSLMODE"`
+	Pool     PostgresPool `yaml:"pool" toml:"pool"`
+}
+
+type PostgresPool struct {
+	MaxConns           *int `yaml:"max_conns" toml:"max_conns" env:"PG_POOL_MAX_CONNS" zero:"allowed"`
+	MinConns           *int `yaml:"min_conns" toml:"min_conns" env:"PG_POOL_MIN_CONNS" zero:"allowed"`
+	MaxConnLifetimeSec *int `yaml:"max_conn_lifetime_sec" toml:"max_conn_lifetime_sec" env:"PG_POOL_MAX_CONN_LIFETIME_SEC" zero:"allowed"`
+	MaxConnIdleSec     *int `yaml:"max_conn_idle_sec" toml:"max_conn_idle_sec" env:"PG_POOL_MAX_CONN_IDLE_SEC" zero:"allowed"`
+	HealthCheckSec     *int `yaml:"health_check_sec" toml:"health_check_sec" env:"PG_POOL_HEALTH_CHECK_SEC" zero:"allowed"`
+	ConnectTimeoutSec  *int `yaml:"connect_timeout_sec" toml:"connect_timeout_sec" env:"PG_CONNECT_TIMEOUT_SEC" zero:"allowed"`
+}
+
+type MySQLFile struct {
+	DSN string `yaml:"dsn" toml:"dsn" env:"MYSQL_DSN" secret:"true"`
+}
+
+type EvidenceFile struct {
+	Backend    string `yaml:"backend" toml:"backend" env:"EVIDENCE_BACKEND"`
+	SQLitePath string `yaml:"sqlite_path" toml:"sqlite_path" env:"EVIDENCE_SQLITE_PATH"`
+	// CanonicalJSON is legacy or jcs (RFC 8785).
+	CanonicalJSON string          `yaml:"canonical_json" toml:"canonical_json" env:"EVIDENCE_CANONICAL_JSON"`
+	S3            S3File          `yaml:"s3" toml:"s3"`
+	AuditLog      AuditLogFile    `yaml:"audit_log" toml:"audit_log"`
+	Replication   ReplicationFile `yaml:"replication" toml:"replication"`
+}
+
+// ReplicationFile configures cmd/replicator, which copies a primary
+// region's evidence log into this region's store. Token is the primary
+// gateway's admin token.
+type ReplicationFile struct {
+	PrimaryURL   string `yaml:"primary_url" toml:"primary_url" env:"REPLICATION_PRIMARY_URL"`
+	Token        string `yaml:"token" toml:"token" env:"REPLICATION_TOKEN" secret:"true"`
+	IntervalSec  *int   `yaml:"interval_sec" toml:"interval_sec" env:"REPLICATION_INTERVAL_SEC"`
+	BatchSize    *int   `yaml:"batch_size" toml:"batch_size" env:"REPLICATION_BATCH_SIZE"`
+	MaxLagEvents *int   `yaml:"max_lag_events" toml:"max_lag_events" env:"REPLICATION_MAX_LAG_EVENTS" zero:"allowed"`
+	MaxStaleSec  *int   `yaml:"max_stale_sec" toml:"max_stale_sec" env:"REPLICATION_MAX_STALE_SEC"`
+	Addr         string `yaml:"addr" toml:"addr" env:"REPLICATOR_ADDR"`
+	MetricsAddr  string `yaml:"metrics_addr" toml:"metrics_addr" env:"REPLICATOR_METRICS_ADDR"`
+}
+
+// AuditLogFile configures the audit log, the hash-chained JSON lines each
+// service writes apart from its operational logs. The OTLP settings
+// default to otel's.
+type AuditLogFile struct {
+	// Sinks is a comma-separated list of stdout, stderr, file and otlp.
+	Sinks        string `yaml:"sinks" toml:"sinks" env:"AUDIT_LOG_SINKS"`
+	File         string `yaml:"file" toml:"file" env:"AUDIT_LOG_FILE"`
+	OTLPEndpoint string `yaml:"otlp_endpoint" toml:"otlp_endpoint" env:"AUDIT_LOG_OTLP_ENDPOINT"`
+	OTLPHeaders  string `yaml:"otlp_headers" toml:"otlp_headers" env:"AUDIT_LOG_OTLP_HEADERS" secret:"true"`
+}
+
+type S3File struct {
+	Endpoint  string `yaml:"endpoint" toml:"endpoint" env:"EVIDENCE_S3_ENDPOINT"`
+	Bucket    string `yaml:"bucket" toml:"bucket" env:"EVIDENCE_S3_BUCKET"`
+	AccessKey string `yaml:"access_key" toml:"access_key" env:"EVIDENCE_S3_ACCESS_KEY"`
+	SecretKey string `yaml:"secret_key" toml:"secret_key" env:"EVIDENCE_S3_SECRET_KEY" secret:"true"`
+	Secure    *bool  `yaml:"secure" toml:"secure" env:"EVIDENCE_S3_SECURE"`
+}
+
+type OPAFile struct {
+	URL                string `yaml:"url" toml:"url" env:"OPA_URL"`
+	RetryMaxAttempts   *int   `yaml:"retry_max_attempts" toml:"retry_max_attempts" env:"OPA_RETRY_MAX_ATTEMPTS"`
+	RetryBaseDelayMS   *int   `yaml:"retry_base_delay_ms" toml:"retry_base_delay_ms" env:"OPA_RETRY_BASE_DELAY_MS"`
+	BreakerThreshold   *int   `yaml:"breaker_threshold" toml:"breaker_threshold" env:"OPA_BREAKER_THRESHOLD" zero:"allowed"`
+	BreakerCooldownSec *int   `yaml:"breaker_cooldown_sec" toml:"breaker_cooldown_sec" env:"OPA_BREAKER_COOLDOWN_SEC"`
+}
+
+// PolicyFile selects the policy engine. The embedded engine evaluates the
+// bundle in BundleDir, or the default bundle, in process against DataFile,
+// or the bundle's data.json.
+type PolicyFile struct {
+	Engine    string `yaml:"engine" toml:"engine" env:"POLICY_ENGINE"`
+	BundleDir string `yaml:"bundle_dir" toml:"bundle_dir" env:"POLICY_BUNDLE_DIR"`
+	DataFile  string `yaml:"data_file" toml:"data_file" env:"POLICY_DATA_FILE"`
+}
+
+type GatewayFile struct {
+	Addr                string     `yaml:"addr" toml:"addr" env:"GATEWAY_ADDR"`
+	MetricsAddr         string     `yaml:"metrics_addr" toml:"metrics_addr" env:"METRICS_ADDR"`
+	RateLimitPerTenant  *int       `yaml:"rate_limit_per_tenant" toml:"rate_limit_per_tenant" env:"RATE_LIMIT_PER_TENANT"`
+	RateLimitBurst      *int       `yaml:"rate_limit_burst_per_tenant" toml:"rate_limit_burst_per_tenant" env:"RATE_LIMIT_BURST_PER_TENANT" zero:"allowed"`
+	MaxInFlight         *int       `yaml:"max_inflight" toml:"max_inflight" env:"GATEWAY_MAX_INFLIGHT" zero:"allowed"`
+	AgentMaxConcurrent  *int       `yaml:"agent_max_concurrent_executions" toml:"agent_max_concurrent_executions" env:"AGENT_MAX_CONCURRENT_EXECUTIONS" zero:"allowed"`
+	ShedTargetLatencyMS *int       `yaml:"shed_target_latency_ms" toml:"shed_target_latency_ms" env:"GATEWAY_SHED_TARGET_LATENCY_MS" zero:"allowed"`
+	ReceiptSigningKey   string     `yaml:"receipt_signing_key" toml:"receipt_signing_key" env:"RECEIPT_SIGNING_KEY" secret:"true"`
+	ReceiptPreviousKeys string     `yaml:"receipt_previous_public_keys" toml:"receipt_previous_public_keys" env:"RECEIPT_PREVIOUS_PUBLIC_KEYS"`
+	ResponseSigning     *bool      `yaml:"response_signing_enabled" toml:"response_signing_enabled" env:"RESPONSE_SIGNING_ENABLED"`
+	InjectionDetection  *bool      `yaml:"injection_detection" toml:"injection_detection" env:"INJECTION_DETECTION"`
+	InjectionDomains    string     `yaml:"injection_blocked_domains" toml:"injection_blocked_domains" env:"INJECTION_BLOCKED_DOMAINS"`
+	ApprovalContext     *int       `yaml:"approval_context_events" toml:"approval_context_events" env:"APPROVAL_CONTEXT_EVENTS" zero:"allowed"`
+	SchedulerPollSec    *int       `yaml:"scheduler_poll_sec" toml:"scheduler_poll_sec" env:"SCHEDULER_POLL_SEC"`
+	Blobs               BlobsFile  `yaml:"blobs" toml:"blobs"`
+	Mirror              MirrorFile `yaml:"mirror" toml:"mirror"`
+}
+
+// MirrorFile configures copying a sample of tool calls to a staging
+// gateway, and receiving such copies on one.
+type MirrorFile struct {
+	URL          string `yaml:"url" toml:"url" env:"MIRROR_URL"`
+	Token        string `yaml:"token" toml:"token" env:"MIRROR_TOKEN" secret:"true"`
+	SampleRatio  string `yaml:"sample_ratio" toml:"sample_ratio" env:"MIRROR_SAMPLE_RATIO"`
+	QueueSize    *int   `yaml:"queue_size" toml:"queue_size" env:"MIRROR_QUEUE_SIZE"`
+	ReceiveToken string `yaml:"receive_token" toml:"receive_token" env:"MIRROR_RECEIVE_TOKEN" secret:"true"`
+}
+
+// BlobsFile configures the store for params sent by params_ref. Unset
+// connection settings fall back to evidence.s3.
+type BlobsFile struct {
+	Endpoint     string `yaml:"endpoint" toml:"endpoint" env:"BLOB_S3_ENDPOINT"`
+	Bucket       string `yaml:"bucket" toml:"bucket" env:"BLOB_S3_BUCKET"`
+	AccessKey    string `yaml:"access_key" toml:"access_key" env:"BLOB_S3_ACCESS_KEY"`
+	SecretKey    string `yaml:"secret_key" toml:"secret_key" env:"BLOB_S3_SECRET_KEY" secret:"true"`
+	Secure       *bool  `yaml:"secure" toml:"secure" env:"BLOB_S3_SECURE"`
+	UploadTTLSec *int   `yaml:"upload_ttl_sec" toml:"upload_ttl_sec" env:"BLOB_UPLOAD_TTL_SEC"`
+}
+
+type ApprovalsFile struct {
+	Backend             string        `yaml:"backend" toml:"backend" env:"APPROVALS_BACKEND"`
+	SQLitePath          string        `yaml:"sqlite_path" toml:"sqlite_path" env:"APPROVALS_SQLITE_PATH"`
+	Addr                string        `yaml:"addr" toml:"addr" env:"APPROVALS_ADDR"`
+	URL                 string        `yaml:"url" toml:"url" env:"APPROVALS_URL"`
+	MetricsAddr         string        `yaml:"metrics_addr" toml:"metrics_addr" env:"APPROVALS_METRICS_ADDR"`
+	NotifierEnabled     *bool         `yaml:"notifier_enabled" toml:"notifier_enabled" env:"APPROVALS_NOTIFIER_ENABLED"`
+	NotifierIntervalSec *int          `yaml:"notifier_interval_sec" toml:"notifier_interval_sec" env:"APPROVALS_NOTIFIER_INTERVAL_SEC"`
+	NotifierSource      string        `yaml:"notifier_source" toml:"notifier_source" env:"APPROVALS_NOTIFIER_SOURCE"`
+	NotifierDestRate    *int          `yaml:"notifier_dest_rate_per_min" toml:"notifier_dest_rate_per_min" env:"APPROVALS_NOTIFIER_DEST_RATE_PER_MIN"`
+	NotifierDestBurst   *int          `yaml:"notifier_dest_burst" toml:"notifier_dest_burst" env:"APPROVALS_NOTIFIER_DEST_BURST"`
+	DigestsEnabled      *bool         `yaml:"digests_enabled" toml:"digests_enabled" env:"APPROVALS_DIGESTS_ENABLED"`
+	DigestsIntervalSec  *int          `yaml:"digests_interval_sec" toml:"digests_interval_sec" env:"APPROVALS_DIGESTS_INTERVAL_SEC"`
+	EmailAllowlist      string        `yaml:"approver_email_allowlist" toml:"approver_email_allowlist" env:"APPROVER_EMAIL_ALLOWLIST"`
+	SlackAllowlist      string        `yaml:"approver_slack_allowlist" toml:"approver_slack_allowlist" env:"APPROVER_SLACK_ALLOWLIST"`
+	Directory           DirectoryFile `yaml:"directory" toml:"directory"`
+	OIDC                OIDCFile      `yaml:"oidc" toml:"oidc"`
+	WebhookSecretRefs   string        `yaml:"webhook_secret_refs" toml:"webhook_secret_refs" env:"WEBHOOK_SECRET_REFS" secret:"true"`
+	WebhookDomains      string        `yaml:"webhook_allowed_domains" toml:"webhook_allowed_domains" env:"WEBHOOK_ALLOWED_DOMAINS"`
+	WebhookCIDRs        string        `yaml:"webhook_allowed_cidrs" toml:"webhook_allowed_cidrs" env:"WEBHOOK_ALLOWED_CIDRS"`
+	SMTPAddr            string        `yaml:"smtp_addr" toml:"smtp_addr" env:"NOTIFY_SMTP_ADDR"`
+	SMTPFrom            string        `yaml:"smtp_from" toml:"smtp_from" env:"NOTIFY_SMTP_FROM"`
+	SMTPUsername        string        `yaml:"smtp_username" toml:"smtp_username" env:"NOTIFY_SMTP_USERNAME"`
+	SMTPPassword        string        `yaml:"smtp_password" toml:"smtp_password" env:"NOTIFY_SMTP_PASSWORD" secret:"true"`
+}
+
+// DirectoryFile configures the directory approvers are authorized against
+// in place of the allowlists.
+type DirectoryFile struct {
+	Provider       string `yaml:"provider" toml:"provider" env:"APPROVER_DIRECTORY"`
+	URL            string `yaml:"url" toml:"url" env:"APPROVER_DIRECTORY_URL"`
+	Token          string `yaml:"token" toml:"token" env:"APPROVER_DIRECTORY_TOKEN" secret:"true"`
+	AzureTenantID  string `yaml:"azure_tenant_id" toml:"azure_tenant_id" env:"APPROVER_DIRECTORY_AZURE_TENANT_ID"`
+	AzureClientID  string `yaml:"azure_client_id" toml:"azure_client_id" env:"APPROVER_DIRECTORY_AZURE_CLIENT_ID"`
+	Groups         string `yaml:"groups" toml:"groups" env:"APPROVER_DIRECTORY_GROUPS"`
+	SlackAttribute string `yaml:"slack_attribute" toml:"slack_attribute" env:"APPROVER_DIRECTORY_SLACK_ATTRIBUTE"`
+	SyncSec        *int   `yaml:"sync_sec" toml:"sync_sec" env:"APPROVER_DIRECTORY_SYNC_SEC"`
+}
+
+// OIDCFile configures approver sign-in, which makes the recorded approver
+// a verified identity.
+type OIDCFile struct {
+	Issuer        string `yaml:"issuer" toml:"issuer" env:"APPROVER_OIDC_ISSUER"`
+	ClientID      string `yaml:"client_id" toml:"client_id" env:"APPROVER_OIDC_CLIENT_ID"`
+	ClientSecret  string `yaml:"client_secret" toml:"client_secret" env:"APPROVER_OIDC_CLIENT_SECRET" secret:"true"`
+	RedirectURL   string `yaml:"redirect_url" toml:"redirect_url" env:"APPROVER_OIDC_REDIRECT_URL"`
+	Scopes        string `yaml:"scopes" toml:"scopes" env:"APPROVER_OIDC_SCOPES"`
+	SessionKey    string `yaml:"session_key" toml:"session_key" env:"APPROVALS_SESSION_KEY" secret:"true"`
+	SessionTTLSec *int   `yaml:"session_ttl_sec" toml:"session_ttl_sec" env:"APPROVALS_SESSION_TTL_SEC"`
+}
+
+type ConnectorsFile struct {
+	Mock                *bool  `yaml:"mock" toml:"mock" env:"MOCK_CONNECTORS"`
+	SlackURL            string `yaml:"slack_url" toml:"slack_url" env:"CONNECTOR_SLACK_URL"`
+	SlackAddr           string `yaml:"slack_addr" toml:"slack_addr" env:"CONNECTOR_SLACK_ADDR"`
+	SlackMetricsAddr    string `yaml:"slack_metrics_addr" toml:"slack_metrics_addr" env:"CONNECTOR_SLACK_METRICS_ADDR"`
+	JiraURL             string `yaml:"jira_url" toml:"jira_url" env:"CONNECTOR_JIRA_URL"`
+	JiraAddr            string `yaml:"jira_addr" toml:"jira_addr" env:"CONNECTOR_JIRA_ADDR"`
+	JiraMetricsAddr     string `yaml:"jira_metrics_addr" toml:"jira_metrics_addr" env:"CONNECTOR_JIRA_METRICS_ADDR"`
+	Routes              string `yaml:"routes" toml:"routes" env:"CONNECTOR_ROUTES"`
+	FallbackURL         string `yaml:"fallback_url" toml:"fallback_url" env:"CONNECTOR_FALLBACK_URL"`
+	PlanTools           string `yaml:"plan_tools" toml:"plan_tools" env:"CONNECTOR_PLAN_TOOLS"`
+	ManifestCacheSec    *int   `yaml:"manifest_cache_sec" toml:"manifest_cache_sec" env:"CONNECTOR_MANIFEST_CACHE_SEC"`
+	TimeoutSec          *int   `yaml:"timeout_sec" toml:"timeout_sec" env:"CONNECTOR_TIMEOUT_SEC"`
+	MaxOutputBytes      *int   `yaml:"max_output_bytes" toml:"max_output_bytes" env:"CONNECTOR_MAX_OUTPUT_BYTES"`
+	OutputSpill         *bool  `yaml:"output_spill" toml:"output_spill" env:"CONNECTOR_OUTPUT_SPILL"`
+	TemplateAddr        string `yaml:"template_addr" toml:"template_addr" env:"CONNECTOR_TEMPLATE_ADDR"`
+	TemplateMetricsAddr string `yaml:"template_metrics_addr" toml:"template_metrics_addr" env:"CONNECTOR_TEMPLATE_METRICS_ADDR"`
+	MCPServers          string `yaml:"mcp_servers" toml:"mcp_servers" env:"MCP_SERVERS"`
+	MCPAddr             string `yaml:"mcp_addr" toml:"mcp_addr" env:"CONNECTOR_MCP_ADDR"`
+	MCPMetricsAddr      string `yaml:"mcp_metrics_addr" toml:"mcp_metrics_addr" env:"CONNECTOR_MCP_METRICS_ADDR"`
+	MCPTimeoutSec       *int   `yaml:"mcp_timeout_sec" toml:"mcp_timeout_sec" env:"MCP_TIMEOUT_SEC"`
+	SandboxCommandsFile string `yaml:"sandbox_commands_file" toml:"sandbox_commands_file" env:"SANDBOX_COMMANDS_FILE"`
+	SandboxTool         string `yaml:"sandbox_tool" toml:"sandbox_tool" env:"SANDBOX_TOOL"`
+	SandboxAddr         string `yaml:"sandbox_addr" toml:"sandbox_addr" env:"CONNECTOR_SANDBOX_ADDR"`
+	SandboxMetricsAddr  string `yaml:"sandbox_metrics_addr" toml:"sandbox_metrics_addr" env:"CONNECTOR_SANDBOX_METRICS_ADDR"`
+	WebAllowedDomains   string `yaml:"web_allowed_domains" toml:"web_allowed_domains" env:"WEB_FETCH_ALLOWED_DOMAINS"`
+	WebAllowedCIDRs     string `yaml:"web_allowed_cidrs" toml:"web_allowed_cidrs" env:"WEB_FETCH_ALLOWED_CIDRS"`
+	WebContentTypes     string `yaml:"web_content_types" toml:"web_content_types" env:"WEB_FETCH_CONTENT_TYPES"`
+	WebMaxBytes         *int   `yaml:"web_max_bytes" toml:"web_max_bytes" env:"WEB_FETCH_MAX_BYTES"`
+	WebTimeoutSec       *int   `yaml:"web_timeout_sec" toml:"web_timeout_sec" env:"WEB_FETCH_TIMEOUT_SEC"`
+	WebAddr             string `yaml:"web_addr" toml:"web_addr" env:"CONNECTOR_WEB_ADDR"`
+	WebMetricsAddr      string `yaml:"web_metrics_addr" toml:"web_metrics_addr" env:"CONNECTOR_WEB_METRICS_ADDR"`
+}
+
+type AuthFile struct {
+	APIKeys       string `yaml:"api_keys" toml:"api_keys" env:"API_KEYS" secret:"true"`
+	InternalToken string `yaml:"internal_token" toml:"internal_token" env:"INTERNAL_AUTH_TOKEN" secret:"true"`
+	AdminToken    string `yaml:"admin_token" toml:"admin_token" env:"ADMIN_API_TOKEN" secret:"true"`
+}
+
+type SlackFile struct {
+	BotToken      string `yaml:"bot_token" toml:"bot_token" env:"SLACK_BOT_TOKEN" secret:"true"`
+	SigningSecret string `yaml:"signing_secret" toml:"signing_secret" env:"SLACK_SIGNING_SECRET" secret:"true"`
+	// PreviousSigningSecrets is a comma-separated list still accepted during rotation.
+	PreviousSigningSecrets string `yaml:"previous_signing_secrets" toml:"previous_signing_secrets" env:"SLACK_SIGNING_SECRET_PREVIOUS" secret:"true"`
+}
+
+type JiraFile struct {
+	BaseURL  string `yaml:"base_url" toml:"base_url" env:"JIRA_BASE_URL"`
+	Email    string `yaml:"email" toml:"email" env:"JIRA_EMAIL"`
+	APIToken string `yaml:"api_token" toml:"api_token" env:"JIRA_API_TOKEN" secret:"true"`
+}
+
+type ArchiverFile struct {
+	IntervalSec *int   `yaml:"interval_sec" toml:"interval_sec" env:"ARCHIVER_INTERVAL_SEC"`
+	RunOnce     *bool  `yaml:"run_once" toml:"run_once" env:"ARCHIVER_RUN_ONCE"`
+	TenantID    string `yaml:"tenant_id" toml:"tenant_id" env:"ARCHIVER_TENANT_ID"`
+	// Dir stores bundles under a local directory instead of S3.
+	Dir string `yaml:"dir" toml:"dir" env:"ARCHIVER_DIR"`
+	// OutboxRetentionDays archives and deletes delivered and failed
+	// notification outbox rows older than this many days.
+	OutboxRetentionDays *int `yaml:"outbox_retention_days" toml:"outbox_retention_days" env:"OUTBOX_RETENTION_DAYS" zero:"allowed"`
+}
+
+type EventBusFile struct {
+	Driver      string `yaml:"driver" toml:"driver" env:"EVENTBUS_DRIVER"`
+	URL         string `yaml:"url" toml:"url" env:"EVENTBUS_URL"`
+	TopicPrefix string `yaml:"topic_prefix" toml:"topic_prefix" env:"EVENTBUS_TOPIC_PREFIX"`
+}
+
+type EventsFile struct {
+	Source    string `yaml:"source" toml:"source" env:"EVENTS_SOURCE"`
+	QueueSize *int   `yaml:"queue_size" toml:"queue_size" env:"EVENTS_QUEUE_SIZE"`
+}
+
+type SIEMFile struct {
+	ConfigFile string `yaml:"config_file" toml:"config_file" env:"SIEM_CONFIG_FILE"`
+}
+
+type OTelFile struct {
+	Endpoint           string `yaml:"endpoint" toml:"endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	Insecure           *bool  `yaml:"insecure" toml:"insecure" env:"OTEL_EXPORTER_OTLP_INSECURE"`
+	Headers            string `yaml:"headers" toml:"headers" env:"OTEL_EXPORTER_OTLP_HEADERS" secret:"true"`
+	ServiceName        string `yaml:"service_name" toml:"service_name" env:"OTEL_SERVICE_NAME"`
+	ResourceAttributes string `yaml:"resource_attributes" toml:"resource_attributes" env:"OTEL_RESOURCE_ATTRIBUTES"`
+	MetricsExporter    string `yaml:"metrics_exporter" toml:"metrics_exporter" env:"OTEL_METRICS_EXPORTER"`
+	MetricExportMS     *int   `yaml:"metric_export_interval_ms" toml:"metric_export_interval_ms" env:"OTEL_METRIC_EXPORT_INTERVAL"`
+	TracesSampler      string `yaml:"traces_sampler" toml:"traces_sampler" env:"OTEL_TRACES_SAMPLER"`
+	TracesSamplerArg   string `yaml:"traces_sampler_arg" toml:"traces_sampler_arg" env:"OTEL_TRACES_SAMPLER_ARG"`
+}
+
+// SecretsFile configures the secrets-manager providers used to resolve
+// vault://, awssm://, and gcpsm:// references in secret settings.
+type SecretsFile struct {
+	RefreshSec     *int   `yaml:"refresh_sec" toml:"refresh_sec" env:"SECRETS_REFRESH_SEC"`
+	VaultAddr      string `yaml:"vault_addr" toml:"vault_addr" env:"VAULT_ADDR"`
+	VaultToken     string `yaml:"vault_token" toml:"vault_token" env:"VAULT_TOKEN" secret:"true"`
+	VaultNamespace string `yaml:"vault_namespace" toml:"vault_namespace" env:"VAULT_NAMESPACE"`
+	AWSRegion      string `yaml:"aws_region" toml:"aws_region" env:"AWS_REGION"`
+	AWSEndpoint    string `yaml:"aws_endpoint" toml:"aws_endpoint" env:"AWS_SECRETSMANAGER_ENDPOINT"`
+	GCPEndpoint    string `yaml:"gcp_endpoint" toml:"gcp_endpoint" env:"GCP_SECRETMANAGER_ENDPOINT"`
+}
+
+// CredsFile configures per-tenant connector credentials stored encrypted
+// in Postgres.
+type CredsFile struct {
+	Enabled        *bool  `yaml:"enabled" toml:"enabled" env:"CONNECTOR_CREDENTIALS_ENABLED"`
+	EncryptionKeys string `yaml:"encryption_keys" toml:"encryption_keys" env:"CREDENTIALS_ENCRYPTION_KEYS" secret:"true"`
+	KMSKeyID       string `yaml:"kms_key_id" toml:"kms_key_id" env:"CREDENTIALS_KMS_KEY_ID"`
+	KMSEndpoint    string `yaml:"kms_endpoint" toml:"kms_endpoint" env:"CREDENTIALS_KMS_ENDPOINT"`
+	CacheSec       *int   `yaml:"cache_sec" toml:"cache_sec" env:"CONNECTOR_CREDENTIALS_CACHE_SEC"`
+}
+
+// TenantsFile configures the tenant admin API (enabled by auth.admin_token).
+type TenantsFile struct {
+	DefaultConfig    string `yaml:"default_config" toml:"default_config" env:"TENANT_DEFAULT_CONFIG"`
+	KeysRefreshSec   *int   `yaml:"keys_refresh_sec" toml:"keys_refresh_sec" env:"TENANT_KEYS_REFRESH_SEC"`
+	SettingsCacheSec *int   `yaml:"settings_cache_sec" toml:"settings_cache_sec" env:"TENANT_SETTINGS_CACHE_SEC"`
+	PolicySyncSec    *int   `yaml:"policy_data_sync_sec" toml:"policy_data_sync_sec" env:"POLICY_DATA_SYNC_SEC"`
+}
+
+// MeteringFile configures usage metering.
+type MeteringFile struct {
+	FlushSec *int `yaml:"flush_sec" toml:"flush_sec" env:"METERING_FLUSH_SEC"`
+}
+
+// DashboardFile configures the read-only operations dashboard.
+type DashboardFile struct {
+	Enabled       *bool  `yaml:"enabled" toml:"enabled" env:"DASHBOARD_ENABLED"`
+	AuditorTokens string `yaml:"auditor_tokens" toml:"auditor_tokens" env:"AUDITOR_TOKENS" secret:"true"`
+}
+
+// ── Loading ──────────────────────────────────────────────────────────────
+
+// LiteMode is the OC_MODE value that runs OpenClause without Postgres, OPA,
+// S3 or connector deployments.
+const LiteMode = "lite"
+
+// liteDefaults are exported for unset variables in lite mode.
+var liteDefaults = [][2]string{
+	{"EVIDENCE_BACKEND", "sqlite"},
+	{"APPROVALS_BACKEND", "sqlite"},
+	{"POLICY_ENGINE", "embedded"},
+	{"MOCK_CONNECTORS", "true"},
+	{"ARCHIVER_DIR", "openclause-archive"},
+}
+
+// Load reads the file named by OC_CONFIG_FILE (if any), exports its values
+// for unset environment variables, and validates the resulting effective
+// configuration, which must also set every variable in required. It
+// returns that effective configuration, or an error listing every
+// malformed, invalid and missing setting. In lite mode the SQLite,
+// embedded policy and mock connector settings default on.
+func Load(required ...string) (*File, error) {
+	eff, env, err := Resolve(os.Getenv(FileEnv), required...)
+	if err != nil {
+		return nil, err
+	}
+	if err := env.export(); err != nil {
+		return nil, err
+	}
+	return eff, nil
+}
+
+// Resolve is Load without changing the process environment: it returns
+// the values of the file at path (if any) and the lite-mode defaults as an
+// Env, for services to read in place of the environment, together with
+// the validated effective configuration. An empty path reads no file.
+func Resolve(path string, required ...string) (*File, Env, error) {
+	env := Env{}
+	if path != "" {
+		f, err := LoadFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		env = f.Values()
+	}
+	if env.Get("OC_MODE") == LiteMode {
+		for _, kv := range liteDefaults {
+			if _, ok := env.Lookup(kv[0]); !ok {
+				env[kv[0]] = kv[1]
+			}
+		}
+	}
+	var errs []error
+	eff, err := env.File()
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if err := eff.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := env.Require(required...); err != nil {
+		errs = append(errs, fmt.Errorf("config: %w", err))
+	}
+	if len(errs) > 0 {
+		return nil, nil, errors.Join(errs...)
+	}
+	return eff, env, nil
+}
+
+// LoadFile decodes a YAML (.yaml, .yml) or TOML (.toml) config file.
+// Unknown keys are rejected so typos do not silently fall back to defaults.
+func LoadFile(path string) (*File, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("config.LoadFile: %w", err)
+	}
+	var f File
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(b))
+		dec.KnownFields(true)
+		if err := dec.Decode(&f); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("config.LoadFile %s: %w", path, err)
+		}
+	case ".toml":
+		md, err := toml.Decode(string(b), &f)
+		if err != nil {
+			return nil, fmt.Errorf("config.LoadFile %s: %w", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return nil, fmt.Errorf("config.LoadFile %s: unknown keys %v", path, undecoded)
+		}
+	default:
+		return nil, fmt.Errorf("config.LoadFile: unsupported extension %q (want .yaml, .yml, or .toml)", ext)
+	}
+	return &f, nil
+}
+
+// Values returns the value of every field set in the file, keyed by its
+// environment variable.
+func (f *File) Values() Env {
+	env := Env{}
+	walk(reflect.ValueOf(f).Elem(), func(fv reflect.Value, _ reflect.StructField, key string) {
+		if s, set := formatField(fv); set {
+			env[key] = s
+		}
+	})
+	return env
+}
+
+// Apply sets the environment variable for every field set in the file
+// whose variable is not already present in the environment.
+func (f *File) Apply() error {
+	return f.Values().export()
+}
+
+// export sets the environment variable for each of e's values whose
+// variable is not already present in the environment.
+func (e Env) export() error {
+	var errs []error
+	for key, v := range e {
+		if _, ok := os.LookupEnv(key); ok {
+			continue
+		}
+		if err := os.Setenv(key, v); err != nil {
+			errs = append(errs, fmt.Errorf("config: set %s: %w", key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// FromEnv builds a File from the current environment, reporting variables
+// whose values do not parse as the field's type. The File is returned with
+// the error too, holding the values that did parse, so it can still be
+// validated.
+func FromEnv() (*File, error) {
+	return Env(nil).File()
+}
+
+// File builds a File from the environment and e, as FromEnv does from the
+// environment alone.
+func (e Env) File() (*File, error) {
+	var f File
+	var errs []error
+	walk(reflect.ValueOf(&f).Elem(), func(fv reflect.Value, _ reflect.StructField, env string) {
+		v, ok := e.Lookup(env)
+		if !ok || v == "" {
+			return
+		}
+		if err := parseField(fv, env, v); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", env, err))
+		}
+	})
+	if len(errs) > 0 {
+		return &f, fmt.Errorf("config: %w", errors.Join(errs...))
+	}
+	return &f, nil
+}
+
+// Settings returns the configured variables as env name → value, with
+// secrets masked, for startup logging.
+func (f *File) Settings() map[string]string {
+	out := map[string]string{}
+	walk(reflect.ValueOf(f).Elem(), func(fv reflect.Value, sf reflect.StructField, env string) {
+		s, set := formatField(fv)
+		if !set {
+			return
+		}
+		if sf.Tag.Get("secret") == "true" {
+			s = "********"
+		}
+		out[env] = s
+	})
+	return out
+}
+
+// ── Validation ───────────────────────────────────────────────────────────
+
+// Validate checks enumerations, cross-field requirements, ports, and URLs.
+// All problems are reported together.
+func (f *File) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	oneOf := func(env, v string, allowed ...string) {
+		check(v == "" || slices.Contains(allowed, v), "%s: %q is not one of %s", env, v, strings.Join(allowed, ", "))
+	}
+
+	oneOf("EVIDENCE_BACKEND", f.Evidence.Backend, "postgres", "mysql", "sqlite")
+	oneOf("EVIDENCE_CANONICAL_JSON", f.Evidence.CanonicalJSON, "legacy", "jcs")
+	oneOf("APPROVALS_BACKEND", f.Approvals.Backend, "postgres", "mysql", "sqlite")
+	oneOf("OC_MODE", f.Mode, LiteMode)
+	oneOf("POLICY_ENGINE", f.Policy.Engine, "opa", "embedded")
+	oneOf("EVENTBUS_DRIVER", strings.ToLower(f.EventBus.Driver), "kafka", "nats")
+	oneOf("APPROVER_DIRECTORY", f.Approvals.Directory.Provider, "scim", "okta", "azuread")
+	oneOf("OTEL_TRACES_SAMPLER", strings.ToLower(f.OTel.TracesSampler), "always_on", "always_off", "traceidratio",
+		"parentbased_always_on", "parentbased_always_off", "parentbased_traceidratio")
+	for _, e := range strings.Split(f.OTel.MetricsExporter, ",") {
+		oneOf("OTEL_METRICS_EXPORTER", strings.ToLower(strings.TrimSpace(e)), "prometheus", "otlp", "none")
+		if strings.EqualFold(strings.TrimSpace(e), "otlp") {
+			check(f.OTel.Endpoint != "", "OTEL_EXPORTER_OTLP_ENDPOINT: required when OTEL_METRICS_EXPORTER includes otlp")
+		}
+	}
+	for _, s := range strings.Split(f.Evidence.AuditLog.Sinks, ",") {
+		oneOf("AUDIT_LOG_SINKS", strings.ToLower(strings.TrimSpace(s)), "stdout", "stderr", "file", "otlp", "none")
+		if strings.EqualFold(strings.TrimSpace(s), "otlp") {
+			check(f.Evidence.AuditLog.OTLPEndpoint != "" || f.OTel.Endpoint != "",
+				"AUDIT_LOG_OTLP_ENDPOINT: required (or OTEL_EXPORTER_OTLP_ENDPOINT) when AUDIT_LOG_SINKS includes otlp")
+		}
+	}
+	if arg := f.OTel.TracesSamplerArg; arg != "" {
+		r, err := strconv.ParseFloat(arg, 64)
+		check(err == nil && r >= 0 && r <= 1, "OTEL_TRACES_SAMPLER_ARG: %q is not a ratio between 0 and 1", arg)
+	}
+	if arg := f.Gateway.Mirror.SampleRatio; arg != "" {
+		r, err := strconv.ParseFloat(arg, 64)
+		check(err == nil && r >= 0 && r <= 1, "MIRROR_SAMPLE_RATIO: %q is not a ratio between 0 and 1", arg)
+	}
+	oneOf("POSTGRES_SSLMODE", f.Postgres.SSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full")
+
+	if f.Evidence.Backend == "mysql" || f.Approvals.Backend == "mysql" {
+		check(f.MySQL.DSN != "", "MYSQL_DSN: required when EVIDENCE_BACKEND or APPROVALS_BACKEND is mysql")
+	}
+	if f.Creds.Enabled != nil && *f.Creds.Enabled {
+		check(f.Creds.EncryptionKeys != "" || f.Creds.KMSKeyID != "",
+			"CREDENTIALS_ENCRYPTION_KEYS: required (or CREDENTIALS_KMS_KEY_ID) when CONNECTOR_CREDENTIALS_ENABLED is true")
+	}
+	if f.Dashboard.Enabled != nil && *f.Dashboard.Enabled {
+		check((f.Evidence.Backend == "" || f.Evidence.Backend == "postgres") && (f.Approvals.Backend == "" || f.Approvals.Backend == "postgres"),
+			"DASHBOARD_ENABLED: requires the postgres evidence and approvals backends")
+	}
+	if f.Approvals.DigestsEnabled != nil && *f.Approvals.DigestsEnabled {
+		check(f.Mode != LiteMode && (f.Evidence.Backend == "" || f.Evidence.Backend == "postgres") && (f.Approvals.Backend == "" || f.Approvals.Backend == "postgres"),
+			"APPROVALS_DIGESTS_ENABLED: requires the postgres evidence and approvals backends")
+	}
+	if f.Mode == LiteMode {
+		// Lite mode never connects to Postgres, so nothing may need it.
+		check(f.Evidence.Backend == "sqlite" && f.Approvals.Backend == "sqlite",
+			"OC_MODE: lite requires the sqlite evidence and approvals backends")
+		check(f.Auth.AdminToken == "", "ADMIN_API_TOKEN: the tenant admin API is not available in lite mode")
+		check(f.Creds.Enabled == nil || !*f.Creds.Enabled,
+			"CONNECTOR_CREDENTIALS_ENABLED: connector credentials are not available in lite mode")
+	}
+	if f.Tenants.DefaultConfig != "" {
+		var m map[string]any
+		check(json.Unmarshal([]byte(f.Tenants.DefaultConfig), &m) == nil && m != nil,
+			"TENANT_DEFAULT_CONFIG: must be a JSON object")
+	}
+	if f.Connectors.OutputSpill != nil && *f.Connectors.OutputSpill {
+		check(f.Gateway.Blobs.Bucket != "", "BLOB_S3_BUCKET: required when CONNECTOR_OUTPUT_SPILL is set")
+	}
+	if f.EventBus.Driver != "" {
+		check(f.EventBus.URL != "", "EVENTBUS_URL: required when EVENTBUS_DRIVER is set")
+	}
+	if dir := f.Approvals.Directory; dir.Provider != "" {
+		check(dir.Groups != "", "APPROVER_DIRECTORY_GROUPS: required when APPROVER_DIRECTORY is set")
+		check(dir.Token != "", "APPROVER_DIRECTORY_TOKEN: required when APPROVER_DIRECTORY is set")
+		if dir.Provider == "azuread" {
+			check(dir.AzureTenantID != "" && dir.AzureClientID != "",
+				"APPROVER_DIRECTORY_AZURE_TENANT_ID, APPROVER_DIRECTORY_AZURE_CLIENT_ID: required when APPROVER_DIRECTORY is azuread")
+		} else {
+			check(dir.URL != "", "APPROVER_DIRECTORY_URL: required when APPROVER_DIRECTORY is %s", dir.Provider)
+		}
+	}
+	if o := f.Approvals.OIDC; o.Issuer != "" {
+		check(o.ClientID != "", "APPROVER_OIDC_CLIENT_ID: required when APPROVER_OIDC_ISSUER is set")
+		check(o.SessionKey != "", "APPROVALS_SESSION_KEY: required when APPROVER_OIDC_ISSUER is set")
+	}
+	if port := f.Postgres.Port; port != nil {
+		check(*port <= 65535, "POSTGRES_PORT: %d is out of range", *port)
+	}
+	if minConns, maxConns := f.Postgres.Pool.MinConns, f.Postgres.Pool.MaxConns; minConns != nil && maxConns != nil && *maxConns != 0 {
+		check(*minConns <= *maxConns, "PG_POOL_MIN_CONNS: %d exceeds PG_POOL_MAX_CONNS %d", *minConns, *maxConns)
+	}
+
+	// Numbers: EnvOrInt and EnvOrDuration would fall back on a negative
+	// value, and 0 is only meaningful where the field allows it.
+	walk(reflect.ValueOf(f).Elem(), func(fv reflect.Value, sf reflect.StructField, env string) {
+		if fv.Kind() != reflect.Pointer || fv.IsNil() || fv.Elem().Kind() != reflect.Int {
+			return
+		}
+		n := fv.Elem().Int()
+		check(n >= 0, "%s: must not be negative, got %d", env, n)
+		check(n != 0 || sf.Tag.Get("zero") == "allowed", "%s: must be positive, got 0", env)
+	})
+
+	for env, v := range map[string]string{
+		"OPA_URL":             f.OPA.URL,
+		"APPROVALS_URL":       f.Approvals.URL,
+		"CONNECTOR_SLACK_URL": f.Connectors.SlackURL,
+		"CONNECTOR_JIRA_URL":  f.Connectors.JiraURL,
+		"JIRA_BASE_URL":       f.Jira.BaseURL,
+		"VAULT_ADDR":          f.Secrets.VaultAddr,
+
+		"AUDIT_LOG_OTLP_ENDPOINT": f.Evidence.AuditLog.OTLPEndpoint,
+		"REPLICATION_PRIMARY_URL": f.Evidence.Replication.PrimaryURL,
+
+		"APPROVER_DIRECTORY_URL":     f.Approvals.Directory.URL,
+		"APPROVER_OIDC_ISSUER":       f.Approvals.OIDC.Issuer,
+		"APPROVER_OIDC_REDIRECT_URL": f.Approvals.OIDC.RedirectURL,
+	} {
+		if v == "" {
+			continue
+		}
+		u, err := url.Parse(v)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "",
+			"%s: %q is not an http(s) URL", env, v)
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("config: invalid configuration: %w", errors.Join(errs...))
+}
+
+// ── Reflection helpers ───────────────────────────────────────────────────
+
+// walk calls fn for every env-tagged leaf field of the struct v.
+func walk(v reflect.Value, fn func(fv reflect.Value, sf reflect.StructField, env string)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf, fv := t.Field(i), v.Field(i)
+		if env := sf.Tag.Get("env"); env != "" {
+			fn(fv, sf, env)
+			continue
+		}
+		if fv.Kind() == reflect.Struct {
+			walk(fv, fn)
+		}
+	}
+}
+
+// formatField returns fv as its variable's value, and whether it is set: a
+// non-empty string or a non-nil number or boolean.
+func formatField(fv reflect.Value) (string, bool) {
+	switch fv.Kind() {
+	case reflect.String:
+		return fv.String(), fv.String() != ""
+	case reflect.Pointer:
+		if fv.IsNil() {
+			return "", false
+		}
+		if fv.Elem().Kind() == reflect.Int {
+			return strconv.FormatInt(fv.Elem().Int(), 10), true
+		}
+		return strconv.FormatBool(fv.Elem().Bool()), true
+	}
+	return "", false
+}
+
+func parseField(fv reflect.Value, env, v string) error {
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(v)
+	case reflect.Pointer:
+		if fv.Type().Elem().Kind() == reflect.Bool {
+			b, err := parseBool(v)
+			if err != nil {
+				return err
+			}
+			fv.Set(reflect.ValueOf(&b))
+			return nil
+		}
+		// Durations, named *_SEC or *_MS, may also be Go durations, as
+		// EnvOrDuration reads them.
+		if unit := durationUnit(env); unit != 0 {
+			d, err := parseDuration(v, unit)
+			if err != nil || d%unit != 0 {
+				return fmt.Errorf("%q is not a whole number of %s", v, unitName(unit))
+			}
+			n := int(d / unit)
+			fv.Set(reflect.ValueOf(&n))
+			return nil
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", v)
+		}
+		fv.Set(reflect.ValueOf(&n))
+	}
+	return nil
+}
+
+// durationUnit returns the unit of a duration variable, by its suffix, or
+// 0 for other variables.
+func durationUnit(env string) time.Duration {
+	switch {
+	case strings.HasSuffix(env, "_SEC"):
+		return time.Second
+	case strings.HasSuffix(env, "_MS"):
+		return time.Millisecond
+	}
+	return 0
+}
+
+func unitName(unit time.Duration) string {
+	if unit == time.Millisecond {
+		return "milliseconds"
+	}
+	return "seconds"
+}
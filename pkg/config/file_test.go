@@ -0,0 +1,301 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, name, body string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(p, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func intOf(p *int) int {
+	if p == nil {
+		return -1
+	}
+	return *p
+}
+
+func TestLoad_YAMLWithEnvOverride(t *testing.T) {
+	path := writeFile(t, "oc.yaml", `
+postgres:
+  host: db.internal
+  port: 6432
+  password: hunter2
+  pool:
+    max_conns: 30
+approvals:
+  notifier_enabled: false
+opa:
+  url: http://opa:8181
+`)
+	t.Setenv(FileEnv, path)
+	t.Setenv("POSTGRES_HOST", "override.internal")
+	// Register the remaining keys with t.Setenv so they are restored after
+	// the test, then clear them so the file supplies the values.
+	for _, k := range []string{"POSTGRES_PORT", "POSTGRES_PASSWORD", "PG_POOL_MAX_CONNS", "APPROVALS_NOTIFIER_ENABLED", "OPA_URL"} {
+		t.Setenv(k, "")
+		os.Unsetenv(k)
+	}
+
+	eff, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got := EnvOr("POSTGRES_HOST", ""); got != "override.internal" {
+		t.Errorf("env should win over file, POSTGRES_HOST = %q", got)
+	}
+	if got := EnvOrInt("POSTGRES_PORT", 0); got != 6432 {
+		t.Errorf("POSTGRES_PORT = %d, want 6432", got)
+	}
+	if got := os.Getenv("APPROVALS_NOTIFIER_ENABLED"); got != "false" {
+		t.Errorf("APPROVALS_NOTIFIER_ENABLED = %q, want false", got)
+	}
+	if intOf(eff.Postgres.Pool.MaxConns) != 30 || eff.OPA.URL != "http://opa:8181" {
+		t.Errorf("effective config not populated: %+v", eff)
+	}
+	settings := eff.Settings()
+	if settings["POSTGRES_PASSWORD"] != "********" {
+		t.Errorf("secret not masked: %q", settings["POSTGRES_PASSWORD"])
+	}
+	if settings["POSTGRES_HOST"] != "override.internal" {
+		t.Errorf("settings POSTGRES_HOST = %q", settings["POSTGRES_HOST"])
+	}
+}
+
+func TestLoadFile_TOML(t *testing.T) {
+	path := writeFile(t, "oc.toml", `
+[evidence]
+backend = "mysql"
+
+[mysql]
+dsn = "oc:pw@tcp(db:3306)/openclause"
+
+[archiver]
+run_once = true
+`)
+	f, err := LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Evidence.Backend != "mysql" || f.MySQL.DSN == "" || f.Archiver.RunOnce == nil || !*f.Archiver.RunOnce {
+		t.Fatalf("unexpected decode: %+v", f)
+	}
+	if err := f.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+}
+
+func TestLoadFile_RejectsUnknownKeys(t *testing.T) {
+	for name, body := range map[string]string{
+		"oc.yaml": "postgres:\n  hots: db\n",
+		"oc.toml": "[postgres]\nhots = \"db\"\n",
+	} {
+		if _, err := LoadFile(writeFile(t, name, body)); err == nil {
+			t.Errorf("%s: expected unknown-key error", name)
+		}
+	}
+	if _, err := LoadFile(writeFile(t, "oc.json", "{}")); err == nil {
+		t.Error("expected unsupported extension error")
+	}
+}
+
+func TestApply_KeepsExplicitZeros(t *testing.T) {
+	path := writeFile(t, "oc.toml", `
+[gateway]
+max_inflight = 0
+shed_target_latency_ms = 0
+`)
+	f, err := LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"GATEWAY_MAX_INFLIGHT", "GATEWAY_SHED_TARGET_LATENCY_MS", "RATE_LIMIT_PER_TENANT"} {
+		t.Setenv(k, "")
+		os.Unsetenv(k)
+	}
+	if err := f.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"GATEWAY_MAX_INFLIGHT", "GATEWAY_SHED_TARGET_LATENCY_MS"} {
+		if v, ok := os.LookupEnv(k); !ok || v != "0" {
+			t.Errorf("%s = %q (set %v), want 0", k, v, ok)
+		}
+	}
+	if _, ok := os.LookupEnv("RATE_LIMIT_PER_TENANT"); ok {
+		t.Error("RATE_LIMIT_PER_TENANT set though the file omits it")
+	}
+}
+
+func TestValidate_ReportsAllProblems(t *testing.T) {
+	f := &File{}
+	f.Evidence.Backend = "oracle"
+	f.Approvals.Backend = "mysql"
+	f.EventBus.Driver = "nats"
+	port := 70000
+	f.Postgres.Port = &port
+	f.OPA.URL = "opa:8181"
+	enabled := true
+	f.Creds.Enabled = &enabled
+	f.Tenants.DefaultConfig = "[1]"
+	f.Dashboard.Enabled = &enabled
+	f.Approvals.DigestsEnabled = &enabled
+	f.Approvals.Directory.Provider = "okta"
+	f.Approvals.OIDC.Issuer = "https://idp.example.com"
+	f.OTel.TracesSampler = "sometimes"
+	f.OTel.MetricsExporter = "prometheus,otlp"
+	f.Evidence.AuditLog.Sinks = "file,syslog"
+	f.Connectors.OutputSpill = &enabled
+
+	err := f.Validate()
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	for _, want := range []string{"EVIDENCE_BACKEND", "MYSQL_DSN", "EVENTBUS_URL", "POSTGRES_PORT", "OPA_URL", "CREDENTIALS_ENCRYPTION_KEYS", "TENANT_DEFAULT_CONFIG", "DASHBOARD_ENABLED", "APPROVALS_DIGESTS_ENABLED", "APPROVER_DIRECTORY_GROUPS", "APPROVER_DIRECTORY_URL", "APPROVER_OIDC_CLIENT_ID", "APPROVALS_SESSION_KEY", "OTEL_TRACES_SAMPLER", "OTEL_EXPORTER_OTLP_ENDPOINT", "AUDIT_LOG_SINKS", "BLOB_S3_BUCKET"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not mention %s: %v", want, err)
+		}
+	}
+}
+
+func TestFromEnv_RejectsMalformedValues(t *testing.T) {
+	t.Setenv("RATE_LIMIT_PER_TENANT", "lots")
+	t.Setenv("MOCK_CONNECTORS", "yes")
+	_, err := FromEnv()
+	if err == nil || !strings.Contains(err.Error(), "RATE_LIMIT_PER_TENANT") || !strings.Contains(err.Error(), "MOCK_CONNECTORS") {
+		t.Fatalf("expected both variables reported, got %v", err)
+	}
+}
+
+func TestFromEnv_Durations(t *testing.T) {
+	t.Setenv("SECRETS_REFRESH_SEC", "5m")
+	t.Setenv("OPA_RETRY_BASE_DELAY_MS", "1s")
+	t.Setenv("ARCHIVER_INTERVAL_SEC", "600")
+	f, err := FromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	refresh, delay, interval := intOf(f.Secrets.RefreshSec), intOf(f.OPA.RetryBaseDelayMS), intOf(f.Archiver.IntervalSec)
+	if refresh != 300 || delay != 1000 || interval != 600 {
+		t.Fatalf("durations = %d, %d, %d", refresh, delay, interval)
+	}
+	t.Setenv("SECRETS_REFRESH_SEC", "1500ms")
+	if _, err := FromEnv(); err == nil || !strings.Contains(err.Error(), "whole number of seconds") {
+		t.Fatalf("fractional seconds accepted: %v", err)
+	}
+}
+
+func TestLoad_ReportsEverySetting(t *testing.T) {
+	t.Setenv(FileEnv, "")
+	t.Setenv("RATE_LIMIT_PER_TENANT", "lots")
+	t.Setenv("EVIDENCE_BACKEND", "oracle")
+	t.Setenv("INTERNAL_AUTH_TOKEN", "")
+	_, err := Load("INTERNAL_AUTH_TOKEN")
+	for _, want := range []string{"RATE_LIMIT_PER_TENANT", "EVIDENCE_BACKEND", "INTERNAL_AUTH_TOKEN: required"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not mention %s: %v", want, err)
+		}
+	}
+}
+
+func TestLoad_ZeroOnlyWhereAllowed(t *testing.T) {
+	t.Setenv(FileEnv, "")
+	t.Setenv("GATEWAY_SHED_TARGET_LATENCY_MS", "0")
+	t.Setenv("GATEWAY_MAX_INFLIGHT", "0")
+	if _, err := Load(); err != nil {
+		t.Fatalf("0 where it disables a feature: %v", err)
+	}
+	if got := EnvOrDuration("GATEWAY_SHED_TARGET_LATENCY_MS", time.Millisecond, 2*time.Second); got != 0 {
+		t.Fatalf("GATEWAY_SHED_TARGET_LATENCY_MS = %v, want 0", got)
+	}
+	if got := EnvOrInt("GATEWAY_MAX_INFLIGHT", 512); got != 0 {
+		t.Fatalf("GATEWAY_MAX_INFLIGHT = %d, want 0", got)
+	}
+
+	t.Setenv("SCHEDULER_POLL_SEC", "0")
+	t.Setenv("RATE_LIMIT_PER_TENANT", "-1")
+	_, err := Load()
+	for _, want := range []string{"SCHEDULER_POLL_SEC: must be positive", "RATE_LIMIT_PER_TENANT: must not be negative"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not mention %q: %v", want, err)
+		}
+	}
+}
+
+func TestLoadFile_ExampleIsValid(t *testing.T) {
+	for _, name := range []string{"openclause.example.yaml", "openclause.allinone.yaml", "openclause.lite.yaml"} {
+		f, err := LoadFile("../../deploy/config/" + name)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if err := f.Validate(); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestLoad_LiteModeDefaults(t *testing.T) {
+	t.Setenv(FileEnv, writeFile(t, "oc.yaml", "mode: lite\n"))
+	for _, kv := range liteDefaults {
+		t.Setenv(kv[0], "")
+		os.Unsetenv(kv[0])
+	}
+	t.Setenv("POLICY_ENGINE", "opa")
+
+	eff, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if eff.Evidence.Backend != "sqlite" || eff.Approvals.Backend != "sqlite" || eff.Archiver.Dir == "" {
+		t.Errorf("lite defaults not applied: %+v", eff)
+	}
+	if eff.Policy.Engine != "opa" {
+		t.Errorf("env should win over lite defaults, POLICY_ENGINE = %q", eff.Policy.Engine)
+	}
+
+	f := &File{Mode: LiteMode}
+	f.Evidence.Backend = "postgres"
+	f.Auth.AdminToken = "admin"
+	err = f.Validate()
+	for _, want := range []string{"OC_MODE", "ADMIN_API_TOKEN"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not mention %s: %v", want, err)
+		}
+	}
+}
+
+func TestResolve_LeavesEnvironmentUnchanged(t *testing.T) {
+	path := writeFile(t, "oc.yaml", `
+postgres:
+  host: db.internal
+  port: 6432
+`)
+	t.Setenv("POSTGRES_PORT", "7432")
+	t.Setenv("POSTGRES_HOST", "")
+	os.Unsetenv("POSTGRES_HOST")
+
+	eff, env, err := Resolve(path)
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if _, ok := os.LookupEnv("POSTGRES_HOST"); ok {
+		t.Error("Resolve exported POSTGRES_HOST")
+	}
+	if got := env.Get("POSTGRES_HOST"); got != "db.internal" {
+		t.Errorf("POSTGRES_HOST = %q, want db.internal", got)
+	}
+	if got := env.Int("POSTGRES_PORT", 0); got != 7432 {
+		t.Errorf("env should win over file, POSTGRES_PORT = %d", got)
+	}
+	if eff.Postgres.Host != "db.internal" || intOf(eff.Postgres.Port) != 7432 {
+		t.Errorf("effective config = %+v", eff.Postgres)
+	}
+}
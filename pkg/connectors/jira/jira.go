@@ -0,0 +1,455 @@
+// Package jira is the Jira connector: it creates, lists and deletes issues
+// on the deployment's Jira site or a tenant's own. cmd/connector-jira serves
+// it on its own; cmd/openclause serves it in-process.
+package jira
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/bturcanu/OpenClause/pkg/connectors"
+	"github.com/bturcanu/OpenClause/pkg/connectors/sdk"
+	"github.com/bturcanu/OpenClause/pkg/credentials"
+	ocOtel "github.com/bturcanu/OpenClause/pkg/otel"
+	"github.com/bturcanu/OpenClause/pkg/secrets"
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+const maxBodyBytes = 1 << 20
+
+// Config configures a Connector.
+type Config struct {
+	Logger *slog.Logger
+	// Mock answers calls with canned output instead of calling Jira.
+	Mock bool
+	// BaseURL, Email and APIToken are the deployment-wide account,
+	// JIRA_BASE_URL, JIRA_EMAIL and JIRA_API_TOKEN.
+	BaseURL  string
+	Email    string
+	APIToken *secrets.Value
+	// Creds, when set, supplies tenants' own accounts.
+	Creds *credentials.Resolver
+	// InternalToken is the X-Internal-Token callers must send.
+	InternalToken string
+}
+
+// New creates a Jira connector.
+func New(cfg Config) *Connector {
+	log := cfg.Logger
+	if log == nil {
+		log = slog.Default()
+	}
+	return &Connector{
+		log:           log,
+		mock:          cfg.Mock,
+		baseURL:       cfg.BaseURL,
+		email:         cfg.Email,
+		apiToken:      cfg.APIToken,
+		creds:         cfg.Creds,
+		internalToken: cfg.InternalToken,
+		// Calls are bounded by each request's Context instead.
+		httpClient: &http.Client{},
+	}
+}
+
+// Handler serves the connector API: /healthz, /manifest and /exec.
+func (j *Connector) Handler() http.Handler {
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(ocOtel.Middleware)
+	r.Use(middleware.Recoverer)
+
+	r.Get("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("OK"))
+	})
+
+	r.Get("/manifest", sdk.ManifestHandler(manifest, sdk.Config{InternalToken: j.internalToken}))
+
+	identity := sdk.Config{Name: "connector-jira", Endpoint: j.baseURL}
+	if j.mock {
+		identity.Endpoint = "mock"
+	}
+
+	r.Post("/exec", func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Internal-Token")), []byte(j.internalToken)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+		var req connectors.ExecRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid body", http.StatusBadRequest)
+			return
+		}
+
+		resp := j.Exec(r.Context(), req)
+		if !req.Plan {
+			identity.Identify(&resp)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			j.log.Error("response encode failed", "error", err)
+		}
+	})
+	return r
+}
+
+// Connector is the Jira connector.
+type Connector struct {
+	log           *slog.Logger
+	mock          bool
+	baseURL       string
+	email         string
+	apiToken      *secrets.Value
+	creds         *credentials.Resolver
+	internalToken string
+	httpClient    *http.Client
+}
+
+// account returns the Jira site and Authorization header for a tenant. A
+// tenant with its own api_token may also override base_url and email; a
+// tenant without one always uses the deployment-wide account, so a stored
+// base_url can never receive the shared token.
+func (j *Connector) account(ctx context.Context, tenantID string) (baseURL, authorization string, err error) {
+	baseURL, email, token := j.baseURL, j.email, j.apiToken.Get()
+	tenantToken, err := j.creds.Lookup(ctx, tenantID, "jira", "api_token", "")
+	if err != nil {
+		return "", "", err
+	}
+	if tenantToken != "" {
+		token = tenantToken
+		if email, err = j.creds.Lookup(ctx, tenantID, "jira", "email", email); err != nil {
+			return "", "", err
+		}
+		if baseURL, err = j.creds.Lookup(ctx, tenantID, "jira", "base_url", baseURL); err != nil {
+			return "", "", err
+		}
+		if u, perr := url.Parse(baseURL); perr != nil || u.Scheme != "https" || u.Host == "" {
+			return "", "", fmt.Errorf("jira base_url for tenant %s must be an https URL", tenantID)
+		}
+	}
+	if baseURL == "" || email == "" || token == "" {
+		return "", "", fmt.Errorf("no Jira account configured for tenant %s", tenantID)
+	}
+	return strings.TrimRight(baseURL, "/"), "Basic " + base64.StdEncoding.EncodeToString([]byte(email+":"+token)), nil
+}
+
+// manifest lists the actions the connector implements.
+var manifest = connectors.Manifest{
+	Tool: "jira",
+	Plan: true,
+	Actions: []connectors.ActionManifest{
+		{
+			Action:      "issue.create",
+			Description: "Create a Jira issue.",
+			Params: json.RawMessage(`{"type":"object","properties":{` +
+				`"project":{"type":"string","description":"Project key, e.g. OPS"},` +
+				`"summary":{"type":"string"},` +
+				`"description":{"type":"string"},` +
+				`"issue_type":{"type":"string","description":"Issue type name; defaults to Task"}},` +
+				`"required":["project","summary"]}`),
+		},
+		{
+			Action:      "issue.list",
+			Description: "List recent Jira issues.",
+			Params:      json.RawMessage(`{"type":"object","properties":{}}`),
+			ReadOnly:    true,
+		},
+		{
+			Action:      "issue.delete",
+			Description: "Delete a Jira issue.",
+			Params: json.RawMessage(`{"type":"object","properties":{` +
+				`"issue_key":{"type":"string","description":"Issue key, e.g. OPS-1"}},` +
+				`"required":["issue_key"]}`),
+		},
+	},
+}
+
+type jiraIssueParams struct {
+	Project     string `json:"project"`
+	Summary     string `json:"summary"`
+	Description string `json:"description,omitempty"`
+	IssueType   string `json:"issue_type"`
+}
+
+// Exec performs req, within the time the gateway waits for it, and records
+// the Jira site it went to, which differs from JIRA_BASE_URL for tenants
+// with their own account.
+func (j *Connector) Exec(ctx context.Context, req connectors.ExecRequest) connectors.ExecResponse {
+	ctx, cancel := req.Context(ctx)
+	defer cancel()
+	resp := j.exec(ctx, req)
+	if !j.mock && !req.Plan {
+		if baseURL, _, err := j.account(ctx, req.TenantID); err == nil {
+			resp.Connector = &connectors.ConnectorInfo{Endpoint: baseURL}
+		}
+	}
+	return resp
+}
+
+func (j *Connector) exec(ctx context.Context, req connectors.ExecRequest) connectors.ExecResponse {
+	action := req.Tool + "." + req.Action
+	if req.Plan {
+		return j.plan(req)
+	}
+	switch action {
+	case "jira.issue.create":
+		return j.createIssue(ctx, req)
+	case "jira.issue.list":
+		return j.listIssues(ctx, req)
+	case "jira.issue.delete":
+		return j.deleteIssue(ctx, req)
+	default:
+		return connectors.ExecResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("unsupported action: %s", action),
+		}
+	}
+}
+
+// plan describes a request without calling Jira.
+func (j *Connector) plan(req connectors.ExecRequest) connectors.ExecResponse {
+	switch action := req.Tool + "." + req.Action; action {
+	case "jira.issue.create":
+		params, errResp := issueParams(req)
+		if errResp != nil {
+			return *errResp
+		}
+		return connectors.Planned(connectors.ExecPlan{
+			Summary:   fmt.Sprintf("Create %s in %s: %s", params.IssueType, params.Project, params.Summary),
+			Operation: "POST /rest/api/3/issue",
+			Fields: map[string]any{
+				"project":     params.Project,
+				"issue_type":  params.IssueType,
+				"summary":     params.Summary,
+				"description": params.Description,
+			},
+		})
+	case "jira.issue.list":
+		return connectors.Planned(connectors.ExecPlan{
+			Summary:   "List up to 20 issues",
+			Operation: "GET /rest/api/3/search",
+			ReadOnly:  true,
+		})
+	case "jira.issue.delete":
+		params, errResp := deleteParams(req)
+		if errResp != nil {
+			return *errResp
+		}
+		return connectors.Planned(connectors.ExecPlan{
+			Summary:   "Delete " + params.IssueKey,
+			Operation: "DELETE /rest/api/3/issue/" + url.PathEscape(params.IssueKey),
+			Fields:    map[string]any{"issue_key": params.IssueKey},
+		})
+	default:
+		return connectors.ExecResponse{Status: "error", Error: fmt.Sprintf("unsupported action: %s", action)}
+	}
+}
+
+func (j *Connector) listIssues(ctx context.Context, req connectors.ExecRequest) connectors.ExecResponse {
+	if j.mock {
+		output, _ := json.Marshal(map[string]any{
+			"issues": []map[string]any{
+				{"id": "10001", "key": "OPS-1", "summary": "Mock issue 1"},
+				{"id": "10002", "key": "OPS-2", "summary": "Mock issue 2"},
+			},
+			"total": 2,
+			"mock":  true,
+		})
+		return connectors.ExecResponse{Status: "success", OutputJSON: output}
+	}
+	baseURL, authorization, err := j.account(ctx, req.TenantID)
+	if err != nil {
+		return connectors.ExecResponse{Status: "error", Error: err.Error()}
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", baseURL+"/rest/api/3/search?maxResults=20", nil)
+	if err != nil {
+		return connectors.ExecResponse{Status: "error", Error: err.Error()}
+	}
+	httpReq.Header.Set("Authorization", authorization)
+	resp, err := j.httpClient.Do(httpReq)
+	if err != nil {
+		return connectors.ExecResponse{Status: "error", Error: err.Error()}
+	}
+	defer resp.Body.Close()
+	respBody, err := connectors.ReadLimited(resp.Body, req.OutputLimit())
+	if err != nil {
+		return connectors.ExecResponse{Status: "error", Error: "read response: " + err.Error()}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return connectors.ExecResponse{Status: "error", Error: string(respBody)}
+	}
+	return connectors.ExecResponse{Status: "success", OutputJSON: respBody}
+}
+
+// issueParams decodes and defaults issue.create params.
+func issueParams(req connectors.ExecRequest) (jiraIssueParams, *connectors.ExecResponse) {
+	var params jiraIssueParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return params, &connectors.ExecResponse{Status: "error", Error: "invalid params: " + err.Error()}
+	}
+
+	if params.Project == "" || params.Summary == "" {
+		return params, &connectors.ExecResponse{Status: "error", Error: "project and summary are required"}
+	}
+	if params.IssueType == "" {
+		params.IssueType = "Task"
+	}
+	return params, nil
+}
+
+func (j *Connector) createIssue(ctx context.Context, req connectors.ExecRequest) connectors.ExecResponse {
+	params, errResp := issueParams(req)
+	if errResp != nil {
+		return *errResp
+	}
+
+	if j.mock {
+		j.log.Info("mock jira.issue.create", "project", params.Project, "summary", params.Summary)
+		output, _ := json.Marshal(map[string]any{
+			"id":   "10001",
+			"key":  params.Project + "-42",
+			"self": "https://mock.atlassian.net/rest/api/3/issue/10001",
+			"mock": true,
+		})
+		return createdIssue(output)
+	}
+
+	issueBody := map[string]any{
+		"fields": map[string]any{
+			"project":   map[string]string{"key": params.Project},
+			"summary":   params.Summary,
+			"issuetype": map[string]string{"name": params.IssueType},
+		},
+	}
+	if params.Description != "" {
+		fields := issueBody["fields"].(map[string]any)
+		fields["description"] = map[string]any{
+			"type":    "doc",
+			"version": 1,
+			"content": []any{
+				map[string]any{
+					"type": "paragraph",
+					"content": []any{
+						map[string]any{
+							"type": "text",
+							"text": params.Description,
+						},
+					},
+				},
+			},
+		}
+	}
+
+	body, _ := json.Marshal(issueBody)
+	baseURL, authorization, err := j.account(ctx, req.TenantID)
+	if err != nil {
+		return connectors.ExecResponse{Status: "error", Error: err.Error()}
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/rest/api/3/issue", bytes.NewReader(body))
+	if err != nil {
+		return connectors.ExecResponse{Status: "error", Error: err.Error()}
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", authorization)
+
+	resp, err := j.httpClient.Do(httpReq)
+	if err != nil {
+		return connectors.ExecResponse{Status: "error", Error: err.Error()}
+	}
+	defer resp.Body.Close()
+
+	respBody, err := connectors.ReadLimited(resp.Body, req.OutputLimit())
+	if err != nil {
+		return connectors.ExecResponse{Status: "error", Error: "read response: " + err.Error()}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return connectors.ExecResponse{Status: "error", Error: string(respBody)}
+	}
+
+	return createdIssue(respBody)
+}
+
+// createdIssue is the issue.create response, declaring issue.delete of the
+// new issue, jira://project/<key prefix>/issue/<key>, as its compensation.
+func createdIssue(output []byte) connectors.ExecResponse {
+	resp := connectors.ExecResponse{Status: "success", OutputJSON: output}
+	var created struct {
+		Key string `json:"key"`
+	}
+	if err := json.Unmarshal(output, &created); err == nil && created.Key != "" {
+		params, _ := json.Marshal(jiraDeleteParams{IssueKey: created.Key})
+		project, _, _ := strings.Cut(created.Key, "-")
+		resp.Compensation = &connectors.Compensation{
+			Action:   "issue.delete",
+			Params:   params,
+			Resource: types.NewResourceURI("jira", "project", project, "issue", created.Key).String(),
+		}
+	}
+	return resp
+}
+
+type jiraDeleteParams struct {
+	IssueKey string `json:"issue_key"`
+}
+
+func deleteParams(req connectors.ExecRequest) (jiraDeleteParams, *connectors.ExecResponse) {
+	var params jiraDeleteParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return params, &connectors.ExecResponse{Status: "error", Error: "invalid params: " + err.Error()}
+	}
+	if params.IssueKey == "" {
+		return params, &connectors.ExecResponse{Status: "error", Error: "issue_key is required"}
+	}
+	return params, nil
+}
+
+func (j *Connector) deleteIssue(ctx context.Context, req connectors.ExecRequest) connectors.ExecResponse {
+	params, errResp := deleteParams(req)
+	if errResp != nil {
+		return *errResp
+	}
+
+	if j.mock {
+		j.log.Info("mock jira.issue.delete", "issue_key", params.IssueKey)
+		output, _ := json.Marshal(map[string]any{"key": params.IssueKey, "deleted": true, "mock": true})
+		return connectors.ExecResponse{Status: "success", OutputJSON: output}
+	}
+
+	baseURL, authorization, err := j.account(ctx, req.TenantID)
+	if err != nil {
+		return connectors.ExecResponse{Status: "error", Error: err.Error()}
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, "DELETE", baseURL+"/rest/api/3/issue/"+url.PathEscape(params.IssueKey), nil)
+	if err != nil {
+		return connectors.ExecResponse{Status: "error", Error: err.Error()}
+	}
+	httpReq.Header.Set("Authorization", authorization)
+	resp, err := j.httpClient.Do(httpReq)
+	if err != nil {
+		return connectors.ExecResponse{Status: "error", Error: err.Error()}
+	}
+	defer resp.Body.Close()
+	respBody, err := connectors.ReadLimited(resp.Body, req.OutputLimit())
+	if err != nil {
+		return connectors.ExecResponse{Status: "error", Error: "read response: " + err.Error()}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return connectors.ExecResponse{Status: "error", Error: string(respBody)}
+	}
+	output, _ := json.Marshal(map[string]any{"key": params.IssueKey, "deleted": true})
+	return connectors.ExecResponse{Status: "success", OutputJSON: output}
+}
@@ -0,0 +1,34 @@
+package jira
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/bturcanu/OpenClause/pkg/connectors"
+)
+
+func TestHandler_MockInProcess(t *testing.T) {
+	local := connectors.NewLocal(nil)
+	reg := connectors.NewRegistry()
+	reg.SetTransport(local)
+	reg.Register("jira", local.Handle("connector-jira", New(Config{Mock: true, InternalToken: "secret"}).Handler()))
+
+	req := connectors.ExecRequest{Tool: "jira", Action: "issue.create", Params: json.RawMessage(`{"project":"OPS","summary":"Disk full","issue_type":"Task"}`)}
+	if _, err := reg.Exec(context.Background(), req); err == nil || !strings.Contains(err.Error(), "HTTP 401") {
+		t.Fatalf("call without the internal token: err = %v", err)
+	}
+
+	reg.SetInternalToken("secret")
+	resp, err := reg.Exec(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != "success" || !strings.Contains(string(resp.OutputJSON), `"OPS-42"`) {
+		t.Fatalf("resp = %+v", resp)
+	}
+	if c := resp.Connector; c.Name != "connector-jira" || c.Endpoint != "mock" || c.Backend != "inproc://connector-jira" {
+		t.Fatalf("connector = %+v", c)
+	}
+}
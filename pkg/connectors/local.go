@@ -0,0 +1,93 @@
+package connectors
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// LocalScheme is the URL scheme of connectors served in-process by a Local
+// transport, e.g. "inproc://slack".
+const LocalScheme = "inproc"
+
+// Local is an http.RoundTripper that serves inproc:// URLs from handlers in
+// the same process, so a single binary can route connector calls without a
+// network hop. Other URLs go to next.
+type Local struct {
+	next http.RoundTripper
+
+	mu       sync.RWMutex
+	handlers map[string]http.Handler // host → handler
+}
+
+// NewLocal creates a Local transport; next serves every other scheme and
+// defaults to http.DefaultTransport.
+func NewLocal(next http.RoundTripper) *Local {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Local{next: next, handlers: make(map[string]http.Handler)}
+}
+
+// Handle serves inproc://host from h and returns that base URL.
+func (l *Local) Handle(host string, h http.Handler) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.handlers[host] = h
+	return LocalScheme + "://" + host
+}
+
+// RoundTrip implements http.RoundTripper. The whole response is buffered,
+// which suits connector replies; they are bounded by the caller anyway.
+func (l *Local) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme != LocalScheme {
+		return l.next.RoundTrip(req)
+	}
+	l.mu.RLock()
+	h, ok := l.handlers[req.URL.Host]
+	l.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("connectors: no in-process connector %q", req.URL.Host)
+	}
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+	rec := &localResponse{header: http.Header{}}
+	h.ServeHTTP(rec, req)
+	if rec.code == 0 {
+		rec.code = http.StatusOK
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rec.code, http.StatusText(rec.code)),
+		StatusCode:    rec.code,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        rec.header,
+		Body:          io.NopCloser(&rec.body),
+		ContentLength: int64(rec.body.Len()),
+		Request:       req,
+	}, nil
+}
+
+// localResponse is the http.ResponseWriter a Local handler writes to.
+type localResponse struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+}
+
+func (r *localResponse) Header() http.Header { return r.header }
+
+func (r *localResponse) WriteHeader(code int) {
+	if r.code == 0 {
+		r.code = code
+	}
+}
+
+func (r *localResponse) Write(p []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	return r.body.Write(p)
+}
@@ -0,0 +1,100 @@
+package connectors
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const maxManifestBytes = 1 << 20 // 1 MB
+
+// Manifest is a connector's capability manifest, served at GET /manifest:
+// the actions it implements and the params each one takes. Plan is set by
+// connectors that honor ExecRequest.Plan, describing a call without
+// performing it; plan requests are only sent to those.
+type Manifest struct {
+	Tool    string           `json:"tool"`
+	Actions []ActionManifest `json:"actions"`
+	Plan    bool             `json:"plan,omitempty"`
+}
+
+// ActionManifest describes one action. Params is a JSON Schema object for
+// the call's params. Internal actions are called by OpenClause services
+// rather than agents and are left out of generated tool specs.
+type ActionManifest struct {
+	Action      string          `json:"action"`
+	Description string          `json:"description"`
+	Params      json.RawMessage `json:"params,omitempty"`
+	ReadOnly    bool            `json:"read_only,omitempty"`
+	Internal    bool            `json:"internal,omitempty"`
+}
+
+// Manifests fetches the manifest of every registered backend that takes
+// traffic, concurrently. A backend that fails is reported in errs, keyed by
+// URL, rather than failing the whole call. Manifests are sorted by tool.
+func (r *Registry) Manifests(ctx context.Context) (manifests []Manifest, errs map[string]string) {
+	r.mu.RLock()
+	urls := make(map[string]bool)
+	for _, bs := range r.routes {
+		for _, b := range bs {
+			if b.Weight > 0 {
+				urls[b.URL] = true
+			}
+		}
+	}
+	client, token := r.httpClient, r.internalToken
+	r.mu.RUnlock()
+
+	errs = make(map[string]string)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for u := range urls {
+		wg.Add(1)
+		go func(u string) {
+			defer wg.Done()
+			m, err := fetchManifest(ctx, client, token, u)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs[u] = err.Error()
+				return
+			}
+			manifests = append(manifests, *m)
+		}(u)
+	}
+	wg.Wait()
+	sort.Slice(manifests, func(i, j int) bool { return manifests[i].Tool < manifests[j].Tool })
+	return manifests, errs
+}
+
+func fetchManifest(ctx context.Context, client *http.Client, token, baseURL string) (*Manifest, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(baseURL, "/")+"/manifest", nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("X-Internal-Token", token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	var m Manifest
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxManifestBytes)).Decode(&m); err != nil {
+		return nil, fmt.Errorf("decode manifest: %w", err)
+	}
+	if m.Tool == "" {
+		return nil, fmt.Errorf("manifest names no tool")
+	}
+	return &m, nil
+}
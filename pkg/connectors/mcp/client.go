@@ -0,0 +1,302 @@
+// Package mcp proxies upstream Model Context Protocol servers as connectors:
+// each server's tools become actions of one governed tool, so calls to them
+// go through policy, approvals and evidence like any other tool call.
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ProtocolVersion is the MCP revision the client speaks (Streamable HTTP).
+const ProtocolVersion = "2025-03-26"
+
+const maxResponseBytes = 4 << 20 // 4 MB
+
+// errSessionExpired is returned when the server no longer knows the session.
+var errSessionExpired = errors.New("mcp session expired")
+
+// Tool is one tool listed by an MCP server.
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"inputSchema,omitempty"`
+	Annotations *struct {
+		ReadOnlyHint bool `json:"readOnlyHint,omitempty"`
+	} `json:"annotations,omitempty"`
+}
+
+// CallResult is the result of tools/call. IsError marks a tool-level failure,
+// as opposed to a protocol error.
+type CallResult struct {
+	Content           []json.RawMessage `json:"content"`
+	StructuredContent json.RawMessage   `json:"structuredContent,omitempty"`
+	IsError           bool              `json:"isError,omitempty"`
+}
+
+// Text joins the result's text content blocks.
+func (r *CallResult) Text() string {
+	var parts []string
+	for _, c := range r.Content {
+		var block struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		}
+		if json.Unmarshal(c, &block) == nil && block.Type == "text" && block.Text != "" {
+			parts = append(parts, block.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// Client talks JSON-RPC to one MCP server over Streamable HTTP. It
+// initializes a session on first use and again if the server drops it.
+// Thread-safe.
+type Client struct {
+	url        string
+	token      func() string
+	httpClient *http.Client
+	nextID     atomic.Int64
+
+	mu        sync.Mutex
+	session   string
+	initiated bool
+}
+
+// NewClient creates a client for the server at url. token, if set, returns
+// the bearer token sent with every request.
+func NewClient(url string, token func() string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{url: url, token: token, httpClient: httpClient}
+}
+
+// ListTools returns every tool the server offers, following pagination.
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	var tools []Tool
+	cursor := ""
+	for {
+		params := map[string]any{}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+		var page struct {
+			Tools      []Tool `json:"tools"`
+			NextCursor string `json:"nextCursor"`
+		}
+		if err := c.call(ctx, "tools/list", params, &page); err != nil {
+			return nil, fmt.Errorf("mcp.ListTools: %w", err)
+		}
+		tools = append(tools, page.Tools...)
+		if page.NextCursor == "" || page.NextCursor == cursor {
+			return tools, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// CallTool invokes a tool with the given arguments (a JSON object).
+func (c *Client) CallTool(ctx context.Context, name string, arguments json.RawMessage) (*CallResult, error) {
+	if len(arguments) == 0 || string(arguments) == "null" {
+		arguments = json.RawMessage(`{}`)
+	}
+	var res CallResult
+	if err := c.call(ctx, "tools/call", map[string]any{"name": name, "arguments": arguments}, &res); err != nil {
+		return nil, fmt.Errorf("mcp.CallTool: %w", err)
+	}
+	return &res, nil
+}
+
+// Ping checks that the server answers.
+func (c *Client) Ping(ctx context.Context) error {
+	if err := c.call(ctx, "ping", map[string]any{}, nil); err != nil {
+		return fmt.Errorf("mcp.Ping: %w", err)
+	}
+	return nil
+}
+
+// call sends a request within an initialized session, re-initializing once
+// if the server has expired the session.
+func (c *Client) call(ctx context.Context, method string, params, out any) error {
+	if err := c.ensureSession(ctx); err != nil {
+		return err
+	}
+	err := c.roundTrip(ctx, method, params, out)
+	if errors.Is(err, errSessionExpired) {
+		c.mu.Lock()
+		c.initiated, c.session = false, ""
+		c.mu.Unlock()
+		if err := c.ensureSession(ctx); err != nil {
+			return err
+		}
+		err = c.roundTrip(ctx, method, params, out)
+	}
+	return err
+}
+
+func (c *Client) ensureSession(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.initiated {
+		return nil
+	}
+	var res struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	session, err := c.send(ctx, "", "initialize", map[string]any{
+		"protocolVersion": ProtocolVersion,
+		"capabilities":    map[string]any{},
+		"clientInfo":      map[string]string{"name": "openclause", "version": "1"},
+	}, &res)
+	if err != nil {
+		return fmt.Errorf("initialize: %w", err)
+	}
+	if _, err := c.send(ctx, session, "notifications/initialized", nil, nil); err != nil {
+		return fmt.Errorf("initialized notification: %w", err)
+	}
+	c.session, c.initiated = session, true
+	return nil
+}
+
+func (c *Client) roundTrip(ctx context.Context, method string, params, out any) error {
+	c.mu.Lock()
+	session := c.session
+	c.mu.Unlock()
+	_, err := c.send(ctx, session, method, params, out)
+	return err
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string { return fmt.Sprintf("json-rpc error %d: %s", e.Code, e.Message) }
+
+type rpcResponse struct {
+	ID     json.RawMessage `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+}
+
+// send posts one JSON-RPC message and decodes the result into out. A method
+// under "notifications/" is sent without an id and expects no result. It
+// returns the session ID the server assigned, if any.
+func (c *Client) send(ctx context.Context, session, method string, params, out any) (string, error) {
+	msg := map[string]any{"jsonrpc": "2.0", "method": method}
+	if params != nil {
+		msg["params"] = params
+	}
+	notify := strings.HasPrefix(method, "notifications/")
+	var id int64
+	if !notify {
+		id = c.nextID.Add(1)
+		msg["id"] = id
+	}
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	if session != "" {
+		req.Header.Set("Mcp-Session-Id", session)
+		req.Header.Set("Mcp-Protocol-Version", ProtocolVersion)
+	}
+	if c.token != nil {
+		if t := c.token(); t != "" {
+			req.Header.Set("Authorization", "Bearer "+t)
+		}
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	newSession := resp.Header.Get("Mcp-Session-Id")
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound && session != "":
+		return "", errSessionExpired
+	case notify && (resp.StatusCode == http.StatusAccepted || resp.StatusCode == http.StatusOK):
+		return newSession, nil
+	case resp.StatusCode != http.StatusOK:
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		return "", fmt.Errorf("%s: HTTP %d: %s", method, resp.StatusCode, strings.TrimSpace(string(snippet)))
+	}
+
+	rpc, err := readResponse(resp, id)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", method, err)
+	}
+	if rpc.Error != nil {
+		return "", rpc.Error
+	}
+	if out != nil {
+		if err := json.Unmarshal(rpc.Result, out); err != nil {
+			return "", fmt.Errorf("%s: decode result: %w", method, err)
+		}
+	}
+	return newSession, nil
+}
+
+// readResponse reads the JSON-RPC response with the given id from a JSON
+// body or an SSE stream, skipping any server requests or notifications
+// that precede it on the stream.
+func readResponse(resp *http.Response, id int64) (*rpcResponse, error) {
+	body := io.LimitReader(resp.Body, maxResponseBytes)
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/event-stream" {
+		var rpc rpcResponse
+		if err := json.NewDecoder(body).Decode(&rpc); err != nil {
+			return nil, fmt.Errorf("decode response: %w", err)
+		}
+		return &rpc, nil
+	}
+
+	want := fmt.Sprint(id)
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64<<10), maxResponseBytes)
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		if v, ok := strings.CutPrefix(line, "data:"); ok {
+			data.WriteString(strings.TrimPrefix(v, " "))
+			continue
+		}
+		if line != "" || data.Len() == 0 {
+			continue
+		}
+		// A blank line ends the event.
+		var rpc rpcResponse
+		if json.Unmarshal([]byte(data.String()), &rpc) == nil && string(rpc.ID) == want {
+			return &rpc, nil
+		}
+		data.Reset()
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read event stream: %w", err)
+	}
+	if data.Len() > 0 {
+		var rpc rpcResponse
+		if json.Unmarshal([]byte(data.String()), &rpc) == nil && string(rpc.ID) == want {
+			return &rpc, nil
+		}
+	}
+	return nil, errors.New("event stream ended without a response")
+}
@@ -0,0 +1,177 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/bturcanu/OpenClause/pkg/connectors"
+)
+
+// invalidActionChars are the characters an MCP tool name may contain that a
+// governed action may not.
+var invalidActionChars = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// maxActionLen is the longest action the gateway accepts.
+const maxActionLen = 64
+
+// ActionName maps an MCP tool name to the action it is exposed as: lowercased,
+// with characters outside [a-z0-9._-] replaced by "_".
+func ActionName(mcpTool string) string {
+	a := invalidActionChars.ReplaceAllString(strings.ToLower(mcpTool), "_")
+	a = strings.TrimLeft(a, "._-")
+	if len(a) > maxActionLen {
+		a = a[:maxActionLen]
+	}
+	return a
+}
+
+// Server exposes one upstream MCP server as tool Tool: a call of
+// Tool.<action> invokes the MCP tool the action was named after, with the
+// call's params as its arguments. It implements sdk.Executor.
+type Server struct {
+	Tool   string
+	client *Client
+
+	mu    sync.Mutex
+	tools map[string]Tool // action → MCP tool
+	order []string        // actions in the order the server listed them
+}
+
+// NewServer exposes the MCP server behind client as tool.
+func NewServer(tool string, client *Client) *Server {
+	return &Server{Tool: tool, client: client}
+}
+
+// refresh reloads the server's tool list. When two MCP tools map to the
+// same action, the first listed wins.
+func (s *Server) refresh(ctx context.Context) error {
+	tools, err := s.client.ListTools(ctx)
+	if err != nil {
+		return err
+	}
+	byAction := make(map[string]Tool, len(tools))
+	var order []string
+	for _, t := range tools {
+		a := ActionName(t.Name)
+		if _, dup := byAction[a]; dup || a == "" {
+			continue
+		}
+		byAction[a] = t
+		order = append(order, a)
+	}
+	s.mu.Lock()
+	s.tools, s.order = byAction, order
+	s.mu.Unlock()
+	return nil
+}
+
+// lookup returns the MCP tool behind action, listing the server's tools if
+// they are not loaded or do not include action.
+func (s *Server) lookup(ctx context.Context, action string) (Tool, bool, error) {
+	s.mu.Lock()
+	t, ok := s.tools[action]
+	s.mu.Unlock()
+	if ok {
+		return t, true, nil
+	}
+	if err := s.refresh(ctx); err != nil {
+		return Tool{}, false, err
+	}
+	s.mu.Lock()
+	t, ok = s.tools[action]
+	s.mu.Unlock()
+	return t, ok, nil
+}
+
+// Exec invokes the MCP tool named by req.Action. The output is the tool's
+// content blocks and, when present, its structured content, truncated when
+// over req's output limit; a tool that reports an error yields status
+// "error" with its text.
+func (s *Server) Exec(ctx context.Context, req connectors.ExecRequest) connectors.ExecResponse {
+	if req.Tool != s.Tool {
+		return connectors.ExecResponse{Status: "error", Error: fmt.Sprintf("this connector serves %s, not %s", s.Tool, req.Tool)}
+	}
+	t, ok, err := s.lookup(ctx, req.Action)
+	if err != nil {
+		return connectors.ExecResponse{Status: "error", Error: err.Error()}
+	}
+	if !ok {
+		return connectors.ExecResponse{Status: "error", Error: fmt.Sprintf("unsupported action: %s.%s", req.Tool, req.Action)}
+	}
+	res, err := s.client.CallTool(ctx, t.Name, req.Params)
+	if err != nil {
+		return connectors.ExecResponse{Status: "error", Error: err.Error()}
+	}
+	out := map[string]any{"content": res.Content}
+	if len(res.StructuredContent) > 0 {
+		out["structured_content"] = res.StructuredContent
+	}
+	output, _ := json.Marshal(out)
+	output, truncation := connectors.TruncateOutput(output, req.OutputLimit())
+	if res.IsError {
+		msg := res.Text()
+		if msg == "" {
+			msg = "mcp tool reported an error"
+		}
+		return connectors.ExecResponse{Status: "error", OutputJSON: output, Error: msg, Truncation: truncation}
+	}
+	return connectors.ExecResponse{Status: "success", OutputJSON: output, Truncation: truncation}
+}
+
+// Manifest lists the server's tools as actions, with their input schemas
+// as params. It always re-lists, so the manifest follows the server.
+func (s *Server) Manifest(ctx context.Context) (connectors.Manifest, error) {
+	if err := s.refresh(ctx); err != nil {
+		return connectors.Manifest{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := connectors.Manifest{Tool: s.Tool, Actions: make([]connectors.ActionManifest, 0, len(s.order))}
+	for _, a := range s.order {
+		t := s.tools[a]
+		m.Actions = append(m.Actions, connectors.ActionManifest{
+			Action:      a,
+			Description: t.Description,
+			Params:      t.InputSchema,
+			ReadOnly:    t.Annotations != nil && t.Annotations.ReadOnlyHint,
+		})
+	}
+	return m, nil
+}
+
+// Ping checks that the upstream server answers.
+func (s *Server) Ping(ctx context.Context) error {
+	return s.client.Ping(ctx)
+}
+
+// validServerName is a tool name an MCP server can be exposed as.
+var validServerName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// ParseServers parses MCP_SERVERS, "tool=url,tool=url": the tool each
+// upstream MCP server is exposed as and its Streamable HTTP endpoint.
+func ParseServers(raw string) (map[string]string, error) {
+	out := map[string]string{}
+	for _, pair := range strings.Split(raw, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		tool, url, ok := strings.Cut(pair, "=")
+		tool, url = strings.TrimSpace(tool), strings.TrimSpace(url)
+		if !ok || url == "" {
+			return nil, fmt.Errorf("mcp.ParseServers: %q must be tool=url", pair)
+		}
+		if !validServerName.MatchString(tool) {
+			return nil, fmt.Errorf("mcp.ParseServers: %q is not a valid tool name", tool)
+		}
+		if _, dup := out[tool]; dup {
+			return nil, fmt.Errorf("mcp.ParseServers: %q is listed twice", tool)
+		}
+		out[tool] = url
+	}
+	return out, nil
+}
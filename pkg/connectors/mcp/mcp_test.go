@@ -0,0 +1,246 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/bturcanu/OpenClause/pkg/connectors"
+)
+
+// fakeMCP is a minimal Streamable HTTP MCP server. Calls are answered over
+// SSE when sse is set; expire drops the current session.
+type fakeMCP struct {
+	mu       sync.Mutex
+	sessions int
+	session  string
+	sse      bool
+	inits    int
+	calls    []string
+	auth     string
+}
+
+func (f *fakeMCP) expire() {
+	f.mu.Lock()
+	f.session = ""
+	f.mu.Unlock()
+}
+
+func (f *fakeMCP) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var msg struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+		http.Error(w, "bad json", http.StatusBadRequest)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.auth = r.Header.Get("Authorization")
+
+	if msg.Method == "initialize" {
+		f.inits++
+		f.sessions++
+		f.session = fmt.Sprintf("s%d", f.sessions)
+		w.Header().Set("Mcp-Session-Id", f.session)
+		f.reply(w, msg.ID, map[string]any{"protocolVersion": ProtocolVersion, "capabilities": map[string]any{}})
+		return
+	}
+	if r.Header.Get("Mcp-Session-Id") != f.session || f.session == "" {
+		http.Error(w, "unknown session", http.StatusNotFound)
+		return
+	}
+	switch msg.Method {
+	case "notifications/initialized":
+		w.WriteHeader(http.StatusAccepted)
+	case "ping":
+		f.reply(w, msg.ID, map[string]any{})
+	case "tools/list":
+		var p struct {
+			Cursor string `json:"cursor"`
+		}
+		_ = json.Unmarshal(msg.Params, &p)
+		if p.Cursor == "" {
+			f.reply(w, msg.ID, map[string]any{
+				"tools": []any{map[string]any{
+					"name": "search_issues", "description": "Search issues",
+					"inputSchema": map[string]any{"type": "object", "properties": map[string]any{"q": map[string]any{"type": "string"}}},
+					"annotations": map[string]any{"readOnlyHint": true},
+				}},
+				"nextCursor": "p2",
+			})
+			return
+		}
+		f.reply(w, msg.ID, map[string]any{"tools": []any{
+			map[string]any{"name": "Create Issue", "inputSchema": map[string]any{"type": "object"}},
+			map[string]any{"name": "fail"},
+		}})
+	case "tools/call":
+		var p struct {
+			Name      string         `json:"name"`
+			Arguments map[string]any `json:"arguments"`
+		}
+		_ = json.Unmarshal(msg.Params, &p)
+		f.calls = append(f.calls, p.Name)
+		if p.Name == "fail" {
+			f.reply(w, msg.ID, map[string]any{"content": []any{map[string]any{"type": "text", "text": "boom"}}, "isError": true})
+			return
+		}
+		f.reply(w, msg.ID, map[string]any{
+			"content":           []any{map[string]any{"type": "text", "text": fmt.Sprintf("q=%v", p.Arguments["q"])}},
+			"structuredContent": map[string]any{"count": 2},
+		})
+	default:
+		f.rpcError(w, msg.ID, -32601, "method not found")
+	}
+}
+
+func (f *fakeMCP) reply(w http.ResponseWriter, id json.RawMessage, result any) {
+	f.write(w, map[string]any{"jsonrpc": "2.0", "id": id, "result": result})
+}
+
+func (f *fakeMCP) rpcError(w http.ResponseWriter, id json.RawMessage, code int, msg string) {
+	f.write(w, map[string]any{"jsonrpc": "2.0", "id": id, "error": map[string]any{"code": code, "message": msg}})
+}
+
+func (f *fakeMCP) write(w http.ResponseWriter, v any) {
+	b, _ := json.Marshal(v)
+	if !f.sse {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(b)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	// A server notification precedes the response on the stream.
+	fmt.Fprintf(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/message\",\"params\":{}}\n\n")
+	fmt.Fprintf(w, "event: message\ndata: %s\n\n", b)
+}
+
+func newTestServer(t *testing.T) (*fakeMCP, *Server) {
+	t.Helper()
+	fake := &fakeMCP{}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	return fake, NewServer("tracker", NewClient(srv.URL, func() string { return "tok" }, srv.Client()))
+}
+
+func TestActionName(t *testing.T) {
+	for in, want := range map[string]string{
+		"search_issues": "search_issues",
+		"Create Issue":  "create_issue",
+		"repo/get-file": "repo_get-file",
+		"__private":     "private",
+	} {
+		if got := ActionName(in); got != want {
+			t.Errorf("ActionName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestParseServers(t *testing.T) {
+	got, err := ParseServers(" github=http://gh:3000/mcp , linear=http://linear/mcp,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got["github"] != "http://gh:3000/mcp" || got["linear"] != "http://linear/mcp" {
+		t.Fatalf("ParseServers = %v", got)
+	}
+	for _, bad := range []string{"github", "GitHub=http://x", "a=http://x,a=http://y", "a="} {
+		if _, err := ParseServers(bad); err == nil {
+			t.Errorf("ParseServers(%q): expected error", bad)
+		}
+	}
+}
+
+func TestServer_Manifest(t *testing.T) {
+	fake, s := newTestServer(t)
+	m, err := s.Manifest(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Tool != "tracker" || len(m.Actions) != 3 {
+		t.Fatalf("manifest = %+v", m)
+	}
+	search := m.Actions[0]
+	if search.Action != "search_issues" || !search.ReadOnly || search.Description != "Search issues" {
+		t.Errorf("search action = %+v", search)
+	}
+	if m.Actions[1].Action != "create_issue" || m.Actions[1].ReadOnly {
+		t.Errorf("create action = %+v", m.Actions[1])
+	}
+	if fake.auth != "Bearer tok" {
+		t.Errorf("Authorization = %q", fake.auth)
+	}
+
+	specs, err := connectors.ToolSpecs(connectors.SpecFormatAnthropic, []connectors.Manifest{m}, nil)
+	if err != nil || len(specs) != 3 || specs[2]["name"] != "tracker__search_issues" {
+		t.Fatalf("specs = %v, %v", specs, err)
+	}
+}
+
+func TestServer_Exec(t *testing.T) {
+	for _, sse := range []bool{false, true} {
+		t.Run(fmt.Sprintf("sse=%v", sse), func(t *testing.T) {
+			fake, s := newTestServer(t)
+			fake.sse = sse
+			ctx := context.Background()
+
+			resp := s.Exec(ctx, connectors.ExecRequest{Tool: "tracker", Action: "search_issues", Params: json.RawMessage(`{"q":"bug"}`)})
+			if resp.Status != "success" {
+				t.Fatalf("exec = %+v", resp)
+			}
+			var out struct {
+				Content []struct {
+					Text string `json:"text"`
+				} `json:"content"`
+				Structured map[string]int `json:"structured_content"`
+			}
+			if err := json.Unmarshal(resp.OutputJSON, &out); err != nil {
+				t.Fatal(err)
+			}
+			if len(out.Content) != 1 || out.Content[0].Text != "q=bug" || out.Structured["count"] != 2 {
+				t.Errorf("output = %s", resp.OutputJSON)
+			}
+
+			// The action is mapped back to the MCP tool's own name.
+			resp = s.Exec(ctx, connectors.ExecRequest{Tool: "tracker", Action: "create_issue"})
+			if resp.Status != "success" || fake.calls[len(fake.calls)-1] != "Create Issue" {
+				t.Errorf("create = %+v, calls %v", resp, fake.calls)
+			}
+
+			resp = s.Exec(ctx, connectors.ExecRequest{Tool: "tracker", Action: "fail"})
+			if resp.Status != "error" || resp.Error != "boom" {
+				t.Errorf("isError result = %+v", resp)
+			}
+
+			resp = s.Exec(ctx, connectors.ExecRequest{Tool: "tracker", Action: "missing"})
+			if resp.Status != "error" {
+				t.Errorf("unknown action = %+v", resp)
+			}
+			if fake.inits != 1 {
+				t.Errorf("initialized %d times, want 1", fake.inits)
+			}
+		})
+	}
+}
+
+func TestClient_ReinitializesExpiredSession(t *testing.T) {
+	fake, s := newTestServer(t)
+	ctx := context.Background()
+	if err := s.Ping(ctx); err != nil {
+		t.Fatal(err)
+	}
+	fake.expire()
+	if err := s.Ping(ctx); err != nil {
+		t.Fatalf("ping after expiry: %v", err)
+	}
+	if fake.inits != 2 {
+		t.Errorf("initialized %d times, want 2", fake.inits)
+	}
+}
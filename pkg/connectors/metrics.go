@@ -0,0 +1,20 @@
+package connectors
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+)
+
+var execDuration metric.Float64Histogram
+
+func init() {
+	meter := otel.Meter("github.com/bturcanu/OpenClause/pkg/connectors")
+	var err error
+	execDuration, err = meter.Float64Histogram("oc.connector.exec.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Latency of connector executions by tool and outcome."),
+	)
+	if err != nil {
+		panic(err)
+	}
+}
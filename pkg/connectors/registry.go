@@ -4,53 +4,364 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hash/fnv"
 	"io"
+	"math/rand/v2"
 	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	ocOtel "github.com/bturcanu/OpenClause/pkg/otel"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 )
 
-const maxConnectorResponseBytes = 4 << 20 // 4 MB
+var tracer = otel.Tracer("github.com/bturcanu/OpenClause/pkg/connectors")
+
+// responseEnvelopeBytes is the room a connector response may take beyond
+// its output: status, error, plan, compensation and identity.
+const responseEnvelopeBytes = 64 << 10
+
+// FallbackRoute is the route pattern that serves calls no other route matches.
+const FallbackRoute = "*"
+
+// ErrPinUnavailable is returned by Exec when the call is pinned to a version
+// label none of its route's backends carries. The call is not sent to
+// another version.
+var ErrPinUnavailable = errors.New("pinned connector version unavailable")
+
+// ErrPlanUnsupported is returned by Exec for a plan request when the backend
+// the call would go to does not advertise plan support in its manifest. The
+// request is not sent, since a connector that ignores Plan would perform
+// the call.
+var ErrPlanUnsupported = errors.New("connector does not support plans")
+
+// Backend is one connector deployment serving a route. Calls are split
+// between a route's backends in proportion to Weight, so a new connector
+// build can take a small share of traffic before it takes all of it.
+//
+// Label names the connector version the backend runs, e.g. "v1". A tenant
+// that pins a tool to a label is served only by backends carrying it,
+// whatever their weight, so an upgrade does not reach it until it moves
+// the pin.
+type Backend struct {
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+	Label  string `json:"label,omitempty"`
+}
+
+// labelPattern is a version label: letters, digits, '.', '_' and '-'.
+var labelPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// ValidateLabel checks a backend version label.
+func ValidateLabel(label string) error {
+	if !labelPattern.MatchString(label) {
+		return fmt.Errorf("label %q must be 1-64 letters, digits, '.', '_' or '-'", label)
+	}
+	return nil
+}
+
+// backend is a registered Backend with its outcome counters.
+type backend struct {
+	Backend
+	calls    atomic.Int64
+	failures atomic.Int64
+}
 
-// Registry maps tool names to connector base URLs. Thread-safe.
+// Registry maps tool calls to connector base URLs. Thread-safe.
+//
+// A route pattern is a tool ("jira", same as "jira.*"), an exact tool.action
+// ("jira.issue.delete"), an action prefix ("jira.issue.*"), or FallbackRoute.
+// A call goes to its exact tool.action route, else the longest matching
+// action prefix, else its tool's route, else the fallback.
 type Registry struct {
 	mu            sync.RWMutex
-	routes        map[string]string // tool → base URL
+	routes        map[string][]*backend // route pattern → weighted backends
 	httpClient    *http.Client
 	internalToken string
+	maxOutput     int64
 }
 
 // NewRegistry creates a connector registry.
 func NewRegistry() *Registry {
 	return &Registry{
-		routes: make(map[string]string),
+		routes: make(map[string][]*backend),
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		maxOutput: DefaultMaxOutputBytes,
 	}
 }
 
-// Register maps a tool name to a connector URL.
-func (r *Registry) Register(tool, baseURL string) {
+// Register maps a route pattern to a connector URL, replacing any backends
+// the pattern had.
+func (r *Registry) Register(pattern, baseURL string) {
+	_ = r.RegisterWeighted(pattern, Backend{URL: baseURL, Weight: 1})
+}
+
+// RegisterWeighted maps a route pattern to several connector URLs, replacing
+// any backends the pattern had. A backend with weight 0 is registered (and
+// health-checked) but receives no traffic.
+func (r *Registry) RegisterWeighted(pattern string, backends ...Backend) error {
+	total := 0
+	for _, b := range backends {
+		if b.URL == "" || b.Weight < 0 {
+			return fmt.Errorf("connectors.RegisterWeighted: %s: backend needs a url and a non-negative weight", pattern)
+		}
+		if b.Label != "" {
+			if err := ValidateLabel(b.Label); err != nil {
+				return fmt.Errorf("connectors.RegisterWeighted: %s: %w", pattern, err)
+			}
+		}
+		total += b.Weight
+	}
+	if total == 0 {
+		return fmt.Errorf("connectors.RegisterWeighted: %s: weights must not all be zero", pattern)
+	}
+	if tool, ok := strings.CutSuffix(pattern, ".*"); ok && !strings.Contains(tool, ".") {
+		pattern = tool
+	}
+	bs := make([]*backend, len(backends))
+	for i, b := range backends {
+		bs[i] = &backend{Backend: b}
+	}
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	r.routes[tool] = baseURL
+	r.routes[pattern] = bs
+	return nil
+}
+
+// SetFallback routes calls that match no other route to baseURL.
+func (r *Registry) SetFallback(baseURL string) {
+	r.Register(FallbackRoute, baseURL)
+}
+
+// Route returns the pattern and backends that serve tool.action, or false if
+// no route, not even the fallback, matches.
+func (r *Registry) Route(tool, action string) (pattern string, backends []Backend, ok bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	pattern, bs, ok := r.route(tool, action)
+	for _, b := range bs {
+		backends = append(backends, b.Backend)
+	}
+	return pattern, backends, ok
+}
+
+// route must be called with r.mu held.
+func (r *Registry) route(tool, action string) (string, []*backend, bool) {
+	key := tool + "." + action
+	if bs, ok := r.routes[key]; ok && action != "" {
+		return key, bs, true
+	}
+	for prefix := action; ; {
+		i := strings.LastIndex(prefix, ".")
+		if i < 0 {
+			break
+		}
+		prefix = prefix[:i]
+		p := tool + "." + prefix + ".*"
+		if bs, ok := r.routes[p]; ok {
+			return p, bs, true
+		}
+	}
+	if bs, ok := r.routes[tool]; ok {
+		return tool, bs, true
+	}
+	if bs, ok := r.routes[FallbackRoute]; ok {
+		return FallbackRoute, bs, true
+	}
+	return "", nil, false
+}
+
+// pinned returns the backends labeled label.
+func pinned(bs []*backend, label string) []*backend {
+	var out []*backend
+	for _, b := range bs {
+		if b.Label == label {
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+// pick chooses a backend by weight. Calls with the same event ID land on the
+// same backend, so a retried call is not split across versions. Backends
+// that all weigh 0, as a pinned version taken out of rotation may, share
+// calls equally.
+func pick(bs []*backend, eventID string) *backend {
+	if len(bs) == 1 {
+		return bs[0]
+	}
+	weight := func(b *backend) int { return b.Weight }
+	total := 0
+	for _, b := range bs {
+		total += b.Weight
+	}
+	if total == 0 {
+		weight = func(*backend) int { return 1 }
+		total = len(bs)
+	}
+	var n int
+	if eventID != "" {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(eventID))
+		n = int(h.Sum32() % uint32(total))
+	} else {
+		n = rand.IntN(total)
+	}
+	for _, b := range bs {
+		if n < weight(b) {
+			return b
+		}
+		n -= weight(b)
+	}
+	return bs[len(bs)-1]
+}
+
+// ParseRoutes parses "pattern=url" pairs separated by commas, as used by
+// CONNECTOR_ROUTES. A route split between versions lists "url@weight"
+// backends separated by "|", e.g. "jira=http://jira-v1@90|http://jira-v2@10".
+// A backend may end in "#label" to name its version for tenant pins, e.g.
+// "jira=http://jira-v1@90#v1|http://jira-v2@10#v2".
+func ParseRoutes(raw string) (map[string][]Backend, error) {
+	out := map[string][]Backend{}
+	for _, pair := range strings.Split(raw, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		pattern, urls, ok := strings.Cut(pair, "=")
+		pattern, urls = strings.TrimSpace(pattern), strings.TrimSpace(urls)
+		if !ok || pattern == "" || urls == "" {
+			return nil, fmt.Errorf("connectors.ParseRoutes: %q must be pattern=url", pair)
+		}
+		if pattern != FallbackRoute && strings.Contains(strings.TrimSuffix(pattern, ".*"), "*") {
+			return nil, fmt.Errorf("connectors.ParseRoutes: %q: only a trailing .* or a lone * is allowed", pattern)
+		}
+		var backends []Backend
+		for _, u := range strings.Split(urls, "|") {
+			b := Backend{URL: strings.TrimSpace(u), Weight: 1}
+			if i := strings.LastIndex(b.URL, "#"); i >= 0 {
+				if err := ValidateLabel(b.URL[i+1:]); err != nil {
+					return nil, fmt.Errorf("connectors.ParseRoutes: %q: %w", u, err)
+				}
+				b.URL, b.Label = b.URL[:i], b.URL[i+1:]
+			}
+			if i := strings.LastIndex(b.URL, "@"); i >= 0 {
+				w, err := strconv.Atoi(b.URL[i+1:])
+				if err != nil || w < 0 {
+					return nil, fmt.Errorf("connectors.ParseRoutes: %q: weight must be a non-negative integer", u)
+				}
+				b.URL, b.Weight = b.URL[:i], w
+			}
+			if b.URL == "" {
+				return nil, fmt.Errorf("connectors.ParseRoutes: %q must be pattern=url", pair)
+			}
+			backends = append(backends, b)
+		}
+		out[pattern] = backends
+	}
+	return out, nil
 }
 
 // Exec routes the request to the correct connector and returns the result.
 func (r *Registry) Exec(ctx context.Context, req ExecRequest) (*ExecResponse, error) {
+	ctx, span := tracer.Start(ctx, "connectors.Exec", trace.WithAttributes(
+		attribute.String("oc.tool", req.Tool),
+		attribute.String("oc.action", req.Action),
+	))
+	defer span.End()
+
 	r.mu.RLock()
-	baseURL, ok := r.routes[req.Tool]
+	pattern, bs, ok := r.route(req.Tool, req.Action)
 	token := r.internalToken
 	client := r.httpClient
+	req.MaxOutputBytes = r.maxOutput
 	r.mu.RUnlock()
-
+	// Tell the connector how long it has, so it can give up upstream and
+	// answer before the gateway stops waiting.
+	timeout := client.Timeout
+	if dl, ok := ctx.Deadline(); ok {
+		if remaining := time.Until(dl); timeout == 0 || remaining < timeout {
+			timeout = remaining
+		}
+	}
+	if timeout > 0 {
+		req.TimeoutMS = max(timeout.Milliseconds(), 1)
+	}
 	if !ok {
-		return nil, fmt.Errorf("no connector registered for tool %q", req.Tool)
+		err := fmt.Errorf("no connector registered for %s.%s", req.Tool, req.Action)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	if req.Pin != "" {
+		span.SetAttributes(attribute.String("oc.pin", req.Pin))
+		if bs = pinned(bs, req.Pin); len(bs) == 0 {
+			err := fmt.Errorf("%w: route %s has no backend labeled %q", ErrPinUnavailable, pattern, req.Pin)
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return nil, err
+		}
+	}
+	b := pick(bs, req.EventID)
+	span.SetAttributes(attribute.String("oc.route", pattern), attribute.String("oc.backend", b.URL))
+
+	if req.Plan {
+		if m, err := fetchManifest(ctx, client, token, b.URL); err != nil || !m.Plan {
+			if err == nil {
+				err = fmt.Errorf("%w: %s", ErrPlanUnsupported, b.URL)
+			} else {
+				err = fmt.Errorf("%w: %s manifest: %v", ErrPlanUnsupported, b.URL, err)
+			}
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return nil, err
+		}
 	}
 
+	start := time.Now()
+	resp, err := r.exec(ctx, client, token, b.URL, req)
+	if resp != nil {
+		if resp.Connector == nil {
+			resp.Connector = &ConnectorInfo{}
+		}
+		resp.Connector.Backend = b.URL
+	}
+	status := "error"
+	if err == nil && resp != nil && resp.Status != "" {
+		status = resp.Status
+	}
+	b.calls.Add(1)
+	if status == "error" {
+		b.failures.Add(1)
+	}
+	span.SetAttributes(attribute.String("oc.status", status))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	execDuration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(
+		attribute.String("tool", req.Tool),
+		attribute.String("route", pattern),
+		attribute.String("backend", b.URL),
+		attribute.String("status", status),
+	))
+	return resp, err
+}
+
+func (r *Registry) exec(ctx context.Context, client *http.Client, token, baseURL string, req ExecRequest) (*ExecResponse, error) {
 	body, err := json.Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf("connector marshal: %w", err)
@@ -65,6 +376,7 @@ func (r *Registry) Exec(ctx context.Context, req ExecRequest) (*ExecResponse, er
 	if token != "" {
 		httpReq.Header.Set("X-Internal-Token", token)
 	}
+	ocOtel.InjectHTTP(ctx, httpReq.Header)
 
 	resp, err := client.Do(httpReq)
 	if err != nil {
@@ -72,9 +384,11 @@ func (r *Registry) Exec(ctx context.Context, req ExecRequest) (*ExecResponse, er
 	}
 	defer resp.Body.Close()
 
-	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxConnectorResponseBytes))
+	// An error may quote the upstream response alongside the output, so the
+	// body gets room for both; output over the limit is truncated below.
+	respBody, err := ReadLimited(resp.Body, 2*req.MaxOutputBytes+responseEnvelopeBytes)
 	if err != nil {
-		return nil, fmt.Errorf("connector read response: %w", err)
+		return nil, fmt.Errorf("connector %s read response: %w", req.Tool, err)
 	}
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
@@ -89,6 +403,12 @@ func (r *Registry) Exec(ctx context.Context, req ExecRequest) (*ExecResponse, er
 	if err := json.Unmarshal(respBody, &execResp); err != nil {
 		return nil, fmt.Errorf("connector decode response: %w", err)
 	}
+	if t := execResp.Truncation; t != nil {
+		t.Full = nil // the connector kept the full output; we never saw it
+	}
+	if out, t := TruncateOutput(execResp.OutputJSON, req.MaxOutputBytes); t != nil {
+		execResp.OutputJSON, execResp.Truncation = out, t
+	}
 
 	return &execResp, nil
 }
@@ -97,7 +417,24 @@ func (r *Registry) Exec(ctx context.Context, req ExecRequest) (*ExecResponse, er
 func (r *Registry) SetTimeout(d time.Duration) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	r.httpClient = &http.Client{Timeout: d}
+	r.httpClient = &http.Client{Timeout: d, Transport: r.httpClient.Transport}
+}
+
+// SetMaxOutputBytes bounds the output_json of connector responses; larger
+// output is truncated, see TruncateOutput. Connectors are told the limit
+// with each request.
+func (r *Registry) SetMaxOutputBytes(n int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.maxOutput = n
+}
+
+// SetTransport replaces the transport connector calls and health probes
+// use, e.g. with a Local transport for in-process connectors.
+func (r *Registry) SetTransport(rt http.RoundTripper) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.httpClient = &http.Client{Timeout: r.httpClient.Timeout, Transport: rt}
 }
 
 // SetInternalToken configures service-to-service auth header for connectors.
@@ -106,3 +443,84 @@ func (r *Registry) SetInternalToken(token string) {
 	defer r.mu.Unlock()
 	r.internalToken = token
 }
+
+// ConnectorHealth is the result of probing one connector's /healthz. Tool is
+// the route pattern the connector serves; Calls and Failures count the
+// requests it has handled and those that ended with status "error".
+type ConnectorHealth struct {
+	Tool      string `json:"tool"`
+	URL       string `json:"url"`
+	Weight    int    `json:"weight"`
+	Label     string `json:"label,omitempty"`
+	Healthy   bool   `json:"healthy"`
+	Error     string `json:"error,omitempty"`
+	LatencyMS int64  `json:"latency_ms"`
+	Calls     int64  `json:"calls"`
+	Failures  int64  `json:"failures"`
+}
+
+// Health probes every registered backend's /healthz concurrently and
+// returns the results sorted by route pattern and URL.
+func (r *Registry) Health(ctx context.Context) []ConnectorHealth {
+	type target struct {
+		pattern string
+		b       *backend
+	}
+	r.mu.RLock()
+	var targets []target
+	for pattern, bs := range r.routes {
+		for _, b := range bs {
+			targets = append(targets, target{pattern, b})
+		}
+	}
+	client := r.httpClient
+	r.mu.RUnlock()
+
+	out := make([]ConnectorHealth, 0, len(targets))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, t := range targets {
+		wg.Add(1)
+		go func(t target) {
+			defer wg.Done()
+			h := probe(ctx, client, t.pattern, t.b.URL)
+			h.Weight, h.Label = t.b.Weight, t.b.Label
+			h.Calls, h.Failures = t.b.calls.Load(), t.b.failures.Load()
+			mu.Lock()
+			out = append(out, h)
+			mu.Unlock()
+		}(t)
+	}
+	wg.Wait()
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Tool != out[j].Tool {
+			return out[i].Tool < out[j].Tool
+		}
+		return out[i].URL < out[j].URL
+	})
+	return out
+}
+
+func probe(ctx context.Context, client *http.Client, tool, baseURL string) ConnectorHealth {
+	h := ConnectorHealth{Tool: tool, URL: baseURL}
+	start := time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(baseURL, "/")+"/healthz", nil)
+	if err != nil {
+		h.Error = err.Error()
+		return h
+	}
+	resp, err := client.Do(req)
+	h.LatencyMS = time.Since(start).Milliseconds()
+	if err != nil {
+		h.Error = err.Error()
+		return h
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1024))
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		h.Error = fmt.Sprintf("HTTP %d", resp.StatusCode)
+		return h
+	}
+	h.Healthy = true
+	return h
+}
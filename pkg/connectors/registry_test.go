@@ -3,15 +3,23 @@ package connectors
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 )
 
 func TestRegistry_ExecSuccess(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		resp := ExecResponse{Status: "success", OutputJSON: json.RawMessage(`{"ok":true}`)}
+		resp := ExecResponse{
+			Status: "success", OutputJSON: json.RawMessage(`{"ok":true}`),
+			Connector: &ConnectorInfo{Name: "connector-test", Version: "abc123", Endpoint: "https://upstream.example", Backend: "spoofed"},
+		}
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(resp)
 	}))
@@ -27,6 +35,10 @@ func TestRegistry_ExecSuccess(t *testing.T) {
 	if resp.Status != "success" {
 		t.Errorf("expected success, got %s", resp.Status)
 	}
+	want := ConnectorInfo{Name: "connector-test", Version: "abc123", Endpoint: "https://upstream.example", Backend: srv.URL}
+	if resp.Connector == nil || *resp.Connector != want {
+		t.Errorf("connector = %+v, want %+v", resp.Connector, want)
+	}
 }
 
 func TestRegistry_UnregisteredTool(t *testing.T) {
@@ -63,3 +75,420 @@ func TestRegistry_SetTimeout(t *testing.T) {
 	reg := NewRegistry()
 	reg.SetTimeout(5 * time.Second)
 }
+
+func TestRegistry_ExecSendsLimits(t *testing.T) {
+	var got ExecRequest
+	output := `{"data":"` + strings.Repeat("x", 400) + `"}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		_, _ = fmt.Fprintf(w, `{"status":"success","output_json":%s}`, output)
+	}))
+	defer srv.Close()
+
+	reg := NewRegistry()
+	reg.Register("test", srv.URL)
+	reg.SetTimeout(20 * time.Second)
+	reg.SetMaxOutputBytes(1000)
+
+	if _, err := reg.Exec(context.Background(), ExecRequest{Tool: "test", Action: "do"}); err != nil {
+		t.Fatal(err)
+	}
+	if got.TimeoutMS != 20000 || got.MaxOutputBytes != 1000 {
+		t.Errorf("limits = %dms, %d bytes", got.TimeoutMS, got.MaxOutputBytes)
+	}
+
+	// The caller's deadline is tighter than the registry timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if _, err := reg.Exec(ctx, ExecRequest{Tool: "test", Action: "do"}); err != nil {
+		t.Fatal(err)
+	}
+	if got.TimeoutMS <= 0 || got.TimeoutMS > 2000 {
+		t.Errorf("timeout_ms = %d, want at most the 2s deadline", got.TimeoutMS)
+	}
+
+	reg.SetMaxOutputBytes(200)
+	resp, err := reg.Exec(context.Background(), ExecRequest{Tool: "test", Action: "do"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Truncation == nil || resp.Truncation.OriginalBytes != int64(len(output)) || string(resp.Truncation.Full) != output {
+		t.Errorf("truncation = %+v", resp.Truncation)
+	}
+	if len(resp.OutputJSON) > 200 || !json.Valid(resp.OutputJSON) {
+		t.Errorf("truncated output = %s", resp.OutputJSON)
+	}
+}
+
+func TestTruncateOutput(t *testing.T) {
+	small := json.RawMessage(`{"ok":true}`)
+	if out, tr := TruncateOutput(small, 11); tr != nil || string(out) != string(small) {
+		t.Errorf("output within the limit changed: %s, %+v", out, tr)
+	}
+
+	big := json.RawMessage(`{"text":"` + strings.Repeat("é\"<", 200) + `"}`)
+	for _, limit := range []int64{0, 120, 150, 400} {
+		out, tr := TruncateOutput(big, limit)
+		if tr == nil || tr.OriginalBytes != int64(len(big)) || len(tr.SHA256) != 64 {
+			t.Fatalf("limit %d: truncation = %+v", limit, tr)
+		}
+		var stub truncatedOutput
+		if err := json.Unmarshal(out, &stub); err != nil || !stub.Truncated || stub.SHA256 != tr.SHA256 {
+			t.Fatalf("limit %d: output %s: %v", limit, out, err)
+		}
+		if !strings.HasPrefix(string(big), stub.Preview) {
+			t.Errorf("limit %d: preview %q is not a prefix", limit, stub.Preview)
+		}
+		if stub.Preview != "" && int64(len(out)) > limit {
+			t.Errorf("limit %d: %d bytes", limit, len(out))
+		}
+		if again, _ := TruncateOutput(big, limit); string(again) != string(out) {
+			t.Errorf("limit %d: not deterministic", limit)
+		}
+	}
+}
+
+func TestExecRequest_Limits(t *testing.T) {
+	remaining := func(req ExecRequest) time.Duration {
+		ctx, cancel := req.Context(context.Background())
+		defer cancel()
+		dl, _ := ctx.Deadline()
+		return time.Until(dl)
+	}
+	soon := time.Now().Add(time.Minute)
+	if d := remaining(ExecRequest{TimeoutMS: 3000, Deadline: &soon}); d > 3*time.Second || d < 2*time.Second {
+		t.Errorf("timeout_ms: %v left", d)
+	}
+	if d := remaining(ExecRequest{Deadline: &soon}); d < 59*time.Second {
+		t.Errorf("deadline: %v left", d)
+	}
+	if d := remaining(ExecRequest{}); d > DefaultExecTimeout || d < DefaultExecTimeout-time.Second {
+		t.Errorf("default: %v left", d)
+	}
+
+	if (ExecRequest{}).OutputLimit() != DefaultMaxOutputBytes || (ExecRequest{MaxOutputBytes: 10}).OutputLimit() != 10 {
+		t.Error("OutputLimit")
+	}
+	if b, err := ReadLimited(strings.NewReader("12345"), 5); err != nil || string(b) != "12345" {
+		t.Errorf("ReadLimited at the limit = %q, %v", b, err)
+	}
+	if _, err := ReadLimited(strings.NewReader("123456"), 5); err == nil {
+		t.Error("ReadLimited over the limit succeeded")
+	}
+}
+
+func TestRegistry_Health(t *testing.T) {
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer up.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	reg := NewRegistry()
+	reg.Register("slack", up.URL)
+	reg.Register("jira", down.URL)
+
+	got := reg.Health(context.Background())
+	if len(got) != 2 || got[0].Tool != "jira" || got[1].Tool != "slack" {
+		t.Fatalf("health = %+v", got)
+	}
+	if got[0].Healthy || got[0].Error != "HTTP 503" {
+		t.Errorf("jira = %+v, want unhealthy HTTP 503", got[0])
+	}
+	if !got[1].Healthy {
+		t.Errorf("slack = %+v, want healthy", got[1])
+	}
+}
+
+func TestRegistry_RoutesByToolAction(t *testing.T) {
+	reg := NewRegistry()
+	reg.Register("jira", "http://jira")
+	reg.Register("jira.issue.*", "http://jira-issues")
+	reg.Register("jira.issue.delete", "http://jira-admin")
+	reg.Register("github.*", "http://github")
+
+	cases := []struct {
+		tool, action     string
+		pattern, baseURL string
+	}{
+		{"jira", "issue.delete", "jira.issue.delete", "http://jira-admin"},
+		{"jira", "issue.create", "jira.issue.*", "http://jira-issues"},
+		{"jira", "project.list", "jira", "http://jira"},
+		{"github", "pr.merge", "github", "http://github"},
+	}
+	for _, c := range cases {
+		pattern, bs, ok := reg.Route(c.tool, c.action)
+		if !ok || pattern != c.pattern || len(bs) != 1 || bs[0].URL != c.baseURL {
+			t.Errorf("Route(%s, %s) = %q, %v, %v; want %q, %q", c.tool, c.action, pattern, bs, ok, c.pattern, c.baseURL)
+		}
+	}
+	if _, _, ok := reg.Route("slack", "msg.post"); ok {
+		t.Fatal("unrouted tool should not match without a fallback")
+	}
+	reg.SetFallback("http://default")
+	if pattern, bs, ok := reg.Route("slack", "msg.post"); !ok || pattern != FallbackRoute || bs[0].URL != "http://default" {
+		t.Fatalf("fallback = %q, %v, %v", pattern, bs, ok)
+	}
+}
+
+func TestRegistry_ExecUsesActionRoute(t *testing.T) {
+	var hit string
+	srv := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hit = name
+			_ = json.NewEncoder(w).Encode(ExecResponse{Status: "success"})
+		}))
+	}
+	issues, admin := srv("issues"), srv("admin")
+	defer issues.Close()
+	defer admin.Close()
+
+	reg := NewRegistry()
+	reg.Register("jira", issues.URL)
+	reg.Register("jira.issue.delete", admin.URL)
+	if _, err := reg.Exec(context.Background(), ExecRequest{Tool: "jira", Action: "issue.delete"}); err != nil || hit != "admin" {
+		t.Fatalf("issue.delete went to %q: %v", hit, err)
+	}
+	if _, err := reg.Exec(context.Background(), ExecRequest{Tool: "jira", Action: "issue.create"}); err != nil || hit != "issues" {
+		t.Fatalf("issue.create went to %q: %v", hit, err)
+	}
+}
+
+func TestParseRoutes(t *testing.T) {
+	got, err := ParseRoutes(" jira.issue.delete=http://a , github.*=http://b,*=http://c,jira=http://v1@90#v1|http://v2:8083@10#v2.0-rc1,slack=http://s#v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]Backend{
+		"jira.issue.delete": {{URL: "http://a", Weight: 1}},
+		"github.*":          {{URL: "http://b", Weight: 1}},
+		"*":                 {{URL: "http://c", Weight: 1}},
+		"jira":              {{URL: "http://v1", Weight: 90, Label: "v1"}, {URL: "http://v2:8083", Weight: 10, Label: "v2.0-rc1"}},
+		"slack":             {{URL: "http://s", Weight: 1, Label: "v1"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("routes = %v", got)
+	}
+	for _, bad := range []string{"jira", "jira=", "jira.*.delete=http://a", "jira=http://a@x", "jira=http://a|@5", "jira=http://a@5#", "jira=http://a#v/1"} {
+		if _, err := ParseRoutes(bad); err == nil {
+			t.Errorf("ParseRoutes(%q) should fail", bad)
+		}
+	}
+}
+
+func TestRegistry_WeightedCanary(t *testing.T) {
+	var stableHits, canaryHits atomic.Int32
+	stable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/exec" {
+			stableHits.Add(1)
+		}
+		_ = json.NewEncoder(w).Encode(ExecResponse{Status: "success"})
+	}))
+	defer stable.Close()
+	canary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/exec" {
+			canaryHits.Add(1)
+		}
+		_ = json.NewEncoder(w).Encode(ExecResponse{Status: "error", Error: "boom"})
+	}))
+	defer canary.Close()
+
+	reg := NewRegistry()
+	if err := reg.RegisterWeighted("jira", Backend{URL: stable.URL, Weight: 80}, Backend{URL: canary.URL, Weight: 20}); err != nil {
+		t.Fatal(err)
+	}
+	for i := range 500 {
+		_, _ = reg.Exec(context.Background(), ExecRequest{EventID: fmt.Sprintf("evt-%d", i), Tool: "jira", Action: "issue.create"})
+	}
+	if c := canaryHits.Load(); c < 50 || c > 150 || stableHits.Load()+c != 500 {
+		t.Fatalf("stable=%d canary=%d, want about 400/100", stableHits.Load(), c)
+	}
+
+	// A retried call goes to the backend that served it first.
+	before := canaryHits.Load()
+	for range 5 {
+		_, _ = reg.Exec(context.Background(), ExecRequest{EventID: "evt-1", Tool: "jira", Action: "issue.create"})
+	}
+	if d := canaryHits.Load() - before; d != 0 && d != 5 {
+		t.Fatalf("retries of one event were split: %d of 5 to canary", d)
+	}
+
+	for _, h := range reg.Health(context.Background()) {
+		switch h.URL {
+		case stable.URL:
+			if h.Weight != 80 || h.Failures != 0 || h.Calls != int64(stableHits.Load()) {
+				t.Errorf("stable = %+v", h)
+			}
+		case canary.URL:
+			if h.Weight != 20 || h.Failures != h.Calls || h.Calls != int64(canaryHits.Load()) {
+				t.Errorf("canary = %+v", h)
+			}
+		}
+	}
+
+	if err := reg.RegisterWeighted("jira", Backend{URL: stable.URL, Weight: 0}); err == nil {
+		t.Fatal("all-zero weights should be rejected")
+	}
+}
+
+func TestRegistry_PinnedVersion(t *testing.T) {
+	var v1Hits, v2Hits atomic.Int32
+	v1 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v1Hits.Add(1)
+		_ = json.NewEncoder(w).Encode(ExecResponse{Status: "success"})
+	}))
+	defer v1.Close()
+	v2 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v2Hits.Add(1)
+		_ = json.NewEncoder(w).Encode(ExecResponse{Status: "success"})
+	}))
+	defer v2.Close()
+
+	// v1 is out of rotation, but tenants pinned to it still reach it.
+	reg := NewRegistry()
+	if err := reg.RegisterWeighted("jira", Backend{URL: v1.URL, Weight: 0, Label: "v1"}, Backend{URL: v2.URL, Weight: 1, Label: "v2"}); err != nil {
+		t.Fatal(err)
+	}
+	for i := range 20 {
+		resp, err := reg.Exec(context.Background(), ExecRequest{EventID: fmt.Sprintf("evt-%d", i), Tool: "jira", Action: "issue.create", Pin: "v1"})
+		if err != nil || resp.Connector.Backend != v1.URL {
+			t.Fatalf("pinned call: %+v, %v", resp, err)
+		}
+	}
+	if _, err := reg.Exec(context.Background(), ExecRequest{Tool: "jira", Action: "issue.create"}); err != nil {
+		t.Fatal(err)
+	}
+	if v1Hits.Load() != 20 || v2Hits.Load() != 1 {
+		t.Fatalf("v1=%d v2=%d, want 20/1", v1Hits.Load(), v2Hits.Load())
+	}
+
+	_, err := reg.Exec(context.Background(), ExecRequest{Tool: "jira", Action: "issue.create", Pin: "v0"})
+	if !errors.Is(err, ErrPinUnavailable) {
+		t.Fatalf("unknown pin: err = %v", err)
+	}
+	if v1Hits.Load() != 20 || v2Hits.Load() != 1 {
+		t.Fatal("a call pinned to a missing version must not be sent elsewhere")
+	}
+
+	if err := reg.RegisterWeighted("jira", Backend{URL: v1.URL, Weight: 1, Label: "v 1"}); err == nil {
+		t.Fatal("invalid label should be rejected")
+	}
+}
+
+func TestRegistry_PlanOnlyWhereAdvertised(t *testing.T) {
+	serve := func(m Manifest, execs *atomic.Int32) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/manifest" {
+				_ = json.NewEncoder(w).Encode(m)
+				return
+			}
+			execs.Add(1)
+			_ = json.NewEncoder(w).Encode(Planned(ExecPlan{Summary: "would post"}))
+		}))
+	}
+	var planned, ignored atomic.Int32
+	planner := serve(Manifest{Tool: "slack", Plan: true}, &planned)
+	defer planner.Close()
+	other := serve(Manifest{Tool: "legacy"}, &ignored)
+	defer other.Close()
+
+	reg := NewRegistry()
+	reg.Register("slack", planner.URL)
+	reg.Register("legacy", other.URL)
+
+	resp, err := reg.Exec(context.Background(), ExecRequest{Tool: "slack", Action: "msg.post", Plan: true})
+	if err != nil || resp.Status != "planned" || planned.Load() != 1 {
+		t.Fatalf("plan = %+v, %v (sent %d)", resp, err, planned.Load())
+	}
+	if _, err := reg.Exec(context.Background(), ExecRequest{Tool: "legacy", Action: "write", Plan: true}); !errors.Is(err, ErrPlanUnsupported) {
+		t.Fatalf("plan on a connector without plan support: err = %v", err)
+	}
+	if ignored.Load() != 0 {
+		t.Fatal("plan request sent to a connector that does not advertise plans")
+	}
+}
+
+func TestRegistry_ManifestsAndToolSpecs(t *testing.T) {
+	jira := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/manifest" || r.Header.Get("X-Internal-Token") != "tok" {
+			http.Error(w, "unexpected", http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(Manifest{Tool: "jira", Actions: []ActionManifest{
+			{Action: "issue.create", Description: "Create an issue", Params: json.RawMessage(`{"type":"object","required":["summary"]}`)},
+			{Action: "issue.list"},
+			{Action: "sync", Internal: true},
+		}})
+	}))
+	defer jira.Close()
+	broken := httptest.NewServer(http.NotFoundHandler())
+	defer broken.Close()
+
+	reg := NewRegistry()
+	reg.SetInternalToken("tok")
+	reg.Register("jira", jira.URL)
+	reg.Register("slack", broken.URL)
+	manifests, errs := reg.Manifests(context.Background())
+	if len(manifests) != 1 || manifests[0].Tool != "jira" || len(errs) != 1 || errs[broken.URL] == "" {
+		t.Fatalf("manifests = %+v, errs = %v", manifests, errs)
+	}
+
+	specs, err := ToolSpecs(SpecFormatOpenAI, manifests, func(tool, action string) bool { return action != "issue.list" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := json.Marshal(specs)
+	want := `[{"function":{"description":"Create an issue","name":"jira__issue__create","parameters":{"type":"object","required":["summary"]}},"type":"function"}]`
+	if string(got) != want {
+		t.Fatalf("openai specs = %s", got)
+	}
+	specs, _ = ToolSpecs(SpecFormatAnthropic, manifests, nil)
+	got, _ = json.Marshal(specs)
+	want = `[{"description":"Create an issue","input_schema":{"type":"object","required":["summary"]},"name":"jira__issue__create"},` +
+		`{"description":"jira.issue.list","input_schema":{"type":"object","properties":{}},"name":"jira__issue__list"}]`
+	if string(got) != want {
+		t.Fatalf("anthropic specs = %s", got)
+	}
+	if _, err := ToolSpecs("gemini", manifests, nil); err == nil {
+		t.Fatal("unknown format accepted")
+	}
+	if tool, action, ok := ParseFunctionName("jira__issue__create"); !ok || tool != "jira" || action != "issue.create" {
+		t.Fatalf("ParseFunctionName = %q %q %v", tool, action, ok)
+	}
+}
+
+func TestRegistry_LocalTransport(t *testing.T) {
+	local := NewLocal(nil)
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /exec", func(w http.ResponseWriter, r *http.Request) {
+		var req ExecRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		_ = json.NewEncoder(w).Encode(ExecResponse{Status: "success", OutputJSON: json.RawMessage(`{"tool":"` + req.Tool + `"}`)})
+	})
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {})
+
+	reg := NewRegistry()
+	reg.SetTransport(local)
+	reg.SetTimeout(time.Second)
+	reg.Register("jira", local.Handle("jira", mux))
+	reg.Register("slack", LocalScheme+"://missing")
+
+	resp, err := reg.Exec(context.Background(), ExecRequest{Tool: "jira", Action: "issue.create"})
+	if err != nil || string(resp.OutputJSON) != `{"tool":"jira"}` || resp.Connector.Backend != "inproc://jira" {
+		t.Fatalf("in-process exec = %+v, %v", resp, err)
+	}
+	if _, err := reg.Exec(context.Background(), ExecRequest{Tool: "slack", Action: "msg.post"}); err == nil {
+		t.Fatal("unknown in-process connector should fail")
+	}
+	for _, h := range reg.Health(context.Background()) {
+		if h.Healthy != (h.Tool == "jira") {
+			t.Errorf("health = %+v", h)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package sandbox
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// isolate refuses commands without network access: network namespaces are
+// Linux-only, and running such a command with the network would break the
+// promise its configuration makes.
+func isolate(_ *exec.Cmd, network bool) error {
+	if !network {
+		return errors.New("network isolation requires Linux; set network: true to run this command here")
+	}
+	return nil
+}
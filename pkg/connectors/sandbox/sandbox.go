@@ -0,0 +1,440 @@
+// Package sandbox is the sandbox connector: it runs the commands an operator
+// allowlists, each as a governed action, so infrastructure agents have an
+// escape hatch that still goes through policy, approvals and evidence.
+// Commands run without a shell, with a minimal environment, a timeout, capped
+// output and, unless a command allows it, no network. cmd/connector-sandbox
+// serves it.
+package sandbox
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/connectors"
+	"github.com/bturcanu/OpenClause/pkg/connectors/sdk"
+	ocOtel "github.com/bturcanu/OpenClause/pkg/otel"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Defaults for commands that do not set their own limits.
+const (
+	DefaultTimeout        = 30 * time.Second
+	DefaultMaxOutputBytes = 64 << 10 // per stream
+)
+
+// defaultPath is the PATH commands run with.
+const defaultPath = "/usr/local/bin:/usr/bin:/bin"
+
+// defaultArgPattern is what call arguments must match when a command sets
+// no ArgPattern. It refuses a leading "-", so an argument cannot turn into
+// an option of the program.
+var defaultArgPattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9._/:=@+,-]*$`)
+
+// validAction is a command name that can be called as an action.
+var validAction = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,63}$`)
+
+// Command is one allowlisted command, called as action <name> of the tool.
+type Command struct {
+	Description string `json:"description,omitempty"`
+	// Argv is the program, by absolute path, and its fixed arguments. It is
+	// run directly, never through a shell.
+	Argv []string `json:"argv"`
+	// MaxArgs is how many arguments a call may append to Argv, from its
+	// params' "args". Each must match ArgPattern in full, or the default
+	// pattern of letters, digits and ._/:=@+,- not starting with "-".
+	MaxArgs    int    `json:"max_args,omitempty"`
+	ArgPattern string `json:"arg_pattern,omitempty"`
+	// Env is set on top of PATH, HOME and the OC_* call identifiers; the
+	// connector's own environment is never passed on.
+	Env map[string]string `json:"env,omitempty"`
+	// Dir is the working directory. When empty each call runs in a new
+	// temporary directory, removed afterwards.
+	Dir string `json:"dir,omitempty"`
+	// TimeoutSec bounds each run (DefaultTimeout when 0); the call's own
+	// deadline still applies.
+	TimeoutSec int `json:"timeout_sec,omitempty"`
+	// MaxOutputBytes caps stdout and stderr each (DefaultMaxOutputBytes
+	// when 0). Output past the cap is dropped and flagged.
+	MaxOutputBytes int64 `json:"max_output_bytes,omitempty"`
+	// Network lets the command reach the network. Without it the command
+	// runs in a network namespace of its own, with no interfaces up.
+	Network  bool `json:"network,omitempty"`
+	ReadOnly bool `json:"read_only,omitempty"`
+
+	argPattern *regexp.Regexp
+}
+
+// ParseCommands parses a JSON object of command name to Command and
+// validates every entry.
+func ParseCommands(data []byte) (map[string]Command, error) {
+	var cmds map[string]Command
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cmds); err != nil {
+		return nil, fmt.Errorf("sandbox.ParseCommands: %w", err)
+	}
+	var errs []error
+	for name, c := range cmds {
+		if err := c.compile(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+		if !validAction.MatchString(name) {
+			errs = append(errs, fmt.Errorf("%q is not a valid action name", name))
+		}
+		cmds[name] = c
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("sandbox.ParseCommands: %w", errors.Join(errs...))
+	}
+	return cmds, nil
+}
+
+// LoadCommands reads and parses the commands file at path.
+func LoadCommands(path string) (map[string]Command, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("sandbox.LoadCommands: %w", err)
+	}
+	return ParseCommands(data)
+}
+
+func (c *Command) compile() error {
+	var errs []error
+	if len(c.Argv) == 0 || !filepath.IsAbs(c.Argv[0]) {
+		errs = append(errs, errors.New("argv must start with an absolute program path"))
+	}
+	if c.MaxArgs < 0 || c.TimeoutSec < 0 || c.MaxOutputBytes < 0 {
+		errs = append(errs, errors.New("max_args, timeout_sec and max_output_bytes must not be negative"))
+	}
+	if c.Dir != "" && !filepath.IsAbs(c.Dir) {
+		errs = append(errs, errors.New("dir must be an absolute path"))
+	}
+	c.argPattern = defaultArgPattern
+	if c.ArgPattern != "" {
+		// The whole argument must match, even when the pattern is not
+		// anchored.
+		re, err := regexp.Compile(`^(?:` + c.ArgPattern + `)$`)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("arg_pattern: %w", err))
+		}
+		c.argPattern = re
+	}
+	return errors.Join(errs...)
+}
+
+func (c Command) timeout() time.Duration {
+	if c.TimeoutSec > 0 {
+		return time.Duration(c.TimeoutSec) * time.Second
+	}
+	return DefaultTimeout
+}
+
+func (c Command) outputCap() int64 {
+	if c.MaxOutputBytes > 0 {
+		return c.MaxOutputBytes
+	}
+	return DefaultMaxOutputBytes
+}
+
+// params are a call's params.
+type params struct {
+	Args []string `json:"args"`
+}
+
+// argv returns the full argument list for a call with raw params.
+func (c Command) argv(raw json.RawMessage) ([]string, error) {
+	var p params
+	if len(raw) > 0 && string(raw) != "null" {
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&p); err != nil {
+			return nil, fmt.Errorf("invalid params: %w", err)
+		}
+	}
+	if len(p.Args) > c.MaxArgs {
+		return nil, fmt.Errorf("at most %d args allowed, got %d", c.MaxArgs, len(p.Args))
+	}
+	for i, a := range p.Args {
+		if !c.argPattern.MatchString(a) {
+			return nil, fmt.Errorf("args[%d] %q is not allowed", i, a)
+		}
+	}
+	return append(append([]string(nil), c.Argv...), p.Args...), nil
+}
+
+// Config configures a Connector.
+type Config struct {
+	Logger *slog.Logger
+	// Tool is the tool the commands are actions of, "sandbox" when empty.
+	Tool     string
+	Commands map[string]Command
+	// InternalToken is the X-Internal-Token callers must send.
+	InternalToken string
+}
+
+// Connector is the sandbox connector. It implements sdk.Executor and
+// sdk.Planner.
+type Connector struct {
+	log           *slog.Logger
+	tool          string
+	commands      map[string]Command
+	internalToken string
+}
+
+// New creates a sandbox connector. Commands must come from ParseCommands
+// or LoadCommands.
+func New(cfg Config) *Connector {
+	log := cfg.Logger
+	if log == nil {
+		log = slog.Default()
+	}
+	tool := cfg.Tool
+	if tool == "" {
+		tool = "sandbox"
+	}
+	return &Connector{log: log, tool: tool, commands: cfg.Commands, internalToken: cfg.InternalToken}
+}
+
+// Handler serves the connector API: /healthz, /manifest and /exec.
+func (s *Connector) Handler() http.Handler {
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(ocOtel.Middleware)
+	r.Use(middleware.Recoverer)
+
+	r.Get("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("OK"))
+	})
+
+	sdkCfg := sdk.Config{InternalToken: s.internalToken, Logger: s.log, Name: "connector-sandbox", Endpoint: "sandbox"}
+	r.Get("/manifest", sdk.ManifestHandler(s.Manifest(), sdkCfg))
+	r.Post("/exec", sdk.Handler(s, sdkCfg))
+	return r
+}
+
+// Manifest lists each command as an action taking up to MaxArgs "args".
+func (s *Connector) Manifest() connectors.Manifest {
+	names := make([]string, 0, len(s.commands))
+	for name := range s.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	m := connectors.Manifest{Tool: s.tool, Plan: true, Actions: make([]connectors.ActionManifest, 0, len(names))}
+	for _, name := range names {
+		c := s.commands[name]
+		schema, _ := json.Marshal(map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"args": map[string]any{
+					"type":     "array",
+					"items":    map[string]any{"type": "string", "pattern": c.argPattern.String()},
+					"maxItems": c.MaxArgs,
+				},
+			},
+			"additionalProperties": false,
+		})
+		m.Actions = append(m.Actions, connectors.ActionManifest{
+			Action:      name,
+			Description: c.Description,
+			Params:      schema,
+			ReadOnly:    c.ReadOnly,
+		})
+	}
+	return m
+}
+
+// resolve returns the command and argv for req.
+func (s *Connector) resolve(req connectors.ExecRequest) (Command, []string, error) {
+	if req.Tool != s.tool {
+		return Command{}, nil, fmt.Errorf("this connector serves %s, not %s", s.tool, req.Tool)
+	}
+	c, ok := s.commands[req.Action]
+	if !ok {
+		return Command{}, nil, fmt.Errorf("unsupported action: %s.%s", req.Tool, req.Action)
+	}
+	argv, err := c.argv(req.Params)
+	if err != nil {
+		return Command{}, nil, err
+	}
+	return c, argv, nil
+}
+
+// Plan describes the exact command line a call would run.
+func (s *Connector) Plan(_ context.Context, req connectors.ExecRequest) (connectors.ExecPlan, error) {
+	c, argv, err := s.resolve(req)
+	if err != nil {
+		return connectors.ExecPlan{}, err
+	}
+	return connectors.ExecPlan{
+		Summary:   fmt.Sprintf("Run %s: %s", req.Action, strings.Join(argv, " ")),
+		Operation: "exec " + argv[0],
+		Fields: map[string]any{
+			"argv":        argv,
+			"timeout_sec": int(c.timeout().Seconds()),
+			"network":     c.Network,
+			"dir":         c.Dir,
+		},
+		ReadOnly: c.ReadOnly,
+	}, nil
+}
+
+// Result is the output of a run.
+type Result struct {
+	Argv []string `json:"argv"`
+	// ProgramSHA256 is the digest of the program file that was run, so the
+	// evidence pins the script version as well as its name.
+	ProgramSHA256   string `json:"program_sha256"`
+	ExitCode        int    `json:"exit_code"`
+	Stdout          string `json:"stdout"`
+	Stderr          string `json:"stderr"`
+	StdoutTruncated bool   `json:"stdout_truncated,omitempty"`
+	StderrTruncated bool   `json:"stderr_truncated,omitempty"`
+	TimedOut        bool   `json:"timed_out,omitempty"`
+	DurationMS      int64  `json:"duration_ms"`
+	Network         bool   `json:"network"`
+}
+
+// Exec runs the command named by req.Action. A run that exits non-zero or
+// times out yields status "error" with its Result as output.
+func (s *Connector) Exec(ctx context.Context, req connectors.ExecRequest) connectors.ExecResponse {
+	c, argv, err := s.resolve(req)
+	if err != nil {
+		return connectors.ExecResponse{Status: "error", Error: err.Error()}
+	}
+	res, err := s.run(ctx, req, c, argv)
+	if res == nil {
+		return connectors.ExecResponse{Status: "error", Error: err.Error()}
+	}
+	output, _ := json.Marshal(res)
+	output, truncation := connectors.TruncateOutput(output, req.OutputLimit())
+	if err != nil {
+		return connectors.ExecResponse{Status: "error", OutputJSON: output, Error: err.Error(), Truncation: truncation}
+	}
+	return connectors.ExecResponse{Status: "success", OutputJSON: output, Truncation: truncation}
+}
+
+// run runs argv under c's limits. It returns a nil Result when the command
+// could not be started.
+func (s *Connector) run(ctx context.Context, req connectors.ExecRequest, c Command, argv []string) (*Result, error) {
+	digest, err := fileSHA256(argv[0])
+	if err != nil {
+		return nil, err
+	}
+	dir := c.Dir
+	if dir == "" {
+		if dir, err = os.MkdirTemp("", "oc-sandbox-"); err != nil {
+			return nil, fmt.Errorf("create working directory: %w", err)
+		}
+		defer os.RemoveAll(dir)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout())
+	defer cancel()
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Dir = dir
+	cmd.Env = commandEnv(c, req, dir)
+	stdout := &capWriter{limit: c.outputCap()}
+	stderr := &capWriter{limit: c.outputCap()}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	// Children that outlive the command and hold its pipes open are given
+	// this long after it exits before the pipes are closed on them.
+	cmd.WaitDelay = 2 * time.Second
+	if err := isolate(cmd, c.Network); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start %s: %w", argv[0], err)
+	}
+	err = cmd.Wait()
+	res := &Result{
+		Argv:            argv,
+		ProgramSHA256:   digest,
+		ExitCode:        cmd.ProcessState.ExitCode(),
+		Stdout:          stdout.buf.String(),
+		Stderr:          stderr.buf.String(),
+		StdoutTruncated: stdout.truncated,
+		StderrTruncated: stderr.truncated,
+		DurationMS:      time.Since(start).Milliseconds(),
+		Network:         c.Network,
+	}
+	if ctx.Err() != nil {
+		res.TimedOut = true
+		s.log.WarnContext(ctx, "sandbox command timed out", "action", req.Action, "event_id", req.EventID)
+		return res, fmt.Errorf("%s timed out", req.Action)
+	}
+	if err != nil {
+		return res, fmt.Errorf("%s: %w", req.Action, err)
+	}
+	return res, nil
+}
+
+// commandEnv is the whole environment of a run.
+func commandEnv(c Command, req connectors.ExecRequest, dir string) []string {
+	env := []string{
+		"PATH=" + defaultPath,
+		"HOME=" + dir,
+		"OC_EVENT_ID=" + req.EventID,
+		"OC_TENANT_ID=" + req.TenantID,
+		"OC_AGENT_ID=" + req.AgentID,
+	}
+	keys := make([]string, 0, len(c.Env))
+	for k := range c.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		env = append(env, k+"="+c.Env[k])
+	}
+	return env
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("open program: %w", err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("hash program: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// capWriter keeps the first limit bytes written to it and discards the
+// rest, so a chatty command can neither exhaust memory nor block on a full
+// pipe.
+type capWriter struct {
+	buf       bytes.Buffer
+	limit     int64
+	truncated bool
+}
+
+func (w *capWriter) Write(p []byte) (int, error) {
+	if room := w.limit - int64(w.buf.Len()); room < int64(len(p)) {
+		w.buf.Write(p[:max(room, 0)])
+		w.truncated = true
+		return len(p), nil
+	}
+	w.buf.Write(p)
+	return len(p), nil
+}
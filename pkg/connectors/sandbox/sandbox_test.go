@@ -0,0 +1,138 @@
+package sandbox
+
+import (
+	"context"
+	"encoding/json"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/bturcanu/OpenClause/pkg/connectors"
+)
+
+const testCommands = `{
+	"echo": {"argv": ["/bin/echo", "hello"], "max_args": 2, "network": true, "read_only": true},
+	"fail": {"argv": ["/bin/sh", "-c", "echo oops >&2; exit 3"], "network": true},
+	"chatty": {"argv": ["/bin/sh", "-c", "yes | head -c 100000"], "max_output_bytes": 10, "network": true},
+	"slow": {"argv": ["/bin/sleep", "10"], "timeout_sec": 1, "network": true},
+	"interfaces": {"argv": ["/bin/cat", "/proc/net/dev"]}
+}`
+
+func newTestConnector(t *testing.T) *Connector {
+	t.Helper()
+	cmds, err := ParseCommands([]byte(testCommands))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(Config{Commands: cmds})
+}
+
+func run(t *testing.T, s *Connector, action, params string) (connectors.ExecResponse, Result) {
+	t.Helper()
+	resp := s.Exec(context.Background(), connectors.ExecRequest{Tool: "sandbox", Action: action, Params: json.RawMessage(params)})
+	var res Result
+	if len(resp.OutputJSON) > 0 {
+		if err := json.Unmarshal(resp.OutputJSON, &res); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp, res
+}
+
+func TestParseCommands_Invalid(t *testing.T) {
+	for _, raw := range []string{
+		`{"ls": {"argv": ["ls"]}}`,
+		`{"ls": {"argv": []}}`,
+		`{"Bad Name": {"argv": ["/bin/ls"]}}`,
+		`{"ls": {"argv": ["/bin/ls"], "arg_pattern": "("}}`,
+		`{"ls": {"argv": ["/bin/ls"], "shell": true}}`,
+	} {
+		if _, err := ParseCommands([]byte(raw)); err == nil {
+			t.Errorf("ParseCommands(%s) accepted", raw)
+		}
+	}
+}
+
+func TestArgPattern_MatchesWholeArgument(t *testing.T) {
+	cmds, err := ParseCommands([]byte(`{"rm": {"argv": ["/bin/rm"], "max_args": 2, "arg_pattern": "[a-z]+"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := cmds["rm"]
+	if _, err := c.argv(json.RawMessage(`{"args": ["-rf", "/"]}`)); err == nil {
+		t.Fatal("unanchored arg_pattern accepted -rf")
+	}
+	if argv, err := c.argv(json.RawMessage(`{"args": ["tmp"]}`)); err != nil || len(argv) != 2 {
+		t.Fatalf("argv = %v, %v", argv, err)
+	}
+}
+
+func TestExec(t *testing.T) {
+	s := newTestConnector(t)
+
+	resp, res := run(t, s, "echo", `{"args":["world"]}`)
+	if resp.Status != "success" || res.Stdout != "hello world\n" || res.ExitCode != 0 || len(res.ProgramSHA256) != 64 {
+		t.Fatalf("echo: resp = %+v, result = %+v", resp, res)
+	}
+
+	// Arguments are checked against the allowlist before anything runs.
+	for _, params := range []string{`{"args":["--help"]}`, `{"args":["a","b","c"]}`, `{"args":["$(id)"]}`, `{"cmd":"id"}`} {
+		if resp, _ := run(t, s, "echo", params); resp.Status != "error" || len(resp.OutputJSON) != 0 {
+			t.Errorf("echo %s: resp = %+v", params, resp)
+		}
+	}
+	if resp, _ := run(t, s, "rm", `{}`); resp.Status != "error" || !strings.Contains(resp.Error, "unsupported action") {
+		t.Fatalf("unlisted command: resp = %+v", resp)
+	}
+
+	resp, res = run(t, s, "fail", `{}`)
+	if resp.Status != "error" || res.ExitCode != 3 || res.Stderr != "oops\n" {
+		t.Fatalf("fail: resp = %+v, result = %+v", resp, res)
+	}
+
+	resp, res = run(t, s, "chatty", `{}`)
+	if resp.Status != "success" || len(res.Stdout) != 10 || !res.StdoutTruncated {
+		t.Fatalf("chatty: resp = %+v, result = %+v", resp, res)
+	}
+
+	resp, res = run(t, s, "slow", `{}`)
+	if resp.Status != "error" || !res.TimedOut || res.DurationMS > 5000 {
+		t.Fatalf("slow: resp = %+v, result = %+v", resp, res)
+	}
+}
+
+func TestExec_NoNetwork(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("network namespaces are Linux-only")
+	}
+	s := newTestConnector(t)
+	resp, res := run(t, s, "interfaces", `{}`)
+	if resp.Status != "success" && strings.Contains(resp.Error, "operation not permitted") {
+		t.Skip("user namespaces are not available:", resp.Error)
+	}
+	if resp.Status != "success" {
+		t.Fatalf("resp = %+v", resp)
+	}
+	// Only the loopback interface exists in the command's namespace.
+	for _, line := range strings.Split(strings.TrimSpace(res.Stdout), "\n")[2:] {
+		if name, _, _ := strings.Cut(strings.TrimSpace(line), ":"); name != "lo" {
+			t.Fatalf("interface %q visible:\n%s", name, res.Stdout)
+		}
+	}
+}
+
+func TestPlanAndManifest(t *testing.T) {
+	s := newTestConnector(t)
+	plan, err := s.Plan(context.Background(), connectors.ExecRequest{Tool: "sandbox", Action: "echo", Params: json.RawMessage(`{"args":["world"]}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Summary != "Run echo: /bin/echo hello world" || !plan.ReadOnly {
+		t.Fatalf("plan = %+v", plan)
+	}
+
+	m := s.Manifest()
+	if m.Tool != "sandbox" || len(m.Actions) != 5 || m.Actions[0].Action != "chatty" {
+		t.Fatalf("manifest = %+v", m)
+	}
+}
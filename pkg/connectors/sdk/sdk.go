@@ -6,9 +6,10 @@ import (
 	"encoding/json"
 	"log/slog"
 	"net/http"
-	"time"
+	"runtime/debug"
 
 	"github.com/bturcanu/OpenClause/pkg/connectors"
+	ocOtel "github.com/bturcanu/OpenClause/pkg/otel"
 )
 
 const maxBodyBytes = 1 << 20
@@ -17,9 +18,71 @@ type Executor interface {
 	Exec(context.Context, connectors.ExecRequest) connectors.ExecResponse
 }
 
+// Planner is implemented by executors that can describe a request without
+// performing it. Plan requests to an executor that is not a Planner are
+// answered with an error and never reach Exec.
+type Planner interface {
+	Plan(context.Context, connectors.ExecRequest) (connectors.ExecPlan, error)
+}
+
 type Config struct {
 	InternalToken string
 	Logger        *slog.Logger
+	// Name, Version and Endpoint identify the connector in execution
+	// evidence: its name, its build (BuildVersion when empty) and the
+	// upstream system it calls.
+	Name     string
+	Version  string
+	Endpoint string
+}
+
+// Identify fills resp.Connector from c, keeping any fields the executor
+// set itself, such as a per-tenant endpoint.
+func (c Config) Identify(resp *connectors.ExecResponse) {
+	if resp.Connector == nil {
+		resp.Connector = &connectors.ConnectorInfo{}
+	}
+	id := resp.Connector
+	if id.Name == "" {
+		id.Name = c.Name
+	}
+	if id.Version == "" {
+		id.Version = c.Version
+		if id.Version == "" {
+			id.Version = BuildVersion()
+		}
+	}
+	if id.Endpoint == "" {
+		id.Endpoint = c.Endpoint
+	}
+}
+
+// BuildVersion returns the running binary's VCS revision (with a -dirty
+// suffix for modified trees), else its module version, else "unknown".
+func BuildVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	var rev string
+	var dirty bool
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			rev = s.Value
+		case "vcs.modified":
+			dirty = s.Value == "true"
+		}
+	}
+	switch {
+	case rev != "" && dirty:
+		return rev + "-dirty"
+	case rev != "":
+		return rev
+	case info.Main.Version != "":
+		return info.Main.Version
+	}
+	return "unknown"
 }
 
 func Handler(executor Executor, cfg Config) http.HandlerFunc {
@@ -38,12 +101,61 @@ func Handler(executor Executor, cfg Config) http.HandlerFunc {
 			http.Error(w, "invalid body", http.StatusBadRequest)
 			return
 		}
-		ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+		ctx, cancel := req.Context(ocOtel.ExtractHTTP(r.Context(), r.Header))
 		defer cancel()
-		resp := executor.Exec(ctx, req)
+		var resp connectors.ExecResponse
+		if req.Plan {
+			resp = plan(ctx, executor, req)
+		} else {
+			resp = executor.Exec(ctx, req)
+			cfg.Identify(&resp)
+		}
 		w.Header().Set("Content-Type", "application/json")
 		if err := json.NewEncoder(w).Encode(resp); err != nil {
 			log.Error("encode response failed", "error", err)
 		}
 	}
 }
+
+func plan(ctx context.Context, executor Executor, req connectors.ExecRequest) connectors.ExecResponse {
+	p, ok := executor.(Planner)
+	if !ok {
+		return connectors.ExecResponse{Status: "error", Error: "plan not supported"}
+	}
+	out, err := p.Plan(ctx, req)
+	if err != nil {
+		return connectors.ExecResponse{Status: "error", Error: err.Error()}
+	}
+	return connectors.Planned(out)
+}
+
+// ManifestHandler serves the connector's capability manifest at GET
+// /manifest, behind the same internal token as /exec.
+func ManifestHandler(m connectors.Manifest, cfg Config) http.HandlerFunc {
+	return ManifestFuncHandler(func(context.Context) (connectors.Manifest, error) { return m, nil }, cfg)
+}
+
+// ManifestFuncHandler is ManifestHandler for connectors whose actions are
+// discovered at run time; a manifest error is answered with 502.
+func ManifestFuncHandler(manifest func(context.Context) (connectors.Manifest, error), cfg Config) http.HandlerFunc {
+	log := cfg.Logger
+	if log == nil {
+		log = slog.Default()
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if cfg.InternalToken != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Internal-Token")), []byte(cfg.InternalToken)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		m, err := manifest(r.Context())
+		if err != nil {
+			log.Error("build manifest failed", "error", err)
+			http.Error(w, "manifest unavailable", http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(m); err != nil {
+			log.Error("encode manifest failed", "error", err)
+		}
+	}
+}
@@ -0,0 +1,734 @@
+// Package slack is the Slack connector: it posts and deletes messages,
+// lists channels, and posts and resolves the approvals service's approval
+// messages. cmd/connector-slack serves it on its own; cmd/openclause serves
+// it in-process.
+package slack
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bturcanu/OpenClause/pkg/connectors"
+	"github.com/bturcanu/OpenClause/pkg/connectors/sdk"
+	"github.com/bturcanu/OpenClause/pkg/credentials"
+	ocOtel "github.com/bturcanu/OpenClause/pkg/otel"
+	"github.com/bturcanu/OpenClause/pkg/secrets"
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+const maxBodyBytes = 1 << 20 // 1 MB
+
+// Config configures a Connector.
+type Config struct {
+	Logger *slog.Logger
+	// Mock answers calls with canned output instead of calling Slack.
+	Mock bool
+	// Token is the deployment-wide bot token, SLACK_BOT_TOKEN.
+	Token *secrets.Value
+	// Creds, when set, supplies tenants' own bot tokens.
+	Creds *credentials.Resolver
+	// InternalToken is the X-Internal-Token callers must send.
+	InternalToken string
+}
+
+// New creates a Slack connector.
+func New(cfg Config) *Connector {
+	log := cfg.Logger
+	if log == nil {
+		log = slog.Default()
+	}
+	return &Connector{
+		log:           log,
+		mock:          cfg.Mock,
+		token:         cfg.Token,
+		creds:         cfg.Creds,
+		internalToken: cfg.InternalToken,
+		// Calls are bounded by each request's Context instead.
+		httpClient: &http.Client{},
+	}
+}
+
+// Handler serves the connector API: /healthz, /manifest and /exec.
+func (s *Connector) Handler() http.Handler {
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(ocOtel.Middleware)
+	r.Use(middleware.Recoverer)
+
+	r.Get("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("OK"))
+	})
+
+	r.Get("/manifest", sdk.ManifestHandler(manifest, sdk.Config{InternalToken: s.internalToken}))
+
+	identity := sdk.Config{Name: "connector-slack", Endpoint: "https://slack.com/api"}
+	if s.mock {
+		identity.Endpoint = "mock"
+	}
+
+	r.Post("/exec", func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Internal-Token")), []byte(s.internalToken)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+		var req connectors.ExecRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid body", http.StatusBadRequest)
+			return
+		}
+
+		resp := s.Exec(r.Context(), req)
+		if !req.Plan {
+			identity.Identify(&resp)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			s.log.Error("response encode failed", "error", err)
+		}
+	})
+	return r
+}
+
+// Connector is the Slack connector.
+type Connector struct {
+	log           *slog.Logger
+	mock          bool
+	token         *secrets.Value
+	creds         *credentials.Resolver
+	internalToken string
+	httpClient    *http.Client
+}
+
+// bearer returns the tenant's own bot token when one is stored, otherwise
+// the deployment-wide SLACK_BOT_TOKEN.
+func (s *Connector) bearer(ctx context.Context, tenantID string) (string, error) {
+	token, err := s.creds.Lookup(ctx, tenantID, "slack", "bot_token", s.token.Get())
+	if err != nil {
+		return "", err
+	}
+	if token == "" {
+		return "", fmt.Errorf("no Slack bot token configured for tenant %s", tenantID)
+	}
+	return "Bearer " + token, nil
+}
+
+// manifest lists the actions agents can call; the approval actions are
+// used by the approvals service.
+var manifest = connectors.Manifest{
+	Tool: "slack",
+	Plan: true,
+	Actions: []connectors.ActionManifest{
+		{
+			Action:      "msg.post",
+			Description: "Post a message to a Slack channel.",
+			Params: json.RawMessage(`{"type":"object","properties":{` +
+				`"channel":{"type":"string","description":"Channel ID or name"},` +
+				`"text":{"type":"string","description":"Message text"}},` +
+				`"required":["channel","text"]}`),
+		},
+		{
+			Action:      "msg.delete",
+			Description: "Delete a Slack message.",
+			Params: json.RawMessage(`{"type":"object","properties":{` +
+				`"channel":{"type":"string","description":"Channel ID"},` +
+				`"ts":{"type":"string","description":"Timestamp of the message to delete"}},` +
+				`"required":["channel","ts"]}`),
+		},
+		{
+			Action:      "channel.list",
+			Description: "List Slack channels.",
+			Params:      json.RawMessage(`{"type":"object","properties":{}}`),
+			ReadOnly:    true,
+		},
+		{Action: "approval.request", Internal: true},
+		{Action: "approval.resolve", Internal: true},
+		{Action: "approval.comment", Internal: true},
+	},
+}
+
+type slackMsgParams struct {
+	Channel string `json:"channel"`
+	Text    string `json:"text"`
+}
+
+type slackApprovalMessageParams struct {
+	Channel           string   `json:"channel"`
+	Tool              string   `json:"tool"`
+	Action            string   `json:"action"`
+	Resource          string   `json:"resource"`
+	RiskScore         int      `json:"risk_score"`
+	Reason            string   `json:"reason"`
+	ApprovalURL       string   `json:"approval_url"`
+	ApprovalRequestID string   `json:"approval_request_id"`
+	EventID           string   `json:"event_id"`
+	TenantID          string   `json:"tenant_id"`
+	RiskFactors       []string `json:"risk_factors,omitempty"`
+	// Plan is the gated call's dry run, shown so approvers see the change.
+	Plan *connectors.ExecPlan `json:"plan,omitempty"`
+	// RecentActivity is the agent's latest calls, newest first.
+	RecentActivity []slackActivity `json:"recent_activity,omitempty"`
+}
+
+// slackActivity is one of the agent's recent calls (approvals.AgentActivity).
+type slackActivity struct {
+	Tool     string    `json:"tool"`
+	Action   string    `json:"action"`
+	Resource string    `json:"resource,omitempty"`
+	Decision string    `json:"decision"`
+	Status   string    `json:"status,omitempty"`
+	At       time.Time `json:"at"`
+}
+
+// Exec performs req within the time the gateway waits for it.
+func (s *Connector) Exec(ctx context.Context, req connectors.ExecRequest) connectors.ExecResponse {
+	ctx, cancel := req.Context(ctx)
+	defer cancel()
+	action := req.Tool + "." + req.Action
+	if req.Plan {
+		return s.plan(req)
+	}
+	switch action {
+	case "slack.msg.post":
+		return s.postMessage(ctx, req)
+	case "slack.msg.delete":
+		return s.deleteMessage(ctx, req)
+	case "slack.channel.list":
+		return s.listChannels(ctx, req)
+	case "slack.approval.request":
+		return s.postApprovalMessage(ctx, req)
+	case "slack.approval.resolve":
+		return s.resolveApprovalMessage(ctx, req)
+	case "slack.approval.comment":
+		return s.postApprovalComment(ctx, req)
+	default:
+		return connectors.ExecResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("unsupported action: %s", action),
+		}
+	}
+}
+
+// plan describes a request without calling Slack. Approval messages are sent
+// by OpenClause itself and are never planned.
+func (s *Connector) plan(req connectors.ExecRequest) connectors.ExecResponse {
+	switch action := req.Tool + "." + req.Action; action {
+	case "slack.msg.post":
+		var params slackMsgParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return connectors.ExecResponse{Status: "error", Error: "invalid params: " + err.Error()}
+		}
+		if params.Channel == "" || params.Text == "" {
+			return connectors.ExecResponse{Status: "error", Error: "channel and text are required"}
+		}
+		return connectors.Planned(connectors.ExecPlan{
+			Summary:   fmt.Sprintf("Post a %d-character message to %s", utf8.RuneCountInString(params.Text), params.Channel),
+			Operation: "POST chat.postMessage",
+			Fields:    map[string]any{"channel": params.Channel, "text": params.Text},
+		})
+	case "slack.msg.delete":
+		params, errResp := msgDeleteParams(req)
+		if errResp != nil {
+			return *errResp
+		}
+		return connectors.Planned(connectors.ExecPlan{
+			Summary:   "Delete message " + params.TS + " in " + params.Channel,
+			Operation: "POST chat.delete",
+			Fields:    map[string]any{"channel": params.Channel, "ts": params.TS},
+		})
+	case "slack.channel.list":
+		return connectors.Planned(connectors.ExecPlan{
+			Summary:   "List up to 200 channels",
+			Operation: "GET conversations.list",
+			ReadOnly:  true,
+		})
+	default:
+		return connectors.ExecResponse{Status: "error", Error: fmt.Sprintf("plan not supported for %s", action)}
+	}
+}
+
+func (s *Connector) listChannels(ctx context.Context, req connectors.ExecRequest) connectors.ExecResponse {
+	if s.mock {
+		output, _ := json.Marshal(map[string]any{
+			"ok": true,
+			"channels": []map[string]any{
+				{"id": "C01GENERAL", "name": "general"},
+				{"id": "C02SECURITY", "name": "security-approvals"},
+			},
+			"mock": true,
+		})
+		return connectors.ExecResponse{Status: "success", OutputJSON: output}
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", "https://slack.com/api/conversations.list?limit=200", nil)
+	if err != nil {
+		return connectors.ExecResponse{Status: "error", Error: err.Error()}
+	}
+	bearer, err := s.bearer(ctx, req.TenantID)
+	if err != nil {
+		return connectors.ExecResponse{Status: "error", Error: err.Error()}
+	}
+	httpReq.Header.Set("Authorization", bearer)
+	resp, err := s.httpClient.Do(httpReq)
+	if err != nil {
+		return connectors.ExecResponse{Status: "error", Error: err.Error()}
+	}
+	defer resp.Body.Close()
+	respBody, err := connectors.ReadLimited(resp.Body, req.OutputLimit())
+	if err != nil {
+		return connectors.ExecResponse{Status: "error", Error: "read response: " + err.Error()}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return connectors.ExecResponse{Status: "error", Error: string(respBody)}
+	}
+	return connectors.ExecResponse{Status: "success", OutputJSON: respBody}
+}
+
+type slackActionValue struct {
+	Decision          string `json:"d"`
+	ApprovalRequestID string `json:"r"`
+	EventID           string `json:"e"`
+	TenantID          string `json:"t"`
+}
+
+func encodeActionValue(decision, requestID, eventID, tenantID string) string {
+	v := slackActionValue{Decision: decision, ApprovalRequestID: requestID, EventID: eventID, TenantID: tenantID}
+	b, _ := json.Marshal(v)
+	return base64.URLEncoding.EncodeToString(b)
+}
+
+// maxPlanBlockText keeps a plan section under Slack's 3000-character limit.
+const maxPlanBlockText = 2900
+
+// planBlock renders a connector plan as a section: the summary, the upstream
+// operation, and the fields it would send.
+func planBlock(plan *connectors.ExecPlan) map[string]any {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*Plan:* %s", plan.Summary)
+	if plan.Operation != "" {
+		fmt.Fprintf(&b, "\n`%s`", plan.Operation)
+	}
+	if len(plan.Fields) > 0 {
+		names := make([]string, 0, len(plan.Fields))
+		for name := range plan.Fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		b.WriteString("\n```")
+		for _, name := range names {
+			v, ok := plan.Fields[name].(string)
+			if !ok {
+				raw, _ := json.Marshal(plan.Fields[name])
+				v = string(raw)
+			}
+			fmt.Fprintf(&b, "\n%s: %s", name, strings.ReplaceAll(v, "```", "'''"))
+		}
+		b.WriteString("\n```")
+	}
+	text := b.String()
+	if r := []rune(text); len(r) > maxPlanBlockText {
+		text = string(r[:maxPlanBlockText]) + "…"
+		if len(plan.Fields) > 0 {
+			text += "```"
+		}
+	}
+	return map[string]any{"type": "section", "text": map[string]any{"type": "mrkdwn", "text": text}}
+}
+
+// activityBlock renders the agent's recent calls as a section, one line per
+// call, newest first.
+func activityBlock(activity []slackActivity) map[string]any {
+	var b strings.Builder
+	b.WriteString("*Recent agent activity:*")
+	for _, a := range activity {
+		fmt.Fprintf(&b, "\n• %s `%s.%s`", a.At.UTC().Format(time.RFC3339), a.Tool, a.Action)
+		if a.Resource != "" {
+			fmt.Fprintf(&b, " on `%s`", strings.ReplaceAll(a.Resource, "`", "'"))
+		}
+		fmt.Fprintf(&b, " — %s", a.Decision)
+		if a.Status != "" {
+			fmt.Fprintf(&b, ", %s", a.Status)
+		}
+	}
+	text := b.String()
+	if r := []rune(text); len(r) > maxPlanBlockText {
+		text = string(r[:maxPlanBlockText]) + "…"
+	}
+	return map[string]any{"type": "section", "text": map[string]any{"type": "mrkdwn", "text": text}}
+}
+
+func (s *Connector) postApprovalMessage(ctx context.Context, req connectors.ExecRequest) connectors.ExecResponse {
+	var params slackApprovalMessageParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return connectors.ExecResponse{Status: "error", Error: "invalid params: " + err.Error()}
+	}
+	if params.Channel == "" || params.ApprovalRequestID == "" || params.EventID == "" || params.TenantID == "" {
+		return connectors.ExecResponse{Status: "error", Error: "channel, approval_request_id, event_id, tenant_id are required"}
+	}
+	valueApprove := encodeActionValue("approve", params.ApprovalRequestID, params.EventID, params.TenantID)
+	valueDeny := encodeActionValue("deny", params.ApprovalRequestID, params.EventID, params.TenantID)
+	blocks := []map[string]any{
+		{
+			"type": "section",
+			"text": map[string]any{
+				"type": "mrkdwn",
+				"text": fmt.Sprintf("*Approval needed*\n`%s.%s` on `%s`\nRisk: *%d* — %s", params.Tool, params.Action, params.Resource, params.RiskScore, params.Reason),
+			},
+		},
+	}
+	if params.Plan != nil {
+		blocks = append(blocks, planBlock(params.Plan))
+	}
+	if len(params.RecentActivity) > 0 {
+		blocks = append(blocks, activityBlock(params.RecentActivity))
+	}
+	blocks = append(blocks, map[string]any{
+		"type": "actions",
+		"elements": []map[string]any{
+			{
+				"type":  "button",
+				"text":  map[string]any{"type": "plain_text", "text": "Approve"},
+				"style": "primary",
+				"value": valueApprove,
+			},
+			{
+				"type":  "button",
+				"text":  map[string]any{"type": "plain_text", "text": "Deny"},
+				"style": "danger",
+				"value": valueDeny,
+			},
+			{
+				"type": "button",
+				"text": map[string]any{"type": "plain_text", "text": "Open"},
+				"url":  params.ApprovalURL,
+			},
+		},
+	})
+
+	if s.mock {
+		output, _ := json.Marshal(map[string]any{
+			"ok":       true,
+			"channel":  params.Channel,
+			"ts":       "1700000000.000001",
+			"message":  map[string]any{"blocks": blocks},
+			"actionId": valueApprove,
+			"mock":     true,
+		})
+		return connectors.ExecResponse{Status: "success", OutputJSON: output}
+	}
+
+	return s.callWebAPI(ctx, req, "chat.postMessage", map[string]any{
+		"channel": params.Channel,
+		"text":    "Approval required",
+		"blocks":  blocks,
+	})
+}
+
+type slackApprovalResolveParams struct {
+	Channel           string `json:"channel"`
+	TS                string `json:"ts"`
+	Status            string `json:"status"` // approved | released | denied | cancelled | expired
+	ResolvedBy        string `json:"resolved_by"`
+	ResolutionReason  string `json:"resolution_reason"`
+	Tool              string `json:"tool"`
+	Action            string `json:"action"`
+	Resource          string `json:"resource"`
+	RiskScore         int    `json:"risk_score"`
+	Reason            string `json:"reason"`
+	ApprovalURL       string `json:"approval_url"`
+	ApprovalRequestID string `json:"approval_request_id"`
+}
+
+// resolveApprovalMessage rewrites a posted approval message with its outcome.
+// The replacement has no actions block, so the Approve/Deny buttons are gone
+// whichever channel resolved the request.
+func (s *Connector) resolveApprovalMessage(ctx context.Context, req connectors.ExecRequest) connectors.ExecResponse {
+	var params slackApprovalResolveParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return connectors.ExecResponse{Status: "error", Error: "invalid params: " + err.Error()}
+	}
+	if params.Channel == "" || params.TS == "" || params.Status == "" {
+		return connectors.ExecResponse{Status: "error", Error: "channel, ts, status are required"}
+	}
+	var outcome string
+	switch params.Status {
+	case "approved":
+		outcome = fmt.Sprintf(":white_check_mark: Approved by %s", params.ResolvedBy)
+	case "released":
+		outcome = fmt.Sprintf(":white_check_mark: Output released by %s", params.ResolvedBy)
+	case "denied":
+		outcome = fmt.Sprintf(":no_entry: Denied by %s", params.ResolvedBy)
+		if params.ResolutionReason != "" {
+			outcome += ": " + params.ResolutionReason
+		}
+	case "cancelled":
+		outcome = fmt.Sprintf(":leftwards_arrow_with_hook: Withdrawn by %s", params.ResolvedBy)
+		if params.ResolutionReason != "" {
+			outcome += ": " + params.ResolutionReason
+		}
+	case "expired":
+		outcome = ":hourglass: Expired without a decision"
+	default:
+		return connectors.ExecResponse{Status: "error", Error: "status must be approved, released, denied, cancelled or expired"}
+	}
+	footer := []map[string]any{{"type": "mrkdwn", "text": outcome}}
+	if params.ApprovalURL != "" {
+		footer = append(footer, map[string]any{"type": "mrkdwn", "text": fmt.Sprintf("<%s|Details>", params.ApprovalURL)})
+	}
+	blocks := []map[string]any{
+		{
+			"type": "section",
+			"text": map[string]any{
+				"type": "mrkdwn",
+				"text": fmt.Sprintf("*Approval %s*\n`%s.%s` on `%s`\nRisk: *%d* — %s", params.Status, params.Tool, params.Action, params.Resource, params.RiskScore, params.Reason),
+			},
+		},
+		{"type": "context", "elements": footer},
+	}
+
+	if s.mock {
+		s.log.Info("mock slack.approval.resolve", "channel", params.Channel, "ts", params.TS, "status", params.Status)
+		output, _ := json.Marshal(map[string]any{
+			"ok":      true,
+			"channel": params.Channel,
+			"ts":      params.TS,
+			"message": map[string]any{"blocks": blocks},
+			"mock":    true,
+		})
+		return connectors.ExecResponse{Status: "success", OutputJSON: output}
+	}
+
+	return s.callWebAPI(ctx, req, "chat.update", map[string]any{
+		"channel": params.Channel,
+		"ts":      params.TS,
+		"text":    "Approval " + params.Status,
+		"blocks":  blocks,
+	})
+}
+
+type slackApprovalCommentParams struct {
+	Channel           string `json:"channel"`
+	ThreadTS          string `json:"thread_ts"`
+	Author            string `json:"author"`
+	Role              string `json:"role"` // approver | requester
+	Body              string `json:"body"`
+	ApprovalURL       string `json:"approval_url"`
+	ApprovalRequestID string `json:"approval_request_id"`
+}
+
+// postApprovalComment mirrors a comment on an approval request as a reply
+// in the thread of its approval message.
+func (s *Connector) postApprovalComment(ctx context.Context, req connectors.ExecRequest) connectors.ExecResponse {
+	var params slackApprovalCommentParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return connectors.ExecResponse{Status: "error", Error: "invalid params: " + err.Error()}
+	}
+	if params.Channel == "" || params.ThreadTS == "" || params.Body == "" {
+		return connectors.ExecResponse{Status: "error", Error: "channel, thread_ts, body are required"}
+	}
+	who := params.Author
+	if params.Role == "requester" {
+		who = "agent " + who
+	}
+	blocks := []map[string]any{
+		{
+			"type": "section",
+			"text": map[string]any{"type": "mrkdwn", "text": fmt.Sprintf("*%s:* %s", who, params.Body)},
+		},
+	}
+	if params.ApprovalURL != "" {
+		blocks = append(blocks, map[string]any{
+			"type":     "context",
+			"elements": []map[string]any{{"type": "mrkdwn", "text": fmt.Sprintf("<%s|Reply in OpenClause>", params.ApprovalURL)}},
+		})
+	}
+
+	if s.mock {
+		s.log.Info("mock slack.approval.comment", "channel", params.Channel, "thread_ts", params.ThreadTS)
+		output, _ := json.Marshal(map[string]any{
+			"ok":      true,
+			"channel": params.Channel,
+			"ts":      "1700000000.000002",
+			"message": map[string]any{"thread_ts": params.ThreadTS, "blocks": blocks},
+			"mock":    true,
+		})
+		return connectors.ExecResponse{Status: "success", OutputJSON: output}
+	}
+
+	return s.callWebAPI(ctx, req, "chat.postMessage", map[string]any{
+		"channel":   params.Channel,
+		"thread_ts": params.ThreadTS,
+		"text":      who + ": " + params.Body,
+		"blocks":    blocks,
+	})
+}
+
+// callWebAPI POSTs a JSON body to a Slack Web API method and maps Slack's
+// "ok": false envelope to an error response.
+func (s *Connector) callWebAPI(ctx context.Context, req connectors.ExecRequest, method string, payload map[string]any) connectors.ExecResponse {
+	body, _ := json.Marshal(payload)
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", "https://slack.com/api/"+method, bytes.NewReader(body))
+	if err != nil {
+		return connectors.ExecResponse{Status: "error", Error: err.Error()}
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	bearer, err := s.bearer(ctx, req.TenantID)
+	if err != nil {
+		return connectors.ExecResponse{Status: "error", Error: err.Error()}
+	}
+	httpReq.Header.Set("Authorization", bearer)
+	resp, err := s.httpClient.Do(httpReq)
+	if err != nil {
+		return connectors.ExecResponse{Status: "error", Error: err.Error()}
+	}
+	defer resp.Body.Close()
+	respBody, err := connectors.ReadLimited(resp.Body, req.OutputLimit())
+	if err != nil {
+		return connectors.ExecResponse{Status: "error", Error: "read response: " + err.Error()}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return connectors.ExecResponse{Status: "error", Error: string(respBody)}
+	}
+	var slackResp struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(respBody, &slackResp); err != nil {
+		return connectors.ExecResponse{Status: "error", Error: "slack: invalid response body", OutputJSON: respBody}
+	}
+	if !slackResp.OK {
+		return connectors.ExecResponse{Status: "error", Error: "slack: " + slackResp.Error, OutputJSON: respBody}
+	}
+	return connectors.ExecResponse{Status: "success", OutputJSON: respBody}
+}
+
+func (s *Connector) postMessage(ctx context.Context, req connectors.ExecRequest) connectors.ExecResponse {
+	var params slackMsgParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return connectors.ExecResponse{Status: "error", Error: "invalid params: " + err.Error()}
+	}
+
+	if params.Channel == "" || params.Text == "" {
+		return connectors.ExecResponse{Status: "error", Error: "channel and text are required"}
+	}
+
+	if s.mock {
+		s.log.Info("mock slack.msg.post", "channel", params.Channel, "text_len", len(params.Text))
+		output, _ := json.Marshal(map[string]any{
+			"ok":      true,
+			"channel": params.Channel,
+			"ts":      fmt.Sprintf("%d.000000", time.Now().Unix()),
+			"mock":    true,
+		})
+		return postedMessage(connectors.ExecResponse{Status: "success", OutputJSON: output})
+	}
+
+	body, _ := json.Marshal(map[string]string{
+		"channel": params.Channel,
+		"text":    params.Text,
+	})
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", "https://slack.com/api/chat.postMessage", bytes.NewReader(body))
+	if err != nil {
+		return connectors.ExecResponse{Status: "error", Error: err.Error()}
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	bearer, err := s.bearer(ctx, req.TenantID)
+	if err != nil {
+		return connectors.ExecResponse{Status: "error", Error: err.Error()}
+	}
+	httpReq.Header.Set("Authorization", bearer)
+
+	resp, err := s.httpClient.Do(httpReq)
+	if err != nil {
+		return connectors.ExecResponse{Status: "error", Error: err.Error()}
+	}
+	defer resp.Body.Close()
+
+	respBody, err := connectors.ReadLimited(resp.Body, req.OutputLimit())
+	if err != nil {
+		return connectors.ExecResponse{Status: "error", Error: "read response: " + err.Error()}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return connectors.ExecResponse{Status: "error", Error: string(respBody)}
+	}
+
+	var slackResp struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(respBody, &slackResp); err != nil {
+		return connectors.ExecResponse{Status: "error", Error: "slack: invalid response body", OutputJSON: respBody}
+	}
+	if !slackResp.OK {
+		return connectors.ExecResponse{Status: "error", Error: "slack: " + slackResp.Error, OutputJSON: respBody}
+	}
+	return postedMessage(connectors.ExecResponse{Status: "success", OutputJSON: respBody})
+}
+
+// postedMessage declares msg.delete of the posted message,
+// slack://channel/<channel>/message/<ts>, as the compensation of a
+// successful msg.post.
+func postedMessage(resp connectors.ExecResponse) connectors.ExecResponse {
+	var posted slackMsgRef
+	if err := json.Unmarshal(resp.OutputJSON, &posted); err == nil && posted.Channel != "" && posted.TS != "" {
+		params, _ := json.Marshal(posted)
+		resp.Compensation = &connectors.Compensation{
+			Action:   "msg.delete",
+			Params:   params,
+			Resource: types.NewResourceURI("slack", "channel", posted.Channel, "message", posted.TS).String(),
+		}
+	}
+	return resp
+}
+
+// slackMsgRef identifies a posted message; it is the msg.delete params.
+type slackMsgRef struct {
+	Channel string `json:"channel"`
+	TS      string `json:"ts"`
+}
+
+func msgDeleteParams(req connectors.ExecRequest) (slackMsgRef, *connectors.ExecResponse) {
+	var params slackMsgRef
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return params, &connectors.ExecResponse{Status: "error", Error: "invalid params: " + err.Error()}
+	}
+	if params.Channel == "" || params.TS == "" {
+		return params, &connectors.ExecResponse{Status: "error", Error: "channel and ts are required"}
+	}
+	return params, nil
+}
+
+func (s *Connector) deleteMessage(ctx context.Context, req connectors.ExecRequest) connectors.ExecResponse {
+	params, errResp := msgDeleteParams(req)
+	if errResp != nil {
+		return *errResp
+	}
+	if s.mock {
+		s.log.Info("mock slack.msg.delete", "channel", params.Channel, "ts", params.TS)
+		output, _ := json.Marshal(map[string]any{
+			"ok":      true,
+			"channel": params.Channel,
+			"ts":      params.TS,
+			"mock":    true,
+		})
+		return connectors.ExecResponse{Status: "success", OutputJSON: output}
+	}
+	return s.callWebAPI(ctx, req, "chat.delete", map[string]any{
+		"channel": params.Channel,
+		"ts":      params.TS,
+	})
+}
@@ -0,0 +1,81 @@
+package connectors
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Tool spec formats served by GET /v1/tools/spec.
+const (
+	SpecFormatOpenAI    = "openai"
+	SpecFormatAnthropic = "anthropic"
+)
+
+// maxFunctionName is the longest function name OpenAI and Anthropic accept.
+const maxFunctionName = 64
+
+// emptyParams is the schema of an action that declares no params.
+var emptyParams = json.RawMessage(`{"type":"object","properties":{}}`)
+
+// FunctionName is the LLM function name for tool.action: the dots become
+// "__", since function names may not contain dots ("jira.issue.create" is
+// "jira__issue__create").
+func FunctionName(tool, action string) string {
+	return strings.ReplaceAll(tool+"."+action, ".", "__")
+}
+
+// ParseFunctionName reverses FunctionName.
+func ParseFunctionName(name string) (tool, action string, ok bool) {
+	tool, action, ok = strings.Cut(strings.ReplaceAll(name, "__", "."), ".")
+	return tool, action, ok && tool != "" && action != ""
+}
+
+// ToolSpecs renders the agent-callable actions of manifests as function
+// definitions in format, sorted by name. permit, if set, filters which
+// tool.action pairs are included. An action offered by several connectors
+// appears once.
+func ToolSpecs(format string, manifests []Manifest, permit func(tool, action string) bool) ([]map[string]any, error) {
+	if format != SpecFormatOpenAI && format != SpecFormatAnthropic {
+		return nil, fmt.Errorf("format must be %s or %s", SpecFormatOpenAI, SpecFormatAnthropic)
+	}
+	actions := make(map[string]ActionManifest)
+	for _, m := range manifests {
+		for _, a := range m.Actions {
+			name := FunctionName(m.Tool, a.Action)
+			if _, dup := actions[name]; dup || a.Internal || len(name) > maxFunctionName {
+				continue
+			}
+			if permit != nil && !permit(m.Tool, a.Action) {
+				continue
+			}
+			if a.Description == "" {
+				a.Description = m.Tool + "." + a.Action
+			}
+			if len(a.Params) == 0 || string(a.Params) == "null" {
+				a.Params = emptyParams
+			}
+			actions[name] = a
+		}
+	}
+	names := make([]string, 0, len(actions))
+	for name := range actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out := make([]map[string]any, 0, len(names))
+	for _, name := range names {
+		a := actions[name]
+		if format == SpecFormatAnthropic {
+			out = append(out, map[string]any{"name": name, "description": a.Description, "input_schema": a.Params})
+			continue
+		}
+		out = append(out, map[string]any{
+			"type":     "function",
+			"function": map[string]any{"name": name, "description": a.Description, "parameters": a.Params},
+		})
+	}
+	return out, nil
+}
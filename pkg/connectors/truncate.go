@@ -0,0 +1,71 @@
+package connectors
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"unicode/utf8"
+)
+
+// OutputTruncation describes output that was cut to fit the gateway's
+// limit.
+type OutputTruncation struct {
+	// OriginalBytes and SHA256 are the size and hex SHA-256 digest of the
+	// full output.
+	OriginalBytes int64  `json:"original_bytes"`
+	SHA256        string `json:"sha256"`
+	// Full is the full output when whoever truncated it still has it, so
+	// the gateway can spill it to object storage. It is never sent.
+	Full json.RawMessage `json:"-"`
+}
+
+// truncatedOutput is the JSON that stands in for output over the limit.
+type truncatedOutput struct {
+	Truncated     bool   `json:"truncated"`
+	OriginalBytes int64  `json:"original_bytes"`
+	SHA256        string `json:"sha256"`
+	// Preview is the start of the full output, cut on a UTF-8 boundary.
+	Preview string `json:"preview"`
+}
+
+// TruncateOutput returns output unchanged when it fits in limit bytes.
+// Otherwise it returns a JSON object of at most limit bytes standing in for
+// it, {"truncated":true,"original_bytes":…,"sha256":…,"preview":…}, and
+// the truncation. The result depends only on output and limit, and is
+// always valid JSON; cutting output itself at a byte offset would not be.
+// A limit too small for the object without a preview yields the object
+// with an empty preview.
+func TruncateOutput(output json.RawMessage, limit int64) (json.RawMessage, *OutputTruncation) {
+	if int64(len(output)) <= limit {
+		return output, nil
+	}
+	sum := sha256.Sum256(output)
+	t := &OutputTruncation{OriginalBytes: int64(len(output)), SHA256: hex.EncodeToString(sum[:]), Full: output}
+	stub := truncatedOutput{Truncated: true, OriginalBytes: t.OriginalBytes, SHA256: t.SHA256}
+
+	// Escaping can make the preview longer than the bytes it holds, so
+	// shrink it by the overshoot until the object fits.
+	n := limit
+	for {
+		n = max(n, 0)
+		stub.Preview = previewOf(output, int(n))
+		b, _ := json.Marshal(stub)
+		over := int64(len(b)) - limit
+		if over <= 0 || n == 0 {
+			return b, t
+		}
+		n = int64(len(stub.Preview)) - over
+	}
+}
+
+// previewOf returns up to n leading bytes of b as a string, backing off to
+// the start of a UTF-8 sequence so no character is split.
+func previewOf(b []byte, n int) string {
+	if n >= len(b) {
+		return string(b)
+	}
+	for n > 0 && !utf8.RuneStart(b[n]) {
+		n--
+	}
+	return string(b[:n])
+}
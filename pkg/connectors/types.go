@@ -4,6 +4,16 @@ package connectors
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Limits a connector applies to requests that carry none, e.g. from a
+// gateway that predates TimeoutMS and MaxOutputBytes.
+const (
+	DefaultExecTimeout    = 15 * time.Second
+	DefaultMaxOutputBytes = 4 << 20 // 4 MB
 )
 
 // Connector executes a tool action on an external system.
@@ -20,11 +30,122 @@ type ExecRequest struct {
 	Action   string          `json:"action"`
 	Params   json.RawMessage `json:"params"`
 	Resource string          `json:"resource,omitempty"`
+	// Deadline, when set, is the caller's deadline; the connector must give
+	// up on the call by then.
+	Deadline *time.Time `json:"deadline,omitempty"`
+	// TimeoutMS is how long, from when the request was sent, the gateway
+	// waits for the answer: the tighter of the caller's deadline and the
+	// gateway's connector timeout. Unlike Deadline it does not depend on
+	// the connector's clock agreeing with the gateway's.
+	TimeoutMS int64 `json:"timeout_ms,omitempty"`
+	// MaxOutputBytes is the largest output_json the gateway keeps; larger
+	// output is truncated (see TruncateOutput). Connectors bound their
+	// upstream reads by it rather than fetch output the gateway would cut.
+	MaxOutputBytes int64 `json:"max_output_bytes,omitempty"`
+	// Plan asks for a dry run: the connector must not change anything
+	// upstream and answers with Planned, or with an error if it cannot
+	// describe the action.
+	Plan bool `json:"plan,omitempty"`
+	// Pin, when set, is the version label the tenant pinned the tool to.
+	// The registry sends the call only to backends with that label.
+	Pin string `json:"-"`
+}
+
+// Context bounds ctx by the time the gateway waits for req: TimeoutMS,
+// else Deadline, else DefaultExecTimeout.
+func (req ExecRequest) Context(ctx context.Context) (context.Context, context.CancelFunc) {
+	switch {
+	case req.TimeoutMS > 0:
+		return context.WithTimeout(ctx, time.Duration(req.TimeoutMS)*time.Millisecond)
+	case req.Deadline != nil:
+		return context.WithDeadline(ctx, *req.Deadline)
+	}
+	return context.WithTimeout(ctx, DefaultExecTimeout)
+}
+
+// OutputLimit is MaxOutputBytes, or DefaultMaxOutputBytes when unset.
+func (req ExecRequest) OutputLimit() int64 {
+	if req.MaxOutputBytes > 0 {
+		return req.MaxOutputBytes
+	}
+	return DefaultMaxOutputBytes
+}
+
+// ReadLimited reads r to the end, failing rather than truncating when it
+// holds more than limit bytes.
+func ReadLimited(r io.Reader, limit int64) ([]byte, error) {
+	b, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) > limit {
+		return nil, fmt.Errorf("response exceeds %d bytes", limit)
+	}
+	return b, nil
 }
 
 // ExecResponse is what the connector returns.
 type ExecResponse struct {
-	Status     string          `json:"status"` // "success" | "error"
+	Status     string          `json:"status"` // "success" | "error" | "planned"
 	OutputJSON json.RawMessage `json:"output_json,omitempty"`
 	Error      string          `json:"error,omitempty"`
+	Plan       *ExecPlan       `json:"plan,omitempty"`
+	// Compensation declares how to undo a successful call. The gateway keeps
+	// it with the execution evidence and runs it on request.
+	Compensation *Compensation `json:"compensation,omitempty"`
+	// Cost is what the call spent upstream (API credits, a dollar
+	// estimate). The gateway charges it to the tenant's and agent's budgets.
+	Cost float64 `json:"cost,omitempty"`
+	// Connector identifies the build that handled the call. The SDK
+	// handler fills it from its Config; the registry adds Backend.
+	Connector *ConnectorInfo `json:"connector,omitempty"`
+	// Truncation is set when OutputJSON stands in for output over the
+	// limit; see TruncateOutput. Connectors that cannot return their output
+	// whole truncate it themselves, and the registry truncates output that
+	// arrives over the limit.
+	Truncation *OutputTruncation `json:"truncation,omitempty"`
+	// Flags are findings about the output that need a human to look at it
+	// before the agent does, e.g. "dlp:credit_card" from a DLP scan. The
+	// gateway quarantines flagged output of successful calls.
+	Flags []string `json:"flags,omitempty"`
+}
+
+// ConnectorInfo identifies the connector build behind an execution, for the
+// evidence record.
+type ConnectorInfo struct {
+	// Name is the connector's name, e.g. "connector-jira".
+	Name string `json:"name,omitempty"`
+	// Version is the connector's build version, e.g. a VCS revision.
+	Version string `json:"version,omitempty"`
+	// Endpoint is the upstream system the connector called, e.g. the Jira
+	// base URL.
+	Endpoint string `json:"endpoint,omitempty"`
+	// Backend is the connector URL the gateway sent the call to, set by the
+	// registry rather than reported by the connector.
+	Backend string `json:"backend,omitempty"`
+}
+
+// Compensation is the compensating (undo) action for one executed call: an
+// action on the same tool, with the params that identify what to undo.
+type Compensation struct {
+	Action   string          `json:"action"`
+	Params   json.RawMessage `json:"params,omitempty"`
+	Resource string          `json:"resource,omitempty"`
+}
+
+// ExecPlan describes what a connector would do for a request, so approvers
+// see the concrete change before they grant it.
+type ExecPlan struct {
+	// Summary is one human-readable line, e.g. "Create Task in OPS: Rotate keys".
+	Summary string `json:"summary"`
+	// Operation is the upstream call, e.g. "POST /rest/api/3/issue".
+	Operation string `json:"operation,omitempty"`
+	// Fields are the values the call would send, after defaults are applied.
+	Fields   map[string]any `json:"fields,omitempty"`
+	ReadOnly bool           `json:"read_only,omitempty"`
+}
+
+// Planned is the response to a plan request.
+func Planned(plan ExecPlan) ExecResponse {
+	return ExecResponse{Status: "planned", Plan: &plan}
 }
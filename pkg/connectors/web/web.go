@@ -0,0 +1,350 @@
+// Package web is the web connector: its read-only fetch action retrieves a
+// page over https for research agents, limited to each tenant's allowed
+// domains, to public addresses, to text content types and to a size and time
+// budget. cmd/connector-web serves it.
+package web
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bturcanu/OpenClause/pkg/approvals"
+	"github.com/bturcanu/OpenClause/pkg/connectors"
+	"github.com/bturcanu/OpenClause/pkg/connectors/sdk"
+	ocOtel "github.com/bturcanu/OpenClause/pkg/otel"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Defaults for a Config that leaves the limits unset.
+const (
+	DefaultMaxBytes  = 1 << 20 // 1 MB
+	DefaultTimeout   = 10 * time.Second
+	DefaultRedirects = 5
+)
+
+// DefaultContentTypes are the media types fetched when Config sets none.
+var DefaultContentTypes = []string{
+	"text/html", "text/plain", "text/markdown", "text/csv", "text/xml",
+	"application/json", "application/xml", "application/xhtml+xml",
+}
+
+// userAgent identifies the connector to the sites it fetches.
+const userAgent = "OpenClause-WebFetch/1.0"
+
+// Allowlists maps tenant → domains its fetches may reach. The "*" entry
+// applies to tenants without their own.
+type Allowlists map[string][]string
+
+// ParseAllowlists parses "tenant1:example.com|docs.example.org,*:example.org".
+// A domain also allows its subdomains.
+func ParseAllowlists(raw string) (Allowlists, error) {
+	out := Allowlists{}
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		tenantID, domains, ok := strings.Cut(entry, ":")
+		tenantID = strings.TrimSpace(tenantID)
+		if !ok || tenantID == "" {
+			return nil, fmt.Errorf("web.ParseAllowlists: %q: want tenant:domain|domain", entry)
+		}
+		for _, d := range strings.Split(domains, "|") {
+			if d = strings.ToLower(strings.TrimSpace(d)); d != "" {
+				out[tenantID] = append(out[tenantID], d)
+			}
+		}
+		p := approvals.EgressPolicy{AllowedDomains: out[tenantID]}
+		if err := p.Validate(); err != nil {
+			return nil, fmt.Errorf("web.ParseAllowlists: %s: %w", tenantID, err)
+		}
+	}
+	return out, nil
+}
+
+// domains returns the domains tenantID may fetch from.
+func (a Allowlists) domains(tenantID string) []string {
+	if d, ok := a[tenantID]; ok {
+		return d
+	}
+	return a["*"]
+}
+
+// Config configures a Connector.
+type Config struct {
+	Logger *slog.Logger
+	// Allowlists are the domains each tenant may fetch from. A tenant
+	// with no entry, and no "*" entry to fall back on, can fetch nothing.
+	Allowlists Allowlists
+	// AllowedCIDRs, when set, is the complete list of address ranges a
+	// fetched host may resolve to, and may name private ranges. By default
+	// only public addresses are allowed.
+	AllowedCIDRs []string
+	// ContentTypes are the media types a response may have; "text/*"
+	// matches every text type. DefaultContentTypes when empty.
+	ContentTypes []string
+	// MaxBytes caps the body kept per fetch (DefaultMaxBytes when 0).
+	// The call's output limit also applies.
+	MaxBytes int64
+	// Timeout bounds each fetch, redirects included (DefaultTimeout when
+	// 0). The call's own deadline also applies.
+	Timeout time.Duration
+	// InternalToken is the X-Internal-Token callers must send.
+	InternalToken string
+}
+
+// Connector is the web connector. It implements sdk.Executor and
+// sdk.Planner.
+type Connector struct {
+	log           *slog.Logger
+	allowlists    Allowlists
+	allowedCIDRs  []string
+	contentTypes  []string
+	maxBytes      int64
+	timeout       time.Duration
+	internalToken string
+	client        *http.Client
+}
+
+// New creates a web connector.
+func New(cfg Config) *Connector {
+	log := cfg.Logger
+	if log == nil {
+		log = slog.Default()
+	}
+	c := &Connector{
+		log:           log,
+		allowlists:    cfg.Allowlists,
+		allowedCIDRs:  cfg.AllowedCIDRs,
+		contentTypes:  cfg.ContentTypes,
+		maxBytes:      cfg.MaxBytes,
+		timeout:       cfg.Timeout,
+		internalToken: cfg.InternalToken,
+	}
+	if len(c.contentTypes) == 0 {
+		c.contentTypes = DefaultContentTypes
+	}
+	if c.maxBytes <= 0 {
+		c.maxBytes = DefaultMaxBytes
+	}
+	if c.timeout <= 0 {
+		c.timeout = DefaultTimeout
+	}
+	// Fetches are bounded by their context instead.
+	c.client = approvals.NewEgressClient(0)
+	return c
+}
+
+// Handler serves the connector API: /healthz, /manifest and /exec.
+func (c *Connector) Handler() http.Handler {
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(ocOtel.Middleware)
+	r.Use(middleware.Recoverer)
+
+	r.Get("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("OK"))
+	})
+
+	sdkCfg := sdk.Config{InternalToken: c.internalToken, Logger: c.log, Name: "connector-web", Endpoint: "web"}
+	r.Get("/manifest", sdk.ManifestHandler(manifest, sdkCfg))
+	r.Post("/exec", sdk.Handler(c, sdkCfg))
+	return r
+}
+
+// manifest lists the actions the connector implements.
+var manifest = connectors.Manifest{
+	Tool: "web",
+	Plan: true,
+	Actions: []connectors.ActionManifest{{
+		Action:      "fetch",
+		Description: "Fetch a web page or document over https from an allowed domain.",
+		Params:      json.RawMessage(`{"type":"object","properties":{"url":{"type":"string","description":"https URL to fetch"}},"required":["url"],"additionalProperties":false}`),
+		ReadOnly:    true,
+	}},
+}
+
+// fetchParams are the params of web.fetch.
+type fetchParams struct {
+	URL string `json:"url"`
+}
+
+// Page is the output of web.fetch.
+type Page struct {
+	URL         string `json:"url"`
+	FinalURL    string `json:"final_url"`
+	StatusCode  int    `json:"status_code"`
+	ContentType string `json:"content_type"`
+	Content     string `json:"content"`
+	// Bytes and SHA256 describe the body as kept, after truncation.
+	Bytes     int64  `json:"bytes"`
+	SHA256    string `json:"sha256"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// request checks req and returns its URL and the egress policy it is held to.
+func (c *Connector) request(req connectors.ExecRequest) (string, approvals.EgressPolicy, error) {
+	if req.Tool != "web" || req.Action != "fetch" {
+		return "", approvals.EgressPolicy{}, fmt.Errorf("unsupported action: %s.%s", req.Tool, req.Action)
+	}
+	var p fetchParams
+	if err := json.Unmarshal(req.Params, &p); err != nil || p.URL == "" {
+		return "", approvals.EgressPolicy{}, errors.New("params.url is required")
+	}
+	domains := c.allowlists.domains(req.TenantID)
+	if len(domains) == 0 {
+		return "", approvals.EgressPolicy{}, fmt.Errorf("no domains are allowed for tenant %s", req.TenantID)
+	}
+	policy := approvals.EgressPolicy{AllowedDomains: domains, AllowedCIDRs: c.allowedCIDRs}
+	if err := checkURL(policy, p.URL); err != nil {
+		return "", approvals.EgressPolicy{}, err
+	}
+	return p.URL, policy, nil
+}
+
+// checkURL checks rawURL against policy. Unlike for webhooks, an IP literal
+// must be listed as a domain itself: a tenant's allowlist names the sites
+// it may read, not every public address.
+func checkURL(policy approvals.EgressPolicy, rawURL string) error {
+	if err := policy.CheckURL(rawURL); err != nil {
+		return err
+	}
+	u, _ := url.Parse(rawURL)
+	host := u.Hostname()
+	if _, err := netip.ParseAddr(host); err == nil {
+		for _, d := range policy.AllowedDomains {
+			if d == host {
+				return nil
+			}
+		}
+		return fmt.Errorf("host %s is not in the allowed domains", host)
+	}
+	return nil
+}
+
+// Plan describes the fetch.
+func (c *Connector) Plan(_ context.Context, req connectors.ExecRequest) (connectors.ExecPlan, error) {
+	rawURL, _, err := c.request(req)
+	if err != nil {
+		return connectors.ExecPlan{}, err
+	}
+	return connectors.ExecPlan{
+		Summary:   "Fetch " + rawURL,
+		Operation: "GET " + rawURL,
+		Fields:    map[string]any{"url": rawURL, "max_bytes": c.maxBytes, "timeout_sec": int(c.timeout.Seconds())},
+		ReadOnly:  true,
+	}, nil
+}
+
+// Exec fetches params.url. Each redirect is checked against the tenant's
+// allowlist as the original URL was. A response that is not 2xx yields
+// status "error" with the page as output; one whose content type is not
+// allowed yields an error without its body.
+func (c *Connector) Exec(ctx context.Context, req connectors.ExecRequest) connectors.ExecResponse {
+	rawURL, policy, err := c.request(req)
+	if err != nil {
+		return connectors.ExecResponse{Status: "error", Error: err.Error()}
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	page, err := c.fetch(approvals.WithEgressPolicy(ctx, policy), policy, rawURL, min(c.maxBytes, req.OutputLimit()))
+	if page == nil {
+		return connectors.ExecResponse{Status: "error", Error: err.Error()}
+	}
+	output, _ := json.Marshal(page)
+	output, truncation := connectors.TruncateOutput(output, req.OutputLimit())
+	if err != nil {
+		return connectors.ExecResponse{Status: "error", OutputJSON: output, Error: err.Error(), Truncation: truncation}
+	}
+	return connectors.ExecResponse{Status: "success", OutputJSON: output, Truncation: truncation}
+}
+
+// fetch GETs rawURL, following up to DefaultRedirects redirects. It returns
+// a nil Page when there is no body to show.
+func (c *Connector) fetch(ctx context.Context, policy approvals.EgressPolicy, rawURL string, limit int64) (*Page, error) {
+	next := rawURL
+	for hop := 0; ; hop++ {
+		if hop > 0 {
+			if err := checkURL(policy, next); err != nil {
+				return nil, fmt.Errorf("redirect to %s refused: %w", next, err)
+			}
+		}
+		httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, next, nil)
+		if err != nil {
+			return nil, err
+		}
+		httpReq.Header.Set("User-Agent", userAgent)
+		httpReq.Header.Set("Accept", strings.Join(c.contentTypes, ", "))
+		resp, err := c.client.Do(httpReq)
+		if errors.Is(err, approvals.ErrRedirectRefused) && resp != nil {
+			loc, lerr := resp.Location()
+			if lerr != nil {
+				return nil, fmt.Errorf("redirect without a valid Location: %w", lerr)
+			}
+			if hop >= DefaultRedirects {
+				return nil, fmt.Errorf("stopped after %d redirects", DefaultRedirects)
+			}
+			next = loc.String()
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		return c.read(resp, rawURL, next, limit)
+	}
+}
+
+// read builds the Page for resp, keeping at most limit bytes of its body.
+func (c *Connector) read(resp *http.Response, rawURL, finalURL string, limit int64) (*Page, error) {
+	contentType := resp.Header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if !c.contentTypeAllowed(mediaType) {
+		return nil, fmt.Errorf("content type %q is not allowed", contentType)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+	page := &Page{URL: rawURL, FinalURL: finalURL, StatusCode: resp.StatusCode, ContentType: contentType}
+	if int64(len(body)) > limit {
+		// Cut at the start of a character so none is split.
+		n := int(limit)
+		for n > 0 && !utf8.RuneStart(body[n]) {
+			n--
+		}
+		body, page.Truncated = body[:n], true
+	}
+	sum := sha256.Sum256(body)
+	page.Content, page.Bytes, page.SHA256 = string(body), int64(len(body)), hex.EncodeToString(sum[:])
+	if resp.StatusCode/100 != 2 {
+		return page, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return page, nil
+}
+
+func (c *Connector) contentTypeAllowed(mediaType string) bool {
+	mediaType = strings.ToLower(mediaType)
+	for _, t := range c.contentTypes {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == mediaType || (strings.HasSuffix(t, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(t, "*"))) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bturcanu/OpenClause/pkg/connectors"
+)
+
+func TestParseAllowlists(t *testing.T) {
+	a, err := ParseAllowlists("tenant1:Example.com|docs.example.org, *:example.org")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := a.domains("tenant1"); len(got) != 2 || got[0] != "example.com" {
+		t.Fatalf("tenant1 = %v", got)
+	}
+	if got := a.domains("tenant2"); len(got) != 1 || got[0] != "example.org" {
+		t.Fatalf("tenant2 = %v", got)
+	}
+	for _, raw := range []string{"example.com", "tenant1:https://example.com", "tenant1:*.example.com"} {
+		if _, err := ParseAllowlists(raw); err == nil {
+			t.Errorf("ParseAllowlists(%q) accepted", raw)
+		}
+	}
+}
+
+func TestFetch(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/page":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = w.Write([]byte("<p>héllo world</p>"))
+		case "/redirect":
+			http.Redirect(w, r, "/page", http.StatusFound)
+		case "/away":
+			http.Redirect(w, r, "https://example.net/", http.StatusFound)
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte("\x89PNG"))
+		default:
+			w.Header().Set("Content-Type", "text/plain")
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := New(Config{
+		Allowlists:   Allowlists{"tenant1": {"127.0.0.1"}},
+		AllowedCIDRs: []string{"127.0.0.0/8"},
+		MaxBytes:     11,
+	})
+	c.client.Transport.(*http.Transport).TLSClientConfig = srv.Client().Transport.(*http.Transport).TLSClientConfig
+	fetch := func(tenantID, rawURL string) (connectors.ExecResponse, Page) {
+		t.Helper()
+		params, _ := json.Marshal(fetchParams{URL: rawURL})
+		resp := c.Exec(context.Background(), connectors.ExecRequest{TenantID: tenantID, Tool: "web", Action: "fetch", Params: params})
+		var page Page
+		if len(resp.OutputJSON) > 0 {
+			if err := json.Unmarshal(resp.OutputJSON, &page); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return resp, page
+	}
+
+	// The body is cut at MaxBytes, on a character boundary.
+	resp, page := fetch("tenant1", srv.URL+"/page")
+	if resp.Status != "success" || page.Content != "<p>héllo w" || !page.Truncated || page.StatusCode != 200 || len(page.SHA256) != 64 {
+		t.Fatalf("page: resp = %+v, page = %+v", resp, page)
+	}
+	resp, page = fetch("tenant1", srv.URL+"/redirect")
+	if resp.Status != "success" || page.FinalURL != srv.URL+"/page" || page.URL != srv.URL+"/redirect" {
+		t.Fatalf("redirect: resp = %+v, page = %+v", resp, page)
+	}
+	resp, page = fetch("tenant1", srv.URL+"/missing")
+	if resp.Status != "error" || page.StatusCode != 404 {
+		t.Fatalf("missing: resp = %+v, page = %+v", resp, page)
+	}
+
+	for _, tc := range []struct {
+		tenantID, url, err string
+	}{
+		{"tenant1", srv.URL + "/away", "redirect to https://example.net/ refused"},
+		{"tenant1", srv.URL + "/image", `content type "image/png" is not allowed`},
+		{"tenant1", "https://example.com/", "not in the allowed domains"},
+		{"tenant1", strings.Replace(srv.URL, "https", "http", 1) + "/page", "only https"},
+		{"tenant2", srv.URL + "/page", "no domains are allowed"},
+	} {
+		resp, _ := fetch(tc.tenantID, tc.url)
+		if resp.Status != "error" || !strings.Contains(resp.Error, tc.err) || len(resp.OutputJSON) != 0 {
+			t.Errorf("%s %s: resp = %+v", tc.tenantID, tc.url, resp)
+		}
+	}
+
+	// Without AllowedCIDRs only public addresses may be reached.
+	c.allowedCIDRs = nil
+	if resp, _ := fetch("tenant1", srv.URL+"/page"); resp.Status != "error" || !strings.Contains(resp.Error, "private/loopback") {
+		t.Fatalf("loopback: resp = %+v", resp)
+	}
+}
@@ -0,0 +1,220 @@
+// Package credentials stores per-tenant upstream credentials (Slack bot
+// tokens, Jira API tokens, ...) encrypted at rest in Postgres, and lets
+// connectors resolve them by the tenant_id carried in each ExecRequest so one
+// connector deployment can act on behalf of many tenants' accounts.
+package credentials
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/awssig"
+	"github.com/bturcanu/OpenClause/pkg/config"
+)
+
+// Cipher encrypts credential values. aad binds a ciphertext to the row it
+// belongs to, so a value copied to another tenant or name fails to decrypt.
+// keyID records which key produced the ciphertext so keys can be rotated.
+type Cipher interface {
+	Encrypt(ctx context.Context, plaintext, aad []byte) (keyID string, ciphertext []byte, err error)
+	Decrypt(ctx context.Context, keyID string, ciphertext, aad []byte) ([]byte, error)
+}
+
+// CipherFromEnv returns a KMS cipher when CREDENTIALS_KMS_KEY_ID is set,
+// otherwise a local keyring from CREDENTIALS_ENCRYPTION_KEYS.
+func CipherFromEnv(env config.Env) (Cipher, error) {
+	if keyID := env.Get("CREDENTIALS_KMS_KEY_ID"); keyID != "" {
+		return NewKMSFromEnv(env, keyID), nil
+	}
+	raw := env.Get("CREDENTIALS_ENCRYPTION_KEYS")
+	if raw == "" {
+		return nil, fmt.Errorf("credentials: CREDENTIALS_ENCRYPTION_KEYS or CREDENTIALS_KMS_KEY_ID is required")
+	}
+	return ParseKeyring(raw)
+}
+
+// ── Local keyring (AES-256-GCM) ──────────────────────────────────────────
+
+// Keyring encrypts with its primary key and decrypts with any key it holds.
+type Keyring struct {
+	primary string
+	keys    map[string]cipher.AEAD
+}
+
+// ParseKeyring parses comma-separated "id:base64key" pairs. Each key must
+// decode to 32 bytes. The first entry is the primary (encrypting) key; the
+// rest remain available for decrypting rows written before a rotation.
+func ParseKeyring(raw string) (*Keyring, error) {
+	kr := &Keyring{keys: make(map[string]cipher.AEAD)}
+	for _, pair := range strings.Split(raw, ",") {
+		id, b64, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("credentials.ParseKeyring: entry %q is not id:base64key", pair)
+		}
+		key, err := base64.StdEncoding.DecodeString(b64)
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("credentials.ParseKeyring: key %q must be 32 base64-encoded bytes", id)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("credentials.ParseKeyring: %w", err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("credentials.ParseKeyring: %w", err)
+		}
+		if _, dup := kr.keys[id]; dup {
+			return nil, fmt.Errorf("credentials.ParseKeyring: duplicate key id %q", id)
+		}
+		kr.keys[id] = aead
+		if kr.primary == "" {
+			kr.primary = id
+		}
+	}
+	return kr, nil
+}
+
+// Encrypt seals plaintext with the primary key; the nonce is prepended.
+func (k *Keyring) Encrypt(_ context.Context, plaintext, aad []byte) (string, []byte, error) {
+	aead := k.keys[k.primary]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", nil, fmt.Errorf("credentials.Keyring.Encrypt: %w", err)
+	}
+	return k.primary, aead.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+// Decrypt opens ciphertext with the key named keyID.
+func (k *Keyring) Decrypt(_ context.Context, keyID string, ciphertext, aad []byte) ([]byte, error) {
+	aead, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("credentials.Keyring.Decrypt: unknown key id %q", keyID)
+	}
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, fmt.Errorf("credentials.Keyring.Decrypt: ciphertext too short")
+	}
+	nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	out, err := aead.Open(nil, nonce, sealed, aad)
+	if err != nil {
+		return nil, fmt.Errorf("credentials.Keyring.Decrypt: %w", err)
+	}
+	return out, nil
+}
+
+// ── AWS KMS ──────────────────────────────────────────────────────────────
+
+// KMS encrypts each value directly with an AWS KMS key (values are well
+// under the 4 KiB Encrypt limit). The aad is passed as encryption context,
+// which KMS also records in CloudTrail.
+type KMS struct {
+	KeyID      string
+	Region     string
+	Creds      awssig.Credentials
+	Endpoint   string // default https://kms.<region>.amazonaws.com
+	HTTPClient *http.Client
+}
+
+// NewKMSFromEnv configures KMS for keyID using AWS_REGION and the standard
+// AWS_* credential variables. CREDENTIALS_KMS_ENDPOINT overrides the endpoint.
+func NewKMSFromEnv(env config.Env, keyID string) *KMS {
+	region := env.Get("AWS_REGION")
+	if region == "" {
+		region = env.Get("AWS_DEFAULT_REGION")
+	}
+	return &KMS{
+		KeyID:  keyID,
+		Region: region,
+		Creds: awssig.Credentials{
+			AccessKeyID:     env.Get("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: env.Get("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    env.Get("AWS_SESSION_TOKEN"),
+		},
+		Endpoint:   env.Get("CREDENTIALS_KMS_ENDPOINT"),
+		HTTPClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Encrypt calls kms:Encrypt. The returned key ID is the key ARN KMS reports.
+func (k *KMS) Encrypt(ctx context.Context, plaintext, aad []byte) (string, []byte, error) {
+	var out struct {
+		CiphertextBlob []byte `json:"CiphertextBlob"`
+		KeyID          string `json:"KeyId"`
+	}
+	err := k.call(ctx, "TrentService.Encrypt", map[string]any{
+		"KeyId":             k.KeyID,
+		"Plaintext":         plaintext,
+		"EncryptionContext": map[string]string{"oc": string(aad)},
+	}, &out)
+	if err != nil {
+		return "", nil, fmt.Errorf("credentials.KMS.Encrypt: %w", err)
+	}
+	return "kms:" + out.KeyID, out.CiphertextBlob, nil
+}
+
+// Decrypt calls kms:Decrypt, pinning the key recorded at encryption time.
+func (k *KMS) Decrypt(ctx context.Context, keyID string, ciphertext, aad []byte) ([]byte, error) {
+	arn, ok := strings.CutPrefix(keyID, "kms:")
+	if !ok {
+		return nil, fmt.Errorf("credentials.KMS.Decrypt: key id %q was not produced by KMS", keyID)
+	}
+	var out struct {
+		Plaintext []byte `json:"Plaintext"`
+	}
+	err := k.call(ctx, "TrentService.Decrypt", map[string]any{
+		"KeyId":             arn,
+		"CiphertextBlob":    ciphertext,
+		"EncryptionContext": map[string]string{"oc": string(aad)},
+	}, &out)
+	if err != nil {
+		return nil, fmt.Errorf("credentials.KMS.Decrypt: %w", err)
+	}
+	return out.Plaintext, nil
+}
+
+// call performs one KMS JSON API request. []byte fields marshal to base64,
+// which is what KMS expects for blobs.
+func (k *KMS) call(ctx context.Context, target string, in, out any) error {
+	endpoint := k.Endpoint
+	if endpoint == "" {
+		endpoint = "https://kms." + k.Region + ".amazonaws.com"
+	}
+	payload, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(endpoint, "/")+"/", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", target)
+	awssig.Sign(req, payload, k.Creds, k.Region, "kms", time.Now().UTC())
+
+	client := k.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&apiErr)
+		return fmt.Errorf("status %d: %s %s", resp.StatusCode, apiErr.Type, apiErr.Message)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,210 @@
+package credentials
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/auth"
+	"github.com/go-chi/chi/v5"
+)
+
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(b), 32)))
+}
+
+func TestKeyring_RoundTripAndRotation(t *testing.T) {
+	ctx := context.Background()
+	old, err := ParseKeyring("k1:" + testKey('a'))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyID, ct, err := old.Encrypt(ctx, []byte("xoxb-tenant1"), aad("t1", "slack", "bot_token"))
+	if err != nil || keyID != "k1" {
+		t.Fatalf("Encrypt = %q, %v", keyID, err)
+	}
+
+	// After rotation k2 encrypts, but k1 rows still decrypt.
+	rotated, err := ParseKeyring("k2:" + testKey('b') + ",k1:" + testKey('a'))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pt, err := rotated.Decrypt(ctx, keyID, ct, aad("t1", "slack", "bot_token"))
+	if err != nil || string(pt) != "xoxb-tenant1" {
+		t.Fatalf("Decrypt = %q, %v", pt, err)
+	}
+	if id, _, _ := rotated.Encrypt(ctx, []byte("x"), nil); id != "k2" {
+		t.Fatalf("primary key = %q, want k2", id)
+	}
+
+	// A ciphertext moved to another tenant's row must not decrypt.
+	if _, err := rotated.Decrypt(ctx, keyID, ct, aad("t2", "slack", "bot_token")); err == nil {
+		t.Fatal("expected AAD mismatch to fail")
+	}
+}
+
+func TestParseKeyring_Invalid(t *testing.T) {
+	for _, raw := range []string{"", "nokey", "k1:short", "k1:" + testKey('a') + ",k1:" + testKey('b')} {
+		if _, err := ParseKeyring(raw); err == nil {
+			t.Errorf("ParseKeyring(%q): expected error", raw)
+		}
+	}
+}
+
+func TestKMS_EncryptDecrypt(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Authorization"), "/us-east-1/kms/aws4_request") {
+			http.Error(w, `{"__type":"UnrecognizedClientException"}`, http.StatusBadRequest)
+			return
+		}
+		var in struct {
+			Plaintext         []byte
+			CiphertextBlob    []byte
+			EncryptionContext map[string]string
+		}
+		_ = json.NewDecoder(r.Body).Decode(&in)
+		switch r.Header.Get("X-Amz-Target") {
+		case "TrentService.Encrypt":
+			blob := append([]byte(in.EncryptionContext["oc"]+"|"), in.Plaintext...)
+			_ = json.NewEncoder(w).Encode(map[string]any{"CiphertextBlob": blob, "KeyId": "arn:aws:kms:us-east-1:1:key/abc"})
+		case "TrentService.Decrypt":
+			ctxPrefix := in.EncryptionContext["oc"] + "|"
+			if !strings.HasPrefix(string(in.CiphertextBlob), ctxPrefix) {
+				http.Error(w, `{"__type":"InvalidCiphertextException"}`, http.StatusBadRequest)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"Plaintext": in.CiphertextBlob[len(ctxPrefix):]})
+		}
+	}))
+	defer srv.Close()
+
+	k := &KMS{KeyID: "alias/oc", Region: "us-east-1", Endpoint: srv.URL, HTTPClient: srv.Client()}
+	ctx := context.Background()
+	keyID, ct, err := k.Encrypt(ctx, []byte("jira-token"), aad("t1", "jira", "api_token"))
+	if err != nil || keyID != "kms:arn:aws:kms:us-east-1:1:key/abc" {
+		t.Fatalf("Encrypt = %q, %v", keyID, err)
+	}
+	pt, err := k.Decrypt(ctx, keyID, ct, aad("t1", "jira", "api_token"))
+	if err != nil || string(pt) != "jira-token" {
+		t.Fatalf("Decrypt = %q, %v", pt, err)
+	}
+	if _, err := k.Decrypt(ctx, keyID, ct, aad("t2", "jira", "api_token")); err == nil {
+		t.Fatal("expected encryption context mismatch to fail")
+	}
+}
+
+type fakeGetter struct {
+	values map[string]string
+	calls  int
+	err    error
+}
+
+func (f *fakeGetter) Get(_ context.Context, tenantID, connector, name string) (string, bool, error) {
+	f.calls++
+	if f.err != nil {
+		return "", false, f.err
+	}
+	v, ok := f.values[tenantID+"/"+connector+"/"+name]
+	return v, ok, nil
+}
+
+func TestResolver_LookupCachesAndFallsBack(t *testing.T) {
+	g := &fakeGetter{values: map[string]string{"t1/slack/bot_token": "xoxb-t1"}}
+	r := NewResolver(g, time.Minute)
+	ctx := context.Background()
+
+	for range 3 {
+		v, err := r.Lookup(ctx, "t1", "slack", "bot_token", "xoxb-shared")
+		if err != nil || v != "xoxb-t1" {
+			t.Fatalf("Lookup(t1) = %q, %v", v, err)
+		}
+	}
+	v, err := r.Lookup(ctx, "t2", "slack", "bot_token", "xoxb-shared")
+	if err != nil || v != "xoxb-shared" {
+		t.Fatalf("Lookup(t2) = %q, %v", v, err)
+	}
+	if g.calls != 2 {
+		t.Fatalf("store calls = %d, want 2 (hits and misses cached)", g.calls)
+	}
+
+	g.err = errors.New("db down")
+	if _, err := r.Lookup(ctx, "t3", "slack", "bot_token", "xoxb-shared"); err == nil {
+		t.Fatal("store error must not fall back to the shared credential")
+	}
+}
+
+type fakeStore struct {
+	puts    map[string]string
+	deleted bool
+}
+
+func (f *fakeStore) Put(_ context.Context, tenantID, connector, name, value string) (*Credential, error) {
+	f.puts[tenantID+"/"+connector+"/"+name] = value
+	return &Credential{TenantID: tenantID, Connector: connector, Name: name, KeyID: "k1"}, nil
+}
+
+func (f *fakeStore) List(_ context.Context, tenantID string) ([]Credential, error) {
+	return []Credential{{TenantID: tenantID, Connector: "slack", Name: "bot_token"}}, nil
+}
+
+func (f *fakeStore) Delete(_ context.Context, _, _, _ string) (bool, error) {
+	return f.deleted, nil
+}
+
+func TestHandlers_AdminOnlyAndWriteOnly(t *testing.T) {
+	store := &fakeStore{puts: map[string]string{}}
+	r := chi.NewRouter()
+	r.Use(auth.AdminAuth("admin-secret"))
+	NewHandlers(store, nil).RegisterRoutes(r)
+
+	// A tenant's API key cannot manage its credentials.
+	req := httptest.NewRequest(http.MethodPut, "/v1/admin/tenants/t1/connector-credentials/slack/bot_token", strings.NewReader(`{"value":"evil"}`))
+	req.Header.Set("X-API-Key", "sk-1")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized || len(store.puts) != 0 {
+		t.Fatalf("PUT with an API key = %d, puts=%v", rec.Code, store.puts)
+	}
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-Admin-Token", "admin-secret")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec = do(http.MethodPut, "/v1/admin/tenants/t1/connector-credentials/slack/bot_token", `{"value":"xoxb-secret"}`)
+	if rec.Code != http.StatusOK || store.puts["t1/slack/bot_token"] != "xoxb-secret" {
+		t.Fatalf("PUT = %d %s, puts=%v", rec.Code, rec.Body, store.puts)
+	}
+	if strings.Contains(rec.Body.String(), "xoxb-secret") {
+		t.Fatal("PUT response must not echo the value")
+	}
+
+	if rec := do(http.MethodPut, "/v1/admin/tenants/t1/connector-credentials/Slack!/bot_token", `{"value":"x"}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("invalid connector name: status %d", rec.Code)
+	}
+	if rec := do(http.MethodPut, "/v1/admin/tenants/t1/connector-credentials/slack/bot_token", `{"value":""}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("empty value: status %d", rec.Code)
+	}
+
+	rec = do(http.MethodGet, "/v1/admin/tenants/t1/connector-credentials", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"tenant_id":"t1"`) {
+		t.Fatalf("GET = %d %s", rec.Code, rec.Body)
+	}
+
+	if rec := do(http.MethodDelete, "/v1/admin/tenants/t1/connector-credentials/slack/bot_token", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("DELETE missing: status %d", rec.Code)
+	}
+	store.deleted = true
+	if rec := do(http.MethodDelete, "/v1/admin/tenants/t1/connector-credentials/slack/bot_token", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("DELETE: status %d", rec.Code)
+	}
+}
@@ -0,0 +1,116 @@
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"github.com/go-chi/chi/v5"
+)
+
+const maxBodyBytes = 64 << 10
+
+type handlersStore interface {
+	Put(ctx context.Context, tenantID, connector, name, value string) (*Credential, error)
+	List(ctx context.Context, tenantID string) ([]Credential, error)
+	Delete(ctx context.Context, tenantID, connector, name string) (bool, error)
+}
+
+// Handlers exposes credential management to operators. A tenant's agents
+// cannot reach it: their API keys would otherwise let them swap in
+// credentials they control. Values are write-only: no endpoint returns them.
+type Handlers struct {
+	store handlersStore
+	log   *slog.Logger
+}
+
+// NewHandlers creates handlers backed by store.
+func NewHandlers(store handlersStore, log *slog.Logger) *Handlers {
+	if log == nil {
+		log = slog.Default()
+	}
+	return &Handlers{store: store, log: log}
+}
+
+// RegisterRoutes mounts the credential endpoints on r; callers wrap them in
+// admin authentication.
+func (h *Handlers) RegisterRoutes(r chi.Router) {
+	r.Get("/v1/admin/tenants/{tenant_id}/connector-credentials", h.List)
+	r.Put("/v1/admin/tenants/{tenant_id}/connector-credentials/{connector}/{name}", h.Put)
+	r.Delete("/v1/admin/tenants/{tenant_id}/connector-credentials/{connector}/{name}", h.Delete)
+}
+
+// List handles GET /v1/admin/tenants/{tenant_id}/connector-credentials
+func (h *Handlers) List(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenant_id")
+	creds, err := h.store.List(r.Context(), tenantID)
+	if err != nil {
+		h.log.Error("list credentials failed", "tenant_id", tenantID, "error", err)
+		types.ErrInternal("failed to list credentials").WriteJSON(w)
+		return
+	}
+	if creds == nil {
+		creds = []Credential{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]any{"credentials": creds}); err != nil {
+		h.log.Error("response encode failed", "error", err)
+	}
+}
+
+// Put handles PUT /v1/admin/tenants/{tenant_id}/connector-credentials/{connector}/{name}
+func (h *Handlers) Put(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenant_id")
+	connector, name := chi.URLParam(r, "connector"), chi.URLParam(r, "name")
+	if err := ValidateIdent("connector", connector); err != nil {
+		types.ErrValidation(err).WriteJSON(w)
+		return
+	}
+	if err := ValidateIdent("name", name); err != nil {
+		types.ErrValidation(err).WriteJSON(w)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	var in struct {
+		Value string `json:"value"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		types.ErrBadRequest("invalid JSON body").WriteJSON(w)
+		return
+	}
+	if in.Value == "" {
+		types.ErrBadRequest("value is required").WriteJSON(w)
+		return
+	}
+	c, err := h.store.Put(r.Context(), tenantID, connector, name, in.Value)
+	if err != nil {
+		h.log.Error("put credential failed", "tenant_id", tenantID, "connector", connector, "name", name, "error", err)
+		types.ErrInternal("failed to store credential").WriteJSON(w)
+		return
+	}
+	h.log.Info("connector credential stored", "tenant_id", tenantID, "connector", connector, "name", name, "key_id", c.KeyID)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(c); err != nil {
+		h.log.Error("response encode failed", "error", err)
+	}
+}
+
+// Delete handles DELETE /v1/admin/tenants/{tenant_id}/connector-credentials/{connector}/{name}
+func (h *Handlers) Delete(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenant_id")
+	connector, name := chi.URLParam(r, "connector"), chi.URLParam(r, "name")
+	found, err := h.store.Delete(r.Context(), tenantID, connector, name)
+	if err != nil {
+		h.log.Error("delete credential failed", "tenant_id", tenantID, "error", err)
+		types.ErrInternal("failed to delete credential").WriteJSON(w)
+		return
+	}
+	if !found {
+		types.ErrNotFound("credential not found").WriteJSON(w)
+		return
+	}
+	h.log.Info("connector credential deleted", "tenant_id", tenantID, "connector", connector, "name", name)
+	w.WriteHeader(http.StatusNoContent)
+}
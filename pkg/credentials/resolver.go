@@ -0,0 +1,71 @@
+package credentials
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const maxCacheEntries = 10_000
+
+type getter interface {
+	Get(ctx context.Context, tenantID, connector, name string) (string, bool, error)
+}
+
+// Resolver is the connector-side view of the store: it caches decrypted
+// values (and misses) for ttl so an exec does not cost a query plus a
+// decrypt, and falls back to the deployment-wide credential for tenants that
+// have not configured their own.
+type Resolver struct {
+	store getter
+	ttl   time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	value   string
+	found   bool
+	expires time.Time
+}
+
+// NewResolver creates a resolver over store. ttl <= 0 disables caching.
+func NewResolver(store getter, ttl time.Duration) *Resolver {
+	return &Resolver{store: store, ttl: ttl, cache: make(map[string]cacheEntry)}
+}
+
+// Lookup returns the tenant's credential, or fallback when the tenant has
+// none. A store error is returned rather than masked by the fallback, so a
+// tenant with its own account is never silently served by the shared one.
+func (r *Resolver) Lookup(ctx context.Context, tenantID, connector, name, fallback string) (string, error) {
+	if r == nil || tenantID == "" {
+		return fallback, nil
+	}
+	key := tenantID + "\x00" + connector + "\x00" + name
+	now := time.Now()
+
+	r.mu.Lock()
+	e, ok := r.cache[key]
+	r.mu.Unlock()
+	if !ok || now.After(e.expires) {
+		v, found, err := r.store.Get(ctx, tenantID, connector, name)
+		if err != nil {
+			return "", fmt.Errorf("credentials.Lookup %s/%s for tenant %s: %w", connector, name, tenantID, err)
+		}
+		e = cacheEntry{value: v, found: found, expires: now.Add(r.ttl)}
+		if r.ttl > 0 {
+			r.mu.Lock()
+			if len(r.cache) >= maxCacheEntries {
+				r.cache = make(map[string]cacheEntry)
+			}
+			r.cache[key] = e
+			r.mu.Unlock()
+		}
+	}
+	if !e.found {
+		return fallback, nil
+	}
+	return e.value, nil
+}
@@ -0,0 +1,133 @@
+package credentials
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Credential is a stored credential's metadata. Values are never listed.
+type Credential struct {
+	TenantID  string    `json:"tenant_id"`
+	Connector string    `json:"connector"`
+	Name      string    `json:"name"`
+	KeyID     string    `json:"key_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+var identRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,63}$`)
+
+// ValidateIdent checks a connector or credential name.
+func ValidateIdent(kind, v string) error {
+	if !identRe.MatchString(v) {
+		return fmt.Errorf("%s %q must match %s", kind, v, identRe)
+	}
+	return nil
+}
+
+// Store persists encrypted credentials in the connector_credentials table.
+type Store struct {
+	pool   *pgxpool.Pool
+	cipher Cipher
+}
+
+// NewStore creates a credential store that encrypts with c.
+func NewStore(pool *pgxpool.Pool, c Cipher) *Store {
+	return &Store{pool: pool, cipher: c}
+}
+
+// aad binds a ciphertext to its row.
+func aad(tenantID, connector, name string) []byte {
+	return []byte(tenantID + "\x00" + connector + "\x00" + name)
+}
+
+// Put encrypts value and upserts it.
+func (s *Store) Put(ctx context.Context, tenantID, connector, name, value string) (*Credential, error) {
+	if tenantID == "" || value == "" {
+		return nil, fmt.Errorf("credentials.Put: tenant_id and value are required")
+	}
+	if err := ValidateIdent("connector", connector); err != nil {
+		return nil, fmt.Errorf("credentials.Put: %w", err)
+	}
+	if err := ValidateIdent("name", name); err != nil {
+		return nil, fmt.Errorf("credentials.Put: %w", err)
+	}
+	keyID, ct, err := s.cipher.Encrypt(ctx, []byte(value), aad(tenantID, connector, name))
+	if err != nil {
+		return nil, fmt.Errorf("credentials.Put: %w", err)
+	}
+	c := &Credential{TenantID: tenantID, Connector: connector, Name: name, KeyID: keyID}
+	err = s.pool.QueryRow(ctx, `
+		INSERT INTO connector_credentials (tenant_id, connector, name, key_id, ciphertext)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (tenant_id, connector, name)
+		DO UPDATE SET key_id = EXCLUDED.key_id, ciphertext = EXCLUDED.ciphertext, updated_at = NOW()
+		RETURNING created_at, updated_at`,
+		tenantID, connector, name, keyID, ct,
+	).Scan(&c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("credentials.Put: %w", err)
+	}
+	return c, nil
+}
+
+// Get decrypts a credential. It returns ("", false, nil) when none is stored.
+func (s *Store) Get(ctx context.Context, tenantID, connector, name string) (string, bool, error) {
+	var keyID string
+	var ct []byte
+	err := s.pool.QueryRow(ctx, `
+		SELECT key_id, ciphertext FROM connector_credentials
+		WHERE tenant_id = $1 AND connector = $2 AND name = $3`,
+		tenantID, connector, name,
+	).Scan(&keyID, &ct)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("credentials.Get: %w", err)
+	}
+	pt, err := s.cipher.Decrypt(ctx, keyID, ct, aad(tenantID, connector, name))
+	if err != nil {
+		return "", false, fmt.Errorf("credentials.Get: %w", err)
+	}
+	return string(pt), true, nil
+}
+
+// List returns metadata for a tenant's credentials.
+func (s *Store) List(ctx context.Context, tenantID string) ([]Credential, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT tenant_id, connector, name, key_id, created_at, updated_at
+		FROM connector_credentials WHERE tenant_id = $1
+		ORDER BY connector, name`, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("credentials.List: %w", err)
+	}
+	defer rows.Close()
+	var out []Credential
+	for rows.Next() {
+		var c Credential
+		if err := rows.Scan(&c.TenantID, &c.Connector, &c.Name, &c.KeyID, &c.CreatedAt, &c.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("credentials.List scan: %w", err)
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// Delete removes a credential and reports whether it existed.
+func (s *Store) Delete(ctx context.Context, tenantID, connector, name string) (bool, error) {
+	tag, err := s.pool.Exec(ctx, `
+		DELETE FROM connector_credentials
+		WHERE tenant_id = $1 AND connector = $2 AND name = $3`,
+		tenantID, connector, name)
+	if err != nil {
+		return false, fmt.Errorf("credentials.Delete: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
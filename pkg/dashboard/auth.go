@@ -0,0 +1,76 @@
+package dashboard
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/bturcanu/OpenClause/pkg/auth"
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+// AllTenants is the tenant an auditor token is bound to when it may view
+// every tenant, e.g. AUDITOR_TOKENS="*:tok-compliance".
+const AllTenants = "*"
+
+// Viewer is the authenticated dashboard user.
+type Viewer struct {
+	Role   string `json:"role"`   // "admin" or "auditor"
+	Tenant string `json:"tenant"` // AllTenants, or the single tenant an auditor may see
+}
+
+// CanView reports whether the viewer may see tenantID.
+func (v Viewer) CanView(tenantID string) bool {
+	return v.Tenant == AllTenants || v.Tenant == tenantID
+}
+
+type contextKey struct{}
+
+// ViewerFromContext returns the viewer set by Auth.
+func ViewerFromContext(ctx context.Context) (Viewer, bool) {
+	v, ok := ctx.Value(contextKey{}).(Viewer)
+	return v, ok
+}
+
+// Auth returns middleware that admits the operator admin token or an auditor
+// token. Tokens are read from X-Admin-Token, Authorization: Bearer, or the
+// password of HTTP Basic auth so the dashboard can be opened in a browser.
+// Auditor tokens use the API_KEYS "tenant:token" format and only ever reach
+// the read-only dashboard routes.
+func Auth(adminToken string, auditors *auth.KeyStore) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := credential(r)
+			var v Viewer
+			switch {
+			case token == "":
+			case adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) == 1:
+				v = Viewer{Role: "admin", Tenant: AllTenants}
+			default:
+				if tenant, ok := auditors.Lookup(token); ok {
+					v = Viewer{Role: "auditor", Tenant: tenant}
+				}
+			}
+			if v.Role == "" {
+				w.Header().Set("WWW-Authenticate", `Basic realm="OpenClause dashboard"`)
+				types.ErrUnauthorized("admin or auditor token required").WriteJSON(w)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, v)))
+		})
+	}
+}
+
+func credential(r *http.Request) string {
+	if t := r.Header.Get("X-Admin-Token"); t != "" {
+		return t
+	}
+	if h := r.Header.Get("Authorization"); strings.HasPrefix(h, "Bearer ") {
+		return strings.TrimPrefix(h, "Bearer ")
+	}
+	if _, pass, ok := r.BasicAuth(); ok {
+		return pass
+	}
+	return ""
+}
@@ -0,0 +1,336 @@
+// Package dashboard serves a read-only, server-rendered operations view of
+// recent decisions, deny reasons, approval queue depth, connector health and
+// evidence chain status per tenant, and per-tenant audit reports for a
+// period.
+package dashboard
+
+import (
+	"context"
+	"encoding/json"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/connectors"
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	summaryWindow  = 24 * time.Hour
+	recentLimit    = 50
+	reasonLimit    = 10
+	chainWindow    = 200
+	healthDeadline = 3 * time.Second
+)
+
+// TenantSummary is one row of the tenant overview.
+type TenantSummary struct {
+	TenantID         string `json:"tenant_id"`
+	Allowed          int64  `json:"allowed"`
+	Denied           int64  `json:"denied"`
+	NeedsApproval    int64  `json:"needs_approval"`
+	PendingApprovals int64  `json:"pending_approvals"`
+}
+
+// Decision is one evaluated tool call.
+type Decision struct {
+	EventID    string    `json:"event_id"`
+	AgentID    string    `json:"agent_id"`
+	Tool       string    `json:"tool"`
+	Action     string    `json:"action"`
+	RiskScore  int       `json:"risk_score"`
+	Decision   string    `json:"decision"`
+	Reason     string    `json:"reason"`
+	ReceivedAt time.Time `json:"received_at"`
+}
+
+// ReasonCount is how often a deny reason occurred.
+type ReasonCount struct {
+	Reason string `json:"reason"`
+	Count  int64  `json:"count"`
+}
+
+// ChainStatus is the result of verifying a tenant's most recent events.
+type ChainStatus struct {
+	TenantID        string    `json:"tenant_id"`
+	Verified        bool      `json:"verified"`
+	Checked         int       `json:"checked"`
+	Error           string    `json:"error,omitempty"`
+	LastEventAt     time.Time `json:"last_event_at"`
+	ArchivedThrough time.Time `json:"archived_through"`
+}
+
+// Page is everything the dashboard renders; format=json returns it as is.
+type Page struct {
+	Viewer      Viewer                       `json:"viewer"`
+	GeneratedAt time.Time                    `json:"generated_at"`
+	Since       time.Time                    `json:"since"`
+	Tenants     []TenantSummary              `json:"tenants"`
+	Connectors  []connectors.ConnectorHealth `json:"connectors"`
+	Selected    string                       `json:"selected_tenant,omitempty"`
+	Recent      []Decision                   `json:"recent_decisions,omitempty"`
+	DenyReasons []ReasonCount                `json:"deny_reasons,omitempty"`
+	Chain       *ChainStatus                 `json:"chain,omitempty"`
+}
+
+type source interface {
+	Summaries(ctx context.Context, since time.Time) ([]TenantSummary, error)
+	RecentDecisions(ctx context.Context, tenantID string, limit int) ([]Decision, error)
+	DenyReasons(ctx context.Context, tenantID string, since time.Time, limit int) ([]ReasonCount, error)
+	ChainStatus(ctx context.Context, tenantID string, window int) (ChainStatus, error)
+	ActionDecisions(ctx context.Context, tenantID string, from, to time.Time) ([]ActionDecisions, error)
+	ApprovalStats(ctx context.Context, tenantID string, from, to time.Time) (ApprovalStats, error)
+	AttestChain(ctx context.Context, tenantID string, from, to time.Time) (ChainAttestation, error)
+	SampleEvents(ctx context.Context, tenantID string, from, to time.Time, n int, seed string) ([]SampledEvent, error)
+}
+
+type healthChecker interface {
+	Health(ctx context.Context) []connectors.ConnectorHealth
+}
+
+// Handlers serves the dashboard. Every route is a GET; nothing here mutates
+// state, which is what makes auditor tokens safe to hand out.
+type Handlers struct {
+	source source
+	health healthChecker
+	log    *slog.Logger
+	now    func() time.Time
+}
+
+// NewHandlers creates dashboard handlers. health may be nil.
+func NewHandlers(src source, health healthChecker, log *slog.Logger) *Handlers {
+	if log == nil {
+		log = slog.Default()
+	}
+	return &Handlers{source: src, health: health, log: log, now: time.Now}
+}
+
+// RegisterRoutes mounts the dashboard. It must sit behind Auth.
+func (h *Handlers) RegisterRoutes(r chi.Router) {
+	r.Get("/dashboard", h.Overview)
+	r.Get("/dashboard/report", h.ReportHandler)
+}
+
+// Overview handles GET /dashboard?tenant_id=&format=html|json
+func (h *Handlers) Overview(w http.ResponseWriter, r *http.Request) {
+	viewer, ok := ViewerFromContext(r.Context())
+	if !ok {
+		types.ErrUnauthorized("admin or auditor token required").WriteJSON(w)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "html" && format != "json" {
+		types.ErrBadRequest("format must be html or json").WriteJSON(w)
+		return
+	}
+	selected := r.URL.Query().Get("tenant_id")
+	if selected != "" && !viewer.CanView(selected) {
+		types.ErrForbidden("tenant not visible to this auditor").WriteJSON(w)
+		return
+	}
+
+	ctx := r.Context()
+	now := h.now().UTC()
+	page := Page{Viewer: viewer, GeneratedAt: now, Since: now.Add(-summaryWindow)}
+
+	all, err := h.source.Summaries(ctx, page.Since)
+	if err != nil {
+		h.fail(w, "summaries", err)
+		return
+	}
+	page.Tenants = []TenantSummary{}
+	for _, ts := range all {
+		if viewer.CanView(ts.TenantID) {
+			page.Tenants = append(page.Tenants, ts)
+		}
+	}
+	if selected == "" {
+		if viewer.Tenant != AllTenants {
+			selected = viewer.Tenant
+		} else if len(page.Tenants) > 0 {
+			selected = page.Tenants[0].TenantID
+		}
+	}
+
+	if h.health != nil {
+		hctx, cancel := context.WithTimeout(ctx, healthDeadline)
+		page.Connectors = h.health.Health(hctx)
+		cancel()
+		if viewer.Role != "admin" {
+			redactBackends(page.Connectors)
+		}
+	}
+
+	if selected != "" {
+		page.Selected = selected
+		if page.Recent, err = h.source.RecentDecisions(ctx, selected, recentLimit); err != nil {
+			h.fail(w, "recent decisions", err)
+			return
+		}
+		if page.DenyReasons, err = h.source.DenyReasons(ctx, selected, page.Since, reasonLimit); err != nil {
+			h.fail(w, "deny reasons", err)
+			return
+		}
+		chain, err := h.source.ChainStatus(ctx, selected, chainWindow)
+		if err != nil {
+			h.fail(w, "chain status", err)
+			return
+		}
+		page.Chain = &chain
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	if format == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(page); err != nil {
+			h.log.Error("response encode failed", "error", err)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := pageTmpl.Execute(w, page); err != nil {
+		h.log.Error("template execute failed", "error", err)
+	}
+}
+
+// redactBackends hides connector backend URLs, which map the internal
+// network, from auditors. Probe errors other than an HTTP status can name
+// the backend too, so they are reduced to "unreachable".
+func redactBackends(health []connectors.ConnectorHealth) {
+	for i := range health {
+		c := &health[i]
+		c.URL = ""
+		if c.Error != "" && !strings.HasPrefix(c.Error, "HTTP ") {
+			c.Error = "unreachable"
+		}
+	}
+}
+
+func (h *Handlers) fail(w http.ResponseWriter, what string, err error) {
+	h.log.Error("dashboard query failed", "query", what, "error", err)
+	types.ErrInternal("failed to load dashboard").WriteJSON(w)
+}
+
+var pageTmpl = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"ts": func(t time.Time) string {
+		if t.IsZero() {
+			return "never"
+		}
+		return t.UTC().Format("2006-01-02 15:04:05")
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <meta http-equiv="refresh" content="30">
+  <title>OpenClause Operations</title>
+  <style>
+    body { font-family: system-ui, sans-serif; max-width: 1200px; margin: 2rem auto; padding: 0 1rem; color: #2d3748; }
+    table { width: 100%; border-collapse: collapse; margin: 0.5rem 0 1.5rem; }
+    th, td { text-align: left; padding: 0.4rem 0.75rem; border-bottom: 1px solid #e2e8f0; }
+    th { background: #f7fafc; font-weight: 600; }
+    tr.selected { background: #ebf8ff; }
+    .badge { display: inline-block; padding: 2px 8px; border-radius: 4px; font-size: 0.85em; }
+    .allow, .ok { background: #c6f6d5; color: #22543d; }
+    .deny, .bad { background: #fed7d7; color: #742a2a; }
+    .approve { background: #fefcbf; color: #744210; }
+    .risk-high { color: #c53030; font-weight: 600; }
+    .muted, .empty { color: #718096; }
+  </style>
+</head>
+<body>
+  <h1>Operations</h1>
+  <p class="muted">Signed in as {{.Viewer.Role}}{{if ne .Viewer.Tenant "*"}} for tenant <strong>{{.Viewer.Tenant}}</strong>{{end}} · read-only · generated {{ts .GeneratedAt}} UTC · counts since {{ts .Since}} UTC</p>
+
+  <h2>Tenants</h2>
+  {{if .Tenants}}
+  <table>
+    <thead><tr><th>Tenant</th><th>Allowed</th><th>Denied</th><th>Needs approval</th><th>Approval queue</th></tr></thead>
+    <tbody>
+      {{range .Tenants}}
+      <tr{{if eq .TenantID $.Selected}} class="selected"{{end}}>
+        <td><a href="?tenant_id={{.TenantID}}">{{.TenantID}}</a></td>
+        <td>{{.Allowed}}</td>
+        <td>{{.Denied}}</td>
+        <td>{{.NeedsApproval}}</td>
+        <td>{{.PendingApprovals}}</td>
+      </tr>
+      {{end}}
+    </tbody>
+  </table>
+  {{else}}
+  <p class="empty">No tenants.</p>
+  {{end}}
+
+  <h2>Connectors</h2>
+  {{if .Connectors}}
+  <table>
+    <thead><tr><th>Route</th><th>URL</th><th>Weight</th><th>Label</th><th>Status</th><th>Latency</th><th>Calls</th><th>Failures</th></tr></thead>
+    <tbody>
+      {{range .Connectors}}
+      <tr>
+        <td>{{.Tool}}</td>
+        <td>{{if .URL}}<code>{{.URL}}</code>{{else}}—{{end}}</td>
+        <td>{{.Weight}}</td>
+        <td>{{.Label}}</td>
+        <td>{{if .Healthy}}<span class="badge ok">healthy</span>{{else}}<span class="badge bad">down</span> {{.Error}}{{end}}</td>
+        <td>{{.LatencyMS}} ms</td>
+        <td>{{.Calls}}</td>
+        <td>{{.Failures}}</td>
+      </tr>
+      {{end}}
+    </tbody>
+  </table>
+  {{else}}
+  <p class="empty">No connectors registered.</p>
+  {{end}}
+
+  {{if .Selected}}
+  <h2>Tenant {{.Selected}}</h2>
+  {{with .Chain}}
+  <h3>Evidence chain</h3>
+  <p>
+    {{if .Verified}}<span class="badge ok">intact</span>{{else}}<span class="badge bad">broken</span>{{end}}
+    last {{.Checked}} events checked · last event {{ts .LastEventAt}} · archived through {{ts .ArchivedThrough}}
+  </p>
+  {{if .Error}}<p class="risk-high">{{.Error}}</p>{{end}}
+  {{end}}
+
+  <h3>Top deny reasons</h3>
+  {{if .DenyReasons}}
+  <table>
+    <thead><tr><th>Reason</th><th>Count</th></tr></thead>
+    <tbody>{{range .DenyReasons}}<tr><td>{{.Reason}}</td><td>{{.Count}}</td></tr>{{end}}</tbody>
+  </table>
+  {{else}}
+  <p class="empty">No denials in this window.</p>
+  {{end}}
+
+  <h3>Recent decisions</h3>
+  {{if .Recent}}
+  <table>
+    <thead><tr><th>Time</th><th>Event</th><th>Agent</th><th>Tool</th><th>Action</th><th>Risk</th><th>Decision</th><th>Reason</th></tr></thead>
+    <tbody>
+      {{range .Recent}}
+      <tr>
+        <td>{{ts .ReceivedAt}}</td>
+        <td><code>{{.EventID}}</code></td>
+        <td>{{.AgentID}}</td>
+        <td>{{.Tool}}</td>
+        <td>{{.Action}}</td>
+        <td {{if ge .RiskScore 7}}class="risk-high"{{end}}>{{.RiskScore}}</td>
+        <td><span class="badge {{.Decision}}">{{.Decision}}</span></td>
+        <td>{{.Reason}}</td>
+      </tr>
+      {{end}}
+    </tbody>
+  </table>
+  {{else}}
+  <p class="empty">No decisions recorded.</p>
+  {{end}}
+  {{end}}
+</body>
+</html>`))
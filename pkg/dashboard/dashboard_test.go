@@ -0,0 +1,229 @@
+package dashboard
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/auth"
+	"github.com/bturcanu/OpenClause/pkg/connectors"
+	"github.com/go-chi/chi/v5"
+)
+
+type fakeSource struct {
+	detailFor []string
+}
+
+func (f *fakeSource) Summaries(_ context.Context, _ time.Time) ([]TenantSummary, error) {
+	return []TenantSummary{
+		{TenantID: "t1", Allowed: 5, Denied: 2, PendingApprovals: 1},
+		{TenantID: "t2", Allowed: 1},
+	}, nil
+}
+
+func (f *fakeSource) RecentDecisions(_ context.Context, tenantID string, _ int) ([]Decision, error) {
+	f.detailFor = append(f.detailFor, tenantID)
+	return []Decision{{EventID: "evt-" + tenantID, Tool: "slack", Action: "msg.post", Decision: "deny", Reason: "<blocked>"}}, nil
+}
+
+func (f *fakeSource) DenyReasons(_ context.Context, _ string, _ time.Time, _ int) ([]ReasonCount, error) {
+	return []ReasonCount{{Reason: "<blocked>", Count: 2}}, nil
+}
+
+func (f *fakeSource) ChainStatus(_ context.Context, tenantID string, _ int) (ChainStatus, error) {
+	return ChainStatus{TenantID: tenantID, Verified: true, Checked: 7}, nil
+}
+
+func (f *fakeSource) ActionDecisions(_ context.Context, _ string, _, _ time.Time) ([]ActionDecisions, error) {
+	return []ActionDecisions{
+		{Tool: "slack", Action: "msg.post", Allowed: 5, Denied: 1},
+		{Tool: "jira", Action: "issue.delete", Denied: 1, NeedsApproval: 2},
+	}, nil
+}
+
+func (f *fakeSource) ApprovalStats(_ context.Context, _ string, _, _ time.Time) (ApprovalStats, error) {
+	return ApprovalStats{Requested: 2, Approved: 1, Pending: 1, MedianSec: 252, P90Sec: 252, MaxSec: 252}, nil
+}
+
+func (f *fakeSource) AttestChain(_ context.Context, _ string, _, _ time.Time) (ChainAttestation, error) {
+	return ChainAttestation{Verified: true, Checked: 9, FirstSeq: 10, LastSeq: 18, StartPrevHash: "aaa", HeadHash: "bbb"}, nil
+}
+
+func (f *fakeSource) SampleEvents(_ context.Context, tenantID string, _, _ time.Time, n int, _ string) ([]SampledEvent, error) {
+	out := []SampledEvent{{EventID: "evt-" + tenantID, Tool: "jira", Action: "issue.delete", Decision: "approve", Reason: "<high risk>", ApprovalStatus: "approved", Approver: "alice"}}
+	return out[:min(n, len(out))], nil
+}
+
+type fakeHealth struct{}
+
+func (fakeHealth) Health(context.Context) []connectors.ConnectorHealth {
+	return []connectors.ConnectorHealth{
+		{Tool: "jira", URL: "http://jira.internal:8080", Error: "HTTP 503"},
+		{Tool: "slack", URL: "http://slack.internal:8080", Error: `Get "http://slack.internal:8080/healthz": connection refused`},
+	}
+}
+
+func newRouter(src *fakeSource) http.Handler {
+	r := chi.NewRouter()
+	r.Group(func(r chi.Router) {
+		r.Use(Auth("admin-tok", auth.NewKeyStore("t2:aud-t2,*:aud-all")))
+		NewHandlers(src, fakeHealth{}, nil).RegisterRoutes(r)
+	})
+	return r
+}
+
+func TestAuth_TokensAndScope(t *testing.T) {
+	src := &fakeSource{}
+	r := newRouter(src)
+
+	do := func(path string, set func(*http.Request)) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if set != nil {
+			set(req)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do("/dashboard", nil)
+	if rec.Code != http.StatusUnauthorized || rec.Header().Get("WWW-Authenticate") == "" {
+		t.Fatalf("anonymous = %d %v", rec.Code, rec.Header())
+	}
+	if rec := do("/dashboard", func(r *http.Request) { r.SetBasicAuth("x", "sk-tenant") }); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("tenant key accepted: %d", rec.Code)
+	}
+
+	// A tenant-scoped auditor sees only its tenant and cannot select another.
+	rec = do("/dashboard?format=json", func(r *http.Request) { r.SetBasicAuth("auditor", "aud-t2") })
+	if rec.Code != http.StatusOK {
+		t.Fatalf("auditor = %d %s", rec.Code, rec.Body)
+	}
+	var page Page
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Tenants) != 1 || page.Tenants[0].TenantID != "t2" || page.Selected != "t2" || page.Viewer.Role != "auditor" {
+		t.Fatalf("auditor page = %+v", page)
+	}
+	if strings.Contains(rec.Body.String(), "internal:8080") || page.Connectors[0].Error != "HTTP 503" || page.Connectors[1].Error != "unreachable" {
+		t.Fatalf("auditor sees backends: %+v", page.Connectors)
+	}
+	if rec := do("/dashboard?tenant_id=t1", func(r *http.Request) { r.Header.Set("Authorization", "Bearer aud-t2") }); rec.Code != http.StatusForbidden {
+		t.Fatalf("cross-tenant = %d", rec.Code)
+	}
+
+	// Admin and all-tenant auditors see everything; the first tenant is selected.
+	for _, tok := range []string{"admin-tok", "aud-all"} {
+		rec = do("/dashboard?format=json", func(r *http.Request) { r.Header.Set("X-Admin-Token", tok) })
+		page = Page{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Tenants) != 2 || page.Selected != "t1" || page.Chain == nil || page.Chain.Checked != 7 {
+			t.Fatalf("%s page = %+v", tok, page)
+		}
+		if wantURL := tok == "admin-tok"; (page.Connectors[0].URL != "") != wantURL {
+			t.Fatalf("%s connector URL = %q", tok, page.Connectors[0].URL)
+		}
+	}
+
+	if rec := do("/dashboard?format=xml", func(r *http.Request) { r.Header.Set("X-Admin-Token", "admin-tok") }); rec.Code != http.StatusBadRequest {
+		t.Fatalf("format=xml = %d", rec.Code)
+	}
+}
+
+func TestOverview_RendersEscapedHTML(t *testing.T) {
+	r := newRouter(&fakeSource{})
+	req := httptest.NewRequest(http.MethodGet, "/dashboard?tenant_id=t1", nil)
+	req.SetBasicAuth("admin", "admin-tok")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("status = %d %v", rec.Code, rec.Header())
+	}
+	body := rec.Body.String()
+	for _, want := range []string{"evt-t1", "&lt;blocked&gt;", "HTTP 503", "intact"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body missing %q", want)
+		}
+	}
+	if strings.Contains(body, "<blocked>") {
+		t.Error("deny reason was not escaped")
+	}
+}
+
+func TestReport(t *testing.T) {
+	r := newRouter(&fakeSource{})
+	do := func(path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.SetBasicAuth("auditor", token)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// A tenant-scoped auditor gets its own tenant's report by default.
+	rec := do("/dashboard/report?from=2026-07-01&to=2026-10-01&format=json", "aud-t2")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("json report = %d %s", rec.Code, rec.Body)
+	}
+	var rep Report
+	if err := json.Unmarshal(rec.Body.Bytes(), &rep); err != nil {
+		t.Fatal(err)
+	}
+	if rep.TenantID != "t2" || rep.Decisions != (DecisionTotals{Total: 9, Allowed: 5, Denied: 2, NeedsApproval: 2}) ||
+		!rep.Chain.Verified || len(rep.Samples) != 1 || rep.SampleSeed != "t2/2026-07-01T00:00:00Z/2026-10-01T00:00:00Z" {
+		t.Fatalf("report = %+v", rep)
+	}
+
+	for path, want := range map[string]int{
+		"/dashboard/report?tenant_id=t1&from=2026-07-01&to=2026-10-01": http.StatusForbidden,
+		"/dashboard/report?to=2026-10-01":                              http.StatusUnprocessableEntity,
+		"/dashboard/report?from=2026-10-01&to=2026-07-01":              http.StatusUnprocessableEntity,
+		"/dashboard/report?from=2025-01-01&to=2026-10-01":              http.StatusUnprocessableEntity,
+		"/dashboard/report?from=2026-07-01&to=2026-10-01&sample=-1":    http.StatusUnprocessableEntity,
+		"/dashboard/report?from=2026-07-01&to=2026-10-01&format=docx":  http.StatusBadRequest,
+	} {
+		if rec := do(path, "aud-t2"); rec.Code != want {
+			t.Errorf("%s = %d, want %d", path, rec.Code, want)
+		}
+	}
+	if rec := do("/dashboard/report?from=2026-07-01&to=2026-10-01", "aud-all"); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("all-tenant auditor without tenant_id = %d, want 422", rec.Code)
+	}
+
+	rec = do("/dashboard/report?tenant_id=t1&from=2026-07-01T00:00:00Z&to=2026-10-01T00:00:00Z", "aud-all")
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK || !strings.Contains(body, "evt-t1") || !strings.Contains(body, "&lt;high risk&gt;") || strings.Contains(body, "<high risk>") {
+		t.Fatalf("html report = %d %s", rec.Code, body)
+	}
+
+	rec = do("/dashboard/report?tenant_id=t1&from=2026-07-01&to=2026-10-01&format=pdf", "aud-all")
+	pdf := rec.Body.String()
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/pdf" ||
+		!strings.HasPrefix(pdf, "%PDF-1.4") || !strings.HasSuffix(pdf, "%%EOF\n") ||
+		!strings.Contains(pdf, "Intact: 9 events") || !strings.Contains(pdf, "approved by alice") {
+		t.Fatalf("pdf report = %d %v", rec.Code, rec.Header())
+	}
+}
+
+func TestWritePDF_Paginates(t *testing.T) {
+	lines := make([]string, pdfPageLines+1)
+	lines[0] = strings.Repeat("x", pdfLineChars+10) + " (é)"
+	var buf strings.Builder
+	if err := writePDF(&buf, "t", lines); err != nil {
+		t.Fatal(err)
+	}
+	pdf := buf.String()
+	for _, want := range []string{"/Count 2", "Page 2 of 2", `xxxxxxxxxx \(?\)`} {
+		if !strings.Contains(pdf, want) {
+			t.Errorf("pdf missing %q", want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package dashboard
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// PDF page layout: A4 in points, Courier 8pt.
+const (
+	pdfPageWidth   = 595
+	pdfPageHeight  = 842
+	pdfMargin      = 40
+	pdfFontSize    = 8
+	pdfLeading     = 11
+	pdfLineChars   = 105 // Courier glyphs are 0.6em wide: (595-80) / 4.8
+	pdfPageLines   = (pdfPageHeight - 2*pdfMargin) / pdfLeading
+	pdfFirstObject = 4 // 1 catalog, 2 page tree, 3 font
+)
+
+// writePDF renders lines of text as a minimal PDF 1.4 document in the
+// standard Courier font, so reports can be produced without a rendering
+// dependency. Long lines wrap; characters outside printable ASCII are
+// replaced with '?'.
+func writePDF(w io.Writer, title string, lines []string) error {
+	var wrapped []string
+	for _, l := range lines {
+		l = pdfText(l)
+		for len(l) > pdfLineChars {
+			wrapped = append(wrapped, l[:pdfLineChars])
+			l = "    " + l[pdfLineChars:]
+		}
+		wrapped = append(wrapped, l)
+	}
+	var pages [][]string
+	for len(wrapped) > pdfPageLines {
+		pages = append(pages, wrapped[:pdfPageLines])
+		wrapped = wrapped[pdfPageLines:]
+	}
+	pages = append(pages, wrapped)
+
+	var buf bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+	buf.WriteString("%PDF-1.4\n")
+
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", pdfFirstObject+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+	for i, page := range pages {
+		var content bytes.Buffer
+		fmt.Fprintf(&content, "BT\n/F1 %d Tf\n%d TL\n%d %d Td\n", pdfFontSize, pdfLeading, pdfMargin, pdfPageHeight-pdfMargin)
+		for _, l := range page {
+			fmt.Fprintf(&content, "(%s) '\n", pdfEscape(l))
+		}
+		footer := fmt.Sprintf("Page %d of %d", i+1, len(pages))
+		fmt.Fprintf(&content, "ET\nBT\n/F1 %d Tf\n%d %d Td\n(%s) Tj\nET", pdfFontSize, pdfMargin, pdfMargin/2, footer)
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, pdfFirstObject+2*i+1))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.Bytes()))
+	}
+	object(fmt.Sprintf("<< /Title (%s) /Producer (OpenClause) >>", pdfEscape(pdfText(title))))
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, len(offsets), xref)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// pdfText replaces characters the Courier WinAnsi subset cannot show.
+func pdfText(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e {
+			return '?'
+		}
+		return r
+	}, s)
+}
+
+// pdfEscape escapes a string for a PDF literal string.
+func pdfEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `(`, `\(`, `)`, `\)`).Replace(s)
+}
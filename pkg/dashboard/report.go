@@ -0,0 +1,404 @@
+package dashboard
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+const (
+	maxReportRange    = 366 * 24 * time.Hour
+	defaultSampleSize = 25
+	maxSampleSize     = 500
+)
+
+// Report is a tenant's audit package for a period: what was decided, how
+// long humans took to approve, whether the evidence chain is intact, and a
+// reproducible sample of events for auditors to trace by hand.
+type Report struct {
+	TenantID    string            `json:"tenant_id"`
+	From        time.Time         `json:"from"`
+	To          time.Time         `json:"to"`
+	GeneratedAt time.Time         `json:"generated_at"`
+	GeneratedBy Viewer            `json:"generated_by"`
+	Decisions   DecisionTotals    `json:"decisions"`
+	Actions     []ActionDecisions `json:"actions"`
+	Approvals   ApprovalStats     `json:"approvals"`
+	Chain       ChainAttestation  `json:"chain"`
+	SampleSeed  string            `json:"sample_seed"`
+	Samples     []SampledEvent    `json:"samples"`
+}
+
+// DecisionTotals counts the period's decisions.
+type DecisionTotals struct {
+	Total         int64 `json:"total"`
+	Allowed       int64 `json:"allowed"`
+	Denied        int64 `json:"denied"`
+	NeedsApproval int64 `json:"needs_approval"`
+}
+
+// ActionDecisions counts the period's decisions for one tool.action.
+type ActionDecisions struct {
+	Tool          string `json:"tool"`
+	Action        string `json:"action"`
+	Allowed       int64  `json:"allowed"`
+	Denied        int64  `json:"denied"`
+	NeedsApproval int64  `json:"needs_approval"`
+}
+
+// ApprovalStats summarizes the approval requests opened in the period and
+// how long approved or denied ones waited for a decision.
+type ApprovalStats struct {
+	Requested int64   `json:"requested"`
+	Approved  int64   `json:"approved"`
+	Denied    int64   `json:"denied"`
+	Expired   int64   `json:"expired"`
+	Pending   int64   `json:"pending"`
+	MedianSec float64 `json:"median_sec"`
+	P90Sec    float64 `json:"p90_sec"`
+	MaxSec    float64 `json:"max_sec"`
+}
+
+// ChainAttestation is the result of re-hashing every event of the period
+// in chain order. StartPrevHash and HeadHash let consecutive reports, and
+// archived bundles, be checked against each other.
+type ChainAttestation struct {
+	Verified      bool   `json:"verified"`
+	Checked       int64  `json:"checked"`
+	FirstSeq      int64  `json:"first_seq,omitempty"`
+	LastSeq       int64  `json:"last_seq,omitempty"`
+	StartPrevHash string `json:"start_prev_hash,omitempty"`
+	HeadHash      string `json:"head_hash,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// SampledEvent is one event of the sample with its approval and execution
+// outcome, if any.
+type SampledEvent struct {
+	EventID         string    `json:"event_id"`
+	ReceivedAt      time.Time `json:"received_at"`
+	AgentID         string    `json:"agent_id"`
+	Tool            string    `json:"tool"`
+	Action          string    `json:"action"`
+	Resource        string    `json:"resource,omitempty"`
+	RiskScore       int       `json:"risk_score"`
+	Decision        string    `json:"decision"`
+	Reason          string    `json:"reason"`
+	Hash            string    `json:"hash"`
+	ApprovalStatus  string    `json:"approval_status,omitempty"`
+	Approver        string    `json:"approver,omitempty"`
+	ExecutionStatus string    `json:"execution_status,omitempty"`
+}
+
+// ReportHandler handles GET /dashboard/report?tenant_id=&from=&to=&format=html|pdf|json&sample=&seed=
+func (h *Handlers) ReportHandler(w http.ResponseWriter, r *http.Request) {
+	viewer, ok := ViewerFromContext(r.Context())
+	if !ok {
+		types.ErrUnauthorized("admin or auditor token required").WriteJSON(w)
+		return
+	}
+	q := r.URL.Query()
+	format := q.Get("format")
+	if format == "" {
+		format = "html"
+	}
+	if format != "html" && format != "pdf" && format != "json" {
+		types.ErrBadRequest("format must be html, pdf or json").WriteJSON(w)
+		return
+	}
+	tenantID := q.Get("tenant_id")
+	if tenantID == "" && viewer.Tenant != AllTenants {
+		tenantID = viewer.Tenant
+	}
+	if tenantID == "" {
+		types.ErrValidation(&types.ValidationError{Field: "tenant_id", Reason: "is required"}).WriteJSON(w)
+		return
+	}
+	if !viewer.CanView(tenantID) {
+		types.ErrForbidden("tenant not visible to this auditor").WriteJSON(w)
+		return
+	}
+	from, err := parseReportTime(q.Get("from"))
+	if err != nil {
+		types.ErrValidation(&types.ValidationError{Field: "from", Reason: err.Error()}).WriteJSON(w)
+		return
+	}
+	to, err := parseReportTime(q.Get("to"))
+	if err != nil {
+		types.ErrValidation(&types.ValidationError{Field: "to", Reason: err.Error()}).WriteJSON(w)
+		return
+	}
+	if !to.After(from) || to.Sub(from) > maxReportRange {
+		types.ErrValidation(&types.ValidationError{Field: "to", Reason: "must be after from and at most 366 days later"}).WriteJSON(w)
+		return
+	}
+	sample := defaultSampleSize
+	if s := q.Get("sample"); s != "" {
+		if sample, err = strconv.Atoi(s); err != nil || sample < 0 || sample > maxSampleSize {
+			types.ErrValidation(&types.ValidationError{Field: "sample", Reason: fmt.Sprintf("must be 0-%d", maxSampleSize)}).WriteJSON(w)
+			return
+		}
+	}
+	seed := q.Get("seed")
+	if seed == "" {
+		seed = fmt.Sprintf("%s/%s/%s", tenantID, from.Format(time.RFC3339), to.Format(time.RFC3339))
+	}
+
+	rep, err := h.buildReport(r.Context(), viewer, tenantID, from, to, sample, seed)
+	if err != nil {
+		h.log.Error("report query failed", "tenant_id", tenantID, "error", err)
+		types.ErrInternal("failed to build report").WriteJSON(w)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	name := fmt.Sprintf("openclause-report-%s-%s-%s", tenantID, from.Format("20060102"), to.Format("20060102"))
+	switch format {
+	case "json":
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(rep); err != nil {
+			h.log.Error("response encode failed", "error", err)
+		}
+	case "pdf":
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".pdf"))
+		if err := writePDF(w, "OpenClause audit report: "+tenantID, rep.textLines()); err != nil {
+			h.log.Error("pdf render failed", "error", err)
+		}
+	default:
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := reportTmpl.Execute(w, rep); err != nil {
+			h.log.Error("template execute failed", "error", err)
+		}
+	}
+}
+
+func (h *Handlers) buildReport(ctx context.Context, viewer Viewer, tenantID string, from, to time.Time, sample int, seed string) (*Report, error) {
+	rep := &Report{
+		TenantID:    tenantID,
+		From:        from,
+		To:          to,
+		GeneratedAt: h.now().UTC(),
+		GeneratedBy: viewer,
+		SampleSeed:  seed,
+	}
+	var err error
+	if rep.Actions, err = h.source.ActionDecisions(ctx, tenantID, from, to); err != nil {
+		return nil, err
+	}
+	for _, a := range rep.Actions {
+		rep.Decisions.Allowed += a.Allowed
+		rep.Decisions.Denied += a.Denied
+		rep.Decisions.NeedsApproval += a.NeedsApproval
+	}
+	rep.Decisions.Total = rep.Decisions.Allowed + rep.Decisions.Denied + rep.Decisions.NeedsApproval
+	if rep.Approvals, err = h.source.ApprovalStats(ctx, tenantID, from, to); err != nil {
+		return nil, err
+	}
+	if rep.Chain, err = h.source.AttestChain(ctx, tenantID, from, to); err != nil {
+		return nil, err
+	}
+	if sample > 0 {
+		if rep.Samples, err = h.source.SampleEvents(ctx, tenantID, from, to, sample, seed); err != nil {
+			return nil, err
+		}
+	}
+	if rep.Actions == nil {
+		rep.Actions = []ActionDecisions{}
+	}
+	if rep.Samples == nil {
+		rep.Samples = []SampledEvent{}
+	}
+	return rep, nil
+}
+
+// parseReportTime accepts an RFC 3339 timestamp or a date, which means
+// midnight UTC.
+func parseReportTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, fmt.Errorf("is required")
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("must be YYYY-MM-DD or RFC 3339")
+	}
+	return t.UTC(), nil
+}
+
+// textLines lays the report out as plain text for the PDF rendering.
+func (r *Report) textLines() []string {
+	ts := func(t time.Time) string { return t.UTC().Format("2006-01-02 15:04:05") + " UTC" }
+	lines := []string{
+		"OpenClause audit report",
+		"",
+		"Tenant:     " + r.TenantID,
+		"Period:     " + ts(r.From) + " to " + ts(r.To) + " (end exclusive)",
+		"Generated:  " + ts(r.GeneratedAt) + " by " + r.GeneratedBy.Role,
+		"",
+		"DECISION SUMMARY",
+		fmt.Sprintf("  Total %d   Allowed %d   Denied %d   Needs approval %d",
+			r.Decisions.Total, r.Decisions.Allowed, r.Decisions.Denied, r.Decisions.NeedsApproval),
+	}
+	if len(r.Actions) > 0 {
+		lines = append(lines, "", fmt.Sprintf("  %-50s %10s %10s %10s", "Tool.action", "Allowed", "Denied", "Approval"))
+		for _, a := range r.Actions {
+			lines = append(lines, fmt.Sprintf("  %-50s %10d %10d %10d", a.Tool+"."+a.Action, a.Allowed, a.Denied, a.NeedsApproval))
+		}
+	}
+	lines = append(lines,
+		"",
+		"APPROVALS",
+		fmt.Sprintf("  Requested %d   Approved %d   Denied %d   Expired %d   Pending %d",
+			r.Approvals.Requested, r.Approvals.Approved, r.Approvals.Denied, r.Approvals.Expired, r.Approvals.Pending),
+		fmt.Sprintf("  Time to decision: median %s   p90 %s   max %s",
+			seconds(r.Approvals.MedianSec), seconds(r.Approvals.P90Sec), seconds(r.Approvals.MaxSec)),
+		"",
+		"EVIDENCE CHAIN ATTESTATION",
+	)
+	if r.Chain.Verified {
+		lines = append(lines, fmt.Sprintf("  Intact: %d events re-hashed in chain order", r.Chain.Checked))
+	} else {
+		lines = append(lines, fmt.Sprintf("  BROKEN after %d events: %s", r.Chain.Checked, r.Chain.Error))
+	}
+	if r.Chain.Checked > 0 {
+		lines = append(lines,
+			fmt.Sprintf("  Sequence:        %d to %d", r.Chain.FirstSeq, r.Chain.LastSeq),
+			"  Start prev_hash: "+r.Chain.StartPrevHash,
+			"  Head hash:       "+r.Chain.HeadHash,
+		)
+	}
+	lines = append(lines, "", "SAMPLED EVENTS", fmt.Sprintf("  %d events, seed %q", len(r.Samples), r.SampleSeed))
+	for _, e := range r.Samples {
+		lines = append(lines,
+			"",
+			"  "+e.EventID+"  "+ts(e.ReceivedAt),
+			fmt.Sprintf("    %s.%s by %s, risk %d", e.Tool, e.Action, e.AgentID, e.RiskScore),
+		)
+		if e.Resource != "" {
+			lines = append(lines, "    Resource:  "+e.Resource)
+		}
+		lines = append(lines, "    Decision:  "+e.Decision+": "+e.Reason)
+		if e.ApprovalStatus != "" {
+			lines = append(lines, "    Approval:  "+e.ApprovalStatus+approverSuffix(e.Approver))
+		}
+		if e.ExecutionStatus != "" {
+			lines = append(lines, "    Execution: "+e.ExecutionStatus)
+		}
+		lines = append(lines, "    Hash:      "+e.Hash)
+	}
+	return lines
+}
+
+func approverSuffix(approver string) string {
+	if approver == "" {
+		return ""
+	}
+	return " by " + approver
+}
+
+// seconds formats a duration in seconds for the report, e.g. "4m12s".
+func seconds(s float64) string {
+	return (time.Duration(s * float64(time.Second))).Round(time.Second).String()
+}
+
+var reportTmpl = template.Must(template.New("report").Funcs(template.FuncMap{
+	"ts": func(t time.Time) string {
+		return t.UTC().Format("2006-01-02 15:04:05")
+	},
+	"seconds": seconds,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>OpenClause audit report: {{.TenantID}}</title>
+  <style>
+    body { font-family: system-ui, sans-serif; max-width: 1000px; margin: 2rem auto; padding: 0 1rem; color: #2d3748; }
+    table { width: 100%; border-collapse: collapse; margin: 0.5rem 0 1.5rem; }
+    th, td { text-align: left; padding: 0.4rem 0.75rem; border-bottom: 1px solid #e2e8f0; vertical-align: top; }
+    th { background: #f7fafc; font-weight: 600; }
+    code { font-size: 0.85em; word-break: break-all; }
+    .badge { display: inline-block; padding: 2px 8px; border-radius: 4px; font-size: 0.85em; }
+    .allow, .ok { background: #c6f6d5; color: #22543d; }
+    .deny, .bad { background: #fed7d7; color: #742a2a; }
+    .approve { background: #fefcbf; color: #744210; }
+    .muted, .empty { color: #718096; }
+    @media print { body { margin: 0; max-width: none; } h2 { break-after: avoid; } tr { break-inside: avoid; } }
+  </style>
+</head>
+<body>
+  <h1>Audit report: {{.TenantID}}</h1>
+  <p class="muted">{{ts .From}} to {{ts .To}} UTC (end exclusive) · generated {{ts .GeneratedAt}} UTC by {{.GeneratedBy.Role}}</p>
+
+  <h2>Decision summary</h2>
+  <table>
+    <thead><tr><th>Total</th><th>Allowed</th><th>Denied</th><th>Needs approval</th></tr></thead>
+    <tbody><tr><td>{{.Decisions.Total}}</td><td>{{.Decisions.Allowed}}</td><td>{{.Decisions.Denied}}</td><td>{{.Decisions.NeedsApproval}}</td></tr></tbody>
+  </table>
+  {{if .Actions}}
+  <table>
+    <thead><tr><th>Tool.action</th><th>Allowed</th><th>Denied</th><th>Needs approval</th></tr></thead>
+    <tbody>
+      {{range .Actions}}<tr><td>{{.Tool}}.{{.Action}}</td><td>{{.Allowed}}</td><td>{{.Denied}}</td><td>{{.NeedsApproval}}</td></tr>
+      {{end}}
+    </tbody>
+  </table>
+  {{end}}
+
+  <h2>Approvals</h2>
+  <table>
+    <thead><tr><th>Requested</th><th>Approved</th><th>Denied</th><th>Expired</th><th>Pending</th><th>Median wait</th><th>p90 wait</th><th>Longest wait</th></tr></thead>
+    <tbody><tr>
+      <td>{{.Approvals.Requested}}</td><td>{{.Approvals.Approved}}</td><td>{{.Approvals.Denied}}</td><td>{{.Approvals.Expired}}</td><td>{{.Approvals.Pending}}</td>
+      <td>{{seconds .Approvals.MedianSec}}</td><td>{{seconds .Approvals.P90Sec}}</td><td>{{seconds .Approvals.MaxSec}}</td>
+    </tr></tbody>
+  </table>
+
+  <h2>Evidence chain attestation</h2>
+  {{with .Chain}}
+  <p>
+    {{if .Verified}}<span class="badge ok">intact</span> {{.Checked}} events re-hashed in chain order{{else}}<span class="badge bad">broken</span> after {{.Checked}} events: {{.Error}}{{end}}
+  </p>
+  {{if .Checked}}
+  <table>
+    <tbody>
+      <tr><th>Sequence</th><td>{{.FirstSeq}} to {{.LastSeq}}</td></tr>
+      <tr><th>Start prev_hash</th><td><code>{{.StartPrevHash}}</code></td></tr>
+      <tr><th>Head hash</th><td><code>{{.HeadHash}}</code></td></tr>
+    </tbody>
+  </table>
+  {{end}}
+  {{end}}
+
+  <h2>Sampled events</h2>
+  <p class="muted">{{len .Samples}} events, seed <code>{{.SampleSeed}}</code></p>
+  {{if .Samples}}
+  <table>
+    <thead><tr><th>Time</th><th>Event</th><th>Call</th><th>Risk</th><th>Decision</th><th>Approval</th><th>Execution</th></tr></thead>
+    <tbody>
+      {{range .Samples}}
+      <tr>
+        <td>{{ts .ReceivedAt}}</td>
+        <td><code>{{.EventID}}</code><br><code class="muted">{{.Hash}}</code></td>
+        <td>{{.Tool}}.{{.Action}} by {{.AgentID}}{{if .Resource}}<br><span class="muted">{{.Resource}}</span>{{end}}</td>
+        <td>{{.RiskScore}}</td>
+        <td><span class="badge {{.Decision}}">{{.Decision}}</span> {{.Reason}}</td>
+        <td>{{.ApprovalStatus}}{{if .Approver}} by {{.Approver}}{{end}}</td>
+        <td>{{.ExecutionStatus}}</td>
+      </tr>
+      {{end}}
+    </tbody>
+  </table>
+  {{else}}
+  <p class="empty">No events in this period.</p>
+  {{end}}
+</body>
+</html>`))
@@ -0,0 +1,344 @@
+package dashboard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/evidence"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Store reads dashboard data from the Postgres evidence and approvals tables.
+type Store struct {
+	pool *pgxpool.Pool
+}
+
+// NewStore creates a dashboard store.
+func NewStore(pool *pgxpool.Pool) *Store {
+	return &Store{pool: pool}
+}
+
+// Summaries returns decision counts since the given time and the pending
+// approval queue depth for every tenant that has not been deleted.
+func (s *Store) Summaries(ctx context.Context, since time.Time) ([]TenantSummary, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT t.id,
+		       COUNT(e.event_id) FILTER (WHERE e.decision = 'allow'),
+		       COUNT(e.event_id) FILTER (WHERE e.decision = 'deny'),
+		       COUNT(e.event_id) FILTER (WHERE e.decision = 'approve'),
+		       (SELECT COUNT(*) FROM approval_requests a
+		         WHERE a.tenant_id = t.id AND a.status = 'pending' AND a.expires_at > NOW())
+		FROM tenants t
+		LEFT JOIN tool_events e ON e.tenant_id = t.id AND e.received_at >= $1
+		WHERE t.status <> 'deleted'
+		GROUP BY t.id
+		ORDER BY t.id ASC`, since)
+	if err != nil {
+		return nil, fmt.Errorf("dashboard.Summaries: %w", err)
+	}
+	defer rows.Close()
+
+	var out []TenantSummary
+	for rows.Next() {
+		var ts TenantSummary
+		if err := rows.Scan(&ts.TenantID, &ts.Allowed, &ts.Denied, &ts.NeedsApproval, &ts.PendingApprovals); err != nil {
+			return nil, fmt.Errorf("dashboard.Summaries scan: %w", err)
+		}
+		out = append(out, ts)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("dashboard.Summaries iteration: %w", err)
+	}
+	return out, nil
+}
+
+// RecentDecisions returns the tenant's latest policy decisions, newest first.
+func (s *Store) RecentDecisions(ctx context.Context, tenantID string, limit int) ([]Decision, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT event_id, agent_id, tool, action, COALESCE(adjusted_risk_score, risk_score), decision,
+		       COALESCE(policy_result->>'reason', ''), received_at
+		FROM tool_events
+		WHERE tenant_id = $1
+		ORDER BY received_at DESC
+		LIMIT $2`, tenantID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("dashboard.RecentDecisions: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Decision
+	for rows.Next() {
+		var d Decision
+		if err := rows.Scan(&d.EventID, &d.AgentID, &d.Tool, &d.Action, &d.RiskScore, &d.Decision, &d.Reason, &d.ReceivedAt); err != nil {
+			return nil, fmt.Errorf("dashboard.RecentDecisions scan: %w", err)
+		}
+		out = append(out, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("dashboard.RecentDecisions iteration: %w", err)
+	}
+	return out, nil
+}
+
+// DenyReasons returns the most frequent deny reasons since the given time.
+func (s *Store) DenyReasons(ctx context.Context, tenantID string, since time.Time, limit int) ([]ReasonCount, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT COALESCE(NULLIF(policy_result->>'reason', ''), '(none)') AS reason, COUNT(*)
+		FROM tool_events
+		WHERE tenant_id = $1 AND decision = 'deny' AND received_at >= $2
+		GROUP BY reason
+		ORDER BY COUNT(*) DESC, reason ASC
+		LIMIT $3`, tenantID, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("dashboard.DenyReasons: %w", err)
+	}
+	defer rows.Close()
+
+	var out []ReasonCount
+	for rows.Next() {
+		var rc ReasonCount
+		if err := rows.Scan(&rc.Reason, &rc.Count); err != nil {
+			return nil, fmt.Errorf("dashboard.DenyReasons scan: %w", err)
+		}
+		out = append(out, rc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("dashboard.DenyReasons iteration: %w", err)
+	}
+	return out, nil
+}
+
+// ChainStatus verifies the tenant's latest window events against their own
+// stored links and reports the archiver checkpoint. It is a liveness check
+// for the dashboard; full verification is the archiver's job.
+func (s *Store) ChainStatus(ctx context.Context, tenantID string, window int) (ChainStatus, error) {
+	st := ChainStatus{TenantID: tenantID}
+	rows, err := s.pool.Query(ctx, `
+		SELECT e.event_seq, e.event_id, e.prev_hash, e.hash, e.payload_canon, r.result_canon, e.received_at
+		FROM tool_events e
+		LEFT JOIN tool_results r ON r.event_id = e.event_id
+		WHERE e.tenant_id = $1
+		ORDER BY e.event_seq DESC
+		LIMIT $2`, tenantID, window)
+	if err != nil {
+		return st, fmt.Errorf("dashboard.ChainStatus: %w", err)
+	}
+	var events []evidence.ChainEvent
+	for rows.Next() {
+		var ev evidence.ChainEvent
+		if err := rows.Scan(&ev.EventSeq, &ev.EventID, &ev.PrevHash, &ev.Hash, &ev.CanonPayload, &ev.CanonResult, &ev.ReceivedAt); err != nil {
+			rows.Close()
+			return st, fmt.Errorf("dashboard.ChainStatus scan: %w", err)
+		}
+		events = append(events, ev)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return st, fmt.Errorf("dashboard.ChainStatus iteration: %w", err)
+	}
+
+	// Rows arrive newest first; verification walks oldest first.
+	for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+		events[i], events[j] = events[j], events[i]
+	}
+	st.Checked = len(events)
+	st.Verified = true
+	if len(events) > 0 {
+		st.LastEventAt = events[len(events)-1].ReceivedAt
+		if err := evidence.VerifyChainFrom(events[0].PrevHash, events); err != nil {
+			st.Verified = false
+			st.Error = err.Error()
+		}
+	}
+
+	err = s.pool.QueryRow(ctx, `
+		SELECT last_archived_at FROM evidence_archive_checkpoints WHERE tenant_id = $1`,
+		tenantID).Scan(&st.ArchivedThrough)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return st, fmt.Errorf("dashboard.ChainStatus checkpoint: %w", err)
+	}
+	return st, nil
+}
+
+// chainBatch is how many events AttestChain re-hashes per query.
+const chainBatch = 1000
+
+// ActionDecisions counts the tenant's decisions in [from, to) per
+// tool.action, busiest first.
+func (s *Store) ActionDecisions(ctx context.Context, tenantID string, from, to time.Time) ([]ActionDecisions, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT tool, action,
+		       COUNT(*) FILTER (WHERE decision = 'allow'),
+		       COUNT(*) FILTER (WHERE decision = 'deny'),
+		       COUNT(*) FILTER (WHERE decision = 'approve')
+		FROM tool_events
+		WHERE tenant_id = $1 AND received_at >= $2 AND received_at < $3
+		GROUP BY tool, action
+		ORDER BY COUNT(*) DESC, tool ASC, action ASC`, tenantID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("dashboard.ActionDecisions: %w", err)
+	}
+	defer rows.Close()
+
+	var out []ActionDecisions
+	for rows.Next() {
+		var a ActionDecisions
+		if err := rows.Scan(&a.Tool, &a.Action, &a.Allowed, &a.Denied, &a.NeedsApproval); err != nil {
+			return nil, fmt.Errorf("dashboard.ActionDecisions scan: %w", err)
+		}
+		out = append(out, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("dashboard.ActionDecisions iteration: %w", err)
+	}
+	return out, nil
+}
+
+// ApprovalStats summarizes the approval requests the tenant opened in
+// [from, to). Wait times cover approved and denied requests, from creation
+// to the decision.
+func (s *Store) ApprovalStats(ctx context.Context, tenantID string, from, to time.Time) (ApprovalStats, error) {
+	var st ApprovalStats
+	err := s.pool.QueryRow(ctx, `
+		WITH reqs AS (
+			SELECT status,
+			       CASE WHEN status IN ('approved', 'denied') AND updated_at IS NOT NULL
+			            THEN EXTRACT(EPOCH FROM updated_at - created_at) END AS wait
+			FROM approval_requests
+			WHERE tenant_id = $1 AND created_at >= $2 AND created_at < $3
+		)
+		SELECT COUNT(*),
+		       COUNT(*) FILTER (WHERE status = 'approved'),
+		       COUNT(*) FILTER (WHERE status = 'denied'),
+		       COUNT(*) FILTER (WHERE status = 'expired'),
+		       COUNT(*) FILTER (WHERE status = 'pending'),
+		       COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY wait), 0),
+		       COALESCE(percentile_cont(0.9) WITHIN GROUP (ORDER BY wait), 0),
+		       COALESCE(MAX(wait), 0)::float8
+		FROM reqs`, tenantID, from, to).Scan(
+		&st.Requested, &st.Approved, &st.Denied, &st.Expired, &st.Pending,
+		&st.MedianSec, &st.P90Sec, &st.MaxSec,
+	)
+	if err != nil {
+		return st, fmt.Errorf("dashboard.ApprovalStats: %w", err)
+	}
+	return st, nil
+}
+
+// AttestChain re-hashes, in chain order, every event of the tenant from the
+// first to the last one received in [from, to), in batches, and reports the
+// first broken link.
+func (s *Store) AttestChain(ctx context.Context, tenantID string, from, to time.Time) (ChainAttestation, error) {
+	var att ChainAttestation
+	var first, last *int64
+	err := s.pool.QueryRow(ctx, `
+		SELECT MIN(event_seq), MAX(event_seq) FROM tool_events
+		WHERE tenant_id = $1 AND received_at >= $2 AND received_at < $3`,
+		tenantID, from, to).Scan(&first, &last)
+	if err != nil {
+		return att, fmt.Errorf("dashboard.AttestChain range: %w", err)
+	}
+	att.Verified = true
+	if first == nil {
+		return att, nil
+	}
+	att.FirstSeq, att.LastSeq = *first, *last
+
+	prev, after := "", *first-1
+	for {
+		events, err := s.chainEvents(ctx, tenantID, after, att.LastSeq)
+		if err != nil {
+			return att, err
+		}
+		if len(events) == 0 {
+			return att, nil
+		}
+		if att.Checked == 0 {
+			prev = events[0].PrevHash
+			att.StartPrevHash = prev
+		}
+		if err := evidence.VerifyChainFrom(prev, events); err != nil {
+			att.Verified = false
+			att.Error = err.Error()
+			return att, nil
+		}
+		att.Checked += int64(len(events))
+		prev = events[len(events)-1].Hash
+		att.HeadHash = prev
+		after = events[len(events)-1].EventSeq
+	}
+}
+
+// chainEvents returns up to chainBatch of the tenant's events with
+// sequence numbers in (after, through], in order.
+func (s *Store) chainEvents(ctx context.Context, tenantID string, after, through int64) ([]evidence.ChainEvent, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT e.event_seq, e.event_id, e.prev_hash, e.hash, e.payload_canon, r.result_canon, e.received_at
+		FROM tool_events e
+		LEFT JOIN tool_results r ON r.event_id = e.event_id
+		WHERE e.tenant_id = $1 AND e.event_seq > $2 AND e.event_seq <= $3
+		ORDER BY e.event_seq ASC
+		LIMIT $4`, tenantID, after, through, chainBatch)
+	if err != nil {
+		return nil, fmt.Errorf("dashboard.AttestChain: %w", err)
+	}
+	defer rows.Close()
+
+	var events []evidence.ChainEvent
+	for rows.Next() {
+		var ev evidence.ChainEvent
+		if err := rows.Scan(&ev.EventSeq, &ev.EventID, &ev.PrevHash, &ev.Hash, &ev.CanonPayload, &ev.CanonResult, &ev.ReceivedAt); err != nil {
+			return nil, fmt.Errorf("dashboard.AttestChain scan: %w", err)
+		}
+		events = append(events, ev)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("dashboard.AttestChain iteration: %w", err)
+	}
+	return events, nil
+}
+
+// SampleEvents returns n of the tenant's events received in [from, to),
+// chosen by hashing each event ID with seed so the same seed reproduces
+// the same sample.
+func (s *Store) SampleEvents(ctx context.Context, tenantID string, from, to time.Time, n int, seed string) ([]SampledEvent, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT e.event_id, e.received_at, e.agent_id, e.tool, e.action,
+		       COALESCE(e.payload_json->>'resource', ''), COALESCE(e.adjusted_risk_score, e.risk_score),
+		       e.decision, COALESCE(e.policy_result->>'reason', ''), e.hash,
+		       COALESCE(a.status, ''), COALESCE(g.approver, NULLIF(a.denied_by, ''), ''),
+		       COALESCE(r.status, xr.status, '')
+		FROM tool_events e
+		LEFT JOIN approval_requests a ON a.event_id = e.event_id
+		LEFT JOIN approval_grants g ON g.request_id = a.id
+		LEFT JOIN tool_results r ON r.event_id = e.event_id
+		LEFT JOIN tool_executions x ON x.parent_event_id = e.event_id
+		LEFT JOIN tool_results xr ON xr.event_id = x.execution_event_id
+		WHERE e.tenant_id = $1 AND e.received_at >= $2 AND e.received_at < $3
+		ORDER BY md5(e.event_id || $4) ASC
+		LIMIT $5`, tenantID, from, to, seed, n)
+	if err != nil {
+		return nil, fmt.Errorf("dashboard.SampleEvents: %w", err)
+	}
+	defer rows.Close()
+
+	var out []SampledEvent
+	for rows.Next() {
+		var e SampledEvent
+		if err := rows.Scan(
+			&e.EventID, &e.ReceivedAt, &e.AgentID, &e.Tool, &e.Action,
+			&e.Resource, &e.RiskScore, &e.Decision, &e.Reason, &e.Hash,
+			&e.ApprovalStatus, &e.Approver, &e.ExecutionStatus,
+		); err != nil {
+			return nil, fmt.Errorf("dashboard.SampleEvents scan: %w", err)
+		}
+		out = append(out, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("dashboard.SampleEvents iteration: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,107 @@
+// Package diagnostics provides the internal-only metrics and profiling listener
+// shared by all services.
+package diagnostics
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Config holds diagnostics listener settings.
+type Config struct {
+	Addr string
+	// InternalToken guards /debug/* via the X-Internal-Token header.
+	// When empty, profiling endpoints are not mounted at all.
+	InternalToken string
+}
+
+// NewServer returns the internal diagnostics server. /metrics is always
+// served (the listener is expected to be bound to a private address);
+// /debug/pprof/* and /debug/runtime require the internal token.
+func NewServer(cfg Config) *http.Server {
+	return &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           NewMux(cfg.InternalToken),
+		ReadTimeout:       5 * time.Second,
+		ReadHeaderTimeout: 2 * time.Second,
+		// CPU profiles and traces stream for up to ?seconds=N.
+		WriteTimeout: 90 * time.Second,
+		IdleTimeout:  30 * time.Second,
+	}
+}
+
+// NewMux builds the diagnostics handler tree.
+func NewMux(internalToken string) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	if internalToken == "" {
+		return mux
+	}
+
+	debug := http.NewServeMux()
+	debug.HandleFunc("/debug/pprof/", pprof.Index)
+	debug.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	debug.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	debug.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	debug.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	debug.HandleFunc("/debug/runtime", handleRuntime)
+	mux.Handle("/debug/", requireToken(internalToken, debug))
+	return mux
+}
+
+func requireToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Internal-Token")), []byte(token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RuntimeStats is a point-in-time snapshot of Go runtime state.
+type RuntimeStats struct {
+	GoVersion      string  `json:"go_version"`
+	NumCPU         int     `json:"num_cpu"`
+	GOMAXPROCS     int     `json:"gomaxprocs"`
+	NumGoroutine   int     `json:"num_goroutine"`
+	HeapAllocBytes uint64  `json:"heap_alloc_bytes"`
+	HeapInuseBytes uint64  `json:"heap_inuse_bytes"`
+	SysBytes       uint64  `json:"sys_bytes"`
+	NumGC          uint32  `json:"num_gc"`
+	LastGCPauseMS  float64 `json:"last_gc_pause_ms"`
+	GCCPUFraction  float64 `json:"gc_cpu_fraction"`
+}
+
+// ReadRuntimeStats captures the current runtime statistics.
+func ReadRuntimeStats() RuntimeStats {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	var lastPause float64
+	if m.NumGC > 0 {
+		lastPause = float64(m.PauseNs[(m.NumGC+255)%256]) / float64(time.Millisecond)
+	}
+	return RuntimeStats{
+		GoVersion:      runtime.Version(),
+		NumCPU:         runtime.NumCPU(),
+		GOMAXPROCS:     runtime.GOMAXPROCS(0),
+		NumGoroutine:   runtime.NumGoroutine(),
+		HeapAllocBytes: m.HeapAlloc,
+		HeapInuseBytes: m.HeapInuse,
+		SysBytes:       m.Sys,
+		NumGC:          m.NumGC,
+		LastGCPauseMS:  lastPause,
+		GCCPUFraction:  m.GCCPUFraction,
+	}
+}
+
+func handleRuntime(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(ReadRuntimeStats())
+}
@@ -0,0 +1,60 @@
+package diagnostics
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMux_DebugRequiresToken(t *testing.T) {
+	mux := NewMux("secret")
+
+	req := httptest.NewRequest(http.MethodGet, "/debug/runtime", nil)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without token, got %d", rr.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/debug/runtime", nil)
+	req.Header.Set("X-Internal-Token", "secret")
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 with token, got %d", rr.Code)
+	}
+	var stats RuntimeStats
+	if err := json.NewDecoder(rr.Body).Decode(&stats); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if stats.NumGoroutine == 0 || stats.GoVersion == "" {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil)
+	req.Header.Set("X-Internal-Token", "secret")
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected pprof index 200, got %d", rr.Code)
+	}
+}
+
+func TestMux_DebugDisabledWithoutToken(t *testing.T) {
+	mux := NewMux("")
+
+	req := httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 when profiling disabled, got %d", rr.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected /metrics 200, got %d", rr.Code)
+	}
+}
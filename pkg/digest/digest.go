@@ -0,0 +1,203 @@
+// Package digest sends tenants' periodic compliance digests: once a week
+// or month ends, a summary of calls by decision, the most denied actions,
+// outstanding approvals and evidence chain health goes to the tenant's
+// admins through the approvals notification providers.
+package digest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/approvals"
+	"github.com/bturcanu/OpenClause/pkg/dashboard"
+	"github.com/bturcanu/OpenClause/pkg/tenants"
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+const (
+	// topDenied is how many of the most denied actions a digest lists.
+	topDenied = 5
+	// claimLease is how long a claimed digest waits before another
+	// attempt, by this or another replica.
+	claimLease = 15 * time.Minute
+	// maxAttempts bounds delivery attempts per digest and period.
+	maxAttempts = 10
+)
+
+// Summary is the content of one tenant's digest for one period.
+type Summary struct {
+	TenantID         string
+	Name             string
+	Period           string
+	From             time.Time
+	To               time.Time
+	Decisions        dashboard.DecisionTotals
+	TopDenied        []dashboard.ActionDecisions
+	PendingApprovals int64
+	Chain            dashboard.ChainAttestation
+}
+
+type source interface {
+	Summaries(ctx context.Context, since time.Time) ([]dashboard.TenantSummary, error)
+	ActionDecisions(ctx context.Context, tenantID string, from, to time.Time) ([]dashboard.ActionDecisions, error)
+	AttestChain(ctx context.Context, tenantID string, from, to time.Time) (dashboard.ChainAttestation, error)
+}
+
+type settingsSource interface {
+	Get(ctx context.Context, tenantID string) (tenants.Settings, error)
+}
+
+type deliverer interface {
+	DeliverDigest(ctx context.Context, route types.PolicyNotify, m approvals.DigestMessage) error
+}
+
+type ledger interface {
+	Claim(ctx context.Context, tenantID, name string, periodStart time.Time, lease time.Duration) (bool, error)
+	MarkSent(ctx context.Context, tenantID, name string, periodStart time.Time) error
+	MarkFailed(ctx context.Context, tenantID, name string, periodStart time.Time, reason string) error
+}
+
+// Job sends the digests configured in tenant settings.
+type Job struct {
+	source   source
+	settings settingsSource
+	deliver  deliverer
+	ledger   ledger
+	log      *slog.Logger
+	now      func() time.Time
+}
+
+// NewJob creates a digest job. src is usually a dashboard.Store, settings a
+// tenants.SettingsCache, d the approvals Dispatcher and l a Store.
+func NewJob(src source, settings settingsSource, d deliverer, l ledger, log *slog.Logger) *Job {
+	if log == nil {
+		log = slog.Default()
+	}
+	return &Job{source: src, settings: settings, deliver: d, ledger: l, log: log, now: time.Now}
+}
+
+// RunOnce sends every tenant digest whose last complete period has not been
+// sent yet. Failures are logged and retried on a later run once the claim's
+// lease runs out.
+func (j *Job) RunOnce(ctx context.Context) error {
+	now := j.now()
+	// Counting from now leaves only the tenant list and queue depths.
+	list, err := j.source.Summaries(ctx, now)
+	if err != nil {
+		return fmt.Errorf("digest.RunOnce: %w", err)
+	}
+	for _, ts := range list {
+		s, err := j.settings.Get(ctx, ts.TenantID)
+		if err != nil {
+			j.log.Error("digest settings lookup failed", "tenant_id", ts.TenantID, "error", err)
+			continue
+		}
+		for _, d := range s.Digests {
+			if err := j.send(ctx, ts, d, now); err != nil {
+				j.log.Error("digest delivery failed", "tenant_id", ts.TenantID, "digest", d.Name, "error", err)
+			}
+		}
+	}
+	return nil
+}
+
+func (j *Job) send(ctx context.Context, ts dashboard.TenantSummary, d tenants.Digest, now time.Time) error {
+	from, to := d.LastPeriod(now)
+	ok, err := j.ledger.Claim(ctx, ts.TenantID, d.Name, from, claimLease)
+	if err != nil || !ok {
+		return err
+	}
+	sum, err := j.summarize(ctx, ts, d, from, to)
+	if err == nil {
+		err = j.deliver.DeliverDigest(ctx, d.Notify, sum.Message())
+	}
+	if err != nil {
+		if markErr := j.ledger.MarkFailed(ctx, ts.TenantID, d.Name, from, err.Error()); markErr != nil {
+			j.log.Error("mark digest failed error", "tenant_id", ts.TenantID, "digest", d.Name, "error", markErr)
+		}
+		return err
+	}
+	j.log.Info("digest sent", "tenant_id", ts.TenantID, "digest", d.Name, "from", from, "to", to)
+	return j.ledger.MarkSent(ctx, ts.TenantID, d.Name, from)
+}
+
+func (j *Job) summarize(ctx context.Context, ts dashboard.TenantSummary, d tenants.Digest, from, to time.Time) (Summary, error) {
+	sum := Summary{
+		TenantID:         ts.TenantID,
+		Name:             d.Name,
+		Period:           d.Period,
+		From:             from,
+		To:               to,
+		PendingApprovals: ts.PendingApprovals,
+	}
+	actions, err := j.source.ActionDecisions(ctx, ts.TenantID, from, to)
+	if err != nil {
+		return sum, err
+	}
+	for _, a := range actions {
+		sum.Decisions.Allowed += a.Allowed
+		sum.Decisions.Denied += a.Denied
+		sum.Decisions.NeedsApproval += a.NeedsApproval
+		if a.Denied > 0 {
+			sum.TopDenied = append(sum.TopDenied, a)
+		}
+	}
+	sum.Decisions.Total = sum.Decisions.Allowed + sum.Decisions.Denied + sum.Decisions.NeedsApproval
+	sort.SliceStable(sum.TopDenied, func(a, b int) bool { return sum.TopDenied[a].Denied > sum.TopDenied[b].Denied })
+	if len(sum.TopDenied) > topDenied {
+		sum.TopDenied = sum.TopDenied[:topDenied]
+	}
+	if sum.Chain, err = j.source.AttestChain(ctx, ts.TenantID, from, to); err != nil {
+		return sum, err
+	}
+	return sum, nil
+}
+
+// Message renders the summary as a digest message.
+func (s Summary) Message() approvals.DigestMessage {
+	id := sha256.Sum256([]byte(s.TenantID + "\x00" + s.Name + "\x00" + s.From.Format(time.RFC3339)))
+	last := s.To.AddDate(0, 0, -1).Format("2006-01-02")
+	title := "Weekly"
+	if s.Period == tenants.DigestMonthly {
+		title = "Monthly"
+	}
+
+	lines := []string{
+		fmt.Sprintf("OpenClause %s compliance digest", s.Period),
+		fmt.Sprintf("Tenant: %s", s.TenantID),
+		fmt.Sprintf("Period: %s to %s (%s)", s.From.Format("2006-01-02"), last, s.From.Location()),
+		"",
+		fmt.Sprintf("Tool calls: %d (allowed %d, denied %d, needed approval %d)",
+			s.Decisions.Total, s.Decisions.Allowed, s.Decisions.Denied, s.Decisions.NeedsApproval),
+		"",
+		"Most denied actions:",
+	}
+	if len(s.TopDenied) == 0 {
+		lines = append(lines, "  none")
+	}
+	for _, a := range s.TopDenied {
+		lines = append(lines, fmt.Sprintf("  %-40s %d", a.Tool+"."+a.Action, a.Denied))
+	}
+	lines = append(lines, "", fmt.Sprintf("Outstanding approvals: %d", s.PendingApprovals), "")
+	switch {
+	case !s.Chain.Verified:
+		lines = append(lines, "Evidence chain: BROKEN: "+s.Chain.Error)
+	case s.Chain.Checked == 0:
+		lines = append(lines, "Evidence chain: no events in the period")
+	default:
+		lines = append(lines, fmt.Sprintf("Evidence chain: intact, %d events re-hashed (seq %d-%d, head %s)",
+			s.Chain.Checked, s.Chain.FirstSeq, s.Chain.LastSeq, s.Chain.HeadHash))
+	}
+
+	return approvals.DigestMessage{
+		ID:       "digest-" + hex.EncodeToString(id[:16]),
+		TenantID: s.TenantID,
+		Subject:  fmt.Sprintf("[OpenClause] %s compliance digest: %s (%s to %s)", title, s.TenantID, s.From.Format("2006-01-02"), last),
+		Text:     lines,
+	}
+}
@@ -0,0 +1,136 @@
+package digest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/approvals"
+	"github.com/bturcanu/OpenClause/pkg/dashboard"
+	"github.com/bturcanu/OpenClause/pkg/tenants"
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+type fakeSource struct{}
+
+func (fakeSource) Summaries(_ context.Context, _ time.Time) ([]dashboard.TenantSummary, error) {
+	return []dashboard.TenantSummary{{TenantID: "acme", PendingApprovals: 3}, {TenantID: "quiet"}}, nil
+}
+
+func (fakeSource) ActionDecisions(_ context.Context, _ string, _, _ time.Time) ([]dashboard.ActionDecisions, error) {
+	return []dashboard.ActionDecisions{
+		{Tool: "slack", Action: "msg.post", Allowed: 40, Denied: 1},
+		{Tool: "jira", Action: "issue.read", Allowed: 12},
+		{Tool: "github", Action: "pr.merge", Allowed: 2, Denied: 6, NeedsApproval: 4},
+	}, nil
+}
+
+func (fakeSource) AttestChain(_ context.Context, _ string, _, _ time.Time) (dashboard.ChainAttestation, error) {
+	return dashboard.ChainAttestation{Verified: true, Checked: 65, FirstSeq: 100, LastSeq: 164, HeadHash: "beef"}, nil
+}
+
+type fakeSettings map[string]tenants.Settings
+
+func (f fakeSettings) Get(_ context.Context, tenantID string) (tenants.Settings, error) {
+	return f[tenantID], nil
+}
+
+type fakeDeliverer struct {
+	sent []approvals.DigestMessage
+	err  error
+}
+
+func (f *fakeDeliverer) DeliverDigest(_ context.Context, _ types.PolicyNotify, m approvals.DigestMessage) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.sent = append(f.sent, m)
+	return nil
+}
+
+// fakeLedger ignores leases: a failed period can be claimed again at once.
+type fakeLedger struct {
+	sent   map[string]bool
+	failed map[string]string
+}
+
+func ledgerKey(tenantID, name string, periodStart time.Time) string {
+	return tenantID + "/" + name + "/" + periodStart.Format(time.RFC3339)
+}
+
+func (f *fakeLedger) Claim(_ context.Context, tenantID, name string, periodStart time.Time, _ time.Duration) (bool, error) {
+	return !f.sent[ledgerKey(tenantID, name, periodStart)], nil
+}
+
+func (f *fakeLedger) MarkSent(_ context.Context, tenantID, name string, periodStart time.Time) error {
+	f.sent[ledgerKey(tenantID, name, periodStart)] = true
+	return nil
+}
+
+func (f *fakeLedger) MarkFailed(_ context.Context, tenantID, name string, periodStart time.Time, reason string) error {
+	f.failed[ledgerKey(tenantID, name, periodStart)] = reason
+	return nil
+}
+
+func TestJob_RunOnce(t *testing.T) {
+	route := types.PolicyNotify{Kind: "email", Config: map[string]string{"to": "secops@acme.com"}}
+	settings := fakeSettings{"acme": {Digests: []tenants.Digest{
+		{Name: "weekly", Period: tenants.DigestWeekly, Notify: route},
+		{Name: "monthly", Period: tenants.DigestMonthly, Notify: route},
+	}}}
+	d := &fakeDeliverer{err: errors.New("smtp down")}
+	l := &fakeLedger{sent: map[string]bool{}, failed: map[string]string{}}
+	job := NewJob(fakeSource{}, settings, d, l, nil)
+	// Friday 2026-10-16.
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	job.now = func() time.Time { return now }
+
+	if err := job.RunOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(l.failed) != 2 || l.failed["acme/weekly/2026-10-05T00:00:00Z"] != "smtp down" {
+		t.Fatalf("failed = %v", l.failed)
+	}
+
+	d.err = nil
+	for range 2 {
+		if err := job.RunOnce(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(d.sent) != 2 {
+		t.Fatalf("sent %d digests, want one per digest and period", len(d.sent))
+	}
+	m := d.sent[0]
+	if m.Subject != "[OpenClause] Weekly compliance digest: acme (2026-10-05 to 2026-10-11)" || m.TenantID != "acme" {
+		t.Errorf("subject = %q", m.Subject)
+	}
+	text := strings.Join(m.Text, "\n")
+	for _, want := range []string{
+		"Tool calls: 65 (allowed 54, denied 7, needed approval 4)",
+		"github.pr.merge",
+		"Outstanding approvals: 3",
+		"Evidence chain: intact, 65 events re-hashed (seq 100-164, head beef)",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("digest lacks %q:\n%s", want, text)
+		}
+	}
+	if strings.Index(text, "github.pr.merge") > strings.Index(text, "slack.msg.post") || strings.Contains(text, "jira.issue.read") {
+		t.Errorf("denied actions not ranked by denials:\n%s", text)
+	}
+	if d.sent[1].ID == m.ID || !strings.HasPrefix(d.sent[1].Subject, "[OpenClause] Monthly compliance digest: acme (2026-09-01 to 2026-09-30)") {
+		t.Errorf("monthly digest = %+v", d.sent[1])
+	}
+
+	// The next week's digest goes out once that week ends.
+	now = now.AddDate(0, 0, 3)
+	if err := job.RunOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.sent) != 3 || !strings.Contains(d.sent[2].Subject, "2026-10-12 to 2026-10-18") {
+		t.Fatalf("after a week: %d digests", len(d.sent))
+	}
+}
@@ -0,0 +1,73 @@
+package digest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Store records digest deliveries in the tenant_digests table, so each
+// period is sent once across restarts and replicas.
+type Store struct {
+	pool *pgxpool.Pool
+}
+
+// NewStore creates a digest store.
+func NewStore(pool *pgxpool.Pool) *Store {
+	return &Store{pool: pool}
+}
+
+// Claim reserves the tenant's digest name for the period starting at
+// periodStart. It returns false when the period was already sent, another
+// replica claimed it less than lease ago, or it has failed maxAttempts
+// times.
+func (s *Store) Claim(ctx context.Context, tenantID, name string, periodStart time.Time, lease time.Duration) (bool, error) {
+	var attempts int
+	err := s.pool.QueryRow(ctx, `
+		INSERT INTO tenant_digests (tenant_id, name, period_start, claimed_at, attempts)
+		VALUES ($1, $2, $3, NOW(), 1)
+		ON CONFLICT (tenant_id, name, period_start) DO UPDATE
+		SET claimed_at = NOW(), attempts = tenant_digests.attempts + 1
+		WHERE tenant_digests.sent_at IS NULL
+		  AND tenant_digests.attempts < $5
+		  AND tenant_digests.claimed_at < NOW() - make_interval(secs => $4)
+		RETURNING attempts`,
+		tenantID, name, periodStart, lease.Seconds(), maxAttempts,
+	).Scan(&attempts)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("digest.Claim: %w", err)
+	}
+	return true, nil
+}
+
+// MarkSent records a delivered digest.
+func (s *Store) MarkSent(ctx context.Context, tenantID, name string, periodStart time.Time) error {
+	_, err := s.pool.Exec(ctx, `
+		UPDATE tenant_digests SET sent_at = NOW(), last_error = NULL
+		WHERE tenant_id = $1 AND name = $2 AND period_start = $3`,
+		tenantID, name, periodStart)
+	if err != nil {
+		return fmt.Errorf("digest.MarkSent: %w", err)
+	}
+	return nil
+}
+
+// MarkFailed records why a delivery failed. The claim stays in place until
+// its lease runs out, which spaces out retries.
+func (s *Store) MarkFailed(ctx context.Context, tenantID, name string, periodStart time.Time, reason string) error {
+	_, err := s.pool.Exec(ctx, `
+		UPDATE tenant_digests SET last_error = $4
+		WHERE tenant_id = $1 AND name = $2 AND period_start = $3`,
+		tenantID, name, periodStart, reason)
+	if err != nil {
+		return fmt.Errorf("digest.MarkFailed: %w", err)
+	}
+	return nil
+}
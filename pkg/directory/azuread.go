@@ -0,0 +1,164 @@
+package directory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AzureAD reads groups from Microsoft Graph as an app registration with the
+// client-credentials grant; the app needs GroupMember.Read.All and
+// User.Read.All.
+type AzureAD struct {
+	// TenantID is the Entra ID (Azure AD) tenant, not an OpenClause tenant.
+	TenantID     string
+	ClientID     string
+	ClientSecret string
+	// SlackAttribute names the Graph user property holding the Slack member
+	// ID, e.g. an extension attribute.
+	SlackAttribute string
+	// GraphURL and LoginURL default to the public cloud endpoints.
+	GraphURL   string
+	LoginURL   string
+	HTTPClient *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// Members finds the group by displayName and returns its users, including
+// members of nested groups.
+func (a *AzureAD) Members(ctx context.Context, group string) ([]Member, error) {
+	q := url.Values{
+		"$filter": {"displayName eq '" + strings.ReplaceAll(group, "'", "''") + "'"},
+		"$select": {"id"},
+	}
+	var found struct {
+		Value []struct {
+			ID string `json:"id"`
+		} `json:"value"`
+	}
+	if _, err := a.get(ctx, "/v1.0/groups?"+q.Encode(), &found); err != nil {
+		return nil, fmt.Errorf("azuread: %w", err)
+	}
+	if len(found.Value) == 0 {
+		return nil, fmt.Errorf("azuread: group %q not found", group)
+	}
+
+	fields := []string{"id", "mail", "userPrincipalName", "otherMails"}
+	if a.SlackAttribute != "" {
+		fields = append(fields, a.SlackAttribute)
+	}
+	next := "/v1.0/groups/" + url.PathEscape(found.Value[0].ID) + "/transitiveMembers/microsoft.graph.user?" +
+		url.Values{"$select": {strings.Join(fields, ",")}, "$top": {"999"}}.Encode()
+	var out []Member
+	for next != "" {
+		var page struct {
+			Value []map[string]any `json:"value"`
+		}
+		var err error
+		if next, err = a.get(ctx, next, &page); err != nil {
+			return nil, fmt.Errorf("azuread: %w", err)
+		}
+		for _, u := range page.Value {
+			m := Member{ID: stringAttr(u, "id"), SlackID: stringAttr(u, a.SlackAttribute)}
+			for _, attr := range []string{"mail", "userPrincipalName"} {
+				if v := stringAttr(u, attr); strings.Contains(v, "@") {
+					m.Emails = append(m.Emails, v)
+				}
+			}
+			if others, ok := u["otherMails"].([]any); ok {
+				for _, o := range others {
+					if v, ok := o.(string); ok {
+						m.Emails = append(m.Emails, v)
+					}
+				}
+			}
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+// get decodes the page at pathOrURL into out and returns the
+// @odata.nextLink of the response, or "".
+func (a *AzureAD) get(ctx context.Context, pathOrURL string, out any) (string, error) {
+	target, err := resolvePage(a.graphURL(), pathOrURL)
+	if err != nil {
+		return "", err
+	}
+	token, err := a.bearer(ctx)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	body, _, err := doRequest(a.HTTPClient, req)
+	if err != nil {
+		return "", err
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return "", fmt.Errorf("decode %s: %w", req.URL.Path, err)
+	}
+	var paging struct {
+		NextLink string `json:"@odata.nextLink"`
+	}
+	_ = json.Unmarshal(body, &paging)
+	return paging.NextLink, nil
+}
+
+func (a *AzureAD) graphURL() string {
+	if a.GraphURL != "" {
+		return strings.TrimRight(a.GraphURL, "/")
+	}
+	return "https://graph.microsoft.com"
+}
+
+// bearer returns a cached Graph token, renewing it a minute before expiry.
+func (a *AzureAD) bearer(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.token != "" && time.Now().Before(a.expires) {
+		return a.token, nil
+	}
+	login := a.LoginURL
+	if login == "" {
+		login = "https://login.microsoftonline.com"
+	}
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {a.ClientID},
+		"client_secret": {a.ClientSecret},
+		"scope":         {a.graphURL() + "/.default"},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		strings.TrimRight(login, "/")+"/"+url.PathEscape(a.TenantID)+"/oauth2/v2.0/token",
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	body, _, err := doRequest(a.HTTPClient, req)
+	if err != nil {
+		return "", fmt.Errorf("token: %w", err)
+	}
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &tok); err != nil {
+		return "", fmt.Errorf("token: %w", err)
+	}
+	a.token = tok.AccessToken
+	a.expires = time.Now().Add(time.Duration(tok.ExpiresIn)*time.Second - time.Minute)
+	return a.token, nil
+}
@@ -0,0 +1,247 @@
+// Package directory authorizes approvers by group membership in an identity
+// provider. Policy routes an approval to an approver_group by name; each
+// tenant maps those names to groups in its directory (SCIM 2.0, Okta or
+// Azure AD), and a Directory keeps an in-memory copy of their members,
+// refreshed periodically, to check approvers against.
+package directory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const maxResponseBytes = 4 << 20
+
+// DefaultGroup is the policy group name that maps a tenant's directory group
+// for requests whose policy named no approver group, or one the tenant has
+// not mapped.
+const DefaultGroup = "*"
+
+// Member is a user in a directory group.
+type Member struct {
+	ID     string
+	Emails []string
+	// SlackID is the user's Slack member ID, read from the provider's
+	// configured Slack attribute; empty when there is none.
+	SlackID string
+}
+
+// Provider lists the members of a directory group, including members of
+// nested groups where the directory supports it.
+type Provider interface {
+	Members(ctx context.Context, group string) ([]Member, error)
+}
+
+// Groups maps tenant → policy approver group → directory group name.
+type Groups map[string]map[string]string
+
+// ParseGroups parses tenant mappings of the form
+// "tenant1:security=Security Approvers|*=IT Admins,tenant2:*=Approvers".
+func ParseGroups(raw string) (Groups, error) {
+	out := Groups{}
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		tenantID, mappings, ok := strings.Cut(entry, ":")
+		tenantID = strings.TrimSpace(tenantID)
+		if !ok || tenantID == "" {
+			return nil, fmt.Errorf("directory.ParseGroups: %q: want tenant:group=directory_group", entry)
+		}
+		for _, m := range strings.Split(mappings, "|") {
+			group, dirGroup, ok := strings.Cut(m, "=")
+			group, dirGroup = strings.TrimSpace(group), strings.TrimSpace(dirGroup)
+			if !ok || group == "" || dirGroup == "" {
+				return nil, fmt.Errorf("directory.ParseGroups: %q: want group=directory_group", m)
+			}
+			if out[tenantID] == nil {
+				out[tenantID] = map[string]string{}
+			}
+			out[tenantID][group] = dirGroup
+		}
+	}
+	return out, nil
+}
+
+// membership is the synced copy of one directory group.
+type membership struct {
+	emails map[string]struct{}
+	slack  map[string]struct{}
+}
+
+// Directory authorizes approvers against the synced members of each
+// tenant's mapped directory groups. Until the first successful sync of a
+// group, nobody is a member of it.
+type Directory struct {
+	provider Provider
+	groups   Groups
+	log      *slog.Logger
+
+	mu      sync.RWMutex
+	members map[string]*membership // directory group → members
+}
+
+// New creates a Directory over provider for the given mappings. Call Sync or
+// Run to load members. A nil log uses slog.Default().
+func New(provider Provider, groups Groups, log *slog.Logger) *Directory {
+	if log == nil {
+		log = slog.Default()
+	}
+	return &Directory{provider: provider, groups: groups, log: log, members: map[string]*membership{}}
+}
+
+// Sync reloads the members of every mapped directory group. A group that
+// fails to load keeps its previous members; the errors are joined.
+func (d *Directory) Sync(ctx context.Context) error {
+	seen := map[string]bool{}
+	var errs []error
+	for _, mappings := range d.groups {
+		for _, dirGroup := range mappings {
+			if seen[dirGroup] {
+				continue
+			}
+			seen[dirGroup] = true
+			members, err := d.provider.Members(ctx, dirGroup)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("directory.Sync %q: %w", dirGroup, err))
+				continue
+			}
+			m := &membership{emails: map[string]struct{}{}, slack: map[string]struct{}{}}
+			for _, u := range members {
+				for _, e := range u.Emails {
+					if e = normalize(e); e != "" {
+						m.emails[e] = struct{}{}
+					}
+				}
+				if id := normalize(u.SlackID); id != "" {
+					m.slack[id] = struct{}{}
+				}
+			}
+			d.mu.Lock()
+			d.members[dirGroup] = m
+			d.mu.Unlock()
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Run syncs immediately and then every interval until ctx is done, logging
+// failures.
+func (d *Directory) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := d.Sync(ctx); err != nil {
+			d.log.Error("approver directory sync failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// AllowEmail reports whether email belongs to the directory group the
+// tenant maps group to.
+func (d *Directory) AllowEmail(tenantID, group, email string) bool {
+	m := d.membership(tenantID, group)
+	if m == nil {
+		return false
+	}
+	_, ok := m.emails[normalize(email)]
+	return ok
+}
+
+// AllowSlack reports whether the Slack user belongs to the directory group
+// the tenant maps group to.
+func (d *Directory) AllowSlack(tenantID, group, userID string) bool {
+	m := d.membership(tenantID, group)
+	if m == nil {
+		return false
+	}
+	_, ok := m.slack[normalize(userID)]
+	return ok
+}
+
+// membership returns the synced members for the tenant's mapping of group,
+// falling back to its DefaultGroup mapping.
+func (d *Directory) membership(tenantID, group string) *membership {
+	mappings := d.groups[tenantID]
+	dirGroup, ok := mappings[group]
+	if !ok || group == "" {
+		dirGroup, ok = mappings[DefaultGroup]
+	}
+	if !ok {
+		return nil
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.members[dirGroup]
+}
+
+func normalize(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+// doRequest performs req and returns the body and headers of a 2xx
+// response.
+func doRequest(client *http.Client, req *http.Request) ([]byte, http.Header, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, nil, fmt.Errorf("%s %s: status %d: %s", req.Method, req.URL.Path, resp.StatusCode,
+			strings.TrimSpace(string(body[:min(len(body), 256)])))
+	}
+	return body, resp.Header, nil
+}
+
+// resolvePage joins a path to base, or checks that an absolute paging URL
+// stays on base's host so the credential is never sent elsewhere.
+func resolvePage(base, pathOrURL string) (string, error) {
+	base = strings.TrimRight(base, "/")
+	if strings.HasPrefix(pathOrURL, "/") {
+		return base + pathOrURL, nil
+	}
+	b, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(pathOrURL)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != b.Scheme || u.Host != b.Host {
+		return "", fmt.Errorf("next page %s is not on %s", u.Redacted(), b.Host)
+	}
+	return pathOrURL, nil
+}
+
+// stringAttr returns obj[name] when it is a non-empty string.
+func stringAttr(obj map[string]any, name string) string {
+	if name == "" {
+		return ""
+	}
+	s, _ := obj[name].(string)
+	return s
+}
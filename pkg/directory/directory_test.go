@@ -0,0 +1,236 @@
+package directory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseGroups(t *testing.T) {
+	g, err := ParseGroups("tenant1:security=Security Approvers|*=IT Admins, tenant2:*=Approvers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g["tenant1"]["security"] != "Security Approvers" || g["tenant1"]["*"] != "IT Admins" || g["tenant2"]["*"] != "Approvers" {
+		t.Fatalf("groups = %v", g)
+	}
+	for _, bad := range []string{"tenant1", "tenant1:security", ":*=Admins", "tenant1:=Admins"} {
+		if _, err := ParseGroups(bad); err == nil {
+			t.Errorf("ParseGroups(%q) succeeded", bad)
+		}
+	}
+}
+
+type fakeProvider struct {
+	members map[string][]Member
+	err     error
+}
+
+func (f *fakeProvider) Members(_ context.Context, group string) ([]Member, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.members[group], nil
+}
+
+func TestDirectory_AuthorizesByGroup(t *testing.T) {
+	p := &fakeProvider{members: map[string][]Member{
+		"Security Approvers": {{ID: "1", Emails: []string{"Alice@Example.com"}, SlackID: "U1"}},
+		"IT Admins":          {{ID: "2", Emails: []string{"bob@example.com"}, SlackID: "U2"}},
+	}}
+	d := New(p, Groups{"tenant1": {"security": "Security Approvers", DefaultGroup: "IT Admins"}}, nil)
+	if d.AllowEmail("tenant1", "security", "alice@example.com") {
+		t.Fatal("allowed before the first sync")
+	}
+	if err := d.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		tenant, group, email string
+		want                 bool
+	}{
+		{"tenant1", "security", "alice@example.com", true},
+		{"tenant1", "security", "bob@example.com", false},
+		{"tenant1", "finance", "bob@example.com", true}, // unmapped: default group
+		{"tenant1", "", "bob@example.com", true},
+		{"tenant1", "", "alice@example.com", false},
+		{"tenant2", "security", "alice@example.com", false},
+	} {
+		if got := d.AllowEmail(tc.tenant, tc.group, tc.email); got != tc.want {
+			t.Errorf("AllowEmail(%q, %q, %q) = %v, want %v", tc.tenant, tc.group, tc.email, got, tc.want)
+		}
+	}
+	if !d.AllowSlack("tenant1", "security", "u1") || d.AllowSlack("tenant1", "security", "U2") {
+		t.Error("Slack membership not checked against the group")
+	}
+
+	// A failed sync keeps the last members.
+	p.err = errors.New("directory down")
+	if err := d.Sync(context.Background()); err == nil {
+		t.Fatal("expected sync error")
+	}
+	if !d.AllowEmail("tenant1", "security", "alice@example.com") {
+		t.Fatal("failed sync dropped members")
+	}
+}
+
+func TestSCIM_Members(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer scim-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var out any
+		switch r.URL.Path {
+		case "/scim/v2/Groups":
+			out = map[string]any{"Resources": []any{}}
+			if r.URL.Query().Get("filter") == `displayName eq "Approvers"` {
+				out = map[string]any{"Resources": []any{map[string]any{"id": "g1"}}}
+			}
+		case "/scim/v2/Groups/g1":
+			out = map[string]any{"members": []any{
+				map[string]any{"value": "u1", "type": "User"},
+				map[string]any{"value": "g2", "type": "Group"},
+			}}
+		case "/scim/v2/Groups/g2":
+			out = map[string]any{"members": []any{map[string]any{"value": "u2"}, map[string]any{"value": "u1"}}}
+		case "/scim/v2/Users/u1":
+			out = map[string]any{"userName": "alice@example.com", "emails": []any{map[string]any{"value": "a@example.com"}},
+				"urn:example:slack:User": map[string]any{"slackId": "U1"}}
+		case "/scim/v2/Users/u2":
+			out = map[string]any{"userName": "bob", "emails": []any{map[string]any{"value": "bob@example.com"}}, "slackId": "U2"}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(out)
+	}))
+	defer srv.Close()
+
+	s := &SCIM{BaseURL: srv.URL + "/scim/v2", Token: "scim-token", SlackAttribute: "slackId"}
+	members, err := s.Members(context.Background(), "Approvers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 2 {
+		t.Fatalf("members = %+v", members)
+	}
+	if members[0].SlackID != "U1" || strings.Join(members[0].Emails, ",") != "a@example.com,alice@example.com" {
+		t.Errorf("u1 = %+v", members[0])
+	}
+	if members[1].SlackID != "U2" || strings.Join(members[1].Emails, ",") != "bob@example.com" {
+		t.Errorf("u2 = %+v", members[1])
+	}
+	if _, err := s.Members(context.Background(), "Missing"); err == nil {
+		t.Error("expected error for a missing group")
+	}
+}
+
+func TestOkta_MembersFollowsLinkPaging(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "SSWS okta-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.URL.Path == "/api/v1/groups":
+			_, _ = w.Write([]byte(`[{"id":"g0","profile":{"name":"Approvers Old"}},{"id":"g1","profile":{"name":"Approvers"}}]`))
+		case r.URL.Path == "/api/v1/groups/g1/users" && r.URL.Query().Get("after") == "":
+			w.Header().Set("Link", `<`+srv.URL+`/api/v1/groups/g1/users?limit=200>; rel="self", <`+srv.URL+`/api/v1/groups/g1/users?after=u1&limit=200>; rel="next"`)
+			_, _ = w.Write([]byte(`[{"id":"u1","profile":{"login":"alice@example.com","email":"alice@example.com","slackId":"U1"}}]`))
+		case r.URL.Path == "/api/v1/groups/g1/users":
+			_, _ = w.Write([]byte(`[{"id":"u2","profile":{"login":"bob","email":"bob@example.com"}}]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	o := &Okta{BaseURL: srv.URL, Token: "okta-token", SlackAttribute: "slackId"}
+	members, err := o.Members(context.Background(), "Approvers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 2 || members[0].SlackID != "U1" || members[1].Emails[0] != "bob@example.com" {
+		t.Fatalf("members = %+v", members)
+	}
+}
+
+func TestOkta_RefusesOffHostPaging(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/groups" {
+			_, _ = w.Write([]byte(`[{"id":"g1","profile":{"name":"Approvers"}}]`))
+			return
+		}
+		w.Header().Set("Link", `<https://attacker.example/steal>; rel="next"`)
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer srv.Close()
+
+	o := &Okta{BaseURL: srv.URL, Token: "okta-token"}
+	if _, err := o.Members(context.Background(), "Approvers"); err == nil || !strings.Contains(err.Error(), "attacker.example") {
+		t.Fatalf("err = %v, want off-host paging refused", err)
+	}
+}
+
+func TestAzureAD_Members(t *testing.T) {
+	var srv *httptest.Server
+	tokens := 0
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/entra-tenant/oauth2/v2.0/token" {
+			tokens++
+			if err := r.ParseForm(); err != nil || r.PostForm.Get("client_secret") != "s3cret" || r.PostForm.Get("grant_type") != "client_credentials" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"access_token":"graph-token","expires_in":3600}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer graph-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.URL.Path == "/v1.0/groups":
+			if r.URL.Query().Get("$filter") != "displayName eq 'O''Brien Approvers'" {
+				t.Errorf("$filter = %q", r.URL.Query().Get("$filter"))
+			}
+			_, _ = w.Write([]byte(`{"value":[{"id":"g1"}]}`))
+		case r.URL.Path == "/v1.0/groups/g1/transitiveMembers/microsoft.graph.user" && r.URL.Query().Get("$skiptoken") == "":
+			if !strings.Contains(r.URL.Query().Get("$select"), "employeeId") {
+				t.Errorf("$select = %q", r.URL.Query().Get("$select"))
+			}
+			_, _ = w.Write([]byte(`{"value":[{"id":"u1","mail":"alice@example.com","employeeId":"U1"}],
+				"@odata.nextLink":"` + srv.URL + `/v1.0/groups/g1/transitiveMembers/microsoft.graph.user?$skiptoken=x"}`))
+		case r.URL.Path == "/v1.0/groups/g1/transitiveMembers/microsoft.graph.user":
+			_, _ = w.Write([]byte(`{"value":[{"id":"u2","userPrincipalName":"bob@example.com","otherMails":["robert@example.com"]}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	a := &AzureAD{
+		TenantID: "entra-tenant", ClientID: "app", ClientSecret: "s3cret", SlackAttribute: "employeeId",
+		GraphURL: srv.URL, LoginURL: srv.URL,
+	}
+	members, err := a.Members(context.Background(), "O'Brien Approvers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 2 || members[0].SlackID != "U1" || strings.Join(members[1].Emails, ",") != "bob@example.com,robert@example.com" {
+		t.Fatalf("members = %+v", members)
+	}
+	if _, err := a.Members(context.Background(), "O'Brien Approvers"); err != nil {
+		t.Fatal(err)
+	}
+	if tokens != 1 {
+		t.Errorf("token fetched %d times, want 1", tokens)
+	}
+}
@@ -0,0 +1,106 @@
+package directory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Okta reads groups from the Okta management API with an API token.
+type Okta struct {
+	// BaseURL is the org URL, e.g. https://example.okta.com.
+	BaseURL string
+	Token   string
+	// SlackAttribute names the user profile attribute holding the Slack
+	// member ID.
+	SlackAttribute string
+	HTTPClient     *http.Client
+}
+
+type oktaUser struct {
+	ID      string         `json:"id"`
+	Profile map[string]any `json:"profile"`
+}
+
+// Members finds the group by name and returns its users. Okta groups do not
+// nest.
+func (o *Okta) Members(ctx context.Context, group string) ([]Member, error) {
+	var groups []struct {
+		ID      string `json:"id"`
+		Profile struct {
+			Name string `json:"name"`
+		} `json:"profile"`
+	}
+	next := "/api/v1/groups?" + url.Values{"q": {group}}.Encode()
+	if _, err := o.get(ctx, next, &groups); err != nil {
+		return nil, fmt.Errorf("okta: %w", err)
+	}
+	groupID := ""
+	for _, g := range groups {
+		if g.Profile.Name == group {
+			groupID = g.ID
+			break
+		}
+	}
+	if groupID == "" {
+		return nil, fmt.Errorf("okta: group %q not found", group)
+	}
+
+	var out []Member
+	next = "/api/v1/groups/" + url.PathEscape(groupID) + "/users?limit=200"
+	for next != "" {
+		var users []oktaUser
+		var err error
+		if next, err = o.get(ctx, next, &users); err != nil {
+			return nil, fmt.Errorf("okta: %w", err)
+		}
+		for _, u := range users {
+			m := Member{ID: u.ID, SlackID: stringAttr(u.Profile, o.SlackAttribute)}
+			for _, attr := range []string{"email", "login", "secondEmail"} {
+				if v := stringAttr(u.Profile, attr); strings.Contains(v, "@") {
+					m.Emails = append(m.Emails, v)
+				}
+			}
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+// get decodes the page at pathOrURL into out and returns the URL of the
+// next page from the Link header, or "".
+func (o *Okta) get(ctx context.Context, pathOrURL string, out any) (string, error) {
+	target, err := resolvePage(o.BaseURL, pathOrURL)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "SSWS "+o.Token)
+	body, header, err := doRequest(o.HTTPClient, req)
+	if err != nil {
+		return "", err
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return "", fmt.Errorf("decode %s: %w", req.URL.Path, err)
+	}
+	return nextLink(header), nil
+}
+
+// nextLink returns the rel="next" target of an RFC 8288 Link header.
+func nextLink(header http.Header) string {
+	for _, v := range header.Values("Link") {
+		for _, link := range strings.Split(v, ",") {
+			target, params, ok := strings.Cut(link, ";")
+			if ok && strings.Contains(params, `rel="next"`) {
+				return strings.Trim(strings.TrimSpace(target), "<>")
+			}
+		}
+	}
+	return ""
+}
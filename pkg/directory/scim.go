@@ -0,0 +1,120 @@
+package directory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// SCIM reads groups from a SCIM 2.0 service provider with a bearer token.
+type SCIM struct {
+	// BaseURL is the SCIM endpoint, e.g. https://idp.example.com/scim/v2.
+	BaseURL string
+	Token   string
+	// SlackAttribute names the user attribute holding the Slack member ID,
+	// looked up on the user and in its schema extensions.
+	SlackAttribute string
+	HTTPClient     *http.Client
+}
+
+// Members finds the group by displayName and returns its users, following
+// nested groups.
+func (s *SCIM) Members(ctx context.Context, group string) ([]Member, error) {
+	q := url.Values{"filter": {fmt.Sprintf("displayName eq %q", group)}}
+	var found struct {
+		Resources []struct {
+			ID string `json:"id"`
+		} `json:"Resources"`
+	}
+	if err := s.get(ctx, "/Groups?"+q.Encode(), &found); err != nil {
+		return nil, fmt.Errorf("scim: %w", err)
+	}
+	if len(found.Resources) == 0 {
+		return nil, fmt.Errorf("scim: group %q not found", group)
+	}
+
+	var out []Member
+	seenGroups := map[string]bool{}
+	seenUsers := map[string]bool{}
+	pending := []string{found.Resources[0].ID}
+	for len(pending) > 0 {
+		id := pending[0]
+		pending = pending[1:]
+		if seenGroups[id] {
+			continue
+		}
+		seenGroups[id] = true
+		var g struct {
+			Members []struct {
+				Value string `json:"value"`
+				Type  string `json:"type"`
+			} `json:"members"`
+		}
+		if err := s.get(ctx, "/Groups/"+url.PathEscape(id), &g); err != nil {
+			return nil, fmt.Errorf("scim: %w", err)
+		}
+		for _, m := range g.Members {
+			if strings.EqualFold(m.Type, "Group") {
+				pending = append(pending, m.Value)
+				continue
+			}
+			if seenUsers[m.Value] {
+				continue
+			}
+			seenUsers[m.Value] = true
+			u, err := s.user(ctx, m.Value)
+			if err != nil {
+				return nil, fmt.Errorf("scim: %w", err)
+			}
+			out = append(out, u)
+		}
+	}
+	return out, nil
+}
+
+func (s *SCIM) user(ctx context.Context, id string) (Member, error) {
+	var raw map[string]any
+	if err := s.get(ctx, "/Users/"+url.PathEscape(id), &raw); err != nil {
+		return Member{}, err
+	}
+	m := Member{ID: id}
+	if emails, ok := raw["emails"].([]any); ok {
+		for _, e := range emails {
+			if obj, ok := e.(map[string]any); ok {
+				if v := stringAttr(obj, "value"); v != "" {
+					m.Emails = append(m.Emails, v)
+				}
+			}
+		}
+	}
+	// userName is usually the sign-in email.
+	if name := stringAttr(raw, "userName"); strings.Contains(name, "@") {
+		m.Emails = append(m.Emails, name)
+	}
+	m.SlackID = stringAttr(raw, s.SlackAttribute)
+	for key, v := range raw {
+		if ext, ok := v.(map[string]any); ok && m.SlackID == "" && strings.HasPrefix(key, "urn:") {
+			m.SlackID = stringAttr(ext, s.SlackAttribute)
+		}
+	}
+	return m, nil
+}
+
+func (s *SCIM) get(ctx context.Context, path string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(s.BaseURL, "/")+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.Token)
+	body, _, err := doRequest(s.HTTPClient, req)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("decode %s: %w", req.URL.Path, err)
+	}
+	return nil
+}
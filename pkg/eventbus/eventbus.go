@@ -0,0 +1,185 @@
+// Package eventbus streams redacted evidence events to Kafka or NATS so SIEM
+// and analytics pipelines can consume governance decisions in real time.
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/evidence"
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+const defaultTopicPrefix = "oc.events"
+
+// queueCapacity bounds the evidence events waiting to be sent. Past it new
+// events are dropped and counted, so a slow or down broker never holds up
+// the evidence write; the evidence store remains the source of truth.
+const queueCapacity = 4096
+
+// closeTimeout bounds how long Close waits for queued events to be sent.
+const closeTimeout = 30 * time.Second
+
+// Config selects and configures the bus driver.
+type Config struct {
+	Driver      string // "kafka", "nats", or "" to disable
+	URL         string // Kafka: REST proxy URL; NATS: server URL
+	TopicPrefix string // topics/subjects are "<prefix>.<tenant_id>"
+}
+
+// Publisher is an evidence.Sink that must be closed on shutdown to flush
+// buffered events. PublishResult sends an oc.toolcall.executed CloudEvent to
+// the tenant's results topic, "<prefix>.results.<tenant_id>", for tenants
+// with a queue result sink.
+type Publisher interface {
+	evidence.Sink
+	PublishResult(ctx context.Context, tenantID string, value []byte) error
+	Close() error
+}
+
+// transport is the driver-specific send path.
+type transport interface {
+	send(ctx context.Context, topic string, key, value []byte) error
+	close() error
+}
+
+// New returns a publisher for cfg.Driver, or (nil, nil) when disabled.
+func New(cfg Config) (Publisher, error) {
+	prefix := cfg.TopicPrefix
+	if prefix == "" {
+		prefix = defaultTopicPrefix
+	}
+
+	var (
+		t   transport
+		err error
+	)
+	switch strings.ToLower(cfg.Driver) {
+	case "":
+		return nil, nil
+	case "kafka":
+		t, err = newKafkaTransport(cfg.URL)
+	case "nats":
+		t, err = newNATSTransport(cfg.URL)
+	default:
+		return nil, fmt.Errorf("eventbus: unknown driver %q", cfg.Driver)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newPublisher(prefix, t), nil
+}
+
+// message is an evidence event waiting to be sent.
+type message struct {
+	tenantID string
+	topic    string
+	value    []byte
+}
+
+type publisher struct {
+	prefix string
+	t      transport
+
+	mu     sync.RWMutex
+	closed bool
+	queue  chan message
+	done   chan struct{}
+}
+
+func newPublisher(prefix string, t transport) *publisher {
+	p := &publisher{
+		prefix: prefix,
+		t:      t,
+		queue:  make(chan message, queueCapacity),
+		done:   make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+// Publish queues the redacted envelope for the tenant's topic, keyed by
+// tenant so a tenant's events stay ordered within a partition. It never
+// blocks; when the queue is full the event is dropped.
+func (p *publisher) Publish(ctx context.Context, env *types.ToolCallEnvelope) error {
+	value, err := json.Marshal(evidence.NewAuditEvent(env))
+	if err != nil {
+		return fmt.Errorf("eventbus marshal: %w", err)
+	}
+	tenantID := env.Request.TenantID
+	msg := message{tenantID: tenantID, topic: Topic(p.prefix, tenantID), value: value}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return nil
+	}
+	select {
+	case p.queue <- msg:
+	default:
+		recordPublish(ctx, tenantID, "dropped")
+	}
+	return nil
+}
+
+// run sends queued events in order until the queue is closed.
+func (p *publisher) run() {
+	defer close(p.done)
+	ctx := context.Background()
+	for msg := range p.queue {
+		sendCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		err := p.t.send(sendCtx, msg.topic, []byte(msg.tenantID), msg.value)
+		cancel()
+		if err != nil {
+			recordPublish(ctx, msg.tenantID, "failed")
+			slog.Warn("eventbus publish failed", "topic", msg.topic, "error", err)
+			continue
+		}
+		recordPublish(ctx, msg.tenantID, "sent")
+	}
+}
+
+func (p *publisher) PublishResult(ctx context.Context, tenantID string, value []byte) error {
+	return p.t.send(ctx, Topic(p.prefix+".results", tenantID), []byte(tenantID), value)
+}
+
+// Close sends the events still queued, waiting up to closeTimeout, and
+// closes the transport.
+func (p *publisher) Close() error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.queue)
+	}
+	p.mu.Unlock()
+	select {
+	case <-p.done:
+	case <-time.After(closeTimeout):
+		slog.Warn("eventbus close timed out, dropping queued events", "queued", len(p.queue))
+	}
+	return p.t.close()
+}
+
+// Topic returns the per-tenant topic name. Letters, digits and '-' are
+// kept; every other byte, '_' included, is escaped as '_' and two hex
+// digits, which both Kafka and NATS accept. The escaping is reversible, so
+// tenants such as "acme.prod" and "acme_prod" never share a topic.
+func Topic(prefix, tenantID string) string {
+	var b strings.Builder
+	b.WriteString(prefix)
+	b.WriteByte('.')
+	for i := 0; i < len(tenantID); i++ {
+		switch c := tenantID[i]; {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "_%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,201 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+type fakeTransport struct {
+	topic string
+	key   []byte
+	value []byte
+}
+
+func (f *fakeTransport) send(_ context.Context, topic string, key, value []byte) error {
+	f.topic, f.key, f.value = topic, key, value
+	return nil
+}
+
+func (f *fakeTransport) close() error { return nil }
+
+func testEnvelope() *types.ToolCallEnvelope {
+	return &types.ToolCallEnvelope{
+		EventID: "evt-1",
+		Request: types.ToolCallRequest{
+			TenantID: "acme corp",
+			AgentID:  "agent-1",
+			Tool:     "slack",
+			Action:   "msg.post",
+			Params:   json.RawMessage(`{"text":"secret"}`),
+			SourceIP: "10.0.0.1",
+		},
+		PayloadJSON: json.RawMessage(`{"params":{"text":"secret"}}`),
+		Decision:    types.DecisionAllow,
+		ExecutionResult: &types.ExecutionResult{
+			Status:     "success",
+			OutputJSON: json.RawMessage(`{"ts":"secret-output"}`),
+		},
+		Hash: "abc",
+	}
+}
+
+func TestPublish_RedactsAndRoutesPerTenant(t *testing.T) {
+	ft := &fakeTransport{}
+	p := newPublisher("oc.events", ft)
+	if err := p.Publish(context.Background(), testEnvelope()); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if ft.topic != "oc.events.acme_20corp" {
+		t.Fatalf("topic = %q", ft.topic)
+	}
+	if string(ft.key) != "acme corp" {
+		t.Fatalf("key = %q", ft.key)
+	}
+	if strings.Contains(string(ft.value), "secret") || strings.Contains(string(ft.value), "10.0.0.1") {
+		t.Fatalf("sensitive fields leaked: %s", ft.value)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(ft.value, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["event_id"] != "evt-1" || got["execution_status"] != "success" || got["hash"] != "abc" {
+		t.Fatalf("unexpected event: %v", got)
+	}
+}
+
+func TestPublishResult_UsesResultsTopic(t *testing.T) {
+	ft := &fakeTransport{}
+	p := newPublisher("oc.events", ft)
+	defer p.Close()
+	if err := p.PublishResult(context.Background(), "acme corp", []byte(`{"type":"oc.toolcall.executed"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if ft.topic != "oc.events.results.acme_20corp" || string(ft.key) != "acme corp" || string(ft.value) != `{"type":"oc.toolcall.executed"}` {
+		t.Fatalf("sent %q %q %s", ft.topic, ft.key, ft.value)
+	}
+}
+
+func TestTopic_DistinctPerTenant(t *testing.T) {
+	seen := make(map[string]string)
+	for _, tenant := range []string{"acme.prod", "acme_prod", "acme-prod", "acme prod", "acme_2Eprod"} {
+		topic := Topic("oc.events", tenant)
+		if other, ok := seen[topic]; ok {
+			t.Errorf("tenants %q and %q share topic %q", other, tenant, topic)
+		}
+		seen[topic] = tenant
+	}
+	if got := Topic("oc.events", "tenant-1"); got != "oc.events.tenant-1" {
+		t.Errorf("Topic(tenant-1) = %q", got)
+	}
+}
+
+func TestNew_DisabledAndUnknownDriver(t *testing.T) {
+	p, err := New(Config{})
+	if err != nil || p != nil {
+		t.Fatalf("expected disabled publisher, got %v, %v", p, err)
+	}
+	if _, err := New(Config{Driver: "carrier-pigeon"}); err == nil {
+		t.Fatal("expected error for unknown driver")
+	}
+	if _, err := New(Config{Driver: "kafka", URL: "broker:9092"}); err == nil {
+		t.Fatal("expected error for non-HTTP kafka proxy URL")
+	}
+}
+
+func TestKafkaTransport_PostsToRESTProxy(t *testing.T) {
+	var path, contentType string
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		contentType = r.Header.Get("Content-Type")
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	p, err := New(Config{Driver: "kafka", URL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Publish(context.Background(), testEnvelope()); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if path != "/topics/oc.events.acme_20corp" {
+		t.Fatalf("path = %q", path)
+	}
+	if contentType != "application/vnd.kafka.json.v2+json" {
+		t.Fatalf("content-type = %q", contentType)
+	}
+	var payload struct {
+		Records []struct {
+			Key   string          `json:"key"`
+			Value json.RawMessage `json:"value"`
+		} `json:"records"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil || len(payload.Records) != 1 {
+		t.Fatalf("unexpected body %s: %v", body, err)
+	}
+	if payload.Records[0].Key != "acme corp" {
+		t.Fatalf("record key = %q", payload.Records[0].Key)
+	}
+}
+
+// blockedTransport holds every send until release is closed.
+type blockedTransport struct {
+	release chan struct{}
+	sent    int
+}
+
+func (b *blockedTransport) send(context.Context, string, []byte, []byte) error {
+	<-b.release
+	b.sent++
+	return nil
+}
+
+func (b *blockedTransport) close() error { return nil }
+
+func TestPublish_DoesNotWaitForASlowBroker(t *testing.T) {
+	bt := &blockedTransport{release: make(chan struct{})}
+	p := newPublisher("oc.events", bt)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range queueCapacity + 10 {
+			if err := p.Publish(context.Background(), testEnvelope()); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Publish blocked on a stalled broker")
+	}
+
+	close(bt.release)
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// One event was taken by the sender and the queue held the rest it could.
+	if bt.sent > queueCapacity+1 || bt.sent < queueCapacity {
+		t.Fatalf("sent %d events, want the queue's %d plus at most one in flight", bt.sent, queueCapacity)
+	}
+	if err := p.Publish(context.Background(), testEnvelope()); err != nil {
+		t.Fatalf("publish after close: %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+package eventbus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// kafkaTransport produces through a Kafka REST Proxy (v2 JSON API), which
+// keeps the binary free of a native Kafka client and works with Confluent
+// REST Proxy, Redpanda's HTTP proxy, and compatible gateways.
+type kafkaTransport struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+func newKafkaTransport(proxyURL string) (*kafkaTransport, error) {
+	u, err := url.Parse(strings.TrimSpace(proxyURL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("eventbus: kafka requires an http(s) REST proxy URL, got %q", proxyURL)
+	}
+	return &kafkaTransport{
+		baseURL:    strings.TrimRight(u.String(), "/"),
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+	}, nil
+}
+
+type kafkaRecord struct {
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+func (k *kafkaTransport) send(ctx context.Context, topic string, key, value []byte) error {
+	body, err := json.Marshal(map[string][]kafkaRecord{
+		"records": {{Key: string(key), Value: value}},
+	})
+	if err != nil {
+		return fmt.Errorf("eventbus kafka marshal: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, k.baseURL+"/topics/"+url.PathEscape(topic), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("eventbus kafka new request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+
+	resp, err := k.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("eventbus kafka produce: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("eventbus kafka proxy returned %d: %s", resp.StatusCode, string(b))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+func (k *kafkaTransport) close() error {
+	k.httpClient.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,29 @@
+package eventbus
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+var publishedEvents metric.Int64Counter
+
+func init() {
+	meter := otel.Meter("github.com/bturcanu/OpenClause/pkg/eventbus")
+	var err error
+	publishedEvents, err = meter.Int64Counter("oc.eventbus.events",
+		metric.WithDescription("Evidence events streamed to the event bus, by tenant and outcome (sent, failed, dropped)."),
+	)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func recordPublish(ctx context.Context, tenantID, outcome string) {
+	publishedEvents.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("tenant", tenantID),
+		attribute.String("outcome", outcome),
+	))
+}
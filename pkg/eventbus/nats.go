@@ -0,0 +1,39 @@
+package eventbus
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+)
+
+type natsTransport struct {
+	conn *nats.Conn
+}
+
+func newNATSTransport(url string) (*natsTransport, error) {
+	if url == "" {
+		url = nats.DefaultURL
+	}
+	conn, err := nats.Connect(url, nats.Name("openclause"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, fmt.Errorf("eventbus nats connect: %w", err)
+	}
+	return &natsTransport{conn: conn}, nil
+}
+
+// send buffers in the NATS client; it only fails when the connection is
+// closed or the reconnect buffer is full.
+func (n *natsTransport) send(_ context.Context, subject string, _, value []byte) error {
+	if err := n.conn.Publish(subject, value); err != nil {
+		return fmt.Errorf("eventbus nats publish: %w", err)
+	}
+	return nil
+}
+
+func (n *natsTransport) close() error {
+	if err := n.conn.Drain(); err != nil {
+		return fmt.Errorf("eventbus nats drain: %w", err)
+	}
+	return nil
+}
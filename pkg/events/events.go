@@ -0,0 +1,446 @@
+// Package events delivers CloudEvents for the tool-call and approval
+// lifecycle to the sinks each tenant subscribes to in its settings.
+//
+// Delivery is best effort: events are queued in memory, retried a few
+// times, and dropped when the queue is full. Durable approval notifications
+// go through the approvals outbox instead.
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/approvals"
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+const (
+	defaultQueueSize   = 1000
+	defaultWorkers     = 4
+	maxDeliveryRetries = 3
+	lookupTimeout      = 5 * time.Second
+)
+
+// Subscriptions returns a tenant's event subscriptions.
+type Subscriptions func(ctx context.Context, tenantID string) ([]types.EventSubscription, error)
+
+// ResultSinks returns a tenant's result sinks.
+type ResultSinks func(ctx context.Context, tenantID string) ([]types.ResultSink, error)
+
+// ResultQueue publishes a tenant's execution results to the event bus.
+type ResultQueue interface {
+	PublishResult(ctx context.Context, tenantID string, value []byte) error
+}
+
+// Config configures an Emitter.
+type Config struct {
+	Source    string // CloudEvents source, e.g. "oc://gateway"
+	QueueSize int
+	Workers   int
+	// SkipURLValidation disables egress checks on subscription URLs;
+	// testing only.
+	SkipURLValidation bool
+}
+
+// Emitter queues lifecycle events and posts them, in structured mode, to
+// every subscription of the event's tenant that wants its type. It is an
+// evidence.Sink, an approvals.RequestSink and an approvals.ResolutionSink.
+type Emitter struct {
+	cfg    Config
+	subs   Subscriptions
+	client *http.Client
+	egress *approvals.Egress
+	queue  chan types.CloudEvent
+	wg     sync.WaitGroup
+
+	mu     sync.RWMutex // guards closed against sends on the closed queue
+	closed bool
+
+	secretsMu sync.RWMutex
+	secrets   map[string]string
+
+	// results and resultQueue deliver oc.toolcall.executed events to
+	// tenants' result sinks; see SetResultSinks.
+	results     ResultSinks
+	resultQueue ResultQueue
+}
+
+// New starts an emitter's delivery workers. Close stops them.
+func New(cfg Config, subs Subscriptions) *Emitter {
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = defaultQueueSize
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = defaultWorkers
+	}
+	e := &Emitter{
+		cfg:     cfg,
+		subs:    subs,
+		client:  approvals.NewEgressClient(10 * time.Second),
+		queue:   make(chan types.CloudEvent, cfg.QueueSize),
+		secrets: map[string]string{},
+	}
+	for range cfg.Workers {
+		e.wg.Add(1)
+		go e.work()
+	}
+	return e
+}
+
+// SetSecret sets the HMAC secret used for subscriptions naming ref.
+func (e *Emitter) SetSecret(ref, secret string) {
+	e.secretsMu.Lock()
+	defer e.secretsMu.Unlock()
+	e.secrets[ref] = secret
+}
+
+// SetEgress sets the egress policies webhook deliveries are held to;
+// without it every tenant gets the zero approvals.EgressPolicy. Call before
+// emitting.
+func (e *Emitter) SetEgress(g *approvals.Egress) {
+	e.egress = g
+}
+
+// SetResultSinks makes the emitter deliver oc.toolcall.executed events to
+// the result sinks of the event's tenant as well as its subscriptions. q
+// carries queue sinks; without it they are skipped. Call before emitting.
+func (e *Emitter) SetResultSinks(sinks ResultSinks, q ResultQueue) {
+	e.results = sinks
+	e.resultQueue = q
+}
+
+// Emit queues ev without blocking; it is dropped if the queue is full.
+func (e *Emitter) Emit(ev types.CloudEvent) {
+	if e == nil {
+		return
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closed {
+		return
+	}
+	select {
+	case e.queue <- ev:
+	default:
+		slog.Warn("event queue full; dropping event", "type", ev.Type, "id", ev.ID, "tenant_id", ev.TenantID)
+	}
+}
+
+// Publish implements evidence.Sink.
+func (e *Emitter) Publish(_ context.Context, env *types.ToolCallEnvelope) error {
+	if e == nil {
+		return nil
+	}
+	for _, ev := range ToolCallEvents(env, e.cfg.Source) {
+		e.Emit(ev)
+	}
+	return nil
+}
+
+// PublishRequest implements approvals.RequestSink.
+func (e *Emitter) PublishRequest(_ context.Context, req approvals.ApprovalRequest) {
+	if e == nil {
+		return
+	}
+	e.Emit(ApprovalEvent(types.EventApprovalRequested, e.cfg.Source, req, "", req.Reason, req.CreatedAt))
+}
+
+// PublishResolution implements approvals.ResolutionSink.
+func (e *Emitter) PublishResolution(_ context.Context, res approvals.Resolution) {
+	if e == nil {
+		return
+	}
+	var eventType string
+	switch res.Status {
+	case "approved":
+		eventType = types.EventApprovalGranted
+	case "denied":
+		eventType = types.EventApprovalDenied
+	case "expired":
+		eventType = types.EventApprovalExpired
+	case "released":
+		eventType = types.EventApprovalReleased
+	case "cancelled":
+		eventType = types.EventApprovalCancelled
+	default:
+		return
+	}
+	req := res.Request
+	req.Status = res.Status
+	e.Emit(ApprovalEvent(eventType, e.cfg.Source, req, res.Approver, res.Reason, res.At))
+}
+
+// PublishCanary raises oc.alert.canary_touched for the recorded call env,
+// refused under res when it was about to execute. A denied call's own
+// alert comes with its lifecycle events instead.
+func (e *Emitter) PublishCanary(_ context.Context, env *types.ToolCallEnvelope, res *types.PolicyResult) {
+	if e == nil {
+		return
+	}
+	data := toolCallData(env)
+	data.Decision, data.Reason, data.ReasonCode, data.Canary = res.Decision, res.Reason, res.ReasonCode, res.Canary
+	at := time.Now().UTC()
+	id := fmt.Sprintf("%s:canary_touched:%d", env.EventID, at.UnixNano())
+	if ev, err := types.NewCloudEvent(types.EventCanaryTouched, id, e.cfg.Source, env.Request.TenantID, env.EventID, at, data); err == nil {
+		e.Emit(ev)
+	}
+}
+
+// Close stops accepting events and waits for queued ones to be delivered.
+func (e *Emitter) Close() error {
+	if e == nil {
+		return nil
+	}
+	e.mu.Lock()
+	if !e.closed {
+		e.closed = true
+		close(e.queue)
+	}
+	e.mu.Unlock()
+	e.wg.Wait()
+	return nil
+}
+
+// ToolCallEvents returns the lifecycle events a recorded envelope stands
+// for: received plus the policy outcome, and executed when a connector ran.
+// The envelope recorded when an approved call executes yields only executed.
+// A denial for touching a canary resource also yields
+// oc.alert.canary_touched.
+func ToolCallEvents(env *types.ToolCallEnvelope, source string) []types.CloudEvent {
+	req := env.Request
+	data := toolCallData(env)
+
+	var out []types.CloudEvent
+	add := func(stage string, data types.ToolCallEventData) {
+		id := env.EventID + ":" + stage[strings.LastIndexByte(stage, '.')+1:]
+		if ev, err := types.NewCloudEvent(stage, id, source, req.TenantID, env.EventID, env.ReceivedAt, data); err == nil {
+			out = append(out, ev)
+		}
+	}
+	if !strings.HasPrefix(req.IdempotencyKey, types.ExecIdempotencyPrefix) {
+		add(types.EventToolCallReceived, data)
+		switch env.Decision {
+		case types.DecisionAllow:
+			add(types.EventToolCallAllowed, data)
+		case types.DecisionDeny:
+			add(types.EventToolCallDenied, data)
+			if env.PolicyResult != nil && env.PolicyResult.Canary != "" {
+				alert := data
+				alert.Canary = env.PolicyResult.Canary
+				add(types.EventCanaryTouched, alert)
+			}
+		}
+	}
+	if env.ExecutionResult != nil {
+		data.ExecutionStatus = env.ExecutionResult.Status
+		data.DurationMS = env.ExecutionResult.DurationMS
+		data.OutputSHA256 = outputDigest(env.ExecutionResult)
+		add(types.EventToolCallExecuted, data)
+	}
+	return out
+}
+
+// toolCallData is the event data of env's request and decision.
+func toolCallData(env *types.ToolCallEnvelope) types.ToolCallEventData {
+	req := env.Request
+	data := types.ToolCallEventData{
+		EventID:     env.EventID,
+		TenantID:    req.TenantID,
+		AgentID:     req.AgentID,
+		SessionID:   req.SessionID,
+		Tool:        req.Tool,
+		Action:      req.Action,
+		Resource:    req.Resource,
+		RiskScore:   env.RiskScore(),
+		RiskFactors: req.RiskFactors,
+		Decision:    env.Decision,
+		TraceID:     req.TraceID,
+		ReceivedAt:  env.ReceivedAt,
+
+		CompensatesEventID: req.CompensatesEventID,
+		ParentEventID:      req.ParentEventID,
+		PlanID:             req.PlanID,
+	}
+	if env.PolicyResult != nil {
+		data.Reason = env.PolicyResult.Reason
+		data.ReasonCode = env.PolicyResult.ReasonCode
+	}
+	if env.AdjustedRiskScore != nil {
+		data.OriginalRiskScore = &req.RiskScore
+	}
+	return data
+}
+
+// outputDigest is the hex SHA-256 of res's full output. A truncated
+// output's digest was taken before truncation.
+func outputDigest(res *types.ExecutionResult) string {
+	if res.Truncation != nil {
+		return res.Truncation.SHA256
+	}
+	if len(res.OutputJSON) == 0 {
+		return ""
+	}
+	sum := sha256.Sum256(res.OutputJSON)
+	return hex.EncodeToString(sum[:])
+}
+
+// ApprovalEvent builds an oc.approval.* event for req. eventType must be
+// one of the registered approval types.
+func ApprovalEvent(eventType, source string, req approvals.ApprovalRequest, approver, reason string, at time.Time) types.CloudEvent {
+	data := types.ApprovalEventData{
+		ApprovalRequestID: req.ID,
+		EventID:           req.EventID,
+		TenantID:          req.TenantID,
+		AgentID:           req.AgentID,
+		Tool:              req.Tool,
+		Action:            req.Action,
+		Resource:          req.Resource,
+		RiskScore:         req.RiskScore,
+		Kind:              req.Kind,
+		Status:            req.Status,
+		Approver:          approver,
+		Reason:            reason,
+		ExpiresAt:         req.ExpiresAt,
+	}
+	ev, _ := types.NewCloudEvent(eventType, req.ID+":"+strings.TrimPrefix(eventType, "oc.approval."), source, req.TenantID, req.ID, at, data)
+	return ev
+}
+
+func (e *Emitter) work() {
+	defer e.wg.Done()
+	for ev := range e.queue {
+		e.deliver(ev)
+	}
+}
+
+func (e *Emitter) deliver(ev types.CloudEvent) {
+	ctx, cancel := context.WithTimeout(context.Background(), lookupTimeout)
+	subs, err := e.subs(ctx, ev.TenantID)
+	cancel()
+	if err != nil {
+		slog.Error("event subscriptions lookup failed", "tenant_id", ev.TenantID, "error", err)
+		return
+	}
+	var body []byte
+	for _, sub := range subs {
+		if !sub.Wants(ev.Type) {
+			continue
+		}
+		if body == nil {
+			if body, err = json.Marshal(ev); err != nil {
+				slog.Error("event marshal failed", "type", ev.Type, "error", err)
+				return
+			}
+		}
+		if err := e.post(sub, ev, body); err != nil {
+			slog.Warn("event delivery failed", "type", ev.Type, "id", ev.ID, "tenant_id", ev.TenantID, "url", sub.URL, "error", err)
+		}
+	}
+	if ev.Type == types.EventToolCallExecuted && e.results != nil {
+		e.deliverResult(ev, body)
+	}
+}
+
+// deliverResult sends an executed event to the tenant's result sinks.
+func (e *Emitter) deliverResult(ev types.CloudEvent, body []byte) {
+	ctx, cancel := context.WithTimeout(context.Background(), lookupTimeout)
+	sinks, err := e.results(ctx, ev.TenantID)
+	cancel()
+	if err != nil {
+		slog.Error("result sinks lookup failed", "tenant_id", ev.TenantID, "error", err)
+		return
+	}
+	for _, sink := range sinks {
+		if body == nil {
+			if body, err = json.Marshal(ev); err != nil {
+				slog.Error("event marshal failed", "type", ev.Type, "error", err)
+				return
+			}
+		}
+		switch sink.Type {
+		case types.ResultSinkWebhook:
+			err = e.post(types.EventSubscription{URL: sink.URL, SecretRef: sink.SecretRef}, ev, body)
+		case types.ResultSinkQueue:
+			if e.resultQueue == nil {
+				slog.Warn("result sink needs the event bus; EVENTBUS_DRIVER is unset", "tenant_id", ev.TenantID)
+				continue
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), lookupTimeout)
+			err = e.resultQueue.PublishResult(ctx, ev.TenantID, body)
+			cancel()
+		default:
+			continue
+		}
+		if err != nil {
+			slog.Warn("result delivery failed", "id", ev.ID, "tenant_id", ev.TenantID, "sink", sink.Type, "error", err)
+		}
+	}
+}
+
+// post sends one event to one subscription, retrying with backoff.
+func (e *Emitter) post(sub types.EventSubscription, ev types.CloudEvent, body []byte) error {
+	ctx := context.Background()
+	if !e.cfg.SkipURLValidation {
+		lookupCtx, cancel := context.WithTimeout(ctx, lookupTimeout)
+		policy, err := e.egress.Policy(lookupCtx, ev.TenantID)
+		cancel()
+		if err != nil {
+			return err
+		}
+		if err := policy.CheckURL(sub.URL); err != nil {
+			return fmt.Errorf("egress policy: %w", err)
+		}
+		ctx = approvals.WithEgressPolicy(ctx, policy)
+	}
+	e.secretsMu.RLock()
+	secret := e.secrets[sub.SecretRef]
+	e.secretsMu.RUnlock()
+
+	id := deliveryID(ev, sub)
+	var err error
+	for attempt := range maxDeliveryRetries {
+		if attempt > 0 {
+			time.Sleep(time.Duration(1<<(attempt-1)) * time.Second)
+		}
+		if err = e.postOnce(ctx, sub.URL, secret, id, attempt+1, body); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// deliveryID identifies one event sent to one subscription. It is the same
+// on every retry.
+func deliveryID(ev types.CloudEvent, sub types.EventSubscription) string {
+	sum := sha256.Sum256([]byte(ev.Source + "\n" + ev.ID + "\n" + sub.URL))
+	return hex.EncodeToString(sum[:16])
+}
+
+func (e *Emitter) postOnce(ctx context.Context, url, secret, deliveryID string, attempt int, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/cloudevents+json")
+	approvals.SignWebhookRequest(req, body, secret, deliveryID, attempt, time.Now())
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("event sink status=%d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/approvals"
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+func eventTypes(evs []types.CloudEvent) []string {
+	out := make([]string, len(evs))
+	for i, ev := range evs {
+		out[i] = ev.Type
+	}
+	return out
+}
+
+func TestToolCallEvents(t *testing.T) {
+	now := time.Now().UTC()
+	cases := []struct {
+		name string
+		env  types.ToolCallEnvelope
+		want []string
+	}{
+		{"allowed", types.ToolCallEnvelope{Decision: types.DecisionAllow, ExecutionResult: &types.ExecutionResult{Status: "success"}},
+			[]string{types.EventToolCallReceived, types.EventToolCallAllowed, types.EventToolCallExecuted}},
+		{"denied", types.ToolCallEnvelope{Decision: types.DecisionDeny},
+			[]string{types.EventToolCallReceived, types.EventToolCallDenied}},
+		{"canary", types.ToolCallEnvelope{Decision: types.DecisionDeny, PolicyResult: &types.PolicyResult{Decision: types.DecisionDeny, ReasonCode: types.ReasonCodeCanary, Canary: "decoy"}},
+			[]string{types.EventToolCallReceived, types.EventToolCallDenied, types.EventCanaryTouched}},
+		{"approval", types.ToolCallEnvelope{Decision: types.DecisionApprove},
+			[]string{types.EventToolCallReceived}},
+		{"approved execution", types.ToolCallEnvelope{Decision: types.DecisionAllow, Request: types.ToolCallRequest{IdempotencyKey: "exec:evt-0"}, ExecutionResult: &types.ExecutionResult{Status: "error"}},
+			[]string{types.EventToolCallExecuted}},
+	}
+	for _, c := range cases {
+		c.env.EventID, c.env.ReceivedAt, c.env.Request.TenantID = "evt-1", now, "acme"
+		evs := ToolCallEvents(&c.env, "oc://gateway")
+		if got := eventTypes(evs); !slices.Equal(got, c.want) {
+			t.Errorf("%s: types = %v, want %v", c.name, got, c.want)
+			continue
+		}
+		for _, ev := range evs {
+			data := ev.Data.(types.ToolCallEventData)
+			if ev.TenantID != "acme" || ev.Subject != "evt-1" || ev.DataSchema == "" || ev.SpecVersion != "1.0" {
+				t.Errorf("%s: envelope = %+v", c.name, ev)
+			}
+			if (ev.Type == types.EventToolCallExecuted) != (data.ExecutionStatus != "") {
+				t.Errorf("%s: %s execution_status = %q", c.name, ev.Type, data.ExecutionStatus)
+			}
+			if (ev.Type == types.EventCanaryTouched) != (data.Canary != "") {
+				t.Errorf("%s: %s canary = %q", c.name, ev.Type, data.Canary)
+			}
+		}
+	}
+	if _, err := types.NewCloudEvent("oc.toolcall.exploded", "x", "s", "t", "", now, nil); err == nil {
+		t.Fatal("unregistered type should be rejected")
+	}
+}
+
+func TestEmitterDeliversSubscribedTypes(t *testing.T) {
+	var mu sync.Mutex
+	var got []map[string]any
+	var signed []bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var ev map[string]any
+		_ = json.Unmarshal(body, &ev)
+		mu.Lock()
+		got = append(got, ev)
+		signed = append(signed, r.Header.Get(approvals.HeaderDeliveryID) != "" && r.Header.Get(approvals.HeaderAttempt) == "1" &&
+			approvals.VerifyWebhookV2(r.Header, body, []string{"s3cret"}, approvals.DefaultWebhookTolerance, time.Now()))
+		mu.Unlock()
+		if r.Header.Get("Content-Type") != "application/cloudevents+json" {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+		}
+	}))
+	defer srv.Close()
+
+	subs := func(_ context.Context, tenantID string) ([]types.EventSubscription, error) {
+		if tenantID != "acme" {
+			return nil, nil
+		}
+		return []types.EventSubscription{{URL: srv.URL, SecretRef: "acme_events", Types: []string{types.EventApprovalExpired}}}, nil
+	}
+	e := New(Config{Source: "oc://approvals", SkipURLValidation: true}, subs)
+	e.SetSecret("acme_events", "s3cret")
+
+	req := approvals.ApprovalRequest{ID: "req-1", TenantID: "acme", Tool: "jira", Action: "issue.delete", Status: "pending"}
+	e.PublishRequest(context.Background(), req)
+	e.PublishResolution(context.Background(), approvals.Resolution{Request: req, Status: "expired", At: time.Now()})
+	other := req
+	other.TenantID = "globex"
+	e.PublishResolution(context.Background(), approvals.Resolution{Request: other, Status: "expired", At: time.Now()})
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != 1 {
+		t.Fatalf("deliveries = %v", got)
+	}
+	ev := got[0]
+	data, _ := ev["data"].(map[string]any)
+	if ev["type"] != types.EventApprovalExpired || ev["id"] != "req-1:expired" || ev["tenantid"] != "acme" || data["status"] != "expired" {
+		t.Fatalf("event = %v", ev)
+	}
+	if !signed[0] {
+		t.Fatal("delivery should carry a delivery id, attempt 1 and a valid v2 signature")
+	}
+	e.Emit(types.CloudEvent{}) // after Close: dropped, not a panic
+}
+
+type fakeQueue struct {
+	mu      sync.Mutex
+	tenants []string
+	values  [][]byte
+}
+
+func (q *fakeQueue) PublishResult(_ context.Context, tenantID string, value []byte) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.tenants = append(q.tenants, tenantID)
+	q.values = append(q.values, value)
+	return nil
+}
+
+func TestEmitterDeliversResultsToResultSinks(t *testing.T) {
+	var mu sync.Mutex
+	var got []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ev map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&ev)
+		mu.Lock()
+		got = append(got, ev)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	noSubs := func(context.Context, string) ([]types.EventSubscription, error) { return nil, nil }
+	sinks := func(context.Context, string) ([]types.ResultSink, error) {
+		return []types.ResultSink{{Type: types.ResultSinkWebhook, URL: srv.URL}, {Type: types.ResultSinkQueue}}, nil
+	}
+	q := &fakeQueue{}
+	e := New(Config{Source: "oc://gateway", SkipURLValidation: true}, noSubs)
+	e.SetResultSinks(sinks, q)
+
+	env := &types.ToolCallEnvelope{
+		EventID: "evt-1", ReceivedAt: time.Now(), Decision: types.DecisionAllow,
+		Request:         types.ToolCallRequest{TenantID: "acme", Tool: "jira", Action: "issue.create"},
+		ExecutionResult: &types.ExecutionResult{Status: "success", OutputJSON: json.RawMessage(`{"key":"OPS-1"}`)},
+	}
+	if err := e.Publish(context.Background(), env); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the executed event reaches result sinks, once per sink.
+	if len(got) != 1 || len(q.values) != 1 || q.tenants[0] != "acme" {
+		t.Fatalf("webhook deliveries = %v, queue = %v %s", got, q.tenants, q.values)
+	}
+	const digest = "cd21455ab7eee9666c9aa41ab0c2f5f5f0d4f48c51e5202786a762e897a55780" // sha256 of {"key":"OPS-1"}
+	var queued map[string]any
+	if err := json.Unmarshal(q.values[0], &queued); err != nil {
+		t.Fatal(err)
+	}
+	for _, ev := range []map[string]any{got[0], queued} {
+		data, _ := ev["data"].(map[string]any)
+		if ev["type"] != types.EventToolCallExecuted || data["execution_status"] != "success" || data["output_sha256"] != digest {
+			t.Fatalf("result event = %v", ev)
+		}
+	}
+}
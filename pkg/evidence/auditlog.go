@@ -0,0 +1,362 @@
+package evidence
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/config"
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+// Audit log destinations, as named by AUDIT_LOG_SINKS.
+const (
+	AuditStdout = "stdout"
+	AuditStderr = "stderr"
+	AuditFile   = "file"
+	AuditOTLP   = "otlp"
+)
+
+// AuditRecord is one line of the audit log. Each line is hashed over its
+// canonical JSON with Hash empty, and carries the hash of the line before
+// it, so deleting, reordering or editing lines breaks the chain.
+type AuditRecord struct {
+	Seq      uint64    `json:"seq"`
+	Time     time.Time `json:"time"`
+	Service  string    `json:"service"`
+	Kind     string    `json:"kind"`
+	TenantID string    `json:"tenant_id"`
+	EventID  string    `json:"event_id,omitempty"`
+	// EventHash is the event's hash in the evidence chain, linking the
+	// line to the stored event.
+	EventHash string          `json:"event_hash,omitempty"`
+	Data      json.RawMessage `json:"data"`
+	PrevHash  string          `json:"prev_hash"`
+	Hash      string          `json:"hash"`
+}
+
+// AuditConfig selects where a service's audit log goes.
+type AuditConfig struct {
+	Service string
+	// Sinks lists the destinations: AuditStdout, AuditStderr, AuditFile
+	// and AuditOTLP. Empty disables the audit log.
+	Sinks []string
+	// File is the AuditFile path; default "<service>-audit.jsonl".
+	File string
+	// OTLPEndpoint is the collector's OTLP/HTTP base URL; records go to
+	// /v1/logs under it.
+	OTLPEndpoint string
+	OTLPHeaders  map[string]string
+}
+
+// AuditConfigFromEnv reads AUDIT_LOG_SINKS, AUDIT_LOG_FILE,
+// AUDIT_LOG_OTLP_ENDPOINT and AUDIT_LOG_OTLP_HEADERS. The OTLP settings
+// default to OTEL_EXPORTER_OTLP_ENDPOINT and OTEL_EXPORTER_OTLP_HEADERS.
+func AuditConfigFromEnv(env config.Env, service string) AuditConfig {
+	cfg := AuditConfig{
+		Service:      service,
+		File:         env.Get("AUDIT_LOG_FILE"),
+		OTLPEndpoint: env.Get("AUDIT_LOG_OTLP_ENDPOINT"),
+	}
+	if cfg.OTLPEndpoint == "" {
+		// The tracing endpoint may be a bare host:port.
+		cfg.OTLPEndpoint = env.Get("OTEL_EXPORTER_OTLP_ENDPOINT")
+		if cfg.OTLPEndpoint != "" && !strings.Contains(cfg.OTLPEndpoint, "://") {
+			scheme := "https://"
+			if env.Bool("OTEL_EXPORTER_OTLP_INSECURE", false) {
+				scheme = "http://"
+			}
+			cfg.OTLPEndpoint = scheme + cfg.OTLPEndpoint
+		}
+	}
+	headers := env.Get("AUDIT_LOG_OTLP_HEADERS")
+	if headers == "" {
+		headers = env.Get("OTEL_EXPORTER_OTLP_HEADERS")
+	}
+	cfg.OTLPHeaders = parseHeaders(headers)
+	for _, s := range strings.Split(env.Get("AUDIT_LOG_SINKS"), ",") {
+		if s = strings.ToLower(strings.TrimSpace(s)); s != "" && s != "none" {
+			cfg.Sinks = append(cfg.Sinks, s)
+		}
+	}
+	return cfg
+}
+
+// auditWriter is one destination of the audit log.
+type auditWriter interface {
+	write(line []byte, rec *AuditRecord) error
+	Close() error
+}
+
+// AuditLogger writes audit records, kept apart from operational logs, as
+// hash-chained JSON lines. A nil *AuditLogger discards records. It is safe
+// for concurrent use.
+type AuditLogger struct {
+	service string
+	mu      sync.Mutex
+	seq     uint64
+	prev    string
+	writers []auditWriter
+}
+
+// NewAuditLogger returns an audit logger writing lines to w.
+func NewAuditLogger(service string, w io.Writer) *AuditLogger {
+	return &AuditLogger{service: service, writers: []auditWriter{lineWriter{w: w}}}
+}
+
+// OpenAuditLog opens the destinations in cfg. It returns nil when no sinks
+// are configured. A file that already holds records is appended to, and
+// its chain continued.
+func OpenAuditLog(cfg AuditConfig) (*AuditLogger, error) {
+	if len(cfg.Sinks) == 0 {
+		return nil, nil
+	}
+	a := &AuditLogger{service: cfg.Service}
+	for _, sink := range cfg.Sinks {
+		switch sink {
+		case AuditStdout:
+			a.writers = append(a.writers, lineWriter{w: os.Stdout})
+		case AuditStderr:
+			a.writers = append(a.writers, lineWriter{w: os.Stderr})
+		case AuditFile:
+			path := cfg.File
+			if path == "" {
+				path = cfg.Service + "-audit.jsonl"
+			}
+			last, err := lastAuditRecord(path)
+			if err != nil {
+				a.Close()
+				return nil, fmt.Errorf("evidence.OpenAuditLog: %w", err)
+			}
+			if last != nil {
+				a.seq, a.prev = last.Seq, last.Hash
+			}
+			f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+			if err != nil {
+				a.Close()
+				return nil, fmt.Errorf("evidence.OpenAuditLog: %w", err)
+			}
+			a.writers = append(a.writers, lineWriter{w: f, c: f})
+		case AuditOTLP:
+			if cfg.OTLPEndpoint == "" {
+				a.Close()
+				return nil, errors.New("evidence.OpenAuditLog: the otlp audit sink needs an OTLP endpoint")
+			}
+			a.writers = append(a.writers, newOTLPLogWriter(cfg.Service, strings.TrimRight(cfg.OTLPEndpoint, "/")+"/v1/logs", cfg.OTLPHeaders))
+		default:
+			a.Close()
+			return nil, fmt.Errorf("evidence.OpenAuditLog: unknown audit sink %q (want stdout, stderr, file or otlp)", sink)
+		}
+	}
+	return a, nil
+}
+
+// Record appends a record of kind, e.g. "tool_event", for tenantID. data
+// is the record's body; eventID and eventHash tie it to an evidence event
+// when there is one.
+func (a *AuditLogger) Record(ctx context.Context, kind, tenantID, eventID, eventHash string, data any) error {
+	if a == nil {
+		return nil
+	}
+	body, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("evidence.AuditLogger.Record: %w", err)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	rec := AuditRecord{
+		Seq:       a.seq + 1,
+		Time:      time.Now().UTC(),
+		Service:   a.service,
+		Kind:      kind,
+		TenantID:  tenantID,
+		EventID:   eventID,
+		EventHash: eventHash,
+		Data:      body,
+		PrevHash:  a.prev,
+	}
+	if rec.Hash, err = auditHash(rec); err != nil {
+		return fmt.Errorf("evidence.AuditLogger.Record: %w", err)
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("evidence.AuditLogger.Record: %w", err)
+	}
+	line = append(line, '\n')
+	var errs []error
+	for _, w := range a.writers {
+		if err := w.write(line, &rec); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	// The chain advances even when a destination failed, so the gap shows
+	// in that destination rather than forking the others.
+	a.seq, a.prev = rec.Seq, rec.Hash
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("evidence.AuditLogger.Record: %w", err)
+	}
+	return nil
+}
+
+// Publish records env as a "tool_event", so an AuditLogger can be added
+// to a Logger as a Sink.
+func (a *AuditLogger) Publish(ctx context.Context, env *types.ToolCallEnvelope) error {
+	return a.Record(ctx, "tool_event", env.Request.TenantID, env.EventID, env.Hash, NewAuditEvent(env))
+}
+
+// Close flushes and closes the destinations.
+func (a *AuditLogger) Close() error {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var errs []error
+	for _, w := range a.writers {
+		if err := w.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	a.writers = nil
+	return errors.Join(errs...)
+}
+
+// VerifyAuditLog checks the chain of the audit log read from r and returns
+// the number of records. The first record may continue a chain whose
+// earlier lines were rotated away; every later one must follow its
+// predecessor.
+func VerifyAuditLog(r io.Reader) (int, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	var prev *AuditRecord
+	n := 0
+	for line := 1; sc.Scan(); line++ {
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		rec, err := checkAuditLine(sc.Bytes())
+		if err != nil {
+			return n, fmt.Errorf("line %d: %w", line, err)
+		}
+		if prev != nil && (rec.PrevHash != prev.Hash || rec.Seq != prev.Seq+1) {
+			return n, fmt.Errorf("line %d: seq %d does not follow seq %d", line, rec.Seq, prev.Seq)
+		}
+		prev = rec
+		n++
+	}
+	if err := sc.Err(); err != nil {
+		return n, fmt.Errorf("evidence.VerifyAuditLog: %w", err)
+	}
+	return n, nil
+}
+
+// checkAuditLine parses a line and checks its hash. The hash is recomputed
+// from every field on the line, not just the known ones, so added fields
+// are caught too.
+func checkAuditLine(line []byte) (*AuditRecord, error) {
+	var rec AuditRecord
+	if err := json.Unmarshal(line, &rec); err != nil {
+		return nil, fmt.Errorf("malformed record: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	var fields map[string]any
+	if err := dec.Decode(&fields); err != nil {
+		return nil, fmt.Errorf("malformed record: %w", err)
+	}
+	fields["hash"] = ""
+	canon, err := CanonicalJSON(fields)
+	if err != nil {
+		return nil, err
+	}
+	if HashBytes(canon) != rec.Hash {
+		return nil, fmt.Errorf("seq %d: hash mismatch", rec.Seq)
+	}
+	return &rec, nil
+}
+
+// auditHash is the hash of rec's canonical JSON with Hash empty.
+func auditHash(rec AuditRecord) (string, error) {
+	rec.Hash = ""
+	canon, err := CanonicalJSON(rec)
+	if err != nil {
+		return "", err
+	}
+	return HashBytes(canon), nil
+}
+
+// lastAuditRecord returns the final record of the audit log at path, or
+// nil when the file is missing or empty. Only the file's tail is read.
+func lastAuditRecord(path string) (*AuditRecord, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	const maxTail = 1 << 20
+	off := max(st.Size()-maxTail, 0)
+	tail := make([]byte, st.Size()-off)
+	if _, err := f.ReadAt(tail, off); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	tail = bytes.TrimRight(tail, "\n")
+	if len(tail) == 0 {
+		return nil, nil
+	}
+	last := tail[bytes.LastIndexByte(tail, '\n')+1:]
+	rec, err := checkAuditLine(last)
+	if err != nil {
+		return nil, fmt.Errorf("audit log %s: last record: %w", path, err)
+	}
+	return rec, nil
+}
+
+// lineWriter writes each record as a line to w, closing c on Close.
+type lineWriter struct {
+	w io.Writer
+	c io.Closer
+}
+
+func (l lineWriter) write(line []byte, _ *AuditRecord) error {
+	_, err := l.w.Write(line)
+	return err
+}
+
+func (l lineWriter) Close() error {
+	if l.c == nil {
+		return nil
+	}
+	return l.c.Close()
+}
+
+// parseHeaders reads a "k1=v1,k2=v2" header list whose values may be
+// percent-encoded, as in OTEL_EXPORTER_OTLP_HEADERS.
+func parseHeaders(s string) map[string]string {
+	out := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(pair, "=")
+		if k = strings.TrimSpace(k); !ok || k == "" {
+			continue
+		}
+		if dec, err := url.PathUnescape(strings.TrimSpace(v)); err == nil {
+			out[k] = dec
+		}
+	}
+	return out
+}
@@ -0,0 +1,165 @@
+package evidence
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func auditEnvelope(eventID string) *types.ToolCallEnvelope {
+	return &types.ToolCallEnvelope{
+		EventID:    eventID,
+		Request:    types.ToolCallRequest{TenantID: "t1", AgentID: "a1", Tool: "slack", Action: "msg.post", Params: json.RawMessage(`{"text":"secret"}`)},
+		ReceivedAt: time.Now().UTC(),
+		Decision:   types.DecisionAllow,
+		Hash:       "h-" + eventID,
+	}
+}
+
+func TestAuditLogger_ChainVerifies(t *testing.T) {
+	var buf bytes.Buffer
+	a := NewAuditLogger("oc-test", &buf)
+	ctx := context.Background()
+	for _, id := range []string{"e1", "e2"} {
+		if err := a.Publish(ctx, auditEnvelope(id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := a.Record(ctx, "approval_approved", "t1", "e2", "", map[string]any{"approver": "alice@example.com", "risk_score": 7}); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := VerifyAuditLog(bytes.NewReader(buf.Bytes())); err != nil || n != 3 {
+		t.Fatalf("VerifyAuditLog = %d, %v", n, err)
+	}
+
+	lines := strings.SplitAfter(strings.TrimRight(buf.String(), "\n"), "\n")
+	var first AuditRecord
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatal(err)
+	}
+	if first.Seq != 1 || first.PrevHash != "" || first.EventHash != "h-e1" || first.Service != "oc-test" || strings.Contains(lines[0], "secret") {
+		t.Fatalf("first record = %s", lines[0])
+	}
+
+	for name, tampered := range map[string]string{
+		"edited":    lines[0] + strings.Replace(lines[1], `"decision":"allow"`, `"decision":"deny"`, 1) + lines[2],
+		"deleted":   lines[0] + lines[2],
+		"reordered": lines[1] + lines[0] + lines[2],
+		"added":     lines[0] + strings.Replace(lines[1], `{"seq"`, `{"note":"x","seq"`, 1) + lines[2],
+	} {
+		if _, err := VerifyAuditLog(strings.NewReader(tampered)); err == nil {
+			t.Errorf("%s: tampered log verified", name)
+		}
+	}
+}
+
+func TestOpenAuditLog_FileContinuesChain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	cfg := AuditConfig{Service: "oc-test", Sinks: []string{AuditFile}, File: path}
+	ctx := context.Background()
+	for i := range 2 {
+		a, err := OpenAuditLog(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := a.Record(ctx, "tool_event", "t1", "e", "", i); err != nil {
+			t.Fatal(err)
+		}
+		if err := a.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if n, err := VerifyAuditLog(f); err != nil || n != 2 {
+		t.Fatalf("VerifyAuditLog = %d, %v", n, err)
+	}
+	if st, _ := f.Stat(); st.Mode().Perm() != 0o600 {
+		t.Errorf("mode = %v", st.Mode().Perm())
+	}
+
+	if err := os.WriteFile(path, []byte(`{"seq":1,"hash":"forged"}`+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenAuditLog(cfg); err == nil {
+		t.Error("opened a log whose last record does not verify")
+	}
+}
+
+func TestOpenAuditLog_OTLP(t *testing.T) {
+	var mu sync.Mutex
+	var got []*collogspb.ExportLogsServiceRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req collogspb.ExportLogsServiceRequest
+		if r.URL.Path != "/v1/logs" || r.Header.Get("X-Api-Key") != "k1" || proto.Unmarshal(body, &req) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		got = append(got, &req)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	t.Setenv("AUDIT_LOG_SINKS", "otlp")
+	t.Setenv("AUDIT_LOG_OTLP_ENDPOINT", srv.URL)
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "X-Api-Key=k1")
+	a, err := OpenAuditLog(AuditConfigFromEnv(nil, "oc-test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Publish(context.Background(), auditEnvelope("e1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Close(); err != nil { // sends the queued batch
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != 1 {
+		t.Fatalf("exports = %d", len(got))
+	}
+	rl := got[0].ResourceLogs[0]
+	if rl.Resource.Attributes[0].Value.GetStringValue() != "oc-test" {
+		t.Errorf("resource = %v", rl.Resource.Attributes)
+	}
+	lr := rl.ScopeLogs[0].LogRecords[0]
+	if lr.EventName != "openclause.audit.tool_event" {
+		t.Errorf("event name = %q", lr.EventName)
+	}
+	if n, err := VerifyAuditLog(strings.NewReader(lr.Body.GetStringValue())); err != nil || n != 1 {
+		t.Errorf("exported body does not verify: %d, %v", n, err)
+	}
+}
+
+func TestOpenAuditLog_RejectsBadConfig(t *testing.T) {
+	for _, cfg := range []AuditConfig{
+		{Sinks: []string{"syslog"}},
+		{Sinks: []string{AuditOTLP}},
+	} {
+		if _, err := OpenAuditLog(cfg); err == nil {
+			t.Errorf("OpenAuditLog(%+v) succeeded", cfg)
+		}
+	}
+	if a, err := OpenAuditLog(AuditConfig{}); a != nil || err != nil {
+		t.Errorf("no sinks: %v, %v", a, err)
+	}
+}
@@ -0,0 +1,151 @@
+package evidence
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	otlpLogQueueSize = 4096
+	otlpLogBatchSize = 256
+	otlpLogInterval  = time.Second
+)
+
+// otlpLogWriter exports audit records as OTLP log records over HTTP. The
+// record's JSON line is the log body, so collectors can forward it intact
+// and the chain stays verifiable downstream. Records are queued and sent in
+// batches; when the queue is full they are dropped and the drop logged.
+type otlpLogWriter struct {
+	url      string
+	headers  map[string]string
+	resource *resourcepb.Resource
+	client   *http.Client
+	queue    chan *logspb.LogRecord
+	done     chan struct{}
+}
+
+func newOTLPLogWriter(service, url string, headers map[string]string) *otlpLogWriter {
+	w := &otlpLogWriter{
+		url:     url,
+		headers: headers,
+		resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{
+			stringAttr("service.name", service),
+		}},
+		client: &http.Client{Timeout: 10 * time.Second},
+		queue:  make(chan *logspb.LogRecord, otlpLogQueueSize),
+		done:   make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+func (w *otlpLogWriter) write(line []byte, rec *AuditRecord) error {
+	lr := &logspb.LogRecord{
+		TimeUnixNano:         uint64(rec.Time.UnixNano()),
+		ObservedTimeUnixNano: uint64(time.Now().UnixNano()),
+		SeverityNumber:       logspb.SeverityNumber_SEVERITY_NUMBER_INFO,
+		SeverityText:         "INFO",
+		EventName:            "openclause.audit." + rec.Kind,
+		Body:                 &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: string(bytes.TrimRight(line, "\n"))}},
+		Attributes: []*commonpb.KeyValue{
+			stringAttr("oc.audit.kind", rec.Kind),
+			stringAttr("oc.audit.hash", rec.Hash),
+			stringAttr("oc.tenant_id", rec.TenantID),
+			stringAttr("oc.event_id", rec.EventID),
+		},
+	}
+	select {
+	case w.queue <- lr:
+		return nil
+	default:
+		return fmt.Errorf("otlp audit log: queue full, seq %d dropped", rec.Seq)
+	}
+}
+
+// Close sends what is queued and stops the writer.
+func (w *otlpLogWriter) Close() error {
+	close(w.queue)
+	<-w.done
+	return nil
+}
+
+func (w *otlpLogWriter) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(otlpLogInterval)
+	defer ticker.Stop()
+	var batch []*logspb.LogRecord
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := w.export(batch); err != nil {
+			slog.Default().Error("otlp audit log export failed", "records", len(batch), "error", err)
+		}
+		batch = nil
+	}
+	for {
+		select {
+		case lr, ok := <-w.queue:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, lr)
+			if len(batch) >= otlpLogBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+func (w *otlpLogWriter) export(records []*logspb.LogRecord) error {
+	body, err := proto.Marshal(&collogspb.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{{
+			Resource: w.resource,
+			ScopeLogs: []*logspb.ScopeLogs{{
+				Scope:      &commonpb.InstrumentationScope{Name: "github.com/bturcanu/OpenClause/pkg/evidence"},
+				LogRecords: records,
+			}},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	for k, v := range w.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s: status %d: %s", w.url, resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+func stringAttr(k, v string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: k, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v}}}
+}
@@ -8,11 +8,43 @@ import (
 	"encoding/json"
 	"fmt"
 	"sort"
+	"strings"
 )
 
+// CanonVersion selects the canonical JSON form hashed into the evidence
+// chain. Each event records the version it was written with, so verifiers
+// know how to reproduce payload_canon from payload_json.
+type CanonVersion int
+
+const (
+	// CanonLegacy is encoding/json output with object keys sorted by byte
+	// value. Numbers keep their textual form and <, > and & are escaped.
+	CanonLegacy CanonVersion = 1
+	// CanonJCS is RFC 8785, which JCS libraries in other languages
+	// reproduce byte for byte. Numbers are read as IEEE 754 doubles, so
+	// integers beyond 2^53 lose precision, as the RFC specifies.
+	CanonJCS CanonVersion = 2
+)
+
+// ParseCanonVersion parses "legacy" and "jcs"; "" is CanonLegacy.
+func ParseCanonVersion(s string) (CanonVersion, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "legacy":
+		return CanonLegacy, nil
+	case "jcs":
+		return CanonJCS, nil
+	}
+	return 0, fmt.Errorf("unknown canonical json version %q (want legacy or jcs)", s)
+}
+
 // CanonicalJSON produces a stable byte representation of v.
 // Object keys are sorted lexicographically; no extraneous whitespace.
 func CanonicalJSON(v any) ([]byte, error) {
+	return CanonicalJSONVersion(v, CanonLegacy)
+}
+
+// CanonicalJSONVersion produces the canonical form of v for version ver.
+func CanonicalJSONVersion(v any, ver CanonVersion) ([]byte, error) {
 	raw, err := json.Marshal(v)
 	if err != nil {
 		return nil, fmt.Errorf("canonical json marshal: %w", err)
@@ -25,6 +57,13 @@ func CanonicalJSON(v any) ([]byte, error) {
 		return nil, fmt.Errorf("canonical json unmarshal: %w", err)
 	}
 
+	if ver == CanonJCS {
+		out, err := appendJCS(nil, generic)
+		if err != nil {
+			return nil, fmt.Errorf("canonical json jcs: %w", err)
+		}
+		return out, nil
+	}
 	sorted := sortKeys(generic)
 	out, err := json.Marshal(sorted)
 	if err != nil {
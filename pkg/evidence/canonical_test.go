@@ -1,6 +1,7 @@
 package evidence
 
 import (
+	"encoding/json"
 	"testing"
 )
 
@@ -83,3 +84,38 @@ func TestHashPayload_Deterministic(t *testing.T) {
 		t.Errorf("expected SHA-256 hex length 64, got %d", len(h1))
 	}
 }
+
+func TestCanonicalJSONVersion_JCS(t *testing.T) {
+	// RFC 8785 section 3.2.2 and the sorting example of section 3.2.3.
+	tests := []struct{ in, want string }{
+		{
+			`{"numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001],
+			  "string": "€$\u000F\u000aA'\u0042\u0022\u005c\\\"\/",
+			  "literals": [null, true, false]}`,
+			`{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`,
+		},
+		{
+			`{"\u20ac": "Euro Sign", "\r": "Carriage Return", "\ufb33": "Hebrew Letter Dalet With Dagesh",
+			  "1": "One", "\ud83d\ude00": "Emoji: Grinning Face", "\u0080": "Control", "\u00f6": "Latin Small Letter O With Diaeresis"}`,
+			"{\"\\r\":\"Carriage Return\",\"1\":\"One\",\"\u0080\":\"Control\",\"\u00f6\":\"Latin Small Letter O With Diaeresis\"," +
+				"\"\u20ac\":\"Euro Sign\",\"\U0001F600\":\"Emoji: Grinning Face\",\"\ufb33\":\"Hebrew Letter Dalet With Dagesh\"}",
+		},
+		{`[0, -0, 1e21, 1e20, 1e-7, 0.000001, -1.5, 9007199254740993, 123456789012]`, `[0,0,1e+21,100000000000000000000,1e-7,0.000001,-1.5,9007199254740992,123456789012]`},
+		{`{"html": "<a&b>", "sep": "\u2028"}`, "{\"html\":\"<a&b>\",\"sep\":\"\u2028\"}"},
+	}
+	for _, tt := range tests {
+		got, err := CanonicalJSONVersion(json.RawMessage(tt.in), CanonJCS)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.in, err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("jcs(%s)\n got %s\nwant %s", tt.in, got, tt.want)
+		}
+	}
+
+	// The legacy form is unchanged.
+	legacy, err := CanonicalJSONVersion(json.RawMessage(`{"b":1.50,"a":"<"}`), CanonLegacy)
+	if err != nil || string(legacy) != `{"a":"\u003c","b":1.50}` {
+		t.Fatalf("legacy = %s, %v", legacy, err)
+	}
+}
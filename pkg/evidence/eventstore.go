@@ -0,0 +1,94 @@
+package evidence
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+// EventStore is the persistence contract for the evidence log. Implementations
+// must serialise hash-chain appends per tenant, enforce (tenant,
+// idempotency_key) uniqueness, and keep tool_executions links append-only.
+// Schedules of approved calls live beside the executions they produce.
+type EventStore interface {
+	RecordEvent(ctx context.Context, env *types.ToolCallEnvelope) error
+	CheckIdempotency(ctx context.Context, tenantID, idempotencyKey string) (*types.ToolCallResponse, error)
+	GetEvent(ctx context.Context, eventID string) (*types.ToolCallEnvelope, error)
+	GetExecutionByParentEvent(ctx context.Context, parentEventID string) (*types.ToolCallResponse, error)
+	LinkExecutionToParent(ctx context.Context, parentEventID, executionEventID, consumedGrantID string) (bool, error)
+	GetChainEvents(ctx context.Context, tenantID string, afterSeq int64) ([]ChainEvent, error)
+	ListTraceEvents(ctx context.Context, tenantID, traceID string, limit int) ([]types.ToolCallEnvelope, error)
+	ListEvents(ctx context.Context, tenantID string, f EventFilter) ([]types.ToolCallEnvelope, error)
+	CreateSchedule(ctx context.Context, s *types.ScheduledExecution) error
+	GetSchedule(ctx context.Context, parentEventID string) (*types.ScheduledExecution, error)
+	CancelSchedule(ctx context.Context, parentEventID string) (*types.ScheduledExecution, error)
+	ClaimDueSchedules(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]types.ScheduledExecution, error)
+	FinishSchedule(ctx context.Context, id, status, executionEventID, errMsg string) error
+}
+
+// Bounds on the page ListEvents returns.
+const (
+	DefaultListEvents = 100
+	MaxListEvents     = 500
+)
+
+// EventFilter selects the events ListEvents returns. Empty fields match any
+// value. AfterSeq is a position in the tenant's chain (an event's EventSeq):
+// passing the last seq of one page fetches the next. Newest returns the
+// last Limit matching events, newest first, instead of the first.
+type EventFilter struct {
+	AgentID  string
+	Tool     string
+	Decision types.Decision
+	AfterSeq int64
+	Limit    int
+	Newest   bool
+}
+
+// limit clamps Limit to (0, MaxListEvents], defaulting to DefaultListEvents.
+func (f EventFilter) limit() int {
+	switch {
+	case f.Limit <= 0:
+		return DefaultListEvents
+	case f.Limit > MaxListEvents:
+		return MaxListEvents
+	}
+	return f.Limit
+}
+
+// where renders the filter as a WHERE clause over tool_events e, using
+// placeholder(n) for the nth bind parameter.
+func (f EventFilter) where(tenantID string, placeholder func(n int) string) (string, []any) {
+	args := []any{tenantID, f.AfterSeq}
+	clauses := []string{"e.tenant_id = " + placeholder(1), "e.event_seq > " + placeholder(2)}
+	add := func(column string, v any) {
+		args = append(args, v)
+		clauses = append(clauses, column+" = "+placeholder(len(args)))
+	}
+	if f.AgentID != "" {
+		add("e.agent_id", f.AgentID)
+	}
+	if f.Tool != "" {
+		add("e.tool", f.Tool)
+	}
+	if f.Decision != "" {
+		add("e.decision", string(f.Decision))
+	}
+	return strings.Join(clauses, " AND "), args
+}
+
+// order renders the filter's ORDER BY direction over event_seq.
+func (f EventFilter) order() string {
+	if f.Newest {
+		return "DESC"
+	}
+	return "ASC"
+}
+
+var (
+	_ EventStore = (*Store)(nil)
+	_ EventStore = (*SQLiteStore)(nil)
+	_ EventStore = (*MySQLStore)(nil)
+)
@@ -0,0 +1,145 @@
+package evidence
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// appendJCS appends v, as decoded by encoding/json with UseNumber, in the
+// RFC 8785 JSON Canonicalization Scheme: object members sorted by the UTF-16
+// code units of their names, strings with only the escapes JSON requires,
+// and numbers serialized as ECMAScript's Number.prototype.toString does.
+func appendJCS(buf []byte, v any) ([]byte, error) {
+	switch val := v.(type) {
+	case nil:
+		return append(buf, "null"...), nil
+	case bool:
+		return strconv.AppendBool(buf, val), nil
+	case string:
+		return appendJCSString(buf, val), nil
+	case json.Number:
+		f, err := strconv.ParseFloat(string(val), 64)
+		if err != nil {
+			return nil, fmt.Errorf("number %s: %w", val, err)
+		}
+		return appendJCSNumber(buf, f)
+	case []any:
+		buf = append(buf, '[')
+		for i, item := range val {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			var err error
+			if buf, err = appendJCS(buf, item); err != nil {
+				return nil, err
+			}
+		}
+		return append(buf, ']'), nil
+	case map[string]any:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		slices.SortFunc(keys, func(a, b string) int {
+			return slices.Compare(utf16.Encode([]rune(a)), utf16.Encode([]rune(b)))
+		})
+		buf = append(buf, '{')
+		for i, k := range keys {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			buf = appendJCSString(buf, k)
+			buf = append(buf, ':')
+			var err error
+			if buf, err = appendJCS(buf, val[k]); err != nil {
+				return nil, err
+			}
+		}
+		return append(buf, '}'), nil
+	default:
+		return nil, fmt.Errorf("unexpected %T", v)
+	}
+}
+
+// appendJCSString escapes the quote, the backslash and control characters,
+// using the two-character forms where JSON has one and lowercase \u00xx
+// otherwise. Everything else, including <, > and &, is written as UTF-8.
+func appendJCSString(buf []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	buf = append(buf, '"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			buf = append(buf, '\\', c)
+		case c == '\b':
+			buf = append(buf, '\\', 'b')
+		case c == '\f':
+			buf = append(buf, '\\', 'f')
+		case c == '\n':
+			buf = append(buf, '\\', 'n')
+		case c == '\r':
+			buf = append(buf, '\\', 'r')
+		case c == '\t':
+			buf = append(buf, '\\', 't')
+		case c < 0x20:
+			buf = append(buf, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return append(buf, '"')
+}
+
+// appendJCSNumber formats f like ECMAScript: the shortest digits that
+// round-trip, in plain notation for decimal exponents from -6 to 20 and in
+// exponent notation ("1e+21", "1e-7") outside it. -0 is "0".
+func appendJCSNumber(buf []byte, f float64) ([]byte, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, fmt.Errorf("number %v is not valid JSON", f)
+	}
+	if f == 0 {
+		return append(buf, '0'), nil
+	}
+	if f < 0 {
+		buf = append(buf, '-')
+		f = -f
+	}
+	// "d.ddde±x": digits holds the significant digits and n the position of
+	// the decimal point relative to them, as in ECMA-262 Number::toString.
+	mant, exp, _ := strings.Cut(strconv.FormatFloat(f, 'e', -1, 64), "e")
+	digits := strings.Replace(mant, ".", "", 1)
+	x, err := strconv.Atoi(exp)
+	if err != nil {
+		return nil, err
+	}
+	k, n := len(digits), x+1
+	switch {
+	case k <= n && n <= 21:
+		buf = append(buf, digits...)
+		return append(buf, strings.Repeat("0", n-k)...), nil
+	case 0 < n && n <= 21:
+		buf = append(buf, digits[:n]...)
+		buf = append(buf, '.')
+		return append(buf, digits[n:]...), nil
+	case -6 < n && n <= 0:
+		buf = append(buf, "0."...)
+		buf = append(buf, strings.Repeat("0", -n)...)
+		return append(buf, digits...), nil
+	}
+	buf = append(buf, digits[0])
+	if k > 1 {
+		buf = append(buf, '.')
+		buf = append(buf, digits[1:]...)
+	}
+	buf = append(buf, 'e')
+	if n-1 >= 0 {
+		buf = append(buf, '+')
+	}
+	return strconv.AppendInt(buf, int64(n-1), 10), nil
+}
@@ -4,28 +4,66 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"time"
 
 	"github.com/bturcanu/OpenClause/pkg/types"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("github.com/bturcanu/OpenClause/pkg/evidence")
+
 // Logger wraps the Store and emits structured logs alongside DB writes.
 type Logger struct {
-	store *Store
+	store EventStore
 	log   *slog.Logger
+	audit *AuditLogger
+	sinks []Sink
 }
 
 // NewLogger creates an evidence logger backed by the given store.
-func NewLogger(store *Store, log *slog.Logger) *Logger {
+func NewLogger(store EventStore, log *slog.Logger) *Logger {
 	return &Logger{store: store, log: log}
 }
 
-// RecordEvent persists and logs the event.
+// AddSink registers a sink that receives every successfully recorded
+// envelope. It must be called before the logger is shared across goroutines.
+func (l *Logger) AddSink(s Sink) {
+	l.sinks = append(l.sinks, s)
+}
+
+// SetAuditLog sends every recorded event to the audit log a. The
+// operational log then only notes recorded events at debug level, leaving
+// the audit trail to a.
+func (l *Logger) SetAuditLog(a *AuditLogger) {
+	l.audit = a
+}
+
+// RecordEvent persists and logs the event, then fans it out to any sinks.
 func (l *Logger) RecordEvent(ctx context.Context, env *types.ToolCallEnvelope) error {
 	if env == nil {
 		return fmt.Errorf("evidence.RecordEvent: nil envelope")
 	}
 
-	if err := l.store.RecordEvent(ctx, env); err != nil {
+	ctx, span := tracer.Start(ctx, "evidence.RecordEvent", trace.WithAttributes(
+		attribute.String("oc.event_id", env.EventID),
+		attribute.String("oc.decision", string(env.Decision)),
+	))
+	defer span.End()
+
+	start := time.Now()
+	err := l.store.RecordEvent(ctx, env)
+	outcome := "ok"
+	if err != nil {
+		outcome = "error"
+	}
+	writeDuration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(attribute.String("outcome", outcome)))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 		l.log.ErrorContext(ctx, "evidence record failed",
 			"event_id", env.EventID,
 			"tenant_id", env.Request.TenantID,
@@ -34,16 +72,34 @@ func (l *Logger) RecordEvent(ctx context.Context, env *types.ToolCallEnvelope) e
 		return err
 	}
 
-	l.log.InfoContext(ctx, "tool_event recorded",
+	level := slog.LevelInfo
+	if l.audit != nil {
+		level = slog.LevelDebug
+		if err := l.audit.Publish(ctx, env); err != nil {
+			l.log.ErrorContext(ctx, "audit log write failed",
+				"event_id", env.EventID,
+				"error", err,
+			)
+		}
+	}
+	l.log.Log(ctx, level, "tool_event recorded",
 		"event_id", env.EventID,
 		"tenant_id", env.Request.TenantID,
 		"agent_id", env.Request.AgentID,
 		"tool", env.Request.Tool,
 		"action", env.Request.Action,
 		"decision", string(env.Decision),
-		"risk_score", env.Request.RiskScore,
+		"risk_score", env.RiskScore(),
 		"hash", env.Hash,
 	)
+	for _, s := range l.sinks {
+		if err := s.Publish(ctx, env); err != nil {
+			l.log.WarnContext(ctx, "evidence sink publish failed",
+				"event_id", env.EventID,
+				"error", err,
+			)
+		}
+	}
 	return nil
 }
 
@@ -77,3 +133,38 @@ func (l *Logger) GetExecutionByParentEvent(ctx context.Context, parentEventID st
 func (l *Logger) LinkExecutionToParent(ctx context.Context, parentEventID, executionEventID, consumedGrantID string) (bool, error) {
 	return l.store.LinkExecutionToParent(ctx, parentEventID, executionEventID, consumedGrantID)
 }
+
+// ListEvents delegates to the store.
+func (l *Logger) ListEvents(ctx context.Context, tenantID string, f EventFilter) ([]types.ToolCallEnvelope, error) {
+	return l.store.ListEvents(ctx, tenantID, f)
+}
+
+// ListTraceEvents delegates to the store.
+func (l *Logger) ListTraceEvents(ctx context.Context, tenantID, traceID string, limit int) ([]types.ToolCallEnvelope, error) {
+	return l.store.ListTraceEvents(ctx, tenantID, traceID, limit)
+}
+
+// CreateSchedule delegates to the store.
+func (l *Logger) CreateSchedule(ctx context.Context, s *types.ScheduledExecution) error {
+	return l.store.CreateSchedule(ctx, s)
+}
+
+// GetSchedule delegates to the store.
+func (l *Logger) GetSchedule(ctx context.Context, parentEventID string) (*types.ScheduledExecution, error) {
+	return l.store.GetSchedule(ctx, parentEventID)
+}
+
+// CancelSchedule delegates to the store.
+func (l *Logger) CancelSchedule(ctx context.Context, parentEventID string) (*types.ScheduledExecution, error) {
+	return l.store.CancelSchedule(ctx, parentEventID)
+}
+
+// ClaimDueSchedules delegates to the store.
+func (l *Logger) ClaimDueSchedules(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]types.ScheduledExecution, error) {
+	return l.store.ClaimDueSchedules(ctx, now, lease, limit)
+}
+
+// FinishSchedule delegates to the store.
+func (l *Logger) FinishSchedule(ctx context.Context, id, status, executionEventID, errMsg string) error {
+	return l.store.FinishSchedule(ctx, id, status, executionEventID, errMsg)
+}
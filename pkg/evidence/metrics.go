@@ -0,0 +1,20 @@
+package evidence
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+)
+
+var writeDuration metric.Float64Histogram
+
+func init() {
+	meter := otel.Meter("github.com/bturcanu/OpenClause/pkg/evidence")
+	var err error
+	writeDuration, err = meter.Float64Histogram("oc.evidence.write.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Latency of evidence writes (hash-chain append + result insert)."),
+	)
+	if err != nil {
+		panic(err)
+	}
+}
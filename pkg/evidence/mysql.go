@@ -0,0 +1,99 @@
+package evidence
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+// chainLockTimeoutSec bounds how long RecordEvent waits for a tenant's chain
+// lock before failing the write.
+const chainLockTimeoutSec = 10
+
+// MySQLStore persists the evidence log in MySQL 8 / Aurora MySQL using the
+// schema in migrations/mysql. Chain appends are serialised per tenant with a
+// named lock (GET_LOCK), the MySQL counterpart of the Postgres advisory lock.
+type MySQLStore struct {
+	sqlEvents
+}
+
+// NewMySQLStore wraps db, which should be opened with mysqldb.Open so that
+// timestamps round-trip in UTC.
+func NewMySQLStore(db *sql.DB) *MySQLStore {
+	return &MySQLStore{sqlEvents{db: db, canon: CanonLegacy}}
+}
+
+// RecordEvent appends env to the tenant's hash chain and stores its result.
+func (s *MySQLStore) RecordEvent(ctx context.Context, env *types.ToolCallEnvelope) error {
+	var appended chainAppend
+	err := s.inChainTx(ctx, "evidence.RecordEvent", env.Request.TenantID, func(tx *sql.Tx) (err error) {
+		appended, err = s.appendEvent(ctx, tx, env)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	appended.apply(env)
+	return nil
+}
+
+// inChainTx runs fn in a transaction holding the tenant's chain lock and
+// commits it. Named locks belong to a session, so the lock, transaction, and
+// release all run on one pinned connection. op prefixes errors.
+func (s *MySQLStore) inChainTx(ctx context.Context, op, tenantID string, fn func(*sql.Tx) error) error {
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("%s conn: %w", op, err)
+	}
+	defer conn.Close()
+
+	lockName := mysqlChainLockName(tenantID)
+	var acquired sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", lockName, chainLockTimeoutSec).Scan(&acquired); err != nil {
+		return fmt.Errorf("%s chain lock: %w", op, err)
+	}
+	if acquired.Int64 != 1 {
+		return fmt.Errorf("%s chain lock: timed out waiting for tenant %s", op, tenantID)
+	}
+	defer conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", lockName) //nolint:errcheck // lock is also dropped when the session ends
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s begin tx: %w", op, err)
+	}
+	defer tx.Rollback() //nolint:errcheck // rollback after commit is a no-op
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s commit: %w", op, err)
+	}
+	return nil
+}
+
+// LinkExecutionToParent stores the append-only parent→execution relation.
+// Returns false when another request already linked the parent: the no-op
+// ON DUPLICATE KEY UPDATE reports zero affected rows.
+func (s *MySQLStore) LinkExecutionToParent(ctx context.Context, parentEventID, executionEventID, consumedGrantID string) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO tool_executions(parent_event_id, execution_event_id, consumed_grant_id)
+		VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE parent_event_id = parent_event_id`, parentEventID, executionEventID, consumedGrantID)
+	if err != nil {
+		return false, fmt.Errorf("evidence.LinkExecutionToParent: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("evidence.LinkExecutionToParent rows: %w", err)
+	}
+	return n == 1, nil
+}
+
+// mysqlChainLockName derives a GET_LOCK name for tenantID. Lock names are
+// capped at 64 characters, so the tenant is hashed like the Postgres lock ID.
+func mysqlChainLockName(tenantID string) string {
+	return fmt.Sprintf("oc_evidence_chain_%016x", uint64(tenantLockID(tenantID)))
+}
@@ -0,0 +1,17 @@
+package evidence
+
+import "testing"
+
+func TestMySQLChainLockName(t *testing.T) {
+	a := mysqlChainLockName("tenant1")
+	if a != mysqlChainLockName("tenant1") {
+		t.Fatal("lock name must be stable for a tenant")
+	}
+	if a == mysqlChainLockName("tenant2") {
+		t.Fatal("distinct tenants should get distinct lock names")
+	}
+	long := mysqlChainLockName(string(make([]byte, 500)))
+	if len(a) > 64 || len(long) > 64 {
+		t.Fatalf("GET_LOCK names are limited to 64 chars, got %d and %d", len(a), len(long))
+	}
+}
@@ -0,0 +1,381 @@
+package evidence
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"github.com/jackc/pgx/v5"
+)
+
+// ReplicaStore is implemented by evidence stores that can stream their
+// chains to a standby region and apply a primary's stream. A standby
+// resumes after the hash at the head of its own chain, so what it has
+// applied and where it resumes cannot disagree.
+type ReplicaStore interface {
+	ChainHeads(ctx context.Context) ([]ChainHead, error)
+	ReplicationEvents(ctx context.Context, tenantID, afterHash string, limit int) ([]ReplicatedEvent, int64, error)
+	ApplyReplicated(ctx context.Context, ev *ReplicatedEvent) error
+}
+
+var (
+	// ErrUnknownChainHash is returned by ReplicationEvents when afterHash is
+	// not in the tenant's chain: the standby holds events the primary does
+	// not.
+	ErrUnknownChainHash = errors.New("hash is not in the tenant's chain")
+	// ErrChainMismatch is returned by ApplyReplicated when an event does not
+	// extend the standby's chain or its hash does not verify.
+	ErrChainMismatch = errors.New("replicated event does not extend the chain")
+)
+
+// ChainHead is the latest event of a tenant's chain.
+type ChainHead struct {
+	TenantID   string    `json:"tenant_id"`
+	Seq        int64     `json:"seq"`
+	Hash       string    `json:"hash"`
+	ReceivedAt time.Time `json:"received_at"`
+}
+
+// ReplicatedEvent is a tool_events row and its tool_results row as a primary
+// streams them. ResultCanon is the stored canonical result, so the standby
+// verifies the chain link over the same bytes the primary hashed.
+type ReplicatedEvent struct {
+	types.ToolCallEnvelope
+	ResultCanon []byte `json:"result_canon,omitempty"`
+}
+
+// chain is the chain position ev is stored at.
+func (ev *ReplicatedEvent) chain() chainAppend {
+	v := CanonVersion(ev.CanonVersion)
+	if v == 0 {
+		v = CanonLegacy
+	}
+	return chainAppend{hash: ev.Hash, prevHash: ev.PrevHash, canon: ev.PayloadCanon, canonVersion: v}
+}
+
+// verifyReplicated checks that ev extends a chain whose head is head and
+// that its request and result are the ones hashed into it. The result must
+// re-encode to exactly the hashed result. The request is decoded from the
+// hashed payload, which must be in canonical form, and must agree with the
+// envelope's request; ev's request and payload are then replaced by the
+// decoded ones, since a stored RequestedAt has lost the precision the hash
+// was taken over. The decision and policy result are not in the chain and
+// are stored as the primary sent them.
+func verifyReplicated(head string, ev *ReplicatedEvent) error {
+	if ev.PrevHash != head {
+		return fmt.Errorf("%w: event %s follows %q, the standby head is %q", ErrChainMismatch, ev.EventID, ev.PrevHash, head)
+	}
+	if (ev.ExecutionResult == nil) != (ev.ResultCanon == nil) {
+		return fmt.Errorf("%w: event %s result and canonical result disagree", ErrChainMismatch, ev.EventID)
+	}
+	if want := ChainHash(ev.PrevHash, ev.PayloadCanon, ev.ResultCanon); ev.Hash != want {
+		return fmt.Errorf("%w: event %s hash %s, recomputed %s", ErrChainMismatch, ev.EventID, ev.Hash, want)
+	}
+	v := ev.chain().canonVersion
+	var req types.ToolCallRequest
+	if err := decodeCanonical(ev.PayloadCanon, v, &req); err != nil {
+		return fmt.Errorf("%w: event %s payload: %v", ErrChainMismatch, ev.EventID, err)
+	}
+	if !sameRequest(ev.Request, req) {
+		return fmt.Errorf("%w: event %s request does not match its hashed payload", ErrChainMismatch, ev.EventID)
+	}
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("evidence.ApplyReplicated marshal payload: %w", err)
+	}
+	ev.Request, ev.PayloadJSON = req, payload
+	if ev.ResultCanon == nil {
+		return nil
+	}
+	canon, err := CanonicalJSONVersion(ev.ExecutionResult, v)
+	if err != nil {
+		return fmt.Errorf("evidence.ApplyReplicated canonical result: %w", err)
+	}
+	if !bytes.Equal(canon, ev.ResultCanon) {
+		return fmt.Errorf("%w: event %s result does not match its hashed result", ErrChainMismatch, ev.EventID)
+	}
+	return nil
+}
+
+// decodeCanonical decodes canon into v and checks that v encodes back to
+// exactly canon in version ver.
+func decodeCanonical(canon []byte, ver CanonVersion, v any) error {
+	if err := json.Unmarshal(canon, v); err != nil {
+		return err
+	}
+	again, err := CanonicalJSONVersion(v, ver)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(again, canon) {
+		return errors.New("not in canonical form")
+	}
+	return nil
+}
+
+// sameRequest reports whether the request a primary sent agrees with the
+// hashed one on the fields stored in their own columns. RequestedAt is
+// compared to the microsecond, the precision the stores keep.
+func sameRequest(got, hashed types.ToolCallRequest) bool {
+	return got.TenantID == hashed.TenantID && got.AgentID == hashed.AgentID &&
+		got.Tool == hashed.Tool && got.Action == hashed.Action &&
+		got.RiskScore == hashed.RiskScore && got.IdempotencyKey == hashed.IdempotencyKey &&
+		got.SessionID == hashed.SessionID && got.UserID == hashed.UserID &&
+		got.SourceIP == hashed.SourceIP && got.TraceID == hashed.TraceID &&
+		got.RequestedAt.Truncate(time.Microsecond).Equal(hashed.RequestedAt.Truncate(time.Microsecond))
+}
+
+// resultCanonRow scans a row of eventColumns followed by r.result_canon.
+type resultCanonRow struct {
+	row   interface{ Scan(...any) error }
+	canon *[]byte
+}
+
+func (r resultCanonRow) Scan(dest ...any) error {
+	return r.row.Scan(append(dest, r.canon)...)
+}
+
+// chainHeadsQuery selects each tenant's latest event.
+const chainHeadsQuery = `
+	SELECT e.tenant_id, e.event_seq, e.hash, e.received_at
+	FROM tool_events e
+	JOIN (SELECT tenant_id, MAX(event_seq) AS head FROM tool_events GROUP BY tenant_id) h
+	  ON h.tenant_id = e.tenant_id AND h.head = e.event_seq
+	ORDER BY e.tenant_id ASC`
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Postgres
+// ──────────────────────────────────────────────────────────────────────────────
+
+// ChainHeads returns the head of every tenant's chain.
+func (s *Store) ChainHeads(ctx context.Context) ([]ChainHead, error) {
+	rows, err := s.pool.Query(ctx, chainHeadsQuery)
+	if err != nil {
+		return nil, fmt.Errorf("evidence.ChainHeads: %w", err)
+	}
+	defer rows.Close()
+
+	heads := make([]ChainHead, 0)
+	for rows.Next() {
+		var h ChainHead
+		if err := rows.Scan(&h.TenantID, &h.Seq, &h.Hash, &h.ReceivedAt); err != nil {
+			return nil, fmt.Errorf("evidence.ChainHeads scan: %w", err)
+		}
+		heads = append(heads, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("evidence.ChainHeads iteration: %w", err)
+	}
+	return heads, nil
+}
+
+// ReplicationEvents returns up to limit of the tenant's events after the
+// one hashed afterHash ("" for the start of the chain), in chain order, and
+// how many events follow afterHash in all.
+func (s *Store) ReplicationEvents(ctx context.Context, tenantID, afterHash string, limit int) ([]ReplicatedEvent, int64, error) {
+	var afterSeq int64
+	if afterHash != "" {
+		err := s.pool.QueryRow(ctx, `
+			SELECT event_seq FROM tool_events
+			WHERE tenant_id = $1 AND hash = $2`, tenantID, afterHash).Scan(&afterSeq)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, 0, ErrUnknownChainHash
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("evidence.ReplicationEvents position: %w", err)
+		}
+	}
+	var pending int64
+	if err := s.pool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM tool_events
+		WHERE tenant_id = $1 AND event_seq > $2`, tenantID, afterSeq).Scan(&pending); err != nil {
+		return nil, 0, fmt.Errorf("evidence.ReplicationEvents count: %w", err)
+	}
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+eventColumns+`, r.result_canon
+		FROM tool_events e
+		LEFT JOIN tool_results r ON r.event_id = e.event_id
+		WHERE e.tenant_id = $1 AND e.event_seq > $2
+		ORDER BY e.event_seq ASC
+		LIMIT $3`, tenantID, afterSeq, limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("evidence.ReplicationEvents: %w", err)
+	}
+	defer rows.Close()
+
+	var events []ReplicatedEvent
+	for rows.Next() {
+		var ev ReplicatedEvent
+		env, err := scanEvent(resultCanonRow{rows, &ev.ResultCanon})
+		if err != nil {
+			return nil, 0, fmt.Errorf("evidence.ReplicationEvents: %w", err)
+		}
+		ev.ToolCallEnvelope = *env
+		events = append(events, ev)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("evidence.ReplicationEvents iteration: %w", err)
+	}
+	return events, pending, nil
+}
+
+// ApplyReplicated appends a primary's event to the standby chain as is:
+// same event ID, canonical bytes and hashes. It fails with
+// ErrChainMismatch unless the event links to the tenant's head and its hash
+// verifies. The advisory lock serialises it with RecordEvent.
+func (s *Store) ApplyReplicated(ctx context.Context, ev *ReplicatedEvent) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("evidence.ApplyReplicated begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback after commit is a no-op
+
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", tenantLockID(ev.Request.TenantID)); err != nil {
+		return fmt.Errorf("evidence.ApplyReplicated advisory lock: %w", err)
+	}
+	head, err := s.lastHashTx(ctx, tx, ev.Request.TenantID)
+	if err != nil {
+		return fmt.Errorf("evidence.ApplyReplicated last hash: %w", err)
+	}
+	if err := verifyReplicated(head, ev); err != nil {
+		return err
+	}
+	if _, err := insertEventTx(ctx, tx, &ev.ToolCallEnvelope, ev.chain(), ev.ResultCanon); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("evidence.ApplyReplicated commit: %w", err)
+	}
+	return nil
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// SQLite and MySQL
+// ──────────────────────────────────────────────────────────────────────────────
+
+// ChainHeads returns the head of every tenant's chain.
+func (s sqlEvents) ChainHeads(ctx context.Context) ([]ChainHead, error) {
+	rows, err := s.db.QueryContext(ctx, chainHeadsQuery)
+	if err != nil {
+		return nil, fmt.Errorf("evidence.ChainHeads: %w", err)
+	}
+	defer rows.Close()
+
+	heads := make([]ChainHead, 0)
+	for rows.Next() {
+		var h ChainHead
+		if err := rows.Scan(&h.TenantID, &h.Seq, &h.Hash, &h.ReceivedAt); err != nil {
+			return nil, fmt.Errorf("evidence.ChainHeads scan: %w", err)
+		}
+		h.ReceivedAt = h.ReceivedAt.UTC()
+		heads = append(heads, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("evidence.ChainHeads iteration: %w", err)
+	}
+	return heads, nil
+}
+
+// ReplicationEvents returns up to limit of the tenant's events after the
+// one hashed afterHash ("" for the start of the chain), in chain order, and
+// how many events follow afterHash in all.
+func (s sqlEvents) ReplicationEvents(ctx context.Context, tenantID, afterHash string, limit int) ([]ReplicatedEvent, int64, error) {
+	var afterSeq int64
+	if afterHash != "" {
+		err := s.db.QueryRowContext(ctx, `
+			SELECT event_seq FROM tool_events
+			WHERE tenant_id = ? AND hash = ?`, tenantID, afterHash).Scan(&afterSeq)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, 0, ErrUnknownChainHash
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("evidence.ReplicationEvents position: %w", err)
+		}
+	}
+	var pending int64
+	if err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM tool_events
+		WHERE tenant_id = ? AND event_seq > ?`, tenantID, afterSeq).Scan(&pending); err != nil {
+		return nil, 0, fmt.Errorf("evidence.ReplicationEvents count: %w", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+eventColumns+`, r.result_canon
+		FROM tool_events e
+		LEFT JOIN tool_results r ON r.event_id = e.event_id
+		WHERE e.tenant_id = ? AND e.event_seq > ?
+		ORDER BY e.event_seq ASC
+		LIMIT ?`, tenantID, afterSeq, limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("evidence.ReplicationEvents: %w", err)
+	}
+	defer rows.Close()
+
+	var events []ReplicatedEvent
+	for rows.Next() {
+		var ev ReplicatedEvent
+		env, err := scanSQLEvent(resultCanonRow{rows, &ev.ResultCanon})
+		if err != nil {
+			return nil, 0, fmt.Errorf("evidence.ReplicationEvents: %w", err)
+		}
+		ev.ToolCallEnvelope = *env
+		events = append(events, ev)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("evidence.ReplicationEvents iteration: %w", err)
+	}
+	return events, pending, nil
+}
+
+// applySQLReplicated verifies ev against the tenant's head and inserts it
+// inside tx. The caller must already hold the tenant's chain lock.
+func applySQLReplicated(ctx context.Context, tx *sql.Tx, ev *ReplicatedEvent) error {
+	head, err := lastSQLHash(ctx, tx, ev.Request.TenantID)
+	if err != nil {
+		return fmt.Errorf("evidence.ApplyReplicated last hash: %w", err)
+	}
+	if err := verifyReplicated(head, ev); err != nil {
+		return err
+	}
+	_, err = insertSQLEvent(ctx, tx, &ev.ToolCallEnvelope, ev.chain(), ev.ResultCanon)
+	return err
+}
+
+// ApplyReplicated appends a primary's event to the standby chain as is; see
+// Store.ApplyReplicated. BEGIN IMMEDIATE serialises it with RecordEvent.
+func (s *SQLiteStore) ApplyReplicated(ctx context.Context, ev *ReplicatedEvent) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("evidence.ApplyReplicated begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // rollback after commit is a no-op
+
+	if err := applySQLReplicated(ctx, tx, ev); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("evidence.ApplyReplicated commit: %w", err)
+	}
+	return nil
+}
+
+// ApplyReplicated appends a primary's event to the standby chain as is; see
+// Store.ApplyReplicated. The tenant's named lock serialises it with
+// RecordEvent.
+func (s *MySQLStore) ApplyReplicated(ctx context.Context, ev *ReplicatedEvent) error {
+	return s.inChainTx(ctx, "evidence.ApplyReplicated", ev.Request.TenantID, func(tx *sql.Tx) error {
+		return applySQLReplicated(ctx, tx, ev)
+	})
+}
+
+var (
+	_ ReplicaStore = (*Store)(nil)
+	_ ReplicaStore = (*SQLiteStore)(nil)
+	_ ReplicaStore = (*MySQLStore)(nil)
+)
@@ -0,0 +1,269 @@
+package evidence
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"github.com/jackc/pgx/v5"
+)
+
+// scheduleColumns are the scheduled_executions columns scanSchedule reads.
+const scheduleColumns = `id, parent_event_id, tenant_id, grant_id, execute_at, status,
+		COALESCE(execution_event_id, ''), error_msg, created_at, updated_at`
+
+func scanSchedule(row interface{ Scan(...any) error }) (*types.ScheduledExecution, error) {
+	var s types.ScheduledExecution
+	if err := row.Scan(&s.ID, &s.EventID, &s.TenantID, &s.GrantID, &s.ExecuteAt, &s.Status,
+		&s.ExecutionEventID, &s.Error, &s.CreatedAt, &s.UpdatedAt); err != nil {
+		return nil, err
+	}
+	s.ExecuteAt, s.CreatedAt, s.UpdatedAt = s.ExecuteAt.UTC(), s.CreatedAt.UTC(), s.UpdatedAt.UTC()
+	return &s, nil
+}
+
+// ── Postgres ────────────────────────────────────────────────────────────────
+
+// CreateSchedule stores s as scheduled, setting its status and timestamps.
+func (s *Store) CreateSchedule(ctx context.Context, sched *types.ScheduledExecution) error {
+	now := time.Now().UTC()
+	sched.Status, sched.CreatedAt, sched.UpdatedAt = types.ScheduleScheduled, now, now
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO scheduled_executions (id, parent_event_id, tenant_id, grant_id, execute_at, status, error_msg, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, '', $7, $7)`,
+		sched.ID, sched.EventID, sched.TenantID, sched.GrantID, sched.ExecuteAt.UTC(), sched.Status, now)
+	if err != nil {
+		return fmt.Errorf("evidence.CreateSchedule: %w", err)
+	}
+	return nil
+}
+
+// GetSchedule returns the latest schedule made for the approved event
+// parentEventID, or nil if it was never scheduled.
+func (s *Store) GetSchedule(ctx context.Context, parentEventID string) (*types.ScheduledExecution, error) {
+	sched, err := scanSchedule(s.pool.QueryRow(ctx, `
+		SELECT `+scheduleColumns+`
+		FROM scheduled_executions
+		WHERE parent_event_id = $1
+		ORDER BY created_at DESC LIMIT 1`, parentEventID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("evidence.GetSchedule: %w", err)
+	}
+	return sched, nil
+}
+
+// CancelSchedule cancels the event's pending schedule. It returns nil when
+// none is pending, including when the scheduler has already claimed it.
+func (s *Store) CancelSchedule(ctx context.Context, parentEventID string) (*types.ScheduledExecution, error) {
+	sched, err := scanSchedule(s.pool.QueryRow(ctx, `
+		UPDATE scheduled_executions
+		SET status = $2, updated_at = NOW()
+		WHERE parent_event_id = $1 AND status = $3
+		RETURNING `+scheduleColumns, parentEventID, types.ScheduleCancelled, types.ScheduleScheduled))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("evidence.CancelSchedule: %w", err)
+	}
+	return sched, nil
+}
+
+// ClaimDueSchedules moves up to limit schedules due by now to running,
+// leased until now+lease, and returns them. A running schedule whose lease
+// has lapsed is claimed again, so one left by a scheduler that stopped
+// mid-run still fires. Concurrent schedulers claim disjoint sets.
+func (s *Store) ClaimDueSchedules(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]types.ScheduledExecution, error) {
+	now = now.UTC()
+	rows, err := s.pool.Query(ctx, `
+		UPDATE scheduled_executions
+		SET status = $1, lease_until = $5, updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM scheduled_executions
+			WHERE (status = $2 AND execute_at <= $3)
+			   OR (status = $1 AND (lease_until <= $3 OR (lease_until IS NULL AND updated_at <= $6)))
+			ORDER BY execute_at ASC
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED)
+		RETURNING `+scheduleColumns, types.ScheduleRunning, types.ScheduleScheduled, now, limit, now.Add(lease), now.Add(-lease))
+	if err != nil {
+		return nil, fmt.Errorf("evidence.ClaimDueSchedules: %w", err)
+	}
+	defer rows.Close()
+	var out []types.ScheduledExecution
+	for rows.Next() {
+		sched, err := scanSchedule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("evidence.ClaimDueSchedules scan: %w", err)
+		}
+		out = append(out, *sched)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("evidence.ClaimDueSchedules iteration: %w", err)
+	}
+	return out, nil
+}
+
+// FinishSchedule records the outcome of a claimed schedule: executed with
+// the execution's event, failed with the reason, or scheduled to put it back
+// for a later claim.
+func (s *Store) FinishSchedule(ctx context.Context, id, status, executionEventID, errMsg string) error {
+	_, err := s.pool.Exec(ctx, `
+		UPDATE scheduled_executions
+		SET status = $2, execution_event_id = NULLIF($3, ''), error_msg = $4, lease_until = NULL, updated_at = NOW()
+		WHERE id = $1`, id, status, executionEventID, errMsg)
+	if err != nil {
+		return fmt.Errorf("evidence.FinishSchedule: %w", err)
+	}
+	return nil
+}
+
+// ── SQLite / MySQL ──────────────────────────────────────────────────────────
+
+// CreateSchedule stores s as scheduled, setting its status and timestamps.
+func (s sqlEvents) CreateSchedule(ctx context.Context, sched *types.ScheduledExecution) error {
+	now := time.Now().UTC()
+	sched.Status, sched.CreatedAt, sched.UpdatedAt = types.ScheduleScheduled, now, now
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO scheduled_executions (id, parent_event_id, tenant_id, grant_id, execute_at, status, error_msg, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, '', ?, ?)`,
+		sched.ID, sched.EventID, sched.TenantID, sched.GrantID, sched.ExecuteAt.UTC(), sched.Status, now, now)
+	if err != nil {
+		return fmt.Errorf("evidence.CreateSchedule: %w", err)
+	}
+	return nil
+}
+
+// GetSchedule returns the latest schedule made for the approved event
+// parentEventID, or nil if it was never scheduled.
+func (s sqlEvents) GetSchedule(ctx context.Context, parentEventID string) (*types.ScheduledExecution, error) {
+	sched, err := scanSchedule(s.db.QueryRowContext(ctx, `
+		SELECT `+scheduleColumns+`
+		FROM scheduled_executions
+		WHERE parent_event_id = ?
+		ORDER BY created_at DESC LIMIT 1`, parentEventID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("evidence.GetSchedule: %w", err)
+	}
+	return sched, nil
+}
+
+// CancelSchedule cancels the event's pending schedule. It returns nil when
+// none is pending, including when the scheduler has already claimed it.
+func (s sqlEvents) CancelSchedule(ctx context.Context, parentEventID string) (*types.ScheduledExecution, error) {
+	sched, err := s.GetSchedule(ctx, parentEventID)
+	if err != nil || sched == nil || sched.Status != types.ScheduleScheduled {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	moved, err := s.moveSchedule(ctx, sched.ID, types.ScheduleScheduled, types.ScheduleCancelled, now)
+	if err != nil {
+		return nil, fmt.Errorf("evidence.CancelSchedule: %w", err)
+	}
+	if !moved {
+		return nil, nil
+	}
+	sched.Status, sched.UpdatedAt = types.ScheduleCancelled, now
+	return sched, nil
+}
+
+// ClaimDueSchedules moves up to limit schedules due by now, or running
+// with a lapsed lease, to running, leased until now+lease, and returns
+// them. Each row is claimed with an update that rechecks those conditions,
+// so concurrent schedulers claim disjoint sets.
+func (s sqlEvents) ClaimDueSchedules(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]types.ScheduledExecution, error) {
+	now = now.UTC()
+	args := []any{types.ScheduleScheduled, now, types.ScheduleRunning, now, now.Add(-lease)}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+scheduleColumns+`
+		FROM scheduled_executions
+		WHERE `+claimableSchedule+`
+		ORDER BY execute_at ASC
+		LIMIT ?`, append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("evidence.ClaimDueSchedules: %w", err)
+	}
+	var due []types.ScheduledExecution
+	for rows.Next() {
+		sched, err := scanSchedule(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("evidence.ClaimDueSchedules scan: %w", err)
+		}
+		due = append(due, *sched)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("evidence.ClaimDueSchedules iteration: %w", err)
+	}
+
+	var out []types.ScheduledExecution
+	for _, sched := range due {
+		updated := time.Now().UTC()
+		res, err := s.db.ExecContext(ctx, `
+			UPDATE scheduled_executions SET status = ?, lease_until = ?, updated_at = ?
+			WHERE id = ? AND (`+claimableSchedule+`)`,
+			append([]any{types.ScheduleRunning, now.Add(lease), updated, sched.ID}, args...)...)
+		if err != nil {
+			return nil, fmt.Errorf("evidence.ClaimDueSchedules: %w", err)
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return nil, fmt.Errorf("evidence.ClaimDueSchedules: %w", err)
+		} else if n == 1 {
+			sched.Status, sched.UpdatedAt = types.ScheduleRunning, updated
+			out = append(out, sched)
+		}
+	}
+	return out, nil
+}
+
+// claimableSchedule matches schedules ClaimDueSchedules may claim: those
+// due, and those running whose lease has lapsed or, for rows claimed
+// before leases were recorded, that have run for a lease. Its arguments
+// are the scheduled status, now, the running status, now again and now
+// less the lease.
+const claimableSchedule = `(status = ? AND execute_at <= ?)
+		OR (status = ? AND (lease_until <= ? OR (lease_until IS NULL AND updated_at <= ?)))`
+
+// FinishSchedule records the outcome of a claimed schedule: executed with
+// the execution's event, failed with the reason, or scheduled to put it back
+// for a later claim.
+func (s sqlEvents) FinishSchedule(ctx context.Context, id, status, executionEventID, errMsg string) error {
+	var execID any
+	if executionEventID != "" {
+		execID = executionEventID
+	}
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE scheduled_executions
+		SET status = ?, execution_event_id = ?, error_msg = ?, lease_until = NULL, updated_at = ?
+		WHERE id = ?`, status, execID, errMsg, time.Now().UTC(), id)
+	if err != nil {
+		return fmt.Errorf("evidence.FinishSchedule: %w", err)
+	}
+	return nil
+}
+
+// moveSchedule changes a schedule's status from one value to another and
+// reports whether it was still in from.
+func (s sqlEvents) moveSchedule(ctx context.Context, id, from, to string, at time.Time) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE scheduled_executions SET status = ?, updated_at = ?
+		WHERE id = ? AND status = ?`, to, at, id, from)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
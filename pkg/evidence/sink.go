@@ -0,0 +1,76 @@
+package evidence
+
+import (
+	"context"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+// Sink receives envelopes after they are committed to the hash chain.
+// Publish must not block for long; sink failures never fail the write.
+type Sink interface {
+	Publish(ctx context.Context, env *types.ToolCallEnvelope) error
+}
+
+// AuditEvent is the redacted form of an envelope exported to external
+// systems. Params, payloads, connector output, and caller network metadata
+// are deliberately omitted.
+type AuditEvent struct {
+	EventID         string         `json:"event_id"`
+	TenantID        string         `json:"tenant_id"`
+	AgentID         string         `json:"agent_id"`
+	Tool            string         `json:"tool"`
+	Action          string         `json:"action"`
+	Resource        string         `json:"resource,omitempty"`
+	RiskScore       int            `json:"risk_score"`
+	RiskFactors     []string       `json:"risk_factors,omitempty"`
+	Decision        types.Decision `json:"decision"`
+	Reason          string         `json:"reason,omitempty"`
+	ReasonCode      string         `json:"reason_code,omitempty"`
+	ExecutionStatus string         `json:"execution_status,omitempty"`
+	DurationMS      int64          `json:"duration_ms,omitempty"`
+	TraceID         string         `json:"trace_id,omitempty"`
+	ReceivedAt      time.Time      `json:"received_at"`
+	Hash            string         `json:"hash"`
+	PrevHash        string         `json:"prev_hash"`
+	// OriginalRiskScore is the agent's risk_score when policy adjusted it.
+	OriginalRiskScore *int `json:"original_risk_score,omitempty"`
+	// ChangeTicket is the key of the change ticket filed for the execution.
+	ChangeTicket string `json:"change_ticket,omitempty"`
+}
+
+// NewAuditEvent builds the redacted export form of env.
+func NewAuditEvent(env *types.ToolCallEnvelope) AuditEvent {
+	ev := AuditEvent{
+		EventID:     env.EventID,
+		TenantID:    env.Request.TenantID,
+		AgentID:     env.Request.AgentID,
+		Tool:        env.Request.Tool,
+		Action:      env.Request.Action,
+		Resource:    env.Request.Resource,
+		RiskScore:   env.RiskScore(),
+		RiskFactors: env.Request.RiskFactors,
+		Decision:    env.Decision,
+		TraceID:     env.Request.TraceID,
+		ReceivedAt:  env.ReceivedAt,
+		Hash:        env.Hash,
+		PrevHash:    env.PrevHash,
+	}
+	if env.AdjustedRiskScore != nil {
+		original := env.Request.RiskScore
+		ev.OriginalRiskScore = &original
+	}
+	if env.PolicyResult != nil {
+		ev.Reason = env.PolicyResult.Reason
+		ev.ReasonCode = env.PolicyResult.ReasonCode
+	}
+	if env.ExecutionResult != nil {
+		ev.ExecutionStatus = env.ExecutionResult.Status
+		ev.DurationMS = env.ExecutionResult.DurationMS
+		if t := env.ExecutionResult.ChangeTicket; t != nil {
+			ev.ChangeTicket = t.Key
+		}
+	}
+	return ev
+}
@@ -0,0 +1,255 @@
+package evidence
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+	_ "github.com/mattn/go-sqlite3" // registers the "sqlite3" driver (requires cgo)
+)
+
+// sqliteSchema mirrors the evidence tables from migrations/001_initial.sql.
+// Tenant foreign keys are omitted: edge deployments have no tenants table.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS tool_events (
+    event_seq       INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id        TEXT NOT NULL UNIQUE,
+    tenant_id       TEXT NOT NULL,
+    agent_id        TEXT NOT NULL,
+    tool            TEXT NOT NULL,
+    action          TEXT NOT NULL,
+    payload_json    BLOB NOT NULL,
+    payload_canon   BLOB NOT NULL,
+    risk_score      INTEGER NOT NULL DEFAULT 0 CHECK (risk_score >= 0 AND risk_score <= 10),
+    adjusted_risk_score INTEGER,
+    decision        TEXT NOT NULL CHECK (decision IN ('allow', 'deny', 'approve')),
+    policy_result   BLOB,
+    idempotency_key TEXT NOT NULL,
+    session_id      TEXT DEFAULT '',
+    user_id         TEXT DEFAULT '',
+    source_ip       TEXT DEFAULT '',
+    trace_id        TEXT DEFAULT '',
+    received_at     TIMESTAMP NOT NULL,
+    requested_at    TIMESTAMP NOT NULL,
+    hash            TEXT NOT NULL,
+    prev_hash       TEXT NOT NULL DEFAULT '',
+    canon_version   INTEGER NOT NULL DEFAULT 1
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tool_events_idempotency ON tool_events(tenant_id, idempotency_key);
+CREATE INDEX IF NOT EXISTS idx_tool_events_tenant_seq ON tool_events(tenant_id, event_seq);
+CREATE INDEX IF NOT EXISTS idx_tool_events_tenant_trace ON tool_events(tenant_id, trace_id, event_seq);
+CREATE INDEX IF NOT EXISTS idx_tool_events_tenant_hash ON tool_events(tenant_id, hash);
+
+CREATE TABLE IF NOT EXISTS tool_results (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id     TEXT NOT NULL UNIQUE REFERENCES tool_events(event_id),
+    tenant_id    TEXT NOT NULL,
+    status       TEXT NOT NULL CHECK (status IN ('success', 'error', 'timeout')),
+    output_json  BLOB,
+    error_msg    TEXT DEFAULT '',
+    duration_ms  INTEGER NOT NULL DEFAULT 0,
+    result_canon BLOB,
+    compensation_json BLOB,
+    cost         REAL,
+    connector_name     TEXT,
+    connector_version  TEXT,
+    connector_endpoint TEXT,
+    connector_backend  TEXT,
+    output_truncated   BOOLEAN,
+    output_bytes       INTEGER,
+    output_sha256      TEXT,
+    output_ref         TEXT,
+    quarantine_json    BLOB,
+    change_ticket_json BLOB,
+    schedule_json      BLOB,
+    created_at   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS tool_executions (
+    parent_event_id    TEXT PRIMARY KEY REFERENCES tool_events(event_id),
+    execution_event_id TEXT NOT NULL UNIQUE REFERENCES tool_events(event_id),
+    consumed_grant_id  TEXT,
+    created_at         TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS scheduled_executions (
+    id                 TEXT PRIMARY KEY,
+    parent_event_id    TEXT NOT NULL REFERENCES tool_events(event_id),
+    tenant_id          TEXT NOT NULL,
+    grant_id           TEXT NOT NULL,
+    execute_at         TIMESTAMP NOT NULL,
+    status             TEXT NOT NULL DEFAULT 'scheduled'
+                       CHECK (status IN ('scheduled', 'running', 'executed', 'cancelled', 'failed')),
+    execution_event_id TEXT,
+    error_msg          TEXT NOT NULL DEFAULT '',
+    lease_until        TIMESTAMP,
+    created_at         TIMESTAMP NOT NULL,
+    updated_at         TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_scheduled_executions_parent ON scheduled_executions(parent_event_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_scheduled_executions_due ON scheduled_executions(status, execute_at);
+
+CREATE TABLE IF NOT EXISTS evidence_archive_checkpoints (
+    tenant_id         TEXT PRIMARY KEY,
+    last_archived_at  TIMESTAMP NOT NULL,
+    last_hash         TEXT NOT NULL DEFAULT '',
+    last_event_seq    INTEGER NOT NULL DEFAULT 0,
+    updated_at        TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+`
+
+// sqliteUpgrades add columns to database files created before the column
+// was part of sqliteSchema. SQLite has no ADD COLUMN IF NOT EXISTS, so a
+// "duplicate column name" error means the file is already up to date.
+var sqliteUpgrades = []string{
+	`ALTER TABLE tool_results ADD COLUMN compensation_json BLOB`,
+	`ALTER TABLE tool_results ADD COLUMN cost REAL`,
+	`ALTER TABLE tool_events ADD COLUMN adjusted_risk_score INTEGER`,
+	`ALTER TABLE tool_events ADD COLUMN canon_version INTEGER NOT NULL DEFAULT 1`,
+	`ALTER TABLE tool_results ADD COLUMN connector_name TEXT`,
+	`ALTER TABLE tool_results ADD COLUMN connector_version TEXT`,
+	`ALTER TABLE tool_results ADD COLUMN connector_endpoint TEXT`,
+	`ALTER TABLE tool_results ADD COLUMN connector_backend TEXT`,
+	`ALTER TABLE tool_results ADD COLUMN output_truncated BOOLEAN`,
+	`ALTER TABLE tool_results ADD COLUMN output_bytes INTEGER`,
+	`ALTER TABLE tool_results ADD COLUMN output_sha256 TEXT`,
+	`ALTER TABLE tool_results ADD COLUMN output_ref TEXT`,
+	`ALTER TABLE tool_results ADD COLUMN quarantine_json BLOB`,
+	`ALTER TABLE tool_results ADD COLUMN change_ticket_json BLOB`,
+	`ALTER TABLE tool_results ADD COLUMN schedule_json BLOB`,
+	`ALTER TABLE scheduled_executions ADD COLUMN lease_until TIMESTAMP`,
+}
+
+// SQLiteStore persists the evidence log in a single SQLite file for
+// single-node and edge deployments. Writes use BEGIN IMMEDIATE, which takes
+// the database write lock up front and so serialises chain appends the same
+// way the Postgres advisory lock does.
+type SQLiteStore struct {
+	sqlEvents
+}
+
+// OpenSQLite opens (creating if needed) the SQLite database at path and
+// applies the evidence schema. The binary must be built with CGO_ENABLED=1.
+func OpenSQLite(ctx context.Context, path string) (*SQLiteStore, error) {
+	q := url.Values{}
+	q.Set("_busy_timeout", "5000")
+	q.Set("_journal_mode", "WAL")
+	q.Set("_txlock", "immediate")
+	q.Set("_foreign_keys", "on")
+	db, err := sql.Open("sqlite3", "file:"+path+"?"+q.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("evidence.OpenSQLite: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, sqliteSchema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("evidence.OpenSQLite schema: %w", err)
+	}
+	for _, stmt := range sqliteUpgrades {
+		if _, err := db.ExecContext(ctx, stmt); err != nil && !strings.Contains(err.Error(), "duplicate column name") {
+			_ = db.Close()
+			return nil, fmt.Errorf("evidence.OpenSQLite upgrade: %w", err)
+		}
+	}
+	return &SQLiteStore{sqlEvents{db: db, canon: CanonLegacy}}, nil
+}
+
+// RecordEvent appends env to the tenant's hash chain and stores its result.
+func (s *SQLiteStore) RecordEvent(ctx context.Context, env *types.ToolCallEnvelope) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("evidence.RecordEvent begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // rollback after commit is a no-op
+
+	appended, err := s.appendEvent(ctx, tx, env)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("evidence.RecordEvent commit: %w", err)
+	}
+	appended.apply(env)
+	return nil
+}
+
+// LinkExecutionToParent stores the append-only parent→execution relation.
+// Returns false when another request already linked the parent.
+func (s *SQLiteStore) LinkExecutionToParent(ctx context.Context, parentEventID, executionEventID, consumedGrantID string) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO tool_executions(parent_event_id, execution_event_id, consumed_grant_id)
+		VALUES (?, ?, ?)
+		ON CONFLICT(parent_event_id) DO NOTHING`, parentEventID, executionEventID, consumedGrantID)
+	if err != nil {
+		return false, fmt.Errorf("evidence.LinkExecutionToParent: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("evidence.LinkExecutionToParent rows: %w", err)
+	}
+	return n == 1, nil
+}
+
+// ListTenantIDs returns every tenant with recorded events. SQLite databases
+// have no tenants table, so the evidence log itself is the source.
+func (s *SQLiteStore) ListTenantIDs(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT DISTINCT tenant_id FROM tool_events ORDER BY tenant_id ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("evidence.ListTenantIDs: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("evidence.ListTenantIDs scan: %w", err)
+		}
+		out = append(out, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("evidence.ListTenantIDs iteration: %w", err)
+	}
+	return out, nil
+}
+
+// GetArchiveCheckpoint returns archival position for a tenant.
+func (s *SQLiteStore) GetArchiveCheckpoint(ctx context.Context, tenantID string) (time.Time, string, int64, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT last_archived_at, last_hash, last_event_seq
+		FROM evidence_archive_checkpoints
+		WHERE tenant_id = ?`, tenantID)
+	var ts time.Time
+	var h string
+	var seq int64
+	err := row.Scan(&ts, &h, &seq)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Unix(0, 0).UTC(), "", 0, nil
+	}
+	if err != nil {
+		return time.Time{}, "", 0, fmt.Errorf("evidence.GetArchiveCheckpoint: %w", err)
+	}
+	return ts.UTC(), h, seq, nil
+}
+
+// UpsertArchiveCheckpoint advances archival position after successful upload.
+func (s *SQLiteStore) UpsertArchiveCheckpoint(ctx context.Context, tenantID string, archivedAt time.Time, hash string, seq int64) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO evidence_archive_checkpoints(tenant_id, last_archived_at, last_hash, last_event_seq, updated_at)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT (tenant_id) DO UPDATE
+		SET last_archived_at = excluded.last_archived_at,
+		    last_hash = excluded.last_hash,
+		    last_event_seq = excluded.last_event_seq,
+		    updated_at = CURRENT_TIMESTAMP`,
+		tenantID, archivedAt.UTC(), hash, seq,
+	)
+	if err != nil {
+		return fmt.Errorf("evidence.UpsertArchiveCheckpoint: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,488 @@
+//go:build cgo
+
+package evidence
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+func openTestSQLite(t *testing.T) *SQLiteStore {
+	t.Helper()
+	s, err := OpenSQLite(context.Background(), filepath.Join(t.TempDir(), "evidence.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func sqliteEnvelope(eventID, key string, result *types.ExecutionResult) *types.ToolCallEnvelope {
+	req := types.ToolCallRequest{
+		TenantID: "t1", AgentID: "a1", Tool: "slack", Action: "msg.post",
+		Params: json.RawMessage(`{"channel":"#ops"}`), Resource: "C1",
+		IdempotencyKey: key, RequestedAt: time.Now().UTC(),
+	}
+	payload, _ := json.Marshal(req)
+	return &types.ToolCallEnvelope{
+		EventID: eventID, Request: req, PayloadJSON: payload,
+		ReceivedAt:      time.Now().UTC(),
+		Decision:        types.DecisionAllow,
+		PolicyResult:    &types.PolicyResult{Decision: types.DecisionAllow, Reason: "ok"},
+		ExecutionResult: result,
+	}
+}
+
+func TestSQLiteStore_ConcurrentAppendsKeepChainValid(t *testing.T) {
+	s := openTestSQLite(t)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res := &types.ExecutionResult{Status: "success", OutputJSON: json.RawMessage(`{"ok":true}`)}
+			errs <- s.RecordEvent(ctx, sqliteEnvelope(fmt.Sprintf("e%d", i), fmt.Sprintf("k%d", i), res))
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	events, err := s.GetChainEvents(ctx, "t1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 20 {
+		t.Fatalf("expected 20 events, got %d", len(events))
+	}
+	if err := VerifyChain(events); err != nil {
+		t.Fatalf("chain invalid: %v", err)
+	}
+}
+
+func TestSQLiteStore_CanonVersion(t *testing.T) {
+	s := openTestSQLite(t)
+	ctx := context.Background()
+
+	legacy := sqliteEnvelope("e1", "k1", nil)
+	if err := s.RecordEvent(ctx, legacy); err != nil {
+		t.Fatal(err)
+	}
+	s.SetCanonVersion(CanonJCS)
+	jcs := sqliteEnvelope("e2", "k2", &types.ExecutionResult{Status: "success", OutputJSON: json.RawMessage(`{"n":1.50}`)})
+	if err := s.RecordEvent(ctx, jcs); err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := CanonicalJSONVersion(jcs.Request, CanonJCS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.GetEvent(ctx, "e2")
+	if err != nil || got.CanonVersion != int(CanonJCS) || string(got.PayloadCanon) != string(want) {
+		t.Fatalf("jcs event: version %d canon %s, %v", got.CanonVersion, got.PayloadCanon, err)
+	}
+	if got, err := s.GetEvent(ctx, "e1"); err != nil || got.CanonVersion != int(CanonLegacy) {
+		t.Fatalf("legacy event version = %d, %v", got.CanonVersion, err)
+	}
+
+	// Versions can change mid-chain: links hash the stored bytes.
+	events, err := s.GetChainEvents(ctx, "t1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyChain(events); err != nil {
+		t.Fatalf("chain invalid: %v", err)
+	}
+}
+
+func TestSQLiteStore_IdempotencyAndRoundTrip(t *testing.T) {
+	s := openTestSQLite(t)
+	ctx := context.Background()
+
+	connector := &types.ConnectorInfo{Name: "connector-jira", Version: "4f2a9c1", Endpoint: "https://acme.atlassian.net", Backend: "http://jira:8083"}
+	env := sqliteEnvelope("e1", "k1", &types.ExecutionResult{Status: "error", Error: "boom", DurationMS: 12, Connector: connector})
+	adjusted := 7
+	env.AdjustedRiskScore = &adjusted
+	if err := s.RecordEvent(ctx, env); err != nil {
+		t.Fatal(err)
+	}
+	if env.EventSeq != 1 {
+		t.Errorf("EventSeq = %d, want 1", env.EventSeq)
+	}
+	if err := s.RecordEvent(ctx, sqliteEnvelope("e2", "k1", nil)); err == nil {
+		t.Fatal("expected unique violation for duplicate idempotency key")
+	}
+
+	prior, err := s.CheckIdempotency(ctx, "t1", "k1")
+	if err != nil || prior == nil || prior.EventID != "e1" {
+		t.Fatalf("idempotency lookup: %+v, %v", prior, err)
+	}
+	if miss, err := s.CheckIdempotency(ctx, "t1", "nope"); err != nil || miss != nil {
+		t.Fatalf("expected miss, got %+v, %v", miss, err)
+	}
+
+	got, err := s.GetEvent(ctx, "e1")
+	if err != nil || got == nil {
+		t.Fatalf("GetEvent: %+v, %v", got, err)
+	}
+	if got.Request.Resource != "C1" || string(got.Request.Params) != `{"channel":"#ops"}` {
+		t.Fatalf("request not rebuilt from payload: %+v", got.Request)
+	}
+	if got.Hash != env.Hash || got.PolicyResult == nil || got.PolicyResult.Reason != "ok" {
+		t.Fatalf("unexpected envelope: %+v", got)
+	}
+	if got.AdjustedRiskScore == nil || *got.AdjustedRiskScore != 7 || got.RiskScore() != 7 {
+		t.Fatalf("adjusted risk score not round-tripped: %v", got.AdjustedRiskScore)
+	}
+	if got.ExecutionResult == nil || got.ExecutionResult.Error != "boom" || got.ExecutionResult.DurationMS != 12 {
+		t.Fatalf("unexpected result: %+v", got.ExecutionResult)
+	}
+	if c := got.ExecutionResult.Connector; c == nil || *c != *connector {
+		t.Fatalf("connector not round-tripped: %+v", c)
+	}
+	if got.ExecutionResult.Truncation != nil {
+		t.Fatalf("untruncated output read back as truncated: %+v", got.ExecutionResult.Truncation)
+	}
+
+	truncation := &types.OutputTruncation{OriginalBytes: 9000, SHA256: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08", Ref: "outputs/t1/abab"}
+	if err := s.RecordEvent(ctx, sqliteEnvelope("e3", "k3", &types.ExecutionResult{
+		Status: "success", OutputJSON: json.RawMessage(`{"truncated":true}`), Truncation: truncation,
+		Quarantine:   &types.Quarantine{Reason: "connector flagged output", Flags: []string{"dlp:credit_card"}},
+		ChangeTicket: &types.ChangeTicket{Project: "CHG", Key: "CHG-7"},
+	})); err != nil {
+		t.Fatal(err)
+	}
+	got, err = s.GetEvent(ctx, "e3")
+	if err != nil || got.ExecutionResult.Truncation == nil || *got.ExecutionResult.Truncation != *truncation {
+		t.Fatalf("truncation not round-tripped: %+v, %v", got, err)
+	}
+	if q := got.ExecutionResult.Quarantine; q == nil || q.Reason != "connector flagged output" || len(q.Flags) != 1 || string(got.ExecutionResult.OutputJSON) != `{"truncated":true}` {
+		t.Fatalf("quarantine not round-tripped: %+v", got.ExecutionResult)
+	}
+	if c := got.ExecutionResult.ChangeTicket; c == nil || *c != (types.ChangeTicket{Project: "CHG", Key: "CHG-7"}) {
+		t.Fatalf("change ticket not round-tripped: %+v", c)
+	}
+	if missing, err := s.GetEvent(ctx, "nope"); err != nil || missing != nil {
+		t.Fatalf("expected nil for missing event, got %+v, %v", missing, err)
+	}
+}
+
+func TestSQLiteStore_ListTraceEvents(t *testing.T) {
+	s := openTestSQLite(t)
+	ctx := context.Background()
+
+	for i, trace := range []string{"tr1", "tr2", "tr1", "tr1"} {
+		env := sqliteEnvelope(fmt.Sprintf("e%d", i), fmt.Sprintf("k%d", i), nil)
+		env.Request.TraceID = trace
+		env.Request.ParentEventID = "e0"
+		env.PayloadJSON, _ = json.Marshal(env.Request)
+		if err := s.RecordEvent(ctx, env); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	events, err := s.ListTraceEvents(ctx, "t1", "tr1", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].EventID != "e0" || events[1].EventID != "e2" {
+		t.Fatalf("unexpected trace events: %+v", events)
+	}
+	if events[1].Request.TraceID != "tr1" || events[1].Request.ParentEventID != "e0" {
+		t.Fatalf("request not rebuilt: %+v", events[1].Request)
+	}
+	if other, err := s.ListTraceEvents(ctx, "t2", "tr1", 10); err != nil || len(other) != 0 {
+		t.Fatalf("expected no events for another tenant, got %+v, %v", other, err)
+	}
+}
+
+func TestSQLiteStore_ListEvents(t *testing.T) {
+	s := openTestSQLite(t)
+	ctx := context.Background()
+
+	for i, agent := range []string{"a1", "a2", "a1", "a1"} {
+		env := sqliteEnvelope(fmt.Sprintf("e%d", i), fmt.Sprintf("k%d", i), nil)
+		env.Request.AgentID = agent
+		if i == 3 {
+			env.Decision = types.DecisionDeny
+		}
+		if err := s.RecordEvent(ctx, env); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	page, err := s.ListEvents(ctx, "t1", EventFilter{AgentID: "a1", Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 1 || page[0].EventID != "e0" || page[0].EventSeq == 0 {
+		t.Fatalf("unexpected first page: %+v", page)
+	}
+	page, err = s.ListEvents(ctx, "t1", EventFilter{AgentID: "a1", AfterSeq: page[0].EventSeq})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 2 || page[0].EventID != "e2" || page[1].EventID != "e3" {
+		t.Fatalf("unexpected second page: %+v", page)
+	}
+	newest, err := s.ListEvents(ctx, "t1", EventFilter{AgentID: "a1", Limit: 2, Newest: true})
+	if err != nil || len(newest) != 2 || newest[0].EventID != "e3" || newest[1].EventID != "e2" {
+		t.Fatalf("unexpected newest page: %+v, %v", newest, err)
+	}
+	denied, err := s.ListEvents(ctx, "t1", EventFilter{Decision: types.DecisionDeny, Tool: "slack"})
+	if err != nil || len(denied) != 1 || denied[0].EventID != "e3" {
+		t.Fatalf("unexpected decision filter result: %+v, %v", denied, err)
+	}
+	if other, err := s.ListEvents(ctx, "t2", EventFilter{}); err != nil || len(other) != 0 {
+		t.Fatalf("expected no events for another tenant, got %+v, %v", other, err)
+	}
+}
+
+func TestSQLiteStore_LinkExecutionOnce(t *testing.T) {
+	s := openTestSQLite(t)
+	ctx := context.Background()
+
+	parent := sqliteEnvelope("p1", "k1", nil)
+	parent.Decision = types.DecisionApprove
+	undo := &types.Compensation{Action: "msg.delete", Params: json.RawMessage(`{"channel":"C1","ts":"1.2"}`)}
+	for _, env := range []*types.ToolCallEnvelope{
+		parent,
+		sqliteEnvelope("x1", "exec:p1", &types.ExecutionResult{Status: "success", Compensation: undo}),
+		sqliteEnvelope("x2", "exec:p1:dup", &types.ExecutionResult{Status: "success"}),
+	} {
+		if err := s.RecordEvent(ctx, env); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	linked, err := s.LinkExecutionToParent(ctx, "p1", "x1", "g1")
+	if err != nil || !linked {
+		t.Fatalf("first link: %v, %v", linked, err)
+	}
+	linked, err = s.LinkExecutionToParent(ctx, "p1", "x2", "g1")
+	if err != nil || linked {
+		t.Fatalf("second link should lose the race: %v, %v", linked, err)
+	}
+
+	resp, err := s.GetExecutionByParentEvent(ctx, "p1")
+	if err != nil || resp == nil || resp.EventID != "x1" || resp.Result == nil || resp.Result.Status != "success" {
+		t.Fatalf("unexpected replay: %+v, %v", resp, err)
+	}
+	if c := resp.Result.Compensation; c == nil || c.Action != "msg.delete" || string(c.Params) != string(undo.Params) {
+		t.Fatalf("compensation = %+v, want %+v", c, undo)
+	}
+}
+
+func TestSQLiteStore_Schedules(t *testing.T) {
+	s := openTestSQLite(t)
+	ctx := context.Background()
+	parent := sqliteEnvelope("p1", "k1", nil)
+	parent.Decision = types.DecisionApprove
+	if err := s.RecordEvent(ctx, parent); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.GetSchedule(ctx, "p1"); err != nil || got != nil {
+		t.Fatalf("unscheduled event: %+v, %v", got, err)
+	}
+
+	at := time.Now().Add(time.Hour).UTC().Truncate(time.Microsecond)
+	first := &types.ScheduledExecution{ID: "s1", EventID: "p1", TenantID: "t1", GrantID: "g1", ExecuteAt: at}
+	if err := s.CreateSchedule(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+	if cancelled, err := s.CancelSchedule(ctx, "p1"); err != nil || cancelled == nil || cancelled.Status != types.ScheduleCancelled {
+		t.Fatalf("cancel: %+v, %v", cancelled, err)
+	}
+	if again, err := s.CancelSchedule(ctx, "p1"); err != nil || again != nil {
+		t.Fatalf("second cancel: %+v, %v", again, err)
+	}
+
+	time.Sleep(time.Millisecond) // the latest schedule is the newest created_at
+	second := &types.ScheduledExecution{ID: "s2", EventID: "p1", TenantID: "t1", GrantID: "g2", ExecuteAt: at}
+	if err := s.CreateSchedule(ctx, second); err != nil {
+		t.Fatal(err)
+	}
+	if due, err := s.ClaimDueSchedules(ctx, time.Now(), time.Minute, 10); err != nil || len(due) != 0 {
+		t.Fatalf("claimed before execute_at: %+v, %v", due, err)
+	}
+	due, err := s.ClaimDueSchedules(ctx, at, time.Minute, 10)
+	if err != nil || len(due) != 1 || due[0].ID != "s2" || due[0].Status != types.ScheduleRunning || !due[0].ExecuteAt.Equal(at) {
+		t.Fatalf("claim: %+v, %v", due, err)
+	}
+	if again, err := s.ClaimDueSchedules(ctx, at, time.Minute, 10); err != nil || len(again) != 0 {
+		t.Fatalf("claimed twice: %+v, %v", again, err)
+	}
+	// A scheduler that stopped mid-run leaves the schedule to be claimed
+	// again once its lease lapses.
+	reclaimed, err := s.ClaimDueSchedules(ctx, at.Add(2*time.Minute), time.Minute, 10)
+	if err != nil || len(reclaimed) != 1 || reclaimed[0].ID != "s2" {
+		t.Fatalf("reclaim after lease: %+v, %v", reclaimed, err)
+	}
+	if cancelled, err := s.CancelSchedule(ctx, "p1"); err != nil || cancelled != nil {
+		t.Fatalf("cancelled a running schedule: %+v, %v", cancelled, err)
+	}
+
+	sched := &types.ExecutionSchedule{ID: "s2", ScheduledAt: second.CreatedAt, ExecuteAt: at}
+	if err := s.RecordEvent(ctx, sqliteEnvelope("x1", "exec:p1", &types.ExecutionResult{Status: "success", Schedule: sched})); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.FinishSchedule(ctx, "s2", types.ScheduleExecuted, "x1", ""); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.GetSchedule(ctx, "p1")
+	if err != nil || got.ID != "s2" || got.Status != types.ScheduleExecuted || got.ExecutionEventID != "x1" || got.GrantID != "g2" {
+		t.Fatalf("finished schedule: %+v, %v", got, err)
+	}
+	env, err := s.GetEvent(ctx, "x1")
+	if err != nil || env.ExecutionResult.Schedule == nil || env.ExecutionResult.Schedule.ID != "s2" || !env.ExecutionResult.Schedule.ExecuteAt.Equal(at) {
+		t.Fatalf("schedule not round-tripped: %+v, %v", env.ExecutionResult, err)
+	}
+}
+
+func TestOpenSQLite_ReopenAppliesUpgradesOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "evidence.db")
+	for range 2 {
+		s, err := OpenSQLite(context.Background(), path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = s.Close()
+	}
+}
+
+func TestSQLiteStore_ArchiveCheckpoint(t *testing.T) {
+	s := openTestSQLite(t)
+	ctx := context.Background()
+
+	if err := s.RecordEvent(ctx, sqliteEnvelope("e1", "k1", nil)); err != nil {
+		t.Fatal(err)
+	}
+	ids, err := s.ListTenantIDs(ctx)
+	if err != nil || len(ids) != 1 || ids[0] != "t1" {
+		t.Fatalf("tenants = %v, %v", ids, err)
+	}
+
+	ts, h, seq, err := s.GetArchiveCheckpoint(ctx, "t1")
+	if err != nil || !ts.Equal(time.Unix(0, 0)) || h != "" || seq != 0 {
+		t.Fatalf("initial checkpoint = %v %q %d, %v", ts, h, seq, err)
+	}
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, hash := range []string{"h1", "h2"} {
+		if err := s.UpsertArchiveCheckpoint(ctx, "t1", at, hash, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ts, h, seq, err = s.GetArchiveCheckpoint(ctx, "t1")
+	if err != nil || !ts.Equal(at) || h != "h2" || seq != 1 {
+		t.Fatalf("checkpoint = %v %q %d, %v", ts, h, seq, err)
+	}
+}
+
+func TestSQLiteStore_Replication(t *testing.T) {
+	primary, standby := openTestSQLite(t), openTestSQLite(t)
+	ctx := context.Background()
+
+	primary.SetCanonVersion(CanonJCS)
+	for i := range 3 {
+		var res *types.ExecutionResult
+		if i == 1 {
+			res = &types.ExecutionResult{Status: "success", OutputJSON: json.RawMessage(`{"n":1.50}`)}
+		}
+		if err := primary.RecordEvent(ctx, sqliteEnvelope(fmt.Sprintf("e%d", i), fmt.Sprintf("k%d", i), res)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Events travel as JSON; the standby resumes after its own head.
+	head := ""
+	for {
+		page, pending, err := primary.ReplicationEvents(ctx, "t1", head, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		if head == "" && pending != 3 {
+			t.Fatalf("pending = %d, want 3", pending)
+		}
+		for _, ev := range page {
+			b, _ := json.Marshal(ev)
+			var wire ReplicatedEvent
+			if err := json.Unmarshal(b, &wire); err != nil {
+				t.Fatal(err)
+			}
+			if err := standby.ApplyReplicated(ctx, &wire); err != nil {
+				t.Fatal(err)
+			}
+			head = wire.Hash
+		}
+	}
+
+	want, _ := primary.ChainHeads(ctx)
+	got, err := standby.ChainHeads(ctx)
+	if err != nil || len(got) != 1 || got[0].Hash != want[0].Hash {
+		t.Fatalf("standby heads = %+v, %v; primary %+v", got, err, want)
+	}
+	events, err := standby.GetChainEvents(ctx, "t1", 0)
+	if err != nil || len(events) != 3 {
+		t.Fatalf("standby chain = %d events, %v", len(events), err)
+	}
+	if err := VerifyChain(events); err != nil {
+		t.Fatalf("standby chain invalid: %v", err)
+	}
+	if ev, err := standby.GetEvent(ctx, "e1"); err != nil || ev.CanonVersion != int(CanonJCS) || string(ev.ExecutionResult.OutputJSON) != `{"n":1.50}` {
+		t.Fatalf("replicated event = %+v, %v", ev, err)
+	}
+	if _, _, err := primary.ReplicationEvents(ctx, "t1", "feed", 2); !errors.Is(err, ErrUnknownChainHash) {
+		t.Fatalf("unknown hash: err = %v", err)
+	}
+
+	// A new event applies only once, on the head it was chained to, and
+	// only if its hash verifies.
+	if err := primary.RecordEvent(ctx, sqliteEnvelope("e3", "k3", &types.ExecutionResult{Status: "success"})); err != nil {
+		t.Fatal(err)
+	}
+	page, _, err := primary.ReplicationEvents(ctx, "t1", head, 10)
+	if err != nil || len(page) != 1 {
+		t.Fatalf("page = %+v, %v", page, err)
+	}
+	forged := page[0]
+	forged.PayloadCanon = []byte(`{"tenant_id":"t1"}`)
+	if err := standby.ApplyReplicated(ctx, &forged); !errors.Is(err, ErrChainMismatch) {
+		t.Fatalf("forged event: err = %v", err)
+	}
+	// The hash verifies but the request or result beside it was altered.
+	forged = page[0]
+	forged.Request.Tool = "payments"
+	if err := standby.ApplyReplicated(ctx, &forged); !errors.Is(err, ErrChainMismatch) {
+		t.Fatalf("forged request: err = %v", err)
+	}
+	forged = page[0]
+	forged.ExecutionResult = &types.ExecutionResult{Status: "error"}
+	if err := standby.ApplyReplicated(ctx, &forged); !errors.Is(err, ErrChainMismatch) {
+		t.Fatalf("forged result: err = %v", err)
+	}
+	if err := standby.ApplyReplicated(ctx, &page[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := standby.ApplyReplicated(ctx, &page[0]); !errors.Is(err, ErrChainMismatch) {
+		t.Fatalf("replayed event: err = %v", err)
+	}
+}
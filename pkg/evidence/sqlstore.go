@@ -0,0 +1,445 @@
+package evidence
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+// sqlEvents holds the database/sql queries shared by the SQLite and MySQL
+// backends. Both drivers take "?" placeholders and accept the same SQL for
+// everything except chain locking and the execution-link upsert.
+type sqlEvents struct {
+	db    *sql.DB
+	canon CanonVersion
+}
+
+// SetCanonVersion selects the canonical JSON form of events recorded from
+// now on. Events already in the chain keep theirs.
+func (s *sqlEvents) SetCanonVersion(v CanonVersion) {
+	s.canon = v
+}
+
+// Close releases the underlying database handle.
+func (s sqlEvents) Close() error {
+	return s.db.Close()
+}
+
+// chainAppend is the hash-chain state written by appendEvent; callers copy
+// it onto the envelope once the transaction commits.
+type chainAppend struct {
+	hash         string
+	prevHash     string
+	seq          int64
+	canon        []byte
+	canonVersion CanonVersion
+}
+
+// jsonArg binds JSON as text (NULL when empty): MySQL rejects binary-charset
+// values for JSON columns, and SQLite stores either form.
+func jsonArg(b []byte) any {
+	if len(b) == 0 {
+		return nil
+	}
+	return string(b)
+}
+
+func (c chainAppend) apply(env *types.ToolCallEnvelope) {
+	env.Hash = c.hash
+	env.PrevHash = c.prevHash
+	env.EventSeq = c.seq
+	env.PayloadCanon = c.canon
+	env.CanonVersion = int(c.canonVersion)
+}
+
+// appendEvent inserts env (and its result) inside tx, chaining from the
+// tenant's latest hash. The caller must already hold the tenant's chain lock.
+func (s sqlEvents) appendEvent(ctx context.Context, tx *sql.Tx, env *types.ToolCallEnvelope) (chainAppend, error) {
+	prevHash, err := lastSQLHash(ctx, tx, env.Request.TenantID)
+	if err != nil {
+		return chainAppend{}, fmt.Errorf("evidence.RecordEvent last hash: %w", err)
+	}
+
+	canonPayload, err := CanonicalJSONVersion(env.Request, s.canon)
+	if err != nil {
+		return chainAppend{}, fmt.Errorf("evidence.RecordEvent canonical: %w", err)
+	}
+	var canonResult []byte
+	if env.ExecutionResult != nil {
+		canonResult, err = CanonicalJSONVersion(env.ExecutionResult, s.canon)
+		if err != nil {
+			return chainAppend{}, fmt.Errorf("evidence.RecordEvent canonical result: %w", err)
+		}
+	}
+	hash := ChainHash(prevHash, canonPayload, canonResult)
+
+	c := chainAppend{hash: hash, prevHash: prevHash, canon: canonPayload, canonVersion: s.canon}
+	if c.seq, err = insertSQLEvent(ctx, tx, env, c, canonResult); err != nil {
+		return chainAppend{}, err
+	}
+	return c, nil
+}
+
+// lastSQLHash returns the hash at the head of the tenant's chain, "" for an
+// empty chain.
+func lastSQLHash(ctx context.Context, tx *sql.Tx, tenantID string) (string, error) {
+	var h string
+	err := tx.QueryRowContext(ctx, `
+		SELECT hash FROM tool_events
+		WHERE tenant_id = ?
+		ORDER BY event_seq DESC LIMIT 1`, tenantID).Scan(&h)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", err
+	}
+	return h, nil
+}
+
+// insertSQLEvent writes env's tool_events row, chained as c, and its
+// tool_results row with the canonical result bytes.
+func insertSQLEvent(ctx context.Context, tx *sql.Tx, env *types.ToolCallEnvelope, c chainAppend, canonResult []byte) (int64, error) {
+	policyJSON, err := json.Marshal(env.PolicyResult)
+	if err != nil {
+		return 0, fmt.Errorf("evidence.RecordEvent marshal policy: %w", err)
+	}
+
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO tool_events (
+			event_id, tenant_id, agent_id, tool, action,
+			payload_json, payload_canon,
+			risk_score, adjusted_risk_score, decision, policy_result,
+			idempotency_key, session_id, user_id, source_ip, trace_id,
+			received_at, requested_at,
+			hash, prev_hash, canon_version
+		) VALUES (?,?,?,?,?, ?,?, ?,?,?,?, ?,?,?,?,?, ?,?, ?,?,?)`,
+		env.EventID, env.Request.TenantID, env.Request.AgentID,
+		env.Request.Tool, env.Request.Action,
+		jsonArg(env.PayloadJSON), c.canon,
+		env.Request.RiskScore, env.AdjustedRiskScore, string(env.Decision), jsonArg(policyJSON),
+		env.Request.IdempotencyKey, env.Request.SessionID, env.Request.UserID,
+		env.Request.SourceIP, env.Request.TraceID,
+		env.ReceivedAt.UTC(), env.Request.RequestedAt.UTC(),
+		c.hash, c.prevHash, int(c.canonVersion),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("evidence.RecordEvent insert event: %w", err)
+	}
+	// event_seq is the auto-increment key in both schemas.
+	seq, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("evidence.RecordEvent event seq: %w", err)
+	}
+
+	if env.ExecutionResult != nil {
+		compensation, err := compensationJSON(env.ExecutionResult.Compensation)
+		if err != nil {
+			return 0, fmt.Errorf("evidence.RecordEvent marshal compensation: %w", err)
+		}
+		quarantine, err := quarantineJSON(env.ExecutionResult.Quarantine)
+		if err != nil {
+			return 0, fmt.Errorf("evidence.RecordEvent marshal quarantine: %w", err)
+		}
+		changeTicket, err := changeTicketJSON(env.ExecutionResult.ChangeTicket)
+		if err != nil {
+			return 0, fmt.Errorf("evidence.RecordEvent marshal change ticket: %w", err)
+		}
+		schedule, err := scheduleJSON(env.ExecutionResult.Schedule)
+		if err != nil {
+			return 0, fmt.Errorf("evidence.RecordEvent marshal schedule: %w", err)
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO tool_results (event_id, tenant_id, status, output_json, error_msg, duration_ms, result_canon, compensation_json, cost, quarantine_json, change_ticket_json, schedule_json,
+				connector_name, connector_version, connector_endpoint, connector_backend,
+				output_truncated, output_bytes, output_sha256, output_ref)
+			VALUES (?,?,?,?,?,?,?,?,?,?,?,?, ?,?,?,?, ?,?,?,?)`,
+			append([]any{env.EventID, env.Request.TenantID,
+				env.ExecutionResult.Status, jsonArg(env.ExecutionResult.OutputJSON),
+				env.ExecutionResult.Error, env.ExecutionResult.DurationMS, canonResult,
+				jsonArg(compensation), env.ExecutionResult.Cost, jsonArg(quarantine), jsonArg(changeTicket), jsonArg(schedule),
+			}, append(connectorArgs(env.ExecutionResult.Connector),
+				truncationArgs(env.ExecutionResult.Truncation)...)...)...,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("evidence.RecordEvent insert result: %w", err)
+		}
+	}
+
+	return seq, nil
+}
+
+// CheckIdempotency returns a prior response if one exists for (tenant, key).
+func (s sqlEvents) CheckIdempotency(ctx context.Context, tenantID, idempotencyKey string) (*types.ToolCallResponse, error) {
+	var eventID, decision string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT event_id, decision FROM tool_events
+		WHERE tenant_id = ? AND idempotency_key = ?
+		LIMIT 1`, tenantID, idempotencyKey).Scan(&eventID, &decision)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("evidence.CheckIdempotency: %w", err)
+	}
+	return &types.ToolCallResponse{
+		EventID:  eventID,
+		Decision: types.Decision(decision),
+		Reason:   "idempotent replay",
+	}, nil
+}
+
+// GetEvent retrieves a single event by ID.
+func (s sqlEvents) GetEvent(ctx context.Context, eventID string) (*types.ToolCallEnvelope, error) {
+	env, err := scanSQLEvent(s.db.QueryRowContext(ctx, `
+		SELECT `+eventColumns+`
+		FROM tool_events e
+		LEFT JOIN tool_results r ON r.event_id = e.event_id
+		WHERE e.event_id = ?`, eventID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("evidence.GetEvent: %w", err)
+	}
+	return env, nil
+}
+
+// ListTraceEvents returns up to limit of the tenant's events recorded under
+// traceID, in insertion order.
+func (s sqlEvents) ListTraceEvents(ctx context.Context, tenantID, traceID string, limit int) ([]types.ToolCallEnvelope, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+eventColumns+`
+		FROM tool_events e
+		LEFT JOIN tool_results r ON r.event_id = e.event_id
+		WHERE e.tenant_id = ? AND e.trace_id = ?
+		ORDER BY e.event_seq ASC
+		LIMIT ?`, tenantID, traceID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("evidence.ListTraceEvents: %w", err)
+	}
+	defer rows.Close()
+
+	var events []types.ToolCallEnvelope
+	for rows.Next() {
+		env, err := scanSQLEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("evidence.ListTraceEvents: %w", err)
+		}
+		events = append(events, *env)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("evidence.ListTraceEvents iteration: %w", err)
+	}
+	return events, nil
+}
+
+// ListEvents returns up to f.Limit of the tenant's events matching f, in
+// insertion order (reversed for f.Newest), starting after f.AfterSeq.
+func (s sqlEvents) ListEvents(ctx context.Context, tenantID string, f EventFilter) ([]types.ToolCallEnvelope, error) {
+	where, args := f.where(tenantID, func(int) string { return "?" })
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+eventColumns+`
+		FROM tool_events e
+		LEFT JOIN tool_results r ON r.event_id = e.event_id
+		WHERE `+where+`
+		ORDER BY e.event_seq `+f.order()+`
+		LIMIT ?`, append(args, f.limit())...)
+	if err != nil {
+		return nil, fmt.Errorf("evidence.ListEvents: %w", err)
+	}
+	defer rows.Close()
+
+	var events []types.ToolCallEnvelope
+	for rows.Next() {
+		env, err := scanSQLEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("evidence.ListEvents: %w", err)
+		}
+		events = append(events, *env)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("evidence.ListEvents iteration: %w", err)
+	}
+	return events, nil
+}
+
+// scanSQLEvent reads one row of eventColumns from *sql.Row or *sql.Rows.
+func scanSQLEvent(row interface{ Scan(...any) error }) (*types.ToolCallEnvelope, error) {
+	var env types.ToolCallEnvelope
+	var tenantID, agentID, tool, action string
+	var riskScore int
+	var adjustedRiskScore sql.NullInt64
+	var idempotencyKey, sessionID, userID, sourceIP, traceID sql.NullString
+	var requestedAt time.Time
+	var payloadJSON, policyJSON, resultOutput, resultCompensation, resultQuarantine, resultChangeTicket, resultSchedule []byte
+	var resultStatus, resultError sql.NullString
+	var resultDuration sql.NullInt64
+	var resultCost sql.NullFloat64
+	var connName, connVersion, connEndpoint, connBackend string
+	var outTruncated bool
+	var outBytes int64
+	var outSHA256, outRef string
+	err := row.Scan(
+		&env.EventID, &tenantID, &agentID, &tool, &action,
+		&payloadJSON, &env.PayloadCanon, &riskScore, &adjustedRiskScore,
+		&env.Decision, &policyJSON,
+		&idempotencyKey, &sessionID, &userID, &sourceIP, &traceID,
+		&env.ReceivedAt, &requestedAt, &env.Hash, &env.PrevHash, &env.CanonVersion,
+		&resultStatus, &resultOutput, &resultError, &resultDuration, &resultCompensation, &resultCost, &resultQuarantine, &resultChangeTicket, &resultSchedule,
+		&connName, &connVersion, &connEndpoint, &connBackend,
+		&outTruncated, &outBytes, &outSHA256, &outRef,
+		&env.EventSeq,
+	)
+	if err != nil {
+		return nil, err
+	}
+	env.PayloadJSON = payloadJSON
+	if len(payloadJSON) > 0 {
+		if err := json.Unmarshal(payloadJSON, &env.Request); err != nil {
+			return nil, fmt.Errorf("unmarshal payload: %w", err)
+		}
+	}
+	env.Request.TenantID = tenantID
+	env.Request.AgentID = agentID
+	env.Request.Tool = tool
+	env.Request.Action = action
+	env.Request.RiskScore = riskScore
+	if adjustedRiskScore.Valid {
+		adjusted := int(adjustedRiskScore.Int64)
+		env.AdjustedRiskScore = &adjusted
+	}
+	env.Request.IdempotencyKey = idempotencyKey.String
+	env.Request.SessionID = sessionID.String
+	env.Request.UserID = userID.String
+	env.Request.SourceIP = sourceIP.String
+	env.Request.TraceID = traceID.String
+	env.Request.RequestedAt = requestedAt
+
+	if len(policyJSON) > 0 && string(policyJSON) != "null" {
+		env.PolicyResult = &types.PolicyResult{}
+		if err := json.Unmarshal(policyJSON, env.PolicyResult); err != nil {
+			return nil, fmt.Errorf("unmarshal policy: %w", err)
+		}
+	}
+	if resultStatus.Valid {
+		env.ExecutionResult = &types.ExecutionResult{
+			Status:     resultStatus.String,
+			Error:      resultError.String,
+			DurationMS: resultDuration.Int64,
+			Cost:       resultCost.Float64,
+		}
+		if len(resultOutput) > 0 {
+			env.ExecutionResult.OutputJSON = resultOutput
+		}
+		if env.ExecutionResult.Compensation, err = parseCompensation(resultCompensation); err != nil {
+			return nil, err
+		}
+		env.ExecutionResult.Connector = connectorInfo(connName, connVersion, connEndpoint, connBackend)
+		env.ExecutionResult.Truncation = outputTruncation(outTruncated, outBytes, outSHA256, outRef)
+		if env.ExecutionResult.Quarantine, err = parseQuarantine(resultQuarantine); err != nil {
+			return nil, err
+		}
+		if env.ExecutionResult.ChangeTicket, err = parseChangeTicket(resultChangeTicket); err != nil {
+			return nil, err
+		}
+		if env.ExecutionResult.Schedule, err = parseSchedule(resultSchedule); err != nil {
+			return nil, err
+		}
+	}
+	return &env, nil
+}
+
+// GetExecutionByParentEvent returns the execution response for a previously
+// resumed approval flow, if one exists.
+func (s sqlEvents) GetExecutionByParentEvent(ctx context.Context, parentEventID string) (*types.ToolCallResponse, error) {
+	var eventID string
+	var decision types.Decision
+	var policyJSON, output, compensation, quarantine, changeTicket, schedule []byte
+	var status, errMsg sql.NullString
+	var duration sql.NullInt64
+	var cost sql.NullFloat64
+	var connName, connVersion, connEndpoint, connBackend string
+	var outTruncated bool
+	var outBytes int64
+	var outSHA256, outRef string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT e.event_id, e.decision, e.policy_result,
+		       r.status, r.output_json, r.error_msg, r.duration_ms, r.compensation_json, r.cost, r.quarantine_json, r.change_ticket_json, r.schedule_json,
+		       `+connectorColumns+`, `+truncationColumns+`
+		FROM tool_executions x
+		JOIN tool_events e ON e.event_id = x.execution_event_id
+		LEFT JOIN tool_results r ON r.event_id = e.event_id
+		WHERE x.parent_event_id = ?`, parentEventID,
+	).Scan(&eventID, &decision, &policyJSON, &status, &output, &errMsg, &duration, &compensation, &cost, &quarantine, &changeTicket, &schedule,
+		&connName, &connVersion, &connEndpoint, &connBackend,
+		&outTruncated, &outBytes, &outSHA256, &outRef)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("evidence.GetExecutionByParentEvent: %w", err)
+	}
+
+	resp := &types.ToolCallResponse{
+		EventID:  eventID,
+		Decision: decision,
+		Reason:   "idempotent execute replay",
+	}
+	if status.Valid {
+		resp.Result = &types.ExecutionResult{
+			Status:     status.String,
+			Error:      errMsg.String,
+			DurationMS: duration.Int64,
+			Cost:       cost.Float64,
+		}
+		if len(output) > 0 {
+			resp.Result.OutputJSON = output
+		}
+		if resp.Result.Compensation, err = parseCompensation(compensation); err != nil {
+			return nil, fmt.Errorf("evidence.GetExecutionByParentEvent: %w", err)
+		}
+		resp.Result.Connector = connectorInfo(connName, connVersion, connEndpoint, connBackend)
+		resp.Result.Truncation = outputTruncation(outTruncated, outBytes, outSHA256, outRef)
+		if resp.Result.Quarantine, err = parseQuarantine(quarantine); err != nil {
+			return nil, fmt.Errorf("evidence.GetExecutionByParentEvent: %w", err)
+		}
+		if resp.Result.ChangeTicket, err = parseChangeTicket(changeTicket); err != nil {
+			return nil, fmt.Errorf("evidence.GetExecutionByParentEvent: %w", err)
+		}
+		if resp.Result.Schedule, err = parseSchedule(schedule); err != nil {
+			return nil, fmt.Errorf("evidence.GetExecutionByParentEvent: %w", err)
+		}
+	}
+	return resp, nil
+}
+
+// GetChainEvents returns events for chain verification in insertion order.
+// The returned window starts strictly after afterSeq.
+func (s sqlEvents) GetChainEvents(ctx context.Context, tenantID string, afterSeq int64) ([]ChainEvent, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT e.event_seq, e.event_id, e.prev_hash, e.hash, e.payload_canon, r.result_canon, e.received_at
+		FROM tool_events e
+		LEFT JOIN tool_results r ON r.event_id = e.event_id
+		WHERE e.tenant_id = ? AND e.event_seq > ?
+		ORDER BY e.event_seq ASC`, tenantID, afterSeq)
+	if err != nil {
+		return nil, fmt.Errorf("evidence.GetChainEvents: %w", err)
+	}
+	defer rows.Close()
+
+	var events []ChainEvent
+	for rows.Next() {
+		var ev ChainEvent
+		if err := rows.Scan(&ev.EventSeq, &ev.EventID, &ev.PrevHash, &ev.Hash, &ev.CanonPayload, &ev.CanonResult, &ev.ReceivedAt); err != nil {
+			return nil, fmt.Errorf("evidence.GetChainEvents scan: %w", err)
+		}
+		events = append(events, ev)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("evidence.GetChainEvents iteration: %w", err)
+	}
+	return events, nil
+}
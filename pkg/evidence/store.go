@@ -16,12 +16,19 @@ import (
 
 // Store persists tool-call events and execution results in Postgres.
 type Store struct {
-	pool *pgxpool.Pool
+	pool  *pgxpool.Pool
+	canon CanonVersion
 }
 
 // NewStore creates a new evidence store backed by the given connection pool.
 func NewStore(pool *pgxpool.Pool) *Store {
-	return &Store{pool: pool}
+	return &Store{pool: pool, canon: CanonLegacy}
+}
+
+// SetCanonVersion selects the canonical JSON form of events recorded from
+// now on. Events already in the chain keep theirs.
+func (s *Store) SetCanonVersion(v CanonVersion) {
+	s.canon = v
 }
 
 // ──────────────────────────────────────────────────────────────────────────────
@@ -49,14 +56,14 @@ func (s *Store) RecordEvent(ctx context.Context, env *types.ToolCallEnvelope) er
 		return fmt.Errorf("evidence.RecordEvent last hash: %w", err)
 	}
 
-	canonPayload, err := CanonicalJSON(env.Request)
+	canonPayload, err := CanonicalJSONVersion(env.Request, s.canon)
 	if err != nil {
 		return fmt.Errorf("evidence.RecordEvent canonical: %w", err)
 	}
 
 	var canonResult []byte
 	if env.ExecutionResult != nil {
-		canonResult, err = CanonicalJSON(env.ExecutionResult)
+		canonResult, err = CanonicalJSONVersion(env.ExecutionResult, s.canon)
 		if err != nil {
 			return fmt.Errorf("evidence.RecordEvent canonical result: %w", err)
 		}
@@ -64,62 +71,98 @@ func (s *Store) RecordEvent(ctx context.Context, env *types.ToolCallEnvelope) er
 
 	hash := ChainHash(prevHash, canonPayload, canonResult)
 
+	seq, err := insertEventTx(ctx, tx, env, chainAppend{hash: hash, prevHash: prevHash, canon: canonPayload, canonVersion: s.canon}, canonResult)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("evidence.RecordEvent commit: %w", err)
+	}
+
+	env.Hash = hash
+	env.PrevHash = prevHash
+	env.EventSeq = seq
+	env.PayloadCanon = canonPayload
+	env.CanonVersion = int(s.canon)
+
+	return nil
+}
+
+// insertEventTx writes env's tool_events row, chained as c, and its
+// tool_results row with the canonical result bytes.
+func insertEventTx(ctx context.Context, tx pgx.Tx, env *types.ToolCallEnvelope, c chainAppend, canonResult []byte) (int64, error) {
 	policyJSON, err := json.Marshal(env.PolicyResult)
 	if err != nil {
-		return fmt.Errorf("evidence.RecordEvent marshal policy: %w", err)
+		return 0, fmt.Errorf("evidence.RecordEvent marshal policy: %w", err)
 	}
 
-	_, err = tx.Exec(ctx, `
+	var seq int64
+	err = tx.QueryRow(ctx, `
 		INSERT INTO tool_events (
 			event_id, tenant_id, agent_id, tool, action,
 			payload_json, payload_canon,
-			risk_score, decision, policy_result,
+			risk_score, adjusted_risk_score, decision, policy_result,
 			idempotency_key, session_id, user_id, source_ip, trace_id,
 			received_at, requested_at,
-			hash, prev_hash
+			hash, prev_hash, canon_version
 		) VALUES (
 			$1,$2,$3,$4,$5,
 			$6,$7,
-			$8,$9,$10,
-			$11,$12,$13,$14,$15,
-			$16,$17,
-			$18,$19
-		)`,
+			$8,$9,$10,$11,
+			$12,$13,$14,$15,$16,
+			$17,$18,
+			$19,$20,$21
+		)
+		RETURNING event_seq`,
 		env.EventID, env.Request.TenantID, env.Request.AgentID,
 		env.Request.Tool, env.Request.Action,
-		env.PayloadJSON, canonPayload,
-		env.Request.RiskScore, string(env.Decision), policyJSON,
+		env.PayloadJSON, c.canon,
+		env.Request.RiskScore, env.AdjustedRiskScore, string(env.Decision), policyJSON,
 		env.Request.IdempotencyKey, env.Request.SessionID, env.Request.UserID,
 		env.Request.SourceIP, env.Request.TraceID,
 		env.ReceivedAt, env.Request.RequestedAt,
-		hash, prevHash,
-	)
+		c.hash, c.prevHash, int(c.canonVersion),
+	).Scan(&seq)
 	if err != nil {
-		return fmt.Errorf("evidence.RecordEvent insert event: %w", err)
+		return 0, fmt.Errorf("evidence.RecordEvent insert event: %w", err)
 	}
 
 	if env.ExecutionResult != nil {
+		compensation, err := compensationJSON(env.ExecutionResult.Compensation)
+		if err != nil {
+			return 0, fmt.Errorf("evidence.RecordEvent marshal compensation: %w", err)
+		}
+		quarantine, err := quarantineJSON(env.ExecutionResult.Quarantine)
+		if err != nil {
+			return 0, fmt.Errorf("evidence.RecordEvent marshal quarantine: %w", err)
+		}
+		changeTicket, err := changeTicketJSON(env.ExecutionResult.ChangeTicket)
+		if err != nil {
+			return 0, fmt.Errorf("evidence.RecordEvent marshal change ticket: %w", err)
+		}
+		schedule, err := scheduleJSON(env.ExecutionResult.Schedule)
+		if err != nil {
+			return 0, fmt.Errorf("evidence.RecordEvent marshal schedule: %w", err)
+		}
 		_, err = tx.Exec(ctx, `
-			INSERT INTO tool_results (event_id, tenant_id, status, output_json, error_msg, duration_ms, result_canon)
-			VALUES ($1,$2,$3,$4,$5,$6,$7)`,
-			env.EventID, env.Request.TenantID,
-			env.ExecutionResult.Status, env.ExecutionResult.OutputJSON,
-			env.ExecutionResult.Error, env.ExecutionResult.DurationMS, canonResult,
+			INSERT INTO tool_results (event_id, tenant_id, status, output_json, error_msg, duration_ms, result_canon, compensation_json, cost, quarantine_json, change_ticket_json, schedule_json,
+				connector_name, connector_version, connector_endpoint, connector_backend,
+				output_truncated, output_bytes, output_sha256, output_ref)
+			VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20)`,
+			append([]any{env.EventID, env.Request.TenantID,
+				env.ExecutionResult.Status, env.ExecutionResult.OutputJSON,
+				env.ExecutionResult.Error, env.ExecutionResult.DurationMS, canonResult,
+				compensation, env.ExecutionResult.Cost, quarantine, changeTicket, schedule,
+			}, append(connectorArgs(env.ExecutionResult.Connector),
+				truncationArgs(env.ExecutionResult.Truncation)...)...)...,
 		)
 		if err != nil {
-			return fmt.Errorf("evidence.RecordEvent insert result: %w", err)
+			return 0, fmt.Errorf("evidence.RecordEvent insert result: %w", err)
 		}
 	}
 
-	if err := tx.Commit(ctx); err != nil {
-		return fmt.Errorf("evidence.RecordEvent commit: %w", err)
-	}
-
-	env.Hash = hash
-	env.PrevHash = prevHash
-	env.PayloadCanon = canonPayload
-
-	return nil
+	return seq, nil
 }
 
 // CheckIdempotency returns a prior response if one exists for (tenant, key).
@@ -150,19 +193,95 @@ func (s *Store) CheckIdempotency(ctx context.Context, tenantID, idempotencyKey s
 // Read path
 // ──────────────────────────────────────────────────────────────────────────────
 
+// eventColumns are the tool_events and tool_results columns scanEvent reads.
+const eventColumns = `
+		e.event_id, e.tenant_id, e.agent_id, e.tool, e.action,
+		e.payload_json, e.payload_canon, e.risk_score, e.adjusted_risk_score,
+		e.decision, e.policy_result,
+		e.idempotency_key, e.session_id, e.user_id, e.source_ip, e.trace_id,
+		e.received_at, e.requested_at, e.hash, e.prev_hash, e.canon_version,
+		r.status, r.output_json, r.error_msg, r.duration_ms, r.compensation_json, r.cost, r.quarantine_json, r.change_ticket_json, r.schedule_json,
+		` + connectorColumns + `,
+		` + truncationColumns + `,
+		e.event_seq`
+
 // GetEvent retrieves a single event by ID.
 func (s *Store) GetEvent(ctx context.Context, eventID string) (*types.ToolCallEnvelope, error) {
-	row := s.pool.QueryRow(ctx, `
-		SELECT event_id, tenant_id, agent_id, tool, action,
-		       payload_json, payload_canon, risk_score,
-		       decision, policy_result,
-		       idempotency_key, session_id, user_id, source_ip, trace_id,
-		       received_at, requested_at, hash, prev_hash,
-		       r.status, r.output_json, r.error_msg, r.duration_ms
+	env, err := scanEvent(s.pool.QueryRow(ctx, `
+		SELECT `+eventColumns+`
+		FROM tool_events e
+		LEFT JOIN tool_results r ON r.event_id = e.event_id
+		WHERE e.event_id = $1`, eventID))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("evidence.GetEvent: %w", err)
+	}
+	return env, nil
+}
+
+// ListTraceEvents returns up to limit of the tenant's events recorded under
+// traceID, in insertion order.
+func (s *Store) ListTraceEvents(ctx context.Context, tenantID, traceID string, limit int) ([]types.ToolCallEnvelope, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+eventColumns+`
 		FROM tool_events e
 		LEFT JOIN tool_results r ON r.event_id = e.event_id
-		WHERE e.event_id = $1`, eventID)
+		WHERE e.tenant_id = $1 AND e.trace_id = $2
+		ORDER BY e.event_seq ASC
+		LIMIT $3`, tenantID, traceID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("evidence.ListTraceEvents: %w", err)
+	}
+	defer rows.Close()
+
+	var events []types.ToolCallEnvelope
+	for rows.Next() {
+		env, err := scanEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("evidence.ListTraceEvents: %w", err)
+		}
+		events = append(events, *env)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("evidence.ListTraceEvents iteration: %w", err)
+	}
+	return events, nil
+}
+
+// ListEvents returns up to f.Limit of the tenant's events matching f, in
+// insertion order (reversed for f.Newest), starting after f.AfterSeq.
+func (s *Store) ListEvents(ctx context.Context, tenantID string, f EventFilter) ([]types.ToolCallEnvelope, error) {
+	where, args := f.where(tenantID, func(n int) string { return fmt.Sprintf("$%d", n) })
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+eventColumns+`
+		FROM tool_events e
+		LEFT JOIN tool_results r ON r.event_id = e.event_id
+		WHERE `+where+`
+		ORDER BY e.event_seq `+f.order()+`
+		LIMIT `+fmt.Sprintf("$%d", len(args)+1), append(args, f.limit())...)
+	if err != nil {
+		return nil, fmt.Errorf("evidence.ListEvents: %w", err)
+	}
+	defer rows.Close()
+
+	var events []types.ToolCallEnvelope
+	for rows.Next() {
+		env, err := scanEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("evidence.ListEvents: %w", err)
+		}
+		events = append(events, *env)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("evidence.ListEvents iteration: %w", err)
+	}
+	return events, nil
+}
 
+// scanEvent reads one row of eventColumns.
+func scanEvent(row pgx.Row) (*types.ToolCallEnvelope, error) {
 	var env types.ToolCallEnvelope
 	var tenantID, agentID, tool, action string
 	var riskScore int
@@ -173,29 +292,35 @@ func (s *Store) GetEvent(ctx context.Context, eventID string) (*types.ToolCallEn
 	var resultOutput []byte
 	var resultError *string
 	var resultDuration *int64
+	var resultCompensation, resultQuarantine, resultChangeTicket, resultSchedule []byte
+	var resultCost *float64
+	var connName, connVersion, connEndpoint, connBackend string
+	var outTruncated bool
+	var outBytes int64
+	var outSHA256, outRef string
 	err := row.Scan(
 		&env.EventID,
 		&tenantID, &agentID,
 		&tool, &action,
-		&env.PayloadJSON, &env.PayloadCanon, &riskScore,
+		&env.PayloadJSON, &env.PayloadCanon, &riskScore, &env.AdjustedRiskScore,
 		&env.Decision, &policyJSON,
 		&idempotencyKey, &sessionID,
 		&userID, &sourceIP, &traceID,
 		&env.ReceivedAt, &requestedAt,
-		&env.Hash, &env.PrevHash,
-		&resultStatus, &resultOutput, &resultError, &resultDuration,
+		&env.Hash, &env.PrevHash, &env.CanonVersion,
+		&resultStatus, &resultOutput, &resultError, &resultDuration, &resultCompensation, &resultCost, &resultQuarantine, &resultChangeTicket, &resultSchedule,
+		&connName, &connVersion, &connEndpoint, &connBackend,
+		&outTruncated, &outBytes, &outSHA256, &outRef,
+		&env.EventSeq,
 	)
-	if err == pgx.ErrNoRows {
-		return nil, nil
-	}
 	if err != nil {
-		return nil, fmt.Errorf("evidence.GetEvent: %w", err)
+		return nil, err
 	}
 	// Rebuild the full original request from persisted payload_json so fields
 	// such as params/resource/risk_factors are preserved for resume execution.
 	if len(env.PayloadJSON) > 0 {
 		if err := json.Unmarshal(env.PayloadJSON, &env.Request); err != nil {
-			return nil, fmt.Errorf("evidence.GetEvent unmarshal payload: %w", err)
+			return nil, fmt.Errorf("unmarshal payload: %w", err)
 		}
 	}
 	// Overlay canonical DB columns as source-of-truth.
@@ -214,7 +339,7 @@ func (s *Store) GetEvent(ctx context.Context, eventID string) (*types.ToolCallEn
 	if len(policyJSON) > 0 {
 		env.PolicyResult = &types.PolicyResult{}
 		if err := json.Unmarshal(policyJSON, env.PolicyResult); err != nil {
-			return nil, fmt.Errorf("evidence.GetEvent unmarshal policy: %w", err)
+			return nil, fmt.Errorf("unmarshal policy: %w", err)
 		}
 	}
 	if resultStatus != nil {
@@ -230,6 +355,23 @@ func (s *Store) GetEvent(ctx context.Context, eventID string) (*types.ToolCallEn
 		if resultDuration != nil {
 			env.ExecutionResult.DurationMS = *resultDuration
 		}
+		if resultCost != nil {
+			env.ExecutionResult.Cost = *resultCost
+		}
+		if env.ExecutionResult.Compensation, err = parseCompensation(resultCompensation); err != nil {
+			return nil, err
+		}
+		env.ExecutionResult.Connector = connectorInfo(connName, connVersion, connEndpoint, connBackend)
+		env.ExecutionResult.Truncation = outputTruncation(outTruncated, outBytes, outSHA256, outRef)
+		if env.ExecutionResult.Quarantine, err = parseQuarantine(resultQuarantine); err != nil {
+			return nil, err
+		}
+		if env.ExecutionResult.ChangeTicket, err = parseChangeTicket(resultChangeTicket); err != nil {
+			return nil, err
+		}
+		if env.ExecutionResult.Schedule, err = parseSchedule(resultSchedule); err != nil {
+			return nil, err
+		}
 	}
 	return &env, nil
 }
@@ -239,7 +381,8 @@ func (s *Store) GetEvent(ctx context.Context, eventID string) (*types.ToolCallEn
 func (s *Store) GetExecutionByParentEvent(ctx context.Context, parentEventID string) (*types.ToolCallResponse, error) {
 	row := s.pool.QueryRow(ctx, `
 		SELECT e.event_id, e.decision, e.policy_result,
-		       r.status, r.output_json, r.error_msg, r.duration_ms
+		       r.status, r.output_json, r.error_msg, r.duration_ms, r.compensation_json, r.cost, r.quarantine_json, r.change_ticket_json, r.schedule_json,
+		       `+connectorColumns+`, `+truncationColumns+`
 		FROM tool_executions x
 		JOIN tool_events e ON e.event_id = x.execution_event_id
 		LEFT JOIN tool_results r ON r.event_id = e.event_id
@@ -252,8 +395,16 @@ func (s *Store) GetExecutionByParentEvent(ctx context.Context, parentEventID str
 	var output []byte
 	var errMsg *string
 	var duration *int64
-
-	err := row.Scan(&eventID, &decision, &policyJSON, &status, &output, &errMsg, &duration)
+	var compensation, quarantine, changeTicket, schedule []byte
+	var cost *float64
+	var connName, connVersion, connEndpoint, connBackend string
+	var outTruncated bool
+	var outBytes int64
+	var outSHA256, outRef string
+
+	err := row.Scan(&eventID, &decision, &policyJSON, &status, &output, &errMsg, &duration, &compensation, &cost, &quarantine, &changeTicket, &schedule,
+		&connName, &connVersion, &connEndpoint, &connBackend,
+		&outTruncated, &outBytes, &outSHA256, &outRef)
 	if err == pgx.ErrNoRows {
 		return nil, nil
 	}
@@ -277,6 +428,23 @@ func (s *Store) GetExecutionByParentEvent(ctx context.Context, parentEventID str
 		if duration != nil {
 			resp.Result.DurationMS = *duration
 		}
+		if cost != nil {
+			resp.Result.Cost = *cost
+		}
+		if resp.Result.Compensation, err = parseCompensation(compensation); err != nil {
+			return nil, fmt.Errorf("evidence.GetExecutionByParentEvent: %w", err)
+		}
+		resp.Result.Connector = connectorInfo(connName, connVersion, connEndpoint, connBackend)
+		resp.Result.Truncation = outputTruncation(outTruncated, outBytes, outSHA256, outRef)
+		if resp.Result.Quarantine, err = parseQuarantine(quarantine); err != nil {
+			return nil, fmt.Errorf("evidence.GetExecutionByParentEvent: %w", err)
+		}
+		if resp.Result.ChangeTicket, err = parseChangeTicket(changeTicket); err != nil {
+			return nil, fmt.Errorf("evidence.GetExecutionByParentEvent: %w", err)
+		}
+		if resp.Result.Schedule, err = parseSchedule(schedule); err != nil {
+			return nil, fmt.Errorf("evidence.GetExecutionByParentEvent: %w", err)
+		}
 	}
 	return resp, nil
 }
@@ -406,6 +574,136 @@ func (s *Store) lastHashTx(ctx context.Context, tx pgx.Tx, tenantID string) (str
 	return h, err
 }
 
+// compensationJSON encodes an execution's compensation for the
+// compensation_json column; nil stays NULL.
+func compensationJSON(c *types.Compensation) ([]byte, error) {
+	if c == nil {
+		return nil, nil
+	}
+	return json.Marshal(c)
+}
+
+// parseCompensation decodes a compensation_json column.
+func parseCompensation(b []byte) (*types.Compensation, error) {
+	if len(b) == 0 || string(b) == "null" {
+		return nil, nil
+	}
+	var c types.Compensation
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("unmarshal compensation: %w", err)
+	}
+	return &c, nil
+}
+
+// quarantineJSON encodes a result's quarantine for the quarantine_json
+// column; nil stays NULL.
+func quarantineJSON(q *types.Quarantine) ([]byte, error) {
+	if q == nil {
+		return nil, nil
+	}
+	return json.Marshal(q)
+}
+
+// parseQuarantine decodes a quarantine_json column.
+func parseQuarantine(b []byte) (*types.Quarantine, error) {
+	if len(b) == 0 || string(b) == "null" {
+		return nil, nil
+	}
+	var q types.Quarantine
+	if err := json.Unmarshal(b, &q); err != nil {
+		return nil, fmt.Errorf("unmarshal quarantine: %w", err)
+	}
+	return &q, nil
+}
+
+// changeTicketJSON encodes a result's change ticket for the
+// change_ticket_json column; nil stays NULL.
+func changeTicketJSON(t *types.ChangeTicket) ([]byte, error) {
+	if t == nil {
+		return nil, nil
+	}
+	return json.Marshal(t)
+}
+
+// parseChangeTicket decodes a change_ticket_json column.
+func parseChangeTicket(b []byte) (*types.ChangeTicket, error) {
+	if len(b) == 0 || string(b) == "null" {
+		return nil, nil
+	}
+	var t types.ChangeTicket
+	if err := json.Unmarshal(b, &t); err != nil {
+		return nil, fmt.Errorf("unmarshal change ticket: %w", err)
+	}
+	return &t, nil
+}
+
+// scheduleJSON encodes a result's schedule for the schedule_json column;
+// nil stays NULL.
+func scheduleJSON(s *types.ExecutionSchedule) ([]byte, error) {
+	if s == nil {
+		return nil, nil
+	}
+	return json.Marshal(s)
+}
+
+// parseSchedule decodes a schedule_json column.
+func parseSchedule(b []byte) (*types.ExecutionSchedule, error) {
+	if len(b) == 0 || string(b) == "null" {
+		return nil, nil
+	}
+	var s types.ExecutionSchedule
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, fmt.Errorf("unmarshal schedule: %w", err)
+	}
+	return &s, nil
+}
+
+// connectorColumns are the tool_results columns that identify the connector
+// build behind an execution, with NULL read as "".
+const connectorColumns = `COALESCE(r.connector_name, ''), COALESCE(r.connector_version, ''),
+		COALESCE(r.connector_endpoint, ''), COALESCE(r.connector_backend, '')`
+
+// connectorArgs returns the values of the connector_* columns; nil leaves
+// them NULL.
+func connectorArgs(c *types.ConnectorInfo) []any {
+	if c == nil {
+		return []any{nil, nil, nil, nil}
+	}
+	return []any{c.Name, c.Version, c.Endpoint, c.Backend}
+}
+
+// connectorInfo rebuilds a result's connector from connectorColumns; nil
+// when none was recorded.
+func connectorInfo(name, version, endpoint, backend string) *types.ConnectorInfo {
+	if name == "" && version == "" && endpoint == "" && backend == "" {
+		return nil
+	}
+	return &types.ConnectorInfo{Name: name, Version: version, Endpoint: endpoint, Backend: backend}
+}
+
+// truncationColumns are the tool_results columns that describe truncated
+// output, with NULL read as false, 0 or "".
+const truncationColumns = `COALESCE(r.output_truncated, FALSE), COALESCE(r.output_bytes, 0),
+		COALESCE(r.output_sha256, ''), COALESCE(r.output_ref, '')`
+
+// truncationArgs returns the values of the output_* columns; nil leaves
+// them NULL.
+func truncationArgs(t *types.OutputTruncation) []any {
+	if t == nil {
+		return []any{nil, nil, nil, nil}
+	}
+	return []any{true, t.OriginalBytes, t.SHA256, t.Ref}
+}
+
+// outputTruncation rebuilds a result's truncation from truncationColumns;
+// nil when the output was kept whole.
+func outputTruncation(truncated bool, originalBytes int64, sha256, ref string) *types.OutputTruncation {
+	if !truncated {
+		return nil
+	}
+	return &types.OutputTruncation{OriginalBytes: originalBytes, SHA256: sha256, Ref: ref}
+}
+
 const evidenceLockNamespace = 0x4F43_4556 // "OCEV" — OpenClause evidence
 
 // tenantLockID produces a deterministic int64 advisory-lock ID from a tenant string.
@@ -0,0 +1,47 @@
+package gateway
+
+import (
+	"context"
+
+	"github.com/bturcanu/OpenClause/pkg/approvals"
+	"github.com/bturcanu/OpenClause/pkg/evidence"
+)
+
+// recentActivity returns the agent's latest recorded calls other than
+// eventID, newest first, at most gw.approvalContext of them, for approvers
+// to see with a request. A failed lookup is logged and leaves the request
+// without context.
+func (gw *Gateway) recentActivity(ctx context.Context, tenantID, agentID, eventID string) []approvals.AgentActivity {
+	if gw.approvalContext <= 0 || agentID == "" {
+		return nil
+	}
+	// One more than wanted, in case the request's own event is among them.
+	events, err := gw.evidence.ListEvents(ctx, tenantID, evidence.EventFilter{
+		AgentID: agentID,
+		Limit:   gw.approvalContext + 1,
+		Newest:  true,
+	})
+	if err != nil {
+		gw.log.WarnContext(ctx, "recent agent activity unavailable", "error", err)
+		return nil
+	}
+	var out []approvals.AgentActivity
+	for _, env := range events {
+		if env.EventID == eventID || len(out) == gw.approvalContext {
+			continue
+		}
+		a := approvals.AgentActivity{
+			EventID:  env.EventID,
+			Tool:     env.Request.Tool,
+			Action:   env.Request.Action,
+			Resource: env.Request.Resource,
+			Decision: string(env.Decision),
+			At:       env.ReceivedAt,
+		}
+		if env.ExecutionResult != nil {
+			a.Status = env.ExecutionResult.Status
+		}
+		out = append(out, a)
+	}
+	return out
+}
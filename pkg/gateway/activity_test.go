@@ -0,0 +1,62 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+func TestHandleToolCall_ApprovalCarriesRecentActivity(t *testing.T) {
+	fe := newFakeEvidence()
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i, prior := range []struct{ id, agent, action string }{
+		{"ev-1", "agent-1", "issue.get"},
+		{"ev-2", "agent-1", "issue.search"},
+		{"ev-3", "agent-2", "issue.delete"},
+		{"ev-4", "agent-1", "issue.comment"},
+	} {
+		env := &types.ToolCallEnvelope{
+			EventID:         prior.id,
+			Request:         types.ToolCallRequest{TenantID: "tenant1", AgentID: prior.agent, Tool: "jira", Action: prior.action},
+			ReceivedAt:      at.Add(time.Duration(i) * time.Minute),
+			Decision:        types.DecisionAllow,
+			ExecutionResult: &types.ExecutionResult{Status: "success"},
+		}
+		if err := fe.RecordEvent(t.Context(), env); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fa := &fakeApprovals{}
+	gw := &Gateway{
+		log:             slog.New(slog.NewTextHandler(bytes.NewBuffer(nil), nil)),
+		evidence:        fe,
+		policy:          fakePolicy{decision: types.DecisionApprove},
+		connectors:      &fakeConnectors{},
+		approvals:       fa,
+		perTenantLimit:  100,
+		approvalContext: 2,
+	}
+	body, _ := json.Marshal(types.ToolCallRequest{TenantID: "tenant1", AgentID: "agent-1", Tool: "jira", Action: "issue.delete", IdempotencyKey: "a1"})
+	if rr := postToolCall(t, gw, body); rr.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rr.Code, rr.Body.String())
+	}
+	got := fa.last.RecentActivity
+	if len(got) != 2 || got[0].EventID != "ev-4" || got[1].EventID != "ev-2" {
+		t.Fatalf("recent activity = %+v, want ev-4 then ev-2", got)
+	}
+	if a := got[0]; a.Action != "issue.comment" || a.Decision != "allow" || a.Status != "success" || !a.At.Equal(at.Add(3*time.Minute)) {
+		t.Fatalf("activity = %+v", a)
+	}
+
+	gw.approvalContext = 0
+	body, _ = json.Marshal(types.ToolCallRequest{TenantID: "tenant1", AgentID: "agent-1", Tool: "jira", Action: "issue.delete", IdempotencyKey: "a2"})
+	postToolCall(t, gw, body)
+	if fa.last.RecentActivity != nil {
+		t.Fatalf("disabled context still attached %+v", fa.last.RecentActivity)
+	}
+}
@@ -0,0 +1,68 @@
+package gateway
+
+import (
+	"context"
+
+	"github.com/bturcanu/OpenClause/pkg/admission"
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+// shedRetryAfterSec is the Retry-After hint sent with load-shedding 503s.
+const shedRetryAfterSec = "1"
+
+// priorityRank orders declared priorities; an empty priority ranks as
+// normal.
+var priorityRank = map[types.Priority]int{
+	types.PriorityLow:    0,
+	"":                   1,
+	types.PriorityNormal: 1,
+	types.PriorityHigh:   2,
+}
+
+// requestPriority classifies a request for load shedding. A priority the
+// caller declared is used, else one inferred from the request: low-risk
+// reads are cheapest to retry and are shed first; high-risk calls are most
+// likely already awaiting a human and are shed last. Either is capped at
+// maxPriority, the highest its tenant may claim; an empty maxPriority
+// means normal.
+func requestPriority(req types.ToolCallRequest, read bool, maxPriority types.Priority) admission.Priority {
+	p := req.Priority
+	if p == "" {
+		p = inferPriority(req, read)
+	}
+	if priorityRank[p] > priorityRank[maxPriority] {
+		p = maxPriority
+		if p == "" {
+			p = types.PriorityNormal
+		}
+	}
+	switch p {
+	case types.PriorityLow:
+		return admission.PriorityLow
+	case types.PriorityHigh:
+		return admission.PriorityHigh
+	}
+	return admission.PriorityNormal
+}
+
+// inferPriority is the priority of a request that declares none; read
+// reports whether its action is a read.
+func inferPriority(req types.ToolCallRequest, read bool) types.Priority {
+	if req.RiskScore >= 7 {
+		return types.PriorityHigh
+	}
+	if read && req.RiskScore <= 2 {
+		return types.PriorityLow
+	}
+	return types.PriorityNormal
+}
+
+// admissionPriority is requestPriority capped by the tenant's max_priority
+// setting. When the settings cannot be read, no call may declare high.
+func (gw *Gateway) admissionPriority(ctx context.Context, req types.ToolCallRequest) admission.Priority {
+	settings, err := gw.settings.Get(ctx, req.TenantID)
+	if err != nil {
+		gw.log.WarnContext(ctx, "tenant settings lookup failed, capping declared priority at normal", "tenant_id", req.TenantID, "error", err)
+	}
+	return requestPriority(req, gw.isReadAction(ctx, req.Tool, req.Action), settings.MaxPriority)
+}
@@ -0,0 +1,134 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/auth"
+	"github.com/bturcanu/OpenClause/pkg/blobs"
+	"github.com/bturcanu/OpenClause/pkg/config"
+	"github.com/bturcanu/OpenClause/pkg/connectors"
+	"github.com/bturcanu/OpenClause/pkg/secrets"
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+// gatewayBlobs holds params uploaded out of band and spilled connector
+// output; see pkg/blobs.
+type gatewayBlobs interface {
+	PresignPut(ctx context.Context, key string, ttl time.Duration) (string, error)
+	Get(ctx context.Context, key string, maxBytes int64) ([]byte, error)
+	Put(ctx context.Context, key string, data []byte) error
+}
+
+// blobStoreFromEnv builds the params blob store from BLOB_S3_*, falling back
+// to the EVIDENCE_S3_* connection settings. It returns nil when
+// BLOB_S3_BUCKET is not set.
+func blobStoreFromEnv(ctx context.Context, env config.Env, resolver *secrets.Resolver) (*blobs.S3, error) {
+	bucket := env.Get("BLOB_S3_BUCKET")
+	if bucket == "" {
+		return nil, nil
+	}
+	secretKey, err := resolver.Resolve(ctx, env.Or("BLOB_S3_SECRET_KEY", env.Or("EVIDENCE_S3_SECRET_KEY", "minioadmin")))
+	if err != nil {
+		return nil, err
+	}
+	return blobs.NewS3(
+		env.Or("BLOB_S3_ENDPOINT", env.Or("EVIDENCE_S3_ENDPOINT", "localhost:9000")),
+		env.Or("BLOB_S3_ACCESS_KEY", env.Or("EVIDENCE_S3_ACCESS_KEY", "minioadmin")),
+		secretKey,
+		env.Bool("BLOB_S3_SECURE", env.Bool("EVIDENCE_S3_SECURE", false)),
+		bucket,
+	)
+}
+
+// HandleCreateBlobUpload is POST /v1/blobs: it takes the digest and size of
+// params the client wants to send by reference and returns a presigned URL
+// to PUT them to.
+func (gw *Gateway) HandleCreateBlobUpload(w http.ResponseWriter, r *http.Request) {
+	if gw.blobs == nil {
+		types.ErrNotFound("blob uploads are not enabled").WriteJSON(w)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	var ref types.BlobRef
+	if err := json.NewDecoder(r.Body).Decode(&ref); err != nil {
+		types.ErrBadRequest("invalid JSON body").WriteJSON(w)
+		return
+	}
+	if err := ref.Validate("params_ref"); err != nil {
+		types.ErrValidation(err).WriteJSON(w)
+		return
+	}
+	ctx := r.Context()
+	expiresAt := time.Now().UTC().Add(gw.blobUploadTTL)
+	uploadURL, err := gw.blobs.PresignPut(ctx, blobs.Key(auth.TenantFromContext(ctx), ref), gw.blobUploadTTL)
+	if err != nil {
+		gw.log.ErrorContext(ctx, "presign blob upload failed", "error", err)
+		types.ErrInternal("failed to create upload URL").WriteJSON(w)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(types.BlobUpload{ParamsRef: ref, UploadURL: uploadURL, ExpiresAt: expiresAt})
+}
+
+// loadParams returns req's params: Params, or the blob ParamsRef names,
+// read back and checked against its digest.
+func (gw *Gateway) loadParams(ctx context.Context, req types.ToolCallRequest) (json.RawMessage, error) {
+	if req.ParamsRef == nil {
+		return req.Params, nil
+	}
+	if gw.blobs == nil {
+		return nil, errors.New("blob uploads are not enabled")
+	}
+	data, err := gw.blobs.Get(ctx, blobs.Key(req.TenantID, *req.ParamsRef), req.ParamsRef.Size)
+	if err != nil {
+		return nil, err
+	}
+	if err := blobs.Verify(data, *req.ParamsRef); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// verifyParamsRef checks that req's params_ref, if any, names an uploaded
+// blob that matches its digest. A missing or mismatched blob is a 422.
+func (gw *Gateway) verifyParamsRef(ctx context.Context, req types.ToolCallRequest) *types.APIError {
+	if req.ParamsRef == nil {
+		return nil
+	}
+	if gw.blobs == nil {
+		return types.ErrValidation(&types.ValidationError{Field: "params_ref", Reason: "blob uploads are not enabled"})
+	}
+	_, err := gw.loadParams(ctx, req)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, blobs.ErrNotFound), errors.Is(err, blobs.ErrMismatch), errors.Is(err, blobs.ErrInvalidJSON):
+		return types.ErrValidation(&types.ValidationError{Field: "params_ref", Reason: err.Error()})
+	default:
+		gw.log.ErrorContext(ctx, "read params blob failed", "error", err)
+		return types.ErrInternal("failed to read params_ref")
+	}
+}
+
+// outputTruncation converts a connector's truncation for the execution
+// result. When output spilling is on and the full output is at hand, it is
+// written to the blob bucket first and its key recorded; a failed write is
+// logged and the result keeps just the size and digest.
+func (gw *Gateway) outputTruncation(ctx context.Context, tenantID string, t *connectors.OutputTruncation) *types.OutputTruncation {
+	out := &types.OutputTruncation{OriginalBytes: t.OriginalBytes, SHA256: t.SHA256}
+	if !gw.spillOutput || gw.blobs == nil || len(t.Full) == 0 {
+		return out
+	}
+	key := blobs.OutputKey(tenantID, t.SHA256)
+	if err := gw.blobs.Put(ctx, key, t.Full); err != nil {
+		gw.log.ErrorContext(ctx, "spill truncated output failed", "bytes", t.OriginalBytes, "error", err)
+		return out
+	}
+	out.Ref = key
+	return out
+}
@@ -0,0 +1,50 @@
+package gateway
+
+import (
+	"context"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/metering"
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+// gatewaySpend accumulates connector-reported cost; see metering.SpendStore.
+type gatewaySpend interface {
+	AddSpend(ctx context.Context, tenantID, agentID string, cost float64, at time.Time) error
+	Spent(ctx context.Context, tenantID, agentID string, at time.Time) (metering.Spend, error)
+}
+
+// budgetStates returns the state of the tenant's budgets that cover req's
+// agent, for policy input. It returns nil when the tenant has none, and
+// ok=false when they cannot be read, in which case the call is denied.
+func (gw *Gateway) budgetStates(ctx context.Context, req types.ToolCallRequest) (states []types.BudgetState, ok bool) {
+	if gw.spend == nil {
+		return nil, true
+	}
+	settings, err := gw.settings.Get(ctx, req.TenantID)
+	if err != nil {
+		gw.log.ErrorContext(ctx, "tenant settings lookup failed", "tenant_id", req.TenantID, "error", err)
+		return nil, false
+	}
+	if len(settings.Budgets) == 0 {
+		return nil, true
+	}
+	spend, err := gw.spend.Spent(ctx, req.TenantID, req.AgentID, time.Now())
+	if err != nil {
+		gw.log.ErrorContext(ctx, "budget spend lookup failed", "tenant_id", req.TenantID, "error", err)
+		return nil, false
+	}
+	return metering.BudgetStates(settings.Budgets, req.AgentID, spend, req.CostEstimate), true
+}
+
+// chargeSpend adds an execution's reported cost to its tenant's and agent's
+// spend, even past the call's deadline. A failure is logged: the call has
+// already happened.
+func (gw *Gateway) chargeSpend(ctx context.Context, req types.ToolCallRequest, cost float64) {
+	if gw.spend == nil || cost <= 0 {
+		return
+	}
+	if err := gw.spend.AddSpend(context.WithoutCancel(ctx), req.TenantID, req.AgentID, cost, time.Now()); err != nil {
+		gw.log.ErrorContext(ctx, "record spend failed", "tenant_id", req.TenantID, "agent_id", req.AgentID, "cost", cost, "error", err)
+	}
+}
@@ -0,0 +1,103 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/metering"
+	"github.com/bturcanu/OpenClause/pkg/tenants"
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+type fakeSpend struct {
+	spend metering.Spend
+}
+
+func (f *fakeSpend) AddSpend(_ context.Context, _, _ string, cost float64, _ time.Time) error {
+	f.spend.TenantDay += cost
+	f.spend.TenantMonth += cost
+	f.spend.AgentDay += cost
+	f.spend.AgentMonth += cost
+	return nil
+}
+
+func (f *fakeSpend) Spent(context.Context, string, string, time.Time) (metering.Spend, error) {
+	return f.spend, nil
+}
+
+// budgetPolicy denies like the default bundle's budget rule and keeps the
+// last input it saw.
+type budgetPolicy struct {
+	last types.PolicyInput
+}
+
+func (p *budgetPolicy) Evaluate(_ context.Context, in types.PolicyInput) (*types.PolicyResult, error) {
+	p.last = in
+	for _, b := range in.Budgets {
+		if b.Exceeded || b.WouldExceed {
+			return &types.PolicyResult{Decision: types.DecisionDeny, Reason: "budget exceeded"}, nil
+		}
+	}
+	return &types.PolicyResult{Decision: types.DecisionAllow, Reason: "ok"}, nil
+}
+
+func TestBudgets_ChargeCostAndReachPolicy(t *testing.T) {
+	fe := newFakeEvidence()
+	fc := &fakeConnectors{output: json.RawMessage(`{"ok":true}`), cost: 0.4}
+	gw := newExecuteGateway(fe, fc, &fakeApprovals{})
+	gw.perTenantLimit = 100
+	policy := &budgetPolicy{}
+	gw.policy = policy
+	spend := &fakeSpend{}
+	gw.spend = spend
+	gw.settings = tenants.NewSettingsCache(fakeSettings{"tenant1": {Budgets: []types.Budget{
+		{Period: types.BudgetPeriodMonth, Limit: 1},
+		{AgentID: "other-agent", Period: types.BudgetPeriodDay, Limit: 0.1},
+	}}}, time.Minute)
+
+	post := func(key string, estimate float64) types.ToolCallResponse {
+		body, _ := json.Marshal(types.ToolCallRequest{
+			SchemaVersion: types.SchemaVersion11, TenantID: "tenant1", AgentID: "agent-1",
+			Tool: "slack", Action: "msg.post", IdempotencyKey: key, CostEstimate: estimate,
+		})
+		rr := postToolCall(t, gw, body)
+		var resp types.ToolCallResponse
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return resp
+	}
+
+	resp := post("k1", 0)
+	if resp.Decision != types.DecisionAllow || resp.Result == nil || resp.Result.Cost != 0.4 {
+		t.Fatalf("first call = %+v", resp)
+	}
+	if env := fe.events[resp.EventID]; env == nil || env.ExecutionResult == nil || env.ExecutionResult.Cost != 0.4 {
+		t.Fatalf("cost not recorded: %+v", env)
+	}
+	if spend.spend.TenantMonth != 0.4 {
+		t.Fatalf("spend = %+v, want 0.4 charged", spend.spend)
+	}
+	// Only the budget covering agent-1 reaches policy.
+	if len(policy.last.Budgets) != 1 || policy.last.Budgets[0].Spent != 0 || policy.last.Budgets[0].Remaining != 1 {
+		t.Fatalf("policy budgets = %+v", policy.last.Budgets)
+	}
+
+	if resp := post("k2", 0.5); resp.Decision != types.DecisionAllow {
+		t.Fatalf("second call = %+v", resp)
+	}
+	b := policy.last.Budgets[0]
+	if b.Spent != 0.4 || b.Exceeded || b.WouldExceed {
+		t.Fatalf("budget state = %+v", b)
+	}
+
+	// 0.8 spent: an estimate of 0.5 would take the budget over.
+	if resp := post("k3", 0.5); resp.Decision != types.DecisionDeny || fc.calls != 2 {
+		t.Fatalf("over-budget call = %+v after %d connector calls", resp, fc.calls)
+	}
+	if b := policy.last.Budgets[0]; !b.WouldExceed || b.Exceeded {
+		t.Fatalf("budget state = %+v, want would_exceed", b)
+	}
+}
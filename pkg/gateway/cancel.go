@@ -0,0 +1,77 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/approvals"
+	"github.com/bturcanu/OpenClause/pkg/auth"
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"github.com/go-chi/chi/v5"
+)
+
+// cancelApprovalInput is the body of POST /v1/approvals/requests/{id}/cancel.
+type cancelApprovalInput struct {
+	AgentID string `json:"agent_id"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// HandleCancelApproval is POST /v1/approvals/requests/{id}/cancel, the
+// agent-facing twin of the approvals service route. The requesting agent
+// withdraws a pending request of the authenticated tenant so approvers are
+// not left deciding a call it no longer wants; its Slack messages are
+// rewritten, and the cancellation is audited and emitted as
+// oc.approval.cancelled.
+func (gw *Gateway) HandleCancelApproval(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	var body cancelApprovalInput
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		types.ErrBadRequest("invalid JSON body").WriteJSON(w)
+		return
+	}
+	id := chi.URLParam(r, "id")
+	req, err := gw.approvals.GetRequest(ctx, id)
+	if err != nil {
+		gw.log.ErrorContext(ctx, "get approval request failed", "request_id", id, "error", err)
+		types.ErrInternal("failed to cancel approval request").WriteJSON(w)
+		return
+	}
+	// Without an authenticated tenant (internal callers) any tenant's
+	// request may be cancelled, as with tenantEvent.
+	in := approvals.CancelInput{TenantID: auth.TenantFromContext(ctx), AgentID: body.AgentID, Reason: body.Reason}
+	if in.TenantID == "" && req != nil {
+		in.TenantID = req.TenantID
+	}
+	if apiErr := approvals.CheckCancel(req, in); apiErr != nil {
+		apiErr.WriteJSON(w)
+		return
+	}
+	if err := gw.approvals.CancelRequest(ctx, id, in); errors.Is(err, approvals.ErrNotPending) {
+		types.ErrConflict("approval request is no longer pending").WriteJSON(w)
+		return
+	} else if err != nil {
+		gw.log.ErrorContext(ctx, "cancel approval request failed", "request_id", id, "error", err)
+		types.ErrInternal("failed to cancel approval request").WriteJSON(w)
+		return
+	}
+
+	now := time.Now().UTC()
+	gw.events.PublishResolution(ctx, approvals.Resolution{Request: *req, Status: "cancelled", Reason: in.Reason, At: now})
+	audit := struct {
+		RequestID   string    `json:"request_id"`
+		AgentID     string    `json:"agent_id"`
+		Tool        string    `json:"tool"`
+		Action      string    `json:"action"`
+		Resource    string    `json:"resource,omitempty"`
+		Reason      string    `json:"reason,omitempty"`
+		CancelledAt time.Time `json:"cancelled_at"`
+	}{req.ID, req.AgentID, req.Tool, req.Action, req.Resource, in.Reason, now}
+	if err := gw.audit.Record(ctx, "approval_cancelled", req.TenantID, req.EventID, "", audit); err != nil {
+		gw.log.ErrorContext(ctx, "audit log write failed", "request_id", req.ID, "error", err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"status": "cancelled"})
+}
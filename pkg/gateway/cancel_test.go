@@ -0,0 +1,63 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bturcanu/OpenClause/pkg/approvals"
+	"github.com/bturcanu/OpenClause/pkg/auth"
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestCancelApproval_RequestingAgentOnly(t *testing.T) {
+	fa := &fakeApprovals{}
+	gw := &Gateway{
+		log:            slog.New(slog.NewTextHandler(bytes.NewBuffer(nil), nil)),
+		evidence:       newFakeEvidence(),
+		policy:         fakePolicy{decision: types.DecisionApprove, reason: "needs approval"},
+		connectors:     &fakeConnectors{},
+		approvals:      fa,
+		perTenantLimit: 100,
+	}
+	body, _ := json.Marshal(types.ToolCallRequest{TenantID: "tenant1", AgentID: "agent-1", Tool: "jira", Action: "issue.delete", IdempotencyKey: "c1"})
+	if rr := postToolCall(t, gw, body); rr.Code != http.StatusOK {
+		t.Fatalf("tool call = %d %s", rr.Code, rr.Body.String())
+	}
+
+	r := chi.NewRouter()
+	r.With(auth.APIKeyAuth(auth.NewKeyStore("tenant1:key1,tenant2:key2"))).Post("/v1/approvals/requests/{id}/cancel", gw.HandleCancelApproval)
+	cancel := func(apiKey, body string) int {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/v1/approvals/requests/req-1/cancel", strings.NewReader(body))
+		req.Header.Set("X-API-Key", apiKey)
+		r.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := cancel("key2", `{"agent_id":"agent-1"}`); code != http.StatusNotFound {
+		t.Fatalf("other tenant = %d, want 404", code)
+	}
+	if code := cancel("key1", `{"agent_id":"agent-2"}`); code != http.StatusForbidden {
+		t.Fatalf("other agent = %d, want 403", code)
+	}
+	if code := cancel("key1", `{"agent_id":"agent-1","reason":"user aborted"}`); code != http.StatusOK || fa.requests[0].Status != "cancelled" {
+		t.Fatalf("cancel = %d, request %+v", code, fa.requests[0])
+	}
+	if code := cancel("key1", `{"agent_id":"agent-1"}`); code != http.StatusConflict {
+		t.Fatalf("second cancel = %d, want 409", code)
+	}
+
+	// A request resolved after it was read is a conflict, not a failure.
+	fa.requests[0].Status = "pending"
+	fa.cancelErr = fmt.Errorf("cancel: %w", approvals.ErrNotPending)
+	if code := cancel("key1", `{"agent_id":"agent-1"}`); code != http.StatusConflict {
+		t.Fatalf("cancel of a request resolved concurrently = %d, want 409", code)
+	}
+}
@@ -0,0 +1,110 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/bturcanu/OpenClause/pkg/connectors"
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+// changeTicketIssue is the jira.issue.create params of a change ticket.
+type changeTicketIssue struct {
+	Project     string `json:"project"`
+	Summary     string `json:"summary"`
+	Description string `json:"description"`
+	IssueType   string `json:"issue_type,omitempty"`
+}
+
+// fileChangeTicket files the change ticket required by the policy result
+// of parent, an approved call that ran as execEventID under grantID, and
+// records it in result. Nothing is filed unless policy set the
+// change_ticket requirement and the execution succeeded. A failure to
+// file is recorded in the ticket; the execution stands.
+func (gw *Gateway) fileChangeTicket(ctx context.Context, parent *types.ToolCallEnvelope, execEventID, grantID string, result *types.ExecutionResult) {
+	pr := parent.PolicyResult
+	if result == nil || result.Status != "success" || pr == nil {
+		return
+	}
+	spec, ok := pr.Requirements[types.RequirementChangeTicket]
+	if !ok {
+		return
+	}
+	project, issueType, _ := strings.Cut(spec, ":")
+	ticket := &types.ChangeTicket{Project: project}
+	result.ChangeTicket = ticket
+	if project == "" {
+		ticket.Error = "change_ticket requirement names no project"
+		return
+	}
+
+	req := parent.Request
+	params, err := json.Marshal(changeTicketIssue{
+		Project:     project,
+		Summary:     fmt.Sprintf("Change: %s on %s by agent %s", req.ToolAction(), orNone(req.Resource), req.AgentID),
+		Description: changeTicketDescription(parent, execEventID, grantID),
+		IssueType:   issueType,
+	})
+	if err != nil {
+		ticket.Error = err.Error()
+		return
+	}
+	resp, err := gw.connectors.Exec(ctx, connectors.ExecRequest{
+		EventID:  execEventID,
+		TenantID: req.TenantID,
+		AgentID:  req.AgentID,
+		Tool:     "jira",
+		Action:   "issue.create",
+		Params:   params,
+		Resource: types.NewResourceURI("jira", "project", project).String(),
+	})
+	switch {
+	case err != nil:
+		ticket.Error = err.Error()
+	case resp.Status != "success":
+		ticket.Error = resp.Error
+	default:
+		var created struct {
+			Key string `json:"key"`
+		}
+		if err := json.Unmarshal(resp.OutputJSON, &created); err != nil || created.Key == "" {
+			ticket.Error = "connector returned no issue key"
+		}
+		ticket.Key = created.Key
+	}
+	if ticket.Error != "" {
+		gw.log.WarnContext(ctx, "change ticket not filed", "event_id", parent.EventID, "project", project, "error", ticket.Error)
+	}
+}
+
+// changeTicketDescription is the body of the change ticket of parent.
+func changeTicketDescription(parent *types.ToolCallEnvelope, execEventID, grantID string) string {
+	req := parent.Request
+	lines := []string{
+		"Executed by OpenClause after approval.",
+		"Tenant: " + req.TenantID,
+		"Agent: " + req.AgentID,
+		"Action: " + req.ToolAction(),
+		"Resource: " + orNone(req.Resource),
+		fmt.Sprintf("Risk score: %d", parent.RiskScore()),
+		"Approved event: " + parent.EventID,
+		"Execution event: " + execEventID,
+		"Grant: " + grantID,
+	}
+	if pr := parent.PolicyResult; pr != nil && pr.Reason != "" {
+		lines = append(lines, "Policy reason: "+pr.Reason)
+	}
+	if req.TraceID != "" {
+		lines = append(lines, "Trace: "+req.TraceID)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
@@ -0,0 +1,97 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+func TestExecute_FilesChangeTicket(t *testing.T) {
+	for name, tc := range map[string]struct {
+		fail               bool
+		wantKey, wantError string
+	}{
+		"filed":  {wantKey: "CHG-7"},
+		"failed": {fail: true, wantError: "upstream failed"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			const parentID = "00000000-0000-0000-0000-000000000031"
+			fe := newFakeEvidence()
+			fe.events[parentID] = &types.ToolCallEnvelope{
+				EventID:  parentID,
+				Request:  types.ToolCallRequest{TenantID: "tenant1", AgentID: "agent-1", Tool: "k8s", Action: "deployment.scale", Resource: "prod/api", RiskScore: 9},
+				Decision: types.DecisionApprove,
+				PolicyResult: &types.PolicyResult{
+					Decision:     types.DecisionApprove,
+					Reason:       "production change",
+					Requirements: map[string]string{types.RequirementChangeTicket: "CHG:Change"},
+				},
+			}
+			fc := &fakeConnectors{output: json.RawMessage(`{"key":"CHG-7"}`), failActions: map[string]bool{}}
+			fc.failActions["issue.create"] = tc.fail
+			gw := newExecuteGateway(fe, fc, &fakeApprovals{usesLeft: 1})
+
+			rr := executeRequest(t, gw, parentID)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("execute = %d %s", rr.Code, rr.Body.String())
+			}
+			var resp types.ToolCallResponse
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Result.Status != "success" {
+				t.Fatalf("execution = %+v", resp.Result)
+			}
+			ticket := resp.Result.ChangeTicket
+			if ticket == nil || ticket.Project != "CHG" || ticket.Key != tc.wantKey || ticket.Error != tc.wantError {
+				t.Fatalf("change ticket = %+v", ticket)
+			}
+			if env, _ := fe.GetEvent(t.Context(), resp.EventID); env.ExecutionResult.ChangeTicket == nil {
+				t.Fatal("change ticket missing from evidence")
+			}
+
+			if fc.calls != 2 || fc.last.Tool != "jira" || fc.last.Action != "issue.create" {
+				t.Fatalf("connector calls = %d, last = %+v", fc.calls, fc.last)
+			}
+			var issue changeTicketIssue
+			if err := json.Unmarshal(fc.last.Params, &issue); err != nil {
+				t.Fatal(err)
+			}
+			if issue.Project != "CHG" || issue.IssueType != "Change" || issue.Summary != "Change: k8s.deployment.scale on prod/api by agent agent-1" {
+				t.Fatalf("issue = %+v", issue)
+			}
+		})
+	}
+}
+
+func TestFileChangeTicket_OnlyWhenRequiredAndSucceeded(t *testing.T) {
+	fc := &fakeConnectors{output: json.RawMessage(`{"key":"CHG-1"}`)}
+	gw := newExecuteGateway(newFakeEvidence(), fc, &fakeApprovals{})
+	required := &types.PolicyResult{Requirements: map[string]string{types.RequirementChangeTicket: "CHG"}}
+
+	for name, tc := range map[string]struct {
+		pr     *types.PolicyResult
+		status string
+	}{
+		"not required": {&types.PolicyResult{}, "success"},
+		"no policy":    {nil, "success"},
+		"failed call":  {required, "error"},
+	} {
+		result := &types.ExecutionResult{Status: tc.status}
+		gw.fileChangeTicket(t.Context(), &types.ToolCallEnvelope{PolicyResult: tc.pr}, "e1", "g1", result)
+		if result.ChangeTicket != nil {
+			t.Errorf("%s: filed %+v", name, result.ChangeTicket)
+		}
+	}
+	if fc.calls != 0 {
+		t.Fatalf("connector called %d times", fc.calls)
+	}
+
+	result := &types.ExecutionResult{Status: "success"}
+	gw.fileChangeTicket(t.Context(), &types.ToolCallEnvelope{PolicyResult: &types.PolicyResult{Requirements: map[string]string{types.RequirementChangeTicket: ""}}}, "e1", "g1", result)
+	if result.ChangeTicket == nil || result.ChangeTicket.Error == "" || fc.calls != 0 {
+		t.Fatalf("empty project = %+v", result.ChangeTicket)
+	}
+}
@@ -0,0 +1,92 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/bturcanu/OpenClause/pkg/approvals"
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"github.com/go-chi/chi/v5"
+)
+
+// commentInput is the body of POST /v1/toolcalls/{event_id}/comments.
+type commentInput struct {
+	Body string `json:"body"`
+}
+
+// HandleCreateComment is POST /v1/toolcalls/{event_id}/comments. The
+// agent answers approvers on the thread of the latest approval request
+// of the event; the comment is recorded in the audit trail.
+func (gw *Gateway) HandleCreateComment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	var in commentInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		types.ErrBadRequest("invalid JSON body").WriteJSON(w)
+		return
+	}
+	req, apiErr := gw.eventApproval(ctx, chi.URLParam(r, "event_id"))
+	if apiErr != nil {
+		apiErr.WriteJSON(w)
+		return
+	}
+	c, apiErr := approvals.NewComment(req, req.AgentID, approvals.RoleRequester, in.Body)
+	if apiErr != nil {
+		apiErr.WriteJSON(w)
+		return
+	}
+	stored, err := gw.approvals.AddComment(ctx, c)
+	if err != nil {
+		gw.log.ErrorContext(ctx, "add comment failed", "request_id", req.ID, "error", err)
+		types.ErrInternal("failed to add comment").WriteJSON(w)
+		return
+	}
+	body := struct {
+		approvals.Comment
+		AgentID string `json:"agent_id"`
+	}{Comment: *stored, AgentID: req.AgentID}
+	if err := gw.audit.Record(ctx, "approval_comment", req.TenantID, req.EventID, "", body); err != nil {
+		gw.log.ErrorContext(ctx, "audit log write failed", "request_id", req.ID, "error", err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(stored)
+}
+
+// HandleListComments is GET /v1/toolcalls/{event_id}/comments, the thread
+// of the latest approval request of the event, oldest first.
+func (gw *Gateway) HandleListComments(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	req, apiErr := gw.eventApproval(ctx, chi.URLParam(r, "event_id"))
+	if apiErr != nil {
+		apiErr.WriteJSON(w)
+		return
+	}
+	comments, err := gw.approvals.ListComments(ctx, []string{req.ID})
+	if err != nil {
+		gw.log.ErrorContext(ctx, "list comments failed", "request_id", req.ID, "error", err)
+		types.ErrInternal("failed to list comments").WriteJSON(w)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(approvals.CommentsPage{Comments: comments})
+}
+
+// eventApproval returns the latest approval request of event eventID of
+// the authenticated tenant.
+func (gw *Gateway) eventApproval(ctx context.Context, eventID string) (*approvals.ApprovalRequest, *types.APIError) {
+	env, apiErr := gw.tenantEvent(ctx, eventID)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	reqs, err := gw.approvals.ListRequestsByEvents(ctx, env.Request.TenantID, []string{eventID})
+	if err != nil {
+		gw.log.ErrorContext(ctx, "list approval requests failed", "event_id", eventID, "error", err)
+		return nil, types.ErrInternal("failed to retrieve approval request")
+	}
+	if len(reqs) == 0 {
+		return nil, types.ErrNotFound("event has no approval request")
+	}
+	return &reqs[len(reqs)-1], nil
+}
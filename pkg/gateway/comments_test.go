@@ -0,0 +1,65 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bturcanu/OpenClause/pkg/approvals"
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestComments_AgentAnswersOnApprovalThread(t *testing.T) {
+	fe := newFakeEvidence()
+	fa := &fakeApprovals{}
+	gw := &Gateway{
+		log:            slog.New(slog.NewTextHandler(bytes.NewBuffer(nil), nil)),
+		evidence:       fe,
+		policy:         fakePolicy{decision: types.DecisionApprove, reason: "needs approval"},
+		connectors:     &fakeConnectors{},
+		approvals:      fa,
+		perTenantLimit: 100,
+	}
+	body, _ := json.Marshal(types.ToolCallRequest{TenantID: "tenant1", AgentID: "agent-1", Tool: "jira", Action: "issue.delete", IdempotencyKey: "c1"})
+	rr := postToolCall(t, gw, body)
+	var resp types.ToolCallResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil || resp.Decision != types.DecisionApprove {
+		t.Fatalf("tool call = %+v, %v", resp, err)
+	}
+
+	r := chi.NewRouter()
+	r.Get("/v1/toolcalls/{event_id}/comments", gw.HandleListComments)
+	r.Post("/v1/toolcalls/{event_id}/comments", gw.HandleCreateComment)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rr
+	}
+	path := "/v1/toolcalls/" + resp.EventID + "/comments"
+	if rr := do(http.MethodPost, path, `{"body":"Deleting the duplicate of OPS-2"}`); rr.Code != http.StatusCreated {
+		t.Fatalf("create = %d %s", rr.Code, rr.Body.String())
+	}
+	if rr := do(http.MethodPost, path, `{"body":""}`); rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("empty comment = %d, want 422", rr.Code)
+	}
+	if rr := do(http.MethodPost, "/v1/toolcalls/00000000-0000-0000-0000-000000000000/comments", `{"body":"hi"}`); rr.Code != http.StatusNotFound {
+		t.Fatalf("unknown event = %d, want 404", rr.Code)
+	}
+
+	rr = do(http.MethodGet, path, "")
+	var page approvals.CommentsPage
+	if err := json.NewDecoder(rr.Body).Decode(&page); err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Comments) != 1 {
+		t.Fatalf("comments = %+v", page.Comments)
+	}
+	if c := page.Comments[0]; c.RequestID != "req-1" || c.Author != "agent-1" || c.Role != approvals.RoleRequester {
+		t.Fatalf("comment = %+v", c)
+	}
+}
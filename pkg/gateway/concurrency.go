@@ -0,0 +1,112 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// concurrencyRetryAfterSec is the Retry-After hint sent when an agent has
+// too many calls in flight; slots free as soon as a call returns.
+const concurrencyRetryAfterSec = 1
+
+// agentSlots counts each agent's calls in flight on this replica. The zero
+// value is ready to use.
+type agentSlots struct {
+	mu       sync.Mutex
+	inFlight map[agentKey]int
+}
+
+type agentKey struct {
+	tenantID, agentID string
+}
+
+// acquire takes one of the agent's limit slots. It returns false when all
+// are taken; otherwise release frees the slot and may be called more than
+// once.
+func (s *agentSlots) acquire(k agentKey, limit int) (release func(), ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.inFlight == nil {
+		s.inFlight = make(map[agentKey]int)
+	}
+	if s.inFlight[k] >= limit {
+		return nil, false
+	}
+	s.inFlight[k]++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if s.inFlight[k]--; s.inFlight[k] <= 0 {
+				delete(s.inFlight, k)
+			}
+		})
+	}, true
+}
+
+// concurrencyDetails is the details of a 429 CONCURRENCY_LIMITED.
+type concurrencyDetails struct {
+	AgentID    string `json:"agent_id"`
+	Limit      int    `json:"limit"`
+	RetryAfter int    `json:"retry_after_sec"`
+}
+
+// agentConcurrency returns how many calls each of the tenant's agents may
+// have in flight: its settings' agent_max_concurrent_executions when set,
+// else AGENT_MAX_CONCURRENT_EXECUTIONS. 0 is unlimited.
+func (gw *Gateway) agentConcurrency(ctx context.Context, tenantID string) int {
+	settings, err := gw.settings.Get(ctx, tenantID)
+	if err != nil {
+		gw.log.WarnContext(ctx, "tenant settings lookup failed, using default agent concurrency", "tenant_id", tenantID, "error", err)
+		return gw.agentMaxConcurrent
+	}
+	if settings.AgentMaxConcurrentExecutions > 0 {
+		return settings.AgentMaxConcurrentExecutions
+	}
+	return gw.agentMaxConcurrent
+}
+
+// acquireAgentSlot takes one of req's agent's in-flight slots while the
+// call executes. When the agent has none left it returns the 429 to send
+// instead; release is then nil.
+func (gw *Gateway) acquireAgentSlot(ctx context.Context, req types.ToolCallRequest) (release func(), limited *concurrencyDetails) {
+	limit := gw.agentConcurrency(ctx, req.TenantID)
+	if limit <= 0 {
+		return func() {}, nil
+	}
+	release, ok := gw.agentSlots.acquire(agentKey{req.TenantID, req.AgentID}, limit)
+	if ok {
+		return release, nil
+	}
+	concurrencyLimited.Add(ctx, 1, metric.WithAttributes(attribute.String("tenant", req.TenantID)))
+	gw.log.WarnContext(ctx, "agent concurrency limit reached", "tenant_id", req.TenantID, "agent_id", req.AgentID, "limit", limit)
+	return nil, &concurrencyDetails{AgentID: req.AgentID, Limit: limit, RetryAfter: concurrencyRetryAfterSec}
+}
+
+// concurrencyError returns the 429 CONCURRENCY_LIMITED for d.
+func concurrencyError(d *concurrencyDetails) *types.APIError {
+	apiErr := types.ErrConcurrencyLimited()
+	apiErr.Details = d
+	return apiErr
+}
+
+// writeConcurrencyLimited writes 429 CONCURRENCY_LIMITED with Retry-After.
+func writeConcurrencyLimited(w http.ResponseWriter, d *concurrencyDetails) {
+	writeAPIError(w, concurrencyError(d))
+}
+
+// writeAPIError writes apiErr, with Retry-After when it is a
+// CONCURRENCY_LIMITED returned by process or processPlan.
+func writeAPIError(w http.ResponseWriter, apiErr *types.APIError) {
+	if d, ok := apiErr.Details.(*concurrencyDetails); ok {
+		w.Header().Set("Retry-After", strconv.Itoa(d.RetryAfter))
+	}
+	apiErr.WriteJSON(w)
+}
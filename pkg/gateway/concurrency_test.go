@@ -0,0 +1,160 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/tenants"
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+func TestAgentSlots_AcquireRelease(t *testing.T) {
+	var s agentSlots
+	a, b := agentKey{"tenant1", "agent-a"}, agentKey{"tenant1", "agent-b"}
+
+	rel1, ok := s.acquire(a, 2)
+	if !ok {
+		t.Fatal("first slot refused")
+	}
+	if _, ok := s.acquire(a, 2); !ok {
+		t.Fatal("second slot refused")
+	}
+	if _, ok := s.acquire(a, 2); ok {
+		t.Fatal("third slot granted over a limit of 2")
+	}
+	if _, ok := s.acquire(b, 2); !ok {
+		t.Fatal("another agent was limited")
+	}
+
+	rel1()
+	rel1() // a second release must not free another slot
+	if _, ok := s.acquire(a, 2); !ok {
+		t.Fatal("released slot not reusable")
+	}
+	if _, ok := s.acquire(a, 2); ok {
+		t.Fatal("double release freed two slots")
+	}
+}
+
+func TestHandleToolCall_ConcurrencyLimited(t *testing.T) {
+	fc := &fakeConnectors{output: json.RawMessage(`{}`)}
+	gw := newExecuteGateway(newFakeEvidence(), fc, &fakeApprovals{})
+	gw.perTenantLimit, gw.agentMaxConcurrent = 100, 1
+
+	post := func(agent, key string) *http.Response {
+		body, _ := json.Marshal(types.ToolCallRequest{TenantID: "tenant1", AgentID: agent, Tool: "slack", Action: "msg.post", IdempotencyKey: key})
+		return postToolCall(t, gw, body).Result()
+	}
+
+	// Another call by agent-1 is in flight.
+	release, ok := gw.agentSlots.acquire(agentKey{"tenant1", "agent-1"}, 1)
+	if !ok {
+		t.Fatal("could not take slot")
+	}
+	resp := post("agent-1", "k1")
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") != "1" {
+		t.Fatalf("busy agent = %d Retry-After %q, want 429 and 1", resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+	var apiErr struct {
+		Code    string             `json:"code"`
+		Details concurrencyDetails `json:"details"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&apiErr); err != nil {
+		t.Fatal(err)
+	}
+	if apiErr.Code != "CONCURRENCY_LIMITED" || apiErr.Details.AgentID != "agent-1" || apiErr.Details.Limit != 1 {
+		t.Fatalf("error = %+v", apiErr)
+	}
+	if fc.calls != 0 {
+		t.Fatalf("connector called %d times for a refused call", fc.calls)
+	}
+
+	if resp := post("agent-2", "k2"); resp.StatusCode != http.StatusOK {
+		t.Fatalf("other agent = %d, want 200", resp.StatusCode)
+	}
+
+	// The refused call was not recorded, so its key can be retried.
+	release()
+	if resp := post("agent-1", "k1"); resp.StatusCode != http.StatusOK {
+		t.Fatalf("retry after release = %d, want 200", resp.StatusCode)
+	}
+	if n := len(gw.agentSlots.inFlight); n != 0 {
+		t.Fatalf("%d agents still hold slots after their calls returned", n)
+	}
+}
+
+func TestHandleToolCall_OnlyExecutingCallsTakeSlots(t *testing.T) {
+	for _, d := range []types.Decision{types.DecisionDeny, types.DecisionApprove} {
+		gw := newExecuteGateway(newFakeEvidence(), &fakeConnectors{}, &fakeApprovals{})
+		gw.perTenantLimit, gw.agentMaxConcurrent = 100, 1
+		gw.policy = fakePolicy{decision: d}
+		if _, ok := gw.agentSlots.acquire(agentKey{"tenant1", "agent-1"}, 1); !ok {
+			t.Fatal("could not take slot")
+		}
+		body, _ := json.Marshal(types.ToolCallRequest{TenantID: "tenant1", AgentID: "agent-1", Tool: "slack", Action: "msg.post", IdempotencyKey: "k-" + string(d)})
+		if rr := postToolCall(t, gw, body); rr.Code != http.StatusOK {
+			t.Fatalf("%s call from a busy agent = %d, want 200", d, rr.Code)
+		}
+	}
+}
+
+func TestScheduler_DefersBusyAgent(t *testing.T) {
+	const parentID = "00000000-0000-0000-0000-000000000047"
+	fe := newFakeEvidence()
+	approvedEvent(fe, parentID)
+	fc := &fakeConnectors{output: json.RawMessage(`{}`)}
+	gw := newExecuteGateway(fe, fc, &fakeApprovals{usesLeft: 1})
+	gw.agentMaxConcurrent = 1
+	r := scheduleRouter(gw)
+
+	// Scheduling does not execute, so it needs no slot.
+	release, _ := gw.agentSlots.acquire(agentKey{"tenant1", "agent-1"}, 1)
+	at := time.Now().Add(time.Minute).UTC().Truncate(time.Second)
+	if rr := scheduleCall(t, r, http.MethodPost, "/v1/toolcalls/"+parentID+"/execute", `{"execute_at":"`+at.Format(time.RFC3339)+`"}`); rr.Code != http.StatusAccepted {
+		t.Fatalf("schedule from a busy agent = %d %s", rr.Code, rr.Body)
+	}
+
+	gw.runDueSchedules(t.Context(), at)
+	if fc.calls != 0 {
+		t.Fatal("schedule ran over the agent's concurrency cap")
+	}
+	if s, _ := fe.GetSchedule(t.Context(), parentID); s.Status != types.ScheduleScheduled {
+		t.Fatalf("deferred schedule status = %q, want scheduled", s.Status)
+	}
+
+	release()
+	gw.runDueSchedules(t.Context(), at)
+	if fc.calls != 1 {
+		t.Fatalf("connector calls after release = %d, want 1", fc.calls)
+	}
+}
+
+func TestAgentConcurrency_UsesTenantSettings(t *testing.T) {
+	gw := newExecuteGateway(newFakeEvidence(), &fakeConnectors{}, &fakeApprovals{})
+	gw.agentMaxConcurrent = 4
+	gw.settings = tenants.NewSettingsCache(fakeSettings{"strict": {AgentMaxConcurrentExecutions: 1}}, time.Minute)
+	ctx := context.Background()
+
+	if n := gw.agentConcurrency(ctx, "strict"); n != 1 {
+		t.Fatalf("strict tenant = %d, want 1", n)
+	}
+	if n := gw.agentConcurrency(ctx, "other"); n != 4 {
+		t.Fatalf("default tenant = %d, want 4", n)
+	}
+
+	req := types.ToolCallRequest{TenantID: "strict", AgentID: "a1"}
+	release, limited := gw.acquireAgentSlot(ctx, req)
+	if limited != nil {
+		t.Fatalf("first call limited: %+v", limited)
+	}
+	if _, limited := gw.acquireAgentSlot(ctx, req); limited == nil || limited.Limit != 1 || limited.RetryAfter != concurrencyRetryAfterSec {
+		t.Fatalf("second call = %+v, want limited at 1", limited)
+	}
+	release()
+	if _, limited := gw.acquireAgentSlot(ctx, req); limited != nil {
+		t.Fatalf("call after release limited: %+v", limited)
+	}
+}
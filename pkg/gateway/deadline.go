@@ -0,0 +1,46 @@
+package gateway
+
+import (
+	"context"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/approvals"
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+// withDeadline bounds ctx by req's deadline, when it has one. Only the
+// work done on the caller's behalf (policy, connector) is bounded; evidence
+// is recorded on the unbounded context so a late call is still recorded.
+func withDeadline(ctx context.Context, req types.ToolCallRequest) (context.Context, context.CancelFunc) {
+	if req.Deadline == nil {
+		return ctx, func() {}
+	}
+	return context.WithDeadline(ctx, *req.Deadline)
+}
+
+// deadlinePassed reports whether req's deadline is at or before now.
+func deadlinePassed(req types.ToolCallRequest, now time.Time) bool {
+	return req.Deadline != nil && !now.Before(*req.Deadline)
+}
+
+// deadlineDenial is the decision on a call whose deadline has passed.
+func deadlineDenial() *types.PolicyResult {
+	return &types.PolicyResult{
+		Decision:   types.DecisionDeny,
+		Reason:     "deadline passed before the call could be decided",
+		ReasonCode: types.ReasonCodeDeadlineExceeded,
+	}
+}
+
+// capApprovalTTL keeps an approval request from outliving the call's
+// deadline: a later approval could no longer be acted on.
+func capApprovalTTL(in *approvals.CreateApprovalInput, req types.ToolCallRequest, now time.Time) {
+	if req.Deadline == nil {
+		return
+	}
+	remaining := req.Deadline.Sub(now)
+	if in.RequestTTL() <= remaining {
+		return
+	}
+	in.ExpiresInSec = max(int(remaining/time.Second), 1)
+}
@@ -0,0 +1,133 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/admission"
+	"github.com/bturcanu/OpenClause/pkg/tenants"
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+func TestDeadline_PassedIsDeniedWithoutExecuting(t *testing.T) {
+	fe := newFakeEvidence()
+	fc := &fakeConnectors{output: json.RawMessage(`{"ok":true}`)}
+	gw := newExecuteGateway(fe, fc, &fakeApprovals{})
+	gw.perTenantLimit = 100
+
+	requested := time.Now().UTC().Add(-time.Minute)
+	deadline := requested.Add(time.Second)
+	body, _ := json.Marshal(types.ToolCallRequest{
+		TenantID: "tenant1", AgentID: "agent-1", Tool: "slack", Action: "msg.post",
+		IdempotencyKey: "late", RequestedAt: requested, Deadline: &deadline,
+	})
+	rr := postToolCall(t, gw, body)
+	var resp types.ToolCallResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Decision != types.DecisionDeny || resp.ReasonCode != types.ReasonCodeDeadlineExceeded || fc.calls != 0 {
+		t.Fatalf("late call = %+v after %d connector calls", resp, fc.calls)
+	}
+	if env := fe.events[resp.EventID]; env == nil || env.PolicyResult.ReasonCode != types.ReasonCodeDeadlineExceeded {
+		t.Fatalf("denial not recorded: %+v", env)
+	}
+}
+
+func TestDeadline_BoundsApprovalAndConnector(t *testing.T) {
+	fe := newFakeEvidence()
+	fc := &fakeConnectors{output: json.RawMessage(`{"ok":true}`)}
+	fa := &fakeApprovals{}
+	gw := newExecuteGateway(fe, fc, fa)
+	gw.perTenantLimit = 100
+	gw.policy = fakePolicy{decision: types.DecisionApprove, reason: "needs approval"}
+
+	deadline := time.Now().UTC().Add(10 * time.Minute)
+	body, _ := json.Marshal(types.ToolCallRequest{
+		TenantID: "tenant1", AgentID: "agent-1", Tool: "slack", Action: "msg.post",
+		IdempotencyKey: "urgent", Deadline: &deadline,
+	})
+	rr := postToolCall(t, gw, body)
+	var resp types.ToolCallResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if ttl := fa.last.RequestTTL(); ttl > 10*time.Minute || ttl < 9*time.Minute {
+		t.Fatalf("approval TTL = %s, want capped to the deadline", ttl)
+	}
+
+	fa.usesLeft = 1
+	if rr := executeRequest(t, gw, resp.EventID); rr.Code != http.StatusOK {
+		t.Fatalf("execute: %d %s", rr.Code, rr.Body.String())
+	}
+	if fc.last.Deadline == nil || !fc.last.Deadline.Equal(deadline) {
+		t.Fatalf("connector deadline = %v, want %v", fc.last.Deadline, deadline)
+	}
+}
+
+func TestDeadline_ExecuteAfterDeadlineKeepsGrant(t *testing.T) {
+	const parentID = "00000000-0000-0000-0000-000000000011"
+	fe := newFakeEvidence()
+	fc := &fakeConnectors{}
+	fa := &fakeApprovals{usesLeft: 1}
+	gw := newExecuteGateway(fe, fc, fa)
+
+	deadline := time.Now().Add(-time.Second)
+	fe.events[parentID] = &types.ToolCallEnvelope{
+		EventID: parentID,
+		Request: types.ToolCallRequest{
+			TenantID: "tenant1", AgentID: "agent-1", Tool: "slack", Action: "msg.post",
+			IdempotencyKey: "expired", Deadline: &deadline,
+		},
+		Decision: types.DecisionApprove,
+	}
+	if rr := executeRequest(t, gw, parentID); rr.Code != http.StatusForbidden || fa.usesLeft != 1 || fc.calls != 0 {
+		t.Fatalf("execute after deadline = %d with %d grant uses left, %d connector calls", rr.Code, fa.usesLeft, fc.calls)
+	}
+}
+
+func TestRequestPriority_DeclaredUpToTenantMax(t *testing.T) {
+	read := types.ToolCallRequest{Action: "channel.list", RiskScore: 1}
+	if got := requestPriority(read, true, ""); got != admission.PriorityLow {
+		t.Fatalf("undeclared read = %s, want low", got)
+	}
+	read.Priority = types.PriorityHigh
+	if got := requestPriority(read, true, ""); got != admission.PriorityNormal {
+		t.Fatalf("declared high without max_priority = %s, want normal", got)
+	}
+	if got := requestPriority(read, true, types.PriorityHigh); got != admission.PriorityHigh {
+		t.Fatalf("declared high under max_priority high = %s", got)
+	}
+	if got := requestPriority(read, true, types.PriorityLow); got != admission.PriorityLow {
+		t.Fatalf("declared high under max_priority low = %s, want low", got)
+	}
+	risky := types.ToolCallRequest{Action: "issue.delete", RiskScore: 9, Priority: types.PriorityLow}
+	if got := requestPriority(risky, false, ""); got != admission.PriorityLow {
+		t.Fatalf("declared low = %s", got)
+	}
+	risky.Priority = ""
+	if got := requestPriority(risky, false, types.PriorityNormal); got != admission.PriorityNormal {
+		t.Fatalf("undeclared high risk under max_priority normal = %s, want normal", got)
+	}
+	if got := requestPriority(risky, false, types.PriorityHigh); got != admission.PriorityHigh {
+		t.Fatalf("undeclared high risk under max_priority high = %s, want high", got)
+	}
+}
+
+func TestAdmissionPriority_UsesTenantSettings(t *testing.T) {
+	gw := newExecuteGateway(newFakeEvidence(), &fakeConnectors{}, &fakeApprovals{})
+	gw.settings = tenants.NewSettingsCache(fakeSettings{"trusted": {MaxPriority: types.PriorityHigh}}, time.Minute)
+	ctx := context.Background()
+
+	req := types.ToolCallRequest{TenantID: "trusted", Action: "msg.post", Priority: types.PriorityHigh}
+	if got := gw.admissionPriority(ctx, req); got != admission.PriorityHigh {
+		t.Fatalf("trusted tenant = %s, want high", got)
+	}
+	req.TenantID = "other"
+	if got := gw.admissionPriority(ctx, req); got != admission.PriorityNormal {
+		t.Fatalf("other tenant = %s, want normal", got)
+	}
+}
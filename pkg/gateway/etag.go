@@ -0,0 +1,43 @@
+package gateway
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+// eventETag is the strong ETag of an envelope served by GET
+// /v1/toolcalls/{event_id}. A recorded event never changes, so its chain
+// hash identifies it; the execution result is stored separately and
+// arrives later, so a digest of it is appended once it is set. Events
+// without a hash get no ETag.
+func eventETag(env *types.ToolCallEnvelope) string {
+	if env.Hash == "" {
+		return ""
+	}
+	tag := env.Hash
+	if env.ExecutionResult != nil {
+		raw, err := json.Marshal(env.ExecutionResult)
+		if err != nil {
+			return ""
+		}
+		sum := sha256.Sum256(raw)
+		tag += "." + hex.EncodeToString(sum[:8])
+	}
+	return `"` + tag + `"`
+}
+
+// etagMatches reports whether an If-None-Match header value matches etag,
+// using the weak comparison RFC 9110 prescribes for it.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
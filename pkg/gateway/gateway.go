@@ -0,0 +1,1782 @@
+// Package gateway is the single entrypoint for AI agent tool-call
+// requests. It validates, evaluates policy, routes to connectors, and
+// records evidence. cmd/gateway runs it as a service; cmd/openclause runs it
+// alongside the approvals service in one process.
+package gateway
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/admission"
+	"github.com/bturcanu/OpenClause/pkg/approvals"
+	"github.com/bturcanu/OpenClause/pkg/auth"
+	"github.com/bturcanu/OpenClause/pkg/config"
+	"github.com/bturcanu/OpenClause/pkg/connectors"
+	"github.com/bturcanu/OpenClause/pkg/credentials"
+	"github.com/bturcanu/OpenClause/pkg/dashboard"
+	"github.com/bturcanu/OpenClause/pkg/diagnostics"
+	"github.com/bturcanu/OpenClause/pkg/eventbus"
+	"github.com/bturcanu/OpenClause/pkg/events"
+	"github.com/bturcanu/OpenClause/pkg/evidence"
+	"github.com/bturcanu/OpenClause/pkg/injection"
+	"github.com/bturcanu/OpenClause/pkg/metering"
+	"github.com/bturcanu/OpenClause/pkg/mysqldb"
+	ocOtel "github.com/bturcanu/OpenClause/pkg/otel"
+	"github.com/bturcanu/OpenClause/pkg/pgpool"
+	"github.com/bturcanu/OpenClause/pkg/policy"
+	"github.com/bturcanu/OpenClause/pkg/receipts"
+	"github.com/bturcanu/OpenClause/pkg/replication"
+	"github.com/bturcanu/OpenClause/pkg/secrets"
+	"github.com/bturcanu/OpenClause/pkg/siem"
+	"github.com/bturcanu/OpenClause/pkg/tenants"
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"github.com/bturcanu/OpenClause/policy/bundles"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	maxBodyBytes     = 1 << 20 // 1 MB
+	maxRateLimiters  = 10_000
+	executePollCount = 5
+
+	// compensateIdempotencyPrefix keys the undo of an execution, so each
+	// execution is compensated at most once.
+	compensateIdempotencyPrefix = "compensate:"
+)
+
+// Options are what a process embedding the gateway shares with it. Zero
+// fields are built from the environment, as cmd/gateway does.
+type Options struct {
+	// Pool is the Postgres pool; nil connects with the POSTGRES_* and
+	// PG_POOL_* settings, except in lite mode, which runs without one.
+	Pool *pgxpool.Pool
+	// Evidence records tool-call events; nil opens the EVIDENCE_BACKEND
+	// store.
+	Evidence evidence.EventStore
+	// ConnectorTransport carries connector calls and health probes; nil
+	// uses HTTP. A connectors.Local serves inproc:// routes in-process.
+	ConnectorTransport http.RoundTripper
+	// Audit receives the audit trail of recorded events; nil opens the
+	// AUDIT_LOG_* destinations as service oc-gateway.
+	Audit *evidence.AuditLogger
+	// Env holds settings for variables the process environment leaves
+	// unset, such as those openclause.LoadConfig reads from a file.
+	Env config.Env
+}
+
+// Server is a gateway built by New: its HTTP API and the background work
+// and connections behind it.
+type Server struct {
+	handler http.Handler
+	cancel  context.CancelFunc
+	closers []func(context.Context) error
+}
+
+// New builds the gateway from the environment without serving it, so a
+// service embedding it can mount Handler on its own server. ctx bounds the
+// gateway's background work; Close stops it and releases what New opened.
+// Configuration must already be loaded.
+func New(ctx context.Context, log *slog.Logger, opts Options) (_ *Server, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	s := &Server{cancel: cancel}
+	env := opts.Env
+	defer func() {
+		if err != nil {
+			_ = s.Close(context.Background())
+		}
+	}()
+
+	// ── Postgres ─────────────────────────────────────────────────────────
+	// Lite mode runs without Postgres: no tenant store, metering or budgets,
+	// so every tenant gets the default settings.
+	lite := env.Get("OC_MODE") == config.LiteMode
+	pool := opts.Pool
+	if pool == nil && !lite {
+		pool, err = pgpool.New(ctx, pgpool.DSNFromEnv(env), pgpool.ConfigFromEnv(env))
+		if err != nil {
+			return nil, fmt.Errorf("gateway.New: postgres connect: %w", err)
+		}
+		s.onClose(func(context.Context) error { pool.Close(); return nil })
+		if err := pgpool.RegisterMetrics(pool, "postgres"); err != nil {
+			log.Error("register pool metrics failed", "error", err)
+		}
+	}
+
+	// ── MySQL (optional) ─────────────────────────────────────────────────
+	// A supplied evidence store is opaque: /readyz does not probe it and the
+	// dashboard, which reads the Postgres tables, stays off.
+	evidenceBackend := env.Or("EVIDENCE_BACKEND", "postgres")
+	if opts.Evidence != nil {
+		evidenceBackend = ""
+	}
+	approvalsBackend := env.Or("APPROVALS_BACKEND", "postgres")
+	var mysqlDB *sql.DB
+	if evidenceBackend == "mysql" || approvalsBackend == "mysql" {
+		mysqlDB, err = mysqldb.Open(ctx, env.Get("MYSQL_DSN"))
+		if err != nil {
+			return nil, fmt.Errorf("gateway.New: mysql connect: %w", err)
+		}
+		s.onClose(func(context.Context) error { return mysqlDB.Close() })
+	}
+
+	// ── Dependencies ─────────────────────────────────────────────────────
+	canonVersion, err := evidence.ParseCanonVersion(env.Get("EVIDENCE_CANONICAL_JSON"))
+	if err != nil {
+		return nil, fmt.Errorf("gateway.New: invalid EVIDENCE_CANONICAL_JSON: %w", err)
+	}
+	evidenceStore := opts.Evidence
+	switch evidenceBackend {
+	case "": // opts.Evidence
+	case "postgres":
+		pgStore := evidence.NewStore(pool)
+		pgStore.SetCanonVersion(canonVersion)
+		evidenceStore = pgStore
+	case "mysql":
+		mysqlStore := evidence.NewMySQLStore(mysqlDB)
+		mysqlStore.SetCanonVersion(canonVersion)
+		evidenceStore = mysqlStore
+	case "sqlite":
+		sqliteStore, err := evidence.OpenSQLite(ctx, env.Or("EVIDENCE_SQLITE_PATH", "openclause-evidence.db"))
+		if err != nil {
+			return nil, fmt.Errorf("gateway.New: sqlite evidence store open: %w", err)
+		}
+		s.onClose(func(context.Context) error { return sqliteStore.Close() })
+		sqliteStore.SetCanonVersion(canonVersion)
+		evidenceStore = sqliteStore
+	default:
+		return nil, fmt.Errorf("gateway.New: unknown EVIDENCE_BACKEND %q", evidenceBackend)
+	}
+	evidenceLogger := evidence.NewLogger(evidenceStore, log)
+	audit := opts.Audit
+	if audit == nil {
+		if audit, err = evidence.OpenAuditLog(evidence.AuditConfigFromEnv(env, "oc-gateway")); err != nil {
+			return nil, fmt.Errorf("gateway.New: audit log setup: %w", err)
+		}
+		s.onClose(func(context.Context) error { return audit.Close() })
+	}
+	if audit != nil {
+		evidenceLogger.SetAuditLog(audit)
+	}
+	bus, err := eventbus.New(eventbus.Config{
+		Driver:      env.Get("EVENTBUS_DRIVER"),
+		URL:         env.Get("EVENTBUS_URL"),
+		TopicPrefix: env.Get("EVENTBUS_TOPIC_PREFIX"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("gateway.New: event bus setup: %w", err)
+	}
+	if bus != nil {
+		evidenceLogger.AddSink(bus)
+		s.onClose(func(context.Context) error { return bus.Close() })
+	}
+	policyEngine, err := policyFromEnv(env)
+	if err != nil {
+		return nil, fmt.Errorf("gateway.New: policy setup: %w", err)
+	}
+	var approvalsStore gatewayApprovals
+	switch approvalsBackend {
+	case "postgres":
+		approvalsStore = approvals.NewStore(pool)
+	case "mysql":
+		approvalsStore = approvals.NewMySQLStore(mysqlDB)
+	case "sqlite":
+		sqliteStore, err := approvals.OpenSQLite(ctx, env.Or("APPROVALS_SQLITE_PATH", "openclause-approvals.db"))
+		if err != nil {
+			return nil, fmt.Errorf("gateway.New: sqlite approvals store open: %w", err)
+		}
+		s.onClose(func(context.Context) error { return sqliteStore.Close() })
+		approvalsStore = sqliteStore
+	default:
+		return nil, fmt.Errorf("gateway.New: unknown APPROVALS_BACKEND %q", approvalsBackend)
+	}
+	// ── Secrets ──────────────────────────────────────────────────────────
+	secretResolver := secrets.NewResolverFromEnv(env, log)
+	keyStore := auth.NewKeyStore("")
+	apiKeys, err := secretResolver.Bind(ctx, env.Get("API_KEYS"), keyStore.Replace)
+	if err != nil {
+		return nil, fmt.Errorf("gateway.New: resolve API_KEYS: %w", err)
+	}
+	keyStore.Replace(apiKeys.Get())
+	go secretResolver.Run(ctx, env.Duration("SECRETS_REFRESH_SEC", time.Second, 5*time.Minute))
+	if path := env.Get("SIEM_CONFIG_FILE"); path != "" {
+		siemRouter, err := siem.NewFromFile(ctx, path, env, secretResolver)
+		if err != nil {
+			return nil, fmt.Errorf("gateway.New: siem setup: %w", err)
+		}
+		evidenceLogger.AddSink(siemRouter)
+		s.onClose(func(context.Context) error { return siemRouter.Close() })
+	}
+
+	// ── Tenants ──────────────────────────────────────────────────────────
+	adminToken, err := secretResolver.Resolve(ctx, env.Get("ADMIN_API_TOKEN"))
+	if err != nil {
+		return nil, fmt.Errorf("gateway.New: resolve ADMIN_API_TOKEN: %w", err)
+	}
+	var (
+		tenantStore    *tenants.Store
+		tenantHandlers *tenants.Handlers
+		settingsCache  *tenants.SettingsCache
+		blocklist      *tenants.Blocklist
+		meter          *metering.Recorder
+		usageHandlers  *metering.Handlers
+		spend          gatewaySpend
+	)
+	if pool != nil {
+		tenantStore = tenants.NewStore(pool)
+		reloadKeys := func(ctx context.Context) {
+			hashes, disabled, err := tenantStore.AuthState(ctx)
+			if err != nil {
+				// Without tenant status a suspended tenant's keys would
+				// pass, so reject all keys until a reload succeeds.
+				log.Error("reload tenant api keys failed", "error", err)
+				keyStore.SetStateUnknown()
+				return
+			}
+			keyStore.SetProvisioned(hashes, disabled)
+		}
+		reloadKeys(ctx)
+		go func() {
+			// Picks up keys issued or revoked through other gateway replicas.
+			ticker := time.NewTicker(env.Duration("TENANT_KEYS_REFRESH_SEC", time.Second, 30*time.Second))
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					reloadKeys(ctx)
+				}
+			}
+		}()
+		tenantDefaults, err := tenants.ParseDefaults(env.Get("TENANT_DEFAULT_CONFIG"))
+		if err != nil {
+			return nil, fmt.Errorf("gateway.New: invalid TENANT_DEFAULT_CONFIG: %w", err)
+		}
+		tenantHandlers = tenants.NewHandlers(tenantStore, policyEngine, tenantDefaults, log)
+		tenantHandlers.OnChange = reloadKeys
+		meter = metering.NewRecorder(metering.NewStore(pool), log)
+		go meter.Run(ctx, env.Duration("METERING_FLUSH_SEC", time.Second, 10*time.Second))
+		s.onClose(meter.Flush)
+		usageHandlers = metering.NewHandlers(metering.NewStore(pool), log)
+		settingsCache = tenants.NewSettingsCache(tenantStore, env.Duration("TENANT_SETTINGS_CACHE_SEC", time.Second, 30*time.Second))
+		tenantHandlers.OnSettingsChange = settingsCache.Invalidate
+		if adminToken != "" {
+			// Tenant data reaches policy as data.tenants, kept current on
+			// every settings change.
+			policySync := tenants.NewPolicySync(tenantStore, policyEngine, log)
+			go policySync.Run(ctx, env.Duration("POLICY_DATA_SYNC_SEC", time.Second, 5*time.Minute))
+			tenantHandlers.OnSettingsChange = func(tenantID string) {
+				settingsCache.Invalidate(tenantID)
+				policySync.Notify(tenantID)
+			}
+		}
+		blocklist = tenants.NewBlocklist(tenantStore)
+		spend = metering.NewSpendStore(pool)
+	} else if adminToken != "" {
+		return nil, errors.New("gateway.New: ADMIN_API_TOKEN requires Postgres")
+	}
+	// Lifecycle CloudEvents go to the sinks each tenant subscribes to.
+	emitter := events.New(events.Config{
+		Source:    env.Or("EVENTS_SOURCE", "oc://gateway"),
+		QueueSize: env.Int("EVENTS_QUEUE_SIZE", 1000),
+	}, settingsCache.EventSubscriptions)
+	s.onClose(func(context.Context) error { return emitter.Close() })
+	egress, err := approvals.ParseEgressPolicy(env.Get("WEBHOOK_ALLOWED_DOMAINS"), env.Get("WEBHOOK_ALLOWED_CIDRS"))
+	if err != nil {
+		return nil, fmt.Errorf("gateway.New: %w", err)
+	}
+	emitter.SetEgress(&approvals.Egress{Default: egress, Tenants: settingsCache.WebhookEgress})
+	for ref, raw := range approvals.ParseSecretRefMap(env.Get("WEBHOOK_SECRET_REFS")) {
+		secret, err := secretResolver.Bind(ctx, raw, func(v string) { emitter.SetSecret(ref, v) })
+		if err != nil {
+			return nil, fmt.Errorf("gateway.New: resolve WEBHOOK_SECRET_REFS %s: %w", ref, err)
+		}
+		emitter.SetSecret(ref, secret.Get())
+	}
+	// Execution results also go to each tenant's result sinks; queue
+	// sinks need the event bus.
+	var resultQueue events.ResultQueue
+	if bus != nil {
+		resultQueue = bus
+	}
+	emitter.SetResultSinks(settingsCache.ResultSinks, resultQueue)
+	evidenceLogger.AddSink(emitter)
+
+	connectorReg := connectors.NewRegistry()
+	connectorReg.Register("slack", env.Or("CONNECTOR_SLACK_URL", "http://localhost:8082"))
+	connectorReg.Register("jira", env.Or("CONNECTOR_JIRA_URL", "http://localhost:8083"))
+	connectorRoutes, err := connectors.ParseRoutes(env.Get("CONNECTOR_ROUTES"))
+	if err != nil {
+		return nil, fmt.Errorf("gateway.New: invalid CONNECTOR_ROUTES: %w", err)
+	}
+	for pattern, backends := range connectorRoutes {
+		if err := connectorReg.RegisterWeighted(pattern, backends...); err != nil {
+			return nil, fmt.Errorf("gateway.New: invalid CONNECTOR_ROUTES: %w", err)
+		}
+	}
+	if u := env.Get("CONNECTOR_FALLBACK_URL"); u != "" {
+		connectorReg.SetFallback(u)
+	}
+	connectorReg.SetInternalToken(env.Get("INTERNAL_AUTH_TOKEN"))
+	// Connectors are told both limits with each call.
+	connectorReg.SetTimeout(env.Duration("CONNECTOR_TIMEOUT_SEC", time.Second, 30*time.Second))
+	connectorReg.SetMaxOutputBytes(int64(env.Int("CONNECTOR_MAX_OUTPUT_BYTES", connectors.DefaultMaxOutputBytes)))
+	if opts.ConnectorTransport != nil {
+		connectorReg.SetTransport(opts.ConnectorTransport)
+	}
+
+	gw := &Gateway{
+		log:            log,
+		evidence:       evidenceLogger,
+		policy:         policyEngine,
+		connectors:     connectorReg,
+		approvals:      approvalsStore,
+		approvalsURL:   env.Or("APPROVALS_URL", "http://localhost:8081"),
+		perTenantLimit: env.Int("RATE_LIMIT_PER_TENANT", 100),
+		perTenantBurst: env.Int("RATE_LIMIT_BURST_PER_TENANT", 0),
+		settings:       settingsCache,
+		blocklist:      blocklist,
+		events:         emitter,
+		meter:          meter,
+		admission: admission.New(admission.Config{
+			MaxInFlight:   env.Int("GATEWAY_MAX_INFLIGHT", 512),
+			TargetLatency: env.Duration("GATEWAY_SHED_TARGET_LATENCY_MS", time.Millisecond, 2*time.Second),
+		}),
+		planTools:     make(map[string]bool),
+		manifests:     manifestCache{ttl: env.Duration("CONNECTOR_MANIFEST_CACHE_SEC", time.Second, 5*time.Minute)},
+		blobUploadTTL: env.Duration("BLOB_UPLOAD_TTL_SEC", time.Second, 15*time.Minute),
+		spend:         spend,
+
+		approvalContext: env.Int("APPROVAL_CONTEXT_EVENTS", 10),
+		audit:           audit,
+
+		agentMaxConcurrent: env.Int("AGENT_MAX_CONCURRENT_EXECUTIONS", 0),
+	}
+	if tenantStore != nil {
+		gw.tenants = tenantStore
+	}
+	// Approved calls scheduled with execute_at run from here.
+	go gw.RunScheduler(ctx, env.Duration("SCHEDULER_POLL_SEC", time.Second, 15*time.Second))
+	if env.Bool("INJECTION_DETECTION", true) {
+		gw.injection = injection.New(strings.Split(env.Get("INJECTION_BLOCKED_DOMAINS"), ","))
+	}
+	if err := registerRateLimitGauge(&gw.rateLimits); err != nil {
+		log.Error("register rate limit metrics failed", "error", err)
+	}
+	if gw.receipts, err = receiptSignerFromEnv(ctx, env, secretResolver); err != nil {
+		return nil, fmt.Errorf("gateway.New: receipt signing setup: %w", err)
+	}
+	if env.Bool("RESPONSE_SIGNING_ENABLED", false) {
+		if gw.receipts == nil {
+			return nil, errors.New("gateway.New: RESPONSE_SIGNING_ENABLED requires RECEIPT_SIGNING_KEY")
+		}
+		gw.signResponses = true
+	}
+	blobStore, err := blobStoreFromEnv(ctx, env, secretResolver)
+	if err != nil {
+		return nil, fmt.Errorf("gateway.New: blob store setup: %w", err)
+	}
+	if blobStore != nil {
+		gw.blobs = blobStore
+	}
+	if env.Bool("CONNECTOR_OUTPUT_SPILL", false) {
+		if blobStore == nil {
+			return nil, errors.New("gateway.New: CONNECTOR_OUTPUT_SPILL requires BLOB_S3_BUCKET")
+		}
+		gw.spillOutput = true
+	}
+	if mirrorURL := env.Get("MIRROR_URL"); mirrorURL != "" {
+		ratio, err := strconv.ParseFloat(env.Or("MIRROR_SAMPLE_RATIO", "1"), 64)
+		if err != nil || ratio < 0 || ratio > 1 {
+			return nil, fmt.Errorf("gateway.New: MIRROR_SAMPLE_RATIO %q is not a ratio between 0 and 1", env.Get("MIRROR_SAMPLE_RATIO"))
+		}
+		mirrorToken, err := secretResolver.Resolve(ctx, env.Get("MIRROR_TOKEN"))
+		if err != nil {
+			return nil, fmt.Errorf("gateway.New: resolve MIRROR_TOKEN: %w", err)
+		}
+		gw.mirror = newMirror(log, mirrorURL, mirrorToken, ratio, env.Int("MIRROR_QUEUE_SIZE", 1000))
+		go gw.mirror.Run(ctx)
+	}
+	mirrorReceiveToken, err := secretResolver.Resolve(ctx, env.Get("MIRROR_RECEIVE_TOKEN"))
+	if err != nil {
+		return nil, fmt.Errorf("gateway.New: resolve MIRROR_RECEIVE_TOKEN: %w", err)
+	}
+	for _, tool := range strings.Split(env.Get("CONNECTOR_PLAN_TOOLS"), ",") {
+		if tool = strings.TrimSpace(tool); tool != "" {
+			gw.planTools[tool] = true
+		}
+	}
+
+	// ── Router ───────────────────────────────────────────────────────────
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(ocOtel.Middleware)
+	r.Use(middleware.RealIP)
+	r.Use(middleware.Recoverer)
+	r.Use(middleware.Timeout(30 * time.Second))
+	r.Use(middleware.Logger)
+
+	r.Get("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("OK"))
+	})
+	r.Get("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		if evidenceBackend == "postgres" || approvalsBackend == "postgres" {
+			err = pool.Ping(r.Context())
+		}
+		if err == nil && mysqlDB != nil {
+			err = mysqlDB.PingContext(r.Context())
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte("NOT READY"))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("OK"))
+	})
+	// Receipt verification is public: agents' log consumers hold no API key.
+	r.Get("/.well-known/jwks.json", gw.HandleJWKS)
+	r.Post("/v1/receipts/verify", gw.HandleVerifyReceipt)
+	var credHandlers *credentials.Handlers
+	if env.Bool("CONNECTOR_CREDENTIALS_ENABLED", false) {
+		if pool == nil {
+			return nil, errors.New("gateway.New: CONNECTOR_CREDENTIALS_ENABLED requires Postgres")
+		}
+		credCipher, err := credentials.CipherFromEnv(env)
+		if err != nil {
+			return nil, fmt.Errorf("gateway.New: connector credentials setup: %w", err)
+		}
+		credHandlers = credentials.NewHandlers(credentials.NewStore(pool, credCipher), log)
+	}
+	r.Group(func(r chi.Router) {
+		r.Use(auth.APIKeyAuth(keyStore))
+		r.Post("/v1/toolcalls", gw.HandleToolCall)
+		r.Get("/v1/toolcalls", gw.HandleListEvents)
+		r.Get("/v1/toolcalls/{event_id}", gw.HandleGetEvent)
+		r.Post("/v1/toolcalls/{event_id}/execute", gw.HandleExecuteToolCall)
+		r.Post("/v1/toolcalls/{event_id}/compensate", gw.HandleCompensateToolCall)
+		r.Get("/v1/toolcalls/{event_id}/schedule", gw.HandleGetSchedule)
+		r.Post("/v1/toolcalls/{event_id}/schedule/cancel", gw.HandleCancelSchedule)
+		r.Get("/v1/toolcalls/{event_id}/output", gw.HandleGetOutput)
+		r.Get("/v1/toolcalls/{event_id}/comments", gw.HandleListComments)
+		r.Post("/v1/toolcalls/{event_id}/comments", gw.HandleCreateComment)
+		r.Post("/v1/approvals/requests/{id}/cancel", gw.HandleCancelApproval)
+		r.Post("/v1/plans", gw.HandleSubmitPlan)
+		r.Post("/v1/blobs", gw.HandleCreateBlobUpload)
+		r.Get("/v1/traces/{trace_id}", gw.HandleGetTrace)
+		r.Get("/v1/tools/spec", gw.HandleToolSpec)
+		if usageHandlers != nil {
+			usageHandlers.RegisterRoutes(r)
+		}
+	})
+	// Credentials are managed by operators, never by the tenant's agents.
+	if credHandlers != nil {
+		if adminToken == "" {
+			log.Warn("ADMIN_API_TOKEN is not set; the connector credential endpoints reject every request")
+		}
+		r.Group(func(r chi.Router) {
+			r.Use(auth.AdminAuth(adminToken))
+			credHandlers.RegisterRoutes(r)
+		})
+	}
+	// A staging gateway evaluates calls mirrored from production.
+	if mirrorReceiveToken != "" {
+		r.Group(func(r chi.Router) {
+			r.Use(mirrorAuth(mirrorReceiveToken))
+			r.Post("/v1/mirror/toolcalls", gw.HandleMirroredToolCall)
+		})
+	}
+	if tenantHandlers != nil && adminToken != "" {
+		r.Group(func(r chi.Router) {
+			r.Use(auth.AdminAuth(adminToken))
+			tenantHandlers.RegisterRoutes(r)
+			usageHandlers.RegisterAdminRoutes(r)
+			r.Post("/v1/admin/policies/validate", gw.HandleValidatePolicy)
+			// A standby region's replicator pulls the evidence log from here.
+			if rs, ok := evidenceStore.(evidence.ReplicaStore); ok {
+				replication.NewHandlers(rs, log).RegisterRoutes(r)
+			}
+		})
+	}
+	if env.Bool("DASHBOARD_ENABLED", false) {
+		if evidenceBackend != "postgres" || approvalsBackend != "postgres" {
+			return nil, errors.New("gateway.New: DASHBOARD_ENABLED requires the postgres evidence and approvals backends")
+		}
+		auditorTokens, err := secretResolver.Resolve(ctx, env.Get("AUDITOR_TOKENS"))
+		if err != nil {
+			return nil, fmt.Errorf("gateway.New: resolve AUDITOR_TOKENS: %w", err)
+		}
+		if adminToken == "" && auditorTokens == "" {
+			log.Warn("dashboard enabled but neither ADMIN_API_TOKEN nor AUDITOR_TOKENS is set; it will reject every request")
+		}
+		r.Group(func(r chi.Router) {
+			r.Use(dashboard.Auth(adminToken, auth.NewKeyStore(auditorTokens)))
+			dashboard.NewHandlers(dashboard.NewStore(pool), connectorReg, log).RegisterRoutes(r)
+		})
+	}
+	s.handler = r
+	return s, nil
+}
+
+// Handler returns the gateway's HTTP API, including /healthz and /readyz.
+func (s *Server) Handler() http.Handler { return s.handler }
+
+// Close stops the gateway's background work, flushes usage counters and
+// releases what New opened. Call it once requests have drained.
+func (s *Server) Close(ctx context.Context) error {
+	s.cancel()
+	var errs []error
+	for i := len(s.closers) - 1; i >= 0; i-- {
+		if err := s.closers[i](ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	s.closers = nil
+	return errors.Join(errs...)
+}
+
+func (s *Server) onClose(fn func(context.Context) error) {
+	s.closers = append(s.closers, fn)
+}
+
+// Run builds the gateway from the environment and serves it on
+// GATEWAY_ADDR, with metrics and diagnostics on METRICS_ADDR, until ctx is
+// done or the server fails. Configuration must already be loaded.
+func Run(ctx context.Context, log *slog.Logger, opts Options) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	s, err := New(ctx, log, opts)
+	if err != nil {
+		return err
+	}
+	env := opts.Env
+
+	// ── Metrics + diagnostics (internal) ────────────────────────────────
+	metricsAddr := env.Or("METRICS_ADDR", "127.0.0.1:9090")
+	metricsSrv := diagnostics.NewServer(diagnostics.Config{
+		Addr:          metricsAddr,
+		InternalToken: env.Get("INTERNAL_AUTH_TOKEN"),
+	})
+	go func() {
+		log.Info("metrics server starting", "addr", metricsAddr)
+		if err := metricsSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Error("metrics server error", "error", err)
+		}
+	}()
+
+	// ── Server ───────────────────────────────────────────────────────────
+	addr := env.Or("GATEWAY_ADDR", ":8080")
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           s.Handler(),
+		ReadTimeout:       15 * time.Second,
+		ReadHeaderTimeout: 5 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       60 * time.Second,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Info("gateway starting", "addr", addr)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			serveErr <- err
+			cancel()
+		}
+	}()
+
+	<-ctx.Done()
+	log.Info("shutting down gateway")
+	shutCtx, shutCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer shutCancel()
+	if err := srv.Shutdown(shutCtx); err != nil {
+		log.Error("server shutdown error", "error", err)
+	}
+	if err := metricsSrv.Shutdown(shutCtx); err != nil {
+		log.Error("metrics server shutdown error", "error", err)
+	}
+	if err := s.Close(shutCtx); err != nil {
+		log.Error("gateway close failed", "error", err)
+	}
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("gateway.Run: serve: %w", err)
+	default:
+		return nil
+	}
+}
+
+// tenantPolicy evaluates tool calls and holds the per-tenant documents the
+// tenant admin API writes.
+type tenantPolicy interface {
+	gatewayPolicy
+	tenants.PolicyData
+}
+
+// policyFromEnv returns the POLICY_ENGINE policy: OPA at OPA_URL, retried
+// and circuit-broken per OPA_RETRY_* and OPA_BREAKER_*, or the bundle in
+// POLICY_BUNDLE_DIR (else the default bundle) evaluated in process against
+// POLICY_DATA_FILE or the bundle's own data.json.
+func policyFromEnv(env config.Env) (tenantPolicy, error) {
+	switch engine := env.Or("POLICY_ENGINE", "opa"); engine {
+	case "opa":
+		c := policy.NewClient(env.Or("OPA_URL", "http://localhost:8181"))
+		c.SetRetryPolicy(policy.RetryPolicy{
+			MaxAttempts: env.Int("OPA_RETRY_MAX_ATTEMPTS", policy.DefaultRetryPolicy.MaxAttempts),
+			BaseDelay:   env.Duration("OPA_RETRY_BASE_DELAY_MS", time.Millisecond, policy.DefaultRetryPolicy.BaseDelay),
+			MaxDelay:    policy.DefaultRetryPolicy.MaxDelay,
+		})
+		c.SetCircuitBreaker(env.Int("OPA_BREAKER_THRESHOLD", policy.DefaultBreakerThreshold),
+			env.Duration("OPA_BREAKER_COOLDOWN_SEC", time.Second, policy.DefaultBreakerCooldown))
+		return c, nil
+	case "embedded":
+		modules, data := bundles.DefaultModules, bundles.DefaultData
+		if dir := env.Get("POLICY_BUNDLE_DIR"); dir != "" {
+			b, err := policy.ReadBundleDir(dir)
+			if err != nil {
+				return nil, err
+			}
+			modules, data = b.Modules, b.Data
+		}
+		if path := env.Get("POLICY_DATA_FILE"); path != "" {
+			b, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			data = b
+		}
+		return policy.NewEmbedded(modules, data)
+	default:
+		return nil, fmt.Errorf("unknown POLICY_ENGINE %q", engine)
+	}
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Gateway handler
+// ──────────────────────────────────────────────────────────────────────────────
+
+type Gateway struct {
+	log            *slog.Logger
+	evidence       gatewayEvidence
+	policy         gatewayPolicy
+	connectors     gatewayConnectors
+	approvals      gatewayApprovals
+	approvalsURL   string
+	rateLimits     tenantLimiters
+	perTenantLimit int
+	perTenantBurst int // 0 is twice perTenantLimit
+	settings       *tenants.SettingsCache
+	// tenants reports tenant status to the scheduler, which runs calls no
+	// API key authenticated; nil, in lite mode, treats every tenant as
+	// active.
+	tenants   gatewayTenants
+	blocklist *tenants.Blocklist
+	events    *events.Emitter
+	meter     *metering.Recorder
+	admission *admission.Controller
+	// agentMaxConcurrent caps each agent's calls in flight; 0 is unlimited.
+	agentMaxConcurrent int
+	agentSlots         agentSlots
+	// planTools are the tools whose calls that need approval carry the
+	// connector's plan. The registry only sends plan requests to connectors
+	// that advertise plan support.
+	planTools map[string]bool
+	manifests manifestCache
+	// receipts signs a receipt into every recorded response; nil disables
+	// receipts.
+	receipts *receipts.Signer
+	// signResponses adds a detached JWS of each tool-call response body,
+	// made with the receipt key, in the X-Response-Signature header.
+	signResponses bool
+	// blobs holds params sent by params_ref; nil disables POST /v1/blobs
+	// and params_ref.
+	blobs         gatewayBlobs
+	blobUploadTTL time.Duration
+	// spillOutput writes the full output of truncated connector responses
+	// to blobs.
+	spillOutput bool
+	// spend accumulates connector-reported cost against tenant budgets;
+	// nil leaves budgets out of policy input.
+	spend gatewaySpend
+	// injection scans params for prompt injection before policy runs; nil
+	// disables the scan.
+	injection *injection.Detector
+	// approvalContext is how many of the agent's latest calls an approval
+	// request carries for approvers; 0 disables.
+	approvalContext int
+	// audit records agent comments on and cancellations of approval
+	// requests and the scheduling of approved calls; nil skips them.
+	audit *evidence.AuditLogger
+	// mirror copies a sample of decided tool calls to a staging gateway;
+	// nil disables mirroring.
+	mirror *mirror
+}
+
+type gatewayEvidence interface {
+	RecordEvent(context.Context, *types.ToolCallEnvelope) error
+	CheckIdempotency(context.Context, string, string) (*types.ToolCallResponse, error)
+	GetEvent(context.Context, string) (*types.ToolCallEnvelope, error)
+	GetExecutionByParentEvent(context.Context, string) (*types.ToolCallResponse, error)
+	LinkExecutionToParent(context.Context, string, string, string) (bool, error)
+	ListTraceEvents(context.Context, string, string, int) ([]types.ToolCallEnvelope, error)
+	ListEvents(context.Context, string, evidence.EventFilter) ([]types.ToolCallEnvelope, error)
+	CreateSchedule(context.Context, *types.ScheduledExecution) error
+	GetSchedule(context.Context, string) (*types.ScheduledExecution, error)
+	CancelSchedule(context.Context, string) (*types.ScheduledExecution, error)
+	ClaimDueSchedules(context.Context, time.Time, time.Duration, int) ([]types.ScheduledExecution, error)
+	FinishSchedule(context.Context, string, string, string, string) error
+}
+
+type gatewayTenants interface {
+	Get(context.Context, string) (*tenants.Tenant, error)
+}
+
+type gatewayPolicy interface {
+	Evaluate(context.Context, types.PolicyInput) (*types.PolicyResult, error)
+}
+
+type gatewayConnectors interface {
+	Exec(context.Context, connectors.ExecRequest) (*connectors.ExecResponse, error)
+	Manifests(context.Context) ([]connectors.Manifest, map[string]string)
+}
+
+type gatewayApprovals interface {
+	CreateRequest(context.Context, approvals.CreateApprovalInput) (*approvals.ApprovalRequest, error)
+	FindAndConsumeGrant(context.Context, string, string, string, string, string, string, string) (*approvals.ApprovalGrant, error)
+	FindAndConsumeSessionGrant(context.Context, string, string, string, string, string, string, string) (*approvals.ApprovalGrant, error)
+	ListRequestsByEvents(context.Context, string, []string) ([]approvals.ApprovalRequest, error)
+	GetRequest(context.Context, string) (*approvals.ApprovalRequest, error)
+	CancelRequest(context.Context, string, approvals.CancelInput) error
+	AddComment(context.Context, approvals.Comment) (*approvals.Comment, error)
+	ListComments(context.Context, []string) ([]approvals.Comment, error)
+}
+
+// Schema negotiation headers on tool-call responses: the request schema
+// versions the gateway accepts, and the version a request was read as.
+const (
+	schemaVersionsHeader = "X-Schema-Versions"
+	schemaVersionHeader  = "X-Schema-Version"
+)
+
+// HandleToolCall is POST /v1/toolcalls
+func (gw *Gateway) HandleToolCall(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// 1. Parse + validate (with body size limit)
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	w.Header().Set(schemaVersionsHeader, strings.Join(types.SupportedSchemaVersions, ", "))
+	var req types.ToolCallRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		types.ErrBadRequest("invalid JSON body").WriteJSON(w)
+		return
+	}
+	if err := req.NormalizeAndValidate(); err != nil {
+		types.ErrValidation(err).WriteJSON(w)
+		return
+	}
+	w.Header().Set(schemaVersionHeader, req.SchemaVersion)
+	if req.CompensatesEventID != "" {
+		types.ErrBadRequest("compensates_event_id is set by the gateway; use POST /v1/toolcalls/{event_id}/compensate").WriteJSON(w)
+		return
+	}
+	if req.Tool == types.PlanTool {
+		types.ErrBadRequest(fmt.Sprintf("tool %q is reserved; submit plans to POST /v1/plans", types.PlanTool)).WriteJSON(w)
+		return
+	}
+	if req.PlanID != "" {
+		types.ErrBadRequest("plan_id is set by the gateway; submit plans to POST /v1/plans").WriteJSON(w)
+		return
+	}
+
+	// Override tenant from auth context
+	if t := auth.TenantFromContext(ctx); t != "" {
+		req.TenantID = t
+	}
+
+	// 2. Admission control: shed low-priority work first under overload,
+	// before the parent lookup and the params_ref read.
+	release, admitted := gw.admission.Admit(gw.admissionPriority(ctx, req))
+	if !admitted {
+		w.Header().Set("Retry-After", shedRetryAfterSec)
+		types.ErrOverloaded().WriteJSON(w)
+		return
+	}
+	defer release()
+
+	if apiErr := gw.resolveParent(ctx, &req); apiErr != nil {
+		apiErr.WriteJSON(w)
+		return
+	}
+	if apiErr := gw.verifyParamsRef(ctx, req); apiErr != nil {
+		apiErr.WriteJSON(w)
+		return
+	}
+
+	ctx, span := startToolCallSpan(ctx, &req)
+	defer span.End()
+
+	// 3. Rate limits
+	if limited := gw.allowRate(ctx, req); limited != nil {
+		writeRateLimited(w, limited)
+		return
+	}
+
+	// 4. Idempotency
+	prior, err := gw.evidence.CheckIdempotency(ctx, req.TenantID, req.IdempotencyKey)
+	if err != nil {
+		gw.log.ErrorContext(ctx, "idempotency check failed", "error", err)
+		types.ErrInternal("failed to validate idempotency").WriteJSON(w)
+		return
+	}
+	if prior != nil {
+		gw.writeResponse(ctx, w, prior)
+		return
+	}
+
+	resp, apiErr := gw.process(ctx, req)
+	if apiErr != nil {
+		writeAPIError(w, apiErr)
+		return
+	}
+	gw.mirror.Send(ctx, req, resp.Decision)
+	gw.writeResponse(ctx, w, resp)
+}
+
+// process records, evaluates and acts on an admitted tool call that passed
+// the rate limit and idempotency checks. A call that would execute while its
+// agent is at its concurrency cap is refused before anything is recorded.
+func (gw *Gateway) process(ctx context.Context, req types.ToolCallRequest) (*types.ToolCallResponse, *types.APIError) {
+	// 5. Build envelope
+	gw.meter.Add(req.TenantID, metering.Calls, 1)
+	eventID := uuid.NewString()
+	payloadJSON, err := json.Marshal(req)
+	if err != nil {
+		gw.log.ErrorContext(ctx, "payload marshal failed", "error", err)
+		return nil, types.ErrInternal("request processing failed")
+	}
+
+	env := &types.ToolCallEnvelope{
+		EventID:     eventID,
+		Request:     req,
+		PayloadJSON: payloadJSON,
+		ReceivedAt:  time.Now().UTC(),
+	}
+
+	// 6. Evaluate policy
+	policyResult := gw.evaluate(ctx, req)
+	env.Decision = policyResult.Decision
+	env.PolicyResult = policyResult
+	if score, ok := policyResult.AdjustedRiskScore(req.RiskScore); ok {
+		env.AdjustedRiskScore = &score
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("oc.decision", string(policyResult.Decision)))
+
+	// 7. Act on decision
+	resp := types.ToolCallResponse{
+		EventID:      eventID,
+		Decision:     policyResult.Decision,
+		Reason:       policyResult.Reason,
+		ReasonCode:   policyResult.ReasonCode,
+		MatchedRules: policyResult.MatchedRules,
+	}
+
+	switch policyResult.Decision {
+	case types.DecisionDeny:
+		if err := gw.evidence.RecordEvent(ctx, env); err != nil {
+			gw.log.ErrorContext(ctx, "evidence record failed", "error", err)
+		}
+
+	case types.DecisionApprove:
+		// A session grant would execute the call, so take the agent's slot
+		// before it can be consumed, and free it if there is none.
+		releaseSlot := func() {}
+		if req.SessionID != "" {
+			var busy *concurrencyDetails
+			if releaseSlot, busy = gw.acquireAgentSlot(ctx, req); busy != nil {
+				return nil, concurrencyError(busy)
+			}
+			defer releaseSlot()
+		}
+		// Record evidence first so the tool_events row exists before
+		// approval_requests references it via FK.
+		if err := gw.evidence.RecordEvent(ctx, env); err != nil {
+			gw.log.ErrorContext(ctx, "evidence record failed", "error", err)
+		} else if grant := gw.sessionGrant(ctx, eventID, req); grant != nil {
+			// A human already approved this tool and action for the session;
+			// execute under that grant instead of opening a new request.
+			granted, apiErr := gw.executeGranted(ctx, env, grant.ID, "approved by session grant "+grant.ID, nil)
+			if apiErr != nil {
+				return nil, apiErr
+			}
+			resp = *granted
+			break
+		}
+		releaseSlot()
+		approvalIn := approvals.CreateApprovalInput{
+			EventID:         eventID,
+			TenantID:        req.TenantID,
+			AgentID:         req.AgentID,
+			Tool:            req.Tool,
+			Action:          req.Action,
+			Resource:        req.Resource,
+			SessionID:       req.SessionID,
+			RiskScore:       env.RiskScore(),
+			RiskFactors:     req.RiskFactors,
+			Reason:          policyResult.Reason,
+			TraceID:         req.TraceID,
+			ApproverGroup:   policyResult.ApproverGroup,
+			Notify:          policyResult.Notify,
+			ExpiresInSec:    policyResult.ApprovalTTLSec,
+			GrantTTLSec:     policyResult.GrantTTLSec,
+			ApprovalBaseURL: gw.approvalsURL,
+			Plan:            gw.planConnector(ctx, eventID, req),
+			RecentActivity:  gw.recentActivity(ctx, req.TenantID, req.AgentID, eventID),
+		}
+		gw.routeApproval(ctx, &approvalIn, req)
+		capApprovalTTL(&approvalIn, req, time.Now())
+		approvalReq, err := gw.approvals.CreateRequest(ctx, approvalIn)
+		if err != nil {
+			gw.log.ErrorContext(ctx, "create approval failed", "error", err)
+		} else {
+			gw.meter.Add(req.TenantID, metering.Approvals, 1)
+			gw.events.PublishRequest(ctx, *approvalReq)
+			resp.ApprovalURL = fmt.Sprintf("%s/v1/approvals/requests/%s", gw.approvalsURL, approvalReq.ID)
+		}
+
+	case types.DecisionAllow:
+		releaseSlot, busy := gw.acquireAgentSlot(ctx, req)
+		if busy != nil {
+			return nil, concurrencyError(busy)
+		}
+		defer releaseSlot()
+		env.ExecutionResult = gw.executeConnector(ctx, eventID, req)
+		quarantineByPolicy(env.ExecutionResult, policyResult)
+		resp.Result = env.ExecutionResult
+
+		if err := gw.evidence.RecordEvent(ctx, env); err != nil {
+			gw.log.ErrorContext(ctx, "evidence record failed", "error", err)
+			return nil, types.ErrInternal("evidence recording failed after execution")
+		}
+		if env.ExecutionResult.Quarantine != nil {
+			gw.requestReview(ctx, env, &resp)
+		}
+
+	default:
+		// Fail-closed: treat unrecognized decisions as deny.
+		gw.log.ErrorContext(ctx, "unrecognized policy decision, defaulting to deny",
+			"decision", string(policyResult.Decision),
+			"event_id", eventID,
+		)
+		env.Decision = types.DecisionDeny
+		resp.Decision = types.DecisionDeny
+		resp.Reason = "unrecognized policy decision"
+		resp.ReasonCode = types.ReasonCodeInvalidDecision
+		if err := gw.evidence.RecordEvent(ctx, env); err != nil {
+			gw.log.ErrorContext(ctx, "evidence record failed", "error", err)
+		}
+	}
+
+	// A call run under a session grant already carries its execution's receipt.
+	if resp.Receipt == "" {
+		resp.Receipt = gw.receipt(ctx, env)
+	}
+	recordDecision(ctx, req, resp.Decision)
+	return &resp, nil
+}
+
+// evaluate asks the policy engine for a decision on req, failing closed.
+// Calls past their deadline, or that the tenant's blocklist, tool catalog
+// or a deny freeze window deny, are denied without asking; the policy
+// engine is given until the deadline to answer, along with the state of the
+// tenant's budgets and the injection findings in its params, read from the
+// blob when sent by params_ref. If the engine fails, the tenant's fallback
+// policy decides, or the call is denied when it has none. An open approve
+// freeze window escalates what policy allows to approve.
+func (gw *Gateway) evaluate(ctx context.Context, req types.ToolCallRequest) *types.PolicyResult {
+	if deadlinePassed(req, time.Now()) {
+		return deadlineDenial()
+	}
+	params, err := gw.loadParams(ctx, req)
+	if err != nil {
+		gw.log.ErrorContext(ctx, "read params blob failed", "error", err)
+		return &types.PolicyResult{Decision: types.DecisionDeny, Reason: "params_ref could not be read", ReasonCode: types.ReasonCodeStateUnavailable}
+	}
+	// The blob is read once: tenantDenial checks the loaded params.
+	loaded := req
+	loaded.Params, loaded.ParamsRef = params, nil
+	if res := gw.tenantDenial(ctx, loaded); res != nil {
+		return res
+	}
+	budgets, ok := gw.budgetStates(ctx, req)
+	if !ok {
+		return &types.PolicyResult{Decision: types.DecisionDeny, Reason: "budget state unavailable", ReasonCode: types.ReasonCodeStateUnavailable}
+	}
+	findings := gw.injection.Scan(params)
+	recordInjection(ctx, req, findings)
+	pctx, cancel := withDeadline(ctx, req)
+	defer cancel()
+	policyResult, err := gw.policy.Evaluate(pctx, types.PolicyInput{
+		ToolCall: req,
+		Environment: types.PolicyEnvironment{
+			Timestamp: time.Now().UTC(),
+		},
+		Resource:  types.NewPolicyResource(req.Resource),
+		Budgets:   budgets,
+		Injection: findings,
+	})
+	if err != nil {
+		if deadlinePassed(req, time.Now()) {
+			return deadlineDenial()
+		}
+		gw.log.ErrorContext(ctx, "policy evaluation failed", "error", err)
+		policyResult = gw.fallback(ctx, req, budgets, findings)
+	}
+	policyResult.Injection = findings
+	if policyResult.Decision == types.DecisionAllow {
+		gw.applyFreeze(ctx, req, policyResult)
+	}
+	return policyResult
+}
+
+// fallback decides req with the tenant's fallback policy after the policy
+// engine failed, or denies it when the tenant has none or its settings are
+// unavailable. Budgets and injection findings still apply.
+func (gw *Gateway) fallback(ctx context.Context, req types.ToolCallRequest, budgets []types.BudgetState, findings []types.InjectionFinding) *types.PolicyResult {
+	res, err := gw.settings.FallbackDecision(ctx, req, gw.isReadAction(ctx, req.Tool, req.Action), budgets, findings)
+	if err != nil {
+		gw.log.ErrorContext(ctx, "tenant fallback policy unavailable", "error", err)
+	}
+	if res == nil {
+		return &types.PolicyResult{Decision: types.DecisionDeny, Reason: "policy evaluation failed", ReasonCode: types.ReasonCodePolicyUnavailable}
+	}
+	recordFallback(ctx, req, res.Decision)
+	return res
+}
+
+// applyFreeze escalates an allowed req to approve while an approve freeze
+// window covers it. Deny windows are enforced by tenantDenial.
+func (gw *Gateway) applyFreeze(ctx context.Context, req types.ToolCallRequest, res *types.PolicyResult) {
+	f, err := gw.settings.ActiveFreeze(ctx, req.TenantID, req.Tool, req.Action, gw.isReadAction(ctx, req.Tool, req.Action), time.Now())
+	if err != nil {
+		gw.log.ErrorContext(ctx, "tenant freeze windows unavailable", "error", err)
+		*res = types.PolicyResult{Decision: types.DecisionDeny, Reason: "tenant freeze windows unavailable", ReasonCode: types.ReasonCodeStateUnavailable}
+		return
+	}
+	if f == nil || f.Decision != types.DecisionApprove {
+		return
+	}
+	res.Decision = types.DecisionApprove
+	res.Reason = fmt.Sprintf("freeze window %q requires approval: %s", f.Name, res.Reason)
+	res.ReasonCode = types.ReasonCodeFreezeWindow
+	res.FreezeWindow = f.Name
+}
+
+// HandleExecuteToolCall is POST /v1/toolcalls/{event_id}/execute.
+// It resumes an approval-gated request once a grant exists and records execution
+// as a new append-only evidence event linked to the parent event. A body
+// with a future execute_at consumes the grant now and schedules the
+// execution instead (202 with the schedule).
+func (gw *Gateway) HandleExecuteToolCall(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	parentEventID := chi.URLParam(r, "event_id")
+
+	ctx, span := tracer.Start(ctx, "gateway.ExecuteToolCall", trace.WithAttributes(
+		attribute.String("oc.parent_event_id", parentEventID),
+	))
+	defer span.End()
+
+	// Approved executions already carry a human decision; shed them last.
+	release, admitted := gw.admission.Admit(admission.PriorityHigh)
+	if !admitted {
+		w.Header().Set("Retry-After", shedRetryAfterSec)
+		types.ErrOverloaded().WriteJSON(w)
+		return
+	}
+	defer release()
+
+	if _, err := uuid.Parse(parentEventID); err != nil {
+		types.ErrBadRequest("invalid event_id format").WriteJSON(w)
+		return
+	}
+	// The body is optional; agents that run the call at once send none.
+	var in types.ExecuteRequest
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil && !errors.Is(err, io.EOF) {
+		types.ErrBadRequest("invalid JSON body").WriteJSON(w)
+		return
+	}
+
+	parent, err := gw.evidence.GetEvent(ctx, parentEventID)
+	if err != nil {
+		gw.log.ErrorContext(ctx, "get parent event failed", "event_id", parentEventID, "error", err)
+		types.ErrInternal("failed to retrieve event").WriteJSON(w)
+		return
+	}
+	if parent == nil {
+		types.ErrNotFound("event not found").WriteJSON(w)
+		return
+	}
+	authTenant := auth.TenantFromContext(ctx)
+	if authTenant != "" && parent.Request.TenantID != authTenant {
+		types.ErrNotFound("event not found").WriteJSON(w)
+		return
+	}
+	span.SetAttributes(
+		attribute.String("oc.tenant_id", parent.Request.TenantID),
+		attribute.String("oc.tool", parent.Request.Tool),
+		attribute.String("oc.action", parent.Request.Action),
+		attribute.String("oc.trace_id", parent.Request.TraceID),
+	)
+	if parent.Decision != types.DecisionApprove {
+		types.ErrConflict("event does not require approval execution").WriteJSON(w)
+		return
+	}
+
+	// Idempotent replay by parent event ID.
+	existing, err := gw.evidence.GetExecutionByParentEvent(ctx, parentEventID)
+	if err != nil {
+		gw.log.ErrorContext(ctx, "get linked execution failed", "event_id", parentEventID, "error", err)
+		types.ErrInternal("failed to retrieve prior execution").WriteJSON(w)
+		return
+	}
+	if existing != nil {
+		gw.writeResponse(ctx, w, existing)
+		return
+	}
+	// A scheduled call holds its grant until the scheduler runs it.
+	sched, err := gw.evidence.GetSchedule(ctx, parentEventID)
+	if err != nil {
+		gw.log.ErrorContext(ctx, "get schedule failed", "event_id", parentEventID, "error", err)
+		types.ErrInternal("failed to retrieve schedule").WriteJSON(w)
+		return
+	}
+	if sched != nil && (sched.Status == types.ScheduleScheduled || sched.Status == types.ScheduleRunning) {
+		writeSchedule(w, http.StatusAccepted, sched)
+		return
+	}
+	now := time.Now()
+	scheduled := in.ExecuteAt != nil && in.ExecuteAt.After(now)
+	if scheduled {
+		if apiErr := validateExecuteAt(parent.Request, *in.ExecuteAt, now); apiErr != nil {
+			apiErr.WriteJSON(w)
+			return
+		}
+	}
+
+	// An approval that came after the deadline cannot be acted on; refuse
+	// without using up the grant.
+	if deadlinePassed(parent.Request, now) {
+		types.ErrForbidden("deadline passed; the call was not executed").WriteJSON(w)
+		return
+	}
+
+	// The blocklist and catalog may have changed, or a freeze window opened,
+	// since the call was approved; check them before using up the grant.
+	if res := gw.tenantDenial(ctx, parent.Request); res != nil {
+		if res.Canary != "" {
+			gw.events.PublishCanary(ctx, parent, res)
+		}
+		types.ErrForbidden(res.Reason).WriteJSON(w)
+		return
+	}
+
+	// Refused before the grant is used up, so the agent can retry. A
+	// scheduled call takes its slot when the scheduler runs it.
+	if !scheduled {
+		releaseSlot, busy := gw.acquireAgentSlot(ctx, parent.Request)
+		if busy != nil {
+			writeConcurrencyLimited(w, busy)
+			return
+		}
+		defer releaseSlot()
+	}
+
+	grant, err := gw.approvals.FindAndConsumeGrant(
+		ctx,
+		parentEventID,
+		parent.Request.TenantID,
+		parent.Request.AgentID,
+		parent.Request.SessionID,
+		parent.Request.Tool,
+		parent.Request.Action,
+		parent.Request.Resource,
+	)
+	if err != nil {
+		gw.log.ErrorContext(ctx, "grant consume failed", "event_id", parentEventID, "error", err)
+		types.ErrInternal("failed to consume approval grant").WriteJSON(w)
+		return
+	}
+	if grant == nil {
+		// Handle race with an in-flight executor: brief replay polling before
+		// returning awaiting-approval.
+		for range executePollCount {
+			select {
+			case <-time.After(50 * time.Millisecond):
+			case <-ctx.Done():
+				types.ErrInternal("request cancelled").WriteJSON(w)
+				return
+			}
+			existing, err := gw.evidence.GetExecutionByParentEvent(ctx, parentEventID)
+			if err != nil {
+				gw.log.ErrorContext(ctx, "poll linked execution failed", "event_id", parentEventID, "error", err)
+				types.ErrInternal("failed to retrieve prior execution").WriteJSON(w)
+				return
+			}
+			if existing != nil {
+				gw.writeResponse(ctx, w, existing)
+				return
+			}
+		}
+		types.ErrConflict("awaiting approval").WriteJSON(w)
+		return
+	}
+
+	if scheduled {
+		sched, apiErr := gw.scheduleExecution(ctx, parent, grant.ID, *in.ExecuteAt)
+		if apiErr != nil {
+			apiErr.WriteJSON(w)
+			return
+		}
+		writeSchedule(w, http.StatusAccepted, sched)
+		return
+	}
+
+	resp, apiErr := gw.executeGranted(ctx, parent, grant.ID, "approved execution", nil)
+	if apiErr != nil {
+		apiErr.WriteJSON(w)
+		return
+	}
+	gw.writeResponse(ctx, w, resp)
+}
+
+// HandleCompensateToolCall is POST /v1/toolcalls/{event_id}/compensate.
+// It undoes an executed call with the compensation its connector declared.
+// The undo is a new tool call, evaluated by policy like any other, whose
+// request names the executed event in compensates_event_id. Each execution
+// is compensated at most once; repeats replay the first response.
+func (gw *Gateway) HandleCompensateToolCall(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	eventID := chi.URLParam(r, "event_id")
+
+	ctx, span := tracer.Start(ctx, "gateway.CompensateToolCall", trace.WithAttributes(
+		attribute.String("oc.compensates_event_id", eventID),
+	))
+	defer span.End()
+
+	// Undoing a change is remediation; shed it last.
+	release, admitted := gw.admission.Admit(admission.PriorityHigh)
+	if !admitted {
+		w.Header().Set("Retry-After", shedRetryAfterSec)
+		types.ErrOverloaded().WriteJSON(w)
+		return
+	}
+	defer release()
+
+	if _, err := uuid.Parse(eventID); err != nil {
+		types.ErrBadRequest("invalid event_id format").WriteJSON(w)
+		return
+	}
+	original, err := gw.evidence.GetEvent(ctx, eventID)
+	if err != nil {
+		gw.log.ErrorContext(ctx, "get event failed", "event_id", eventID, "error", err)
+		types.ErrInternal("failed to retrieve event").WriteJSON(w)
+		return
+	}
+	if original == nil {
+		types.ErrNotFound("event not found").WriteJSON(w)
+		return
+	}
+	if authTenant := auth.TenantFromContext(ctx); authTenant != "" && original.Request.TenantID != authTenant {
+		types.ErrNotFound("event not found").WriteJSON(w)
+		return
+	}
+
+	executedID, result, err := gw.executionOf(ctx, original)
+	if err != nil {
+		gw.log.ErrorContext(ctx, "get linked execution failed", "event_id", eventID, "error", err)
+		types.ErrInternal("failed to retrieve execution").WriteJSON(w)
+		return
+	}
+	switch {
+	case result == nil:
+		types.ErrConflict("event has not been executed").WriteJSON(w)
+		return
+	case result.Status != "success":
+		types.ErrConflict("execution did not succeed; nothing to compensate").WriteJSON(w)
+		return
+	case result.Compensation == nil:
+		types.ErrConflict(fmt.Sprintf("connector declared no compensation for %s", original.Request.ToolAction())).WriteJSON(w)
+		return
+	}
+
+	req := original.Request
+	req.Action = result.Compensation.Action
+	req.Params, req.ParamsRef = result.Compensation.Params, nil
+	if result.Compensation.Resource != "" {
+		req.Resource = result.Compensation.Resource
+	}
+	req.IdempotencyKey = compensateIdempotencyPrefix + executedID
+	req.CompensatesEventID = executedID
+	req.ParentEventID, req.PlanID = "", ""
+	req.Deadline = nil
+	req.RequestedAt = time.Time{}
+	if err := req.NormalizeAndValidate(); err != nil {
+		types.ErrValidation(err).WriteJSON(w)
+		return
+	}
+
+	if limited := gw.allowRate(ctx, req); limited != nil {
+		writeRateLimited(w, limited)
+		return
+	}
+	prior, err := gw.evidence.CheckIdempotency(ctx, req.TenantID, req.IdempotencyKey)
+	if err != nil {
+		gw.log.ErrorContext(ctx, "idempotency check failed", "error", err)
+		types.ErrInternal("failed to validate idempotency").WriteJSON(w)
+		return
+	}
+	if prior != nil {
+		gw.writeResponse(ctx, w, prior)
+		return
+	}
+
+	resp, apiErr := gw.process(ctx, req)
+	if apiErr != nil {
+		writeAPIError(w, apiErr)
+		return
+	}
+	gw.writeResponse(ctx, w, resp)
+}
+
+// executionOf returns the event that executed the call recorded as env, and
+// its result: env itself, or the execution linked to an approval-gated env.
+// The result is nil when the call never ran.
+func (gw *Gateway) executionOf(ctx context.Context, env *types.ToolCallEnvelope) (string, *types.ExecutionResult, error) {
+	if env.ExecutionResult != nil {
+		return env.EventID, env.ExecutionResult, nil
+	}
+	if env.Decision != types.DecisionApprove {
+		return "", nil, nil
+	}
+	linked, err := gw.evidence.GetExecutionByParentEvent(ctx, env.EventID)
+	if err != nil || linked == nil {
+		return "", nil, err
+	}
+	return linked.EventID, linked.Result, nil
+}
+
+// tenantDenial denies req when it, or any step of a recorded plan, touches
+// one of its tenant's canary resources or an entry of its blocklist, falls
+// outside its tool catalog, or is held back by an open deny freeze window.
+// It returns nil when none applies.
+func (gw *Gateway) tenantDenial(ctx context.Context, req types.ToolCallRequest) *types.PolicyResult {
+	calls := []types.ToolCallRequest{req}
+	if req.ParamsRef != nil {
+		params, err := gw.loadParams(ctx, req)
+		if err != nil {
+			gw.log.ErrorContext(ctx, "read params blob failed", "error", err)
+			return &types.PolicyResult{Decision: types.DecisionDeny, Reason: "params_ref could not be read", ReasonCode: types.ReasonCodeStateUnavailable}
+		}
+		calls[0].Params = params
+	}
+	if req.IsPlan() {
+		var steps []types.ToolCallRequest
+		if err := json.Unmarshal(req.Params, &steps); err != nil {
+			return &types.PolicyResult{Decision: types.DecisionDeny, Reason: "invalid plan steps", ReasonCode: types.ReasonCodeInvalidRequest}
+		}
+		calls = steps
+	}
+	canary, err := gw.settings.TouchedCanary(ctx, req.TenantID, calls...)
+	if err != nil {
+		gw.log.ErrorContext(ctx, "tenant canary resources unavailable", "error", err)
+		return &types.PolicyResult{Decision: types.DecisionDeny, Reason: "tenant canary resources unavailable", ReasonCode: types.ReasonCodeStateUnavailable}
+	}
+	if canary != nil {
+		return gw.canaryDenial(ctx, req, canary)
+	}
+	if reason := gw.blocklist.Denial(ctx, req.TenantID, calls...); reason != "" {
+		return &types.PolicyResult{Decision: types.DecisionDeny, Reason: reason, ReasonCode: types.ReasonCodeBlocklisted}
+	}
+	for _, c := range calls {
+		if reason := gw.settings.CatalogDenial(ctx, req.TenantID, c.Tool, c.Action); reason != "" {
+			return &types.PolicyResult{Decision: types.DecisionDeny, Reason: reason, ReasonCode: types.ReasonCodeNotInCatalog}
+		}
+	}
+	now := time.Now()
+	for _, c := range calls {
+		f, err := gw.settings.ActiveFreeze(ctx, req.TenantID, c.Tool, c.Action, gw.isReadAction(ctx, c.Tool, c.Action), now)
+		if err != nil {
+			gw.log.ErrorContext(ctx, "tenant freeze windows unavailable", "error", err)
+			return &types.PolicyResult{Decision: types.DecisionDeny, Reason: "tenant freeze windows unavailable", ReasonCode: types.ReasonCodeStateUnavailable}
+		}
+		if f != nil && f.Decision == types.DecisionDeny {
+			return &types.PolicyResult{
+				Decision:     types.DecisionDeny,
+				Reason:       fmt.Sprintf("%s is frozen by freeze window %q", c.ToolAction(), f.Name),
+				ReasonCode:   types.ReasonCodeFreezeWindow,
+				FreezeWindow: f.Name,
+			}
+		}
+	}
+	return nil
+}
+
+// canaryDenial denies req for touching canary, at the highest risk score so
+// that SIEM records and events carry it at top severity, and alerts on it.
+// The oc.alert.canary_touched event is raised when the denial is recorded,
+// or by the caller when it refuses an execution.
+func (gw *Gateway) canaryDenial(ctx context.Context, req types.ToolCallRequest, canary *tenants.Canary) *types.PolicyResult {
+	gw.log.ErrorContext(ctx, "canary resource touched",
+		"tenant_id", req.TenantID, "agent_id", req.AgentID, "session_id", req.SessionID,
+		"tool", req.Tool, "action", req.Action, "canary", canary.Name(), "kind", canary.Kind)
+	recordCanary(ctx, req, canary.Kind)
+	return &types.PolicyResult{
+		Decision:      types.DecisionDeny,
+		Reason:        fmt.Sprintf("%s %q is a canary resource", canary.Kind, canary.Name()),
+		ReasonCode:    types.ReasonCodeCanary,
+		RiskOverrides: map[string]int{types.RiskOverrideScore: 10},
+		Canary:        canary.Name(),
+	}
+}
+
+// resolveParent checks that req's parent_event_id names one of the tenant's
+// events and, when req has no trace_id, puts it in the parent's trace.
+func (gw *Gateway) resolveParent(ctx context.Context, req *types.ToolCallRequest) *types.APIError {
+	if req.ParentEventID == "" {
+		return nil
+	}
+	if _, err := uuid.Parse(req.ParentEventID); err != nil {
+		return types.ErrValidation(&types.ValidationError{Field: "parent_event_id", Reason: "must be an event ID"})
+	}
+	parent, err := gw.evidence.GetEvent(ctx, req.ParentEventID)
+	if err != nil {
+		gw.log.ErrorContext(ctx, "get parent event failed", "parent_event_id", req.ParentEventID, "error", err)
+		return types.ErrInternal("failed to retrieve parent event")
+	}
+	if parent == nil || parent.Request.TenantID != req.TenantID {
+		return types.ErrValidation(&types.ValidationError{Field: "parent_event_id", Reason: "event not found"})
+	}
+	if req.TraceID == "" {
+		req.TraceID = parent.Request.TraceID
+	}
+	return nil
+}
+
+// sessionGrant consumes, for event eventID, a grant scoped to the
+// request's session, if any. Lookup failures fall back to the normal
+// approval flow.
+func (gw *Gateway) sessionGrant(ctx context.Context, eventID string, req types.ToolCallRequest) *approvals.ApprovalGrant {
+	if req.SessionID == "" {
+		return nil
+	}
+	grant, err := gw.approvals.FindAndConsumeSessionGrant(ctx, eventID, req.TenantID, req.AgentID, req.SessionID, req.Tool, req.Action, req.Resource)
+	if err != nil {
+		gw.log.WarnContext(ctx, "session grant lookup failed", "session_id", req.SessionID, "error", err)
+		return nil
+	}
+	return grant
+}
+
+// executeGranted runs an approval-gated request under a consumed grant and
+// records the execution as a new evidence event linked to the parent event,
+// carrying over the parent's policy-adjusted risk score. sched is set when
+// the execution runs from a schedule. If a concurrent caller linked first,
+// its response is returned instead.
+func (gw *Gateway) executeGranted(ctx context.Context, parent *types.ToolCallEnvelope, grantID, reason string, sched *types.ExecutionSchedule) (*types.ToolCallResponse, *types.APIError) {
+	parentEventID, req := parent.EventID, parent.Request
+	execEventID := uuid.NewString()
+	payloadJSON, err := json.Marshal(req)
+	if err != nil {
+		gw.log.ErrorContext(ctx, "payload marshal failed", "event_id", parentEventID, "error", err)
+		return nil, types.ErrInternal("request processing failed")
+	}
+	var result *types.ExecutionResult
+	if req.IsPlan() {
+		result = gw.runRecordedPlan(ctx, parentEventID, req)
+	} else {
+		result = gw.executeConnector(ctx, execEventID, req)
+	}
+	result.Schedule = sched
+	quarantineByPolicy(result, parent.PolicyResult)
+	gw.fileChangeTicket(ctx, parent, execEventID, grantID, result)
+
+	env := &types.ToolCallEnvelope{
+		EventID:     execEventID,
+		Request:     req,
+		PayloadJSON: payloadJSON,
+		ReceivedAt:  time.Now().UTC(),
+		Decision:    types.DecisionAllow,
+		PolicyResult: &types.PolicyResult{
+			Decision: types.DecisionAllow,
+			Reason:   reason,
+		},
+		ExecutionResult:   result,
+		AdjustedRiskScore: parent.AdjustedRiskScore,
+	}
+	// Avoid conflicting with original request idempotency uniqueness constraint.
+	env.Request.IdempotencyKey = types.ExecIdempotencyPrefix + parentEventID
+	payloadJSON, err = json.Marshal(env.Request)
+	if err != nil {
+		gw.log.ErrorContext(ctx, "execution payload marshal failed", "event_id", parentEventID, "error", err)
+		return nil, types.ErrInternal("request processing failed")
+	}
+	env.PayloadJSON = payloadJSON
+
+	if err := gw.evidence.RecordEvent(ctx, env); err != nil {
+		gw.log.ErrorContext(ctx, "execution evidence record failed", "event_id", execEventID, "error", err)
+		return nil, types.ErrInternal("failed to record execution evidence")
+	}
+
+	linked, err := gw.evidence.LinkExecutionToParent(ctx, parentEventID, execEventID, grantID)
+	if err != nil {
+		gw.log.ErrorContext(ctx, "link execution failed", "parent_event_id", parentEventID, "execution_event_id", execEventID, "error", err)
+		return nil, types.ErrInternal("failed to finalize execution")
+	}
+	if !linked {
+		// Another concurrent request linked first; return canonical replay response.
+		prior, err := gw.evidence.GetExecutionByParentEvent(ctx, parentEventID)
+		if err != nil {
+			gw.log.ErrorContext(ctx, "get concurrent linked execution failed", "event_id", parentEventID, "error", err)
+			return nil, types.ErrInternal("failed to retrieve prior execution")
+		}
+		if prior != nil {
+			return prior, nil
+		}
+	}
+
+	resp := &types.ToolCallResponse{
+		EventID:  execEventID,
+		Decision: types.DecisionAllow,
+		Reason:   reason,
+		Result:   env.ExecutionResult,
+		Receipt:  gw.receipt(ctx, env),
+	}
+	if result.Quarantine != nil {
+		gw.requestReview(ctx, env, resp)
+	}
+	return resp, nil
+}
+
+// HandleGetEvent is GET /v1/toolcalls/{event_id}
+func (gw *Gateway) HandleGetEvent(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "event_id")
+
+	if _, err := uuid.Parse(eventID); err != nil {
+		types.ErrBadRequest("invalid event_id format").WriteJSON(w)
+		return
+	}
+
+	env, err := gw.evidence.GetEvent(r.Context(), eventID)
+	if err != nil {
+		gw.log.ErrorContext(r.Context(), "get event failed", "error", err)
+		types.ErrInternal("failed to retrieve event").WriteJSON(w)
+		return
+	}
+	if env == nil {
+		types.ErrNotFound("event not found").WriteJSON(w)
+		return
+	}
+	authTenant := auth.TenantFromContext(r.Context())
+	if authTenant != "" && env.Request.TenantID != authTenant {
+		types.ErrNotFound("event not found").WriteJSON(w)
+		return
+	}
+	// Released output is read from /output; the event never carries it.
+	env.ExecutionResult = env.ExecutionResult.Withheld()
+
+	// Pollers revalidate rather than re-download an unchanged envelope.
+	if etag := eventETag(env); etag != "" {
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "private, no-cache")
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(env); err != nil {
+		gw.log.ErrorContext(r.Context(), "response encode failed", "error", err)
+	}
+}
+
+// HandleListEvents is GET /v1/toolcalls?agent_id=&tool=&decision=&after_seq=&limit=.
+// It pages through the tenant's events in the order they were recorded.
+func (gw *Gateway) HandleListEvents(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	q := r.URL.Query()
+	tenantID := auth.TenantFromContext(ctx)
+	if tenantID == "" {
+		tenantID = q.Get("tenant_id")
+	}
+	if tenantID == "" {
+		types.ErrBadRequest("tenant_id is required").WriteJSON(w)
+		return
+	}
+	f := evidence.EventFilter{
+		AgentID:  q.Get("agent_id"),
+		Tool:     q.Get("tool"),
+		Decision: types.Decision(q.Get("decision")),
+		Limit:    evidence.DefaultListEvents,
+	}
+	switch f.Decision {
+	case "", types.DecisionAllow, types.DecisionDeny, types.DecisionApprove:
+	default:
+		types.ErrBadRequest("decision must be allow, deny or approve").WriteJSON(w)
+		return
+	}
+	if v := q.Get("after_seq"); v != "" {
+		seq, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || seq < 0 {
+			types.ErrBadRequest("invalid after_seq parameter").WriteJSON(w)
+			return
+		}
+		f.AfterSeq = seq
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > evidence.MaxListEvents {
+			types.ErrBadRequest(fmt.Sprintf("limit must be between 1 and %d", evidence.MaxListEvents)).WriteJSON(w)
+			return
+		}
+		f.Limit = limit
+	}
+
+	// One extra row tells whether another page follows.
+	want := f.Limit
+	f.Limit++
+	events, err := gw.evidence.ListEvents(ctx, tenantID, f)
+	if err != nil {
+		gw.log.ErrorContext(ctx, "list events failed", "error", err)
+		types.ErrInternal("failed to list events").WriteJSON(w)
+		return
+	}
+	for i := range events {
+		events[i].ExecutionResult = events[i].ExecutionResult.Withheld()
+	}
+	out := types.EventList{Events: events}
+	if len(events) > want {
+		out.Events = events[:want]
+		out.NextAfterSeq = out.Events[want-1].EventSeq
+	}
+	if out.Events == nil {
+		out.Events = []types.ToolCallEnvelope{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(out); err != nil {
+		gw.log.ErrorContext(ctx, "response encode failed", "error", err)
+	}
+}
+
+// planConnector asks the tool's connector to describe the call without
+// performing it. It returns nil when the connector does not plan or the plan
+// fails; the approval request is then created without one.
+func (gw *Gateway) planConnector(ctx context.Context, eventID string, req types.ToolCallRequest) *connectors.ExecPlan {
+	if !gw.planTools[req.Tool] {
+		return nil
+	}
+	params, err := gw.loadParams(ctx, req)
+	if err != nil {
+		gw.log.WarnContext(ctx, "connector plan skipped: params_ref unreadable", "error", err)
+		return nil
+	}
+	pin, err := gw.settings.ConnectorPin(ctx, req.TenantID, req.Tool)
+	if err != nil {
+		gw.log.WarnContext(ctx, "connector plan skipped: tenant connector pin unavailable", "error", err)
+		return nil
+	}
+	resp, err := gw.connectors.Exec(ctx, connectors.ExecRequest{
+		EventID:  eventID,
+		TenantID: req.TenantID,
+		AgentID:  req.AgentID,
+		Tool:     req.Tool,
+		Action:   req.Action,
+		Params:   params,
+		Resource: req.Resource,
+		Plan:     true,
+		Pin:      pin,
+	})
+	switch {
+	case err != nil:
+		gw.log.WarnContext(ctx, "connector plan failed", "tool", req.Tool, "action", req.Action, "error", err)
+		return nil
+	case resp.Status != "planned" || resp.Plan == nil:
+		gw.log.WarnContext(ctx, "connector returned no plan", "tool", req.Tool, "action", req.Action, "status", resp.Status, "error", resp.Error)
+		return nil
+	}
+	return resp.Plan
+}
+
+func (gw *Gateway) executeConnector(ctx context.Context, eventID string, req types.ToolCallRequest) *types.ExecutionResult {
+	gw.meter.Add(req.TenantID, metering.Executions, 1)
+	ctx, cancel := withDeadline(ctx, req)
+	defer cancel()
+	start := time.Now()
+	params, err := gw.loadParams(ctx, req)
+	if err != nil {
+		return &types.ExecutionResult{Status: "error", Error: "params_ref: " + err.Error()}
+	}
+	pin, err := gw.settings.ConnectorPin(ctx, req.TenantID, req.Tool)
+	if err != nil {
+		return &types.ExecutionResult{Status: "error", Error: "connector pin: " + err.Error()}
+	}
+	execResp, err := gw.connectors.Exec(ctx, connectors.ExecRequest{
+		EventID:  eventID,
+		TenantID: req.TenantID,
+		AgentID:  req.AgentID,
+		Tool:     req.Tool,
+		Action:   req.Action,
+		Params:   params,
+		Resource: req.Resource,
+		Deadline: req.Deadline,
+		Pin:      pin,
+	})
+	duration := time.Since(start)
+
+	if err != nil {
+		return &types.ExecutionResult{
+			Status:     "error",
+			Error:      err.Error(),
+			DurationMS: duration.Milliseconds(),
+		}
+	}
+	result := &types.ExecutionResult{
+		Status:     execResp.Status,
+		OutputJSON: execResp.OutputJSON,
+		Error:      execResp.Error,
+		DurationMS: duration.Milliseconds(),
+	}
+	if execResp.Cost > 0 && !math.IsInf(execResp.Cost, 0) {
+		result.Cost = execResp.Cost
+		gw.chargeSpend(ctx, req, result.Cost)
+	}
+	if c := execResp.Compensation; c != nil && execResp.Status == "success" {
+		result.Compensation = &types.Compensation{Action: c.Action, Params: c.Params, Resource: c.Resource}
+	}
+	if c := execResp.Connector; c != nil {
+		result.Connector = &types.ConnectorInfo{Name: c.Name, Version: c.Version, Endpoint: c.Endpoint, Backend: c.Backend}
+	}
+	if t := execResp.Truncation; t != nil {
+		result.Truncation = gw.outputTruncation(ctx, req.TenantID, t)
+	}
+	result.Quarantine = flaggedQuarantine(execResp.Status, execResp.Flags)
+	return result
+}
@@ -0,0 +1,148 @@
+package gateway
+
+import (
+	"context"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+var (
+	toolcallsTotal       metric.Int64Counter
+	rateLimitDecisions   metric.Int64Counter
+	rateLimiterEvictions metric.Int64Counter
+	concurrencyLimited   metric.Int64Counter
+	policyFallbacks      metric.Int64Counter
+	injectionFindings    metric.Int64Counter
+	canaryTouches        metric.Int64Counter
+	mirrorRequests       metric.Int64Counter
+	mirrorDecisions      metric.Int64Counter
+)
+
+func init() {
+	meter := otel.Meter("github.com/bturcanu/OpenClause/pkg/gateway")
+	var err error
+	toolcallsTotal, err = meter.Int64Counter("oc.toolcalls",
+		metric.WithDescription("Tool-call decisions by decision, tool, and tenant."),
+	)
+	if err != nil {
+		panic(err)
+	}
+	rateLimitDecisions, err = meter.Int64Counter("oc.ratelimit.requests",
+		metric.WithDescription("Rate-limit checks by tenant and outcome (allowed, limited)."),
+	)
+	if err != nil {
+		panic(err)
+	}
+	rateLimiterEvictions, err = meter.Int64Counter("oc.ratelimit.evictions",
+		metric.WithDescription("Tenant rate limiters evicted as least recently used."),
+	)
+	if err != nil {
+		panic(err)
+	}
+	concurrencyLimited, err = meter.Int64Counter("oc.concurrency.limited",
+		metric.WithDescription("Calls rejected because their agent had AGENT_MAX_CONCURRENT_EXECUTIONS calls in flight, by tenant."),
+	)
+	if err != nil {
+		panic(err)
+	}
+	policyFallbacks, err = meter.Int64Counter("oc.policy.fallbacks",
+		metric.WithDescription("Calls decided by a tenant fallback policy because the policy engine failed, by decision and tenant."),
+	)
+	if err != nil {
+		panic(err)
+	}
+	injectionFindings, err = meter.Int64Counter("oc.injection.findings",
+		metric.WithDescription("Prompt-injection findings in tool-call params, by rule and tenant."),
+	)
+	if err != nil {
+		panic(err)
+	}
+	mirrorRequests, err = meter.Int64Counter("oc.mirror.requests",
+		metric.WithDescription("Tool calls mirrored to a staging gateway, by tool and outcome (sent, failed, dropped)."),
+	)
+	if err != nil {
+		panic(err)
+	}
+	canaryTouches, err = meter.Int64Counter("oc.canary.touches",
+		metric.WithDescription("Calls denied for touching a tenant canary resource, by tenant and canary kind."),
+	)
+	if err != nil {
+		panic(err)
+	}
+	mirrorDecisions, err = meter.Int64Counter("oc.mirror.decisions",
+		metric.WithDescription("Mirrored tool calls evaluated by this gateway, by tool, tenant, and outcome (match, mismatch)."),
+	)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func recordDecision(ctx context.Context, req types.ToolCallRequest, decision types.Decision) {
+	toolcallsTotal.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("decision", string(decision)),
+		attribute.String("tool", req.Tool),
+		attribute.String("tenant", req.TenantID),
+	))
+}
+
+func recordFallback(ctx context.Context, req types.ToolCallRequest, decision types.Decision) {
+	policyFallbacks.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("decision", string(decision)),
+		attribute.String("tenant", req.TenantID),
+	))
+}
+
+func recordCanary(ctx context.Context, req types.ToolCallRequest, kind string) {
+	canaryTouches.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("tenant", req.TenantID),
+		attribute.String("kind", kind),
+	))
+}
+
+func recordInjection(ctx context.Context, req types.ToolCallRequest, findings []types.InjectionFinding) {
+	for _, f := range findings {
+		injectionFindings.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("rule", f.Rule),
+			attribute.String("tenant", req.TenantID),
+		))
+	}
+}
+
+func recordMirror(ctx context.Context, req types.ToolCallRequest, outcome string) {
+	mirrorRequests.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("tool", req.Tool),
+		attribute.String("outcome", outcome),
+	))
+}
+
+func recordMirrorDecision(ctx context.Context, req types.ToolCallRequest, match bool) {
+	outcome := "match"
+	if !match {
+		outcome = "mismatch"
+	}
+	mirrorDecisions.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("tool", req.Tool),
+		attribute.String("tenant", req.TenantID),
+		attribute.String("outcome", outcome),
+	))
+}
+
+// registerRateLimitGauge publishes the tokens left in each tracked tenant's
+// rate limiter, read on each collection.
+func registerRateLimitGauge(limiters *tenantLimiters) error {
+	meter := otel.Meter("github.com/bturcanu/OpenClause/pkg/gateway")
+	_, err := meter.Float64ObservableGauge("oc.ratelimit.tokens",
+		metric.WithDescription("Tokens available in each tenant's rate limiter."),
+		metric.WithFloat64Callback(func(_ context.Context, o metric.Float64Observer) error {
+			for tenantID, tokens := range limiters.tokens(time.Now()) {
+				o.Observe(tokens, metric.WithAttributes(attribute.String("tenant", tenantID)))
+			}
+			return nil
+		}),
+	)
+	return err
+}
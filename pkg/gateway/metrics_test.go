@@ -0,0 +1,46 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestRecordDecision_CountsByLabels(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+
+	req := types.ToolCallRequest{TenantID: "t1", Tool: "slack"}
+	recordDecision(context.Background(), req, types.DecisionAllow)
+	recordDecision(context.Background(), req, types.DecisionAllow)
+	recordDecision(context.Background(), req, types.DecisionDeny)
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]int64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != "oc.toolcalls" {
+				continue
+			}
+			for _, dp := range m.Data.(metricdata.Sum[int64]).DataPoints {
+				d, _ := dp.Attributes.Value(attribute.Key("decision"))
+				tenant, _ := dp.Attributes.Value(attribute.Key("tenant"))
+				if tenant.AsString() != "t1" {
+					t.Fatalf("tenant label = %q", tenant.AsString())
+				}
+				got[d.AsString()] = dp.Value
+			}
+		}
+	}
+	if got["allow"] != 2 || got["deny"] != 1 {
+		t.Fatalf("unexpected counts: %v", got)
+	}
+}
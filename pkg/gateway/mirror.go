@@ -0,0 +1,183 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+// mirrorTokenHeader carries the token a staging gateway requires on
+// mirrored calls.
+const mirrorTokenHeader = "X-Mirror-Token"
+
+// mirrorWorkers is how many mirrored calls are in flight at once.
+const mirrorWorkers = 4
+
+// mirroredCall is the body of POST /v1/mirror/toolcalls: a tool call as
+// the production gateway decided it.
+type mirroredCall struct {
+	Request  types.ToolCallRequest `json:"request"`
+	Decision types.Decision        `json:"decision"`
+}
+
+// mirrorResult is a staging gateway's decision on a mirrored call.
+type mirrorResult struct {
+	Decision   types.Decision `json:"decision"`
+	Reason     string         `json:"reason"`
+	ReasonCode string         `json:"reason_code,omitempty"`
+	Match      bool           `json:"match"`
+}
+
+// mirror sends a sampled copy of the tool calls the gateway decides to a
+// staging gateway, which evaluates policy on them and executes nothing.
+// Calls are sent in the background from a bounded queue; when the queue is
+// full they are dropped, so a slow or down staging stack never holds up
+// production traffic.
+type mirror struct {
+	log    *slog.Logger
+	url    string
+	token  string
+	ratio  float64
+	client *http.Client
+	queue  chan mirroredCall
+}
+
+// newMirror mirrors ratio (0 to 1) of tool calls to the gateway at
+// baseURL, authenticated with token.
+func newMirror(log *slog.Logger, baseURL, token string, ratio float64, queueSize int) *mirror {
+	return &mirror{
+		log:    log,
+		url:    strings.TrimSuffix(baseURL, "/") + "/v1/mirror/toolcalls",
+		token:  token,
+		ratio:  ratio,
+		client: &http.Client{Timeout: 5 * time.Second},
+		queue:  make(chan mirroredCall, queueSize),
+	}
+}
+
+// Send queues req, decided as decision, for mirroring if it is sampled. It
+// never blocks; a nil mirror sends nothing.
+func (m *mirror) Send(ctx context.Context, req types.ToolCallRequest, decision types.Decision) {
+	if m == nil || m.ratio <= 0 || (m.ratio < 1 && rand.Float64() >= m.ratio) {
+		return
+	}
+	select {
+	case m.queue <- mirroredCall{Request: req, Decision: decision}:
+	default:
+		recordMirror(ctx, req, "dropped")
+	}
+}
+
+// Run sends queued calls until ctx is done.
+func (m *mirror) Run(ctx context.Context) {
+	for range mirrorWorkers {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case call := <-m.queue:
+					m.post(ctx, call)
+				}
+			}
+		}()
+	}
+}
+
+func (m *mirror) post(ctx context.Context, call mirroredCall) {
+	outcome := "sent"
+	if err := m.postOnce(ctx, call); err != nil {
+		outcome = "failed"
+		m.log.DebugContext(ctx, "mirror tool call failed", "tool", call.Request.Tool, "error", err)
+	}
+	recordMirror(ctx, call.Request, outcome)
+}
+
+func (m *mirror) postOnce(ctx context.Context, call mirroredCall) error {
+	body, err := json.Marshal(call)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(mirrorTokenHeader, m.token)
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("mirror returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// mirrorAuth requires the mirror token in the X-Mirror-Token header.
+func mirrorAuth(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			provided := r.Header.Get(mirrorTokenHeader)
+			if token == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+				types.ErrUnauthorized("invalid mirror token").WriteJSON(w)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// HandleMirroredToolCall is POST /v1/mirror/toolcalls on a staging
+// gateway. It evaluates a call mirrored from production exactly as
+// POST /v1/toolcalls would decide it, then compares the decision with
+// production's, counting and logging any mismatch. Nothing is recorded,
+// no approval is requested and nothing is executed. Parent events and
+// params_ref blobs belong to production and are not looked up.
+func (gw *Gateway) HandleMirroredToolCall(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	var call mirroredCall
+	if err := json.NewDecoder(r.Body).Decode(&call); err != nil {
+		types.ErrBadRequest("invalid JSON body").WriteJSON(w)
+		return
+	}
+	req := call.Request
+	if err := req.NormalizeAndValidate(); err != nil {
+		types.ErrValidation(err).WriteJSON(w)
+		return
+	}
+
+	res := gw.evaluate(ctx, req)
+	out := mirrorResult{
+		Decision:   res.Decision,
+		Reason:     res.Reason,
+		ReasonCode: res.ReasonCode,
+		Match:      res.Decision == call.Decision,
+	}
+	recordMirrorDecision(ctx, req, out.Match)
+	if !out.Match {
+		gw.log.WarnContext(ctx, "mirrored decision differs from production",
+			"tenant_id", req.TenantID,
+			"tool", req.Tool,
+			"action", req.Action,
+			"production", string(call.Decision),
+			"staging", string(res.Decision),
+			"reason", res.Reason,
+		)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
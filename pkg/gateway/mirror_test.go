@@ -0,0 +1,105 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestMirrorSendsSampledCalls(t *testing.T) {
+	got := make(chan mirroredCall, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/mirror/toolcalls" || r.Header.Get(mirrorTokenHeader) != "staging-token" {
+			t.Errorf("request = %s %s", r.URL.Path, r.Header.Get(mirrorTokenHeader))
+		}
+		var call mirroredCall
+		_ = json.NewDecoder(r.Body).Decode(&call)
+		got <- call
+	}))
+	defer srv.Close()
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m := newMirror(log, srv.URL+"/", "staging-token", 1, 1)
+	m.Run(ctx)
+	req := types.ToolCallRequest{TenantID: "tenant1", Tool: "jira", Action: "issue.delete"}
+	m.Send(ctx, req, types.DecisionApprove)
+	select {
+	case call := <-got:
+		if call.Request.Action != "issue.delete" || call.Decision != types.DecisionApprove {
+			t.Fatalf("mirrored = %+v", call)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("call was not mirrored")
+	}
+
+	// Unsampled calls are not queued, and a full queue drops rather than
+	// blocking the caller.
+	newMirror(log, srv.URL, "", 0, 1).Send(ctx, req, types.DecisionAllow)
+	full := newMirror(log, srv.URL, "", 1, 1)
+	full.Send(ctx, req, types.DecisionAllow)
+	full.Send(ctx, req, types.DecisionAllow)
+	if len(full.queue) != 1 {
+		t.Fatalf("queue = %d", len(full.queue))
+	}
+	(*mirror)(nil).Send(ctx, req, types.DecisionAllow)
+}
+
+func TestHandleMirroredToolCall(t *testing.T) {
+	fe := newFakeEvidence()
+	fc := &fakeConnectors{}
+	gw := &Gateway{
+		log:        slog.New(slog.NewTextHandler(bytes.NewBuffer(nil), nil)),
+		evidence:   fe,
+		policy:     fakePolicy{decision: types.DecisionApprove, reason: "needs review"},
+		connectors: fc,
+		approvals:  &fakeApprovals{},
+	}
+	r := chi.NewRouter()
+	r.With(mirrorAuth("staging-token")).Post("/v1/mirror/toolcalls", gw.HandleMirroredToolCall)
+	post := func(token string, decision types.Decision) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(mirroredCall{
+			Request:  types.ToolCallRequest{TenantID: "tenant1", AgentID: "agent-1", Tool: "jira", Action: "issue.delete", IdempotencyKey: "k1"},
+			Decision: decision,
+		})
+		req := httptest.NewRequest(http.MethodPost, "/v1/mirror/toolcalls", bytes.NewReader(body))
+		req.Header.Set(mirrorTokenHeader, token)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := post("wrong", types.DecisionAllow); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("bad token = %d", rr.Code)
+	}
+	for _, tc := range []struct {
+		production types.Decision
+		match      bool
+	}{
+		{types.DecisionAllow, false},
+		{types.DecisionApprove, true},
+	} {
+		rr := post("staging-token", tc.production)
+		var res mirrorResult
+		if err := json.NewDecoder(rr.Body).Decode(&res); err != nil || rr.Code != http.StatusOK {
+			t.Fatalf("status = %d, %v", rr.Code, err)
+		}
+		if res.Decision != types.DecisionApprove || res.Reason != "needs review" || res.Match != tc.match {
+			t.Fatalf("production %s: result = %+v", tc.production, res)
+		}
+	}
+	// Mirrored calls are evaluated only.
+	if len(fe.events) != 0 || fc.calls != 0 {
+		t.Fatalf("events = %d, connector calls = %d", len(fe.events), fc.calls)
+	}
+}
@@ -0,0 +1,354 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/approvals"
+	"github.com/bturcanu/OpenClause/pkg/auth"
+	"github.com/bturcanu/OpenClause/pkg/connectors"
+	"github.com/bturcanu/OpenClause/pkg/metering"
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// HandleSubmitPlan is POST /v1/plans. The plan is recorded as one event on
+// the reserved oc.plan tool and decided as a unit: it runs at once when
+// every step is allowed, and otherwise needs a single approval, after which
+// POST /v1/toolcalls/{event_id}/execute runs it.
+func (gw *Gateway) HandleSubmitPlan(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	var plan types.ToolCallPlan
+	if err := json.NewDecoder(r.Body).Decode(&plan); err != nil {
+		types.ErrBadRequest("invalid JSON body").WriteJSON(w)
+		return
+	}
+	// Override tenant from auth context before the steps inherit it.
+	if t := auth.TenantFromContext(ctx); t != "" {
+		plan.TenantID = t
+	}
+	if err := plan.NormalizeAndValidate(); err != nil {
+		types.ErrValidation(err).WriteJSON(w)
+		return
+	}
+
+	req := types.ToolCallRequest{
+		TenantID:       plan.TenantID,
+		AgentID:        plan.AgentID,
+		Tool:           types.PlanTool,
+		Action:         types.PlanAction,
+		SessionID:      plan.SessionID,
+		TraceID:        plan.TraceID,
+		ParentEventID:  plan.ParentEventID,
+		IdempotencyKey: plan.IdempotencyKey,
+		ApproverGroup:  plan.ApproverGroup,
+		NotifyChannel:  plan.NotifyChannel,
+	}
+	for _, step := range plan.Steps {
+		req.RiskScore = max(req.RiskScore, step.RiskScore)
+	}
+	if apiErr := gw.resolveParent(ctx, &req); apiErr != nil {
+		apiErr.WriteJSON(w)
+		return
+	}
+
+	ctx, span := startToolCallSpan(ctx, &req)
+	defer span.End()
+	span.SetAttributes(attribute.Int("oc.plan_steps", len(plan.Steps)))
+
+	for i := range plan.Steps {
+		plan.Steps[i].TraceID = req.TraceID
+	}
+	params, err := json.Marshal(plan.Steps)
+	if err != nil {
+		types.ErrBadRequest("invalid steps").WriteJSON(w)
+		return
+	}
+	req.Params = params
+	if err := req.NormalizeAndValidate(); err != nil {
+		types.ErrValidation(err).WriteJSON(w)
+		return
+	}
+
+	release, admitted := gw.admission.Admit(gw.admissionPriority(ctx, req))
+	if !admitted {
+		w.Header().Set("Retry-After", shedRetryAfterSec)
+		types.ErrOverloaded().WriteJSON(w)
+		return
+	}
+	defer release()
+
+	if limited := gw.allowPlanRate(ctx, req, plan.Steps); limited != nil {
+		writeRateLimited(w, limited)
+		return
+	}
+	prior, err := gw.evidence.CheckIdempotency(ctx, req.TenantID, req.IdempotencyKey)
+	if err != nil {
+		gw.log.ErrorContext(ctx, "idempotency check failed", "error", err)
+		types.ErrInternal("failed to validate idempotency").WriteJSON(w)
+		return
+	}
+	if prior != nil {
+		gw.writeResponse(ctx, w, prior)
+		return
+	}
+
+	resp, apiErr := gw.processPlan(ctx, req, plan.Steps)
+	if apiErr != nil {
+		writeAPIError(w, apiErr)
+		return
+	}
+	gw.writeResponse(ctx, w, resp)
+}
+
+// processPlan records, evaluates and acts on a plan; req is its recorded
+// form.
+func (gw *Gateway) processPlan(ctx context.Context, req types.ToolCallRequest, steps []types.ToolCallRequest) (*types.ToolCallResponse, *types.APIError) {
+	gw.meter.Add(req.TenantID, metering.Calls, int64(len(steps)))
+	eventID := uuid.NewString()
+	payloadJSON, err := json.Marshal(req)
+	if err != nil {
+		gw.log.ErrorContext(ctx, "payload marshal failed", "error", err)
+		return nil, types.ErrInternal("request processing failed")
+	}
+	env := &types.ToolCallEnvelope{
+		EventID:     eventID,
+		Request:     req,
+		PayloadJSON: payloadJSON,
+		ReceivedAt:  time.Now().UTC(),
+	}
+
+	policyResult := gw.evaluatePlan(ctx, steps)
+	env.Decision = policyResult.Decision
+	env.PolicyResult = policyResult
+	if score, ok := policyResult.AdjustedRiskScore(req.RiskScore); ok {
+		env.AdjustedRiskScore = &score
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("oc.decision", string(policyResult.Decision)))
+
+	resp := types.ToolCallResponse{
+		EventID:      eventID,
+		Decision:     policyResult.Decision,
+		Reason:       policyResult.Reason,
+		ReasonCode:   policyResult.ReasonCode,
+		MatchedRules: policyResult.MatchedRules,
+	}
+	switch policyResult.Decision {
+	case types.DecisionApprove:
+		// Record evidence first so the tool_events row exists before
+		// approval_requests references it via FK.
+		if err := gw.evidence.RecordEvent(ctx, env); err != nil {
+			gw.log.ErrorContext(ctx, "evidence record failed", "error", err)
+		}
+		approvalIn := approvals.CreateApprovalInput{
+			EventID:         eventID,
+			TenantID:        req.TenantID,
+			AgentID:         req.AgentID,
+			Tool:            req.Tool,
+			Action:          req.Action,
+			SessionID:       req.SessionID,
+			RiskScore:       env.RiskScore(),
+			Reason:          policyResult.Reason,
+			TraceID:         req.TraceID,
+			ApproverGroup:   policyResult.ApproverGroup,
+			Notify:          policyResult.Notify,
+			ExpiresInSec:    policyResult.ApprovalTTLSec,
+			GrantTTLSec:     policyResult.GrantTTLSec,
+			ApprovalBaseURL: gw.approvalsURL,
+			Plan:            gw.describePlan(ctx, eventID, steps),
+			RecentActivity:  gw.recentActivity(ctx, req.TenantID, req.AgentID, eventID),
+		}
+		gw.routeApproval(ctx, &approvalIn, req)
+		approvalReq, err := gw.approvals.CreateRequest(ctx, approvalIn)
+		if err != nil {
+			gw.log.ErrorContext(ctx, "create approval failed", "error", err)
+		} else {
+			gw.meter.Add(req.TenantID, metering.Approvals, 1)
+			gw.events.PublishRequest(ctx, *approvalReq)
+			resp.ApprovalURL = fmt.Sprintf("%s/v1/approvals/requests/%s", gw.approvalsURL, approvalReq.ID)
+		}
+
+	case types.DecisionAllow:
+		releaseSlot, busy := gw.acquireAgentSlot(ctx, req)
+		if busy != nil {
+			return nil, concurrencyError(busy)
+		}
+		defer releaseSlot()
+		env.ExecutionResult = gw.runPlan(ctx, eventID, steps)
+		resp.Result = env.ExecutionResult
+		if err := gw.evidence.RecordEvent(ctx, env); err != nil {
+			gw.log.ErrorContext(ctx, "evidence record failed", "error", err)
+			return nil, types.ErrInternal("evidence recording failed after execution")
+		}
+		if env.ExecutionResult.Quarantine != nil {
+			gw.requestReview(ctx, env, &resp)
+		}
+
+	default:
+		if err := gw.evidence.RecordEvent(ctx, env); err != nil {
+			gw.log.ErrorContext(ctx, "evidence record failed", "error", err)
+		}
+	}
+
+	resp.Receipt = gw.receipt(ctx, env)
+	recordDecision(ctx, req, resp.Decision)
+	return &resp, nil
+}
+
+// evaluatePlan decides a plan as a unit: it is denied if any step is denied,
+// needs approval if any step does, and is allowed only if every step is. A
+// plan needing approval is routed as its first such step would be. The
+// result is marked as a fallback if a fallback policy decided any step, and
+// carries every step's injection findings, with paths into the plan's params.
+func (gw *Gateway) evaluatePlan(ctx context.Context, steps []types.ToolCallRequest) *types.PolicyResult {
+	var approve *types.PolicyResult
+	var findings []types.InjectionFinding
+	fallback := false
+	for i, step := range steps {
+		res := gw.evaluate(ctx, step)
+		fallback = fallback || res.Fallback
+		for _, f := range res.Injection {
+			f.Path = fmt.Sprintf("/%d/params%s", i, f.Path)
+			findings = append(findings, f)
+		}
+		switch res.Decision {
+		case types.DecisionAllow:
+		case types.DecisionApprove:
+			if approve == nil {
+				approve = res
+				approve.Reason = fmt.Sprintf("step %d (%s): %s", i+1, step.ToolAction(), res.Reason)
+			}
+		default:
+			// Deny, or an unrecognized decision: fail closed.
+			out := &types.PolicyResult{
+				Decision:     types.DecisionDeny,
+				Reason:       fmt.Sprintf("step %d (%s): %s", i+1, step.ToolAction(), res.Reason),
+				ReasonCode:   res.ReasonCode,
+				MatchedRules: res.MatchedRules,
+				FreezeWindow: res.FreezeWindow,
+				Canary:       res.Canary,
+				Fallback:     fallback,
+				Injection:    findings,
+			}
+			if res.Canary != "" {
+				out.RiskOverrides = res.RiskOverrides
+			}
+			return out
+		}
+	}
+	if approve != nil {
+		approve.Fallback = fallback
+		approve.Injection = findings
+		return approve
+	}
+	return &types.PolicyResult{
+		Decision:  types.DecisionAllow,
+		Reason:    fmt.Sprintf("all %d steps allowed", len(steps)),
+		Fallback:  fallback,
+		Injection: findings,
+	}
+}
+
+// describePlan is the connector plan shown to approvers of a plan: one line
+// per step, with the step's own connector plan when its tool has one.
+func (gw *Gateway) describePlan(ctx context.Context, planEventID string, steps []types.ToolCallRequest) *connectors.ExecPlan {
+	fields := make(map[string]any, len(steps))
+	for i, step := range steps {
+		line := step.ToolAction()
+		if step.Resource != "" {
+			line += " on " + step.Resource
+		}
+		if p := gw.planConnector(ctx, planEventID, step); p != nil {
+			line += ": " + p.Summary
+		}
+		fields[fmt.Sprintf("step %02d", i+1)] = line
+	}
+	return &connectors.ExecPlan{
+		Summary: fmt.Sprintf("Run %d steps in order, stopping at the first failure: %s", len(steps), types.PlanSummary(steps)),
+		Fields:  fields,
+	}
+}
+
+// runRecordedPlan runs the plan recorded as event planEventID.
+func (gw *Gateway) runRecordedPlan(ctx context.Context, planEventID string, req types.ToolCallRequest) *types.ExecutionResult {
+	var steps []types.ToolCallRequest
+	if err := json.Unmarshal(req.Params, &steps); err != nil {
+		return &types.ExecutionResult{Status: "error", Error: "invalid plan steps: " + err.Error()}
+	}
+	return gw.runPlan(ctx, planEventID, steps)
+}
+
+// runPlan executes steps in order, recording each as its own evidence event
+// keyed by types.PlanStepKey, and stops at the first step that does not
+// succeed. The returned result summarises the run; its output_json lists
+// every step's outcome, and it is quarantined when any step's output is.
+func (gw *Gateway) runPlan(ctx context.Context, planEventID string, steps []types.ToolCallRequest) *types.ExecutionResult {
+	start := time.Now()
+	out := make([]types.PlanStepResult, len(steps))
+	var failed string
+	var quarantined []string
+	for i, step := range steps {
+		n := i + 1
+		out[i] = types.PlanStepResult{Step: n, Tool: step.Tool, Action: step.Action, Resource: step.Resource, Status: "skipped"}
+		if failed != "" {
+			continue
+		}
+
+		step.IdempotencyKey = types.PlanStepKey(planEventID, n)
+		step.PlanID = planEventID
+		stepEventID := uuid.NewString()
+		res := gw.executeConnector(ctx, stepEventID, step)
+		payloadJSON, err := json.Marshal(step)
+		if err == nil {
+			err = gw.evidence.RecordEvent(ctx, &types.ToolCallEnvelope{
+				EventID:     stepEventID,
+				Request:     step,
+				PayloadJSON: payloadJSON,
+				ReceivedAt:  time.Now().UTC(),
+				Decision:    types.DecisionAllow,
+				PolicyResult: &types.PolicyResult{
+					Decision: types.DecisionAllow,
+					Reason:   fmt.Sprintf("step %d of plan %s", n, planEventID),
+				},
+				ExecutionResult: res,
+			})
+		}
+		if err != nil {
+			// The step ran but is not in the evidence log; do not go on.
+			gw.log.ErrorContext(ctx, "plan step evidence record failed", "plan_event_id", planEventID, "step", n, "error", err)
+			failed = fmt.Sprintf("step %d (%s): evidence recording failed", n, step.ToolAction())
+		}
+		out[i].EventID = stepEventID
+		out[i].Status = res.Status
+		out[i].Result = res
+		if res.Quarantine != nil {
+			quarantined = append(quarantined, fmt.Sprintf("step %d: %s", n, res.Quarantine.Reason))
+		}
+		if failed == "" && res.Status != "success" {
+			failed = fmt.Sprintf("step %d (%s) %s: %s", n, step.ToolAction(), res.Status, res.Error)
+		}
+	}
+
+	output, _ := json.Marshal(map[string]any{"steps": out})
+	result := &types.ExecutionResult{
+		Status:     "success",
+		OutputJSON: output,
+		DurationMS: time.Since(start).Milliseconds(),
+	}
+	if failed != "" {
+		result.Status = "error"
+		result.Error = failed
+	}
+	if len(quarantined) > 0 {
+		result.Quarantine = &types.Quarantine{Reason: strings.Join(quarantined, "; ")}
+	}
+	return result
+}
@@ -0,0 +1,142 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"github.com/go-chi/chi/v5"
+)
+
+func postPlan(t *testing.T, gw *Gateway, plan types.ToolCallPlan) (*httptest.ResponseRecorder, types.ToolCallResponse) {
+	t.Helper()
+	body, _ := json.Marshal(plan)
+	r := chi.NewRouter()
+	r.Post("/v1/plans", gw.HandleSubmitPlan)
+	req := httptest.NewRequest(http.MethodPost, "/v1/plans", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	var resp types.ToolCallResponse
+	if rr.Code == http.StatusOK {
+		if err := json.NewDecoder(bytes.NewReader(rr.Body.Bytes())).Decode(&resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+	}
+	return rr, resp
+}
+
+func ticketThenPost(key string) types.ToolCallPlan {
+	return types.ToolCallPlan{
+		TenantID:       "tenant1",
+		AgentID:        "agent-1",
+		IdempotencyKey: key,
+		Steps: []types.ToolCallRequest{
+			{Tool: "jira", Action: "issue.create", Resource: "project/OPS", Params: json.RawMessage(`{"project":"OPS","summary":"Outage"}`)},
+			{Tool: "slack", Action: "msg.post", Resource: "C1", Params: json.RawMessage(`{"channel":"C1","text":"filed"}`)},
+			{Tool: "slack", Action: "msg.react", Resource: "C1"},
+		},
+	}
+}
+
+func planSteps(t *testing.T, res *types.ExecutionResult) []types.PlanStepResult {
+	t.Helper()
+	var out struct {
+		Steps []types.PlanStepResult `json:"steps"`
+	}
+	if res == nil || json.Unmarshal(res.OutputJSON, &out) != nil {
+		t.Fatalf("plan result = %+v", res)
+	}
+	return out.Steps
+}
+
+func TestSubmitPlan_AllowedRunsStepsInOrder(t *testing.T) {
+	fe := newFakeEvidence()
+	fc := &fakeConnectors{output: json.RawMessage(`{"ok":true}`)}
+	gw := newExecuteGateway(fe, fc, &fakeApprovals{})
+	gw.perTenantLimit = 100
+
+	rr, resp := postPlan(t, gw, ticketThenPost("plan-1"))
+	if rr.Code != http.StatusOK || resp.Decision != types.DecisionAllow || resp.Result == nil || resp.Result.Status != "success" {
+		t.Fatalf("plan response %d %s", rr.Code, rr.Body.String())
+	}
+	steps := planSteps(t, resp.Result)
+	if len(steps) != 3 || fc.calls != 3 {
+		t.Fatalf("steps=%d connector calls=%d, want 3 and 3", len(steps), fc.calls)
+	}
+	for i, s := range steps {
+		env := fe.events[s.EventID]
+		if s.Status != "success" || env == nil || env.Request.IdempotencyKey != types.PlanStepKey(resp.EventID, i+1) {
+			t.Fatalf("step %d = %+v, evidence %+v", i+1, s, env)
+		}
+	}
+	if anchor := fe.events[resp.EventID]; anchor == nil || !anchor.Request.IsPlan() || anchor.ExecutionResult == nil {
+		t.Fatalf("plan evidence = %+v", anchor)
+	}
+}
+
+func TestSubmitPlan_AbortsOnFailedStep(t *testing.T) {
+	fe := newFakeEvidence()
+	fc := &fakeConnectors{failActions: map[string]bool{"msg.post": true}}
+	gw := newExecuteGateway(fe, fc, &fakeApprovals{})
+	gw.perTenantLimit = 100
+
+	_, resp := postPlan(t, gw, ticketThenPost("plan-2"))
+	if resp.Result == nil || resp.Result.Status != "error" {
+		t.Fatalf("plan result = %+v", resp.Result)
+	}
+	steps := planSteps(t, resp.Result)
+	got := []string{steps[0].Status, steps[1].Status, steps[2].Status}
+	if got[0] != "success" || got[1] != "error" || got[2] != "skipped" || fc.calls != 2 {
+		t.Fatalf("step statuses %v after %d calls, want success, error, skipped after 2", got, fc.calls)
+	}
+	if steps[2].EventID != "" {
+		t.Fatalf("skipped step has evidence %s", steps[2].EventID)
+	}
+}
+
+func TestSubmitPlan_SingleApprovalThenExecute(t *testing.T) {
+	fe := newFakeEvidence()
+	fc := &fakeConnectors{output: json.RawMessage(`{"ok":true}`)}
+	fa := &fakeApprovals{}
+	gw := newExecuteGateway(fe, fc, fa)
+	gw.perTenantLimit = 100
+	gw.approvalsURL = "http://approvals"
+	gw.policy = fakePolicy{decision: types.DecisionApprove, reason: "needs approval"}
+
+	_, resp := postPlan(t, gw, ticketThenPost("plan-3"))
+	if resp.Decision != types.DecisionApprove || resp.ApprovalURL == "" || fa.created != 1 || fc.calls != 0 {
+		t.Fatalf("plan = %+v, approvals=%d calls=%d", resp, fa.created, fc.calls)
+	}
+	if fa.last.Tool != types.PlanTool || fa.last.Plan == nil || len(fa.last.Plan.Fields) != 3 {
+		t.Fatalf("approval input = %+v", fa.last)
+	}
+
+	fa.usesLeft = 1
+	rr := executeRequest(t, gw, resp.EventID)
+	var executed types.ToolCallResponse
+	if err := json.NewDecoder(rr.Body).Decode(&executed); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("execute: %d %v", rr.Code, err)
+	}
+	if len(planSteps(t, executed.Result)) != 3 || fc.calls != 3 {
+		t.Fatalf("executed plan = %+v after %d calls", executed.Result, fc.calls)
+	}
+}
+
+func TestSubmitPlan_Validation(t *testing.T) {
+	gw := newExecuteGateway(newFakeEvidence(), &fakeConnectors{}, &fakeApprovals{})
+	plan := ticketThenPost("plan-4")
+	plan.Steps[1].Tool = types.PlanTool
+	if rr, _ := postPlan(t, gw, plan); rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("nested plan status = %d, want 422", rr.Code)
+	}
+
+	body, _ := json.Marshal(types.ToolCallRequest{
+		TenantID: "tenant1", AgentID: "agent-1", Tool: types.PlanTool, Action: types.PlanAction, IdempotencyKey: "p",
+	})
+	if rr := postToolCall(t, gw, body); rr.Code != http.StatusBadRequest {
+		t.Fatalf("direct plan tool call status = %d, want 400", rr.Code)
+	}
+}
@@ -0,0 +1,47 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/bturcanu/OpenClause/pkg/policy"
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+// maxPolicyBundleBytes bounds an uploaded bundle, compressed.
+const maxPolicyBundleBytes = 4 << 20
+
+// policyDryRunner is implemented by policy engines that can compile and
+// evaluate a bundle without activating it: OPA and the embedded engine.
+type policyDryRunner interface {
+	DryRun(ctx context.Context, b *policy.Bundle) ([]policy.LintIssue, error)
+}
+
+// HandleValidatePolicy is POST /v1/admin/policies/validate. The body is a
+// gzipped tar policy bundle; the response is a policy.LintReport. The bundle
+// is checked statically for the oc.main entrypoint and its decision and
+// reason rules, then compiled and evaluated by the policy engine against
+// sample inputs so decisions the gateway does not act on, such as
+// "escalate", are caught before the bundle is activated.
+func (gw *Gateway) HandleValidatePolicy(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	b, err := policy.ReadBundle(http.MaxBytesReader(w, r.Body, maxPolicyBundleBytes))
+	if err != nil {
+		types.ErrBadRequest("body must be a gzipped tar policy bundle: " + err.Error()).WriteJSON(w)
+		return
+	}
+	issues := policy.Lint(b)
+	compiled := false
+	if dr, ok := gw.policy.(policyDryRunner); ok && len(b.Modules) > 0 {
+		found, err := dr.DryRun(ctx, b)
+		if err != nil {
+			gw.log.ErrorContext(ctx, "policy dry run failed", "error", err)
+			types.ErrInternal("policy engine unavailable").WriteJSON(w)
+			return
+		}
+		issues, compiled = append(issues, found...), true
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(policy.NewLintReport(issues, compiled))
+}
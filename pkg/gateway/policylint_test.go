@@ -0,0 +1,84 @@
+package gateway
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bturcanu/OpenClause/pkg/policy"
+	"github.com/bturcanu/OpenClause/policy/bundles"
+)
+
+// dryRunPolicy is a fakePolicy that also answers dry runs with issues.
+type dryRunPolicy struct {
+	fakePolicy
+	issues []policy.LintIssue
+	bundle *policy.Bundle
+}
+
+func (p *dryRunPolicy) DryRun(_ context.Context, b *policy.Bundle) ([]policy.LintIssue, error) {
+	p.bundle = b
+	return p.issues, nil
+}
+
+func postPolicyBundle(t *testing.T, gw *Gateway, src string) (int, policy.LintReport) {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	_ = tw.WriteHeader(&tar.Header{Name: "main.rego", Mode: 0o644, Size: int64(len(src)), Typeflag: tar.TypeReg})
+	_, _ = tw.Write([]byte(src))
+	_ = tw.Close()
+	_ = gz.Close()
+
+	rr := httptest.NewRecorder()
+	gw.HandleValidatePolicy(rr, httptest.NewRequest(http.MethodPost, "/v1/admin/policies/validate", &buf))
+	var report policy.LintReport
+	if rr.Code == http.StatusOK {
+		if err := json.NewDecoder(rr.Body).Decode(&report); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return rr.Code, report
+}
+
+func TestValidatePolicy(t *testing.T) {
+	gw := newExecuteGateway(newFakeEvidence(), &fakeConnectors{}, &fakeApprovals{})
+	const valid = "package oc.main\n\ndefault decision := \"deny\"\n\ndefault reason := \"no\"\n"
+
+	// An engine without dry runs: static checks only.
+	code, report := postPolicyBundle(t, gw, strings.Replace(valid, `"deny"`, `"escalate"`, 1))
+	if code != http.StatusOK || report.Valid || report.Compiled || len(report.Issues) != 1 || report.Issues[0].Line != 3 {
+		t.Fatalf("escalate bundle: %d %+v", code, report)
+	}
+
+	dr := &dryRunPolicy{issues: []policy.LintIssue{{Severity: policy.LintWarning, Input: "read", Message: "reason is undefined"}}}
+	gw.policy = dr
+	code, report = postPolicyBundle(t, gw, valid)
+	if code != http.StatusOK || !report.Valid || !report.Compiled || len(report.Issues) != 1 || dr.bundle.Modules["main.rego"] != valid {
+		t.Fatalf("valid bundle: %d %+v", code, report)
+	}
+
+	// The embedded engine of lite mode dry-runs bundles too.
+	engine, err := policy.NewEmbedded(bundles.DefaultModules, bundles.DefaultData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gw.policy = engine
+	code, report = postPolicyBundle(t, gw, "package oc.main\n\ndefault reason := \"no\"\n\ndecision := concat(\"\", [\"esc\", \"alate\"])\n")
+	if code != http.StatusOK || report.Valid || !report.Compiled || len(report.Issues) == 0 || report.Issues[0].Input == "" {
+		t.Fatalf("computed escalate under the embedded engine: %d %+v", code, report)
+	}
+
+	rr := httptest.NewRecorder()
+	gw.HandleValidatePolicy(rr, httptest.NewRequest(http.MethodPost, "/v1/admin/policies/validate", strings.NewReader(valid)))
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("plain rego body: %d", rr.Code)
+	}
+}
@@ -0,0 +1,187 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/bturcanu/OpenClause/pkg/approvals"
+	"github.com/bturcanu/OpenClause/pkg/auth"
+	"github.com/bturcanu/OpenClause/pkg/connectors"
+	"github.com/bturcanu/OpenClause/pkg/metering"
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// flaggedQuarantine returns the quarantine for a successful execution
+// whose connector flagged its output, or nil.
+func flaggedQuarantine(status string, flags []string) *types.Quarantine {
+	if status != "success" || len(flags) == 0 {
+		return nil
+	}
+	return &types.Quarantine{Reason: "connector flagged output: " + strings.Join(flags, ", "), Flags: flags}
+}
+
+// quarantineByPolicy quarantines a successful result when policy set the
+// quarantine requirement. A connector's flags are kept.
+func quarantineByPolicy(result *types.ExecutionResult, pr *types.PolicyResult) {
+	if result == nil || result.Status != "success" || pr == nil {
+		return
+	}
+	reason, ok := pr.Requirements[types.RequirementQuarantine]
+	if !ok {
+		return
+	}
+	if reason == "" {
+		reason = "policy requires review of output"
+	}
+	if result.Quarantine == nil {
+		result.Quarantine = &types.Quarantine{}
+	} else {
+		reason = result.Quarantine.Reason + "; " + reason
+	}
+	result.Quarantine.Reason = reason
+}
+
+// requestReview opens a quarantine review of the output recorded with env
+// and points resp at it. The review is an approval request of kind
+// quarantine, routed like any other approval; failures are logged and the
+// output stays withheld.
+func (gw *Gateway) requestReview(ctx context.Context, env *types.ToolCallEnvelope, resp *types.ToolCallResponse) {
+	result, req := env.ExecutionResult, env.Request
+	resp.Reason = "output quarantined pending review: " + result.Quarantine.Reason
+	resp.ReasonCode = types.ReasonCodeQuarantined
+
+	in := approvals.CreateApprovalInput{
+		EventID:         env.EventID,
+		TenantID:        req.TenantID,
+		AgentID:         req.AgentID,
+		Tool:            req.Tool,
+		Action:          req.Action,
+		Resource:        req.Resource,
+		SessionID:       req.SessionID,
+		RiskScore:       env.RiskScore(),
+		RiskFactors:     req.RiskFactors,
+		Reason:          resp.Reason,
+		TraceID:         req.TraceID,
+		ApprovalBaseURL: gw.approvalsURL,
+		Kind:            approvals.KindQuarantine,
+		RecentActivity:  gw.recentActivity(ctx, req.TenantID, req.AgentID, env.EventID),
+		Plan: &connectors.ExecPlan{
+			Summary: fmt.Sprintf("%s ran; its output is withheld from the agent until released", req.ToolAction()),
+			Fields: map[string]any{
+				"reason":       result.Quarantine.Reason,
+				"flags":        result.Quarantine.Flags,
+				"output_bytes": len(result.OutputJSON),
+			},
+		},
+	}
+	if pr := env.PolicyResult; pr != nil {
+		in.ApproverGroup, in.Notify = pr.ApproverGroup, pr.Notify
+	}
+	gw.routeApproval(ctx, &in, req)
+	review, err := gw.approvals.CreateRequest(ctx, in)
+	if err != nil {
+		gw.log.ErrorContext(ctx, "create quarantine review failed", "event_id", env.EventID, "error", err)
+		return
+	}
+	gw.meter.Add(req.TenantID, metering.Approvals, 1)
+	gw.events.PublishRequest(ctx, *review)
+	resp.ApprovalURL = fmt.Sprintf("%s/v1/approvals/requests/%s", gw.approvalsURL, review.ID)
+}
+
+// HandleGetOutput is GET /v1/toolcalls/{event_id}/output. It returns the
+// execution result of the event, or of the execution linked to an
+// approval-gated event, with its output. Quarantined output is returned
+// only once a reviewer has released it: while the review is open the
+// answer is 409, and after a denial or expiry 403.
+func (gw *Gateway) HandleGetOutput(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	eventID := chi.URLParam(r, "event_id")
+	if _, err := uuid.Parse(eventID); err != nil {
+		types.ErrBadRequest("invalid event_id format").WriteJSON(w)
+		return
+	}
+	env, err := gw.evidence.GetEvent(ctx, eventID)
+	if err != nil {
+		gw.log.ErrorContext(ctx, "get event failed", "event_id", eventID, "error", err)
+		types.ErrInternal("failed to retrieve event").WriteJSON(w)
+		return
+	}
+	if env == nil {
+		types.ErrNotFound("event not found").WriteJSON(w)
+		return
+	}
+	if authTenant := auth.TenantFromContext(ctx); authTenant != "" && env.Request.TenantID != authTenant {
+		types.ErrNotFound("event not found").WriteJSON(w)
+		return
+	}
+	executedID, result, err := gw.executionOf(ctx, env)
+	if err != nil {
+		gw.log.ErrorContext(ctx, "get linked execution failed", "event_id", eventID, "error", err)
+		types.ErrInternal("failed to retrieve execution").WriteJSON(w)
+		return
+	}
+	if result == nil {
+		types.ErrConflict("event has not been executed").WriteJSON(w)
+		return
+	}
+
+	if result.Quarantine != nil {
+		status, err := gw.reviewStatus(ctx, env, executedID)
+		if err != nil {
+			gw.log.ErrorContext(ctx, "quarantine review lookup failed", "event_id", eventID, "error", err)
+			types.ErrInternal("failed to retrieve quarantine review").WriteJSON(w)
+			return
+		}
+		switch status {
+		case "approved":
+		case "denied", "expired":
+			types.ErrForbidden("quarantined output was not released").WriteJSON(w)
+			return
+		default:
+			types.ErrConflict("output is quarantined pending review").WriteJSON(w)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		gw.log.ErrorContext(ctx, "response encode failed", "error", err)
+	}
+}
+
+// reviewStatus returns the status of the latest quarantine review covering
+// the execution executedID of env, or "" when there is none. A plan step's
+// output is also covered by the review of its plan.
+func (gw *Gateway) reviewStatus(ctx context.Context, env *types.ToolCallEnvelope, executedID string) (string, error) {
+	ids := []string{executedID}
+	if planID := env.Request.PlanID; planID != "" {
+		plan, err := gw.evidence.GetEvent(ctx, planID)
+		if err != nil {
+			return "", err
+		}
+		if plan != nil {
+			planExecutedID, _, err := gw.executionOf(ctx, plan)
+			if err != nil {
+				return "", err
+			}
+			if planExecutedID != "" {
+				ids = append(ids, planExecutedID)
+			}
+		}
+	}
+	reqs, err := gw.approvals.ListRequestsByEvents(ctx, env.Request.TenantID, ids)
+	if err != nil {
+		return "", err
+	}
+	status := ""
+	for _, req := range reqs {
+		if req.IsQuarantine() {
+			status = req.Status
+		}
+	}
+	return status, nil
+}
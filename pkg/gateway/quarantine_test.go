@@ -0,0 +1,86 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bturcanu/OpenClause/pkg/approvals"
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestQuarantine_WithholdsOutputUntilReleased(t *testing.T) {
+	fe := newFakeEvidence()
+	fc := &fakeConnectors{output: json.RawMessage(`{"card":"4111111111111111"}`), flags: []string{"dlp:credit_card"}}
+	fa := &fakeApprovals{}
+	gw := &Gateway{
+		log:            slog.New(slog.NewTextHandler(bytes.NewBuffer(nil), nil)),
+		evidence:       fe,
+		policy:         fakePolicy{decision: types.DecisionAllow},
+		connectors:     fc,
+		approvals:      fa,
+		perTenantLimit: 100,
+	}
+	body, _ := json.Marshal(types.ToolCallRequest{TenantID: "tenant1", AgentID: "agent-1", Tool: "crm", Action: "contact.get", IdempotencyKey: "q1"})
+	rr := postToolCall(t, gw, body)
+	if rr.Code != http.StatusOK || bytes.Contains(rr.Body.Bytes(), []byte("4111")) {
+		t.Fatalf("response leaked output or failed: %d %s", rr.Code, rr.Body.String())
+	}
+	var resp types.ToolCallResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.ReasonCode != types.ReasonCodeQuarantined || resp.ApprovalURL == "" || resp.Result.Quarantine == nil || resp.Result.Quarantine.Flags[0] != "dlp:credit_card" {
+		t.Fatalf("response = %+v", resp)
+	}
+	if fa.last.Kind != approvals.KindQuarantine || fa.last.EventID != resp.EventID {
+		t.Fatalf("review request = %+v", fa.last)
+	}
+	if env, _ := fe.GetEvent(t.Context(), resp.EventID); string(env.ExecutionResult.OutputJSON) != string(fc.output) {
+		t.Fatalf("evidence lost the output: %s", env.ExecutionResult.OutputJSON)
+	}
+
+	r := chi.NewRouter()
+	r.Get("/v1/toolcalls/{event_id}", gw.HandleGetEvent)
+	r.Get("/v1/toolcalls/{event_id}/output", gw.HandleGetOutput)
+	get := func(path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, http.NoBody))
+		return rr
+	}
+	if rr := get("/v1/toolcalls/" + resp.EventID); bytes.Contains(rr.Body.Bytes(), []byte("4111")) {
+		t.Fatalf("event leaked output: %s", rr.Body.String())
+	}
+	for status, want := range map[string]int{"pending": http.StatusConflict, "denied": http.StatusForbidden, "approved": http.StatusOK} {
+		fa.requests[0].Status = status
+		rr := get("/v1/toolcalls/" + resp.EventID + "/output")
+		if rr.Code != want {
+			t.Errorf("review %s: status %d, want %d (%s)", status, rr.Code, want, rr.Body.String())
+		}
+		if status == "approved" && !bytes.Contains(rr.Body.Bytes(), []byte("4111")) {
+			t.Errorf("released output missing: %s", rr.Body.String())
+		}
+	}
+}
+
+func TestQuarantineByPolicy(t *testing.T) {
+	pr := &types.PolicyResult{Requirements: map[string]string{types.RequirementQuarantine: "anomalous access"}}
+	ok := &types.ExecutionResult{Status: "success", OutputJSON: json.RawMessage(`{}`)}
+	quarantineByPolicy(ok, pr)
+	if ok.Quarantine == nil || ok.Quarantine.Reason != "anomalous access" {
+		t.Fatalf("quarantine = %+v", ok.Quarantine)
+	}
+	if ok.Withheld().OutputJSON != nil || ok.OutputJSON == nil {
+		t.Error("Withheld must drop the output from a copy only")
+	}
+
+	failed := &types.ExecutionResult{Status: "error"}
+	quarantineByPolicy(failed, pr)
+	if failed.Quarantine != nil {
+		t.Error("a failed execution has no output to quarantine")
+	}
+}
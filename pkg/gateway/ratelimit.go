@@ -0,0 +1,252 @@
+package gateway
+
+import (
+	"container/list"
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/tenants"
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"golang.org/x/time/rate"
+)
+
+// tenantLimiters holds the token buckets of tenants and of their
+// per-agent and per-tool.action rate limits, evicting the least recently
+// used bucket once maxRateLimiters are tracked. The zero value is ready to
+// use.
+type tenantLimiters struct {
+	mu    sync.Mutex
+	byKey map[string]*list.Element
+	order list.List // front is most recently used; values are *tenantLimiter
+}
+
+type tenantLimiter struct {
+	key      string
+	tenantID string
+	lim      *rate.Limiter
+}
+
+// rateBucket is one token bucket a call draws from. The tenant-wide bucket
+// is keyed by the tenant ID and has no rule.
+type rateBucket struct {
+	key      string
+	tenantID string
+	limit    rate.Limit
+	burst    int
+	rule     *tenants.RateLimit
+	agentID  string // the agent the bucket counts, for a per-agent rule
+	tokens   int    // tokens the call takes; 0 takes one
+}
+
+// reserve takes each bucket's tokens, creating or resizing each to its
+// limit and burst. When a bucket has too few available it takes nothing
+// from any of them, and returns that bucket's index and how long until it
+// has enough; otherwise it returns -1.
+func (t *tenantLimiters) reserve(buckets []rateBucket, now time.Time) (limited int, retryAfter time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.byKey == nil {
+		t.byKey = make(map[string]*list.Element)
+	}
+
+	taken := make([]*rate.Reservation, 0, len(buckets))
+	for i, b := range buckets {
+		r := t.limiter(b, now).ReserveN(now, max(b.tokens, 1))
+		delay := r.DelayFrom(now)
+		if !r.OK() {
+			// A burst below the tokens taken never admits; retry after a
+			// second.
+			delay = time.Second
+		}
+		if delay > 0 {
+			r.CancelAt(now)
+			for _, prev := range taken {
+				prev.CancelAt(now)
+			}
+			return i, delay
+		}
+		taken = append(taken, r)
+	}
+	return -1, 0
+}
+
+// limiter returns b's limiter, sized to b. Callers hold t.mu.
+func (t *tenantLimiters) limiter(b rateBucket, now time.Time) *rate.Limiter {
+	if e, found := t.byKey[b.key]; found {
+		t.order.MoveToFront(e)
+		lim := e.Value.(*tenantLimiter).lim
+		if lim.Limit() != b.limit || lim.Burst() != b.burst {
+			lim.SetLimitAt(now, b.limit)
+			lim.SetBurstAt(now, b.burst)
+		}
+		return lim
+	}
+	if t.order.Len() >= maxRateLimiters {
+		oldest := t.order.Back()
+		t.order.Remove(oldest)
+		delete(t.byKey, oldest.Value.(*tenantLimiter).key)
+		rateLimiterEvictions.Add(context.Background(), 1)
+	}
+	lim := rate.NewLimiter(b.limit, b.burst)
+	t.byKey[b.key] = t.order.PushFront(&tenantLimiter{key: b.key, tenantID: b.tenantID, lim: lim})
+	return lim
+}
+
+// tokens returns the tokens available in each tracked tenant-wide bucket
+// at now.
+func (t *tenantLimiters) tokens(now time.Time) map[string]float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make(map[string]float64, t.order.Len())
+	for e := t.order.Front(); e != nil; e = e.Next() {
+		if tl := e.Value.(*tenantLimiter); tl.key == tl.tenantID {
+			out[tl.tenantID] = tl.lim.TokensAt(now)
+		}
+	}
+	return out
+}
+
+// tenantRate returns the tenant-wide rate and burst for tenantID, and the
+// tenant's further rate limits. The rate and burst are its settings'
+// rate_limit_per_sec and rate_limit_burst when set, else
+// RATE_LIMIT_PER_TENANT and RATE_LIMIT_BURST_PER_TENANT; a burst left unset
+// is twice the rate.
+func (gw *Gateway) tenantRate(ctx context.Context, tenantID string) (limit, burst int, rules []tenants.RateLimit) {
+	limit, burst = gw.perTenantLimit, gw.perTenantBurst
+	settings, err := gw.settings.Get(ctx, tenantID)
+	if err != nil {
+		gw.log.WarnContext(ctx, "tenant settings lookup failed, using default rate limit", "tenant_id", tenantID, "error", err)
+	} else {
+		if settings.RateLimitPerSec > 0 {
+			limit, burst = settings.RateLimitPerSec, settings.RateLimitBurst
+		}
+		rules = settings.RateLimits
+	}
+	if burst == 0 {
+		burst = limit * 2
+	}
+	return limit, burst, rules
+}
+
+// rateBuckets returns the buckets req draws from: its tenant's, then one
+// for each of the tenant's rate limits that req matches.
+func (gw *Gateway) rateBuckets(ctx context.Context, req types.ToolCallRequest) []rateBucket {
+	limit, burst, rules := gw.tenantRate(ctx, req.TenantID)
+	buckets := []rateBucket{{key: req.TenantID, tenantID: req.TenantID, limit: rate.Limit(limit), burst: burst}}
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.Matches(req.AgentID, req.Tool, req.Action) {
+			continue
+		}
+		agent := rule.AgentID
+		if agent == tenants.RateLimitEachAgent {
+			agent = req.AgentID
+		}
+		buckets = append(buckets, rateBucket{
+			key:      strings.Join([]string{req.TenantID, rule.AgentID, agent, rule.ToolAction, rule.Per}, "\x00"),
+			tenantID: req.TenantID,
+			limit:    rate.Limit(rule.PerSecond()),
+			burst:    rule.BurstOrLimit(),
+			rule:     rule,
+			agentID:  agent,
+		})
+	}
+	return buckets
+}
+
+// rateLimitDetails is the details of a 429 RATE_LIMITED: the limit the call
+// ran into.
+type rateLimitDetails struct {
+	// Dimension is tenant, agent, tool_action or agent_tool_action.
+	Dimension  string `json:"dimension"`
+	AgentID    string `json:"agent_id,omitempty"`
+	ToolAction string `json:"tool_action,omitempty"`
+	Limit      int    `json:"limit"`
+	Per        string `json:"per"`
+	RetryAfter int    `json:"retry_after_sec"`
+}
+
+// allowRate takes one call from every rate limit req is subject to. When
+// one of them is exhausted it returns the 429 to send, naming that limit.
+func (gw *Gateway) allowRate(ctx context.Context, req types.ToolCallRequest) *rateLimitDetails {
+	return gw.takeRate(ctx, req, gw.rateBuckets(ctx, req))
+}
+
+// allowPlanRate charges a plan as the calls it makes: the tenant's limit
+// and every rule a step matches give up one token per step, and the rules
+// matching the plan itself (oc.plan) one more. A plan with more steps than
+// a bucket's burst is refused.
+func (gw *Gateway) allowPlanRate(ctx context.Context, req types.ToolCallRequest, steps []types.ToolCallRequest) *rateLimitDetails {
+	buckets := gw.rateBuckets(ctx, req)
+	buckets[0].tokens = max(len(steps), 1)
+	index := make(map[string]int, len(buckets))
+	for i, b := range buckets {
+		index[b.key] = i
+	}
+	for _, step := range steps {
+		// The first bucket is the tenant's, already charged per step.
+		for _, b := range gw.rateBuckets(ctx, step)[1:] {
+			if i, ok := index[b.key]; ok {
+				buckets[i].tokens = max(buckets[i].tokens, 1) + 1
+				continue
+			}
+			b.tokens = 1
+			index[b.key] = len(buckets)
+			buckets = append(buckets, b)
+		}
+	}
+	return gw.takeRate(ctx, req, buckets)
+}
+
+// takeRate reserves buckets for req and records the outcome.
+func (gw *Gateway) takeRate(ctx context.Context, req types.ToolCallRequest, buckets []rateBucket) *rateLimitDetails {
+	i, retryAfter := gw.rateLimits.reserve(buckets, time.Now())
+	if i < 0 {
+		rateLimitDecisions.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("tenant", req.TenantID),
+			attribute.String("outcome", "allowed"),
+		))
+		return nil
+	}
+	d := &rateLimitDetails{Dimension: "tenant", Per: "second", RetryAfter: retryAfterSec(retryAfter)}
+	if b := buckets[i]; b.rule == nil {
+		d.Limit = int(b.limit)
+	} else {
+		d.AgentID, d.ToolAction, d.Limit, d.Per = b.agentID, b.rule.ToolAction, b.rule.Limit, b.rule.Per
+		switch {
+		case d.AgentID != "" && d.ToolAction != "":
+			d.Dimension = "agent_tool_action"
+		case d.AgentID != "":
+			d.Dimension = "agent"
+		default:
+			d.Dimension = "tool_action"
+		}
+	}
+	rateLimitDecisions.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("tenant", req.TenantID),
+		attribute.String("outcome", "limited"),
+		attribute.String("dimension", d.Dimension),
+	))
+	return d
+}
+
+// retryAfterSec rounds a wait up to whole seconds, at least one.
+func retryAfterSec(d time.Duration) int {
+	return max(1, int(math.Ceil(d.Seconds())))
+}
+
+// writeRateLimited writes 429 RATE_LIMITED with Retry-After and the limit
+// that was hit.
+func writeRateLimited(w http.ResponseWriter, d *rateLimitDetails) {
+	w.Header().Set("Retry-After", strconv.Itoa(d.RetryAfter))
+	apiErr := types.ErrRateLimited()
+	apiErr.Details = d
+	apiErr.WriteJSON(w)
+}
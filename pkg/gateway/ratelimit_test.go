@@ -0,0 +1,174 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/tenants"
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"golang.org/x/time/rate"
+)
+
+func tenantBucket(tenantID string, limit float64, burst int) []rateBucket {
+	return []rateBucket{{key: tenantID, tenantID: tenantID, limit: rate.Limit(limit), burst: burst}}
+}
+
+func TestTenantLimiters_EvictsLeastRecentlyUsed(t *testing.T) {
+	var l tenantLimiters
+	now := time.Now()
+	for i := range maxRateLimiters {
+		l.reserve(tenantBucket(fmt.Sprintf("t%d", i), 1, 1), now)
+	}
+	// Touch the oldest tenant so the second oldest is evicted instead.
+	first, second := "t0", "t1"
+	if i, _ := l.reserve(tenantBucket(first, 1, 1), now); i < 0 {
+		t.Fatal("tracked tenant's bucket was reset")
+	}
+	l.reserve(tenantBucket("new", 1, 1), now)
+
+	tokens := l.tokens(now)
+	if len(tokens) != maxRateLimiters {
+		t.Fatalf("tracking %d tenants, want %d", len(tokens), maxRateLimiters)
+	}
+	if _, ok := tokens[first]; !ok {
+		t.Error("recently used tenant was evicted")
+	}
+	if _, ok := tokens[second]; ok {
+		t.Error("least recently used tenant was kept")
+	}
+	if i, _ := l.reserve(tenantBucket(second, 1, 1), now); i >= 0 {
+		t.Error("evicted tenant should start with a full bucket")
+	}
+}
+
+func TestTenantLimiters_RetryAfterFromReservation(t *testing.T) {
+	var l tenantLimiters
+	now := time.Now()
+	for range 3 {
+		if i, _ := l.reserve(tenantBucket("t1", 2, 3), now); i >= 0 {
+			t.Fatal("burst call rejected")
+		}
+	}
+	i, retryAfter := l.reserve(tenantBucket("t1", 2, 3), now)
+	if i != 0 || retryAfter != 500*time.Millisecond {
+		t.Fatalf("over burst = %d, %v; want limited for 500ms", i, retryAfter)
+	}
+	// The rejected call took no token, so one is free after the wait.
+	if i, _ := l.reserve(tenantBucket("t1", 2, 3), now.Add(retryAfter)); i >= 0 {
+		t.Fatal("call after Retry-After rejected")
+	}
+	if i, _ := l.reserve(tenantBucket("t1", 2, 0), now.Add(time.Hour)); i < 0 {
+		t.Fatal("zero burst admitted a call")
+	}
+}
+
+func TestAllowRate_PerAgentAndToolAction(t *testing.T) {
+	gw := newExecuteGateway(newFakeEvidence(), &fakeConnectors{}, &fakeApprovals{})
+	gw.perTenantLimit = 100
+	gw.settings = tenants.NewSettingsCache(fakeSettings{"tenant1": {RateLimits: []tenants.RateLimit{
+		{AgentID: tenants.RateLimitEachAgent, ToolAction: "jira.issue.create", Limit: 2, Per: "minute"},
+		{ToolAction: "slack.*", Limit: 3, Per: "hour"},
+	}}}, time.Minute)
+	ctx := context.Background()
+	call := func(agent, tool, action string) *rateLimitDetails {
+		return gw.allowRate(ctx, types.ToolCallRequest{TenantID: "tenant1", AgentID: agent, Tool: tool, Action: action})
+	}
+
+	for range 2 {
+		if d := call("a1", "jira", "issue.create"); d != nil {
+			t.Fatalf("call within agent limit = %+v", d)
+		}
+	}
+	d := call("a1", "jira", "issue.create")
+	if d == nil || d.Dimension != "agent_tool_action" || d.AgentID != "a1" || d.ToolAction != "jira.issue.create" ||
+		d.Limit != 2 || d.Per != "minute" || d.RetryAfter != 30 {
+		t.Fatalf("third call by a1 = %+v", d)
+	}
+	// Each agent has its own bucket, and other actions are not limited.
+	if d := call("a2", "jira", "issue.create"); d != nil {
+		t.Fatalf("a2 limited by a1's calls: %+v", d)
+	}
+	if d := call("a1", "jira", "issue.get"); d != nil {
+		t.Fatalf("unmatched action limited: %+v", d)
+	}
+
+	// Matching actions share a tool_action bucket across agents.
+	for i, action := range []string{"msg.post", "msg.delete", "msg.post"} {
+		if d := call(fmt.Sprintf("b%d", i), "slack", action); d != nil {
+			t.Fatalf("slack call %d = %+v", i, d)
+		}
+	}
+	if d := call("b9", "slack", "msg.post"); d == nil || d.Dimension != "tool_action" || d.AgentID != "" || d.Per != "hour" {
+		t.Fatalf("fourth slack call = %+v", d)
+	}
+}
+
+func TestAllowPlanRate_ChargesEachStep(t *testing.T) {
+	gw := newExecuteGateway(newFakeEvidence(), &fakeConnectors{}, &fakeApprovals{})
+	gw.perTenantLimit, gw.perTenantBurst = 1, 5
+	gw.settings = tenants.NewSettingsCache(fakeSettings{"tenant1": {RateLimits: []tenants.RateLimit{
+		{AgentID: tenants.RateLimitEachAgent, ToolAction: "slack.msg.post", Limit: 3, Per: "minute"},
+	}}}, time.Minute)
+	ctx := context.Background()
+	plan := types.ToolCallRequest{TenantID: "tenant1", AgentID: "a1", Tool: types.PlanTool, Action: types.PlanAction}
+	post := types.ToolCallRequest{TenantID: "tenant1", AgentID: "a1", Tool: "slack", Action: "msg.post"}
+	steps := func(n int) []types.ToolCallRequest {
+		out := make([]types.ToolCallRequest, n)
+		for i := range out {
+			out[i] = post
+		}
+		return out
+	}
+
+	// Four posts exceed the agent's limit of three, though the plan is one
+	// request, and nothing is taken.
+	if d := gw.allowPlanRate(ctx, plan, steps(4)); d == nil || d.Dimension != "agent_tool_action" || d.AgentID != "a1" {
+		t.Fatalf("plan of 4 posts = %+v, want agent_tool_action limit", d)
+	}
+	if d := gw.allowPlanRate(ctx, plan, steps(2)); d != nil {
+		t.Fatalf("plan of 2 posts = %+v", d)
+	}
+	if d := gw.allowRate(ctx, post); d != nil {
+		t.Fatalf("third post = %+v", d)
+	}
+	if d := gw.allowRate(ctx, post); d == nil || d.Dimension != "agent_tool_action" {
+		t.Fatalf("fourth post = %+v, want agent_tool_action limit", d)
+	}
+
+	// Each step also draws on the tenant's bucket: 3 of its 5 are gone.
+	other := types.ToolCallRequest{TenantID: "tenant1", AgentID: "a2", Tool: "jira", Action: "issue.get"}
+	if d := gw.allowPlanRate(ctx, plan, []types.ToolCallRequest{other, other, other}); d == nil || d.Dimension != "tenant" {
+		t.Fatalf("plan over the tenant bucket = %+v, want tenant limit", d)
+	}
+}
+
+func TestHandleToolCall_RateLimitedSetsRetryAfter(t *testing.T) {
+	gw := newExecuteGateway(newFakeEvidence(), &fakeConnectors{output: json.RawMessage(`{}`)}, &fakeApprovals{})
+	gw.perTenantLimit, gw.perTenantBurst = 1, 1
+
+	post := func(key string) *http.Response {
+		body, _ := json.Marshal(types.ToolCallRequest{TenantID: "tenant1", AgentID: "agent-1", Tool: "slack", Action: "msg.post", IdempotencyKey: key})
+		return postToolCall(t, gw, body).Result()
+	}
+	if resp := post("k1"); resp.StatusCode != http.StatusOK {
+		t.Fatalf("first call = %d", resp.StatusCode)
+	}
+	resp := post("k2")
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") != "1" {
+		t.Fatalf("second call = %d with Retry-After %q, want 429 and 1", resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+	var apiErr struct {
+		Code    string           `json:"code"`
+		Details rateLimitDetails `json:"details"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&apiErr); err != nil {
+		t.Fatal(err)
+	}
+	if apiErr.Code != "RATE_LIMITED" || apiErr.Details.Dimension != "tenant" || apiErr.Details.Limit != 1 {
+		t.Fatalf("429 body = %+v", apiErr)
+	}
+}
@@ -0,0 +1,159 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/bturcanu/OpenClause/pkg/config"
+	"github.com/bturcanu/OpenClause/pkg/receipts"
+	"github.com/bturcanu/OpenClause/pkg/secrets"
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+// receiptSignerFromEnv builds the receipt signer from RECEIPT_SIGNING_KEY
+// (a base64 Ed25519 seed, literal or secret reference) and
+// RECEIPT_PREVIOUS_PUBLIC_KEYS. It returns nil when no key is set.
+func receiptSignerFromEnv(ctx context.Context, env config.Env, resolver *secrets.Resolver) (*receipts.Signer, error) {
+	raw, err := resolver.Resolve(ctx, env.Get("RECEIPT_SIGNING_KEY"))
+	if err != nil || raw == "" {
+		return nil, err
+	}
+	key, err := receipts.ParsePrivateKey(raw)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := receipts.ParsePublicKeys(env.Get("RECEIPT_PREVIOUS_PUBLIC_KEYS"))
+	if err != nil {
+		return nil, err
+	}
+	return receipts.NewSigner(key, previous...), nil
+}
+
+// receipt signs a receipt for env once it is recorded. It returns "" when
+// receipts are disabled or env was not recorded; a signing failure is logged
+// and never fails the call.
+func (gw *Gateway) receipt(ctx context.Context, env *types.ToolCallEnvelope) string {
+	if gw.receipts == nil || env.Hash == "" {
+		return ""
+	}
+	receipt, err := gw.receipts.Sign(receipts.Claims{
+		EventID:  env.EventID,
+		TenantID: env.Request.TenantID,
+		Decision: string(env.Decision),
+		Hash:     env.Hash,
+		PrevHash: env.PrevHash,
+		Seq:      env.EventSeq,
+	})
+	if err != nil {
+		gw.log.ErrorContext(ctx, "receipt signing failed", "event_id", env.EventID, "error", err)
+		return ""
+	}
+	return receipt
+}
+
+// responseSignatureHeader carries the detached JWS of a tool-call response
+// body when response signing is enabled.
+const responseSignatureHeader = "X-Response-Signature"
+
+// writeResponse writes resp as the JSON body of a tool-call response,
+// signed when response signing is enabled. A signing failure is logged and
+// the response goes out unsigned. Quarantined output is left out.
+func (gw *Gateway) writeResponse(ctx context.Context, w http.ResponseWriter, resp *types.ToolCallResponse) {
+	if result := resp.Result.Withheld(); result != resp.Result {
+		withheld := *resp
+		withheld.Result = result
+		resp = &withheld
+	}
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(resp); err != nil {
+		gw.log.ErrorContext(ctx, "response encode failed", "error", err)
+		types.ErrInternal("failed to encode response").WriteJSON(w)
+		return
+	}
+	if gw.signResponses && gw.receipts != nil {
+		sig, err := gw.receipts.SignDetached(body.Bytes())
+		if err != nil {
+			gw.log.ErrorContext(ctx, "response signing failed", "event_id", resp.EventID, "error", err)
+		} else {
+			w.Header().Set(responseSignatureHeader, sig)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(body.Bytes()); err != nil {
+		gw.log.ErrorContext(ctx, "response write failed", "error", err)
+	}
+}
+
+// HandleJWKS is GET /.well-known/jwks.json: the public keys receipts are
+// signed with, for verifying them offline.
+func (gw *Gateway) HandleJWKS(w http.ResponseWriter, r *http.Request) {
+	if gw.receipts == nil {
+		types.ErrNotFound("receipts are not enabled").WriteJSON(w)
+		return
+	}
+	w.Header().Set("Content-Type", "application/jwk-set+json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	if err := json.NewEncoder(w).Encode(gw.receipts.JWKS()); err != nil {
+		gw.log.ErrorContext(r.Context(), "response encode failed", "error", err)
+	}
+}
+
+// receiptVerification is the response of POST /v1/receipts/verify.
+type receiptVerification struct {
+	Valid  bool             `json:"valid"`
+	Error  string           `json:"error,omitempty"`
+	Claims *receipts.Claims `json:"claims,omitempty"`
+}
+
+// HandleVerifyReceipt is POST /v1/receipts/verify. It checks the receipt's
+// signature and that the evidence log still holds the event it attests,
+// with the same hash, tenant and decision. It needs no API key: a receipt
+// only discloses what its holder already has.
+func (gw *Gateway) HandleVerifyReceipt(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if gw.receipts == nil {
+		types.ErrNotFound("receipts are not enabled").WriteJSON(w)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, 64<<10)
+	var in struct {
+		Receipt string `json:"receipt"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil || in.Receipt == "" {
+		types.ErrBadRequest("body must be {\"receipt\": \"...\"}").WriteJSON(w)
+		return
+	}
+
+	out := receiptVerification{}
+	claims, err := gw.receipts.Verify(in.Receipt)
+	if err != nil {
+		out.Error = err.Error()
+		gw.writeVerification(w, r, out)
+		return
+	}
+	out.Claims = claims
+	env, err := gw.evidence.GetEvent(ctx, claims.EventID)
+	if err != nil {
+		gw.log.ErrorContext(ctx, "receipt event lookup failed", "event_id", claims.EventID, "error", err)
+		types.ErrInternal("failed to look up receipt event").WriteJSON(w)
+		return
+	}
+	switch {
+	case env == nil:
+		out.Error = "event not found in the evidence log"
+	case env.Hash != claims.Hash || env.Request.TenantID != claims.TenantID || string(env.Decision) != claims.Decision:
+		out.Error = "receipt does not match the evidence log"
+	default:
+		out.Valid = true
+	}
+	gw.writeVerification(w, r, out)
+}
+
+func (gw *Gateway) writeVerification(w http.ResponseWriter, r *http.Request, out receiptVerification) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(out); err != nil {
+		gw.log.ErrorContext(r.Context(), "response encode failed", "error", err)
+	}
+}
@@ -0,0 +1,117 @@
+package gateway
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bturcanu/OpenClause/pkg/receipts"
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+func verifyReceipt(t *testing.T, gw *Gateway, receipt string) receiptVerification {
+	t.Helper()
+	body, _ := json.Marshal(map[string]string{"receipt": receipt})
+	rr := httptest.NewRecorder()
+	gw.HandleVerifyReceipt(rr, httptest.NewRequest(http.MethodPost, "/v1/receipts/verify", bytes.NewReader(body)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("verify: %d %s", rr.Code, rr.Body.String())
+	}
+	var out receiptVerification
+	if err := json.NewDecoder(rr.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestReceipts_IssuedAndVerified(t *testing.T) {
+	fe := newFakeEvidence()
+	gw := newExecuteGateway(fe, &fakeConnectors{output: json.RawMessage(`{"ok":true}`)}, &fakeApprovals{})
+	gw.perTenantLimit = 100
+	gw.receipts = receipts.NewSigner(ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize)))
+
+	body, _ := json.Marshal(types.ToolCallRequest{
+		TenantID: "tenant1", AgentID: "agent-1", Tool: "slack", Action: "msg.post", IdempotencyKey: "r1",
+	})
+	rr := postToolCall(t, gw, body)
+	var resp types.ToolCallResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Receipt == "" {
+		t.Fatalf("no receipt in %+v", resp)
+	}
+
+	claims, err := receipts.Verify(resp.Receipt, gw.receipts.JWKS())
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := fe.events[resp.EventID]
+	if claims.EventID != resp.EventID || claims.Hash != env.Hash || claims.Seq != env.EventSeq || claims.Decision != "allow" {
+		t.Errorf("claims = %+v", claims)
+	}
+	if out := verifyReceipt(t, gw, resp.Receipt); !out.Valid {
+		t.Errorf("verify = %+v", out)
+	}
+
+	// A receipt no longer matching the log does not verify.
+	env.Hash = "rewritten"
+	if out := verifyReceipt(t, gw, resp.Receipt); out.Valid || out.Error == "" {
+		t.Errorf("verify after rewrite = %+v", out)
+	}
+
+	jwks := httptest.NewRecorder()
+	gw.HandleJWKS(jwks, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", http.NoBody))
+	var set receipts.JWKS
+	if err := json.NewDecoder(jwks.Body).Decode(&set); err != nil || len(set.Keys) != 1 || set.Keys[0].Kid != gw.receipts.KeyID() {
+		t.Errorf("jwks = %+v, %v", set, err)
+	}
+}
+
+func TestReceipts_DisabledWithoutKey(t *testing.T) {
+	gw := newExecuteGateway(newFakeEvidence(), &fakeConnectors{}, &fakeApprovals{})
+	gw.perTenantLimit = 100
+	body, _ := json.Marshal(types.ToolCallRequest{
+		TenantID: "tenant1", AgentID: "agent-1", Tool: "slack", Action: "msg.post", IdempotencyKey: "r1",
+	})
+	var resp types.ToolCallResponse
+	if err := json.NewDecoder(postToolCall(t, gw, body).Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Receipt != "" {
+		t.Errorf("receipt issued without a key: %s", resp.Receipt)
+	}
+	rr := httptest.NewRecorder()
+	gw.HandleJWKS(rr, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", http.NoBody))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("jwks status = %d", rr.Code)
+	}
+}
+
+func TestResponseSigning(t *testing.T) {
+	gw := newExecuteGateway(newFakeEvidence(), &fakeConnectors{output: json.RawMessage(`{"ok":true}`)}, &fakeApprovals{})
+	gw.perTenantLimit = 100
+	gw.receipts = receipts.NewSigner(ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize)))
+	body, _ := json.Marshal(types.ToolCallRequest{
+		TenantID: "tenant1", AgentID: "agent-1", Tool: "slack", Action: "msg.post", IdempotencyKey: "r1",
+	})
+
+	if rr := postToolCall(t, gw, body); rr.Header().Get(responseSignatureHeader) != "" {
+		t.Fatal("response signed with signing disabled")
+	}
+
+	gw.signResponses = true
+	// The replay of an idempotent call is signed too.
+	rr := postToolCall(t, gw, body)
+	sig := rr.Header().Get(responseSignatureHeader)
+	if err := receipts.VerifyDetached(sig, rr.Body.Bytes(), gw.receipts.JWKS()); err != nil {
+		t.Fatalf("signature %q does not verify: %v", sig, err)
+	}
+	altered := bytes.Replace(rr.Body.Bytes(), []byte(`"allow"`), []byte(`"deny"`), 1)
+	if err := receipts.VerifyDetached(sig, altered, gw.receipts.JWKS()); err == nil {
+		t.Error("signature verifies an altered body")
+	}
+}
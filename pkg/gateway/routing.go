@@ -0,0 +1,52 @@
+package gateway
+
+import (
+	"context"
+	"slices"
+
+	"github.com/bturcanu/OpenClause/pkg/approvals"
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+// routeApproval routes the approval request in. The policy decision's
+// group and routes come first and the tenant's defaults fill what it left
+// unset. Only then is the call's own approver_group and notify_channel
+// considered, and only where the tenant's settings allow it: a requested
+// group replaces the tenant's default but never the policy's, and a
+// requested channel is added to the routes, never in place of them. A
+// compromised agent therefore cannot steer its approvals away from the
+// approvers the tenant chose.
+func (gw *Gateway) routeApproval(ctx context.Context, in *approvals.CreateApprovalInput, req types.ToolCallRequest) {
+	policyGroup := in.ApproverGroup != ""
+	if err := gw.settings.ApplyApprovalDefaults(ctx, in); err != nil {
+		gw.log.WarnContext(ctx, "tenant approval defaults unavailable", "error", err)
+		return
+	}
+	if req.ApproverGroup == "" && req.NotifyChannel == "" {
+		return
+	}
+	settings, err := gw.settings.Get(ctx, req.TenantID)
+	if err != nil {
+		gw.log.WarnContext(ctx, "tenant settings unavailable, ignoring requested routing", "error", err)
+		return
+	}
+	if g := req.ApproverGroup; g != "" && g != in.ApproverGroup {
+		switch {
+		case policyGroup:
+		case slices.Contains(settings.RequestableApproverGroups, g):
+			in.ApproverGroup = g
+		default:
+			gw.log.WarnContext(ctx, "requested approver group not allowed by tenant", "tenant_id", req.TenantID, "agent_id", req.AgentID, "approver_group", g)
+		}
+	}
+	if ch := req.NotifyChannel; ch != "" {
+		route := types.PolicyNotify{Kind: "slack", Channel: ch}
+		switch {
+		case !slices.Contains(settings.RequestableNotifyChannels, ch):
+			gw.log.WarnContext(ctx, "requested notify channel not allowed by tenant", "tenant_id", req.TenantID, "agent_id", req.AgentID, "notify_channel", ch)
+		case !slices.ContainsFunc(in.Notify, func(n types.PolicyNotify) bool { return n.Kind == route.Kind && n.Channel == ch }):
+			// in.Notify may be the tenant's cached slice; never append in place.
+			in.Notify = append(slices.Clip(in.Notify), route)
+		}
+	}
+}
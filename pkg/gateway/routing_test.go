@@ -0,0 +1,59 @@
+package gateway
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/tenants"
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+func TestRouteApproval_TenantAllowlistAndPolicyWin(t *testing.T) {
+	fa := &fakeApprovals{}
+	gw := newExecuteGateway(newFakeEvidence(), &fakeConnectors{}, fa)
+	gw.perTenantLimit = 100
+	gw.policy = fakePolicy{decision: types.DecisionApprove, reason: "needs approval"}
+	tenantRoute := types.PolicyNotify{Kind: "slack", Channel: "#sec-approvals"}
+	settings := fakeSettings{"tenant1": {ApproverGroup: "security", Notify: []types.PolicyNotify{tenantRoute}}}
+	gw.settings = tenants.NewSettingsCache(settings, time.Minute)
+
+	call := types.ToolCallRequest{
+		TenantID: "tenant1", AgentID: "agent-1", Tool: "slack", Action: "msg.post",
+		IdempotencyKey: "routed", ApproverGroup: "sre", NotifyChannel: "#sre-approvals",
+	}
+	post := func(key string) {
+		t.Helper()
+		call.IdempotencyKey = key
+		body, _ := json.Marshal(call)
+		postToolCall(t, gw, body)
+	}
+
+	// Nothing requestable: the tenant's routing stands.
+	post("routed-1")
+	if fa.last.ApproverGroup != "security" || len(fa.last.Notify) != 1 || fa.last.Notify[0].Channel != tenantRoute.Channel {
+		t.Fatalf("unlisted routing applied: group %q, notify %+v", fa.last.ApproverGroup, fa.last.Notify)
+	}
+
+	// Allowlisted: the group replaces the tenant default, the channel is added.
+	settings["tenant1"] = tenants.Settings{
+		ApproverGroup: "security", Notify: []types.PolicyNotify{tenantRoute},
+		RequestableApproverGroups: []string{"sre"}, RequestableNotifyChannels: []string{"#sre-approvals"},
+	}
+	gw.settings = tenants.NewSettingsCache(settings, time.Minute)
+	post("routed-2")
+	if fa.last.ApproverGroup != "sre" || len(fa.last.Notify) != 2 ||
+		fa.last.Notify[0].Channel != tenantRoute.Channel || fa.last.Notify[1].Channel != "#sre-approvals" {
+		t.Fatalf("requested routing not applied: group %q, notify %+v", fa.last.ApproverGroup, fa.last.Notify)
+	}
+	if s, _ := gw.settings.Get(t.Context(), "tenant1"); len(s.Notify) != 1 {
+		t.Fatalf("requested channel leaked into cached tenant settings: %+v", s.Notify)
+	}
+
+	// A group the policy names is never overridden.
+	gw.policy = fakePolicy{decision: types.DecisionApprove, reason: "needs approval", approverGroup: "compliance"}
+	post("routed-3")
+	if fa.last.ApproverGroup != "compliance" {
+		t.Fatalf("approver group = %q, want the policy's", fa.last.ApproverGroup)
+	}
+}
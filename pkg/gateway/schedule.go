@@ -0,0 +1,233 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/auth"
+	"github.com/bturcanu/OpenClause/pkg/tenants"
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	// maxScheduleAhead bounds how far in the future execute_at may be.
+	maxScheduleAhead = 30 * 24 * time.Hour
+	// scheduleBatch is how many due schedules one scheduler pass claims.
+	scheduleBatch = 50
+	// scheduleLease is how long a claimed schedule stays running before
+	// another scheduler may claim it again, for a replica that stopped
+	// mid-run. It is well above the connector timeout.
+	scheduleLease = 10 * time.Minute
+)
+
+// validateExecuteAt checks that an approved call may be scheduled for at:
+// within maxScheduleAhead and before the call's deadline.
+func validateExecuteAt(req types.ToolCallRequest, at, now time.Time) *types.APIError {
+	if at.Sub(now) > maxScheduleAhead {
+		return types.ErrValidation(&types.ValidationError{Field: "execute_at", Reason: fmt.Sprintf("must be within %s", maxScheduleAhead)})
+	}
+	if req.Deadline != nil && !at.Before(*req.Deadline) {
+		return types.ErrValidation(&types.ValidationError{Field: "execute_at", Reason: "must be before the call's deadline"})
+	}
+	return nil
+}
+
+// scheduleExecution stores a schedule that runs parent under the consumed
+// grant at executeAt, and records it in the audit trail.
+func (gw *Gateway) scheduleExecution(ctx context.Context, parent *types.ToolCallEnvelope, grantID string, executeAt time.Time) (*types.ScheduledExecution, *types.APIError) {
+	sched := &types.ScheduledExecution{
+		ID:        uuid.NewString(),
+		EventID:   parent.EventID,
+		TenantID:  parent.Request.TenantID,
+		GrantID:   grantID,
+		ExecuteAt: executeAt.UTC(),
+	}
+	if err := gw.evidence.CreateSchedule(ctx, sched); err != nil {
+		gw.log.ErrorContext(ctx, "create schedule failed", "event_id", parent.EventID, "grant_id", grantID, "error", err)
+		return nil, types.ErrInternal("failed to schedule execution")
+	}
+	if err := gw.audit.Record(ctx, "toolcall_scheduled", sched.TenantID, parent.EventID, parent.Hash, sched); err != nil {
+		gw.log.ErrorContext(ctx, "audit log write failed", "schedule_id", sched.ID, "error", err)
+	}
+	return sched, nil
+}
+
+// HandleGetSchedule is GET /v1/toolcalls/{event_id}/schedule, the latest
+// schedule of an approved call.
+func (gw *Gateway) HandleGetSchedule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	env, apiErr := gw.tenantEvent(ctx, chi.URLParam(r, "event_id"))
+	if apiErr != nil {
+		apiErr.WriteJSON(w)
+		return
+	}
+	sched, err := gw.evidence.GetSchedule(ctx, env.EventID)
+	if err != nil {
+		gw.log.ErrorContext(ctx, "get schedule failed", "event_id", env.EventID, "error", err)
+		types.ErrInternal("failed to retrieve schedule").WriteJSON(w)
+		return
+	}
+	if sched == nil {
+		types.ErrNotFound("event has no schedule").WriteJSON(w)
+		return
+	}
+	writeSchedule(w, http.StatusOK, sched)
+}
+
+// HandleCancelSchedule is POST /v1/toolcalls/{event_id}/schedule/cancel.
+// Only a schedule that has not started running can be cancelled; the
+// grant it consumed is not given back.
+func (gw *Gateway) HandleCancelSchedule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	env, apiErr := gw.tenantEvent(ctx, chi.URLParam(r, "event_id"))
+	if apiErr != nil {
+		apiErr.WriteJSON(w)
+		return
+	}
+	sched, err := gw.evidence.CancelSchedule(ctx, env.EventID)
+	if err != nil {
+		gw.log.ErrorContext(ctx, "cancel schedule failed", "event_id", env.EventID, "error", err)
+		types.ErrInternal("failed to cancel schedule").WriteJSON(w)
+		return
+	}
+	if sched == nil {
+		types.ErrConflict("event has no pending schedule").WriteJSON(w)
+		return
+	}
+	if err := gw.audit.Record(ctx, "toolcall_schedule_cancelled", sched.TenantID, env.EventID, env.Hash, sched); err != nil {
+		gw.log.ErrorContext(ctx, "audit log write failed", "schedule_id", sched.ID, "error", err)
+	}
+	writeSchedule(w, http.StatusOK, sched)
+}
+
+// RunScheduler executes due schedules every interval until ctx is done.
+// Schedules are claimed atomically, so every gateway replica may run it.
+func (gw *Gateway) RunScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			gw.runDueSchedules(ctx, now)
+		}
+	}
+}
+
+// runDueSchedules claims the schedules due by now and runs each under its
+// grant, rechecking the deadline, the tenant's status and its blocklist,
+// catalog and freeze windows first. A schedule whose agent is at its
+// concurrency cap is put back and claimed again on a later run.
+func (gw *Gateway) runDueSchedules(ctx context.Context, now time.Time) {
+	due, err := gw.evidence.ClaimDueSchedules(ctx, now, scheduleLease, scheduleBatch)
+	if err != nil {
+		gw.log.ErrorContext(ctx, "claim due schedules failed", "error", err)
+		return
+	}
+	for _, sched := range due {
+		status, execID, reason := gw.runSchedule(ctx, sched)
+		switch status {
+		case types.ScheduleFailed:
+			gw.log.WarnContext(ctx, "scheduled execution failed", "schedule_id", sched.ID, "event_id", sched.EventID, "reason", reason)
+		case types.ScheduleScheduled:
+			gw.log.InfoContext(ctx, "scheduled execution deferred", "schedule_id", sched.ID, "event_id", sched.EventID)
+		}
+		if err := gw.evidence.FinishSchedule(ctx, sched.ID, status, execID, reason); err != nil {
+			gw.log.ErrorContext(ctx, "finish schedule failed", "schedule_id", sched.ID, "error", err)
+		}
+	}
+}
+
+// runSchedule executes one claimed schedule. It returns the schedule's new
+// status: executed with the execution's event ID, failed with why the call
+// was not executed, or scheduled again when the agent has no free slot or
+// an earlier run of the schedule cannot be ruled out.
+func (gw *Gateway) runSchedule(ctx context.Context, sched types.ScheduledExecution) (status, execID, reason string) {
+	parent, err := gw.evidence.GetEvent(ctx, sched.EventID)
+	if err != nil || parent == nil {
+		gw.log.ErrorContext(ctx, "get scheduled event failed", "event_id", sched.EventID, "error", err)
+		return types.ScheduleFailed, "", "scheduled event could not be read"
+	}
+	// A schedule claimed again after its lease lapsed may already have run.
+	if prior, err := gw.evidence.GetExecutionByParentEvent(ctx, sched.EventID); err != nil {
+		gw.log.ErrorContext(ctx, "get prior execution failed", "event_id", sched.EventID, "error", err)
+		return types.ScheduleScheduled, "", ""
+	} else if prior != nil {
+		return types.ScheduleExecuted, prior.EventID, ""
+	}
+	if deadlinePassed(parent.Request, time.Now()) {
+		return types.ScheduleFailed, "", "deadline passed; the call was not executed"
+	}
+	if reason := gw.tenantInactive(ctx, parent.Request.TenantID); reason != "" {
+		return types.ScheduleFailed, "", reason
+	}
+	if res := gw.tenantDenial(ctx, parent.Request); res != nil {
+		if res.Canary != "" {
+			gw.events.PublishCanary(ctx, parent, res)
+		}
+		return types.ScheduleFailed, "", res.Reason
+	}
+	releaseSlot, busy := gw.acquireAgentSlot(ctx, parent.Request)
+	if busy != nil {
+		return types.ScheduleScheduled, "", ""
+	}
+	defer releaseSlot()
+	resp, apiErr := gw.executeGranted(ctx, parent, sched.GrantID, "scheduled execution", &types.ExecutionSchedule{
+		ID:          sched.ID,
+		ScheduledAt: sched.CreatedAt,
+		ExecuteAt:   sched.ExecuteAt,
+	})
+	if apiErr != nil {
+		return types.ScheduleFailed, "", apiErr.Message
+	}
+	return types.ScheduleExecuted, resp.EventID, ""
+}
+
+// tenantInactive returns why a scheduled call of tenantID may not run: its
+// tenant is suspended or deleted, or its status cannot be loaded. Tenants
+// that were never provisioned, such as those of API_KEYS, are active.
+func (gw *Gateway) tenantInactive(ctx context.Context, tenantID string) string {
+	if gw.tenants == nil {
+		return ""
+	}
+	t, err := gw.tenants.Get(ctx, tenantID)
+	if err != nil {
+		gw.log.ErrorContext(ctx, "tenant status lookup failed", "tenant_id", tenantID, "error", err)
+		return "tenant status unavailable; the call was not executed"
+	}
+	if t != nil && t.Status != tenants.StatusActive {
+		return "tenant is " + t.Status + "; the call was not executed"
+	}
+	return ""
+}
+
+// tenantEvent returns event eventID of the authenticated tenant.
+func (gw *Gateway) tenantEvent(ctx context.Context, eventID string) (*types.ToolCallEnvelope, *types.APIError) {
+	if _, err := uuid.Parse(eventID); err != nil {
+		return nil, types.ErrBadRequest("invalid event_id format")
+	}
+	env, err := gw.evidence.GetEvent(ctx, eventID)
+	if err != nil {
+		gw.log.ErrorContext(ctx, "get event failed", "event_id", eventID, "error", err)
+		return nil, types.ErrInternal("failed to retrieve event")
+	}
+	if env == nil {
+		return nil, types.ErrNotFound("event not found")
+	}
+	if authTenant := auth.TenantFromContext(ctx); authTenant != "" && env.Request.TenantID != authTenant {
+		return nil, types.ErrNotFound("event not found")
+	}
+	return env, nil
+}
+
+func writeSchedule(w http.ResponseWriter, status int, sched *types.ScheduledExecution) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(sched)
+}
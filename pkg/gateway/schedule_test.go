@@ -0,0 +1,178 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/tenants"
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"github.com/go-chi/chi/v5"
+)
+
+func scheduleRouter(gw *Gateway) *chi.Mux {
+	r := chi.NewRouter()
+	r.Post("/v1/toolcalls/{event_id}/execute", gw.HandleExecuteToolCall)
+	r.Get("/v1/toolcalls/{event_id}/schedule", gw.HandleGetSchedule)
+	r.Post("/v1/toolcalls/{event_id}/schedule/cancel", gw.HandleCancelSchedule)
+	return r
+}
+
+func scheduleCall(t *testing.T, r http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	return rr
+}
+
+func approvedEvent(fe *fakeEvidence, id string) {
+	fe.events[id] = &types.ToolCallEnvelope{
+		EventID:  id,
+		Request:  types.ToolCallRequest{TenantID: "tenant1", AgentID: "agent-1", Tool: "slack", Action: "msg.post", Resource: "channel/general"},
+		Decision: types.DecisionApprove,
+	}
+}
+
+func TestExecuteAt_SchedulesAndSchedulerRuns(t *testing.T) {
+	const parentID = "00000000-0000-0000-0000-000000000041"
+	fe := newFakeEvidence()
+	approvedEvent(fe, parentID)
+	fc := &fakeConnectors{output: json.RawMessage(`{"ok":true}`)}
+	fa := &fakeApprovals{usesLeft: 1}
+	gw := newExecuteGateway(fe, fc, fa)
+	r := scheduleRouter(gw)
+
+	at := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	rr := scheduleCall(t, r, http.MethodPost, "/v1/toolcalls/"+parentID+"/execute", `{"execute_at":"`+at.Format(time.RFC3339)+`"}`)
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("schedule = %d %s", rr.Code, rr.Body)
+	}
+	var sched types.ScheduledExecution
+	if err := json.NewDecoder(rr.Body).Decode(&sched); err != nil {
+		t.Fatal(err)
+	}
+	if sched.Status != types.ScheduleScheduled || !sched.ExecuteAt.Equal(at) || sched.GrantID == "" || fc.calls != 0 {
+		t.Fatalf("schedule = %+v, connector calls = %d", sched, fc.calls)
+	}
+	if len(fa.usedBy) != 1 {
+		t.Fatal("scheduling should consume the grant")
+	}
+	// Repeats report the schedule rather than look for another grant.
+	if rr := scheduleCall(t, r, http.MethodPost, "/v1/toolcalls/"+parentID+"/execute", ""); rr.Code != http.StatusAccepted {
+		t.Fatalf("repeat = %d %s", rr.Code, rr.Body)
+	}
+
+	gw.runDueSchedules(t.Context(), time.Now())
+	if fc.calls != 0 {
+		t.Fatal("schedule ran before execute_at")
+	}
+	gw.runDueSchedules(t.Context(), at)
+	if fc.calls != 1 {
+		t.Fatalf("connector calls = %d", fc.calls)
+	}
+	rr = scheduleCall(t, r, http.MethodGet, "/v1/toolcalls/"+parentID+"/schedule", "")
+	if err := json.NewDecoder(rr.Body).Decode(&sched); err != nil {
+		t.Fatal(err)
+	}
+	if sched.Status != types.ScheduleExecuted || sched.ExecutionEventID == "" {
+		t.Fatalf("schedule after run = %+v", sched)
+	}
+	env, _ := fe.GetEvent(t.Context(), sched.ExecutionEventID)
+	if s := env.ExecutionResult.Schedule; s == nil || s.ID != sched.ID || !s.ExecuteAt.Equal(at) {
+		t.Fatalf("execution evidence schedule = %+v", s)
+	}
+	if rr := scheduleCall(t, r, http.MethodPost, "/v1/toolcalls/"+parentID+"/execute", ""); rr.Code != http.StatusOK {
+		t.Fatalf("execute after run should replay, got %d", rr.Code)
+	}
+}
+
+type fakeTenants map[string]string
+
+func (f fakeTenants) Get(_ context.Context, id string) (*tenants.Tenant, error) {
+	status, ok := f[id]
+	if !ok {
+		return nil, nil
+	}
+	return &tenants.Tenant{ID: id, Status: status}, nil
+}
+
+func TestExecuteAt_SuspendedTenantDoesNotRun(t *testing.T) {
+	const parentID = "00000000-0000-0000-0000-000000000043"
+	fe := newFakeEvidence()
+	approvedEvent(fe, parentID)
+	fc := &fakeConnectors{output: json.RawMessage(`{"ok":true}`)}
+	gw := newExecuteGateway(fe, fc, &fakeApprovals{usesLeft: 1})
+	r := scheduleRouter(gw)
+
+	at := time.Now().Add(time.Minute).UTC().Format(time.RFC3339)
+	if rr := scheduleCall(t, r, http.MethodPost, "/v1/toolcalls/"+parentID+"/execute", `{"execute_at":"`+at+`"}`); rr.Code != http.StatusAccepted {
+		t.Fatalf("schedule = %d %s", rr.Code, rr.Body)
+	}
+	gw.tenants = fakeTenants{"tenant1": tenants.StatusSuspended}
+	gw.runDueSchedules(t.Context(), time.Now().Add(time.Hour))
+	if fc.calls != 0 {
+		t.Fatal("suspended tenant's schedule ran")
+	}
+	sched, _ := fe.GetSchedule(t.Context(), parentID)
+	if sched.Status != types.ScheduleFailed || !strings.Contains(sched.Error, "suspended") {
+		t.Fatalf("schedule = %+v", sched)
+	}
+}
+
+func TestExecuteAt_Cancel(t *testing.T) {
+	const parentID = "00000000-0000-0000-0000-000000000042"
+	fe := newFakeEvidence()
+	approvedEvent(fe, parentID)
+	fc := &fakeConnectors{output: json.RawMessage(`{"ok":true}`)}
+	gw := newExecuteGateway(fe, fc, &fakeApprovals{usesLeft: 1})
+	r := scheduleRouter(gw)
+
+	if rr := scheduleCall(t, r, http.MethodPost, "/v1/toolcalls/"+parentID+"/schedule/cancel", ""); rr.Code != http.StatusConflict {
+		t.Fatalf("cancel without schedule = %d", rr.Code)
+	}
+	at := time.Now().Add(time.Minute).UTC().Format(time.RFC3339)
+	if rr := scheduleCall(t, r, http.MethodPost, "/v1/toolcalls/"+parentID+"/execute", `{"execute_at":"`+at+`"}`); rr.Code != http.StatusAccepted {
+		t.Fatalf("schedule = %d %s", rr.Code, rr.Body)
+	}
+	rr := scheduleCall(t, r, http.MethodPost, "/v1/toolcalls/"+parentID+"/schedule/cancel", "")
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"status":"cancelled"`) {
+		t.Fatalf("cancel = %d %s", rr.Code, rr.Body)
+	}
+	gw.runDueSchedules(t.Context(), time.Now().Add(time.Hour))
+	if fc.calls != 0 {
+		t.Fatal("cancelled schedule ran")
+	}
+	// The grant went with the schedule.
+	if rr := scheduleCall(t, r, http.MethodPost, "/v1/toolcalls/"+parentID+"/execute", ""); rr.Code != http.StatusConflict {
+		t.Fatalf("execute after cancel = %d", rr.Code)
+	}
+}
+
+func TestExecuteAt_Validation(t *testing.T) {
+	const parentID = "00000000-0000-0000-0000-000000000043"
+	fe := newFakeEvidence()
+	approvedEvent(fe, parentID)
+	deadline := time.Now().Add(time.Hour)
+	fe.events[parentID].Request.Deadline = &deadline
+	fa := &fakeApprovals{usesLeft: 1}
+	gw := newExecuteGateway(fe, &fakeConnectors{}, fa)
+	r := scheduleRouter(gw)
+
+	for _, at := range []time.Time{deadline.Add(time.Minute), time.Now().Add(maxScheduleAhead + time.Hour)} {
+		rr := scheduleCall(t, r, http.MethodPost, "/v1/toolcalls/"+parentID+"/execute", `{"execute_at":"`+at.UTC().Format(time.RFC3339)+`"}`)
+		if rr.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("execute_at %s = %d %s", at, rr.Code, rr.Body)
+		}
+	}
+	if rr := scheduleCall(t, r, http.MethodPost, "/v1/toolcalls/"+parentID+"/execute", `{"execute_at":`); rr.Code != http.StatusBadRequest {
+		t.Fatalf("malformed body = %d", rr.Code)
+	}
+	if len(fa.usedBy) != 0 {
+		t.Fatal("rejected schedules must not consume the grant")
+	}
+}
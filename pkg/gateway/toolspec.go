@@ -0,0 +1,121 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/auth"
+	"github.com/bturcanu/OpenClause/pkg/connectors"
+	"github.com/bturcanu/OpenClause/pkg/tenants"
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+// manifestRetry is how long an incomplete set of manifests is cached.
+const manifestRetry = 10 * time.Second
+
+// manifestCache holds the connectors' capability manifests for ttl, so spec
+// requests do not fan out to every connector.
+type manifestCache struct {
+	ttl        time.Duration
+	mu         sync.Mutex
+	manifests  []connectors.Manifest
+	incomplete bool
+	expires    time.Time
+}
+
+// get returns the cached manifests, refetching them once they expire.
+// incomplete reports that some connector's manifest could not be fetched.
+func (c *manifestCache) get(ctx context.Context, gw *Gateway) (manifests []connectors.Manifest, incomplete bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if time.Now().Before(c.expires) {
+		return c.manifests, c.incomplete
+	}
+	manifests, errs := gw.connectors.Manifests(ctx)
+	for url, msg := range errs {
+		gw.log.WarnContext(ctx, "connector manifest unavailable", "url", url, "error", msg)
+	}
+	c.manifests, c.incomplete = manifests, len(errs) > 0
+	if ttl := c.ttl; ttl > 0 {
+		// An incomplete set is retried sooner, but not on every call:
+		// calls are classified as reads from it.
+		if c.incomplete {
+			ttl = min(ttl, manifestRetry)
+		}
+		c.expires = time.Now().Add(ttl)
+	}
+	return c.manifests, c.incomplete
+}
+
+// readOnly reports whether a connector manifest describes tool.action, and
+// if so whether it declares the action read-only.
+func (c *manifestCache) readOnly(ctx context.Context, gw *Gateway, tool, action string) (readOnly, described bool) {
+	manifests, _ := c.get(ctx, gw)
+	for _, m := range manifests {
+		if m.Tool != tool {
+			continue
+		}
+		for _, a := range m.Actions {
+			if a.Action == action {
+				return a.ReadOnly, true
+			}
+		}
+	}
+	return false, false
+}
+
+// isReadAction reports whether tool.action is a side-effect-free read, as
+// its connector's manifest declares, or as its name suggests (see
+// tenants.IsReadAction) when no manifest describes it.
+func (gw *Gateway) isReadAction(ctx context.Context, tool, action string) bool {
+	if readOnly, ok := gw.manifests.readOnly(ctx, gw, tool, action); ok {
+		return readOnly
+	}
+	return tenants.IsReadAction(action)
+}
+
+// HandleToolSpec is GET /v1/tools/spec?format=openai|anthropic. It renders
+// the connectors' agent-callable actions as LLM function definitions, less
+// any the tenant's tool catalog excludes, so agents can be wired to governed
+// tools without hand-written schemas.
+func (gw *Gateway) HandleToolSpec(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = connectors.SpecFormatOpenAI
+	}
+	if format != connectors.SpecFormatOpenAI && format != connectors.SpecFormatAnthropic {
+		types.ErrBadRequest("format must be openai or anthropic").WriteJSON(w)
+		return
+	}
+	tenantID := auth.TenantFromContext(ctx)
+	if tenantID == "" {
+		tenantID = r.URL.Query().Get("tenant_id")
+	}
+	settings, err := gw.settings.Get(ctx, tenantID)
+	if err != nil {
+		gw.log.ErrorContext(ctx, "tenant settings lookup failed", "tenant_id", tenantID, "error", err)
+		types.ErrInternal("tenant tool catalog unavailable").WriteJSON(w)
+		return
+	}
+
+	manifests, incomplete := gw.manifests.get(ctx, gw)
+	specs, err := connectors.ToolSpecs(format, manifests, func(tool, action string) bool {
+		return tool != types.PlanTool && tenants.CatalogPermits(settings.ToolCatalog, tool, action)
+	})
+	if err != nil {
+		types.ErrBadRequest(err.Error()).WriteJSON(w)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]any{
+		"format":     format,
+		"tools":      specs,
+		"incomplete": incomplete,
+	}); err != nil {
+		gw.log.ErrorContext(ctx, "response encode failed", "error", err)
+	}
+}
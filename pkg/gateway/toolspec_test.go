@@ -0,0 +1,49 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/connectors"
+	"github.com/bturcanu/OpenClause/pkg/tenants"
+)
+
+func TestHandleToolSpec_FiltersByCatalog(t *testing.T) {
+	fc := &fakeConnectors{manifests: []connectors.Manifest{
+		{Tool: "jira", Actions: []connectors.ActionManifest{{Action: "issue.create"}, {Action: "issue.delete"}}},
+		{Tool: "slack", Actions: []connectors.ActionManifest{{Action: "msg.post"}, {Action: "approval.request", Internal: true}}},
+	}}
+	gw := newExecuteGateway(newFakeEvidence(), fc, &fakeApprovals{})
+	gw.settings = tenants.NewSettingsCache(fakeSettings{"tenant1": {ToolCatalog: []string{"jira.issue.create", "slack.*"}}}, time.Minute)
+
+	get := func(query string) (int, []string) {
+		rr := httptest.NewRecorder()
+		gw.HandleToolSpec(rr, httptest.NewRequest(http.MethodGet, "/v1/tools/spec?"+query, http.NoBody))
+		var out struct {
+			Tools []struct {
+				Name     string `json:"name"`
+				Function struct {
+					Name string `json:"name"`
+				} `json:"function"`
+			} `json:"tools"`
+		}
+		_ = json.NewDecoder(rr.Body).Decode(&out)
+		var names []string
+		for _, tool := range out.Tools {
+			names = append(names, tool.Name+tool.Function.Name)
+		}
+		return rr.Code, names
+	}
+	if code, names := get("tenant_id=tenant1"); code != http.StatusOK || len(names) != 2 || names[0] != "jira__issue__create" || names[1] != "slack__msg__post" {
+		t.Fatalf("openai spec = %d %v", code, names)
+	}
+	if code, names := get("tenant_id=other&format=anthropic"); code != http.StatusOK || len(names) != 3 {
+		t.Fatalf("anthropic spec without catalog = %d %v", code, names)
+	}
+	if code, _ := get("format=yaml"); code != http.StatusBadRequest {
+		t.Fatalf("unknown format = %d", code)
+	}
+}
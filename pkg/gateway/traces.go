@@ -0,0 +1,113 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/bturcanu/OpenClause/pkg/auth"
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"github.com/go-chi/chi/v5"
+)
+
+// HandleGetTrace is GET /v1/traces/{trace_id}. It assembles every event the
+// tenant recorded under the trace into a tree of calls, their approvals,
+// executions, plan steps and compensations, for incident review.
+func (gw *Gateway) HandleGetTrace(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	traceID := chi.URLParam(r, "trace_id")
+	if traceID == "" || len(traceID) > 128 {
+		types.ErrBadRequest("invalid trace_id").WriteJSON(w)
+		return
+	}
+	tenantID := auth.TenantFromContext(ctx)
+	if tenantID == "" {
+		tenantID = r.URL.Query().Get("tenant_id")
+	}
+	if tenantID == "" {
+		types.ErrBadRequest("tenant_id is required").WriteJSON(w)
+		return
+	}
+
+	// One extra row tells a full trace from a truncated one.
+	events, err := gw.evidence.ListTraceEvents(ctx, tenantID, traceID, types.MaxTraceEvents+1)
+	if err != nil {
+		gw.log.ErrorContext(ctx, "list trace events failed", "trace_id", traceID, "error", err)
+		types.ErrInternal("failed to retrieve trace").WriteJSON(w)
+		return
+	}
+	if len(events) == 0 {
+		types.ErrNotFound("trace not found").WriteJSON(w)
+		return
+	}
+	out := &types.Trace{TraceID: traceID, TenantID: tenantID}
+	if len(events) > types.MaxTraceEvents {
+		events = events[:types.MaxTraceEvents]
+		out.Truncated = true
+	}
+	out.Events = len(events)
+
+	eventIDs := make([]string, len(events))
+	for i := range events {
+		eventIDs[i] = events[i].EventID
+	}
+	reqs, err := gw.approvals.ListRequestsByEvents(ctx, tenantID, eventIDs)
+	if err != nil {
+		gw.log.ErrorContext(ctx, "list trace approvals failed", "trace_id", traceID, "error", err)
+		types.ErrInternal("failed to retrieve trace approvals").WriteJSON(w)
+		return
+	}
+	approvalsByEvent := make(map[string][]types.TraceApproval, len(reqs))
+	for _, a := range reqs {
+		approvalsByEvent[a.EventID] = append(approvalsByEvent[a.EventID], types.TraceApproval{
+			ID:         a.ID,
+			Status:     a.Status,
+			Reason:     a.Reason,
+			DenyReason: a.DenyReason,
+			Kind:       a.Kind,
+			CreatedAt:  a.CreatedAt,
+		})
+	}
+	out.Roots = buildTrace(events, approvalsByEvent)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(out); err != nil {
+		gw.log.ErrorContext(ctx, "response encode failed", "error", err)
+	}
+}
+
+// buildTrace links events, given in insertion order, to the events that
+// caused them. An event whose parent is not among events is a root. Parents
+// always exist before their children are submitted, so links cannot cycle.
+func buildTrace(events []types.ToolCallEnvelope, approvalsByEvent map[string][]types.TraceApproval) []*types.TraceNode {
+	nodes := make(map[string]*types.TraceNode, len(events))
+	for i := range events {
+		env := &events[i]
+		node := &types.TraceNode{
+			EventID:    env.EventID,
+			AgentID:    env.Request.AgentID,
+			Tool:       env.Request.Tool,
+			Action:     env.Request.Action,
+			Resource:   env.Request.Resource,
+			Decision:   env.Decision,
+			ReceivedAt: env.ReceivedAt,
+			Result:     env.ExecutionResult.Withheld(),
+			Approvals:  approvalsByEvent[env.EventID],
+		}
+		node.ParentEventID, node.Relation = types.TraceParent(&env.Request)
+		if env.PolicyResult != nil {
+			node.Reason = env.PolicyResult.Reason
+		}
+		nodes[env.EventID] = node
+	}
+
+	roots := make([]*types.TraceNode, 0)
+	for i := range events {
+		node := nodes[events[i].EventID]
+		if parent, ok := nodes[node.ParentEventID]; ok {
+			parent.Children = append(parent.Children, node)
+			continue
+		}
+		roots = append(roots, node)
+	}
+	return roots
+}
@@ -0,0 +1,103 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"github.com/go-chi/chi/v5"
+)
+
+func getTrace(t *testing.T, gw *Gateway, traceID string) (*httptest.ResponseRecorder, types.Trace) {
+	t.Helper()
+	r := chi.NewRouter()
+	r.Get("/v1/traces/{trace_id}", gw.HandleGetTrace)
+	req := httptest.NewRequest(http.MethodGet, "/v1/traces/"+traceID+"?tenant_id=tenant1", http.NoBody)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	var out types.Trace
+	if rr.Code == http.StatusOK {
+		if err := json.NewDecoder(rr.Body).Decode(&out); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+	}
+	return rr, out
+}
+
+func TestGetTrace_AssemblesPlanApprovalAndExecution(t *testing.T) {
+	fe := newFakeEvidence()
+	fc := &fakeConnectors{output: json.RawMessage(`{"ok":true}`)}
+	fa := &fakeApprovals{}
+	gw := newExecuteGateway(fe, fc, fa)
+	gw.perTenantLimit = 100
+	gw.approvalsURL = "http://approvals"
+	gw.policy = fakePolicy{decision: types.DecisionApprove, reason: "needs approval"}
+
+	plan := ticketThenPost("trace-plan")
+	plan.TraceID = "trace-1"
+	_, planned := postPlan(t, gw, plan)
+	fa.usesLeft = 1
+	if rr := executeRequest(t, gw, planned.EventID); rr.Code != http.StatusOK {
+		t.Fatalf("execute: %d %s", rr.Code, rr.Body.String())
+	}
+
+	// A follow-up call names the plan as its parent and joins its trace.
+	gw.policy = fakePolicy{}
+	body, _ := json.Marshal(types.ToolCallRequest{
+		TenantID: "tenant1", AgentID: "agent-1", Tool: "slack", Action: "msg.post",
+		IdempotencyKey: "follow-up", ParentEventID: planned.EventID,
+	})
+	if rr := postToolCall(t, gw, body); rr.Code != http.StatusOK {
+		t.Fatalf("follow-up: %d %s", rr.Code, rr.Body.String())
+	}
+
+	rr, tr := getTrace(t, gw, "trace-1")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("trace: %d %s", rr.Code, rr.Body.String())
+	}
+	if tr.Events != 6 || len(tr.Roots) != 1 {
+		t.Fatalf("trace has %d events and %d roots, want 6 and 1", tr.Events, len(tr.Roots))
+	}
+	root := tr.Roots[0]
+	if root.EventID != planned.EventID || len(root.Approvals) != 1 || root.Approvals[0].Status != "pending" {
+		t.Fatalf("root = %+v", root)
+	}
+	relations := map[string]int{}
+	for _, c := range root.Children {
+		relations[c.Relation]++
+		if c.ParentEventID != planned.EventID {
+			t.Fatalf("child %s has parent %s", c.EventID, c.ParentEventID)
+		}
+	}
+	if relations[types.RelationExecution] != 1 || relations[types.RelationPlanStep] != 3 || relations[types.RelationChild] != 1 {
+		t.Fatalf("child relations = %v", relations)
+	}
+
+	if rr, _ := getTrace(t, gw, "unknown"); rr.Code != http.StatusNotFound {
+		t.Fatalf("unknown trace status = %d, want 404", rr.Code)
+	}
+}
+
+func TestHandleToolCall_ParentEventID(t *testing.T) {
+	gw := newExecuteGateway(newFakeEvidence(), &fakeConnectors{}, &fakeApprovals{})
+	gw.perTenantLimit = 100
+	for name, tc := range map[string]struct {
+		req  types.ToolCallRequest
+		want int
+	}{
+		"unknown parent": {types.ToolCallRequest{ParentEventID: "00000000-0000-0000-0000-000000000009"}, http.StatusUnprocessableEntity},
+		"malformed":      {types.ToolCallRequest{ParentEventID: "not-an-id"}, http.StatusUnprocessableEntity},
+		"agent-set plan": {types.ToolCallRequest{PlanID: "00000000-0000-0000-0000-000000000009"}, http.StatusBadRequest},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := tc.req
+			req.TenantID, req.AgentID, req.Tool, req.Action, req.IdempotencyKey = "tenant1", "agent-1", "slack", "msg.post", name
+			body, _ := json.Marshal(req)
+			if rr := postToolCall(t, gw, body); rr.Code != tc.want {
+				t.Fatalf("status = %d, want %d: %s", rr.Code, tc.want, rr.Body.String())
+			}
+		})
+	}
+}
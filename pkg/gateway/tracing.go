@@ -0,0 +1,31 @@
+package gateway
+
+import (
+	"context"
+
+	ocOtel "github.com/bturcanu/OpenClause/pkg/otel"
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/bturcanu/OpenClause/pkg/gateway")
+
+// startToolCallSpan opens the root span for a tool call. Without an inbound
+// traceparent the span joins the trace named by req.TraceID; when the caller
+// supplied no TraceID, it is filled from the span so evidence rows and traces
+// can be correlated.
+func startToolCallSpan(ctx context.Context, req *types.ToolCallRequest) (context.Context, trace.Span) {
+	ctx = ocOtel.ContextWithTraceID(ctx, req.TraceID)
+	ctx, span := tracer.Start(ctx, "gateway.ToolCall", trace.WithAttributes(
+		attribute.String("oc.tenant_id", req.TenantID),
+		attribute.String("oc.agent_id", req.AgentID),
+		attribute.String("oc.tool", req.Tool),
+		attribute.String("oc.action", req.Action),
+	))
+	if sc := span.SpanContext(); req.TraceID == "" && sc.IsValid() {
+		req.TraceID = sc.TraceID().String()
+	}
+	return ctx, span
+}
@@ -0,0 +1,155 @@
+// Package injection scans tool-call params for signs of prompt injection:
+// text that tries to override an agent's instructions, extract its prompt
+// or secrets, or carry data to an attacker's URL. A match is a heuristic
+// signal for policy and reviewers, not proof of an attack.
+package injection
+
+import (
+	"encoding/json"
+	"maps"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+const (
+	// maxFindings bounds the findings of one scan.
+	maxFindings = 20
+	// maxMatch bounds the matched text recorded with a finding, in bytes.
+	maxMatch = 80
+)
+
+// Rules the Detector reports.
+const (
+	RuleInstructionOverride = "instruction_override"
+	RulePromptExtraction    = "prompt_extraction"
+	RuleSecretExfiltration  = "secret_exfiltration"
+	RuleRoleMarker          = "role_marker"
+	RuleMarkdownImage       = "markdown_image"
+	RuleURLPayload          = "url_payload"
+	RuleBlockedDomain       = "blocked_domain"
+)
+
+// patterns are the text rules, checked in order on every string.
+var patterns = []struct {
+	rule string
+	re   *regexp.Regexp
+}{
+	{RuleInstructionOverride, regexp.MustCompile(`(?i)\b(ignore|disregard|forget|override)\b.{0,40}\b(previous|prior|above|earlier|all|your)\b.{0,20}\b(instructions?|prompts?|rules|directions|guidelines)\b`)},
+	{RulePromptExtraction, regexp.MustCompile(`(?i)\b(reveal|print|show|repeat|output|leak)\b.{0,30}\b(system prompt|hidden instructions|your (instructions|prompt))\b`)},
+	{RuleSecretExfiltration, regexp.MustCompile(`(?i)\b(send|post|forward|upload|exfiltrate|leak|email|transmit)\b.{0,60}\b(api[_ -]?keys?|secrets?|passwords?|credentials?|access tokens?|private keys?|env(ironment)? variables)\b`)},
+	{RuleRoleMarker, regexp.MustCompile(`(?i)<\|?(im_start|im_end|system)\|?>|\[/?INST\]|(^|\n)\s*(system|assistant)\s*:`)},
+	{RuleMarkdownImage, regexp.MustCompile(`!\[[^\]]*\]\(\s*https?://[^)\s]*\?[^)\s]*\)`)},
+	{RuleURLPayload, regexp.MustCompile(`https?://[^\s"'<>]+[?&][^=\s&]+=[A-Za-z0-9+/_%-]{64,}`)},
+}
+
+var urlRe = regexp.MustCompile(`(?i)\bhttps?://[^\s"'<>)\]]+`)
+
+// Detector scans params. The zero value applies the text rules only.
+type Detector struct {
+	blocked []string
+}
+
+// New returns a Detector that also reports URLs to the blocked domains and
+// their subdomains, e.g. "attacker.example".
+func New(blockedDomains []string) *Detector {
+	d := &Detector{}
+	for _, domain := range blockedDomains {
+		domain = strings.Trim(strings.ToLower(strings.TrimSpace(domain)), ".")
+		if domain != "" {
+			d.blocked = append(d.blocked, domain)
+		}
+	}
+	return d
+}
+
+// Scan returns the findings in the string values and object keys of params,
+// in document order with object keys sorted. Params that are not JSON have
+// none. A nil Detector finds nothing.
+func (d *Detector) Scan(params json.RawMessage) []types.InjectionFinding {
+	if d == nil || len(params) == 0 {
+		return nil
+	}
+	var v any
+	if err := json.Unmarshal(params, &v); err != nil {
+		return nil
+	}
+	var findings []types.InjectionFinding
+	d.walk("", v, &findings)
+	return findings
+}
+
+func (d *Detector) walk(path string, v any, findings *[]types.InjectionFinding) {
+	switch v := v.(type) {
+	case string:
+		d.scanString(path, v, findings)
+	case map[string]any:
+		for _, k := range slices.Sorted(maps.Keys(v)) {
+			p := path + "/" + escapePointer(k)
+			d.scanString(p, k, findings)
+			d.walk(p, v[k], findings)
+		}
+	case []any:
+		for i, child := range v {
+			d.walk(path+"/"+strconv.Itoa(i), child, findings)
+		}
+	}
+}
+
+func (d *Detector) scanString(path, s string, findings *[]types.InjectionFinding) {
+	add := func(rule, match string) {
+		if len(*findings) < maxFindings {
+			*findings = append(*findings, types.InjectionFinding{Rule: rule, Path: path, Match: truncate(match)})
+		}
+	}
+	for _, p := range patterns {
+		if m := p.re.FindString(s); m != "" {
+			add(p.rule, strings.TrimSpace(m))
+		}
+	}
+	if len(d.blocked) == 0 {
+		return
+	}
+	for _, raw := range urlRe.FindAllString(s, -1) {
+		u, err := url.Parse(raw)
+		if err != nil {
+			continue
+		}
+		if d.isBlocked(u.Hostname()) {
+			add(RuleBlockedDomain, raw)
+			return
+		}
+	}
+}
+
+func (d *Detector) isBlocked(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for _, domain := range d.blocked {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// escapePointer escapes a JSON Pointer reference token (RFC 6901).
+func escapePointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
+
+// truncate cuts s to maxMatch bytes without splitting a UTF-8 sequence.
+func truncate(s string) string {
+	if len(s) <= maxMatch {
+		return s
+	}
+	n := maxMatch
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + "…"
+}
@@ -0,0 +1,162 @@
+package metering
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/auth"
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxPeriods caps a from..to range.
+const maxPeriods = 36
+
+type usageStore interface {
+	Usage(ctx context.Context, tenantID, from, to string) ([]Usage, error)
+	AllUsage(ctx context.Context, from, to string) ([]Usage, error)
+}
+
+// Handlers serves usage reports as JSON or, with format=csv, as CSV.
+type Handlers struct {
+	store usageStore
+	log   *slog.Logger
+}
+
+// NewHandlers creates usage handlers backed by store.
+func NewHandlers(store usageStore, log *slog.Logger) *Handlers {
+	if log == nil {
+		log = slog.Default()
+	}
+	return &Handlers{store: store, log: log}
+}
+
+// RegisterRoutes mounts the tenant-scoped usage endpoint; the tenant comes
+// from the authenticated API key.
+func (h *Handlers) RegisterRoutes(r chi.Router) {
+	r.Get("/v1/usage", h.TenantUsage)
+}
+
+// RegisterAdminRoutes mounts the all-tenant usage endpoint. It must sit
+// behind auth.AdminAuth.
+func (h *Handlers) RegisterAdminRoutes(r chi.Router) {
+	r.Get("/v1/admin/usage", h.AdminUsage)
+}
+
+// TenantUsage handles GET /v1/usage
+func (h *Handlers) TenantUsage(w http.ResponseWriter, r *http.Request) {
+	tenantID := auth.TenantFromContext(r.Context())
+	if tenantID == "" {
+		types.ErrUnauthorized("missing tenant").WriteJSON(w)
+		return
+	}
+	h.serve(w, r, tenantID)
+}
+
+// AdminUsage handles GET /v1/admin/usage
+func (h *Handlers) AdminUsage(w http.ResponseWriter, r *http.Request) {
+	h.serve(w, r, r.URL.Query().Get("tenant_id"))
+}
+
+// serve writes tenantID's usage, or every tenant's when tenantID is empty;
+// only AdminUsage passes an empty one.
+func (h *Handlers) serve(w http.ResponseWriter, r *http.Request, tenantID string) {
+	from, to, err := periodRange(r)
+	if err != nil {
+		types.ErrBadRequest(err.Error()).WriteJSON(w)
+		return
+	}
+	var usage []Usage
+	if tenantID == "" {
+		usage, err = h.store.AllUsage(r.Context(), from, to)
+	} else {
+		usage, err = h.store.Usage(r.Context(), tenantID, from, to)
+	}
+	if err != nil {
+		h.log.Error("usage query failed", "tenant_id", tenantID, "error", err)
+		types.ErrInternal("failed to load usage").WriteJSON(w)
+		return
+	}
+	if usage == nil {
+		usage = []Usage{}
+	}
+
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]any{"from": from, "to": to, "usage": usage}); err != nil {
+			h.log.Error("response encode failed", "error", err)
+		}
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="usage_%s_%s.csv"`, from, to))
+		if err := writeCSV(w, usage); err != nil {
+			h.log.Error("csv encode failed", "error", err)
+		}
+	default:
+		types.ErrBadRequest("format must be json or csv").WriteJSON(w)
+	}
+}
+
+// periodRange reads period, or from and to, defaulting to the current period.
+func periodRange(r *http.Request) (string, string, error) {
+	q := r.URL.Query()
+	if p := q.Get("period"); p != "" {
+		if q.Get("from") != "" || q.Get("to") != "" {
+			return "", "", fmt.Errorf("use either period or from/to")
+		}
+		p, err := ParsePeriod(p)
+		return p, p, err
+	}
+	current := Period(time.Now())
+	from, to := q.Get("from"), q.Get("to")
+	if from == "" {
+		from = current
+	}
+	if to == "" {
+		to = current
+	}
+	var err error
+	if from, err = ParsePeriod(from); err != nil {
+		return "", "", err
+	}
+	if to, err = ParsePeriod(to); err != nil {
+		return "", "", err
+	}
+	if from > to {
+		return "", "", fmt.Errorf("from must not be after to")
+	}
+	f, _ := time.Parse(periodLayout, from)
+	t, _ := time.Parse(periodLayout, to)
+	if months := (t.Year()-f.Year())*12 + int(t.Month()-f.Month()) + 1; months > maxPeriods {
+		return "", "", fmt.Errorf("range spans %d periods; max is %d", months, maxPeriods)
+	}
+	return from, to, nil
+}
+
+func writeCSV(w http.ResponseWriter, usage []Usage) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"tenant_id", "period", "calls", "executions", "approvals", "archived_bytes"}); err != nil {
+		return err
+	}
+	for _, u := range usage {
+		rec := []string{
+			u.TenantID, u.Period,
+			strconv.FormatInt(u.Calls, 10),
+			strconv.FormatInt(u.Executions, 10),
+			strconv.FormatInt(u.Approvals, 10),
+			strconv.FormatInt(u.ArchivedBytes, 10),
+		}
+		if err := cw.Write(rec); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,130 @@
+// Package metering counts billable usage per tenant per billing period
+// (calendar month, UTC) and serves it for chargeback.
+package metering
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Metrics recorded by the gateway and archiver.
+const (
+	Calls         = "calls"          // tool-call requests evaluated (idempotent replays excluded)
+	Executions    = "executions"     // connector executions
+	Approvals     = "approvals"      // approval requests created
+	ArchivedBytes = "archived_bytes" // evidence bundle bytes uploaded
+)
+
+const periodLayout = "2006-01"
+
+// Period returns the billing period containing t, e.g. "2026-10".
+func Period(t time.Time) string {
+	return t.UTC().Format(periodLayout)
+}
+
+// ParsePeriod validates a YYYY-MM billing period.
+func ParsePeriod(s string) (string, error) {
+	t, err := time.Parse(periodLayout, s)
+	if err != nil {
+		return "", fmt.Errorf("period %q must be YYYY-MM", s)
+	}
+	return Period(t), nil
+}
+
+// Counter is one increment to persist.
+type Counter struct {
+	TenantID string
+	Period   string
+	Metric   string
+	Value    int64
+}
+
+type incrementer interface {
+	Increment(ctx context.Context, counters []Counter) error
+}
+
+type counterKey struct {
+	tenantID, period, metric string
+}
+
+// Recorder batches increments in memory and persists them on Flush, so the
+// request path never waits on the database. A nil Recorder discards.
+type Recorder struct {
+	store incrementer
+	log   *slog.Logger
+	now   func() time.Time
+
+	mu      sync.Mutex
+	pending map[counterKey]int64
+}
+
+// NewRecorder creates a recorder that flushes to store.
+func NewRecorder(store incrementer, log *slog.Logger) *Recorder {
+	if log == nil {
+		log = slog.Default()
+	}
+	return &Recorder{store: store, log: log, now: time.Now, pending: make(map[counterKey]int64)}
+}
+
+// Add records n units of metric for tenantID in the current period.
+func (r *Recorder) Add(tenantID, metric string, n int64) {
+	if r == nil || tenantID == "" || n == 0 {
+		return
+	}
+	k := counterKey{tenantID: tenantID, period: Period(r.now()), metric: metric}
+	r.mu.Lock()
+	r.pending[k] += n
+	r.mu.Unlock()
+}
+
+// Flush persists pending increments. On failure they are kept for the next
+// flush, so a database outage delays metering rather than losing it.
+func (r *Recorder) Flush(ctx context.Context) error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	batch := r.pending
+	r.pending = make(map[counterKey]int64)
+	r.mu.Unlock()
+	if len(batch) == 0 {
+		return nil
+	}
+
+	counters := make([]Counter, 0, len(batch))
+	for k, v := range batch {
+		counters = append(counters, Counter{TenantID: k.tenantID, Period: k.period, Metric: k.metric, Value: v})
+	}
+	if err := r.store.Increment(ctx, counters); err != nil {
+		r.mu.Lock()
+		for k, v := range batch {
+			r.pending[k] += v
+		}
+		r.mu.Unlock()
+		return fmt.Errorf("metering.Flush: %w", err)
+	}
+	return nil
+}
+
+// Run flushes every interval until ctx is cancelled. Callers flush once more
+// on shutdown, after the server has stopped accepting requests.
+func (r *Recorder) Run(ctx context.Context, interval time.Duration) {
+	if r == nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Flush(ctx); err != nil {
+				r.log.Warn("usage flush failed; will retry", "error", err)
+			}
+		}
+	}
+}
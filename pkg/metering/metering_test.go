@@ -0,0 +1,171 @@
+package metering
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/auth"
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"github.com/go-chi/chi/v5"
+)
+
+type fakeIncrementer struct {
+	got []Counter
+	err error
+}
+
+func (f *fakeIncrementer) Increment(_ context.Context, counters []Counter) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.got = append(f.got, counters...)
+	return nil
+}
+
+func TestRecorder_BatchesAndRetries(t *testing.T) {
+	store := &fakeIncrementer{err: errors.New("db down")}
+	r := NewRecorder(store, nil)
+	r.now = func() time.Time { return time.Date(2026, 10, 31, 23, 0, 0, 0, time.UTC) }
+
+	r.Add("t1", Calls, 1)
+	r.Add("t1", Calls, 1)
+	r.Add("t1", ArchivedBytes, 512)
+	r.Add("", Calls, 1) // unauthenticated: ignored
+
+	if err := r.Flush(context.Background()); err == nil {
+		t.Fatal("expected flush error")
+	}
+	r.now = func() time.Time { return time.Date(2026, 11, 1, 0, 0, 1, 0, time.UTC) }
+	r.Add("t1", Calls, 1)
+
+	store.err = nil
+	if err := r.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	totals := map[string]int64{}
+	for _, c := range store.got {
+		totals[c.Period+"/"+c.Metric] += c.Value
+	}
+	want := map[string]int64{"2026-10/calls": 2, "2026-10/archived_bytes": 512, "2026-11/calls": 1}
+	for k, v := range want {
+		if totals[k] != v {
+			t.Errorf("%s = %d, want %d", k, totals[k], v)
+		}
+	}
+	if len(totals) != len(want) {
+		t.Errorf("totals = %v", totals)
+	}
+
+	var nilRecorder *Recorder
+	nilRecorder.Add("t1", Calls, 1)
+	if err := nilRecorder.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type fakeUsage struct {
+	tenant, from, to string
+}
+
+func (f *fakeUsage) Usage(_ context.Context, tenantID, from, to string) ([]Usage, error) {
+	f.tenant, f.from, f.to = tenantID, from, to
+	return []Usage{{TenantID: "t1", Period: from, Calls: 10, Executions: 7, Approvals: 2, ArchivedBytes: 4096}}, nil
+}
+
+func (f *fakeUsage) AllUsage(_ context.Context, from, to string) ([]Usage, error) {
+	f.tenant, f.from, f.to = "*", from, to
+	return nil, nil
+}
+
+func TestHandlers_TenantScopedJSONAndCSV(t *testing.T) {
+	store := &fakeUsage{}
+	h := NewHandlers(store, nil)
+	r := chi.NewRouter()
+	r.Group(func(r chi.Router) {
+		r.Use(auth.APIKeyAuth(auth.NewKeyStore("t1:sk-1")))
+		h.RegisterRoutes(r)
+	})
+	h.RegisterAdminRoutes(r)
+
+	do := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-API-Key", "sk-1")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do("/v1/usage?period=2026-09&tenant_id=t2")
+	if rec.Code != http.StatusOK || store.tenant != "t1" || store.from != "2026-09" || store.to != "2026-09" {
+		t.Fatalf("GET /v1/usage = %d, store saw %+v", rec.Code, store)
+	}
+	if !strings.Contains(rec.Body.String(), `"executions":7`) {
+		t.Fatalf("body = %s", rec.Body)
+	}
+
+	rec = do("/v1/usage?from=2026-01&to=2026-03&format=csv")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "text/csv" {
+		t.Fatalf("csv = %d %s", rec.Code, rec.Header())
+	}
+	want := "tenant_id,period,calls,executions,approvals,archived_bytes\nt1,2026-01,10,7,2,4096\n"
+	if rec.Body.String() != want {
+		t.Fatalf("csv body = %q", rec.Body)
+	}
+
+	rec = do("/v1/admin/usage?tenant_id=t2")
+	if rec.Code != http.StatusOK || store.tenant != "t2" {
+		t.Fatalf("admin usage = %d, tenant %q", rec.Code, store.tenant)
+	}
+	rec = do("/v1/admin/usage")
+	if rec.Code != http.StatusOK || store.tenant != "*" {
+		t.Fatalf("admin usage for all tenants = %d, tenant %q", rec.Code, store.tenant)
+	}
+
+	// Without an authenticated tenant the handler must not fall through to
+	// every tenant's usage.
+	store.tenant = ""
+	rec = httptest.NewRecorder()
+	h.TenantUsage(rec, httptest.NewRequest(http.MethodGet, "/v1/usage", nil))
+	if rec.Code != http.StatusUnauthorized || store.tenant != "" {
+		t.Fatalf("unauthenticated usage = %d, store saw %q", rec.Code, store.tenant)
+	}
+
+	for _, q := range []string{"period=2026-13", "from=2026-05&to=2026-01", "from=2020-01&to=2026-01", "period=2026-01&from=2026-01", "format=xml"} {
+		if rec := do("/v1/usage?" + q); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", q, rec.Code)
+		}
+	}
+}
+
+func TestBudgetStates(t *testing.T) {
+	at := time.Date(2026, 10, 31, 23, 0, 0, 0, time.FixedZone("x", -2*3600))
+	if day, month := BudgetPeriod(types.BudgetPeriodDay, at), BudgetPeriod(types.BudgetPeriodMonth, at); day != "2026-11-01" || month != "2026-11" {
+		t.Fatalf("periods = %s, %s; want UTC", day, month)
+	}
+
+	budgets := []types.Budget{
+		{Period: types.BudgetPeriodMonth, Limit: 100},
+		{AgentID: types.BudgetEachAgent, Period: types.BudgetPeriodDay, Limit: 5},
+		{AgentID: "a1", Period: types.BudgetPeriodMonth, Limit: 20},
+		{AgentID: "a2", Period: types.BudgetPeriodDay, Limit: 1},
+	}
+	spend := Spend{TenantDay: 9, TenantMonth: 60, AgentDay: 4, AgentMonth: 20}
+	got := BudgetStates(budgets, "a1", spend, 2)
+	if len(got) != 3 {
+		t.Fatalf("states = %+v, want the three covering a1", got)
+	}
+	if s := got[0]; s.Spent != 60 || s.Remaining != 40 || s.Exceeded || s.WouldExceed {
+		t.Errorf("tenant month = %+v", s)
+	}
+	if s := got[1]; s.Spent != 4 || s.Remaining != 1 || s.Exceeded || !s.WouldExceed {
+		t.Errorf("each-agent day = %+v", s)
+	}
+	if s := got[2]; s.Spent != 20 || s.Remaining != 0 || !s.Exceeded || !s.WouldExceed {
+		t.Errorf("a1 month = %+v", s)
+	}
+}
@@ -0,0 +1,124 @@
+package metering
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const dayLayout = "2006-01-02"
+
+// BudgetPeriod returns the key of the budget period containing t: the UTC
+// day ("2026-10-16") or month ("2026-10").
+func BudgetPeriod(period string, t time.Time) string {
+	if period == types.BudgetPeriodDay {
+		return t.UTC().Format(dayLayout)
+	}
+	return Period(t)
+}
+
+// Spend is what a tenant, and one of its agents, have spent in the current
+// day and month.
+type Spend struct {
+	TenantDay, TenantMonth float64
+	AgentDay, AgentMonth   float64
+}
+
+// of returns the spend a budget counts against.
+func (s Spend) of(b types.Budget) float64 {
+	switch {
+	case b.AgentID == "" && b.Period == types.BudgetPeriodDay:
+		return s.TenantDay
+	case b.AgentID == "":
+		return s.TenantMonth
+	case b.Period == types.BudgetPeriodDay:
+		return s.AgentDay
+	default:
+		return s.AgentMonth
+	}
+}
+
+// BudgetStates returns the state of each budget that covers agentID, for a
+// call estimated to cost costEstimate.
+func BudgetStates(budgets []types.Budget, agentID string, spend Spend, costEstimate float64) []types.BudgetState {
+	var out []types.BudgetState
+	for _, b := range budgets {
+		if b.Applies(agentID) {
+			out = append(out, types.NewBudgetState(b, spend.of(b), costEstimate))
+		}
+	}
+	return out
+}
+
+// SpendStore accumulates connector-reported cost in the budget_spend table,
+// per tenant and per agent, by day and by month. The tenant's own rows have
+// an empty agent_id.
+type SpendStore struct {
+	pool *pgxpool.Pool
+}
+
+// NewSpendStore creates a spend store.
+func NewSpendStore(pool *pgxpool.Pool) *SpendStore {
+	return &SpendStore{pool: pool}
+}
+
+// AddSpend charges cost, incurred at at, to the tenant and agent.
+func (s *SpendStore) AddSpend(ctx context.Context, tenantID, agentID string, cost float64, at time.Time) error {
+	agents := []string{""}
+	if agentID != "" {
+		agents = append(agents, agentID)
+	}
+	batch := &pgx.Batch{}
+	for _, agent := range agents {
+		for _, period := range []string{BudgetPeriod(types.BudgetPeriodDay, at), BudgetPeriod(types.BudgetPeriodMonth, at)} {
+			batch.Queue(`
+				INSERT INTO budget_spend (tenant_id, agent_id, period, spent)
+				VALUES ($1, $2, $3, $4)
+				ON CONFLICT (tenant_id, agent_id, period)
+				DO UPDATE SET spent = budget_spend.spent + EXCLUDED.spent, updated_at = NOW()`,
+				tenantID, agent, period, cost)
+		}
+	}
+	if err := s.pool.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("metering.AddSpend: %w", err)
+	}
+	return nil
+}
+
+// Spent returns the tenant's and agent's spend for the day and month
+// containing at.
+func (s *SpendStore) Spent(ctx context.Context, tenantID, agentID string, at time.Time) (Spend, error) {
+	day, month := BudgetPeriod(types.BudgetPeriodDay, at), BudgetPeriod(types.BudgetPeriodMonth, at)
+	rows, err := s.pool.Query(ctx, `
+		SELECT agent_id, period, spent FROM budget_spend
+		WHERE tenant_id = $1 AND agent_id IN ('', $2) AND period IN ($3, $4)`,
+		tenantID, agentID, day, month)
+	if err != nil {
+		return Spend{}, fmt.Errorf("metering.Spent: %w", err)
+	}
+	defer rows.Close()
+
+	var out Spend
+	for rows.Next() {
+		var agent, period string
+		var spent float64
+		if err := rows.Scan(&agent, &period, &spent); err != nil {
+			return Spend{}, fmt.Errorf("metering.Spent scan: %w", err)
+		}
+		switch {
+		case agent == "" && period == day:
+			out.TenantDay = spent
+		case agent == "":
+			out.TenantMonth = spent
+		case period == day:
+			out.AgentDay = spent
+		default:
+			out.AgentMonth = spent
+		}
+	}
+	return out, rows.Err()
+}
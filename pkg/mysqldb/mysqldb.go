@@ -0,0 +1,49 @@
+// Package mysqldb opens MySQL / Aurora MySQL connections for the evidence
+// and approvals stores with the session settings they rely on.
+package mysqldb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Open parses dsn (go-sql-driver format, e.g.
+// "user:pass@tcp(host:3306)/openclause"), normalises it with NormalizeDSN,
+// and verifies connectivity.
+func Open(ctx context.Context, dsn string) (*sql.DB, error) {
+	normalized, err := NormalizeDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("mysql", normalized)
+	if err != nil {
+		return nil, fmt.Errorf("mysqldb.Open: %w", err)
+	}
+	db.SetConnMaxLifetime(5 * time.Minute)
+	if err := db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("mysqldb.Open ping: %w", err)
+	}
+	return db, nil
+}
+
+// NormalizeDSN forces the settings the stores depend on: DATETIME columns
+// scan into time.Time, times are sent and read as UTC, and the session time
+// zone is UTC so NOW() agrees with Go-supplied timestamps.
+func NormalizeDSN(dsn string) (string, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return "", fmt.Errorf("mysqldb.NormalizeDSN: %w", err)
+	}
+	cfg.ParseTime = true
+	cfg.Loc = time.UTC
+	if cfg.Params == nil {
+		cfg.Params = map[string]string{}
+	}
+	cfg.Params["time_zone"] = "'+00:00'"
+	return cfg.FormatDSN(), nil
+}
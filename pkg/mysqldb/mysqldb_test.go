@@ -0,0 +1,39 @@
+package mysqldb
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestNormalizeDSN_ForcesUTCAndParseTime(t *testing.T) {
+	out, err := NormalizeDSN("oc:secret@tcp(db:3306)/openclause?time_zone=%27SYSTEM%27&tls=true")
+	if err != nil {
+		t.Fatalf("NormalizeDSN: %v", err)
+	}
+	cfg, err := mysql.ParseDSN(out)
+	if err != nil {
+		t.Fatalf("ParseDSN(%q): %v", out, err)
+	}
+	if !cfg.ParseTime {
+		t.Error("parseTime not enabled")
+	}
+	if cfg.Loc != time.UTC {
+		t.Errorf("loc = %v, want UTC", cfg.Loc)
+	}
+	if got := cfg.Params["time_zone"]; got != "'+00:00'" {
+		t.Errorf("time_zone = %q, want '+00:00'", got)
+	}
+	if cfg.TLSConfig != "true" || cfg.DBName != "openclause" || cfg.Passwd != "secret" {
+		t.Errorf("unrelated settings not preserved: %q", out)
+	}
+}
+
+func TestNormalizeDSN_Invalid(t *testing.T) {
+	_, err := NormalizeDSN("not a dsn")
+	if err == nil || !strings.Contains(err.Error(), "mysqldb.NormalizeDSN") {
+		t.Fatalf("expected wrapped parse error, got %v", err)
+	}
+}
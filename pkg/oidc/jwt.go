@@ -0,0 +1,133 @@
+package oidc
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+var b64 = base64.RawURLEncoding
+
+// jwk is a public key from the provider's JWKS.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey decodes an RSA, P-256 or Ed25519 key; other keys are skipped.
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch {
+	case k.Kty == "RSA":
+		n, err := b64.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := b64.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case k.Kty == "EC" && k.Crv == "P-256":
+		x, err := b64.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := b64.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, errors.New("point not on P-256")
+		}
+		return pub, nil
+	case k.Kty == "OKP" && k.Crv == "Ed25519":
+		x, err := b64.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, nil
+}
+
+// parseJWT splits a compact JWS, returning its header, the signing input,
+// the signature, and the decoded payload.
+func parseJWT(token string) (header struct{ Alg, Kid string }, signed string, sig, payload []byte, err error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return header, "", nil, nil, errors.New("malformed token")
+	}
+	raw, err := b64.DecodeString(parts[0])
+	if err != nil {
+		return header, "", nil, nil, fmt.Errorf("header: %w", err)
+	}
+	var h struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := json.Unmarshal(raw, &h); err != nil {
+		return header, "", nil, nil, fmt.Errorf("header: %w", err)
+	}
+	header.Alg, header.Kid = h.Alg, h.Kid
+	if payload, err = b64.DecodeString(parts[1]); err != nil {
+		return header, "", nil, nil, fmt.Errorf("payload: %w", err)
+	}
+	if sig, err = b64.DecodeString(parts[2]); err != nil {
+		return header, "", nil, nil, fmt.Errorf("signature: %w", err)
+	}
+	return header, parts[0] + "." + parts[1], sig, payload, nil
+}
+
+// verifySignature checks sig over signed with key for alg. Only asymmetric
+// algorithms are accepted, so a token cannot be forged with "none" or by
+// using a public key as an HMAC secret.
+func verifySignature(alg string, key crypto.PublicKey, signed string, sig []byte) error {
+	digest := sha256.Sum256([]byte(signed))
+	switch alg {
+	case "RS256":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("RS256 token signed with a non-RSA key")
+		}
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig)
+	case "PS256":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("PS256 token signed with a non-RSA key")
+		}
+		return rsa.VerifyPSS(pub, crypto.SHA256, digest[:], sig, nil)
+	case "ES256":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(sig) != 64 {
+			return errors.New("invalid ES256 signature")
+		}
+		r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(pub, digest[:], r, s) {
+			return errors.New("signature mismatch")
+		}
+		return nil
+	case "EdDSA":
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok || !ed25519.Verify(pub, []byte(signed), sig) {
+			return errors.New("signature mismatch")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported alg %q", alg)
+}
@@ -0,0 +1,296 @@
+// Package oidc signs approvers in with an OpenID Connect provider, so the
+// approvals service records who approved a request as a verified identity
+// instead of a free-text name. Browsers use the authorization-code flow
+// with PKCE and then carry a signed session cookie; API clients send the ID
+// token they obtained from the same provider as a bearer token.
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	maxResponseBytes = 1 << 20
+	// clockSkew is the leeway allowed on token timestamps.
+	clockSkew = time.Minute
+	// jwksMinRefresh limits how often an unknown kid refetches the JWKS.
+	jwksMinRefresh = time.Minute
+)
+
+// Config identifies the approvals service at its OIDC provider.
+type Config struct {
+	// Issuer is the provider's issuer URL; its discovery document is read
+	// from Issuer + "/.well-known/openid-configuration".
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is the service's callback, e.g.
+	// https://approvals.example.com/ui/callback.
+	RedirectURL string
+	// Scopes requested at login; "openid" is always included.
+	Scopes     []string
+	HTTPClient *http.Client
+}
+
+// Identity is a verified approver.
+type Identity struct {
+	Issuer  string `json:"iss"`
+	Subject string `json:"sub"`
+	// Email is set only when the provider marked it verified.
+	Email string `json:"email,omitempty"`
+	Name  string `json:"name,omitempty"`
+}
+
+// Approver is the name recorded for the identity: its email, or its
+// subject when the provider gave no verified one.
+func (id Identity) Approver() string {
+	if id.Email != "" {
+		return id.Email
+	}
+	return id.Subject
+}
+
+// Provider is an OIDC provider whose endpoints were discovered. Safe for
+// concurrent use.
+type Provider struct {
+	cfg      Config
+	client   *http.Client
+	authURL  string
+	tokenURL string
+	jwksURL  string
+
+	mu      sync.Mutex
+	keys    map[string]crypto.PublicKey
+	fetched time.Time
+}
+
+// Discover reads the provider's discovery document.
+func Discover(ctx context.Context, cfg Config) (*Provider, error) {
+	if cfg.Issuer == "" || cfg.ClientID == "" {
+		return nil, errors.New("oidc.Discover: issuer and client ID are required")
+	}
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	p := &Provider{cfg: cfg, client: client}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		strings.TrimRight(cfg.Issuer, "/")+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, fmt.Errorf("oidc.Discover: %w", err)
+	}
+	var doc struct {
+		Issuer   string `json:"issuer"`
+		AuthURL  string `json:"authorization_endpoint"`
+		TokenURL string `json:"token_endpoint"`
+		JWKSURL  string `json:"jwks_uri"`
+	}
+	if err := p.do(req, &doc); err != nil {
+		return nil, fmt.Errorf("oidc.Discover: %w", err)
+	}
+	if doc.Issuer != cfg.Issuer {
+		return nil, fmt.Errorf("oidc.Discover: discovery issuer %q does not match %q", doc.Issuer, cfg.Issuer)
+	}
+	if doc.AuthURL == "" || doc.TokenURL == "" || doc.JWKSURL == "" {
+		return nil, errors.New("oidc.Discover: discovery document lacks an endpoint")
+	}
+	p.authURL, p.tokenURL, p.jwksURL = doc.AuthURL, doc.TokenURL, doc.JWKSURL
+	return p, nil
+}
+
+// AuthCodeURL is the login URL for a browser. state and nonce bind the
+// callback and ID token to this login; verifier is the PKCE code verifier.
+func (p *Provider) AuthCodeURL(state, nonce, verifier string) string {
+	scopes := []string{"openid"}
+	for _, s := range p.cfg.Scopes {
+		if s != "openid" {
+			scopes = append(scopes, s)
+		}
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {p.cfg.RedirectURL},
+		"scope":                 {strings.Join(scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {b64.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(p.authURL, "?") {
+		sep = "&"
+	}
+	return p.authURL + sep + q.Encode()
+}
+
+// Exchange redeems an authorization code and returns the raw ID token.
+func (p *Provider) Exchange(ctx context.Context, code, verifier string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("oidc.Exchange: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	var tok struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := p.do(req, &tok); err != nil {
+		return "", fmt.Errorf("oidc.Exchange: %w", err)
+	}
+	if tok.IDToken == "" {
+		return "", errors.New("oidc.Exchange: token response has no id_token")
+	}
+	return tok.IDToken, nil
+}
+
+// Verify checks an ID token's signature, issuer, audience and lifetime and
+// returns its identity. A non-empty nonce must match the token's.
+func (p *Provider) Verify(ctx context.Context, rawIDToken, nonce string) (*Identity, error) {
+	header, signed, sig, payload, err := parseJWT(rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("oidc.Verify: %w", err)
+	}
+	key, err := p.key(ctx, header.Kid)
+	if err != nil {
+		return nil, fmt.Errorf("oidc.Verify: %w", err)
+	}
+	if err := verifySignature(header.Alg, key, signed, sig); err != nil {
+		return nil, fmt.Errorf("oidc.Verify: %w", err)
+	}
+
+	var c struct {
+		Issuer        string          `json:"iss"`
+		Subject       string          `json:"sub"`
+		Audience      json.RawMessage `json:"aud"`
+		AZP           string          `json:"azp"`
+		Expiry        int64           `json:"exp"`
+		NotBefore     int64           `json:"nbf"`
+		Nonce         string          `json:"nonce"`
+		Email         string          `json:"email"`
+		EmailVerified *bool           `json:"email_verified"`
+		Name          string          `json:"name"`
+	}
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return nil, fmt.Errorf("oidc.Verify: claims: %w", err)
+	}
+	now := time.Now()
+	switch {
+	case c.Issuer != p.cfg.Issuer:
+		return nil, fmt.Errorf("oidc.Verify: issuer %q is not %q", c.Issuer, p.cfg.Issuer)
+	case c.Subject == "":
+		return nil, errors.New("oidc.Verify: token has no subject")
+	case !audienceIncludes(c.Audience, p.cfg.ClientID):
+		return nil, errors.New("oidc.Verify: token was not issued for this client")
+	case c.AZP != "" && c.AZP != p.cfg.ClientID:
+		return nil, errors.New("oidc.Verify: token was issued to another party")
+	case c.Expiry == 0 || now.After(time.Unix(c.Expiry, 0).Add(clockSkew)):
+		return nil, errors.New("oidc.Verify: token expired")
+	case c.NotBefore != 0 && now.Add(clockSkew).Before(time.Unix(c.NotBefore, 0)):
+		return nil, errors.New("oidc.Verify: token not yet valid")
+	case nonce != "" && c.Nonce != nonce:
+		return nil, errors.New("oidc.Verify: nonce mismatch")
+	}
+	id := &Identity{Issuer: c.Issuer, Subject: c.Subject, Name: c.Name}
+	// An email the provider does not vouch for could name another
+	// approver, so only a verified one is kept.
+	if c.EmailVerified != nil && *c.EmailVerified {
+		id.Email = strings.ToLower(c.Email)
+	}
+	return id, nil
+}
+
+func audienceIncludes(raw json.RawMessage, clientID string) bool {
+	var one string
+	if json.Unmarshal(raw, &one) == nil {
+		return one == clientID
+	}
+	var many []string
+	if json.Unmarshal(raw, &many) == nil {
+		for _, a := range many {
+			if a == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// key returns the signing key kid, refetching the JWKS when it is unknown,
+// e.g. after the provider rotated keys, at most once per jwksMinRefresh.
+func (p *Provider) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if k, ok := p.keys[kid]; ok {
+		return k, nil
+	}
+	if !p.fetched.IsZero() && time.Since(p.fetched) < jwksMinRefresh {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.jwksURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := p.do(req, &set); err != nil {
+		return nil, fmt.Errorf("jwks: %w", err)
+	}
+	p.fetched = time.Now()
+	p.keys = map[string]crypto.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pub, err := k.publicKey()
+		if err != nil || pub == nil {
+			continue
+		}
+		p.keys[k.Kid] = pub
+	}
+	if k, ok := p.keys[kid]; ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// do performs req and decodes a 2xx JSON response into out.
+func (p *Provider) do(req *http.Request, out any) error {
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s: status %d: %s", req.Method, req.URL.Path, resp.StatusCode,
+			strings.TrimSpace(string(body[:min(len(body), 256)])))
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("decode %s: %w", req.URL.Path, err)
+	}
+	return nil
+}
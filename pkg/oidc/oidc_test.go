@@ -0,0 +1,265 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeIdP is an OIDC provider signing ID tokens with an RSA key.
+type fakeIdP struct {
+	srv   *httptest.Server
+	key   *rsa.PrivateKey
+	nonce string // nonce of the pending login
+	chal  string // PKCE challenge of the pending login
+}
+
+func newFakeIdP(t *testing.T) *fakeIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeIdP{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 f.srv.URL,
+			"authorization_endpoint": f.srv.URL + "/authorize",
+			"token_endpoint":         f.srv.URL + "/token",
+			"jwks_uri":               f.srv.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA", "kid": "k1", "use": "sig",
+			"n": b64.EncodeToString(key.N.Bytes()),
+			"e": b64.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		id, secret, _ := r.BasicAuth()
+		sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+		if id != "approvals" || secret != "s3cret" || r.PostFormValue("code") != "code-1" || b64.EncodeToString(sum[:]) != f.chal {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"id_token": f.token(t, "RS256", map[string]any{"nonce": f.nonce})})
+	})
+	f.srv = httptest.NewServer(mux)
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+// token signs an ID token for alice, with overrides applied to the claims.
+func (f *fakeIdP) token(t *testing.T, alg string, overrides map[string]any) string {
+	t.Helper()
+	claims := map[string]any{
+		"iss": f.srv.URL, "sub": "00u1", "aud": "approvals",
+		"exp": time.Now().Add(time.Hour).Unix(), "iat": time.Now().Unix(),
+		"email": "Alice@Example.com", "email_verified": true,
+	}
+	for k, v := range overrides {
+		if v == nil {
+			delete(claims, k)
+		} else {
+			claims[k] = v
+		}
+	}
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": "k1", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := b64.EncodeToString(header) + "." + b64.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, f.key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + b64.EncodeToString(sig)
+}
+
+func (f *fakeIdP) provider(t *testing.T) *Provider {
+	t.Helper()
+	p, err := Discover(context.Background(), Config{
+		Issuer: f.srv.URL, ClientID: "approvals", ClientSecret: "s3cret",
+		RedirectURL: "http://approvals.test/ui/callback", Scopes: []string{"openid", "email"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestVerify(t *testing.T) {
+	idp := newFakeIdP(t)
+	p := idp.provider(t)
+	ctx := context.Background()
+
+	id, err := p.Verify(ctx, idp.token(t, "RS256", nil), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.Subject != "00u1" || id.Issuer != idp.srv.URL || id.Approver() != "alice@example.com" {
+		t.Fatalf("identity = %+v", id)
+	}
+	unverified, err := p.Verify(ctx, idp.token(t, "RS256", map[string]any{"email_verified": false}), "")
+	if err != nil || unverified.Approver() != "00u1" {
+		t.Fatalf("unverified email: %+v, %v", unverified, err)
+	}
+	unmarked, err := p.Verify(ctx, idp.token(t, "RS256", map[string]any{"email_verified": nil}), "")
+	if err != nil || unmarked.Email != "" || unmarked.Approver() != "00u1" {
+		t.Fatalf("email without email_verified: %+v, %v", unmarked, err)
+	}
+
+	good := idp.token(t, "RS256", nil)
+	for name, tok := range map[string]string{
+		"audience":  idp.token(t, "RS256", map[string]any{"aud": []string{"other"}}),
+		"expired":   idp.token(t, "RS256", map[string]any{"exp": time.Now().Add(-time.Hour).Unix()}),
+		"issuer":    idp.token(t, "RS256", map[string]any{"iss": "https://evil.example"}),
+		"no sub":    idp.token(t, "RS256", map[string]any{"sub": nil}),
+		"alg none":  strings.Join(strings.Split(idp.token(t, "none", nil), ".")[:2], ".") + ".",
+		"HS256":     idp.token(t, "HS256", nil),
+		"tampered":  good[:strings.LastIndex(good, ".")-4] + "AAAA" + good[strings.LastIndex(good, "."):],
+		"malformed": "not-a-token",
+	} {
+		if _, err := p.Verify(ctx, tok, ""); err == nil {
+			t.Errorf("%s: token accepted", name)
+		}
+	}
+	if _, err := p.Verify(ctx, idp.token(t, "RS256", map[string]any{"nonce": "n1"}), "n2"); err == nil {
+		t.Error("nonce mismatch accepted")
+	}
+}
+
+func TestAuthenticator_LoginFlow(t *testing.T) {
+	idp := newFakeIdP(t)
+	a, err := NewAuthenticator(idp.provider(t), []byte(strings.Repeat("k", 32)), time.Hour, "/ui", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Login redirects to the provider and sets the login cookie.
+	rr := httptest.NewRecorder()
+	a.Login(rr, httptest.NewRequest(http.MethodGet, "/ui/login?return_to=/ui/pending%3Ftenant_id%3Dt1", nil))
+	loc, err := url.Parse(rr.Header().Get("Location"))
+	if err != nil || !strings.HasPrefix(loc.String(), idp.srv.URL+"/authorize?") {
+		t.Fatalf("login redirect = %q", rr.Header().Get("Location"))
+	}
+	q := loc.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("scope") != "openid email" || q.Get("client_id") != "approvals" {
+		t.Fatalf("authorize query = %v", q)
+	}
+	idp.nonce, idp.chal = q.Get("nonce"), q.Get("code_challenge")
+	loginCookies := rr.Result().Cookies()
+
+	// A callback with the wrong state is refused.
+	bad := httptest.NewRequest(http.MethodGet, "/ui/callback?code=code-1&state=wrong", nil)
+	for _, c := range loginCookies {
+		bad.AddCookie(c)
+	}
+	rr = httptest.NewRecorder()
+	a.Callback(rr, bad)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("wrong state: status = %d", rr.Code)
+	}
+
+	// The provider redirects back with the code; the session starts.
+	cb := httptest.NewRequest(http.MethodGet, "/ui/callback?code=code-1&state="+url.QueryEscape(q.Get("state")), nil)
+	for _, c := range loginCookies {
+		cb.AddCookie(c)
+	}
+	rr = httptest.NewRecorder()
+	a.Callback(rr, cb)
+	if rr.Code != http.StatusFound || rr.Header().Get("Location") != "/ui/pending?tenant_id=t1" {
+		t.Fatalf("callback: status = %d, location = %q, body = %s", rr.Code, rr.Header().Get("Location"), rr.Body.String())
+	}
+	var session *http.Cookie
+	for _, c := range rr.Result().Cookies() {
+		if c.Name == SessionCookie {
+			session = c
+		}
+	}
+	if session == nil || !session.HttpOnly {
+		t.Fatalf("session cookie = %+v", session)
+	}
+
+	// The session cookie authenticates later requests.
+	var got Session
+	h := a.RequireSession(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		got, _ = FromContext(r.Context())
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/ui/pending", nil)
+	req.AddCookie(session)
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if got.Approver() != "alice@example.com" || got.Subject != "00u1" || got.CSRF == "" || !got.CheckCSRF(got.CSRF) {
+		t.Fatalf("session = %+v", got)
+	}
+
+	// A forged cookie is ignored and the browser sent to log in.
+	forged := *session
+	forged.Value = strings.Replace(session.Value, ".", "x.", 1)
+	req = httptest.NewRequest(http.MethodGet, "/ui/pending", nil)
+	req.AddCookie(&forged)
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	if rr.Code != http.StatusFound || !strings.HasPrefix(rr.Header().Get("Location"), "/ui/login?return_to=") {
+		t.Fatalf("forged cookie: status = %d, location = %q", rr.Code, rr.Header().Get("Location"))
+	}
+}
+
+func TestAuthenticate_BearerToken(t *testing.T) {
+	idp := newFakeIdP(t)
+	a, err := NewAuthenticator(idp.provider(t), []byte(strings.Repeat("k", 32)), time.Hour, "/ui", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Session
+	var authed bool
+	h := a.Authenticate(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		got, authed = FromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/approvals/requests/r1/approve", nil)
+	req.Header.Set("Authorization", "Bearer "+idp.token(t, "RS256", nil))
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if !authed || got.Subject != "00u1" {
+		t.Fatalf("bearer: session = %+v, %v", got, authed)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/v1/approvals/requests/r1/approve", nil)
+	req.Header.Set("Authorization", "Bearer "+idp.token(t, "RS256", map[string]any{"aud": "other"}))
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("invalid bearer: status = %d", rr.Code)
+	}
+
+	authed = false
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if authed {
+		t.Fatal("request without credentials authenticated")
+	}
+}
+
+func TestLocalPath(t *testing.T) {
+	for in, want := range map[string]string{
+		"/ui/pending?tenant_id=t1": "/ui/pending?tenant_id=t1",
+		"//evil.example/":          "/fallback",
+		"/\\evil.example":          "/fallback",
+		"https://evil.example/":    "/fallback",
+		"":                         "/fallback",
+	} {
+		if got := localPath(in, "/fallback"); got != want {
+			t.Errorf("localPath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package oidc
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// SessionCookie carries a signed-in approver's session.
+	SessionCookie = "oc_approver_session"
+	// loginCookie carries the state, nonce and PKCE verifier of a login in
+	// progress.
+	loginCookie = "oc_oidc_login"
+	loginTTL    = 10 * time.Minute
+)
+
+// Session is a signed-in approver.
+type Session struct {
+	Identity
+	// CSRF is a per-session token that forms posting with the session
+	// cookie must echo.
+	CSRF    string `json:"csrf"`
+	Expires int64  `json:"exp"`
+}
+
+type login struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	ReturnTo string `json:"return_to"`
+	Expires  int64  `json:"exp"`
+}
+
+// Authenticator signs approvers in with a Provider and authenticates their
+// requests.
+type Authenticator struct {
+	provider *Provider
+	key      []byte
+	ttl      time.Duration
+	secure   bool
+	basePath string
+	log      *slog.Logger
+}
+
+// NewAuthenticator creates an authenticator whose session cookies, signed
+// with key, last ttl. Cookies are marked Secure when the provider's
+// redirect URL is https. basePath prefixes the login routes, e.g. "/ui".
+// A nil log uses slog.Default().
+func NewAuthenticator(p *Provider, key []byte, ttl time.Duration, basePath string, log *slog.Logger) (*Authenticator, error) {
+	if len(key) < 32 {
+		return nil, errors.New("oidc.NewAuthenticator: session key must be at least 32 bytes")
+	}
+	if log == nil {
+		log = slog.Default()
+	}
+	return &Authenticator{
+		provider: p,
+		key:      key,
+		ttl:      ttl,
+		secure:   strings.HasPrefix(p.cfg.RedirectURL, "https://"),
+		basePath: strings.TrimRight(basePath, "/"),
+		log:      log,
+	}, nil
+}
+
+type contextKey struct{}
+
+// FromContext returns the session set by Authenticate.
+func FromContext(ctx context.Context) (Session, bool) {
+	s, ok := ctx.Value(contextKey{}).(Session)
+	return s, ok
+}
+
+// WithSession returns ctx carrying s, as Authenticate does. Tests and
+// embedding services that authenticate approvers themselves use it.
+func WithSession(ctx context.Context, s Session) context.Context {
+	return context.WithValue(ctx, contextKey{}, s)
+}
+
+// Authenticate is middleware that attaches the approver's session to the
+// request context when the request carries a valid session cookie or an
+// "Authorization: Bearer" ID token. An invalid bearer token is rejected;
+// requests without credentials pass through unauthenticated.
+func (a *Authenticator) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h := r.Header.Get("Authorization"); strings.HasPrefix(h, "Bearer ") {
+			id, err := a.provider.Verify(r.Context(), strings.TrimPrefix(h, "Bearer "), "")
+			if err != nil {
+				a.log.Warn("approver token rejected", "error", err)
+				types.ErrUnauthorized("invalid approver token").WriteJSON(w)
+				return
+			}
+			r = r.WithContext(WithSession(r.Context(), Session{Identity: *id}))
+		} else {
+			var s Session
+			if a.read(r, SessionCookie, &s) && time.Now().Unix() < s.Expires {
+				r = r.WithContext(WithSession(r.Context(), s))
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireSession is middleware for browser pages: requests without a
+// session are redirected to the login route, which returns them here.
+func (a *Authenticator) RequireSession(next http.Handler) http.Handler {
+	return a.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := FromContext(r.Context()); !ok {
+			http.Redirect(w, r, a.basePath+"/login?"+url.Values{"return_to": {r.URL.RequestURI()}}.Encode(), http.StatusFound)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}))
+}
+
+// RegisterRoutes mounts GET login, GET callback and POST logout under the
+// base path.
+func (a *Authenticator) RegisterRoutes(r chi.Router) {
+	r.Get(a.basePath+"/login", a.Login)
+	r.Get(a.basePath+"/callback", a.Callback)
+	r.Post(a.basePath+"/logout", a.Logout)
+}
+
+// Login starts the authorization-code flow.
+func (a *Authenticator) Login(w http.ResponseWriter, r *http.Request) {
+	l := login{
+		State:    rand.Text(),
+		Nonce:    rand.Text(),
+		Verifier: rand.Text() + rand.Text(),
+		ReturnTo: localPath(r.URL.Query().Get("return_to"), a.basePath+"/pending"),
+		Expires:  time.Now().Add(loginTTL).Unix(),
+	}
+	a.write(w, loginCookie, l, loginTTL)
+	http.Redirect(w, r, a.provider.AuthCodeURL(l.State, l.Nonce, l.Verifier), http.StatusFound)
+}
+
+// Callback completes a login and starts the session.
+func (a *Authenticator) Callback(w http.ResponseWriter, r *http.Request) {
+	var l login
+	if !a.read(r, loginCookie, &l) || time.Now().Unix() >= l.Expires {
+		http.Error(w, "login expired; start again", http.StatusBadRequest)
+		return
+	}
+	a.clear(w, loginCookie)
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		http.Error(w, "login failed: "+e, http.StatusUnauthorized)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(q.Get("state")), []byte(l.State)) != 1 {
+		http.Error(w, "login state mismatch", http.StatusBadRequest)
+		return
+	}
+	raw, err := a.provider.Exchange(r.Context(), q.Get("code"), l.Verifier)
+	if err != nil {
+		a.log.Error("oidc code exchange failed", "error", err)
+		http.Error(w, "login failed", http.StatusBadGateway)
+		return
+	}
+	id, err := a.provider.Verify(r.Context(), raw, l.Nonce)
+	if err != nil {
+		a.log.Warn("oidc id token rejected", "error", err)
+		http.Error(w, "login failed", http.StatusUnauthorized)
+		return
+	}
+	a.write(w, SessionCookie, Session{
+		Identity: *id,
+		CSRF:     rand.Text(),
+		Expires:  time.Now().Add(a.ttl).Unix(),
+	}, a.ttl)
+	a.log.Info("approver signed in", "issuer", id.Issuer, "subject", id.Subject, "email", id.Email)
+	http.Redirect(w, r, l.ReturnTo, http.StatusFound)
+}
+
+// Logout ends the session.
+func (a *Authenticator) Logout(w http.ResponseWriter, r *http.Request) {
+	a.clear(w, SessionCookie)
+	http.Redirect(w, r, a.basePath+"/login", http.StatusSeeOther)
+}
+
+// CheckCSRF reports whether token is the session's CSRF token.
+func (s Session) CheckCSRF(token string) bool {
+	return s.CSRF != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.CSRF)) == 1
+}
+
+// write sets cookie name to v, signed: base64url(JSON) "." base64url(HMAC).
+func (a *Authenticator) write(w http.ResponseWriter, name string, v any, ttl time.Duration) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		a.log.Error("encode cookie failed", "cookie", name, "error", err)
+		return
+	}
+	value := b64.EncodeToString(payload)
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value + "." + b64.EncodeToString(a.sign(name, value)),
+		Path:     "/",
+		MaxAge:   int(ttl.Seconds()),
+		HttpOnly: true,
+		Secure:   a.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// read decodes cookie name into v when its signature is valid.
+func (a *Authenticator) read(r *http.Request, name string, v any) bool {
+	c, err := r.Cookie(name)
+	if err != nil {
+		return false
+	}
+	value, sig, ok := strings.Cut(c.Value, ".")
+	if !ok {
+		return false
+	}
+	got, err := b64.DecodeString(sig)
+	if err != nil || !hmac.Equal(got, a.sign(name, value)) {
+		return false
+	}
+	payload, err := b64.DecodeString(value)
+	return err == nil && json.Unmarshal(payload, v) == nil
+}
+
+func (a *Authenticator) clear(w http.ResponseWriter, name string) {
+	http.SetCookie(w, &http.Cookie{Name: name, Value: "", Path: "/", MaxAge: -1, HttpOnly: true, Secure: a.secure, SameSite: http.SameSiteLaxMode})
+}
+
+// sign MACs the cookie name with its value, so one cookie's value cannot
+// be replayed as another.
+func (a *Authenticator) sign(name, value string) []byte {
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(name + "\x00" + value))
+	return mac.Sum(nil)
+}
+
+// localPath returns p when it is a path on this host, else fallback, so the
+// login cannot be used as an open redirect.
+func localPath(p, fallback string) string {
+	if !strings.HasPrefix(p, "/") || strings.HasPrefix(p, "//") || strings.HasPrefix(p, "/\\") {
+		return fallback
+	}
+	return p
+}
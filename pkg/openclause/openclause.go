@@ -0,0 +1,143 @@
+// Package openclause embeds OpenClause governance in another Go service.
+// Instead of deploying the gateway and approvals binaries, a service builds
+// their HTTP APIs in-process and mounts them on its own router:
+//
+//	_, env, err := openclause.LoadConfig("openclause.yaml")
+//	if err != nil { ... }
+//	gw, err := openclause.NewGateway(ctx, log, openclause.GatewayOptions{Pool: pool, Env: env})
+//	if err != nil { ... }
+//	defer gw.Close(context.Background())
+//	mux.Handle("/", gw.Handler())
+//
+// Both services read the same configuration as their binaries: the process
+// environment, falling back to the Env that LoadConfig returns.
+// NewEvidenceStore opens an evidence store directly, for services that
+// record or verify events themselves or hand the gateway a store they built.
+package openclause
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/bturcanu/OpenClause/pkg/approvals/service"
+	"github.com/bturcanu/OpenClause/pkg/config"
+	"github.com/bturcanu/OpenClause/pkg/evidence"
+	"github.com/bturcanu/OpenClause/pkg/gateway"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type (
+	// Gateway serves the tool-call API: POST /v1/toolcalls and the rest of
+	// the gateway routes.
+	Gateway = gateway.Server
+	// GatewayOptions are what the embedding service shares with the gateway.
+	GatewayOptions = gateway.Options
+	// Approvals serves the approvals API, Slack interactions and the
+	// pending-approvals page, and runs the notifier and digest jobs.
+	Approvals = service.Server
+	// ApprovalsOptions are what the embedding service shares with the
+	// approvals service.
+	ApprovalsOptions = service.Options
+)
+
+// LoadConfig reads the YAML or TOML file at path, as OC_CONFIG_FILE does
+// for the binaries, and returns the validated effective configuration and
+// the file's values as an Env for GatewayOptions.Env and
+// ApprovalsOptions.Env. The process environment is left unchanged, and its
+// variables override the file. An empty path loads the environment alone.
+func LoadConfig(path string) (*config.File, config.Env, error) {
+	f, env, err := config.Resolve(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("openclause.LoadConfig: %w", err)
+	}
+	return f, env, nil
+}
+
+// NewGateway builds the gateway. A nil log uses slog.Default().
+func NewGateway(ctx context.Context, log *slog.Logger, opts GatewayOptions) (*Gateway, error) {
+	if log == nil {
+		log = slog.Default()
+	}
+	return gateway.New(ctx, log, opts)
+}
+
+// NewApprovals builds the approvals service and starts its background jobs.
+// A nil log uses slog.Default().
+func NewApprovals(ctx context.Context, log *slog.Logger, opts ApprovalsOptions) (*Approvals, error) {
+	if log == nil {
+		log = slog.Default()
+	}
+	return service.New(ctx, log, opts)
+}
+
+// EvidenceConfig selects and configures an evidence store.
+type EvidenceConfig struct {
+	// Backend is "postgres" (the default), "mysql" or "sqlite".
+	Backend string
+	// Pool backs a postgres store.
+	Pool *pgxpool.Pool
+	// DB backs a mysql store.
+	DB *sql.DB
+	// SQLitePath is the database file of a sqlite store; it is created if
+	// missing. SQLite needs a cgo build.
+	SQLitePath string
+	// Canon is the canonical JSON form of recorded events; zero is
+	// evidence.CanonLegacy.
+	Canon evidence.CanonVersion
+}
+
+// EvidenceStore is an evidence store opened by NewEvidenceStore.
+type EvidenceStore struct {
+	evidence.EventStore
+	closer io.Closer
+}
+
+// Close closes a sqlite store's database. Postgres and mysql stores leave
+// the pool or DB to the caller.
+func (s *EvidenceStore) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	return s.closer.Close()
+}
+
+// NewEvidenceStore opens the evidence store cfg describes. The store can be
+// passed to NewGateway as GatewayOptions.Evidence.
+func NewEvidenceStore(ctx context.Context, cfg EvidenceConfig) (*EvidenceStore, error) {
+	canon := cfg.Canon
+	if canon == 0 {
+		canon = evidence.CanonLegacy
+	}
+	switch cfg.Backend {
+	case "", "postgres":
+		if cfg.Pool == nil {
+			return nil, errors.New("openclause.NewEvidenceStore: postgres backend needs a Pool")
+		}
+		s := evidence.NewStore(cfg.Pool)
+		s.SetCanonVersion(canon)
+		return &EvidenceStore{EventStore: s}, nil
+	case "mysql":
+		if cfg.DB == nil {
+			return nil, errors.New("openclause.NewEvidenceStore: mysql backend needs a DB")
+		}
+		s := evidence.NewMySQLStore(cfg.DB)
+		s.SetCanonVersion(canon)
+		return &EvidenceStore{EventStore: s}, nil
+	case "sqlite":
+		if cfg.SQLitePath == "" {
+			return nil, errors.New("openclause.NewEvidenceStore: sqlite backend needs a SQLitePath")
+		}
+		s, err := evidence.OpenSQLite(ctx, cfg.SQLitePath)
+		if err != nil {
+			return nil, fmt.Errorf("openclause.NewEvidenceStore: %w", err)
+		}
+		s.SetCanonVersion(canon)
+		return &EvidenceStore{EventStore: s, closer: s}, nil
+	default:
+		return nil, fmt.Errorf("openclause.NewEvidenceStore: unknown backend %q", cfg.Backend)
+	}
+}
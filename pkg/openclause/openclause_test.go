@@ -0,0 +1,66 @@
+//go:build cgo
+
+package openclause
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/evidence"
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+func TestNewEvidenceStore_SQLite(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewEvidenceStore(ctx, EvidenceConfig{
+		Backend:    "sqlite",
+		SQLitePath: filepath.Join(t.TempDir(), "evidence.db"),
+		Canon:      evidence.CanonJCS,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := types.ToolCallRequest{
+		TenantID: "t1", AgentID: "a1", Tool: "slack", Action: "msg.post",
+		Params: json.RawMessage(`{"channel":"#ops"}`), IdempotencyKey: "k1",
+		RequestedAt: time.Now().UTC(),
+	}
+	payload, _ := json.Marshal(req)
+	if err := s.RecordEvent(ctx, &types.ToolCallEnvelope{
+		EventID: "e1", Request: req, PayloadJSON: payload,
+		ReceivedAt:   time.Now().UTC(),
+		Decision:     types.DecisionAllow,
+		PolicyResult: &types.PolicyResult{Decision: types.DecisionAllow, Reason: "ok"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.GetEvent(ctx, "e1")
+	if err != nil || got.CanonVersion != int(evidence.CanonJCS) {
+		t.Fatalf("event = %+v, %v", got, err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewEvidenceStore_Invalid(t *testing.T) {
+	for _, tc := range []struct {
+		cfg  EvidenceConfig
+		want string
+	}{
+		{EvidenceConfig{}, "postgres backend needs a Pool"},
+		{EvidenceConfig{Backend: "mysql"}, "mysql backend needs a DB"},
+		{EvidenceConfig{Backend: "sqlite"}, "sqlite backend needs a SQLitePath"},
+		{EvidenceConfig{Backend: "dynamo"}, `unknown backend "dynamo"`},
+	} {
+		_, err := NewEvidenceStore(context.Background(), tc.cfg)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%+v: err = %v, want %q", tc.cfg, err, tc.want)
+		}
+	}
+}
@@ -5,25 +5,101 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/bturcanu/OpenClause/pkg/config"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
 	"go.opentelemetry.io/otel/exporters/prometheus"
-	"go.opentelemetry.io/otel/propagation"
 	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
 )
 
+// Metrics exporters, as named by OTEL_METRICS_EXPORTER.
+const (
+	MetricsPrometheus = "prometheus"
+	MetricsOTLP       = "otlp"
+	MetricsNone       = "none"
+)
+
+// Trace samplers, as named by OTEL_TRACES_SAMPLER.
+var samplers = []string{
+	"always_on", "always_off", "traceidratio",
+	"parentbased_always_on", "parentbased_always_off", "parentbased_traceidratio",
+}
+
 // Config holds setup parameters.
 type Config struct {
-	ServiceName    string
-	OTLPEndpoint   string // e.g. "localhost:4318"
-	OTLPInsecure   bool   // set true to disable TLS (default for local dev)
-	MetricsEnabled bool
-	TracingEnabled bool
+	ServiceName string
+	// OTLPEndpoint is the collector's OTLP/HTTP base, either host:port,
+	// e.g. "localhost:4318", or a URL such as "http://collector:4318".
+	// Traces go to /v1/traces and metrics to /v1/metrics under it.
+	OTLPEndpoint string
+	OTLPInsecure bool              // set true to disable TLS (default for local dev)
+	OTLPHeaders  map[string]string // sent with every export, e.g. an API key
+	// MetricsEnabled sets up metrics; MetricsExporters says where they go:
+	// MetricsPrometheus (the default) for the /metrics scrape endpoint,
+	// MetricsOTLP to push them to the collector, or both.
+	MetricsEnabled   bool
+	MetricsExporters []string
+	// MetricsInterval is how often metrics are pushed over OTLP; default
+	// one minute.
+	MetricsInterval time.Duration
+	TracingEnabled  bool
+	// Sampler names the trace sampler (see OTEL_TRACES_SAMPLER); default
+	// parentbased_always_on. SamplerRatio is the fraction of traces the
+	// traceidratio samplers keep.
+	Sampler      string
+	SamplerRatio float64
+	// ResourceAttributes are added to every span and metric, e.g.
+	// deployment.environment. ServiceName wins over service.name.
+	ResourceAttributes map[string]string
+}
+
+// ConfigFromEnv reads the standard OpenTelemetry variables:
+// OTEL_SERVICE_NAME (default serviceName), OTEL_EXPORTER_OTLP_ENDPOINT,
+// OTEL_EXPORTER_OTLP_INSECURE, OTEL_EXPORTER_OTLP_HEADERS,
+// OTEL_METRICS_EXPORTER, OTEL_METRIC_EXPORT_INTERVAL (milliseconds),
+// OTEL_TRACES_SAMPLER, OTEL_TRACES_SAMPLER_ARG and
+// OTEL_RESOURCE_ATTRIBUTES. Metrics are enabled, and tracing is enabled
+// when an endpoint is set.
+func ConfigFromEnv(serviceName string) Config {
+	attrs := parsePairs(os.Getenv("OTEL_RESOURCE_ATTRIBUTES"))
+	if name := attrs[string(semconv.ServiceNameKey)]; name != "" {
+		serviceName = name
+	}
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	cfg := Config{
+		ServiceName:        config.EnvOr("OTEL_SERVICE_NAME", serviceName),
+		OTLPEndpoint:       endpoint,
+		OTLPInsecure:       config.EnvOrBool("OTEL_EXPORTER_OTLP_INSECURE", strings.HasPrefix(endpoint, "http://")),
+		OTLPHeaders:        parsePairs(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS")),
+		MetricsEnabled:     true,
+		MetricsInterval:    config.EnvOrDuration("OTEL_METRIC_EXPORT_INTERVAL", time.Millisecond, time.Minute),
+		TracingEnabled:     endpoint != "",
+		Sampler:            strings.ToLower(os.Getenv("OTEL_TRACES_SAMPLER")),
+		ResourceAttributes: attrs,
+	}
+	if v := os.Getenv("OTEL_METRICS_EXPORTER"); v != "" {
+		for _, e := range strings.Split(v, ",") {
+			cfg.MetricsExporters = append(cfg.MetricsExporters, strings.ToLower(strings.TrimSpace(e)))
+		}
+	}
+	cfg.SamplerRatio = 1
+	if v := os.Getenv("OTEL_TRACES_SAMPLER_ARG"); v != "" {
+		if r, err := strconv.ParseFloat(v, 64); err == nil {
+			cfg.SamplerRatio = r
+		}
+	}
+	return cfg
 }
 
 // Shutdown is returned by Setup to allow graceful shutdown.
@@ -32,11 +108,29 @@ type Shutdown func(ctx context.Context) error
 // Setup initializes tracing and metrics exporters.
 // Returns a shutdown function that should be deferred.
 func Setup(ctx context.Context, cfg Config) (Shutdown, error) {
-	res, err := resource.New(ctx,
-		resource.WithAttributes(
-			semconv.ServiceNameKey.String(cfg.ServiceName),
-		),
-	)
+	sampler, err := newSampler(cfg.Sampler, cfg.SamplerRatio)
+	if err != nil {
+		return nil, err
+	}
+	exporters := cfg.MetricsExporters
+	if len(exporters) == 0 {
+		exporters = []string{MetricsPrometheus}
+	}
+	for _, e := range exporters {
+		if e != MetricsPrometheus && e != MetricsOTLP && e != MetricsNone {
+			return nil, fmt.Errorf("otel: unknown metrics exporter %q", e)
+		}
+	}
+	if slices.Contains(exporters, MetricsOTLP) && cfg.OTLPEndpoint == "" {
+		return nil, errors.New("otel: the otlp metrics exporter needs an OTLP endpoint")
+	}
+
+	attrs := make([]attribute.KeyValue, 0, len(cfg.ResourceAttributes)+1)
+	for k, v := range cfg.ResourceAttributes {
+		attrs = append(attrs, attribute.String(k, v))
+	}
+	attrs = append(attrs, semconv.ServiceNameKey.String(cfg.ServiceName))
+	res, err := resource.New(ctx, resource.WithAttributes(attrs...))
 	if err != nil {
 		return nil, fmt.Errorf("otel resource: %w", err)
 	}
@@ -45,12 +139,18 @@ func Setup(ctx context.Context, cfg Config) (Shutdown, error) {
 
 	// ── Tracing ──────────────────────────────────────────────────────────
 	if cfg.TracingEnabled && cfg.OTLPEndpoint != "" {
-		opts := []otlptracehttp.Option{
-			otlptracehttp.WithEndpoint(cfg.OTLPEndpoint),
+		opts := []otlptracehttp.Option{}
+		if strings.Contains(cfg.OTLPEndpoint, "://") {
+			opts = append(opts, otlptracehttp.WithEndpointURL(otlpURL(cfg, "/v1/traces")))
+		} else {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.OTLPEndpoint))
 		}
 		if cfg.OTLPInsecure {
 			opts = append(opts, otlptracehttp.WithInsecure())
 		}
+		if len(cfg.OTLPHeaders) > 0 {
+			opts = append(opts, otlptracehttp.WithHeaders(cfg.OTLPHeaders))
+		}
 
 		exporter, err := otlptracehttp.New(ctx, opts...)
 		if err != nil {
@@ -60,28 +160,35 @@ func Setup(ctx context.Context, cfg Config) (Shutdown, error) {
 		tp := sdktrace.NewTracerProvider(
 			sdktrace.WithBatcher(exporter, sdktrace.WithBatchTimeout(5*time.Second)),
 			sdktrace.WithResource(res),
+			sdktrace.WithSampler(sampler),
 		)
 		otel.SetTracerProvider(tp)
 		shutdowns = append(shutdowns, tp.Shutdown)
 	}
 
 	// ── Propagation ─────────────────────────────────────────────────────
-	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
-		propagation.TraceContext{},
-		propagation.Baggage{},
-	))
-
-	// ── Metrics (Prometheus) ────────────────────────────────────────────
-	if cfg.MetricsEnabled {
-		promExporter, err := prometheus.New()
-		if err != nil {
-			return nil, fmt.Errorf("otel prometheus exporter: %w", err)
+	otel.SetTextMapPropagator(propagator)
+
+	// ── Metrics (Prometheus and/or OTLP) ────────────────────────────────
+	if cfg.MetricsEnabled && !slices.Equal(exporters, []string{MetricsNone}) {
+		opts := []sdkmetric.Option{sdkmetric.WithResource(res)}
+		if slices.Contains(exporters, MetricsPrometheus) {
+			promExporter, err := prometheus.New()
+			if err != nil {
+				return nil, fmt.Errorf("otel prometheus exporter: %w", err)
+			}
+			opts = append(opts, sdkmetric.WithReader(promExporter))
+		}
+		if slices.Contains(exporters, MetricsOTLP) {
+			interval := cfg.MetricsInterval
+			if interval <= 0 {
+				interval = time.Minute
+			}
+			exporter := newOTLPMetricExporter(otlpURL(cfg, "/v1/metrics"), cfg.OTLPHeaders)
+			opts = append(opts, sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter, sdkmetric.WithInterval(interval))))
 		}
 
-		mp := sdkmetric.NewMeterProvider(
-			sdkmetric.WithReader(promExporter),
-			sdkmetric.WithResource(res),
-		)
+		mp := sdkmetric.NewMeterProvider(opts...)
 		otel.SetMeterProvider(mp)
 		shutdowns = append(shutdowns, mp.Shutdown)
 	}
@@ -98,3 +205,57 @@ func Setup(ctx context.Context, cfg Config) (Shutdown, error) {
 
 	return shutdown, nil
 }
+
+// newSampler builds the named trace sampler; empty means
+// parentbased_always_on, the SDK default.
+func newSampler(name string, ratio float64) (sdktrace.Sampler, error) {
+	if strings.HasSuffix(name, "traceidratio") && (ratio < 0 || ratio > 1) {
+		return nil, fmt.Errorf("otel: sampler ratio %v is not between 0 and 1", ratio)
+	}
+	switch name {
+	case "", "parentbased_always_on":
+		return sdktrace.ParentBased(sdktrace.AlwaysSample()), nil
+	case "parentbased_always_off":
+		return sdktrace.ParentBased(sdktrace.NeverSample()), nil
+	case "parentbased_traceidratio":
+		return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio)), nil
+	case "always_on":
+		return sdktrace.AlwaysSample(), nil
+	case "always_off":
+		return sdktrace.NeverSample(), nil
+	case "traceidratio":
+		return sdktrace.TraceIDRatioBased(ratio), nil
+	}
+	return nil, fmt.Errorf("otel: unknown sampler %q (want one of %s)", name, strings.Join(samplers, ", "))
+}
+
+// otlpURL returns the collector URL for signal path p.
+func otlpURL(cfg Config, p string) string {
+	base := cfg.OTLPEndpoint
+	if !strings.Contains(base, "://") {
+		scheme := "https://"
+		if cfg.OTLPInsecure {
+			scheme = "http://"
+		}
+		base = scheme + base
+	}
+	return strings.TrimRight(base, "/") + p
+}
+
+// parsePairs reads the "k1=v1,k2=v2" lists of OTEL_RESOURCE_ATTRIBUTES and
+// OTEL_EXPORTER_OTLP_HEADERS, whose values may be percent-encoded.
+// Malformed entries are skipped.
+func parsePairs(s string) map[string]string {
+	out := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(pair, "=")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" {
+			continue
+		}
+		if dec, err := url.PathUnescape(strings.TrimSpace(v)); err == nil {
+			out[k] = dec
+		}
+	}
+	return out
+}
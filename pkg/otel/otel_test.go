@@ -0,0 +1,167 @@
+package otel
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	colmetricpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("OTEL_SERVICE_NAME", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://collector:4318")
+	t.Setenv("OTEL_EXPORTER_OTLP_INSECURE", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "x-api-key=abc%3D%3D, bad")
+	t.Setenv("OTEL_METRICS_EXPORTER", "prometheus, OTLP")
+	t.Setenv("OTEL_METRIC_EXPORT_INTERVAL", "15000")
+	t.Setenv("OTEL_TRACES_SAMPLER", "parentbased_traceidratio")
+	t.Setenv("OTEL_TRACES_SAMPLER_ARG", "0.25")
+	t.Setenv("OTEL_RESOURCE_ATTRIBUTES", "deployment.environment=prod,service.name=gw-eu")
+
+	cfg := ConfigFromEnv("oc-gateway")
+	if cfg.ServiceName != "gw-eu" || cfg.ResourceAttributes["deployment.environment"] != "prod" {
+		t.Errorf("service = %q, attributes = %v", cfg.ServiceName, cfg.ResourceAttributes)
+	}
+	if !cfg.OTLPInsecure || !cfg.TracingEnabled || cfg.OTLPHeaders["x-api-key"] != "abc==" || len(cfg.OTLPHeaders) != 1 {
+		t.Errorf("otlp = %+v", cfg)
+	}
+	if len(cfg.MetricsExporters) != 2 || cfg.MetricsExporters[1] != MetricsOTLP || cfg.MetricsInterval != 15*time.Second {
+		t.Errorf("metrics = %v every %v", cfg.MetricsExporters, cfg.MetricsInterval)
+	}
+	if cfg.Sampler != "parentbased_traceidratio" || cfg.SamplerRatio != 0.25 {
+		t.Errorf("sampler = %s(%v)", cfg.Sampler, cfg.SamplerRatio)
+	}
+
+	t.Setenv("OTEL_SERVICE_NAME", "gw-explicit")
+	if got := ConfigFromEnv("oc-gateway").ServiceName; got != "gw-explicit" {
+		t.Errorf("OTEL_SERVICE_NAME should win, got %q", got)
+	}
+}
+
+func TestNewSampler(t *testing.T) {
+	sample := func(s sdktrace.Sampler, parent trace.SpanContext) bool {
+		ctx := trace.ContextWithRemoteSpanContext(context.Background(), parent)
+		tid := trace.TraceID{8: 0xff, 9: 0xff, 10: 0xff, 11: 0xff, 12: 0xff, 13: 0xff, 14: 0xff, 15: 0xff} // above any ratio bound
+		return s.ShouldSample(sdktrace.SamplingParameters{ParentContext: ctx, TraceID: tid}).Decision == sdktrace.RecordAndSample
+	}
+	sampledParent := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1}, SpanID: trace.SpanID{1}, TraceFlags: trace.FlagsSampled, Remote: true,
+	})
+
+	ratio, err := newSampler("traceidratio", 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sample(ratio, trace.SpanContext{}) {
+		t.Error("traceidratio sampled a trace ID above the ratio")
+	}
+	parentRatio, err := newSampler("parentbased_traceidratio", 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sample(parentRatio, sampledParent) {
+		t.Error("parentbased sampler dropped a sampled parent's span")
+	}
+	off, _ := newSampler("always_off", 1)
+	if sample(off, sampledParent) {
+		t.Error("always_off sampled")
+	}
+	for _, bad := range []struct {
+		name  string
+		ratio float64
+	}{{"sometimes", 1}, {"traceidratio", 1.5}} {
+		if _, err := newSampler(bad.name, bad.ratio); err == nil {
+			t.Errorf("newSampler(%q, %v) succeeded", bad.name, bad.ratio)
+		}
+	}
+}
+
+func TestSetup_ExportsMetricsOverOTLP(t *testing.T) {
+	var mu sync.Mutex
+	var got []*colmetricpb.ExportMetricsServiceRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/metrics" || r.Header.Get("Content-Type") != "application/x-protobuf" || r.Header.Get("X-Api-Key") != "k1" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		var req colmetricpb.ExportMetricsServiceRequest
+		if err := proto.Unmarshal(body, &req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		got = append(got, &req)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	shutdown, err := Setup(context.Background(), Config{
+		ServiceName:        "oc-test",
+		OTLPEndpoint:       srv.URL,
+		OTLPHeaders:        map[string]string{"X-Api-Key": "k1"},
+		MetricsEnabled:     true,
+		MetricsExporters:   []string{MetricsOTLP},
+		MetricsInterval:    time.Hour,
+		ResourceAttributes: map[string]string{"deployment.environment": "test"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	counter, _ := otel.Meter("openclause/test").Int64Counter("oc_test_calls_total")
+	counter.Add(context.Background(), 3)
+	hist, _ := otel.Meter("openclause/test").Float64Histogram("oc_test_latency_seconds")
+	hist.Record(context.Background(), 0.2)
+	if err := shutdown(context.Background()); err != nil { // flushes the periodic reader
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) == 0 {
+		t.Fatal("no metrics exported")
+	}
+	rm := got[0].ResourceMetrics[0]
+	attrs := map[string]string{}
+	for _, kv := range rm.Resource.Attributes {
+		attrs[kv.Key] = kv.Value.GetStringValue()
+	}
+	if attrs["service.name"] != "oc-test" || attrs["deployment.environment"] != "test" {
+		t.Errorf("resource = %v", attrs)
+	}
+	metrics := map[string]bool{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			switch {
+			case m.GetSum() != nil && m.GetSum().DataPoints[0].GetAsInt() == 3 && m.GetSum().IsMonotonic:
+				metrics[m.Name] = true
+			case m.GetHistogram() != nil && m.GetHistogram().DataPoints[0].GetCount() == 1:
+				metrics[m.Name] = true
+			}
+		}
+	}
+	if !metrics["oc_test_calls_total"] || !metrics["oc_test_latency_seconds"] {
+		t.Errorf("exported metrics = %v", metrics)
+	}
+}
+
+func TestSetup_RejectsBadConfig(t *testing.T) {
+	for _, cfg := range []Config{
+		{MetricsEnabled: true, MetricsExporters: []string{"statsd"}},
+		{MetricsEnabled: true, MetricsExporters: []string{MetricsOTLP}},
+		{Sampler: "sometimes"},
+	} {
+		if _, err := Setup(context.Background(), cfg); err == nil {
+			t.Errorf("Setup(%+v) succeeded", cfg)
+		}
+	}
+}
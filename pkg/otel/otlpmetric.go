@@ -0,0 +1,228 @@
+package otel
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	colmetricpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// otlpMetricExporter pushes metrics to an OTLP/HTTP collector as protobuf.
+// It exports sums, gauges and explicit-bucket histograms, the aggregations
+// the SDK's default selector produces; exemplars are not sent.
+type otlpMetricExporter struct {
+	url      string
+	headers  map[string]string
+	client   *http.Client
+	shutdown atomic.Bool
+}
+
+func newOTLPMetricExporter(url string, headers map[string]string) *otlpMetricExporter {
+	return &otlpMetricExporter{url: url, headers: headers, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (e *otlpMetricExporter) Temporality(k sdkmetric.InstrumentKind) metricdata.Temporality {
+	return sdkmetric.DefaultTemporalitySelector(k)
+}
+
+func (e *otlpMetricExporter) Aggregation(k sdkmetric.InstrumentKind) sdkmetric.Aggregation {
+	return sdkmetric.DefaultAggregationSelector(k)
+}
+
+func (e *otlpMetricExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	if e.shutdown.Load() {
+		return fmt.Errorf("otel metric export: exporter is shut down")
+	}
+	body, err := proto.Marshal(&colmetricpb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricpb.ResourceMetrics{resourceMetrics(rm)},
+	})
+	if err != nil {
+		return fmt.Errorf("otel metric export: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("otel metric export: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("otel metric export: %w", err)
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("otel metric export: %s: status %d: %s", e.url, resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+func (e *otlpMetricExporter) ForceFlush(context.Context) error { return nil }
+
+func (e *otlpMetricExporter) Shutdown(context.Context) error {
+	e.shutdown.Store(true)
+	return nil
+}
+
+// ── metricdata → OTLP protobuf ───────────────────────────────────────────
+
+func resourceMetrics(rm *metricdata.ResourceMetrics) *metricpb.ResourceMetrics {
+	out := &metricpb.ResourceMetrics{Resource: &resourcepb.Resource{}}
+	if rm.Resource != nil {
+		out.Resource.Attributes = keyValues(rm.Resource.Attributes())
+		out.SchemaUrl = rm.Resource.SchemaURL()
+	}
+	for _, sm := range rm.ScopeMetrics {
+		scope := &metricpb.ScopeMetrics{
+			Scope:     &commonpb.InstrumentationScope{Name: sm.Scope.Name, Version: sm.Scope.Version},
+			SchemaUrl: sm.Scope.SchemaURL,
+		}
+		for _, m := range sm.Metrics {
+			if pm := metric(m); pm != nil {
+				scope.Metrics = append(scope.Metrics, pm)
+			}
+		}
+		out.ScopeMetrics = append(out.ScopeMetrics, scope)
+	}
+	return out
+}
+
+// metric converts m, or returns nil for an aggregation OTLP export does
+// not carry.
+func metric(m metricdata.Metrics) *metricpb.Metric {
+	out := &metricpb.Metric{Name: m.Name, Description: m.Description, Unit: m.Unit}
+	switch d := m.Data.(type) {
+	case metricdata.Sum[int64]:
+		out.Data = &metricpb.Metric_Sum{Sum: &metricpb.Sum{
+			DataPoints: numberPoints(d.DataPoints), AggregationTemporality: temporality(d.Temporality), IsMonotonic: d.IsMonotonic,
+		}}
+	case metricdata.Sum[float64]:
+		out.Data = &metricpb.Metric_Sum{Sum: &metricpb.Sum{
+			DataPoints: numberPoints(d.DataPoints), AggregationTemporality: temporality(d.Temporality), IsMonotonic: d.IsMonotonic,
+		}}
+	case metricdata.Gauge[int64]:
+		out.Data = &metricpb.Metric_Gauge{Gauge: &metricpb.Gauge{DataPoints: numberPoints(d.DataPoints)}}
+	case metricdata.Gauge[float64]:
+		out.Data = &metricpb.Metric_Gauge{Gauge: &metricpb.Gauge{DataPoints: numberPoints(d.DataPoints)}}
+	case metricdata.Histogram[int64]:
+		out.Data = &metricpb.Metric_Histogram{Histogram: &metricpb.Histogram{
+			DataPoints: histogramPoints(d.DataPoints), AggregationTemporality: temporality(d.Temporality),
+		}}
+	case metricdata.Histogram[float64]:
+		out.Data = &metricpb.Metric_Histogram{Histogram: &metricpb.Histogram{
+			DataPoints: histogramPoints(d.DataPoints), AggregationTemporality: temporality(d.Temporality),
+		}}
+	default:
+		return nil
+	}
+	return out
+}
+
+func numberPoints[N int64 | float64](points []metricdata.DataPoint[N]) []*metricpb.NumberDataPoint {
+	out := make([]*metricpb.NumberDataPoint, 0, len(points))
+	for _, p := range points {
+		dp := &metricpb.NumberDataPoint{
+			Attributes:        keyValues(p.Attributes.ToSlice()),
+			StartTimeUnixNano: unixNano(p.StartTime),
+			TimeUnixNano:      unixNano(p.Time),
+		}
+		switch v := any(p.Value).(type) {
+		case int64:
+			dp.Value = &metricpb.NumberDataPoint_AsInt{AsInt: v}
+		case float64:
+			dp.Value = &metricpb.NumberDataPoint_AsDouble{AsDouble: v}
+		}
+		out = append(out, dp)
+	}
+	return out
+}
+
+func histogramPoints[N int64 | float64](points []metricdata.HistogramDataPoint[N]) []*metricpb.HistogramDataPoint {
+	out := make([]*metricpb.HistogramDataPoint, 0, len(points))
+	for _, p := range points {
+		sum := float64(p.Sum)
+		dp := &metricpb.HistogramDataPoint{
+			Attributes:        keyValues(p.Attributes.ToSlice()),
+			StartTimeUnixNano: unixNano(p.StartTime),
+			TimeUnixNano:      unixNano(p.Time),
+			Count:             p.Count,
+			Sum:               &sum,
+			BucketCounts:      p.BucketCounts,
+			ExplicitBounds:    p.Bounds,
+		}
+		if v, ok := p.Min.Value(); ok {
+			f := float64(v)
+			dp.Min = &f
+		}
+		if v, ok := p.Max.Value(); ok {
+			f := float64(v)
+			dp.Max = &f
+		}
+		out = append(out, dp)
+	}
+	return out
+}
+
+func temporality(t metricdata.Temporality) metricpb.AggregationTemporality {
+	if t == metricdata.DeltaTemporality {
+		return metricpb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA
+	}
+	return metricpb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE
+}
+
+func unixNano(t time.Time) uint64 {
+	if t.IsZero() {
+		return 0
+	}
+	return uint64(t.UnixNano())
+}
+
+func keyValues(attrs []attribute.KeyValue) []*commonpb.KeyValue {
+	out := make([]*commonpb.KeyValue, 0, len(attrs))
+	for _, kv := range attrs {
+		out = append(out, &commonpb.KeyValue{Key: string(kv.Key), Value: anyValue(kv.Value)})
+	}
+	return out
+}
+
+func anyValue(v attribute.Value) *commonpb.AnyValue {
+	switch v.Type() {
+	case attribute.BOOL:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: v.AsBool()}}
+	case attribute.INT64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: v.AsInt64()}}
+	case attribute.FLOAT64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: v.AsFloat64()}}
+	case attribute.BOOLSLICE:
+		return arrayValue(v.AsBoolSlice(), func(b bool) *commonpb.AnyValue { return anyValue(attribute.BoolValue(b)) })
+	case attribute.INT64SLICE:
+		return arrayValue(v.AsInt64Slice(), func(n int64) *commonpb.AnyValue { return anyValue(attribute.Int64Value(n)) })
+	case attribute.FLOAT64SLICE:
+		return arrayValue(v.AsFloat64Slice(), func(f float64) *commonpb.AnyValue { return anyValue(attribute.Float64Value(f)) })
+	case attribute.STRINGSLICE:
+		return arrayValue(v.AsStringSlice(), func(s string) *commonpb.AnyValue { return anyValue(attribute.StringValue(s)) })
+	}
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v.Emit()}}
+}
+
+func arrayValue[T any](vs []T, conv func(T) *commonpb.AnyValue) *commonpb.AnyValue {
+	arr := &commonpb.ArrayValue{Values: make([]*commonpb.AnyValue, 0, len(vs))}
+	for _, v := range vs {
+		arr.Values = append(arr.Values, conv(v))
+	}
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: arr}}
+}
@@ -0,0 +1,62 @@
+package otel
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// propagator is shared by Setup and the HTTP helpers so services that never
+// call Setup (e.g. connectors) still honour inbound traceparent headers.
+var propagator = propagation.NewCompositeTextMapPropagator(
+	propagation.TraceContext{},
+	propagation.Baggage{},
+)
+
+// InjectHTTP writes the trace context carried by ctx onto outbound headers.
+func InjectHTTP(ctx context.Context, h http.Header) {
+	propagator.Inject(ctx, propagation.HeaderCarrier(h))
+}
+
+// ExtractHTTP returns ctx enriched with any trace context found in h.
+func ExtractHTTP(ctx context.Context, h http.Header) context.Context {
+	return propagator.Extract(ctx, propagation.HeaderCarrier(h))
+}
+
+// Middleware extracts inbound W3C trace context so handler spans join the
+// caller's trace.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(ExtractHTTP(r.Context(), r.Header)))
+	})
+}
+
+// ContextWithTraceID links ctx to the caller-supplied trace ID when no
+// inbound span context is present. Hyphenated UUIDs are accepted since the
+// SDK generates those by default. Invalid IDs leave ctx unchanged.
+func ContextWithTraceID(ctx context.Context, traceID string) context.Context {
+	if trace.SpanContextFromContext(ctx).IsValid() || traceID == "" {
+		return ctx
+	}
+	tid, err := trace.TraceIDFromHex(strings.ReplaceAll(strings.ToLower(traceID), "-", ""))
+	if err != nil {
+		return ctx
+	}
+	// A remote parent needs a non-zero span ID; derive one from the trace ID
+	// so repeated calls for the same trace are stable.
+	var sid trace.SpanID
+	copy(sid[:], tid[8:])
+	if !sid.IsValid() {
+		sid[7] = 1
+	}
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    tid,
+		SpanID:     sid,
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+	return trace.ContextWithRemoteSpanContext(ctx, sc)
+}
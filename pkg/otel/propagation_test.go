@@ -0,0 +1,47 @@
+package otel
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestContextWithTraceID_AcceptsUUID(t *testing.T) {
+	ctx := ContextWithTraceID(context.Background(), "4BF92F35-77B3-4DA6-A3CE-929D0E0E4736")
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() || !sc.IsRemote() {
+		t.Fatalf("expected valid remote span context, got %+v", sc)
+	}
+	if got := sc.TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("trace id = %s", got)
+	}
+}
+
+func TestContextWithTraceID_IgnoresInvalidAndExisting(t *testing.T) {
+	if sc := trace.SpanContextFromContext(ContextWithTraceID(context.Background(), "not-a-trace")); sc.IsValid() {
+		t.Fatal("invalid trace id should leave context unchanged")
+	}
+
+	h := http.Header{}
+	h.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	ctx := ExtractHTTP(context.Background(), h)
+	ctx = ContextWithTraceID(ctx, "4bf92f3577b34da6a3ce929d0e0e4736")
+	if got := trace.SpanContextFromContext(ctx).TraceID().String(); got != "0af7651916cd43dd8448eb211c80319c" {
+		t.Fatalf("inbound traceparent should win, got %s", got)
+	}
+}
+
+func TestInjectHTTP_RoundTrip(t *testing.T) {
+	ctx := ContextWithTraceID(context.Background(), "4bf92f3577b34da6a3ce929d0e0e4736")
+	h := http.Header{}
+	InjectHTTP(ctx, h)
+	if h.Get("traceparent") == "" {
+		t.Fatal("expected traceparent header")
+	}
+	got := trace.SpanContextFromContext(ExtractHTTP(context.Background(), h))
+	if got.TraceID() != trace.SpanContextFromContext(ctx).TraceID() {
+		t.Fatalf("round trip trace id mismatch: %s", got.TraceID())
+	}
+}
@@ -0,0 +1,70 @@
+package pgpool
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// RegisterMetrics publishes utilisation of pool, labelled pool=name, read
+// from pool.Stat() on each collection:
+//
+//	oc.db.pool.connections{state=acquired|idle|constructing}
+//	oc.db.pool.max_connections
+//	oc.db.pool.acquires            (cumulative)
+//	oc.db.pool.empty_acquires      (cumulative; acquires that had to wait)
+//	oc.db.pool.canceled_acquires   (cumulative)
+//	oc.db.pool.acquire_wait        (cumulative seconds spent waiting)
+func RegisterMetrics(pool *pgxpool.Pool, name string) error {
+	meter := otel.Meter("github.com/bturcanu/OpenClause/pkg/pgpool")
+	poolAttr := attribute.String("pool", name)
+
+	conns, err := meter.Int64ObservableGauge("oc.db.pool.connections",
+		metric.WithDescription("Pool connections by state."))
+	if err != nil {
+		return err
+	}
+	maxConns, err := meter.Int64ObservableGauge("oc.db.pool.max_connections",
+		metric.WithDescription("Configured maximum pool size."))
+	if err != nil {
+		return err
+	}
+	acquires, err := meter.Int64ObservableCounter("oc.db.pool.acquires",
+		metric.WithDescription("Successful connection acquires."))
+	if err != nil {
+		return err
+	}
+	emptyAcquires, err := meter.Int64ObservableCounter("oc.db.pool.empty_acquires",
+		metric.WithDescription("Acquires that waited because no idle connection was available."))
+	if err != nil {
+		return err
+	}
+	canceledAcquires, err := meter.Int64ObservableCounter("oc.db.pool.canceled_acquires",
+		metric.WithDescription("Acquires canceled by their context."))
+	if err != nil {
+		return err
+	}
+	acquireWait, err := meter.Float64ObservableCounter("oc.db.pool.acquire_wait",
+		metric.WithUnit("s"),
+		metric.WithDescription("Total time spent waiting for a connection."))
+	if err != nil {
+		return err
+	}
+
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		st := pool.Stat()
+		o.ObserveInt64(conns, int64(st.AcquiredConns()), metric.WithAttributes(poolAttr, attribute.String("state", "acquired")))
+		o.ObserveInt64(conns, int64(st.IdleConns()), metric.WithAttributes(poolAttr, attribute.String("state", "idle")))
+		o.ObserveInt64(conns, int64(st.ConstructingConns()), metric.WithAttributes(poolAttr, attribute.String("state", "constructing")))
+		o.ObserveInt64(maxConns, int64(st.MaxConns()), metric.WithAttributes(poolAttr))
+		o.ObserveInt64(acquires, st.AcquireCount(), metric.WithAttributes(poolAttr))
+		o.ObserveInt64(emptyAcquires, st.EmptyAcquireCount(), metric.WithAttributes(poolAttr))
+		o.ObserveInt64(canceledAcquires, st.CanceledAcquireCount(), metric.WithAttributes(poolAttr))
+		o.ObserveFloat64(acquireWait, st.EmptyAcquireWaitTime().Seconds(), metric.WithAttributes(poolAttr))
+		return nil
+	}, conns, maxConns, acquires, emptyAcquires, canceledAcquires, acquireWait)
+	return err
+}
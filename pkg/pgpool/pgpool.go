@@ -0,0 +1,93 @@
+// Package pgpool builds the Postgres connection pools shared by all services
+// from environment configuration and publishes pool utilisation metrics.
+package pgpool
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/config"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Config tunes a pgxpool. Zero values keep the pgx defaults (max conns =
+// max(4, GOMAXPROCS), 1h lifetime, 30m idle, 1m health check).
+type Config struct {
+	MaxConns          int32
+	MinConns          int32
+	MaxConnLifetime   time.Duration
+	MaxConnIdleTime   time.Duration
+	HealthCheckPeriod time.Duration
+	ConnectTimeout    time.Duration
+}
+
+// ConfigFromEnv reads the PG_POOL_* variables from env.
+func ConfigFromEnv(env config.Env) Config {
+	return Config{
+		MaxConns:          int32(env.Int("PG_POOL_MAX_CONNS", 0)),
+		MinConns:          int32(env.Int("PG_POOL_MIN_CONNS", 0)),
+		MaxConnLifetime:   env.Duration("PG_POOL_MAX_CONN_LIFETIME_SEC", time.Second, 0),
+		MaxConnIdleTime:   env.Duration("PG_POOL_MAX_CONN_IDLE_SEC", time.Second, 0),
+		HealthCheckPeriod: env.Duration("PG_POOL_HEALTH_CHECK_SEC", time.Second, 0),
+		ConnectTimeout:    env.Duration("PG_CONNECT_TIMEOUT_SEC", time.Second, 0),
+	}
+}
+
+// DSNFromEnv builds a connection URL from the POSTGRES_* variables in env.
+func DSNFromEnv(env config.Env) string {
+	u := &url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(env.Or("POSTGRES_USER", "openclause"), env.Or("POSTGRES_PASSWORD", "changeme")),
+		Host:     net.JoinHostPort(env.Or("POSTGRES_HOST", "localhost"), env.Or("POSTGRES_PORT", "5432")),
+		Path:     env.Or("POSTGRES_DB", "openclause"),
+		RawQuery: "sslmode=" + url.QueryEscape(env.Or("POSTGRES_SSLMODE", "disable")),
+	}
+	return u.String()
+}
+
+// New parses dsn, applies cfg, and creates the pool. Like pgxpool.New it
+// does not wait for a connection; use Ping for readiness.
+func New(ctx context.Context, dsn string, cfg Config) (*pgxpool.Pool, error) {
+	pc, err := ParseConfig(dsn, cfg)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := pgxpool.NewWithConfig(ctx, pc)
+	if err != nil {
+		return nil, fmt.Errorf("pgpool.New: %w", err)
+	}
+	return pool, nil
+}
+
+// ParseConfig returns the pgxpool configuration for dsn with cfg applied.
+func ParseConfig(dsn string, cfg Config) (*pgxpool.Config, error) {
+	pc, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("pgpool.ParseConfig: %w", err)
+	}
+	if cfg.MaxConns > 0 {
+		pc.MaxConns = cfg.MaxConns
+	}
+	if cfg.MinConns > 0 {
+		pc.MinConns = cfg.MinConns
+	}
+	if pc.MinConns > pc.MaxConns {
+		return nil, fmt.Errorf("pgpool.ParseConfig: min conns (%d) exceeds max conns (%d)", pc.MinConns, pc.MaxConns)
+	}
+	if cfg.MaxConnLifetime > 0 {
+		pc.MaxConnLifetime = cfg.MaxConnLifetime
+	}
+	if cfg.MaxConnIdleTime > 0 {
+		pc.MaxConnIdleTime = cfg.MaxConnIdleTime
+	}
+	if cfg.HealthCheckPeriod > 0 {
+		pc.HealthCheckPeriod = cfg.HealthCheckPeriod
+	}
+	if cfg.ConnectTimeout > 0 {
+		pc.ConnConfig.ConnectTimeout = cfg.ConnectTimeout
+	}
+	return pc, nil
+}
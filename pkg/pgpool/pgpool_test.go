@@ -0,0 +1,83 @@
+package pgpool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+const testDSN = "postgres://oc:pw@127.0.0.1:1/openclause?sslmode=disable"
+
+func TestParseConfig_AppliesOverrides(t *testing.T) {
+	pc, err := ParseConfig(testDSN, Config{
+		MaxConns:          40,
+		MinConns:          5,
+		MaxConnLifetime:   10 * time.Minute,
+		MaxConnIdleTime:   2 * time.Minute,
+		HealthCheckPeriod: 15 * time.Second,
+		ConnectTimeout:    3 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pc.MaxConns != 40 || pc.MinConns != 5 {
+		t.Errorf("conns = %d/%d, want 5/40", pc.MinConns, pc.MaxConns)
+	}
+	if pc.MaxConnLifetime != 10*time.Minute || pc.MaxConnIdleTime != 2*time.Minute {
+		t.Errorf("lifetimes = %v/%v", pc.MaxConnLifetime, pc.MaxConnIdleTime)
+	}
+	if pc.HealthCheckPeriod != 15*time.Second || pc.ConnConfig.ConnectTimeout != 3*time.Second {
+		t.Errorf("health/connect = %v/%v", pc.HealthCheckPeriod, pc.ConnConfig.ConnectTimeout)
+	}
+}
+
+func TestParseConfig_ZeroKeepsDefaultsAndRejectsMinAboveMax(t *testing.T) {
+	def, err := ParseConfig(testDSN, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if def.MaxConns <= 0 || def.HealthCheckPeriod <= 0 {
+		t.Fatalf("expected pgx defaults, got max=%d health=%v", def.MaxConns, def.HealthCheckPeriod)
+	}
+	if _, err := ParseConfig(testDSN, Config{MaxConns: 2, MinConns: 3}); err == nil {
+		t.Fatal("expected error when min conns exceeds max conns")
+	}
+}
+
+func TestRegisterMetrics_ReportsMaxConns(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+
+	pool, err := New(context.Background(), testDSN, Config{MaxConns: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	if err := RegisterMetrics(pool, "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != "oc.db.pool.max_connections" {
+				continue
+			}
+			dp := m.Data.(metricdata.Gauge[int64]).DataPoints[0]
+			name, _ := dp.Attributes.Value(attribute.Key("pool"))
+			if dp.Value != 7 || name.AsString() != "test" {
+				t.Fatalf("max_connections = %d pool=%q", dp.Value, name.AsString())
+			}
+			return
+		}
+	}
+	t.Fatal("oc.db.pool.max_connections not collected")
+}
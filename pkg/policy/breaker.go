@@ -0,0 +1,137 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without calling OPA while the circuit breaker
+// is open after repeated failures.
+var ErrCircuitOpen = errors.New("policy: circuit open, OPA unavailable")
+
+// RetryPolicy bounds the retries of an evaluation that failed because OPA
+// was unreachable or returned 5xx. Delays grow exponentially from BaseDelay
+// up to MaxDelay with full jitter, so a burst of calls does not retry in
+// lockstep against a restarting OPA.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first;
+	// 1 or less disables retries.
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// DefaultRetryPolicy is the policy a new client uses.
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: 50 * time.Millisecond, MaxDelay: time.Second}
+
+// Circuit breaker defaults for a new client.
+const (
+	DefaultBreakerThreshold = 5
+	DefaultBreakerCooldown  = 10 * time.Second
+)
+
+// delay is the wait before attempt+1.
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	backoff := p.MaxDelay
+	if p.BaseDelay > 0 && attempt < 32 {
+		if b := p.BaseDelay << (attempt - 1); b > 0 && b < p.MaxDelay {
+			backoff = b
+		}
+	}
+	if backoff <= 0 {
+		return 0
+	}
+	return rand.N(backoff + 1)
+}
+
+// sleep waits d, or returns false if ctx ends first or its deadline leaves
+// no time for another attempt after the wait.
+func sleep(ctx context.Context, d time.Duration) bool {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= d {
+		return false
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+// unavailableError marks a failure that says OPA is down or overloaded:
+// a transport error or a 5xx. Only these are retried and trip the breaker;
+// a 4xx or an undecodable result would fail again the same way.
+type unavailableError struct{ err error }
+
+func (e *unavailableError) Error() string { return e.err.Error() }
+func (e *unavailableError) Unwrap() error { return e.err }
+
+func isUnavailable(err error) bool {
+	var u *unavailableError
+	return errors.As(err, &u)
+}
+
+// breaker is a consecutive-failure circuit breaker. After threshold
+// evaluations in a row find OPA unavailable it opens, failing evaluations
+// fast for cooldown; then one probe is let through, which closes it on
+// success or reopens it on failure.
+type breaker struct {
+	mu        sync.Mutex
+	threshold int // 0 disables the breaker
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// allow reports whether an evaluation may call OPA now.
+func (b *breaker) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.threshold <= 0 || b.failures < b.threshold {
+		return true
+	}
+	if now.Before(b.openUntil) || b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// success closes the breaker.
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures, b.probing = 0, false
+}
+
+// failure counts an evaluation that found OPA unavailable and reports
+// whether it opened the breaker.
+func (b *breaker) failure(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.threshold <= 0 {
+		return false
+	}
+	probe := b.probing
+	b.probing = false
+	b.failures++
+	if b.failures < b.threshold {
+		return false
+	}
+	b.openUntil = now.Add(b.cooldown)
+	return probe || b.failures == b.threshold
+}
+
+// abandon ends an evaluation whose outcome says nothing about OPA, such
+// as one the caller canceled.
+func (b *breaker) abandon() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
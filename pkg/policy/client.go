@@ -5,32 +5,63 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"time"
 
+	ocOtel "github.com/bturcanu/OpenClause/pkg/otel"
 	"github.com/bturcanu/OpenClause/pkg/types"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const maxOPAResponseBytes = 1 << 20 // 1 MB
 
-// Client calls OPA over HTTP to evaluate tool-call policies.
+var tracer = otel.Tracer("github.com/bturcanu/OpenClause/pkg/policy")
+
+// Client calls OPA over HTTP to evaluate tool-call policies. Evaluations
+// that find OPA unreachable are retried, and a circuit breaker fails them
+// fast while OPA stays down, so callers can fall back without each waiting
+// out its own timeouts.
 type Client struct {
 	baseURL    string
 	httpClient *http.Client
+	retry      RetryPolicy
+	breaker    *breaker
 }
 
-// NewClient creates a new OPA policy client.
+// NewClient creates a new OPA policy client with DefaultRetryPolicy and the
+// default circuit breaker.
 func NewClient(baseURL string) *Client {
 	return &Client{
 		baseURL: baseURL,
 		httpClient: &http.Client{
 			Timeout: 5 * time.Second,
 		},
+		retry:   DefaultRetryPolicy,
+		breaker: &breaker{threshold: DefaultBreakerThreshold, cooldown: DefaultBreakerCooldown},
 	}
 }
 
+// SetRetryPolicy replaces the retry policy. Call it before the client is
+// used.
+func (c *Client) SetRetryPolicy(p RetryPolicy) {
+	c.retry = p
+}
+
+// SetCircuitBreaker opens the breaker after threshold consecutive
+// evaluations find OPA unavailable, for cooldown before a probe is let
+// through; threshold 0 disables it. Call it before the client is used.
+func (c *Client) SetCircuitBreaker(threshold int, cooldown time.Duration) {
+	c.breaker = &breaker{threshold: threshold, cooldown: cooldown}
+}
+
 // opaRequest is the top-level envelope OPA expects.
 type opaRequest struct {
 	Input types.PolicyInput `json:"input"`
@@ -44,28 +75,86 @@ type opaResponse struct {
 type opaResult struct {
 	Decision      string               `json:"decision"`
 	Reason        string               `json:"reason"`
+	ReasonCode    string               `json:"reason_code,omitempty"`
+	MatchedRules  []string             `json:"matched_rules,omitempty"`
 	Requirements  map[string]string    `json:"requirements,omitempty"`
+	RiskOverrides map[string]int       `json:"risk_overrides,omitempty"`
 	Notify        []types.PolicyNotify `json:"notify,omitempty"`
 	ApproverGroup string               `json:"approver_group,omitempty"`
+	// ApprovalTTLSec and GrantTTLSec let a rule give a risk class its own
+	// approval and grant lifetimes.
+	ApprovalTTLSec int `json:"approval_ttl_sec,omitempty"`
+	GrantTTLSec    int `json:"grant_ttl_sec,omitempty"`
 }
 
-// Evaluate sends a PolicyInput to OPA and returns the decision.
-func (c *Client) Evaluate(ctx context.Context, input types.PolicyInput) (*types.PolicyResult, error) {
+// Evaluate sends a PolicyInput to OPA and returns the decision. It returns
+// ErrCircuitOpen without calling OPA while the circuit breaker is open.
+func (c *Client) Evaluate(ctx context.Context, input types.PolicyInput) (_ *types.PolicyResult, err error) {
+	ctx, span := tracer.Start(ctx, "policy.Evaluate", trace.WithAttributes(
+		attribute.String("oc.tool", input.ToolCall.Tool),
+		attribute.String("oc.action", input.ToolCall.Action),
+	))
+	start := time.Now()
+	defer func() {
+		outcome := "ok"
+		switch {
+		case errors.Is(err, ErrCircuitOpen):
+			outcome = "circuit_open"
+		case isUnavailable(err):
+			outcome = "unavailable"
+		case err != nil:
+			outcome = "error"
+		}
+		evalDuration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(attribute.String("outcome", outcome)))
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
 	body, err := json.Marshal(opaRequest{Input: input})
 	if err != nil {
 		return nil, fmt.Errorf("policy marshal: %w", err)
 	}
+	if !c.breaker.allow(time.Now()) {
+		return nil, ErrCircuitOpen
+	}
+
+	var res *types.PolicyResult
+	for attempt := 1; ; attempt++ {
+		res, err = c.evaluateOnce(ctx, body)
+		if err == nil || !isUnavailable(err) || attempt >= c.retry.MaxAttempts || !sleep(ctx, c.retry.delay(attempt)) {
+			break
+		}
+		evalRetries.Add(ctx, 1)
+		span.AddEvent("retry", trace.WithAttributes(attribute.Int("attempt", attempt+1)))
+	}
+	switch {
+	case err == nil || !isUnavailable(err):
+		// OPA answered, even if with an error: it is up.
+		c.breaker.success()
+	case ctx.Err() != nil:
+		c.breaker.abandon()
+	case c.breaker.failure(time.Now()):
+		circuitOpens.Add(ctx, 1)
+	}
+	return res, err
+}
 
+// evaluateOnce makes a single evaluation request.
+func (c *Client) evaluateOnce(ctx context.Context, body []byte) (*types.PolicyResult, error) {
 	url := c.baseURL + "/v1/data/oc/main"
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("policy new request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
+	ocOtel.InjectHTTP(ctx, req.Header)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("policy request: %w", err)
+		return nil, &unavailableError{fmt.Errorf("policy request: %w", err)}
 	}
 	defer resp.Body.Close()
 
@@ -73,26 +162,112 @@ func (c *Client) Evaluate(ctx context.Context, input types.PolicyInput) (*types.
 
 	if resp.StatusCode != http.StatusOK {
 		b, _ := io.ReadAll(limited)
-		return nil, fmt.Errorf("policy OPA returned %d: %s", resp.StatusCode, string(b))
+		err := fmt.Errorf("policy OPA returned %d: %s", resp.StatusCode, string(b))
+		if resp.StatusCode >= 500 {
+			return nil, &unavailableError{err}
+		}
+		return nil, err
 	}
 
 	var opaResp opaResponse
 	if err := json.NewDecoder(limited).Decode(&opaResp); err != nil {
 		return nil, fmt.Errorf("policy decode response: %w", err)
 	}
+	return opaResp.Result.policyResult(), nil
+}
 
-	decision := types.Decision(opaResp.Result.Decision)
+// policyResult converts r to the gateway's result. A decision other than
+// allow, deny or approve, including an undefined one, becomes a deny.
+func (r opaResult) policyResult() *types.PolicyResult {
+	decision := types.Decision(r.Decision)
 	if !isValidDecision(decision) {
 		decision = types.DecisionDeny
 	}
 
+	reasonCode := r.ReasonCode
+	if !types.ValidReasonCode(reasonCode) {
+		reasonCode = ""
+	}
+	if decision != types.Decision(r.Decision) {
+		reasonCode = types.ReasonCodeInvalidDecision
+	}
+
 	return &types.PolicyResult{
 		Decision:      decision,
-		Reason:        opaResp.Result.Reason,
-		Requirements:  opaResp.Result.Requirements,
-		Notify:        opaResp.Result.Notify,
-		ApproverGroup: opaResp.Result.ApproverGroup,
-	}, nil
+		Reason:        r.Reason,
+		ReasonCode:    reasonCode,
+		MatchedRules:  matchedRules(r.MatchedRules),
+		Requirements:  r.Requirements,
+		RiskOverrides: r.RiskOverrides,
+		Notify:        r.Notify,
+		ApproverGroup: r.ApproverGroup,
+
+		ApprovalTTLSec: approvalTTLSec(r.ApprovalTTLSec),
+		GrantTTLSec:    approvalTTLSec(r.GrantTTLSec),
+	}
+}
+
+// approvalTTLSec holds a policy's approval_ttl_sec or grant_ttl_sec to the
+// bounds tenant settings are held to. Zero or negative means unset.
+func approvalTTLSec(sec int) int {
+	if sec <= 0 {
+		return 0
+	}
+	return min(max(sec, int(types.MinApprovalTTL.Seconds())), int(types.MaxApprovalTTL.Seconds()))
+}
+
+// PutTenantData writes doc to data.tenants[tenantID], the per-tenant
+// document the bundle reads (max_risk_auto_approve, approver_group, notify).
+// OPA must load the bundle from files rather than as a signed bundle, since
+// bundle-owned roots reject data API writes.
+func (c *Client) PutTenantData(ctx context.Context, tenantID string, doc json.RawMessage) error {
+	return c.tenantData(ctx, http.MethodPut, tenantID, doc)
+}
+
+// DeleteTenantData removes data.tenants[tenantID]. A missing document is
+// not an error.
+func (c *Client) DeleteTenantData(ctx context.Context, tenantID string) error {
+	return c.tenantData(ctx, http.MethodDelete, tenantID, nil)
+}
+
+func (c *Client) tenantData(ctx context.Context, method, tenantID string, doc json.RawMessage) error {
+	var body io.Reader
+	if doc != nil {
+		body = bytes.NewReader(doc)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+"/v1/data/tenants/"+url.PathEscape(tenantID), body)
+	if err != nil {
+		return fmt.Errorf("policy tenant data request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("policy tenant data: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound && method == http.MethodDelete {
+		return nil
+	}
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, maxOPAResponseBytes))
+		return fmt.Errorf("policy tenant data: OPA returned %d: %s", resp.StatusCode, string(b))
+	}
+	return nil
+}
+
+// matchedRules keeps the well-formed rule identifiers, up to
+// types.MaxMatchedRules.
+func matchedRules(ids []string) []string {
+	var out []string
+	for _, id := range ids {
+		if len(out) == types.MaxMatchedRules {
+			break
+		}
+		if types.ValidMatchedRule(id) {
+			out = append(out, id)
+		}
+	}
+	return out
 }
 
 func isValidDecision(d types.Decision) bool {
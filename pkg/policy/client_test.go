@@ -3,10 +3,15 @@ package policy
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	ocOtel "github.com/bturcanu/OpenClause/pkg/otel"
 	"github.com/bturcanu/OpenClause/pkg/types"
 )
 
@@ -36,6 +41,46 @@ func TestEvaluate_AllowDecision(t *testing.T) {
 	}
 }
 
+func TestEvaluate_RiskOverrides(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"result":{"decision":"approve","reason":"prod","risk_overrides":{"prod_resource":3}}}`))
+	}))
+	defer srv.Close()
+
+	client := NewClient(srv.URL)
+	result, err := client.Evaluate(context.Background(), types.PolicyInput{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.RiskOverrides["prod_resource"] != 3 {
+		t.Errorf("expected risk override prod_resource=3, got %v", result.RiskOverrides)
+	}
+}
+
+func TestEvaluate_TTLs(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"result":{"decision":"approve","reason":"prod","approval_ttl_sec":3600,"grant_ttl_sec":-5}}`))
+	}))
+	defer srv.Close()
+
+	client := NewClient(srv.URL)
+	result, err := client.Evaluate(context.Background(), types.PolicyInput{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.ApprovalTTLSec != 3600 || result.GrantTTLSec != 0 {
+		t.Errorf("expected approval_ttl_sec=3600 and grant_ttl_sec=0, got %d and %d", result.ApprovalTTLSec, result.GrantTTLSec)
+	}
+
+	for sec, want := range map[int]int{1: 60, 60: 60, 100 * 24 * 3600: 30 * 24 * 3600} {
+		if got := approvalTTLSec(sec); got != want {
+			t.Errorf("approvalTTLSec(%d) = %d, want %d", sec, got, want)
+		}
+	}
+}
+
 func TestEvaluate_DefaultDenyOnEmptyDecision(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		resp := map[string]any{
@@ -77,8 +122,25 @@ func TestEvaluate_DefaultDenyOnUnknownDecision(t *testing.T) {
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if result.Decision != types.DecisionDeny {
-		t.Errorf("expected deny for unknown decision, got %s", result.Decision)
+	if result.Decision != types.DecisionDeny || result.ReasonCode != types.ReasonCodeInvalidDecision {
+		t.Errorf("expected deny with invalid_decision for unknown decision, got %s (%s)", result.Decision, result.ReasonCode)
+	}
+}
+
+func TestEvaluate_ReasonCodeAndMatchedRules(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"result": {"decision": "approve", "reason": "high risk", "reason_code": "high_risk",
+			"matched_rules": ["risk.high", "bad rule", "allowlist.write"]}}`))
+	}))
+	defer srv.Close()
+
+	result, err := NewClient(srv.URL).Evaluate(context.Background(), types.PolicyInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ReasonCode != "high_risk" || len(result.MatchedRules) != 2 || result.MatchedRules[1] != "allowlist.write" {
+		t.Fatalf("result = %+v", result)
 	}
 }
 
@@ -95,3 +157,120 @@ func TestEvaluate_NonOKStatus(t *testing.T) {
 		t.Fatal("expected error for non-200 status")
 	}
 }
+
+func TestEvaluate_RetriesUnavailable(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/bad/"):
+			w.WriteHeader(http.StatusBadRequest)
+		case calls.Add(1) < 3:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			_, _ = w.Write([]byte(`{"result":{"decision":"allow"}}`))
+		}
+	}))
+	defer srv.Close()
+
+	client := NewClient(srv.URL)
+	client.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond})
+	result, err := client.Evaluate(context.Background(), types.PolicyInput{})
+	if err != nil || result.Decision != types.DecisionAllow || calls.Load() != 3 {
+		t.Fatalf("result = %+v, %v after %d calls", result, err, calls.Load())
+	}
+
+	// A 4xx is not retried.
+	client = NewClient(srv.URL + "/bad")
+	client.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond})
+	calls.Store(0)
+	if _, err := client.Evaluate(context.Background(), types.PolicyInput{}); err == nil || calls.Load() != 0 {
+		t.Fatalf("4xx: err = %v after %d counted calls", err, calls.Load())
+	}
+}
+
+func TestEvaluate_CircuitBreaker(t *testing.T) {
+	var calls atomic.Int32
+	var down atomic.Bool
+	down.Store(true)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if down.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(`{"result":{"decision":"allow"}}`))
+	}))
+	defer srv.Close()
+
+	client := NewClient(srv.URL)
+	client.SetRetryPolicy(RetryPolicy{MaxAttempts: 1})
+	client.SetCircuitBreaker(2, 50*time.Millisecond)
+	ctx := context.Background()
+	for range 2 {
+		if _, err := client.Evaluate(ctx, types.PolicyInput{}); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("err = %v, want OPA error", err)
+		}
+	}
+	if _, err := client.Evaluate(ctx, types.PolicyInput{}); !errors.Is(err, ErrCircuitOpen) || calls.Load() != 2 {
+		t.Fatalf("open breaker: err = %v after %d calls", err, calls.Load())
+	}
+
+	// After the cooldown a failed probe reopens it; a successful one closes it.
+	time.Sleep(60 * time.Millisecond)
+	if _, err := client.Evaluate(ctx, types.PolicyInput{}); err == nil || errors.Is(err, ErrCircuitOpen) || calls.Load() != 3 {
+		t.Fatalf("probe: err = %v after %d calls", err, calls.Load())
+	}
+	if _, err := client.Evaluate(ctx, types.PolicyInput{}); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("after failed probe: err = %v", err)
+	}
+	down.Store(false)
+	time.Sleep(60 * time.Millisecond)
+	for range 3 {
+		if _, err := client.Evaluate(ctx, types.PolicyInput{}); err != nil {
+			t.Fatalf("recovered: %v", err)
+		}
+	}
+}
+
+func TestEvaluate_PropagatesTraceparent(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("traceparent")
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"result": map[string]any{"decision": "allow"}})
+	}))
+	defer srv.Close()
+
+	ctx := ocOtel.ContextWithTraceID(context.Background(), "4bf92f3577b34da6a3ce929d0e0e4736")
+	if _, err := NewClient(srv.URL).Evaluate(ctx, types.PolicyInput{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(got, "4bf92f3577b34da6a3ce929d0e0e4736") {
+		t.Fatalf("traceparent = %q", got)
+	}
+}
+
+func TestTenantData_PutAndDelete(t *testing.T) {
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Method+" "+r.URL.Path)
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	client := NewClient(srv.URL)
+	if err := client.PutTenantData(context.Background(), "acme", json.RawMessage(`{"approver_group":"ops"}`)); err != nil {
+		t.Fatalf("PutTenantData: %v", err)
+	}
+	if err := client.DeleteTenantData(context.Background(), "acme"); err != nil {
+		t.Fatalf("DeleteTenantData on missing doc: %v", err)
+	}
+	want := []string{"PUT /v1/data/tenants/acme", "DELETE /v1/data/tenants/acme"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("requests = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,167 @@
+package policy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/rego"
+	"github.com/open-policy-agent/opa/v1/storage"
+	"github.com/open-policy-agent/opa/v1/storage/inmem"
+	"github.com/open-policy-agent/opa/v1/util"
+)
+
+// Embedded evaluates a policy bundle in process with OPA's Go API, so lite
+// mode needs no OPA server. It queries the same entrypoint as Client and
+// keeps the bundle's data document, and the tenant documents pushed through
+// PutTenantData, in an in-memory store, where OPA would hold them.
+type Embedded struct {
+	store storage.Store
+	query rego.PreparedEvalQuery
+}
+
+// NewEmbedded compiles modules, keyed by file name, against the data
+// document data, such as bundles.DefaultModules and bundles.DefaultData.
+func NewEmbedded(modules map[string]string, data []byte) (*Embedded, error) {
+	doc := map[string]any{}
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err := util.UnmarshalJSON(data, &doc); err != nil {
+			return nil, fmt.Errorf("policy.NewEmbedded: data: %w", err)
+		}
+	}
+	if _, ok := doc["tenants"]; !ok {
+		doc["tenants"] = map[string]any{}
+	}
+	store := inmem.NewFromObject(doc)
+
+	opts := []func(*rego.Rego){rego.Query("data." + Entrypoint), rego.Store(store)}
+	for name, src := range modules {
+		opts = append(opts, rego.Module(name, src))
+	}
+	query, err := rego.New(opts...).PrepareForEval(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("policy.NewEmbedded: %w", err)
+	}
+	return &Embedded{store: store, query: query}, nil
+}
+
+// Evaluate evaluates the entrypoint for input and reads the result as
+// Client does an OPA response.
+func (e *Embedded) Evaluate(ctx context.Context, input types.PolicyInput) (*types.PolicyResult, error) {
+	rs, err := e.query.Eval(ctx, rego.EvalInput(input))
+	if err != nil {
+		return nil, fmt.Errorf("policy embedded evaluate: %w", err)
+	}
+	var result opaResult
+	if len(rs) > 0 && len(rs[0].Expressions) > 0 {
+		raw, err := json.Marshal(rs[0].Expressions[0].Value)
+		if err != nil {
+			return nil, fmt.Errorf("policy embedded result: %w", err)
+		}
+		if err := json.Unmarshal(raw, &result); err != nil {
+			return nil, fmt.Errorf("policy embedded result: %w", err)
+		}
+	}
+	return result.policyResult(), nil
+}
+
+// PutTenantData replaces data.tenants[tenantID], as Client.PutTenantData
+// does in OPA.
+func (e *Embedded) PutTenantData(ctx context.Context, tenantID string, doc json.RawMessage) error {
+	var v any
+	if err := util.UnmarshalJSON(doc, &v); err != nil {
+		return fmt.Errorf("policy tenant data: %w", err)
+	}
+	if err := storage.WriteOne(ctx, e.store, storage.AddOp, storage.Path{"tenants", tenantID}, v); err != nil {
+		return fmt.Errorf("policy tenant data: %w", err)
+	}
+	return nil
+}
+
+// DeleteTenantData removes data.tenants[tenantID]. A missing document is
+// not an error.
+func (e *Embedded) DeleteTenantData(ctx context.Context, tenantID string) error {
+	err := storage.WriteOne(ctx, e.store, storage.RemoveOp, storage.Path{"tenants", tenantID}, nil)
+	if err != nil && !storage.IsNotFound(err) {
+		return fmt.Errorf("policy tenant data: %w", err)
+	}
+	return nil
+}
+
+// DryRun compiles b's modules and evaluates its entrypoint against
+// LintInputs, as Client.DryRun does in OPA, without replacing the active
+// policy. Modules read the data the engine holds now, not the bundle's
+// data.json. Compile errors and results that break the output contract
+// are returned as issues.
+func (e *Embedded) DryRun(ctx context.Context, b *Bundle) ([]LintIssue, error) {
+	opts := []func(*rego.Rego){rego.Query("data." + Entrypoint), rego.Store(e.store)}
+	for name, src := range b.Modules {
+		opts = append(opts, rego.Module(name, src))
+	}
+	query, err := rego.New(opts...).PrepareForEval(ctx)
+	if issues := regoCompileIssues(err); len(issues) > 0 {
+		return issues, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("policy.DryRun: %w", err)
+	}
+	return checkLintInputs(ctx, func(ctx context.Context, input types.PolicyInput) (map[string]json.RawMessage, string, error) {
+		rs, err := query.Eval(ctx, rego.EvalInput(input))
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, "", err
+			}
+			// Evaluation errors, such as conflicting rule values.
+			return nil, err.Error(), nil
+		}
+		result := map[string]json.RawMessage{}
+		if len(rs) > 0 && len(rs[0].Expressions) > 0 {
+			raw, err := json.Marshal(rs[0].Expressions[0].Value)
+			if err != nil {
+				return nil, "", err
+			}
+			if err := json.Unmarshal(raw, &result); err != nil {
+				return nil, "", err
+			}
+		}
+		return result, "", nil
+	})
+}
+
+// regoCompileIssues returns the parse and compile errors in err, the
+// error of preparing a query, as issues. It returns nil for any other
+// error.
+func regoCompileIssues(err error) []LintIssue {
+	var errs []error
+	var re rego.Errors
+	if errors.As(err, &re) {
+		errs = re
+	} else if err != nil {
+		errs = []error{err}
+	}
+	var issues []LintIssue
+	add := func(ae *ast.Error) {
+		is := LintIssue{Severity: LintError, Message: ae.Message}
+		if ae.Location != nil {
+			is.File, is.Line = ae.Location.File, ae.Location.Row
+		}
+		issues = append(issues, is)
+	}
+	for _, err := range errs {
+		var aes ast.Errors
+		var ae *ast.Error
+		switch {
+		case errors.As(err, &aes):
+			for _, ae := range aes {
+				add(ae)
+			}
+		case errors.As(err, &ae):
+			add(ae)
+		}
+	}
+	return issues
+}
@@ -0,0 +1,209 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"github.com/bturcanu/OpenClause/policy/bundles"
+)
+
+// TestEmbedded_MatchesBundleTests runs the cases of policy/tests/main_test.rego
+// through the embedded engine.
+func TestEmbedded_MatchesBundleTests(t *testing.T) {
+	e, err := NewEmbedded(bundles.DefaultModules, bundles.DefaultData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	budget := func(exceeded, wouldExceed bool) []types.BudgetState {
+		return []types.BudgetState{{Spent: 4, Remaining: 6, Exceeded: exceeded, WouldExceed: wouldExceed}}
+	}
+	injection := []types.InjectionFinding{{Rule: "instruction_override", Path: "/text", Match: "ignore all previous instructions"}}
+	for _, tc := range []struct {
+		name, tenant, tool, action string
+		risk                       int
+		budgets                    []types.BudgetState
+		injection                  []types.InjectionFinding
+		want                       types.Decision
+		reason                     string
+	}{
+		{"low risk read", "tenant1", "jira", "issue.list", 1, nil, nil, types.DecisionAllow, "read action on allowlist within tenant threshold"},
+		{"high risk", "tenant1", "slack", "msg.post", 8, nil, nil, types.DecisionApprove, "high risk score requires approval"},
+		{"destructive", "tenant1", "jira", "issue.delete", 3, nil, nil, types.DecisionApprove, "destructive action requires approval"},
+		{"unknown action", "tenant1", "unknown", "do.something", 2, nil, nil, types.DecisionDeny, "action not in allowlist"},
+		{"moderate write", "tenant1", "slack", "msg.post", 4, nil, nil, types.DecisionAllow, "write action on allowlist within tenant threshold"},
+		{"destructive high risk", "tenant1", "jira", "issue.delete", 8, nil, nil, types.DecisionApprove, "high risk score requires approval"},
+		{"read at risk 4", "tenant1", "jira", "issue.list", 4, nil, nil, types.DecisionAllow, ""},
+		{"write above threshold", "tenant1", "slack", "msg.post", 6, nil, nil, types.DecisionDeny, ""},
+		{"approve at risk 7", "tenant1", "slack", "msg.post", 7, nil, nil, types.DecisionApprove, ""},
+		{"tenant2 within threshold", "tenant2", "jira", "issue.list", 2, nil, nil, types.DecisionAllow, ""},
+		{"tenant2 at threshold", "tenant2", "jira", "issue.list", 3, nil, nil, types.DecisionDeny, ""},
+		{"unknown tenant default threshold", "unknown-tenant", "slack", "msg.post", 6, nil, nil, types.DecisionAllow, ""},
+		{"within budget", "tenant1", "jira", "issue.list", 1, budget(false, false), nil, types.DecisionAllow, ""},
+		{"budget exceeded", "tenant1", "jira", "issue.list", 1, budget(true, true), nil, types.DecisionDeny, "budget exceeded"},
+		{"budget would exceed", "tenant1", "jira", "issue.list", 1, budget(false, true), nil, types.DecisionDeny, "budget exceeded"},
+		{"suspected injection", "tenant1", "slack", "msg.post", 1, nil, injection, types.DecisionApprove, "possible prompt injection in params requires approval"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := e.Evaluate(context.Background(), types.PolicyInput{
+				ToolCall:  types.ToolCallRequest{TenantID: tc.tenant, Tool: tc.tool, Action: tc.action, RiskScore: tc.risk},
+				Budgets:   tc.budgets,
+				Injection: tc.injection,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Decision != tc.want || (tc.reason != "" && res.Reason != tc.reason) {
+				t.Errorf("got %s %q, want %s %q", res.Decision, res.Reason, tc.want, tc.reason)
+			}
+			if (tc.injection != nil) != (res.RiskOverrides["prompt_injection"] == 3) {
+				t.Errorf("risk overrides = %v", res.RiskOverrides)
+			}
+		})
+	}
+}
+
+// TestEmbedded_ReasonCodes mirrors the bundle's reason code tests. matched_rules
+// is a set in Rego, so it comes back sorted.
+func TestEmbedded_ReasonCodes(t *testing.T) {
+	e, err := NewEmbedded(bundles.DefaultModules, bundles.DefaultData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name  string
+		input types.PolicyInput
+		code  string
+		rules []string
+	}{
+		{"injection", types.PolicyInput{
+			ToolCall:  types.ToolCallRequest{TenantID: "tenant1", Tool: "slack", Action: "msg.post", RiskScore: 1},
+			Injection: []types.InjectionFinding{{Rule: "instruction_override"}},
+		}, types.ReasonCodePromptInjection, []string{"allowlist.write", "injection.suspected"}},
+		{"budget", types.PolicyInput{
+			ToolCall: types.ToolCallRequest{TenantID: "tenant1", Tool: "jira", Action: "issue.list", RiskScore: 1},
+			Budgets:  []types.BudgetState{{Exceeded: true, WouldExceed: true}},
+		}, types.ReasonCodeBudgetExceeded, []string{"allowlist.read", "budget.exceeded"}},
+		{"not allowlisted", types.PolicyInput{
+			ToolCall: types.ToolCallRequest{TenantID: "tenant1", Tool: "unknown", Action: "do", RiskScore: 1},
+		}, types.ReasonCodeNotAllowlisted, nil},
+	} {
+		res, err := e.Evaluate(context.Background(), tc.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.ReasonCode != tc.code || !slices.Equal(res.MatchedRules, tc.rules) {
+			t.Errorf("%s: code %q rules %v, want %q %v", tc.name, res.ReasonCode, res.MatchedRules, tc.code, tc.rules)
+		}
+	}
+}
+
+func TestEmbedded_ApprovalRoutingAndTenantData(t *testing.T) {
+	e, err := NewEmbedded(bundles.DefaultModules, bundles.DefaultData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	input := types.PolicyInput{ToolCall: types.ToolCallRequest{TenantID: "tenant1", Tool: "jira", Action: "issue.delete", RiskScore: 3}}
+
+	res, _ := e.Evaluate(ctx, input)
+	if res.Requirements["approval_scope"] != "single_use" || res.ApproverGroup != "security" || len(res.Notify) != 2 {
+		t.Fatalf("approve result = %+v", res)
+	}
+
+	if err := e.PutTenantData(ctx, "tenant1", json.RawMessage(`{"max_risk_auto_approve":2,"approver_group":"sre"}`)); err != nil {
+		t.Fatal(err)
+	}
+	res, _ = e.Evaluate(ctx, input)
+	if res.ApproverGroup != "sre" || len(res.Notify) != 0 {
+		t.Fatalf("after put = %+v", res)
+	}
+	input.ToolCall.Action, input.ToolCall.RiskScore = "issue.list", 2
+	if res, _ := e.Evaluate(ctx, input); res.Decision != types.DecisionDeny {
+		t.Fatalf("threshold not applied: %+v", res)
+	}
+
+	if err := e.DeleteTenantData(ctx, "tenant1"); err != nil {
+		t.Fatal(err)
+	}
+	if res, _ := e.Evaluate(ctx, input); res.Decision != types.DecisionAllow {
+		t.Fatalf("after delete want default threshold: %+v", res)
+	}
+	if err := e.PutTenantData(ctx, "tenant1", json.RawMessage(`[`)); err == nil {
+		t.Fatal("expected error for malformed document")
+	}
+}
+
+func TestEmbedded_EvaluatesCustomModules(t *testing.T) {
+	e, err := NewEmbedded(map[string]string{"main.rego": `package oc.main
+
+import rego.v1
+
+default decision := "deny"
+
+decision := "approve" if input.toolcall.tool == data.review.tool
+
+reason := "reviewed tool"
+
+approver_group := data.tenants[input.toolcall.tenant_id].approver_group
+`}, []byte(`{"review":{"tool":"github"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := e.PutTenantData(ctx, "t1", json.RawMessage(`{"approver_group":"platform"}`)); err != nil {
+		t.Fatal(err)
+	}
+	res, err := e.Evaluate(ctx, types.PolicyInput{ToolCall: types.ToolCallRequest{TenantID: "t1", Tool: "github", Action: "repo.delete"}})
+	if err != nil || res.Decision != types.DecisionApprove || res.Reason != "reviewed tool" || res.ApproverGroup != "platform" {
+		t.Fatalf("custom module = %+v, %v", res, err)
+	}
+	if err := e.DeleteTenantData(ctx, "t2"); err != nil {
+		t.Fatalf("deleting a missing document: %v", err)
+	}
+
+	if _, err := NewEmbedded(map[string]string{"main.rego": "package oc.main\n\ndecision := "}, nil); err == nil {
+		t.Fatal("expected a compile error")
+	}
+}
+
+func TestEmbedded_DryRun(t *testing.T) {
+	e, err := NewEmbedded(bundles.DefaultModules, bundles.DefaultData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issues, err := e.DryRun(t.Context(), &Bundle{Modules: bundles.DefaultModules})
+	if err != nil || len(issues) != 0 {
+		t.Fatalf("default bundle: %+v, %v", issues, err)
+	}
+
+	b := &Bundle{Modules: map[string]string{
+		"main.rego": "package oc.main\n\nimport data.oc.lib\n\ndecision := lib.pick\n",
+		"lib.rego":  "package oc.lib\n\npick := \"escalate\"\n",
+	}}
+	issues, err = e.DryRun(t.Context(), b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) == 0 || !strings.Contains(issues[0].Message, `"escalate"`) || issues[0].Input == "" {
+		t.Fatalf("escalate issues = %+v", issues)
+	}
+
+	b.Modules["lib.rego"] = "package oc.lib\n\npick :=\n"
+	issues, err = e.DryRun(t.Context(), b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) == 0 || issues[0].File != "lib.rego" || issues[0].Line == 0 || issues[0].Input != "" {
+		t.Fatalf("compile issues = %+v", issues)
+	}
+
+	// The active policy is untouched.
+	res, err := e.Evaluate(t.Context(), types.PolicyInput{ToolCall: types.ToolCallRequest{TenantID: "t", Tool: "slack", Action: "channel.list", RiskScore: 1}})
+	if err != nil || res.Decision != types.DecisionAllow {
+		t.Fatalf("after dry runs: %+v, %v", res, err)
+	}
+}
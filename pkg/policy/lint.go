@@ -0,0 +1,616 @@
+package policy
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/approvals"
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+// Entrypoint is the package the gateway queries for decisions.
+const Entrypoint = "oc.main"
+
+const (
+	maxBundleBytes   = 4 << 20 // uncompressed
+	maxBundleModules = 200
+)
+
+// Lint issue severities. A bundle with an error issue is not valid.
+const (
+	LintError   = "error"
+	LintWarning = "warning"
+)
+
+// Bundle is a policy bundle as OPA loads it: Rego modules by path and the
+// root data.json, if any.
+type Bundle struct {
+	Modules map[string]string
+	Data    json.RawMessage
+}
+
+// LintIssue is one problem found in a bundle. File and Line point into the
+// bundle where the issue has a location; Input names the sample input that
+// produced it for issues found by evaluation.
+type LintIssue struct {
+	Severity string `json:"severity"`
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Input    string `json:"input,omitempty"`
+	Message  string `json:"message"`
+}
+
+// LintReport is the outcome of validating a bundle. Compiled reports
+// whether the bundle went through the policy engine's DryRun, which
+// compiles the modules and, if they compile, evaluates them against the
+// sample inputs; without it only the static checks ran.
+type LintReport struct {
+	Valid    bool        `json:"valid"`
+	Compiled bool        `json:"compiled"`
+	Issues   []LintIssue `json:"issues"`
+}
+
+// NewLintReport returns the report for issues, sorted by file and line.
+func NewLintReport(issues []LintIssue, compiled bool) LintReport {
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].File != issues[j].File {
+			return issues[i].File < issues[j].File
+		}
+		return issues[i].Line < issues[j].Line
+	})
+	r := LintReport{Valid: true, Compiled: compiled, Issues: issues}
+	if r.Issues == nil {
+		r.Issues = []LintIssue{}
+	}
+	for _, is := range issues {
+		if is.Severity == LintError {
+			r.Valid = false
+		}
+	}
+	return r
+}
+
+// ReadBundle reads a gzipped tar bundle, as built by "opa build" or
+// "occtl policy-validate". Test modules (*_test.rego), the manifest and
+// data files below the root are skipped.
+func ReadBundle(r io.Reader) (*Bundle, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("policy.ReadBundle: %w", err)
+	}
+	defer gz.Close()
+	b := &Bundle{Modules: map[string]string{}}
+	tr := tar.NewReader(io.LimitReader(gz, maxBundleBytes+1))
+	var total int64
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("policy.ReadBundle: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := strings.TrimPrefix(path.Clean("/"+hdr.Name), "/")
+		isModule := strings.HasSuffix(name, ".rego") && !strings.HasSuffix(name, "_test.rego")
+		if !isModule && name != "data.json" {
+			continue
+		}
+		body, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("policy.ReadBundle: %s: %w", name, err)
+		}
+		if total += int64(len(body)); total > maxBundleBytes {
+			return nil, fmt.Errorf("policy.ReadBundle: bundle exceeds %d bytes", maxBundleBytes)
+		}
+		if !isModule {
+			b.Data = body
+			continue
+		}
+		if len(b.Modules) == maxBundleModules {
+			return nil, fmt.Errorf("policy.ReadBundle: bundle has more than %d modules", maxBundleModules)
+		}
+		b.Modules[name] = string(body)
+	}
+	return b, nil
+}
+
+// ReadBundleDir reads a bundle from the directory dir: its .rego files,
+// other than tests (*_test.rego), and its data.json, as "occtl
+// policy-validate" packs them.
+func ReadBundleDir(dir string) (*Bundle, error) {
+	b := &Bundle{Modules: map[string]string{}}
+	root := os.DirFS(dir)
+	err := fs.WalkDir(root, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		isModule := strings.HasSuffix(name, ".rego") && !strings.HasSuffix(name, "_test.rego")
+		if !isModule && name != "data.json" {
+			return nil
+		}
+		body, err := fs.ReadFile(root, name)
+		if err != nil {
+			return err
+		}
+		if !isModule {
+			b.Data = body
+			return nil
+		}
+		if len(b.Modules) == maxBundleModules {
+			return fmt.Errorf("bundle has more than %d modules", maxBundleModules)
+		}
+		b.Modules[name] = string(body)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("policy.ReadBundleDir: %w", err)
+	}
+	return b, nil
+}
+
+var (
+	regoPackage = regexp.MustCompile(`^package\s+([A-Za-z_][A-Za-z0-9_.]*)`)
+	regoRule    = regexp.MustCompile(`^(default\s+)?([A-Za-z_][A-Za-z0-9_]*)(.*)$`)
+	regoLiteral = regexp.MustCompile(`^\s*:?=\s*"([^"]*)"`)
+	regoElse    = regexp.MustCompile(`^\s*\}?\s*else\s*:?=\s*"([^"]*)"`)
+	regoSetHead = regexp.MustCompile(`^\s*(contains\b|\[)`)
+)
+
+// Lint checks b without evaluating it: the entrypoint package must exist
+// and define decision and reason, and every literal decision must be one
+// the gateway acts on. Decisions computed from data are only caught by
+// evaluation; see Client.DryRun and Embedded.DryRun.
+func Lint(b *Bundle) []LintIssue {
+	var issues []LintIssue
+	if len(b.Modules) == 0 {
+		return []LintIssue{{Severity: LintError, Message: "bundle has no Rego modules"}}
+	}
+	if len(b.Data) > 0 {
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(b.Data, &obj); err != nil || obj == nil {
+			issues = append(issues, LintIssue{Severity: LintError, File: "data.json", Message: "must be a JSON object"})
+		}
+	}
+
+	var entry []string
+	defined := map[string]bool{}
+	for name, src := range b.Modules {
+		lines := regoLines(src)
+		pkg := ""
+		for _, line := range lines {
+			if m := regoPackage.FindStringSubmatch(line); m != nil {
+				pkg = m[1]
+				break
+			}
+		}
+		if pkg == "" {
+			issues = append(issues, LintIssue{Severity: LintError, File: name, Message: "module has no package declaration"})
+			continue
+		}
+		if pkg != Entrypoint {
+			continue
+		}
+		entry = append(entry, name)
+		rule := ""
+		for i, line := range lines {
+			literal := ""
+			if m := regoElse.FindStringSubmatch(line); m != nil {
+				literal = m[1]
+			} else if m := regoRule.FindStringSubmatch(line); m != nil && m[2] != "package" && m[2] != "import" {
+				rule = m[2]
+				if m[1] != "" {
+					defined["default "+rule] = true
+				}
+				defined[rule] = true
+				if rule == "decision" && regoSetHead.MatchString(m[3]) {
+					issues = append(issues, LintIssue{Severity: LintError, File: name, Line: i + 1,
+						Message: "decision must be a single value, not a set"})
+				}
+				if l := regoLiteral.FindStringSubmatch(m[3]); l != nil {
+					literal = l[1]
+				} else {
+					continue
+				}
+			} else {
+				continue
+			}
+			if rule == "decision" && !isValidDecision(types.Decision(literal)) {
+				issues = append(issues, LintIssue{Severity: LintError, File: name, Line: i + 1,
+					Message: fmt.Sprintf("decision %q is not allow, deny or approve; the gateway would deny the call", literal)})
+			}
+		}
+	}
+	if len(entry) == 0 {
+		return append(issues, LintIssue{Severity: LintError,
+			Message: "no module declares package " + Entrypoint + ", the entrypoint the gateway queries"})
+	}
+	sort.Strings(entry)
+	for _, rule := range []string{"decision", "reason"} {
+		if !defined[rule] {
+			issues = append(issues, LintIssue{Severity: LintError, File: entry[0],
+				Message: fmt.Sprintf("package %s defines no %s rule", Entrypoint, rule)})
+		}
+	}
+	if defined["decision"] && !defined["default decision"] {
+		issues = append(issues, LintIssue{Severity: LintWarning, File: entry[0],
+			Message: `no "default decision"; calls no rule matches get no decision and are denied`})
+	}
+	return issues
+}
+
+// regoLines splits src into lines with comments removed, leaving string
+// contents alone.
+func regoLines(src string) []string {
+	lines := strings.Split(src, "\n")
+	inRaw := false
+	for i, line := range lines {
+		var out strings.Builder
+		inStr := false
+		for j := 0; j < len(line); j++ {
+			c := line[j]
+			switch {
+			case inRaw:
+				inRaw = c != '`'
+			case inStr:
+				if c == '\\' && j+1 < len(line) {
+					out.WriteByte(c)
+					j++
+					c = line[j]
+				} else if c == '"' {
+					inStr = false
+				}
+			case c == '#':
+				j = len(line)
+				continue
+			case c == '"':
+				inStr = true
+			case c == '`':
+				inRaw = true
+			}
+			out.WriteByte(c)
+		}
+		lines[i] = out.String()
+	}
+	return lines
+}
+
+// LintInput is a sample input the bundle is evaluated against.
+type LintInput struct {
+	Name  string
+	Input types.PolicyInput
+}
+
+// LintInputs are the sample inputs DryRun evaluates: a read, a write, a
+// destructive action, a high-risk call, a prompt-injection finding and a
+// spent budget.
+func LintInputs(now time.Time) []LintInput {
+	call := func(tool, action string, risk int) types.ToolCallRequest {
+		return types.ToolCallRequest{
+			TenantID: "oc-lint", AgentID: "oc-lint", Tool: tool, Action: action,
+			IdempotencyKey: "oc-lint", RiskScore: risk, RequestedAt: now, SchemaVersion: types.SchemaVersion11,
+		}
+	}
+	env := types.PolicyEnvironment{Timestamp: now}
+	return []LintInput{
+		{"read", types.PolicyInput{ToolCall: call("jira", "issue.list", 1), Environment: env}},
+		{"write", types.PolicyInput{ToolCall: call("slack", "msg.post", 2), Environment: env}},
+		{"destructive", types.PolicyInput{ToolCall: call("jira", "issue.delete", 3), Environment: env}},
+		{"high_risk", types.PolicyInput{ToolCall: call("slack", "msg.post", 9), Environment: env}},
+		{"injection", types.PolicyInput{ToolCall: call("slack", "msg.post", 2), Environment: env,
+			Injection: []types.InjectionFinding{{Rule: "instruction_override", Path: "/text", Match: "ignore all previous instructions"}}}},
+		{"over_budget", types.PolicyInput{ToolCall: call("slack", "msg.post", 2), Environment: env,
+			Budgets: []types.BudgetState{types.NewBudgetState(types.Budget{Period: types.BudgetPeriodDay, Limit: 10}, 12, 0)}}},
+	}
+}
+
+// CheckResult checks one evaluation of the entrypoint against the output
+// contract the gateway reads: decision, reason, reason_code, matched_rules,
+// notify, approver_group, requirements, risk_overrides and the TTLs, each
+// of the right shape.
+// result is nil when the entrypoint was undefined for the input.
+func CheckResult(input string, result map[string]json.RawMessage) []LintIssue {
+	var issues []LintIssue
+	add := func(severity, format string, args ...any) {
+		issues = append(issues, LintIssue{Severity: severity, Input: input, Message: fmt.Sprintf(format, args...)})
+	}
+
+	var decision string
+	switch raw, ok := result["decision"]; {
+	case !ok:
+		add(LintWarning, "decision is undefined; the gateway denies the call")
+	case json.Unmarshal(raw, &decision) != nil:
+		add(LintError, "decision must be a string, got %s", raw)
+	case !isValidDecision(types.Decision(decision)):
+		add(LintError, "decision %q is not allow, deny or approve; the gateway would deny the call", decision)
+	}
+	var reason string
+	if raw, ok := result["reason"]; !ok {
+		add(LintWarning, "reason is undefined")
+	} else if json.Unmarshal(raw, &reason) != nil {
+		add(LintError, "reason must be a string, got %s", raw)
+	}
+	var code string
+	if raw, ok := result["reason_code"]; ok && (json.Unmarshal(raw, &code) != nil || (code != "" && !types.ValidReasonCode(code))) {
+		add(LintError, "reason_code must be lowercase snake_case of at most 64 bytes, got %s", raw)
+	}
+	var rules []string
+	if raw, ok := result["matched_rules"]; ok {
+		if json.Unmarshal(raw, &rules) != nil {
+			add(LintError, "matched_rules must be a list of strings, got %s", raw)
+		}
+		for _, id := range rules {
+			if !types.ValidMatchedRule(id) {
+				add(LintWarning, "matched_rules entry %q is not a valid rule identifier and is dropped", id)
+			}
+		}
+	}
+	if raw, ok := result["notify"]; ok {
+		var routes []types.PolicyNotify
+		if err := json.Unmarshal(raw, &routes); err != nil {
+			add(LintError, "notify must be a list of routes: %v", err)
+		}
+		for i, route := range routes {
+			if err := approvals.ValidateNotifyRoute(route); err != nil {
+				add(LintError, "notify[%d]: %v", i, err)
+			}
+		}
+	}
+	var s string
+	if raw, ok := result["approver_group"]; ok && json.Unmarshal(raw, &s) != nil {
+		add(LintError, "approver_group must be a string, got %s", raw)
+	}
+	var strs map[string]string
+	if raw, ok := result["requirements"]; ok && json.Unmarshal(raw, &strs) != nil {
+		add(LintError, "requirements must be an object of strings, got %s", raw)
+	}
+	var ints map[string]int
+	if raw, ok := result["risk_overrides"]; ok && json.Unmarshal(raw, &ints) != nil {
+		add(LintError, "risk_overrides must be an object of integers, got %s", raw)
+	}
+	for _, key := range []string{"approval_ttl_sec", "grant_ttl_sec"} {
+		var n int
+		if raw, ok := result[key]; ok && json.Unmarshal(raw, &n) != nil {
+			add(LintError, "%s must be an integer, got %s", key, raw)
+		}
+	}
+	return issues
+}
+
+// opaErrors is the body OPA returns when it rejects a module.
+type opaErrors struct {
+	Message string `json:"message"`
+	Errors  []struct {
+		Message  string `json:"message"`
+		Location *struct {
+			File string `json:"file"`
+			Row  int    `json:"row"`
+		} `json:"location"`
+	} `json:"errors"`
+}
+
+// DryRun compiles b's modules in OPA and evaluates its entrypoint against
+// LintInputs, without touching the active policy: every package is moved
+// under a scratch root for the run, and removed again afterwards. Modules
+// read the data OPA holds now, not the bundle's data.json. Compile errors
+// and results that break the output contract are returned as issues; the
+// error is for OPA being unreachable or failing.
+func (c *Client) DryRun(ctx context.Context, b *Bundle) ([]LintIssue, error) {
+	var nonce [6]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return nil, fmt.Errorf("policy.DryRun: %w", err)
+	}
+	root := "oc_lint_" + hex.EncodeToString(nonce[:])
+
+	packages := map[string]bool{}
+	for _, src := range b.Modules {
+		for _, line := range regoLines(src) {
+			if m := regoPackage.FindStringSubmatch(line); m != nil {
+				packages[m[1]] = true
+				break
+			}
+		}
+	}
+	pending := make([]string, 0, len(b.Modules))
+	for name := range b.Modules {
+		pending = append(pending, name)
+	}
+	sort.Strings(pending)
+
+	var uploaded []string
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		defer cancel()
+		for _, name := range uploaded {
+			_, _ = c.opaDo(ctx, http.MethodDelete, "/v1/policies/"+root+"/"+name, "", nil)
+		}
+	}()
+
+	// A module may call into one not yet loaded, so loading repeats until
+	// a pass makes no progress; what is left failed on its own.
+	failures := map[string][]LintIssue{}
+	for len(pending) > 0 {
+		var retry []string
+		clear(failures)
+		for _, name := range pending {
+			src := rebaseModule(b.Modules[name], root, packages)
+			_, err := c.opaDo(ctx, http.MethodPut, "/v1/policies/"+root+"/"+name, "text/plain", []byte(src))
+			var rejected *opaRejection
+			switch {
+			case errors.As(err, &rejected):
+				failures[name] = compileIssues(name, root, rejected.body)
+				retry = append(retry, name)
+			case err != nil:
+				return nil, fmt.Errorf("policy.DryRun: load %s: %w", name, err)
+			default:
+				uploaded = append(uploaded, name)
+			}
+		}
+		if len(retry) == len(pending) {
+			break
+		}
+		pending = retry
+	}
+	if len(failures) > 0 {
+		var issues []LintIssue
+		for _, name := range pending {
+			issues = append(issues, failures[name]...)
+		}
+		return issues, nil
+	}
+
+	entry := "/v1/data/" + root + "/" + strings.ReplaceAll(Entrypoint, ".", "/")
+	return checkLintInputs(ctx, func(ctx context.Context, input types.PolicyInput) (map[string]json.RawMessage, string, error) {
+		req, err := json.Marshal(opaRequest{Input: input})
+		if err != nil {
+			return nil, "", err
+		}
+		body, err := c.opaDo(ctx, http.MethodPost, entry, "application/json", req)
+		var rejected *opaRejection
+		if errors.As(err, &rejected) {
+			// Evaluation errors, such as conflicting rule values.
+			return nil, opaMessage(rejected.body), nil
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		var resp struct {
+			Result map[string]json.RawMessage `json:"result"`
+		}
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, "", fmt.Errorf("decode: %w", err)
+		}
+		return resp.Result, "", nil
+	})
+}
+
+// lintEval evaluates a compiled bundle's entrypoint for one sample input.
+// A non-empty problem is an evaluation error in the bundle itself; err is
+// for the engine failing.
+type lintEval func(ctx context.Context, input types.PolicyInput) (result map[string]json.RawMessage, problem string, err error)
+
+// checkLintInputs evaluates every LintInput with eval and checks the
+// results against the output contract. Both engines' DryRun share it.
+func checkLintInputs(ctx context.Context, eval lintEval) ([]LintIssue, error) {
+	var issues []LintIssue
+	for _, in := range LintInputs(time.Now().UTC()) {
+		result, problem, err := eval(ctx, in.Input)
+		if err != nil {
+			return nil, fmt.Errorf("policy.DryRun: evaluate %s: %w", in.Name, err)
+		}
+		if problem != "" {
+			issues = append(issues, LintIssue{Severity: LintError, Input: in.Name, Message: problem})
+			continue
+		}
+		issues = append(issues, CheckResult(in.Name, result)...)
+	}
+	return issues, nil
+}
+
+// opaRejection is a 4xx answer from OPA: the request reached it and was
+// refused, e.g. a module that does not compile.
+type opaRejection struct {
+	status int
+	body   []byte
+}
+
+func (e *opaRejection) Error() string {
+	return fmt.Sprintf("OPA returned %d: %s", e.status, e.body)
+}
+
+// opaDo sends one request to OPA and returns the response body.
+func (c *Client) opaDo(ctx context.Context, method, path, contentType string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxOPAResponseBytes))
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return nil, &opaRejection{status: resp.StatusCode, body: b}
+	case resp.StatusCode/100 != 2:
+		return nil, fmt.Errorf("OPA returned %d: %s", resp.StatusCode, b)
+	}
+	return b, nil
+}
+
+// rebaseModule moves src's package, and its references to the bundle's
+// packages, under root.
+func rebaseModule(src, root string, packages map[string]bool) string {
+	names := make([]string, 0, len(packages))
+	for p := range packages {
+		names = append(names, regexp.QuoteMeta(p))
+	}
+	// Longest first, so oc.main.lib is not read as oc.main.
+	sort.Slice(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+	src = regoPackageLine.ReplaceAllString(src, "${1}"+root+".${2}")
+	if len(names) == 0 {
+		return src
+	}
+	ref := regexp.MustCompile(`\bdata\.(` + strings.Join(names, "|") + `)\b`)
+	return ref.ReplaceAllString(src, "data."+root+".${1}")
+}
+
+var regoPackageLine = regexp.MustCompile(`(?m)^(package\s+)([A-Za-z_])`)
+
+// compileIssues turns OPA's rejection of module name into issues located
+// in the bundle.
+func compileIssues(name, root string, body []byte) []LintIssue {
+	var e opaErrors
+	if json.Unmarshal(body, &e) != nil || len(e.Errors) == 0 {
+		return []LintIssue{{Severity: LintError, File: name, Message: opaMessage(body)}}
+	}
+	issues := make([]LintIssue, 0, len(e.Errors))
+	for _, oe := range e.Errors {
+		is := LintIssue{Severity: LintError, File: name, Message: strings.ReplaceAll(oe.Message, root+".", "")}
+		if oe.Location != nil {
+			is.Line = oe.Location.Row
+			if f := strings.TrimPrefix(oe.Location.File, root+"/"); f != "" && f != oe.Location.File {
+				is.File = f
+			}
+		}
+		issues = append(issues, is)
+	}
+	return issues
+}
+
+// opaMessage is the message of an OPA error body, or the body itself.
+func opaMessage(body []byte) string {
+	var e opaErrors
+	if json.Unmarshal(body, &e) == nil && e.Message != "" {
+		if len(e.Errors) > 0 {
+			return e.Errors[0].Message
+		}
+		return e.Message
+	}
+	return strings.TrimSpace(string(body))
+}
@@ -0,0 +1,219 @@
+package policy
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func tarBundle(t *testing.T, files map[string]string) io.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, body := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(body)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func TestReadBundle(t *testing.T) {
+	b, err := ReadBundle(tarBundle(t, map[string]string{
+		"./main.rego":       "package oc.main",
+		"lib/util.rego":     "package oc.lib",
+		"main_test.rego":    "package oc.main_test",
+		"data.json":         `{"allowlist": {}}`,
+		".manifest":         `{}`,
+		"tenants/data.json": `{}`,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Modules) != 2 || b.Modules["main.rego"] != "package oc.main" || b.Modules["lib/util.rego"] == "" {
+		t.Fatalf("modules = %v", b.Modules)
+	}
+	if string(b.Data) != `{"allowlist": {}}` {
+		t.Fatalf("data = %s", b.Data)
+	}
+	if _, err := ReadBundle(strings.NewReader("package oc.main")); err == nil {
+		t.Fatal("plain text accepted as a bundle")
+	}
+}
+
+func TestLint_DefaultBundle(t *testing.T) {
+	src, err := os.ReadFile("../../policy/bundles/v0/main.rego")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile("../../policy/bundles/v0/data.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issues := Lint(&Bundle{Modules: map[string]string{"main.rego": string(src)}, Data: data}); len(issues) != 0 {
+		t.Fatalf("default bundle issues: %+v", issues)
+	}
+}
+
+func TestLint_Contract(t *testing.T) {
+	cases := []struct {
+		name    string
+		modules map[string]string
+		want    []string // messages of the expected issues, in order
+	}{
+		{"escalate", map[string]string{"main.rego": `package oc.main
+
+default decision := "deny" # or "escalate"
+default reason := "no"
+
+decision := "allow" if input.toolcall.risk_score < 3
+else := "escalate" if {
+	input.toolcall.risk_score > 8 # "escalate"
+}
+
+reason := "escalate"
+`}, []string{`decision "escalate" is not`}},
+		{"missing rules", map[string]string{"main.rego": "package oc.main\n\nallow := true\n"},
+			[]string{"defines no decision rule", "defines no reason rule"}},
+		{"no default", map[string]string{"main.rego": "package oc.main\n\ndecision := \"allow\" if true\nreason := \"ok\"\n"},
+			[]string{`no "default decision"`}},
+		{"set", map[string]string{"main.rego": "package oc.main\n\ndefault decision := \"deny\"\ndecision contains \"allow\" if true\nreason := \"ok\"\n"},
+			[]string{"not a set"}},
+		{"wrong package", map[string]string{"main.rego": "package oc.policy\n\ndefault decision := \"deny\"\n"},
+			[]string{"no module declares package oc.main"}},
+	}
+	for _, tc := range cases {
+		issues := Lint(&Bundle{Modules: tc.modules})
+		if len(issues) != len(tc.want) {
+			t.Errorf("%s: issues = %+v", tc.name, issues)
+			continue
+		}
+		for i, want := range tc.want {
+			if !strings.Contains(issues[i].Message, want) {
+				t.Errorf("%s: issue %d = %+v, want %q", tc.name, i, issues[i], want)
+			}
+		}
+	}
+	if issues := Lint(&Bundle{Modules: cases[0].modules}); issues[0].Line != 7 || issues[0].Severity != LintError {
+		t.Fatalf("escalate issue = %+v, want an error on line 7", issues[0])
+	}
+}
+
+func TestCheckResult(t *testing.T) {
+	var ok map[string]json.RawMessage
+	_ = json.Unmarshal([]byte(`{"decision": "approve", "reason": "r", "approver_group": "sre",
+		"notify": [{"kind": "slack", "channel": "#ops"}], "risk_overrides": {"x": 2}, "approval_ttl_sec": 600}`), &ok)
+	if issues := CheckResult("write", ok); len(issues) != 0 {
+		t.Fatalf("valid result issues: %+v", issues)
+	}
+
+	var bad map[string]json.RawMessage
+	_ = json.Unmarshal([]byte(`{"decision": "escalate", "reason": 3, "reason_code": "Bad Code", "notify": [{"kind": "pager"}],
+		"requirements": {"ticket": 1}, "grant_ttl_sec": "1h"}`), &bad)
+	issues := CheckResult("write", bad)
+	if len(issues) != 6 {
+		t.Fatalf("issues = %+v", issues)
+	}
+	for _, is := range issues {
+		if is.Severity != LintError || is.Input != "write" {
+			t.Errorf("issue = %+v", is)
+		}
+	}
+	if issues := CheckResult("read", nil); len(issues) != 2 || issues[0].Severity != LintWarning {
+		t.Fatalf("undefined result issues = %+v", issues)
+	}
+}
+
+// fakeOPA keeps uploaded policies and answers evaluations of the scratch
+// entrypoint with result.
+type fakeOPA struct {
+	mu       sync.Mutex
+	policies map[string]string
+	deleted  []string
+	reject   string // module text that fails to compile
+	result   string
+}
+
+func (f *fakeOPA) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/v1/policies/") && r.Method == http.MethodPut:
+		b, _ := io.ReadAll(r.Body)
+		id := strings.TrimPrefix(r.URL.Path, "/v1/policies/")
+		if f.reject != "" && strings.Contains(string(b), f.reject) {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"code":"invalid_parameter","message":"error(s) occurred while compiling module(s)","errors":[{"code":"rego_parse_error","message":"unexpected eof token","location":{"file":"` + id + `","row":4,"col":1}}]}`))
+			return
+		}
+		f.policies[id] = string(b)
+	case strings.HasPrefix(r.URL.Path, "/v1/policies/") && r.Method == http.MethodDelete:
+		f.deleted = append(f.deleted, strings.TrimPrefix(r.URL.Path, "/v1/policies/"))
+	case strings.HasPrefix(r.URL.Path, "/v1/data/oc_lint_") && strings.HasSuffix(r.URL.Path, "/oc/main"):
+		_, _ = w.Write([]byte(`{"result": ` + f.result + `}`))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	opa := &fakeOPA{policies: map[string]string{}, result: `{"decision": "escalate", "reason": "r"}`}
+	srv := httptest.NewServer(opa)
+	defer srv.Close()
+	c := NewClient(srv.URL)
+
+	b := &Bundle{Modules: map[string]string{
+		"main.rego": "package oc.main\n\nimport data.oc.lib\n\ndecision := lib.pick\n",
+		"lib.rego":  "package oc.lib\n\npick := \"escalate\"\n",
+	}}
+	issues, err := c.DryRun(t.Context(), b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != len(LintInputs(time.Now())) || !strings.Contains(issues[0].Message, `"escalate"`) || issues[0].Input == "" {
+		t.Fatalf("issues = %+v", issues)
+	}
+	for id, src := range opa.policies {
+		if !strings.HasPrefix(id, "oc_lint_") {
+			t.Fatalf("module loaded outside the scratch root: %s", id)
+		}
+		root := strings.SplitN(id, "/", 2)[0]
+		if strings.HasSuffix(id, "/main.rego") && !strings.Contains(src, "package "+root+".oc.main") || strings.Contains(src, "import data.oc.lib") {
+			t.Fatalf("module not rebased:\n%s", src)
+		}
+	}
+	if len(opa.deleted) != 2 {
+		t.Fatalf("deleted = %v, want both modules removed", opa.deleted)
+	}
+
+	opa.deleted, opa.reject = nil, "pick :="
+	issues, err = c.DryRun(t.Context(), b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 1 || issues[0].File != "lib.rego" || issues[0].Line != 4 || issues[0].Message != "unexpected eof token" {
+		t.Fatalf("compile issues = %+v", issues)
+	}
+	if len(opa.deleted) != 1 {
+		t.Fatalf("deleted = %v, want the module that loaded removed", opa.deleted)
+	}
+}
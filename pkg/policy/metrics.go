@@ -0,0 +1,36 @@
+package policy
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+)
+
+var (
+	evalDuration metric.Float64Histogram
+	evalRetries  metric.Int64Counter
+	circuitOpens metric.Int64Counter
+)
+
+func init() {
+	meter := otel.Meter("github.com/bturcanu/OpenClause/pkg/policy")
+	var err error
+	evalDuration, err = meter.Float64Histogram("oc.policy.eval.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Latency of OPA policy evaluations, including retries, by outcome (ok, error, unavailable, circuit_open)."),
+	)
+	if err != nil {
+		panic(err)
+	}
+	evalRetries, err = meter.Int64Counter("oc.policy.eval.retries",
+		metric.WithDescription("OPA policy evaluations retried after OPA was unreachable or returned 5xx."),
+	)
+	if err != nil {
+		panic(err)
+	}
+	circuitOpens, err = meter.Int64Counter("oc.policy.circuit.opens",
+		metric.WithDescription("Times the OPA circuit breaker opened."),
+	)
+	if err != nil {
+		panic(err)
+	}
+}
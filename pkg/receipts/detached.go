@@ -0,0 +1,60 @@
+package receipts
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ResponseType is the JWS "typ" header of a response signature.
+const ResponseType = "oc-response+jws"
+
+// SignDetached signs body as a JWS with a detached payload (RFC 7515,
+// appendix F): the result is "header..signature", and a verifier rebuilds
+// the signing input from the body it received. The gateway sends it with
+// tool-call responses so systems downstream of an agent can check that a
+// result came from the gateway unaltered.
+func (s *Signer) SignDetached(body []byte) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": alg, "kid": s.kid, "typ": ResponseType})
+	if err != nil {
+		return "", fmt.Errorf("receipts.SignDetached: %w", err)
+	}
+	h := b64.EncodeToString(header)
+	sig := ed25519.Sign(s.key, []byte(h+"."+b64.EncodeToString(body)))
+	return h + ".." + b64.EncodeToString(sig), nil
+}
+
+// VerifyDetached checks a signature from SignDetached against body and
+// keys. body must be the exact bytes received.
+func VerifyDetached(signature string, body []byte, keys JWKS) error {
+	parts := strings.Split(signature, ".")
+	if len(parts) != 3 || parts[1] != "" {
+		return errors.New("signature is not a detached JWS")
+	}
+	rawHeader, err := b64.DecodeString(parts[0])
+	if err != nil {
+		return errors.New("signature header is not base64url")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+		Typ string `json:"typ"`
+	}
+	if err := json.Unmarshal(rawHeader, &header); err != nil {
+		return errors.New("signature header is not JSON")
+	}
+	if header.Alg != alg || header.Typ != ResponseType {
+		return fmt.Errorf("unsupported signature alg %q or typ %q", header.Alg, header.Typ)
+	}
+	pub := keys.publicKey(header.Kid)
+	if pub == nil {
+		return fmt.Errorf("unknown signature key %q", header.Kid)
+	}
+	sig, err := b64.DecodeString(parts[2])
+	if err != nil || !ed25519.Verify(pub, []byte(parts[0]+"."+b64.EncodeToString(body)), sig) {
+		return errors.New("signature is invalid")
+	}
+	return nil
+}
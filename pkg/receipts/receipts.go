@@ -0,0 +1,212 @@
+// Package receipts issues and verifies execution receipts: compact JWS
+// tokens, signed with Ed25519, that bind a tool call's evidence-chain hash
+// to the gateway's decision. Agent frameworks attach them to their own logs
+// as proof that a call was governed, and anyone holding the gateway's JWKS
+// can check them offline. The same key can sign whole response bodies as
+// detached JWS.
+package receipts
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TokenType is the JWS "typ" header of a receipt.
+const TokenType = "oc-receipt+jwt"
+
+const alg = "EdDSA"
+
+var b64 = base64.RawURLEncoding
+
+// Claims are what a receipt attests: the evidence event, its position in the
+// tenant's hash chain, and the decision the gateway recorded for it.
+type Claims struct {
+	EventID  string `json:"event_id"`
+	TenantID string `json:"tenant_id"`
+	Decision string `json:"decision"`
+	Hash     string `json:"hash"`
+	PrevHash string `json:"prev_hash"`
+	// Seq is the event's position in the evidence log, when the store
+	// reports it.
+	Seq      int64 `json:"seq,omitempty"`
+	IssuedAt int64 `json:"iat"`
+}
+
+// JWK is an Ed25519 public key in JWK form (RFC 8037).
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// publicKey returns the Ed25519 key with kid, or nil.
+func (s JWKS) publicKey(kid string) ed25519.PublicKey {
+	for _, k := range s.Keys {
+		if k.Kid == kid && k.Kty == "OKP" && k.Crv == "Ed25519" {
+			if pub, err := b64.DecodeString(k.X); err == nil && len(pub) == ed25519.PublicKeySize {
+				return pub
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// PublicJWK renders pub as a JWK whose kid is its RFC 7638 thumbprint.
+func PublicJWK(pub ed25519.PublicKey) JWK {
+	x := b64.EncodeToString(pub)
+	// RFC 7638: the required members, lexicographically ordered, no spaces.
+	sum := sha256.Sum256([]byte(`{"crv":"Ed25519","kty":"OKP","x":"` + x + `"}`))
+	return JWK{Kty: "OKP", Crv: "Ed25519", X: x, Kid: b64.EncodeToString(sum[:]), Use: "sig", Alg: alg}
+}
+
+// ParsePrivateKey decodes a base64 (standard or URL, padded or not) Ed25519
+// seed (32 bytes) or private key (64 bytes).
+func ParsePrivateKey(s string) (ed25519.PrivateKey, error) {
+	raw, err := decodeBase64(s)
+	if err != nil {
+		return nil, fmt.Errorf("receipts.ParsePrivateKey: %w", err)
+	}
+	switch len(raw) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(raw), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(raw), nil
+	}
+	return nil, fmt.Errorf("receipts.ParsePrivateKey: want a %d-byte seed or %d-byte key, got %d bytes",
+		ed25519.SeedSize, ed25519.PrivateKeySize, len(raw))
+}
+
+// ParsePublicKeys decodes a comma-separated list of base64 Ed25519 public
+// keys.
+func ParsePublicKeys(s string) ([]ed25519.PublicKey, error) {
+	var keys []ed25519.PublicKey
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		raw, err := decodeBase64(part)
+		if err != nil {
+			return nil, fmt.Errorf("receipts.ParsePublicKeys: %w", err)
+		}
+		if len(raw) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("receipts.ParsePublicKeys: want %d-byte keys, got %d bytes", ed25519.PublicKeySize, len(raw))
+		}
+		keys = append(keys, ed25519.PublicKey(raw))
+	}
+	return keys, nil
+}
+
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.TrimRight(strings.TrimSpace(s), "=")
+	if strings.ContainsAny(s, "+/") {
+		return base64.RawStdEncoding.DecodeString(s)
+	}
+	return b64.DecodeString(s)
+}
+
+// Signer signs receipts with one key and publishes it, along with retired
+// keys whose receipts should still verify, as a JWKS. Safe for concurrent use.
+type Signer struct {
+	key  ed25519.PrivateKey
+	kid  string
+	jwks JWKS
+}
+
+// NewSigner creates a signer for key. previous are public keys of earlier
+// signing keys, kept in the JWKS after a rotation.
+func NewSigner(key ed25519.PrivateKey, previous ...ed25519.PublicKey) *Signer {
+	current := PublicJWK(key.Public().(ed25519.PublicKey))
+	s := &Signer{key: key, kid: current.Kid, jwks: JWKS{Keys: []JWK{current}}}
+	for _, pub := range previous {
+		if jwk := PublicJWK(pub); jwk.Kid != current.Kid {
+			s.jwks.Keys = append(s.jwks.Keys, jwk)
+		}
+	}
+	return s
+}
+
+// KeyID is the kid of the current signing key.
+func (s *Signer) KeyID() string { return s.kid }
+
+// JWKS returns the public keys receipts are verified against.
+func (s *Signer) JWKS() JWKS { return s.jwks }
+
+// Sign issues a receipt for c. A zero IssuedAt is set to now.
+func (s *Signer) Sign(c Claims) (string, error) {
+	if c.IssuedAt == 0 {
+		c.IssuedAt = time.Now().Unix()
+	}
+	header, err := json.Marshal(map[string]string{"alg": alg, "kid": s.kid, "typ": TokenType})
+	if err != nil {
+		return "", fmt.Errorf("receipts.Sign: %w", err)
+	}
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("receipts.Sign: %w", err)
+	}
+	signingInput := b64.EncodeToString(header) + "." + b64.EncodeToString(payload)
+	sig := ed25519.Sign(s.key, []byte(signingInput))
+	return signingInput + "." + b64.EncodeToString(sig), nil
+}
+
+// Verify checks the receipt's signature against the signer's JWKS.
+func (s *Signer) Verify(receipt string) (*Claims, error) {
+	return Verify(receipt, s.jwks)
+}
+
+// Verify checks a receipt's signature against keys and returns its claims.
+// It does not check the claims against the evidence log.
+func Verify(receipt string, keys JWKS) (*Claims, error) {
+	parts := strings.Split(receipt, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("receipt is not a compact JWS")
+	}
+	rawHeader, err := b64.DecodeString(parts[0])
+	if err != nil {
+		return nil, errors.New("receipt header is not base64url")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+		Typ string `json:"typ"`
+	}
+	if err := json.Unmarshal(rawHeader, &header); err != nil {
+		return nil, errors.New("receipt header is not JSON")
+	}
+	if header.Alg != alg || header.Typ != TokenType {
+		return nil, fmt.Errorf("unsupported receipt alg %q or typ %q", header.Alg, header.Typ)
+	}
+	pub := keys.publicKey(header.Kid)
+	if pub == nil {
+		return nil, fmt.Errorf("unknown receipt key %q", header.Kid)
+	}
+	sig, err := b64.DecodeString(parts[2])
+	if err != nil || !ed25519.Verify(pub, []byte(parts[0]+"."+parts[1]), sig) {
+		return nil, errors.New("receipt signature is invalid")
+	}
+	payload, err := b64.DecodeString(parts[1])
+	if err != nil {
+		return nil, errors.New("receipt payload is not base64url")
+	}
+	var c Claims
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return nil, errors.New("receipt payload is not valid claims")
+	}
+	return &c, nil
+}
@@ -0,0 +1,114 @@
+package receipts
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func testKey(t *testing.T, b byte) ed25519.PrivateKey {
+	t.Helper()
+	seed := make([]byte, ed25519.SeedSize)
+	seed[0] = b
+	k, err := ParsePrivateKey(base64.StdEncoding.EncodeToString(seed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestSignAndVerify(t *testing.T) {
+	s := NewSigner(testKey(t, 1))
+	claims := Claims{EventID: "e1", TenantID: "t1", Decision: "allow", Hash: "abc", PrevHash: "prev", Seq: 7}
+	receipt, err := s.Sign(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Verify(receipt, s.JWKS())
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if got.EventID != "e1" || got.Hash != "abc" || got.Seq != 7 || got.IssuedAt == 0 {
+		t.Errorf("claims = %+v", got)
+	}
+
+	parts := strings.Split(receipt, ".")
+	forged, _ := (&Signer{key: testKey(t, 2), kid: s.KeyID()}).Sign(claims)
+	for name, bad := range map[string]string{
+		"tampered payload": parts[0] + "." + b64.EncodeToString([]byte(`{"event_id":"e2"}`)) + "." + parts[2],
+		"wrong key":        forged,
+		"not a jws":        "abc",
+	} {
+		if _, err := Verify(bad, s.JWKS()); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestSigner_RotationKeepsPreviousKeys(t *testing.T) {
+	old := NewSigner(testKey(t, 1))
+	receipt, err := old.Sign(Claims{EventID: "e1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated := NewSigner(testKey(t, 2), testKey(t, 1).Public().(ed25519.PublicKey))
+	if len(rotated.JWKS().Keys) != 2 || rotated.KeyID() == old.KeyID() {
+		t.Fatalf("jwks = %+v", rotated.JWKS())
+	}
+	if _, err := rotated.Verify(receipt); err != nil {
+		t.Errorf("receipt from the previous key: %v", err)
+	}
+}
+
+func TestPublicJWK_Thumbprint(t *testing.T) {
+	// RFC 8037 appendix A.3.
+	pub, err := base64.RawURLEncoding.DecodeString("11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := PublicJWK(pub).Kid; got != "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k" {
+		t.Errorf("kid = %s", got)
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	if _, err := ParsePrivateKey("c2hvcnQ"); err == nil {
+		t.Error("short key: expected error")
+	}
+	pub := testKey(t, 1).Public().(ed25519.PublicKey)
+	keys, err := ParsePublicKeys(" " + base64.RawURLEncoding.EncodeToString(pub) + ", ")
+	if err != nil || len(keys) != 1 || !keys[0].Equal(pub) {
+		t.Fatalf("ParsePublicKeys = %v, %v", keys, err)
+	}
+}
+
+func TestSignDetached(t *testing.T) {
+	s := NewSigner(testKey(t, 1))
+	body := []byte(`{"event_id":"e1","decision":"allow"}` + "\n")
+	sig, err := s.SignDetached(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parts := strings.Split(sig, "."); len(parts) != 3 || parts[1] != "" {
+		t.Fatalf("signature %q is not detached", sig)
+	}
+	if err := VerifyDetached(sig, body, s.JWKS()); err != nil {
+		t.Fatalf("VerifyDetached: %v", err)
+	}
+
+	receipt, _ := s.Sign(Claims{EventID: "e1"})
+	for name, tc := range map[string]struct {
+		sig  string
+		body []byte
+		keys JWKS
+	}{
+		"altered body": {sig, []byte(`{"event_id":"e1","decision":"deny"}` + "\n"), s.JWKS()},
+		"unknown key":  {sig, body, NewSigner(testKey(t, 2)).JWKS()},
+		"receipt":      {receipt, body, s.JWKS()},
+	} {
+		if err := VerifyDetached(tc.sig, tc.body, tc.keys); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
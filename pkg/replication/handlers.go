@@ -0,0 +1,108 @@
+// Package replication streams the evidence log from a primary region to a
+// standby. The primary's gateway serves each tenant's chain head and the
+// events after a given hash; the standby's replicator pulls them, verifies
+// every link against its own chain before applying it, and reports whether
+// the standby is ready to take over.
+package replication
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/evidence"
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"github.com/go-chi/chi/v5"
+)
+
+// Bounds on the page of events a standby fetches.
+const (
+	DefaultBatchSize = 100
+	MaxBatchSize     = 500
+)
+
+// Heads is the body of GET /v1/admin/replication/heads.
+type Heads struct {
+	Heads []evidence.ChainHead `json:"heads"`
+	At    time.Time            `json:"at"`
+}
+
+// Events is the body of GET /v1/admin/replication/events. Pending counts
+// every event after after_hash, including those returned.
+type Events struct {
+	Events  []evidence.ReplicatedEvent `json:"events"`
+	Pending int64                      `json:"pending"`
+}
+
+// Handlers serves a primary region's evidence chains to standbys.
+type Handlers struct {
+	store evidence.ReplicaStore
+	log   *slog.Logger
+}
+
+// NewHandlers serves store's chains.
+func NewHandlers(store evidence.ReplicaStore, log *slog.Logger) *Handlers {
+	if log == nil {
+		log = slog.Default()
+	}
+	return &Handlers{store: store, log: log}
+}
+
+// RegisterRoutes mounts the replication source routes; callers wrap them in
+// admin authentication.
+func (h *Handlers) RegisterRoutes(r chi.Router) {
+	r.Get("/v1/admin/replication/heads", h.Heads)
+	r.Get("/v1/admin/replication/events", h.Events)
+}
+
+// Heads is GET /v1/admin/replication/heads: the head of every tenant's
+// chain.
+func (h *Handlers) Heads(w http.ResponseWriter, r *http.Request) {
+	heads, err := h.store.ChainHeads(r.Context())
+	if err != nil {
+		h.log.ErrorContext(r.Context(), "list chain heads failed", "error", err)
+		types.ErrInternal("failed to list chain heads").WriteJSON(w)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(Heads{Heads: heads, At: time.Now().UTC()})
+}
+
+// Events is GET /v1/admin/replication/events?tenant_id=&after_hash=&limit=:
+// the tenant's events after after_hash, in chain order. An after_hash the
+// primary's chain does not contain is a 409.
+func (h *Handlers) Events(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	tenantID := q.Get("tenant_id")
+	if tenantID == "" {
+		types.ErrBadRequest("tenant_id is required").WriteJSON(w)
+		return
+	}
+	limit := DefaultBatchSize
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			types.ErrBadRequest("limit must be a positive integer").WriteJSON(w)
+			return
+		}
+		limit = min(n, MaxBatchSize)
+	}
+	events, pending, err := h.store.ReplicationEvents(r.Context(), tenantID, q.Get("after_hash"), limit)
+	if errors.Is(err, evidence.ErrUnknownChainHash) {
+		types.ErrConflict("after_hash is not in the tenant's chain on this region").WriteJSON(w)
+		return
+	}
+	if err != nil {
+		h.log.ErrorContext(r.Context(), "list replication events failed", "tenant_id", tenantID, "error", err)
+		types.ErrInternal("failed to list events").WriteJSON(w)
+		return
+	}
+	if events == nil {
+		events = []evidence.ReplicatedEvent{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(Events{Events: events, Pending: pending})
+}
@@ -0,0 +1,58 @@
+package replication
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+var appliedEvents metric.Int64Counter
+
+func init() {
+	meter := otel.Meter("github.com/bturcanu/OpenClause/pkg/replication")
+	var err error
+	appliedEvents, err = meter.Int64Counter("oc.replication.applied",
+		metric.WithDescription("Evidence events applied to this standby, by tenant."),
+	)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func recordApplied(ctx context.Context, tenantID string) {
+	appliedEvents.Add(ctx, 1, metric.WithAttributes(attribute.String("tenant", tenantID)))
+}
+
+// RegisterGauges publishes r's per-tenant lag and whether the standby is
+// ready for failover.
+func RegisterGauges(r *Replicator) error {
+	meter := otel.Meter("github.com/bturcanu/OpenClause/pkg/replication")
+	lag, err := meter.Int64ObservableGauge("oc.replication.lag_events",
+		metric.WithDescription("Primary evidence events not yet applied to this standby, by tenant."),
+	)
+	if err != nil {
+		return err
+	}
+	ready, err := meter.Int64ObservableGauge("oc.replication.ready",
+		metric.WithDescription("1 when this standby is ready for failover, else 0."),
+	)
+	if err != nil {
+		return err
+	}
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		st := r.Status(time.Now().UTC())
+		for _, ts := range st.Tenants {
+			o.ObserveInt64(lag, ts.LagEvents, metric.WithAttributes(attribute.String("tenant", ts.TenantID)))
+		}
+		var v int64
+		if st.ReadyForFailover {
+			v = 1
+		}
+		o.ObserveInt64(ready, v)
+		return nil
+	}, lag, ready)
+	return err
+}
@@ -0,0 +1,371 @@
+package replication
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/evidence"
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+// Tenant replication states.
+const (
+	StateInSync     = "in_sync"
+	StateCatchingUp = "catching_up"
+	// StateDiverged means the chains disagree: the standby holds events the
+	// primary does not, or an event failed verification. Nothing more is
+	// applied for the tenant until an operator resolves it.
+	StateDiverged = "diverged"
+	// StateError means the last pass failed for a reason that may pass,
+	// such as the primary or the standby database being unreachable.
+	StateError = "error"
+)
+
+// Defaults for Config.
+const (
+	DefaultMaxStale = time.Minute
+)
+
+// errDiverged marks a 409 from the primary: the standby head is not in its
+// chain.
+var errDiverged = errors.New("the standby head is not in the primary's chain")
+
+// Config configures a Replicator.
+type Config struct {
+	Store evidence.ReplicaStore
+	// PrimaryURL is the primary region's gateway; Token is its
+	// ADMIN_API_TOKEN.
+	PrimaryURL string
+	Token      string
+	// BatchSize is how many events are fetched at once, at most
+	// MaxBatchSize.
+	BatchSize int
+	// MaxLagEvents is how far behind a tenant may be while the standby
+	// still reports ready for failover.
+	MaxLagEvents int64
+	// MaxStale is how old the last successful pass may be while the standby
+	// still reports ready for failover.
+	MaxStale time.Duration
+	Client   *http.Client
+	Logger   *slog.Logger
+}
+
+// Status is what GET /v1/admin/replication/status reports: the standby's
+// view of every tenant and whether it is ready to be promoted.
+type Status struct {
+	Role    string `json:"role"`
+	Primary string `json:"primary"`
+	// ReadyForFailover is false while any Blockers remain.
+	ReadyForFailover bool           `json:"ready_for_failover"`
+	Blockers         []string       `json:"blockers,omitempty"`
+	LastSyncAt       *time.Time     `json:"last_sync_at,omitempty"`
+	LastSuccessAt    *time.Time     `json:"last_success_at,omitempty"`
+	LastError        string         `json:"last_error,omitempty"`
+	Tenants          []TenantStatus `json:"tenants"`
+}
+
+// TenantStatus is one tenant's replication state. LagEvents counts the
+// primary's events not yet applied; LagSeconds is how much older the
+// standby's head is than the primary's.
+type TenantStatus struct {
+	TenantID    string     `json:"tenant_id"`
+	State       string     `json:"state"`
+	PrimarySeq  int64      `json:"primary_seq"`
+	PrimaryHash string     `json:"primary_hash"`
+	AppliedHash string     `json:"applied_hash"`
+	LagEvents   int64      `json:"lag_events"`
+	LagSeconds  float64    `json:"lag_seconds"`
+	Applied     int64      `json:"applied"`
+	AppliedAt   *time.Time `json:"applied_at,omitempty"`
+	Error       string     `json:"error,omitempty"`
+}
+
+// Replicator keeps a standby region's evidence store a verified copy of
+// the primary's. Each pass lists the primary's chain heads and, for every
+// tenant whose head differs from the standby's, fetches and applies the
+// events after the standby's head. ApplyReplicated re-verifies each link,
+// so a tampered or reordered stream is refused rather than stored.
+type Replicator struct {
+	store        evidence.ReplicaStore
+	primary      string
+	token        string
+	batch        int
+	maxLagEvents int64
+	maxStale     time.Duration
+	client       *http.Client
+	log          *slog.Logger
+
+	mu            sync.Mutex
+	tenants       map[string]*TenantStatus
+	lastSyncAt    time.Time
+	lastSuccessAt time.Time
+	lastError     string
+}
+
+// New builds a Replicator from cfg.
+func New(cfg Config) *Replicator {
+	r := &Replicator{
+		store:        cfg.Store,
+		primary:      strings.TrimSuffix(cfg.PrimaryURL, "/"),
+		token:        cfg.Token,
+		batch:        cfg.BatchSize,
+		maxLagEvents: cfg.MaxLagEvents,
+		maxStale:     cfg.MaxStale,
+		client:       cfg.Client,
+		log:          cfg.Logger,
+		tenants:      map[string]*TenantStatus{},
+	}
+	if r.batch <= 0 || r.batch > MaxBatchSize {
+		r.batch = DefaultBatchSize
+	}
+	if r.maxStale <= 0 {
+		r.maxStale = DefaultMaxStale
+	}
+	if r.client == nil {
+		r.client = &http.Client{Timeout: 30 * time.Second}
+	}
+	if r.log == nil {
+		r.log = slog.Default()
+	}
+	return r
+}
+
+// Run syncs every interval until ctx is done.
+func (r *Replicator) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := r.Sync(ctx); err != nil && ctx.Err() == nil {
+			r.log.ErrorContext(ctx, "replication pass failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync runs one pass over every tenant. It returns an error when the pass
+// could not run at all; per-tenant failures are recorded in Status.
+func (r *Replicator) Sync(ctx context.Context) error {
+	now := time.Now().UTC()
+	err := r.sync(ctx)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastSyncAt = now
+	if err != nil {
+		r.lastError = err.Error()
+		return err
+	}
+	r.lastError = ""
+	r.lastSuccessAt = now
+	return nil
+}
+
+func (r *Replicator) sync(ctx context.Context) error {
+	var primary Heads
+	if err := r.get(ctx, "/v1/admin/replication/heads", nil, &primary); err != nil {
+		return fmt.Errorf("replication.Sync primary heads: %w", err)
+	}
+	local, err := r.store.ChainHeads(ctx)
+	if err != nil {
+		return fmt.Errorf("replication.Sync standby heads: %w", err)
+	}
+	standby := make(map[string]evidence.ChainHead, len(local))
+	for _, h := range local {
+		standby[h.TenantID] = h
+	}
+
+	seen := make(map[string]bool, len(primary.Heads))
+	for _, head := range primary.Heads {
+		seen[head.TenantID] = true
+		r.syncTenant(ctx, head, standby[head.TenantID])
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+	// A standby chain the primary does not have at all cannot be extended.
+	for tenantID, h := range standby {
+		if !seen[tenantID] {
+			r.update(tenantID, func(ts *TenantStatus) {
+				ts.State, ts.Error = StateDiverged, "the primary has no events for this tenant"
+				ts.PrimarySeq, ts.PrimaryHash, ts.AppliedHash = 0, "", h.Hash
+			})
+		}
+	}
+	return nil
+}
+
+// syncTenant applies the primary's events after the standby's head until
+// the tenant is caught up or an error stops it.
+func (r *Replicator) syncTenant(ctx context.Context, primary, standby evidence.ChainHead) {
+	tenantID := primary.TenantID
+	r.update(tenantID, func(ts *TenantStatus) {
+		ts.PrimarySeq, ts.PrimaryHash, ts.AppliedHash = primary.Seq, primary.Hash, standby.Hash
+	})
+	head := standby
+	// remaining is how many of the primary's events are still to apply;
+	// -1 until the primary has said.
+	remaining := int64(-1)
+	if head.Hash == primary.Hash {
+		remaining = 0
+	}
+	for remaining != 0 {
+		var page Events
+		q := url.Values{"tenant_id": {tenantID}, "after_hash": {head.Hash}, "limit": {strconv.Itoa(r.batch)}}
+		err := r.get(ctx, "/v1/admin/replication/events", q, &page)
+		if err != nil {
+			r.fail(ctx, tenantID, head, err)
+			return
+		}
+		for i := range page.Events {
+			ev := &page.Events[i]
+			if ev.Request.TenantID != tenantID {
+				r.fail(ctx, tenantID, head, fmt.Errorf("%w: event %s belongs to tenant %s", evidence.ErrChainMismatch, ev.EventID, ev.Request.TenantID))
+				return
+			}
+			if err := r.store.ApplyReplicated(ctx, ev); err != nil {
+				r.fail(ctx, tenantID, head, err)
+				return
+			}
+			head = evidence.ChainHead{TenantID: tenantID, Hash: ev.Hash, ReceivedAt: ev.ReceivedAt}
+			recordApplied(ctx, tenantID)
+			r.update(tenantID, func(ts *TenantStatus) {
+				at := time.Now().UTC()
+				ts.Applied++
+				ts.AppliedAt = &at
+				ts.AppliedHash = head.Hash
+				ts.LagEvents = max(page.Pending-int64(i+1), 0)
+			})
+		}
+		remaining = max(page.Pending-int64(len(page.Events)), 0)
+		if len(page.Events) == 0 {
+			break
+		}
+	}
+
+	r.update(tenantID, func(ts *TenantStatus) {
+		ts.Error = ""
+		ts.LagEvents, ts.LagSeconds = max(remaining, 0), 0
+		if remaining == 0 {
+			ts.State = StateInSync
+			return
+		}
+		ts.State = StateCatchingUp
+		if !head.ReceivedAt.IsZero() {
+			ts.LagSeconds = max(primary.ReceivedAt.Sub(head.ReceivedAt).Seconds(), 0)
+		}
+	})
+}
+
+// fail records err for the tenant, whose head is still head.
+func (r *Replicator) fail(ctx context.Context, tenantID string, head evidence.ChainHead, err error) {
+	state := StateError
+	if errors.Is(err, errDiverged) || errors.Is(err, evidence.ErrChainMismatch) {
+		state = StateDiverged
+	}
+	r.log.ErrorContext(ctx, "tenant replication failed", "tenant_id", tenantID, "state", state, "error", err)
+	r.update(tenantID, func(ts *TenantStatus) {
+		ts.State, ts.Error, ts.AppliedHash = state, err.Error(), head.Hash
+	})
+}
+
+func (r *Replicator) update(tenantID string, fn func(*TenantStatus)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ts := r.tenants[tenantID]
+	if ts == nil {
+		ts = &TenantStatus{TenantID: tenantID}
+		r.tenants[tenantID] = ts
+	}
+	fn(ts)
+}
+
+// get fetches path from the primary into out.
+func (r *Replicator) get(ctx context.Context, path string, q url.Values, out any) error {
+	u := r.primary + path
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Admin-Token", r.token)
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusConflict {
+		return errDiverged
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr types.APIError
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Message != "" {
+			return fmt.Errorf("primary returned %d: %s", resp.StatusCode, apiErr.Message)
+		}
+		return fmt.Errorf("primary returned %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// Status reports replication as of now.
+func (r *Replicator) Status(now time.Time) Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	st := Status{Role: "standby", Primary: r.primary, LastError: r.lastError, Tenants: make([]TenantStatus, 0, len(r.tenants))}
+	if !r.lastSyncAt.IsZero() {
+		at := r.lastSyncAt
+		st.LastSyncAt = &at
+	}
+	switch {
+	case r.lastSuccessAt.IsZero():
+		st.Blockers = append(st.Blockers, "no replication pass has completed")
+	default:
+		at := r.lastSuccessAt
+		st.LastSuccessAt = &at
+		if age := now.Sub(at); age > r.maxStale {
+			st.Blockers = append(st.Blockers, fmt.Sprintf("last successful pass was %s ago", age.Round(time.Second)))
+		}
+	}
+	for _, ts := range r.tenants {
+		st.Tenants = append(st.Tenants, *ts)
+	}
+	slices.SortFunc(st.Tenants, func(a, b TenantStatus) int { return strings.Compare(a.TenantID, b.TenantID) })
+	for _, ts := range st.Tenants {
+		switch {
+		case ts.State == StateDiverged || ts.State == StateError:
+			st.Blockers = append(st.Blockers, fmt.Sprintf("tenant %s: %s: %s", ts.TenantID, ts.State, ts.Error))
+		case ts.LagEvents > r.maxLagEvents:
+			st.Blockers = append(st.Blockers, fmt.Sprintf("tenant %s: %d events behind", ts.TenantID, ts.LagEvents))
+		}
+	}
+	st.ReadyForFailover = len(st.Blockers) == 0
+	return st
+}
+
+// HandleStatus is GET /v1/admin/replication/status on the standby: 200
+// when it is ready for failover, 503 with the blockers otherwise, so a
+// probe or runbook step can gate promotion on it.
+func (r *Replicator) HandleStatus(w http.ResponseWriter, req *http.Request) {
+	st := r.Status(time.Now().UTC())
+	w.Header().Set("Content-Type", "application/json")
+	if !st.ReadyForFailover {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(st)
+}
@@ -0,0 +1,193 @@
+//go:build cgo
+
+package replication
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/auth"
+	"github.com/bturcanu/OpenClause/pkg/evidence"
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"github.com/go-chi/chi/v5"
+)
+
+func openStore(t *testing.T) *evidence.SQLiteStore {
+	t.Helper()
+	s, err := evidence.OpenSQLite(context.Background(), filepath.Join(t.TempDir(), "evidence.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+var seq int
+
+func record(t *testing.T, s *evidence.SQLiteStore, tenantID string, n int) {
+	t.Helper()
+	for range n {
+		seq++
+		req := types.ToolCallRequest{
+			TenantID: tenantID, AgentID: "a1", Tool: "slack", Action: "msg.post",
+			Params: json.RawMessage(`{}`), IdempotencyKey: fmt.Sprintf("k%d", seq), RequestedAt: time.Now().UTC(),
+		}
+		payload, _ := json.Marshal(req)
+		env := &types.ToolCallEnvelope{
+			EventID: fmt.Sprintf("e%d", seq), Request: req, PayloadJSON: payload,
+			ReceivedAt: time.Now().UTC(), Decision: types.DecisionAllow,
+			PolicyResult:    &types.PolicyResult{Decision: types.DecisionAllow, Reason: "ok"},
+			ExecutionResult: &types.ExecutionResult{Status: "success", OutputJSON: json.RawMessage(`{"ok":true}`)},
+		}
+		if err := s.RecordEvent(context.Background(), env); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func servePrimary(t *testing.T, s evidence.ReplicaStore) string {
+	t.Helper()
+	r := chi.NewRouter()
+	r.Group(func(r chi.Router) {
+		r.Use(auth.AdminAuth("admin"))
+		NewHandlers(s, nil).RegisterRoutes(r)
+	})
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func statusCode(r *Replicator) (int, Status) {
+	rec := httptest.NewRecorder()
+	r.HandleStatus(rec, httptest.NewRequest(http.MethodGet, "/v1/admin/replication/status", nil))
+	var st Status
+	_ = json.NewDecoder(rec.Body).Decode(&st)
+	return rec.Code, st
+}
+
+func TestReplicator_CatchesUpAndReportsReady(t *testing.T) {
+	primary, standby := openStore(t), openStore(t)
+	ctx := context.Background()
+	record(t, primary, "t1", 5)
+	record(t, primary, "t2", 2)
+
+	r := New(Config{Store: standby, PrimaryURL: servePrimary(t, primary), Token: "admin", BatchSize: 2})
+	if code, st := statusCode(r); code != http.StatusServiceUnavailable || st.ReadyForFailover {
+		t.Fatalf("before first pass: %d %+v", code, st)
+	}
+	if err := r.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	code, st := statusCode(r)
+	if code != http.StatusOK || !st.ReadyForFailover || len(st.Tenants) != 2 {
+		t.Fatalf("after pass: %d %+v", code, st)
+	}
+	for _, ts := range st.Tenants {
+		if ts.State != StateInSync || ts.LagEvents != 0 || ts.AppliedHash != ts.PrimaryHash {
+			t.Fatalf("tenant %+v", ts)
+		}
+	}
+	if st.Tenants[0].Applied != 5 || st.Tenants[1].Applied != 2 {
+		t.Fatalf("applied = %d, %d", st.Tenants[0].Applied, st.Tenants[1].Applied)
+	}
+
+	// Later events are picked up from the standby's head.
+	record(t, primary, "t1", 3)
+	if err := r.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	want, _ := primary.ChainHeads(ctx)
+	got, _ := standby.ChainHeads(ctx)
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("standby heads %+v, primary %+v", got, want)
+	}
+	events, err := standby.GetChainEvents(ctx, "t1", 0)
+	if err != nil || len(events) != 8 {
+		t.Fatalf("standby t1 chain = %d events, %v", len(events), err)
+	}
+	if err := evidence.VerifyChain(events); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReplicator_DivergedStandbyBlocksFailover(t *testing.T) {
+	primary, standby := openStore(t), openStore(t)
+	ctx := context.Background()
+	record(t, primary, "t1", 2)
+
+	r := New(Config{Store: standby, PrimaryURL: servePrimary(t, primary), Token: "admin"})
+	if err := r.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// A write accepted by the standby forks its chain from the primary's.
+	record(t, standby, "t1", 1)
+	record(t, primary, "t1", 1)
+	if err := r.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	code, st := statusCode(r)
+	if code != http.StatusServiceUnavailable || st.ReadyForFailover {
+		t.Fatalf("diverged: %d %+v", code, st)
+	}
+	if len(st.Tenants) != 1 || st.Tenants[0].State != StateDiverged {
+		t.Fatalf("tenants = %+v", st.Tenants)
+	}
+}
+
+func TestReplicator_LagAndStalenessBlockFailover(t *testing.T) {
+	primary, standby := openStore(t), openStore(t)
+	record(t, primary, "t1", 1)
+	r := New(Config{Store: standby, PrimaryURL: servePrimary(t, primary), Token: "admin", MaxStale: time.Minute})
+	if err := r.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if st := r.Status(time.Now().UTC().Add(2 * time.Minute)); st.ReadyForFailover || len(st.Blockers) != 1 {
+		t.Fatalf("stale: %+v", st)
+	}
+	r.update("t1", func(ts *TenantStatus) { ts.LagEvents = 3 })
+	if st := r.Status(time.Now().UTC()); st.ReadyForFailover {
+		t.Fatalf("lagging: %+v", st)
+	}
+}
+
+func TestReplicator_BadTokenIsAnError(t *testing.T) {
+	r := New(Config{Store: openStore(t), PrimaryURL: servePrimary(t, openStore(t)), Token: "wrong"})
+	if err := r.Sync(context.Background()); err == nil {
+		t.Fatal("Sync with a bad token succeeded")
+	}
+	if _, st := statusCode(r); st.LastError == "" || st.ReadyForFailover {
+		t.Fatalf("status = %+v", st)
+	}
+}
+
+func TestHandlers_Events(t *testing.T) {
+	primary := openStore(t)
+	record(t, primary, "t1", 1)
+	base := servePrimary(t, primary)
+	for _, tc := range []struct {
+		query string
+		want  int
+	}{
+		{"", http.StatusBadRequest},
+		{"?tenant_id=t1&limit=0", http.StatusBadRequest},
+		{"?tenant_id=t1&after_hash=nope", http.StatusConflict},
+		{"?tenant_id=t1", http.StatusOK},
+	} {
+		req, _ := http.NewRequest(http.MethodGet, base+"/v1/admin/replication/events"+tc.query, nil)
+		req.Header.Set("X-Admin-Token", "admin")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != tc.want {
+			t.Errorf("%s: status %d, want %d", tc.query, resp.StatusCode, tc.want)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+// Package schedule parses cron expressions for tenant-configured schedules.
+package schedule
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron is a parsed five-field cron expression: minute, hour, day of month,
+// month, and day of week. Fields accept *, lists (1,15), ranges (1-5),
+// steps (*/15, 9-17/2), and month and weekday names (jan, mon); weekday 7
+// is Sunday like 0. As in cron, when both day fields are restricted a time
+// matches if either does.
+type Cron struct {
+	expr                         string
+	minute, hour, dom, month     uint64
+	dow                          uint64
+	domRestricted, dowRestricted bool
+}
+
+var (
+	monthNames   = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+	weekdayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+)
+
+// Parse parses a five-field cron expression.
+func Parse(expr string) (*Cron, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron %q: want 5 fields (minute hour day-of-month month day-of-week), got %d", expr, len(fields))
+	}
+	c := &Cron{expr: expr}
+	var err error
+	if c.minute, err = parseField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("cron %q: minute: %w", expr, err)
+	}
+	if c.hour, err = parseField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("cron %q: hour: %w", expr, err)
+	}
+	if c.dom, err = parseField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("cron %q: day of month: %w", expr, err)
+	}
+	if c.month, err = parseField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, fmt.Errorf("cron %q: month: %w", expr, err)
+	}
+	if c.dow, err = parseField(fields[4], 0, 7, weekdayNames); err != nil {
+		return nil, fmt.Errorf("cron %q: day of week: %w", expr, err)
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.domRestricted = fields[2] != "*"
+	c.dowRestricted = fields[4] != "*"
+	return c, nil
+}
+
+// String returns the expression as given.
+func (c *Cron) String() string {
+	return c.expr
+}
+
+// Matches reports whether the minute containing t is one the expression
+// fires on, in t's location.
+func (c *Cron) Matches(t time.Time) bool {
+	if c.minute&(1<<t.Minute()) == 0 || c.hour&(1<<t.Hour()) == 0 || c.month&(1<<int(t.Month())) == 0 {
+		return false
+	}
+	return c.dayMatches(t)
+}
+
+// dayMatches reports whether the expression's day fields allow t's day.
+func (c *Cron) dayMatches(t time.Time) bool {
+	domOK := c.dom&(1<<t.Day()) != 0
+	dowOK := c.dow&(1<<int(t.Weekday())) != 0
+	if c.domRestricted && c.dowRestricted {
+		return domOK || dowOK
+	}
+	return domOK && dowOK
+}
+
+// LastFire returns the latest time at or before t, truncated to the minute,
+// that the expression fires on, looking back no further than within. It
+// returns false when there is none in that span. Months, days and hours
+// the expression skips are stepped over whole, so the search takes a few
+// steps per day in the span rather than one per minute.
+func (c *Cron) LastFire(t time.Time, within time.Duration) (time.Time, bool) {
+	t = t.Truncate(time.Minute)
+	from := t.Add(-within)
+	for !t.Before(from) {
+		loc := t.Location()
+		switch {
+		case c.month&(1<<int(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc).Add(-time.Minute)
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc).Add(-time.Minute)
+		case c.hour&(1<<t.Hour()) == 0:
+			t = t.Add(-time.Duration(t.Minute()+1) * time.Minute)
+		default:
+			// The latest minute of this hour, up to t's, that fires.
+			below := c.minute & (1<<(t.Minute()+1) - 1)
+			if below == 0 {
+				t = t.Add(-time.Duration(t.Minute()+1) * time.Minute)
+				continue
+			}
+			t = t.Add(-time.Duration(t.Minute()-(bits.Len64(below)-1)) * time.Minute)
+			if t.Before(from) {
+				return time.Time{}, false
+			}
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseField parses one comma-separated field into a bit set of the values
+// it allows.
+func parseField(field string, lo, hi int, names []string) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepStr)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepStr)
+			}
+			step = n
+		}
+		from, to := lo, hi
+		if rng != "*" {
+			a, b, isRange := strings.Cut(rng, "-")
+			var err error
+			if from, err = parseValue(a, lo, hi, names); err != nil {
+				return 0, err
+			}
+			to = from
+			if isRange {
+				if to, err = parseValue(b, lo, hi, names); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				to = hi
+			}
+			if from > to {
+				return 0, fmt.Errorf("invalid range %q", rng)
+			}
+		}
+		for v := from; v <= to; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+func parseValue(s string, lo, hi int, names []string) (int, error) {
+	for i, name := range names {
+		if strings.EqualFold(s, name) {
+			// Month names start at 1, weekday names at 0.
+			return i + lo, nil
+		}
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < lo || n > hi {
+		return 0, fmt.Errorf("value %q must be %d-%d", s, lo, hi)
+	}
+	return n, nil
+}
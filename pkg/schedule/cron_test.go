@@ -0,0 +1,94 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCron_Matches(t *testing.T) {
+	// 2026-10-16 is a Friday.
+	at := func(day, hour, minute int) time.Time { return time.Date(2026, 10, day, hour, minute, 0, 0, time.UTC) }
+	for _, tc := range []struct {
+		expr string
+		t    time.Time
+		want bool
+	}{
+		{"* * * * *", at(16, 3, 7), true},
+		{"0 18 * * fri", at(16, 18, 0), true},
+		{"0 18 * * fri", at(16, 18, 1), false},
+		{"0 18 * * 5", at(17, 18, 0), false},
+		{"*/15 9-17 * * mon-fri", at(16, 9, 45), true},
+		{"*/15 9-17 * * mon-fri", at(16, 9, 50), false},
+		{"0 0 * * 7", at(18, 0, 0), true},   // 7 is Sunday
+		{"0 0 1 * mon", at(19, 0, 0), true}, // restricted day fields OR
+		{"0 0 1 * mon", at(20, 0, 0), false},
+		{"30 22 24 dec *", time.Date(2026, 12, 24, 22, 30, 0, 0, time.UTC), true},
+		{"0 9-17/4 * * *", at(16, 13, 0), true},
+		{"0 9-17/4 * * *", at(16, 15, 0), false},
+		{"5/20 * * * *", at(16, 1, 45), true},
+	} {
+		c, err := Parse(tc.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tc.expr, err)
+		}
+		if got := c.Matches(tc.t); got != tc.want {
+			t.Errorf("%q.Matches(%s) = %v, want %v", tc.expr, tc.t, got, tc.want)
+		}
+	}
+}
+
+func TestCron_LastFire(t *testing.T) {
+	c, err := Parse("0 18 * * fri")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opened := time.Date(2026, 10, 16, 18, 0, 0, 0, time.UTC)
+	if got, ok := c.LastFire(opened.Add(63*time.Hour+30*time.Second), 64*time.Hour); !ok || !got.Equal(opened) {
+		t.Errorf("LastFire = %s, %v; want %s", got, ok, opened)
+	}
+	if _, ok := c.LastFire(opened.Add(65*time.Hour), 64*time.Hour); ok {
+		t.Error("LastFire found a fire time outside the span")
+	}
+}
+
+func TestCron_LastFireMatchesMinuteScan(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no tz database:", err)
+	}
+	scan := func(c *Cron, t time.Time, within time.Duration) (time.Time, bool) {
+		t = t.Truncate(time.Minute)
+		for from := t.Add(-within); !t.Before(from); t = t.Add(-time.Minute) {
+			if c.Matches(t) {
+				return t, true
+			}
+		}
+		return time.Time{}, false
+	}
+	// 2026-10-25 is the end of summer time in Berlin.
+	start := time.Date(2026, 10, 20, 0, 7, 0, 0, berlin)
+	for _, expr := range []string{"0 18 * * fri", "*/15 9-17 * * mon-fri", "30 2 * * *", "0 0 1 * mon", "5/20 * 31 * *", "0 0 29 feb *"} {
+		c, err := Parse(expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for at := start; at.Before(start.Add(10 * 24 * time.Hour)); at = at.Add(97 * time.Minute) {
+			got, gotOK := c.LastFire(at, 7*24*time.Hour)
+			want, wantOK := scan(c, at, 7*24*time.Hour)
+			if gotOK != wantOK || !got.Equal(want) {
+				t.Fatalf("%q.LastFire(%s) = %s, %v; want %s, %v", expr, at, got, gotOK, want, wantOK)
+			}
+		}
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *",
+		"* * * * 8", "*/0 * * * *", "5-1 * * * *", "* * * * funday", "a b c d e",
+	} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) succeeded", expr)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+// Package agenttool adapts governed OpenClause actions to the tool
+// interface agent frameworks call. Each call is submitted to the gateway;
+// when policy asks for approval the tool waits for it and executes, so the
+// agent sees a single call that returns the connector's output.
+package agenttool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/connectors"
+	"github.com/bturcanu/OpenClause/pkg/sdk/client"
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+// Tool is the interface agent frameworks call tools through. It matches
+// LangChainGo's tools.Tool method for method, so a *Governed can be handed
+// to a LangChainGo agent as is.
+type Tool interface {
+	Name() string
+	Description() string
+	Call(ctx context.Context, input string) (string, error)
+}
+
+var _ Tool = (*Governed)(nil)
+
+// Defaults for Config.PollEvery and Config.ApprovalTimeout.
+const (
+	DefaultPollEvery       = 2 * time.Second
+	DefaultApprovalTimeout = 15 * time.Minute
+)
+
+// Config is the identity and risk a tool's calls are submitted with.
+type Config struct {
+	TenantID  string
+	AgentID   string
+	SessionID string
+	RiskScore int
+	// Resource, if set, names the target of a call from its params, for
+	// policies and grants that match on resource.
+	Resource func(tool, action string, params json.RawMessage) string
+	// PollEvery is how often a call awaiting approval retries execution.
+	PollEvery time.Duration
+	// ApprovalTimeout bounds the wait for approval. A call not approved in
+	// time returns an observation saying so rather than an error.
+	ApprovalTimeout time.Duration
+}
+
+// Governed is one tool.action called through the gateway.
+type Governed struct {
+	c           *client.Client
+	cfg         Config
+	tool        string
+	action      string
+	description string
+	schema      json.RawMessage
+}
+
+// New wraps tool.action as a Tool. The description tells the model what
+// the action does and what its input must contain.
+func New(c *client.Client, tool, action, description string, cfg Config) *Governed {
+	if cfg.PollEvery <= 0 {
+		cfg.PollEvery = DefaultPollEvery
+	}
+	if cfg.ApprovalTimeout <= 0 {
+		cfg.ApprovalTimeout = DefaultApprovalTimeout
+	}
+	return &Governed{c: c, cfg: cfg, tool: tool, action: action, description: description}
+}
+
+// FromSpec returns a Tool for every action the tenant may call, named,
+// described and typed from the gateway's tool specs.
+func FromSpec(ctx context.Context, c *client.Client, cfg Config) ([]*Governed, error) {
+	specs, err := c.ToolSpec(ctx, connectors.SpecFormatOpenAI)
+	if err != nil {
+		return nil, fmt.Errorf("agenttool.FromSpec: %w", err)
+	}
+	out := make([]*Governed, 0, len(specs))
+	for _, raw := range specs {
+		var spec struct {
+			Function struct {
+				Name        string          `json:"name"`
+				Description string          `json:"description"`
+				Parameters  json.RawMessage `json:"parameters"`
+			} `json:"function"`
+		}
+		if err := json.Unmarshal(raw, &spec); err != nil {
+			return nil, fmt.Errorf("agenttool.FromSpec: %w", err)
+		}
+		tool, action, ok := connectors.ParseFunctionName(spec.Function.Name)
+		if !ok {
+			continue
+		}
+		g := New(c, tool, action, spec.Function.Description, cfg)
+		g.schema = spec.Function.Parameters
+		out = append(out, g)
+	}
+	return out, nil
+}
+
+// Name is the function name of the action, e.g. "jira__issue__create".
+func (g *Governed) Name() string {
+	return connectors.FunctionName(g.tool, g.action)
+}
+
+// Description describes the action to the model.
+func (g *Governed) Description() string {
+	return g.description
+}
+
+// Schema is the JSON Schema of the action's params, for frameworks that
+// declare tools to the model with function calling. It is nil for tools
+// made with New.
+func (g *Governed) Schema() json.RawMessage {
+	return g.schema
+}
+
+// Call submits the action with input, a JSON object of params, and returns
+// the connector's output. Outcomes the model should see and react to —
+// invalid input, a policy denial, an approval that never came, a failed
+// execution — are returned as the observation text; errors are reserved for
+// failures to reach the gateway.
+func (g *Governed) Call(ctx context.Context, input string) (string, error) {
+	params := json.RawMessage(strings.TrimSpace(input))
+	if len(params) == 0 {
+		params = json.RawMessage(`{}`)
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(params, &obj); err != nil {
+		return "invalid input: params must be a JSON object", nil
+	}
+	req := types.ToolCallRequest{
+		TenantID:  g.cfg.TenantID,
+		AgentID:   g.cfg.AgentID,
+		SessionID: g.cfg.SessionID,
+		Tool:      g.tool,
+		Action:    g.action,
+		Params:    params,
+		RiskScore: g.cfg.RiskScore,
+	}
+	if g.cfg.Resource != nil {
+		req.Resource = g.cfg.Resource(g.tool, g.action, params)
+	}
+
+	resp, err := g.c.Submit(ctx, req)
+	if err != nil {
+		return refused(err)
+	}
+	if resp.Decision == types.DecisionApprove {
+		wctx, cancel := context.WithTimeout(ctx, g.cfg.ApprovalTimeout)
+		defer cancel()
+		resp, err = g.c.WaitForApprovalThenExecute(wctx, resp.EventID, g.cfg.PollEvery)
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			return fmt.Sprintf("not approved within %s; the call was not executed", g.cfg.ApprovalTimeout), nil
+		}
+		if err != nil {
+			return refused(err)
+		}
+	}
+	return observe(resp), nil
+}
+
+// refused turns a gateway refusal the model can act on into an observation
+// and passes any other error through.
+func refused(err error) (string, error) {
+	var apiErr *types.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.HTTPCode {
+		case http.StatusForbidden, http.StatusUnprocessableEntity, http.StatusBadRequest:
+			return "refused: " + apiErr.Message, nil
+		}
+	}
+	return "", err
+}
+
+// observe renders the outcome of an allowed or executed call.
+func observe(resp *types.ToolCallResponse) string {
+	if resp.Decision == types.DecisionDeny {
+		return "denied by policy: " + resp.Reason
+	}
+	res := resp.Result
+	if res == nil {
+		return "no result"
+	}
+	if res.Status != "success" {
+		return fmt.Sprintf("execution %s: %s", res.Status, res.Error)
+	}
+	if len(res.OutputJSON) == 0 {
+		return "{}"
+	}
+	return string(res.OutputJSON)
+}
@@ -0,0 +1,136 @@
+package agenttool
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/sdk/client"
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+// fakeGateway allows slack calls, denies jira deletes, and sends jira
+// creates to approval, granting it on the second execute.
+type fakeGateway struct {
+	mu       sync.Mutex
+	submits  []types.ToolCallRequest
+	executes int
+}
+
+func (f *fakeGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case r.URL.Path == "/v1/tools/spec":
+		_ = json.NewEncoder(w).Encode(map[string]any{"tools": []map[string]any{{
+			"type": "function",
+			"function": map[string]any{
+				"name": "slack__msg__post", "description": "Post a message",
+				"parameters": map[string]any{"type": "object"},
+			},
+		}}})
+	case r.URL.Path == "/v1/toolcalls":
+		var req types.ToolCallRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		f.submits = append(f.submits, req)
+		resp := types.ToolCallResponse{EventID: "e1", Decision: types.DecisionAllow,
+			Result: &types.ExecutionResult{Status: "success", OutputJSON: json.RawMessage(`{"ts":"1.2"}`)}}
+		switch req.Tool + "." + req.Action {
+		case "jira.issue.delete":
+			resp = types.ToolCallResponse{EventID: "e2", Decision: types.DecisionDeny, Reason: "destructive"}
+		case "jira.issue.create":
+			resp = types.ToolCallResponse{EventID: "e3", Decision: types.DecisionApprove}
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	case strings.HasSuffix(r.URL.Path, "/execute"):
+		f.executes++
+		if f.executes < 2 {
+			types.ErrConflict("awaiting approval").WriteJSON(w)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(types.ToolCallResponse{EventID: "e4", Decision: types.DecisionAllow,
+			Result: &types.ExecutionResult{Status: "success", OutputJSON: json.RawMessage(`{"key":"OPS-1"}`)}})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newTestTool(t *testing.T, tool, action string, cfg Config) (*Governed, *fakeGateway) {
+	t.Helper()
+	fg := &fakeGateway{}
+	srv := httptest.NewServer(fg)
+	t.Cleanup(srv.Close)
+	c := client.New(srv.URL, "sk-test")
+	c.SetRetryPolicy(client.RetryPolicy{MaxAttempts: 1})
+	cfg.PollEvery = time.Millisecond
+	return New(c, tool, action, "test", cfg), fg
+}
+
+func TestCall_Allowed(t *testing.T) {
+	g, fg := newTestTool(t, "slack", "msg.post", Config{
+		TenantID: "acme", AgentID: "bot", RiskScore: 2,
+		Resource: func(_, _ string, params json.RawMessage) string {
+			var p struct{ Channel string }
+			_ = json.Unmarshal(params, &p)
+			return "channel/" + p.Channel
+		},
+	})
+	if g.Name() != "slack__msg__post" {
+		t.Fatalf("name = %q", g.Name())
+	}
+	out, err := g.Call(context.Background(), `{"channel":"C1","text":"hi"}`)
+	if err != nil || out != `{"ts":"1.2"}` {
+		t.Fatalf("call = %q, %v", out, err)
+	}
+	req := fg.submits[0]
+	if req.TenantID != "acme" || req.AgentID != "bot" || req.RiskScore != 2 || req.Resource != "channel/C1" {
+		t.Fatalf("submitted %+v", req)
+	}
+}
+
+func TestCall_DeniedAndInvalidInputAreObservations(t *testing.T) {
+	g, fg := newTestTool(t, "jira", "issue.delete", Config{TenantID: "acme", AgentID: "bot"})
+	if out, err := g.Call(context.Background(), `{"issue_key":"OPS-1"}`); err != nil || out != "denied by policy: destructive" {
+		t.Fatalf("call = %q, %v", out, err)
+	}
+	if out, err := g.Call(context.Background(), `not json`); err != nil || !strings.HasPrefix(out, "invalid input") {
+		t.Fatalf("call = %q, %v", out, err)
+	}
+	if len(fg.submits) != 1 {
+		t.Fatalf("invalid input was submitted: %d submits", len(fg.submits))
+	}
+}
+
+func TestCall_WaitsForApprovalThenExecutes(t *testing.T) {
+	g, fg := newTestTool(t, "jira", "issue.create", Config{TenantID: "acme", AgentID: "bot"})
+	out, err := g.Call(context.Background(), `{"project":"OPS","summary":"outage"}`)
+	if err != nil || out != `{"key":"OPS-1"}` || fg.executes != 2 {
+		t.Fatalf("call = %q, %v after %d executes", out, err, fg.executes)
+	}
+}
+
+func TestCall_ApprovalTimeout(t *testing.T) {
+	g, _ := newTestTool(t, "jira", "issue.create", Config{TenantID: "acme", AgentID: "bot", ApprovalTimeout: time.Nanosecond})
+	out, err := g.Call(context.Background(), `{}`)
+	if err != nil || !strings.HasPrefix(out, "not approved within") {
+		t.Fatalf("call = %q, %v", out, err)
+	}
+}
+
+func TestFromSpec(t *testing.T) {
+	fg := &fakeGateway{}
+	srv := httptest.NewServer(fg)
+	t.Cleanup(srv.Close)
+	tools, err := FromSpec(context.Background(), client.New(srv.URL, "sk-test"), Config{TenantID: "acme", AgentID: "bot"})
+	if err != nil || len(tools) != 1 {
+		t.Fatalf("tools = %v, %v", tools, err)
+	}
+	if tools[0].Name() != "slack__msg__post" || tools[0].Description() != "Post a message" || string(tools[0].Schema()) != `{"type":"object"}` {
+		t.Fatalf("tool = %+v", tools[0])
+	}
+}
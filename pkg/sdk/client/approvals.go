@@ -0,0 +1,148 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/approvals"
+)
+
+// Approvals is a client for the approvals service, for internal tools that
+// act as approvers. The service trusts the tenant and approver its callers
+// name, so it authenticates with the internal token rather than an API key.
+type Approvals struct {
+	c *Client
+}
+
+// NewApprovals creates a client for the approvals service at baseURL.
+func NewApprovals(baseURL, internalToken string) *Approvals {
+	return &Approvals{c: newClient(baseURL, "X-Internal-Token", internalToken)}
+}
+
+// SetRetryPolicy replaces the retry policy for reads; approving and denying
+// are never retried. Call it before the client is shared.
+func (a *Approvals) SetRetryPolicy(p RetryPolicy) {
+	a.c.SetRetryPolicy(p)
+}
+
+// SetHTTPClient replaces the HTTP client requests are sent with.
+func (a *Approvals) SetHTTPClient(hc *http.Client) {
+	a.c.SetHTTPClient(hc)
+}
+
+// AddRequestHook registers a hook run before each attempt.
+func (a *Approvals) AddRequestHook(h RequestHook) {
+	a.c.AddRequestHook(h)
+}
+
+// AddResponseHook registers a hook run after each attempt.
+func (a *Approvals) AddResponseHook(h ResponseHook) {
+	a.c.AddResponseHook(h)
+}
+
+// Get returns an approval request.
+func (a *Approvals) Get(ctx context.Context, id string) (*approvals.ApprovalRequest, error) {
+	var req approvals.ApprovalRequest
+	if err := a.c.do(ctx, http.MethodGet, "/v1/approvals/requests/"+url.PathEscape(id), nil, &req); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// ListPending returns one page of the tenant's unexpired pending requests,
+// newest first. A limit of 0 takes the service's default page size; cursor
+// is "" for the first page and the previous page's NextCursor after that.
+func (a *Approvals) ListPending(ctx context.Context, tenantID string, limit int, cursor string) (*approvals.PendingPage, error) {
+	q := url.Values{"tenant_id": {tenantID}}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	if cursor != "" {
+		q.Set("cursor", cursor)
+	}
+	var page approvals.PendingPage
+	if err := a.c.do(ctx, http.MethodGet, "/v1/approvals/pending?"+q.Encode(), nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// Pending iterates over every unexpired pending request of the tenant,
+// fetching pages as it goes. Iteration stops at the first error, which is
+// yielded with a zero request.
+func (a *Approvals) Pending(ctx context.Context, tenantID string) iter.Seq2[approvals.ApprovalRequest, error] {
+	return func(yield func(approvals.ApprovalRequest, error) bool) {
+		cursor := ""
+		for {
+			page, err := a.ListPending(ctx, tenantID, 0, cursor)
+			if err != nil {
+				yield(approvals.ApprovalRequest{}, err)
+				return
+			}
+			for _, req := range page.Requests {
+				if !yield(req, nil) {
+					return
+				}
+			}
+			if page.NextCursor == "" {
+				return
+			}
+			cursor = page.NextCursor
+		}
+	}
+}
+
+// Approve approves a pending request and returns the grant it created.
+// It is sent once: a retry after a lost response would fail against the
+// already-approved request, so callers should re-read it with Get instead.
+func (a *Approvals) Approve(ctx context.Context, id string, in approvals.GrantInput) (*approvals.ApprovalGrant, error) {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+	var grant approvals.ApprovalGrant
+	if _, err := a.c.send(ctx, http.MethodPost, "/v1/approvals/requests/"+url.PathEscape(id)+"/approve", body, &grant); err != nil {
+		return nil, err
+	}
+	return &grant, nil
+}
+
+// Deny denies a pending request. Like Approve, it is sent once.
+func (a *Approvals) Deny(ctx context.Context, id, approver, reason string) error {
+	body, err := json.Marshal(approvals.DenyInput{Approver: approver, Reason: reason})
+	if err != nil {
+		return err
+	}
+	var out struct {
+		Status string `json:"status"`
+	}
+	_, err = a.c.send(ctx, http.MethodPost, "/v1/approvals/requests/"+url.PathEscape(id)+"/deny", body, &out)
+	return err
+}
+
+// WaitForResolution polls the request every pollEvery until it is no
+// longer pending, and returns it approved, denied, cancelled or expired.
+func (a *Approvals) WaitForResolution(ctx context.Context, id string, pollEvery time.Duration) (*approvals.ApprovalRequest, error) {
+	t := time.NewTicker(pollEvery)
+	defer t.Stop()
+
+	for {
+		req, err := a.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if req.Status != "pending" {
+			return req, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/approvals"
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+func newTestApprovals(t *testing.T, h http.HandlerFunc) *Approvals {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Internal-Token") != "internal" {
+			types.ErrForbidden("bad token").WriteJSON(w)
+			return
+		}
+		h(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	a := NewApprovals(srv.URL, "internal")
+	a.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond})
+	return a
+}
+
+func TestApprovals_ApproveIsNotRetried(t *testing.T) {
+	attempts := 0
+	a := newTestApprovals(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		types.ErrInternal("failed to approve request").WriteJSON(w)
+	})
+
+	_, err := a.Approve(context.Background(), "req-1", approvals.GrantInput{Approver: "alice@example.com"})
+	var apiErr *types.APIError
+	if !errors.As(err, &apiErr) || apiErr.HTTPCode != http.StatusInternalServerError {
+		t.Fatalf("err = %v", err)
+	}
+	if attempts != 1 {
+		t.Fatalf("approve sent %d times, want 1", attempts)
+	}
+}
+
+func TestApprovals_ApproveAndDeny(t *testing.T) {
+	a := newTestApprovals(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/approvals/requests/req-1/approve":
+			var in approvals.GrantInput
+			_ = json.NewDecoder(r.Body).Decode(&in)
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(approvals.ApprovalGrant{ID: "g1", RequestID: "req-1", Approver: in.Approver})
+		case "/v1/approvals/requests/req-2/deny":
+			var in approvals.DenyInput
+			_ = json.NewDecoder(r.Body).Decode(&in)
+			if in.Reason != "not today" {
+				types.ErrBadRequest("missing reason").WriteJSON(w)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]string{"status": "denied"})
+		default:
+			types.ErrNotFound("approval request not found").WriteJSON(w)
+		}
+	})
+	ctx := context.Background()
+
+	grant, err := a.Approve(ctx, "req-1", approvals.GrantInput{Approver: "alice@example.com"})
+	if err != nil || grant.ID != "g1" || grant.Approver != "alice@example.com" {
+		t.Fatalf("approve = %+v, %v", grant, err)
+	}
+	if err := a.Deny(ctx, "req-2", "alice@example.com", "not today"); err != nil {
+		t.Fatalf("deny: %v", err)
+	}
+	if _, err := a.Get(ctx, "missing"); err == nil {
+		t.Fatal("expected not found")
+	}
+}
+
+func TestApprovals_WaitForResolution(t *testing.T) {
+	var mu sync.Mutex
+	polls := 0
+	a := newTestApprovals(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		polls++
+		status := "pending"
+		if polls == 3 {
+			status = "approved"
+		}
+		mu.Unlock()
+		_ = json.NewEncoder(w).Encode(approvals.ApprovalRequest{ID: "req-1", Status: status})
+	})
+
+	req, err := a.WaitForResolution(context.Background(), "req-1", time.Millisecond)
+	if err != nil || req.Status != "approved" || polls != 3 {
+		t.Fatalf("resolution = %+v, %v after %d polls", req, err, polls)
+	}
+}
+
+func TestApprovals_Pending(t *testing.T) {
+	a := newTestApprovals(t, func(w http.ResponseWriter, r *http.Request) {
+		page := approvals.PendingPage{Requests: []approvals.ApprovalRequest{{ID: "req-2"}}, NextCursor: "c1"}
+		if r.URL.Query().Get("cursor") == "c1" {
+			page = approvals.PendingPage{Requests: []approvals.ApprovalRequest{{ID: "req-1"}}}
+		}
+		_ = json.NewEncoder(w).Encode(page)
+	})
+
+	var ids []string
+	for req, err := range a.Pending(context.Background(), "acme") {
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, req.ID)
+	}
+	if len(ids) != 2 || ids[0] != "req-2" || ids[1] != "req-1" {
+		t.Fatalf("pending = %v", ids)
+	}
+}
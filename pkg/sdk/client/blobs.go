@@ -0,0 +1,42 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+// UploadParams uploads params too large to send inline (over
+// types.MaxParamsBytes) and returns the ref to send as params_ref. The
+// gateway hands out a presigned URL and the params go straight to object
+// storage.
+func (c *Client) UploadParams(ctx context.Context, params json.RawMessage) (*types.BlobRef, error) {
+	ref := types.NewBlobRef(params)
+	body, err := json.Marshal(ref)
+	if err != nil {
+		return nil, err
+	}
+	var up types.BlobUpload
+	if err := c.do(ctx, http.MethodPost, "/v1/blobs", body, &up); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, up.UploadURL, bytes.NewReader(params))
+	if err != nil {
+		return nil, fmt.Errorf("client.UploadParams: %w", err)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("client.UploadParams: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &StatusError{StatusCode: resp.StatusCode}
+	}
+	return &up.ParamsRef, nil
+}
@@ -8,8 +8,11 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"time"
 
+	"github.com/bturcanu/OpenClause/pkg/connectors"
+	ocOtel "github.com/bturcanu/OpenClause/pkg/otel"
 	"github.com/bturcanu/OpenClause/pkg/types"
 	"github.com/google/uuid"
 )
@@ -18,57 +21,149 @@ type Client struct {
 	baseURL    string
 	apiKey     string
 	httpClient *http.Client
+	retry      RetryPolicy
+	// authHeader carries apiKey: X-API-Key for the gateway,
+	// X-Internal-Token for the approvals service.
+	authHeader string
+
+	requestHooks  []RequestHook
+	responseHooks []ResponseHook
 }
 
 func New(baseURL, apiKey string) *Client {
+	return newClient(baseURL, "X-API-Key", apiKey)
+}
+
+func newClient(baseURL, authHeader, key string) *Client {
 	return &Client{
 		baseURL:    baseURL,
-		apiKey:     apiKey,
+		apiKey:     key,
 		httpClient: &http.Client{Timeout: 15 * time.Second},
+		retry:      DefaultRetryPolicy,
+		authHeader: authHeader,
 	}
 }
 
+// SetRetryPolicy replaces the retry policy; RetryPolicy{MaxAttempts: 1}
+// disables retries. Call it before the client is shared.
+func (c *Client) SetRetryPolicy(p RetryPolicy) {
+	c.retry = p
+}
+
+// SetHTTPClient replaces the HTTP client requests are sent with.
+func (c *Client) SetHTTPClient(hc *http.Client) {
+	c.httpClient = hc
+}
+
 // Submit sends a tool-call request. If IdempotencyKey is empty, a unique key
-// is generated per call — callers wanting retry-safe idempotency should set it
-// explicitly before calling Submit.
+// is generated per call and reused by its retries — callers wanting
+// idempotency across their own retries of Submit should set it explicitly.
 func (c *Client) Submit(ctx context.Context, req types.ToolCallRequest) (*types.ToolCallResponse, error) {
 	if req.IdempotencyKey == "" {
 		req.IdempotencyKey = uuid.NewString()
 	}
 	if req.TraceID == "" {
-		req.TraceID = uuid.NewString()
+		req.TraceID = traceIDFromContext(ctx)
 	}
 	body, err := json.Marshal(req)
 	if err != nil {
 		return nil, err
 	}
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/v1/toolcalls", bytes.NewReader(body))
-	if err != nil {
+	var resp types.ToolCallResponse
+	if err := c.submit(ctx, "/v1/toolcalls", body, &resp); err != nil {
 		return nil, err
 	}
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("X-API-Key", c.apiKey)
+	return &resp, nil
+}
 
+// SubmitPlan sends an ordered plan of tool calls that is approved as a unit.
+// The response's event ID identifies the plan; pass it to Execute once the
+// plan is approved.
+func (c *Client) SubmitPlan(ctx context.Context, plan types.ToolCallPlan) (*types.ToolCallResponse, error) {
+	if plan.IdempotencyKey == "" {
+		plan.IdempotencyKey = uuid.NewString()
+	}
+	if plan.TraceID == "" {
+		plan.TraceID = traceIDFromContext(ctx)
+	}
+	body, err := json.Marshal(plan)
+	if err != nil {
+		return nil, err
+	}
 	var resp types.ToolCallResponse
-	if err := c.doJSON(httpReq, &resp); err != nil {
+	if err := c.submit(ctx, "/v1/plans", body, &resp); err != nil {
 		return nil, err
 	}
 	return &resp, nil
 }
 
 func (c *Client) Execute(ctx context.Context, parentEventID string) (*types.ToolCallResponse, error) {
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/v1/toolcalls/"+parentEventID+"/execute", http.NoBody)
-	if err != nil {
+	var resp types.ToolCallResponse
+	if err := c.submit(ctx, "/v1/toolcalls/"+parentEventID+"/execute", nil, &resp); err != nil {
 		return nil, err
 	}
-	httpReq.Header.Set("X-API-Key", c.apiKey)
+	return &resp, nil
+}
+
+// Compensate undoes an executed tool call with the compensation its connector
+// declared. The undo is evaluated by policy like any call, so the response
+// may require approval; repeat calls replay the first response once it is
+// recorded.
+func (c *Client) Compensate(ctx context.Context, eventID string) (*types.ToolCallResponse, error) {
 	var resp types.ToolCallResponse
-	if err := c.doJSON(httpReq, &resp); err != nil {
+	if err := c.submit(ctx, "/v1/toolcalls/"+eventID+"/compensate", nil, &resp); err != nil {
 		return nil, err
 	}
 	return &resp, nil
 }
 
+// CancelApproval withdraws the pending approval request requestID, which
+// agentID made, so approvers no longer see it. requestID is the last path
+// segment of a response's ApprovalURL. It is sent once: a retry after a
+// lost response would fail against the already-cancelled request.
+func (c *Client) CancelApproval(ctx context.Context, requestID, agentID, reason string) error {
+	body, err := json.Marshal(map[string]string{"agent_id": agentID, "reason": reason})
+	if err != nil {
+		return err
+	}
+	var out struct {
+		Status string `json:"status"`
+	}
+	_, err = c.send(ctx, http.MethodPost, "/v1/approvals/requests/"+url.PathEscape(requestID)+"/cancel", body, &out)
+	return err
+}
+
+// GetTrace returns the tree of events recorded under traceID.
+func (c *Client) GetTrace(ctx context.Context, traceID string) (*types.Trace, error) {
+	var trace types.Trace
+	if err := c.do(ctx, http.MethodGet, "/v1/traces/"+url.PathEscape(traceID), nil, &trace); err != nil {
+		return nil, err
+	}
+	return &trace, nil
+}
+
+// ToolSpec returns function-calling definitions of the tools the tenant may
+// call, in format "openai" or "anthropic", ready to pass to the model.
+func (c *Client) ToolSpec(ctx context.Context, format string) ([]json.RawMessage, error) {
+	var out struct {
+		Tools []json.RawMessage `json:"tools"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/v1/tools/spec?format="+url.QueryEscape(format), nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Tools, nil
+}
+
+// ToolCallFromFunction maps a model's call of a function from ToolSpec back
+// to a tool call; the caller fills in tenant, agent and idempotency key.
+func ToolCallFromFunction(name string, arguments json.RawMessage) (types.ToolCallRequest, error) {
+	tool, action, ok := connectors.ParseFunctionName(name)
+	if !ok {
+		return types.ToolCallRequest{}, fmt.Errorf("client.ToolCallFromFunction: %q is not a tool function name", name)
+	}
+	return types.ToolCallRequest{Tool: tool, Action: action, Params: arguments}, nil
+}
+
 func (c *Client) WaitForApprovalThenExecute(ctx context.Context, eventID string, pollEvery time.Duration) (*types.ToolCallResponse, error) {
 	t := time.NewTicker(pollEvery)
 	defer t.Stop()
@@ -100,20 +195,72 @@ func isRetryable(err error) bool {
 
 const maxResponseBytes = 4 << 20 // 4 MB
 
-func (c *Client) doJSON(req *http.Request, out any) error {
+// do sends one API request, retrying transient failures under the client's
+// retry policy, and decodes a 2xx response into out. It is for calls with
+// no side effect that a repeat could duplicate.
+func (c *Client) do(ctx context.Context, method, path string, body []byte, out any) error {
+	return c.retrying(ctx, retryableAttempt, method, path, body, out)
+}
+
+// submit POSTs a call that may run a connector. The gateway only replays
+// calls already recorded, so a retry while the first attempt is still
+// executing could run the connector twice; submit retries only attempts the
+// gateway refused outright.
+func (c *Client) submit(ctx context.Context, path string, body []byte, out any) error {
+	return c.retrying(ctx, refusedAttempt, http.MethodPost, path, body, out)
+}
+
+func (c *Client) retrying(ctx context.Context, retryable func(context.Context, error) bool, method, path string, body []byte, out any) error {
+	for attempt := 1; ; attempt++ {
+		retryAfter, err := c.send(ctx, method, path, body, out)
+		if err == nil || attempt >= c.retry.MaxAttempts || !retryable(ctx, err) {
+			return err
+		}
+		if err := sleep(ctx, c.retry.delay(attempt, retryAfter)); err != nil {
+			return err
+		}
+	}
+}
+
+// send makes a single attempt. It returns the Retry-After delay the server
+// asked for, if any.
+func (c *Client) send(ctx context.Context, method, path string, body []byte, out any) (time.Duration, error) {
+	var reader io.Reader = http.NoBody
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return 0, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set(c.authHeader, c.apiKey)
+	ocOtel.InjectHTTP(ctx, req.Header)
+	for _, h := range c.requestHooks {
+		if err := h(req); err != nil {
+			return 0, &hookError{err}
+		}
+	}
+
 	resp, err := c.httpClient.Do(req)
+	for _, h := range c.responseHooks {
+		h(req, resp, err)
+	}
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer resp.Body.Close()
 	limited := io.LimitReader(resp.Body, maxResponseBytes)
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
 		var apiErr types.APIError
 		if decodeErr := json.NewDecoder(limited).Decode(&apiErr); decodeErr == nil && apiErr.Message != "" {
 			apiErr.HTTPCode = resp.StatusCode
-			return &apiErr
+			return retryAfter, &apiErr
 		}
-		return fmt.Errorf("http status %d", resp.StatusCode)
+		return retryAfter, &StatusError{StatusCode: resp.StatusCode}
 	}
-	return json.NewDecoder(limited).Decode(out)
+	return 0, json.NewDecoder(limited).Decode(out)
 }
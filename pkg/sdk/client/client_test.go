@@ -0,0 +1,290 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newTestClient(t *testing.T, h http.HandlerFunc) *Client {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	c := New(srv.URL, "sk-test")
+	c.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond})
+	return c
+}
+
+func TestSubmit_RetriesTransientErrorsWithSameKey(t *testing.T) {
+	var mu sync.Mutex
+	var keys []string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var req types.ToolCallRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		keys = append(keys, req.IdempotencyKey)
+		n := len(keys)
+		mu.Unlock()
+		switch n {
+		case 1:
+			types.ErrOverloaded().WriteJSON(w)
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			_ = json.NewEncoder(w).Encode(types.ToolCallResponse{EventID: "e1", Decision: types.DecisionAllow})
+		}
+	})
+
+	resp, err := c.Submit(context.Background(), types.ToolCallRequest{Tool: "slack", Action: "msg.post"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.EventID != "e1" || len(keys) != 3 {
+		t.Fatalf("resp = %+v after %d attempts", resp, len(keys))
+	}
+	if keys[0] == "" || keys[1] != keys[0] || keys[2] != keys[0] {
+		t.Errorf("retries changed the idempotency key: %v", keys)
+	}
+}
+
+func TestSubmit_DoesNotRetryAfterItMayHaveRun(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		fail func(w http.ResponseWriter)
+	}{
+		{"internal error", func(w http.ResponseWriter) { types.ErrInternal("evidence recording failed").WriteJSON(w) }},
+		{"bad gateway", func(w http.ResponseWriter) { w.WriteHeader(http.StatusBadGateway) }},
+		{"gateway timeout", func(w http.ResponseWriter) { w.WriteHeader(http.StatusGatewayTimeout) }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			attempts := 0
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				attempts++
+				tc.fail(w)
+			})
+			if _, err := c.Submit(context.Background(), types.ToolCallRequest{}); err == nil {
+				t.Fatal("expected an error")
+			}
+			if attempts != 1 {
+				t.Errorf("attempts = %d, want 1", attempts)
+			}
+		})
+	}
+}
+
+func TestSubmit_RetriesRefusedConnection(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	addr := srv.URL
+	srv.Close()
+	c := New(addr, "sk-test")
+	c.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond})
+	attempts := 0
+	c.AddResponseHook(func(*http.Request, *http.Response, error) { attempts++ })
+	if _, err := c.Submit(context.Background(), types.ToolCallRequest{}); err == nil {
+		t.Fatal("expected an error")
+	}
+	if attempts != 3 {
+		t.Errorf("attempts = %d, want 3", attempts)
+	}
+}
+
+func TestSubmit_DoesNotRetryPermanentErrors(t *testing.T) {
+	attempts := 0
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		types.ErrBadRequest("bad").WriteJSON(w)
+	})
+	_, err := c.Submit(context.Background(), types.ToolCallRequest{})
+	if apiErr, ok := err.(*types.APIError); !ok || apiErr.HTTPCode != http.StatusBadRequest {
+		t.Fatalf("err = %v", err)
+	}
+	if attempts != 1 {
+		t.Errorf("attempts = %d, want 1", attempts)
+	}
+}
+
+func TestCancelApproval_SentOnce(t *testing.T) {
+	attempts := 0
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if r.URL.Path != "/v1/approvals/requests/req-1/cancel" || body["agent_id"] != "agent-1" || body["reason"] != "aborted" {
+			t.Errorf("request = %s %v", r.URL.Path, body)
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	if err := c.CancelApproval(context.Background(), "req-1", "agent-1", "aborted"); err == nil {
+		t.Fatal("expected an error")
+	}
+	if attempts != 1 {
+		t.Errorf("attempts = %d, want 1", attempts)
+	}
+}
+
+func TestSubmit_GivesUpAfterMaxAttempts(t *testing.T) {
+	attempts := 0
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		types.ErrRateLimited().WriteJSON(w)
+	})
+	if _, err := c.Submit(context.Background(), types.ToolCallRequest{}); err == nil {
+		t.Fatal("expected error")
+	}
+	if attempts != 3 {
+		t.Errorf("attempts = %d, want 3", attempts)
+	}
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 5, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt := 1; attempt <= 10; attempt++ {
+		if d := p.delay(attempt, 0); d < 0 || d > time.Second {
+			t.Errorf("delay(%d) = %v, want within [0, 1s]", attempt, d)
+		}
+	}
+	if d := p.delay(1, 3*time.Second); d != 3*time.Second {
+		t.Errorf("delay with Retry-After = %v, want 3s", d)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	if d := parseRetryAfter("2", now); d != 2*time.Second {
+		t.Errorf("seconds form = %v", d)
+	}
+	if d := parseRetryAfter(now.Add(10*time.Second).Format(http.TimeFormat), now); d != 10*time.Second {
+		t.Errorf("date form = %v", d)
+	}
+	if d := parseRetryAfter("soon", now); d != 0 {
+		t.Errorf("garbage = %v", d)
+	}
+}
+
+func TestEvents_FollowsPages(t *testing.T) {
+	var queries []string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		page := types.EventList{Events: []types.ToolCallEnvelope{{EventID: "e1", EventSeq: 1}, {EventID: "e2", EventSeq: 2}}, NextAfterSeq: 2}
+		if r.URL.Query().Get("after_seq") == "2" {
+			page = types.EventList{Events: []types.ToolCallEnvelope{{EventID: "e3", EventSeq: 3}}}
+		}
+		_ = json.NewEncoder(w).Encode(page)
+	})
+
+	var ids []string
+	for env, err := range c.Events(context.Background(), ListEventsOptions{AgentID: "agent-1", Limit: 2}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, env.EventID)
+	}
+	if len(ids) != 3 || ids[2] != "e3" {
+		t.Fatalf("events = %v", ids)
+	}
+	if len(queries) != 2 || queries[0] != "agent_id=agent-1&limit=2" || queries[1] != "after_seq=2&agent_id=agent-1&limit=2" {
+		t.Fatalf("queries = %v", queries)
+	}
+}
+
+func TestSubmit_PropagatesTraceContextAndRunsHooks(t *testing.T) {
+	var traceparents, traceIDs []string
+	attempts := 0
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var req types.ToolCallRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		traceparents = append(traceparents, r.Header.Get("traceparent"))
+		traceIDs = append(traceIDs, req.TraceID)
+		if r.Header.Get("X-Agent") != "triage" {
+			types.ErrBadRequest("hook header missing").WriteJSON(w)
+			return
+		}
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(types.ToolCallResponse{EventID: "e1"})
+	})
+	c.AddRequestHook(func(r *http.Request) error {
+		r.Header.Set("X-Agent", "triage")
+		return nil
+	})
+	var statuses []int
+	c.AddResponseHook(func(_ *http.Request, resp *http.Response, err error) {
+		if err == nil {
+			statuses = append(statuses, resp.StatusCode)
+		}
+	})
+
+	tid, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	sid, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: tid, SpanID: sid, TraceFlags: trace.FlagsSampled,
+	}))
+	if _, err := c.Submit(ctx, types.ToolCallRequest{Tool: "slack", Action: "msg.post"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 2 || statuses[0] != http.StatusServiceUnavailable || statuses[1] != http.StatusOK {
+		t.Fatalf("response hook saw %v", statuses)
+	}
+	for i := range traceIDs {
+		if traceIDs[i] != tid.String() || traceparents[i] != "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01" {
+			t.Fatalf("attempt %d: trace_id %q, traceparent %q", i, traceIDs[i], traceparents[i])
+		}
+	}
+}
+
+func TestRequestHookErrorAbortsCall(t *testing.T) {
+	called := false
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) { called = true })
+	hookErr := errors.New("no credentials")
+	hookCalls := 0
+	c.AddRequestHook(func(*http.Request) error {
+		hookCalls++
+		return hookErr
+	})
+	if _, err := c.Execute(context.Background(), "e1"); !errors.Is(err, hookErr) || called || hookCalls != 1 {
+		t.Fatalf("err = %v, server called = %v, hook ran %d times", err, called, hookCalls)
+	}
+}
+
+func TestUploadParams_PutsToPresignedURL(t *testing.T) {
+	params := json.RawMessage(`{"channel":"C1","text":"long report"}`)
+	var uploaded []byte
+	var srvURL string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/blobs":
+			var ref types.BlobRef
+			_ = json.NewDecoder(r.Body).Decode(&ref)
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(types.BlobUpload{ParamsRef: ref, UploadURL: srvURL + "/upload?sig=x"})
+		case r.Method == http.MethodPut && r.URL.Path == "/upload":
+			if r.Header.Get("X-API-Key") != "" {
+				t.Error("API key sent to the presigned URL")
+			}
+			uploaded, _ = io.ReadAll(r.Body)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	srvURL = c.baseURL
+
+	ref, err := c.UploadParams(context.Background(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *ref != types.NewBlobRef(params) || string(uploaded) != string(params) {
+		t.Fatalf("ref = %+v, uploaded %s", ref, uploaded)
+	}
+}
@@ -0,0 +1,89 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+// ListEventsOptions filters ListEvents. Empty fields match any value.
+type ListEventsOptions struct {
+	AgentID  string
+	Tool     string
+	Decision types.Decision
+	// AfterSeq resumes listing after an event's EventSeq, such as the
+	// NextAfterSeq of the previous page.
+	AfterSeq int64
+	// Limit is the page size; the gateway defaults to 100 and allows 500.
+	Limit int
+}
+
+func (o ListEventsOptions) query() string {
+	q := url.Values{}
+	if o.AgentID != "" {
+		q.Set("agent_id", o.AgentID)
+	}
+	if o.Tool != "" {
+		q.Set("tool", o.Tool)
+	}
+	if o.Decision != "" {
+		q.Set("decision", string(o.Decision))
+	}
+	if o.AfterSeq > 0 {
+		q.Set("after_seq", strconv.FormatInt(o.AfterSeq, 10))
+	}
+	if o.Limit > 0 {
+		q.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if len(q) == 0 {
+		return ""
+	}
+	return "?" + q.Encode()
+}
+
+// GetEvent returns the recorded event, with its policy decision and, once
+// executed, its result.
+func (c *Client) GetEvent(ctx context.Context, eventID string) (*types.ToolCallEnvelope, error) {
+	var env types.ToolCallEnvelope
+	if err := c.do(ctx, http.MethodGet, "/v1/toolcalls/"+url.PathEscape(eventID), nil, &env); err != nil {
+		return nil, err
+	}
+	return &env, nil
+}
+
+// ListEvents returns one page of the tenant's events, oldest first.
+func (c *Client) ListEvents(ctx context.Context, opts ListEventsOptions) (*types.EventList, error) {
+	var page types.EventList
+	if err := c.do(ctx, http.MethodGet, "/v1/toolcalls"+opts.query(), nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// Events iterates over every event matching opts, fetching pages as it
+// goes. Iteration stops at the first error, which is yielded with a zero
+// event.
+func (c *Client) Events(ctx context.Context, opts ListEventsOptions) iter.Seq2[types.ToolCallEnvelope, error] {
+	return func(yield func(types.ToolCallEnvelope, error) bool) {
+		for {
+			page, err := c.ListEvents(ctx, opts)
+			if err != nil {
+				yield(types.ToolCallEnvelope{}, err)
+				return
+			}
+			for _, env := range page.Events {
+				if !yield(env, nil) {
+					return
+				}
+			}
+			if page.NextAfterSeq == 0 {
+				return
+			}
+			opts.AfterSeq = page.NextAfterSeq
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestHook runs on every attempt just before it is sent, after the
+// client has set its own headers and the caller's trace context. It may add
+// headers; an error aborts the call without retrying.
+type RequestHook func(req *http.Request) error
+
+// ResponseHook runs on every attempt once the gateway answers or the
+// transport fails; exactly one of resp and err is set. It must not read or
+// close resp.Body.
+type ResponseHook func(req *http.Request, resp *http.Response, err error)
+
+// hookError is a RequestHook's refusal, which is never retried.
+type hookError struct{ err error }
+
+func (e *hookError) Error() string { return "request hook: " + e.err.Error() }
+func (e *hookError) Unwrap() error { return e.err }
+
+// AddRequestHook registers a hook run before each attempt, in the order
+// added. Call it before the client is shared.
+func (c *Client) AddRequestHook(h RequestHook) {
+	c.requestHooks = append(c.requestHooks, h)
+}
+
+// AddResponseHook registers a hook run after each attempt, in the order
+// added. Call it before the client is shared.
+func (c *Client) AddResponseHook(h ResponseHook) {
+	c.responseHooks = append(c.responseHooks, h)
+}
+
+// traceIDFromContext is the TraceID for a submission that did not set one:
+// the trace of the caller's active span, so the gateway's spans and the
+// evidence join the agent's trace, or else a fresh ID.
+func traceIDFromContext(ctx context.Context) string {
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		return sc.TraceID().String()
+	}
+	return uuid.NewString()
+}
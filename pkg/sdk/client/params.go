@@ -0,0 +1,167 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+// Params is a typed builder for one connector action's params. Validate
+// applies the checks the connector would, so malformed calls fail before
+// they reach the gateway and its evidence log.
+type Params interface {
+	ToolAction() (tool, action string)
+	Validate() error
+}
+
+// ResourceParams is implemented by params that name the resource they act
+// on, as a resource URI (see types.ResourceURI).
+type ResourceParams interface {
+	Params
+	Resource() string
+}
+
+// NewToolCall validates p and returns a tool call of its action with p as
+// params, and p's resource if it implements ResourceParams. The caller fills
+// in tenant, agent and risk.
+func NewToolCall(p Params) (types.ToolCallRequest, error) {
+	if err := p.Validate(); err != nil {
+		return types.ToolCallRequest{}, err
+	}
+	params, err := json.Marshal(p)
+	if err != nil {
+		return types.ToolCallRequest{}, fmt.Errorf("client.NewToolCall: %w", err)
+	}
+	tool, action := p.ToolAction()
+	req := types.ToolCallRequest{Tool: tool, Action: action, Params: params}
+	if rp, ok := p.(ResourceParams); ok {
+		req.Resource = rp.Resource()
+	}
+	return req, nil
+}
+
+// maxSlackText is the longest message text Slack accepts.
+const maxSlackText = 40000
+
+var (
+	jiraProjectKey = regexp.MustCompile(`^[A-Z][A-Z0-9_]{0,254}$`)
+	jiraIssueKey   = regexp.MustCompile(`^[A-Z][A-Z0-9_]{0,254}-[1-9][0-9]*$`)
+	slackTS        = regexp.MustCompile(`^[0-9]+\.[0-9]+$`)
+)
+
+func required(field, v string) error {
+	if strings.TrimSpace(v) == "" {
+		return &types.ValidationError{Field: field, Reason: "is required"}
+	}
+	return nil
+}
+
+// SlackMessage is slack.msg.post: post Text to Channel (an ID or name).
+type SlackMessage struct {
+	Channel string `json:"channel"`
+	Text    string `json:"text"`
+}
+
+func (SlackMessage) ToolAction() (string, string) { return "slack", "msg.post" }
+
+func (p SlackMessage) Resource() string {
+	return types.NewResourceURI("slack", "channel", p.Channel).String()
+}
+
+func (p SlackMessage) Validate() error {
+	if err := required("params.channel", p.Channel); err != nil {
+		return err
+	}
+	if err := required("params.text", p.Text); err != nil {
+		return err
+	}
+	if len(p.Text) > maxSlackText {
+		return &types.ValidationError{Field: "params.text", Reason: fmt.Sprintf("must be at most %d bytes", maxSlackText)}
+	}
+	return nil
+}
+
+// SlackDelete is slack.msg.delete: delete the message posted at TS.
+type SlackDelete struct {
+	Channel string `json:"channel"`
+	TS      string `json:"ts"`
+}
+
+func (SlackDelete) ToolAction() (string, string) { return "slack", "msg.delete" }
+
+func (p SlackDelete) Resource() string {
+	return types.NewResourceURI("slack", "channel", p.Channel, "message", p.TS).String()
+}
+
+func (p SlackDelete) Validate() error {
+	if err := required("params.channel", p.Channel); err != nil {
+		return err
+	}
+	if !slackTS.MatchString(p.TS) {
+		return &types.ValidationError{Field: "params.ts", Reason: "must be a message timestamp such as 1712345678.000100"}
+	}
+	return nil
+}
+
+// SlackChannelList is slack.channel.list.
+type SlackChannelList struct{}
+
+func (SlackChannelList) ToolAction() (string, string) { return "slack", "channel.list" }
+
+func (SlackChannelList) Validate() error { return nil }
+
+// JiraIssue is jira.issue.create. IssueType defaults to Task.
+type JiraIssue struct {
+	Project     string `json:"project"`
+	Summary     string `json:"summary"`
+	Description string `json:"description,omitempty"`
+	IssueType   string `json:"issue_type,omitempty"`
+}
+
+func (JiraIssue) ToolAction() (string, string) { return "jira", "issue.create" }
+
+func (p JiraIssue) Resource() string {
+	return types.NewResourceURI("jira", "project", p.Project).String()
+}
+
+func (p JiraIssue) Validate() error {
+	if !jiraProjectKey.MatchString(p.Project) {
+		return &types.ValidationError{Field: "params.project", Reason: "must be a project key such as OPS"}
+	}
+	if err := required("params.summary", p.Summary); err != nil {
+		return err
+	}
+	if len(p.Summary) > 255 {
+		return &types.ValidationError{Field: "params.summary", Reason: "must be at most 255 bytes"}
+	}
+	return nil
+}
+
+// JiraIssueList is jira.issue.list.
+type JiraIssueList struct{}
+
+func (JiraIssueList) ToolAction() (string, string) { return "jira", "issue.list" }
+
+func (JiraIssueList) Validate() error { return nil }
+
+// JiraIssueDelete is jira.issue.delete.
+type JiraIssueDelete struct {
+	IssueKey string `json:"issue_key"`
+}
+
+func (JiraIssueDelete) ToolAction() (string, string) { return "jira", "issue.delete" }
+
+func (p JiraIssueDelete) Resource() string {
+	project, _, _ := strings.Cut(p.IssueKey, "-")
+	return types.NewResourceURI("jira", "project", project, "issue", p.IssueKey).String()
+}
+
+func (p JiraIssueDelete) Validate() error {
+	if !jiraIssueKey.MatchString(p.IssueKey) {
+		return &types.ValidationError{Field: "params.issue_key", Reason: "must be an issue key such as OPS-1"}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+func TestNewToolCall(t *testing.T) {
+	req, err := NewToolCall(JiraIssue{Project: "OPS", Summary: "DB outage"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.ToolAction() != "jira.issue.create" || string(req.Params) != `{"project":"OPS","summary":"DB outage"}` {
+		t.Errorf("req = %s %s", req.ToolAction(), req.Params)
+	}
+	if req.Resource != "jira://project/OPS" {
+		t.Errorf("resource = %q", req.Resource)
+	}
+
+	req, err = NewToolCall(SlackChannelList{})
+	if err != nil || req.ToolAction() != "slack.channel.list" || string(req.Params) != `{}` {
+		t.Errorf("channel list = %s %s, %v", req.ToolAction(), req.Params, err)
+	}
+	if req.Resource != "" {
+		t.Errorf("channel list resource = %q, want none", req.Resource)
+	}
+
+	req, _ = NewToolCall(SlackDelete{Channel: "C123", TS: "1712345678.000100"})
+	if req.Resource != "slack://channel/C123/message/1712345678.000100" {
+		t.Errorf("delete resource = %q", req.Resource)
+	}
+	req.TenantID, req.AgentID, req.IdempotencyKey = "t1", "a1", "k1"
+	if err := req.NormalizeAndValidate(); err != nil {
+		t.Errorf("built request does not validate: %v", err)
+	}
+}
+
+func TestParams_Validate(t *testing.T) {
+	for _, tc := range []struct {
+		p     Params
+		field string
+	}{
+		{SlackMessage{Text: "hi"}, "params.channel"},
+		{SlackMessage{Channel: "C1", Text: strings.Repeat("x", maxSlackText+1)}, "params.text"},
+		{SlackDelete{Channel: "C1", TS: "yesterday"}, "params.ts"},
+		{JiraIssue{Project: "ops", Summary: "x"}, "params.project"},
+		{JiraIssue{Project: "OPS"}, "params.summary"},
+		{JiraIssueDelete{IssueKey: "OPS-0"}, "params.issue_key"},
+	} {
+		_, err := NewToolCall(tc.p)
+		var verr *types.ValidationError
+		if !errors.As(err, &verr) || verr.Field != tc.field {
+			t.Errorf("%T: err = %v, want a %s validation error", tc.p, err, tc.field)
+		}
+	}
+	for _, ok := range []Params{
+		SlackMessage{Channel: "#general", Text: "hi"},
+		SlackDelete{Channel: "C1", TS: "1712345678.000100"},
+		JiraIssueDelete{IssueKey: "OPS-12"},
+	} {
+		if err := ok.Validate(); err != nil {
+			t.Errorf("%T: %v", ok, err)
+		}
+	}
+}
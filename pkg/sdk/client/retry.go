@@ -0,0 +1,129 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+// RetryPolicy controls how the client retries transient failures: network
+// errors, API errors marked retryable (429, 503 overload, 500) and bare
+// 429/502/503/504 responses. Submissions, which may run a connector, are
+// retried only when they were refused before reaching it. Delays grow
+// exponentially from BaseDelay up to MaxDelay with full jitter; a longer
+// Retry-After from the server wins.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first;
+	// 1 or less disables retries.
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// DefaultRetryPolicy is the policy a new client uses.
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 4, BaseDelay: 200 * time.Millisecond, MaxDelay: 5 * time.Second}
+
+// StatusError is a non-2xx response whose body is not an API error.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("http status %d", e.StatusCode)
+}
+
+// delay is the wait before attempt+1: a random duration up to the capped
+// exponential backoff, or retryAfter if that is longer.
+func (p RetryPolicy) delay(attempt int, retryAfter time.Duration) time.Duration {
+	backoff := p.MaxDelay
+	if p.BaseDelay > 0 && attempt < 32 {
+		if b := p.BaseDelay << (attempt - 1); b > 0 && b < p.MaxDelay {
+			backoff = b
+		}
+	}
+	var d time.Duration
+	if backoff > 0 {
+		d = rand.N(backoff + 1)
+	}
+	return max(d, retryAfter)
+}
+
+// retryableAttempt reports whether a failed attempt may be retried.
+func retryableAttempt(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var apiErr *types.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Retryable
+	}
+	var hookErr *hookError
+	if errors.As(err, &hookErr) {
+		return false
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		switch statusErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	// The request did not complete (connection refused or reset, client
+	// timeout); the caller's own context is still live.
+	return true
+}
+
+// refusedAttempt reports whether a failed submission may be retried: the
+// gateway refused it before processing (429 or 503), or the connection was
+// never made. After a timeout, a reset connection or a 500 the first
+// attempt may still be running its connector, and a retry could repeat the
+// side effect.
+func refusedAttempt(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var apiErr *types.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Retryable && (apiErr.HTTPCode == http.StatusTooManyRequests || apiErr.HTTPCode == http.StatusServiceUnavailable)
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode == http.StatusServiceUnavailable
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP
+// date.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
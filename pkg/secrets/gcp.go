@@ -0,0 +1,118 @@
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/config"
+)
+
+// GCP reads secrets from GCP Secret Manager. The access token comes from
+// AccessToken when set, otherwise from the GCE/GKE metadata server.
+type GCP struct {
+	AccessToken  string
+	Endpoint     string // default https://secretmanager.googleapis.com
+	MetadataHost string // default metadata.google.internal
+	HTTPClient   *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// NewGCPFromEnv configures GCP from GCP_ACCESS_TOKEN,
+// GCP_SECRETMANAGER_ENDPOINT, and GCE_METADATA_HOST.
+func NewGCPFromEnv(env config.Env) *GCP {
+	return &GCP{
+		AccessToken:  env.Get("GCP_ACCESS_TOKEN"),
+		Endpoint:     env.Get("GCP_SECRETMANAGER_ENDPOINT"),
+		MetadataHost: env.Get("GCE_METADATA_HOST"),
+		HTTPClient:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Fetch accesses ref.Path (projects/<p>/secrets/<s>[/versions/<v>], version
+// defaulting to latest) and returns the payload, or ref.Field from it.
+func (g *GCP) Fetch(ctx context.Context, ref Ref) (string, error) {
+	name := ref.Path
+	if !strings.Contains(name, "/versions/") {
+		name += "/versions/latest"
+	}
+	token, err := g.bearer(ctx)
+	if err != nil {
+		return "", fmt.Errorf("gcpsm: token: %w", err)
+	}
+	endpoint := g.Endpoint
+	if endpoint == "" {
+		endpoint = "https://secretmanager.googleapis.com"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(endpoint, "/")+"/v1/"+name+":access", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	body, err := doSecretRequest(g.HTTPClient, req)
+	if err != nil {
+		return "", fmt.Errorf("gcpsm: %w", err)
+	}
+	var out struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	if err := json.Unmarshal(body, &out); err != nil {
+		return "", fmt.Errorf("gcpsm: decode: %w", err)
+	}
+	data, err := base64.StdEncoding.DecodeString(out.Payload.Data)
+	if err != nil {
+		return "", fmt.Errorf("gcpsm: decode payload: %w", err)
+	}
+	s, err := jsonField(string(data), ref.Field)
+	if err != nil {
+		return "", fmt.Errorf("gcpsm: %w", err)
+	}
+	return s, nil
+}
+
+// bearer returns a cached metadata-server token, renewing it a minute
+// before expiry.
+func (g *GCP) bearer(ctx context.Context) (string, error) {
+	if g.AccessToken != "" {
+		return g.AccessToken, nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.token != "" && time.Now().Before(g.expires) {
+		return g.token, nil
+	}
+	host := g.MetadataHost
+	if host == "" {
+		host = "metadata.google.internal"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		"http://"+host+"/computeMetadata/v1/instance/service-accounts/default/token", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	body, err := doSecretRequest(g.HTTPClient, req)
+	if err != nil {
+		return "", err
+	}
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &tok); err != nil {
+		return "", err
+	}
+	g.token = tok.AccessToken
+	g.expires = time.Now().Add(time.Duration(tok.ExpiresIn)*time.Second - time.Minute)
+	return g.token, nil
+}
@@ -0,0 +1,235 @@
+// Package secrets resolves secret references held in configuration values
+// from HashiCorp Vault, AWS Secrets Manager, or GCP Secret Manager, and keeps
+// bound values fresh with a periodic refresh.
+//
+// A configuration value is a reference when it starts with one of:
+//
+//	vault://<path>#<field>                        KV v1 or v2 read, e.g. vault://secret/data/openclause#api_keys
+//	awssm://<secret-id or ARN>[#<json-field>]     AWS Secrets Manager GetSecretValue
+//	gcpsm://projects/<p>/secrets/<s>[/versions/<v>][#<json-field>]
+//
+// Anything else is treated as a literal and returned unchanged, so existing
+// plain env vars keep working.
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/config"
+)
+
+// Ref is a parsed secret reference.
+type Ref struct {
+	Scheme string // "vault", "awssm", or "gcpsm"
+	Path   string
+	Field  string // optional key inside a JSON / KV secret
+}
+
+func (r Ref) String() string {
+	s := r.Scheme + "://" + r.Path
+	if r.Field != "" {
+		s += "#" + r.Field
+	}
+	return s
+}
+
+// ParseRef reports whether value is a secret reference and parses it. The
+// path is not URL-parsed because AWS ARNs contain colons.
+func ParseRef(value string) (Ref, bool) {
+	scheme, rest, ok := strings.Cut(strings.TrimSpace(value), "://")
+	if !ok {
+		return Ref{}, false
+	}
+	switch scheme {
+	case "vault", "awssm", "gcpsm":
+	default:
+		return Ref{}, false
+	}
+	ref := Ref{Scheme: scheme, Path: rest}
+	if i := strings.LastIndex(rest, "#"); i >= 0 {
+		ref.Path, ref.Field = rest[:i], rest[i+1:]
+	}
+	ref.Path = strings.Trim(ref.Path, "/")
+	if ref.Path == "" {
+		return Ref{}, false
+	}
+	return ref, true
+}
+
+// Provider fetches the current value of a reference for one scheme.
+type Provider interface {
+	Fetch(ctx context.Context, ref Ref) (string, error)
+}
+
+// Resolver dispatches references to providers and tracks bound values for
+// refresh.
+type Resolver struct {
+	providers map[string]Provider
+	log       *slog.Logger
+
+	mu    sync.Mutex
+	bound []*binding
+}
+
+type binding struct {
+	ref      Ref
+	value    *Value
+	onChange func(string)
+}
+
+// Value is a secret that may change on refresh. The zero Value is empty.
+type Value struct {
+	v atomic.Pointer[string]
+}
+
+// Static returns a Value that never changes.
+func Static(s string) *Value {
+	v := &Value{}
+	v.v.Store(&s)
+	return v
+}
+
+// Get returns the current secret.
+func (v *Value) Get() string {
+	if v == nil {
+		return ""
+	}
+	if p := v.v.Load(); p != nil {
+		return *p
+	}
+	return ""
+}
+
+// NewResolver creates a resolver over the given scheme → provider map.
+func NewResolver(providers map[string]Provider, log *slog.Logger) *Resolver {
+	if log == nil {
+		log = slog.Default()
+	}
+	return &Resolver{providers: providers, log: log}
+}
+
+// NewResolverFromEnv registers every provider using its standard variables
+// in env (VAULT_ADDR/VAULT_TOKEN, AWS_REGION and AWS_* credentials, GCP
+// metadata or GCP_ACCESS_TOKEN). Providers only contact their backend when
+// a reference with their scheme is resolved.
+func NewResolverFromEnv(env config.Env, log *slog.Logger) *Resolver {
+	return NewResolver(map[string]Provider{
+		"vault": NewVaultFromEnv(env),
+		"awssm": NewAWSFromEnv(env),
+		"gcpsm": NewGCPFromEnv(env),
+	}, log)
+}
+
+// Resolve returns value unchanged when it is not a reference, otherwise the
+// fetched secret.
+func (r *Resolver) Resolve(ctx context.Context, value string) (string, error) {
+	ref, ok := ParseRef(value)
+	if !ok {
+		return value, nil
+	}
+	return r.fetch(ctx, ref)
+}
+
+// Bind resolves value and, when it is a reference, re-fetches it on every
+// refresh. onChange (may be nil) runs after a refresh observes a new value.
+func (r *Resolver) Bind(ctx context.Context, value string, onChange func(string)) (*Value, error) {
+	ref, ok := ParseRef(value)
+	if !ok {
+		return Static(value), nil
+	}
+	s, err := r.fetch(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	v := Static(s)
+	r.mu.Lock()
+	r.bound = append(r.bound, &binding{ref: ref, value: v, onChange: onChange})
+	r.mu.Unlock()
+	return v, nil
+}
+
+// Refresh re-fetches every bound reference. A failed fetch keeps the last
+// good value so a backend outage does not blank credentials.
+func (r *Resolver) Refresh(ctx context.Context) {
+	r.mu.Lock()
+	bound := append([]*binding(nil), r.bound...)
+	r.mu.Unlock()
+	for _, b := range bound {
+		s, err := r.fetch(ctx, b.ref)
+		if err != nil {
+			r.log.Warn("secret refresh failed, keeping previous value", "ref", b.ref.String(), "error", err)
+			continue
+		}
+		if s == b.value.Get() {
+			continue
+		}
+		b.value.v.Store(&s)
+		r.log.Info("secret rotated", "ref", b.ref.String())
+		if b.onChange != nil {
+			b.onChange(s)
+		}
+	}
+}
+
+// Run refreshes bound references every interval until ctx is done.
+func (r *Resolver) Run(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			fctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+			r.Refresh(fctx)
+			cancel()
+		}
+	}
+}
+
+func (r *Resolver) fetch(ctx context.Context, ref Ref) (string, error) {
+	p, ok := r.providers[ref.Scheme]
+	if !ok {
+		return "", fmt.Errorf("secrets: no provider for %s", ref.Scheme)
+	}
+	s, err := p.Fetch(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("secrets: resolve %s: %w", ref, err)
+	}
+	return s, nil
+}
+
+// jsonField extracts field from a JSON object secret. Without a field the
+// raw secret is returned.
+func jsonField(secret, field string) (string, error) {
+	if field == "" {
+		return secret, nil
+	}
+	var m map[string]any
+	if err := json.Unmarshal([]byte(secret), &m); err != nil {
+		return "", fmt.Errorf("field %q requested but secret is not a JSON object", field)
+	}
+	return fieldString(m, field)
+}
+
+func fieldString(m map[string]any, field string) (string, error) {
+	v, ok := m[field]
+	if !ok {
+		return "", fmt.Errorf("field %q not found", field)
+	}
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,173 @@
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRef(t *testing.T) {
+	tests := []struct {
+		in   string
+		ok   bool
+		want Ref
+	}{
+		{"plain-value", false, Ref{}},
+		{"https://example.com", false, Ref{}},
+		{"vault://secret/data/oc#api_keys", true, Ref{"vault", "secret/data/oc", "api_keys"}},
+		{"awssm://arn:aws:secretsmanager:us-east-1:123:secret:oc-AbC#token", true, Ref{"awssm", "arn:aws:secretsmanager:us-east-1:123:secret:oc-AbC", "token"}},
+		{"gcpsm://projects/p/secrets/s", true, Ref{"gcpsm", "projects/p/secrets/s", ""}},
+		{"vault://", false, Ref{}},
+	}
+	for _, tt := range tests {
+		got, ok := ParseRef(tt.in)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("ParseRef(%q) = %+v, %v; want %+v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+type fakeProvider struct {
+	values map[string]string
+	err    error
+}
+
+func (f *fakeProvider) Fetch(_ context.Context, ref Ref) (string, error) {
+	if f.err != nil {
+		return "", f.err
+	}
+	return f.values[ref.Path], nil
+}
+
+func TestResolver_BindAndRefresh(t *testing.T) {
+	p := &fakeProvider{values: map[string]string{"oc/keys": "t1:sk-1"}}
+	r := NewResolver(map[string]Provider{"awssm": p}, nil)
+	ctx := context.Background()
+
+	if got, _ := r.Resolve(ctx, "literal"); got != "literal" {
+		t.Fatalf("literal passthrough = %q", got)
+	}
+
+	var changed []string
+	v, err := r.Bind(ctx, "awssm://oc/keys", func(s string) { changed = append(changed, s) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Get() != "t1:sk-1" {
+		t.Fatalf("bound value = %q", v.Get())
+	}
+
+	r.Refresh(ctx)
+	if len(changed) != 0 {
+		t.Fatalf("onChange fired without a change: %v", changed)
+	}
+
+	p.values["oc/keys"] = "t1:sk-2"
+	r.Refresh(ctx)
+	if v.Get() != "t1:sk-2" || len(changed) != 1 || changed[0] != "t1:sk-2" {
+		t.Fatalf("after rotation value=%q changed=%v", v.Get(), changed)
+	}
+
+	p.err = errors.New("backend down")
+	r.Refresh(ctx)
+	if v.Get() != "t1:sk-2" {
+		t.Fatalf("failed refresh should keep last value, got %q", v.Get())
+	}
+
+	if _, err := r.Resolve(ctx, "gcpsm://projects/p/secrets/s"); err == nil {
+		t.Fatal("expected error for unregistered scheme")
+	}
+}
+
+func TestVault_KVv2(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" || r.URL.Path != "/v1/secret/data/oc" {
+			http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"data":{"slack_bot_token":"xoxb-1","jira":"j"},"metadata":{"version":3}}}`))
+	}))
+	defer srv.Close()
+
+	v := &Vault{Addr: srv.URL, Token: "root", HTTPClient: srv.Client()}
+	ref, _ := ParseRef("vault://secret/data/oc#slack_bot_token")
+	got, err := v.Fetch(context.Background(), ref)
+	if err != nil || got != "xoxb-1" {
+		t.Fatalf("Fetch = %q, %v", got, err)
+	}
+
+	ref.Field = ""
+	if _, err := v.Fetch(context.Background(), ref); err == nil {
+		t.Fatal("expected error when field omitted for multi-key secret")
+	}
+
+	v.Token = "wrong"
+	ref.Field = "jira"
+	if _, err := v.Fetch(context.Background(), ref); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Fatalf("expected 403 error, got %v", err)
+	}
+}
+
+func TestAWS_GetSecretValue(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Target") != "secretsmanager.GetSecretValue" ||
+			!strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/20240102/us-east-1/secretsmanager/aws4_request") {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		var in struct{ SecretId string }
+		_ = json.NewDecoder(r.Body).Decode(&in)
+		_ = json.NewEncoder(w).Encode(map[string]string{"SecretString": `{"token":"jira-` + in.SecretId + `"}`})
+	}))
+	defer srv.Close()
+
+	a := &AWS{
+		Region: "us-east-1", AccessKeyID: "AKID", SecretAccessKey: "secret",
+		Endpoint: srv.URL, HTTPClient: srv.Client(),
+		now: func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) },
+	}
+	got, err := a.Fetch(context.Background(), Ref{Scheme: "awssm", Path: "oc", Field: "token"})
+	if err != nil || got != "jira-oc" {
+		t.Fatalf("Fetch = %q, %v", got, err)
+	}
+}
+
+func TestGCP_AccessWithMetadataToken(t *testing.T) {
+	var tokenCalls int
+	meta := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			http.Error(w, "missing flavor", http.StatusForbidden)
+			return
+		}
+		tokenCalls++
+		_, _ = w.Write([]byte(`{"access_token":"ya29.test","expires_in":3600}`))
+	}))
+	defer meta.Close()
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer ya29.test" ||
+			r.URL.Path != "/v1/projects/p/secrets/slack/versions/latest:access" {
+			http.Error(w, "nope", http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"payload":{"data":"` + base64.StdEncoding.EncodeToString([]byte("xoxb-gcp")) + `"}}`))
+	}))
+	defer api.Close()
+
+	g := &GCP{Endpoint: api.URL, MetadataHost: strings.TrimPrefix(meta.URL, "http://"), HTTPClient: http.DefaultClient}
+	ref, _ := ParseRef("gcpsm://projects/p/secrets/slack")
+	for range 2 {
+		got, err := g.Fetch(context.Background(), ref)
+		if err != nil || got != "xoxb-gcp" {
+			t.Fatalf("Fetch = %q, %v", got, err)
+		}
+	}
+	if tokenCalls != 1 {
+		t.Fatalf("metadata token fetched %d times, want 1 (cached)", tokenCalls)
+	}
+}
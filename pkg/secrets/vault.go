@@ -0,0 +1,96 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/config"
+)
+
+const maxSecretResponseBytes = 1 << 20
+
+// Vault reads secrets over the Vault HTTP API with a static token.
+type Vault struct {
+	Addr       string
+	Token      string
+	Namespace  string
+	HTTPClient *http.Client
+}
+
+// NewVaultFromEnv configures Vault from VAULT_ADDR, VAULT_TOKEN, and
+// VAULT_NAMESPACE.
+func NewVaultFromEnv(env config.Env) *Vault {
+	return &Vault{
+		Addr:       env.Get("VAULT_ADDR"),
+		Token:      env.Get("VAULT_TOKEN"),
+		Namespace:  env.Get("VAULT_NAMESPACE"),
+		HTTPClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Fetch reads ref.Path and returns ref.Field. KV v2 responses nest the
+// secret under data.data; KV v1 under data. The field may be omitted when
+// the secret has exactly one key.
+func (v *Vault) Fetch(ctx context.Context, ref Ref) (string, error) {
+	if v.Addr == "" || v.Token == "" {
+		return "", fmt.Errorf("vault: VAULT_ADDR and VAULT_TOKEN are required")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(v.Addr, "/")+"/v1/"+ref.Path, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", v.Token)
+	if v.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.Namespace)
+	}
+	body, err := doSecretRequest(v.HTTPClient, req)
+	if err != nil {
+		return "", fmt.Errorf("vault: %w", err)
+	}
+
+	var out struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal(body, &out); err != nil {
+		return "", fmt.Errorf("vault: decode: %w", err)
+	}
+	data := out.Data
+	if inner, ok := data["data"].(map[string]any); ok {
+		if _, hasMeta := data["metadata"]; hasMeta {
+			data = inner
+		}
+	}
+	field := ref.Field
+	if field == "" {
+		if len(data) != 1 {
+			return "", fmt.Errorf("vault: %s has %d keys; add #<field> to the reference", ref.Path, len(data))
+		}
+		for k := range data {
+			field = k
+		}
+	}
+	return fieldString(data, field)
+}
+
+// doSecretRequest performs req and returns the body of a 2xx response.
+func doSecretRequest(client *http.Client, req *http.Request) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSecretResponseBytes))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		// Error bodies from these APIs describe the failure, never the secret.
+		return nil, fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(body[:min(len(body), 256)])))
+	}
+	return body, nil
+}
@@ -0,0 +1,202 @@
+package siem
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	cefVendor        = "OpenClause"
+	cefProduct       = "OpenClause"
+	cefDeviceVersion = "1.0"
+
+	// syslog PRI values for facility local0.
+	syslogPriInfo    = 16*8 + 6
+	syslogPriWarning = 16*8 + 4
+)
+
+// defaultCEFFields maps record fields onto CEF extension keys when a
+// syslog_cef sink has no explicit field mapping. Empty values are omitted.
+var defaultCEFFields = map[string]string{
+	"act":        "{{if .decision}}{{.decision}}{{else}}{{.status}}{{end}}",
+	"suser":      "{{.agent_id}}",
+	"duser":      "{{.approver}}",
+	"externalId": "{{.event_id}}",
+	"msg":        "{{.reason}}",
+	"cs1Label":   "tool",
+	"cs1":        "{{.tool}}",
+	"cs2Label":   "action",
+	"cs2":        "{{.action}}",
+	"cs3Label":   "resource",
+	"cs3":        "{{.resource}}",
+	"cs4Label":   "tenant",
+	"cs4":        "{{.tenant_id}}",
+	"cn1Label":   "risk_score",
+	"cn1":        "{{.risk_score}}",
+}
+
+// syslogCEFWriter emits one RFC 5424 syslog message per record with a CEF
+// payload. UDP sends one datagram per message; TCP and TLS use
+// newline-delimited framing.
+type syslogCEFWriter struct {
+	network  string
+	addr     string
+	useTLS   bool
+	hostname string
+	conn     net.Conn
+}
+
+func newSyslogCEFWriter(rawURL string) (*syslogCEFWriter, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid syslog url %q", rawURL)
+	}
+	w := &syslogCEFWriter{addr: u.Host}
+	switch u.Scheme {
+	case "udp", "tcp":
+		w.network = u.Scheme
+	case "tls":
+		w.network, w.useTLS = "tcp", true
+	default:
+		return nil, fmt.Errorf("syslog url scheme must be udp, tcp, or tls, got %q", u.Scheme)
+	}
+	if u.Port() == "" {
+		port := "514"
+		if w.useTLS {
+			port = "6514"
+		}
+		w.addr = net.JoinHostPort(u.Hostname(), port)
+	}
+	w.hostname, _ = os.Hostname()
+	if w.hostname == "" {
+		w.hostname = "-"
+	}
+	return w, nil
+}
+
+func (w *syslogCEFWriter) dial(ctx context.Context) (net.Conn, error) {
+	if w.conn != nil {
+		return w.conn, nil
+	}
+	d := &net.Dialer{Timeout: 5 * time.Second}
+	var (
+		conn net.Conn
+		err  error
+	)
+	if w.useTLS {
+		td := &tls.Dialer{NetDialer: d, Config: &tls.Config{MinVersion: tls.VersionTLS12}}
+		conn, err = td.DialContext(ctx, w.network, w.addr)
+	} else {
+		conn, err = d.DialContext(ctx, w.network, w.addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("syslog dial %s: %w", w.addr, err)
+	}
+	w.conn = conn
+	return conn, nil
+}
+
+// write sends each document in order. On failure the connection is dropped
+// so the retry redials; the batch may be partially re-sent.
+func (w *syslogCEFWriter) write(ctx context.Context, batch []document) error {
+	conn, err := w.dial(ctx)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetWriteDeadline(deadline)
+	}
+	for _, d := range batch {
+		msg := w.frame(d)
+		if w.network == "tcp" {
+			msg += "\n"
+		}
+		if _, err := conn.Write([]byte(msg)); err != nil {
+			_ = conn.Close()
+			w.conn = nil
+			return fmt.Errorf("syslog write: %w", err)
+		}
+	}
+	return nil
+}
+
+func (w *syslogCEFWriter) Close() error {
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// frame renders the RFC 5424 header followed by the CEF record.
+func (w *syslogCEFWriter) frame(d document) string {
+	pri := syslogPriInfo
+	if d.Source["decision"] == "deny" || d.Source["status"] == "denied" {
+		pri = syslogPriWarning
+	}
+	return fmt.Sprintf("<%d>1 %s %s openclause - - - %s",
+		pri, d.Time.UTC().Format(time.RFC3339Nano), w.hostname, formatCEF(d))
+}
+
+// formatCEF renders d as a CEF:0 record. The signature ID is
+// "<kind>:<decision|status>", the name is "<tool>.<action>", severity is the
+// request risk score (0–10), and d.Body supplies the extension.
+func formatCEF(d document) string {
+	outcome := stringField(d.Source, "decision")
+	if outcome == "" {
+		outcome = stringField(d.Source, "status")
+	}
+	name := stringField(d.Source, "tool") + "." + stringField(d.Source, "action")
+
+	severity := 0
+	if v, ok := d.Source["risk_score"].(float64); ok {
+		severity = min(max(int(v), 0), 10)
+	} else if v, ok := d.Source["risk_score"].(int); ok {
+		severity = min(max(v, 0), 10)
+	}
+
+	keys := make([]string, 0, len(d.Body))
+	for k, v := range d.Body {
+		if s := fmt.Sprint(v); v != nil && s != "" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	ext := make([]string, 0, len(keys)+1)
+	ext = append(ext, "rt="+fmt.Sprint(d.Time.UnixMilli()))
+	for _, k := range keys {
+		ext = append(ext, k+"="+cefExtEscape(fmt.Sprint(d.Body[k])))
+	}
+
+	return strings.Join([]string{
+		"CEF:0",
+		cefHeaderEscape(cefVendor),
+		cefHeaderEscape(cefProduct),
+		cefHeaderEscape(cefDeviceVersion),
+		cefHeaderEscape(d.Kind + ":" + outcome),
+		cefHeaderEscape(name),
+		fmt.Sprint(severity),
+		strings.Join(ext, " "),
+	}, "|")
+}
+
+func stringField(m map[string]any, k string) string {
+	s, _ := m[k].(string)
+	return s
+}
+
+var (
+	cefHeaderReplacer = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\r", " ", "\n", " ")
+	cefExtReplacer    = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\r", `\r`, "\n", `\n`)
+)
+
+func cefHeaderEscape(s string) string { return cefHeaderReplacer.Replace(s) }
+func cefExtEscape(s string) string    { return cefExtReplacer.Replace(s) }
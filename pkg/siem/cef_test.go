@@ -0,0 +1,106 @@
+package siem
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/approvals"
+)
+
+func TestFormatCEF_DefaultMappingAndEscaping(t *testing.T) {
+	env := testEnvelope("acme")
+	env.Request.Resource = "proj=OPS|board"
+	env.Decision = "deny"
+	env.PolicyResult.Reason = "line1\nline2"
+	rec := DecisionRecord(env)
+
+	m, err := newFieldMapping(defaultCEFFields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := formatCEF(document{Kind: rec.Kind, Time: rec.Time, Body: m.apply(rec), Source: rec.Fields})
+
+	wantPrefix := "CEF:0|OpenClause|OpenClause|1.0|decision:deny|jira.issue.create|6|"
+	if !strings.HasPrefix(got, wantPrefix) {
+		t.Fatalf("header mismatch:\n got %s\nwant prefix %s", got, wantPrefix)
+	}
+	for _, want := range []string{
+		"act=deny", "cs1=jira", "cs2=issue.create", `cs3=proj\=OPS|board`,
+		"cn1=6", "suser=agent-1", `msg=line1\nline2`, "cs4=acme",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in %s", want, got)
+		}
+	}
+	if strings.Contains(got, "duser=") {
+		t.Errorf("empty approver should be omitted: %s", got)
+	}
+}
+
+func TestSyslogCEFSink_TCPDeliversApprovals(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	lines := make(chan string, 4)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		sc := bufio.NewScanner(conn)
+		for sc.Scan() {
+			lines <- sc.Text()
+		}
+	}()
+
+	r, err := New(context.Background(), Config{Sinks: []SinkConfig{{
+		Kind: "syslog_cef", URL: "tcp://" + ln.Addr().String(), Tenants: []string{"acme"},
+	}}}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.PublishResolution(context.Background(), approvals.Resolution{
+		Request:  approvals.ApprovalRequest{ID: "req-1", TenantID: "acme", Tool: "jira", Action: "issue.create", RiskScore: 8},
+		Status:   "denied",
+		Approver: "sec@example.com",
+		At:       time.Now(),
+	})
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case line := <-lines:
+		if !strings.HasPrefix(line, "<132>1 ") {
+			t.Errorf("expected warning PRI for denial, got %s", line)
+		}
+		if !strings.Contains(line, "CEF:0|OpenClause|OpenClause|1.0|approval:denied|jira.issue.create|8|") {
+			t.Errorf("unexpected CEF header: %s", line)
+		}
+		if !strings.Contains(line, "duser=sec@example.com") || !strings.Contains(line, "act=denied") {
+			t.Errorf("unexpected extension: %s", line)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no syslog line received")
+	}
+}
+
+func TestNewSyslogCEFWriter_Defaults(t *testing.T) {
+	w, err := newSyslogCEFWriter("tls://siem.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.addr != "siem.example.com:6514" || !w.useTLS {
+		t.Fatalf("unexpected writer: %+v", w)
+	}
+	if _, err := newSyslogCEFWriter("http://siem.example.com"); err == nil {
+		t.Fatal("expected error for http scheme")
+	}
+}
@@ -0,0 +1,55 @@
+package siem
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// fieldRef matches templates that are a bare field reference; those copy the
+// source value as-is so numbers and lists keep their JSON type.
+var fieldRef = regexp.MustCompile(`^\{\{\s*\.([A-Za-z0-9_]+)\s*\}\}$`)
+
+type fieldMapping struct {
+	raw  map[string]string
+	tmpl map[string]*template.Template
+}
+
+func newFieldMapping(fields map[string]string) (*fieldMapping, error) {
+	m := &fieldMapping{raw: map[string]string{}, tmpl: map[string]*template.Template{}}
+	for out, expr := range fields {
+		if sm := fieldRef.FindStringSubmatch(expr); sm != nil {
+			m.raw[out] = sm[1]
+			continue
+		}
+		t, err := template.New(out).Option("missingkey=zero").Parse(expr)
+		if err != nil {
+			return nil, fmt.Errorf("siem field %q: %w", out, err)
+		}
+		m.tmpl[out] = t
+	}
+	return m, nil
+}
+
+// apply renders the mapped document for rec. With no mapping configured the
+// source fields are returned unchanged.
+func (m *fieldMapping) apply(rec Record) map[string]any {
+	if len(m.raw) == 0 && len(m.tmpl) == 0 {
+		return rec.Fields
+	}
+	out := make(map[string]any, len(m.raw)+len(m.tmpl))
+	for k, src := range m.raw {
+		if v, ok := rec.Fields[src]; ok {
+			out[k] = v
+		}
+	}
+	for k, t := range m.tmpl {
+		var sb strings.Builder
+		if err := t.Execute(&sb, rec.Fields); err != nil {
+			continue
+		}
+		out[k] = strings.ReplaceAll(sb.String(), "<no value>", "")
+	}
+	return out
+}
@@ -0,0 +1,126 @@
+package siem
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+// ── OPA decision logs ────────────────────────────────────────────────────
+
+// decisionLogPath is the policy the gateway queries, as OPA names it in
+// decision logs.
+const decisionLogPath = "oc/main"
+
+// DecisionLogEvent is a gateway decision in OPA's decision log format, so
+// tooling built for OPA's decision log API can ingest it unchanged. The
+// call's params and source IP are left out of input and listed in erased,
+// as OPA does for masked fields.
+type DecisionLogEvent struct {
+	Labels     map[string]string  `json:"labels"`
+	DecisionID string             `json:"decision_id"`
+	Path       string             `json:"path"`
+	Input      types.PolicyInput  `json:"input"`
+	Result     types.PolicyResult `json:"result"`
+	Erased     []string           `json:"erased,omitempty"`
+	Timestamp  time.Time          `json:"timestamp"`
+}
+
+// NewDecisionLogEvent builds the decision log event for a recorded
+// envelope, with the event ID as the decision ID.
+func NewDecisionLogEvent(env *types.ToolCallEnvelope, labels map[string]string) DecisionLogEvent {
+	call := env.Request
+	var erased []string
+	if len(call.Params) > 0 {
+		call.Params = nil
+		erased = append(erased, "/input/toolcall/params")
+	}
+	if call.SourceIP != "" {
+		call.SourceIP = ""
+		erased = append(erased, "/input/toolcall/source_ip")
+	}
+	result := types.PolicyResult{Decision: env.Decision}
+	if env.PolicyResult != nil {
+		result = *env.PolicyResult
+	}
+	return DecisionLogEvent{
+		Labels:     labels,
+		DecisionID: env.EventID,
+		Path:       decisionLogPath,
+		Input: types.PolicyInput{
+			ToolCall:    call,
+			Environment: types.PolicyEnvironment{Timestamp: env.ReceivedAt},
+			Resource:    types.NewPolicyResource(call.Resource),
+		},
+		Result:    result,
+		Erased:    erased,
+		Timestamp: env.ReceivedAt.UTC(),
+	}
+}
+
+type opaWriter struct {
+	endpoint   string
+	token      string
+	labels     map[string]string
+	httpClient *http.Client
+}
+
+// newOPAWriter uploads to baseURL+resource like OPA's decision log plugin,
+// with resource defaulting to "/logs". Labels are added to OPA's usual id
+// label, which is the host name here.
+func newOPAWriter(baseURL, token, resource string, labels map[string]string) (*opaWriter, error) {
+	base, err := parseBaseURL(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	if resource == "" {
+		resource = "/logs"
+	}
+	all := map[string]string{"app": "openclause"}
+	if host, err := os.Hostname(); err == nil {
+		all["id"] = host
+	}
+	for k, v := range labels {
+		all[k] = v
+	}
+	return &opaWriter{
+		endpoint:   base + resource,
+		token:      token,
+		labels:     all,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// write sends the batch's decisions as one gzipped JSON array, the body of
+// OPA's decision log upload. Records without an envelope are skipped.
+func (w *opaWriter) write(ctx context.Context, batch []document) error {
+	events := make([]DecisionLogEvent, 0, len(batch))
+	for _, d := range batch {
+		if d.Envelope != nil {
+			events = append(events, NewDecisionLogEvent(d.Envelope, w.labels))
+		}
+	}
+	if len(events) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(gz).Encode(events); err != nil {
+		return &permanentError{fmt.Errorf("opa decision log marshal: %w", err)}
+	}
+	if err := gz.Close(); err != nil {
+		return &permanentError{fmt.Errorf("opa decision log gzip: %w", err)}
+	}
+	auth := ""
+	if w.token != "" {
+		auth = "Bearer " + w.token
+	}
+	return postEncoded(ctx, w.httpClient, w.endpoint, "application/json", "gzip", auth, &buf)
+}
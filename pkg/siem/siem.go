@@ -0,0 +1,204 @@
+// Package siem forwards governance decisions and approval outcomes to
+// security tooling (Splunk HEC, Elasticsearch, CEF over syslog, OPA decision
+// log collectors) with batching, retries, and per-tenant field mapping.
+package siem
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/approvals"
+	"github.com/bturcanu/OpenClause/pkg/config"
+	"github.com/bturcanu/OpenClause/pkg/evidence"
+	"github.com/bturcanu/OpenClause/pkg/secrets"
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+// Record kinds.
+const (
+	KindDecision = "decision"
+	KindApproval = "approval"
+)
+
+// Record is a single exportable event. Fields holds the flattened source
+// attributes that field-mapping templates are evaluated against.
+type Record struct {
+	Kind     string
+	TenantID string
+	Time     time.Time
+	Fields   map[string]any
+	// Envelope is the recorded call behind a decision record; sinks with a
+	// fixed format, such as OPA decision logs, build on it.
+	Envelope *types.ToolCallEnvelope
+}
+
+// Config is the top-level SIEM configuration file.
+type Config struct {
+	Sinks []SinkConfig `json:"sinks"`
+}
+
+// SinkConfig describes one export destination.
+type SinkConfig struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"` // "splunk_hec", "elasticsearch", "syslog_cef", or "opa_decision_log"
+	// URL is the HTTP endpoint, or udp://, tcp://, tls:// host:port for syslog.
+	URL string `json:"url"`
+	// TokenEnv names the environment variable holding the HEC token,
+	// Elasticsearch API key, or decision log bearer token, so secrets stay
+	// out of the config file. Its value may be a secret reference.
+	TokenEnv string `json:"token_env,omitempty"`
+	Index    string `json:"index,omitempty"`
+	// Resource is the decision log upload path under URL, "/logs" by
+	// default, as in OPA's decision_logs.resource.
+	Resource string `json:"resource,omitempty"`
+	// Labels are added to every decision log event's labels.
+	Labels map[string]string `json:"labels,omitempty"`
+	// Tenants restricts the sink to the listed tenants; empty means all.
+	Tenants []string `json:"tenants,omitempty"`
+	// Kinds restricts the sink to "decision" and/or "approval"; empty means both.
+	Kinds []string `json:"kinds,omitempty"`
+	// Fields maps output field names to text/template expressions over the
+	// record's source fields, e.g. {"user": "{{.agent_id}}"}. Empty exports
+	// the source fields unchanged.
+	Fields          map[string]string `json:"fields,omitempty"`
+	BatchSize       int               `json:"batch_size,omitempty"`
+	FlushIntervalMS int               `json:"flush_interval_ms,omitempty"`
+	// MaxRetries is how many times a failed batch is resent; unset is 3 and
+	// 0 disables retries.
+	MaxRetries *int `json:"max_retries,omitempty"`
+}
+
+// LoadConfig reads a JSON SIEM configuration from path.
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("siem.LoadConfig: %w", err)
+	}
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return cfg, fmt.Errorf("siem.LoadConfig decode: %w", err)
+	}
+	return cfg, nil
+}
+
+// NewFromFile loads the configuration at path and builds a router.
+func NewFromFile(ctx context.Context, path string, env config.Env, resolver *secrets.Resolver) (*Router, error) {
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return New(ctx, cfg, env, resolver)
+}
+
+// Router fans records out to the sinks configured for each tenant.
+type Router struct {
+	sinks []*sink
+}
+
+// New builds a router and starts a batching worker per sink. Sink tokens are
+// read from env and resolved through resolver, which may be nil when no
+// token is a secret reference.
+func New(ctx context.Context, cfg Config, env config.Env, resolver *secrets.Resolver) (*Router, error) {
+	r := &Router{}
+	for i, sc := range cfg.Sinks {
+		if sc.Name == "" {
+			sc.Name = fmt.Sprintf("%s-%d", sc.Kind, i)
+		}
+		s, err := newSink(ctx, sc, env, resolver)
+		if err != nil {
+			_ = r.Close()
+			return nil, err
+		}
+		r.sinks = append(r.sinks, s)
+	}
+	return r, nil
+}
+
+// Enqueue routes rec to every matching sink without blocking.
+func (r *Router) Enqueue(rec Record) {
+	if r == nil {
+		return
+	}
+	for _, s := range r.sinks {
+		if s.matches(rec) {
+			s.enqueue(rec)
+		}
+	}
+}
+
+// Publish implements evidence.Sink for recorded tool-call decisions.
+func (r *Router) Publish(_ context.Context, env *types.ToolCallEnvelope) error {
+	r.Enqueue(DecisionRecord(env))
+	return nil
+}
+
+// PublishResolution implements approvals.ResolutionSink.
+func (r *Router) PublishResolution(_ context.Context, res approvals.Resolution) {
+	r.Enqueue(ApprovalRecord(res))
+}
+
+// Close flushes pending batches and stops all workers.
+func (r *Router) Close() error {
+	if r == nil {
+		return nil
+	}
+	var errs []error
+	for _, s := range r.sinks {
+		if err := s.close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// DecisionRecord converts a recorded envelope into a redacted SIEM record.
+func DecisionRecord(env *types.ToolCallEnvelope) Record {
+	ev := evidence.NewAuditEvent(env)
+	return Record{
+		Kind:     KindDecision,
+		TenantID: ev.TenantID,
+		Time:     ev.ReceivedAt,
+		Fields:   toFields(ev),
+		Envelope: env,
+	}
+}
+
+// ApprovalRecord converts an approve/deny outcome into a SIEM record.
+func ApprovalRecord(res approvals.Resolution) Record {
+	req := res.Request
+	return Record{
+		Kind:     KindApproval,
+		TenantID: req.TenantID,
+		Time:     res.At,
+		Fields: map[string]any{
+			"approval_request_id": req.ID,
+			"event_id":            req.EventID,
+			"tenant_id":           req.TenantID,
+			"agent_id":            req.AgentID,
+			"tool":                req.Tool,
+			"action":              req.Action,
+			"resource":            req.Resource,
+			"risk_score":          req.RiskScore,
+			"kind":                req.Kind,
+			"status":              res.Status,
+			"approver":            res.Approver,
+			"reason":              res.Reason,
+		},
+	}
+}
+
+// toFields flattens v through its JSON form so templates see the same
+// field names as the wire format.
+func toFields(v any) map[string]any {
+	out := map[string]any{}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return out
+	}
+	_ = json.Unmarshal(b, &out)
+	return out
+}
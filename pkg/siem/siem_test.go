@@ -0,0 +1,309 @@
+package siem
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/approvals"
+	"github.com/bturcanu/OpenClause/pkg/config"
+	"github.com/bturcanu/OpenClause/pkg/secrets"
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+// staticProvider resolves every secret reference to the same value.
+type staticProvider string
+
+func (p staticProvider) Fetch(context.Context, secrets.Ref) (string, error) { return string(p), nil }
+
+func testEnvelope(tenant string) *types.ToolCallEnvelope {
+	return &types.ToolCallEnvelope{
+		EventID: "evt-1",
+		Request: types.ToolCallRequest{
+			TenantID:  tenant,
+			AgentID:   "agent-1",
+			Tool:      "jira",
+			Action:    "issue.create",
+			RiskScore: 6,
+			Params:    json.RawMessage(`{"summary":"secret"}`),
+		},
+		Decision:     types.DecisionAllow,
+		PolicyResult: &types.PolicyResult{Decision: types.DecisionAllow, Reason: "ok"},
+		ReceivedAt:   time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+}
+
+func TestFieldMapping_RawAndTemplated(t *testing.T) {
+	m, err := newFieldMapping(map[string]string{
+		"risk":   "{{ .risk_score }}",
+		"signal": "{{.tool}}.{{.action}}",
+		"absent": "x-{{.nope}}",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := m.apply(DecisionRecord(testEnvelope("acme")))
+	if out["risk"] != float64(6) {
+		t.Fatalf("risk should keep numeric type, got %#v", out["risk"])
+	}
+	if out["signal"] != "jira.issue.create" {
+		t.Fatalf("signal = %#v", out["signal"])
+	}
+	if out["absent"] != "x-" {
+		t.Fatalf("absent = %#v", out["absent"])
+	}
+	if _, ok := out["params"]; ok {
+		t.Fatal("unmapped fields must not be exported")
+	}
+}
+
+func TestSplunkSink_BatchesAndAuthenticates(t *testing.T) {
+	var mu sync.Mutex
+	var events []hecEvent
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		auth = r.Header.Get("Authorization")
+		dec := json.NewDecoder(r.Body)
+		for dec.More() {
+			var ev hecEvent
+			if err := dec.Decode(&ev); err != nil {
+				t.Errorf("decode: %v", err)
+				return
+			}
+			events = append(events, ev)
+		}
+	}))
+	defer srv.Close()
+
+	r, err := New(context.Background(), Config{Sinks: []SinkConfig{{
+		Kind: "splunk_hec", URL: srv.URL, TokenEnv: "TEST_HEC_TOKEN", Index: "oc",
+		Tenants: []string{"acme"}, BatchSize: 10, FlushIntervalMS: 60_000,
+	}}}, config.Env{"TEST_HEC_TOKEN": "tok"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = r.Publish(context.Background(), testEnvelope("acme"))
+	_ = r.Publish(context.Background(), testEnvelope("other")) // filtered by tenant
+	r.PublishResolution(context.Background(), approvals.Resolution{
+		Request: approvals.ApprovalRequest{ID: "req-1", TenantID: "acme", Tool: "jira"},
+		Status:  "approved", Approver: "a@example.com", At: time.Now(),
+	})
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if auth != "Splunk tok" {
+		t.Fatalf("authorization = %q", auth)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events in one batch, got %d", len(events))
+	}
+	if events[0].Sourcetype != "openclause:decision" || events[1].Sourcetype != "openclause:approval" {
+		t.Fatalf("unexpected sourcetypes: %s, %s", events[0].Sourcetype, events[1].Sourcetype)
+	}
+	if events[1].Event["approver"] != "a@example.com" || events[0].Index != "oc" {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+}
+
+func TestOPADecisionLogSink_UploadsGzippedDecisions(t *testing.T) {
+	var mu sync.Mutex
+	var events []map[string]any
+	var path, auth, encoding string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		path, auth, encoding = r.URL.Path, r.Header.Get("Authorization"), r.Header.Get("Content-Encoding")
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			t.Errorf("gzip: %v", err)
+			return
+		}
+		var batch []map[string]any
+		if err := json.NewDecoder(gz).Decode(&batch); err != nil {
+			t.Errorf("decode: %v", err)
+			return
+		}
+		events = append(events, batch...)
+	}))
+	defer srv.Close()
+
+	// The token is a secret reference, resolved once when the sink starts.
+	resolver := secrets.NewResolver(map[string]secrets.Provider{"vault": staticProvider("tok")}, slog.Default())
+	r, err := New(context.Background(), Config{Sinks: []SinkConfig{{
+		Kind: "opa_decision_log", URL: srv.URL + "/v1", TokenEnv: "TEST_DL_TOKEN",
+		Labels: map[string]string{"environment": "prod"}, FlushIntervalMS: 60_000,
+	}}}, config.Env{"TEST_DL_TOKEN": "vault://secret/siem#token"}, resolver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := testEnvelope("acme")
+	env.Request.SourceIP = "10.0.0.1"
+	env.Request.Resource = "jira://project/OPS"
+	_ = r.Publish(context.Background(), env)
+	r.PublishResolution(context.Background(), approvals.Resolution{
+		Request: approvals.ApprovalRequest{ID: "req-1", TenantID: "acme"}, Status: "approved", At: time.Now(),
+	})
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if path != "/v1/logs" || auth != "Bearer tok" || encoding != "gzip" {
+		t.Fatalf("upload to %s with auth %q, encoding %q", path, auth, encoding)
+	}
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want the decision only", len(events))
+	}
+	ev := events[0]
+	if ev["decision_id"] != "evt-1" || ev["path"] != "oc/main" || ev["timestamp"] != "2026-01-02T03:04:05Z" {
+		t.Fatalf("event = %+v", ev)
+	}
+	labels := ev["labels"].(map[string]any)
+	if labels["environment"] != "prod" || labels["app"] != "openclause" {
+		t.Fatalf("labels = %+v", labels)
+	}
+	input := ev["input"].(map[string]any)
+	call := input["toolcall"].(map[string]any)
+	if call["tool"] != "jira" || call["params"] != nil || call["source_ip"] != nil {
+		t.Fatalf("input.toolcall = %+v", call)
+	}
+	if res := input["resource"].(map[string]any); res["ids"].(map[string]any)["project"] != "OPS" {
+		t.Fatalf("input.resource = %+v", res)
+	}
+	if result := ev["result"].(map[string]any); result["decision"] != "allow" || result["reason"] != "ok" {
+		t.Fatalf("result = %+v", result)
+	}
+	erased, _ := json.Marshal(ev["erased"])
+	if string(erased) != `["/input/toolcall/params","/input/toolcall/source_ip"]` {
+		t.Fatalf("erased = %s", erased)
+	}
+}
+
+func TestSink_RetriesTransientButNotPermanentFailures(t *testing.T) {
+	old := retryBaseDelay
+	retryBaseDelay = time.Millisecond
+	defer func() { retryBaseDelay = old }()
+
+	for _, tc := range []struct {
+		status     int
+		maxRetries int
+		attempts   int32
+	}{
+		{http.StatusServiceUnavailable, 2, 3},
+		{http.StatusServiceUnavailable, 0, 1},
+		{http.StatusBadRequest, 2, 1},
+	} {
+		var calls atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			_, _ = io.Copy(io.Discard, r.Body)
+			w.WriteHeader(tc.status)
+		}))
+		r, err := New(context.Background(), Config{Sinks: []SinkConfig{{Kind: "elasticsearch", URL: srv.URL, MaxRetries: &tc.maxRetries}}}, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = r.Publish(context.Background(), testEnvelope("acme"))
+		_ = r.Close()
+		srv.Close()
+		if got := calls.Load(); got != tc.attempts {
+			t.Errorf("status %d, max_retries %d: expected %d attempts, got %d", tc.status, tc.maxRetries, tc.attempts, got)
+		}
+	}
+}
+
+func TestElasticWriter_BulkFormat(t *testing.T) {
+	var body []byte
+	var ctype string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctype = r.Header.Get("Content-Type")
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	w, err := newElasticWriter(srv.URL, "", "oc-idx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := document{Kind: KindDecision, Time: time.Unix(0, 0), Body: map[string]any{"tool": "jira"}}
+	if err := w.write(context.Background(), []document{doc}); err != nil {
+		t.Fatal(err)
+	}
+	if ctype != "application/x-ndjson" {
+		t.Fatalf("content-type = %q", ctype)
+	}
+	sc := bufio.NewScanner(bytes.NewReader(body))
+	var lines []map[string]any
+	for sc.Scan() {
+		var m map[string]any
+		if err := json.Unmarshal(sc.Bytes(), &m); err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, m)
+	}
+	if len(lines) != 2 || lines[1]["tool"] != "jira" || lines[1]["oc_kind"] != "decision" {
+		t.Fatalf("unexpected bulk body: %s", body)
+	}
+}
+
+func TestElasticSink_RetriesOnlyFailedItems(t *testing.T) {
+	old := retryBaseDelay
+	retryBaseDelay = time.Millisecond
+	defer func() { retryBaseDelay = old }()
+
+	var docsPerCall []int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		docsPerCall = append(docsPerCall, bytes.Count(b, []byte("\n"))/2)
+		if len(docsPerCall) == 1 {
+			// Bulk failures come back with 200: one throttled, one rejected.
+			_, _ = w.Write([]byte(`{"errors":true,"items":[` +
+				`{"index":{"status":429,"error":{"type":"es_rejected_execution_exception"}}},` +
+				`{"index":{"status":400,"error":{"type":"mapper_parsing_exception"}}},` +
+				`{"index":{"status":201}}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"errors":false,"items":[{"index":{"status":201}}]}`))
+	}))
+	defer srv.Close()
+
+	r, err := New(context.Background(), Config{Sinks: []SinkConfig{{Kind: "elasticsearch", URL: srv.URL, BatchSize: 3}}}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tenant := range []string{"a", "b", "c"} {
+		_ = r.Publish(context.Background(), testEnvelope(tenant))
+	}
+	_ = r.Close()
+	if len(docsPerCall) != 2 || docsPerCall[0] != 3 || docsPerCall[1] != 1 {
+		t.Fatalf("documents per bulk call = %v, want [3 1]", docsPerCall)
+	}
+}
+
+func TestNew_RejectsBadConfig(t *testing.T) {
+	if _, err := New(context.Background(), Config{Sinks: []SinkConfig{{Kind: "graylog", URL: "http://x"}}}, nil, nil); err == nil {
+		t.Fatal("expected error for unknown kind")
+	}
+	if _, err := New(context.Background(), Config{Sinks: []SinkConfig{{Kind: "splunk_hec", URL: "http://x"}}}, nil, nil); err == nil {
+		t.Fatal("expected error for missing HEC token")
+	}
+	if _, err := New(context.Background(), Config{Sinks: []SinkConfig{{Kind: "elasticsearch", URL: "http://x", Fields: map[string]string{"a": "{{"}}}}, nil, nil); err == nil {
+		t.Fatal("expected error for invalid template")
+	}
+}
@@ -0,0 +1,239 @@
+package siem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/config"
+	"github.com/bturcanu/OpenClause/pkg/secrets"
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+const (
+	defaultBatchSize     = 100
+	defaultFlushInterval = 2 * time.Second
+	defaultMaxRetries    = 3
+	queueCapacity        = 4096
+)
+
+// retryBaseDelay is the first backoff step; later retries double it.
+var retryBaseDelay = 500 * time.Millisecond
+
+// writer delivers one batch of mapped documents to a destination.
+type writer interface {
+	write(ctx context.Context, batch []document) error
+}
+
+// document is a mapped record ready for delivery. Source keeps the unmapped
+// fields for writers whose framing needs them (e.g. CEF headers).
+type document struct {
+	Kind     string
+	Time     time.Time
+	Body     map[string]any
+	Source   map[string]any
+	Envelope *types.ToolCallEnvelope
+}
+
+// permanentError marks a delivery failure that retrying cannot fix
+// (e.g. 4xx from the destination).
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// partialError reports a batch the destination accepted only in part.
+// Retry holds the documents that failed transiently; only they are resent.
+type partialError struct {
+	err   error
+	retry []document
+}
+
+func (e *partialError) Error() string { return e.err.Error() }
+func (e *partialError) Unwrap() error { return e.err }
+
+type sink struct {
+	cfg     SinkConfig
+	mapping *fieldMapping
+	w       writer
+
+	batchSize     int
+	flushInterval time.Duration
+	maxRetries    int
+
+	mu     sync.RWMutex
+	closed bool
+	queue  chan Record
+	done   chan struct{}
+}
+
+func newSink(ctx context.Context, cfg SinkConfig, env config.Env, resolver *secrets.Resolver) (*sink, error) {
+	fields := cfg.Fields
+	if cfg.Kind == "syslog_cef" && len(fields) == 0 {
+		fields = defaultCEFFields
+	}
+	mapping, err := newFieldMapping(fields)
+	if err != nil {
+		return nil, err
+	}
+	token := ""
+	if cfg.TokenEnv != "" {
+		token = env.Get(cfg.TokenEnv)
+		if resolver != nil {
+			if token, err = resolver.Resolve(ctx, token); err != nil {
+				return nil, fmt.Errorf("siem sink %q: resolve %s: %w", cfg.Name, cfg.TokenEnv, err)
+			}
+		}
+	}
+
+	var w writer
+	switch cfg.Kind {
+	case "splunk_hec":
+		w, err = newSplunkWriter(cfg.URL, token, cfg.Index)
+	case "elasticsearch":
+		w, err = newElasticWriter(cfg.URL, token, cfg.Index)
+	case "syslog_cef":
+		w, err = newSyslogCEFWriter(cfg.URL)
+	case "opa_decision_log":
+		// Decision logs carry decisions only, in a fixed format.
+		if len(cfg.Kinds) == 0 {
+			cfg.Kinds = []string{KindDecision}
+		}
+		w, err = newOPAWriter(cfg.URL, token, cfg.Resource, cfg.Labels)
+	default:
+		return nil, fmt.Errorf("siem sink %q: unknown kind %q", cfg.Name, cfg.Kind)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("siem sink %q: %w", cfg.Name, err)
+	}
+	return startSink(cfg, mapping, w), nil
+}
+
+func startSink(cfg SinkConfig, mapping *fieldMapping, w writer) *sink {
+	s := &sink{
+		cfg:           cfg,
+		mapping:       mapping,
+		w:             w,
+		batchSize:     cfg.BatchSize,
+		flushInterval: time.Duration(cfg.FlushIntervalMS) * time.Millisecond,
+		maxRetries:    defaultMaxRetries,
+		queue:         make(chan Record, queueCapacity),
+		done:          make(chan struct{}),
+	}
+	if s.batchSize <= 0 {
+		s.batchSize = defaultBatchSize
+	}
+	if s.flushInterval <= 0 {
+		s.flushInterval = defaultFlushInterval
+	}
+	if cfg.MaxRetries != nil {
+		s.maxRetries = max(*cfg.MaxRetries, 0)
+	}
+	go s.run()
+	return s
+}
+
+func (s *sink) matches(rec Record) bool {
+	if len(s.cfg.Tenants) > 0 && !slices.Contains(s.cfg.Tenants, rec.TenantID) {
+		return false
+	}
+	if len(s.cfg.Kinds) > 0 && !slices.Contains(s.cfg.Kinds, rec.Kind) {
+		return false
+	}
+	return true
+}
+
+// enqueue never blocks the caller; when the queue is full the record is
+// dropped and logged, since evidence in Postgres remains the source of truth.
+func (s *sink) enqueue(rec Record) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.queue <- rec:
+	default:
+		slog.Warn("siem queue full, dropping record", "sink", s.cfg.Name, "tenant_id", rec.TenantID)
+	}
+}
+
+func (s *sink) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]document, 0, s.batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		s.deliver(batch)
+		batch = make([]document, 0, s.batchSize)
+	}
+	for {
+		select {
+		case rec, ok := <-s.queue:
+			if !ok {
+				flush()
+				if c, ok := s.w.(io.Closer); ok {
+					_ = c.Close()
+				}
+				return
+			}
+			batch = append(batch, document{Kind: rec.Kind, Time: rec.Time, Body: s.mapping.apply(rec), Source: rec.Fields, Envelope: rec.Envelope})
+			if len(batch) >= s.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+func (s *sink) deliver(batch []document) {
+	var err error
+	for attempt := 0; attempt <= s.maxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(retryBaseDelay << (attempt - 1))
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		err = s.w.write(ctx, batch)
+		cancel()
+		if err == nil {
+			return
+		}
+		var perm *permanentError
+		if errors.As(err, &perm) {
+			break
+		}
+		var partial *partialError
+		if errors.As(err, &partial) {
+			if len(partial.retry) == 0 {
+				break
+			}
+			batch = partial.retry
+		}
+	}
+	slog.Error("siem batch delivery failed", "sink", s.cfg.Name, "records", len(batch), "error", err)
+}
+
+func (s *sink) close() error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.mu.Unlock()
+	select {
+	case <-s.done:
+		return nil
+	case <-time.After(30 * time.Second):
+		return fmt.Errorf("siem sink %q: timed out flushing", s.cfg.Name)
+	}
+}
@@ -0,0 +1,229 @@
+package siem
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	maxSIEMErrorBodyBytes = 512
+	// maxBulkResponseBytes bounds the _bulk response read for item errors.
+	maxBulkResponseBytes = 8 << 20
+)
+
+// ── Splunk HEC ───────────────────────────────────────────────────────────
+
+type splunkWriter struct {
+	endpoint   string
+	token      string
+	index      string
+	httpClient *http.Client
+}
+
+func newSplunkWriter(baseURL, token, index string) (*splunkWriter, error) {
+	base, err := parseBaseURL(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	if token == "" {
+		return nil, fmt.Errorf("splunk_hec requires a token (token_env)")
+	}
+	return &splunkWriter{
+		endpoint:   base + "/services/collector/event",
+		token:      token,
+		index:      index,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+type hecEvent struct {
+	Time       float64        `json:"time"`
+	Index      string         `json:"index,omitempty"`
+	Source     string         `json:"source"`
+	Sourcetype string         `json:"sourcetype"`
+	Event      map[string]any `json:"event"`
+}
+
+// write sends the batch as concatenated HEC event objects in one request.
+func (w *splunkWriter) write(ctx context.Context, batch []document) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, d := range batch {
+		if err := enc.Encode(hecEvent{
+			Time:       float64(d.Time.UnixMilli()) / 1000,
+			Index:      w.index,
+			Source:     "openclause",
+			Sourcetype: "openclause:" + d.Kind,
+			Event:      d.Body,
+		}); err != nil {
+			return &permanentError{fmt.Errorf("splunk marshal: %w", err)}
+		}
+	}
+	return post(ctx, w.httpClient, w.endpoint, "application/json", "Splunk "+w.token, &buf)
+}
+
+// ── Elasticsearch ────────────────────────────────────────────────────────
+
+type elasticWriter struct {
+	endpoint   string
+	apiKey     string
+	index      string
+	httpClient *http.Client
+}
+
+func newElasticWriter(baseURL, apiKey, index string) (*elasticWriter, error) {
+	base, err := parseBaseURL(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	if index == "" {
+		index = "openclause-events"
+	}
+	return &elasticWriter{
+		endpoint:   base + "/_bulk",
+		apiKey:     apiKey,
+		index:      index,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// bulkResponse is the part of a _bulk response that reports per-item
+// failures. Items are in the order of the batch.
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int             `json:"status"`
+		Error  json.RawMessage `json:"error"`
+	} `json:"items"`
+}
+
+// write sends the batch through the _bulk API as NDJSON. Elasticsearch
+// answers 200 even when documents fail, so the items are checked: 429 and
+// 5xx items are returned for a retry, and other failures are dropped.
+func (w *elasticWriter) write(ctx context.Context, batch []document) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	action := map[string]any{"index": map[string]string{"_index": w.index}}
+	for _, d := range batch {
+		doc := make(map[string]any, len(d.Body)+2)
+		for k, v := range d.Body {
+			doc[k] = v
+		}
+		doc["@timestamp"] = d.Time.UTC().Format(time.RFC3339Nano)
+		doc["oc_kind"] = d.Kind
+		if err := enc.Encode(action); err != nil {
+			return &permanentError{fmt.Errorf("elastic marshal: %w", err)}
+		}
+		if err := enc.Encode(doc); err != nil {
+			return &permanentError{fmt.Errorf("elastic marshal: %w", err)}
+		}
+	}
+	auth := ""
+	if w.apiKey != "" {
+		auth = "ApiKey " + w.apiKey
+	}
+	body, err := postRead(ctx, w.httpClient, w.endpoint, "application/x-ndjson", "", auth, &buf, maxBulkResponseBytes)
+	if err != nil {
+		return err
+	}
+	var resp bulkResponse
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return &permanentError{fmt.Errorf("elastic bulk response: %w", err)}
+	}
+	if !resp.Errors {
+		return nil
+	}
+	var retry []document
+	failed, first := 0, ""
+	for i, item := range resp.Items {
+		for _, res := range item {
+			if len(res.Error) == 0 || string(res.Error) == "null" {
+				continue
+			}
+			failed++
+			if first == "" {
+				first = string(res.Error)
+			}
+			if i < len(batch) && (res.Status == http.StatusTooManyRequests || res.Status >= 500) {
+				retry = append(retry, batch[i])
+			}
+		}
+	}
+	if failed == 0 {
+		return nil
+	}
+	if len(first) > maxSIEMErrorBodyBytes {
+		first = first[:maxSIEMErrorBodyBytes]
+	}
+	return &partialError{
+		err:   fmt.Errorf("elastic bulk: %d of %d documents failed, %d retryable: %s", failed, len(batch), len(retry), first),
+		retry: retry,
+	}
+}
+
+// ── shared ───────────────────────────────────────────────────────────────
+
+func parseBaseURL(raw string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("invalid url %q", raw)
+	}
+	return strings.TrimRight(u.String(), "/"), nil
+}
+
+// post delivers body and classifies failures: network errors, 429 and 5xx
+// are retryable; other non-2xx responses are permanent.
+func post(ctx context.Context, client *http.Client, endpoint, contentType, authorization string, body io.Reader) error {
+	return postEncoded(ctx, client, endpoint, contentType, "", authorization, body)
+}
+
+// postEncoded is post with a Content-Encoding, when set.
+func postEncoded(ctx context.Context, client *http.Client, endpoint, contentType, contentEncoding, authorization string, body io.Reader) error {
+	_, err := postRead(ctx, client, endpoint, contentType, contentEncoding, authorization, body, 0)
+	return err
+}
+
+// postRead is postEncoded that also returns up to maxBody bytes of a 2xx
+// response.
+func postRead(ctx context.Context, client *http.Client, endpoint, contentType, contentEncoding, authorization string, body io.Reader, maxBody int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, body)
+	if err != nil {
+		return nil, &permanentError{fmt.Errorf("siem new request: %w", err)}
+	}
+	req.Header.Set("Content-Type", contentType)
+	if contentEncoding != "" {
+		req.Header.Set("Content-Encoding", contentEncoding)
+	}
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("siem request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		b, err := io.ReadAll(io.LimitReader(resp.Body, maxBody))
+		if err != nil {
+			return nil, fmt.Errorf("siem response: %w", err)
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return b, nil
+	}
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, maxSIEMErrorBodyBytes))
+	err = fmt.Errorf("siem destination returned %d: %s", resp.StatusCode, string(b))
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return nil, err
+	}
+	return nil, &permanentError{err}
+}
@@ -0,0 +1,81 @@
+package tenants
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bturcanu/OpenClause/pkg/approvals"
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+// AutoApproval is a rule under which the approvals service approves a
+// request without a human, as approvals.AutoApprover. A request matches
+// when every condition the rule sets holds, e.g. low-risk Jira reads made
+// during office hours:
+//
+//	{"name": "low-risk-jira", "risk_below": 3, "tool_actions": ["jira.*"],
+//	 "hours": {"days": ["mon","tue","wed","thu","fri"], "start": "09:00", "end": "17:00"}}
+type AutoApproval struct {
+	// Name identifies the rule in the approval's audit trail.
+	Name string `json:"name"`
+	// RiskBelow matches requests whose risk_score is lower.
+	RiskBelow *int `json:"risk_below,omitempty"`
+	// ToolActions are tool catalog patterns; the request's tool.action
+	// must match one.
+	ToolActions []string `json:"tool_actions,omitempty"`
+	// Hours matches requests made inside the window.
+	Hours *types.TimeWindow `json:"hours,omitempty"`
+}
+
+// Validate checks the name, the conditions, and that at least one is set.
+func (a AutoApproval) Validate() error {
+	if a.Name == "" || len(a.Name) > 128 {
+		return errors.New("name is required and at most 128 bytes")
+	}
+	if a.RiskBelow == nil && len(a.ToolActions) == 0 && a.Hours == nil {
+		return errors.New("set at least one of risk_below, tool_actions or hours")
+	}
+	if a.RiskBelow != nil && (*a.RiskBelow < 1 || *a.RiskBelow > types.MaxRiskScore+1) {
+		return fmt.Errorf("risk_below must be between 1 and %d", types.MaxRiskScore+1)
+	}
+	for _, p := range a.ToolActions {
+		if err := ValidateCatalogPattern(p); err != nil {
+			return fmt.Errorf("tool_actions: %w", err)
+		}
+	}
+	if a.Hours != nil {
+		if err := a.Hours.Validate(); err != nil {
+			return fmt.Errorf("hours: %w", err)
+		}
+	}
+	return nil
+}
+
+// Matches reports whether the rule approves req. Hours are checked against
+// the time the request was created.
+func (a AutoApproval) Matches(req approvals.ApprovalRequest) bool {
+	if a.RiskBelow != nil && req.RiskScore >= *a.RiskBelow {
+		return false
+	}
+	if len(a.ToolActions) > 0 && !CatalogPermits(a.ToolActions, req.Tool, req.Action) {
+		return false
+	}
+	return a.Hours == nil || a.Hours.Contains(req.CreatedAt)
+}
+
+// AutoApproval returns the name of the tenant's first auto-approval rule
+// that matches req, or "" when none does; it has the approvals.AutoApproval
+// signature.
+func (c *SettingsCache) AutoApproval(ctx context.Context, req approvals.ApprovalRequest) (string, error) {
+	s, err := c.Get(ctx, req.TenantID)
+	if err != nil {
+		return "", fmt.Errorf("tenants.AutoApproval: %w", err)
+	}
+	for _, rule := range s.AutoApprovals {
+		if rule.Matches(req) {
+			return rule.Name, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,315 @@
+package tenants
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Blocklist — resources and principals a tenant's calls may not touch
+// ──────────────────────────────────────────────────────────────────────────────
+
+// Blocklist entry kinds. Channel, project and recipient entries match the
+// call's params; resource and agent entries match the call itself.
+const (
+	BlockResource  = "resource"  // the call's resource
+	BlockAgent     = "agent"     // the calling agent
+	BlockChannel   = "channel"   // params.channel
+	BlockProject   = "project"   // params.project
+	BlockRecipient = "recipient" // params.to, cc, bcc, recipient, recipients, email
+)
+
+// maxBlockValue bounds a blocklist entry's value.
+const maxBlockValue = 512
+
+// recipientParams are the param fields a recipient entry is matched against.
+var recipientParams = []string{"to", "cc", "bcc", "recipient", "recipients", "email"}
+
+// Block is one blocklist entry. A value ending in "*" matches as a prefix;
+// matching ignores case.
+type Block struct {
+	ID        string    `json:"id"`
+	TenantID  string    `json:"tenant_id"`
+	Kind      string    `json:"kind"`
+	Value     string    `json:"value"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Validate checks the entry's kind and value.
+func (b Block) Validate() error {
+	switch b.Kind {
+	case BlockResource, BlockAgent, BlockChannel, BlockProject, BlockRecipient:
+	default:
+		return fmt.Errorf("kind must be one of resource, agent, channel, project, recipient")
+	}
+	if b.Value == "" || b.Value == "*" {
+		return fmt.Errorf("value is required and cannot be a bare *")
+	}
+	if len(b.Value) > maxBlockValue {
+		return fmt.Errorf("value exceeds %d bytes", maxBlockValue)
+	}
+	return nil
+}
+
+func (b Block) matches(v string) bool {
+	if v == "" {
+		return false
+	}
+	if prefix, ok := strings.CutSuffix(b.Value, "*"); ok {
+		return len(v) >= len(prefix) && strings.EqualFold(v[:len(prefix)], prefix)
+	}
+	return strings.EqualFold(v, b.Value)
+}
+
+// MatchBlock returns the first entry of blocks that req touches, or nil.
+func MatchBlock(blocks []Block, req types.ToolCallRequest) *Block {
+	if len(blocks) == 0 {
+		return nil
+	}
+	var params map[string]json.RawMessage
+	_ = json.Unmarshal(req.Params, &params) // non-object params carry no fields
+	for i := range blocks {
+		b := &blocks[i]
+		var values []string
+		switch b.Kind {
+		case BlockResource:
+			values = []string{req.Resource}
+		case BlockAgent:
+			values = []string{req.AgentID}
+		case BlockChannel:
+			values = paramValues(params, "channel")
+		case BlockProject:
+			values = paramValues(params, "project")
+		case BlockRecipient:
+			values = paramValues(params, recipientParams...)
+		}
+		for _, v := range values {
+			if b.matches(v) {
+				return b
+			}
+		}
+	}
+	return nil
+}
+
+// paramValues collects the named string or string-array params, splitting
+// comma-separated lists such as an email "to".
+func paramValues(params map[string]json.RawMessage, keys ...string) []string {
+	var out []string
+	for _, k := range keys {
+		raw, ok := params[k]
+		if !ok {
+			continue
+		}
+		var list []string
+		var s string
+		if json.Unmarshal(raw, &s) == nil {
+			list = strings.Split(s, ",")
+		} else if json.Unmarshal(raw, &list) != nil {
+			continue
+		}
+		for _, v := range list {
+			if v = strings.TrimSpace(v); v != "" {
+				out = append(out, v)
+			}
+		}
+	}
+	return out
+}
+
+// AddBlock adds an entry to a tenant's blocklist; re-adding a kind and value
+// updates its reason. It returns nil when the tenant is missing or deleted.
+func (s *Store) AddBlock(ctx context.Context, tenantID string, b Block) (*Block, error) {
+	t, err := s.Get(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("tenants.AddBlock: %w", err)
+	}
+	if t == nil || t.Status == StatusDeleted {
+		return nil, nil
+	}
+	out := Block{TenantID: tenantID, Kind: b.Kind, Value: b.Value}
+	err = s.pool.QueryRow(ctx, `
+		INSERT INTO tenant_blocklist (id, tenant_id, kind, value, reason, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (tenant_id, kind, value) DO UPDATE SET reason = EXCLUDED.reason
+		RETURNING id, reason, created_by, created_at`,
+		uuid.NewString(), tenantID, b.Kind, b.Value, b.Reason, b.CreatedBy,
+	).Scan(&out.ID, &out.Reason, &out.CreatedBy, &out.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("tenants.AddBlock: %w", err)
+	}
+	return &out, nil
+}
+
+// ListBlocks returns a tenant's blocklist, oldest first.
+func (s *Store) ListBlocks(ctx context.Context, tenantID string) ([]Block, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, tenant_id, kind, value, reason, created_by, created_at
+		FROM tenant_blocklist WHERE tenant_id = $1
+		ORDER BY created_at, id`, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("tenants.ListBlocks: %w", err)
+	}
+	defer rows.Close()
+	var out []Block
+	for rows.Next() {
+		var b Block
+		if err := rows.Scan(&b.ID, &b.TenantID, &b.Kind, &b.Value, &b.Reason, &b.CreatedBy, &b.CreatedAt); err != nil {
+			return nil, fmt.Errorf("tenants.ListBlocks scan: %w", err)
+		}
+		out = append(out, b)
+	}
+	return out, rows.Err()
+}
+
+// RemoveBlock deletes one entry and reports whether it existed.
+func (s *Store) RemoveBlock(ctx context.Context, tenantID, id string) (bool, error) {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM tenant_blocklist WHERE tenant_id = $1 AND id = $2`, tenantID, id)
+	if err != nil {
+		return false, fmt.Errorf("tenants.RemoveBlock: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+type blockLister interface {
+	ListBlocks(ctx context.Context, tenantID string) ([]Block, error)
+}
+
+// Blocklist checks calls against their tenant's blocklist. It is not cached:
+// a new entry takes effect on every replica with the next call.
+type Blocklist struct {
+	store blockLister
+}
+
+// NewBlocklist creates a blocklist checker over store.
+func NewBlocklist(store blockLister) *Blocklist {
+	return &Blocklist{store: store}
+}
+
+// Denial returns why any of calls is blocked by the tenant's blocklist, or
+// "" when none is. A lookup error denies, so an outage cannot lift a
+// containment block.
+func (b *Blocklist) Denial(ctx context.Context, tenantID string, calls ...types.ToolCallRequest) string {
+	if b == nil || tenantID == "" {
+		return ""
+	}
+	blocks, err := b.store.ListBlocks(ctx, tenantID)
+	if err != nil {
+		return "tenant blocklist unavailable"
+	}
+	for _, req := range calls {
+		if m := MatchBlock(blocks, req); m != nil {
+			reason := fmt.Sprintf("%s %q is blocklisted", m.Kind, m.Value)
+			if m.Reason != "" {
+				reason += ": " + m.Reason
+			}
+			return reason
+		}
+	}
+	return ""
+}
+
+// ListBlocks handles GET /v1/admin/tenants/{tenant_id}/blocklist
+func (h *Handlers) ListBlocks(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "tenant_id")
+	t, err := h.store.Get(r.Context(), id)
+	if err != nil {
+		h.log.Error("get tenant failed", "tenant_id", id, "error", err)
+		types.ErrInternal("failed to list blocklist").WriteJSON(w)
+		return
+	}
+	if t == nil {
+		types.ErrNotFound("tenant not found").WriteJSON(w)
+		return
+	}
+	blocks, err := h.store.ListBlocks(r.Context(), id)
+	if err != nil {
+		h.log.Error("list blocklist failed", "tenant_id", id, "error", err)
+		types.ErrInternal("failed to list blocklist").WriteJSON(w)
+		return
+	}
+	if blocks == nil {
+		blocks = []Block{}
+	}
+	h.writeJSON(w, http.StatusOK, map[string]any{"entries": blocks})
+}
+
+// AddBlock handles POST /v1/admin/tenants/{tenant_id}/blocklist. The entry
+// applies to the tenant's next call on any gateway replica.
+func (h *Handlers) AddBlock(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "tenant_id")
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	var in struct {
+		Kind   string `json:"kind"`
+		Value  string `json:"value"`
+		Reason string `json:"reason"`
+	}
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&in); err != nil && !errors.Is(err, io.EOF) {
+		types.ErrBadRequest("invalid JSON body").WriteJSON(w)
+		return
+	}
+	b := Block{
+		Kind:      strings.ToLower(strings.TrimSpace(in.Kind)),
+		Value:     strings.TrimSpace(in.Value),
+		Reason:    in.Reason,
+		CreatedBy: r.Header.Get("X-Admin-Actor"),
+	}
+	if b.CreatedBy == "" {
+		b.CreatedBy = "admin"
+	}
+	if err := b.Validate(); err != nil {
+		types.ErrValidation(err).WriteJSON(w)
+		return
+	}
+	out, err := h.store.AddBlock(r.Context(), id, b)
+	if err != nil {
+		h.log.Error("add blocklist entry failed", "tenant_id", id, "error", err)
+		types.ErrInternal("failed to add blocklist entry").WriteJSON(w)
+		return
+	}
+	if out == nil {
+		types.ErrNotFound("tenant not found").WriteJSON(w)
+		return
+	}
+	h.log.Info("tenant blocklist entry added", "tenant_id", id, "block_id", out.ID, "kind", out.Kind, "value", out.Value, "actor", b.CreatedBy)
+	h.writeJSON(w, http.StatusCreated, out)
+}
+
+// RemoveBlock handles DELETE /v1/admin/tenants/{tenant_id}/blocklist/{block_id}
+func (h *Handlers) RemoveBlock(w http.ResponseWriter, r *http.Request) {
+	id, blockID := chi.URLParam(r, "tenant_id"), chi.URLParam(r, "block_id")
+	if _, err := uuid.Parse(blockID); err != nil {
+		types.ErrNotFound("blocklist entry not found").WriteJSON(w)
+		return
+	}
+	found, err := h.store.RemoveBlock(r.Context(), id, blockID)
+	if err != nil {
+		h.log.Error("remove blocklist entry failed", "tenant_id", id, "error", err)
+		types.ErrInternal("failed to remove blocklist entry").WriteJSON(w)
+		return
+	}
+	if !found {
+		types.ErrNotFound("blocklist entry not found").WriteJSON(w)
+		return
+	}
+	actor := r.Header.Get("X-Admin-Actor")
+	if actor == "" {
+		actor = "admin"
+	}
+	h.log.Info("tenant blocklist entry removed", "tenant_id", id, "block_id", blockID, "actor", actor)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,78 @@
+package tenants
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Canary resources — decoys no legitimate agent has reason to touch
+// ──────────────────────────────────────────────────────────────────────────────
+
+// Canary is a honeypot resource: a resource, channel, project or recipient
+// planted for no agent to use. Any call touching it is denied and raises an
+// alert, as an early warning of a compromised or misbehaving agent. It
+// matches as a Block of the same kind and value does.
+type Canary struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+	// Label names the canary in evidence and alerts; it defaults to the
+	// value.
+	Label string `json:"label,omitempty"`
+}
+
+// Validate checks the canary's kind, value and label. Agents cannot be
+// canaries: they are what a canary catches.
+func (c Canary) Validate() error {
+	switch c.Kind {
+	case BlockResource, BlockChannel, BlockProject, BlockRecipient:
+	default:
+		return errors.New("kind must be one of resource, channel, project, recipient")
+	}
+	if c.Value == "" || c.Value == "*" {
+		return errors.New("value is required and cannot be a bare *")
+	}
+	if len(c.Value) > maxBlockValue {
+		return fmt.Errorf("value exceeds %d bytes", maxBlockValue)
+	}
+	if len(c.Label) > 128 {
+		return errors.New("label must be at most 128 bytes")
+	}
+	return nil
+}
+
+// Name is the canary's label, or its value when it has none.
+func (c Canary) Name() string {
+	if c.Label != "" {
+		return c.Label
+	}
+	return c.Value
+}
+
+// MatchCanary returns the first of canaries that req touches, or nil.
+func MatchCanary(canaries []Canary, req types.ToolCallRequest) *Canary {
+	for i, c := range canaries {
+		if MatchBlock([]Block{{Kind: c.Kind, Value: c.Value}}, req) != nil {
+			return &canaries[i]
+		}
+	}
+	return nil
+}
+
+// TouchedCanary returns the tenant's canary that any of calls touches, or
+// nil. A settings lookup error is returned for the caller to deny on.
+func (c *SettingsCache) TouchedCanary(ctx context.Context, tenantID string, calls ...types.ToolCallRequest) (*Canary, error) {
+	s, err := c.Get(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("tenants.TouchedCanary: %w", err)
+	}
+	for _, req := range calls {
+		if m := MatchCanary(s.CanaryResources, req); m != nil {
+			return m, nil
+		}
+	}
+	return nil, nil
+}
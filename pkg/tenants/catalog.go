@@ -0,0 +1,178 @@
+package tenants
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"github.com/go-chi/chi/v5"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Tool catalog — the tool.action pairs a tenant may call
+// ──────────────────────────────────────────────────────────────────────────────
+
+// catalogPattern is a lowercase tool.action, optionally ending in ".*".
+var catalogPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*(\.[a-z0-9_-]+)*(\.\*)?$`)
+
+// ErrLastCatalogEntry is returned when removing an entry would leave the
+// catalog empty, which lifts it instead of denying everything.
+var ErrLastCatalogEntry = errors.New("cannot remove the last catalog entry; clear tool_catalog in settings to stop enforcing the catalog")
+
+// ValidateCatalogPattern checks a catalog entry: an exact tool.action
+// ("jira.issue.create"), an action prefix ("jira.issue.*"), or a whole tool
+// ("jira.*").
+func ValidateCatalogPattern(p string) error {
+	if !catalogPattern.MatchString(p) || !strings.Contains(p, ".") {
+		return fmt.Errorf("%q must be tool.action, tool.prefix.* or tool.*", p)
+	}
+	return nil
+}
+
+// CatalogPermits reports whether tool.action matches a catalog pattern. An
+// empty catalog permits everything.
+func CatalogPermits(catalog []string, tool, action string) bool {
+	if len(catalog) == 0 {
+		return true
+	}
+	call := tool + "." + action
+	for _, p := range catalog {
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			if strings.HasPrefix(call, prefix) {
+				return true
+			}
+		} else if p == call {
+			return true
+		}
+	}
+	return false
+}
+
+// CatalogDenial returns why tool.action is outside the tenant's catalog, or
+// "" when the catalog permits it. A settings lookup error denies: the
+// catalog is an allowlist and fails closed.
+func (c *SettingsCache) CatalogDenial(ctx context.Context, tenantID, tool, action string) string {
+	s, err := c.Get(ctx, tenantID)
+	if err != nil {
+		return "tenant tool catalog unavailable"
+	}
+	if !CatalogPermits(s.ToolCatalog, tool, action) {
+		return fmt.Sprintf("%s.%s is not in the tenant's tool catalog", tool, action)
+	}
+	return ""
+}
+
+// GetCatalog handles GET /v1/admin/tenants/{tenant_id}/catalog
+func (h *Handlers) GetCatalog(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "tenant_id")
+	t, err := h.store.Get(r.Context(), id)
+	if err != nil {
+		h.log.Error("get tenant failed", "tenant_id", id, "error", err)
+		types.ErrInternal("failed to get catalog").WriteJSON(w)
+		return
+	}
+	if t == nil {
+		types.ErrNotFound("tenant not found").WriteJSON(w)
+		return
+	}
+	rec, err := h.store.GetSettings(r.Context(), id)
+	if err != nil {
+		h.log.Error("get tenant settings failed", "tenant_id", id, "error", err)
+		types.ErrInternal("failed to get catalog").WriteJSON(w)
+		return
+	}
+	h.writeCatalog(w, id, rec)
+}
+
+// AddCatalogEntry handles PUT /v1/admin/tenants/{tenant_id}/catalog/{entry}.
+// Adding an entry that is already present is a no-op.
+func (h *Handlers) AddCatalogEntry(w http.ResponseWriter, r *http.Request) {
+	entry := strings.ToLower(chi.URLParam(r, "entry"))
+	if err := ValidateCatalogPattern(entry); err != nil {
+		types.ErrValidation(err).WriteJSON(w)
+		return
+	}
+	h.updateCatalog(w, r, func(s *Settings) error {
+		if slices.Contains(s.ToolCatalog, entry) {
+			return errCatalogUnchanged
+		}
+		s.ToolCatalog = append(s.ToolCatalog, entry)
+		return nil
+	})
+}
+
+// RemoveCatalogEntry handles DELETE /v1/admin/tenants/{tenant_id}/catalog/{entry}.
+func (h *Handlers) RemoveCatalogEntry(w http.ResponseWriter, r *http.Request) {
+	entry := strings.ToLower(chi.URLParam(r, "entry"))
+	h.updateCatalog(w, r, func(s *Settings) error {
+		i := slices.Index(s.ToolCatalog, entry)
+		if i < 0 {
+			return errCatalogEntryMissing
+		}
+		if len(s.ToolCatalog) == 1 {
+			return ErrLastCatalogEntry
+		}
+		s.ToolCatalog = slices.Delete(s.ToolCatalog, i, i+1)
+		return nil
+	})
+}
+
+var (
+	errCatalogEntryMissing = errors.New("catalog entry not found")
+	errCatalogUnchanged    = errors.New("catalog unchanged")
+)
+
+// updateCatalog applies fn to the tenant's settings as an audited change.
+func (h *Handlers) updateCatalog(w http.ResponseWriter, r *http.Request, fn func(*Settings) error) {
+	id := chi.URLParam(r, "tenant_id")
+	actor := r.Header.Get("X-Admin-Actor")
+	if actor == "" {
+		actor = "admin"
+	}
+	rec, err := h.store.UpdateSettings(r.Context(), id, actor, fn)
+	if errors.Is(err, errCatalogUnchanged) {
+		// Nothing to audit; report the catalog as it stands.
+		h.GetCatalog(w, r)
+		return
+	}
+	switch {
+	case errors.Is(err, errCatalogEntryMissing):
+		types.ErrNotFound("catalog entry not found").WriteJSON(w)
+		return
+	case errors.Is(err, ErrLastCatalogEntry):
+		types.ErrConflict(ErrLastCatalogEntry.Error()).WriteJSON(w)
+		return
+	case err != nil:
+		h.log.Error("update tenant catalog failed", "tenant_id", id, "error", err)
+		types.ErrInternal("failed to update catalog").WriteJSON(w)
+		return
+	case rec == nil:
+		types.ErrNotFound("tenant not found").WriteJSON(w)
+		return
+	}
+	h.log.Info("tenant tool catalog changed", "tenant_id", id, "version", rec.Version, "actor", actor, "entries", len(rec.Settings.ToolCatalog))
+	if h.OnSettingsChange != nil {
+		h.OnSettingsChange(id)
+	}
+	h.writeCatalog(w, id, rec)
+}
+
+func (h *Handlers) writeCatalog(w http.ResponseWriter, tenantID string, rec *SettingsRecord) {
+	var version int
+	entries := []string{}
+	if rec != nil {
+		version = rec.Version
+		entries = append(entries, rec.Settings.ToolCatalog...)
+	}
+	h.writeJSON(w, http.StatusOK, map[string]any{
+		"tenant_id": tenantID,
+		"enforced":  len(entries) > 0,
+		"entries":   entries,
+		"version":   version,
+	})
+}
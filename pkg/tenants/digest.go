@@ -0,0 +1,71 @@
+package tenants
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/approvals"
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+// Digest periods.
+const (
+	DigestWeekly  = "weekly"
+	DigestMonthly = "monthly"
+)
+
+// Digest schedules a compliance summary of the tenant's activity: calls by
+// decision, the most denied actions, outstanding approvals and evidence
+// chain health. It is sent once per period, shortly after the period ends.
+// For example, a weekly email to the tenant's admins:
+//
+//	{"name": "secops-weekly", "period": "weekly", "timezone": "Europe/Berlin",
+//	 "notify": {"kind": "email", "config": {"to": "secops@acme.com"}}}
+type Digest struct {
+	// Name identifies the digest; the approvals service records the periods
+	// sent under it.
+	Name string `json:"name"`
+	// Period is weekly (Monday to Monday) or monthly.
+	Period string `json:"period"`
+	// Timezone is the IANA zone periods start in; empty means UTC.
+	Timezone string `json:"timezone,omitempty"`
+	// Notify is the route the digest goes to. Its kind must support
+	// digests; email does.
+	Notify types.PolicyNotify `json:"notify"`
+}
+
+// Validate checks the name, period, time zone and route.
+func (d Digest) Validate() error {
+	if d.Name == "" || len(d.Name) > 128 {
+		return errors.New("name is required and at most 128 bytes")
+	}
+	if d.Period != DigestWeekly && d.Period != DigestMonthly {
+		return errors.New("period must be weekly or monthly")
+	}
+	if _, err := time.LoadLocation(d.Timezone); err != nil {
+		return fmt.Errorf("unknown timezone %q", d.Timezone)
+	}
+	if err := approvals.ValidateDigestRoute(d.Notify); err != nil {
+		return fmt.Errorf("notify: %w", err)
+	}
+	return nil
+}
+
+// LastPeriod returns the most recent complete period as of t: from is
+// inclusive and to, the start of the current period, exclusive. A digest
+// that does not validate uses UTC.
+func (d Digest) LastPeriod(t time.Time) (from, to time.Time) {
+	loc, err := time.LoadLocation(d.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	lt := t.In(loc)
+	if d.Period == DigestMonthly {
+		to = time.Date(lt.Year(), lt.Month(), 1, 0, 0, 0, 0, loc)
+		return to.AddDate(0, -1, 0), to
+	}
+	today := time.Date(lt.Year(), lt.Month(), lt.Day(), 0, 0, 0, 0, loc)
+	to = today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+	return to.AddDate(0, 0, -7), to
+}
@@ -0,0 +1,116 @@
+package tenants
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+// FallbackRule is one rule of a tenant's fallback policy, which the gateway
+// evaluates locally when the policy engine cannot be reached. Rules are
+// checked in order and the first match decides; a call no rule matches is
+// denied. For example, to keep low-risk reads working during an OPA
+// outage:
+//
+//	{"name": "low-risk-reads", "read_only": true, "max_risk": 2, "decision": "allow"}
+type FallbackRule struct {
+	// Name identifies the rule in the decision's reason.
+	Name string `json:"name"`
+	// ReadOnly matches only read actions: those their connector's manifest
+	// marks read_only, or by name (see IsReadAction) without a manifest.
+	ReadOnly bool `json:"read_only,omitempty"`
+	// MaxRisk matches calls whose risk_score is at most this.
+	MaxRisk *int `json:"max_risk,omitempty"`
+	// ToolActions are tool catalog patterns; the call's tool.action must
+	// match one when they are set.
+	ToolActions []string `json:"tool_actions,omitempty"`
+	// Decision is allow, approve or deny.
+	Decision types.Decision `json:"decision"`
+}
+
+// Validate checks the name, risk bound, patterns and decision.
+func (r FallbackRule) Validate() error {
+	if r.Name == "" || len(r.Name) > 128 {
+		return errors.New("name is required and at most 128 bytes")
+	}
+	if r.MaxRisk != nil && (*r.MaxRisk < 0 || *r.MaxRisk > types.MaxRiskScore) {
+		return fmt.Errorf("max_risk must be between 0 and %d", types.MaxRiskScore)
+	}
+	for _, p := range r.ToolActions {
+		if err := ValidateCatalogPattern(p); err != nil {
+			return fmt.Errorf("tool_actions: %w", err)
+		}
+	}
+	switch r.Decision {
+	case types.DecisionAllow, types.DecisionApprove, types.DecisionDeny:
+	default:
+		return errors.New("decision must be allow, approve or deny")
+	}
+	return nil
+}
+
+// Matches reports whether the rule decides req, a read when read is set.
+func (r FallbackRule) Matches(req types.ToolCallRequest, read bool) bool {
+	if r.ReadOnly && !read {
+		return false
+	}
+	if r.MaxRisk != nil && req.RiskScore > *r.MaxRisk {
+		return false
+	}
+	return CatalogPermits(r.ToolActions, req.Tool, req.Action)
+}
+
+// FallbackDecision decides req with the tenant's fallback policy, or
+// returns nil when the tenant has none. The result is marked as a
+// fallback so evidence shows policy did not decide the call. As the
+// default policy does, a call over any of budgets is denied and one with
+// prompt-injection findings needs at least approval, whatever the rules
+// say.
+func (c *SettingsCache) FallbackDecision(ctx context.Context, req types.ToolCallRequest, read bool, budgets []types.BudgetState, findings []types.InjectionFinding) (*types.PolicyResult, error) {
+	s, err := c.Get(ctx, req.TenantID)
+	if err != nil {
+		return nil, fmt.Errorf("tenants.FallbackDecision: %w", err)
+	}
+	if len(s.FallbackPolicy) == 0 {
+		return nil, nil
+	}
+	res := &types.PolicyResult{
+		Decision:   types.DecisionDeny,
+		Reason:     "policy unavailable; no fallback rule matched",
+		ReasonCode: types.ReasonCodePolicyFallback,
+		Fallback:   true,
+	}
+	for _, rule := range s.FallbackPolicy {
+		if rule.Matches(req, read) {
+			res.Decision = rule.Decision
+			res.Reason = fmt.Sprintf("policy unavailable; fallback rule %q", rule.Name)
+			res.MatchedRules = []string{"fallback:" + rule.Name}
+			break
+		}
+	}
+	switch {
+	case overBudget(budgets):
+		res.Decision = types.DecisionDeny
+		res.Reason = "policy unavailable; budget exceeded"
+		res.ReasonCode = types.ReasonCodeBudgetExceeded
+		res.MatchedRules = append(res.MatchedRules, "budget.exceeded")
+	case len(findings) > 0 && res.Decision == types.DecisionAllow:
+		res.Decision = types.DecisionApprove
+		res.Reason = "policy unavailable; possible prompt injection in params requires approval"
+		res.ReasonCode = types.ReasonCodePromptInjection
+		res.MatchedRules = append(res.MatchedRules, "injection.suspected")
+		res.RiskOverrides = map[string]int{"prompt_injection": 3}
+	}
+	return res, nil
+}
+
+func overBudget(budgets []types.BudgetState) bool {
+	for _, b := range budgets {
+		if b.Exceeded || b.WouldExceed {
+			return true
+		}
+	}
+	return false
+}
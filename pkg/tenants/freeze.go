@@ -0,0 +1,175 @@
+package tenants
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/schedule"
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+// maxFreezeDuration bounds how long one freeze window stays open.
+const maxFreezeDuration = 7 * 24 * time.Hour
+
+// readVerbs are action suffixes guessed to be side-effect-free reads.
+var readVerbs = map[string]bool{
+	"get":    true,
+	"list":   true,
+	"read":   true,
+	"search": true,
+	"info":   true,
+}
+
+// IsReadAction guesses from its name whether action is a read: one that
+// ends in a read verb such as list or get ("issue.list"); every other
+// action is write-class. It is the fallback for actions no connector
+// manifest describes, whose read_only flag is authoritative.
+func IsReadAction(action string) bool {
+	verb := action
+	if i := strings.LastIndexByte(verb, '.'); i >= 0 {
+		verb = verb[i+1:]
+	}
+	return readVerbs[verb]
+}
+
+// FreezeWindow is a recurring maintenance or change-freeze window: from
+// each time Schedule fires, for Duration, the gateway holds back the
+// tenant's write-class calls. For example, a weekend freeze from Friday
+// 18:00 Berlin time:
+//
+//	{"name": "weekend", "schedule": "0 18 * * fri", "duration": "63h",
+//	 "timezone": "Europe/Berlin", "decision": "deny"}
+type FreezeWindow struct {
+	// Name identifies the window in evidence.
+	Name string `json:"name"`
+	// Schedule is a five-field cron expression for when the window opens,
+	// in Timezone.
+	Schedule string `json:"schedule"`
+	// Duration is how long the window stays open ("2h", "63h"), at most
+	// seven days.
+	Duration string `json:"duration"`
+	// Timezone is an IANA zone name; empty means UTC.
+	Timezone string `json:"timezone,omitempty"`
+	// Decision is deny, which refuses the call without consulting policy,
+	// or approve, which sends calls policy would allow to a human.
+	Decision types.Decision `json:"decision"`
+	// ToolActions narrows the window to tool catalog patterns; empty
+	// covers every write-class action.
+	ToolActions []string `json:"tool_actions,omitempty"`
+
+	// parsed is the window's schedule, duration and zone, set once by the
+	// settings cache so OpenAt need not parse them on every call.
+	parsed *freezeSchedule
+}
+
+type freezeSchedule struct {
+	cron *schedule.Cron
+	d    time.Duration
+	loc  *time.Location
+}
+
+// parse returns the window's parsed schedule, or false when it does not
+// validate.
+func (f FreezeWindow) parse() (*freezeSchedule, bool) {
+	if f.parsed != nil {
+		return f.parsed, true
+	}
+	cron, err := schedule.Parse(f.Schedule)
+	if err != nil {
+		return nil, false
+	}
+	d, err := time.ParseDuration(f.Duration)
+	if err != nil || d <= 0 || d > maxFreezeDuration {
+		return nil, false
+	}
+	loc, err := time.LoadLocation(f.Timezone)
+	if err != nil {
+		return nil, false
+	}
+	return &freezeSchedule{cron: cron, d: d, loc: loc}, true
+}
+
+// Validate checks the name, schedule, duration, time zone, decision and
+// patterns.
+func (f FreezeWindow) Validate() error {
+	if f.Name == "" || len(f.Name) > 128 {
+		return errors.New("name is required and at most 128 bytes")
+	}
+	if _, err := schedule.Parse(f.Schedule); err != nil {
+		return fmt.Errorf("schedule: %w", err)
+	}
+	d, err := time.ParseDuration(f.Duration)
+	if err != nil || d < time.Minute || d > maxFreezeDuration {
+		return fmt.Errorf("duration must be between 1m and %s", maxFreezeDuration)
+	}
+	if _, err := time.LoadLocation(f.Timezone); err != nil {
+		return fmt.Errorf("unknown timezone %q", f.Timezone)
+	}
+	if f.Decision != types.DecisionDeny && f.Decision != types.DecisionApprove {
+		return errors.New("decision must be deny or approve")
+	}
+	for _, p := range f.ToolActions {
+		if err := ValidateCatalogPattern(p); err != nil {
+			return fmt.Errorf("tool_actions: %w", err)
+		}
+	}
+	return nil
+}
+
+// Covers reports whether the window applies to tool.action: the action is
+// write-class (read is false) and matches ToolActions when they are set.
+func (f FreezeWindow) Covers(tool, action string, read bool) bool {
+	return !read && CatalogPermits(f.ToolActions, tool, action)
+}
+
+// OpenAt reports whether the window is open at t. A window that does not
+// validate is never open.
+func (f FreezeWindow) OpenAt(t time.Time) bool {
+	s, ok := f.parse()
+	if !ok {
+		return false
+	}
+	opened, ok := s.cron.LastFire(t.In(s.loc), s.d)
+	return ok && t.Before(opened.Add(s.d))
+}
+
+// parseFreezeWindows parses each of s's freeze windows once, for the
+// settings cache.
+func parseFreezeWindows(s *Settings) {
+	if len(s.FreezeWindows) == 0 {
+		return
+	}
+	windows := make([]FreezeWindow, len(s.FreezeWindows))
+	for i, f := range s.FreezeWindows {
+		f.parsed, _ = f.parse()
+		windows[i] = f
+	}
+	s.FreezeWindows = windows
+}
+
+// ActiveFreeze returns the tenant's freeze window that holds back a call
+// to tool.action, a read when read is set, at t: the first open deny
+// window that covers it, else the first open approve window, else nil.
+func (c *SettingsCache) ActiveFreeze(ctx context.Context, tenantID, tool, action string, read bool, t time.Time) (*FreezeWindow, error) {
+	s, err := c.Get(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("tenants.ActiveFreeze: %w", err)
+	}
+	var approve *FreezeWindow
+	for i := range s.FreezeWindows {
+		f := &s.FreezeWindows[i]
+		if !f.Covers(tool, action, read) || !f.OpenAt(t) {
+			continue
+		}
+		if f.Decision == types.DecisionDeny {
+			return f, nil
+		}
+		if approve == nil {
+			approve = f
+		}
+	}
+	return approve, nil
+}
@@ -0,0 +1,438 @@
+package tenants
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"github.com/go-chi/chi/v5"
+)
+
+const maxBodyBytes = 64 << 10
+
+type handlersStore interface {
+	Create(ctx context.Context, id, name string, config json.RawMessage) (*Tenant, *APIKey, string, error)
+	Get(ctx context.Context, id string) (*Tenant, error)
+	List(ctx context.Context, status string) ([]Tenant, error)
+	SetStatus(ctx context.Context, id, status string) (*Tenant, error)
+	Delete(ctx context.Context, id string) (bool, error)
+	CreateAPIKey(ctx context.Context, tenantID, label string) (*APIKey, string, error)
+	ListAPIKeys(ctx context.Context, tenantID string) ([]APIKey, error)
+	RevokeAPIKey(ctx context.Context, tenantID, keyID string) (bool, error)
+	GetSettings(ctx context.Context, tenantID string) (*SettingsRecord, error)
+	PutSettings(ctx context.Context, tenantID string, settings Settings, actor string) (*SettingsRecord, error)
+	UpdateSettings(ctx context.Context, tenantID, actor string, fn func(*Settings) error) (*SettingsRecord, error)
+	SettingsHistory(ctx context.Context, tenantID string, limit int) ([]SettingsChange, error)
+	AddBlock(ctx context.Context, tenantID string, b Block) (*Block, error)
+	ListBlocks(ctx context.Context, tenantID string) ([]Block, error)
+	RemoveBlock(ctx context.Context, tenantID, id string) (bool, error)
+}
+
+// PolicyData writes the per-tenant policy document (data.tenants[id]).
+type PolicyData interface {
+	PutTenantData(ctx context.Context, tenantID string, doc json.RawMessage) error
+	DeleteTenantData(ctx context.Context, tenantID string) error
+}
+
+// Handlers exposes the operator-only tenant admin API. Routes must be mounted
+// behind auth.AdminAuth, never behind tenant API key auth.
+type Handlers struct {
+	store    handlersStore
+	policy   PolicyData
+	defaults map[string]json.RawMessage
+	log      *slog.Logger
+
+	// OnChange, if set, is called after any change that affects which API
+	// keys authenticate, so the caller can reload its key store.
+	OnChange func(ctx context.Context)
+	// OnSettingsChange, if set, is called after a tenant's settings change so
+	// the caller can drop cached settings.
+	OnSettingsChange func(tenantID string)
+}
+
+// NewHandlers creates tenant admin handlers. defaults is merged under the
+// config of every new tenant; policy may be nil to disable seed policies.
+func NewHandlers(store handlersStore, policy PolicyData, defaults map[string]json.RawMessage, log *slog.Logger) *Handlers {
+	if log == nil {
+		log = slog.Default()
+	}
+	return &Handlers{store: store, policy: policy, defaults: defaults, log: log}
+}
+
+// ParseDefaults parses TENANT_DEFAULT_CONFIG, a JSON object. Empty is allowed.
+func ParseDefaults(raw string) (map[string]json.RawMessage, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(raw), &m); err != nil || m == nil {
+		return nil, fmt.Errorf("tenants.ParseDefaults: must be a JSON object")
+	}
+	return m, nil
+}
+
+// PolicyDoc returns the seed policy document stored in a tenant's config, or
+// nil if it has none.
+func PolicyDoc(t Tenant) json.RawMessage {
+	var cfg map[string]json.RawMessage
+	if json.Unmarshal(t.Config, &cfg) != nil {
+		return nil
+	}
+	doc := cfg["policy"]
+	if len(doc) == 0 || doc[0] != '{' {
+		return nil
+	}
+	return doc
+}
+
+// RegisterRoutes mounts the tenant admin endpoints on r.
+func (h *Handlers) RegisterRoutes(r chi.Router) {
+	r.Post("/v1/admin/tenants", h.Create)
+	r.Get("/v1/admin/tenants", h.List)
+	r.Get("/v1/admin/tenants/{tenant_id}", h.Get)
+	r.Delete("/v1/admin/tenants/{tenant_id}", h.Delete)
+	r.Post("/v1/admin/tenants/{tenant_id}/suspend", h.Suspend)
+	r.Post("/v1/admin/tenants/{tenant_id}/resume", h.Resume)
+	r.Get("/v1/admin/tenants/{tenant_id}/api-keys", h.ListAPIKeys)
+	r.Post("/v1/admin/tenants/{tenant_id}/api-keys", h.CreateAPIKey)
+	r.Delete("/v1/admin/tenants/{tenant_id}/api-keys/{key_id}", h.RevokeAPIKey)
+	r.Get("/v1/admin/tenants/{tenant_id}/settings", h.GetSettings)
+	r.Put("/v1/admin/tenants/{tenant_id}/settings", h.PutSettings)
+	r.Get("/v1/admin/tenants/{tenant_id}/settings/history", h.SettingsHistory)
+	r.Get("/v1/admin/tenants/{tenant_id}/catalog", h.GetCatalog)
+	r.Put("/v1/admin/tenants/{tenant_id}/catalog/{entry}", h.AddCatalogEntry)
+	r.Delete("/v1/admin/tenants/{tenant_id}/catalog/{entry}", h.RemoveCatalogEntry)
+	r.Get("/v1/admin/tenants/{tenant_id}/blocklist", h.ListBlocks)
+	r.Post("/v1/admin/tenants/{tenant_id}/blocklist", h.AddBlock)
+	r.Delete("/v1/admin/tenants/{tenant_id}/blocklist/{block_id}", h.RemoveBlock)
+}
+
+type createRequest struct {
+	ID     string                     `json:"id"`
+	Name   string                     `json:"name"`
+	Config map[string]json.RawMessage `json:"config,omitempty"`
+	Policy json.RawMessage            `json:"policy,omitempty"`
+}
+
+// Create handles POST /v1/admin/tenants
+func (h *Handlers) Create(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	var in createRequest
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		types.ErrBadRequest("invalid JSON body").WriteJSON(w)
+		return
+	}
+	if err := ValidateID(in.ID); err != nil {
+		types.ErrValidation(err).WriteJSON(w)
+		return
+	}
+	if in.Name == "" {
+		types.ErrBadRequest("name is required").WriteJSON(w)
+		return
+	}
+	if len(in.Policy) > 0 && in.Policy[0] != '{' {
+		types.ErrBadRequest("policy must be a JSON object").WriteJSON(w)
+		return
+	}
+
+	cfg := make(map[string]json.RawMessage, len(h.defaults)+len(in.Config)+1)
+	for k, v := range h.defaults {
+		cfg[k] = v
+	}
+	for k, v := range in.Config {
+		cfg[k] = v
+	}
+	if len(in.Policy) > 0 {
+		cfg["policy"] = in.Policy
+	}
+	cfgJSON, err := json.Marshal(cfg)
+	if err != nil {
+		types.ErrBadRequest("invalid config").WriteJSON(w)
+		return
+	}
+
+	t, key, raw, err := h.store.Create(r.Context(), in.ID, in.Name, cfgJSON)
+	if errors.Is(err, ErrExists) {
+		types.ErrConflict("tenant already exists").WriteJSON(w)
+		return
+	}
+	if err != nil {
+		h.log.Error("create tenant failed", "tenant_id", in.ID, "error", err)
+		types.ErrInternal("failed to create tenant").WriteJSON(w)
+		return
+	}
+	h.log.Info("tenant created", "tenant_id", t.ID, "key_id", key.ID)
+	h.changed(r.Context())
+
+	// A failed push is reported but does not undo the tenant: PolicySync
+	// pushes every tenant's document again on its next full sync.
+	policySynced := false
+	if h.policy != nil {
+		doc, err := PolicyDocFor(*t, Settings{})
+		if err == nil {
+			err = h.policy.PutTenantData(r.Context(), t.ID, doc)
+		}
+		if err != nil {
+			h.log.Error("seed tenant policy failed", "tenant_id", t.ID, "error", err)
+		} else {
+			policySynced = true
+		}
+	}
+
+	resp := map[string]any{"tenant": t, "api_key": key, "key": raw}
+	if len(in.Policy) > 0 {
+		resp["policy_synced"] = policySynced
+	}
+	h.writeJSON(w, http.StatusCreated, resp)
+}
+
+// List handles GET /v1/admin/tenants
+func (h *Handlers) List(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", StatusActive, StatusSuspended, StatusDeleted:
+	default:
+		types.ErrBadRequest("status must be active, suspended or deleted").WriteJSON(w)
+		return
+	}
+	ts, err := h.store.List(r.Context(), status)
+	if err != nil {
+		h.log.Error("list tenants failed", "error", err)
+		types.ErrInternal("failed to list tenants").WriteJSON(w)
+		return
+	}
+	if ts == nil {
+		ts = []Tenant{}
+	}
+	h.writeJSON(w, http.StatusOK, map[string]any{"tenants": ts})
+}
+
+// Get handles GET /v1/admin/tenants/{tenant_id}
+func (h *Handlers) Get(w http.ResponseWriter, r *http.Request) {
+	t, err := h.store.Get(r.Context(), chi.URLParam(r, "tenant_id"))
+	if err != nil {
+		h.log.Error("get tenant failed", "error", err)
+		types.ErrInternal("failed to get tenant").WriteJSON(w)
+		return
+	}
+	if t == nil {
+		types.ErrNotFound("tenant not found").WriteJSON(w)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, t)
+}
+
+// Suspend handles POST /v1/admin/tenants/{tenant_id}/suspend
+func (h *Handlers) Suspend(w http.ResponseWriter, r *http.Request) {
+	h.setStatus(w, r, StatusSuspended)
+}
+
+// Resume handles POST /v1/admin/tenants/{tenant_id}/resume
+func (h *Handlers) Resume(w http.ResponseWriter, r *http.Request) {
+	h.setStatus(w, r, StatusActive)
+}
+
+func (h *Handlers) setStatus(w http.ResponseWriter, r *http.Request, status string) {
+	id := chi.URLParam(r, "tenant_id")
+	t, err := h.store.SetStatus(r.Context(), id, status)
+	if err != nil {
+		h.log.Error("set tenant status failed", "tenant_id", id, "status", status, "error", err)
+		types.ErrInternal("failed to update tenant").WriteJSON(w)
+		return
+	}
+	if t == nil {
+		types.ErrNotFound("tenant not found").WriteJSON(w)
+		return
+	}
+	h.log.Info("tenant status changed", "tenant_id", id, "status", status)
+	h.changed(r.Context())
+	h.writeJSON(w, http.StatusOK, t)
+}
+
+// Delete handles DELETE /v1/admin/tenants/{tenant_id}
+func (h *Handlers) Delete(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "tenant_id")
+	found, err := h.store.Delete(r.Context(), id)
+	if err != nil {
+		h.log.Error("delete tenant failed", "tenant_id", id, "error", err)
+		types.ErrInternal("failed to delete tenant").WriteJSON(w)
+		return
+	}
+	if !found {
+		types.ErrNotFound("tenant not found").WriteJSON(w)
+		return
+	}
+	h.log.Info("tenant deleted", "tenant_id", id)
+	h.changed(r.Context())
+	if h.policy != nil {
+		if err := h.policy.DeleteTenantData(r.Context(), id); err != nil {
+			h.log.Error("delete tenant policy failed", "tenant_id", id, "error", err)
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListAPIKeys handles GET /v1/admin/tenants/{tenant_id}/api-keys
+func (h *Handlers) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.store.ListAPIKeys(r.Context(), chi.URLParam(r, "tenant_id"))
+	if err != nil {
+		h.log.Error("list api keys failed", "error", err)
+		types.ErrInternal("failed to list api keys").WriteJSON(w)
+		return
+	}
+	if keys == nil {
+		keys = []APIKey{}
+	}
+	h.writeJSON(w, http.StatusOK, map[string]any{"api_keys": keys})
+}
+
+// CreateAPIKey handles POST /v1/admin/tenants/{tenant_id}/api-keys
+func (h *Handlers) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "tenant_id")
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	var in struct {
+		Label string `json:"label"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil && !errors.Is(err, io.EOF) {
+		types.ErrBadRequest("invalid JSON body").WriteJSON(w)
+		return
+	}
+	key, raw, err := h.store.CreateAPIKey(r.Context(), id, in.Label)
+	if err != nil {
+		h.log.Error("create api key failed", "tenant_id", id, "error", err)
+		types.ErrInternal("failed to create api key").WriteJSON(w)
+		return
+	}
+	if key == nil {
+		types.ErrNotFound("tenant not found").WriteJSON(w)
+		return
+	}
+	h.log.Info("tenant api key created", "tenant_id", id, "key_id", key.ID)
+	h.changed(r.Context())
+	h.writeJSON(w, http.StatusCreated, map[string]any{"api_key": key, "key": raw})
+}
+
+// RevokeAPIKey handles DELETE /v1/admin/tenants/{tenant_id}/api-keys/{key_id}
+func (h *Handlers) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, keyID := chi.URLParam(r, "tenant_id"), chi.URLParam(r, "key_id")
+	found, err := h.store.RevokeAPIKey(r.Context(), id, keyID)
+	if err != nil {
+		h.log.Error("revoke api key failed", "tenant_id", id, "error", err)
+		types.ErrInternal("failed to revoke api key").WriteJSON(w)
+		return
+	}
+	if !found {
+		types.ErrNotFound("api key not found").WriteJSON(w)
+		return
+	}
+	h.log.Info("tenant api key revoked", "tenant_id", id, "key_id", keyID)
+	h.changed(r.Context())
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetSettings handles GET /v1/admin/tenants/{tenant_id}/settings
+func (h *Handlers) GetSettings(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "tenant_id")
+	t, err := h.store.Get(r.Context(), id)
+	if err != nil {
+		h.log.Error("get tenant failed", "tenant_id", id, "error", err)
+		types.ErrInternal("failed to get settings").WriteJSON(w)
+		return
+	}
+	if t == nil {
+		types.ErrNotFound("tenant not found").WriteJSON(w)
+		return
+	}
+	rec, err := h.store.GetSettings(r.Context(), id)
+	if err != nil {
+		h.log.Error("get tenant settings failed", "tenant_id", id, "error", err)
+		types.ErrInternal("failed to get settings").WriteJSON(w)
+		return
+	}
+	if rec == nil {
+		// Never configured: every setting is the deployment default.
+		rec = &SettingsRecord{TenantID: id, UpdatedAt: t.CreatedAt}
+	}
+	h.writeJSON(w, http.StatusOK, rec)
+}
+
+// PutSettings handles PUT /v1/admin/tenants/{tenant_id}/settings. The body
+// replaces all settings; the optional X-Admin-Actor header is recorded in
+// the change audit.
+func (h *Handlers) PutSettings(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "tenant_id")
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	var in Settings
+	if err := dec.Decode(&in); err != nil {
+		types.ErrBadRequest("invalid JSON body: " + err.Error()).WriteJSON(w)
+		return
+	}
+	if err := in.Validate(); err != nil {
+		types.ErrValidation(err).WriteJSON(w)
+		return
+	}
+	actor := r.Header.Get("X-Admin-Actor")
+	if actor == "" {
+		actor = "admin"
+	}
+	rec, err := h.store.PutSettings(r.Context(), id, in, actor)
+	if err != nil {
+		h.log.Error("put tenant settings failed", "tenant_id", id, "error", err)
+		types.ErrInternal("failed to store settings").WriteJSON(w)
+		return
+	}
+	if rec == nil {
+		types.ErrNotFound("tenant not found").WriteJSON(w)
+		return
+	}
+	h.log.Info("tenant settings changed", "tenant_id", id, "version", rec.Version, "actor", actor)
+	if h.OnSettingsChange != nil {
+		h.OnSettingsChange(id)
+	}
+	h.writeJSON(w, http.StatusOK, rec)
+}
+
+// SettingsHistory handles GET /v1/admin/tenants/{tenant_id}/settings/history
+func (h *Handlers) SettingsHistory(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 500 {
+			types.ErrBadRequest("limit must be between 1 and 500").WriteJSON(w)
+			return
+		}
+		limit = n
+	}
+	changes, err := h.store.SettingsHistory(r.Context(), chi.URLParam(r, "tenant_id"), limit)
+	if err != nil {
+		h.log.Error("tenant settings history failed", "error", err)
+		types.ErrInternal("failed to list settings history").WriteJSON(w)
+		return
+	}
+	if changes == nil {
+		changes = []SettingsChange{}
+	}
+	h.writeJSON(w, http.StatusOK, map[string]any{"changes": changes})
+}
+
+func (h *Handlers) changed(ctx context.Context) {
+	if h.OnChange != nil {
+		h.OnChange(ctx)
+	}
+}
+
+func (h *Handlers) writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		h.log.Error("response encode failed", "error", err)
+	}
+}
@@ -0,0 +1,43 @@
+package tenants
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+
+	"github.com/bturcanu/OpenClause/pkg/connectors"
+)
+
+// toolName is a lowercase tool, as in the first segment of a catalog pattern.
+var toolName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// validateConnectorPins checks that every pin maps a tool to a valid
+// connector version label.
+func validateConnectorPins(pins map[string]string) error {
+	var errs []error
+	for _, tool := range slices.Sorted(maps.Keys(pins)) {
+		label := pins[tool]
+		if !toolName.MatchString(tool) {
+			errs = append(errs, fmt.Errorf("%q must be a tool name", tool))
+			continue
+		}
+		if err := connectors.ValidateLabel(label); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", tool, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ConnectorPin returns the version label the tenant pinned tool to, or ""
+// when it follows the route's weights. A lookup error is returned so the
+// call is not sent to a version the tenant may have pinned away from.
+func (c *SettingsCache) ConnectorPin(ctx context.Context, tenantID, tool string) (string, error) {
+	s, err := c.Get(ctx, tenantID)
+	if err != nil {
+		return "", fmt.Errorf("tenants.ConnectorPin: %w", err)
+	}
+	return s.ConnectorPins[tool], nil
+}
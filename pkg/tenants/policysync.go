@@ -0,0 +1,172 @@
+package tenants
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// PolicyDocFor builds a tenant's policy data document (data.tenants[id]):
+// its seed policy document, plus from its settings the tool catalog as
+// tool_catalog, the budgets as budgets, and the approver group as
+// approver_group unless the seed document names one. Settings keys are
+// present only when set.
+func PolicyDocFor(t Tenant, s Settings) (json.RawMessage, error) {
+	doc := map[string]json.RawMessage{}
+	if seed := PolicyDoc(t); seed != nil {
+		if err := json.Unmarshal(seed, &doc); err != nil {
+			return nil, fmt.Errorf("tenants.PolicyDocFor: seed policy: %w", err)
+		}
+	}
+	set := func(key string, v any) error {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("tenants.PolicyDocFor: %s: %w", key, err)
+		}
+		doc[key] = b
+		return nil
+	}
+	if len(s.ToolCatalog) > 0 {
+		if err := set("tool_catalog", s.ToolCatalog); err != nil {
+			return nil, err
+		}
+	}
+	if len(s.Budgets) > 0 {
+		if err := set("budgets", s.Budgets); err != nil {
+			return nil, err
+		}
+	}
+	if _, ok := doc["approver_group"]; !ok && s.ApproverGroup != "" {
+		if err := set("approver_group", s.ApproverGroup); err != nil {
+			return nil, err
+		}
+	}
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("tenants.PolicyDocFor: %w", err)
+	}
+	return b, nil
+}
+
+type policySyncStore interface {
+	Get(ctx context.Context, id string) (*Tenant, error)
+	List(ctx context.Context, status string) ([]Tenant, error)
+	GetSettings(ctx context.Context, tenantID string) (*SettingsRecord, error)
+}
+
+// PolicySync keeps each tenant's policy data document in the policy engine
+// current, so policies can read tenant data from data.tenants instead of the
+// gateway adding it to every input. Changes are pushed as they are notified;
+// a periodic full sync restores documents an OPA restart lost and picks up
+// changes made through other gateway replicas.
+type PolicySync struct {
+	store  policySyncStore
+	policy PolicyData
+	log    *slog.Logger
+
+	mu      sync.Mutex
+	pending map[string]bool
+	wake    chan struct{}
+}
+
+// NewPolicySync creates a PolicySync; call Run to start it.
+func NewPolicySync(store policySyncStore, policy PolicyData, log *slog.Logger) *PolicySync {
+	if log == nil {
+		log = slog.Default()
+	}
+	return &PolicySync{store: store, policy: policy, log: log, pending: make(map[string]bool), wake: make(chan struct{}, 1)}
+}
+
+// Notify queues a push of the tenant's document. It does not block; repeated
+// notifications before the push are coalesced.
+func (p *PolicySync) Notify(tenantID string) {
+	p.mu.Lock()
+	p.pending[tenantID] = true
+	p.mu.Unlock()
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Sync pushes the tenant's document now. The document of a deleted or
+// unknown tenant is removed.
+func (p *PolicySync) Sync(ctx context.Context, tenantID string) error {
+	t, err := p.store.Get(ctx, tenantID)
+	if err != nil {
+		return fmt.Errorf("tenants.PolicySync: get tenant: %w", err)
+	}
+	if t == nil || t.Status == StatusDeleted {
+		if err := p.policy.DeleteTenantData(ctx, tenantID); err != nil {
+			return fmt.Errorf("tenants.PolicySync: %w", err)
+		}
+		return nil
+	}
+	return p.push(ctx, *t)
+}
+
+// SyncAll pushes the document of every active and suspended tenant. Failures
+// are logged and the rest are still pushed.
+func (p *PolicySync) SyncAll(ctx context.Context) {
+	for _, status := range []string{StatusActive, StatusSuspended} {
+		ts, err := p.store.List(ctx, status)
+		if err != nil {
+			p.log.Error("list tenants for policy sync failed", "status", status, "error", err)
+			continue
+		}
+		for _, t := range ts {
+			if err := p.push(ctx, t); err != nil {
+				p.log.Error("tenant policy sync failed", "tenant_id", t.ID, "error", err)
+			}
+		}
+	}
+}
+
+func (p *PolicySync) push(ctx context.Context, t Tenant) error {
+	rec, err := p.store.GetSettings(ctx, t.ID)
+	if err != nil {
+		return fmt.Errorf("tenants.PolicySync: get settings: %w", err)
+	}
+	var s Settings
+	if rec != nil {
+		s = rec.Settings
+	}
+	doc, err := PolicyDocFor(t, s)
+	if err != nil {
+		return err
+	}
+	if err := p.policy.PutTenantData(ctx, t.ID, doc); err != nil {
+		return fmt.Errorf("tenants.PolicySync: %w", err)
+	}
+	return nil
+}
+
+// Run syncs every tenant, then pushes notified tenants as they come and
+// syncs every tenant again each interval, until ctx is done. A failed push
+// of a notified tenant is logged and left to the next full sync.
+func (p *PolicySync) Run(ctx context.Context, interval time.Duration) {
+	p.SyncAll(ctx)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.SyncAll(ctx)
+		case <-p.wake:
+			p.mu.Lock()
+			ids := p.pending
+			p.pending = make(map[string]bool)
+			p.mu.Unlock()
+			for id := range ids {
+				if err := p.Sync(ctx, id); err != nil {
+					p.log.Error("tenant policy sync failed", "tenant_id", id, "error", err)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package tenants
+
+import (
+	"errors"
+	"time"
+)
+
+// Rate limit windows.
+var rateLimitWindows = map[string]time.Duration{
+	"second": time.Second,
+	"minute": time.Minute,
+	"hour":   time.Hour,
+}
+
+// RateLimitEachAgent as a rate limit's agent_id limits every agent
+// separately.
+const RateLimitEachAgent = "*"
+
+// RateLimit caps calls on one slice of a tenant's traffic, e.g. 10
+// jira.issue.create per minute for each agent:
+//
+//	{"agent_id": "*", "tool_action": "jira.issue.create", "limit": 10, "per": "minute"}
+type RateLimit struct {
+	// AgentID scopes the limit: "" counts all agents together, "*" counts
+	// each agent separately, and any other value limits that one agent.
+	AgentID string `json:"agent_id,omitempty"`
+	// ToolAction is a tool catalog pattern ("jira.issue.create",
+	// "jira.issue.*", "jira.*"); calls that match share the limit. Empty
+	// matches every call.
+	ToolAction string `json:"tool_action,omitempty"`
+	Limit      int    `json:"limit"`
+	// Per is the window Limit applies to: second, minute or hour.
+	Per string `json:"per"`
+	// Burst is how many calls may be made at once; it defaults to Limit.
+	Burst int `json:"burst,omitempty"`
+}
+
+// Validate checks the limit, window, scope and pattern.
+func (l RateLimit) Validate() error {
+	if l.Limit <= 0 {
+		return errors.New("limit must be positive")
+	}
+	if _, ok := rateLimitWindows[l.Per]; !ok {
+		return errors.New("per must be second, minute or hour")
+	}
+	if l.Burst < 0 {
+		return errors.New("burst must not be negative")
+	}
+	if l.AgentID == "" && l.ToolAction == "" {
+		return errors.New("set agent_id or tool_action; use rate_limit_per_sec for the whole tenant")
+	}
+	if len(l.AgentID) > 128 {
+		return errors.New("agent_id is too long")
+	}
+	if l.ToolAction != "" {
+		if err := ValidateCatalogPattern(l.ToolAction); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Matches reports whether a call by agentID to tool.action counts against
+// the limit.
+func (l RateLimit) Matches(agentID, tool, action string) bool {
+	if l.AgentID != "" && l.AgentID != RateLimitEachAgent && l.AgentID != agentID {
+		return false
+	}
+	return l.ToolAction == "" || CatalogPermits([]string{l.ToolAction}, tool, action)
+}
+
+// PerSecond returns the limit as a rate per second.
+func (l RateLimit) PerSecond() float64 {
+	return float64(l.Limit) / rateLimitWindows[l.Per].Seconds()
+}
+
+// BurstOrLimit returns Burst, or Limit when Burst is unset.
+func (l RateLimit) BurstOrLimit() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return l.Limit
+}
@@ -0,0 +1,538 @@
+package tenants
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/approvals"
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	minApprovalTTL = types.MinApprovalTTL
+	maxApprovalTTL = types.MaxApprovalTTL
+
+	maxAgentConcurrency = 10_000
+)
+
+// Settings are tenant-level governance settings. A zero field means the
+// deployment-wide default applies.
+type Settings struct {
+	// ApprovalTTLSec is how long a new approval request stays pending.
+	ApprovalTTLSec int `json:"approval_ttl_sec,omitempty"`
+	// GrantTTLSec is how long a grant lasts when neither its approver nor
+	// the policy decision sets a lifetime.
+	GrantTTLSec int `json:"grant_ttl_sec,omitempty"`
+	// ApproverGroup is used when the policy decision names none.
+	ApproverGroup string `json:"approver_group,omitempty"`
+	// Notify is used when the policy decision lists no notification routes.
+	Notify []types.PolicyNotify `json:"notify,omitempty"`
+	// RequestableApproverGroups and RequestableNotifyChannels are the
+	// approver groups and Slack channels a call may ask for with
+	// approver_group and notify_channel; requests for others are ignored.
+	RequestableApproverGroups []string `json:"requestable_approver_groups,omitempty"`
+	RequestableNotifyChannels []string `json:"requestable_notify_channels,omitempty"`
+	// NotificationKey is a public JWK; when set, the data of the tenant's
+	// webhook notifications is encrypted to it as a JWE.
+	NotificationKey *approvals.EncryptionKey `json:"notification_encryption_key,omitempty"`
+	// WebhookEgress replaces the deployment's egress policy for the
+	// tenant's webhook notifications, event subscriptions and result sinks.
+	WebhookEgress *approvals.EgressPolicy `json:"webhook_egress,omitempty"`
+	// RetentionDays is how long the archiver keeps evidence bundles.
+	RetentionDays int `json:"retention_days,omitempty"`
+	// RateLimitPerSec and RateLimitBurst override RATE_LIMIT_PER_TENANT.
+	RateLimitPerSec int `json:"rate_limit_per_sec,omitempty"`
+	RateLimitBurst  int `json:"rate_limit_burst,omitempty"`
+	// RateLimits are further limits on slices of the tenant's traffic, by
+	// agent and tool.action. A call must pass all of them.
+	RateLimits []RateLimit `json:"rate_limits,omitempty"`
+	// AgentMaxConcurrentExecutions overrides AGENT_MAX_CONCURRENT_EXECUTIONS:
+	// how many calls each of the tenant's agents may have in flight.
+	AgentMaxConcurrentExecutions int `json:"agent_max_concurrent_executions,omitempty"`
+	// MaxPriority is the highest load-shedding priority the tenant's calls
+	// may declare; a higher declared priority is lowered to it. Unset
+	// means normal, so agents cannot exempt themselves from shedding.
+	MaxPriority types.Priority `json:"max_priority,omitempty"`
+	// EventSubscriptions receive the tenant's lifecycle CloudEvents.
+	EventSubscriptions []types.EventSubscription `json:"event_subscriptions,omitempty"`
+	// ResultSinks receive the tenant's oc.toolcall.executed events by
+	// webhook or on the event bus, for reconciliation without polling.
+	ResultSinks []types.ResultSink `json:"result_sinks,omitempty"`
+	// ToolCatalog lists the tool.action patterns the tenant may call. When
+	// it is set, the gateway denies every other call before policy runs.
+	ToolCatalog []string `json:"tool_catalog,omitempty"`
+	// Budgets cap what the tenant and its agents spend per day or month.
+	// The gateway hands their state to policy, which enforces them.
+	Budgets []types.Budget `json:"budgets,omitempty"`
+	// AutoApprovals are rules under which the approvals service approves
+	// requests without a human; the first that matches applies.
+	AutoApprovals []AutoApproval `json:"auto_approvals,omitempty"`
+	// FreezeWindows are recurring windows during which the gateway denies
+	// or escalates the tenant's write-class calls regardless of policy.
+	FreezeWindows []FreezeWindow `json:"freeze_windows,omitempty"`
+	// GrantHours limits use of approval grants that do not set their own
+	// valid_hours to calls made inside the window.
+	GrantHours *types.TimeWindow `json:"grant_hours,omitempty"`
+	// Digests are periodic compliance summaries sent to the tenant's
+	// admins.
+	Digests []Digest `json:"digests,omitempty"`
+	// ConnectorPins maps a tool to the connector version label (see
+	// CONNECTOR_ROUTES) that serves the tenant's calls to it, e.g.
+	// {"jira": "v1"}. A pinned call fails rather than reach another version.
+	ConnectorPins map[string]string `json:"connector_pins,omitempty"`
+	// FallbackPolicy decides the tenant's calls when the policy engine
+	// cannot be reached; without it they are denied.
+	FallbackPolicy []FallbackRule `json:"fallback_policy,omitempty"`
+	// CanaryResources are decoys no agent should touch. The gateway denies
+	// any call that does and raises a security alert.
+	CanaryResources []Canary `json:"canary_resources,omitempty"`
+}
+
+// Validate checks ranges, rate limits, notification routes and encryption
+// key, the egress policy, event subscriptions, result sinks, tool catalog
+// patterns, budgets, auto-approval rules, freeze windows, grant hours,
+// digests, connector pins, fallback rules, and canary resources. Subscription and sink URLs
+// are checked against the tenant's egress policy when it has one.
+func (s Settings) Validate() error {
+	var errs []error
+	if s.ApprovalTTLSec != 0 {
+		ttl := time.Duration(s.ApprovalTTLSec) * time.Second
+		if ttl < minApprovalTTL || ttl > maxApprovalTTL {
+			errs = append(errs, fmt.Errorf("approval_ttl_sec must be between %d and %d", int(minApprovalTTL.Seconds()), int(maxApprovalTTL.Seconds())))
+		}
+	}
+	if s.GrantTTLSec != 0 {
+		ttl := time.Duration(s.GrantTTLSec) * time.Second
+		if ttl < minApprovalTTL || ttl > maxApprovalTTL {
+			errs = append(errs, fmt.Errorf("grant_ttl_sec must be between %d and %d", int(minApprovalTTL.Seconds()), int(maxApprovalTTL.Seconds())))
+		}
+	}
+	if s.RetentionDays < 0 {
+		errs = append(errs, errors.New("retention_days must not be negative"))
+	}
+	if s.RateLimitPerSec < 0 || s.RateLimitBurst < 0 {
+		errs = append(errs, errors.New("rate limits must not be negative"))
+	}
+	if s.AgentMaxConcurrentExecutions < 0 || s.AgentMaxConcurrentExecutions > maxAgentConcurrency {
+		errs = append(errs, fmt.Errorf("agent_max_concurrent_executions must be between 0 and %d", maxAgentConcurrency))
+	}
+	switch s.MaxPriority {
+	case "", types.PriorityLow, types.PriorityNormal, types.PriorityHigh:
+	default:
+		errs = append(errs, errors.New("max_priority must be low, normal or high"))
+	}
+	if s.RateLimitBurst > 0 && s.RateLimitPerSec == 0 {
+		errs = append(errs, errors.New("rate_limit_burst requires rate_limit_per_sec"))
+	}
+	for i, l := range s.RateLimits {
+		if err := l.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("rate_limits[%d]: %w", i, err))
+		}
+	}
+	for i, n := range s.Notify {
+		if err := approvals.ValidateNotifyRoute(n); err != nil {
+			errs = append(errs, fmt.Errorf("notify[%d]: %w", i, err))
+		}
+	}
+	for i, g := range s.RequestableApproverGroups {
+		if g == "" || len(g) > 128 {
+			errs = append(errs, fmt.Errorf("requestable_approver_groups[%d] must be 1 to 128 bytes", i))
+		}
+	}
+	for i, ch := range s.RequestableNotifyChannels {
+		if ch == "" || len(ch) > 80 {
+			errs = append(errs, fmt.Errorf("requestable_notify_channels[%d] must be 1 to 80 bytes", i))
+		}
+	}
+	for i, p := range s.ToolCatalog {
+		if err := ValidateCatalogPattern(p); err != nil {
+			errs = append(errs, fmt.Errorf("tool_catalog[%d]: %w", i, err))
+		}
+	}
+	for i, b := range s.Budgets {
+		if err := b.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("budgets[%d]: %w", i, err))
+		}
+	}
+	for i, a := range s.AutoApprovals {
+		if err := a.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("auto_approvals[%d]: %w", i, err))
+		}
+	}
+	for i, f := range s.FreezeWindows {
+		if err := f.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("freeze_windows[%d]: %w", i, err))
+		}
+	}
+	if s.GrantHours != nil {
+		if err := s.GrantHours.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("grant_hours: %w", err))
+		}
+	}
+	digests := map[string]bool{}
+	for i, d := range s.Digests {
+		if err := d.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("digests[%d]: %w", i, err))
+		} else if digests[d.Name] {
+			errs = append(errs, fmt.Errorf("digests[%d]: duplicate name %q", i, d.Name))
+		}
+		digests[d.Name] = true
+	}
+	if err := validateConnectorPins(s.ConnectorPins); err != nil {
+		errs = append(errs, fmt.Errorf("connector_pins: %w", err))
+	}
+	for i, r := range s.FallbackPolicy {
+		if err := r.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("fallback_policy[%d]: %w", i, err))
+		}
+	}
+	for i, c := range s.CanaryResources {
+		if err := c.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("canary_resources[%d]: %w", i, err))
+		}
+	}
+	if s.NotificationKey != nil {
+		if err := s.NotificationKey.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("notification_encryption_key: %w", err))
+		}
+	}
+	var egress approvals.EgressPolicy
+	if s.WebhookEgress != nil {
+		if err := s.WebhookEgress.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("webhook_egress: %w", err))
+		}
+		egress = *s.WebhookEgress
+	}
+	for i, sub := range s.EventSubscriptions {
+		if err := egress.CheckURL(sub.URL); err != nil {
+			errs = append(errs, fmt.Errorf("event_subscriptions[%d]: url: %w", i, err))
+		}
+		for _, t := range sub.Types {
+			if _, ok := types.EventRegistry[t]; !ok {
+				errs = append(errs, fmt.Errorf("event_subscriptions[%d]: unknown event type %q", i, t))
+			}
+		}
+	}
+	for i, sink := range s.ResultSinks {
+		switch sink.Type {
+		case types.ResultSinkWebhook:
+			if err := egress.CheckURL(sink.URL); err != nil {
+				errs = append(errs, fmt.Errorf("result_sinks[%d]: url: %w", i, err))
+			}
+		case types.ResultSinkQueue:
+			if sink.URL != "" || sink.SecretRef != "" {
+				errs = append(errs, fmt.Errorf("result_sinks[%d]: queue sinks take no url or secret_ref", i))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("result_sinks[%d]: type must be %q or %q", i, types.ResultSinkWebhook, types.ResultSinkQueue))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SettingsRecord is a tenant's current settings with version metadata.
+type SettingsRecord struct {
+	TenantID  string    `json:"tenant_id"`
+	Settings  Settings  `json:"settings"`
+	Version   int       `json:"version"`
+	UpdatedBy string    `json:"updated_by,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SettingsChange is one row of the settings audit.
+type SettingsChange struct {
+	TenantID  string    `json:"tenant_id"`
+	Version   int       `json:"version"`
+	Old       *Settings `json:"old,omitempty"`
+	New       Settings  `json:"new"`
+	ChangedBy string    `json:"changed_by,omitempty"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// GetSettings returns a tenant's settings, or nil if none were ever set.
+func (s *Store) GetSettings(ctx context.Context, tenantID string) (*SettingsRecord, error) {
+	rec := &SettingsRecord{TenantID: tenantID}
+	var raw []byte
+	err := s.pool.QueryRow(ctx, `
+		SELECT settings, version, updated_by, updated_at
+		FROM tenant_settings WHERE tenant_id = $1`, tenantID,
+	).Scan(&raw, &rec.Version, &rec.UpdatedBy, &rec.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("tenants.GetSettings: %w", err)
+	}
+	if err := json.Unmarshal(raw, &rec.Settings); err != nil {
+		return nil, fmt.Errorf("tenants.GetSettings decode: %w", err)
+	}
+	return rec, nil
+}
+
+// PutSettings replaces a tenant's settings and appends the change to the
+// audit in the same transaction. It returns nil when the tenant is missing
+// or deleted.
+func (s *Store) PutSettings(ctx context.Context, tenantID string, settings Settings, actor string) (*SettingsRecord, error) {
+	return s.UpdateSettings(ctx, tenantID, actor, func(cur *Settings) error {
+		*cur = settings
+		return nil
+	})
+}
+
+// UpdateSettings applies fn to a tenant's current settings and stores the
+// result as a new audited version, reading and writing under the tenant row
+// lock so concurrent edits cannot lose each other. An error from fn aborts
+// the update and is returned wrapped. It returns nil when the tenant is
+// missing or deleted.
+func (s *Store) UpdateSettings(ctx context.Context, tenantID, actor string, fn func(*Settings) error) (*SettingsRecord, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("tenants.UpdateSettings begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback after commit is a no-op
+
+	// Lock the tenant row so concurrent writers serialize on the version.
+	var status string
+	err = tx.QueryRow(ctx, `SELECT status FROM tenants WHERE id = $1 FOR UPDATE`, tenantID).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) || status == StatusDeleted {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("tenants.UpdateSettings lock tenant: %w", err)
+	}
+
+	var oldJSON []byte
+	err = tx.QueryRow(ctx, `SELECT settings FROM tenant_settings WHERE tenant_id = $1`, tenantID).Scan(&oldJSON)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("tenants.UpdateSettings read: %w", err)
+	}
+	var settings Settings
+	if oldJSON != nil {
+		if err := json.Unmarshal(oldJSON, &settings); err != nil {
+			return nil, fmt.Errorf("tenants.UpdateSettings decode: %w", err)
+		}
+	}
+	if err := fn(&settings); err != nil {
+		return nil, fmt.Errorf("tenants.UpdateSettings: %w", err)
+	}
+	if err := settings.Validate(); err != nil {
+		return nil, fmt.Errorf("tenants.UpdateSettings: %w", err)
+	}
+	newJSON, err := json.Marshal(settings)
+	if err != nil {
+		return nil, fmt.Errorf("tenants.UpdateSettings marshal: %w", err)
+	}
+
+	rec := &SettingsRecord{TenantID: tenantID, Settings: settings, UpdatedBy: actor}
+	err = tx.QueryRow(ctx, `
+		INSERT INTO tenant_settings (tenant_id, settings, version, updated_by)
+		VALUES ($1, $2, 1, $3)
+		ON CONFLICT (tenant_id) DO UPDATE
+		SET settings = EXCLUDED.settings, version = tenant_settings.version + 1,
+		    updated_by = EXCLUDED.updated_by, updated_at = NOW()
+		RETURNING version, updated_at`,
+		tenantID, newJSON, actor,
+	).Scan(&rec.Version, &rec.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("tenants.UpdateSettings upsert: %w", err)
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO tenant_settings_audit (tenant_id, version, old_settings, new_settings, changed_by, changed_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		tenantID, rec.Version, oldJSON, newJSON, actor, rec.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("tenants.UpdateSettings audit: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("tenants.UpdateSettings commit: %w", err)
+	}
+	return rec, nil
+}
+
+// SettingsHistory returns the most recent settings changes, newest first.
+func (s *Store) SettingsHistory(ctx context.Context, tenantID string, limit int) ([]SettingsChange, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT version, old_settings, new_settings, changed_by, changed_at
+		FROM tenant_settings_audit WHERE tenant_id = $1
+		ORDER BY version DESC LIMIT $2`, tenantID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("tenants.SettingsHistory: %w", err)
+	}
+	defer rows.Close()
+	var out []SettingsChange
+	for rows.Next() {
+		c := SettingsChange{TenantID: tenantID}
+		var oldJSON, newJSON []byte
+		if err := rows.Scan(&c.Version, &oldJSON, &newJSON, &c.ChangedBy, &c.ChangedAt); err != nil {
+			return nil, fmt.Errorf("tenants.SettingsHistory scan: %w", err)
+		}
+		if oldJSON != nil {
+			c.Old = &Settings{}
+			if err := json.Unmarshal(oldJSON, c.Old); err != nil {
+				return nil, fmt.Errorf("tenants.SettingsHistory decode: %w", err)
+			}
+		}
+		if err := json.Unmarshal(newJSON, &c.New); err != nil {
+			return nil, fmt.Errorf("tenants.SettingsHistory decode: %w", err)
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Settings cache (consumers)
+// ──────────────────────────────────────────────────────────────────────────────
+
+type settingsGetter interface {
+	GetSettings(ctx context.Context, tenantID string) (*SettingsRecord, error)
+}
+
+// SettingsCache is the consumer-side view of tenant settings: the gateway
+// and approvals service read settings on the request path, so lookups are
+// cached for ttl. A nil cache returns zero Settings.
+type SettingsCache struct {
+	store settingsGetter
+	ttl   time.Duration
+
+	mu    sync.Mutex
+	cache map[string]settingsEntry
+}
+
+type settingsEntry struct {
+	settings Settings
+	expires  time.Time
+}
+
+const maxSettingsEntries = 10_000
+
+// NewSettingsCache creates a cache over store. ttl <= 0 disables caching.
+func NewSettingsCache(store settingsGetter, ttl time.Duration) *SettingsCache {
+	return &SettingsCache{store: store, ttl: ttl, cache: make(map[string]settingsEntry)}
+}
+
+// Get returns the tenant's settings; zero Settings if none are set.
+func (c *SettingsCache) Get(ctx context.Context, tenantID string) (Settings, error) {
+	if c == nil || tenantID == "" {
+		return Settings{}, nil
+	}
+	now := time.Now()
+	c.mu.Lock()
+	e, ok := c.cache[tenantID]
+	c.mu.Unlock()
+	if ok && now.Before(e.expires) {
+		return e.settings, nil
+	}
+	rec, err := c.store.GetSettings(ctx, tenantID)
+	if err != nil {
+		return Settings{}, err
+	}
+	var s Settings
+	if rec != nil {
+		s = rec.Settings
+	}
+	parseFreezeWindows(&s)
+	if c.ttl > 0 {
+		c.mu.Lock()
+		if len(c.cache) >= maxSettingsEntries {
+			c.cache = make(map[string]settingsEntry)
+		}
+		c.cache[tenantID] = settingsEntry{settings: s, expires: now.Add(c.ttl)}
+		c.mu.Unlock()
+	}
+	return s, nil
+}
+
+// Invalidate drops a tenant's cached settings after a local change.
+func (c *SettingsCache) Invalidate(tenantID string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	delete(c.cache, tenantID)
+	c.mu.Unlock()
+}
+
+// EventSubscriptions returns the tenant's event subscriptions; it has the
+// events.Subscriptions signature.
+func (c *SettingsCache) EventSubscriptions(ctx context.Context, tenantID string) ([]types.EventSubscription, error) {
+	s, err := c.Get(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("tenants.EventSubscriptions: %w", err)
+	}
+	return s.EventSubscriptions, nil
+}
+
+// ResultSinks returns the tenant's result sinks; it has the
+// events.ResultSinks signature.
+func (c *SettingsCache) ResultSinks(ctx context.Context, tenantID string) ([]types.ResultSink, error) {
+	s, err := c.Get(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("tenants.ResultSinks: %w", err)
+	}
+	return s.ResultSinks, nil
+}
+
+// NotificationKey returns the key the tenant's webhook notifications are
+// encrypted to, or nil; it has the approvals.EncryptionKeys signature.
+func (c *SettingsCache) NotificationKey(ctx context.Context, tenantID string) (*approvals.EncryptionKey, error) {
+	s, err := c.Get(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("tenants.NotificationKey: %w", err)
+	}
+	return s.NotificationKey, nil
+}
+
+// WebhookEgress returns the tenant's own egress policy, or nil; it has the
+// approvals.EgressPolicies signature.
+func (c *SettingsCache) WebhookEgress(ctx context.Context, tenantID string) (*approvals.EgressPolicy, error) {
+	s, err := c.Get(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("tenants.WebhookEgress: %w", err)
+	}
+	return s.WebhookEgress, nil
+}
+
+// ApplyApprovalDefaults fills the expiry, approver group, and notification
+// routes of a new approval request from tenant settings where the policy
+// decision left them unset. A lookup error leaves in unchanged.
+func (c *SettingsCache) ApplyApprovalDefaults(ctx context.Context, in *approvals.CreateApprovalInput) error {
+	s, err := c.Get(ctx, in.TenantID)
+	if err != nil {
+		return fmt.Errorf("tenants.ApplyApprovalDefaults: %w", err)
+	}
+	if in.ExpiresInSec == 0 {
+		in.ExpiresInSec = s.ApprovalTTLSec
+	}
+	if in.ApproverGroup == "" {
+		in.ApproverGroup = s.ApproverGroup
+	}
+	if len(in.Notify) == 0 {
+		in.Notify = s.Notify
+	}
+	return nil
+}
+
+// ApplyGrantDefaults limits a grant on req to the tenant's grant hours when
+// the approver set no valid hours, and gives it the tenant's grant_ttl_sec
+// when nothing set its lifetime; it has the approvals.GrantDefaults
+// signature. A lookup error is returned so the grant is not stored without
+// the tenant's restriction.
+func (c *SettingsCache) ApplyGrantDefaults(ctx context.Context, req approvals.ApprovalRequest, in *approvals.GrantInput) error {
+	s, err := c.Get(ctx, req.TenantID)
+	if err != nil {
+		return fmt.Errorf("tenants.ApplyGrantDefaults: %w", err)
+	}
+	if in.ValidHours == nil {
+		in.ValidHours = s.GrantHours
+	}
+	if in.ExpiresInSec <= 0 {
+		in.ExpiresInSec = s.GrantTTLSec
+	}
+	return nil
+}
@@ -0,0 +1,322 @@
+// Package tenants manages tenant lifecycle (create, suspend, resume, delete),
+// the API keys issued to tenants, and tenant governance settings.
+package tenants
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/auth"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	StatusActive    = "active"
+	StatusSuspended = "suspended"
+	StatusDeleted   = "deleted"
+)
+
+// ErrExists is returned by Create when the tenant ID is taken, including by
+// a deleted tenant (IDs are never reused so evidence stays unambiguous).
+var ErrExists = errors.New("tenant already exists")
+
+var idRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// ValidateID checks a tenant ID.
+func ValidateID(id string) error {
+	if !idRe.MatchString(id) {
+		return fmt.Errorf("tenant id %q must match %s", id, idRe)
+	}
+	return nil
+}
+
+// Tenant is a row of the tenants table.
+type Tenant struct {
+	ID          string          `json:"id"`
+	Name        string          `json:"name"`
+	Status      string          `json:"status"`
+	Config      json.RawMessage `json:"config"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	SuspendedAt *time.Time      `json:"suspended_at,omitempty"`
+}
+
+// APIKey is an issued key's metadata. The raw key is only returned once,
+// when it is created.
+type APIKey struct {
+	ID        string     `json:"id"`
+	TenantID  string     `json:"tenant_id"`
+	Prefix    string     `json:"prefix"`
+	Label     string     `json:"label,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Store persists tenants and their API keys in Postgres.
+type Store struct {
+	pool *pgxpool.Pool
+}
+
+// NewStore creates a tenant store.
+func NewStore(pool *pgxpool.Pool) *Store {
+	return &Store{pool: pool}
+}
+
+// newKey returns a fresh "sk-" API key.
+func newKey() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "sk-" + hex.EncodeToString(b), nil
+}
+
+const tenantColumns = `id, name, status, config, created_at, updated_at, suspended_at`
+
+func scanTenant(row pgx.Row) (*Tenant, error) {
+	var t Tenant
+	if err := row.Scan(&t.ID, &t.Name, &t.Status, &t.Config, &t.CreatedAt, &t.UpdatedAt, &t.SuspendedAt); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Create inserts an active tenant and issues its initial API key in one
+// transaction. It returns the raw key, which is not stored.
+func (s *Store) Create(ctx context.Context, id, name string, config json.RawMessage) (*Tenant, *APIKey, string, error) {
+	if err := ValidateID(id); err != nil {
+		return nil, nil, "", fmt.Errorf("tenants.Create: %w", err)
+	}
+	if name == "" {
+		return nil, nil, "", fmt.Errorf("tenants.Create: name is required")
+	}
+	if len(config) == 0 {
+		config = json.RawMessage(`{}`)
+	}
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("tenants.Create begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback after commit is a no-op
+
+	t, err := scanTenant(tx.QueryRow(ctx, `
+		INSERT INTO tenants (id, name, config, status)
+		VALUES ($1, $2, $3, 'active')
+		RETURNING `+tenantColumns, id, name, config))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, nil, "", ErrExists
+		}
+		return nil, nil, "", fmt.Errorf("tenants.Create insert: %w", err)
+	}
+	key, raw, err := insertKey(ctx, tx, id, "initial")
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("tenants.Create: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, "", fmt.Errorf("tenants.Create commit: %w", err)
+	}
+	return t, key, raw, nil
+}
+
+func insertKey(ctx context.Context, q interface {
+	QueryRow(context.Context, string, ...any) pgx.Row
+}, tenantID, label string) (*APIKey, string, error) {
+	raw, err := newKey()
+	if err != nil {
+		return nil, "", fmt.Errorf("generate api key: %w", err)
+	}
+	k := &APIKey{ID: uuid.NewString(), TenantID: tenantID, Prefix: raw[:10], Label: label}
+	err = q.QueryRow(ctx, `
+		INSERT INTO tenant_api_keys (id, tenant_id, key_hash, key_prefix, label)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at`,
+		k.ID, tenantID, auth.HashKey(raw), k.Prefix, label,
+	).Scan(&k.CreatedAt)
+	if err != nil {
+		return nil, "", fmt.Errorf("insert api key: %w", err)
+	}
+	return k, raw, nil
+}
+
+// Get returns a tenant, or nil if it does not exist.
+func (s *Store) Get(ctx context.Context, id string) (*Tenant, error) {
+	t, err := scanTenant(s.pool.QueryRow(ctx, `SELECT `+tenantColumns+` FROM tenants WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("tenants.Get: %w", err)
+	}
+	return t, nil
+}
+
+// List returns tenants ordered by ID, optionally filtered by status.
+func (s *Store) List(ctx context.Context, status string) ([]Tenant, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+tenantColumns+` FROM tenants
+		WHERE $1 = '' OR status = $1
+		ORDER BY id`, status)
+	if err != nil {
+		return nil, fmt.Errorf("tenants.List: %w", err)
+	}
+	defer rows.Close()
+	var out []Tenant
+	for rows.Next() {
+		t, err := scanTenant(rows)
+		if err != nil {
+			return nil, fmt.Errorf("tenants.List scan: %w", err)
+		}
+		out = append(out, *t)
+	}
+	return out, rows.Err()
+}
+
+// SetStatus moves a tenant between active and suspended. Deleted tenants
+// cannot be changed; it returns nil when the tenant is missing or deleted.
+func (s *Store) SetStatus(ctx context.Context, id, status string) (*Tenant, error) {
+	if status != StatusActive && status != StatusSuspended {
+		return nil, fmt.Errorf("tenants.SetStatus: invalid status %q", status)
+	}
+	t, err := scanTenant(s.pool.QueryRow(ctx, `
+		UPDATE tenants
+		SET status = $2,
+		    suspended_at = CASE WHEN $2 = 'suspended' THEN COALESCE(suspended_at, NOW()) ELSE NULL END,
+		    updated_at = NOW()
+		WHERE id = $1 AND status <> 'deleted'
+		RETURNING `+tenantColumns, id, status))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("tenants.SetStatus: %w", err)
+	}
+	return t, nil
+}
+
+// Delete marks a tenant deleted, revokes its keys, and removes its stored
+// connector credentials. Evidence and approvals are retained for audit. It
+// reports false when the tenant is missing or already deleted.
+func (s *Store) Delete(ctx context.Context, id string) (bool, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("tenants.Delete begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback after commit is a no-op
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE tenants SET status = 'deleted', updated_at = NOW()
+		WHERE id = $1 AND status <> 'deleted'`, id)
+	if err != nil {
+		return false, fmt.Errorf("tenants.Delete: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	if _, err := tx.Exec(ctx, `UPDATE tenant_api_keys SET revoked_at = NOW() WHERE tenant_id = $1 AND revoked_at IS NULL`, id); err != nil {
+		return false, fmt.Errorf("tenants.Delete revoke keys: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM connector_credentials WHERE tenant_id = $1`, id); err != nil {
+		return false, fmt.Errorf("tenants.Delete credentials: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("tenants.Delete commit: %w", err)
+	}
+	return true, nil
+}
+
+// CreateAPIKey issues an additional key for an active or suspended tenant.
+// It returns nil when the tenant is missing or deleted.
+func (s *Store) CreateAPIKey(ctx context.Context, tenantID, label string) (*APIKey, string, error) {
+	t, err := s.Get(ctx, tenantID)
+	if err != nil {
+		return nil, "", fmt.Errorf("tenants.CreateAPIKey: %w", err)
+	}
+	if t == nil || t.Status == StatusDeleted {
+		return nil, "", nil
+	}
+	k, raw, err := insertKey(ctx, s.pool, tenantID, label)
+	if err != nil {
+		return nil, "", fmt.Errorf("tenants.CreateAPIKey: %w", err)
+	}
+	return k, raw, nil
+}
+
+// ListAPIKeys returns key metadata for a tenant, newest first.
+func (s *Store) ListAPIKeys(ctx context.Context, tenantID string) ([]APIKey, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, tenant_id, key_prefix, label, created_at, revoked_at
+		FROM tenant_api_keys WHERE tenant_id = $1
+		ORDER BY created_at DESC`, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("tenants.ListAPIKeys: %w", err)
+	}
+	defer rows.Close()
+	var out []APIKey
+	for rows.Next() {
+		var k APIKey
+		if err := rows.Scan(&k.ID, &k.TenantID, &k.Prefix, &k.Label, &k.CreatedAt, &k.RevokedAt); err != nil {
+			return nil, fmt.Errorf("tenants.ListAPIKeys scan: %w", err)
+		}
+		out = append(out, k)
+	}
+	return out, rows.Err()
+}
+
+// RevokeAPIKey revokes one key and reports whether an unrevoked key matched.
+func (s *Store) RevokeAPIKey(ctx context.Context, tenantID, keyID string) (bool, error) {
+	tag, err := s.pool.Exec(ctx, `
+		UPDATE tenant_api_keys SET revoked_at = NOW()
+		WHERE tenant_id = $1 AND id = $2 AND revoked_at IS NULL`, tenantID, keyID)
+	if err != nil {
+		return false, fmt.Errorf("tenants.RevokeAPIKey: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// AuthState returns the hashes of all unrevoked issued keys and the set of
+// tenants that are suspended or deleted, in the shape auth.KeyStore takes.
+func (s *Store) AuthState(ctx context.Context) (map[string]string, map[string]bool, error) {
+	hashes := make(map[string]string)
+	rows, err := s.pool.Query(ctx, `SELECT key_hash, tenant_id FROM tenant_api_keys WHERE revoked_at IS NULL`)
+	if err != nil {
+		return nil, nil, fmt.Errorf("tenants.AuthState keys: %w", err)
+	}
+	for rows.Next() {
+		var h, tenant string
+		if err := rows.Scan(&h, &tenant); err != nil {
+			rows.Close()
+			return nil, nil, fmt.Errorf("tenants.AuthState scan: %w", err)
+		}
+		hashes[h] = tenant
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("tenants.AuthState keys: %w", err)
+	}
+
+	disabled := make(map[string]bool)
+	rows, err = s.pool.Query(ctx, `SELECT id FROM tenants WHERE status <> 'active'`)
+	if err != nil {
+		return nil, nil, fmt.Errorf("tenants.AuthState tenants: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, nil, fmt.Errorf("tenants.AuthState scan: %w", err)
+		}
+		disabled[id] = true
+	}
+	return hashes, disabled, rows.Err()
+}
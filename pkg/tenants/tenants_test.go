@@ -0,0 +1,810 @@
+package tenants
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/approvals"
+	"github.com/bturcanu/OpenClause/pkg/types"
+	"github.com/go-chi/chi/v5"
+)
+
+type fakeStore struct {
+	tenants  map[string]*Tenant
+	keys     map[string]*APIKey
+	settings map[string]*SettingsRecord
+	history  []SettingsChange
+	blocks   []Block
+}
+
+func (f *fakeStore) Create(_ context.Context, id, name string, config json.RawMessage) (*Tenant, *APIKey, string, error) {
+	if _, ok := f.tenants[id]; ok {
+		return nil, nil, "", ErrExists
+	}
+	t := &Tenant{ID: id, Name: name, Status: StatusActive, Config: config}
+	f.tenants[id] = t
+	k := &APIKey{ID: "k-" + id, TenantID: id, Prefix: "sk-0000000"}
+	f.keys[k.ID] = k
+	return t, k, "sk-raw", nil
+}
+
+func (f *fakeStore) Get(_ context.Context, id string) (*Tenant, error) {
+	return f.tenants[id], nil
+}
+
+func (f *fakeStore) List(_ context.Context, _ string) ([]Tenant, error) {
+	var out []Tenant
+	for _, t := range f.tenants {
+		out = append(out, *t)
+	}
+	return out, nil
+}
+
+func (f *fakeStore) SetStatus(_ context.Context, id, status string) (*Tenant, error) {
+	t := f.tenants[id]
+	if t == nil || t.Status == StatusDeleted {
+		return nil, nil
+	}
+	t.Status = status
+	return t, nil
+}
+
+func (f *fakeStore) Delete(_ context.Context, id string) (bool, error) {
+	t := f.tenants[id]
+	if t == nil || t.Status == StatusDeleted {
+		return false, nil
+	}
+	t.Status = StatusDeleted
+	return true, nil
+}
+
+func (f *fakeStore) CreateAPIKey(_ context.Context, tenantID, label string) (*APIKey, string, error) {
+	if f.tenants[tenantID] == nil {
+		return nil, "", nil
+	}
+	k := &APIKey{ID: "k2", TenantID: tenantID, Label: label}
+	f.keys[k.ID] = k
+	return k, "sk-raw2", nil
+}
+
+func (f *fakeStore) ListAPIKeys(_ context.Context, tenantID string) ([]APIKey, error) {
+	var out []APIKey
+	for _, k := range f.keys {
+		if k.TenantID == tenantID {
+			out = append(out, *k)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeStore) RevokeAPIKey(_ context.Context, tenantID, keyID string) (bool, error) {
+	k := f.keys[keyID]
+	if k == nil || k.TenantID != tenantID {
+		return false, nil
+	}
+	delete(f.keys, keyID)
+	return true, nil
+}
+
+func (f *fakeStore) GetSettings(_ context.Context, tenantID string) (*SettingsRecord, error) {
+	return f.settings[tenantID], nil
+}
+
+func (f *fakeStore) PutSettings(_ context.Context, tenantID string, settings Settings, actor string) (*SettingsRecord, error) {
+	if t := f.tenants[tenantID]; t == nil || t.Status == StatusDeleted {
+		return nil, nil
+	}
+	var old *Settings
+	version := 1
+	if prev := f.settings[tenantID]; prev != nil {
+		old, version = &prev.Settings, prev.Version+1
+	}
+	rec := &SettingsRecord{TenantID: tenantID, Settings: settings, Version: version, UpdatedBy: actor}
+	f.settings[tenantID] = rec
+	f.history = append([]SettingsChange{{TenantID: tenantID, Version: version, Old: old, New: settings, ChangedBy: actor}}, f.history...)
+	return rec, nil
+}
+
+func (f *fakeStore) UpdateSettings(ctx context.Context, tenantID, actor string, fn func(*Settings) error) (*SettingsRecord, error) {
+	var cur Settings
+	if prev := f.settings[tenantID]; prev != nil {
+		cur = prev.Settings
+		cur.ToolCatalog = slices.Clone(cur.ToolCatalog)
+	}
+	if err := fn(&cur); err != nil {
+		return nil, err
+	}
+	return f.PutSettings(ctx, tenantID, cur, actor)
+}
+
+func (f *fakeStore) SettingsHistory(_ context.Context, _ string, limit int) ([]SettingsChange, error) {
+	return f.history[:min(limit, len(f.history))], nil
+}
+
+func (f *fakeStore) AddBlock(_ context.Context, tenantID string, b Block) (*Block, error) {
+	if t := f.tenants[tenantID]; t == nil || t.Status == StatusDeleted {
+		return nil, nil
+	}
+	b.ID = fmt.Sprintf("00000000-0000-0000-0000-%012d", len(f.blocks)+1)
+	b.TenantID = tenantID
+	f.blocks = append(f.blocks, b)
+	return &b, nil
+}
+
+func (f *fakeStore) ListBlocks(_ context.Context, tenantID string) ([]Block, error) {
+	var out []Block
+	for _, b := range f.blocks {
+		if b.TenantID == tenantID {
+			out = append(out, b)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeStore) RemoveBlock(_ context.Context, tenantID, id string) (bool, error) {
+	n := len(f.blocks)
+	f.blocks = slices.DeleteFunc(f.blocks, func(b Block) bool { return b.TenantID == tenantID && b.ID == id })
+	return len(f.blocks) < n, nil
+}
+
+type fakePolicy struct {
+	mu   sync.Mutex
+	docs map[string]string
+}
+
+func (f *fakePolicy) PutTenantData(_ context.Context, tenantID string, doc json.RawMessage) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.docs[tenantID] = string(doc)
+	return nil
+}
+
+func (f *fakePolicy) DeleteTenantData(_ context.Context, tenantID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.docs, tenantID)
+	return nil
+}
+
+func TestHandlers_Lifecycle(t *testing.T) {
+	store := &fakeStore{tenants: map[string]*Tenant{}, keys: map[string]*APIKey{}, settings: map[string]*SettingsRecord{}}
+	pol := &fakePolicy{docs: map[string]string{}}
+	defaults, err := ParseDefaults(`{"max_risk_auto_approve":5,"region":"eu"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandlers(store, pol, defaults, nil)
+	changes := 0
+	h.OnChange = func(context.Context) { changes++ }
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+
+	rec := do(http.MethodPost, "/v1/admin/tenants", `{"id":"acme","name":"Acme","config":{"region":"us"},"policy":{"approver_group":"sec"}}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create = %d %s", rec.Code, rec.Body)
+	}
+	var created struct {
+		Tenant       Tenant `json:"tenant"`
+		Key          string `json:"key"`
+		PolicySynced bool   `json:"policy_synced"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	if created.Key != "sk-raw" || !created.PolicySynced {
+		t.Fatalf("create response = %+v", created)
+	}
+	var cfg map[string]any
+	_ = json.Unmarshal(created.Tenant.Config, &cfg)
+	if cfg["region"] != "us" || cfg["max_risk_auto_approve"] != float64(5) || cfg["policy"] == nil {
+		t.Fatalf("config = %v, want request over defaults plus policy", cfg)
+	}
+	if pol.docs["acme"] != `{"approver_group":"sec"}` {
+		t.Fatalf("seeded policy = %q", pol.docs["acme"])
+	}
+
+	if rec := do(http.MethodPost, "/v1/admin/tenants", `{"id":"acme","name":"Again"}`); rec.Code != http.StatusConflict {
+		t.Fatalf("duplicate create = %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/v1/admin/tenants", `{"id":"Bad ID","name":"x"}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("invalid id = %d", rec.Code)
+	}
+
+	if rec := do(http.MethodPost, "/v1/admin/tenants/acme/suspend", ""); rec.Code != http.StatusOK || store.tenants["acme"].Status != StatusSuspended {
+		t.Fatalf("suspend = %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/v1/admin/tenants/acme/resume", ""); rec.Code != http.StatusOK || store.tenants["acme"].Status != StatusActive {
+		t.Fatalf("resume = %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/v1/admin/tenants/acme/api-keys", `{"label":"ci"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("create key = %d", rec.Code)
+	}
+	if rec := do(http.MethodDelete, "/v1/admin/tenants/acme/api-keys/k2", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("revoke key = %d", rec.Code)
+	}
+	if rec := do(http.MethodDelete, "/v1/admin/tenants/acme", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("delete = %d", rec.Code)
+	}
+	if _, ok := pol.docs["acme"]; ok {
+		t.Fatal("delete must remove the tenant policy document")
+	}
+	if rec := do(http.MethodPost, "/v1/admin/tenants/acme/resume", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("resume deleted = %d", rec.Code)
+	}
+	if changes != 6 {
+		t.Fatalf("OnChange calls = %d, want 6", changes)
+	}
+}
+
+func TestPolicySync(t *testing.T) {
+	store := &fakeStore{
+		tenants: map[string]*Tenant{
+			"acme": {ID: "acme", Status: StatusActive, Config: json.RawMessage(`{"policy":{"approver_group":"sec","max_risk_auto_approve":5}}`)},
+			"beta": {ID: "beta", Status: StatusActive, Config: json.RawMessage(`{}`)},
+		},
+		settings: map[string]*SettingsRecord{
+			"acme": {Settings: Settings{ApproverGroup: "ops", ToolCatalog: []string{"jira.*"}}},
+		},
+	}
+	pol := &fakePolicy{docs: map[string]string{"gone": "{}"}}
+	ps := NewPolicySync(store, pol, nil)
+	ps.SyncAll(t.Context())
+	if got := pol.docs["acme"]; got != `{"approver_group":"sec","max_risk_auto_approve":5,"tool_catalog":["jira.*"]}` {
+		t.Errorf("acme doc = %s; the seed approver group must win", got)
+	}
+	if got := pol.docs["beta"]; got != `{}` {
+		t.Errorf("beta doc = %s", got)
+	}
+
+	store.settings["beta"] = &SettingsRecord{Settings: Settings{ApproverGroup: "ops", Budgets: []types.Budget{{Period: "month", Limit: 100}}}}
+	ps.Notify("beta")
+	ps.Notify("gone")
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan struct{})
+	go func() { ps.Run(ctx, time.Hour); close(done) }()
+	want := `{"approver_group":"ops","budgets":[{"period":"month","limit":100}]}`
+	for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(5 * time.Millisecond) {
+		pol.mu.Lock()
+		got, stale := pol.docs["beta"], pol.docs["gone"] != ""
+		pol.mu.Unlock()
+		if got == want && !stale {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("after notify: beta doc = %s, gone doc kept = %v", got, stale)
+		}
+	}
+	cancel()
+	<-done
+}
+
+func TestHandlers_Settings(t *testing.T) {
+	store := &fakeStore{
+		tenants:  map[string]*Tenant{"acme": {ID: "acme", Status: StatusActive}},
+		settings: map[string]*SettingsRecord{},
+	}
+	h := NewHandlers(store, nil, nil, nil)
+	var invalidated []string
+	h.OnSettingsChange = func(id string) { invalidated = append(invalidated, id) }
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-Admin-Actor", "ops@example.com")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do(http.MethodGet, "/v1/admin/tenants/acme/settings", ""); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"version":0`) {
+		t.Fatalf("GET unset = %d %s", rec.Code, rec.Body)
+	}
+	if rec := do(http.MethodPut, "/v1/admin/tenants/acme/settings", `{"approval_ttl_sec":10}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("short TTL = %d", rec.Code)
+	}
+	if rec := do(http.MethodPut, "/v1/admin/tenants/acme/settings", `{"grant_ttl_sec":31536000}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("long grant TTL = %d", rec.Code)
+	}
+	if rec := do(http.MethodPut, "/v1/admin/tenants/acme/settings", `{"event_subscriptions":[{"url":"https://siem.acme.io/oc","types":["oc.toolcall.exploded"]}]}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("unknown event type = %d", rec.Code)
+	}
+	for _, s := range []string{`{"type":"sqs"}`, `{"type":"webhook"}`, `{"type":"queue","url":"https://siem.acme.io/oc"}`} {
+		if rec := do(http.MethodPut, "/v1/admin/tenants/acme/settings", `{"result_sinks":[`+s+`]}`); rec.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("invalid result sink %s = %d", s, rec.Code)
+		}
+	}
+	for _, e := range []string{`{"allowed_cidrs":["10.0.0.0/33"]}`, `{"allowed_domains":["*.acme.io"]}`} {
+		if rec := do(http.MethodPut, "/v1/admin/tenants/acme/settings", `{"webhook_egress":`+e+`}`); rec.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("invalid webhook egress %s = %d", e, rec.Code)
+		}
+	}
+	if rec := do(http.MethodPut, "/v1/admin/tenants/acme/settings", `{"webhook_egress":{"allowed_domains":["acme.io"]},"result_sinks":[{"type":"webhook","url":"https://recon.example.com/oc"}]}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("result sink outside the egress policy = %d", rec.Code)
+	}
+	for _, k := range []string{`{"kty":"oct"}`, `{"kty":"RSA","n":"AQAB","e":"AQAB"}`, `{"kty":"EC","crv":"P-256","x":"AA","y":"AA"}`} {
+		if rec := do(http.MethodPut, "/v1/admin/tenants/acme/settings", `{"notification_encryption_key":`+k+`}`); rec.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("invalid notification key %s = %d", k, rec.Code)
+		}
+	}
+	if rec := do(http.MethodPut, "/v1/admin/tenants/acme/settings", `{"freeze_windows":[{"name":"f","schedule":"0 25 * * *","duration":"1h","decision":"deny"}]}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("invalid freeze window: expected 422, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPut, "/v1/admin/tenants/acme/settings", `{"digests":[{"name":"d","period":"weekly","notify":{"kind":"slack","channel":"#sec"}}]}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("digest to a kind without digest support: expected 422, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPut, "/v1/admin/tenants/acme/settings", `{"auto_approvals":[{"name":"anything"}]}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("auto-approval rule without conditions: expected 422 got %d", rec.Code)
+	}
+	if rec := do(http.MethodPut, "/v1/admin/tenants/acme/settings", `{"connector_pins":{"jira.issue":"v1","slack":"v 2"}}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("invalid connector pins: expected 422, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPut, "/v1/admin/tenants/acme/settings", `{"budgets":[{"period":"week","limit":10}]}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("invalid budget = %d", rec.Code)
+	}
+	for _, l := range []string{`{"limit":5,"per":"minute"}`, `{"agent_id":"*","limit":5,"per":"day"}`, `{"tool_action":"jira","limit":5,"per":"minute"}`} {
+		if rec := do(http.MethodPut, "/v1/admin/tenants/acme/settings", `{"rate_limits":[`+l+`]}`); rec.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("invalid rate limit %s = %d", l, rec.Code)
+		}
+	}
+	if rec := do(http.MethodPut, "/v1/admin/tenants/acme/settings", `{"agent_max_concurrent_executions":-1}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("negative agent concurrency = %d", rec.Code)
+	}
+	if rec := do(http.MethodPut, "/v1/admin/tenants/acme/settings", `{"max_priority":"urgent"}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("unknown max_priority = %d", rec.Code)
+	}
+	if rec := do(http.MethodPut, "/v1/admin/tenants/acme/settings", `{"approval_ttl":3600}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("unknown field = %d", rec.Code)
+	}
+	body := `{"approval_ttl_sec":3600,"approver_group":"sec","notify":[{"kind":"slack","channel":"#approvals"}],"rate_limit_per_sec":5,
+		"event_subscriptions":[{"url":"https://siem.acme.io/oc","types":["oc.toolcall.denied","oc.approval.expired"]}],
+		"result_sinks":[{"type":"webhook","url":"https://recon.acme.io/oc","secret_ref":"acme_events"},{"type":"queue"}]}`
+	if rec := do(http.MethodPut, "/v1/admin/tenants/acme/settings", body); rec.Code != http.StatusOK {
+		t.Fatalf("PUT = %d %s", rec.Code, rec.Body)
+	}
+	if rec := do(http.MethodPut, "/v1/admin/tenants/acme/settings", `{"retention_days":90}`); rec.Code != http.StatusOK {
+		t.Fatalf("second PUT = %d %s", rec.Code, rec.Body)
+	}
+	if rec := do(http.MethodPut, "/v1/admin/tenants/ghost/settings", `{}`); rec.Code != http.StatusNotFound {
+		t.Fatalf("PUT missing tenant = %d", rec.Code)
+	}
+
+	rec := do(http.MethodGet, "/v1/admin/tenants/acme/settings/history", "")
+	var hist struct {
+		Changes []SettingsChange `json:"changes"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &hist); err != nil {
+		t.Fatal(err)
+	}
+	if len(hist.Changes) != 2 || hist.Changes[0].Version != 2 || hist.Changes[0].Old == nil ||
+		hist.Changes[0].Old.ApproverGroup != "sec" || hist.Changes[0].ChangedBy != "ops@example.com" {
+		t.Fatalf("history = %+v", hist.Changes)
+	}
+	if len(invalidated) != 2 {
+		t.Fatalf("OnSettingsChange calls = %v", invalidated)
+	}
+}
+
+func TestCatalogPermits(t *testing.T) {
+	catalog := []string{"jira.issue.create", "slack.msg.*", "github.*"}
+	tests := []struct {
+		tool, action string
+		want         bool
+	}{
+		{"jira", "issue.create", true},
+		{"jira", "issue.delete", false},
+		{"slack", "msg.post", true},
+		{"slack", "msgs.post", false},
+		{"github", "pr.merge", true},
+		{"githubx", "pr.merge", false},
+	}
+	for _, tt := range tests {
+		if got := CatalogPermits(catalog, tt.tool, tt.action); got != tt.want {
+			t.Errorf("%s.%s = %v, want %v", tt.tool, tt.action, got, tt.want)
+		}
+	}
+	if !CatalogPermits(nil, "jira", "issue.delete") {
+		t.Error("empty catalog should permit everything")
+	}
+	for _, bad := range []string{"jira", "*", "jira.*.create", "Jira.issue", "jira..x"} {
+		if ValidateCatalogPattern(bad) == nil {
+			t.Errorf("pattern %q accepted", bad)
+		}
+	}
+}
+
+func TestHandlers_Catalog(t *testing.T) {
+	store := &fakeStore{
+		tenants:  map[string]*Tenant{"acme": {ID: "acme", Status: StatusActive}},
+		settings: map[string]*SettingsRecord{"acme": {TenantID: "acme", Settings: Settings{RetentionDays: 30}, Version: 1}},
+	}
+	h := NewHandlers(store, nil, nil, nil)
+	var invalidated []string
+	h.OnSettingsChange = func(id string) { invalidated = append(invalidated, id) }
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+	do := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(method, path, http.NoBody))
+		return rec
+	}
+
+	for _, entry := range []string{"slack.msg.*", "jira.issue.create", "slack.msg.*"} {
+		if rec := do(http.MethodPut, "/v1/admin/tenants/acme/catalog/"+entry); rec.Code != http.StatusOK {
+			t.Fatalf("add %s = %d %s", entry, rec.Code, rec.Body)
+		}
+	}
+	if rec := do(http.MethodPut, "/v1/admin/tenants/acme/catalog/jira"); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("add invalid = %d", rec.Code)
+	}
+	got := store.settings["acme"].Settings
+	if !slices.Equal(got.ToolCatalog, []string{"slack.msg.*", "jira.issue.create"}) || got.RetentionDays != 30 {
+		t.Fatalf("settings after adds = %+v", got)
+	}
+
+	if rec := do(http.MethodDelete, "/v1/admin/tenants/acme/catalog/slack.msg.*"); rec.Code != http.StatusOK {
+		t.Fatalf("remove = %d %s", rec.Code, rec.Body)
+	}
+	if rec := do(http.MethodDelete, "/v1/admin/tenants/acme/catalog/slack.msg.*"); rec.Code != http.StatusNotFound {
+		t.Fatalf("remove missing = %d", rec.Code)
+	}
+	if rec := do(http.MethodDelete, "/v1/admin/tenants/acme/catalog/jira.issue.create"); rec.Code != http.StatusConflict {
+		t.Fatalf("remove last = %d", rec.Code)
+	}
+	if rec := do(http.MethodPut, "/v1/admin/tenants/ghost/catalog/jira.issue.create"); rec.Code != http.StatusNotFound {
+		t.Fatalf("missing tenant = %d", rec.Code)
+	}
+
+	rec := do(http.MethodGet, "/v1/admin/tenants/acme/catalog")
+	var out struct {
+		Enforced bool     `json:"enforced"`
+		Entries  []string `json:"entries"`
+		Version  int      `json:"version"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if !out.Enforced || !slices.Equal(out.Entries, []string{"jira.issue.create"}) || out.Version != 4 {
+		t.Fatalf("catalog = %+v", out)
+	}
+	if len(invalidated) != 3 {
+		t.Fatalf("OnSettingsChange calls = %v", invalidated)
+	}
+}
+
+func TestMatchBlock(t *testing.T) {
+	blocks := []Block{
+		{Kind: BlockResource, Value: "repo/secrets*"},
+		{Kind: BlockAgent, Value: "agent-rogue"},
+		{Kind: BlockChannel, Value: "#exec"},
+		{Kind: BlockProject, Value: "SEC"},
+		{Kind: BlockRecipient, Value: "ceo@example.com"},
+	}
+	tests := []struct {
+		name string
+		req  types.ToolCallRequest
+		want string
+	}{
+		{"resource prefix", types.ToolCallRequest{AgentID: "a", Resource: "Repo/Secrets-prod"}, "repo/secrets*"},
+		{"agent", types.ToolCallRequest{AgentID: "agent-rogue"}, "agent-rogue"},
+		{"channel param", types.ToolCallRequest{AgentID: "a", Params: json.RawMessage(`{"channel":"#exec"}`)}, "#exec"},
+		{"project param", types.ToolCallRequest{AgentID: "a", Params: json.RawMessage(`{"project":"sec"}`)}, "SEC"},
+		{"recipient list", types.ToolCallRequest{AgentID: "a", Params: json.RawMessage(`{"to":"ops@example.com, CEO@example.com"}`)}, "ceo@example.com"},
+		{"recipient array", types.ToolCallRequest{AgentID: "a", Params: json.RawMessage(`{"cc":["ceo@example.com"]}`)}, "ceo@example.com"},
+		{"unrelated", types.ToolCallRequest{AgentID: "a", Resource: "repo/public", Params: json.RawMessage(`{"channel":"#general","text":"#exec"}`)}, ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if b := MatchBlock(blocks, tt.req); b != nil {
+			got = b.Value
+		}
+		if got != tt.want {
+			t.Errorf("%s: matched %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHandlers_Blocklist(t *testing.T) {
+	store := &fakeStore{tenants: map[string]*Tenant{"acme": {ID: "acme", Status: StatusActive}}}
+	r := chi.NewRouter()
+	NewHandlers(store, nil, nil, nil).RegisterRoutes(r)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-Admin-Actor", "oncall")
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do(http.MethodPost, "/v1/admin/tenants/acme/blocklist", `{"kind":"Channel","value":" #exec ","reason":"INC-42"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("add = %d %s", rec.Code, rec.Body)
+	}
+	var added Block
+	if err := json.Unmarshal(rec.Body.Bytes(), &added); err != nil {
+		t.Fatal(err)
+	}
+	if added.Kind != BlockChannel || added.Value != "#exec" || added.CreatedBy != "oncall" {
+		t.Fatalf("added = %+v", added)
+	}
+	for _, body := range []string{`{"kind":"tool","value":"x"}`, `{"kind":"agent","value":"*"}`} {
+		if rec := do(http.MethodPost, "/v1/admin/tenants/acme/blocklist", body); rec.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("add %s = %d", body, rec.Code)
+		}
+	}
+	if rec := do(http.MethodPost, "/v1/admin/tenants/ghost/blocklist", `{"kind":"agent","value":"a"}`); rec.Code != http.StatusNotFound {
+		t.Fatalf("missing tenant = %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/v1/admin/tenants/acme/blocklist", ""); !strings.Contains(rec.Body.String(), `"INC-42"`) {
+		t.Fatalf("list = %d %s", rec.Code, rec.Body)
+	}
+	if rec := do(http.MethodDelete, "/v1/admin/tenants/acme/blocklist/"+added.ID, ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("remove = %d", rec.Code)
+	}
+	if rec := do(http.MethodDelete, "/v1/admin/tenants/acme/blocklist/"+added.ID, ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("remove again = %d", rec.Code)
+	}
+}
+
+func TestSettingsCache_ApplyApprovalDefaults(t *testing.T) {
+	store := &fakeStore{settings: map[string]*SettingsRecord{
+		"acme": {Settings: Settings{ApprovalTTLSec: 600, GrantTTLSec: 1800, ApproverGroup: "sec", Notify: []types.PolicyNotify{{Kind: "slack", Channel: "#a"}}}},
+	}}
+	c := NewSettingsCache(store, time.Minute)
+
+	grant := approvals.GrantInput{}
+	if err := c.ApplyGrantDefaults(context.Background(), approvals.ApprovalRequest{TenantID: "acme"}, &grant); err != nil || grant.ExpiresInSec != 1800 {
+		t.Fatalf("grant = %+v, %v", grant, err)
+	}
+	grant = approvals.GrantInput{ExpiresInSec: 60}
+	if err := c.ApplyGrantDefaults(context.Background(), approvals.ApprovalRequest{TenantID: "acme"}, &grant); err != nil || grant.ExpiresInSec != 60 {
+		t.Fatalf("explicit grant = %+v, %v", grant, err)
+	}
+
+	in := approvals.CreateApprovalInput{TenantID: "acme", ApproverGroup: "policy-group"}
+	if err := c.ApplyApprovalDefaults(context.Background(), &in); err != nil {
+		t.Fatal(err)
+	}
+	if in.ExpiresInSec != 600 || in.ApproverGroup != "policy-group" || len(in.Notify) != 1 {
+		t.Fatalf("applied = %+v; policy values must win, settings fill gaps", in)
+	}
+
+	// Cached until invalidated.
+	store.settings["acme"].Settings.ApprovalTTLSec = 900
+	if s, _ := c.Get(context.Background(), "acme"); s.ApprovalTTLSec != 600 {
+		t.Fatalf("expected cached value, got %d", s.ApprovalTTLSec)
+	}
+	c.Invalidate("acme")
+	if s, _ := c.Get(context.Background(), "acme"); s.ApprovalTTLSec != 900 {
+		t.Fatalf("expected refreshed value, got %d", s.ApprovalTTLSec)
+	}
+
+	var nilCache *SettingsCache
+	if s, err := nilCache.Get(context.Background(), "acme"); err != nil || s.ApprovalTTLSec != 0 {
+		t.Fatalf("nil cache = %+v, %v", s, err)
+	}
+}
+
+func TestSettingsCache_AutoApproval(t *testing.T) {
+	three := 3
+	store := &fakeStore{settings: map[string]*SettingsRecord{
+		"acme": {Settings: Settings{AutoApprovals: []AutoApproval{
+			{Name: "low-risk-jira", RiskBelow: &three, ToolActions: []string{"jira.*"}},
+			{Name: "weekend-reads", ToolActions: []string{"slack.channel.list"}, Hours: &types.TimeWindow{Days: []string{"sat", "sun"}, Start: "00:00", End: "00:00"}},
+		}}},
+	}}
+	c := NewSettingsCache(store, time.Minute)
+	saturday := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		req  approvals.ApprovalRequest
+		want string
+	}{
+		{approvals.ApprovalRequest{TenantID: "acme", Tool: "jira", Action: "issue.list", RiskScore: 2}, "low-risk-jira"},
+		{approvals.ApprovalRequest{TenantID: "acme", Tool: "jira", Action: "issue.list", RiskScore: 3}, ""},
+		{approvals.ApprovalRequest{TenantID: "acme", Tool: "slack", Action: "msg.post", RiskScore: 0}, ""},
+		{approvals.ApprovalRequest{TenantID: "acme", Tool: "slack", Action: "channel.list", RiskScore: 9, CreatedAt: saturday}, "weekend-reads"},
+		{approvals.ApprovalRequest{TenantID: "acme", Tool: "slack", Action: "channel.list", CreatedAt: saturday.AddDate(0, 0, 2)}, ""},
+		{approvals.ApprovalRequest{TenantID: "other", Tool: "jira", Action: "issue.list"}, ""},
+	} {
+		got, err := c.AutoApproval(context.Background(), tc.req)
+		if err != nil || got != tc.want {
+			t.Errorf("%s.%s risk %d: rule %q, %v; want %q", tc.req.Tool, tc.req.Action, tc.req.RiskScore, got, err, tc.want)
+		}
+	}
+}
+
+func TestSettingsCache_ActiveFreeze(t *testing.T) {
+	store := &fakeStore{settings: map[string]*SettingsRecord{
+		"acme": {Settings: Settings{FreezeWindows: []FreezeWindow{
+			{Name: "weekend", Schedule: "0 18 * * fri", Duration: "63h", Timezone: "Europe/Berlin", Decision: types.DecisionApprove},
+			{Name: "release", Schedule: "0 9 16 oct *", Duration: "2h", Decision: types.DecisionDeny, ToolActions: []string{"github.*"}},
+		}}},
+	}}
+	c := NewSettingsCache(store, time.Minute)
+	// Friday 2026-10-16 18:00 in Berlin (CEST) is 16:00 UTC.
+	at := func(day, hour, minute int) time.Time { return time.Date(2026, 10, day, hour, minute, 0, 0, time.UTC) }
+
+	for _, tc := range []struct {
+		tool, action string
+		t            time.Time
+		want         string
+	}{
+		{"slack", "msg.post", at(16, 15, 59), ""},
+		{"slack", "msg.post", at(16, 16, 0), "weekend"},
+		{"slack", "msg.post", at(19, 6, 59), "weekend"},
+		{"slack", "msg.post", at(19, 7, 0), ""},
+		{"slack", "channel.list", at(17, 12, 0), ""},
+		{"github", "pr.merge", at(16, 10, 30), "release"},
+		{"github", "pr.merge", at(16, 11, 0), ""},
+		{"jira", "issue.create", at(16, 10, 30), ""},
+	} {
+		f, err := c.ActiveFreeze(context.Background(), "acme", tc.tool, tc.action, IsReadAction(tc.action), tc.t)
+		got := ""
+		if f != nil {
+			got = f.Name
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("%s.%s at %s: window %q, %v; want %q", tc.tool, tc.action, tc.t, got, err, tc.want)
+		}
+	}
+
+	// A manifest's read_only flag overrides the action's name.
+	if f, _ := c.ActiveFreeze(context.Background(), "acme", "slack", "channel.info", false, at(17, 12, 0)); f == nil || f.Name != "weekend" {
+		t.Errorf("write action named like a read: window %+v", f)
+	}
+	if f, _ := c.ActiveFreeze(context.Background(), "acme", "slack", "channel.export", true, at(17, 12, 0)); f != nil {
+		t.Errorf("read-only action: window %q", f.Name)
+	}
+
+	s, _ := c.Get(context.Background(), "acme")
+	for _, f := range s.FreezeWindows {
+		if f.parsed == nil {
+			t.Errorf("cached window %q was not parsed", f.Name)
+		}
+	}
+	if store.settings["acme"].Settings.FreezeWindows[0].parsed != nil {
+		t.Error("parsing the cached windows changed the stored settings")
+	}
+}
+
+func TestSettingsCache_FallbackDecision(t *testing.T) {
+	two := 2
+	store := &fakeStore{settings: map[string]*SettingsRecord{
+		"acme": {Settings: Settings{FallbackPolicy: []FallbackRule{
+			{Name: "low-risk-reads", ReadOnly: true, MaxRisk: &two, Decision: types.DecisionAllow},
+			{Name: "jira-writes", ToolActions: []string{"jira.*"}, Decision: types.DecisionApprove},
+		}}},
+	}}
+	c := NewSettingsCache(store, time.Minute)
+
+	for _, tc := range []struct {
+		req  types.ToolCallRequest
+		want types.Decision
+	}{
+		{types.ToolCallRequest{TenantID: "acme", Tool: "slack", Action: "channel.list", RiskScore: 2}, types.DecisionAllow},
+		{types.ToolCallRequest{TenantID: "acme", Tool: "slack", Action: "channel.list", RiskScore: 3}, types.DecisionDeny},
+		{types.ToolCallRequest{TenantID: "acme", Tool: "slack", Action: "msg.post", RiskScore: 0}, types.DecisionDeny},
+		{types.ToolCallRequest{TenantID: "acme", Tool: "jira", Action: "issue.create", RiskScore: 9}, types.DecisionApprove},
+	} {
+		res, err := c.FallbackDecision(context.Background(), tc.req, IsReadAction(tc.req.Action), nil, nil)
+		if err != nil || res == nil || res.Decision != tc.want || !res.Fallback || res.ReasonCode != types.ReasonCodePolicyFallback {
+			t.Errorf("%s.%s risk %d: %+v, %v; want %s", tc.req.Tool, tc.req.Action, tc.req.RiskScore, res, err, tc.want)
+		}
+	}
+	if res, err := c.FallbackDecision(context.Background(), types.ToolCallRequest{TenantID: "other", Tool: "jira", Action: "issue.list"}, true, nil, nil); res != nil || err != nil {
+		t.Errorf("tenant without fallback policy: %+v, %v", res, err)
+	}
+
+	read := types.ToolCallRequest{TenantID: "acme", Tool: "slack", Action: "channel.list"}
+	over := []types.BudgetState{{Budget: types.Budget{Period: "day", Limit: 10}, Spent: 10, Exceeded: true}}
+	if res, _ := c.FallbackDecision(context.Background(), read, true, over, nil); res.Decision != types.DecisionDeny || res.ReasonCode != types.ReasonCodeBudgetExceeded || !res.Fallback {
+		t.Errorf("over budget: %+v", res)
+	}
+	findings := []types.InjectionFinding{{Rule: "ignore_instructions", Path: "/text", Match: "ignore previous"}}
+	if res, _ := c.FallbackDecision(context.Background(), read, true, nil, findings); res.Decision != types.DecisionApprove || res.ReasonCode != types.ReasonCodePromptInjection {
+		t.Errorf("injection findings: %+v", res)
+	}
+
+	eleven := 11
+	for _, r := range []FallbackRule{
+		{Decision: types.DecisionAllow},
+		{Name: "r", MaxRisk: &eleven, Decision: types.DecisionAllow},
+		{Name: "r", ToolActions: []string{"Jira"}, Decision: types.DecisionAllow},
+		{Name: "r", Decision: "maybe"},
+	} {
+		if err := (Settings{FallbackPolicy: []FallbackRule{r}}).Validate(); err == nil {
+			t.Errorf("%+v accepted", r)
+		}
+	}
+}
+
+func TestSettingsCache_TouchedCanary(t *testing.T) {
+	store := &fakeStore{settings: map[string]*SettingsRecord{
+		"acme": {Settings: Settings{CanaryResources: []Canary{
+			{Kind: BlockChannel, Value: "#board-private", Label: "decoy-channel"},
+			{Kind: BlockProject, Value: "VAULT"},
+		}}},
+	}}
+	c := NewSettingsCache(store, time.Minute)
+
+	for _, tc := range []struct {
+		calls []types.ToolCallRequest
+		want  string
+	}{
+		{[]types.ToolCallRequest{{Params: json.RawMessage(`{"channel":"#general"}`)}}, ""},
+		{[]types.ToolCallRequest{{Params: json.RawMessage(`{"channel":"#Board-Private"}`)}}, "decoy-channel"},
+		{[]types.ToolCallRequest{{Tool: "slack"}, {Params: json.RawMessage(`{"project":"vault"}`)}}, "VAULT"},
+		{[]types.ToolCallRequest{{AgentID: "VAULT", Resource: "#board-private"}}, ""},
+	} {
+		m, err := c.TouchedCanary(context.Background(), "acme", tc.calls...)
+		got := ""
+		if m != nil {
+			got = m.Name()
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("%+v: canary %q, %v; want %q", tc.calls, got, err, tc.want)
+		}
+	}
+
+	for _, bad := range []Canary{
+		{Kind: BlockAgent, Value: "agent-1"},
+		{Kind: BlockResource, Value: "*"},
+		{Kind: BlockResource, Value: "x", Label: strings.Repeat("l", 129)},
+	} {
+		if err := (Settings{CanaryResources: []Canary{bad}}).Validate(); err == nil {
+			t.Errorf("%+v accepted", bad)
+		}
+	}
+}
+
+func TestDigest_LastPeriod(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Friday 2026-10-16 12:00 UTC.
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		d        Digest
+		t        time.Time
+		from, to time.Time
+	}{
+		{Digest{Period: DigestWeekly}, now,
+			time.Date(2026, 10, 5, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)},
+		{Digest{Period: DigestWeekly}, time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC),
+			time.Date(2026, 10, 5, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)},
+		{Digest{Period: DigestMonthly}, now,
+			time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)},
+		// 2026-11-01 00:30 in Berlin is still October in UTC.
+		{Digest{Period: DigestMonthly, Timezone: "Europe/Berlin"}, time.Date(2026, 10, 31, 23, 30, 0, 0, time.UTC),
+			time.Date(2026, 10, 1, 0, 0, 0, 0, berlin), time.Date(2026, 11, 1, 0, 0, 0, 0, berlin)},
+	} {
+		from, to := tc.d.LastPeriod(tc.t)
+		if !from.Equal(tc.from) || !to.Equal(tc.to) {
+			t.Errorf("%s %q at %s: %s–%s, want %s–%s", tc.d.Period, tc.d.Timezone, tc.t, from, to, tc.from, tc.to)
+		}
+	}
+}
+
+func TestParseDefaults_Invalid(t *testing.T) {
+	for _, raw := range []string{"[]", "null", "{"} {
+		if _, err := ParseDefaults(raw); err == nil {
+			t.Errorf("ParseDefaults(%q): expected error", raw)
+		}
+	}
+}
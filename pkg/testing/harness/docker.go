@@ -0,0 +1,132 @@
+package harness
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// container is a throwaway container started with the docker CLI. The
+// harness drives the CLI the way testcontainers drives the Docker API,
+// which keeps it free of module dependencies; anything that can run
+// `docker run` (Docker Desktop, Colima, Podman's docker shim) works.
+type container struct {
+	id string
+}
+
+// containerSpec describes one container to start.
+type containerSpec struct {
+	image  string
+	env    map[string]string
+	ports  []string          // container ports to publish, e.g. "5432/tcp"
+	mounts map[string]string // host path → container path, read-only
+	args   []string          // command after the image
+}
+
+// dockerAvailable reports whether a Docker daemon answers.
+func dockerAvailable(ctx context.Context) error {
+	if _, err := exec.LookPath("docker"); err != nil {
+		return errors.New("docker CLI not found")
+	}
+	if _, err := docker(ctx, "info", "--format", "{{.ServerVersion}}"); err != nil {
+		return fmt.Errorf("docker daemon unavailable: %w", err)
+	}
+	return nil
+}
+
+// startContainer runs spec detached with its ports published on random
+// loopback ports. The container is removed when it stops.
+func startContainer(ctx context.Context, spec containerSpec) (*container, error) {
+	args := []string{"run", "-d", "--rm", "--label", "org.openclause.harness=true"}
+	for k, v := range spec.env {
+		args = append(args, "-e", k+"="+v)
+	}
+	for _, p := range spec.ports {
+		args = append(args, "-p", "127.0.0.1::"+p)
+	}
+	for host, path := range spec.mounts {
+		args = append(args, "-v", host+":"+path+":ro")
+	}
+	args = append(args, spec.image)
+	args = append(args, spec.args...)
+	out, err := docker(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("start %s: %w", spec.image, err)
+	}
+	return &container{id: strings.TrimSpace(out)}, nil
+}
+
+// hostAddr returns the host:port that port (e.g. "5432/tcp") is published
+// on.
+func (c *container) hostAddr(ctx context.Context, port string) (string, error) {
+	out, err := docker(ctx, "port", c.id, port)
+	if err != nil {
+		return "", fmt.Errorf("port %s: %w", port, err)
+	}
+	return parsePortOutput(out)
+}
+
+// parsePortOutput picks the IPv4 binding from `docker port` output, which
+// lists one "host:port" per line.
+func parsePortOutput(out string) (string, error) {
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		line = strings.TrimSpace(line)
+		host, port, err := net.SplitHostPort(line)
+		if err != nil || strings.Contains(host, ":") {
+			continue
+		}
+		if host == "0.0.0.0" {
+			host = "127.0.0.1"
+		}
+		return net.JoinHostPort(host, port), nil
+	}
+	return "", fmt.Errorf("no IPv4 binding in %q", out)
+}
+
+// logs returns the container's combined output, for failure messages.
+func (c *container) logs(ctx context.Context) string {
+	out, err := docker(ctx, "logs", "--tail", "50", c.id)
+	if err != nil {
+		return err.Error()
+	}
+	return out
+}
+
+// stop removes the container.
+func (c *container) stop(ctx context.Context) error {
+	_, err := docker(ctx, "rm", "-f", c.id)
+	return err
+}
+
+func docker(ctx context.Context, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("docker %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// waitFor calls ready until it succeeds or timeout passes, and returns the
+// last error on timeout.
+func waitFor(ctx context.Context, timeout time.Duration, ready func(context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for {
+		err := ready(ctx)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("not ready after %s: %w", timeout, err)
+		case <-time.After(250 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package harness
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/approvals/service"
+	"github.com/bturcanu/OpenClause/pkg/connectors"
+	"github.com/bturcanu/OpenClause/pkg/connectors/jira"
+	"github.com/bturcanu/OpenClause/pkg/connectors/slack"
+	"github.com/bturcanu/OpenClause/pkg/gateway"
+	"github.com/bturcanu/OpenClause/pkg/sdk/client"
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+// Tenants and agents created by migrations/002_seed.sql.
+const (
+	Tenant1 = "tenant1"
+	Tenant2 = "tenant2"
+	Agent1  = "agent-1" // tenant1
+	Agent2  = "agent-2" // tenant1
+	Agent3  = "agent-3" // tenant2
+)
+
+// Credentials StartServices configures.
+const (
+	APIKey1       = "sk-harness-tenant1"
+	APIKey2       = "sk-harness-tenant2"
+	InternalToken = "harness-internal-token"
+	// Approver is on the approver allowlist of both seeded tenants.
+	Approver = "approver@example.com"
+)
+
+// Services are the gateway and approvals service running in-process against
+// an Env, with mock Slack and Jira connectors.
+type Services struct {
+	GatewayURL   string
+	ApprovalsURL string
+	// Gateway calls the gateway as Tenant1.
+	Gateway *client.Client
+	// Approvals calls the approvals service with InternalToken.
+	Approvals *client.Approvals
+}
+
+// StartServices serves the gateway and approvals service on loopback ports
+// for the rest of t. Both are built the way cmd/openclause builds them, from
+// environment variables StartServices sets with t.Setenv, so t must not be
+// parallel; set further variables before calling it to change their
+// configuration. Policy is evaluated by the OPA container if the Env has
+// one, and by the embedded engine otherwise.
+func (e *Env) StartServices(t testing.TB) *Services {
+	t.Helper()
+	gwSrv := httptest.NewUnstartedServer(nil)
+	apSrv := httptest.NewUnstartedServer(nil)
+	s := &Services{
+		GatewayURL:   "http://" + gwSrv.Listener.Addr().String(),
+		ApprovalsURL: "http://" + apSrv.Listener.Addr().String(),
+	}
+
+	vars := map[string]string{
+		"OC_MODE":                   "",
+		"EVIDENCE_BACKEND":          "postgres",
+		"APPROVALS_BACKEND":         "postgres",
+		"API_KEYS":                  Tenant1 + ":" + APIKey1 + "," + Tenant2 + ":" + APIKey2,
+		"INTERNAL_AUTH_TOKEN":       InternalToken,
+		"APPROVER_EMAIL_ALLOWLIST":  Tenant1 + ":" + Approver + "," + Tenant2 + ":" + Approver,
+		"APPROVALS_URL":             s.ApprovalsURL,
+		"APPROVALS_DIGESTS_ENABLED": "false",
+		"POLICY_ENGINE":             "embedded",
+	}
+	if e.OPAURL != "" {
+		vars["POLICY_ENGINE"] = "opa"
+		vars["OPA_URL"] = e.OPAURL
+	}
+	if e.MinIO.Endpoint != "" {
+		vars["BLOB_S3_ENDPOINT"] = e.MinIO.Endpoint
+		vars["BLOB_S3_ACCESS_KEY"] = e.MinIO.AccessKey
+		vars["BLOB_S3_SECRET_KEY"] = e.MinIO.SecretKey
+		vars["BLOB_S3_BUCKET"] = e.MinIO.Bucket
+		vars["BLOB_S3_SECURE"] = "false"
+	}
+	log := slog.New(slog.NewTextHandler(testWriter{t}, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	local := connectors.NewLocal(nil)
+	vars["CONNECTOR_SLACK_URL"] = local.Handle("connector-slack",
+		slack.New(slack.Config{Logger: log, Mock: true, InternalToken: InternalToken}).Handler())
+	vars["CONNECTOR_JIRA_URL"] = local.Handle("connector-jira",
+		jira.New(jira.Config{Logger: log, Mock: true, InternalToken: InternalToken}).Handler())
+	for k, v := range vars {
+		t.Setenv(k, v)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	gw, err := gateway.New(ctx, log, gateway.Options{Pool: e.Pool, ConnectorTransport: local})
+	if err != nil {
+		t.Fatalf("harness: gateway: %v", err)
+	}
+	ap, err := service.New(ctx, log, service.Options{Pool: e.Pool, NotifyTransport: local})
+	if err != nil {
+		_ = gw.Close(context.Background())
+		t.Fatalf("harness: approvals: %v", err)
+	}
+	gwSrv.Config.Handler = gw.Handler()
+	apSrv.Config.Handler = ap.Handler()
+	gwSrv.Start()
+	apSrv.Start()
+	// Cleanups run last-in first-out: stop serving, then close the services,
+	// before the containers they use are removed.
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := ap.Close(ctx); err != nil {
+			t.Logf("harness: close approvals: %v", err)
+		}
+		if err := gw.Close(ctx); err != nil {
+			t.Logf("harness: close gateway: %v", err)
+		}
+	})
+	t.Cleanup(apSrv.Close)
+	t.Cleanup(gwSrv.Close)
+
+	s.Gateway = client.New(s.GatewayURL, APIKey1)
+	s.Approvals = client.NewApprovals(s.ApprovalsURL, InternalToken)
+	return s
+}
+
+// Client returns a gateway client authenticated with apiKey, e.g. APIKey2
+// to act as Tenant2.
+func (s *Services) Client(apiKey string) *client.Client {
+	return client.New(s.GatewayURL, apiKey)
+}
+
+// ToolCall returns a tool-call request from Agent1 of Tenant1 with a unique
+// idempotency key, params {} and the given risk score.
+func ToolCall(tool, action string, risk int) types.ToolCallRequest {
+	return types.ToolCallRequest{
+		TenantID:       Tenant1,
+		AgentID:        Agent1,
+		Tool:           tool,
+		Action:         action,
+		Params:         json.RawMessage(`{}`),
+		RiskScore:      risk,
+		IdempotencyKey: fmt.Sprintf("harness-%d", time.Now().UnixNano()),
+	}
+}
+
+// testWriter sends service logs to the test log, so they show up only
+// for failing tests or under -v.
+type testWriter struct{ t testing.TB }
+
+func (w testWriter) Write(p []byte) (int, error) {
+	w.t.Log(strings.TrimRight(string(p), "\n"))
+	return len(p), nil
+}
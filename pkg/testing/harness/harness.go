@@ -0,0 +1,273 @@
+// Package harness runs OpenClause against real components for end-to-end
+// tests. Start launches Postgres, and optionally OPA and MinIO, in throwaway
+// containers, applies the migrations and seed data, and StartServices runs
+// the gateway and approvals service in-process against them:
+//
+//	func TestMyConnector(t *testing.T) {
+//		env := harness.Start(t, harness.Options{OPA: true})
+//		svc := env.StartServices(t)
+//		resp, err := svc.Gateway.Submit(ctx, harness.ToolCall("jira", "issue.list", 1))
+//		...
+//	}
+//
+// Tests skip when Docker is unavailable or under go test -short. Containers
+// are removed when the test ends.
+package harness
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// Default images match deploy/docker-compose.yml.
+const (
+	DefaultPostgresImage = "postgres:16-alpine"
+	DefaultOPAImage      = "openpolicyagent/opa:0.62.0"
+	DefaultMinIOImage    = "minio/minio:RELEASE.2024-06-13T22-53-53Z"
+)
+
+// startTimeout bounds how long each container may take to become ready,
+// including the image pull on first use.
+const startTimeout = 2 * time.Minute
+
+// Options selects the components Start launches. Postgres always runs.
+type Options struct {
+	// OPA runs an OPA server loaded with policy/bundles/v0. Without it,
+	// StartServices evaluates the same bundle with the embedded engine.
+	OPA bool
+	// MinIO runs MinIO with an empty bucket, which StartServices uses for
+	// params sent by reference.
+	MinIO bool
+	// NoSeed skips migrations/002_seed.sql, leaving the schema empty.
+	NoSeed bool
+	// PostgresImage, OPAImage and MinIOImage override the default images.
+	PostgresImage string
+	OPAImage      string
+	MinIOImage    string
+}
+
+// Env is the set of running components.
+type Env struct {
+	// Pool is connected to the migrated database.
+	Pool *pgxpool.Pool
+	// PostgresDSN connects to the same database.
+	PostgresDSN string
+	// OPAURL is the OPA server's base URL, or "" without Options.OPA.
+	OPAURL string
+	// MinIO is the object store, or zero without Options.MinIO.
+	MinIO MinIOConfig
+
+	root string // repository root
+}
+
+// MinIOConfig locates the MinIO container and its bucket.
+type MinIOConfig struct {
+	Endpoint  string // host:port, plain HTTP
+	AccessKey string
+	SecretKey string
+	Bucket    string
+}
+
+// Start launches the components opts selects and registers their removal
+// with t.Cleanup. It skips t when Docker is unavailable or -short is set,
+// and fails it if a component does not come up.
+func Start(t testing.TB, opts Options) *Env {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("harness: skipped with -short")
+	}
+	ctx := context.Background()
+	if err := dockerAvailable(ctx); err != nil {
+		t.Skipf("harness: %v", err)
+	}
+	root, err := repoRoot()
+	if err != nil {
+		t.Fatalf("harness: %v", err)
+	}
+	env := &Env{root: root}
+	if err := env.startPostgres(ctx, t, cmp(opts.PostgresImage, DefaultPostgresImage), !opts.NoSeed); err != nil {
+		t.Fatalf("harness: postgres: %v", err)
+	}
+	if opts.OPA {
+		if err := env.startOPA(ctx, t, cmp(opts.OPAImage, DefaultOPAImage)); err != nil {
+			t.Fatalf("harness: opa: %v", err)
+		}
+	}
+	if opts.MinIO {
+		if err := env.startMinIO(ctx, t, cmp(opts.MinIOImage, DefaultMinIOImage)); err != nil {
+			t.Fatalf("harness: minio: %v", err)
+		}
+	}
+	return env
+}
+
+// run starts spec and removes the container when t ends.
+func run(ctx context.Context, t testing.TB, spec containerSpec) (*container, error) {
+	c, err := startContainer(ctx, spec)
+	if err != nil {
+		return nil, err
+	}
+	t.Cleanup(func() {
+		if err := c.stop(context.Background()); err != nil {
+			t.Logf("harness: remove %s: %v", spec.image, err)
+		}
+	})
+	return c, nil
+}
+
+func (e *Env) startPostgres(ctx context.Context, t testing.TB, image string, seed bool) error {
+	c, err := run(ctx, t, containerSpec{
+		image: image,
+		env:   map[string]string{"POSTGRES_USER": "openclause", "POSTGRES_PASSWORD": "openclause", "POSTGRES_DB": "openclause"},
+		ports: []string{"5432/tcp"},
+	})
+	if err != nil {
+		return err
+	}
+	addr, err := c.hostAddr(ctx, "5432/tcp")
+	if err != nil {
+		return err
+	}
+	e.PostgresDSN = "postgres://openclause:openclause@" + addr + "/openclause?sslmode=disable"
+	pool, err := pgxpool.New(ctx, e.PostgresDSN)
+	if err != nil {
+		return err
+	}
+	t.Cleanup(pool.Close)
+	// The image restarts the server once after initdb, so a single
+	// successful ping is not enough: require a query to succeed.
+	if err := waitFor(ctx, startTimeout, func(ctx context.Context) error {
+		_, err := pool.Exec(ctx, "SELECT 1")
+		return err
+	}); err != nil {
+		return fmt.Errorf("%w\n%s", err, c.logs(ctx))
+	}
+	e.Pool = pool
+	return e.migrate(ctx, seed)
+}
+
+// migrate applies migrations/*.sql in name order; files named *seed* are
+// applied only when seed is set.
+func (e *Env) migrate(ctx context.Context, seed bool) error {
+	files, err := filepath.Glob(filepath.Join(e.root, "migrations", "*.sql"))
+	if err != nil {
+		return err
+	}
+	slices.Sort(files)
+	for _, f := range files {
+		if !seed && strings.Contains(filepath.Base(f), "seed") {
+			continue
+		}
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		if _, err := e.Pool.Exec(ctx, string(b)); err != nil {
+			return fmt.Errorf("apply %s: %w", filepath.Base(f), err)
+		}
+	}
+	return nil
+}
+
+func (e *Env) startOPA(ctx context.Context, t testing.TB, image string) error {
+	c, err := run(ctx, t, containerSpec{
+		image:  image,
+		ports:  []string{"8181/tcp"},
+		mounts: map[string]string{filepath.Join(e.root, "policy", "bundles", "v0"): "/policy"},
+		args:   []string{"run", "--server", "--addr=0.0.0.0:8181", "/policy"},
+	})
+	if err != nil {
+		return err
+	}
+	addr, err := c.hostAddr(ctx, "8181/tcp")
+	if err != nil {
+		return err
+	}
+	e.OPAURL = "http://" + addr
+	if err := waitFor(ctx, startTimeout, func(ctx context.Context) error {
+		return httpOK(ctx, e.OPAURL+"/health?bundles")
+	}); err != nil {
+		return fmt.Errorf("%w\n%s", err, c.logs(ctx))
+	}
+	return nil
+}
+
+func (e *Env) startMinIO(ctx context.Context, t testing.TB, image string) error {
+	cfg := MinIOConfig{AccessKey: "openclause", SecretKey: "openclause-secret", Bucket: "openclause-test"}
+	c, err := run(ctx, t, containerSpec{
+		image: image,
+		env:   map[string]string{"MINIO_ROOT_USER": cfg.AccessKey, "MINIO_ROOT_PASSWORD": cfg.SecretKey},
+		ports: []string{"9000/tcp"},
+		args:  []string{"server", "/data"},
+	})
+	if err != nil {
+		return err
+	}
+	if cfg.Endpoint, err = c.hostAddr(ctx, "9000/tcp"); err != nil {
+		return err
+	}
+	client, err := minio.New(cfg.Endpoint, &minio.Options{Creds: credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, "")})
+	if err != nil {
+		return err
+	}
+	if err := waitFor(ctx, startTimeout, func(ctx context.Context) error {
+		return client.MakeBucket(ctx, cfg.Bucket, minio.MakeBucketOptions{})
+	}); err != nil {
+		return fmt.Errorf("%w\n%s", err, c.logs(ctx))
+	}
+	e.MinIO = cfg
+	return nil
+}
+
+func httpOK(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return nil
+}
+
+// repoRoot finds the module root, where migrations/ and policy/ live, from
+// the test's working directory.
+func repoRoot() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "migrations", "001_initial.sql")); err == nil {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("migrations/001_initial.sql not found above the working directory")
+		}
+		dir = parent
+	}
+}
+
+func cmp(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
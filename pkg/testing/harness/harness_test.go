@@ -0,0 +1,70 @@
+package harness
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bturcanu/OpenClause/pkg/approvals"
+	"github.com/bturcanu/OpenClause/pkg/types"
+)
+
+func TestParsePortOutput(t *testing.T) {
+	for _, tc := range []struct {
+		out, want string
+	}{
+		{"127.0.0.1:49153\n", "127.0.0.1:49153"},
+		{"0.0.0.0:49153\n[::]:49153\n", "127.0.0.1:49153"},
+		{"[::]:49153\n0.0.0.0:49154\n", "127.0.0.1:49154"},
+	} {
+		got, err := parsePortOutput(tc.out)
+		if err != nil || got != tc.want {
+			t.Errorf("parsePortOutput(%q) = %q, %v; want %q", tc.out, got, err, tc.want)
+		}
+	}
+	if _, err := parsePortOutput("[::]:49153\n"); err == nil {
+		t.Error("IPv6-only output: want error")
+	}
+}
+
+// TestHarness_ApprovalFlow runs a call that needs approval through real
+// Postgres and OPA: submit, approve, execute against the mock connector.
+func TestHarness_ApprovalFlow(t *testing.T) {
+	env := Start(t, Options{OPA: true})
+	svc := env.StartServices(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	resp, err := svc.Gateway.Submit(ctx, ToolCall("slack", "msg.post", 8))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Decision != types.DecisionApprove {
+		t.Fatalf("decision = %s (%s), want approve", resp.Decision, resp.Reason)
+	}
+
+	var req *approvals.ApprovalRequest
+	for r, err := range svc.Approvals.Pending(ctx, Tenant1) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.EventID == resp.EventID {
+			req = &r
+			break
+		}
+	}
+	if req == nil {
+		t.Fatalf("no pending approval for event %s", resp.EventID)
+	}
+	if _, err := svc.Approvals.Approve(ctx, req.ID, approvals.GrantInput{Approver: Approver, MaxUses: 1, ExpiresInSec: 300}); err != nil {
+		t.Fatal(err)
+	}
+
+	exec, err := svc.Gateway.Execute(ctx, resp.EventID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exec.Result == nil || exec.Result.Status != "success" {
+		t.Fatalf("execute = %+v", exec)
+	}
+}
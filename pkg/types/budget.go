@@ -0,0 +1,69 @@
+package types
+
+import (
+	"errors"
+	"math"
+)
+
+// Budget periods. Periods are calendar days and months in UTC.
+const (
+	BudgetPeriodDay   = "day"
+	BudgetPeriodMonth = "month"
+)
+
+// BudgetEachAgent as a budget's agent_id caps every agent separately.
+const BudgetEachAgent = "*"
+
+// Budget caps what a tenant, or its agents, may spend per period, in the
+// unit connectors report cost in (API credits, dollars).
+type Budget struct {
+	// AgentID scopes the budget: "" caps the tenant as a whole, "*" caps
+	// each agent, and any other value caps that one agent.
+	AgentID string  `json:"agent_id,omitempty"`
+	Period  string  `json:"period"`
+	Limit   float64 `json:"limit"`
+}
+
+// Validate checks the period, limit and agent scope.
+func (b Budget) Validate() error {
+	if b.Period != BudgetPeriodDay && b.Period != BudgetPeriodMonth {
+		return errors.New("period must be day or month")
+	}
+	if b.Limit <= 0 || math.IsNaN(b.Limit) || math.IsInf(b.Limit, 0) {
+		return errors.New("limit must be a positive number")
+	}
+	if len(b.AgentID) > 128 {
+		return errors.New("agent_id is too long")
+	}
+	return nil
+}
+
+// Applies reports whether the budget covers calls by agentID.
+func (b Budget) Applies(agentID string) bool {
+	return b.AgentID == "" || b.AgentID == BudgetEachAgent || b.AgentID == agentID
+}
+
+// BudgetState is a budget as policy sees it for one call: what has been
+// spent against it this period and whether the call's cost_estimate would
+// take it over, e.g.
+//
+//	deny if some b in input.budgets; b.would_exceed
+type BudgetState struct {
+	Budget
+	Spent       float64 `json:"spent"`
+	Remaining   float64 `json:"remaining"`
+	Exceeded    bool    `json:"exceeded"`
+	WouldExceed bool    `json:"would_exceed"`
+}
+
+// NewBudgetState computes the state of b after spent, for a call
+// estimated to cost costEstimate.
+func NewBudgetState(b Budget, spent, costEstimate float64) BudgetState {
+	return BudgetState{
+		Budget:      b,
+		Spent:       spent,
+		Remaining:   math.Max(0, b.Limit-spent),
+		Exceeded:    spent >= b.Limit,
+		WouldExceed: spent+costEstimate > b.Limit,
+	}
+}
@@ -0,0 +1,49 @@
+package types
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Cursor is a keyset pagination position: the sort time and ID of the last
+// item of a page. A listing ordered newest first resumes with the items
+// that sort after it, so deep pages cost the same as the first, unlike
+// OFFSET. Clients see only its encoded form, as an opaque string.
+type Cursor struct {
+	Time time.Time
+	ID   string
+}
+
+// IsZero reports whether c is the start of a listing.
+func (c Cursor) IsZero() bool { return c.ID == "" }
+
+// String encodes c as an opaque, URL-safe token. The zero Cursor encodes as
+// "".
+func (c Cursor) String() string {
+	if c.IsZero() {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(c.Time.UTC().Format(time.RFC3339Nano) + "|" + c.ID))
+}
+
+// ParseCursor decodes a token made by Cursor.String. "" is the zero Cursor.
+func ParseCursor(s string) (Cursor, error) {
+	if s == "" {
+		return Cursor{}, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, errors.New("malformed cursor")
+	}
+	at, id, ok := strings.Cut(string(raw), "|")
+	if !ok || id == "" {
+		return Cursor{}, errors.New("malformed cursor")
+	}
+	t, err := time.Parse(time.RFC3339Nano, at)
+	if err != nil {
+		return Cursor{}, errors.New("malformed cursor")
+	}
+	return Cursor{Time: t, ID: id}, nil
+}
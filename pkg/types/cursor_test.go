@@ -0,0 +1,25 @@
+package types
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCursor_RoundTrip(t *testing.T) {
+	c := Cursor{Time: time.Date(2026, 10, 16, 9, 30, 0, 123456000, time.FixedZone("CEST", 2*3600)), ID: "req|1"}
+	got, err := ParseCursor(c.String())
+	if err != nil || !got.Time.Equal(c.Time) || got.ID != c.ID {
+		t.Fatalf("ParseCursor(%q) = %+v, %v", c.String(), got, err)
+	}
+	if (Cursor{}).String() != "" {
+		t.Error("zero cursor encodes as non-empty")
+	}
+	if got, err := ParseCursor(""); err != nil || !got.IsZero() {
+		t.Errorf(`ParseCursor("") = %+v, %v`, got, err)
+	}
+	for _, bad := range []string{"%%%", "bm90LWEtY3Vyc29y", "MjAyNi0xMC0xNlQwOTozMDowMFp8"} {
+		if _, err := ParseCursor(bad); err == nil {
+			t.Errorf("ParseCursor(%q) succeeded", bad)
+		}
+	}
+}
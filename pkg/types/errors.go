@@ -78,6 +78,16 @@ func ErrRateLimited() *APIError {
 	return &APIError{Code: "RATE_LIMITED", Message: "too many requests", Retryable: true, HTTPCode: http.StatusTooManyRequests}
 }
 
+// ErrConcurrencyLimited is returned when the calling agent already has as
+// many calls in flight as it may.
+func ErrConcurrencyLimited() *APIError {
+	return &APIError{Code: "CONCURRENCY_LIMITED", Message: "too many calls in flight for this agent", Retryable: true, HTTPCode: http.StatusTooManyRequests}
+}
+
+func ErrOverloaded() *APIError {
+	return &APIError{Code: "OVERLOADED", Message: "server is overloaded, retry later", Retryable: true, HTTPCode: http.StatusServiceUnavailable}
+}
+
 func ErrConnectorTimeout(tool string) *APIError {
 	return &APIError{Code: "CONNECTOR_TIMEOUT", Message: fmt.Sprintf("connector %s timed out", tool), Retryable: true, HTTPCode: http.StatusGatewayTimeout}
 }
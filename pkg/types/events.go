@@ -0,0 +1,180 @@
+package types
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Lifecycle events (CloudEvents 1.0)
+// ──────────────────────────────────────────────────────────────────────────────
+
+// Event types emitted over a tool call's lifecycle.
+const (
+	EventToolCallReceived  = "oc.toolcall.received"
+	EventToolCallAllowed   = "oc.toolcall.allowed"
+	EventToolCallDenied    = "oc.toolcall.denied"
+	EventToolCallExecuted  = "oc.toolcall.executed"
+	EventApprovalRequested = "oc.approval.requested"
+	EventApprovalGranted   = "oc.approval.granted"
+	EventApprovalDenied    = "oc.approval.denied"
+	EventApprovalExpired   = "oc.approval.expired"
+	EventApprovalReleased  = "oc.approval.released"
+	EventApprovalCancelled = "oc.approval.cancelled"
+	EventCanaryTouched     = "oc.alert.canary_touched"
+)
+
+// EventSchema describes one registered event type. DataSchema is the
+// CloudEvents dataschema URI identifying the shape and version of data.
+type EventSchema struct {
+	Type        string `json:"type"`
+	DataSchema  string `json:"dataschema"`
+	Description string `json:"description"`
+}
+
+// EventRegistry is the single list of event types OpenClause emits. Every
+// CloudEvent is built through NewCloudEvent, which rejects other types.
+var EventRegistry = map[string]EventSchema{
+	EventToolCallReceived:  {EventToolCallReceived, "urn:openclause:schema:toolcall:1", "A tool call was accepted for policy evaluation."},
+	EventToolCallAllowed:   {EventToolCallAllowed, "urn:openclause:schema:toolcall:1", "Policy allowed a tool call."},
+	EventToolCallDenied:    {EventToolCallDenied, "urn:openclause:schema:toolcall:1", "Policy denied a tool call."},
+	EventToolCallExecuted:  {EventToolCallExecuted, "urn:openclause:schema:toolcall:1", "A connector executed a tool call; data carries the execution status."},
+	EventApprovalRequested: {EventApprovalRequested, "urn:openclause:schema:approval:1", "A tool call is waiting for human approval."},
+	EventApprovalGranted:   {EventApprovalGranted, "urn:openclause:schema:approval:1", "An approver granted an approval request."},
+	EventApprovalDenied:    {EventApprovalDenied, "urn:openclause:schema:approval:1", "An approver denied an approval request."},
+	EventApprovalExpired:   {EventApprovalExpired, "urn:openclause:schema:approval:1", "An approval request expired undecided."},
+	EventApprovalReleased:  {EventApprovalReleased, "urn:openclause:schema:approval:1", "An approver released a quarantined call's output to the agent."},
+	EventApprovalCancelled: {EventApprovalCancelled, "urn:openclause:schema:approval:1", "The requesting agent withdrew a pending approval request."},
+	EventCanaryTouched:     {EventCanaryTouched, "urn:openclause:schema:toolcall:1", "An agent touched one of the tenant's canary resources; the call was denied."},
+}
+
+// EventTypes returns the registered event types, sorted.
+func EventTypes() []string {
+	out := make([]string, 0, len(EventRegistry))
+	for t := range EventRegistry {
+		out = append(out, t)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// CloudEvent is a structured-mode CloudEvents 1.0 envelope. TenantID is the
+// "tenantid" extension attribute.
+type CloudEvent struct {
+	SpecVersion     string    `json:"specversion"`
+	ID              string    `json:"id"`
+	Type            string    `json:"type"`
+	Source          string    `json:"source"`
+	Subject         string    `json:"subject,omitempty"`
+	Time            time.Time `json:"time"`
+	DataContentType string    `json:"datacontenttype"`
+	DataSchema      string    `json:"dataschema,omitempty"`
+	TenantID        string    `json:"tenantid,omitempty"`
+	Data            any       `json:"data"`
+}
+
+// NewCloudEvent builds an event of a registered type.
+func NewCloudEvent(eventType, id, source, tenantID, subject string, at time.Time, data any) (CloudEvent, error) {
+	schema, ok := EventRegistry[eventType]
+	if !ok {
+		return CloudEvent{}, fmt.Errorf("types.NewCloudEvent: unregistered event type %q", eventType)
+	}
+	return CloudEvent{
+		SpecVersion:     "1.0",
+		ID:              id,
+		Type:            eventType,
+		Source:          source,
+		Subject:         subject,
+		Time:            at.UTC(),
+		DataContentType: "application/json",
+		DataSchema:      schema.DataSchema,
+		TenantID:        tenantID,
+		Data:            data,
+	}, nil
+}
+
+// ToolCallEventData is the data of oc.toolcall.* events. Like exported
+// evidence it carries no params, connector output, or caller metadata.
+type ToolCallEventData struct {
+	EventID         string    `json:"event_id"`
+	TenantID        string    `json:"tenant_id"`
+	AgentID         string    `json:"agent_id"`
+	SessionID       string    `json:"session_id,omitempty"`
+	Tool            string    `json:"tool"`
+	Action          string    `json:"action"`
+	Resource        string    `json:"resource,omitempty"`
+	RiskScore       int       `json:"risk_score"`
+	RiskFactors     []string  `json:"risk_factors,omitempty"`
+	Decision        Decision  `json:"decision,omitempty"`
+	Reason          string    `json:"reason,omitempty"`
+	ReasonCode      string    `json:"reason_code,omitempty"`
+	ExecutionStatus string    `json:"execution_status,omitempty"`
+	DurationMS      int64     `json:"duration_ms,omitempty"`
+	TraceID         string    `json:"trace_id,omitempty"`
+	ReceivedAt      time.Time `json:"received_at"`
+	// OutputSHA256 is the hex SHA-256 of the connector's full output, set
+	// on oc.toolcall.executed events when the connector returned any.
+	OutputSHA256 string `json:"output_sha256,omitempty"`
+	// OriginalRiskScore is the agent's risk_score when policy adjusted it.
+	OriginalRiskScore *int `json:"original_risk_score,omitempty"`
+	// CompensatesEventID is set on events of a compensating call.
+	CompensatesEventID string `json:"compensates_event_id,omitempty"`
+	ParentEventID      string `json:"parent_event_id,omitempty"`
+	PlanID             string `json:"plan_id,omitempty"`
+	// Canary names the canary resource on oc.alert.canary_touched events.
+	Canary string `json:"canary,omitempty"`
+}
+
+// ApprovalEventData is the data of oc.approval.* events.
+type ApprovalEventData struct {
+	ApprovalRequestID string    `json:"approval_request_id"`
+	EventID           string    `json:"event_id"`
+	TenantID          string    `json:"tenant_id"`
+	AgentID           string    `json:"agent_id"`
+	Tool              string    `json:"tool"`
+	Action            string    `json:"action"`
+	Resource          string    `json:"resource,omitempty"`
+	RiskScore         int       `json:"risk_score"`
+	Kind              string    `json:"kind,omitempty"`
+	Status            string    `json:"status"`
+	Approver          string    `json:"approver,omitempty"`
+	Reason            string    `json:"reason,omitempty"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+// EventSubscription is a tenant's CloudEvents sink. Types limits delivery to
+// the listed event types; empty means every type.
+type EventSubscription struct {
+	URL       string   `json:"url"`
+	SecretRef string   `json:"secret_ref,omitempty"`
+	Types     []string `json:"types,omitempty"`
+}
+
+// Result sink kinds.
+const (
+	ResultSinkWebhook = "webhook"
+	ResultSinkQueue   = "queue"
+)
+
+// ResultSink is a tenant's destination for oc.toolcall.executed events. A
+// webhook sink is POSTed to like an event subscription; a queue sink is
+// published to the tenant's results topic on the deployment's event bus.
+type ResultSink struct {
+	Type      string `json:"type"`
+	URL       string `json:"url,omitempty"`
+	SecretRef string `json:"secret_ref,omitempty"`
+}
+
+// Wants reports whether the subscription receives eventType.
+func (s EventSubscription) Wants(eventType string) bool {
+	if len(s.Types) == 0 {
+		return true
+	}
+	for _, t := range s.Types {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
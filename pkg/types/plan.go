@@ -0,0 +1,126 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MaxPlanSteps bounds the number of tool calls in one plan.
+const MaxPlanSteps = 10
+
+// Plans are recorded in the evidence log as one tool call on a reserved
+// tool, whose params are the steps. Agents cannot call it directly.
+const (
+	PlanTool   = "oc"
+	PlanAction = "plan"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// ToolCallPlan — an ordered list of tool calls approved as a unit.
+// ──────────────────────────────────────────────────────────────────────────────
+
+// ToolCallPlan is submitted, evaluated by policy and approved as a unit, then
+// executed step by step; execution stops at the first step that fails.
+// Steps inherit the plan's tenant, agent, session and trace; ParentEventID
+// applies to the plan itself, whose event ID becomes each step's plan_id.
+type ToolCallPlan struct {
+	TenantID       string `json:"tenant_id"`
+	AgentID        string `json:"agent_id"`
+	SessionID      string `json:"session_id,omitempty"`
+	TraceID        string `json:"trace_id,omitempty"`
+	ParentEventID  string `json:"parent_event_id,omitempty"`
+	IdempotencyKey string `json:"idempotency_key"`
+	// ApproverGroup and NotifyChannel route the plan's approval request,
+	// as on a single call.
+	ApproverGroup string            `json:"approver_group,omitempty"`
+	NotifyChannel string            `json:"notify_channel,omitempty"`
+	Steps         []ToolCallRequest `json:"steps"`
+}
+
+// NormalizeAndValidate copies the plan's identity onto its steps and
+// enforces the request invariants on each. Step idempotency keys are set to
+// the plan's; the gateway assigns each step its own when it runs.
+func (p *ToolCallPlan) NormalizeAndValidate() error {
+	if p.TenantID == "" {
+		return &ValidationError{Field: "tenant_id", Reason: "required"}
+	}
+	if p.AgentID == "" {
+		return &ValidationError{Field: "agent_id", Reason: "required"}
+	}
+	if p.IdempotencyKey == "" {
+		return &ValidationError{Field: "idempotency_key", Reason: "required"}
+	}
+	if len(p.Steps) == 0 {
+		return &ValidationError{Field: "steps", Reason: "required"}
+	}
+	if len(p.Steps) > MaxPlanSteps {
+		return &ValidationError{Field: "steps", Reason: fmt.Sprintf("exceeds %d entries", MaxPlanSteps)}
+	}
+	for i := range p.Steps {
+		step := &p.Steps[i]
+		step.TenantID = p.TenantID
+		step.AgentID = p.AgentID
+		step.SessionID = p.SessionID
+		step.TraceID = p.TraceID
+		step.IdempotencyKey = p.IdempotencyKey
+		if err := step.NormalizeAndValidate(); err != nil {
+			var ve *ValidationError
+			if errors.As(err, &ve) {
+				return &ValidationError{Field: fmt.Sprintf("steps[%d].%s", i, ve.Field), Reason: ve.Reason}
+			}
+			return err
+		}
+		if step.Tool == PlanTool {
+			return &ValidationError{Field: fmt.Sprintf("steps[%d].tool", i), Reason: "reserved"}
+		}
+		if step.CompensatesEventID != "" {
+			return &ValidationError{Field: fmt.Sprintf("steps[%d].compensates_event_id", i), Reason: "set by the gateway"}
+		}
+		if step.PlanID != "" {
+			return &ValidationError{Field: fmt.Sprintf("steps[%d].plan_id", i), Reason: "set by the gateway"}
+		}
+		if step.ParentEventID != "" {
+			return &ValidationError{Field: fmt.Sprintf("steps[%d].parent_event_id", i), Reason: "set parent_event_id on the plan"}
+		}
+		if step.ApproverGroup != "" || step.NotifyChannel != "" {
+			return &ValidationError{Field: fmt.Sprintf("steps[%d].approver_group", i), Reason: "set approver_group and notify_channel on the plan"}
+		}
+		if step.ParamsRef != nil {
+			return &ValidationError{Field: fmt.Sprintf("steps[%d].params_ref", i), Reason: "not supported in plan steps; send params inline"}
+		}
+	}
+	return nil
+}
+
+// PlanStepKey is the idempotency key of step n (from 1) of the plan recorded
+// as event planEventID.
+func PlanStepKey(planEventID string, n int) string {
+	return fmt.Sprintf("plan:%s:%d", planEventID, n)
+}
+
+// IsPlan reports whether r is the recorded form of a plan.
+func (r *ToolCallRequest) IsPlan() bool {
+	return r.Tool == PlanTool && r.Action == PlanAction
+}
+
+// PlanStepResult is the outcome of one plan step. Status is the step's
+// execution status, or "skipped" when an earlier step failed.
+type PlanStepResult struct {
+	Step     int              `json:"step"`
+	EventID  string           `json:"event_id,omitempty"`
+	Tool     string           `json:"tool"`
+	Action   string           `json:"action"`
+	Resource string           `json:"resource,omitempty"`
+	Status   string           `json:"status"`
+	Result   *ExecutionResult `json:"result,omitempty"`
+}
+
+// PlanSummary lists a plan's steps as "tool.action", e.g. for approvers.
+func PlanSummary(steps []ToolCallRequest) string {
+	names := make([]string, len(steps))
+	for i := range steps {
+		names[i] = steps[i].ToolAction()
+	}
+	return strings.Join(names, ", ")
+}
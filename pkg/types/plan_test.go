@@ -0,0 +1,40 @@
+package types
+
+import "testing"
+
+func TestToolCallPlan_Validate(t *testing.T) {
+	step := func(tool, action string) ToolCallRequest { return ToolCallRequest{Tool: tool, Action: action} }
+	tooMany := make([]ToolCallRequest, MaxPlanSteps+1)
+	for i := range tooMany {
+		tooMany[i] = step("slack", "msg.post")
+	}
+	tests := []struct {
+		name  string
+		plan  ToolCallPlan
+		field string
+	}{
+		{"no steps", ToolCallPlan{TenantID: "t", AgentID: "a", IdempotencyKey: "k"}, "steps"},
+		{"too many steps", ToolCallPlan{TenantID: "t", AgentID: "a", IdempotencyKey: "k", Steps: tooMany}, "steps"},
+		{"invalid step", ToolCallPlan{TenantID: "t", AgentID: "a", IdempotencyKey: "k", Steps: []ToolCallRequest{step("jira", "issue.create"), step("slack", "")}}, "steps[1].action"},
+		{"nested plan", ToolCallPlan{TenantID: "t", AgentID: "a", IdempotencyKey: "k", Steps: []ToolCallRequest{step(PlanTool, PlanAction)}}, "steps[0].tool"},
+		{"step parent", ToolCallPlan{TenantID: "t", AgentID: "a", IdempotencyKey: "k", Steps: []ToolCallRequest{{Tool: "slack", Action: "msg.post", ParentEventID: "e1"}}}, "steps[0].parent_event_id"},
+		{"step routing", ToolCallPlan{TenantID: "t", AgentID: "a", IdempotencyKey: "k", Steps: []ToolCallRequest{{Tool: "slack", Action: "msg.post", NotifyChannel: "#ops"}}}, "steps[0].approver_group"},
+		{"step plan id", ToolCallPlan{TenantID: "t", AgentID: "a", IdempotencyKey: "k", Steps: []ToolCallRequest{{Tool: "slack", Action: "msg.post", PlanID: "p1"}}}, "steps[0].plan_id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ve, ok := tt.plan.NormalizeAndValidate().(*ValidationError)
+			if !ok || ve.Field != tt.field {
+				t.Fatalf("error = %v, want field %q", ve, tt.field)
+			}
+		})
+	}
+
+	plan := ToolCallPlan{TenantID: "t", AgentID: "a", SessionID: "s", IdempotencyKey: "k", Steps: []ToolCallRequest{step("Jira", "issue.create")}}
+	if err := plan.NormalizeAndValidate(); err != nil {
+		t.Fatal(err)
+	}
+	if got := plan.Steps[0]; got.TenantID != "t" || got.AgentID != "a" || got.SessionID != "s" || got.Tool != "jira" {
+		t.Fatalf("step did not inherit the plan's identity: %+v", got)
+	}
+}
@@ -0,0 +1,200 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Resource URIs — the canonical form of ToolCallRequest.Resource.
+// ──────────────────────────────────────────────────────────────────────────────
+
+// A resource URI names what a call acts on as the tool followed by
+// kind/id pairs from the outermost container inwards:
+//
+//	slack://channel/C123
+//	slack://channel/C123/message/1712345678.000100
+//	jira://project/OPS/issue/42
+//
+// IDs are path-escaped, so they may contain any character. Resources
+// without "://" are free-form strings kept for callers that predate URIs.
+
+var (
+	validResourceScheme = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,63}$`)
+	validResourceKind   = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,31}$`)
+)
+
+// ResourceSegment is one kind/id pair of a resource URI.
+type ResourceSegment struct {
+	Kind string `json:"kind"`
+	ID   string `json:"id"`
+}
+
+// ResourceURI is a parsed resource URI.
+type ResourceURI struct {
+	Scheme   string            `json:"scheme"`
+	Segments []ResourceSegment `json:"segments"`
+}
+
+// NewResourceURI builds the URI for scheme and kind/id pairs, e.g.
+// NewResourceURI("jira", "project", "OPS", "issue", "42").
+func NewResourceURI(scheme string, kindIDs ...string) ResourceURI {
+	u := ResourceURI{Scheme: scheme}
+	for i := 0; i+1 < len(kindIDs); i += 2 {
+		u.Segments = append(u.Segments, ResourceSegment{Kind: kindIDs[i], ID: kindIDs[i+1]})
+	}
+	return u
+}
+
+// IsResourceURI reports whether s is meant as a resource URI rather than a
+// free-form resource.
+func IsResourceURI(s string) bool {
+	return strings.Contains(s, "://")
+}
+
+// ParseResourceURI parses a resource URI. The scheme and kinds are
+// lowercased; IDs are kept as given.
+func ParseResourceURI(s string) (ResourceURI, error) {
+	u, err := parseResource(s, false)
+	if err != nil {
+		return ResourceURI{}, fmt.Errorf("types.ParseResourceURI: %w", err)
+	}
+	return u, nil
+}
+
+// parseResource parses s; with patterns set, IDs may be path.Match globs
+// and the last pair may be the single element "**".
+func parseResource(s string, patterns bool) (ResourceURI, error) {
+	scheme, rest, ok := strings.Cut(s, "://")
+	if !ok {
+		return ResourceURI{}, errors.New("missing scheme")
+	}
+	scheme = strings.ToLower(scheme)
+	if !validResourceScheme.MatchString(scheme) && !(patterns && scheme == "*") {
+		return ResourceURI{}, fmt.Errorf("invalid scheme %q", scheme)
+	}
+	u := ResourceURI{Scheme: scheme}
+	parts := strings.Split(rest, "/")
+	if patterns && parts[len(parts)-1] == "**" {
+		u.Segments = append(u.Segments, ResourceSegment{Kind: "**"})
+		parts = parts[:len(parts)-1]
+	}
+	if len(parts)%2 != 0 || len(parts) == 0 && len(u.Segments) == 0 {
+		return ResourceURI{}, errors.New("path must be kind/id pairs")
+	}
+	var segs []ResourceSegment
+	for i := 0; i < len(parts); i += 2 {
+		kind := strings.ToLower(parts[i])
+		if !validResourceKind.MatchString(kind) && !(patterns && kind == "*") {
+			return ResourceURI{}, fmt.Errorf("invalid kind %q", parts[i])
+		}
+		id, err := url.PathUnescape(parts[i+1])
+		if err != nil || id == "" {
+			return ResourceURI{}, fmt.Errorf("invalid id for %s", kind)
+		}
+		if patterns {
+			if _, err := path.Match(id, ""); err != nil {
+				return ResourceURI{}, fmt.Errorf("invalid id pattern for %s", kind)
+			}
+		}
+		segs = append(segs, ResourceSegment{Kind: kind, ID: id})
+	}
+	u.Segments = append(segs, u.Segments...)
+	return u, nil
+}
+
+// String renders the URI in canonical form.
+func (u ResourceURI) String() string {
+	var b strings.Builder
+	b.WriteString(u.Scheme)
+	b.WriteString("://")
+	for i, s := range u.Segments {
+		if i > 0 {
+			b.WriteByte('/')
+		}
+		if s.Kind == "**" {
+			b.WriteString("**")
+			continue
+		}
+		b.WriteString(s.Kind)
+		b.WriteByte('/')
+		b.WriteString(url.PathEscape(s.ID))
+	}
+	return b.String()
+}
+
+// ID returns the id of the first segment of kind, or "".
+func (u ResourceURI) ID(kind string) string {
+	for _, s := range u.Segments {
+		if s.Kind == kind {
+			return s.ID
+		}
+	}
+	return ""
+}
+
+// ValidateResourcePattern checks a grant resource pattern. URI patterns
+// must parse; free-form patterns must be valid path.Match globs.
+func ValidateResourcePattern(pattern string) error {
+	if pattern == "" || pattern == "*" {
+		return nil
+	}
+	if IsResourceURI(pattern) {
+		if _, err := parseResource(pattern, true); err != nil {
+			return fmt.Errorf("types.ValidateResourcePattern: %w", err)
+		}
+		return nil
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("types.ValidateResourcePattern: %w", err)
+	}
+	return nil
+}
+
+// MatchResource reports whether resource matches a grant's pattern. An
+// empty or "*" pattern matches everything. A URI pattern matches URIs
+// segment by segment: the scheme and kinds match exactly or as "*", IDs
+// match as path.Match globs (which never cross segments), and a trailing
+// "**" matches any number of further segments, so
+// "jira://project/OPS/**" covers the project and everything in it. Any
+// other pattern is a path.Match glob over the whole string.
+func MatchResource(pattern, resource string) bool {
+	if pattern == "" || pattern == "*" {
+		return true
+	}
+	if !IsResourceURI(pattern) {
+		matched, err := path.Match(pattern, resource)
+		return err == nil && matched
+	}
+	p, err := parseResource(pattern, true)
+	if err != nil {
+		return false
+	}
+	r, err := ParseResourceURI(resource)
+	if err != nil {
+		return false
+	}
+	if p.Scheme != "*" && p.Scheme != r.Scheme {
+		return false
+	}
+	for i, ps := range p.Segments {
+		if ps.Kind == "**" {
+			return true
+		}
+		if i >= len(r.Segments) {
+			return false
+		}
+		rs := r.Segments[i]
+		if ps.Kind != "*" && ps.Kind != rs.Kind {
+			return false
+		}
+		if ok, _ := path.Match(ps.ID, rs.ID); !ok {
+			return false
+		}
+	}
+	return len(p.Segments) == len(r.Segments)
+}
@@ -0,0 +1,121 @@
+package types
+
+import "testing"
+
+func TestParseResourceURI(t *testing.T) {
+	u, err := ParseResourceURI("Jira://Project/OPS/issue/OPS-42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Scheme != "jira" || len(u.Segments) != 2 || u.ID("project") != "OPS" || u.ID("issue") != "OPS-42" {
+		t.Fatalf("parsed = %+v", u)
+	}
+	if got := u.String(); got != "jira://project/OPS/issue/OPS-42" {
+		t.Errorf("String() = %q", got)
+	}
+
+	u = NewResourceURI("slack", "channel", "#ops/alerts")
+	if got := u.String(); got != "slack://channel/%23ops%2Falerts" {
+		t.Errorf("escaped = %q", got)
+	}
+	back, err := ParseResourceURI(u.String())
+	if err != nil || back.ID("channel") != "#ops/alerts" {
+		t.Errorf("round trip = %+v, %v", back, err)
+	}
+
+	for _, bad := range []string{
+		"slack://",
+		"slack://channel",
+		"slack://channel/C1/message",
+		"slack://channel//",
+		"sl ack://channel/C1",
+		"slack://Chan nel/C1",
+		"slack://channel/%zz",
+		"slack://channel/C1/**",
+	} {
+		if _, err := ParseResourceURI(bad); err == nil {
+			t.Errorf("ParseResourceURI(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestMatchResource_URIs(t *testing.T) {
+	tests := []struct {
+		pattern, resource string
+		want              bool
+	}{
+		{"jira://project/OPS", "jira://project/OPS", true},
+		{"jira://project/OPS", "jira://project/OPS/issue/OPS-1", false},
+		{"jira://project/OPS/**", "jira://project/OPS", true},
+		{"jira://project/OPS/**", "jira://project/OPS/issue/OPS-1", true},
+		{"jira://project/OPS/**", "jira://project/OPSX/issue/OPSX-1", false},
+		{"jira://project/*/issue/*", "jira://project/SEC/issue/SEC-9", true},
+		{"jira://project/OP*", "jira://project/OPS", true},
+		// A glob never crosses segments, unlike a free-form glob would.
+		{"jira://project/*", "jira://project/OPS/issue/OPS-1", false},
+		{"jira://*/OPS", "jira://project/OPS", true},
+		{"*://project/OPS", "jira://project/OPS", true},
+		{"slack://channel/C1/**", "jira://channel/C1", false},
+		{"slack://channel/%23ops", "slack://channel/%23ops", true},
+		{"slack://channel/C1", "not-a-uri", false},
+		{"slack://channel/[", "slack://channel/C1", false},
+		// Free-form patterns keep path.Match semantics.
+		{"slack://*", "slack://channel/C1", false},
+		{"*", "slack://channel/C1", true},
+		{"channel-*", "channel-ops", true},
+	}
+	for _, tt := range tests {
+		if got := MatchResource(tt.pattern, tt.resource); got != tt.want {
+			t.Errorf("MatchResource(%q, %q) = %v, want %v", tt.pattern, tt.resource, got, tt.want)
+		}
+	}
+}
+
+func TestValidateResourcePattern(t *testing.T) {
+	for _, ok := range []string{"", "*", "C123", "projects/*", "jira://project/OPS/**", "*://*/x", "slack://**"} {
+		if err := ValidateResourcePattern(ok); err != nil {
+			t.Errorf("ValidateResourcePattern(%q) = %v", ok, err)
+		}
+	}
+	for _, bad := range []string{"[", "jira://project", "jira://project/[", "jira://**/project/OPS"} {
+		if err := ValidateResourcePattern(bad); err == nil {
+			t.Errorf("ValidateResourcePattern(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestNormalizeAndValidate_ResourceURI(t *testing.T) {
+	req := ToolCallRequest{
+		TenantID: "t1", AgentID: "a1", Tool: "jira", Action: "issue.create",
+		IdempotencyKey: "k1", Resource: "JIRA://Project/OPS",
+	}
+	if err := req.NormalizeAndValidate(); err != nil {
+		t.Fatal(err)
+	}
+	if req.Resource != "jira://project/OPS" {
+		t.Errorf("resource = %q, want canonical form", req.Resource)
+	}
+
+	for _, resource := range []string{"slack://channel/C1", "jira://project"} {
+		req.Resource = resource
+		err := req.NormalizeAndValidate()
+		if ve, ok := err.(*ValidationError); !ok || ve.Field != "resource" {
+			t.Errorf("resource %q: err = %v, want a resource validation error", resource, err)
+		}
+	}
+
+	req.Resource = "project/OPS"
+	if err := req.NormalizeAndValidate(); err != nil {
+		t.Errorf("free-form resource rejected: %v", err)
+	}
+}
+
+func TestNewPolicyResource(t *testing.T) {
+	if NewPolicyResource("project/OPS") != nil || NewPolicyResource("jira://bad") != nil {
+		t.Error("free-form and invalid resources should have no policy resource")
+	}
+	pr := NewPolicyResource("jira://project/OPS/issue/OPS-7")
+	if pr == nil || pr.URI != "jira://project/OPS/issue/OPS-7" || pr.Scheme != "jira" || pr.IDs["project"] != "OPS" || pr.IDs["issue"] != "OPS-7" {
+		t.Errorf("policy resource = %+v", pr)
+	}
+}
@@ -0,0 +1,81 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// weekdays are TimeWindow day names, indexed by time.Weekday.
+var weekdays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// TimeWindow is a window that recurs every week, in a time zone, e.g.
+// weekdays 9–17 in Berlin:
+//
+//	{"days": ["mon","tue","wed","thu","fri"], "start": "09:00", "end": "17:00", "timezone": "Europe/Berlin"}
+type TimeWindow struct {
+	// Days are the days the window opens on ("mon" … "sun"); empty means
+	// every day.
+	Days []string `json:"days,omitempty"`
+	// Start and End are "HH:MM" local times. End is exclusive; an End at or
+	// before Start closes the window the next day.
+	Start string `json:"start"`
+	End   string `json:"end"`
+	// Timezone is an IANA zone name; empty means UTC.
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// Validate checks the days, times and time zone.
+func (w TimeWindow) Validate() error {
+	for _, d := range w.Days {
+		if !slices.Contains(weekdays, d) {
+			return fmt.Errorf("unknown day %q; use mon, tue, wed, thu, fri, sat or sun", d)
+		}
+	}
+	if _, err := clockMinutes(w.Start); err != nil {
+		return fmt.Errorf("start: %w", err)
+	}
+	if _, err := clockMinutes(w.End); err != nil {
+		return fmt.Errorf("end: %w", err)
+	}
+	if _, err := time.LoadLocation(w.Timezone); err != nil {
+		return fmt.Errorf("unknown timezone %q", w.Timezone)
+	}
+	return nil
+}
+
+// Contains reports whether t falls inside the window. An invalid window
+// contains nothing.
+func (w TimeWindow) Contains(t time.Time) bool {
+	loc, err := time.LoadLocation(w.Timezone)
+	if err != nil {
+		return false
+	}
+	start, err1 := clockMinutes(w.Start)
+	end, err2 := clockMinutes(w.End)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	t = t.In(loc)
+	now := t.Hour()*60 + t.Minute()
+	if start < end {
+		return w.opensOn(t.Weekday()) && now >= start && now < end
+	}
+	// The window runs past midnight: it is either in the part opened today
+	// or in the tail of yesterday's.
+	return (w.opensOn(t.Weekday()) && now >= start) || (w.opensOn((t.Weekday()+6)%7) && now < end)
+}
+
+func (w TimeWindow) opensOn(d time.Weekday) bool {
+	return len(w.Days) == 0 || slices.Contains(w.Days, weekdays[d])
+}
+
+// clockMinutes parses "HH:MM" into minutes after midnight.
+func clockMinutes(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, errors.New("must be HH:MM")
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
@@ -0,0 +1,50 @@
+package types
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeWindow_Contains(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no tz database:", err)
+	}
+	office := TimeWindow{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "09:00", End: "17:00", Timezone: "Europe/Berlin"}
+	night := TimeWindow{Days: []string{"fri"}, Start: "22:00", End: "02:00"}
+
+	for _, tc := range []struct {
+		w    TimeWindow
+		at   time.Time
+		want bool
+	}{
+		{office, time.Date(2026, 10, 16, 9, 0, 0, 0, berlin), true},   // Friday
+		{office, time.Date(2026, 10, 16, 17, 0, 0, 0, berlin), false}, // end is exclusive
+		{office, time.Date(2026, 10, 16, 7, 30, 0, 0, time.UTC), true},
+		{office, time.Date(2026, 10, 18, 3, 0, 0, 0, berlin), false}, // Sunday
+		{night, time.Date(2026, 10, 16, 23, 0, 0, 0, time.UTC), true},
+		{night, time.Date(2026, 10, 17, 1, 59, 0, 0, time.UTC), true}, // Saturday, tail of Friday's
+		{night, time.Date(2026, 10, 17, 23, 0, 0, 0, time.UTC), false},
+		{TimeWindow{Start: "09:00", End: "bad"}, time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC), false},
+	} {
+		if got := tc.w.Contains(tc.at); got != tc.want {
+			t.Errorf("%+v.Contains(%s) = %v, want %v", tc.w, tc.at, got, tc.want)
+		}
+	}
+}
+
+func TestTimeWindow_Validate(t *testing.T) {
+	if err := (TimeWindow{Days: []string{"mon"}, Start: "09:00", End: "17:00", Timezone: "UTC"}).Validate(); err != nil {
+		t.Fatalf("valid window: %v", err)
+	}
+	for _, w := range []TimeWindow{
+		{Days: []string{"monday"}, Start: "09:00", End: "17:00"},
+		{Start: "9", End: "17:00"},
+		{Start: "09:00", End: "24:00"},
+		{Start: "09:00", End: "17:00", Timezone: "Mars/Olympus"},
+	} {
+		if err := w.Validate(); err == nil {
+			t.Errorf("%+v: expected error", w)
+		}
+	}
+}
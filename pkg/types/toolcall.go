@@ -2,8 +2,12 @@
 package types
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"math"
+	"net/url"
 	"regexp"
 	"strings"
 	"time"
@@ -11,6 +15,13 @@ import (
 
 var validToolAction = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,63}$`)
 
+var (
+	validApproverGroup = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._@:-]{0,127}$`)
+	// validNotifyChannel accepts a Slack channel name ("#ops-approvals")
+	// or ID ("C0123456789").
+	validNotifyChannel = regexp.MustCompile(`^(#[a-z0-9][a-z0-9._-]{0,79}|[CG][A-Z0-9]{8,20})$`)
+)
+
 // ──────────────────────────────────────────────────────────────────────────────
 // Limits
 // ──────────────────────────────────────────────────────────────────────────────
@@ -21,9 +32,77 @@ const (
 	MaxIdempotencyKeyBytes = 256
 	MaxLabelsCount         = 50
 	MaxRiskScore           = 10
-	CurrentSchemaVer       = "1.0"
+	MaxCallbackURLBytes    = 2048
+	MaxParamsRefBytes      = 16 * 1024 * 1024 // 16 MB
 )
 
+// ──────────────────────────────────────────────────────────────────────────────
+// Schema versions
+// ──────────────────────────────────────────────────────────────────────────────
+
+// Schema 1.1 adds deadline, priority, callback_url, cost_estimate,
+// params_ref, approver_group and notify_channel, and
+// makes parent_event_id part of the schema (1.0 requests may still carry it).
+// A request without schema_version is read as the current version; one that
+// declares 1.0 is validated as 1.0 and keeps that version in evidence.
+const (
+	SchemaVersion10  = "1.0"
+	SchemaVersion11  = "1.1"
+	CurrentSchemaVer = SchemaVersion11
+)
+
+// SupportedSchemaVersions lists the request schema versions the gateway
+// accepts, oldest first.
+var SupportedSchemaVersions = []string{SchemaVersion10, SchemaVersion11}
+
+// Priority ranks a call against other traffic. Empty means normal.
+type Priority string
+
+const (
+	PriorityLow    Priority = "low"
+	PriorityNormal Priority = "normal"
+	PriorityHigh   Priority = "high"
+)
+
+// BlobRef names an uploaded blob by its SHA-256 digest, "sha256:<64 hex>",
+// and its size in bytes.
+type BlobRef struct {
+	Digest string `json:"digest"`
+	Size   int64  `json:"size"`
+}
+
+var validBlobDigest = regexp.MustCompile(`^sha256:[0-9a-f]{64}$`)
+
+// NewBlobRef returns the ref of data.
+func NewBlobRef(data []byte) BlobRef {
+	sum := sha256.Sum256(data)
+	return BlobRef{Digest: "sha256:" + hex.EncodeToString(sum[:]), Size: int64(len(data))}
+}
+
+// Validate checks the digest format and that the size is within
+// MaxParamsRefBytes. field names the ref in the returned ValidationError.
+func (b BlobRef) Validate(field string) error {
+	if !validBlobDigest.MatchString(b.Digest) {
+		return &ValidationError{Field: field + ".digest", Reason: `must be "sha256:" and 64 lowercase hex digits`}
+	}
+	if b.Size <= 0 || b.Size > MaxParamsRefBytes {
+		return &ValidationError{Field: field + ".size", Reason: fmt.Sprintf("must be 1–%d bytes", MaxParamsRefBytes)}
+	}
+	return nil
+}
+
+// BlobUpload is the response of POST /v1/blobs: PUT the blob to UploadURL
+// before ExpiresAt, then send ParamsRef as a call's params_ref.
+type BlobUpload struct {
+	ParamsRef BlobRef   `json:"params_ref"`
+	UploadURL string    `json:"upload_url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ExecIdempotencyPrefix keys the event the gateway records when it executes
+// a call that was approved earlier: "exec:<approved event id>".
+const ExecIdempotencyPrefix = "exec:"
+
 // ──────────────────────────────────────────────────────────────────────────────
 // ToolCallRequest — the payload sent by an AI agent.
 // ──────────────────────────────────────────────────────────────────────────────
@@ -37,8 +116,10 @@ type ToolCallRequest struct {
 	Tool   string `json:"tool"`
 	Action string `json:"action"`
 
-	// Inputs
-	Params json.RawMessage `json:"params,omitempty"`
+	// Inputs. ParamsRef (schema 1.1) replaces Params for params too large to
+	// send inline: they are uploaded first and referenced by digest.
+	Params    json.RawMessage `json:"params,omitempty"`
+	ParamsRef *BlobRef        `json:"params_ref,omitempty"`
 
 	// Target
 	Resource string `json:"resource,omitempty"`
@@ -58,6 +139,35 @@ type ToolCallRequest struct {
 	IdempotencyKey string    `json:"idempotency_key"`
 	RequestedAt    time.Time `json:"requested_at"`
 	SchemaVersion  string    `json:"schema_version"`
+
+	// CompensatesEventID is set by the gateway on a compensating call to
+	// the event whose execution it undoes. Agents cannot set it.
+	CompensatesEventID string `json:"compensates_event_id,omitempty"`
+
+	// Schema 1.1. Deadline is the time after which the caller no longer
+	// wants the call made. Priority ranks it against other traffic.
+	// CallbackURL is where the caller wants the outcome delivered, and
+	// CostEstimate is its estimate of what the call will spend, in the
+	// tenant's budget unit.
+	Deadline     *time.Time `json:"deadline,omitempty"`
+	Priority     Priority   `json:"priority,omitempty"`
+	CallbackURL  string     `json:"callback_url,omitempty"`
+	CostEstimate float64    `json:"cost_estimate,omitempty"`
+
+	// Schema 1.1. ApproverGroup and NotifyChannel ask for the call's
+	// approval request to go to that approver group and also be posted to
+	// that Slack channel. The gateway honours them only where the tenant's
+	// settings allow it, and never over a group the policy decision names.
+	// Policy sees both as input.toolcall.* and can refuse them.
+	ApproverGroup string `json:"approver_group,omitempty"`
+	NotifyChannel string `json:"notify_channel,omitempty"`
+
+	// Correlation. ParentEventID names the call that caused this one, e.g.
+	// a call made from another call's output; the call inherits its
+	// parent's trace_id when it has none. PlanID is set by the gateway on
+	// plan steps to the plan's event ID. Agents cannot set it.
+	ParentEventID string `json:"parent_event_id,omitempty"`
+	PlanID        string `json:"plan_id,omitempty"`
 }
 
 // Normalize lowercases tool/action and ensures dotted format.
@@ -103,17 +213,91 @@ func (r *ToolCallRequest) NormalizeAndValidate() error {
 	if len(r.Resource) > MaxResourceBytes {
 		return &ValidationError{Field: "resource", Reason: fmt.Sprintf("exceeds %d bytes", MaxResourceBytes)}
 	}
+	if IsResourceURI(r.Resource) {
+		u, err := parseResource(r.Resource, false)
+		if err != nil {
+			return &ValidationError{Field: "resource", Reason: "invalid resource URI: " + err.Error()}
+		}
+		if u.Scheme != r.Tool {
+			return &ValidationError{Field: "resource", Reason: fmt.Sprintf("URI scheme must be the tool %q", r.Tool)}
+		}
+		r.Resource = u.String()
+	}
 	if len(r.Labels) > MaxLabelsCount {
 		return &ValidationError{Field: "labels", Reason: fmt.Sprintf("exceeds %d entries", MaxLabelsCount)}
 	}
-	if r.SchemaVersion == "" {
+	switch r.SchemaVersion {
+	case "":
 		r.SchemaVersion = CurrentSchemaVer
-	} else if r.SchemaVersion != CurrentSchemaVer {
-		return &ValidationError{Field: "schema_version", Reason: fmt.Sprintf("unsupported version %q, expected %q", r.SchemaVersion, CurrentSchemaVer)}
+	case SchemaVersion10:
+		if field := r.schema11Field(); field != "" {
+			return &ValidationError{Field: field, Reason: fmt.Sprintf("requires schema_version %s", SchemaVersion11)}
+		}
+	case SchemaVersion11:
+	default:
+		return &ValidationError{Field: "schema_version", Reason: fmt.Sprintf("unsupported version %q, supported: %s", r.SchemaVersion, strings.Join(SupportedSchemaVersions, ", "))}
 	}
 	if r.RequestedAt.IsZero() {
 		r.RequestedAt = time.Now().UTC()
 	}
+	return r.validateSchema11()
+}
+
+// schema11Field returns the first field set that schema 1.0 lacks, or "".
+func (r *ToolCallRequest) schema11Field() string {
+	switch {
+	case r.Deadline != nil:
+		return "deadline"
+	case r.Priority != "":
+		return "priority"
+	case r.CallbackURL != "":
+		return "callback_url"
+	case r.CostEstimate != 0:
+		return "cost_estimate"
+	case r.ParamsRef != nil:
+		return "params_ref"
+	case r.ApproverGroup != "":
+		return "approver_group"
+	case r.NotifyChannel != "":
+		return "notify_channel"
+	}
+	return ""
+}
+
+// validateSchema11 checks the fields schema 1.1 added.
+func (r *ToolCallRequest) validateSchema11() error {
+	if r.Deadline != nil && !r.Deadline.After(r.RequestedAt) {
+		return &ValidationError{Field: "deadline", Reason: "must be after requested_at"}
+	}
+	switch r.Priority {
+	case "", PriorityLow, PriorityNormal, PriorityHigh:
+	default:
+		return &ValidationError{Field: "priority", Reason: "must be low, normal or high"}
+	}
+	if r.CallbackURL != "" {
+		if len(r.CallbackURL) > MaxCallbackURLBytes {
+			return &ValidationError{Field: "callback_url", Reason: fmt.Sprintf("exceeds %d bytes", MaxCallbackURLBytes)}
+		}
+		u, err := url.Parse(r.CallbackURL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return &ValidationError{Field: "callback_url", Reason: "must be an absolute http(s) URL"}
+		}
+	}
+	if r.CostEstimate < 0 || math.IsNaN(r.CostEstimate) || math.IsInf(r.CostEstimate, 0) {
+		return &ValidationError{Field: "cost_estimate", Reason: "must be a non-negative number"}
+	}
+	if r.ApproverGroup != "" && !validApproverGroup.MatchString(r.ApproverGroup) {
+		return &ValidationError{Field: "approver_group", Reason: "must be 1–128 characters of A-Z, a-z, 0-9 and ._@:-"}
+	}
+	if r.NotifyChannel != "" && !validNotifyChannel.MatchString(r.NotifyChannel) {
+		return &ValidationError{Field: "notify_channel", Reason: `must be a Slack channel name ("#name") or ID`}
+	}
+	if r.ParamsRef != nil {
+		if len(r.Params) > 0 && string(r.Params) != "null" {
+			return &ValidationError{Field: "params_ref", Reason: "cannot be combined with params"}
+		}
+		return r.ParamsRef.Validate("params_ref")
+	}
 	return nil
 }
 
@@ -131,15 +315,42 @@ type ToolCallEnvelope struct {
 	Request      ToolCallRequest `json:"request"`
 	PayloadJSON  json.RawMessage `json:"payload_json"`
 	PayloadCanon []byte          `json:"payload_canon"`
-	ReceivedAt   time.Time       `json:"received_at"`
+	// CanonVersion is the canonical JSON form of PayloadCanon and the
+	// result's canonical bytes: 1 for legacy, 2 for RFC 8785 (JCS).
+	CanonVersion int       `json:"canon_version,omitempty"`
+	ReceivedAt   time.Time `json:"received_at"`
 
 	Decision     Decision      `json:"decision"`
 	PolicyResult *PolicyResult `json:"policy_result,omitempty"`
 
 	ExecutionResult *ExecutionResult `json:"execution_result,omitempty"`
 
+	// AdjustedRiskScore is the risk score after the policy's risk
+	// overrides, when it returned any; Request.RiskScore stays the score
+	// the agent sent.
+	AdjustedRiskScore *int `json:"adjusted_risk_score,omitempty"`
+
 	Hash     string `json:"hash"`
 	PrevHash string `json:"prev_hash"`
+	// EventSeq is the event's position in the evidence log, set by
+	// RecordEvent and on read.
+	EventSeq int64 `json:"event_seq,omitempty"`
+}
+
+// EventList is one page of GET /v1/toolcalls. NextAfterSeq, when set, is the
+// after_seq that fetches the next page.
+type EventList struct {
+	Events       []ToolCallEnvelope `json:"events"`
+	NextAfterSeq int64              `json:"next_after_seq,omitempty"`
+}
+
+// RiskScore returns the call's policy-adjusted risk score, or the score
+// the agent sent when policy did not override it.
+func (e *ToolCallEnvelope) RiskScore() int {
+	if e.AdjustedRiskScore != nil {
+		return *e.AdjustedRiskScore
+	}
+	return e.Request.RiskScore
 }
 
 // ──────────────────────────────────────────────────────────────────────────────
@@ -158,6 +369,50 @@ const (
 type PolicyInput struct {
 	ToolCall    ToolCallRequest   `json:"toolcall"`
 	Environment PolicyEnvironment `json:"environment"`
+	// Resource is toolcall.resource parsed, when it is a resource URI.
+	Resource *PolicyResource `json:"resource,omitempty"`
+	// Budgets are the tenant's budgets that cover the calling agent, with
+	// this period's spend. Absent when the tenant has none.
+	Budgets []BudgetState `json:"budgets,omitempty"`
+	// Injection is what the gateway's prompt-injection scan found in the
+	// params. Absent when it found nothing.
+	Injection []InjectionFinding `json:"injection,omitempty"`
+}
+
+// InjectionFinding is a heuristic match for prompt injection in a call's
+// params: the rule, the JSON Pointer of the string that matched, and the
+// matched text, cut to a short excerpt.
+type InjectionFinding struct {
+	Rule  string `json:"rule"`
+	Path  string `json:"path"`
+	Match string `json:"match"`
+}
+
+// PolicyResource is a resource URI as policy sees it. IDs maps each kind to
+// its id, e.g. input.resource.ids.project == "OPS".
+type PolicyResource struct {
+	URI string `json:"uri"`
+	ResourceURI
+	IDs map[string]string `json:"ids"`
+}
+
+// NewPolicyResource parses resource for policy input; it returns nil for
+// free-form or invalid resources.
+func NewPolicyResource(resource string) *PolicyResource {
+	if !IsResourceURI(resource) {
+		return nil
+	}
+	u, err := ParseResourceURI(resource)
+	if err != nil {
+		return nil
+	}
+	ids := make(map[string]string, len(u.Segments))
+	for _, s := range u.Segments {
+		if _, dup := ids[s.Kind]; !dup {
+			ids[s.Kind] = s.ID
+		}
+	}
+	return &PolicyResource{URI: u.String(), ResourceURI: u, IDs: ids}
 }
 
 type PolicyEnvironment struct {
@@ -165,14 +420,159 @@ type PolicyEnvironment struct {
 	TenantConfig map[string]string `json:"tenant_config,omitempty"`
 }
 
+// Reason codes say, in a form clients can branch on, why a call was
+// decided as it was; reason is the free text for humans. Policy sets its
+// own codes as reason_code; the gateway sets the ones below for decisions
+// it makes itself. The default bundle's codes are the ReasonCode*
+// constants marked as such.
+
+// ReasonCodeBlocklisted marks a denial by the tenant's blocklist, which
+// the gateway applies before policy.
+const ReasonCodeBlocklisted = "blocklisted"
+
+// ReasonCodeCanary marks a denial because the call touched one of the
+// tenant's canary resources, which the gateway checks before anything else.
+const ReasonCodeCanary = "canary_resource"
+
+// ReasonCodeNotInCatalog marks a denial because the call's tool and action
+// are outside the tenant's tool catalog.
+const ReasonCodeNotInCatalog = "not_in_catalog"
+
+// ReasonCodePolicyUnavailable marks a denial because the policy engine
+// could not be reached and the tenant has no fallback policy.
+const ReasonCodePolicyUnavailable = "policy_unavailable"
+
+// ReasonCodeStateUnavailable marks a denial because state the decision
+// depends on, such as budgets, freeze windows or the params blob, could
+// not be read.
+const ReasonCodeStateUnavailable = "state_unavailable"
+
+// ReasonCodeInvalidRequest marks a denial because the call itself could
+// not be read, such as a recorded plan whose steps do not parse.
+const ReasonCodeInvalidRequest = "invalid_request"
+
+// ReasonCodeInvalidDecision marks a denial because policy returned a
+// decision other than allow, deny or approve.
+const ReasonCodeInvalidDecision = "invalid_decision"
+
+// ReasonCodeDeadlineExceeded marks a denial because the call's deadline
+// passed before it could be decided.
+const ReasonCodeDeadlineExceeded = "deadline_exceeded"
+
+// ReasonCodeFreezeWindow marks a decision forced by one of the tenant's
+// freeze windows.
+const ReasonCodeFreezeWindow = "freeze_window"
+
+// ReasonCodePolicyFallback marks a decision made by the tenant's fallback
+// policy because the policy engine could not be reached.
+const ReasonCodePolicyFallback = "policy_fallback"
+
+// ReasonCodeQuarantined marks an executed call whose output is withheld
+// from the agent until an approver releases it.
+const ReasonCodeQuarantined = "quarantined"
+
+// Reason codes of the default bundle (policy/bundles/v0).
+const (
+	ReasonCodeBudgetExceeded    = "budget_exceeded"
+	ReasonCodePromptInjection   = "prompt_injection"
+	ReasonCodeHighRisk          = "high_risk"
+	ReasonCodeDestructiveAction = "destructive_action"
+	ReasonCodeAllowlistedRead   = "allowlisted_read"
+	ReasonCodeAllowlistedWrite  = "allowlisted_write"
+	ReasonCodeNotAllowlisted    = "not_allowlisted"
+)
+
+// MaxMatchedRules caps the rule identifiers kept from a decision.
+const MaxMatchedRules = 20
+
+var (
+	validReasonCode  = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+	validMatchedRule = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._:/@-]{0,127}$`)
+)
+
+// ValidReasonCode reports whether code is a well-formed reason code:
+// lowercase snake_case, at most 64 bytes.
+func ValidReasonCode(code string) bool {
+	return validReasonCode.MatchString(code)
+}
+
+// ValidMatchedRule reports whether id is a well-formed rule identifier,
+// e.g. "allowlist.read" or "acme/pci:card-data".
+func ValidMatchedRule(id string) bool {
+	return validMatchedRule.MatchString(id)
+}
+
+// RequirementQuarantine is the policy requirement that quarantines an
+// allowed call's output; its value is the reason shown to reviewers, e.g.
+// {"quarantine": "anomalous access pattern"}.
+const RequirementQuarantine = "quarantine"
+
+// RequirementChangeTicket is the policy requirement that files a Jira
+// change ticket once an approved call has executed. Its value is the
+// project key, optionally followed by ":" and the issue type, e.g.
+// {"change_ticket": "CHG:Change"}.
+const RequirementChangeTicket = "change_ticket"
+
+// MinApprovalTTL and MaxApprovalTTL bound how long an approval request may
+// stay pending and a grant may last, whether tenant settings or policy set
+// the lifetime.
+const (
+	MinApprovalTTL = time.Minute
+	MaxApprovalTTL = 30 * 24 * time.Hour
+)
+
 // PolicyResult is what OPA returns.
 type PolicyResult struct {
-	Decision      Decision          `json:"decision"`
-	Reason        string            `json:"reason"`
+	Decision   Decision `json:"decision"`
+	Reason     string   `json:"reason"`
+	ReasonCode string   `json:"reason_code,omitempty"`
+	// MatchedRules identifies the rules that matched the call, in the
+	// policy's own naming, e.g. ["risk.high", "allowlist.write"].
+	MatchedRules  []string          `json:"matched_rules,omitempty"`
 	Requirements  map[string]string `json:"requirements,omitempty"`
 	RiskOverrides map[string]int    `json:"risk_overrides,omitempty"`
 	Notify        []PolicyNotify    `json:"notify,omitempty"`
 	ApproverGroup string            `json:"approver_group,omitempty"`
+	// ApprovalTTLSec and GrantTTLSec, when positive, set how long an
+	// approval request the decision opens stays pending and how long grants
+	// on it last, overriding the tenant's approval_ttl_sec and grant_ttl_sec.
+	// They are held to MinApprovalTTL..MaxApprovalTTL.
+	ApprovalTTLSec int `json:"approval_ttl_sec,omitempty"`
+	GrantTTLSec    int `json:"grant_ttl_sec,omitempty"`
+	// FreezeWindow names the tenant freeze window that forced the decision.
+	FreezeWindow string `json:"freeze_window,omitempty"`
+	// Canary names the tenant canary resource the call touched.
+	Canary string `json:"canary,omitempty"`
+	// Fallback is set when the tenant's fallback policy decided because
+	// the policy engine could not be reached.
+	Fallback bool `json:"fallback,omitempty"`
+	// Injection is the prompt-injection findings in the call's params that
+	// policy was given; kept here so evidence records them.
+	Injection []InjectionFinding `json:"injection,omitempty"`
+}
+
+// RiskOverrideScore is the risk override that replaces the agent's score
+// outright. Any other override key names an adjustment added to it, e.g.
+// {"prod_resource": 3}.
+const RiskOverrideScore = "risk_score"
+
+// AdjustedRiskScore applies the result's risk overrides to score: the
+// risk_score override, when present, replaces it, then the other overrides
+// are added, and the result is clamped to 0–MaxRiskScore. It returns false
+// when there are no overrides.
+func (r *PolicyResult) AdjustedRiskScore(score int) (int, bool) {
+	if r == nil || len(r.RiskOverrides) == 0 {
+		return score, false
+	}
+	if v, ok := r.RiskOverrides[RiskOverrideScore]; ok {
+		score = v
+	}
+	for k, v := range r.RiskOverrides {
+		if k != RiskOverrideScore {
+			score += v
+		}
+	}
+	return min(max(score, 0), MaxRiskScore), true
 }
 
 type PolicyNotify struct {
@@ -180,6 +580,8 @@ type PolicyNotify struct {
 	URL       string `json:"url,omitempty"`
 	SecretRef string `json:"secret_ref,omitempty"`
 	Channel   string `json:"channel,omitempty"`
+	// Config holds provider-specific settings, e.g. email "to" and "from".
+	Config map[string]string `json:"config,omitempty"`
 }
 
 // ──────────────────────────────────────────────────────────────────────────────
@@ -191,6 +593,132 @@ type ExecutionResult struct {
 	OutputJSON json.RawMessage `json:"output_json,omitempty"`
 	Error      string          `json:"error,omitempty"`
 	DurationMS int64           `json:"duration_ms"`
+	// Cost is what the execution spent as reported by the connector (API
+	// credits, a dollar estimate), in the tenant's budget unit.
+	Cost float64 `json:"cost,omitempty"`
+	// Compensation is the call that undoes this execution, when the
+	// connector declared one.
+	Compensation *Compensation `json:"compensation,omitempty"`
+	// Connector identifies the connector build that performed the
+	// execution. It is part of the hashed result evidence.
+	Connector *ConnectorInfo `json:"connector,omitempty"`
+	// Truncation is set when the connector's output was over the gateway's
+	// limit and OutputJSON holds a truncated stand-in for it.
+	Truncation *OutputTruncation `json:"truncation,omitempty"`
+	// Quarantine is set when the output is withheld from the agent pending
+	// human review. OutputJSON is kept in evidence but left out of
+	// responses until the review releases it.
+	Quarantine *Quarantine `json:"quarantine,omitempty"`
+	// ChangeTicket is the change ticket filed for an approved execution
+	// under the change_ticket policy requirement.
+	ChangeTicket *ChangeTicket `json:"change_ticket,omitempty"`
+	// Schedule is set when the execution ran from a schedule made with
+	// execute_at rather than when it was requested.
+	Schedule *ExecutionSchedule `json:"schedule,omitempty"`
+}
+
+// ExecutionSchedule records, on a scheduled execution's result, when it was
+// scheduled and for when.
+type ExecutionSchedule struct {
+	ID          string    `json:"id"`
+	ScheduledAt time.Time `json:"scheduled_at"`
+	ExecuteAt   time.Time `json:"execute_at"`
+}
+
+// ChangeTicket records the change-management ticket filed for an
+// execution. Key is empty and Error says why when filing failed; the
+// execution itself stands either way.
+type ChangeTicket struct {
+	Project string `json:"project"`
+	Key     string `json:"key,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Quarantine records why an execution's output was withheld.
+type Quarantine struct {
+	Reason string `json:"reason"`
+	// Flags are the connector's findings about the output, e.g.
+	// "dlp:credit_card".
+	Flags []string `json:"flags,omitempty"`
+}
+
+// Withheld returns a copy of r without its output when r is quarantined,
+// and r itself otherwise.
+func (r *ExecutionResult) Withheld() *ExecutionResult {
+	if r == nil || r.Quarantine == nil {
+		return r
+	}
+	c := *r
+	c.OutputJSON = nil
+	return &c
+}
+
+// OutputTruncation records output that was too large to keep: its size and
+// digest, so the full output can be matched later, and where it was
+// spilled to, if anywhere.
+type OutputTruncation struct {
+	OriginalBytes int64  `json:"original_bytes"`
+	SHA256        string `json:"sha256"`
+	// Ref is the object key the full output was written to in the blob
+	// bucket; empty when it was not kept.
+	Ref string `json:"ref,omitempty"`
+}
+
+// Compensation is a call on the same tool that undoes an execution, e.g.
+// jira issue.delete for issue.create.
+type Compensation struct {
+	Action   string          `json:"action"`
+	Params   json.RawMessage `json:"params,omitempty"`
+	Resource string          `json:"resource,omitempty"`
+}
+
+// ConnectorInfo identifies a connector build: its name and version as the
+// connector reported them, the upstream endpoint it called, and the
+// connector URL the gateway routed the call to.
+type ConnectorInfo struct {
+	Name     string `json:"name,omitempty"`
+	Version  string `json:"version,omitempty"`
+	Endpoint string `json:"endpoint,omitempty"`
+	Backend  string `json:"backend,omitempty"`
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Scheduled executions
+// ──────────────────────────────────────────────────────────────────────────────
+
+// ExecuteRequest is the optional body of POST /v1/toolcalls/{event_id}/execute.
+// A future ExecuteAt schedules the approved call instead of running it now.
+type ExecuteRequest struct {
+	ExecuteAt *time.Time `json:"execute_at,omitempty"`
+}
+
+// Scheduled execution statuses.
+const (
+	ScheduleScheduled = "scheduled"
+	ScheduleRunning   = "running"
+	ScheduleExecuted  = "executed"
+	ScheduleCancelled = "cancelled"
+	ScheduleFailed    = "failed"
+)
+
+// ScheduledExecution is an approved call set to run at ExecuteAt. Its
+// grant was consumed when it was scheduled, so cancelling it does not give
+// the grant back.
+type ScheduledExecution struct {
+	ID       string `json:"id"`
+	EventID  string `json:"event_id"`
+	TenantID string `json:"tenant_id"`
+	GrantID  string `json:"grant_id"`
+	// ExecuteAt is when the scheduler runs the call, at the first poll
+	// after it.
+	ExecuteAt time.Time `json:"execute_at"`
+	Status    string    `json:"status"`
+	// ExecutionEventID is the evidence event of the execution once it ran.
+	ExecutionEventID string `json:"execution_event_id,omitempty"`
+	// Error says why a failed schedule did not run.
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 // ──────────────────────────────────────────────────────────────────────────────
@@ -198,9 +726,15 @@ type ExecutionResult struct {
 // ──────────────────────────────────────────────────────────────────────────────
 
 type ToolCallResponse struct {
-	EventID     string           `json:"event_id"`
-	Decision    Decision         `json:"decision"`
-	Reason      string           `json:"reason,omitempty"`
-	ApprovalURL string           `json:"approval_url,omitempty"`
-	Result      *ExecutionResult `json:"result,omitempty"`
+	EventID    string   `json:"event_id"`
+	Decision   Decision `json:"decision"`
+	Reason     string   `json:"reason,omitempty"`
+	ReasonCode string   `json:"reason_code,omitempty"`
+	// MatchedRules are the policy decision's matched rule identifiers.
+	MatchedRules []string         `json:"matched_rules,omitempty"`
+	ApprovalURL  string           `json:"approval_url,omitempty"`
+	Result       *ExecutionResult `json:"result,omitempty"`
+	// Receipt is a signed attestation of the recorded event (a compact JWS,
+	// see pkg/receipts). Set when the gateway has a receipt signing key.
+	Receipt string `json:"receipt,omitempty"`
 }
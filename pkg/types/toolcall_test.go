@@ -202,3 +202,96 @@ func TestToolAction(t *testing.T) {
 		t.Errorf("expected 'slack.msg.post', got %q", got)
 	}
 }
+
+func TestValidate_Schema10RejectsSchema11Fields(t *testing.T) {
+	req := ToolCallRequest{
+		TenantID: "t", AgentID: "a", Tool: "t", Action: "a",
+		IdempotencyKey: "k", SchemaVersion: SchemaVersion10, ParentEventID: "p",
+	}
+	if err := req.NormalizeAndValidate(); err != nil {
+		t.Fatalf("1.0 request rejected: %v", err)
+	}
+	if req.SchemaVersion != SchemaVersion10 {
+		t.Errorf("schema_version rewritten to %q", req.SchemaVersion)
+	}
+
+	req.Priority = PriorityHigh
+	err := req.NormalizeAndValidate()
+	if ve, ok := err.(*ValidationError); !ok || ve.Field != "priority" {
+		t.Fatalf("expected priority to require 1.1, got %v", err)
+	}
+}
+
+func TestValidate_Schema11Fields(t *testing.T) {
+	now := time.Now().UTC()
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Minute)
+	base := func() ToolCallRequest {
+		return ToolCallRequest{
+			TenantID: "t", AgentID: "a", Tool: "t", Action: "a",
+			IdempotencyKey: "k", RequestedAt: now,
+		}
+	}
+
+	ok := base()
+	ok.Deadline, ok.Priority, ok.CallbackURL, ok.CostEstimate = &future, PriorityLow, "https://hooks.example.com/oc", 0.25
+	if err := ok.NormalizeAndValidate(); err != nil || ok.SchemaVersion != SchemaVersion11 {
+		t.Fatalf("valid 1.1 request: %v, version %q", err, ok.SchemaVersion)
+	}
+
+	for field, mutate := range map[string]func(*ToolCallRequest){
+		"deadline":       func(r *ToolCallRequest) { r.Deadline = &past },
+		"priority":       func(r *ToolCallRequest) { r.Priority = "urgent" },
+		"callback_url":   func(r *ToolCallRequest) { r.CallbackURL = "ftp://example.com" },
+		"cost_estimate":  func(r *ToolCallRequest) { r.CostEstimate = -1 },
+		"approver_group": func(r *ToolCallRequest) { r.ApproverGroup = "sre team" },
+		"notify_channel": func(r *ToolCallRequest) { r.NotifyChannel = "#SRE" },
+		"params_ref": func(r *ToolCallRequest) {
+			r.Params, r.ParamsRef = json.RawMessage(`{}`), &BlobRef{Digest: "sha256:" + strings.Repeat("a", 64), Size: 2}
+		},
+		"params_ref.digest": func(r *ToolCallRequest) { r.ParamsRef = &BlobRef{Digest: "sha256:abc", Size: 2} },
+		"params_ref.size": func(r *ToolCallRequest) {
+			r.ParamsRef = &BlobRef{Digest: "sha256:" + strings.Repeat("a", 64), Size: MaxParamsRefBytes + 1}
+		},
+	} {
+		req := base()
+		mutate(&req)
+		err := req.NormalizeAndValidate()
+		if ve, ok := err.(*ValidationError); !ok || ve.Field != field {
+			t.Errorf("%s: expected validation error, got %v", field, err)
+		}
+	}
+}
+
+func TestPolicyResult_AdjustedRiskScore(t *testing.T) {
+	cases := []struct {
+		name      string
+		overrides map[string]int
+		want      int
+		adjusted  bool
+	}{
+		{"none", nil, 4, false},
+		{"delta", map[string]int{"prod_resource": 3}, 7, true},
+		{"deltas sum", map[string]int{"prod_resource": 3, "read_only": -2}, 5, true},
+		{"replace then delta", map[string]int{RiskOverrideScore: 2, "prod_resource": 1}, 3, true},
+		{"clamped high", map[string]int{"prod_resource": 20}, MaxRiskScore, true},
+		{"clamped low", map[string]int{RiskOverrideScore: -5}, 0, true},
+	}
+	for _, tc := range cases {
+		r := &PolicyResult{RiskOverrides: tc.overrides}
+		got, adjusted := r.AdjustedRiskScore(4)
+		if got != tc.want || adjusted != tc.adjusted {
+			t.Errorf("%s: got %d, %v; want %d, %v", tc.name, got, adjusted, tc.want, tc.adjusted)
+		}
+	}
+
+	env := &ToolCallEnvelope{Request: ToolCallRequest{RiskScore: 4}}
+	if env.RiskScore() != 4 {
+		t.Errorf("unadjusted envelope risk score = %d, want 4", env.RiskScore())
+	}
+	adjusted := 9
+	env.AdjustedRiskScore = &adjusted
+	if env.RiskScore() != 9 {
+		t.Errorf("adjusted envelope risk score = %d, want 9", env.RiskScore())
+	}
+}
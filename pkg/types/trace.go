@@ -0,0 +1,77 @@
+package types
+
+import (
+	"strings"
+	"time"
+)
+
+// MaxTraceEvents bounds the events assembled into one trace view.
+const MaxTraceEvents = 500
+
+// How a trace node relates to its parent node.
+const (
+	RelationChild        = "child"        // the call named the parent in parent_event_id
+	RelationExecution    = "execution"    // the approved parent's execution
+	RelationPlanStep     = "plan_step"    // a step of the parent plan
+	RelationCompensation = "compensation" // undoes the parent's execution
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Trace — the tree of related tool calls sharing a trace ID.
+// ──────────────────────────────────────────────────────────────────────────────
+
+// Trace is every event recorded under one trace ID, arranged by how the
+// calls caused each other. Events whose parent lies outside the trace are
+// roots. Truncated is set when the trace has more than MaxTraceEvents events.
+type Trace struct {
+	TraceID   string       `json:"trace_id"`
+	TenantID  string       `json:"tenant_id"`
+	Events    int          `json:"events"`
+	Truncated bool         `json:"truncated,omitempty"`
+	Roots     []*TraceNode `json:"roots"`
+}
+
+// TraceNode is one recorded event with its approvals and the events it
+// caused, oldest first.
+type TraceNode struct {
+	EventID       string           `json:"event_id"`
+	Relation      string           `json:"relation,omitempty"`
+	ParentEventID string           `json:"parent_event_id,omitempty"`
+	AgentID       string           `json:"agent_id"`
+	Tool          string           `json:"tool"`
+	Action        string           `json:"action"`
+	Resource      string           `json:"resource,omitempty"`
+	Decision      Decision         `json:"decision"`
+	Reason        string           `json:"reason,omitempty"`
+	ReceivedAt    time.Time        `json:"received_at"`
+	Result        *ExecutionResult `json:"result,omitempty"`
+	Approvals     []TraceApproval  `json:"approvals,omitempty"`
+	Children      []*TraceNode     `json:"children,omitempty"`
+}
+
+// TraceApproval is an approval request raised for a traced event.
+type TraceApproval struct {
+	ID         string `json:"id"`
+	Status     string `json:"status"`
+	Reason     string `json:"reason"`
+	DenyReason string `json:"deny_reason,omitempty"`
+	// Kind is "quarantine" for a review of the event's withheld output.
+	Kind      string    `json:"kind,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TraceParent returns the event r was caused by and how, or "" when r
+// names none. The execution link is recognised by the key /execute assigns.
+func TraceParent(r *ToolCallRequest) (eventID, relation string) {
+	switch {
+	case r.CompensatesEventID != "":
+		return r.CompensatesEventID, RelationCompensation
+	case strings.HasPrefix(r.IdempotencyKey, ExecIdempotencyPrefix):
+		return strings.TrimPrefix(r.IdempotencyKey, ExecIdempotencyPrefix), RelationExecution
+	case r.PlanID != "":
+		return r.PlanID, RelationPlanStep
+	case r.ParentEventID != "":
+		return r.ParentEventID, RelationChild
+	}
+	return "", ""
+}
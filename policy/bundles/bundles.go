@@ -0,0 +1,17 @@
+// Package bundles exposes the default policy bundle to Go, for the
+// gateway's embedded policy engine.
+package bundles
+
+import _ "embed"
+
+// DefaultData is v0/data.json: the allowlists and seed tenants the default
+// bundle evaluates against.
+//
+//go:embed v0/data.json
+var DefaultData []byte
+
+//go:embed v0/main.rego
+var defaultPolicy string
+
+// DefaultModules are the default bundle's Rego modules, keyed by file name.
+var DefaultModules = map[string]string{"main.rego": defaultPolicy}
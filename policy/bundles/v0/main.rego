@@ -17,10 +17,42 @@ default decision := "deny"
 default reason := "action not in allowlist"
 
 # ──────────────────────────────────────────────────────────────────────────────
-# Priority 1: High-risk score → approve (checked first regardless of lists)
+# Priority 0: Spent budget → deny. input.budgets is set by the gateway when
+# the tenant has budgets; would_exceed uses the call's cost_estimate.
 # ──────────────────────────────────────────────────────────────────────────────
 
-decision := "approve" if {
+over_budget if {
+	some b in input.budgets
+	b.exceeded
+}
+
+over_budget if {
+	some b in input.budgets
+	b.would_exceed
+}
+
+# ──────────────────────────────────────────────────────────────────────────────
+# Priority 1: Suspected prompt injection → approve. input.injection lists the
+# gateway's heuristic findings in the params; it is absent when there are none.
+# ──────────────────────────────────────────────────────────────────────────────
+
+suspected_injection if {
+	count(object.get(input, "injection", [])) > 0
+}
+
+risk_overrides := {"prompt_injection": 3} if {
+	suspected_injection
+}
+
+# ──────────────────────────────────────────────────────────────────────────────
+# Priority 2: High-risk score → approve (checked first regardless of lists)
+# ──────────────────────────────────────────────────────────────────────────────
+
+decision := "deny" if {
+	over_budget
+} else := "approve" if {
+	suspected_injection
+} else := "approve" if {
 	input.toolcall.risk_score >= 7
 } else := "approve" if {
 	tool_action := concat(".", [input.toolcall.tool, input.toolcall.action])
@@ -37,7 +69,11 @@ decision := "approve" if {
 	input.toolcall.risk_score < threshold
 }
 
-reason := "high risk score requires approval" if {
+reason := "budget exceeded" if {
+	over_budget
+} else := "possible prompt injection in params requires approval" if {
+	suspected_injection
+} else := "high risk score requires approval" if {
 	input.toolcall.risk_score >= 7
 } else := "destructive action requires approval" if {
 	tool_action := concat(".", [input.toolcall.tool, input.toolcall.action])
@@ -54,6 +90,49 @@ reason := "high risk score requires approval" if {
 	input.toolcall.risk_score < threshold
 }
 
+# ──────────────────────────────────────────────────────────────────────────────
+# Output: reason_code and matched_rules, for clients that branch on why a
+# call was decided rather than parsing reason
+# ──────────────────────────────────────────────────────────────────────────────
+
+destructive_action if {
+	concat(".", [input.toolcall.tool, input.toolcall.action]) in data.allowlist.destructive_actions
+}
+
+allowlisted(actions) if {
+	concat(".", [input.toolcall.tool, input.toolcall.action]) in actions
+	threshold := object.get(object.get(data.tenants, input.toolcall.tenant_id, {}), "max_risk_auto_approve", 7)
+	input.toolcall.risk_score < threshold
+}
+
+default reason_code := "not_allowlisted"
+
+reason_code := "budget_exceeded" if {
+	over_budget
+} else := "prompt_injection" if {
+	suspected_injection
+} else := "high_risk" if {
+	input.toolcall.risk_score >= 7
+} else := "destructive_action" if {
+	destructive_action
+} else := "allowlisted_read" if {
+	allowlisted(data.allowlist.read_actions)
+} else := "allowlisted_write" if {
+	allowlisted(data.allowlist.write_actions)
+}
+
+matched_rules contains "budget.exceeded" if over_budget
+
+matched_rules contains "injection.suspected" if suspected_injection
+
+matched_rules contains "risk.high" if input.toolcall.risk_score >= 7
+
+matched_rules contains "allowlist.destructive" if destructive_action
+
+matched_rules contains "allowlist.read" if allowlisted(data.allowlist.read_actions)
+
+matched_rules contains "allowlist.write" if allowlisted(data.allowlist.write_actions)
+
 # ──────────────────────────────────────────────────────────────────────────────
 # Output: requirements for approve decisions
 # ──────────────────────────────────────────────────────────────────────────────
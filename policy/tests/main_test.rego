@@ -316,3 +316,91 @@ test_unknown_tenant_uses_default_threshold if {
 	}
 	result == "allow"
 }
+
+# ──────────────────────────────────────────────────────────────────────────────
+# Test: budgets deny an allowlisted call once spent or about to be
+# ──────────────────────────────────────────────────────────────────────────────
+
+budget_input(state) := {
+	"toolcall": {
+		"tenant_id": "tenant1",
+		"agent_id": "agent-1",
+		"tool": "jira",
+		"action": "issue.list",
+		"risk_score": 1,
+		"idempotency_key": "key-budget"
+	},
+	"environment": {
+		"timestamp": "2025-01-01T00:00:00Z"
+	},
+	"budgets": [object.union({"period": "month", "limit": 10, "spent": 4, "remaining": 6}, state)]
+}
+
+test_allow_within_budget if {
+	main.decision == "allow" with input as budget_input({"exceeded": false, "would_exceed": false})
+}
+
+test_deny_budget_exceeded if {
+	main.decision == "deny" with input as budget_input({"exceeded": true, "would_exceed": true})
+	main.reason == "budget exceeded" with input as budget_input({"exceeded": true, "would_exceed": true})
+}
+
+test_deny_budget_would_exceed if {
+	main.decision == "deny" with input as budget_input({"exceeded": false, "would_exceed": true})
+}
+
+# ──────────────────────────────────────────────────────────────────────────────
+# Test: injection findings escalate an allowlisted call to approval
+# ──────────────────────────────────────────────────────────────────────────────
+
+injection_input := {
+	"toolcall": {
+		"tenant_id": "tenant1",
+		"agent_id": "agent-1",
+		"tool": "slack",
+		"action": "msg.post",
+		"risk_score": 1,
+		"idempotency_key": "key-injection"
+	},
+	"environment": {
+		"timestamp": "2025-01-01T00:00:00Z"
+	},
+	"injection": [{"rule": "instruction_override", "path": "/text", "match": "ignore all previous instructions"}]
+}
+
+test_approve_suspected_injection if {
+	main.decision == "approve" with input as injection_input
+	main.reason == "possible prompt injection in params requires approval" with input as injection_input
+	main.risk_overrides == {"prompt_injection": 3} with input as injection_input
+	main.reason_code == "prompt_injection" with input as injection_input
+	main.matched_rules == {"injection.suspected", "allowlist.write"} with input as injection_input
+}
+
+# ──────────────────────────────────────────────────────────────────────────────
+# Test: reason codes follow the decision that was made
+# ──────────────────────────────────────────────────────────────────────────────
+
+test_reason_codes if {
+	main.reason_code == "budget_exceeded" with input as budget_input({"exceeded": true, "would_exceed": true})
+	main.reason_code == "allowlisted_read" with input as budget_input({"exceeded": false, "would_exceed": false})
+	main.matched_rules == {"budget.exceeded", "allowlist.read"} with input as budget_input({"exceeded": true, "would_exceed": true})
+}
+
+test_reason_code_not_allowlisted if {
+	unknown := {
+		"toolcall": {
+			"tenant_id": "tenant1",
+			"agent_id": "agent-1",
+			"tool": "unknown",
+			"action": "do",
+			"risk_score": 1,
+			"idempotency_key": "key-unknown"
+		},
+		"environment": {
+			"timestamp": "2025-01-01T00:00:00Z"
+		}
+	}
+	main.decision == "deny" with input as unknown
+	main.reason_code == "not_allowlisted" with input as unknown
+	count(main.matched_rules) == 0 with input as unknown
+}